@@ -3,67 +3,340 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
 	httpHandlers "github.com/erickfunier/ai-smart-queue/internal/adapters/inbound/http"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/ai"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/webhook"
 	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
+	domainInsights "github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	domainQueue "github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/redaction"
 	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
 	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/database"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
 )
 
 func main() {
 	// Load configuration
 	cfg, err := config.LoadConfig("configs/config.yaml")
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		logging.Fatal("Failed to load config", slog.String("error", err.Error()))
+	}
+	logging.Setup("ai-insights-service", cfg.Logging.Level, cfg.Logging.Format)
+	if err := cfg.Validate(); err != nil {
+		logging.Fatal("Invalid config", slog.String("error", err.Error()))
+	}
+
+	// Sensitive payload data is masked in logs and in the prompts sent to the AI provider
+	redactor, err := logging.SetupRedaction(cfg.Redaction)
+	if err != nil {
+		logging.Fatal("Invalid redaction config", slog.String("error", err.Error()))
+	}
+
+	aiService, err := ai.NewProviderChain(cfg.AI)
+	if err != nil {
+		logging.Fatal("Invalid AI provider config", slog.String("error", err.Error()))
+	}
+
+	// Standalone, the service only analyzes failures sent in the request body and needs no database
+	if cfg.AI.Standalone {
+		insightsAppService := newInsightsService(cfg, nil, nil, aiService, redactor)
+		enableProviderHealth(cfg.AI.HealthCheck, insightsAppService, aiService)
+		mux := http.NewServeMux()
+		httpHandlers.RegisterStandaloneInsightsRoutes(mux, httpHandlers.NewInsightsHandlers(insightsAppService))
+		slog.Info("Running standalone, without Postgres and Redis")
+		serve(cfg, mux, aiService, insightsAppService)
+		return
 	}
 
 	// Initialize infrastructure - database connections
-	postgres, err := database.NewPostgresConnection(cfg.Postgres.DSN)
+	// Each statement is canceled on the server once it runs longer than the query timeout
+	queryLimits := database.QueryLimits{
+		Timeout:       time.Duration(cfg.Postgres.QueryTimeoutMs) * time.Millisecond,
+		SlowThreshold: time.Duration(cfg.Postgres.SlowQueryMs) * time.Millisecond,
+	}
+	postgres, err := database.NewPostgresConnection(cfg.Postgres.DSN, queryLimits)
 	if err != nil {
-		log.Fatalf("postgres connection error: %v", err)
+		logging.Fatal("Postgres connection error", slog.String("error", err.Error()))
 	}
 	defer postgres.Close()
 
 	if err := postgres.Ping(context.Background()); err != nil {
-		log.Fatalf("postgres ping error: %v", err)
+		logging.Fatal("Postgres ping error", slog.String("error", err.Error()))
+	}
+	slog.Info("Connected to Postgres")
+
+	// Listing and analytics queries go to the read replica when one is configured
+	readRouter := persistence.NewReadRouter(postgres.Pool, nil)
+	if cfg.Postgres.ReadDSN != "" {
+		replica, err := database.NewPostgresConnection(cfg.Postgres.ReadDSN, queryLimits)
+		if err != nil {
+			slog.Warn("Read replica config error, using primary for reads", slog.String("error", err.Error()))
+		} else {
+			defer replica.Close()
+			readRouter = persistence.NewReadRouter(postgres.Pool, replica.Pool)
+			slog.Info("Read replica configured")
+		}
 	}
-	log.Println("✅ Connected to Postgres")
 
 	// Initialize secondary adapters
-	insightRepo := persistence.NewPostgresInsightRepository(postgres.Pool)
-	jobRepo := persistence.NewPostgresJobRepository(postgres.Pool)
-	aiService := ai.NewOllamaAIService(cfg.AI.OllamaURL)
+	// Fixes applied to large payloads are stored compressed, like the payloads they patch
+	payloadCompressor, err := persistence.NewPayloadCompressor(cfg.PayloadCompression.Algorithm, cfg.PayloadCompression.ThresholdBytes)
+	if err != nil {
+		logging.Fatal("Invalid payload compression config", slog.String("error", err.Error()))
+	}
+	jobRepo := persistence.NewPostgresJobRepository(postgres.Pool).WithReadRouter(readRouter).WithPayloadCompression(payloadCompressor)
+	// Insights are stored next to the jobs in Postgres, or in MongoDB when the insight store is mongodb
+	var insightRepo domainInsights.InsightRepository = persistence.NewPostgresInsightRepository(postgres.Pool).WithReadRouter(readRouter)
+	var analysisRepo domainInsights.AnalysisRepository = persistence.NewPostgresAnalysisRepository(postgres.Pool)
+	if cfg.InsightStore.Mongo() {
+		mongo, err := database.NewMongoConnection(cfg.InsightStore.MongoDB.URI, cfg.InsightStore.MongoDB.ResolvedDatabase())
+		if err != nil {
+			logging.Fatal("MongoDB connection error", slog.String("error", err.Error()))
+		}
+		defer mongo.Close()
+		if err := mongo.Ping(context.Background()); err != nil {
+			logging.Fatal("MongoDB ping error", slog.String("error", err.Error()))
+		}
+		mongoInsights := persistence.NewMongoInsightRepository(mongo.Database, jobRepo)
+		if err := mongoInsights.EnsureIndexes(context.Background()); err != nil {
+			logging.Fatal("MongoDB index error", slog.String("error", err.Error()))
+		}
+		insightRepo = mongoInsights
+		mongoAnalyses := persistence.NewMongoAnalysisRepository(mongo.Database)
+		if err := mongoAnalyses.EnsureIndexes(context.Background()); err != nil {
+			logging.Fatal("MongoDB index error", slog.String("error", err.Error()))
+		}
+		analysisRepo = mongoAnalyses
+		slog.Info("Using the MongoDB insight store", slog.String("database", cfg.InsightStore.MongoDB.ResolvedDatabase()))
+	}
 
 	// Initialize application service
-	insightsAppService := appInsights.NewService(insightRepo, jobRepo, aiService)
+	insightsAppService := newInsightsService(cfg, insightRepo, jobRepo, aiService, redactor)
+	// Failure analyses see the job's past runs, the end of its output and its queue's settings
+	insightsAppService.SetAttemptHistory(persistence.NewPostgresAttemptRepository(postgres.Pool))
+	insightsAppService.SetQueueDefinitions(persistence.NewPostgresQueueDefinitionRepository(postgres.Pool))
+	if cfg.JobOutput.Enabled {
+		insightsAppService.SetOutputStore(persistence.NewPostgresOutputStore(postgres.Pool))
+	}
+
+	// During a failure storm only one job per error signature is analyzed by the AI
+	if cfg.AI.Storm.Enabled {
+		detector, err := domainInsights.NewStormDetector(stormConfig(cfg.AI.Storm))
+		if err != nil {
+			logging.Fatal("Invalid failure storm config", slog.String("error", err.Error()))
+		}
+		insightsAppService.SetStormDetector(detector)
+		slog.Info("Failure storm sampling enabled")
+	}
+	// A failure whose error fingerprint was analyzed recently is linked to that insight
+	if cfg.AI.InsightReuse.Enabled {
+		insightsAppService.SetInsightReuse(time.Duration(cfg.AI.InsightReuse.WindowHours) * time.Hour)
+		slog.Info("Insight reuse by error fingerprint enabled")
+	}
+	// Insights are grouped by the similarity of their diagnoses for GET /api/insights/clusters
+	if cfg.AI.Clustering.Enabled {
+		if err := insightsAppService.SetInsightClustering(aiService, clusterConfig(cfg.AI.Clustering)); err != nil {
+			logging.Fatal("Invalid insight clustering config", slog.String("error", err.Error()))
+		}
+		go insightsAppService.RunInsightClustering(context.Background(), time.Duration(cfg.AI.Clustering.IntervalMinutes)*time.Minute)
+		slog.Info("Insight clustering enabled")
+	}
+	enableProviderHealth(cfg.AI.HealthCheck, insightsAppService, aiService)
+
+	// Jobs an applied fix retries are enqueued again; without Redis they wait for the consistency
+	// repair. With the Postgres queue backend the retried row is already ready to be claimed.
+	if err := cfg.QueueBackend.Validate(); err != nil {
+		logging.Fatal("Invalid queue backend config", slog.String("error", err.Error()))
+	}
+	redis, err := database.NewRedisConnection(cfg.Redis)
+	if err != nil {
+		logging.Fatal("Redis config error", slog.String("error", err.Error()))
+	}
+	defer redis.Close()
+	if cfg.QueueBackend.Postgres() {
+		insightsAppService.SetQueueService(persistence.NewPostgresQueueService(postgres.Pool))
+	} else if err := redis.Ping(context.Background()); err != nil {
+		slog.Warn("Redis unavailable, applied fixes won't enqueue their jobs", slog.String("error", err.Error()))
+	} else {
+		queueCodec, err := persistence.NewJobCodec(cfg.Redis.Codec, payloadCompressor)
+		if err != nil {
+			logging.Fatal("Invalid Redis queue codec", slog.String("error", err.Error()))
+		}
+		insightsAppService.SetQueueService(persistence.NewRedisQueueService(redis.Client).
+			WithKeyPrefix(cfg.Redis.ResolvedKeyPrefix()).WithCodec(queueCodec))
+		slog.Info("Connected to Redis")
+	}
+
+	// Job payloads are HMAC signed at creation and verified before execution
+	if cfg.PayloadSigning.Enabled {
+		signer, err := domainQueue.NewPayloadSigner(cfg.PayloadSigning.Secret, cfg.PayloadSigning.APIKeySecrets, cfg.PayloadSigning.Required)
+		if err != nil {
+			logging.Fatal("Invalid payload signing config", slog.String("error", err.Error()))
+		}
+		insightsAppService.SetPayloadSigner(signer)
+	}
+
+	// Analyses requested with async=true run in the background; their outcome is polled or posted back
+	asyncAnalyzer := appInsights.NewAsyncAnalyzer(insightsAppService, analysisRepo, cfg.AI.AnalysisConcurrency, cfg.AI.AsyncBacklog)
+	asyncAnalyzer.SetNotifier(webhook.NewCallbackNotifier(
+		cfg.Webhook.SigningSecret,
+		cfg.Webhook.MaxAttempts,
+		time.Duration(cfg.Webhook.TimeoutSeconds)*time.Second,
+		nil,
+	))
+	go asyncAnalyzer.Run(context.Background())
 
 	// Initialize HTTP handlers
 	insightsHandlers := httpHandlers.NewInsightsHandlers(insightsAppService)
+	insightsHandlers.SetAsyncAnalyzer(asyncAnalyzer)
+	insightsHandlers.SetPageLimits(httpHandlers.PageLimits{Default: cfg.Server.Pagination.DefaultLimit, Max: cfg.Server.Pagination.MaxLimit})
 
 	// Setup routes
 	mux := http.NewServeMux()
 	httpHandlers.RegisterInsightsRoutes(mux, insightsHandlers)
 
-	// Add health endpoint
+	serve(cfg, mux, aiService, insightsAppService)
+}
+
+// newInsightsService creates the insights service with the settings the standalone and the
+// database-backed modes share
+func newInsightsService(cfg *config.Config, insightRepo domainInsights.InsightRepository, jobRepo domainQueue.JobRepository,
+	aiService *ai.ProviderChain, redactor *redaction.Redactor) *appInsights.Service {
+	insightsAppService := appInsights.NewService(insightRepo, jobRepo, aiService)
+	insightsAppService.SetAnalysisTimeout(time.Duration(cfg.AI.AnalysisTimeoutSeconds) * time.Second)
+	insightsAppService.SetRedactor(redactor)
+	return insightsAppService
+}
+
+// enableProviderHealth probes the AI provider when configured, so synchronous analyses answer
+// 503 while it is down
+func enableProviderHealth(cfg config.AIHealthCheckConfig, insightsAppService *appInsights.Service, aiService *ai.ProviderChain) {
+	if !cfg.Enabled {
+		return
+	}
+	if err := insightsAppService.SetProviderHealth(aiService, providerHealthConfig(cfg)); err != nil {
+		logging.Fatal("Invalid AI provider health check config", slog.String("error", err.Error()))
+	}
+	go insightsAppService.RunProviderHealthChecks(context.Background())
+	slog.Info("AI provider health checks enabled")
+}
+
+// serve adds the health endpoint and config reloading to the routes and serves them until the
+// server fails
+func serve(cfg *config.Config, mux *http.ServeMux, aiService *ai.ProviderChain, insightsAppService *appInsights.Service) {
+	// Add health endpoint; while the AI provider is down the service is degraded rather than
+	// down, since analyses can still be queued, so the status stays 200 and the body says why
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
+		if provider, err := insightsAppService.ProviderHealth(); err == nil && provider.State == domainInsights.ProviderDegraded {
+			fmt.Fprintf(w, "DEGRADED: AI provider unhealthy after %d failed probes: %s", provider.ConsecutiveFailures, provider.LastError)
+			return
+		}
 		w.Write([]byte("OK"))
 	})
 
+	// Apply safe config changes on SIGHUP without restarting the server
+	reloader := config.NewReloader("configs/config.yaml", cfg)
+	reloader.OnReload(func(newCfg *config.Config) {
+		if err := aiService.Reload(newCfg.AI); err != nil {
+			slog.Error("Invalid AI provider config, keeping current providers", slog.String("error", err.Error()))
+		}
+	})
+	reloader.WatchSignals(context.Background())
+
 	// Start server
 	addr := fmt.Sprintf(":%d", 8082) // AI Insights runs on 8082
-	log.Printf("🚀 AI Insights service running on %s", addr)
-	log.Println("📦 Hexagonal Architecture initialized:")
-	log.Println("   ├─ Domain: Insight business logic")
-	log.Println("   ├─ Application: Analysis orchestration")
-	log.Println("   ├─ Adapters: HTTP handlers, AI service")
-	log.Println("   └─ Infrastructure: Database, Config")
+	slog.Info("AI Insights service running", slog.String("addr", addr))
+
+	// worker-runtime authenticates with the shared secret, external callers with API keys
+	authCfg := cfg.AI.InsightsAuth
+	auth := httpHandlers.NewServiceAuthenticator(authCfg.ServiceSecret, authCfg.APIKeys, authCfg.APIKeyHeader)
+	if auth == nil {
+		slog.Warn("Insights API authentication disabled, set ai.insights_auth to require credentials")
+	}
+	tlsConfig, err := authCfg.TLS.ServerTLS()
+	if err != nil {
+		logging.Fatal("Invalid insights TLS config", slog.String("error", err.Error()))
+	}
+
+	// Routes are served under /api/v1 and, for existing clients, the unversioned /api paths
+	versions := httpHandlers.NewAPIVersions(httpHandlers.CurrentAPIVersion, mux)
+	var handler http.Handler = httpHandlers.AuthMiddleware(auth, versions)
+	if accessLog := cfg.Logging.AccessLog; accessLog.Enabled {
+		handler = httpHandlers.AccessLogMiddleware(httpHandlers.AccessLogPolicy{
+			SampleRate:    accessLog.Rate(),
+			SlowThreshold: time.Duration(accessLog.SlowThresholdMs) * time.Millisecond,
+			SkipPaths:     accessLog.SkipPaths,
+		}, handler)
+		slog.Info("HTTP access log enabled",
+			slog.Float64("sampleRate", accessLog.Rate()),
+			slog.Int("slowThresholdMs", accessLog.SlowThresholdMs),
+		)
+	}
+
+	// Every request gets an ID that is echoed back and attached to its log records
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   httpHandlers.RequestIDMiddleware(handler),
+		TLSConfig: tlsConfig,
+	}
+	if tlsConfig != nil {
+		slog.Info("Serving over TLS", slog.Bool("clientCertificates", authCfg.TLS.CAFile != ""))
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil {
+		logging.Fatal("Server error", slog.String("error", err.Error()))
+	}
+}
+
+// stormConfig converts the YAML settings, keeping the defaults for unset values
+func stormConfig(cfg config.StormConfig) domainInsights.StormConfig {
+	stormCfg := domainInsights.DefaultStormConfig()
+	if cfg.FailureThreshold > 0 {
+		stormCfg.FailureThreshold = cfg.FailureThreshold
+	}
+	if cfg.WindowSeconds > 0 {
+		stormCfg.Window = time.Duration(cfg.WindowSeconds) * time.Second
+	}
+	return stormCfg
+}
 
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatalf("server error: %v", err)
+// clusterConfig converts the YAML settings, keeping the defaults for unset values
+func clusterConfig(cfg config.InsightClusteringConfig) domainInsights.ClusterConfig {
+	clusterCfg := domainInsights.DefaultClusterConfig()
+	if cfg.Similarity > 0 {
+		clusterCfg.Similarity = cfg.Similarity
+	}
+	if cfg.MinMembers > 0 {
+		clusterCfg.MinMembers = cfg.MinMembers
+	}
+	if cfg.WindowHours > 0 {
+		clusterCfg.Window = time.Duration(cfg.WindowHours) * time.Hour
+	}
+	if cfg.MaxInsights > 0 {
+		clusterCfg.MaxInsights = cfg.MaxInsights
+	}
+	return clusterCfg
+}
+
+// providerHealthConfig converts the YAML settings, keeping the defaults for unset values
+func providerHealthConfig(cfg config.AIHealthCheckConfig) domainInsights.ProviderHealthConfig {
+	healthCfg := domainInsights.DefaultProviderHealthConfig()
+	if cfg.IntervalSeconds > 0 {
+		healthCfg.Interval = time.Duration(cfg.IntervalSeconds) * time.Second
+	}
+	if cfg.TimeoutSeconds > 0 {
+		healthCfg.Timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	if cfg.FailAfter > 0 {
+		healthCfg.FailAfter = cfg.FailAfter
 	}
+	return healthCfg
 }
@@ -3,71 +3,566 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	httpHandlers "github.com/erickfunier/ai-smart-queue/internal/adapters/inbound/http"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/ai"
-	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/metrics"
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/events"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/ratelimit"
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/webhook"
+	appAlert "github.com/erickfunier/ai-smart-queue/internal/application/alert"
 	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
 	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	appQuota "github.com/erickfunier/ai-smart-queue/internal/application/quota"
+	domainAlert "github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	domainEvents "github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	domainInsights "github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	domainQueue "github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	domainQuota "github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	domainRateLimit "github.com/erickfunier/ai-smart-queue/internal/domain/ratelimit"
 	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
 	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/database"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
 )
 
+// queueBackend is what queue-core uses of either queue backend
+type queueBackend interface {
+	domainQueue.QueueService
+	domainQueue.QueueInspector
+	domainQueue.QueueWithdrawer
+	domainQueue.QueuePurger
+}
+
 func main() {
 	// Load configuration
 	cfg, err := config.LoadConfig("configs/config.yaml")
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		logging.Fatal("Failed to load config", slog.String("error", err.Error()))
+	}
+	logging.Setup("queue-core", cfg.Logging.Level, cfg.Logging.Format)
+	if err := cfg.Validate(); err != nil {
+		logging.Fatal("Invalid config", slog.String("error", err.Error()))
+	}
+
+	// SIGTERM drains the HTTP server and stops the background loops
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Sensitive payload data is masked in logs and in the prompts sent to the AI provider
+	redactor, err := logging.SetupRedaction(cfg.Redaction)
+	if err != nil {
+		logging.Fatal("Invalid redaction config", slog.String("error", err.Error()))
 	}
 
 	// Initialize infrastructure - database connections
-	postgres, err := database.NewPostgresConnection(cfg.Postgres.DSN)
+	// Each statement is canceled on the server once it runs longer than the query timeout
+	queryLimits := database.QueryLimits{
+		Timeout:       time.Duration(cfg.Postgres.QueryTimeoutMs) * time.Millisecond,
+		SlowThreshold: time.Duration(cfg.Postgres.SlowQueryMs) * time.Millisecond,
+	}
+	postgres, err := database.NewPostgresConnection(cfg.Postgres.DSN, queryLimits)
 	if err != nil {
-		log.Fatalf("postgres connection error: %v", err)
+		logging.Fatal("Postgres connection error", slog.String("error", err.Error()))
 	}
 	defer postgres.Close()
 
 	if err := postgres.Ping(context.Background()); err != nil {
-		log.Fatalf("postgres ping error: %v", err)
+		logging.Fatal("Postgres ping error", slog.String("error", err.Error()))
 	}
-	log.Println("✅ Connected to Postgres")
+	slog.Info("Connected to Postgres")
 
-	redis := database.NewRedisConnection(cfg.Redis.Addr, cfg.Redis.URL, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.TLSSkipVerify)
+	// Listing and analytics queries go to the read replica when one is configured
+	readRouter := persistence.NewReadRouter(postgres.Pool, nil)
+	if cfg.Postgres.ReadDSN != "" {
+		replica, err := database.NewPostgresConnection(cfg.Postgres.ReadDSN, queryLimits)
+		if err != nil {
+			slog.Warn("Read replica config error, using primary for reads", slog.String("error", err.Error()))
+		} else {
+			defer replica.Close()
+			readRouter = persistence.NewReadRouter(postgres.Pool, replica.Pool)
+			slog.Info("Read replica configured")
+		}
+	}
+
+	redis, err := database.NewRedisConnection(cfg.Redis)
+	if err != nil {
+		logging.Fatal("Redis config error", slog.String("error", err.Error()))
+	}
 	defer redis.Close()
 
 	if err := redis.Ping(context.Background()); err != nil {
-		log.Fatalf("redis ping error: %v", err)
+		logging.Fatal("Redis ping error", slog.String("error", err.Error()))
+	}
+	slog.Info("Connected to Redis")
+
+	// Every Redis key is namespaced so deployments can share one instance
+	redisPrefix := cfg.Redis.ResolvedKeyPrefix()
+	if redisPrefix != "" {
+		slog.Info("Using Redis key prefix", slog.String("prefix", redisPrefix))
 	}
-	log.Println("✅ Connected to Redis")
 
 	// Initialize secondary adapters (output ports implementations)
-	jobRepo := persistence.NewPostgresJobRepository(postgres.Pool)
-	insightRepo := persistence.NewPostgresInsightRepository(postgres.Pool)
-	queueService := persistence.NewRedisQueueService(redis.Client)
-	metricsService := metrics.NewInMemoryMetricsService()
-	aiService := ai.NewOllamaAIService(cfg.AI.OllamaURL)
+	// Large payloads are compressed in Redis and Postgres
+	payloadCompressor, err := persistence.NewPayloadCompressor(cfg.PayloadCompression.Algorithm, cfg.PayloadCompression.ThresholdBytes)
+	if err != nil {
+		logging.Fatal("Invalid payload compression config", slog.String("error", err.Error()))
+	}
+	jobRepo := persistence.NewPostgresJobRepository(postgres.Pool).WithPayloadCompression(payloadCompressor).WithReadRouter(readRouter)
+	// Insights are stored next to the jobs in Postgres, or in MongoDB when the insight store is mongodb
+	var insightRepo domainInsights.InsightRepository = persistence.NewPostgresInsightRepository(postgres.Pool).WithReadRouter(readRouter)
+	var analysisRepo domainInsights.AnalysisRepository = persistence.NewPostgresAnalysisRepository(postgres.Pool)
+	if cfg.InsightStore.Mongo() {
+		mongo, err := database.NewMongoConnection(cfg.InsightStore.MongoDB.URI, cfg.InsightStore.MongoDB.ResolvedDatabase())
+		if err != nil {
+			logging.Fatal("MongoDB connection error", slog.String("error", err.Error()))
+		}
+		defer mongo.Close()
+		if err := mongo.Ping(context.Background()); err != nil {
+			logging.Fatal("MongoDB ping error", slog.String("error", err.Error()))
+		}
+		mongoInsights := persistence.NewMongoInsightRepository(mongo.Database, jobRepo)
+		if err := mongoInsights.EnsureIndexes(context.Background()); err != nil {
+			logging.Fatal("MongoDB index error", slog.String("error", err.Error()))
+		}
+		insightRepo = mongoInsights
+		mongoAnalyses := persistence.NewMongoAnalysisRepository(mongo.Database)
+		if err := mongoAnalyses.EnsureIndexes(context.Background()); err != nil {
+			logging.Fatal("MongoDB index error", slog.String("error", err.Error()))
+		}
+		analysisRepo = mongoAnalyses
+		slog.Info("Using the MongoDB insight store", slog.String("database", cfg.InsightStore.MongoDB.ResolvedDatabase()))
+	}
+	queueCodec, err := persistence.NewJobCodec(cfg.Redis.Codec, payloadCompressor)
+	if err != nil {
+		logging.Fatal("Invalid Redis queue codec", slog.String("error", err.Error()))
+	}
+	// Ready jobs wait in Redis lists, or in the jobs table when the queue backend is postgres
+	if err := cfg.QueueBackend.Validate(); err != nil {
+		logging.Fatal("Invalid queue backend config", slog.String("error", err.Error()))
+	}
+	var queueService queueBackend
+	var deadLetters domainQueue.DeadLetterQueue
+	if cfg.QueueBackend.Postgres() {
+		queueService = persistence.NewPostgresQueueService(postgres.Pool)
+		slog.Info("Using the Postgres queue backend")
+	} else {
+		redisQueue := persistence.NewRedisQueueService(redis.Client).WithKeyPrefix(redisPrefix).WithCodec(queueCodec).
+			WithDeadLetterLimit(cfg.Redis.DeadLetterLimit)
+		queueService, deadLetters = redisQueue, redisQueue
+	}
+	// Counters live in Redis so failures recorded by the workers show here
+	metricsService := persistence.NewRedisMetricsService(redis.Client).WithKeyPrefix(redisPrefix)
+	aiService, err := ai.NewProviderChain(cfg.AI)
+	if err != nil {
+		logging.Fatal("Invalid AI provider config", slog.String("error", err.Error()))
+	}
 
 	// Initialize application services (use cases)
 	queueAppService := appQueue.NewService(jobRepo, queueService, metricsService)
+	queueAppService.SetMetricsReader(metricsService)
+	queueAppService.SetUnitOfWork(persistence.NewPostgresUnitOfWork(postgres.Pool))
+	queueAppService.SetBreakerStore(persistence.NewRedisBreakerStore(redis.Client).WithKeyPrefix(redisPrefix))
+	queueAppService.SetQueueInspector(queueService)
+	if deadLetters != nil {
+		queueAppService.SetDeadLetterQueue(deadLetters)
+	}
+	queueAppService.SetQueueWithdrawer(queueService)
+	queueAppService.SetQueuePurger(queueService, jobRepo)
+	queueAppService.SetParkedJobs(jobRepo)
+	queueAppService.SetGroupRepository(persistence.NewPostgresJobGroupRepository(postgres.Pool).WithReadRouter(readRouter))
+	queueAppService.SetHeartbeatStore(persistence.NewRedisHeartbeatStore(redis.Client).WithKeyPrefix(redisPrefix))
 	insightsAppService := appInsights.NewService(insightRepo, jobRepo, aiService)
+	insightsAppService.SetAnalysisTimeout(time.Duration(cfg.AI.AnalysisTimeoutSeconds) * time.Second)
+	insightsAppService.SetQueueService(queueService)
+
+	// Job payloads are HMAC signed at creation and verified before execution
+	if cfg.PayloadSigning.Enabled {
+		signer, err := domainQueue.NewPayloadSigner(cfg.PayloadSigning.Secret, cfg.PayloadSigning.APIKeySecrets, cfg.PayloadSigning.Required)
+		if err != nil {
+			logging.Fatal("Invalid payload signing config", slog.String("error", err.Error()))
+		}
+		queueAppService.SetPayloadSigner(signer)
+		insightsAppService.SetPayloadSigner(signer)
+	}
+
+	// New jobs record the caller that created them, by name or by hashed API key
+	queueAppService.SetJobOwners(domainQueue.Owners(cfg.Server.APIKeyNames))
+
+	// Queue definitions restrict the job types and creation rate of each queue
+	queueDefinitions := persistence.NewPostgresQueueDefinitionRepository(postgres.Pool)
+	queueAppService.SetQueueDefinitions(queueDefinitions, cfg.Queues.Enforce)
+	// Job lookups list the job's retries, and failure analyses see its past runs and its queue's settings
+	jobAttempts := persistence.NewPostgresAttemptRepository(postgres.Pool)
+	queueAppService.SetAttemptHistory(jobAttempts)
+	insightsAppService.SetAttemptHistory(jobAttempts)
+	insightsAppService.SetQueueDefinitions(queueDefinitions)
+	// Insights are grouped by the similarity of their diagnoses for GET /api/insights/clusters
+	if cfg.AI.Clustering.Enabled {
+		if err := insightsAppService.SetInsightClustering(aiService, clusterConfig(cfg.AI.Clustering)); err != nil {
+			logging.Fatal("Invalid insight clustering config", slog.String("error", err.Error()))
+		}
+		go insightsAppService.RunInsightClustering(ctx, time.Duration(cfg.AI.Clustering.IntervalMinutes)*time.Minute)
+		slog.Info("Insight clustering enabled")
+	}
+	queueAppService.SetQueueRateLimiter(ratelimit.NewRedisRateLimiter(redis.Client, domainRateLimit.Limit{}, nil).WithKeyPrefix(redisPrefix))
+
+	// Jobs and analyses are metered per API key against the default quota or a stored override
+	var quotaService *appQuota.Service
+	if cfg.Quotas.Enabled {
+		limits, err := defaultQuota(cfg.Quotas)
+		if err != nil {
+			logging.Fatal("Invalid quota config", slog.String("error", err.Error()))
+		}
+		quotaService = appQuota.NewService(
+			persistence.NewRedisQuotaStore(redis.Client).WithKeyPrefix(redisPrefix),
+			persistence.NewPostgresQuotaOverrideRepository(postgres.Pool),
+			limits,
+		)
+		queueAppService.SetQuotaEnforcer(quotaService)
+		slog.Info("Per API key quotas enabled")
+	}
+
+	// Per-queue job counts back the queue listing, and are sampled for the metrics history and
+	// scaling recommendation endpoints
+	queueStats := persistence.NewPostgresQueueStatsRepository(postgres.Pool).WithReadRouter(readRouter)
+	queueAppService.SetQueueCounter(queueStats)
+	if cfg.Stats.Enabled {
+		queueAppService.SetStatsRepository(queueStats)
+		go queueAppService.RunStatsSampler(ctx,
+			time.Duration(cfg.Stats.SampleIntervalSeconds)*time.Second,
+			time.Duration(cfg.Stats.RetentionDays)*24*time.Hour,
+		)
+	}
+	// Old completed jobs lose their payload and result but stay for audit; deleted jobs are purged
+	if cfg.Retention.Enabled {
+		queueAppService.SetPayloadRetentionRepository(jobRepo)
+		go queueAppService.RunRetentionJanitor(ctx,
+			domainQueue.RetentionPolicy{
+				PayloadRetention: time.Duration(cfg.Retention.PayloadDays) * 24 * time.Hour,
+				DeletedRetention: time.Duration(cfg.Retention.DeletedJobDays) * 24 * time.Hour,
+			},
+			time.Duration(cfg.Retention.IntervalMinutes)*time.Minute,
+		)
+	}
+	// Delayed jobs wait in Postgres until they are due; one instance at a time promotes them
+	hostname, _ := os.Hostname()
+	go queueAppService.RunScheduler(ctx,
+		persistence.NewRedisLeaderElector(redis.Client).WithKeyPrefix(redisPrefix),
+		fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		time.Duration(cfg.Scheduler.IntervalMs)*time.Millisecond,
+	)
+
+	// Output streamed by running jobs is stored by the workers and read back here
+	if cfg.JobOutput.Enabled {
+		outputStore := persistence.NewPostgresOutputStore(postgres.Pool)
+		queueAppService.SetOutputStore(outputStore)
+		insightsAppService.SetOutputStore(outputStore)
+	}
+
+	// Worker replica recommendations for KEDA/HPA are derived from the sampled history
+	queueAppService.SetScalingPolicy(domainQueue.ScalingPolicy{
+		TargetDrain:          time.Duration(cfg.Scaling.TargetDrainSeconds) * time.Second,
+		DefaultExecutionTime: time.Duration(cfg.Scaling.DefaultExecutionMillis) * time.Millisecond,
+		JobsPerReplica:       cfg.Scaling.JobsPerReplica,
+		MinReplicas:          cfg.Scaling.MinReplicas,
+		MaxReplicas:          cfg.Scaling.MaxReplicas,
+	})
+
+	// Domain events; further subscribers (webhooks, live feeds) plug in here.
+	// Events are relayed through Redis so the live feed also sees those raised by workers.
+	eventsChannel := redisPrefix + "events"
+	eventBus := events.NewInProcessBus()
+	eventBus.Subscribe(events.LogSubscriber(), domainEvents.NameInsightGenerated, domainEvents.NameAlertFired, domainEvents.NameGroupCompleted, domainEvents.NameDLQDigestSent,
+		domainEvents.NameAIProviderDegraded, domainEvents.NameAIProviderRecovered)
+	eventBus.Subscribe(events.RedisRelaySubscriber(redis.Client, eventsChannel))
+	queueAppService.SetEventPublisher(eventBus)
+	insightsAppService.SetEventPublisher(eventBus)
+	if redactor != nil {
+		insightsAppService.SetRedactor(redactor)
+	}
+	// The AI provider is probed, and synchronous analyses answer 503 while it is down
+	if cfg.AI.HealthCheck.Enabled {
+		if err := insightsAppService.SetProviderHealth(aiService, providerHealthConfig(cfg.AI.HealthCheck)); err != nil {
+			logging.Fatal("Invalid AI provider health check config", slog.String("error", err.Error()))
+		}
+		go insightsAppService.RunProviderHealthChecks(ctx)
+		slog.Info("AI provider health checks enabled")
+	}
+
+	// Relayed worker events also tell POST /api/jobs/execute when its job finished
+	liveFeed := httpHandlers.NewLiveFeed(queueAppService, httpHandlers.DefaultLiveFeedInterval)
+	go liveFeed.Run(context.Background())
+	completions := events.NewJobCompletions()
+	queueAppService.SetCompletionWaiter(completions)
+	go events.NewRedisEventStream(redis.Client, eventsChannel).Run(context.Background(), func(envelope domainEvents.Envelope) {
+		liveFeed.PublishEvent(envelope)
+		completions.HandleEnvelope(envelope)
+	})
+
+	// Analyses requested with async=true run in the background; their outcome is polled or posted back
+	asyncAnalyzer := appInsights.NewAsyncAnalyzer(insightsAppService, analysisRepo, cfg.AI.AnalysisConcurrency, cfg.AI.AsyncBacklog)
+	asyncAnalyzer.SetNotifier(webhook.NewCallbackNotifier(
+		cfg.Webhook.SigningSecret,
+		cfg.Webhook.MaxAttempts,
+		time.Duration(cfg.Webhook.TimeoutSeconds)*time.Second,
+		nil,
+	))
+	go asyncAnalyzer.Run(context.Background())
+
+	// Alert rules are evaluated over recent insights and failures; alerts are logged, published and posted to the rule's webhook
+	var alertService *appAlert.Service
+	if cfg.Alerts.Enabled {
+		alertRepo := persistence.NewPostgresAlertRepository(postgres.Pool).WithReadRouter(readRouter)
+		alertService = appAlert.NewService(alertRepo, alertRepo)
+		alertService.SetEventPublisher(eventBus)
+		alertService.SetNotifier(webhook.NewCallbackNotifier(
+			cfg.Webhook.SigningSecret,
+			cfg.Webhook.MaxAttempts,
+			time.Duration(cfg.Webhook.TimeoutSeconds)*time.Second,
+			nil,
+		))
+		go alertService.Run(ctx, time.Duration(cfg.Alerts.IntervalSeconds)*time.Second)
+		slog.Info("Alert rules enabled")
+	}
+
+	// The DLQ is sampled by the lease holder; a surge sends a digest to the webhook and the audit log
+	var dlqMonitor *appAlert.DLQMonitor
+	if digest := cfg.Alerts.DLQDigest; digest.Enabled {
+		digestLog := persistence.NewPostgresAlertRepository(postgres.Pool).WithReadRouter(readRouter)
+		dlqMonitor, err = appAlert.NewDLQMonitor(jobRepo, insightRepo, digestLog, domainAlert.DigestPolicy{
+			Threshold:      int64(digest.Threshold),
+			Window:         time.Duration(digest.WindowMinutes) * time.Minute,
+			Cooldown:       time.Duration(digest.CooldownMinutes) * time.Minute,
+			TopSignatures:  digest.TopSignatures,
+			SampleInsights: digest.SampleInsights,
+		})
+		if err != nil {
+			logging.Fatal("Invalid DLQ digest config", slog.String("error", err.Error()))
+		}
+		dlqMonitor.SetEventPublisher(eventBus)
+		dlqMonitor.SetNotifier(webhook.NewCallbackNotifier(
+			cfg.Webhook.SigningSecret,
+			cfg.Webhook.MaxAttempts,
+			time.Duration(cfg.Webhook.TimeoutSeconds)*time.Second,
+			nil,
+		), digest.WebhookURL)
+		go dlqMonitor.Run(ctx,
+			persistence.NewRedisLeaderElector(redis.Client).WithKeyPrefix(redisPrefix),
+			fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+			time.Duration(digest.IntervalSeconds)*time.Second,
+		)
+		slog.Info("DLQ digests enabled", slog.Int("threshold", digest.Threshold))
+	}
 
 	// Initialize primary adapters (input ports / HTTP handlers)
 	queueHandlers := httpHandlers.NewQueueHandlers(queueAppService, insightsAppService)
+	queueHandlers.SetAPIKeyHeader(cfg.RateLimit.APIKeyHeader)
+	pageLimits := httpHandlers.PageLimits{Default: cfg.Server.Pagination.DefaultLimit, Max: cfg.Server.Pagination.MaxLimit}
+	queueHandlers.SetPageLimits(pageLimits)
 	insightsHandlers := httpHandlers.NewInsightsHandlers(insightsAppService)
+	insightsHandlers.SetAsyncAnalyzer(asyncAnalyzer)
+	insightsHandlers.SetPageLimits(pageLimits)
+	if quotaService != nil {
+		queueHandlers.SetQuotaService(quotaService)
+		insightsHandlers.SetQuotaEnforcer(quotaService, cfg.RateLimit.APIKeyHeader, domainQueue.Owners(cfg.Server.APIKeyNames))
+	}
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	httpHandlers.RegisterQueueRoutes(mux, queueHandlers)
 	httpHandlers.RegisterInsightsRoutes(mux, insightsHandlers)
+	httpHandlers.RegisterLiveFeedRoutes(mux, liveFeed)
+	if alertService != nil {
+		httpHandlers.RegisterAlertRoutes(mux, httpHandlers.NewAlertHandlers(alertService))
+	}
+	if dlqMonitor != nil {
+		httpHandlers.RegisterDigestRoutes(mux, httpHandlers.NewDigestHandlers(dlqMonitor))
+	}
+	if cfg.Server.UI {
+		httpHandlers.RegisterUIRoutes(mux)
+		slog.Info("Admin UI enabled", slog.String("path", "/ui"))
+	}
+
+	// Routes are served under /api/v1 and, for existing clients, the unversioned /api paths
+	var handler http.Handler = httpHandlers.NewAPIVersions(httpHandlers.CurrentAPIVersion, mux)
+	var rateLimiter *ratelimit.RedisRateLimiter
+	var rateLimitedCallers *httpHandlers.RateLimitCallers
+	if cfg.RateLimit.Enabled {
+		callers := rateLimitCallers(cfg)
+		limit, overrides, err := rateLimits(cfg.RateLimit, callers)
+		if err != nil {
+			logging.Fatal("Invalid rate limit config", slog.String("error", err.Error()))
+		}
+		rateLimiter = ratelimit.NewRedisRateLimiter(redis.Client, limit, overrides).WithKeyPrefix(redisPrefix)
+		rateLimitedCallers = httpHandlers.NewRateLimitCallers(callers)
+		handler = httpHandlers.RateLimitMiddleware(rateLimiter, rateLimitedCallers, cfg.RateLimit.APIKeyHeader, handler)
+		slog.Info("Rate limiting enabled",
+			slog.Float64("requestsPerSecond", limit.RequestsPerSecond),
+			slog.Int("burst", limit.Burst),
+		)
+	}
+
+	// Browsers on other origins may call the API; preflights are answered before rate limiting
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		handler = httpHandlers.CORSMiddleware(httpHandlers.CORSPolicy{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAgeSeconds:    cfg.CORS.MaxAgeSeconds,
+		}, handler)
+		slog.Info("CORS enabled", slog.Any("allowedOrigins", cfg.CORS.AllowedOrigins))
+	}
+
+	// Requests are logged inside the request ID middleware so their records carry the ID
+	if accessLog := cfg.Logging.AccessLog; accessLog.Enabled {
+		handler = httpHandlers.AccessLogMiddleware(httpHandlers.AccessLogPolicy{
+			SampleRate:    accessLog.Rate(),
+			SlowThreshold: time.Duration(accessLog.SlowThresholdMs) * time.Millisecond,
+			SkipPaths:     accessLog.SkipPaths,
+		}, handler)
+		slog.Info("HTTP access log enabled",
+			slog.Float64("sampleRate", accessLog.Rate()),
+			slog.Int("slowThresholdMs", accessLog.SlowThresholdMs),
+		)
+	}
+
+	// Every request gets an ID that is echoed back and attached to its log records
+	handler = httpHandlers.RequestIDMiddleware(handler)
+
+	// Apply safe config changes on SIGHUP without restarting the server
+	reloader := config.NewReloader("configs/config.yaml", cfg)
+	reloader.OnReload(func(newCfg *config.Config) {
+		if err := aiService.Reload(newCfg.AI); err != nil {
+			slog.Error("Invalid AI provider config, keeping current providers", slog.String("error", err.Error()))
+		}
+
+		if rateLimiter != nil {
+			callers := rateLimitCallers(newCfg)
+			limit, overrides, err := rateLimits(newCfg.RateLimit, callers)
+			if err != nil {
+				slog.Warn("Ignoring invalid rate limit config on reload", slog.String("error", err.Error()))
+				return
+			}
+			rateLimiter.UpdateLimits(limit, overrides)
+			rateLimitedCallers.Update(callers)
+		}
+	})
+	reloader.WatchSignals(context.Background())
 
 	// Start server
-	addr := fmt.Sprintf(":%d", cfg.Server.Port)
-	log.Printf("🚀 Queue Core service running on %s", addr)
+	opts := serverOptions(cfg.Server)
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		logging.Fatal("Invalid server TLS config", slog.String("error", "cert_file and key_file must be set together"))
+	}
+	server := httpHandlers.NewServer(fmt.Sprintf(":%d", cfg.Server.Port), handler, opts)
+	slog.Info("Queue Core service running",
+		slog.String("addr", server.Addr()),
+		slog.Bool("tls", opts.TLSEnabled()),
+		slog.Bool("h2c", opts.H2C),
+	)
+
+	if err := server.ListenAndServe(ctx); err != nil {
+		logging.Fatal("Server error", slog.String("error", err.Error()))
+	}
+	slog.Info("Queue Core service stopped")
+}
+
+// serverOptions converts the server config into the HTTP server's timeouts, limits and TLS
+func serverOptions(cfg config.ServerConfig) httpHandlers.ServerOptions {
+	return httpHandlers.ServerOptions{
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeoutSeconds) * time.Second,
+		ReadTimeout:       time.Duration(cfg.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(cfg.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(cfg.IdleTimeoutSeconds) * time.Second,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ShutdownTimeout:   time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second,
+		CertFile:          cfg.TLS.CertFile,
+		KeyFile:           cfg.TLS.KeyFile,
+		H2C:               cfg.H2C,
+	}
+}
 
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatalf("server error: %v", err)
+// rateLimits converts the rate limit config into token bucket limits keyed like the middleware keys
+func rateLimits(cfg config.RateLimitConfig, callers domainQueue.Owners) (domainRateLimit.Limit, map[string]domainRateLimit.Limit, error) {
+	limit, err := domainRateLimit.NewLimit(cfg.RequestsPerSecond, cfg.Burst)
+	if err != nil {
+		return domainRateLimit.Limit{}, nil, err
+	}
+
+	// Overrides are keyed by the owner of the API key, so the key itself never reaches Redis
+	overrides := make(map[string]domainRateLimit.Limit, len(cfg.Overrides))
+	for apiKey, override := range cfg.Overrides {
+		owner := callers.Of(apiKey)
+		keyLimit, err := domainRateLimit.NewLimit(override.RequestsPerSecond, override.Burst)
+		if err != nil {
+			return domainRateLimit.Limit{}, nil, fmt.Errorf("override for %q: %w", owner, err)
+		}
+		overrides[httpHandlers.RateLimitOwnerKey(owner)] = keyLimit
+	}
+
+	return limit, overrides, nil
+}
+
+// rateLimitCallers returns the API keys with a rate limit bucket of their own: those named in
+// server.api_key_names and those given an override
+func rateLimitCallers(cfg *config.Config) domainQueue.Owners {
+	callers := make(domainQueue.Owners, len(cfg.Server.APIKeyNames)+len(cfg.RateLimit.Overrides))
+	for apiKey := range cfg.RateLimit.Overrides {
+		callers[apiKey] = ""
+	}
+	for apiKey, name := range cfg.Server.APIKeyNames {
+		callers[apiKey] = name
+	}
+	return callers
+}
+
+// defaultQuota converts the quota config into the quota of API keys without an override
+func defaultQuota(cfg config.QuotaConfig) (domainQuota.Quota, error) {
+	limits := domainQuota.Quota{
+		JobsPerDay:     cfg.JobsPerDay,
+		MaxPendingJobs: cfg.MaxPendingJobs,
+		AnalysesPerDay: cfg.AnalysesPerDay,
+	}
+	return limits, limits.Validate()
+}
+
+// clusterConfig converts the YAML settings, keeping the defaults for unset values
+func clusterConfig(cfg config.InsightClusteringConfig) domainInsights.ClusterConfig {
+	clusterCfg := domainInsights.DefaultClusterConfig()
+	if cfg.Similarity > 0 {
+		clusterCfg.Similarity = cfg.Similarity
+	}
+	if cfg.MinMembers > 0 {
+		clusterCfg.MinMembers = cfg.MinMembers
+	}
+	if cfg.WindowHours > 0 {
+		clusterCfg.Window = time.Duration(cfg.WindowHours) * time.Hour
+	}
+	if cfg.MaxInsights > 0 {
+		clusterCfg.MaxInsights = cfg.MaxInsights
+	}
+	return clusterCfg
+}
+
+// providerHealthConfig converts the YAML settings, keeping the defaults for unset values
+func providerHealthConfig(cfg config.AIHealthCheckConfig) domainInsights.ProviderHealthConfig {
+	healthCfg := domainInsights.DefaultProviderHealthConfig()
+	if cfg.IntervalSeconds > 0 {
+		healthCfg.Interval = time.Duration(cfg.IntervalSeconds) * time.Second
+	}
+	if cfg.TimeoutSeconds > 0 {
+		healthCfg.Timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	if cfg.FailAfter > 0 {
+		healthCfg.FailAfter = cfg.FailAfter
 	}
+	return healthCfg
 }
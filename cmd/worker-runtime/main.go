@@ -1,89 +1,456 @@
 package main
 
 import (
+	"cmp"
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
+	httpHandlers "github.com/erickfunier/ai-smart-queue/internal/adapters/inbound/http"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/ai"
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/events"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/executor"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/insights"
 	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/webhook"
 	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
+	appQuota "github.com/erickfunier/ai-smart-queue/internal/application/quota"
 	appWorker "github.com/erickfunier/ai-smart-queue/internal/application/worker"
+	domainEvents "github.com/erickfunier/ai-smart-queue/internal/domain/events"
 	domainInsights "github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	domainQueue "github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	domainQuota "github.com/erickfunier/ai-smart-queue/internal/domain/quota"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
 	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
 	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/database"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
 )
 
 func main() {
+	opts, err := parseOptions(os.Args[1:])
+	if err != nil {
+		logging.Fatal("Invalid options", slog.String("error", err.Error()))
+	}
+
 	// Load configuration
-	cfg, err := config.LoadConfig("configs/config.yaml")
+	var cfg *config.Config
+	if opts.configPath != "" {
+		cfg, err = config.LoadConfigFile(opts.configPath)
+	} else {
+		cfg, err = config.LoadConfig("configs/config.yaml")
+	}
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		logging.Fatal("Failed to load config", slog.String("error", err.Error()))
+	}
+	logging.Setup("worker-runtime", cfg.Logging.Level, cfg.Logging.Format)
+	if err := cfg.Validate(); err != nil {
+		logging.Fatal("Invalid config", slog.String("error", err.Error()))
+	}
+
+	// Sensitive payload data is masked in logs and in the prompts sent to the AI provider
+	redactor, err := logging.SetupRedaction(cfg.Redaction)
+	if err != nil {
+		logging.Fatal("Invalid redaction config", slog.String("error", err.Error()))
 	}
 
 	// Initialize infrastructure - database connections
-	postgres, err := database.NewPostgresConnection(cfg.Postgres.DSN)
+	// Each statement is canceled on the server once it runs longer than the query timeout
+	queryLimits := database.QueryLimits{
+		Timeout:       time.Duration(cfg.Postgres.QueryTimeoutMs) * time.Millisecond,
+		SlowThreshold: time.Duration(cfg.Postgres.SlowQueryMs) * time.Millisecond,
+	}
+	postgres, err := database.NewPostgresConnection(cfg.Postgres.DSN, queryLimits)
 	if err != nil {
-		log.Fatalf("postgres connection error: %v", err)
+		logging.Fatal("Postgres connection error", slog.String("error", err.Error()))
 	}
 	defer postgres.Close()
 
 	if err := postgres.Ping(context.Background()); err != nil {
-		log.Fatalf("postgres ping error: %v", err)
+		logging.Fatal("Postgres ping error", slog.String("error", err.Error()))
 	}
-	log.Println("✅ Connected to Postgres")
+	slog.Info("Connected to Postgres")
 
-	redis := database.NewRedisConnection(cfg.Redis.Addr, cfg.Redis.URL, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.TLSSkipVerify)
+	redis, err := database.NewRedisConnection(cfg.Redis)
+	if err != nil {
+		logging.Fatal("Redis config error", slog.String("error", err.Error()))
+	}
 	defer redis.Close()
 
 	if err := redis.Ping(context.Background()); err != nil {
-		log.Fatalf("redis ping error: %v", err)
+		logging.Fatal("Redis ping error", slog.String("error", err.Error()))
+	}
+	slog.Info("Connected to Redis")
+
+	// Every Redis key is namespaced so deployments can share one instance
+	redisPrefix := cfg.Redis.ResolvedKeyPrefix()
+	if redisPrefix != "" {
+		slog.Info("Using Redis key prefix", slog.String("prefix", redisPrefix))
 	}
-	log.Println("✅ Connected to Redis")
 
 	// Initialize secondary adapters
-	jobRepo := persistence.NewPostgresJobRepository(postgres.Pool)
-	insightRepo := persistence.NewPostgresInsightRepository(postgres.Pool)
-	queueService := persistence.NewRedisQueueService(redis.Client)
+	// Large payloads are compressed in Redis and Postgres
+	payloadCompressor, err := persistence.NewPayloadCompressor(cfg.PayloadCompression.Algorithm, cfg.PayloadCompression.ThresholdBytes)
+	if err != nil {
+		logging.Fatal("Invalid payload compression config", slog.String("error", err.Error()))
+	}
+	jobRepo := persistence.NewPostgresJobRepository(postgres.Pool).WithPayloadCompression(payloadCompressor)
+	// Insights are stored next to the jobs in Postgres, or in MongoDB when the insight store is mongodb
+	var insightRepo domainInsights.InsightRepository = persistence.NewPostgresInsightRepository(postgres.Pool)
+	if cfg.InsightStore.Mongo() {
+		mongo, err := database.NewMongoConnection(cfg.InsightStore.MongoDB.URI, cfg.InsightStore.MongoDB.ResolvedDatabase())
+		if err != nil {
+			logging.Fatal("MongoDB connection error", slog.String("error", err.Error()))
+		}
+		defer mongo.Close()
+		if err := mongo.Ping(context.Background()); err != nil {
+			logging.Fatal("MongoDB ping error", slog.String("error", err.Error()))
+		}
+		mongoInsights := persistence.NewMongoInsightRepository(mongo.Database, jobRepo)
+		if err := mongoInsights.EnsureIndexes(context.Background()); err != nil {
+			logging.Fatal("MongoDB index error", slog.String("error", err.Error()))
+		}
+		insightRepo = mongoInsights
+		slog.Info("Using the MongoDB insight store", slog.String("database", cfg.InsightStore.MongoDB.ResolvedDatabase()))
+	}
+	queueCodec, err := persistence.NewJobCodec(cfg.Redis.Codec, payloadCompressor)
+	if err != nil {
+		logging.Fatal("Invalid Redis queue codec", slog.String("error", err.Error()))
+	}
+	// Ready jobs wait in Redis lists, or in the jobs table when the queue backend is postgres.
+	// The Postgres backend has no dead letter lists; failed jobs stay in the jobs table.
+	if err := cfg.QueueBackend.Validate(); err != nil {
+		logging.Fatal("Invalid queue backend config", slog.String("error", err.Error()))
+	}
+	var queueService domainQueue.QueueService
+	var deadLetters domainQueue.DeadLetterQueue
+	var deliveries domainQueue.DeliveryRecoverer
+	if cfg.QueueBackend.Postgres() {
+		queueService = persistence.NewPostgresQueueService(postgres.Pool).
+			WithPollInterval(time.Duration(cfg.QueueBackend.PollIntervalMs) * time.Millisecond)
+		slog.Info("Using the Postgres queue backend")
+	} else {
+		redisQueue := persistence.NewRedisQueueService(redis.Client).WithKeyPrefix(redisPrefix).WithCodec(queueCodec).
+			WithDeadLetterLimit(cfg.Redis.DeadLetterLimit).
+			WithVisibilityTimeout(time.Duration(cfg.Redis.VisibilityTimeoutSeconds) * time.Second)
+		queueService, deadLetters, deliveries = redisQueue, redisQueue, redisQueue
+	}
 	jobExecutor := executor.NewDefaultJobExecutor(cfg)
+	executors := []worker.JobExecutor{jobExecutor}
+	if cfg.Command.Enabled {
+		slog.Info("Command executor enabled", slog.Any("allowedCommands", cfg.Command.AllowedCommands))
+		executors = append(executors, executor.NewCommandJobExecutor(cfg.Command))
+	}
 
 	// Initialize insights service (use HTTP client if URL configured, otherwise local service)
 	var aiSvc domainInsights.AIService
+	var providerChain *ai.ProviderChain
 	if cfg.AI.InsightsURL != "" {
 		// Use remote insights service via HTTP
-		log.Printf("Using remote insights service: %s", cfg.AI.InsightsURL)
-		aiSvc = insights.NewHTTPClient(cfg.AI.InsightsURL)
+		slog.Info("Using remote insights service", slog.String("url", cfg.AI.InsightsURL))
+		clientCfg := cfg.AI.InsightsClient
+		httpClient := insights.NewHTTPClient(cfg.AI.InsightsURL).
+			WithRetry(clientCfg.MaxAttempts, clientCfg.BaseBackoffMs, time.Duration(clientCfg.MaxBackoffMs)*time.Millisecond).
+			WithTimeout(time.Duration(clientCfg.TimeoutSeconds) * time.Second).
+			WithServiceSecret(cfg.AI.InsightsAuth.ServiceSecret)
+		insightsTLS, err := cfg.AI.InsightsAuth.TLS.ClientTLS()
+		if err != nil {
+			logging.Fatal("Invalid insights TLS config", slog.String("error", err.Error()))
+		}
+		httpClient = httpClient.WithTLS(insightsTLS)
+		if clientCfg.CircuitBreaker.Enabled {
+			insightsBreaker, err := worker.NewFailureBreaker(breakerConfig(clientCfg.CircuitBreaker))
+			if err != nil {
+				logging.Fatal("Invalid insights client circuit breaker config", slog.String("error", err.Error()))
+			}
+			httpClient = httpClient.WithBreaker(insightsBreaker)
+		}
+		aiSvc = httpClient
 	} else {
 		// Use local insights service with Ollama
-		log.Println("Using local insights service with Ollama")
-		aiSvc = ai.NewOllamaAIService(cfg.AI.OllamaURL)
+		slog.Info("Using local insights service with Ollama")
+		chain, err := ai.NewProviderChain(cfg.AI)
+		if err != nil {
+			logging.Fatal("Invalid AI provider config", slog.String("error", err.Error()))
+		}
+		providerChain = chain
+		aiSvc = chain
 	}
 
+	// Side effects of job processing subscribe to domain events
+	eventBus := events.NewInProcessBus()
+	metricsService := persistence.NewRedisMetricsService(redis.Client).WithKeyPrefix(redisPrefix)
+	eventBus.Subscribe(events.MetricsSubscriber(metricsService))
+	eventBus.Subscribe(events.LogSubscriber(),
+		domainEvents.NameJobMovedToDLQ,
+		domainEvents.NameJobParked,
+		domainEvents.NameInsightGenerated,
+		domainEvents.NameCircuitOpened,
+		domainEvents.NameCircuitClosed,
+		domainEvents.NameGroupCompleted,
+		domainEvents.NameBrokerDegraded,
+		domainEvents.NameBrokerRecovered,
+	)
+	// Relay events to queue-core's live dashboard feed
+	eventBus.Subscribe(events.RedisRelaySubscriber(redis.Client, redisPrefix+"events"))
+
 	insightsAppService := appInsights.NewService(insightRepo, jobRepo, aiSvc)
+	insightsAppService.SetAnalysisTimeout(time.Duration(cfg.AI.AnalysisTimeoutSeconds) * time.Second)
+	insightsAppService.SetEventPublisher(eventBus)
+	if redactor != nil {
+		insightsAppService.SetRedactor(redactor)
+	}
+
+	// During a failure storm only one job per error signature is analyzed by the AI
+	if cfg.AI.Storm.Enabled {
+		detector, err := domainInsights.NewStormDetector(stormConfig(cfg.AI.Storm))
+		if err != nil {
+			logging.Fatal("Invalid failure storm config", slog.String("error", err.Error()))
+		}
+		insightsAppService.SetStormDetector(detector)
+		slog.Info("Failure storm sampling enabled")
+	}
+	// A failure whose error fingerprint was analyzed recently is linked to that insight
+	if cfg.AI.InsightReuse.Enabled {
+		insightsAppService.SetInsightReuse(time.Duration(cfg.AI.InsightReuse.WindowHours) * time.Hour)
+		slog.Info("Insight reuse by error fingerprint enabled")
+	}
 
-	// Create worker configuration
-	workerConfig, err := worker.NewWorkerConfig(
-		"default",
-		cfg.Worker.MaxAttempts,
-		cfg.Worker.BaseBackoffMs,
+	// Failed jobs are analyzed from a Redis-backed queue with bounded concurrency
+	analysisQueue := persistence.NewRedisAnalysisQueue(redis.Client, cfg.AI.AnalysisQueueMax).WithKeyPrefix(redisPrefix)
+	analysisConsumer := appInsights.NewAnalysisConsumer(analysisQueue, insightsAppService, cfg.AI.AnalysisConcurrency)
+
+	// Completed jobs slower than the SLO of their type are analyzed from their own queue, with
+	// the timing history of the type; the remote insights service only analyzes failures
+	var performancePolicy *domainInsights.PerformancePolicy
+	var slowRuns *persistence.RedisSlowRunQueue
+	if cfg.AI.PerformanceInsights.Enabled && cfg.AI.InsightsURL != "" {
+		slog.Warn("Performance insights need the local AI providers, disabled while insights_url is set")
+	} else if cfg.AI.PerformanceInsights.Enabled {
+		performancePolicy, err = performanceInsightsPolicy(cfg.AI.PerformanceInsights)
+		if err != nil {
+			logging.Fatal("Invalid performance insights config", slog.String("error", err.Error()))
+		}
+		slowRuns = persistence.NewRedisSlowRunQueue(redis.Client, cfg.AI.AnalysisQueueMax).WithKeyPrefix(redisPrefix)
+		insightsAppService.SetDurationHistory(metricsService)
+		slog.Info("Performance insights enabled")
+	}
+
+	// Jobs created with a callback_url get their final state posted back
+	callbackNotifier := webhook.NewCallbackNotifier(
+		cfg.Webhook.SigningSecret,
+		cfg.Webhook.MaxAttempts,
+		time.Duration(cfg.Webhook.TimeoutSeconds)*time.Second,
+		insightRepo,
 	)
+
+	// Optionally wake idle workers as soon as the queue service makes a job ready instead of
+	// waiting out the idle sleep: over Postgres LISTEN/NOTIFY with the Postgres queue backend,
+	// Redis pub/sub otherwise
+	var jobListener interface {
+		domainQueue.ReadySignal
+		Run(ctx context.Context)
+	}
+	if cfg.Worker.ListenNotify && cfg.QueueBackend.Postgres() {
+		slog.Info("Postgres LISTEN/NOTIFY wake-up enabled")
+		jobListener = persistence.NewPostgresJobListener(postgres.Pool)
+	} else if cfg.Worker.ListenNotify {
+		slog.Info("Redis pub/sub wake-up enabled")
+		jobListener = persistence.NewRedisJobListener(redis.Client, redisPrefix)
+	}
+
+	// Paused queues aren't consumed and defined retry settings override the worker's
+	queueDefinitions := persistence.NewPostgresQueueDefinitionRepository(postgres.Pool)
+	jobGroups := persistence.NewPostgresJobGroupRepository(postgres.Pool)
+	jobAttempts := persistence.NewPostgresAttemptRepository(postgres.Pool)
+
+	// Job types failing above the threshold are paused; the breaker is shared by all queues
+	var breaker *worker.FailureBreaker
+	breakerStore := persistence.NewRedisBreakerStore(redis.Client).WithKeyPrefix(redisPrefix)
+	if cfg.Worker.CircuitBreaker.Enabled {
+		breaker, err = worker.NewFailureBreaker(breakerConfig(cfg.Worker.CircuitBreaker))
+		if err != nil {
+			logging.Fatal("Invalid circuit breaker config", slog.String("error", err.Error()))
+		}
+		slog.Info("Per job type circuit breaker enabled")
+	}
+
+	// Failing jobs of a queue that used up its retries are parked; the budget is kept per queue
+	var retryBudget *worker.RetryBudget
+	if cfg.Worker.RetryBudget.Enabled {
+		retryBudget, err = worker.NewRetryBudget(retryBudgetConfig(cfg.Worker.RetryBudget))
+		if err != nil {
+			logging.Fatal("Invalid retry budget config", slog.String("error", err.Error()))
+		}
+		slog.Info("Per queue retry budget enabled")
+	}
+
+	// Status updates and acknowledgements of jobs finished together share their round trips;
+	// the Postgres queue backend has nothing to acknowledge
+	var batcher *appWorker.CompletionBatcher
+	if cfg.Worker.Batching.Enabled {
+		acker, _ := queueService.(domainQueue.BatchAcknowledger)
+		batcher = appWorker.NewCompletionBatcher(jobRepo, acker,
+			time.Duration(cfg.Worker.Batching.WindowMs)*time.Millisecond,
+			cfg.Worker.Batching.MaxSize,
+		)
+		slog.Info("Job completion batching enabled")
+	}
+
+	// While the queue broker is unreachable the workers back off together and the process is
+	// reported degraded once enough dequeues failed in a row
+	brokerHealth, err := worker.NewBrokerHealth(brokerHealthConfig(cfg.Worker.BrokerHealth))
 	if err != nil {
-		log.Fatalf("failed to create worker config: %v", err)
+		logging.Fatal("Invalid broker health config", slog.String("error", err.Error()))
 	}
 
-	// Initialize worker application service
-	workerService := appWorker.NewService(
-		jobRepo,
-		queueService,
-		jobExecutor,
-		insightsAppService,
-		workerConfig,
-	)
+	// Tampered or (when required) unsigned payloads fail permanently instead of running
+	var signer *domainQueue.PayloadSigner
+	if cfg.PayloadSigning.Enabled {
+		signer, err = domainQueue.NewPayloadSigner(cfg.PayloadSigning.Secret, cfg.PayloadSigning.APIKeySecrets, cfg.PayloadSigning.Required)
+		if err != nil {
+			logging.Fatal("Invalid payload signing config", slog.String("error", err.Error()))
+		}
+		slog.Info("Job payload signature verification enabled")
+	}
+
+	// Failures sent for AI analysis, unless a queue definition sets its own policy
+	insightPolicy := domainQueue.InsightPolicy(cfg.Worker.InsightPolicy)
+	if err := insightPolicy.Validate(); err != nil {
+		logging.Fatal("Invalid worker insight policy", slog.String("error", err.Error()))
+	}
+
+	// Job types may be retried differently than the worker and queue settings say
+	policies, err := retryPolicies(cfg.Worker.RetryPolicies)
+	if err != nil {
+		logging.Fatal("Invalid worker retry policies", slog.String("error", err.Error()))
+	}
+
+	// Duplicates of idempotent job types return the result cached in Redis instead of running
+	var resultCache *persistence.RedisResultCache
+	idempotency, err := idempotencyPolicies(cfg.Worker.IdempotentTypes)
+	if err != nil {
+		logging.Fatal("Invalid idempotent job types", slog.String("error", err.Error()))
+	}
+	if len(idempotency) > 0 {
+		resultCache = persistence.NewRedisResultCache(redis.Client).WithKeyPrefix(redisPrefix)
+		slog.Info("Result cache enabled for idempotent job types", slog.Int("jobTypes", len(idempotency)))
+	}
+
+	// Executors stream the output of running jobs to Postgres, where queue-core serves it
+	var outputStore *persistence.PostgresOutputStore
+	if cfg.JobOutput.Enabled {
+		outputStore = persistence.NewPostgresOutputStore(postgres.Pool)
+		slog.Info("Job output streaming enabled")
+	}
+
+	// Failure analyses see the job's past runs, the end of its output and its queue's settings
+	insightsAppService.SetAttemptHistory(jobAttempts)
+	insightsAppService.SetQueueDefinitions(queueDefinitions)
+	if outputStore != nil {
+		insightsAppService.SetOutputStore(outputStore)
+	}
+
+	// Analyses of failed jobs count against the quota of the API key that created the job,
+	// and finished jobs free their creator's pending job slot
+	var quotaService *appQuota.Service
+	if cfg.Quotas.Enabled {
+		limits := domainQuota.Quota{
+			JobsPerDay:     cfg.Quotas.JobsPerDay,
+			MaxPendingJobs: cfg.Quotas.MaxPendingJobs,
+			AnalysesPerDay: cfg.Quotas.AnalysesPerDay,
+		}
+		if err := limits.Validate(); err != nil {
+			logging.Fatal("Invalid quota config", slog.String("error", err.Error()))
+		}
+		quotaService = appQuota.NewService(
+			persistence.NewRedisQuotaStore(redis.Client).WithKeyPrefix(redisPrefix),
+			persistence.NewPostgresQuotaOverrideRepository(postgres.Pool),
+			limits,
+		)
+		slog.Info("Per API key quotas enabled")
+	}
+
+	// One worker application service per queue, all reporting to the admin server's tracker
+	compositeExecutor := executor.NewCompositeJobExecutor(executors...)
+	activity := worker.NewActivity()
+	// Executors of job types whose payload schema changed register their migrations here
+	schemas := worker.NewSchemaMigrations(cfg.Worker.SchemaVersion)
+	workerServices := make([]*appWorker.Service, 0, len(opts.queues))
+	for _, queueName := range opts.queues {
+		workerConfig, err := worker.NewWorkerConfig(
+			queueName,
+			cfg.Worker.MaxAttempts,
+			cfg.Worker.BaseBackoffMs,
+		)
+		if err != nil {
+			logging.Fatal("Failed to create worker config", slog.String("error", err.Error()))
+		}
+		workerConfig.WorkerID = opts.workerID
+		workerConfig.DequeueTimeout = opts.dequeueTimeout
+		workerConfig.IdleSleep = opts.idleSleep
+		workerConfig.Capabilities = opts.capabilities
+		workerConfig.InsightPolicy = insightPolicy
+		workerConfig.RetryPolicies = policies
+
+		workerService := appWorker.NewService(
+			jobRepo,
+			queueService,
+			compositeExecutor,
+			analysisQueue,
+			workerConfig,
+		)
+		workerService.SetEventPublisher(eventBus)
+		workerService.SetResultNotifier(callbackNotifier)
+		workerService.SetFixOutcomeRecorder(insightRepo)
+		if deadLetters != nil {
+			workerService.SetDeadLetterQueue(deadLetters)
+		}
+		workerService.SetActivity(activity)
+		workerService.SetBrokerHealth(brokerHealth)
+		workerService.SetGroupRepository(jobGroups)
+		workerService.SetAttemptRepository(jobAttempts)
+		workerService.SetSchemaMigrations(schemas, jobRepo)
+		if performancePolicy != nil {
+			workerService.SetPerformanceAnalysis(performancePolicy, slowRuns)
+		}
+		if breaker != nil {
+			workerService.SetBreaker(breaker, breakerStore)
+		}
+		if retryBudget != nil {
+			workerService.SetRetryBudget(retryBudget)
+		}
+		if batcher != nil {
+			workerService.SetCompletionBatcher(batcher)
+		}
+		if jobListener != nil {
+			workerService.SetReadySignal(jobListener)
+		}
+		if signer != nil {
+			workerService.SetPayloadSigner(signer)
+		}
+		workerService.SetQueueDefinitions(queueDefinitions, cfg.Queues.Enforce)
+		if quotaService != nil {
+			workerService.SetQuotaEnforcer(quotaService)
+		}
+		if resultCache != nil {
+			workerService.SetResultCache(resultCache, idempotency)
+		}
+		if outputStore != nil {
+			workerService.SetOutputStore(outputStore,
+				cfg.JobOutput.ChunkBytes,
+				time.Duration(cfg.JobOutput.FlushIntervalMs)*time.Millisecond,
+				cfg.JobOutput.MaxBytes,
+			)
+		}
+		workerServices = append(workerServices, workerService)
+	}
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -94,17 +461,271 @@ func main() {
 
 	go func() {
 		<-sigChan
-		log.Println("Received shutdown signal")
+		slog.Info("Received shutdown signal")
 		cancel()
 	}()
 
-	log.Println("🚀 Worker Runtime service starting")
-	log.Println("📦 Hexagonal Architecture initialized:")
-	log.Println("   ├─ Domain: Business rules for job processing")
-	log.Println("   ├─ Application: Worker orchestration")
-	log.Println("   ├─ Adapters: Job executor, Queue service")
-	log.Println("   └─ Infrastructure: Database, Config")
+	// Apply safe config changes on SIGHUP without restarting the worker
+	reloader := config.NewReloader("configs/config.yaml", cfg)
+	if opts.configPath != "" {
+		reloader = config.NewFileReloader(opts.configPath, cfg)
+	}
+	reloader.OnReload(func(newCfg *config.Config) {
+		jobExecutor.UpdateSimulation(newCfg.Simulation)
+		if providerChain != nil {
+			if err := providerChain.Reload(newCfg.AI); err != nil {
+				slog.Error("Invalid AI provider config, keeping current providers", slog.String("error", err.Error()))
+			}
+		}
+
+		for i, workerService := range workerServices {
+			// Worker ID, capabilities, dequeue timeout and idle sleep are kept by UpdateConfig
+			updatedWorkerConfig, err := worker.NewWorkerConfig(
+				opts.queues[i],
+				newCfg.Worker.MaxAttempts,
+				newCfg.Worker.BaseBackoffMs,
+			)
+			if err == nil {
+				updatedWorkerConfig.InsightPolicy = domainQueue.InsightPolicy(newCfg.Worker.InsightPolicy)
+				err = updatedWorkerConfig.InsightPolicy.Validate()
+			}
+			if err == nil {
+				updatedWorkerConfig.RetryPolicies, err = retryPolicies(newCfg.Worker.RetryPolicies)
+			}
+			if err != nil {
+				slog.Warn("Ignoring invalid worker config on reload", slog.String("error", err.Error()))
+				return
+			}
+			workerService.UpdateConfig(updatedWorkerConfig)
+		}
+	})
+	reloader.WatchSignals(ctx)
+
+	slog.Info("Worker Runtime service starting",
+		slog.String("workerId", opts.workerID),
+		slog.Any("queues", opts.queues),
+		slog.Int("concurrency", opts.concurrency),
+		slog.Bool("fair", opts.fair),
+		slog.Any("capabilities", opts.capabilities),
+		slog.Duration("dequeueTimeout", opts.dequeueTimeout),
+		slog.Duration("idleSleep", opts.idleSleep),
+	)
+
+	// Heartbeats let the dashboard list live workers
+	info := worker.Heartbeat{
+		WorkerID:     opts.workerID,
+		Queues:       opts.queues,
+		Concurrency:  opts.concurrency,
+		Capabilities: opts.capabilities,
+		StartedAt:    time.Now().UTC(),
+	}
+	go appWorker.RunHeartbeat(ctx,
+		persistence.NewRedisHeartbeatStore(redis.Client).WithKeyPrefix(redisPrefix),
+		info,
+		worker.HeartbeatInterval,
+	)
+
+	// The admin server stays up while the workers drain, so probes see the process until it exits
+	var adminServer *http.Server
+	adminPort := cmp.Or(opts.adminPort, cfg.Worker.AdminPort)
+	if adminPort > 0 {
+		mux := http.NewServeMux()
+		adminHandlers := httpHandlers.NewWorkerAdminHandlers(activity, info)
+		adminHandlers.SetBrokerHealth(brokerHealth)
+		httpHandlers.RegisterWorkerAdminRoutes(mux, adminHandlers)
+		adminServer = &http.Server{Addr: fmt.Sprintf(":%d", adminPort), Handler: mux}
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logging.Fatal("Admin server error", slog.String("error", err.Error()))
+			}
+		}()
+		slog.Info("Worker admin server running", slog.String("addr", adminServer.Addr))
+	}
+
+	if jobListener != nil {
+		go jobListener.Run(ctx)
+	}
+	// Jobs dequeued by workers that died before acknowledging them go back to their queues
+	if deliveries != nil {
+		go appWorker.RunDeliveryRecovery(ctx, deliveries, appWorker.DefaultDeliveryRecoveryInterval)
+	}
+
+	var consumers sync.WaitGroup
+	consumers.Add(1)
+	go func() {
+		defer consumers.Done()
+		analysisConsumer.Run(ctx)
+	}()
+	if slowRuns != nil {
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			appInsights.NewPerformanceConsumer(slowRuns, insightsAppService).Run(ctx)
+		}()
+	}
+
+	// Jobs held by workers of an older release are queued again once a worker knows them
+	for _, workerService := range workerServices {
+		if _, err := workerService.ReleaseHeldJobs(ctx); err != nil {
+			slog.Error("Failed to release held jobs", slog.String("error", err.Error()))
+		}
+	}
+
+	// Start workers and block until they have shut down
+	var wg sync.WaitGroup
+	if opts.fair {
+		// All queues share the slots, so a flooded queue can't starve the others
+		scheduler, err := worker.NewFairScheduler(opts.weights)
+		if err != nil {
+			logging.Fatal("Invalid queue weights", slog.String("error", err.Error()))
+		}
+		dispatcher, err := appWorker.NewFairDispatcher(scheduler, workerServices, opts.concurrency*len(opts.queues))
+		if err != nil {
+			logging.Fatal("Failed to create fair dispatcher", slog.String("error", err.Error()))
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dispatcher.Run(ctx)
+		}()
+	} else {
+		for _, workerService := range workerServices {
+			for i := 0; i < opts.concurrency; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					workerService.Start(ctx)
+				}()
+			}
+		}
+	}
+	wg.Wait()
+	consumers.Wait()
+
+	slog.Info("Waiting for pending job callbacks")
+	callbackNotifier.Wait()
+
+	if adminServer != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Failed to shut down admin server", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// retryPolicies converts the YAML retry policies, keyed by job type
+func retryPolicies(cfg map[string]config.RetryPolicyConfig) (worker.RetryPolicies, error) {
+	policies := make(worker.RetryPolicies, len(cfg))
+	for jobType, policyCfg := range cfg {
+		policy := worker.RetryPolicy{
+			MaxAttempts: policyCfg.MaxAttempts,
+			Backoff:     worker.BackoffStrategy(policyCfg.Backoff),
+			BaseBackoff: time.Duration(policyCfg.BaseBackoffMs) * time.Millisecond,
+			MaxBackoff:  time.Duration(policyCfg.MaxBackoffMs) * time.Millisecond,
+			DLQ:         worker.DLQBehavior(policyCfg.DLQ),
+		}
+		var err error
+		if policy.RetryOn, err = worker.CompileErrorPatterns(policyCfg.RetryOn); err != nil {
+			return nil, fmt.Errorf("job type %s: %w", jobType, err)
+		}
+		if policy.NoRetryOn, err = worker.CompileErrorPatterns(policyCfg.NoRetryOn); err != nil {
+			return nil, fmt.Errorf("job type %s: %w", jobType, err)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("job type %s: %w", jobType, err)
+		}
+		policies[jobType] = policy
+	}
+	return policies, nil
+}
+
+// idempotencyPolicies converts the YAML idempotent job types, keyed by job type
+func idempotencyPolicies(cfg map[string]config.IdempotentTypeConfig) (worker.IdempotencyPolicies, error) {
+	policies := make(worker.IdempotencyPolicies, len(cfg))
+	for jobType, typeCfg := range cfg {
+		policy := worker.IdempotencyPolicy{
+			KeyFields: typeCfg.KeyFields,
+			TTL:       time.Duration(typeCfg.TTLSeconds) * time.Second,
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("job type %s: %w", jobType, err)
+		}
+		policies[jobType] = policy
+	}
+	return policies, nil
+}
 
-	// Start worker
-	workerService.Start(ctx)
+// performanceInsightsPolicy converts the YAML settings
+func performanceInsightsPolicy(cfg config.PerformanceInsightsConfig) (*domainInsights.PerformancePolicy, error) {
+	slos := make(map[string]time.Duration, len(cfg.SLOSeconds))
+	for jobType, seconds := range cfg.SLOSeconds {
+		slos[jobType] = time.Duration(seconds * float64(time.Second))
+	}
+	return domainInsights.NewPerformancePolicy(slos,
+		time.Duration(cfg.DefaultSLOSeconds*float64(time.Second)),
+		time.Duration(cfg.CooldownSeconds)*time.Second,
+	)
+}
+
+// breakerConfig converts the YAML settings, keeping the defaults for unset values
+func breakerConfig(cfg config.CircuitBreakerConfig) worker.BreakerConfig {
+	breakerCfg := worker.DefaultBreakerConfig()
+	if cfg.FailureThreshold > 0 {
+		breakerCfg.FailureThreshold = cfg.FailureThreshold
+	}
+	if cfg.WindowSeconds > 0 {
+		breakerCfg.Window = time.Duration(cfg.WindowSeconds) * time.Second
+	}
+	if cfg.MinSamples > 0 {
+		breakerCfg.MinSamples = cfg.MinSamples
+	}
+	if cfg.CooldownSeconds > 0 {
+		breakerCfg.Cooldown = time.Duration(cfg.CooldownSeconds) * time.Second
+	}
+	return breakerCfg
+}
+
+// retryBudgetConfig converts the YAML settings, keeping the defaults for unset values
+func retryBudgetConfig(cfg config.RetryBudgetConfig) worker.RetryBudgetConfig {
+	budgetCfg := worker.DefaultRetryBudgetConfig()
+	if cfg.Ratio > 0 {
+		budgetCfg.Ratio = cfg.Ratio
+	}
+	if cfg.WindowSeconds > 0 {
+		budgetCfg.Window = time.Duration(cfg.WindowSeconds) * time.Second
+	}
+	if cfg.MinRetries > 0 {
+		budgetCfg.MinRetries = cfg.MinRetries
+	}
+	return budgetCfg
+}
+
+// brokerHealthConfig converts the YAML settings, keeping the defaults for unset values
+func brokerHealthConfig(cfg config.BrokerHealthConfig) worker.BrokerHealthConfig {
+	healthCfg := worker.DefaultBrokerHealthConfig()
+	if cfg.MaxDelayMs > 0 {
+		healthCfg.MaxDelay = time.Duration(cfg.MaxDelayMs) * time.Millisecond
+	}
+	// The default base delay is kept within a shorter max delay
+	healthCfg.BaseDelay = min(healthCfg.BaseDelay, healthCfg.MaxDelay)
+	if cfg.BaseDelayMs > 0 {
+		healthCfg.BaseDelay = time.Duration(cfg.BaseDelayMs) * time.Millisecond
+	}
+	if cfg.AlertAfter > 0 {
+		healthCfg.AlertAfter = cfg.AlertAfter
+	}
+	return healthCfg
+}
+
+// stormConfig converts the YAML settings, keeping the defaults for unset values
+func stormConfig(cfg config.StormConfig) domainInsights.StormConfig {
+	stormCfg := domainInsights.DefaultStormConfig()
+	if cfg.FailureThreshold > 0 {
+		stormCfg.FailureThreshold = cfg.FailureThreshold
+	}
+	if cfg.WindowSeconds > 0 {
+		stormCfg.Window = time.Duration(cfg.WindowSeconds) * time.Second
+	}
+	return stormCfg
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// options controls how a worker-runtime instance runs, so several differently
+// configured workers can be launched from the same binary.
+// Flags take precedence over environment variables.
+type options struct {
+	configPath     string               // -config / WORKER_CONFIG: explicit config file (default: CONFIG_ENV-selected file)
+	workerID       string               // -worker-id / WORKER_ID: identifies the worker in logs (default: hostname-pid)
+	queues         []string             // -queues / WORKER_QUEUES: comma separated queues to consume, each optionally with a :weight (default: "default")
+	weights        []worker.QueueWeight // Weight of each of queues, 1 unless given
+	fair           bool                 // -fair / WORKER_FAIR: share the slots of all queues, polled by weight (default: a dedicated pool per queue)
+	concurrency    int                  // -concurrency / WORKER_CONCURRENCY: jobs processed in parallel per queue (default: 1)
+	dequeueTimeout time.Duration        // -dequeue-timeout / WORKER_DEQUEUE_TIMEOUT: how long a blocking pop waits (default: 2s)
+	idleSleep      time.Duration        // -idle-sleep / WORKER_IDLE_SLEEP: pause after a pop finds no job (default: 0)
+	capabilities   []string             // -capabilities / WORKER_CAPABILITIES: comma separated capabilities, e.g. "gpu,region=eu" (default: none)
+	adminPort      int                  // -admin-port / WORKER_ADMIN_PORT: port of the admin server (default: worker.admin_port)
+}
+
+func parseOptions(args []string) (*options, error) {
+	fs := flag.NewFlagSet("worker-runtime", flag.ContinueOnError)
+
+	configPath := fs.String("config", os.Getenv("WORKER_CONFIG"), "path to the config file (default: configs/config.$CONFIG_ENV.yaml)")
+	workerID := fs.String("worker-id", os.Getenv("WORKER_ID"), "worker identifier used in logs (default: hostname-pid)")
+	queues := fs.String("queues", envOrDefault("WORKER_QUEUES", "default"), "comma separated list of queues to consume, weighted with -fair as name:weight")
+	fair := fs.Bool("fair", envBoolOrDefault("WORKER_FAIR", false), "share slots between queues, polled in proportion to their weights")
+	concurrency := fs.Int("concurrency", envIntOrDefault("WORKER_CONCURRENCY", 1), "number of jobs processed in parallel per queue")
+	dequeueTimeout := fs.Duration("dequeue-timeout", envDurationOrDefault("WORKER_DEQUEUE_TIMEOUT", worker.DefaultDequeueTimeout), "how long a blocking pop waits for a job")
+	idleSleep := fs.Duration("idle-sleep", envDurationOrDefault("WORKER_IDLE_SLEEP", 0), "pause after a pop finds no job")
+	capabilities := fs.String("capabilities", os.Getenv("WORKER_CAPABILITIES"), "comma separated capabilities jobs may require, e.g. gpu,region=eu")
+	adminPort := fs.Int("admin-port", envIntOrDefault("WORKER_ADMIN_PORT", 0), "port of the admin server with /health, /metrics, /jobs and /stats (default: worker.admin_port)")
+	// Deprecated: the worker long-polls now; a poll interval is kept as the idle sleep
+	pollInterval := fs.Duration("poll-interval", envDurationOrDefault("WORKER_POLL_INTERVAL", 0), "deprecated, use -idle-sleep")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	opts := &options{
+		configPath:     *configPath,
+		workerID:       *workerID,
+		fair:           *fair,
+		concurrency:    *concurrency,
+		dequeueTimeout: *dequeueTimeout,
+		idleSleep:      *idleSleep,
+		adminPort:      *adminPort,
+	}
+	if opts.idleSleep == 0 {
+		opts.idleSleep = *pollInterval
+	}
+
+	for _, spec := range strings.Split(*queues, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		weight, err := worker.ParseQueueWeight(spec)
+		if err != nil {
+			return nil, err
+		}
+		if weight.Weight != 1 && !opts.fair {
+			return nil, fmt.Errorf("queue weights need -fair, got %q", spec)
+		}
+		opts.queues = append(opts.queues, weight.Queue)
+		opts.weights = append(opts.weights, weight)
+	}
+	if len(opts.queues) == 0 {
+		return nil, fmt.Errorf("at least one queue is required")
+	}
+	if opts.concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", opts.concurrency)
+	}
+	caps, err := queue.NormalizeCapabilities(strings.Split(*capabilities, ","))
+	if err != nil {
+		return nil, err
+	}
+	opts.capabilities = caps
+	if opts.dequeueTimeout <= 0 {
+		return nil, fmt.Errorf("dequeue timeout must be positive, got %s", opts.dequeueTimeout)
+	}
+	if opts.idleSleep < 0 {
+		return nil, fmt.Errorf("idle sleep can't be negative, got %s", opts.idleSleep)
+	}
+	if opts.adminPort < 0 || opts.adminPort > 65535 {
+		return nil, fmt.Errorf("admin port must be between 0 and 65535, got %d", opts.adminPort)
+	}
+
+	if opts.workerID == "" {
+		hostname, _ := os.Hostname()
+		opts.workerID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	return opts, nil
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envIntOrDefault(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+func envBoolOrDefault(key string, fallback bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+func envDurationOrDefault(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// DefaultAccessLogSkipPaths are the paths left out of the access log when no others are given;
+// probes and scrapers hit them too often to be worth a record each
+var DefaultAccessLogSkipPaths = []string{"/health", "/metrics"}
+
+// AccessLogPolicy tells which requests the access log records
+type AccessLogPolicy struct {
+	// SampleRate is the fraction of requests logged, from 0 to 1. Server errors and slow
+	// requests are logged whatever the rate.
+	SampleRate float64
+	// SlowThreshold marks requests taking at least that long as slow; zero disables it
+	SlowThreshold time.Duration
+	// SkipPaths aren't logged unless they fail or are slow; default DefaultAccessLogSkipPaths
+	SkipPaths []string
+	// Logger receives the records; default slog.Default()
+	Logger *slog.Logger
+}
+
+// AccessLogMiddleware logs the method, path, status, duration and body sizes of requests.
+// Requests slower than the policy's threshold are logged as a warning with timing details
+// for tuning hot endpoints. The response writer keeps supporting hijacking, flushing and
+// http.ResponseController so WebSocket upgrades and deadline changes keep working.
+func AccessLogMiddleware(policy AccessLogPolicy, next http.Handler) http.Handler {
+	if policy.SkipPaths == nil {
+		policy.SkipPaths = DefaultAccessLogSkipPaths
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &accessLogWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		duration := time.Since(start)
+
+		logger := policy.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		status := recorder.statusCode()
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", duration),
+			slog.Int64("bytesIn", max(r.ContentLength, 0)),
+			slog.Int64("bytesOut", recorder.bytes),
+			slog.String("remoteAddr", r.RemoteAddr),
+		}
+
+		// A hijacked connection lasts as long as the client stays, so it is never slow
+		if policy.SlowThreshold > 0 && duration >= policy.SlowThreshold && !recorder.hijacked {
+			firstByte := duration
+			if !recorder.wroteAt.IsZero() {
+				firstByte = recorder.wroteAt.Sub(start)
+			}
+			attrs = append(attrs,
+				slog.String("query", r.URL.RawQuery),
+				slog.String("userAgent", r.UserAgent()),
+				slog.String("contentType", recorder.Header().Get("Content-Type")),
+				slog.Duration("timeToFirstByte", firstByte),
+				slog.Duration("writing", duration-firstByte),
+				slog.Duration("threshold", policy.SlowThreshold),
+			)
+			logger.LogAttrs(r.Context(), slog.LevelWarn, "Slow HTTP request", attrs...)
+			return
+		}
+
+		if status >= http.StatusInternalServerError {
+			logger.LogAttrs(r.Context(), slog.LevelError, "HTTP request", attrs...)
+			return
+		}
+		if slices.Contains(policy.SkipPaths, r.URL.Path) || !sampled(policy.SampleRate) {
+			return
+		}
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "HTTP request", attrs...)
+	})
+}
+
+// sampled reports whether a request falls in the sampled fraction
+func sampled(rate float64) bool {
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
+
+// accessLogWriter records the status and size of a response
+type accessLogWriter struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	wroteAt  time.Time // When the headers were written
+	hijacked bool
+}
+
+func (w *accessLogWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		w.wroteAt = time.Now()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// statusCode returns the status sent, 200 when the handler wrote nothing
+func (w *accessLogWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush sends buffered data to the client when the underlying writer supports it
+func (w *accessLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over, e.g. for a WebSocket upgrade, which is logged as 101
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.hijacked = true
+		if w.status == 0 {
+			w.status = http.StatusSwitchingProtocols
+			w.wroteAt = time.Now()
+		}
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords decodes the JSON log records written to buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		policy        AccessLogPolicy
+		path          string
+		status        int
+		delay         time.Duration
+		expectMessage string
+		expectLevel   string
+	}{
+		{
+			name:          "Sampled request",
+			given:         "a sample rate of 1",
+			when:          "a request is served",
+			then:          "should log it at info with its status and size",
+			policy:        AccessLogPolicy{SampleRate: 1},
+			path:          "/api/jobs",
+			status:        http.StatusCreated,
+			expectMessage: "HTTP request",
+			expectLevel:   "INFO",
+		},
+		{
+			name:   "Request left out of the sample",
+			given:  "a sample rate of 0",
+			when:   "a request succeeds",
+			then:   "should not log it",
+			policy: AccessLogPolicy{SampleRate: 0},
+			path:   "/api/jobs",
+			status: http.StatusOK,
+		},
+		{
+			name:          "Server error",
+			given:         "a sample rate of 0",
+			when:          "a request fails with 500",
+			then:          "should log it at error anyway",
+			policy:        AccessLogPolicy{SampleRate: 0},
+			path:          "/api/jobs",
+			status:        http.StatusInternalServerError,
+			expectMessage: "HTTP request",
+			expectLevel:   "ERROR",
+		},
+		{
+			name:   "Skipped path",
+			given:  "a sample rate of 1 and the default skipped paths",
+			when:   "GET /health is served",
+			then:   "should not log it",
+			policy: AccessLogPolicy{SampleRate: 1},
+			path:   "/health",
+			status: http.StatusOK,
+		},
+		{
+			name:          "Slow request",
+			given:         "a slow request threshold of 5ms and a sample rate of 0",
+			when:          "a request takes longer",
+			then:          "should log a slow request trace at warn",
+			policy:        AccessLogPolicy{SampleRate: 0, SlowThreshold: 5 * time.Millisecond},
+			path:          "/api/dashboard",
+			status:        http.StatusOK,
+			delay:         10 * time.Millisecond,
+			expectMessage: "Slow HTTP request",
+			expectLevel:   "WARN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			var buf bytes.Buffer
+			tt.policy.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"ok":true}`))
+			})
+			handler := AccessLogMiddleware(tt.policy, next)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path+"?limit=5", strings.NewReader(`{"queue":"default"}`))
+			rec := httptest.NewRecorder()
+
+			// When
+			handler.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.status, rec.Code)
+			records := logRecords(t, &buf)
+			if tt.expectMessage == "" {
+				assert.Empty(t, records)
+				return
+			}
+			require.Len(t, records, 1)
+			record := records[0]
+			assert.Equal(t, tt.expectMessage, record["msg"])
+			assert.Equal(t, tt.expectLevel, record["level"])
+			assert.Equal(t, http.MethodPost, record["method"])
+			assert.Equal(t, tt.path, record["path"])
+			assert.Equal(t, float64(tt.status), record["status"])
+			assert.Equal(t, float64(len(`{"queue":"default"}`)), record["bytesIn"])
+			assert.Equal(t, float64(len(`{"ok":true}`)), record["bytesOut"])
+			if tt.delay > 0 {
+				assert.Equal(t, "limit=5", record["query"])
+				assert.Contains(t, record, "timeToFirstByte")
+			} else {
+				assert.NotContains(t, record, "timeToFirstByte")
+			}
+		})
+	}
+}
+
+func TestAccessLogMiddleware_KeepsWriterFeatures(t *testing.T) {
+	// Given
+	var buf bytes.Buffer
+	policy := AccessLogPolicy{SampleRate: 1, SlowThreshold: time.Nanosecond, Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+	var deadlineErr error
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadlineErr = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "not a hijacker", http.StatusInternalServerError)
+			return
+		}
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok"))
+	})
+	server := httptest.NewServer(AccessLogMiddleware(policy, next))
+	defer server.Close()
+
+	// When
+	resp, err := http.Get(server.URL + "/ws")
+
+	// Then
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(body))
+	assert.NoError(t, deadlineErr)
+	records := logRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "HTTP request", records[0]["msg"])
+	assert.Equal(t, float64(http.StatusSwitchingProtocols), records[0]["status"])
+}
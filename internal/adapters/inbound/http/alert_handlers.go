@@ -0,0 +1,293 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appAlert "github.com/erickfunier/ai-smart-queue/internal/application/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/google/uuid"
+)
+
+// AlertHandlers handles HTTP requests managing alert rules
+type AlertHandlers struct {
+	alertService *appAlert.Service
+}
+
+// NewAlertHandlers creates new alert rule HTTP handlers
+func NewAlertHandlers(alertService *appAlert.Service) *AlertHandlers {
+	return &AlertHandlers{alertService: alertService}
+}
+
+// CreateAlertRuleRequest is the body of POST /api/alerts/rules
+type CreateAlertRuleRequest struct {
+	Name            string `json:"name"`
+	Condition       string `json:"condition"`
+	WebhookURL      string `json:"webhook_url"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+}
+
+// UpdateAlertRuleRequest is the body of PATCH /api/alerts/rules/{id}; omitted fields are left unchanged
+type UpdateAlertRuleRequest struct {
+	Name            *string `json:"name"`
+	Condition       *string `json:"condition"`
+	WebhookURL      *string `json:"webhook_url"`
+	CooldownSeconds *int    `json:"cooldown_seconds"`
+	Enabled         *bool   `json:"enabled"`
+}
+
+type AlertRuleResponse struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Condition       string `json:"condition"`
+	WebhookURL      string `json:"webhook_url,omitempty"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+	Enabled         bool   `json:"enabled"`
+	LastFiredAt     string `json:"last_fired_at,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+func newAlertRuleResponse(rule *alert.Rule) AlertRuleResponse {
+	response := AlertRuleResponse{
+		ID:              rule.ID.String(),
+		Name:            rule.Name,
+		Condition:       rule.Condition.String(),
+		WebhookURL:      rule.WebhookURL,
+		CooldownSeconds: int(rule.Cooldown / time.Second),
+		Enabled:         rule.Enabled,
+		CreatedAt:       rule.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:       rule.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if rule.LastFiredAt != nil {
+		response.LastFiredAt = rule.LastFiredAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
+
+// ListAlertRules returns every alert rule, oldest first
+func (h *AlertHandlers) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.alertService.ListRules(r.Context())
+	if err != nil {
+		writeAlertRuleError(w, r, err)
+		return
+	}
+
+	response := make([]AlertRuleResponse, len(rules))
+	for i, rule := range rules {
+		response[i] = newAlertRuleResponse(rule)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"rules": response,
+		"total": len(response),
+	})
+}
+
+// CreateAlertRule stores a new, enabled alert rule
+func (h *AlertHandlers) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var req CreateAlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.alertService.CreateRule(r.Context(), appAlert.CreateRuleCommand{
+		Name:       req.Name,
+		Condition:  req.Condition,
+		WebhookURL: req.WebhookURL,
+		Cooldown:   time.Duration(req.CooldownSeconds) * time.Second,
+	})
+	if err != nil {
+		writeAlertRuleError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Alert rule created",
+		slog.String("ruleId", rule.ID.String()),
+		slog.String("condition", rule.Condition.String()),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newAlertRuleResponse(rule))
+}
+
+// GetAlertRule returns the alert rule whose ID is in the path
+func (h *AlertHandlers) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := alertRuleIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	rule, err := h.alertService.GetRule(r.Context(), id)
+	if err != nil {
+		writeAlertRuleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAlertRuleResponse(rule))
+}
+
+// UpdateAlertRule changes the fields sent of the alert rule whose ID is in the path
+func (h *AlertHandlers) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := alertRuleIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req UpdateAlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	cmd := appAlert.UpdateRuleCommand{
+		Name:       req.Name,
+		Condition:  req.Condition,
+		WebhookURL: req.WebhookURL,
+		Enabled:    req.Enabled,
+	}
+	if req.CooldownSeconds != nil {
+		cooldown := time.Duration(*req.CooldownSeconds) * time.Second
+		cmd.Cooldown = &cooldown
+	}
+	rule, err := h.alertService.UpdateRule(r.Context(), id, cmd)
+	if err != nil {
+		writeAlertRuleError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Alert rule updated",
+		slog.String("ruleId", rule.ID.String()),
+		slog.Bool("enabled", rule.Enabled),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAlertRuleResponse(rule))
+}
+
+// DeleteAlertRule removes the alert rule whose ID is in the path
+func (h *AlertHandlers) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := alertRuleIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := h.alertService.DeleteRule(r.Context(), id); err != nil {
+		writeAlertRuleError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Alert rule deleted",
+		slog.String("ruleId", id.String()),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": id.String(), "status": "deleted"})
+}
+
+// alertRuleIDFromPath parses the ID of /api/alerts/rules/{id}, answering 400 when it isn't one
+func alertRuleIDFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/alerts/rules/"), "/")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "invalid alert rule id", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeAlertRuleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, alert.ErrRuleNotFound):
+		http.Error(w, "alert rule not found", http.StatusNotFound)
+	case errors.Is(err, alert.ErrInvalidRule), errors.Is(err, alert.ErrInvalidCondition):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.ErrorContext(r.Context(), "Alert rule request failed",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DigestHandlers handles HTTP requests reading the DLQ digests sent
+type DigestHandlers struct {
+	monitor *appAlert.DLQMonitor
+}
+
+// NewDigestHandlers creates new DLQ digest HTTP handlers
+func NewDigestHandlers(monitor *appAlert.DLQMonitor) *DigestHandlers {
+	return &DigestHandlers{monitor: monitor}
+}
+
+type DigestResponse struct {
+	ID         string                 `json:"id"`
+	Growth     int64                  `json:"growth"`
+	DLQCount   int64                  `json:"dlq_count"`
+	Window     string                 `json:"window"`
+	Failed     int                    `json:"failed"`
+	Signatures []alert.SignatureCount `json:"signatures"`
+	Queues     []alert.QueueCount     `json:"queues"`
+	Insights   []alert.DigestInsight  `json:"insights"`
+	SentAt     string                 `json:"sent_at"`
+}
+
+func newDigestResponse(digest *alert.Digest) DigestResponse {
+	response := DigestResponse{
+		ID:         digest.ID.String(),
+		Growth:     digest.Growth,
+		DLQCount:   digest.DLQCount,
+		Window:     digest.Window.String(),
+		Failed:     digest.Failed,
+		Signatures: digest.Signatures,
+		Queues:     digest.Queues,
+		Insights:   digest.Insights,
+		SentAt:     digest.SentAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if response.Signatures == nil {
+		response.Signatures = []alert.SignatureCount{}
+	}
+	if response.Queues == nil {
+		response.Queues = []alert.QueueCount{}
+	}
+	if response.Insights == nil {
+		response.Insights = []alert.DigestInsight{}
+	}
+	return response
+}
+
+// ListDigests returns the DLQ digests sent, newest first
+func (h *DigestHandlers) ListDigests(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(l, 100)
+	}
+
+	digests, err := h.monitor.Digests(r.Context(), limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch DLQ digests",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]DigestResponse, len(digests))
+	for i, digest := range digests {
+		response[i] = newDigestResponse(digest)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"digests": response,
+		"total":   len(response),
+	})
+}
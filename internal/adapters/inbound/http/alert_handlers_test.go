@@ -0,0 +1,232 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	appAlert "github.com/erickfunier/ai-smart-queue/internal/application/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// InMemoryRuleRepo is an in-memory alert.RuleRepository
+type InMemoryRuleRepo struct {
+	rules []*alert.Rule
+}
+
+func (r *InMemoryRuleRepo) Create(ctx context.Context, rule *alert.Rule) error {
+	r.rules = append(r.rules, rule)
+	return nil
+}
+
+func (r *InMemoryRuleRepo) Get(ctx context.Context, id uuid.UUID) (*alert.Rule, error) {
+	for _, rule := range r.rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return nil, alert.ErrRuleNotFound
+}
+
+func (r *InMemoryRuleRepo) List(ctx context.Context) ([]*alert.Rule, error) {
+	return r.rules, nil
+}
+
+func (r *InMemoryRuleRepo) Update(ctx context.Context, rule *alert.Rule) error {
+	if _, err := r.Get(ctx, rule.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *InMemoryRuleRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	for i, rule := range r.rules {
+		if rule.ID == id {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			return nil
+		}
+	}
+	return alert.ErrRuleNotFound
+}
+
+func (r *InMemoryRuleRepo) MarkFired(ctx context.Context, id uuid.UUID, firedAt, notBefore time.Time) (bool, error) {
+	return true, nil
+}
+
+// NoSamples is an alert.SampleReader with nothing to read
+type NoSamples struct{}
+
+func (NoSamples) InsightSamples(ctx context.Context, since time.Time, limit int) ([]alert.Sample, error) {
+	return nil, nil
+}
+
+func (NoSamples) CountFailures(ctx context.Context, since time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestAlertRoutes(t *testing.T) {
+	existing, err := alert.NewRule("Auth failures", "category=auth AND count>5 in 10m", "", 0, time.Now().UTC())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder, *InMemoryRuleRepo)
+	}{
+		{
+			name:           "List",
+			given:          "one stored rule",
+			when:           "GET /api/alerts/rules",
+			then:           "should list it with its canonical condition",
+			method:         http.MethodGet,
+			path:           "/api/alerts/rules",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryRuleRepo) {
+				var resp struct {
+					Rules []AlertRuleResponse `json:"rules"`
+					Total int                 `json:"total"`
+				}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				require.Equal(t, 1, resp.Total)
+				assert.Equal(t, existing.ID.String(), resp.Rules[0].ID)
+				assert.Equal(t, "category=auth AND count>5 in 10m", resp.Rules[0].Condition)
+				assert.True(t, resp.Rules[0].Enabled)
+			},
+		},
+		{
+			name:           "Create",
+			given:          "a valid rule with a webhook",
+			when:           "POST /api/alerts/rules",
+			then:           "should store it enabled and return 201",
+			method:         http.MethodPost,
+			path:           "/api/alerts/rules",
+			body:           `{"name":"Failing","condition":"failed >= 10 in 5m","webhook_url":"https://hooks.example.com/alerts","cooldown_seconds":600}`,
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryRuleRepo) {
+				var resp AlertRuleResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "failed>=10 in 5m", resp.Condition)
+				assert.Equal(t, 600, resp.CooldownSeconds)
+				assert.True(t, resp.Enabled)
+				assert.Len(t, repo.rules, 2)
+			},
+		},
+		{
+			name:           "Create with an invalid condition",
+			given:          "a condition without a window",
+			when:           "POST /api/alerts/rules",
+			then:           "should return 400 and store nothing",
+			method:         http.MethodPost,
+			path:           "/api/alerts/rules",
+			body:           `{"name":"Failing","condition":"failed >= 10"}`,
+			expectedStatus: http.StatusBadRequest,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryRuleRepo) {
+				assert.Len(t, repo.rules, 1)
+			},
+		},
+		{
+			name:           "Get",
+			given:          "a stored rule",
+			when:           "GET /api/alerts/rules/{id}",
+			then:           "should return it",
+			method:         http.MethodGet,
+			path:           "/api/alerts/rules/" + existing.ID.String(),
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryRuleRepo) {
+				var resp AlertRuleResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "Auth failures", resp.Name)
+			},
+		},
+		{
+			name:           "Get unknown",
+			given:          "no rule with the ID",
+			when:           "GET /api/alerts/rules/{id}",
+			then:           "should return 404",
+			method:         http.MethodGet,
+			path:           "/api/alerts/rules/" + uuid.New().String(),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid ID",
+			given:          "an ID that isn't a UUID",
+			when:           "GET /api/alerts/rules/{id}",
+			then:           "should return 400",
+			method:         http.MethodGet,
+			path:           "/api/alerts/rules/not-a-uuid",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Disable",
+			given:          "an enabled rule",
+			when:           "PATCH /api/alerts/rules/{id} with enabled false",
+			then:           "should disable it and keep its condition",
+			method:         http.MethodPatch,
+			path:           "/api/alerts/rules/" + existing.ID.String(),
+			body:           `{"enabled":false}`,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryRuleRepo) {
+				var resp AlertRuleResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.False(t, resp.Enabled)
+				assert.Equal(t, "category=auth AND count>5 in 10m", resp.Condition)
+			},
+		},
+		{
+			name:           "Delete",
+			given:          "a stored rule",
+			when:           "DELETE /api/alerts/rules/{id}",
+			then:           "should remove it",
+			method:         http.MethodDelete,
+			path:           "/api/alerts/rules/" + existing.ID.String(),
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryRuleRepo) {
+				assert.Empty(t, repo.rules)
+			},
+		},
+		{
+			name:           "Method not allowed",
+			given:          "the rules collection",
+			when:           "DELETE /api/alerts/rules",
+			then:           "should return 405",
+			method:         http.MethodDelete,
+			path:           "/api/alerts/rules",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			stored := *existing
+			repo := &InMemoryRuleRepo{rules: []*alert.Rule{&stored}}
+			mux := http.NewServeMux()
+			RegisterAlertRoutes(mux, NewAlertHandlers(appAlert.NewService(repo, NoSamples{})))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec, repo)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// CreateGroupRequest is a group of jobs submitted together
+type CreateGroupRequest struct {
+	Jobs []CreateJobRequest `json:"jobs"`
+	// OnComplete is created once every job of the group completed or failed; optional
+	OnComplete *CreateJobRequest `json:"on_complete,omitempty"`
+}
+
+type GroupCreatedResponse struct {
+	GroupID       string        `json:"group_id"`
+	Size          int           `json:"size"`
+	Jobs          []JobResponse `json:"jobs"`
+	CompletionJob *JobResponse  `json:"completion_job,omitempty"`
+}
+
+type GroupResponse struct {
+	ID              string `json:"id"`
+	Status          string `json:"status"` // running or completed
+	Total           int64  `json:"total"`
+	Completed       int64  `json:"completed"`
+	Failed          int64  `json:"failed"`
+	Pending         int64  `json:"pending"`
+	CompletionJobID string `json:"completion_job_id,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	CompletedAt     string `json:"completed_at,omitempty"`
+}
+
+func newGroupResponse(group *queue.Group, progress queue.GroupProgress) GroupResponse {
+	resp := GroupResponse{
+		ID:        group.ID.String(),
+		Status:    "running",
+		Total:     progress.Total,
+		Completed: progress.Completed,
+		Failed:    progress.Failed,
+		Pending:   progress.Pending,
+		CreatedAt: group.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if group.CompletionJobID != nil {
+		resp.CompletionJobID = group.CompletionJobID.String()
+	}
+	if group.CompletedAt != nil {
+		resp.Status = "completed"
+		resp.CompletedAt = group.CompletedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return resp
+}
+
+// CreateGroup creates the jobs of a group, tagged with the group's ID, and its completion job
+func (h *QueueHandlers) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req CreateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	apiKey := r.Header.Get(h.apiKeyHeader)
+	cmd := appQueue.CreateGroupCommand{Jobs: make([]appQueue.CreateJobCommand, 0, len(req.Jobs))}
+	for _, job := range req.Jobs {
+		cmd.Jobs = append(cmd.Jobs, job.command(apiKey))
+	}
+	if req.OnComplete != nil {
+		onComplete := req.OnComplete.command(apiKey)
+		cmd.OnComplete = &onComplete
+	}
+
+	created, err := h.queueService.CreateGroup(r.Context(), cmd)
+	if err != nil {
+		writeGroupError(w, r, err)
+		return
+	}
+
+	resp := GroupCreatedResponse{
+		GroupID: created.Group.ID.String(),
+		Size:    created.Group.Size,
+		Jobs:    make([]JobResponse, 0, len(created.Jobs)),
+	}
+	for _, job := range created.Jobs {
+		resp.Jobs = append(resp.Jobs, newJobResponse(job))
+	}
+	if created.CompletionJob != nil {
+		completionJob := newJobResponse(created.CompletionJob)
+		resp.CompletionJob = &completionJob
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetGroup returns a group and how many of its jobs completed, failed or are still pending
+func (h *QueueHandlers) GetGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/api/groups/"))
+	if err != nil {
+		http.Error(w, "invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	group, progress, err := h.queueService.GetGroup(r.Context(), id)
+	if err != nil {
+		writeGroupError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newGroupResponse(group, progress))
+}
+
+// writeGroupError maps the errors of the job group endpoints to their status codes, leaving
+// the errors of creating its jobs to writeCreateJobError
+func writeGroupError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, appQueue.ErrGroupsDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, queue.ErrGroupNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, queue.ErrInvalidGroup), errors.Is(err, queue.ErrInvalidQueue), errors.Is(err, queue.ErrInvalidType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeCreateJobError(w, r, err)
+	}
+}
@@ -0,0 +1,228 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// InMemoryGroupRepo is an in-memory queue.GroupRepository counting the jobs of InMemoryJobRepo
+type InMemoryGroupRepo struct {
+	jobs   *InMemoryJobRepo
+	groups map[uuid.UUID]*queue.Group
+}
+
+func (r *InMemoryGroupRepo) Create(ctx context.Context, group *queue.Group) error {
+	r.groups[group.ID] = group
+	return nil
+}
+
+func (r *InMemoryGroupRepo) Get(ctx context.Context, id uuid.UUID) (*queue.Group, error) {
+	if group, ok := r.groups[id]; ok {
+		stored := *group
+		return &stored, nil
+	}
+	return nil, queue.ErrGroupNotFound
+}
+
+func (r *InMemoryGroupRepo) Progress(ctx context.Context, id uuid.UUID) (queue.GroupProgress, error) {
+	var progress queue.GroupProgress
+	for _, job := range r.jobs.jobs {
+		if job.GroupID == nil || *job.GroupID != id {
+			continue
+		}
+		progress.Total++
+		switch {
+		case job.Status == queue.StatusCompleted:
+			progress.Completed++
+		case job.Status == queue.StatusFailed || job.IsDeleted():
+			progress.Failed++
+		default:
+			progress.Pending++
+		}
+	}
+	return progress, nil
+}
+
+func (r *InMemoryGroupRepo) Resize(ctx context.Context, id uuid.UUID, size int) error {
+	group, ok := r.groups[id]
+	if !ok {
+		return queue.ErrGroupNotFound
+	}
+	group.Size = size
+	return nil
+}
+
+func (r *InMemoryGroupRepo) Finish(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	group, ok := r.groups[id]
+	if !ok || group.CompletedAt != nil {
+		return false, nil
+	}
+	progress, _ := r.Progress(ctx, id)
+	if progress.Completed+progress.Failed < int64(group.Size) {
+		return false, nil
+	}
+	group.CompletedAt = &now
+	return true, nil
+}
+
+func TestGroupRoutes(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		method         string
+		path           func(group *queue.Group) string
+		body           string
+		disabled       bool
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder, *InMemoryJobRepo)
+	}{
+		{
+			name:           "Create",
+			given:          "two jobs and a completion job",
+			when:           "POST /api/groups",
+			then:           "should create the jobs in the group and hold the completion job",
+			method:         http.MethodPost,
+			path:           func(*queue.Group) string { return "/api/groups" },
+			body:           `{"jobs":[{"queue":"images","type":"resize","payload":{"n":1}},{"queue":"images","type":"resize","payload":{"n":2}}],"on_complete":{"queue":"images","type":"zip"}}`,
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryJobRepo) {
+				var resp GroupCreatedResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, 2, resp.Size)
+				require.Len(t, resp.Jobs, 2)
+				assert.Equal(t, resp.GroupID, resp.Jobs[0].GroupID)
+				require.NotNil(t, resp.CompletionJob)
+				assert.Empty(t, resp.CompletionJob.GroupID)
+				assert.Equal(t, "9999-12-31T00:00:00Z", resp.CompletionJob.ScheduledFor)
+				// The three jobs of the existing group, the two new jobs and the completion job
+				assert.Len(t, repo.jobs, 6)
+			},
+		},
+		{
+			name:           "Create empty",
+			given:          "no jobs",
+			when:           "POST /api/groups",
+			then:           "should return 400",
+			method:         http.MethodPost,
+			path:           func(*queue.Group) string { return "/api/groups" },
+			body:           `{"jobs":[]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Create with an invalid job",
+			given:          "a job without a type",
+			when:           "POST /api/groups",
+			then:           "should return 400 and create nothing",
+			method:         http.MethodPost,
+			path:           func(*queue.Group) string { return "/api/groups" },
+			body:           `{"jobs":[{"queue":"images","type":"resize"},{"queue":"images"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryJobRepo) {
+				// Only the three jobs of the existing group
+				assert.Len(t, repo.jobs, 3)
+			},
+		},
+		{
+			name:           "Get running",
+			given:          "a group with one completed, one failed and one pending job",
+			when:           "GET /api/groups/{id}",
+			then:           "should return the counts and a running status",
+			method:         http.MethodGet,
+			path:           func(group *queue.Group) string { return "/api/groups/" + group.ID.String() },
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryJobRepo) {
+				var resp GroupResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "running", resp.Status)
+				assert.Equal(t, int64(3), resp.Total)
+				assert.Equal(t, int64(1), resp.Completed)
+				assert.Equal(t, int64(1), resp.Failed)
+				assert.Equal(t, int64(1), resp.Pending)
+				assert.Empty(t, resp.CompletedAt)
+			},
+		},
+		{
+			name:           "Get unknown",
+			given:          "no group with the ID",
+			when:           "GET /api/groups/{id}",
+			then:           "should return 404",
+			method:         http.MethodGet,
+			path:           func(*queue.Group) string { return "/api/groups/" + uuid.New().String() },
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid ID",
+			given:          "an ID that isn't a UUID",
+			when:           "GET /api/groups/{id}",
+			then:           "should return 400",
+			method:         http.MethodGet,
+			path:           func(*queue.Group) string { return "/api/groups/not-a-uuid" },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Disabled",
+			given:          "no group repository",
+			when:           "GET /api/groups/{id}",
+			then:           "should return 503",
+			method:         http.MethodGet,
+			path:           func(group *queue.Group) string { return "/api/groups/" + group.ID.String() },
+			disabled:       true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Method not allowed",
+			given:          "the groups collection",
+			when:           "GET /api/groups",
+			then:           "should return 405",
+			method:         http.MethodGet,
+			path:           func(*queue.Group) string { return "/api/groups" },
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			groups := &InMemoryGroupRepo{jobs: repo, groups: make(map[uuid.UUID]*queue.Group)}
+			group, err := queue.NewGroup(3)
+			require.NoError(t, err)
+			groups.groups[group.ID] = group
+			for _, status := range []queue.Status{queue.StatusCompleted, queue.StatusFailed, queue.StatusRetrying} {
+				job := &queue.Job{ID: uuid.New(), Queue: "images", Type: "resize", Status: status, GroupID: &group.ID}
+				repo.jobs[job.ID] = job
+			}
+			service := appQueue.NewService(repo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			if !tt.disabled {
+				service.SetGroupRepository(groups)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path(group), strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec, repo)
+			}
+		})
+	}
+}
@@ -3,72 +3,814 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
 	"github.com/google/uuid"
 )
 
 // InsightsHandlers handles HTTP requests for insights operations
 type InsightsHandlers struct {
 	insightsService *appInsights.Service
+	analyzer        *appInsights.AsyncAnalyzer
+	quotas          quota.Enforcer
+	owners          queue.Owners
+	apiKeyHeader    string
+	pages           PageLimits
 }
 
 // NewInsightsHandlers creates a new insights HTTP handlers
 func NewInsightsHandlers(insightsService *appInsights.Service) *InsightsHandlers {
 	return &InsightsHandlers{
 		insightsService: insightsService,
+		apiKeyHeader:    DefaultAPIKeyHeader,
+		pages:           DefaultPageLimits(),
 	}
 }
 
+// SetPageLimits changes the default and maximum page size of the insight listing; zero values
+// keep the defaults
+func (h *InsightsHandlers) SetPageLimits(limits PageLimits) {
+	h.pages = limits.withDefaults()
+}
+
+// SetQuotaEnforcer meters the analyses requested by each API key, read from the given
+// header, against the quota of the owner it is attributed to
+func (h *InsightsHandlers) SetQuotaEnforcer(enforcer quota.Enforcer, apiKeyHeader string, owners queue.Owners) {
+	h.quotas = enforcer
+	h.owners = owners
+	if apiKeyHeader != "" {
+		h.apiKeyHeader = apiKeyHeader
+	}
+}
+
+// SetAsyncAnalyzer enables asynchronous analyses (POST /api/insights/analyze?async=true)
+func (h *InsightsHandlers) SetAsyncAnalyzer(analyzer *appInsights.AsyncAnalyzer) {
+	h.analyzer = analyzer
+}
+
 type InsightResponse struct {
-	ID             string         `json:"id"`
-	JobID          string         `json:"job_id"`
-	Diagnosis      string         `json:"diagnosis"`
-	Recommendation string         `json:"recommendation"`
-	SuggestedFix   map[string]any `json:"suggested_fix"`
-	CreatedAt      string         `json:"created_at"`
+	ID             string          `json:"id"`
+	JobID          string          `json:"job_id"`
+	Kind           string          `json:"kind"` // failure or performance
+	Diagnosis      string          `json:"diagnosis"`
+	Recommendation string          `json:"recommendation"`
+	SuggestedFix   map[string]any  `json:"suggested_fix"`
+	Confidence     float64         `json:"confidence"`
+	Provider       string          `json:"provider,omitempty"`
+	Usage          *insights.Usage `json:"usage,omitempty"`
+	// AIRecommendation is the AI's original text once an operator replaced the recommendation
+	AIRecommendation string `json:"ai_recommendation,omitempty"`
+	Note             string `json:"note,omitempty"`
+	EditedBy         string `json:"edited_by,omitempty"`
+	EditedAt         string `json:"edited_at,omitempty"`
+	// TriageLabel tells how actionable the failure is: auto-retryable, config-issue or needs-human
+	TriageLabel string `json:"triage_label,omitempty"`
+	// SharedFrom is the insight this one was copied from during a failure storm
+	SharedFrom string `json:"shared_from,omitempty"`
+	// LinkedJobs are the jobs that reused the insight because their error had the same
+	// fingerprint; only returned by GET /api/insights/{id}
+	LinkedJobs []string `json:"linked_jobs,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// newInsightResponse maps a domain insight to its API representation
+func newInsightResponse(insight *insights.Insight) InsightResponse {
+	response := InsightResponse{
+		ID:             insight.ID.String(),
+		JobID:          insight.JobID.String(),
+		Kind:           string(insights.KindOf(insight)),
+		Diagnosis:      insight.Diagnosis,
+		Recommendation: insight.Recommendation,
+		SuggestedFix: map[string]any{
+			"timeout_seconds": insight.SuggestedFix.TimeoutSeconds,
+			"max_retries":     insight.SuggestedFix.MaxRetries,
+			"payload_patch":   insight.SuggestedFix.PayloadPatch,
+		},
+		Confidence:       insight.Confidence,
+		Provider:         insight.Provider,
+		Usage:            insight.Usage,
+		AIRecommendation: insight.AIRecommendation,
+		Note:             insight.Note,
+		EditedBy:         insight.EditedBy,
+		TriageLabel:      string(insight.Triage),
+		CreatedAt:        insight.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if insight.EditedAt != nil {
+		response.EditedAt = insight.EditedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if insight.SharedFrom != nil {
+		response.SharedFrom = insight.SharedFrom.String()
+	}
+	return response
+}
+
+// EditInsightRequest is the body of PATCH /api/insights/{id}; omitted fields are left unchanged
+type EditInsightRequest struct {
+	Recommendation *string `json:"recommendation"`
+	Note           *string `json:"note"`
+	EditedBy       string  `json:"edited_by"`
+}
+
+// EditInsight lets an operator correct an insight's recommendation or annotate it.
+// The AI's original recommendation is kept and returned as ai_recommendation.
+func (h *InsightsHandlers) EditInsight(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/insights/")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid insight ID",
+			slog.String("insightId", idStr),
+		)
+		http.Error(w, "invalid insight id", http.StatusBadRequest)
+		return
+	}
+
+	var req EditInsightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	insight, err := h.insightsService.EditInsight(r.Context(), id, insights.InsightEdit{
+		Recommendation: req.Recommendation,
+		Note:           req.Note,
+		EditedBy:       req.EditedBy,
+	})
+	switch {
+	case errors.Is(err, insights.ErrInsightNotFound):
+		http.Error(w, "insight not found", http.StatusNotFound)
+		return
+	case errors.Is(err, insights.ErrInvalidEdit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to edit insight",
+			slog.String("insightId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newInsightResponse(insight))
+}
+
+type PayloadDiffResponse struct {
+	Before any                      `json:"before"`
+	After  any                      `json:"after"`
+	Diff   []insights.PayloadChange `json:"diff"`
+}
+
+type RetryPlanResponse struct {
+	WillRetry      bool   `json:"will_retry"`
+	StatusBefore   string `json:"status_before"`
+	StatusAfter    string `json:"status_after"`
+	MaxRetries     int    `json:"max_retries,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+type ApplyFixResponse struct {
+	InsightID string              `json:"insight_id"`
+	JobID     string              `json:"job_id"`
+	DryRun    bool                `json:"dry_run"`
+	Applied   bool                `json:"applied"`
+	Payload   PayloadDiffResponse `json:"payload"`
+	RetryPlan RetryPlanResponse   `json:"retry_plan"`
+	// Enqueued tells whether the applied fix put the job back in its queue to be retried
+	Enqueued bool         `json:"enqueued"`
+	Job      *JobResponse `json:"job,omitempty"` // The job as updated; left out of dry runs
+}
+
+// newApplyFixResponse maps a fix plan to its API representation
+func newApplyFixResponse(plan *insights.FixPlan, dryRun bool) ApplyFixResponse {
+	var before, after any
+	json.Unmarshal(plan.PayloadBefore, &before)
+	json.Unmarshal(plan.PayloadAfter, &after)
+
+	return ApplyFixResponse{
+		InsightID: plan.InsightID.String(),
+		JobID:     plan.JobID.String(),
+		DryRun:    dryRun,
+		Applied:   !dryRun,
+		Payload: PayloadDiffResponse{
+			Before: before,
+			After:  after,
+			Diff:   plan.Changes,
+		},
+		RetryPlan: RetryPlanResponse{
+			WillRetry:      plan.WillRetry,
+			StatusBefore:   string(plan.StatusBefore),
+			StatusAfter:    string(plan.StatusAfter),
+			MaxRetries:     plan.MaxRetries,
+			TimeoutSeconds: plan.TimeoutSeconds,
+		},
+	}
+}
+
+// ApplyInsightFix applies an insight's suggested fix to its job.
+// With dry_run=true it only returns the payload diff and retry plan.
+func (h *InsightsHandlers) ApplyInsightFix(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /api/insights/{id}/apply
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/insights/"), "/apply")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid insight ID",
+			slog.String("insightId", idStr),
+		)
+		http.Error(w, "invalid insight id", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			http.Error(w, "dry_run must be a boolean", http.StatusBadRequest)
+			return
+		}
+	}
+
+	slog.InfoContext(r.Context(), "Applying fix",
+		slog.String("insightId", id.String()),
+		slog.Bool("dryRun", dryRun),
+	)
+	var plan *insights.FixPlan
+	var applied *appInsights.AppliedFix
+	if dryRun {
+		plan, err = h.insightsService.PreviewInsightFix(r.Context(), id)
+	} else if applied, err = h.insightsService.ApplyInsightFix(r.Context(), id); err == nil {
+		plan = applied.Plan
+	}
+	switch {
+	case errors.Is(err, insights.ErrInsightNotFound):
+		http.Error(w, "insight not found", http.StatusNotFound)
+		return
+	case errors.Is(err, queue.ErrJobNotFound):
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, insights.ErrPayloadNotObject):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to apply fix",
+			slog.String("insightId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := newApplyFixResponse(plan, dryRun)
+	if applied != nil {
+		job := newJobResponse(applied.Job)
+		resp.Job = &job
+		resp.Enqueued = applied.Enqueued
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Incident report formats
+const (
+	reportFormatMarkdown = "markdown"
+	reportFormatHTML     = "html"
+)
+
+// reportContentSecurityPolicy lets a rendered report use its inline styles and nothing else
+const reportContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'"
+
+// GetInsightReport returns an incident report of the insight's job for a postmortem, as
+// markdown or, with format=html, rendered server-side into a printable page
+func (h *InsightsHandlers) GetInsightReport(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /api/insights/{id}/report
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/insights/"), "/report")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "invalid insight id", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = reportFormatMarkdown
+	}
+	if format != reportFormatMarkdown && format != reportFormatHTML {
+		http.Error(w, "format must be markdown or html", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.insightsService.IncidentReport(r.Context(), id)
+	if errors.Is(err, insights.ErrInsightNotFound) {
+		http.Error(w, "insight not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to build incident report",
+			slog.String("insightId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := "incident-" + id.String()
+	if format == reportFormatHTML {
+		page, err := report.HTML()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", reportContentSecurityPolicy)
+		w.Header().Set("Content-Disposition", `inline; filename="`+filename+`.html"`)
+		w.Write(page)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`.md"`)
+	w.Write([]byte(report.Markdown()))
+}
+
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 365
+)
+
+// UsageEntry is the AI usage of one provider and model on one day
+type UsageEntry struct {
+	Day              string  `json:"day"`
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	PromptBytes      int64   `json:"prompt_bytes"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// UsageTotals sums AI usage over the reported window
+type UsageTotals struct {
+	Calls            int64 `json:"calls"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	PromptBytes      int64 `json:"prompt_bytes"`
+}
+
+func (t *UsageTotals) add(agg *insights.UsageAggregate) {
+	t.Calls += agg.Calls
+	t.PromptTokens += agg.PromptTokens
+	t.CompletionTokens += agg.CompletionTokens
+	t.TotalTokens += agg.PromptTokens + agg.CompletionTokens
+	t.PromptBytes += agg.PromptBytes
+}
+
+type UsageResponse struct {
+	Since      string                  `json:"since"`
+	Days       int                     `json:"days"`
+	Usage      []UsageEntry            `json:"usage"`
+	ByProvider map[string]*UsageTotals `json:"by_provider"`
+	Totals     UsageTotals             `json:"totals"`
+}
+
+// GetUsage reports AI token usage and latency aggregated per day and provider
+func (h *InsightsHandlers) GetUsage(w http.ResponseWriter, r *http.Request) {
+	days := defaultUsageDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > maxUsageDays {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+
+	slog.InfoContext(r.Context(), "Fetching AI usage",
+		slog.String("since", since.Format("2006-01-02")),
+	)
+	summary, err := h.insightsService.GetUsageSummary(r.Context(), since)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch AI usage",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := UsageResponse{
+		Since:      since.Format("2006-01-02T15:04:05Z"),
+		Days:       days,
+		Usage:      make([]UsageEntry, 0, len(summary)),
+		ByProvider: make(map[string]*UsageTotals),
+	}
+	for _, agg := range summary {
+		response.Usage = append(response.Usage, UsageEntry{
+			Day:              agg.Day.UTC().Format("2006-01-02"),
+			Provider:         agg.Provider,
+			Model:            agg.Model,
+			Calls:            agg.Calls,
+			PromptTokens:     agg.PromptTokens,
+			CompletionTokens: agg.CompletionTokens,
+			TotalTokens:      agg.PromptTokens + agg.CompletionTokens,
+			PromptBytes:      agg.PromptBytes,
+			AvgLatencyMs:     agg.AvgLatencyMs,
+		})
+
+		providerTotals, ok := response.ByProvider[agg.Provider]
+		if !ok {
+			providerTotals = &UsageTotals{}
+			response.ByProvider[agg.Provider] = providerTotals
+		}
+		providerTotals.add(agg)
+		response.Totals.add(agg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// defaultEffectivenessDays matches the window of fix history shown to the AI
+const defaultEffectivenessDays = 90
+
+type FixEffectivenessEntry struct {
+	JobType     string  `json:"job_type"`
+	FixKind     string  `json:"fix_kind"`
+	Applied     int64   `json:"applied"`
+	Succeeded   int64   `json:"succeeded"`
+	Failed      int64   `json:"failed"`
+	Pending     int64   `json:"pending"`
+	SuccessRate float64 `json:"success_rate"`
+	Summary     string  `json:"summary"`
+}
+
+type FixEffectivenessResponse struct {
+	Since         string                  `json:"since"`
+	Days          int                     `json:"days"`
+	Effectiveness []FixEffectivenessEntry `json:"effectiveness"`
+}
+
+// GetFixEffectiveness reports how often each kind of applied fix made its job complete on the
+// next run, per job type
+func (h *InsightsHandlers) GetFixEffectiveness(w http.ResponseWriter, r *http.Request) {
+	days := defaultEffectivenessDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > maxUsageDays {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+	jobType := r.URL.Query().Get("job_type")
+
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+
+	stats, err := h.insightsService.GetFixEffectiveness(r.Context(), jobType, since)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch fix effectiveness",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := FixEffectivenessResponse{
+		Since:         since.Format("2006-01-02T15:04:05Z"),
+		Days:          days,
+		Effectiveness: make([]FixEffectivenessEntry, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		response.Effectiveness = append(response.Effectiveness, FixEffectivenessEntry{
+			JobType:     stat.JobType,
+			FixKind:     stat.FixKind,
+			Applied:     stat.Applied,
+			Succeeded:   stat.Succeeded,
+			Failed:      stat.Failed,
+			Pending:     stat.Applied - stat.Resolved(),
+			SuccessRate: stat.SuccessRate(),
+			Summary:     stat.Summary(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type RecurringDiagnosisEntry struct {
+	Diagnosis     string   `json:"diagnosis"`
+	Count         int      `json:"count"`
+	JobTypes      []string `json:"job_types"`
+	AvgConfidence float64  `json:"avg_confidence"`
+	LastSeen      string   `json:"last_seen"`
+}
+
+type CommonFixEntry struct {
+	Kind     string         `json:"kind"`
+	Count    int            `json:"count"`
+	JobTypes []string       `json:"job_types"`
+	Example  map[string]any `json:"example"`
+}
+
+type ExecutiveSummaryResponse struct {
+	Summary        string  `json:"summary"`
+	Recommendation string  `json:"recommendation"`
+	Confidence     float64 `json:"confidence"`
+	Provider       string  `json:"provider,omitempty"`
+}
+
+type QueueSummaryResponse struct {
+	Queue              string                    `json:"queue"`
+	Since              string                    `json:"since"`
+	Days               int                       `json:"days"`
+	Insights           int                       `json:"insights"`
+	RecurringDiagnoses []RecurringDiagnosisEntry `json:"recurring_diagnoses"`
+	CommonFixes        []CommonFixEntry          `json:"common_fixes"`
+	ExecutiveSummary   *ExecutiveSummaryResponse `json:"executive_summary,omitempty"`
+	SummaryError       string                    `json:"summary_error,omitempty"`
+}
+
+// GetQueueSummary aggregates the insights of a queue into recurring diagnoses and common fixes,
+// with an AI executive summary unless ai=false
+func (h *InsightsHandlers) GetQueueSummary(w http.ResponseWriter, r *http.Request) {
+	queueName := r.URL.Query().Get("queue")
+	if queueName == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
+		return
+	}
+
+	days := insights.DefaultSummaryDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > maxUsageDays {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	withAI := true
+	if aiStr := r.URL.Query().Get("ai"); aiStr != "" {
+		b, err := strconv.ParseBool(aiStr)
+		if err != nil {
+			http.Error(w, "ai must be true or false", http.StatusBadRequest)
+			return
+		}
+		withAI = b
+	}
+
+	// The executive summary is one more AI call, metered like an analysis; it is skipped, and
+	// not metered, while the AI provider is degraded
+	if withAI && h.quotas != nil && h.insightsService.CheckProvider() == nil {
+		err := h.quotas.ReserveAnalysis(r.Context(), h.tenant(r))
+		if errors.Is(err, quota.ErrQuotaExceeded) {
+			writeQuotaExceeded(w, r, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+
+	summary, err := h.insightsService.SummarizeQueue(context.WithoutCancel(r.Context()), queueName, since, withAI)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to summarize queue insights",
+			slog.String("queue", queueName),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := QueueSummaryResponse{
+		Queue:              summary.Queue,
+		Since:              since.Format("2006-01-02T15:04:05Z"),
+		Days:               days,
+		Insights:           summary.Insights,
+		RecurringDiagnoses: make([]RecurringDiagnosisEntry, 0, len(summary.Diagnoses)),
+		CommonFixes:        make([]CommonFixEntry, 0, len(summary.Fixes)),
+		SummaryError:       summary.SummaryError,
+	}
+	for _, diagnosis := range summary.Diagnoses {
+		response.RecurringDiagnoses = append(response.RecurringDiagnoses, RecurringDiagnosisEntry{
+			Diagnosis:     diagnosis.Diagnosis,
+			Count:         diagnosis.Count,
+			JobTypes:      diagnosis.JobTypes,
+			AvgConfidence: diagnosis.AvgConfidence,
+			LastSeen:      diagnosis.LastSeen.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	for _, fix := range summary.Fixes {
+		response.CommonFixes = append(response.CommonFixes, CommonFixEntry{
+			Kind:     fix.Kind,
+			Count:    fix.Count,
+			JobTypes: fix.JobTypes,
+			Example: map[string]any{
+				"timeout_seconds": fix.Example.TimeoutSeconds,
+				"max_retries":     fix.Example.MaxRetries,
+				"payload_patch":   fix.Example.PayloadPatch,
+			},
+		})
+	}
+	if s := summary.ExecutiveSummary; s != nil {
+		response.ExecutiveSummary = &ExecutiveSummaryResponse{
+			Summary:        s.Summary,
+			Recommendation: s.Recommendation,
+			Confidence:     s.Confidence,
+			Provider:       s.Provider,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type ClusterRepresentativeEntry struct {
+	InsightID      string  `json:"insight_id"`
+	JobID          string  `json:"job_id"`
+	Diagnosis      string  `json:"diagnosis"`
+	Recommendation string  `json:"recommendation"`
+	Confidence     float64 `json:"confidence"`
+}
+
+type InsightClusterEntry struct {
+	Representative ClusterRepresentativeEntry `json:"representative"`
+	Members        int                        `json:"members"`
+	JobTypes       []string                   `json:"job_types"`
+	AvgConfidence  float64                    `json:"avg_confidence"`
+	Cohesion       float64                    `json:"cohesion"`
+	FirstSeen      string                     `json:"first_seen"`
+	LastSeen       string                     `json:"last_seen"`
+	InsightIDs     []string                   `json:"insight_ids"`
+}
+
+type InsightClustersResponse struct {
+	ComputedAt  string                `json:"computed_at"`
+	Since       string                `json:"since"`
+	Model       string                `json:"model"`
+	Insights    int                   `json:"insights"`
+	Unclustered int                   `json:"unclustered"`
+	Clusters    []InsightClusterEntry `json:"clusters"`
+}
+
+// GetInsightClusters returns the groups of failure insights with similar diagnoses found by the
+// latest clustering run, largest first, so systemic problems stand out from one-off failures
+func (h *InsightsHandlers) GetInsightClusters(w http.ResponseWriter, r *http.Request) {
+	report, err := h.insightsService.InsightClusters()
+	if errors.Is(err, appInsights.ErrClusteringDisabled) || errors.Is(err, appInsights.ErrClustersNotReady) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := InsightClustersResponse{
+		ComputedAt:  report.ComputedAt.Format("2006-01-02T15:04:05Z"),
+		Since:       report.Since.Format("2006-01-02T15:04:05Z"),
+		Model:       report.Model,
+		Insights:    report.Insights,
+		Unclustered: report.Unclustered,
+		Clusters:    make([]InsightClusterEntry, 0, len(report.Clusters)),
+	}
+	for _, cluster := range report.Clusters {
+		representative := cluster.Representative
+		entry := InsightClusterEntry{
+			Representative: ClusterRepresentativeEntry{
+				InsightID:      representative.ID.String(),
+				JobID:          representative.JobID.String(),
+				Diagnosis:      representative.Diagnosis,
+				Recommendation: representative.Recommendation,
+				Confidence:     representative.Confidence,
+			},
+			Members:       cluster.Members,
+			JobTypes:      cluster.JobTypes,
+			AvgConfidence: cluster.AvgConfidence,
+			Cohesion:      cluster.Cohesion,
+			FirstSeen:     cluster.FirstSeen.Format("2006-01-02T15:04:05Z"),
+			LastSeen:      cluster.LastSeen.Format("2006-01-02T15:04:05Z"),
+			InsightIDs:    make([]string, 0, len(cluster.InsightIDs)),
+		}
+		for _, id := range cluster.InsightIDs {
+			entry.InsightIDs = append(entry.InsightIDs, id.String())
+		}
+		response.Clusters = append(response.Clusters, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ProviderHealthResponse is the AI provider's health and downtime since the process started
+type ProviderHealthResponse struct {
+	State               string  `json:"state"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	LastError           string  `json:"last_error,omitempty"`
+	LastProbeAt         string  `json:"last_probe_at,omitempty"`
+	FailingSince        string  `json:"failing_since,omitempty"`
+	RetryAfter          int     `json:"retry_after,omitempty"` // Seconds until the next probe, while degraded
+	Outages             int     `json:"outages"`
+	DowntimeSeconds     float64 `json:"downtime_seconds"`
+}
+
+// GetProviderHealth reports whether the AI provider passes its health probes, and how long it
+// has been down
+func (h *InsightsHandlers) GetProviderHealth(w http.ResponseWriter, r *http.Request) {
+	status, err := h.insightsService.ProviderHealth()
+	if errors.Is(err, appInsights.ErrProviderHealthDisabled) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := ProviderHealthResponse{
+		State:               string(status.State),
+		ConsecutiveFailures: status.ConsecutiveFailures,
+		LastError:           status.LastError,
+		Outages:             status.Outages,
+		DowntimeSeconds:     status.Downtime.Seconds(),
+	}
+	if !status.LastProbeAt.IsZero() {
+		response.LastProbeAt = status.LastProbeAt.Format("2006-01-02T15:04:05Z")
+	}
+	if status.FailingSince != nil {
+		response.FailingSince = status.FailingSince.Format("2006-01-02T15:04:05Z")
+	}
+	var unavailable *appInsights.ProviderUnavailableError
+	if errors.As(h.insightsService.CheckProvider(), &unavailable) {
+		response.RetryAfter = max(1, int(math.Ceil(unavailable.RetryAfter.Seconds())))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 func (h *InsightsHandlers) GetInsightByID(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from path: /api/insights/{id}
 	idStr := r.URL.Path[len("/api/insights/"):]
 	if idStr == "" {
-		log.Printf("[GetInsightByID] Missing insight ID in path")
+		slog.InfoContext(r.Context(), "Missing insight ID in path")
 		http.Error(w, "insight id is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		log.Printf("[GetInsightByID] Invalid insight ID: %s", idStr)
+		slog.InfoContext(r.Context(), "Invalid insight ID",
+			slog.String("insightId", idStr),
+		)
 		http.Error(w, "invalid insight id", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[GetInsightByID] Fetching insight: id=%s", id)
+	slog.InfoContext(r.Context(), "Fetching insight",
+		slog.String("insightId", id.String()),
+	)
 	insight, err := h.insightsService.GetInsight(r.Context(), id)
 	if err != nil {
-		log.Printf("[GetInsightByID] Insight not found: id=%s", id)
+		slog.WarnContext(r.Context(), "Insight not found",
+			slog.String("insightId", id.String()),
+		)
 		http.Error(w, "insight not found", http.StatusNotFound)
 		return
 	}
-	log.Printf("[GetInsightByID] Insight retrieved: id=%s, job_id=%s", insight.ID, insight.JobID)
+	slog.InfoContext(r.Context(), "Insight retrieved",
+		slog.String("insightId", insight.ID.String()),
+		slog.String("jobId", insight.JobID.String()),
+	)
 
-	response := InsightResponse{
-		ID:             insight.ID.String(),
-		JobID:          insight.JobID.String(),
-		Diagnosis:      insight.Diagnosis,
-		Recommendation: insight.Recommendation,
-		SuggestedFix: map[string]any{
-			"timeout_seconds": insight.SuggestedFix.TimeoutSeconds,
-			"max_retries":     insight.SuggestedFix.MaxRetries,
-			"payload_patch":   insight.SuggestedFix.PayloadPatch,
-		},
-		CreatedAt: insight.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	response := newInsightResponse(insight)
+	linked, err := h.insightsService.GetLinkedJobs(r.Context(), insight.ID)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to load the jobs linked to the insight",
+			slog.String("insightId", insight.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+	for _, jobID := range linked {
+		response.LinkedJobs = append(response.LinkedJobs, jobID.String())
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -78,90 +820,91 @@ func (h *InsightsHandlers) GetInsightByID(w http.ResponseWriter, r *http.Request
 func (h *InsightsHandlers) GetInsightByJobID(w http.ResponseWriter, r *http.Request) {
 	jobIDStr := r.URL.Query().Get("job_id")
 	if jobIDStr == "" {
-		log.Printf("[GetInsightByJobID] Missing job_id parameter")
+		slog.InfoContext(r.Context(), "Missing job_id parameter")
 		http.Error(w, "job_id is required", http.StatusBadRequest)
 		return
 	}
 
 	jobID, err := uuid.Parse(jobIDStr)
 	if err != nil {
-		log.Printf("[GetInsightByJobID] Invalid job_id: %s", jobIDStr)
+		slog.InfoContext(r.Context(), "Invalid job ID",
+			slog.String("jobId", jobIDStr),
+		)
 		http.Error(w, "invalid job_id", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[GetInsightByJobID] Fetching insight for job: job_id=%s", jobID)
+	slog.InfoContext(r.Context(), "Fetching insight for job",
+		slog.String("jobId", jobID.String()),
+	)
 	insight, err := h.insightsService.GetInsightByJobID(r.Context(), jobID)
 	if err != nil {
-		log.Printf("[GetInsightByJobID] Insight not found for job: job_id=%s", jobID)
+		slog.WarnContext(r.Context(), "Insight not found for job",
+			slog.String("jobId", jobID.String()),
+		)
 		http.Error(w, "insight not found", http.StatusNotFound)
 		return
 	}
-	log.Printf("[GetInsightByJobID] Insight retrieved: id=%s, job_id=%s", insight.ID, insight.JobID)
+	slog.InfoContext(r.Context(), "Insight retrieved",
+		slog.String("insightId", insight.ID.String()),
+		slog.String("jobId", insight.JobID.String()),
+	)
 
-	response := InsightResponse{
-		ID:             insight.ID.String(),
-		JobID:          insight.JobID.String(),
-		Diagnosis:      insight.Diagnosis,
-		Recommendation: insight.Recommendation,
-		SuggestedFix: map[string]any{
-			"timeout_seconds": insight.SuggestedFix.TimeoutSeconds,
-			"max_retries":     insight.SuggestedFix.MaxRetries,
-			"payload_patch":   insight.SuggestedFix.PayloadPatch,
-		},
-		CreatedAt: insight.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}
+	response := newInsightResponse(insight)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
 func (h *InsightsHandlers) ListInsights(w http.ResponseWriter, r *http.Request) {
-	limit := 50
-	offset := 0
-
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil {
-			limit = l
-		}
+	pg, err := h.pages.parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil {
-			offset = o
-		}
+	limit, offset := pg.Limit, pg.Offset
+
+	kind := insights.Kind(r.URL.Query().Get("kind"))
+	if err := kind.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	log.Printf("[ListInsights] Fetching insights: limit=%d, offset=%d", limit, offset)
-	insights, err := h.insightsService.ListInsights(r.Context(), limit, offset)
+	slog.InfoContext(r.Context(), "Fetching insights",
+		slog.String("kind", string(kind)),
+		slog.Int("limit", limit),
+		slog.Int("offset", offset),
+	)
+	insightsList, err := h.insightsService.ListInsights(r.Context(), kind, limit, offset)
 	if err != nil {
-		log.Printf("[ListInsights] Failed to fetch insights: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to fetch insights",
+			slog.String("error", err.Error()),
+		)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("[ListInsights] Found %d insights", len(insights))
+	slog.InfoContext(r.Context(), "Found insights",
+		slog.Int("count", len(insightsList)),
+	)
 
 	var responses []InsightResponse
-	for _, insight := range insights {
-		responses = append(responses, InsightResponse{
-			ID:             insight.ID.String(),
-			JobID:          insight.JobID.String(),
-			Diagnosis:      insight.Diagnosis,
-			Recommendation: insight.Recommendation,
-			SuggestedFix: map[string]any{
-				"timeout_seconds": insight.SuggestedFix.TimeoutSeconds,
-				"max_retries":     insight.SuggestedFix.MaxRetries,
-				"payload_patch":   insight.SuggestedFix.PayloadPatch,
-			},
-			CreatedAt: insight.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		})
+	for _, insight := range insightsList {
+		responses = append(responses, newInsightResponse(insight))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(responses)
 }
 
+// AnalyzeJob analyzes the failed job named by job_id, or the failed job described in the
+// request body for callers that don't share the jobs table. A standalone service analyzes the
+// body even when job_id is set, so clients can send both whatever mode the service runs in.
 func (h *InsightsHandlers) AnalyzeJob(w http.ResponseWriter, r *http.Request) {
 	jobIDStr := r.URL.Query().Get("job_id")
+	if r.ContentLength != 0 && (jobIDStr == "" || h.insightsService.Standalone()) {
+		h.analyzeReportedJob(w, r)
+		return
+	}
 	if jobIDStr == "" {
 		http.Error(w, "job_id is required", http.StatusBadRequest)
 		return
@@ -172,31 +915,252 @@ func (h *InsightsHandlers) AnalyzeJob(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid job_id", http.StatusBadRequest)
 		return
 	}
+	if h.insightsService.Standalone() {
+		http.Error(w, appInsights.ErrStandalone.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Create a context with longer timeout for AI analysis
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	// Synchronous analyses are turned away while the provider is degraded, queued ones still run
+	async, _ := strconv.ParseBool(r.URL.Query().Get("async"))
+	if !async && !h.providerAvailable(w) {
+		return
+	}
+	if !h.reserveAnalysis(w, r) {
+		return
+	}
+
+	if async {
+		h.submitAnalysis(w, r, jobID)
+		return
+	}
 
-	insight, err := h.insightsService.AnalyzeJobFailure(ctx, jobID)
+	// The analysis outlives a caller that gives up; the service bounds how long the AI may take,
+	// which can exceed the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	insight, err := h.insightsService.AnalyzeJobFailure(context.WithoutCancel(r.Context()), jobID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	response := InsightResponse{
-		ID:             insight.ID.String(),
-		JobID:          insight.JobID.String(),
-		Diagnosis:      insight.Diagnosis,
-		Recommendation: insight.Recommendation,
-		SuggestedFix: map[string]any{
-			"timeout_seconds": insight.SuggestedFix.TimeoutSeconds,
-			"max_retries":     insight.SuggestedFix.MaxRetries,
-			"payload_patch":   insight.SuggestedFix.PayloadPatch,
-		},
-		CreatedAt: insight.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}
+	response := newInsightResponse(insight)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
+
+// AnalyzeJobRequest describes a failed job to analyze when the caller doesn't share the jobs table
+type AnalyzeJobRequest struct {
+	JobID   string          `json:"job_id"` // Optional, defaults to the job_id parameter; identifies the job in the insight and logs
+	Queue   string          `json:"queue"`
+	Type    string          `json:"type"`
+	Error   string          `json:"error"`
+	Payload json.RawMessage `json:"payload"`
+	Output  string          `json:"output"` // Optional end of what the job's last run wrote
+	// Metadata is what the job carries apart from its payload, e.g. its correlation ID
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// analyzeReportedJob analyzes the failed job described in the request body and returns the
+// insight without storing it
+func (h *InsightsHandlers) analyzeReportedJob(w http.ResponseWriter, r *http.Request) {
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		http.Error(w, "async analysis needs the job_id of a stored job", http.StatusBadRequest)
+		return
+	}
+	var req AnalyzeJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	job, err := queue.NewJob(req.Queue, req.Type, req.Payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.JobID == "" {
+		req.JobID = r.URL.Query().Get("job_id")
+	}
+	if req.JobID != "" {
+		if job.ID, err = uuid.Parse(req.JobID); err != nil {
+			http.Error(w, "invalid job_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Error == "" {
+		http.Error(w, "error is required", http.StatusBadRequest)
+		return
+	}
+	if err := job.SetMetadata(req.Metadata); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	job.Status = queue.StatusFailed
+	job.Error = req.Error
+
+	if !h.providerAvailable(w) {
+		return
+	}
+	if !h.reserveAnalysis(w, r) {
+		return
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	insight, err := h.insightsService.AnalyzeReportedFailure(context.WithoutCancel(r.Context()), job, req.Output)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to analyze reported job failure",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newInsightResponse(insight))
+}
+
+// providerAvailable answers 503 with a Retry-After header and returns false while the AI
+// provider is degraded, rather than letting a synchronous analysis wait for it to time out
+func (h *InsightsHandlers) providerAvailable(w http.ResponseWriter) bool {
+	var unavailable *appInsights.ProviderUnavailableError
+	if !errors.As(h.insightsService.CheckProvider(), &unavailable) {
+		return true
+	}
+	retryAfter := max(1, int(math.Ceil(unavailable.RetryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":       unavailable.Error(),
+		"retry_after": retryAfter,
+		"hint":        "the AI provider is failing its health probes; retry after retry_after seconds, when it is probed again",
+	})
+	return false
+}
+
+// reserveAnalysis takes an analysis from the caller's quota, answering the request and
+// returning false when it can't
+func (h *InsightsHandlers) reserveAnalysis(w http.ResponseWriter, r *http.Request) bool {
+	if h.quotas == nil {
+		return true
+	}
+	err := h.quotas.ReserveAnalysis(r.Context(), h.tenant(r))
+	if errors.Is(err, quota.ErrQuotaExceeded) {
+		writeQuotaExceeded(w, r, err)
+		return false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// tenant returns the quota tenant of the request's caller
+func (h *InsightsHandlers) tenant(r *http.Request) string {
+	return quota.Tenant(h.owners.Of(r.Header.Get(h.apiKeyHeader)))
+}
+
+// AnalysisStatusResponse reports the state of an asynchronous analysis
+type AnalysisStatusResponse struct {
+	ID          string           `json:"id"`
+	JobID       string           `json:"job_id"`
+	Status      string           `json:"status"`
+	StatusURL   string           `json:"status_url"`
+	Error       string           `json:"error,omitempty"`
+	CallbackURL string           `json:"callback_url,omitempty"`
+	Insight     *InsightResponse `json:"insight,omitempty"`
+	CreatedAt   string           `json:"created_at"`
+	UpdatedAt   string           `json:"updated_at"`
+}
+
+// newAnalysisStatusResponse maps an analysis and its insight, if any, to their API representation;
+// its status URL is in the API version of the request
+func newAnalysisStatusResponse(r *http.Request, analysis *insights.Analysis, insight *insights.Insight) AnalysisStatusResponse {
+	resp := AnalysisStatusResponse{
+		ID:          analysis.ID.String(),
+		JobID:       analysis.JobID.String(),
+		Status:      string(analysis.Status),
+		StatusURL:   apiPath(r, "/insights/analysis/"+analysis.ID.String()),
+		Error:       analysis.Error,
+		CallbackURL: analysis.CallbackURL,
+		CreatedAt:   analysis.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:   analysis.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if insight != nil {
+		insightResp := newInsightResponse(insight)
+		resp.Insight = &insightResp
+	}
+	return resp
+}
+
+// submitAnalysis schedules a background analysis and answers 202 with the URL to poll
+func (h *InsightsHandlers) submitAnalysis(w http.ResponseWriter, r *http.Request, jobID uuid.UUID) {
+	if h.analyzer == nil {
+		http.Error(w, "asynchronous analysis is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	analysis, err := h.analyzer.Submit(r.Context(), jobID, r.URL.Query().Get("callback_url"))
+	switch {
+	case errors.Is(err, queue.ErrJobNotFound):
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, queue.ErrInvalidCallbackURL):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, insights.ErrAnalysisQueueFull):
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to submit analysis",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := newAnalysisStatusResponse(r, analysis, nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", resp.StatusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetAnalysis reports whether an asynchronous analysis is pending, completed or failed,
+// with the generated insight once completed
+func (h *InsightsHandlers) GetAnalysis(w http.ResponseWriter, r *http.Request) {
+	if h.analyzer == nil {
+		http.Error(w, "asynchronous analysis is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Extract ID from path: /api/insights/analysis/{id}
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/insights/analysis/")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "invalid analysis id", http.StatusBadRequest)
+		return
+	}
+
+	analysis, insight, err := h.analyzer.GetAnalysis(r.Context(), id)
+	if errors.Is(err, insights.ErrAnalysisNotFound) {
+		http.Error(w, "analysis not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch analysis",
+			slog.String("analysisId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAnalysisStatusResponse(r, analysis, insight))
+}
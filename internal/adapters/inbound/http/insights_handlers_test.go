@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,6 +19,7 @@ import (
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInsightsHandlers_GetInsightByID(t *testing.T) {
@@ -67,6 +73,32 @@ func TestInsightsHandlers_GetInsightByID(t *testing.T) {
 				assert.Equal(t, "Increase timeout value", resp.Recommendation)
 			},
 		},
+		{
+			name:      "Get insight reused by other jobs",
+			given:     "an insight two jobs failing with the same error fingerprint were linked to",
+			when:      "GET to /api/insights/{id}",
+			then:      "should return 200 with the linked jobs",
+			insightID: testInsightID,
+			setupService: func(id uuid.UUID) *appInsights.Service {
+				insightRepo := &InMemoryInsightRepo{
+					insights: map[uuid.UUID]*insights.Insight{
+						id: {ID: id, JobID: uuid.New(), Diagnosis: "SMTP server down", CreatedAt: time.Now().UTC()},
+					},
+					insightsByJob: map[uuid.UUID]*insights.Insight{},
+				}
+				for _, jobID := range []string{"5a0e6a4e-3d8c-4b3e-9f0a-1c2d3e4f5a6b", "7b1f7b5f-4e9d-4c4f-8a1b-2d3e4f5a6b7c"} {
+					insightRepo.LinkJob(context.Background(), id, uuid.MustParse(jobID))
+				}
+
+				return appInsights.NewService(insightRepo, &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}, &MockAIService{})
+			},
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp InsightResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, []string{"5a0e6a4e-3d8c-4b3e-9f0a-1c2d3e4f5a6b", "7b1f7b5f-4e9d-4c4f-8a1b-2d3e4f5a6b7c"}, resp.LinkedJobs)
+			},
+		},
 		{
 			name:      "Invalid insight ID",
 			given:     "invalid UUID in path",
@@ -345,6 +377,49 @@ func TestInsightsHandlers_ListInsights(t *testing.T) {
 				assert.Equal(t, 0, len(resp))
 			},
 		},
+		{
+			name:        "Filter insights by kind",
+			given:       "a failure insight and a performance insight",
+			when:        "GET to /api/insights?kind=performance",
+			then:        "should return 200 with only the performance insight",
+			queryParams: "?kind=performance",
+			setupService: func() *appInsights.Service {
+				return appInsights.NewService(
+					&InMemoryInsightRepo{
+						insights: map[uuid.UUID]*insights.Insight{},
+						list: []*insights.Insight{
+							{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "SMTP timeout", CreatedAt: time.Now().UTC()},
+							{ID: uuid.New(), JobID: uuid.New(), Kind: insights.KindPerformance, Diagnosis: "Report scans the whole year", CreatedAt: time.Now().UTC()},
+						},
+					},
+					&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+					&MockAIService{},
+				)
+			},
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp []InsightResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, 1, len(resp))
+				assert.Equal(t, "performance", resp[0].Kind)
+				assert.Equal(t, "Report scans the whole year", resp[0].Diagnosis)
+			},
+		},
+		{
+			name:        "Invalid kind",
+			given:       "an unknown kind",
+			when:        "GET to /api/insights?kind=latency",
+			then:        "should return 400",
+			queryParams: "?kind=latency",
+			setupService: func() *appInsights.Service {
+				return appInsights.NewService(
+					&InMemoryInsightRepo{insights: map[uuid.UUID]*insights.Insight{}},
+					&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+					&MockAIService{},
+				)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -375,6 +450,7 @@ func TestInsightsHandlers_AnalyzeJob(t *testing.T) {
 		when           string
 		then           string
 		jobID          string
+		body           string
 		setupService   func(uuid.UUID) *appInsights.Service
 		expectedStatus int
 		validateResp   func(*testing.T, *httptest.ResponseRecorder)
@@ -456,6 +532,100 @@ func TestInsightsHandlers_AnalyzeJob(t *testing.T) {
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:  "Analyze reported job failure",
+			given: "standalone service and a failed job in the body",
+			when:  "POST to /api/insights/analyze",
+			then:  "should return 200 with the insight",
+			body:  `{"queue":"default","type":"email","error":"Connection timeout","payload":{"to":"test@example.com"}}`,
+			setupService: func(jobID uuid.UUID) *appInsights.Service {
+				return appInsights.NewService(nil, nil, &MockAIService{
+					response: &insights.AnalysisResponse{
+						Diagnosis:      "Network timeout issue",
+						Recommendation: "Increase timeout to 30s",
+					},
+				})
+			},
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp InsightResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NotEmpty(t, resp.JobID)
+				assert.Equal(t, "Network timeout issue", resp.Diagnosis)
+			},
+		},
+		{
+			name:  "Reported job failure without error",
+			given: "a job in the body without its error",
+			when:  "POST to /api/insights/analyze",
+			then:  "should return 400 bad request",
+			body:  `{"queue":"default","type":"email"}`,
+			setupService: func(jobID uuid.UUID) *appInsights.Service {
+				return appInsights.NewService(nil, nil, &MockAIService{})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "Reported job failure without type",
+			given: "a failed job in the body without its type",
+			when:  "POST to /api/insights/analyze",
+			then:  "should return 400 bad request",
+			body:  `{"queue":"default","error":"Connection timeout"}`,
+			setupService: func(jobID uuid.UUID) *appInsights.Service {
+				return appInsights.NewService(nil, nil, &MockAIService{})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "Standalone service given a job_id and the failed job",
+			given: "standalone service and a failed job in the body",
+			when:  "POST to /api/insights/analyze?job_id={id}",
+			then:  "should return 200 with the insight of the job in the body",
+			jobID: "0b1f6c2e-3a4d-4e5f-8a9b-1c2d3e4f5a6b",
+			body:  `{"queue":"default","type":"email","error":"Connection timeout"}`,
+			setupService: func(jobID uuid.UUID) *appInsights.Service {
+				return appInsights.NewService(nil, nil, &MockAIService{
+					response: &insights.AnalysisResponse{Diagnosis: "Network timeout issue"},
+				})
+			},
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp InsightResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "0b1f6c2e-3a4d-4e5f-8a9b-1c2d3e4f5a6b", resp.JobID)
+			},
+		},
+		{
+			name:  "AI provider degraded",
+			given: "a failed job in the body while the AI provider fails its health probes",
+			when:  "POST to /api/insights/analyze",
+			then:  "should return 503 with Retry-After without calling the provider",
+			body:  `{"queue":"default","type":"email","error":"Connection timeout"}`,
+			setupService: func(jobID uuid.UUID) *appInsights.Service {
+				service := appInsights.NewService(nil, nil, &MockAIService{})
+				degradeProvider(service)
+				return service
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+				var resp map[string]any
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Contains(t, resp["error"], "connection refused")
+				assert.NotZero(t, resp["retry_after"])
+			},
+		},
+		{
+			name:  "Standalone service given a job_id",
+			given: "standalone service without the jobs table",
+			when:  "POST to /api/insights/analyze?job_id={id}",
+			then:  "should return 400 bad request",
+			jobID: uuid.New().String(),
+			setupService: func(jobID uuid.UUID) *appInsights.Service {
+				return appInsights.NewService(nil, nil, &MockAIService{})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -473,7 +643,7 @@ func TestInsightsHandlers_AnalyzeJob(t *testing.T) {
 				url += "?job_id=" + tt.jobID
 			}
 
-			req := httptest.NewRequest(http.MethodPost, url, bytes.NewBuffer([]byte{}))
+			req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(tt.body))
 			rec := httptest.NewRecorder()
 
 			// When
@@ -488,11 +658,705 @@ func TestInsightsHandlers_AnalyzeJob(t *testing.T) {
 	}
 }
 
+func TestInsightsHandlers_GetUsage(t *testing.T) {
+	now := time.Now().UTC()
+	usageRepo := func() *InMemoryInsightRepo {
+		return &InMemoryInsightRepo{
+			insights: map[uuid.UUID]*insights.Insight{},
+			list: []*insights.Insight{
+				{ID: uuid.New(), Diagnosis: "a", CreatedAt: now, Usage: &insights.Usage{Provider: "ollama", Model: "phi3:mini", PromptTokens: 100, CompletionTokens: 50, PromptBytes: 800, LatencyMs: 1000}},
+				{ID: uuid.New(), Diagnosis: "b", CreatedAt: now, Usage: &insights.Usage{Provider: "ollama", Model: "phi3:mini", PromptTokens: 200, CompletionTokens: 70, PromptBytes: 1200, LatencyMs: 3000}},
+				{ID: uuid.New(), Diagnosis: "c", CreatedAt: now, Usage: &insights.Usage{Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 300, CompletionTokens: 30, PromptBytes: 1500, LatencyMs: 500}},
+				{ID: uuid.New(), Diagnosis: "no usage", CreatedAt: now},
+				{ID: uuid.New(), Diagnosis: "too old", CreatedAt: now.AddDate(0, 0, -60), Usage: &insights.Usage{Provider: "ollama", PromptTokens: 999}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		queryParams    string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Aggregate usage per day and provider",
+			given:          "insights with usage from two providers",
+			when:           "GET to /api/insights/usage",
+			then:           "should return daily rows, per-provider totals and grand totals for the last 30 days",
+			queryParams:    "",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp UsageResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, 30, resp.Days)
+				assert.Len(t, resp.Usage, 2)
+				assert.Equal(t, now.Format("2006-01-02"), resp.Usage[0].Day)
+				assert.Equal(t, int64(2), resp.ByProvider["ollama"].Calls)
+				assert.Equal(t, int64(420), resp.ByProvider["ollama"].TotalTokens)
+				assert.Equal(t, int64(1), resp.ByProvider["openai"].Calls)
+				assert.Equal(t, int64(3), resp.Totals.Calls)
+				assert.Equal(t, int64(750), resp.Totals.TotalTokens)
+				for _, entry := range resp.Usage {
+					if entry.Provider == "ollama" {
+						assert.Equal(t, 2000.0, entry.AvgLatencyMs)
+					}
+				}
+			},
+		},
+		{
+			name:           "Reject out of range window",
+			given:          "days above the maximum",
+			when:           "GET to /api/insights/usage?days=400",
+			then:           "should return 400",
+			queryParams:    "?days=400",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appInsights.NewService(
+				usageRepo(),
+				&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+				&MockAIService{},
+			)
+			handlers := NewInsightsHandlers(service)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/insights/usage"+tt.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetUsage(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
+
+func TestInsightsHandlers_ApplyInsightFix(t *testing.T) {
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		query           string
+		knownInsight    bool
+		expectedStatus  int
+		expectedApplied bool
+	}{
+		{
+			name:            "Dry run returns diff without changing the job",
+			given:           "an insight suggesting a timeout patch and retries",
+			when:            "POST to /api/insights/{id}/apply?dry_run=true",
+			then:            "should return the diff and retry plan and leave the job untouched",
+			query:           "?dry_run=true",
+			knownInsight:    true,
+			expectedStatus:  http.StatusOK,
+			expectedApplied: false,
+		},
+		{
+			name:            "Apply fix updates the job and records an audit entry",
+			given:           "an insight suggesting a timeout patch and retries",
+			when:            "POST to /api/insights/{id}/apply",
+			then:            "should patch the payload, record the application and enqueue the job to be retried",
+			query:           "",
+			knownInsight:    true,
+			expectedStatus:  http.StatusOK,
+			expectedApplied: true,
+		},
+		{
+			name:           "Unknown insight",
+			given:          "an insight ID that does not exist",
+			when:           "POST to /api/insights/{id}/apply",
+			then:           "should return 404",
+			query:          "",
+			knownInsight:   false,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job := &queue.Job{
+				ID:      uuid.New(),
+				Queue:   "default",
+				Type:    "http",
+				Status:  queue.StatusFailed,
+				Payload: []byte(`{"url":"http://api","timeout":5}`),
+			}
+			insight := &insights.Insight{
+				ID:    uuid.New(),
+				JobID: job.ID,
+				SuggestedFix: insights.SuggestedFix{
+					MaxRetries:   3,
+					PayloadPatch: map[string]any{"timeout": 30},
+				},
+			}
+			insightRepo := &InMemoryInsightRepo{insights: map[uuid.UUID]*insights.Insight{}}
+			if tt.knownInsight {
+				insightRepo.insights[insight.ID] = insight
+			}
+			jobRepo := &InMemoryJobRepo{jobs: map[uuid.UUID]*queue.Job{job.ID: job}}
+			queueSvc := &InMemoryQueueSvc{}
+			service := appInsights.NewService(insightRepo, jobRepo, &MockAIService{})
+			service.SetQueueService(queueSvc)
+			handlers := NewInsightsHandlers(service)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/insights/"+insight.ID.String()+"/apply"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.ApplyInsightFix(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp ApplyFixResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedApplied, resp.Applied)
+			assert.Equal(t, []insights.PayloadChange{{Op: insights.ChangeReplace, Key: "timeout", Before: 5.0, After: 30.0}}, resp.Payload.Diff)
+			assert.True(t, resp.RetryPlan.WillRetry)
+			assert.Equal(t, "retrying", resp.RetryPlan.StatusAfter)
+
+			assert.Equal(t, tt.expectedApplied, resp.Enqueued)
+			if tt.expectedApplied {
+				assert.Equal(t, queue.StatusRetrying, job.Status)
+				assert.JSONEq(t, `{"url":"http://api","timeout":30}`, string(job.Payload))
+				assert.Len(t, insightRepo.applications, 1)
+				require.NotNil(t, resp.Job)
+				assert.Equal(t, "retrying", resp.Job.Status)
+				assert.Equal(t, map[string]any{"url": "http://api", "timeout": 30.0}, resp.Job.Payload)
+				require.Len(t, queueSvc.jobs, 1)
+				assert.Equal(t, job.ID, queueSvc.jobs[0].ID)
+			} else {
+				assert.Equal(t, queue.StatusFailed, job.Status)
+				assert.JSONEq(t, `{"url":"http://api","timeout":5}`, string(job.Payload))
+				assert.Empty(t, insightRepo.applications)
+				assert.Nil(t, resp.Job)
+				assert.Empty(t, queueSvc.jobs)
+			}
+		})
+	}
+}
+
+func TestInsightsHandlers_GetInsightReport(t *testing.T) {
+	job := &queue.Job{
+		ID:       uuid.New(),
+		Queue:    "default",
+		Type:     "http",
+		Status:   queue.StatusFailed,
+		Attempts: 1,
+		Payload:  []byte(`{"url":"http://api","timeout":5}`),
+		Error:    "context deadline exceeded",
+	}
+	insight := &insights.Insight{
+		ID:             uuid.New(),
+		JobID:          job.ID,
+		Kind:           insights.KindFailure,
+		Diagnosis:      "The API answered after the <b>5s</b> timeout",
+		Recommendation: "Raise the timeout to 30s",
+		SuggestedFix:   insights.SuggestedFix{PayloadPatch: map[string]any{"timeout": 30}},
+		Confidence:     0.9,
+		Triage:         insights.TriageAutoRetryable,
+	}
+	orphan := &insights.Insight{ID: uuid.New(), JobID: uuid.New(), Kind: insights.KindFailure, Diagnosis: "Gone"}
+	applied := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		insightID      string
+		query          string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Markdown report",
+			given:          "an insight whose fix was applied to its job",
+			when:           "GET to /api/insights/{id}/report",
+			then:           "should return markdown with the job, diagnosis, recommendation and applied fix",
+			insightID:      insight.ID.String(),
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get("Content-Type"))
+				body := rec.Body.String()
+				assert.Contains(t, body, "# Incident report: http job "+job.ID.String())
+				assert.Contains(t, body, "context deadline exceeded")
+				assert.Contains(t, body, "The API answered after the &lt;b&gt;5s&lt;/b&gt; timeout")
+				assert.Contains(t, body, "Raise the timeout to 30s")
+				assert.Contains(t, body, "- **Triage:** auto-retryable")
+				assert.Contains(t, body, "| 2026-10-01 12:00:00 UTC | `"+job.ID.String()+"` | payload\\_patch | failed → retrying | pending | - |")
+			},
+		},
+		{
+			name:           "HTML report",
+			given:          "an insight whose fix was applied to its job",
+			when:           "GET to /api/insights/{id}/report?format=html",
+			then:           "should return the report rendered into an escaped, printable page",
+			insightID:      insight.ID.String(),
+			query:          "?format=html",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+				assert.Equal(t, reportContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+				body := rec.Body.String()
+				assert.Contains(t, body, "<h1>Incident report: http job "+job.ID.String()+"</h1>")
+				assert.Contains(t, body, "The API answered after the &lt;b&gt;5s&lt;/b&gt; timeout")
+				assert.Contains(t, body, "<td>payload_patch</td>")
+			},
+		},
+		{
+			name:           "Job deleted",
+			given:          "an insight whose job no longer exists",
+			when:           "GET to /api/insights/{id}/report",
+			then:           "should return the report without the job",
+			insightID:      orphan.ID.String(),
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Contains(t, rec.Body.String(), "Job `"+orphan.JobID.String()+"` no longer exists.")
+			},
+		},
+		{
+			name:           "Unknown format",
+			given:          "a format other than markdown or html",
+			when:           "GET to /api/insights/{id}/report?format=pdf",
+			then:           "should return 400",
+			insightID:      insight.ID.String(),
+			query:          "?format=pdf",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown insight",
+			given:          "an insight ID that does not exist",
+			when:           "GET to /api/insights/{id}/report",
+			then:           "should return 404",
+			insightID:      uuid.New().String(),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid insight ID",
+			given:          "an ID that is not a UUID",
+			when:           "GET to /api/insights/{id}/report",
+			then:           "should return 400",
+			insightID:      "not-a-uuid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insightRepo := &InMemoryInsightRepo{insights: map[uuid.UUID]*insights.Insight{insight.ID: insight, orphan.ID: orphan}}
+			plan, err := insight.PlanFix(job)
+			require.NoError(t, err)
+			plan.StatusAfter = queue.StatusRetrying
+			application := insights.NewFixApplication(plan)
+			application.AppliedAt = applied
+			require.NoError(t, insightRepo.RecordFixApplication(context.Background(), application))
+			jobRepo := &InMemoryJobRepo{jobs: map[uuid.UUID]*queue.Job{job.ID: job}}
+			handlers := NewInsightsHandlers(appInsights.NewService(insightRepo, jobRepo, &MockAIService{}))
+
+			mux := http.NewServeMux()
+			RegisterInsightsRoutes(mux, handlers)
+			req := httptest.NewRequest(http.MethodGet, "/api/insights/"+tt.insightID+"/report"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
+
+func TestInsightsHandlers_GetFixEffectiveness(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		query          string
+		expectedStatus int
+		expectedStats  []FixEffectivenessEntry
+	}{
+		{
+			name:           "Effectiveness per job type and fix kind",
+			given:          "timeout fixes applied to smtp jobs that then succeeded, failed or didn't run yet",
+			when:           "GET /api/insights/effectiveness?job_type=smtp",
+			then:           "should rate the fixes whose job ran again",
+			query:          "?job_type=smtp",
+			expectedStatus: http.StatusOK,
+			expectedStats: []FixEffectivenessEntry{{
+				JobType: "smtp", FixKind: insights.FixKindTimeout, Applied: 4, Succeeded: 2, Failed: 1, Pending: 1,
+				SuccessRate: 2.0 / 3.0, Summary: "timeout increase fixed 67% of smtp failures (2 of 3 retries)",
+			}},
+		},
+		{
+			name:           "Invalid window",
+			given:          "a window longer than a year",
+			when:           "GET /api/insights/effectiveness?days=400",
+			then:           "should return 400",
+			query:          "?days=400",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insightRepo := &InMemoryInsightRepo{}
+			for _, outcome := range []insights.FixOutcome{
+				insights.FixOutcomeSucceeded, insights.FixOutcomeSucceeded, insights.FixOutcomeFailed, insights.FixOutcomePending,
+			} {
+				insightRepo.applications = append(insightRepo.applications, &insights.FixApplication{
+					JobType: "smtp", FixKind: insights.FixKindTimeout, Outcome: outcome, AppliedAt: time.Now().UTC(),
+				})
+			}
+			insightRepo.applications = append(insightRepo.applications, &insights.FixApplication{
+				JobType: "http", FixKind: insights.FixKindRetries, Outcome: insights.FixOutcomeFailed, AppliedAt: time.Now().UTC(),
+			})
+			handlers := NewInsightsHandlers(appInsights.NewService(insightRepo, &InMemoryJobRepo{}, &MockAIService{}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/insights/effectiveness"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetFixEffectiveness(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp FixEffectivenessResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, defaultEffectivenessDays, resp.Days)
+			assert.Equal(t, tt.expectedStats, resp.Effectiveness)
+		})
+	}
+}
+
+func TestInsightsHandlers_GetQueueSummary(t *testing.T) {
+	tests := []struct {
+		name               string
+		given              string
+		when               string
+		then               string
+		query              string
+		aiErr              error
+		expectedStatus     int
+		expectedDiagnoses  []RecurringDiagnosisEntry
+		expectedExecutive  *ExecutiveSummaryResponse
+		expectedSummaryErr string
+	}{
+		{
+			name:           "Summary with executive summary",
+			given:          "three analyzed emails jobs, two failing on the same SMTP timeout",
+			when:           "GET /api/insights/summary?queue=emails",
+			then:           "should put the recurring diagnosis first and add the AI's executive summary",
+			query:          "?queue=emails",
+			expectedStatus: http.StatusOK,
+			expectedDiagnoses: []RecurringDiagnosisEntry{
+				{Diagnosis: "SMTP timeout after 30s", Count: 2, JobTypes: []string{"send-email"}, AvgConfidence: 0.8},
+				{Diagnosis: "Invalid recipient", Count: 1, JobTypes: []string{"newsletter"}, AvgConfidence: 0.5},
+			},
+			expectedExecutive: &ExecutiveSummaryResponse{
+				Summary: "The SMTP relay is too slow", Recommendation: "Raise the relay timeout", Confidence: 0.7,
+			},
+		},
+		{
+			name:               "AI failure",
+			given:              "an AI that fails",
+			when:               "GET /api/insights/summary?queue=emails",
+			then:               "should still return the aggregates with the reason there is no executive summary",
+			query:              "?queue=emails",
+			aiErr:              errors.New("provider unavailable"),
+			expectedStatus:     http.StatusOK,
+			expectedSummaryErr: "provider unavailable",
+		},
+		{
+			name:           "Missing queue",
+			given:          "no queue in the query string",
+			when:           "GET /api/insights/summary",
+			then:           "should return 400",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid ai flag",
+			given:          "an ai flag that isn't a boolean",
+			when:           "GET /api/insights/summary?queue=emails&ai=maybe",
+			then:           "should return 400",
+			query:          "?queue=emails&ai=maybe",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			now := time.Now().UTC()
+			insightRepo := &InMemoryInsightRepo{byQueue: map[string][]*insights.QueueInsight{
+				"emails": {
+					{JobType: "send-email", Insight: &insights.Insight{Diagnosis: "SMTP timeout after 30s", Confidence: 0.9,
+						SuggestedFix: insights.SuggestedFix{TimeoutSeconds: 60}, CreatedAt: now}},
+					{JobType: "newsletter", Insight: &insights.Insight{Diagnosis: "Invalid recipient", Confidence: 0.5, CreatedAt: now}},
+					{JobType: "send-email", Insight: &insights.Insight{Diagnosis: "SMTP timeout after 45s", Confidence: 0.7,
+						SuggestedFix: insights.SuggestedFix{TimeoutSeconds: 90}, CreatedAt: now.Add(-time.Minute)}},
+				},
+			}}
+			aiService := &MockAIService{err: tt.aiErr, response: &insights.AnalysisResponse{
+				Diagnosis: "The SMTP relay is too slow", Recommendation: "Raise the relay timeout", Confidence: 0.7,
+			}}
+			handlers := NewInsightsHandlers(appInsights.NewService(insightRepo, &InMemoryJobRepo{}, aiService))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/insights/summary"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetQueueSummary(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp QueueSummaryResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "emails", resp.Queue)
+			assert.Equal(t, insights.DefaultSummaryDays, resp.Days)
+			assert.Equal(t, 3, resp.Insights)
+			for i, want := range tt.expectedDiagnoses {
+				got := resp.RecurringDiagnoses[i]
+				assert.Equal(t, want.Diagnosis, got.Diagnosis)
+				assert.Equal(t, want.Count, got.Count)
+				assert.Equal(t, want.JobTypes, got.JobTypes)
+				assert.InDelta(t, want.AvgConfidence, got.AvgConfidence, 0.001)
+			}
+			assert.Equal(t, insights.FixKindTimeout, resp.CommonFixes[0].Kind)
+			assert.Equal(t, 2, resp.CommonFixes[0].Count)
+			assert.Equal(t, tt.expectedExecutive, resp.ExecutiveSummary)
+			assert.Equal(t, tt.expectedSummaryErr, resp.SummaryError)
+		})
+	}
+}
+
 // In-memory implementations for testing
+// wordEmbedder embeds a text as the counts of the words of a fixed vocabulary it contains
+type wordEmbedder []string
+
+func (e wordEmbedder) Embed(ctx context.Context, texts []string) (*insights.Embeddings, error) {
+	embeddings := &insights.Embeddings{Model: "words"}
+	for _, text := range texts {
+		vector := make([]float64, len(e))
+		for i, word := range e {
+			vector[i] = float64(strings.Count(strings.ToLower(text), word))
+		}
+		embeddings.Vectors = append(embeddings.Vectors, vector)
+	}
+	return embeddings, nil
+}
+
+func TestInsightsHandlers_GetInsightClusters(t *testing.T) {
+	tests := []struct {
+		name             string
+		given            string
+		when             string
+		then             string
+		enabled          bool
+		computed         bool
+		expectedStatus   int
+		expectedClusters []InsightClusterEntry
+	}{
+		{
+			name:           "Clusters computed",
+			given:          "SMTP timeouts worded differently across two queues and a one-off failure",
+			when:           "GET /api/insights/clusters after a clustering run",
+			then:           "should return the SMTP timeouts as one cluster with its job types",
+			enabled:        true,
+			computed:       true,
+			expectedStatus: http.StatusOK,
+			expectedClusters: []InsightClusterEntry{
+				{Members: 3, JobTypes: []string{"digest", "send-email"}, AvgConfidence: 0.7},
+			},
+		},
+		{
+			name:           "Clusters not computed yet",
+			given:          "clustering enabled but not run yet",
+			when:           "GET /api/insights/clusters",
+			then:           "should return 503",
+			enabled:        true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Clustering disabled",
+			given:          "clustering not enabled",
+			when:           "GET /api/insights/clusters",
+			then:           "should return 503",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			now := time.Now().UTC()
+			insightRepo := &InMemoryInsightRepo{byQueue: map[string][]*insights.QueueInsight{
+				"emails": {
+					{JobType: "send-email", Insight: &insights.Insight{ID: uuid.New(), Diagnosis: "SMTP relay timeout", Confidence: 0.9, CreatedAt: now}},
+					{JobType: "send-email", Insight: &insights.Insight{ID: uuid.New(), Diagnosis: "The SMTP relay hit a timeout", Confidence: 0.6, CreatedAt: now}},
+					{JobType: "newsletter", Insight: &insights.Insight{ID: uuid.New(), Diagnosis: "Invalid recipient", Confidence: 0.5, CreatedAt: now}},
+				},
+				"reports": {
+					{JobType: "digest", Insight: &insights.Insight{ID: uuid.New(), Diagnosis: "SMTP timeout", Confidence: 0.6, CreatedAt: now}},
+				},
+			}}
+			service := appInsights.NewService(insightRepo, &InMemoryJobRepo{}, &MockAIService{})
+			if tt.enabled {
+				embedder := wordEmbedder{"smtp", "timeout", "recipient", "invalid"}
+				assert.NoError(t, service.SetInsightClustering(embedder, insights.DefaultClusterConfig()))
+			}
+			if tt.computed {
+				_, err := service.ClusterInsights(context.Background())
+				assert.NoError(t, err)
+			}
+			handlers := NewInsightsHandlers(service)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/insights/clusters", nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetInsightClusters(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp InsightClustersResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "words", resp.Model)
+			assert.Equal(t, 4, resp.Insights)
+			assert.Equal(t, 1, resp.Unclustered)
+			assert.Len(t, resp.Clusters, len(tt.expectedClusters))
+			for i, want := range tt.expectedClusters {
+				got := resp.Clusters[i]
+				assert.Equal(t, want.Members, got.Members)
+				assert.Equal(t, want.JobTypes, got.JobTypes)
+				assert.InDelta(t, want.AvgConfidence, got.AvgConfidence, 0.001)
+				assert.Contains(t, got.Representative.Diagnosis, "SMTP")
+				assert.Len(t, got.InsightIDs, want.Members)
+			}
+		})
+	}
+}
+
+func TestInsightsHandlers_GetProviderHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		enabled        bool
+		degraded       bool
+		expectedStatus int
+		validateResp   func(*testing.T, ProviderHealthResponse)
+	}{
+		{
+			name:           "Provider degraded",
+			given:          "an AI provider that failed as many probes as the threshold",
+			when:           "GET /api/insights/provider-health",
+			then:           "should return its failures, downtime and when to retry",
+			enabled:        true,
+			degraded:       true,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, resp ProviderHealthResponse) {
+				assert.Equal(t, "degraded", resp.State)
+				assert.Equal(t, 3, resp.ConsecutiveFailures)
+				assert.Contains(t, resp.LastError, "connection refused")
+				assert.NotEmpty(t, resp.FailingSince)
+				assert.Positive(t, resp.RetryAfter)
+				assert.Equal(t, 1, resp.Outages)
+			},
+		},
+		{
+			name:           "Provider not probed yet",
+			given:          "health checks enabled but not run yet",
+			when:           "GET /api/insights/provider-health",
+			then:           "should return a healthy provider",
+			enabled:        true,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, resp ProviderHealthResponse) {
+				assert.Equal(t, ProviderHealthResponse{State: "healthy"}, resp)
+			},
+		},
+		{
+			name:           "Health checks disabled",
+			given:          "health checks not enabled",
+			when:           "GET /api/insights/provider-health",
+			then:           "should return 503",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appInsights.NewService(nil, nil, &MockAIService{})
+			if tt.enabled {
+				assert.NoError(t, service.SetProviderHealth(FailingProber{}, insights.DefaultProviderHealthConfig()))
+			}
+			if tt.degraded {
+				degradeProvider(service)
+			}
+			handlers := NewInsightsHandlers(service)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/insights/provider-health", nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetProviderHealth(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				var resp ProviderHealthResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				tt.validateResp(t, resp)
+			}
+		})
+	}
+}
+
 type InMemoryInsightRepo struct {
 	insights      map[uuid.UUID]*insights.Insight
 	insightsByJob map[uuid.UUID]*insights.Insight
 	list          []*insights.Insight
+	dlq           []*insights.JobWithInsight
+	applications  []*insights.FixApplication
+	byQueue       map[string][]*insights.QueueInsight
+	linked        map[uuid.UUID][]uuid.UUID
 }
 
 func (r *InMemoryInsightRepo) Create(ctx context.Context, insight *insights.Insight) error {
@@ -516,15 +1380,32 @@ func (r *InMemoryInsightRepo) GetByJobID(ctx context.Context, jobID uuid.UUID) (
 	return nil, insights.ErrInsightNotFound
 }
 
-func (r *InMemoryInsightRepo) List(ctx context.Context, limit, offset int) ([]*insights.Insight, error) {
-	if offset >= len(r.list) {
+func (r *InMemoryInsightRepo) List(ctx context.Context, kind insights.Kind, limit, offset int) ([]*insights.Insight, error) {
+	list := r.list
+	if kind != "" {
+		list = nil
+		for _, insight := range r.list {
+			if insights.KindOf(insight) == kind {
+				list = append(list, insight)
+			}
+		}
+	}
+	if offset >= len(list) {
 		return []*insights.Insight{}, nil
 	}
 	end := offset + limit
-	if end > len(r.list) {
-		end = len(r.list)
+	if end > len(list) {
+		end = len(list)
 	}
-	return r.list[offset:end], nil
+	return list[offset:end], nil
+}
+
+func (r *InMemoryInsightRepo) Update(ctx context.Context, insight *insights.Insight) error {
+	if _, ok := r.insights[insight.ID]; !ok {
+		return insights.ErrInsightNotFound
+	}
+	r.insights[insight.ID] = insight
+	return nil
 }
 
 func (r *InMemoryInsightRepo) Delete(ctx context.Context, id uuid.UUID) error {
@@ -532,6 +1413,144 @@ func (r *InMemoryInsightRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (r *InMemoryInsightRepo) ListDLQWithInsights(ctx context.Context, limit, offset int, order string) ([]*insights.JobWithInsight, error) {
+	if offset >= len(r.dlq) {
+		return []*insights.JobWithInsight{}, nil
+	}
+	dlq := slices.Clone(r.dlq)
+	if order == insights.DLQOrderActionability {
+		slices.SortStableFunc(dlq, func(a, b *insights.JobWithInsight) int {
+			return triageRank(a) - triageRank(b)
+		})
+	}
+	end := offset + limit
+	if end > len(dlq) {
+		end = len(dlq)
+	}
+	return dlq[offset:end], nil
+}
+
+func triageRank(entry *insights.JobWithInsight) int {
+	if entry.Insight == nil {
+		return insights.TriageLabel("").Rank()
+	}
+	return entry.Insight.Triage.Rank()
+}
+
+func (r *InMemoryInsightRepo) RecordFixApplication(ctx context.Context, application *insights.FixApplication) error {
+	r.applications = append(r.applications, application)
+	return nil
+}
+
+func (r *InMemoryInsightRepo) FixApplications(ctx context.Context, insightID uuid.UUID) ([]*insights.FixApplication, error) {
+	var applications []*insights.FixApplication
+	for _, application := range r.applications {
+		if application.InsightID == insightID {
+			applications = append(applications, application)
+		}
+	}
+	return applications, nil
+}
+
+func (r *InMemoryInsightRepo) RecordFixOutcome(ctx context.Context, jobID uuid.UUID, outcome insights.FixOutcome, at time.Time) error {
+	for _, application := range r.applications {
+		if application.JobID == jobID && application.Outcome == insights.FixOutcomePending {
+			application.Outcome = outcome
+			application.ResolvedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryInsightRepo) FixEffectiveness(ctx context.Context, jobType string, since time.Time) ([]*insights.FixEffectiveness, error) {
+	type fixKey struct{ jobType, fixKind string }
+	byKey := map[fixKey]*insights.FixEffectiveness{}
+	var effectiveness []*insights.FixEffectiveness
+	for _, application := range r.applications {
+		if application.AppliedAt.Before(since) || (jobType != "" && application.JobType != jobType) {
+			continue
+		}
+		key := fixKey{application.JobType, application.FixKind}
+		stat, ok := byKey[key]
+		if !ok {
+			stat = &insights.FixEffectiveness{JobType: key.jobType, FixKind: key.fixKind}
+			byKey[key] = stat
+			effectiveness = append(effectiveness, stat)
+		}
+		stat.Applied++
+		switch application.Outcome {
+		case insights.FixOutcomeSucceeded:
+			stat.Succeeded++
+		case insights.FixOutcomeFailed:
+			stat.Failed++
+		}
+	}
+	return effectiveness, nil
+}
+
+func (r *InMemoryInsightRepo) ListByQueue(ctx context.Context, queueName string, since time.Time, limit int) ([]*insights.QueueInsight, error) {
+	listed := r.byQueue[queueName]
+	if queueName == "" {
+		for _, name := range slices.Sorted(maps.Keys(r.byQueue)) {
+			listed = append(listed, r.byQueue[name]...)
+		}
+	}
+	var entries []*insights.QueueInsight
+	for _, entry := range listed {
+		if entry.Insight.CreatedAt.Before(since) || len(entries) == limit {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *InMemoryInsightRepo) FindByFingerprint(ctx context.Context, jobType, fingerprint string, since time.Time) (*insights.Insight, error) {
+	return nil, insights.ErrInsightNotFound
+}
+
+func (r *InMemoryInsightRepo) LinkJob(ctx context.Context, insightID, jobID uuid.UUID) error {
+	if r.linked == nil {
+		r.linked = map[uuid.UUID][]uuid.UUID{}
+	}
+	r.linked[insightID] = append(r.linked[insightID], jobID)
+	r.insightsByJob[jobID] = r.insights[insightID]
+	return nil
+}
+
+func (r *InMemoryInsightRepo) LinkedJobs(ctx context.Context, insightID uuid.UUID) ([]uuid.UUID, error) {
+	return r.linked[insightID], nil
+}
+
+func (r *InMemoryInsightRepo) UsageSummary(ctx context.Context, since time.Time) ([]*insights.UsageAggregate, error) {
+	type usageKey struct {
+		day             time.Time
+		provider, model string
+	}
+	byKey := map[usageKey]*insights.UsageAggregate{}
+	latency := map[usageKey]int64{}
+	var summary []*insights.UsageAggregate
+	for _, insight := range r.list {
+		if insight.Usage == nil || insight.CreatedAt.Before(since) {
+			continue
+		}
+		key := usageKey{insight.CreatedAt.UTC().Truncate(24 * time.Hour), insight.Usage.Provider, insight.Usage.Model}
+		agg, ok := byKey[key]
+		if !ok {
+			agg = &insights.UsageAggregate{Day: key.day, Provider: key.provider, Model: key.model}
+			byKey[key] = agg
+			summary = append(summary, agg)
+		}
+		agg.Calls++
+		agg.PromptTokens += int64(insight.Usage.PromptTokens)
+		agg.CompletionTokens += int64(insight.Usage.CompletionTokens)
+		agg.PromptBytes += int64(insight.Usage.PromptBytes)
+		latency[key] += insight.Usage.LatencyMs
+		agg.AvgLatencyMs = float64(latency[key]) / float64(agg.Calls)
+	}
+	return summary, nil
+}
+
 type MockAIService struct {
 	response *insights.AnalysisResponse
 	err      error
@@ -553,3 +1572,248 @@ func (m *MockAIService) Analyze(ctx context.Context, request *insights.AnalysisR
 		},
 	}, nil
 }
+
+// FailingProber fails every AI provider health probe
+type FailingProber struct{}
+
+func (FailingProber) Probe(ctx context.Context) error {
+	return errors.New("dial tcp 127.0.0.1:11434: connection refused")
+}
+
+// degradeProvider probes a failing AI provider until the service reports it degraded
+func degradeProvider(service *appInsights.Service) {
+	cfg := insights.DefaultProviderHealthConfig()
+	if err := service.SetProviderHealth(FailingProber{}, cfg); err != nil {
+		panic(err)
+	}
+	for range cfg.FailAfter {
+		service.ProbeProvider(context.Background())
+	}
+}
+
+// InMemoryAnalysisRepo keeps asynchronous analyses in memory
+type InMemoryAnalysisRepo struct {
+	mu       sync.Mutex
+	analyses map[uuid.UUID]insights.Analysis
+}
+
+func (r *InMemoryAnalysisRepo) Create(ctx context.Context, analysis *insights.Analysis) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.analyses[analysis.ID] = *analysis
+	return nil
+}
+
+func (r *InMemoryAnalysisRepo) GetByID(ctx context.Context, id uuid.UUID) (*insights.Analysis, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	analysis, ok := r.analyses[id]
+	if !ok {
+		return nil, insights.ErrAnalysisNotFound
+	}
+	return &analysis, nil
+}
+
+func (r *InMemoryAnalysisRepo) Update(ctx context.Context, analysis *insights.Analysis) error {
+	return r.Create(ctx, analysis)
+}
+
+func (r *InMemoryAnalysisRepo) ListPending(ctx context.Context, updatedBefore time.Time, limit int) ([]*insights.Analysis, error) {
+	return nil, nil
+}
+
+func TestInsightsHandlers_EditInsight(t *testing.T) {
+	tests := []struct {
+		name                   string
+		given                  string
+		when                   string
+		then                   string
+		body                   string
+		knownInsight           bool
+		expectedStatus         int
+		expectedRecommendation string
+		expectedAI             string
+		expectedNote           string
+	}{
+		{
+			name:                   "Replace the recommendation",
+			given:                  "an insight written by the AI",
+			when:                   "PATCH to /api/insights/{id} with a recommendation and edited_by",
+			then:                   "should return the edited insight with the AI's text as ai_recommendation",
+			body:                   `{"recommendation":"Raise the gateway timeout to 60s","edited_by":"ana"}`,
+			knownInsight:           true,
+			expectedStatus:         http.StatusOK,
+			expectedRecommendation: "Raise the gateway timeout to 60s",
+			expectedAI:             "Increase timeout",
+		},
+		{
+			name:                   "Annotate only",
+			given:                  "an insight written by the AI",
+			when:                   "PATCH to /api/insights/{id} with a note",
+			then:                   "should keep the recommendation and add the note",
+			body:                   `{"note":"Known issue with the sandbox gateway","edited_by":"ana"}`,
+			knownInsight:           true,
+			expectedStatus:         http.StatusOK,
+			expectedRecommendation: "Increase timeout",
+			expectedNote:           "Known issue with the sandbox gateway",
+		},
+		{
+			name:           "Missing editor",
+			given:          "an insight written by the AI",
+			when:           "PATCH to /api/insights/{id} without edited_by",
+			then:           "should return 400",
+			body:           `{"recommendation":"Raise the gateway timeout to 60s"}`,
+			knownInsight:   true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown insight",
+			given:          "an insight ID that does not exist",
+			when:           "PATCH to /api/insights/{id}",
+			then:           "should return 404",
+			body:           `{"note":"n/a","edited_by":"ana"}`,
+			knownInsight:   false,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insight := &insights.Insight{ID: uuid.New(), JobID: uuid.New(), Recommendation: "Increase timeout"}
+			insightRepo := &InMemoryInsightRepo{insights: map[uuid.UUID]*insights.Insight{}}
+			if tt.knownInsight {
+				insightRepo.insights[insight.ID] = insight
+			}
+			handlers := NewInsightsHandlers(appInsights.NewService(insightRepo, &InMemoryJobRepo{}, &MockAIService{}))
+			mux := http.NewServeMux()
+			RegisterInsightsRoutes(mux, handlers)
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/insights/"+insight.ID.String(), bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp InsightResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedRecommendation, resp.Recommendation)
+			assert.Equal(t, tt.expectedAI, resp.AIRecommendation)
+			assert.Equal(t, tt.expectedNote, resp.Note)
+			assert.Equal(t, "ana", resp.EditedBy)
+			assert.NotEmpty(t, resp.EditedAt)
+		})
+	}
+}
+
+func TestInsightsHandlers_AsyncAnalysis(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		withAnalyzer   bool
+		knownJob       bool
+		callbackURL    string
+		expectedStatus int
+		expectedFinal  string
+	}{
+		{
+			name:           "Submit and poll until completed",
+			given:          "a failed job and asynchronous analysis enabled",
+			when:           "POST to /api/insights/analyze?job_id={id}&async=true, then polling the status URL",
+			then:           "should return 202 and later report the completed analysis with its insight",
+			withAnalyzer:   true,
+			knownJob:       true,
+			expectedStatus: http.StatusAccepted,
+			expectedFinal:  "completed",
+		},
+		{
+			name:           "Unknown job",
+			given:          "asynchronous analysis enabled",
+			when:           "POST with the ID of a job that doesn't exist",
+			then:           "should return 404",
+			withAnalyzer:   true,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid callback URL",
+			given:          "asynchronous analysis enabled",
+			when:           "POST with a non-http callback_url",
+			then:           "should return 400",
+			withAnalyzer:   true,
+			knownJob:       true,
+			callbackURL:    "ftp://example.com/hooks",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Async analysis disabled",
+			given:          "no async analyzer",
+			when:           "POST with async=true",
+			then:           "should return 503",
+			knownJob:       true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobID := uuid.New()
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			if tt.knownJob {
+				jobRepo.jobs[jobID] = &queue.Job{ID: jobID, Queue: "default", Type: "email", Status: queue.StatusFailed, Error: "Connection timeout"}
+			}
+			insightRepo := &InMemoryInsightRepo{
+				insights:      map[uuid.UUID]*insights.Insight{},
+				insightsByJob: map[uuid.UUID]*insights.Insight{},
+			}
+			service := appInsights.NewService(insightRepo, jobRepo, &MockAIService{})
+			handlers := NewInsightsHandlers(service)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.withAnalyzer {
+				analyzer := appInsights.NewAsyncAnalyzer(service, &InMemoryAnalysisRepo{analyses: map[uuid.UUID]insights.Analysis{}}, 1, 10)
+				handlers.SetAsyncAnalyzer(analyzer)
+				go analyzer.Run(ctx)
+			}
+
+			target := "/api/insights/analyze?async=true&job_id=" + jobID.String()
+			if tt.callbackURL != "" {
+				target += "&callback_url=" + tt.callbackURL
+			}
+			req := httptest.NewRequest(http.MethodPost, target, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.AnalyzeJob(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedFinal == "" {
+				return
+			}
+			var accepted AnalysisStatusResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accepted))
+			assert.Equal(t, "pending", accepted.Status)
+			assert.Equal(t, accepted.StatusURL, rec.Header().Get("Location"))
+
+			var status AnalysisStatusResponse
+			assert.Eventually(t, func() bool {
+				pollRec := httptest.NewRecorder()
+				handlers.GetAnalysis(pollRec, httptest.NewRequest(http.MethodGet, accepted.StatusURL, nil))
+				json.Unmarshal(pollRec.Body.Bytes(), &status)
+				return status.Status == tt.expectedFinal
+			}, 2*time.Second, 10*time.Millisecond)
+			assert.NotNil(t, status.Insight)
+			assert.Equal(t, jobID.String(), status.JobID)
+		})
+	}
+}
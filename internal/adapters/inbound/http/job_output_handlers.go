@@ -0,0 +1,103 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// GetJobOutput returns the output a job streamed so far. A Range header (bytes=100-, bytes=-4096)
+// reads part of it, so consumers can tail a running job by asking for the bytes after those they
+// have; X-Job-Status tells them when it finished.
+func (h *QueueHandlers) GetJobOutput(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /api/jobs/{id}/output
+	idStr := strings.TrimSuffix(r.URL.Path[len("/api/jobs/"):], "/output")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid job ID",
+			slog.String("jobId", idStr),
+		)
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	// Ranges that can't be parsed, or several ranges, are ignored as RFC 9110 allows
+	rng, _ := parseOutputRange(r.Header.Get("Range"))
+	output, err := h.queueService.ReadJobOutput(r.Context(), id, rng)
+	w.Header().Set("Accept-Ranges", "bytes")
+	switch {
+	case err == nil:
+	case errors.Is(err, appQueue.ErrOutputDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, queue.ErrJobNotFound):
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, queue.ErrRangeNotSatisfiable):
+		w.Header().Set("X-Job-Status", string(output.Job.Status))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", output.Size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	default:
+		slog.ErrorContext(r.Context(), "Failed to read job output",
+			slog.String("jobId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Job-Status", string(output.Job.Status))
+	w.Header().Set("Content-Length", strconv.Itoa(len(output.Data)))
+	// A range, or an output longer than is returned at once, is answered with part of it
+	if len(output.Data) > 0 && (rng != nil || int64(len(output.Data)) < output.Size) {
+		end := output.Start + int64(len(output.Data)) - 1
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", output.Start, end, output.Size))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	w.Write(output.Data)
+}
+
+// parseOutputRange parses a single byte range of a Range header; it returns nil for no header
+func parseOutputRange(header string) (*queue.OutputRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, fmt.Errorf("unsupported range %q", header)
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid range %q", header)
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return nil, fmt.Errorf("invalid range %q", header)
+		}
+		return &queue.OutputRange{Suffix: suffix}, nil
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return nil, fmt.Errorf("invalid range %q", header)
+	}
+	rng := &queue.OutputRange{Start: start, End: -1}
+	if last != "" {
+		end, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return nil, fmt.Errorf("invalid range %q", header)
+		}
+		rng.End = end
+	}
+	return rng, nil
+}
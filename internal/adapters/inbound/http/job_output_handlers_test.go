@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// InMemoryOutputStore is an in-memory queue.OutputStore keeping each job's output whole
+type InMemoryOutputStore struct {
+	outputs map[uuid.UUID][]byte
+}
+
+func (s *InMemoryOutputStore) AppendOutput(ctx context.Context, jobID uuid.UUID, offset int64, data []byte) error {
+	s.outputs[jobID] = append(s.outputs[jobID][:offset], data...)
+	return nil
+}
+
+func (s *InMemoryOutputStore) OutputSize(ctx context.Context, jobID uuid.UUID) (int64, error) {
+	return int64(len(s.outputs[jobID])), nil
+}
+
+func (s *InMemoryOutputStore) ReadOutput(ctx context.Context, jobID uuid.UUID, offset, length int64) ([]byte, error) {
+	return s.outputs[jobID][offset : offset+length], nil
+}
+
+func (s *InMemoryOutputStore) ClearOutput(ctx context.Context, jobID uuid.UUID) error {
+	delete(s.outputs, jobID)
+	return nil
+}
+
+func TestQueueHandlers_GetJobOutput(t *testing.T) {
+	running := &queue.Job{ID: uuid.New(), Queue: "default", Type: "data_processing", Status: queue.StatusProcessing}
+
+	tests := []struct {
+		name                 string
+		given                string
+		when                 string
+		then                 string
+		jobID                uuid.UUID
+		rangeHeader          string
+		disabled             bool
+		expectedStatus       int
+		expectedBody         string
+		expectedContentRange string
+	}{
+		{
+			name:           "Whole output",
+			given:          "a running job that streamed output",
+			when:           "GET /api/jobs/{id}/output without a range",
+			then:           "should return all of it with the job's status",
+			jobID:          running.ID,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "step 1\nstep 2\n",
+		},
+		{
+			name:                 "Tail from an offset",
+			given:                "a consumer that already read the first line",
+			when:                 "GET with Range: bytes=7-",
+			then:                 "should return the rest as partial content",
+			jobID:                running.ID,
+			rangeHeader:          "bytes=7-",
+			expectedStatus:       http.StatusPartialContent,
+			expectedBody:         "step 2\n",
+			expectedContentRange: "bytes 7-13/14",
+		},
+		{
+			name:                 "Suffix range",
+			given:                "a consumer after the last bytes",
+			when:                 "GET with Range: bytes=-3",
+			then:                 "should return the last 3 bytes",
+			jobID:                running.ID,
+			rangeHeader:          "bytes=-3",
+			expectedStatus:       http.StatusPartialContent,
+			expectedBody:         " 2\n",
+			expectedContentRange: "bytes 11-13/14",
+		},
+		{
+			name:                 "Nothing new",
+			given:                "a consumer that read all the output so far",
+			when:                 "GET with Range: bytes=14-",
+			then:                 "should return 416 with the output's size",
+			jobID:                running.ID,
+			rangeHeader:          "bytes=14-",
+			expectedStatus:       http.StatusRequestedRangeNotSatisfiable,
+			expectedContentRange: "bytes */14",
+		},
+		{
+			name:           "Unknown job",
+			given:          "a job that doesn't exist",
+			when:           "GET /api/jobs/{id}/output",
+			then:           "should return 404",
+			jobID:          uuid.New(),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Output not stored",
+			given:          "no output store",
+			when:           "GET /api/jobs/{id}/output",
+			then:           "should return 503",
+			jobID:          running.ID,
+			disabled:       true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &InMemoryJobRepo{jobs: map[uuid.UUID]*queue.Job{running.ID: running}}
+			service := appQueue.NewService(repo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			if !tt.disabled {
+				service.SetOutputStore(&InMemoryOutputStore{outputs: map[uuid.UUID][]byte{running.ID: []byte("step 1\nstep 2\n")}})
+			}
+			handlers := NewQueueHandlers(service, nil)
+			req := httptest.NewRequest(http.MethodGet, "/api/jobs/"+tt.jobID.String()+"/output", nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetJobOutput(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedContentRange, rec.Header().Get("Content-Range"))
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+				assert.Equal(t, string(queue.StatusProcessing), rec.Header().Get("X-Job-Status"))
+			}
+		})
+	}
+}
+
+func TestParseOutputRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    *queue.OutputRange
+		wantErr bool
+	}{
+		{name: "Given no header, When parsing, Then should read everything", header: ""},
+		{name: "Given an open range, When parsing, Then should read to the end", header: "bytes=100-", want: &queue.OutputRange{Start: 100, End: -1}},
+		{name: "Given a closed range, When parsing, Then should keep both ends", header: "bytes=0-99", want: &queue.OutputRange{Start: 0, End: 99}},
+		{name: "Given a suffix range, When parsing, Then should read the last bytes", header: "bytes=-500", want: &queue.OutputRange{Suffix: 500}},
+		{name: "Given several ranges, When parsing, Then should fail", header: "bytes=0-1,5-6", wantErr: true},
+		{name: "Given a reversed range, When parsing, Then should fail", header: "bytes=9-1", wantErr: true},
+		{name: "Given another unit, When parsing, Then should fail", header: "lines=1-2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOutputRange(tt.header)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+)
+
+// DefaultLiveFeedInterval is how often metrics snapshots are pushed to dashboard clients
+const DefaultLiveFeedInterval = 2 * time.Second
+
+// liveFeedBuffer is the number of messages queued per client before new ones are dropped
+const liveFeedBuffer = 64
+
+// FeedMessage is a message pushed to live dashboard clients
+type FeedMessage struct {
+	Type    string           `json:"type"` // "metrics" or "event"
+	Metrics map[string]any   `json:"metrics,omitempty"`
+	Event   *events.Envelope `json:"event,omitempty"`
+	At      string           `json:"at"`
+}
+
+// LiveFeed streams metrics snapshots and job state changes to WebSocket clients
+type LiveFeed struct {
+	queueService *appQueue.Service
+	interval     time.Duration
+
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// NewLiveFeed creates a live feed pushing metrics every interval (DefaultLiveFeedInterval when zero)
+func NewLiveFeed(queueService *appQueue.Service, interval time.Duration) *LiveFeed {
+	if interval <= 0 {
+		interval = DefaultLiveFeedInterval
+	}
+	return &LiveFeed{
+		queueService: queueService,
+		interval:     interval,
+		clients:      make(map[chan []byte]struct{}),
+	}
+}
+
+// Run pushes metrics snapshots to connected clients until ctx is cancelled
+func (f *LiveFeed) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if f.clientCount() == 0 {
+				continue
+			}
+			message, err := f.metricsMessage(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to fetch live feed metrics",
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			f.broadcast(message)
+		}
+	}
+}
+
+// PublishEvent pushes a job or insight state change to connected clients
+func (f *LiveFeed) PublishEvent(envelope events.Envelope) {
+	f.broadcast(FeedMessage{
+		Type:  "event",
+		Event: &envelope,
+		At:    time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	})
+}
+
+func (f *LiveFeed) metricsMessage(ctx context.Context) (FeedMessage, error) {
+	metrics, err := f.queueService.GetMetrics(ctx)
+	if err != nil {
+		return FeedMessage{}, err
+	}
+	return FeedMessage{
+		Type:    "metrics",
+		Metrics: metrics,
+		At:      time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}, nil
+}
+
+// broadcast queues the message for every client; clients that fall behind miss messages
+func (f *LiveFeed) broadcast(message FeedMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		slog.Error("Failed to encode live feed message",
+			slog.String("type", message.Type),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for send := range f.clients {
+		select {
+		case send <- data:
+		default:
+		}
+	}
+}
+
+func (f *LiveFeed) clientCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.clients)
+}
+
+func (f *LiveFeed) register() chan []byte {
+	send := make(chan []byte, liveFeedBuffer)
+	f.mu.Lock()
+	f.clients[send] = struct{}{}
+	f.mu.Unlock()
+	return send
+}
+
+func (f *LiveFeed) unregister(send chan []byte) {
+	f.mu.Lock()
+	delete(f.clients, send)
+	f.mu.Unlock()
+}
+
+// ServeWS upgrades the request to a WebSocket and streams feed messages until the client leaves
+func (f *LiveFeed) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Rejected live feed connection",
+			slog.String("remoteAddr", r.RemoteAddr),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	send := f.register()
+	defer f.unregister(send)
+	slog.InfoContext(r.Context(), "Live feed client connected",
+		slog.String("remoteAddr", r.RemoteAddr),
+	)
+
+	// New clients get a snapshot right away instead of waiting for the next tick
+	if message, err := f.metricsMessage(r.Context()); err == nil {
+		if data, err := json.Marshal(message); err == nil {
+			if err := conn.WriteText(data); err != nil {
+				return
+			}
+		}
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.readLoop()
+	}()
+
+	for {
+		select {
+		case <-closed:
+			slog.InfoContext(r.Context(), "Live feed client disconnected",
+				slog.String("remoteAddr", r.RemoteAddr),
+			)
+			return
+		case data := <-send:
+			if err := conn.WriteText(data); err != nil {
+				slog.WarnContext(r.Context(), "Dropping live feed client",
+					slog.String("remoteAddr", r.RemoteAddr),
+					slog.String("error", err.Error()),
+				)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialWebSocket performs a client handshake against /ws and returns the raw connection
+func dialWebSocket(t *testing.T, serverURL string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(serverURL, "http://"))
+	require.NoError(t, err)
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	return conn, reader
+}
+
+// readFeedMessage reads one unmasked server text frame
+func readFeedMessage(t *testing.T, conn net.Conn, reader *bufio.Reader) FeedMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var head [2]byte
+	_, err := io.ReadFull(reader, head[:])
+	require.NoError(t, err)
+	require.Equal(t, byte(0x80|wsOpText), head[0])
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(reader, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(reader, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	require.NoError(t, err)
+
+	var message FeedMessage
+	require.NoError(t, json.Unmarshal(payload, &message))
+	return message
+}
+
+func TestLiveFeed_ServeWS(t *testing.T) {
+	tests := []struct {
+		name  string
+		given string
+		when  string
+		then  string
+		event events.Event
+	}{
+		{
+			name:  "Stream metrics snapshot and job events",
+			given: "a connected dashboard client",
+			when:  "a job event is published",
+			then:  "should receive a metrics snapshot followed by the event",
+			event: events.JobCompleted{JobID: uuid.New(), Queue: "default", Type: "email", Attempt: 1, At: time.Now().UTC()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appQueue.NewService(
+				&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+				&InMemoryQueueSvc{},
+				&InMemoryMetrics{},
+			)
+			feed := NewLiveFeed(service, time.Hour)
+			mux := http.NewServeMux()
+			RegisterLiveFeedRoutes(mux, feed)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			conn, reader := dialWebSocket(t, server.URL)
+			defer conn.Close()
+
+			snapshot := readFeedMessage(t, conn, reader)
+			assert.Equal(t, "metrics", snapshot.Type)
+			assert.Contains(t, snapshot.Metrics, "dlq")
+
+			// When
+			envelope, err := events.NewEnvelope(tt.event)
+			require.NoError(t, err)
+			require.Eventually(t, func() bool { return feed.clientCount() == 1 }, time.Second, 10*time.Millisecond)
+			feed.PublishEvent(envelope)
+
+			// Then
+			message := readFeedMessage(t, conn, reader)
+			assert.Equal(t, "event", message.Type)
+			require.NotNil(t, message.Event)
+			assert.Equal(t, tt.event.Name(), message.Event.Name)
+			assert.Contains(t, string(message.Event.Data), `"queue":"default"`)
+		})
+	}
+}
+
+func TestLiveFeed_RejectsPlainHTTP(t *testing.T) {
+	// Given
+	service := appQueue.NewService(
+		&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+		&InMemoryQueueSvc{},
+		&InMemoryMetrics{},
+	)
+	feed := NewLiveFeed(service, time.Hour)
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	rec := httptest.NewRecorder()
+
+	// When
+	feed.ServeWS(rec, req)
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,348 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/ratelimit"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
+	"github.com/google/uuid"
+)
+
+// DefaultAPIKeyHeader is the header used to identify API clients
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds caller-provided request IDs so they can't bloat log records
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware reuses the caller's X-Request-ID or generates one, echoes it in the
+// response and stores it in the request context so every log record of the request carries it.
+// The response writer is passed through untouched so WebSocket upgrades keep working.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// RateLimitCallers names the callers with a rate limit bucket of their own, by API key: the
+// keys named in server.api_key_names or given an override. Any other key could be made up anew
+// on each request to get a fresh bucket, so those callers are limited by client IP instead.
+type RateLimitCallers struct {
+	mu     sync.RWMutex
+	owners queue.Owners
+}
+
+// NewRateLimitCallers creates the callers; the API keys of owners are told apart by their owner
+func NewRateLimitCallers(owners queue.Owners) *RateLimitCallers {
+	return &RateLimitCallers{owners: owners}
+}
+
+// Update replaces the known callers at runtime
+func (c *RateLimitCallers) Update(owners queue.Owners) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.owners = owners
+}
+
+// key returns the bucket of the API key's owner, so the key itself never reaches Redis or the
+// logs; false when the key isn't one of the callers
+func (c *RateLimitCallers) key(apiKey string) (string, bool) {
+	if c == nil || apiKey == "" {
+		return "", false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, ok := c.owners[apiKey]; !ok {
+		return "", false
+	}
+	return RateLimitOwnerKey(c.owners.Of(apiKey)), true
+}
+
+// RateLimitOwnerKey is the bucket, and the override key, of an owner's requests
+func RateLimitOwnerKey(owner string) string {
+	return "owner:" + owner
+}
+
+// RateLimitMiddleware limits requests per owner for the API keys of callers, and per client
+// IP for anonymous and unknown callers. Limiter failures fail open so Redis hiccups don't take
+// the API down.
+func RateLimitMiddleware(limiter ratelimit.Limiter, callers *RateLimitCallers, apiKeyHeader string, next http.Handler) http.Handler {
+	if apiKeyHeader == "" {
+		apiKeyHeader = DefaultAPIKeyHeader
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := rateLimitKey(r, callers, apiKeyHeader)
+		result, err := limiter.Allow(r.Context(), key)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Rate limiter unavailable, allowing request",
+				slog.String("key", key),
+				slog.String("error", err.Error()),
+			)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			slog.InfoContext(r.Context(), "Rate limit exceeded",
+				slog.String("key", key),
+				slog.Int("retryAfterSeconds", retryAfter),
+			)
+
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error":       "rate limit exceeded",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey identifies the caller by owner when its API key is known, otherwise by client IP
+func rateLimitKey(r *http.Request, callers *RateLimitCallers, apiKeyHeader string) string {
+	if key, ok := callers.key(r.Header.Get(apiKeyHeader)); ok {
+		return key
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Who an authenticated request came from
+const (
+	CallerService = "service" // Presented the shared service secret
+	CallerAPIKey  = "api_key" // Presented one of the API keys
+)
+
+// ServiceAuthenticator authenticates callers of an internal API: services present a shared
+// secret as a bearer token, external callers one of the configured API keys. Secrets are
+// compared as SHA-256 digests in constant time so neither their content nor length leaks.
+type ServiceAuthenticator struct {
+	secret       *[sha256.Size]byte
+	apiKeys      [][sha256.Size]byte
+	apiKeyHeader string
+}
+
+// NewServiceAuthenticator creates an authenticator; it returns nil, which lets every request
+// through, when neither a secret nor API keys are configured
+func NewServiceAuthenticator(secret string, apiKeys []string, apiKeyHeader string) *ServiceAuthenticator {
+	if apiKeyHeader == "" {
+		apiKeyHeader = DefaultAPIKeyHeader
+	}
+	auth := &ServiceAuthenticator{apiKeyHeader: apiKeyHeader}
+	if secret != "" {
+		digest := sha256.Sum256([]byte(secret))
+		auth.secret = &digest
+	}
+	for _, key := range apiKeys {
+		if key != "" {
+			auth.apiKeys = append(auth.apiKeys, sha256.Sum256([]byte(key)))
+		}
+	}
+	if auth.secret == nil && len(auth.apiKeys) == 0 {
+		return nil
+	}
+	return auth
+}
+
+// Authenticate returns who made the request, or false when it carries no valid credentials
+func (a *ServiceAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	if a.secret != nil {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			digest := sha256.Sum256([]byte(token))
+			if subtle.ConstantTimeCompare(digest[:], a.secret[:]) == 1 {
+				return CallerService, true
+			}
+		}
+	}
+	if key := r.Header.Get(a.apiKeyHeader); key != "" {
+		digest := sha256.Sum256([]byte(key))
+		matched := 0
+		for _, apiKey := range a.apiKeys {
+			matched |= subtle.ConstantTimeCompare(digest[:], apiKey[:])
+		}
+		if matched == 1 {
+			return CallerAPIKey, true
+		}
+	}
+	return "", false
+}
+
+// AuthMiddleware rejects requests without valid credentials with 401. /health stays open for
+// probes, and a nil authenticator lets every request through.
+func AuthMiddleware(auth *ServiceAuthenticator, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if _, ok := auth.Authenticate(r); !ok {
+			slog.WarnContext(r.Context(), "Unauthenticated request rejected",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("remoteAddr", r.RemoteAddr),
+			)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Defaults of CORSPolicy fields left empty
+var (
+	DefaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	DefaultCORSHeaders = []string{"Content-Type", "Authorization", DefaultAPIKeyHeader, RequestIDHeader}
+	DefaultCORSExposed = []string{RequestIDHeader, "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", APIVersionHeader, "Deprecation"}
+)
+
+// DefaultCORSMaxAge is how long browsers may cache a preflight response, in seconds
+const DefaultCORSMaxAge = 600
+
+// CORSPolicy tells which browser origins may call the API and how
+type CORSPolicy struct {
+	AllowedOrigins   []string // Exact origins, "*" for any, or a wildcard subdomain like https://*.example.com
+	AllowedMethods   []string // Default DefaultCORSMethods
+	AllowedHeaders   []string // Request headers callers may send; default DefaultCORSHeaders
+	ExposedHeaders   []string // Response headers scripts may read; default DefaultCORSExposed
+	AllowCredentials bool     // Let browsers send cookies and client certificates; ignored with "*"
+	MaxAgeSeconds    int      // Default DefaultCORSMaxAge
+}
+
+// allowsOrigin reports whether the policy lists the origin
+func (p CORSPolicy) allowsOrigin(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSMiddleware lets browsers on the policy's origins call the API. Preflight requests are
+// answered here, before rate limiting and authentication, which browsers don't apply to them.
+// Requests from other origins get no CORS headers, so browsers keep their responses from
+// scripts, and their preflights are refused with 403. A policy without origins disables CORS.
+// A policy allowing "*" never allows credentials, even when AllowCredentials is set.
+func CORSMiddleware(policy CORSPolicy, next http.Handler) http.Handler {
+	if len(policy.AllowedOrigins) == 0 {
+		return next
+	}
+	if len(policy.AllowedMethods) == 0 {
+		policy.AllowedMethods = DefaultCORSMethods
+	}
+	if len(policy.AllowedHeaders) == 0 {
+		policy.AllowedHeaders = DefaultCORSHeaders
+	}
+	if len(policy.ExposedHeaders) == 0 {
+		policy.ExposedHeaders = DefaultCORSExposed
+	}
+	if policy.MaxAgeSeconds <= 0 {
+		policy.MaxAgeSeconds = DefaultCORSMaxAge
+	}
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(policy.MaxAgeSeconds)
+	anyOrigin := slices.Contains(policy.AllowedOrigins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !policy.allowsOrigin(origin) {
+			if preflight {
+				slog.InfoContext(r.Context(), "CORS preflight from disallowed origin rejected",
+					slog.String("origin", origin),
+					slog.String("path", r.URL.Path),
+				)
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Any origin gets a literal "*" and never credentials, so no site can make
+		// credentialed calls; listed origins are echoed, which credentials require
+		if anyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if policy.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", exposed)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,465 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/ratelimit"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+type FakeLimiter struct {
+	result *ratelimit.Result
+	err    error
+	keys   []string
+}
+
+func (l *FakeLimiter) Allow(ctx context.Context, key string) (*ratelimit.Result, error) {
+	l.keys = append(l.keys, key)
+	return l.result, l.err
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		limiter        *FakeLimiter
+		apiKey         string
+		path           string
+		expectedStatus int
+		expectedKey    string
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Request within limit",
+			given: "a caller with tokens left",
+			when:  "GET to /api/metrics with an API key",
+			then:  "should pass through and expose rate limit headers",
+			limiter: &FakeLimiter{result: &ratelimit.Result{
+				Allowed: true, Limit: 20, Remaining: 19, ResetAfter: 100 * time.Millisecond,
+			}},
+			apiKey:         "team-a-key",
+			path:           "/api/metrics",
+			expectedStatus: http.StatusOK,
+			expectedKey:    "owner:team-a",
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "20", rec.Header().Get("X-RateLimit-Limit"))
+				assert.Equal(t, "19", rec.Header().Get("X-RateLimit-Remaining"))
+				assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Reset"))
+			},
+		},
+		{
+			name:  "Request with an unnamed known API key",
+			given: "an API key given an override but no name",
+			when:  "GET to /api/metrics with the API key",
+			then:  "should key the bucket by the key's hashed ID rather than the key",
+			limiter: &FakeLimiter{result: &ratelimit.Result{
+				Allowed: true, Limit: 20, Remaining: 19,
+			}},
+			apiKey:         "batch-key",
+			path:           "/api/metrics",
+			expectedStatus: http.StatusOK,
+			expectedKey:    "owner:" + queue.SigningKeyID("batch-key"),
+		},
+		{
+			name:  "Request with an unknown API key",
+			given: "an API key that isn't one of the callers",
+			when:  "GET to /api/metrics with the API key",
+			then:  "should key the bucket by client IP, so made-up keys share it",
+			limiter: &FakeLimiter{result: &ratelimit.Result{
+				Allowed: true, Limit: 20, Remaining: 19,
+			}},
+			apiKey:         "made-up-key",
+			path:           "/api/metrics",
+			expectedStatus: http.StatusOK,
+			expectedKey:    "ip:192.0.2.1",
+		},
+		{
+			name:  "Request over limit",
+			given: "an anonymous caller with an empty bucket",
+			when:  "GET to /api/metrics",
+			then:  "should return 429 with Retry-After keyed by client IP",
+			limiter: &FakeLimiter{result: &ratelimit.Result{
+				Allowed: false, Limit: 20, Remaining: 0, RetryAfter: 1500 * time.Millisecond,
+			}},
+			path:           "/api/metrics",
+			expectedStatus: http.StatusTooManyRequests,
+			expectedKey:    "ip:192.0.2.1",
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+				assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+			},
+		},
+		{
+			name:           "Limiter unavailable",
+			given:          "the limiter backend returns an error",
+			when:           "GET to /api/metrics",
+			then:           "should fail open and serve the request",
+			limiter:        &FakeLimiter{err: errors.New("redis down")},
+			path:           "/api/metrics",
+			expectedStatus: http.StatusOK,
+			expectedKey:    "ip:192.0.2.1",
+		},
+		{
+			name:           "Health check",
+			given:          "any limiter state",
+			when:           "GET to /health",
+			then:           "should bypass rate limiting",
+			limiter:        &FakeLimiter{result: &ratelimit.Result{Allowed: false}},
+			path:           "/health",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			callers := NewRateLimitCallers(queue.Owners{"team-a-key": "team-a", "batch-key": ""})
+			handler := RateLimitMiddleware(tt.limiter, callers, "", next)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set(DefaultAPIKeyHeader, tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+
+			// When
+			handler.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedKey != "" {
+				assert.Equal(t, []string{tt.expectedKey}, tt.limiter.keys)
+			} else {
+				assert.Empty(t, tt.limiter.keys)
+			}
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		given      string
+		when       string
+		then       string
+		requestID  string
+		expectSame bool
+	}{
+		{
+			name:       "Caller provides a request ID",
+			given:      "a request with an X-Request-ID header",
+			when:       "the request is served",
+			then:       "should echo the ID and expose it in the context",
+			requestID:  "req-123",
+			expectSame: true,
+		},
+		{
+			name:       "Caller omits the request ID",
+			given:      "a request without an X-Request-ID header",
+			when:       "the request is served",
+			then:       "should generate an ID",
+			requestID:  "",
+			expectSame: false,
+		},
+		{
+			name:       "Caller provides an oversized request ID",
+			given:      "a request with a 200 character X-Request-ID",
+			when:       "the request is served",
+			then:       "should replace it with a generated ID",
+			requestID:  strings.Repeat("a", 200),
+			expectSame: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			var contextID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID = logging.RequestID(r.Context())
+			})
+			handler := RequestIDMiddleware(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+
+			// When
+			handler.ServeHTTP(rec, req)
+
+			// Then
+			responseID := rec.Header().Get(RequestIDHeader)
+			assert.NotEmpty(t, responseID)
+			assert.Equal(t, responseID, contextID)
+			if tt.expectSame {
+				assert.Equal(t, tt.requestID, responseID)
+			} else {
+				assert.NotEqual(t, tt.requestID, responseID)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	auth := NewServiceAuthenticator("s3cret", []string{"partner-key"}, "")
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "Service secret",
+			given:          "a request with the shared secret as bearer token",
+			when:           "GET to /api/insights",
+			then:           "should pass through",
+			path:           "/api/insights",
+			headers:        map[string]string{"Authorization": "Bearer s3cret"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "API key",
+			given:          "a request with a configured API key",
+			when:           "GET to /api/insights",
+			then:           "should pass through",
+			path:           "/api/insights",
+			headers:        map[string]string{DefaultAPIKeyHeader: "partner-key"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Wrong secret",
+			given:          "a request with another bearer token",
+			when:           "GET to /api/insights",
+			then:           "should return 401",
+			path:           "/api/insights",
+			headers:        map[string]string{"Authorization": "Bearer s3cre"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "API key sent as bearer token",
+			given:          "a request with an API key in the Authorization header",
+			when:           "GET to /api/insights",
+			then:           "should return 401",
+			path:           "/api/insights",
+			headers:        map[string]string{"Authorization": "Bearer partner-key"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "No credentials",
+			given:          "an anonymous request",
+			when:           "POST to /api/insights/analyze",
+			then:           "should return 401",
+			path:           "/api/insights/analyze",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Health check",
+			given:          "an anonymous request",
+			when:           "GET to /health",
+			then:           "should bypass authentication",
+			path:           "/health",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := AuthMiddleware(auth, next)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			// When
+			handler.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestNewServiceAuthenticator_NothingConfigured(t *testing.T) {
+	// Given
+	auth := NewServiceAuthenticator("", []string{""}, "")
+	handler := AuthMiddleware(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+
+	// When
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/insights", nil))
+
+	// Then
+	assert.Nil(t, auth)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		policy         CORSPolicy
+		method         string
+		headers        map[string]string
+		expectedStatus int
+		expectedNext   bool
+		expectedHeader map[string]string
+	}{
+		{
+			name:           "Preflight from an allowed origin",
+			given:          "a policy allowing https://demo.example.com",
+			when:           "OPTIONS to /api/jobs with Access-Control-Request-Method",
+			then:           "should answer 204 with the allowed methods and headers without calling the handler",
+			policy:         CORSPolicy{AllowedOrigins: []string{"https://demo.example.com"}},
+			method:         http.MethodOptions,
+			headers:        map[string]string{"Origin": "https://demo.example.com", "Access-Control-Request-Method": "POST"},
+			expectedStatus: http.StatusNoContent,
+			expectedHeader: map[string]string{
+				"Access-Control-Allow-Origin":  "https://demo.example.com",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers": "Content-Type, Authorization, X-API-Key, X-Request-ID",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name:           "Preflight from another origin",
+			given:          "a policy allowing https://demo.example.com",
+			when:           "OPTIONS to /api/jobs from https://evil.example.net",
+			then:           "should return 403 without CORS headers",
+			policy:         CORSPolicy{AllowedOrigins: []string{"https://demo.example.com"}},
+			method:         http.MethodOptions,
+			headers:        map[string]string{"Origin": "https://evil.example.net", "Access-Control-Request-Method": "POST"},
+			expectedStatus: http.StatusForbidden,
+			expectedHeader: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "Request from a wildcard subdomain with credentials",
+			given:          "a policy allowing https://*.example.com with credentials",
+			when:           "GET to /api/jobs from https://ui.example.com",
+			then:           "should pass through, echo the origin and allow credentials",
+			policy:         CORSPolicy{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://ui.example.com"},
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+			expectedHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://ui.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After, API-Version, Deprecation",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:           "Request from any origin",
+			given:          "a policy allowing every origin without credentials",
+			when:           "GET to /api/jobs from https://anywhere.test",
+			then:           "should allow every origin with *",
+			policy:         CORSPolicy{AllowedOrigins: []string{"*"}},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://anywhere.test"},
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+			expectedHeader: map[string]string{"Access-Control-Allow-Origin": "*"},
+		},
+		{
+			name:           "Request from any origin with credentials",
+			given:          "a policy allowing every origin with credentials",
+			when:           "GET to /api/jobs from https://evil.example.net",
+			then:           "should allow every origin with * and not allow credentials",
+			policy:         CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://evil.example.net"},
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+			expectedHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name:           "Request from another origin",
+			given:          "a policy allowing https://demo.example.com",
+			when:           "GET to /api/jobs from https://evil.example.net",
+			then:           "should pass through without CORS headers, so the browser hides the response",
+			policy:         CORSPolicy{AllowedOrigins: []string{"https://demo.example.com"}},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://evil.example.net"},
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+			expectedHeader: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "OPTIONS without preflight headers",
+			given:          "a policy allowing https://demo.example.com",
+			when:           "OPTIONS to /api/jobs without Access-Control-Request-Method",
+			then:           "should leave the request to the handler",
+			policy:         CORSPolicy{AllowedOrigins: []string{"https://demo.example.com"}},
+			method:         http.MethodOptions,
+			headers:        map[string]string{"Origin": "https://demo.example.com"},
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := CORSMiddleware(tt.policy, next)
+
+			req := httptest.NewRequest(tt.method, "/api/jobs", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			// When
+			handler.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedNext, called)
+			for name, value := range tt.expectedHeader {
+				assert.Equal(t, value, rec.Header().Get(name), name)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Page sizes of listing endpoints when the server config doesn't set them
+const (
+	DefaultPageLimit    = 50
+	DefaultMaxPageLimit = 200
+)
+
+// PageLimits bounds the pages the listing endpoints return
+type PageLimits struct {
+	Default int // Page size when the request sets no limit
+	Max     int // Largest limit a request may ask for
+}
+
+// DefaultPageLimits returns the page sizes used unless the config changes them
+func DefaultPageLimits() PageLimits {
+	return PageLimits{Default: DefaultPageLimit, Max: DefaultMaxPageLimit}
+}
+
+// withDefaults fills unset limits with the defaults, keeping the default page within the maximum
+func (l PageLimits) withDefaults() PageLimits {
+	if l.Max <= 0 {
+		l.Max = DefaultMaxPageLimit
+	}
+	if l.Default <= 0 {
+		l.Default = DefaultPageLimit
+	}
+	l.Default = min(l.Default, l.Max)
+	return l
+}
+
+// page is the slice of a listing a request asks for
+type page struct {
+	Limit  int
+	Offset int
+}
+
+// parsePage reads limit and offset from the request's query. A missing limit is the default
+// page size; a limit outside 1 to the maximum, or an offset that isn't a non-negative integer,
+// is an error to answer with 400.
+func (l PageLimits) parsePage(r *http.Request) (page, error) {
+	p := page{Limit: l.Default}
+	query := r.URL.Query()
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > l.Max {
+			return page{}, fmt.Errorf("limit must be between 1 and %d", l.Max)
+		}
+		p.Limit = limit
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return page{}, errors.New("offset must be a non-negative integer")
+		}
+		p.Offset = offset
+	}
+	return p, nil
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageLimits_parsePage(t *testing.T) {
+	tests := []struct {
+		name        string
+		given       string
+		when        string
+		then        string
+		limits      PageLimits
+		query       string
+		expected    page
+		expectedErr string
+	}{
+		{
+			name:     "No pagination parameters",
+			given:    "the default page limits",
+			when:     "parsing a request without limit or offset",
+			then:     "should return the default page",
+			limits:   DefaultPageLimits(),
+			expected: page{Limit: DefaultPageLimit},
+		},
+		{
+			name:     "Limit and offset within bounds",
+			given:    "the default page limits",
+			when:     "parsing limit=200&offset=400",
+			then:     "should return them",
+			limits:   DefaultPageLimits(),
+			query:    "?limit=200&offset=400",
+			expected: page{Limit: 200, Offset: 400},
+		},
+		{
+			name:        "Limit above the maximum",
+			given:       "the default page limits",
+			when:        "parsing limit=100000",
+			then:        "should reject it",
+			limits:      DefaultPageLimits(),
+			query:       "?limit=100000",
+			expectedErr: "limit must be between 1 and 200",
+		},
+		{
+			name:        "Zero limit",
+			given:       "the default page limits",
+			when:        "parsing limit=0",
+			then:        "should reject it",
+			limits:      DefaultPageLimits(),
+			query:       "?limit=0",
+			expectedErr: "limit must be between 1 and 200",
+		},
+		{
+			name:        "Limit not a number",
+			given:       "the default page limits",
+			when:        "parsing limit=all",
+			then:        "should reject it",
+			limits:      DefaultPageLimits(),
+			query:       "?limit=all",
+			expectedErr: "limit must be between 1 and 200",
+		},
+		{
+			name:        "Negative offset",
+			given:       "the default page limits",
+			when:        "parsing offset=-1",
+			then:        "should reject it",
+			limits:      DefaultPageLimits(),
+			query:       "?offset=-1",
+			expectedErr: "offset must be a non-negative integer",
+		},
+		{
+			name:     "Configured limits",
+			given:    "a default of 10 and a maximum of 25",
+			when:     "parsing a request without limit",
+			then:     "should return the configured default page",
+			limits:   PageLimits{Default: 10, Max: 25}.withDefaults(),
+			expected: page{Limit: 10},
+		},
+		{
+			name:     "Default above a configured maximum",
+			given:    "a maximum of 20 and no default",
+			when:     "parsing a request without limit",
+			then:     "should cap the default page at the maximum",
+			limits:   PageLimits{Max: 20}.withDefaults(),
+			expected: page{Limit: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodGet, "/api/jobs"+tt.query, nil)
+
+			// When
+			p, err := tt.limits.parsePage(req)
+
+			// Then
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p)
+		})
+	}
+}
+
+func TestQueueHandlers_PageLimits(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		path           string
+		handle         func(*QueueHandlers, http.ResponseWriter, *http.Request)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Jobs within the configured maximum",
+			given:          "a maximum page of 25",
+			when:           "GET /api/jobs?limit=25",
+			then:           "should return 200",
+			path:           "/api/jobs?limit=25",
+			handle:         (*QueueHandlers).ListJobs,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Jobs above the configured maximum",
+			given:          "a maximum page of 25",
+			when:           "GET /api/jobs?limit=100000",
+			then:           "should return 400 naming the maximum",
+			path:           "/api/jobs?limit=100000",
+			handle:         (*QueueHandlers).ListJobs,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "limit must be between 1 and 25\n",
+		},
+		{
+			name:           "DLQ above the configured maximum",
+			given:          "a maximum page of 25",
+			when:           "GET /api/dlq?limit=26",
+			then:           "should return 400 naming the maximum",
+			path:           "/api/dlq?limit=26",
+			handle:         (*QueueHandlers).GetDLQJobs,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "limit must be between 1 and 25\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appQueue.NewService(&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			handlers := NewQueueHandlers(service, nil)
+			handlers.SetPageLimits(PageLimits{Max: 25})
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			tt.handle(handlers, rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
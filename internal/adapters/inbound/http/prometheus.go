@@ -0,0 +1,80 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// prometheusCounters names the Prometheus counter of each job lifecycle event, in output order
+var prometheusCounters = []struct {
+	event string
+	name  string
+	help  string
+}{
+	{queue.CounterCreated, "aisq_jobs_created_total", "Jobs created."},
+	{queue.CounterCompleted, "aisq_jobs_completed_total", "Job executions that completed."},
+	{queue.CounterFailed, "aisq_jobs_failed_total", "Job executions that failed, by failure category."},
+	{queue.CounterRetried, "aisq_jobs_retried_total", "Jobs retried by hand or by replaying dead letters."},
+	{queue.CounterParked, "aisq_jobs_parked_total", "Failed jobs parked because their queue's retry budget was exhausted."},
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusMetrics writes the job counters in the Prometheus text exposition format
+func (h *QueueHandlers) PrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	counters, err := h.queueService.JobCounters(r.Context())
+	if errors.Is(err, appQueue.ErrJobCountersDisabled) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch job counters",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var b strings.Builder
+	for _, metric := range prometheusCounters {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		for _, counter := range counters {
+			if counter.Event != metric.event {
+				continue
+			}
+			labels := fmt.Sprintf(`queue="%s",type="%s"`,
+				prometheusLabelEscaper.Replace(counter.Queue), prometheusLabelEscaper.Replace(counter.Type))
+			if counter.Event == queue.CounterFailed {
+				labels += fmt.Sprintf(`,category="%s"`, prometheusLabelEscaper.Replace(counter.Category))
+			}
+			fmt.Fprintf(&b, "%s{%s} %d\n", metric.name, labels, counter.Count)
+		}
+	}
+	if h.insightsService != nil {
+		if status, err := h.insightsService.ProviderHealth(); err == nil {
+			writeProviderHealthMetrics(&b, status)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// writeProviderHealthMetrics writes the AI provider's health and downtime
+func writeProviderHealthMetrics(b *strings.Builder, status insights.ProviderHealthStatus) {
+	degraded := 0.0
+	if status.State == insights.ProviderDegraded {
+		degraded = 1
+	}
+	writePrometheusGauge(b, "aisq_ai_provider_degraded", "1 while the AI provider has failed enough health probes in a row to skip synchronous analyses.", degraded)
+	writePrometheusGauge(b, "aisq_ai_provider_consecutive_failures", "AI provider health probes failed in a row.", float64(status.ConsecutiveFailures))
+	fmt.Fprintf(b, "# HELP aisq_ai_provider_outages_total Times the AI provider was degraded.\n# TYPE aisq_ai_provider_outages_total counter\naisq_ai_provider_outages_total %d\n", status.Outages)
+	fmt.Fprintf(b, "# HELP aisq_ai_provider_downtime_seconds_total Seconds the AI provider spent degraded, from the first failed probe of each outage.\n# TYPE aisq_ai_provider_downtime_seconds_total counter\naisq_ai_provider_downtime_seconds_total %g\n", status.Downtime.Seconds())
+}
@@ -2,13 +2,22 @@ package http
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"math"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
 	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	appQuota "github.com/erickfunier/ai-smart-queue/internal/application/quota"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
 	"github.com/google/uuid"
 )
 
@@ -16,6 +25,9 @@ import (
 type QueueHandlers struct {
 	queueService    *appQueue.Service
 	insightsService *appInsights.Service
+	quotaService    *appQuota.Service
+	apiKeyHeader    string
+	pages           PageLimits
 }
 
 // NewQueueHandlers creates a new queue HTTP handlers
@@ -23,70 +35,311 @@ func NewQueueHandlers(queueService *appQueue.Service, insightsService *appInsigh
 	return &QueueHandlers{
 		queueService:    queueService,
 		insightsService: insightsService,
+		apiKeyHeader:    DefaultAPIKeyHeader,
+		pages:           DefaultPageLimits(),
 	}
 }
 
+// SetAPIKeyHeader changes the header identifying the caller of CreateJob
+func (h *QueueHandlers) SetAPIKeyHeader(header string) {
+	if header != "" {
+		h.apiKeyHeader = header
+	}
+}
+
+// SetPageLimits changes the default and maximum page size of the job, search and DLQ listings;
+// zero values keep the defaults
+func (h *QueueHandlers) SetPageLimits(limits PageLimits) {
+	h.pages = limits.withDefaults()
+}
+
+// SetQuotaService enables per tenant quotas and GET /api/usage
+func (h *QueueHandlers) SetQuotaService(quotaService *appQuota.Service) {
+	h.quotaService = quotaService
+}
+
 type CreateJobRequest struct {
-	Queue   string      `json:"queue"`
-	Type    string      `json:"type"`
-	Payload any `json:"payload"`
+	Queue       string   `json:"queue"`
+	Type        string   `json:"type"`
+	Payload     any      `json:"payload"`
+	CallbackURL string   `json:"callback_url,omitempty"`
+	Requires    []string `json:"requires,omitempty"`
+	// ScheduledFor delays the job until then, e.g. "2026-01-02T15:04:05Z"
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	// SchemaVersion is the version of the payload's schema the producer wrote (default 1)
+	SchemaVersion int `json:"schema_version,omitempty"`
+	// Metadata is carried with the job apart from its payload, e.g. a correlation ID or traceparent
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// command returns the command creating the requested job on behalf of the API key
+func (req CreateJobRequest) command(apiKey string) appQueue.CreateJobCommand {
+	return appQueue.CreateJobCommand{
+		Queue:        req.Queue,
+		Type:         req.Type,
+		Payload:      req.Payload,
+		CallbackURL:  req.CallbackURL,
+		Requires:     req.Requires,
+		APIKey:       apiKey,
+		ScheduledFor: req.ScheduledFor,
+
+		SchemaVersion: req.SchemaVersion,
+		Metadata:      req.Metadata,
+	}
+}
+
+// EditJobRequest changes a job that hasn't started running; fields left out keep their value
+type EditJobRequest struct {
+	Payload any `json:"payload,omitempty"`
+	// ScheduledFor reschedules a pending job; a time that has passed runs it now
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	// Version is the version of the job the changes were based on, as returned by GET /api/jobs/{id}
+	Version *int `json:"version"`
 }
 
 type JobResponse struct {
-	ID        string           `json:"id"`
-	Queue     string           `json:"queue"`
-	Type      string           `json:"type"`
-	Status    string           `json:"status"`
-	Attempts  int              `json:"attempts"`
-	Payload   any      `json:"payload"`
-	Error     string           `json:"error,omitempty"`
-	Insight   *InsightResponse `json:"insight,omitempty"`
-	CreatedAt string           `json:"created_at"`
-	UpdatedAt string           `json:"updated_at"`
+	ID           string            `json:"id"`
+	Queue        string            `json:"queue"`
+	Type         string            `json:"type"`
+	Status       string            `json:"status"`
+	Attempts     int               `json:"attempts"`
+	Payload      any               `json:"payload"`
+	Result       any               `json:"result,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Fingerprint  string            `json:"error_fingerprint,omitempty"`  // Shared by failures differing only in IDs, numbers or addresses
+	DeadLetter   string            `json:"dead_letter_reason,omitempty"` // Why the job was moved to the DLQ, e.g. max_attempts
+	CallbackURL  string            `json:"callback_url,omitempty"`
+	Requires     []string          `json:"requires,omitempty"`
+	ScheduledFor string            `json:"scheduled_for,omitempty"`
+	Version      int               `json:"version"`
+	Schema       int               `json:"schema_version"` // Version of the payload's schema
+	GroupID      string            `json:"group_id,omitempty"`
+	CreatedBy    string            `json:"created_by,omitempty"` // API key name, or hashed key ID, of the caller that created the job
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	NextRetryAt  string            `json:"next_retry_at,omitempty"` // When a retrying job runs again
+	Retries      []RetryEntry      `json:"retries,omitempty"`       // Failed attempts, oldest first; only returned by GET /api/jobs/{id}
+	Insight      *InsightResponse  `json:"insight,omitempty"`
+	CreatedAt    string            `json:"created_at"`
+	UpdatedAt    string            `json:"updated_at"`
+	DeletedAt    string            `json:"deleted_at,omitempty"`
+}
+
+// RetryEntry is a failed attempt of a job and the backoff it was retried after
+type RetryEntry struct {
+	Attempt   int    `json:"attempt"`
+	At        string `json:"at"` // When the attempt failed
+	Error     string `json:"error"`
+	Category  string `json:"category,omitempty"`
+	WorkerID  string `json:"worker_id,omitempty"`
+	BackoffMs int64  `json:"backoff_ms"`         // Zero when the attempt wasn't retried
+	RetryAt   string `json:"retry_at,omitempty"` // When the retry was due
+}
+
+// newRetryEntries maps failed attempts to their API representation
+func newRetryEntries(attempts []*queue.Attempt) []RetryEntry {
+	entries := make([]RetryEntry, 0, len(attempts))
+	for _, attempt := range attempts {
+		entry := RetryEntry{
+			Attempt:   attempt.Number,
+			At:        attempt.EndedAt().UTC().Format("2006-01-02T15:04:05Z"),
+			Error:     attempt.Error,
+			Category:  attempt.Category,
+			WorkerID:  attempt.WorkerID,
+			BackoffMs: attempt.Backoff.Milliseconds(),
+		}
+		if attempt.RetryAt != nil {
+			entry.RetryAt = attempt.RetryAt.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// newJobResponse maps a domain job to its API representation
+func newJobResponse(job *queue.Job) JobResponse {
+	var payload, result any
+	json.Unmarshal(job.Payload, &payload)
+	if len(job.Result) > 0 {
+		json.Unmarshal(job.Result, &result)
+	}
+
+	var deletedAt, scheduledFor, groupID string
+	if job.GroupID != nil {
+		groupID = job.GroupID.String()
+	}
+	if job.DeletedAt != nil {
+		deletedAt = job.DeletedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if job.ScheduledFor != nil {
+		scheduledFor = job.ScheduledFor.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	var nextRetryAt string
+	if retryAt := job.NextRetryAt(); retryAt != nil {
+		nextRetryAt = retryAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+
+	return JobResponse{
+		ID:           job.ID.String(),
+		Queue:        job.Queue,
+		Type:         job.Type,
+		Status:       string(job.Status),
+		Attempts:     job.Attempts,
+		Payload:      payload,
+		Result:       result,
+		Error:        job.Error,
+		Fingerprint:  job.ErrorFingerprint(),
+		DeadLetter:   job.DeadLetterReason,
+		CallbackURL:  job.CallbackURL,
+		Requires:     job.Requires,
+		ScheduledFor: scheduledFor,
+		Version:      job.Version,
+		Schema:       job.EffectiveSchemaVersion(),
+		GroupID:      groupID,
+		CreatedBy:    job.CreatedBy,
+		Metadata:     job.Metadata,
+		NextRetryAt:  nextRetryAt,
+		CreatedAt:    job.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:    job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		DeletedAt:    deletedAt,
+	}
 }
 
 func (h *QueueHandlers) CreateJob(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[CreateJob] Received request from %s", r.RemoteAddr)
+	slog.DebugContext(r.Context(), "Received create job request",
+		slog.String("remoteAddr", r.RemoteAddr),
+	)
 	var req CreateJobRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[CreateJob] Failed to decode request: %v", err)
+		slog.WarnContext(r.Context(), "Failed to decode create job request",
+			slog.String("error", err.Error()),
+		)
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
-	log.Printf("[CreateJob] Creating job: queue=%s, type=%s", req.Queue, req.Type)
-
-	cmd := appQueue.CreateJobCommand{
-		Queue:   req.Queue,
-		Type:    req.Type,
-		Payload: req.Payload,
-	}
+	slog.InfoContext(r.Context(), "Creating job",
+		slog.String("queue", req.Queue),
+		slog.String("type", req.Type),
+	)
 
-	job, err := h.queueService.CreateJob(r.Context(), cmd)
+	job, err := h.queueService.CreateJob(r.Context(), req.command(r.Header.Get(h.apiKeyHeader)))
 	if err != nil {
-		log.Printf("[CreateJob] Failed to create job: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeCreateJobError(w, r, err)
 		return
 	}
-	log.Printf("[CreateJob] Job created successfully: id=%s, queue=%s", job.ID, job.Queue)
-
-	var payload any
-	json.Unmarshal(job.Payload, &payload)
+	slog.InfoContext(r.Context(), "Job created successfully",
+		slog.String("jobId", job.ID.String()),
+		slog.String("queue", job.Queue),
+	)
 
-	response := JobResponse{
-		ID:        job.ID.String(),
-		Queue:     job.Queue,
-		Type:      job.Type,
-		Status:    string(job.Status),
-		Attempts:  job.Attempts,
-		Payload:   payload,
-		CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-	}
+	response := newJobResponse(job)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[CreateJob] Failed to encode response: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to encode response",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// ExecuteJob creates a job and waits for a worker to finish it, answering 200 with the finished
+// job, or 202 with the job as it is when the timeout passes first
+func (h *QueueHandlers) ExecuteJob(w http.ResponseWriter, r *http.Request) {
+	var timeout time.Duration
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "timeout must be a number of seconds", http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	var req CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.WarnContext(r.Context(), "Failed to decode execute job request",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	slog.InfoContext(r.Context(), "Executing job synchronously",
+		slog.String("queue", req.Queue),
+		slog.String("type", req.Type),
+		slog.Duration("timeout", timeout),
+	)
+
+	// The wait is bounded by the timeout, which can exceed the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	job, finished, err := h.queueService.ExecuteJob(r.Context(), req.command(r.Header.Get(h.apiKeyHeader)), timeout)
+	switch {
+	case errors.Is(err, appQueue.ErrExecuteDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, appQueue.ErrInvalidExecuteTimeout), errors.Is(err, appQueue.ErrExecuteDelayed):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		writeCreateJobError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Job execution wait ended",
+		slog.String("jobId", job.ID.String()),
+		slog.String("status", string(job.Status)),
+		slog.Bool("finished", finished),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if finished {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.Header().Set("Location", apiPath(r, "/jobs/"+job.ID.String()))
+		w.WriteHeader(http.StatusAccepted)
+	}
+	if err := json.NewEncoder(w).Encode(newJobResponse(job)); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode response",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// writeCreateJobError maps the errors of creating a job to their status codes
+func writeCreateJobError(w http.ResponseWriter, r *http.Request, err error) {
+	var limited *appQueue.RateLimitedError
+	switch {
+	case errors.Is(err, queue.ErrInvalidCallbackURL):
+		slog.WarnContext(r.Context(), "Rejected callback URL",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, queue.ErrInvalidCapability), errors.Is(err, queue.ErrInvalidSchemaVersion), errors.Is(err, queue.ErrInvalidMetadata):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, queue.ErrNoSigningSecret):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, queue.ErrQueueDraining):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, queue.ErrInvalidQueue), errors.Is(err, queue.ErrInvalidType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, queue.ErrQueueNotDefined), errors.Is(err, queue.ErrJobTypeNotAllowed), errors.Is(err, queue.ErrPayloadNotObject):
+		slog.WarnContext(r.Context(), "Job rejected by queue definition",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		writeQuotaExceeded(w, r, err)
+	case errors.As(err, &limited):
+		retryAfter := max(1, int(math.Ceil(limited.RetryAfter.Seconds())))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error":       limited.Error(),
+			"retry_after": retryAfter,
+		})
+	default:
+		slog.ErrorContext(r.Context(), "Failed to create job",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
@@ -94,59 +347,57 @@ func (h *QueueHandlers) GetJobByID(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from path: /api/jobs/{id}
 	idStr := r.URL.Path[len("/api/jobs/"):]
 	if idStr == "" {
-		log.Printf("[GetJobByID] Missing job ID in path")
+		slog.InfoContext(r.Context(), "Missing job ID in path")
 		http.Error(w, "job id is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		log.Printf("[GetJobByID] Invalid job ID: %s", idStr)
+		slog.InfoContext(r.Context(), "Invalid job ID",
+			slog.String("jobId", idStr),
+		)
 		http.Error(w, "invalid job id", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[GetJobByID] Fetching job: id=%s", id)
+	slog.InfoContext(r.Context(), "Fetching job",
+		slog.String("jobId", id.String()),
+	)
 	job, err := h.queueService.GetJob(r.Context(), id)
 	if err != nil {
-		log.Printf("[GetJobByID] Job not found: id=%s", id)
+		slog.WarnContext(r.Context(), "Job not found",
+			slog.String("jobId", id.String()),
+		)
 		http.Error(w, "job not found", http.StatusNotFound)
 		return
 	}
-	log.Printf("[GetJobByID] Job retrieved: id=%s, status=%s", job.ID, job.Status)
+	slog.InfoContext(r.Context(), "Job retrieved",
+		slog.String("jobId", job.ID.String()),
+		slog.String("status", string(job.Status)),
+	)
 
-	var payload any
-	json.Unmarshal(job.Payload, &payload)
+	response := newJobResponse(job)
 
-	response := JobResponse{
-		ID:        job.ID.String(),
-		Queue:     job.Queue,
-		Type:      job.Type,
-		Status:    string(job.Status),
-		Attempts:  job.Attempts,
-		Payload:   payload,
-		Error:     job.Error,
-		CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	// The retry history is worth returning the job without, so a failed read is only logged
+	retries, err := h.queueService.RetryHistory(r.Context(), id)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to read job retry history",
+			slog.String("jobId", id.String()),
+			slog.String("error", err.Error()),
+		)
 	}
+	response.Retries = newRetryEntries(retries)
 
 	// Try to fetch insights for this job if it has failed
 	if h.insightsService != nil && job.Status == queue.StatusFailed {
 		insight, err := h.insightsService.GetInsightByJobID(r.Context(), id)
 		if err == nil && insight != nil {
-			log.Printf("[GetJob] Including insight in response: insight_id=%s", insight.ID)
-			response.Insight = &InsightResponse{
-				ID:             insight.ID.String(),
-				JobID:          insight.JobID.String(),
-				Diagnosis:      insight.Diagnosis,
-				Recommendation: insight.Recommendation,
-				SuggestedFix: map[string]any{
-					"timeout_seconds": insight.SuggestedFix.TimeoutSeconds,
-					"max_retries":     insight.SuggestedFix.MaxRetries,
-					"payload_patch":   insight.SuggestedFix.PayloadPatch,
-				},
-				CreatedAt: insight.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			}
+			slog.InfoContext(r.Context(), "Including insight in response",
+				slog.String("insightId", insight.ID.String()),
+			)
+			insightResponse := newInsightResponse(insight)
+			response.Insight = &insightResponse
 		}
 	}
 
@@ -154,111 +405,354 @@ func (h *QueueHandlers) GetJobByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// EditJob changes the payload or schedule of a pending or retrying job
+func (h *QueueHandlers) EditJob(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /api/jobs/{id}
+	idStr := r.URL.Path[len("/api/jobs/"):]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid job ID",
+			slog.String("jobId", idStr),
+		)
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	var req EditJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Version == nil {
+		http.Error(w, "version is required", http.StatusBadRequest)
+		return
+	}
+	if req.Payload == nil && req.ScheduledFor == nil {
+		http.Error(w, "payload or scheduled_for is required", http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Editing job",
+		slog.String("jobId", id.String()),
+		slog.Int("version", *req.Version),
+	)
+	job, err := h.queueService.EditJob(r.Context(), id, appQueue.EditJobCommand{
+		Payload:      req.Payload,
+		ScheduledFor: req.ScheduledFor,
+		Version:      *req.Version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, queue.ErrJobNotFound):
+			http.Error(w, "job not found", http.StatusNotFound)
+		case errors.Is(err, queue.ErrVersionConflict), errors.Is(err, queue.ErrJobNotEditable):
+			slog.WarnContext(r.Context(), "Job edit refused",
+				slog.String("jobId", id.String()),
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, appQueue.ErrJobEditsDisabled):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			slog.ErrorContext(r.Context(), "Failed to edit job",
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
+func (h *QueueHandlers) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /api/jobs/{id}
+	idStr := r.URL.Path[len("/api/jobs/"):]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid job ID",
+			slog.String("jobId", idStr),
+		)
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Soft-deleting job",
+		slog.String("jobId", id.String()),
+	)
+	if err := h.queueService.DeleteJob(r.Context(), id); err != nil {
+		if errors.Is(err, queue.ErrJobNotFound) {
+			slog.WarnContext(r.Context(), "Job not found",
+				slog.String("jobId", id.String()),
+			)
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to delete job",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(r.Context(), "Job deleted",
+		slog.String("jobId", id.String()),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": id.String(), "status": "deleted"})
+}
+
+func (h *QueueHandlers) UndeleteJob(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /api/jobs/{id}/undelete
+	idStr := strings.TrimSuffix(r.URL.Path[len("/api/jobs/"):], "/undelete")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid job ID",
+			slog.String("jobId", idStr),
+		)
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Restoring job",
+		slog.String("jobId", id.String()),
+	)
+	job, err := h.queueService.UndeleteJob(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, queue.ErrJobNotFound):
+			slog.WarnContext(r.Context(), "Job not found",
+				slog.String("jobId", id.String()),
+			)
+			http.Error(w, "job not found", http.StatusNotFound)
+		case errors.Is(err, queue.ErrJobNotDeleted):
+			slog.WarnContext(r.Context(), "Job is not deleted",
+				slog.String("jobId", id.String()),
+			)
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			slog.ErrorContext(r.Context(), "Failed to restore job",
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	slog.InfoContext(r.Context(), "Job restored",
+		slog.String("jobId", id.String()),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
 func (h *QueueHandlers) ListJobs(w http.ResponseWriter, r *http.Request) {
 	// Optional filters
 	statusStr := r.URL.Query().Get("status")
 	queueName := r.URL.Query().Get("queue")
+	createdBy := r.URL.Query().Get("created_by")
+	metadata, err := queue.ParseMetadataFilter(r.URL.Query()["metadata"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Pagination
-	limit := 50
-	offset := 0
-
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil {
-			limit = l
-		}
-	}
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil {
-			offset = o
-		}
+	pg, err := h.pages.parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	limit, offset := pg.Limit, pg.Offset
 
-	log.Printf("[ListJobs] Fetching jobs: status=%s, queue=%s, limit=%d, offset=%d", statusStr, queueName, limit, offset)
+	slog.InfoContext(r.Context(), "Fetching jobs",
+		slog.String("status", statusStr),
+		slog.String("queue", queueName),
+		slog.String("createdBy", createdBy),
+		slog.Int("limit", limit),
+		slog.Int("offset", offset),
+	)
 
 	var jobs []*queue.Job
-	var err error
 
-	// If status filter is provided, use GetJobsByStatus
-	if statusStr != "" {
+	// A creator or metadata filter lists the matching jobs, optionally narrowed to a queue and
+	// status; otherwise a status filter uses GetJobsByStatus
+	if createdBy != "" || !metadata.IsZero() {
+		var listing queue.QueueListing
+		if createdBy != "" {
+			listing, err = queue.NewCreatorListing(createdBy, queueName, queue.Status(statusStr), limit, offset)
+			listing.Metadata = metadata
+		} else {
+			listing, err = queue.NewMetadataListing(metadata, queueName, queue.Status(statusStr), limit, offset)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		jobs, _, err = h.queueService.ListQueueJobs(r.Context(), listing)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to fetch jobs",
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if statusStr != "" {
 		jobs, err = h.queueService.GetJobsByStatus(r.Context(), queue.Status(statusStr), limit)
 		if err != nil {
-			log.Printf("[ListJobs] Failed to fetch jobs: %v", err)
+			slog.ErrorContext(r.Context(), "Failed to fetch jobs",
+				slog.String("error", err.Error()),
+			)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
 		// TODO: Implement GetAllJobs in service for listing without status filter
 		// For now, return empty array if no filter provided
-		log.Printf("[ListJobs] No status filter - returning empty list (implement GetAllJobs for full listing)")
+		slog.InfoContext(r.Context(), "No status filter - returning empty list (implement GetAllJobs for full listing)")
 		jobs = []*queue.Job{}
 	}
 
-	log.Printf("[ListJobs] Found %d jobs", len(jobs))
+	slog.InfoContext(r.Context(), "Found jobs",
+		slog.Int("count", len(jobs)),
+	)
 
 	var responses []JobResponse
 	for _, job := range jobs {
-		var payload any
-		json.Unmarshal(job.Payload, &payload)
-
-		responses = append(responses, JobResponse{
-			ID:        job.ID.String(),
-			Queue:     job.Queue,
-			Type:      job.Type,
-			Status:    string(job.Status),
-			Attempts:  job.Attempts,
-			Payload:   payload,
-			Error:     job.Error,
-			CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt: job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		})
+		responses = append(responses, newJobResponse(job))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(responses)
 }
 
-func (h *QueueHandlers) GetDLQJobs(w http.ResponseWriter, r *http.Request) {
-	limit := 50
-	offset := 0
+func (h *QueueHandlers) SearchJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
 
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil {
-			limit = l
-		}
+	pg, err := h.pages.parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil {
-			offset = o
-		}
+
+	criteria, err := queue.NewSearchCriteria(query.Get("q"), queue.Status(query.Get("status")), query.Get("queue"), pg.Limit, pg.Offset)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid search",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	log.Printf("[GetDLQJobs] Fetching DLQ jobs: limit=%d, offset=%d", limit, offset)
-	jobs, total, err := h.queueService.GetDLQJobs(r.Context(), limit, offset)
+	slog.InfoContext(r.Context(), "Searching jobs",
+		slog.String("q", criteria.Text),
+		slog.String("status", string(criteria.Status)),
+		slog.String("queue", criteria.Queue),
+		slog.Int("limit", criteria.Limit),
+		slog.Int("offset", criteria.Offset),
+	)
+	jobs, total, err := h.queueService.SearchJobs(r.Context(), criteria)
 	if err != nil {
-		log.Printf("[GetDLQJobs] Failed to fetch DLQ jobs: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to search jobs",
+			slog.String("error", err.Error()),
+		)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("[GetDLQJobs] Found %d DLQ jobs (total=%d)", len(jobs), total)
+	slog.InfoContext(r.Context(), "Found jobs",
+		slog.Int("count", len(jobs)),
+		slog.Int64("total", total),
+	)
 
-	var responses []JobResponse
+	responses := make([]JobResponse, 0, len(jobs))
 	for _, job := range jobs {
-		var payload any
-		json.Unmarshal(job.Payload, &payload)
-
-		responses = append(responses, JobResponse{
-			ID:        job.ID.String(),
-			Queue:     job.Queue,
-			Type:      job.Type,
-			Status:    string(job.Status),
-			Attempts:  job.Attempts,
-			Payload:   payload,
-			Error:     job.Error,
-			CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt: job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		})
+		responses = append(responses, newJobResponse(job))
+	}
+
+	result := map[string]any{
+		"query":  criteria.Text,
+		"jobs":   responses,
+		"total":  total,
+		"limit":  criteria.Limit,
+		"offset": criteria.Offset,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *QueueHandlers) GetDLQJobs(w http.ResponseWriter, r *http.Request) {
+	pg, err := h.pages.parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, offset := pg.Limit, pg.Offset
+
+	includeInsights := r.URL.Query().Get("include") == "insights"
+	order := r.URL.Query().Get("sort")
+	if err := insights.ValidateDLQOrder(order); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if order == insights.DLQOrderActionability && (!includeInsights || h.insightsService == nil) {
+		http.Error(w, "sort=actionability requires include=insights", http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Fetching DLQ jobs",
+		slog.Int("limit", limit),
+		slog.Int("offset", offset),
+		slog.Bool("includeInsights", includeInsights),
+		slog.String("sort", order),
+	)
+	var responses []JobResponse
+	var total int64
+	if includeInsights && h.insightsService != nil {
+		entries, err := h.insightsService.GetDLQTriage(r.Context(), limit, offset, order)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to fetch DLQ triage",
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, entry := range entries {
+			response := newJobResponse(entry.Job)
+			if entry.Insight != nil {
+				insightResponse := newInsightResponse(entry.Insight)
+				response.Insight = &insightResponse
+			}
+			responses = append(responses, response)
+		}
+
+		total, err = h.queueService.CountDLQJobs(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to count DLQ jobs",
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		jobs, count, err := h.queueService.GetDLQJobs(r.Context(), limit, offset)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to fetch DLQ jobs",
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, job := range jobs {
+			responses = append(responses, newJobResponse(job))
+		}
+		total = count
 	}
+	slog.InfoContext(r.Context(), "Found DLQ jobs",
+		slog.Int("count", len(responses)),
+		slog.Int64("total", total),
+	)
 
 	result := map[string]any{
 		"jobs":   responses,
@@ -272,43 +766,1142 @@ func (h *QueueHandlers) GetDLQJobs(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *QueueHandlers) GetMetrics(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[GetMetrics] Fetching queue metrics")
+	slog.InfoContext(r.Context(), "Fetching queue metrics")
 	metrics, err := h.queueService.GetMetrics(r.Context())
 	if err != nil {
-		log.Printf("[GetMetrics] Failed to fetch metrics: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to fetch metrics",
+			slog.String("error", err.Error()),
+		)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("[GetMetrics] Metrics retrieved successfully")
+	slog.InfoContext(r.Context(), "Metrics retrieved successfully")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
 }
 
-func (h *QueueHandlers) RetryJob(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		log.Printf("[RetryJob] Missing job ID parameter")
-		http.Error(w, "job id is required", http.StatusBadRequest)
+const (
+	defaultHistoryWindow = 24 * time.Hour
+	maxHistoryWindow     = 30 * 24 * time.Hour
+)
+
+// QueueStatsPoint is one sample of a queue's job counts
+type QueueStatsPoint struct {
+	SampledAt  string `json:"sampled_at"`
+	Pending    int64  `json:"pending"`
+	Processing int64  `json:"processing"`
+	Retrying   int64  `json:"retrying"`
+	Failed     int64  `json:"failed"`
+	Completed  int64  `json:"completed"`
+	Backlog    int64  `json:"backlog"`    // Pending plus retrying
+	Throughput int64  `json:"throughput"` // Jobs completed since the previous sample
+}
+
+// QueueStatsHistoryResponse is a queue's job counts over time
+type QueueStatsHistoryResponse struct {
+	Queue  string            `json:"queue"`
+	Window string            `json:"window"`
+	Points []QueueStatsPoint `json:"points"`
+}
+
+// GetMetricsHistory returns a queue's sampled job counts over a window (default 24h)
+func (h *QueueHandlers) GetMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	queueName := r.URL.Query().Get("queue")
+	if queueName == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
 		return
 	}
 
-	id, err := uuid.Parse(idStr)
+	window, windowLabel := defaultHistoryWindow, "24h"
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		parsed, err := parseWindow(windowStr)
+		if err != nil || parsed < time.Minute || parsed > maxHistoryWindow {
+			http.Error(w, "window must be a duration between 1m and 30d, e.g. 6h or 7d", http.StatusBadRequest)
+			return
+		}
+		window, windowLabel = parsed, windowStr
+	}
+
+	history, err := h.queueService.GetQueueStatsHistory(r.Context(), queueName, window)
 	if err != nil {
-		log.Printf("[RetryJob] Invalid job ID: %s", idStr)
-		http.Error(w, "invalid job id", http.StatusBadRequest)
+		if errors.Is(err, appQueue.ErrStatsDisabled) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to fetch queue stats history",
+			slog.String("queue", queueName),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("[RetryJob] Retrying job: id=%s", id)
-	maxAttempts := 3
-	if err := h.queueService.RetryJob(r.Context(), id, maxAttempts); err != nil {
-		log.Printf("[RetryJob] Failed to retry job: %v", err)
+	throughput := queue.Throughput(history)
+	points := make([]QueueStatsPoint, len(history))
+	for i, sample := range history {
+		points[i] = QueueStatsPoint{
+			SampledAt:  sample.SampledAt.UTC().Format("2006-01-02T15:04:05Z"),
+			Pending:    sample.Pending,
+			Processing: sample.Processing,
+			Retrying:   sample.Retrying,
+			Failed:     sample.Failed,
+			Completed:  sample.Completed,
+			Backlog:    sample.Backlog(),
+			Throughput: throughput[i],
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueueStatsHistoryResponse{
+		Queue:  queueName,
+		Window: windowLabel,
+		Points: points,
+	})
+}
+
+// parseWindow accepts Go durations plus whole days, e.g. "90m", "24h" or "7d"
+func parseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// Scaling recommendation settings
+const (
+	maxScalingWindow = 24 * time.Hour
+	// ScalingMetricName names the desired replicas metric in the external metrics format
+	ScalingMetricName = "desired_worker_replicas"
+)
+
+// ScalingRecommendationResponse is the number of worker replicas a queue needs
+type ScalingRecommendationResponse struct {
+	Queue               string  `json:"queue"`
+	DesiredReplicas     int     `json:"desired_replicas"`
+	Backlog             int64   `json:"backlog"`
+	Processing          int64   `json:"processing"`
+	ArrivalRate         float64 `json:"arrival_rate"`          // Jobs created per second
+	CompletionRate      float64 `json:"completion_rate"`       // Jobs completed per second
+	AvgExecutionSeconds float64 `json:"avg_execution_seconds"` // Zero while no jobs completed in the window
+	SampledAt           string  `json:"sampled_at,omitempty"`
+}
+
+// ScalingResponse lists the scaling recommendations of the requested queues
+type ScalingResponse struct {
+	Window          string                          `json:"window"`
+	Recommendations []ScalingRecommendationResponse `json:"recommendations"`
+}
+
+// ExternalMetricValueList mirrors the Kubernetes external.metrics.k8s.io/v1beta1 list,
+// so the recommendation can back an HPA external metric or a KEDA metrics-api scaler
+type ExternalMetricValueList struct {
+	Kind       string                `json:"kind"`
+	APIVersion string                `json:"apiVersion"`
+	Metadata   struct{}              `json:"metadata"`
+	Items      []ExternalMetricValue `json:"items"`
+}
+
+// ExternalMetricValue is one metric sample of an ExternalMetricValueList
+type ExternalMetricValue struct {
+	MetricName   string            `json:"metricName"`
+	MetricLabels map[string]string `json:"metricLabels"`
+	Timestamp    string            `json:"timestamp"`
+	Value        string            `json:"value"` // Kubernetes quantity
+}
+
+// GetScalingRecommendation returns the desired worker replicas per queue, measured over a
+// window (default 15m). format=external returns a Kubernetes external metrics list instead.
+func (h *QueueHandlers) GetScalingRecommendation(w http.ResponseWriter, r *http.Request) {
+	queueName := r.URL.Query().Get("queue")
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "external" {
+		http.Error(w, "format must be external or omitted", http.StatusBadRequest)
+		return
+	}
+
+	window, windowLabel := appQueue.DefaultScalingWindow, "15m"
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		parsed, err := parseWindow(windowStr)
+		if err != nil || parsed < time.Minute || parsed > maxScalingWindow {
+			http.Error(w, "window must be a duration between 1m and 24h, e.g. 15m", http.StatusBadRequest)
+			return
+		}
+		window, windowLabel = parsed, windowStr
+	}
+
+	recommendations, err := h.queueService.RecommendScaling(r.Context(), queueName, window)
+	if err != nil {
+		if errors.Is(err, appQueue.ErrStatsDisabled) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to compute scaling recommendation",
+			slog.String("queue", queueName),
+			slog.String("error", err.Error()),
+		)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("[RetryJob] Job retry initiated: id=%s", id)
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "retrying"})
+	w.Header().Set("Content-Type", "application/json")
+	if format == "external" {
+		now := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+		items := make([]ExternalMetricValue, len(recommendations))
+		for i, rec := range recommendations {
+			items[i] = ExternalMetricValue{
+				MetricName:   ScalingMetricName,
+				MetricLabels: map[string]string{"queue": rec.Queue},
+				Timestamp:    now,
+				Value:        strconv.Itoa(rec.DesiredReplicas),
+			}
+		}
+		json.NewEncoder(w).Encode(ExternalMetricValueList{
+			Kind:       "ExternalMetricValueList",
+			APIVersion: "external.metrics.k8s.io/v1beta1",
+			Items:      items,
+		})
+		return
+	}
+
+	resp := ScalingResponse{
+		Window:          windowLabel,
+		Recommendations: make([]ScalingRecommendationResponse, len(recommendations)),
+	}
+	for i, rec := range recommendations {
+		item := ScalingRecommendationResponse{
+			Queue:               rec.Queue,
+			DesiredReplicas:     rec.DesiredReplicas,
+			Backlog:             rec.Backlog,
+			Processing:          rec.Processing,
+			ArrivalRate:         rec.ArrivalRate,
+			CompletionRate:      rec.CompletionRate,
+			AvgExecutionSeconds: rec.AvgExecutionTime.Seconds(),
+		}
+		if !rec.SampledAt.IsZero() {
+			item.SampledAt = rec.SampledAt.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		resp.Recommendations[i] = item
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// dashboardRecentInsights is how many of the latest insights the dashboard embeds
+const dashboardRecentInsights = 5
+
+type DashboardResponse struct {
+	GeneratedAt     string                 `json:"generated_at"`
+	Jobs            map[string]int64       `json:"jobs"`
+	DLQ             int64                  `json:"dlq"`
+	TopFailingTypes []TypeFailuresResponse `json:"top_failing_types"`
+	RecentInsights  []InsightResponse      `json:"recent_insights"`
+	Workers         []HeartbeatResponse    `json:"workers"`
+	Throughput      ThroughputResponse     `json:"throughput"`
+}
+
+type TypeFailuresResponse struct {
+	Type   string `json:"type"`
+	Failed int64  `json:"failed"`
+}
+
+type HeartbeatResponse struct {
+	WorkerID     string   `json:"worker_id"`
+	Queues       []string `json:"queues"`
+	Concurrency  int      `json:"concurrency"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	StartedAt    string   `json:"started_at"`
+	LastSeen     string   `json:"last_seen"`
+}
+
+type ThroughputResponse struct {
+	Window       string           `json:"window"`
+	Completed    int64            `json:"completed"`
+	Failed       int64            `json:"failed"`
+	PerMinute    float64          `json:"per_minute"`
+	DeadLettered map[string]int64 `json:"dead_lettered"` // Jobs moved to the DLQ by reason
+}
+
+// GetDashboard aggregates job counts, the DLQ size, failing types, recent insights, live
+// workers and the last hour's throughput. Insights are left out when they can't be loaded.
+func (h *QueueHandlers) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	dashboard, err := h.queueService.GetDashboard(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to build dashboard",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := DashboardResponse{
+		GeneratedAt:     dashboard.GeneratedAt.Format("2006-01-02T15:04:05Z"),
+		Jobs:            make(map[string]int64, len(dashboard.StatusCounts)),
+		DLQ:             dashboard.DLQ,
+		TopFailingTypes: make([]TypeFailuresResponse, len(dashboard.Activity.TopFailingTypes)),
+		RecentInsights:  []InsightResponse{},
+		Workers:         make([]HeartbeatResponse, len(dashboard.Workers)),
+		Throughput: ThroughputResponse{
+			Window:       appQueue.DashboardWindow.String(),
+			Completed:    dashboard.Activity.Completed,
+			Failed:       dashboard.Activity.Failed,
+			PerMinute:    dashboard.Activity.PerMinute(dashboard.GeneratedAt),
+			DeadLettered: make(map[string]int64, len(queue.DeadLetterReasons)),
+		},
+	}
+	for status, count := range dashboard.StatusCounts {
+		resp.Jobs[string(status)] = count
+	}
+	for _, reason := range queue.DeadLetterReasons {
+		resp.Throughput.DeadLettered[reason] = dashboard.Activity.DeadLettered[reason]
+	}
+	for i, failures := range dashboard.Activity.TopFailingTypes {
+		resp.TopFailingTypes[i] = TypeFailuresResponse{Type: failures.Type, Failed: failures.Failed}
+	}
+	for i, heartbeat := range dashboard.Workers {
+		resp.Workers[i] = HeartbeatResponse{
+			WorkerID:     heartbeat.WorkerID,
+			Queues:       heartbeat.Queues,
+			Concurrency:  heartbeat.Concurrency,
+			Capabilities: heartbeat.Capabilities,
+			StartedAt:    heartbeat.StartedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			LastSeen:     heartbeat.LastSeen.UTC().Format("2006-01-02T15:04:05Z"),
+		}
+	}
+
+	if h.insightsService != nil {
+		recent, err := h.insightsService.ListInsights(r.Context(), "", dashboardRecentInsights, 0)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to fetch recent insights for dashboard",
+				slog.String("error", err.Error()),
+			)
+		}
+		for _, insight := range recent {
+			resp.RecentInsights = append(resp.RecentInsights, newInsightResponse(insight))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListBreakers reports the per-type circuit breaker state published by the workers
+func (h *QueueHandlers) ListBreakers(w http.ResponseWriter, r *http.Request) {
+	breakers, err := h.queueService.ListBreakers(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch breakers",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	open := 0
+	for _, breaker := range breakers {
+		if breaker.State == worker.BreakerOpen {
+			open++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"breakers": breakers,
+		"open":     open,
+	})
+}
+
+func (h *QueueHandlers) RetryJob(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		slog.InfoContext(r.Context(), "Missing job ID parameter")
+		http.Error(w, "job id is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid job ID",
+			slog.String("jobId", idStr),
+		)
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Retrying job",
+		slog.String("jobId", id.String()),
+	)
+	maxAttempts := 3
+	if err := h.queueService.RetryJob(r.Context(), id, maxAttempts); err != nil {
+		if errors.Is(err, queue.ErrQueueDraining) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to retry job",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(r.Context(), "Job retry initiated",
+		slog.String("jobId", id.String()),
+	)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "retrying"})
+}
+
+// QueueDefinitionRequest is the body of POST /api/queues and PUT /api/queues/{name}.
+// The name is taken from the path on PUT.
+type QueueDefinitionRequest struct {
+	Name               string   `json:"name"`
+	MaxAttempts        int      `json:"max_attempts"`
+	BaseBackoffMs      int      `json:"base_backoff_ms"`
+	RateLimitPerSecond float64  `json:"rate_limit_per_second"`
+	AllowedTypes       []string `json:"allowed_types"`
+	// DefaultType is the type of the jobs created without one; it must be an allowed type
+	DefaultType   string `json:"default_type"`
+	StrictPayload bool   `json:"strict_payload"`
+	Paused        bool   `json:"paused"`
+	InsightPolicy string `json:"insight_policy"`
+	// Days completed jobs keep their payload and result; 0 uses retention.payload_days
+	PayloadRetentionDays int `json:"payload_retention_days"`
+}
+
+type QueueDefinitionResponse struct {
+	Name                 string   `json:"name"`
+	MaxAttempts          int      `json:"max_attempts"`
+	BaseBackoffMs        int      `json:"base_backoff_ms"`
+	RateLimitPerSecond   float64  `json:"rate_limit_per_second"`
+	AllowedTypes         []string `json:"allowed_types"`
+	DefaultType          string   `json:"default_type"`
+	StrictPayload        bool     `json:"strict_payload"`
+	Paused               bool     `json:"paused"`
+	InsightPolicy        string   `json:"insight_policy"`
+	PayloadRetentionDays int      `json:"payload_retention_days"`
+	CreatedAt            string   `json:"created_at"`
+	UpdatedAt            string   `json:"updated_at"`
+}
+
+func newQueueDefinitionResponse(def *queue.Definition) QueueDefinitionResponse {
+	allowed := def.AllowedTypes
+	if allowed == nil {
+		allowed = []string{}
+	}
+	return QueueDefinitionResponse{
+		Name:                 def.Name,
+		MaxAttempts:          def.MaxAttempts,
+		BaseBackoffMs:        def.BaseBackoffMs,
+		RateLimitPerSecond:   def.RateLimitPerSecond,
+		AllowedTypes:         allowed,
+		DefaultType:          def.DefaultType,
+		StrictPayload:        def.StrictPayload,
+		Paused:               def.Paused,
+		InsightPolicy:        string(def.InsightPolicy),
+		PayloadRetentionDays: def.PayloadRetentionDays,
+		CreatedAt:            def.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:            def.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func (req QueueDefinitionRequest) definition() *queue.Definition {
+	return &queue.Definition{
+		Name:                 req.Name,
+		MaxAttempts:          req.MaxAttempts,
+		BaseBackoffMs:        req.BaseBackoffMs,
+		RateLimitPerSecond:   req.RateLimitPerSecond,
+		AllowedTypes:         req.AllowedTypes,
+		DefaultType:          req.DefaultType,
+		StrictPayload:        req.StrictPayload,
+		Paused:               req.Paused,
+		InsightPolicy:        queue.InsightPolicy(req.InsightPolicy),
+		PayloadRetentionDays: req.PayloadRetentionDays,
+	}
+}
+
+// QueueJobCountsResponse counts a queue's live jobs by status
+type QueueJobCountsResponse struct {
+	Pending    int64 `json:"pending"`
+	Processing int64 `json:"processing"`
+	Retrying   int64 `json:"retrying"`
+	Failed     int64 `json:"failed"`
+	Completed  int64 `json:"completed"`
+}
+
+// QueueResponse is a queue with its job counts. The definition's fields are left out of
+// queues that only exist through their jobs.
+type QueueResponse struct {
+	*QueueDefinitionResponse
+	Name    string                 `json:"name"`
+	Defined bool                   `json:"defined"`
+	Jobs    QueueJobCountsResponse `json:"jobs"`
+}
+
+func newQueueResponse(summary *appQueue.QueueSummary) QueueResponse {
+	resp := QueueResponse{
+		Name:    summary.Name,
+		Defined: summary.Definition != nil,
+		Jobs: QueueJobCountsResponse{
+			Pending:    summary.Counts.Pending,
+			Processing: summary.Counts.Processing,
+			Retrying:   summary.Counts.Retrying,
+			Failed:     summary.Counts.Failed,
+			Completed:  summary.Counts.Completed,
+		},
+	}
+	if summary.Definition != nil {
+		def := newQueueDefinitionResponse(summary.Definition)
+		resp.QueueDefinitionResponse = &def
+	}
+	return resp
+}
+
+// ListQueues returns every queue that is defined or has jobs, with its job counts and settings
+func (h *QueueHandlers) ListQueues(w http.ResponseWriter, r *http.Request) {
+	queues, err := h.queueService.ListQueues(r.Context())
+	if err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+
+	response := make([]QueueResponse, len(queues))
+	for i, summary := range queues {
+		response[i] = newQueueResponse(summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"queues": response,
+		"total":  len(response),
+	})
+}
+
+// ListQueueJobs returns a page of the jobs of the queue named in the path, newest first
+func (h *QueueHandlers) ListQueueJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pg, err := h.pages.parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	listing, err := queue.NewQueueListing(queueNameFromPath(r), queue.Status(query.Get("status")), pg.Limit, pg.Offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	listing.CreatedBy = query.Get("created_by")
+	if listing.Metadata, err = queue.ParseMetadataFilter(query["metadata"]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jobs, total, err := h.queueService.ListQueueJobs(r.Context(), listing)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list queue jobs",
+			slog.String("queue", listing.Queue),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]JobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		responses = append(responses, newJobResponse(job))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"queue":      listing.Queue,
+		"status":     string(listing.Status),
+		"created_by": listing.CreatedBy,
+		"jobs":       responses,
+		"total":      total,
+		"limit":      listing.Limit,
+		"offset":     listing.Offset,
+	})
+}
+
+type PurgeQueueResponse struct {
+	Queue   string `json:"queue"`
+	Status  string `json:"status"`
+	DryRun  bool   `json:"dry_run"`
+	Matched int64  `json:"matched"`
+	Purged  int64  `json:"purged"`
+	Skipped int64  `json:"skipped"`
+}
+
+// PurgeQueueJobs removes the pending jobs of the queue named in the path and cancels them.
+// With dry_run=true it only returns how many jobs would be purged.
+func (h *QueueHandlers) PurgeQueueJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dryRun := false
+	if dryRunStr := query.Get("dry_run"); dryRunStr != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			http.Error(w, "dry_run must be a boolean", http.StatusBadRequest)
+			return
+		}
+	}
+
+	name := queueNameFromPath(r)
+	report, err := h.queueService.PurgeQueue(r.Context(), name, queue.Status(query.Get("status")), dryRun)
+	switch {
+	case err == nil:
+	case errors.Is(err, appQueue.ErrPurgeDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, queue.ErrStatusNotPurgeable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	default:
+		slog.ErrorContext(r.Context(), "Failed to purge queue",
+			slog.String("queue", name),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PurgeQueueResponse{
+		Queue:   report.Queue,
+		Status:  string(report.Status),
+		DryRun:  report.DryRun,
+		Matched: report.Matched,
+		Purged:  report.Purged,
+		Skipped: report.Skipped,
+	})
+}
+
+type ParkedReleaseResponse struct {
+	Queue    string `json:"queue"`
+	Released int    `json:"released"`
+}
+
+// ReleaseParkedJobs retries the jobs of the queue named in the path that were parked while its
+// retry budget was exhausted, up to count of them
+func (h *QueueHandlers) ReleaseParkedJobs(w http.ResponseWriter, r *http.Request) {
+	count := 0
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		var err error
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count <= 0 || count > appQueue.MaxParkedRelease {
+			http.Error(w, "count must be between 1 and "+strconv.Itoa(appQueue.MaxParkedRelease), http.StatusBadRequest)
+			return
+		}
+	}
+
+	name := queueNameFromPath(r)
+	release, err := h.queueService.ReleaseParkedJobs(r.Context(), name, count)
+	switch {
+	case err == nil:
+	case errors.Is(err, appQueue.ErrParkedJobsDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, queue.ErrQueueDraining):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		slog.ErrorContext(r.Context(), "Failed to release parked jobs",
+			slog.String("queue", name),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ParkedReleaseResponse{
+		Queue:    release.Queue,
+		Released: release.Released,
+	})
+}
+
+// CreateQueueDefinition defines a new queue
+func (h *QueueHandlers) CreateQueueDefinition(w http.ResponseWriter, r *http.Request) {
+	var req QueueDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	def := req.definition()
+	if err := h.queueService.DefineQueue(r.Context(), def); err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Queue defined",
+		slog.String("queue", def.Name),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newQueueDefinitionResponse(def))
+}
+
+// GetQueueDefinition returns the definition of the queue named in the path
+func (h *QueueHandlers) GetQueueDefinition(w http.ResponseWriter, r *http.Request) {
+	def, err := h.queueService.GetQueueDefinition(r.Context(), queueNameFromPath(r))
+	if err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newQueueDefinitionResponse(def))
+}
+
+// UpdateQueueDefinition replaces the settings of the queue named in the path
+func (h *QueueHandlers) UpdateQueueDefinition(w http.ResponseWriter, r *http.Request) {
+	var req QueueDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	def := req.definition()
+	def.Name = queueNameFromPath(r)
+	if err := h.queueService.UpdateQueueDefinition(r.Context(), def); err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Queue definition updated",
+		slog.String("queue", def.Name),
+		slog.Bool("paused", def.Paused),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newQueueDefinitionResponse(def))
+}
+
+// DeleteQueueDefinition removes the definition of the queue named in the path
+func (h *QueueHandlers) DeleteQueueDefinition(w http.ResponseWriter, r *http.Request) {
+	name := queueNameFromPath(r)
+	if err := h.queueService.DeleteQueueDefinition(r.Context(), name); err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Queue definition deleted",
+		slog.String("queue", name),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"name": name, "status": "deleted"})
+}
+
+// queueNameFromPath extracts the queue name from /api/queues/{name}, /api/queues/{name}/drain
+// and /api/queues/{name}/jobs
+func queueNameFromPath(r *http.Request) string {
+	rest := queuePath(r)
+	if action := queueAction(r); action != "" {
+		return strings.TrimSuffix(rest, "/"+action)
+	}
+	return rest
+}
+
+// isDrainPath reports whether the request targets /api/queues/{name}/drain
+func isDrainPath(r *http.Request) bool {
+	return queueAction(r) == "drain"
+}
+
+// isQueueJobsPath reports whether the request targets /api/queues/{name}/jobs
+func isQueueJobsPath(r *http.Request) bool {
+	return queueAction(r) == "jobs"
+}
+
+// isParkedReleasePath reports whether the request targets /api/queues/{name}/parked/release
+func isParkedReleasePath(r *http.Request) bool {
+	return queueAction(r) == "parked/release"
+}
+
+// queueAction returns the drain, jobs or parked/release action of a queue path, empty for any
+// other path
+func queueAction(r *http.Request) string {
+	name, action, found := strings.Cut(queuePath(r), "/")
+	if !found || name == "" || (action != "drain" && action != "jobs" && action != "parked/release") {
+		return ""
+	}
+	return action
+}
+
+func queuePath(r *http.Request) string {
+	return strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/queues/"), "/")
+}
+
+type DrainStatusResponse struct {
+	Queue    string `json:"queue"`
+	Draining bool   `json:"draining"`
+	Drained  bool   `json:"drained"`
+	Ready    int64  `json:"ready"`
+	InFlight int64  `json:"in_flight"`
+}
+
+func writeDrainStatus(w http.ResponseWriter, status *appQueue.DrainStatus, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(DrainStatusResponse{
+		Queue:    status.Queue,
+		Draining: status.Draining,
+		Drained:  status.Drained(),
+		Ready:    status.Ready,
+		InFlight: status.InFlight,
+	})
+}
+
+// DrainQueue stops a queue accepting jobs so workers can finish the queued ones. Responds 202
+// while jobs remain and 200 once the queue is drained; poll GetDrainStatus until drained.
+func (h *QueueHandlers) DrainQueue(w http.ResponseWriter, r *http.Request) {
+	status, err := h.queueService.DrainQueue(r.Context(), queueNameFromPath(r))
+	if err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Queue draining",
+		slog.String("queue", status.Queue),
+		slog.Int64("ready", status.Ready),
+		slog.Int64("inFlight", status.InFlight),
+	)
+
+	code := http.StatusAccepted
+	if status.Drained() {
+		code = http.StatusOK
+	}
+	writeDrainStatus(w, status, code)
+}
+
+// GetDrainStatus reports whether a queue is draining and how many jobs are left in it
+func (h *QueueHandlers) GetDrainStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.queueService.GetDrainStatus(r.Context(), queueNameFromPath(r))
+	if err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+	writeDrainStatus(w, status, http.StatusOK)
+}
+
+// ResumeQueue makes a draining queue accept jobs again
+func (h *QueueHandlers) ResumeQueue(w http.ResponseWriter, r *http.Request) {
+	status, err := h.queueService.ResumeQueue(r.Context(), queueNameFromPath(r))
+	if err != nil {
+		writeQueueDefinitionError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Queue resumed",
+		slog.String("queue", status.Queue),
+	)
+	writeDrainStatus(w, status, http.StatusOK)
+}
+
+func writeQueueDefinitionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, appQueue.ErrDefinitionsDisabled), errors.Is(err, appQueue.ErrQueueCountsDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, queue.ErrQueueNotDefined):
+		http.Error(w, "queue not found", http.StatusNotFound)
+	case errors.Is(err, queue.ErrQueueDefined):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, queue.ErrInvalidQueueName), errors.Is(err, queue.ErrInvalidDefinition),
+		errors.Is(err, queue.ErrInvalidInsightPolicy), errors.Is(err, queue.ErrInvalidDefaultType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.ErrorContext(r.Context(), "Queue definition request failed",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ResourceUsageResponse is what a caller used of one resource; remaining is left out when unlimited
+type ResourceUsageResponse struct {
+	Used      int64  `json:"used"`
+	Limit     int    `json:"limit"`
+	Remaining *int64 `json:"remaining,omitempty"`
+}
+
+type QuotaUsageResponse struct {
+	Day            string                `json:"day"`
+	ResetsAt       string                `json:"resets_at"`
+	JobsPerDay     ResourceUsageResponse `json:"jobs_per_day"`
+	PendingJobs    ResourceUsageResponse `json:"pending_jobs"`
+	AnalysesPerDay ResourceUsageResponse `json:"analyses_per_day"`
+}
+
+func newResourceUsageResponse(report *appQuota.Report, resource quota.Resource) ResourceUsageResponse {
+	resp := ResourceUsageResponse{
+		Used:  report.Usage.Used(resource),
+		Limit: report.Quota.Limit(resource),
+	}
+	if resp.Limit > 0 {
+		remaining := max(0, int64(resp.Limit)-resp.Used)
+		resp.Remaining = &remaining
+	}
+	return resp
+}
+
+// GetUsage reports the quota of the calling API key's owner and what it used of it today;
+// callers without an API key get the quota they share
+func (h *QueueHandlers) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if h.quotaService == nil {
+		http.Error(w, "quotas are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	tenant := quota.Tenant(h.queueService.OwnerOf(r.Header.Get(h.apiKeyHeader)))
+	report, err := h.quotaService.GetUsage(r.Context(), tenant)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get quota usage",
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QuotaUsageResponse{
+		Day:            report.Usage.Day.Format("2006-01-02"),
+		ResetsAt:       report.ResetsAt.Format("2006-01-02T15:04:05Z"),
+		JobsPerDay:     newResourceUsageResponse(report, quota.ResourceJobs),
+		PendingJobs:    newResourceUsageResponse(report, quota.ResourcePendingJobs),
+		AnalysesPerDay: newResourceUsageResponse(report, quota.ResourceAnalyses),
+	})
+}
+
+// writeQuotaExceeded answers 429 for a request over the caller's quota. Daily quotas carry a
+// Retry-After until they reset; pending jobs free up as jobs finish.
+func writeQuotaExceeded(w http.ResponseWriter, r *http.Request, err error) {
+	body := map[string]any{"error": err.Error()}
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		body["quota"] = exceeded.Resource
+		body["limit"] = exceeded.Limit
+		if exceeded.RetryAfter > 0 {
+			retryAfter := max(1, int(math.Ceil(exceeded.RetryAfter.Seconds())))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			body["retry_after"] = retryAfter
+		}
+	}
+	slog.WarnContext(r.Context(), "Request over quota",
+		slog.String("error", err.Error()),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(body)
+}
+
+// StrandedJobResponse is a job the database says is ready that the queue backend doesn't hold
+type StrandedJobResponse struct {
+	JobID     string `json:"job_id"`
+	Queue     string `json:"queue"`
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	UpdatedAt string `json:"updated_at"`
+	Repaired  bool   `json:"repaired"`
+}
+
+// ConsistencyReportResponse is the outcome of a consistency check
+type ConsistencyReportResponse struct {
+	CheckedAt string                `json:"checked_at"`
+	Queues    []string              `json:"queues"`
+	Checked   int                   `json:"checked"`
+	Stranded  []StrandedJobResponse `json:"stranded"`
+	Repaired  int                   `json:"repaired"`
+	Truncated bool                  `json:"truncated"`
+}
+
+// CheckConsistency reports the jobs ready to run in Postgres that Redis doesn't hold
+func (h *QueueHandlers) CheckConsistency(w http.ResponseWriter, r *http.Request) {
+	h.checkConsistency(w, r, false)
+}
+
+// RepairStrandedJobs re-enqueues the jobs ready to run in Postgres that Redis doesn't hold
+func (h *QueueHandlers) RepairStrandedJobs(w http.ResponseWriter, r *http.Request) {
+	h.checkConsistency(w, r, true)
+}
+
+func (h *QueueHandlers) checkConsistency(w http.ResponseWriter, r *http.Request, repair bool) {
+	check := appQueue.ConsistencyCheck{Queue: r.URL.Query().Get("queue"), Repair: repair}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > appQueue.MaxConsistencyLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(appQueue.MaxConsistencyLimit), http.StatusBadRequest)
+			return
+		}
+		check.Limit = limit
+	}
+
+	report, err := h.queueService.CheckConsistency(r.Context(), check)
+	if err != nil {
+		if errors.Is(err, appQueue.ErrConsistencyCheckDisabled) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to check queue consistency",
+			slog.String("queue", check.Queue),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stranded := make([]StrandedJobResponse, len(report.Stranded))
+	for i, entry := range report.Stranded {
+		stranded[i] = StrandedJobResponse{
+			JobID:     entry.Job.ID.String(),
+			Queue:     entry.Job.Queue,
+			Type:      entry.Job.Type,
+			Status:    string(entry.Job.Status),
+			UpdatedAt: entry.Job.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			Repaired:  entry.Repaired,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConsistencyReportResponse{
+		CheckedAt: report.CheckedAt.Format("2006-01-02T15:04:05Z"),
+		Queues:    report.Queues,
+		Checked:   report.Checked,
+		Stranded:  stranded,
+		Repaired:  report.Repaired,
+		Truncated: report.Truncated,
+	})
+}
+
+// DeadLettersResponse lists the oldest dead letters Redis keeps for a queue
+type DeadLettersResponse struct {
+	Queue string        `json:"queue"`
+	Total int64         `json:"total"`
+	Jobs  []JobResponse `json:"jobs"`
+}
+
+// ReplayedJobResponse is the outcome of replaying one dead letter
+type ReplayedJobResponse struct {
+	JobID    string `json:"job_id"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Replayed bool   `json:"replayed"`
+	Skipped  string `json:"skipped,omitempty"`
+}
+
+// ReplayReportResponse is the outcome of replaying dead letters
+type ReplayReportResponse struct {
+	Queue     string                `json:"queue"`
+	Replayed  int                   `json:"replayed"`
+	Skipped   int                   `json:"skipped"`
+	Remaining int64                 `json:"remaining"`
+	Jobs      []ReplayedJobResponse `json:"jobs"`
+}
+
+// GetDeadLetters returns the oldest dead letters Redis keeps for a queue, as the jobs were when they failed
+func (h *QueueHandlers) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	queueName := r.URL.Query().Get("queue")
+	if queueName == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
+		return
+	}
+	limit := appQueue.DefaultDeadLetterPeek
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 || l > appQueue.MaxDeadLetterPeek {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(appQueue.MaxDeadLetterPeek), http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	jobs, total, err := h.queueService.PeekDeadLetters(r.Context(), queueName, limit)
+	if err != nil {
+		h.deadLetterError(w, r, queueName, err)
+		return
+	}
+
+	response := DeadLettersResponse{Queue: queueName, Total: total, Jobs: make([]JobResponse, 0, len(jobs))}
+	for _, job := range jobs {
+		response.Jobs = append(response.Jobs, newJobResponse(job))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DumpDeadLetters downloads every dead letter Redis keeps for a queue
+func (h *QueueHandlers) DumpDeadLetters(w http.ResponseWriter, r *http.Request) {
+	queueName := r.URL.Query().Get("queue")
+	if queueName == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := h.queueService.DumpDeadLetters(r.Context(), queueName)
+	if err != nil {
+		h.deadLetterError(w, r, queueName, err)
+		return
+	}
+
+	response := make([]JobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		response = append(response, newJobResponse(job))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "dlq-" + queueName + ".json"}))
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReplayDeadLetters retries the jobs of a queue's oldest dead letters
+func (h *QueueHandlers) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	queueName := r.URL.Query().Get("queue")
+	if queueName == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
+		return
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count <= 0 || count > appQueue.MaxDeadLetterReplay {
+		http.Error(w, "count must be between 1 and "+strconv.Itoa(appQueue.MaxDeadLetterReplay), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.queueService.ReplayDeadLetters(r.Context(), queueName, count)
+	if err != nil {
+		h.deadLetterError(w, r, queueName, err)
+		return
+	}
+
+	response := ReplayReportResponse{
+		Queue:     report.Queue,
+		Replayed:  report.Replayed,
+		Skipped:   report.Skipped,
+		Remaining: report.Remaining,
+		Jobs:      make([]ReplayedJobResponse, 0, len(report.Jobs)),
+	}
+	for _, entry := range report.Jobs {
+		response.Jobs = append(response.Jobs, ReplayedJobResponse{
+			JobID:    entry.Job.ID.String(),
+			Type:     entry.Job.Type,
+			Status:   string(entry.Job.Status),
+			Replayed: entry.Replayed,
+			Skipped:  entry.Skipped,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *QueueHandlers) deadLetterError(w http.ResponseWriter, r *http.Request, queueName string, err error) {
+	switch {
+	case errors.Is(err, appQueue.ErrDeadLettersDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, queue.ErrQueueDraining):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.ErrorContext(r.Context(), "Failed to access dead letters",
+			slog.String("queue", queueName),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
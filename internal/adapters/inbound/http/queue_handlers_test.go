@@ -4,15 +4,25 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
 	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	appQuota "github.com/erickfunier/ai-smart-queue/internal/application/quota"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestQueueHandlers_CreateJob(t *testing.T) {
@@ -44,6 +54,114 @@ func TestQueueHandlers_CreateJob(t *testing.T) {
 				assert.Equal(t, "pending", resp.Status)
 			},
 		},
+		{
+			name:  "Create job with callback URL",
+			given: "a job creation request with an https callback_url",
+			when:  "POST to /api/jobs",
+			then:  "should return 201 and echo the callback URL",
+			requestBody: CreateJobRequest{
+				Queue:       "default",
+				Type:        "email",
+				Payload:     map[string]any{"to": "test@example.com"},
+				CallbackURL: "https://example.com/hooks/jobs",
+			},
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "https://example.com/hooks/jobs", resp.CallbackURL)
+			},
+		},
+		{
+			name:  "Reject invalid callback URL",
+			given: "a job creation request with a non-http callback_url",
+			when:  "POST to /api/jobs",
+			then:  "should return 400",
+			requestBody: CreateJobRequest{
+				Queue:       "default",
+				Type:        "email",
+				CallbackURL: "ftp://example.com/hooks",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "Create delayed job",
+			given: "a job creation request with scheduled_for in the future",
+			when:  "POST to /api/jobs",
+			then:  "should return 201 and echo when the job will be enqueued",
+			requestBody: map[string]any{
+				"queue":         "default",
+				"type":          "email",
+				"scheduled_for": "2099-01-01T09:00:00Z",
+			},
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "pending", resp.Status)
+				assert.Equal(t, "2099-01-01T09:00:00Z", resp.ScheduledFor)
+			},
+		},
+		{
+			name:  "Create job requiring capabilities",
+			given: "a job creation request requiring gpu and region=eu",
+			when:  "POST to /api/jobs",
+			then:  "should return 201 and echo the normalized capabilities",
+			requestBody: CreateJobRequest{
+				Queue:    "default",
+				Type:     "render",
+				Requires: []string{"region=EU", "gpu"},
+			},
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, []string{"gpu", "region=eu"}, resp.Requires)
+			},
+		},
+		{
+			name:  "Reject invalid capability",
+			given: "a job creation request requiring a capability with a space",
+			when:  "POST to /api/jobs",
+			then:  "should return 400",
+			requestBody: CreateJobRequest{
+				Queue:    "default",
+				Type:     "render",
+				Requires: []string{"big gpu"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "Create job with metadata",
+			given: "a job creation request carrying a correlation ID and a traceparent",
+			when:  "POST to /api/jobs",
+			then:  "should return 201 and echo the metadata apart from the payload",
+			requestBody: CreateJobRequest{
+				Queue:    "default",
+				Type:     "email",
+				Payload:  map[string]any{"to": "user@example.com"},
+				Metadata: map[string]string{"Correlation_ID": "abc-123", "traceparent": "00-4bf92f-00f067-01"},
+			},
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, map[string]string{"correlation_id": "abc-123", "traceparent": "00-4bf92f-00f067-01"}, resp.Metadata)
+				assert.Equal(t, map[string]any{"to": "user@example.com"}, resp.Payload)
+			},
+		},
+		{
+			name:  "Reject invalid metadata",
+			given: "a job creation request with a metadata key containing a space",
+			when:  "POST to /api/jobs",
+			then:  "should return 400",
+			requestBody: CreateJobRequest{
+				Queue:    "default",
+				Type:     "email",
+				Metadata: map[string]string{"source system": "billing"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "Invalid JSON request",
 			given:          "malformed JSON in request body",
@@ -86,6 +204,130 @@ func TestQueueHandlers_CreateJob(t *testing.T) {
 	}
 }
 
+// FinishingWaiter stands in for a worker: when a job is waited on it stores it with the given
+// status and reports it finished; with no status the job never finishes
+type FinishingWaiter struct {
+	repo   *InMemoryJobRepo
+	status queue.Status
+	result []byte
+}
+
+func (w *FinishingWaiter) Wait(jobID uuid.UUID) (<-chan struct{}, func()) {
+	done := make(chan struct{})
+	if w.status != "" {
+		job := w.repo.jobs[jobID]
+		job.Status = w.status
+		job.Result = w.result
+		close(done)
+	}
+	return done, func() {}
+}
+
+func TestQueueHandlers_ExecuteJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		status         queue.Status // Status a worker leaves the job in; empty never finishes it
+		disabled       bool
+		query          string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Job completes within the timeout",
+			given:          "a worker completing the job with a result",
+			when:           "POST to /api/jobs/execute",
+			then:           "should return 200 with the completed job and its result",
+			status:         queue.StatusCompleted,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "completed", resp.Status)
+				assert.Equal(t, map[string]any{"sent": true}, resp.Result)
+			},
+		},
+		{
+			name:           "Job fails within the timeout",
+			given:          "a worker failing the job for good",
+			when:           "POST to /api/jobs/execute",
+			then:           "should return 200 with the failed job",
+			status:         queue.StatusFailed,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "failed", resp.Status)
+			},
+		},
+		{
+			name:           "Timeout passes first",
+			given:          "no worker finishing the job",
+			when:           "POST to /api/jobs/execute?timeout=1",
+			then:           "should return 202 with the pending job and where to poll it",
+			query:          "?timeout=1",
+			expectedStatus: http.StatusAccepted,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "pending", resp.Status)
+				assert.Equal(t, "/api/jobs/"+resp.ID, rec.Header().Get("Location"))
+			},
+		},
+		{
+			name:           "Timeout over the maximum",
+			given:          "a timeout of 10 minutes",
+			when:           "POST to /api/jobs/execute?timeout=600",
+			then:           "should return 400",
+			query:          "?timeout=600",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Malformed timeout",
+			given:          "a timeout that isn't a number",
+			when:           "POST to /api/jobs/execute?timeout=soon",
+			then:           "should return 400",
+			query:          "?timeout=soon",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Synchronous execution not enabled",
+			given:          "no completion waiter",
+			when:           "POST to /api/jobs/execute",
+			then:           "should return 503",
+			disabled:       true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			service := appQueue.NewService(repo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			if !tt.disabled {
+				service.SetCompletionWaiter(&FinishingWaiter{repo: repo, status: tt.status, result: []byte(`{"sent":true}`)})
+			}
+			handlers := NewQueueHandlers(service, nil)
+
+			body, _ := json.Marshal(CreateJobRequest{Queue: "default", Type: "email", Payload: map[string]any{"to": "test@example.com"}})
+			req := httptest.NewRequest(http.MethodPost, "/api/jobs/execute"+tt.query, bytes.NewBuffer(body))
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.ExecuteJob(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
+
 // In-memory implementations for testing
 type InMemoryJobRepo struct {
 	jobs map[uuid.UUID]*queue.Job
@@ -103,24 +345,70 @@ func (r *InMemoryJobRepo) GetByID(ctx context.Context, id uuid.UUID) (*queue.Job
 	return nil, queue.ErrJobNotFound
 }
 
+func (r *InMemoryJobRepo) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*queue.Job, error) {
+	return r.GetByID(ctx, id)
+}
+
 func (r *InMemoryJobRepo) Update(ctx context.Context, job *queue.Job) error {
+	job.Version++
 	r.jobs[job.ID] = job
 	return nil
 }
 
 func (r *InMemoryJobRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	delete(r.jobs, id)
+	job, ok := r.jobs[id]
+	if !ok || job.IsDeleted() {
+		return queue.ErrJobNotFound
+	}
+	now := time.Now().UTC()
+	job.DeletedAt = &now
+	return nil
+}
+
+func (r *InMemoryJobRepo) Undelete(ctx context.Context, id uuid.UUID) error {
+	job, ok := r.jobs[id]
+	if !ok || !job.IsDeleted() {
+		return queue.ErrJobNotFound
+	}
+	job.DeletedAt = nil
 	return nil
 }
 
+func (r *InMemoryJobRepo) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	for id, job := range r.jobs {
+		if job.IsDeleted() && job.DeletedAt.Before(deletedBefore) {
+			delete(r.jobs, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
 func (r *InMemoryJobRepo) FindPendingJobs(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
-	return nil, nil
+	var result []*queue.Job
+	for _, job := range r.jobs {
+		if job.Queue == queueName && job.IsReady() && !job.IsDeleted() && len(result) < limit {
+			result = append(result, job)
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryJobRepo) FindDueDelayedJobs(ctx context.Context, now time.Time, limit int) ([]*queue.Job, error) {
+	var result []*queue.Job
+	for _, job := range r.jobs {
+		if job.IsDelayed() && !job.ScheduledFor.After(now) && !job.IsDeleted() && len(result) < limit {
+			result = append(result, job)
+		}
+	}
+	return result, nil
 }
 
 func (r *InMemoryJobRepo) FindByStatus(ctx context.Context, status queue.Status, limit int) ([]*queue.Job, error) {
 	var result []*queue.Job
 	for _, job := range r.jobs {
-		if job.Status == status && len(result) < limit {
+		if job.Status == status && !job.IsDeleted() && len(result) < limit {
 			result = append(result, job)
 		}
 	}
@@ -128,7 +416,113 @@ func (r *InMemoryJobRepo) FindByStatus(ctx context.Context, status queue.Status,
 }
 
 func (r *InMemoryJobRepo) CountByStatus(ctx context.Context, status queue.Status) (int64, error) {
-	return 0, nil
+	var count int64
+	for _, job := range r.jobs {
+		if job.Status == status && !job.IsDeleted() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryJobRepo) CountDelayed(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	for _, job := range r.jobs {
+		if job.IsDelayed() && job.ScheduledFor.After(now) && !job.IsDeleted() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryJobRepo) Search(ctx context.Context, criteria queue.SearchCriteria) ([]*queue.Job, int64, error) {
+	var matches []*queue.Job
+	for _, job := range r.jobs {
+		text := strings.ToLower(job.Error + " " + string(job.Payload))
+		if !job.IsDeleted() && strings.Contains(text, strings.ToLower(criteria.Text)) &&
+			(criteria.Status == "" || job.Status == criteria.Status) {
+			matches = append(matches, job)
+		}
+	}
+	return matches, int64(len(matches)), nil
+}
+
+func (r *InMemoryJobRepo) ListByQueue(ctx context.Context, listing queue.QueueListing) ([]*queue.Job, int64, error) {
+	var matches []*queue.Job
+	for _, job := range r.jobs {
+		if !job.IsDeleted() && (listing.Queue == "" || job.Queue == listing.Queue) &&
+			(listing.Status == "" || job.Status == listing.Status) &&
+			(listing.CreatedBy == "" || job.CreatedBy == listing.CreatedBy) &&
+			listing.Metadata.Matches(job.Metadata) {
+			matches = append(matches, job)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].CreatedAt.After(matches[j].CreatedAt) })
+	total := int64(len(matches))
+	matches = matches[min(listing.Offset, len(matches)):]
+	return matches[:min(listing.Limit, len(matches))], total, nil
+}
+
+// CountByQueue counts the live jobs of every queue, so the repo doubles as a queue counter
+func (r *InMemoryJobRepo) CountByQueue(ctx context.Context) ([]*queue.QueueStats, error) {
+	counts := make(map[string]*queue.QueueStats)
+	for _, job := range r.jobs {
+		if job.IsDeleted() {
+			continue
+		}
+		c, ok := counts[job.Queue]
+		if !ok {
+			c = &queue.QueueStats{Queue: job.Queue}
+			counts[job.Queue] = c
+		}
+		switch job.Status {
+		case queue.StatusPending:
+			c.Pending++
+		case queue.StatusProcessing:
+			c.Processing++
+		case queue.StatusRetrying:
+			c.Retrying++
+		case queue.StatusFailed:
+			c.Failed++
+		case queue.StatusCompleted:
+			c.Completed++
+		}
+	}
+	stats := make([]*queue.QueueStats, 0, len(counts))
+	for _, c := range counts {
+		stats = append(stats, c)
+	}
+	return stats, nil
+}
+
+func (r *InMemoryJobRepo) Activity(ctx context.Context, since time.Time, topTypes int) (*queue.Activity, error) {
+	activity := &queue.Activity{Since: since, TopFailingTypes: []*queue.TypeFailures{}, DeadLettered: map[string]int64{}}
+	failures := make(map[string]int64)
+	for _, job := range r.jobs {
+		if job.IsDeleted() || job.UpdatedAt.Before(since) {
+			continue
+		}
+		switch job.Status {
+		case queue.StatusCompleted:
+			activity.Completed++
+		case queue.StatusFailed, queue.StatusRetrying:
+			activity.Failed++
+			failures[job.Type]++
+		}
+		if job.DeadLetterReason != "" {
+			activity.DeadLettered[job.DeadLetterReason]++
+		}
+	}
+	for jobType, failed := range failures {
+		activity.TopFailingTypes = append(activity.TopFailingTypes, &queue.TypeFailures{Type: jobType, Failed: failed})
+	}
+	sort.Slice(activity.TopFailingTypes, func(i, j int) bool {
+		return activity.TopFailingTypes[i].Failed > activity.TopFailingTypes[j].Failed
+	})
+	if len(activity.TopFailingTypes) > topTypes {
+		activity.TopFailingTypes = activity.TopFailingTypes[:topTypes]
+	}
+	return activity, nil
 }
 
 func (r *InMemoryJobRepo) GetDLQJobs(ctx context.Context, limit, offset int) ([]*queue.Job, error) {
@@ -143,8 +537,36 @@ func (r *InMemoryJobRepo) CountDLQJobs(ctx context.Context) (int64, error) {
 	return 0, nil
 }
 
+func (r *InMemoryJobRepo) CountPurgeable(ctx context.Context, queueName string, status queue.Status) (int64, error) {
+	jobs, _ := r.FindPurgeable(ctx, queueName, status, len(r.jobs))
+	return int64(len(jobs)), nil
+}
+
+func (r *InMemoryJobRepo) FindPurgeable(ctx context.Context, queueName string, status queue.Status, limit int) ([]*queue.Job, error) {
+	var jobs []*queue.Job
+	for _, job := range r.jobs {
+		if job.Queue == queueName && job.Status == status && len(jobs) < limit {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *InMemoryJobRepo) FindParked(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	return r.FindPurgeable(ctx, queueName, queue.StatusParked, limit)
+}
+
+func (r *InMemoryJobRepo) CancelJobs(ctx context.Context, jobs []*queue.Job) (int64, error) {
+	for _, job := range jobs {
+		r.jobs[job.ID] = job
+	}
+	return int64(len(jobs)), nil
+}
+
 type InMemoryQueueSvc struct {
-	jobs []*queue.Job
+	jobs        []*queue.Job
+	deadLetters []*queue.Job
 }
 
 func (q *InMemoryQueueSvc) Enqueue(ctx context.Context, job *queue.Job) error {
@@ -152,7 +574,7 @@ func (q *InMemoryQueueSvc) Enqueue(ctx context.Context, job *queue.Job) error {
 	return nil
 }
 
-func (q *InMemoryQueueSvc) Dequeue(ctx context.Context, queueName string) (*queue.Job, error) {
+func (q *InMemoryQueueSvc) Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*queue.Job, error) {
 	return nil, nil
 }
 
@@ -160,12 +582,104 @@ func (q *InMemoryQueueSvc) Acknowledge(ctx context.Context, jobID uuid.UUID) err
 	return nil
 }
 
+func (q *InMemoryQueueSvc) Nack(ctx context.Context, job *queue.Job) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func (q *InMemoryQueueSvc) DeliveryStats(ctx context.Context) ([]*queue.DeliveryStats, error) {
+	byQueue := make(map[string]*queue.DeliveryStats)
+	var stats []*queue.DeliveryStats
+	for _, job := range q.jobs {
+		if byQueue[job.Queue] == nil {
+			byQueue[job.Queue] = &queue.DeliveryStats{Queue: job.Queue}
+			stats = append(stats, byQueue[job.Queue])
+		}
+		byQueue[job.Queue].Ready++
+	}
+	return stats, nil
+}
+
+func (q *InMemoryQueueSvc) Snapshot(ctx context.Context, queueName string) (queue.QueueSnapshot, error) {
+	snapshot := queue.QueueSnapshot{}
+	for _, job := range q.jobs {
+		if job.Queue == queueName {
+			snapshot[job.ID] = struct{}{}
+		}
+	}
+	return snapshot, nil
+}
+
+func (q *InMemoryQueueSvc) Withdraw(ctx context.Context, job *queue.Job) (bool, error) {
+	for i, queued := range q.jobs {
+		if queued.ID == job.ID {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (q *InMemoryQueueSvc) BeginPurge(ctx context.Context, queueName string) (queue.QueuePurge, error) {
+	return q, nil
+}
+
+func (q *InMemoryQueueSvc) Purge(ctx context.Context, jobs []*queue.Job) ([]uuid.UUID, error) {
+	var removed []uuid.UUID
+	for _, job := range jobs {
+		if withdrawn, _ := q.Withdraw(ctx, job); withdrawn {
+			removed = append(removed, job.ID)
+		}
+	}
+	return removed, nil
+}
+
+func (q *InMemoryQueueSvc) DeadLetter(ctx context.Context, job *queue.Job) error {
+	q.deadLetters = append(q.deadLetters, job)
+	return nil
+}
+
+func (q *InMemoryQueueSvc) PeekDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	var jobs []*queue.Job
+	for _, job := range q.deadLetters {
+		if job.Queue == queueName && (limit <= 0 || len(jobs) < limit) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (q *InMemoryQueueSvc) PopDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	var popped, kept []*queue.Job
+	for _, job := range q.deadLetters {
+		if job.Queue == queueName && len(popped) < limit {
+			popped = append(popped, job)
+		} else {
+			kept = append(kept, job)
+		}
+	}
+	q.deadLetters = kept
+	return popped, nil
+}
+
+func (q *InMemoryQueueSvc) RestoreDeadLetters(ctx context.Context, queueName string, jobs []*queue.Job) error {
+	q.deadLetters = append(append([]*queue.Job{}, jobs...), q.deadLetters...)
+	return nil
+}
+
+func (q *InMemoryQueueSvc) CountDeadLetters(ctx context.Context, queueName string) (int64, error) {
+	jobs, _ := q.PeekDeadLetters(ctx, queueName, 0)
+	return int64(len(jobs)), nil
+}
+
 type InMemoryMetrics struct{}
 
 func (m *InMemoryMetrics) RecordJobCreated(queueName, jobType string)                     {}
 func (m *InMemoryMetrics) RecordJobCompleted(queueName, jobType string, duration float64) {}
 func (m *InMemoryMetrics) RecordJobFailed(queueName, jobType string)                      {}
+func (m *InMemoryMetrics) RecordJobFailedWithReason(queueName, jobType, category string)  {}
 func (m *InMemoryMetrics) RecordJobRetried(queueName, jobType string)                     {}
+func (m *InMemoryMetrics) RecordJobParked(queueName, jobType string)                      {}
 
 func TestQueueHandlers_GetJob(t *testing.T) {
 	// Create shared test IDs
@@ -180,6 +694,7 @@ func TestQueueHandlers_GetJob(t *testing.T) {
 		then           string
 		jobID          uuid.UUID
 		setupRepo      func(*InMemoryJobRepo)
+		attempts       []*queue.Attempt
 		expectedStatus int
 		validateResp   func(*testing.T, *httptest.ResponseRecorder)
 	}{
@@ -207,6 +722,52 @@ func TestQueueHandlers_GetJob(t *testing.T) {
 				json.Unmarshal(rec.Body.Bytes(), &resp)
 				assert.Equal(t, existingJobID.String(), resp.ID)
 				assert.Equal(t, "default", resp.Queue)
+				assert.Empty(t, resp.Retries)
+				assert.Empty(t, resp.NextRetryAt)
+			},
+		},
+		{
+			name:  "Retrying job with its retry history",
+			given: "a job retrying after two failed attempts",
+			when:  "GET to /api/jobs/{id}",
+			then:  "should return the failed attempts with their backoff and when the job runs next",
+			jobID: existingJobID,
+			setupRepo: func(repo *InMemoryJobRepo) {
+				nextRetry := time.Date(2025, 1, 15, 10, 0, 8, 0, time.UTC)
+				repo.jobs[existingJobID] = &queue.Job{
+					ID:           existingJobID,
+					Queue:        "default",
+					Type:         "email",
+					Status:       queue.StatusRetrying,
+					Attempts:     2,
+					Payload:      []byte(`{}`),
+					ScheduledFor: &nextRetry,
+					CreatedAt:    now,
+					UpdatedAt:    now,
+				}
+			},
+			attempts: func() []*queue.Attempt {
+				started := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+				firstRetry := started.Add(3 * time.Second)
+				secondRetry := started.Add(8 * time.Second)
+				return []*queue.Attempt{
+					{JobID: existingJobID, Number: 1, WorkerID: "w1", StartedAt: started, Duration: time.Second,
+						Error: "smtp timeout", Category: queue.FailureTimeout, Backoff: 2 * time.Second, RetryAt: &firstRetry},
+					{JobID: existingJobID, Number: 2, WorkerID: "w2", StartedAt: firstRetry, Duration: time.Second,
+						Error: "smtp timeout", Category: queue.FailureTimeout, Backoff: 4 * time.Second, RetryAt: &secondRetry},
+				}
+			}(),
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "2025-01-15T10:00:08Z", resp.NextRetryAt)
+				assert.Equal(t, []RetryEntry{
+					{Attempt: 1, At: "2025-01-15T10:00:01Z", Error: "smtp timeout", Category: queue.FailureTimeout,
+						WorkerID: "w1", BackoffMs: 2000, RetryAt: "2025-01-15T10:00:03Z"},
+					{Attempt: 2, At: "2025-01-15T10:00:04Z", Error: "smtp timeout", Category: queue.FailureTimeout,
+						WorkerID: "w2", BackoffMs: 4000, RetryAt: "2025-01-15T10:00:08Z"},
+				}, resp.Retries)
 			},
 		},
 		{
@@ -238,6 +799,7 @@ func TestQueueHandlers_GetJob(t *testing.T) {
 			tt.setupRepo(mockRepo)
 
 			service := appQueue.NewService(mockRepo, mockQueue, mockMetrics)
+			service.SetAttemptHistory(StaticAttempts(tt.attempts))
 			handlers := NewQueueHandlers(service, nil)
 
 			// Build path
@@ -263,6 +825,21 @@ func TestQueueHandlers_GetJob(t *testing.T) {
 	}
 }
 
+// StaticAttempts is a recorded attempt history
+type StaticAttempts []*queue.Attempt
+
+func (a StaticAttempts) RecordAttempt(ctx context.Context, attempt *queue.Attempt) error {
+	return nil
+}
+
+func (a StaticAttempts) RecordRetry(ctx context.Context, jobID uuid.UUID, number int, backoff time.Duration, retryAt time.Time) error {
+	return nil
+}
+
+func (a StaticAttempts) ListAttempts(ctx context.Context, jobID uuid.UUID, limit int) ([]*queue.Attempt, error) {
+	return a, nil
+}
+
 func TestQueueHandlers_GetMetrics(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -402,3 +979,2488 @@ func TestQueueHandlers_RetryJob(t *testing.T) {
 		})
 	}
 }
+
+func TestQueueHandlers_GetDLQJobs(t *testing.T) {
+	analyzedJobID := uuid.New()
+	pendingAnalysisJobID := uuid.New()
+	retryableJobID := uuid.New()
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		url            string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "DLQ with embedded insights",
+			given:          "three DLQ jobs, two with an insight",
+			when:           "GET to /api/dlq?include=insights",
+			then:           "should return jobs with the insight embedded where available",
+			url:            "/api/dlq?include=insights",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp struct {
+					Jobs []JobResponse `json:"jobs"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Len(t, resp.Jobs, 3)
+				assert.NotNil(t, resp.Jobs[0].Insight)
+				assert.Equal(t, "SMTP timeout", resp.Jobs[0].Insight.Diagnosis)
+				assert.Equal(t, 0.8, resp.Jobs[0].Insight.Confidence)
+				assert.Equal(t, "needs-human", resp.Jobs[0].Insight.TriageLabel)
+				assert.Equal(t, "max_attempts", resp.Jobs[0].DeadLetter)
+				assert.Nil(t, resp.Jobs[1].Insight)
+			},
+		},
+		{
+			name:           "DLQ by actionability",
+			given:          "DLQ jobs labeled needs-human, not analyzed and auto-retryable",
+			when:           "GET to /api/dlq?include=insights&sort=actionability",
+			then:           "should return the auto-retryable job first and the one not analyzed last",
+			url:            "/api/dlq?include=insights&sort=actionability",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp struct {
+					Jobs []JobResponse `json:"jobs"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				require.Len(t, resp.Jobs, 3)
+				assert.Equal(t, retryableJobID.String(), resp.Jobs[0].ID)
+				assert.Equal(t, analyzedJobID.String(), resp.Jobs[1].ID)
+				assert.Equal(t, pendingAnalysisJobID.String(), resp.Jobs[2].ID)
+			},
+		},
+		{
+			name:           "Actionability without insights",
+			given:          "DLQ jobs exist",
+			when:           "GET to /api/dlq?sort=actionability",
+			then:           "should return 400 since the labels come with the insights",
+			url:            "/api/dlq?sort=actionability",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown sort",
+			given:          "DLQ jobs exist",
+			when:           "GET to /api/dlq?include=insights&sort=oldest",
+			then:           "should return 400",
+			url:            "/api/dlq?include=insights&sort=oldest",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "DLQ without insights",
+			given:          "DLQ jobs exist",
+			when:           "GET to /api/dlq",
+			then:           "should use the plain DLQ listing",
+			url:            "/api/dlq",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp struct {
+					Jobs []JobResponse `json:"jobs"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Empty(t, resp.Jobs)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			insightRepo := &InMemoryInsightRepo{
+				insights:      map[uuid.UUID]*insights.Insight{},
+				insightsByJob: map[uuid.UUID]*insights.Insight{},
+				dlq: []*insights.JobWithInsight{
+					{
+						Job: &queue.Job{ID: analyzedJobID, Queue: "default", Type: "email", Status: queue.StatusFailed, Attempts: 3, DeadLetterReason: queue.DeadLetterMaxAttempts, CreatedAt: now, UpdatedAt: now},
+						Insight: &insights.Insight{
+							ID: uuid.New(), JobID: analyzedJobID, Diagnosis: "SMTP timeout", Confidence: 0.8, Triage: insights.TriageNeedsHuman, CreatedAt: now,
+						},
+					},
+					{
+						Job: &queue.Job{ID: pendingAnalysisJobID, Queue: "default", Type: "email", Status: queue.StatusFailed, Attempts: 3, CreatedAt: now, UpdatedAt: now},
+					},
+					{
+						Job: &queue.Job{ID: retryableJobID, Queue: "default", Type: "email", Status: queue.StatusFailed, Attempts: 3, CreatedAt: now, UpdatedAt: now},
+						Insight: &insights.Insight{
+							ID: uuid.New(), JobID: retryableJobID, Diagnosis: "Missing recipient", Confidence: 0.9, Triage: insights.TriageAutoRetryable, CreatedAt: now,
+						},
+					},
+				},
+			}
+
+			service := appQueue.NewService(mockRepo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			insightsService := appInsights.NewService(insightRepo, mockRepo, &MockAIService{})
+			handlers := NewQueueHandlers(service, insightsService)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetDLQJobs(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_SearchJobs(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		url            string
+		setupRepo      func(*InMemoryJobRepo)
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Find jobs by error text",
+			given: "failed jobs with different errors",
+			when:  "GET to /api/jobs/search?q=SMTP auth&status=failed",
+			then:  "should return only the matching jobs with the total",
+			url:   "/api/jobs/search?q=SMTP+auth&status=failed",
+			setupRepo: func(repo *InMemoryJobRepo) {
+				smtpJob := &queue.Job{ID: uuid.New(), Queue: "default", Type: "email", Status: queue.StatusFailed, Error: "SMTP auth failed", Payload: []byte(`{}`)}
+				otherJob := &queue.Job{ID: uuid.New(), Queue: "default", Type: "email", Status: queue.StatusFailed, Error: "connection timeout", Payload: []byte(`{}`)}
+				repo.jobs[smtpJob.ID] = smtpJob
+				repo.jobs[otherJob.ID] = otherJob
+			},
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp struct {
+					Query string        `json:"query"`
+					Jobs  []JobResponse `json:"jobs"`
+					Total int64         `json:"total"`
+					Limit int           `json:"limit"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "SMTP auth", resp.Query)
+				assert.Equal(t, int64(1), resp.Total)
+				assert.Len(t, resp.Jobs, 1)
+				assert.Equal(t, "SMTP auth failed", resp.Jobs[0].Error)
+				assert.Equal(t, queue.DefaultSearchLimit, resp.Limit)
+			},
+		},
+		{
+			name:           "Missing search text",
+			given:          "no q parameter",
+			when:           "GET to /api/jobs/search",
+			then:           "should return 400 bad request",
+			url:            "/api/jobs/search",
+			setupRepo:      func(repo *InMemoryJobRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			mockQueue := &InMemoryQueueSvc{jobs: []*queue.Job{}}
+			mockMetrics := &InMemoryMetrics{}
+			tt.setupRepo(mockRepo)
+
+			service := appQueue.NewService(mockRepo, mockQueue, mockMetrics)
+			handlers := NewQueueHandlers(service, nil)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.SearchJobs(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_DeleteAndUndeleteJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		exists         bool
+		expectedStatus struct {
+			delete   int
+			undelete int
+		}
+	}{
+		{
+			name:   "Delete then restore a job",
+			given:  "an existing job",
+			when:   "DELETE /api/jobs/{id} then POST /api/jobs/{id}/undelete",
+			then:   "should hide the job from listings and restore it",
+			exists: true,
+			expectedStatus: struct {
+				delete   int
+				undelete int
+			}{delete: http.StatusOK, undelete: http.StatusOK},
+		},
+		{
+			name:   "Unknown job",
+			given:  "no job with the ID",
+			when:   "deleting and undeleting it",
+			then:   "should return 404 for both",
+			exists: false,
+			expectedStatus: struct {
+				delete   int
+				undelete int
+			}{delete: http.StatusNotFound, undelete: http.StatusNotFound},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			mockQueue := &InMemoryQueueSvc{jobs: []*queue.Job{}}
+			mockMetrics := &InMemoryMetrics{}
+
+			id := uuid.New()
+			if tt.exists {
+				mockRepo.jobs[id] = &queue.Job{ID: id, Queue: "default", Type: "email", Status: queue.StatusFailed, Payload: []byte(`{}`)}
+			}
+
+			service := appQueue.NewService(mockRepo, mockQueue, mockMetrics)
+			handlers := NewQueueHandlers(service, nil)
+
+			// When
+			deleteRec := httptest.NewRecorder()
+			handlers.DeleteJob(deleteRec, httptest.NewRequest(http.MethodDelete, "/api/jobs/"+id.String(), nil))
+
+			// Then
+			assert.Equal(t, tt.expectedStatus.delete, deleteRec.Code)
+			if tt.exists {
+				listed, _ := service.GetJobsByStatus(context.Background(), queue.StatusFailed, 10)
+				assert.Empty(t, listed)
+			}
+
+			// When
+			undeleteRec := httptest.NewRecorder()
+			handlers.UndeleteJob(undeleteRec, httptest.NewRequest(http.MethodPost, "/api/jobs/"+id.String()+"/undelete", nil))
+
+			// Then
+			assert.Equal(t, tt.expectedStatus.undelete, undeleteRec.Code)
+			if tt.exists {
+				var resp JobResponse
+				json.Unmarshal(undeleteRec.Body.Bytes(), &resp)
+				assert.Equal(t, id.String(), resp.ID)
+				assert.Empty(t, resp.DeletedAt)
+
+				listed, _ := service.GetJobsByStatus(context.Background(), queue.StatusFailed, 10)
+				assert.Len(t, listed, 1)
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_EditJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		status         queue.Status
+		queued         bool
+		requestBody    string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder, *InMemoryQueueSvc)
+	}{
+		{
+			name:           "Edit queued job",
+			given:          "a pending job waiting in its queue at version 1",
+			when:           "PATCH /api/jobs/{id} with a new payload and version 1",
+			then:           "should return the job at version 2 and requeue it with the new payload",
+			status:         queue.StatusPending,
+			queued:         true,
+			requestBody:    `{"payload": {"to": "new@example.com"}, "version": 1}`,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, queueSvc *InMemoryQueueSvc) {
+				var resp JobResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, map[string]any{"to": "new@example.com"}, resp.Payload)
+				assert.Equal(t, 2, resp.Version)
+				require.Len(t, queueSvc.jobs, 1)
+				assert.JSONEq(t, `{"to": "new@example.com"}`, string(queueSvc.jobs[0].Payload))
+			},
+		},
+		{
+			name:           "Delay queued job",
+			given:          "a pending job waiting in its queue",
+			when:           "PATCH /api/jobs/{id} with a future scheduled_for",
+			then:           "should take it out of the queue until the scheduler promotes it",
+			status:         queue.StatusPending,
+			queued:         true,
+			requestBody:    `{"scheduled_for": "2099-01-01T09:00:00Z", "version": 1}`,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, queueSvc *InMemoryQueueSvc) {
+				var resp JobResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "2099-01-01T09:00:00Z", resp.ScheduledFor)
+				assert.Empty(t, queueSvc.jobs)
+			},
+		},
+		{
+			name:           "Stale version",
+			given:          "a pending job at version 1",
+			when:           "PATCH /api/jobs/{id} with version 0",
+			then:           "should return 409 and leave the queued job alone",
+			status:         queue.StatusPending,
+			queued:         true,
+			requestBody:    `{"payload": {"to": "new@example.com"}, "version": 0}`,
+			expectedStatus: http.StatusConflict,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, queueSvc *InMemoryQueueSvc) {
+				assert.Contains(t, rec.Body.String(), "version")
+				require.Len(t, queueSvc.jobs, 1)
+				assert.JSONEq(t, `{"to": "old@example.com"}`, string(queueSvc.jobs[0].Payload))
+			},
+		},
+		{
+			name:           "Job dequeued by a worker",
+			given:          "a pending job no longer in its queue",
+			when:           "PATCH /api/jobs/{id}",
+			then:           "should return 409",
+			status:         queue.StatusPending,
+			requestBody:    `{"payload": {"to": "new@example.com"}, "version": 1}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Completed job",
+			given:          "a completed job",
+			when:           "PATCH /api/jobs/{id}",
+			then:           "should return 409",
+			status:         queue.StatusCompleted,
+			requestBody:    `{"payload": {"to": "new@example.com"}, "version": 1}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Missing version",
+			given:          "a pending job",
+			when:           "PATCH /api/jobs/{id} without a version",
+			then:           "should return 400",
+			status:         queue.StatusPending,
+			queued:         true,
+			requestBody:    `{"payload": {"to": "new@example.com"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Nothing to change",
+			given:          "a pending job",
+			when:           "PATCH /api/jobs/{id} with only a version",
+			then:           "should return 400",
+			status:         queue.StatusPending,
+			queued:         true,
+			requestBody:    `{"version": 1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			queueSvc := &InMemoryQueueSvc{jobs: []*queue.Job{}}
+			job := &queue.Job{ID: uuid.New(), Queue: "default", Type: "email", Status: tt.status, Payload: []byte(`{"to": "old@example.com"}`), Version: 1}
+			repo.jobs[job.ID] = job
+			if tt.queued {
+				queued := *job
+				queueSvc.jobs = append(queueSvc.jobs, &queued)
+			}
+			service := appQueue.NewService(repo, queueSvc, &InMemoryMetrics{})
+			service.SetQueueWithdrawer(queueSvc)
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/jobs/"+job.ID.String(), strings.NewReader(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec, queueSvc)
+			}
+		})
+	}
+}
+
+type InMemoryBreakerStore struct {
+	statuses []worker.BreakerStatus
+}
+
+func (s *InMemoryBreakerStore) Save(ctx context.Context, status worker.BreakerStatus) error {
+	s.statuses = append(s.statuses, status)
+	return nil
+}
+
+func (s *InMemoryBreakerStore) List(ctx context.Context) ([]worker.BreakerStatus, error) {
+	return s.statuses, nil
+}
+
+func TestQueueHandlers_ListBreakers(t *testing.T) {
+	openUntil := time.Now().Add(time.Minute).UTC()
+	expiredUntil := time.Now().Add(-time.Minute).UTC()
+
+	tests := []struct {
+		name         string
+		given        string
+		when         string
+		then         string
+		statuses     []worker.BreakerStatus
+		expectedOpen int
+		expectedType map[string]worker.BreakerState
+	}{
+		{
+			name:  "Report open and expired breakers",
+			given: "one breaker in its cooldown and one whose cooldown has passed",
+			when:  "GET to /api/breakers",
+			then:  "should report only the first one as open",
+			statuses: []worker.BreakerStatus{
+				{JobType: "http", State: worker.BreakerOpen, FailureRate: 0.9, Samples: 10, OpenUntil: &openUntil},
+				{JobType: "email", State: worker.BreakerOpen, FailureRate: 0.8, Samples: 12, OpenUntil: &expiredUntil},
+			},
+			expectedOpen: 1,
+			expectedType: map[string]worker.BreakerState{"http": worker.BreakerOpen, "email": worker.BreakerClosed},
+		},
+		{
+			name:         "No breakers reported",
+			given:        "no worker has recorded a breaker",
+			when:         "GET to /api/breakers",
+			then:         "should return an empty list",
+			statuses:     nil,
+			expectedOpen: 0,
+			expectedType: map[string]worker.BreakerState{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appQueue.NewService(
+				&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+				&InMemoryQueueSvc{},
+				&InMemoryMetrics{},
+			)
+			service.SetBreakerStore(&InMemoryBreakerStore{statuses: tt.statuses})
+			handlers := NewQueueHandlers(service, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/breakers", nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.ListBreakers(rec, req)
+
+			// Then
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var response struct {
+				Breakers []worker.BreakerStatus `json:"breakers"`
+				Open     int                    `json:"open"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedOpen, response.Open)
+			assert.Len(t, response.Breakers, len(tt.expectedType))
+			for _, breaker := range response.Breakers {
+				assert.Equal(t, tt.expectedType[breaker.JobType], breaker.State)
+			}
+		})
+	}
+}
+
+type InMemoryHeartbeatStore struct {
+	heartbeats []worker.Heartbeat
+	err        error
+}
+
+func (s *InMemoryHeartbeatStore) Beat(ctx context.Context, heartbeat worker.Heartbeat) error {
+	s.heartbeats = append(s.heartbeats, heartbeat)
+	return nil
+}
+
+func (s *InMemoryHeartbeatStore) List(ctx context.Context) ([]worker.Heartbeat, error) {
+	return s.heartbeats, s.err
+}
+
+func TestQueueHandlers_GetDashboard(t *testing.T) {
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name              string
+		given             string
+		when              string
+		then              string
+		jobs              []*queue.Job
+		heartbeats        *InMemoryHeartbeatStore
+		expectedJobs      map[string]int64
+		expectedThrough   ThroughputResponse
+		expectedFailing   []TypeFailuresResponse
+		expectedWorkerIDs []string
+	}{
+		{
+			name:  "Aggregate jobs, failures and workers",
+			given: "recent completed and failed jobs, an old completed job and one live worker",
+			when:  "GET to /api/dashboard",
+			then:  "should count every job by status but only recent ones in the throughput",
+			jobs: []*queue.Job{
+				{ID: uuid.New(), Type: "http", Status: queue.StatusCompleted, UpdatedAt: now},
+				{ID: uuid.New(), Type: "http", Status: queue.StatusCompleted, UpdatedAt: now.Add(-2 * time.Hour)},
+				{ID: uuid.New(), Type: "email", Status: queue.StatusFailed, DeadLetterReason: queue.DeadLetterMaxAttempts, UpdatedAt: now},
+				{ID: uuid.New(), Type: "email", Status: queue.StatusRetrying, UpdatedAt: now},
+				{ID: uuid.New(), Type: "http", Status: queue.StatusPending, UpdatedAt: now},
+			},
+			heartbeats: &InMemoryHeartbeatStore{heartbeats: []worker.Heartbeat{
+				{WorkerID: "worker-1", Queues: []string{"default"}, Concurrency: 2, StartedAt: now, LastSeen: now},
+			}},
+			expectedJobs: map[string]int64{"pending": 1, "processing": 0, "retrying": 1, "completed": 2, "failed": 1, "cancelled": 0, "held": 0, "parked": 0},
+			expectedThrough: ThroughputResponse{Window: "1h0m0s", Completed: 1, Failed: 2, PerMinute: 1.0 / 60, DeadLettered: map[string]int64{
+				"max_attempts": 1, "non_retryable_error": 0, "expired": 0, "cancelled_by_policy": 0,
+			}},
+			expectedFailing:   []TypeFailuresResponse{{Type: "email", Failed: 2}},
+			expectedWorkerIDs: []string{"worker-1"},
+		},
+		{
+			name:         "Heartbeat store unavailable",
+			given:        "no jobs and a heartbeat store returning an error",
+			when:         "GET to /api/dashboard",
+			then:         "should still return 200 with no workers",
+			heartbeats:   &InMemoryHeartbeatStore{err: errors.New("redis down")},
+			expectedJobs: map[string]int64{"pending": 0, "processing": 0, "retrying": 0, "completed": 0, "failed": 0, "cancelled": 0, "held": 0, "parked": 0},
+			expectedThrough: ThroughputResponse{Window: "1h0m0s", DeadLettered: map[string]int64{
+				"max_attempts": 0, "non_retryable_error": 0, "expired": 0, "cancelled_by_policy": 0,
+			}},
+			expectedFailing:   []TypeFailuresResponse{},
+			expectedWorkerIDs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			for _, job := range tt.jobs {
+				repo.jobs[job.ID] = job
+			}
+			service := appQueue.NewService(repo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			service.SetHeartbeatStore(tt.heartbeats)
+			handlers := NewQueueHandlers(service, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/dashboard", nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetDashboard(rec, req)
+
+			// Then
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var response DashboardResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedJobs, response.Jobs)
+			assert.Equal(t, tt.expectedThrough.Window, response.Throughput.Window)
+			assert.Equal(t, tt.expectedThrough.Completed, response.Throughput.Completed)
+			assert.Equal(t, tt.expectedThrough.Failed, response.Throughput.Failed)
+			assert.InDelta(t, tt.expectedThrough.PerMinute, response.Throughput.PerMinute, 0.001)
+			assert.Equal(t, tt.expectedThrough.DeadLettered, response.Throughput.DeadLettered)
+			assert.Equal(t, tt.expectedFailing, response.TopFailingTypes)
+			assert.Empty(t, response.RecentInsights)
+			workerIDs := []string{}
+			for _, heartbeat := range response.Workers {
+				workerIDs = append(workerIDs, heartbeat.WorkerID)
+			}
+			assert.Equal(t, tt.expectedWorkerIDs, workerIDs)
+		})
+	}
+}
+
+type InMemoryQueueStatsRepo struct {
+	samples []*queue.QueueStats
+}
+
+func (r *InMemoryQueueStatsRepo) CountByQueue(ctx context.Context) ([]*queue.QueueStats, error) {
+	var counts []*queue.QueueStats
+	seen := make(map[string]bool)
+	for _, sample := range r.samples {
+		if !seen[sample.Queue] {
+			seen[sample.Queue] = true
+			counts = append(counts, &queue.QueueStats{Queue: sample.Queue})
+		}
+	}
+	return counts, nil
+}
+
+func (r *InMemoryQueueStatsRepo) Record(ctx context.Context, stats []*queue.QueueStats) error {
+	r.samples = append(r.samples, stats...)
+	return nil
+}
+
+func (r *InMemoryQueueStatsRepo) History(ctx context.Context, queueName string, since time.Time) ([]*queue.QueueStats, error) {
+	history := []*queue.QueueStats{}
+	for _, sample := range r.samples {
+		if sample.Queue == queueName && !sample.SampledAt.Before(since) {
+			history = append(history, sample)
+		}
+	}
+	return history, nil
+}
+
+func (r *InMemoryQueueStatsRepo) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestQueueHandlers_GetMetricsHistory(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	samples := []*queue.QueueStats{
+		{Queue: "default", Pending: 9, Completed: 100, SampledAt: now.Add(-48 * time.Hour)},
+		{Queue: "default", Pending: 6, Retrying: 1, Completed: 120, SampledAt: now.Add(-2 * time.Minute)},
+		{Queue: "default", Pending: 2, Retrying: 1, Completed: 128, SampledAt: now.Add(-time.Minute)},
+		{Queue: "emails", Pending: 50, SampledAt: now.Add(-time.Minute)},
+	}
+
+	tests := []struct {
+		name               string
+		given              string
+		when               string
+		then               string
+		query              string
+		withStats          bool
+		expectedStatus     int
+		expectedBacklog    []int64
+		expectedThroughput []int64
+	}{
+		{
+			name:               "Default window",
+			given:              "samples inside and outside the last 24 hours",
+			when:               "GET to /api/metrics/history?queue=default",
+			then:               "should return the queue's samples of the last 24 hours with backlog and throughput",
+			query:              "?queue=default",
+			withStats:          true,
+			expectedStatus:     http.StatusOK,
+			expectedBacklog:    []int64{7, 3},
+			expectedThroughput: []int64{0, 8},
+		},
+		{
+			name:               "Window in days",
+			given:              "samples over the last two days",
+			when:               "GET to /api/metrics/history?queue=default&window=3d",
+			then:               "should include the older sample",
+			query:              "?queue=default&window=3d",
+			withStats:          true,
+			expectedStatus:     http.StatusOK,
+			expectedBacklog:    []int64{9, 7, 3},
+			expectedThroughput: []int64{0, 20, 8},
+		},
+		{
+			name:           "Missing queue",
+			given:          "stats history enabled",
+			when:           "GET to /api/metrics/history without queue",
+			then:           "should return 400 Bad Request",
+			query:          "",
+			withStats:      true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid window",
+			given:          "stats history enabled",
+			when:           "GET to /api/metrics/history with a window above 30 days",
+			then:           "should return 400 Bad Request",
+			query:          "?queue=default&window=90d",
+			withStats:      true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "History disabled",
+			given:          "no stats repository",
+			when:           "GET to /api/metrics/history?queue=default",
+			then:           "should return 503 Service Unavailable",
+			query:          "?queue=default",
+			withStats:      false,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appQueue.NewService(
+				&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+				&InMemoryQueueSvc{},
+				&InMemoryMetrics{},
+			)
+			if tt.withStats {
+				service.SetStatsRepository(&InMemoryQueueStatsRepo{samples: samples})
+			}
+			handlers := NewQueueHandlers(service, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/metrics/history"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetMetricsHistory(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response QueueStatsHistoryResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, "default", response.Queue)
+			backlog := make([]int64, len(response.Points))
+			throughput := make([]int64, len(response.Points))
+			for i, point := range response.Points {
+				backlog[i] = point.Backlog
+				throughput[i] = point.Throughput
+			}
+			assert.Equal(t, tt.expectedBacklog, backlog)
+			assert.Equal(t, tt.expectedThroughput, throughput)
+		})
+	}
+}
+
+func TestQueueHandlers_GetScalingRecommendation(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	samples := []*queue.QueueStats{
+		{Queue: "default", Processing: 2, SampledAt: now.Add(-10 * time.Minute)},
+		{Queue: "default", Pending: 120, Processing: 2, Completed: 600, SampledAt: now},
+		{Queue: "emails", SampledAt: now},
+	}
+
+	tests := []struct {
+		name             string
+		given            string
+		when             string
+		then             string
+		query            string
+		withStats        bool
+		expectedStatus   int
+		expectedReplicas map[string]int
+	}{
+		{
+			name:             "Recommendation for one queue",
+			given:            "a queue with a backlog and two second jobs",
+			when:             "GET to /api/scaling/recommendation?queue=default",
+			then:             "should return its desired replicas",
+			query:            "?queue=default",
+			withStats:        true,
+			expectedStatus:   http.StatusOK,
+			expectedReplicas: map[string]int{"default": 7},
+		},
+		{
+			name:             "Recommendations for every queue",
+			given:            "a busy and an idle queue",
+			when:             "GET to /api/scaling/recommendation",
+			then:             "should return a recommendation per queue",
+			withStats:        true,
+			expectedStatus:   http.StatusOK,
+			expectedReplicas: map[string]int{"default": 7, "emails": 0},
+		},
+		{
+			name:             "External metrics format",
+			given:            "a queue with a backlog",
+			when:             "GET to /api/scaling/recommendation?queue=default&format=external",
+			then:             "should return a Kubernetes external metric value list",
+			query:            "?queue=default&format=external",
+			withStats:        true,
+			expectedStatus:   http.StatusOK,
+			expectedReplicas: map[string]int{"default": 7},
+		},
+		{
+			name:           "Invalid format",
+			given:          "stats history enabled",
+			when:           "GET to /api/scaling/recommendation?format=xml",
+			then:           "should return 400 Bad Request",
+			query:          "?format=xml",
+			withStats:      true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid window",
+			given:          "stats history enabled",
+			when:           "GET to /api/scaling/recommendation with a window above 24 hours",
+			then:           "should return 400 Bad Request",
+			query:          "?window=2d",
+			withStats:      true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "History disabled",
+			given:          "no stats repository",
+			when:           "GET to /api/scaling/recommendation",
+			then:           "should return 503 Service Unavailable",
+			withStats:      false,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appQueue.NewService(
+				&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)},
+				&InMemoryQueueSvc{},
+				&InMemoryMetrics{},
+			)
+			if tt.withStats {
+				service.SetStatsRepository(&InMemoryQueueStatsRepo{samples: samples})
+			}
+			handlers := NewQueueHandlers(service, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/scaling/recommendation"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.GetScalingRecommendation(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			replicas := make(map[string]int)
+			if strings.Contains(tt.query, "format=external") {
+				var resp ExternalMetricValueList
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "ExternalMetricValueList", resp.Kind)
+				for _, item := range resp.Items {
+					assert.Equal(t, ScalingMetricName, item.MetricName)
+					value, err := strconv.Atoi(item.Value)
+					assert.NoError(t, err)
+					replicas[item.MetricLabels["queue"]] = value
+				}
+			} else {
+				var resp ScalingResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "15m", resp.Window)
+				for _, item := range resp.Recommendations {
+					replicas[item.Queue] = item.DesiredReplicas
+				}
+			}
+			assert.Equal(t, tt.expectedReplicas, replicas)
+		})
+	}
+}
+
+type InMemoryDefinitionRepo struct {
+	defs map[string]*queue.Definition
+}
+
+func (r *InMemoryDefinitionRepo) Create(ctx context.Context, def *queue.Definition) error {
+	if _, ok := r.defs[def.Name]; ok {
+		return queue.ErrQueueDefined
+	}
+	r.defs[def.Name] = def
+	return nil
+}
+
+func (r *InMemoryDefinitionRepo) Get(ctx context.Context, name string) (*queue.Definition, error) {
+	def, ok := r.defs[name]
+	if !ok {
+		return nil, queue.ErrQueueNotDefined
+	}
+	return def, nil
+}
+
+func (r *InMemoryDefinitionRepo) List(ctx context.Context) ([]*queue.Definition, error) {
+	defs := make([]*queue.Definition, 0, len(r.defs))
+	for _, def := range r.defs {
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func (r *InMemoryDefinitionRepo) Update(ctx context.Context, def *queue.Definition) error {
+	if _, ok := r.defs[def.Name]; !ok {
+		return queue.ErrQueueNotDefined
+	}
+	r.defs[def.Name] = def
+	return nil
+}
+
+func (r *InMemoryDefinitionRepo) Delete(ctx context.Context, name string) error {
+	if _, ok := r.defs[name]; !ok {
+		return queue.ErrQueueNotDefined
+	}
+	delete(r.defs, name)
+	return nil
+}
+
+func TestQueueHandlers_QueueDefinitions(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		disabled       bool
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder, *InMemoryDefinitionRepo)
+	}{
+		{
+			name:           "Define a queue",
+			given:          "a new queue name",
+			when:           "POST to /api/queues",
+			then:           "should return 201 and store the definition",
+			method:         http.MethodPost,
+			path:           "/api/queues",
+			body:           `{"name":"emails","max_attempts":5,"allowed_types":["email"]}`,
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				var resp QueueDefinitionResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "emails", resp.Name)
+				assert.Equal(t, 5, resp.MaxAttempts)
+				assert.Contains(t, repo.defs, "emails")
+			},
+		},
+		{
+			name:           "Define an existing queue",
+			given:          "the default queue is already defined",
+			when:           "POST to /api/queues with its name",
+			then:           "should return 409",
+			method:         http.MethodPost,
+			path:           "/api/queues",
+			body:           `{"name":"default"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Define a queue with an invalid name",
+			given:          "a queue name containing spaces",
+			when:           "POST to /api/queues",
+			then:           "should return 400",
+			method:         http.MethodPost,
+			path:           "/api/queues",
+			body:           `{"name":"my queue"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "List queues",
+			given:          "the default queue is defined",
+			when:           "GET to /api/queues",
+			then:           "should return it with its settings and no jobs",
+			method:         http.MethodGet,
+			path:           "/api/queues",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				var resp struct {
+					Queues []QueueResponse `json:"queues"`
+					Total  int             `json:"total"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, 1, resp.Total)
+				assert.Equal(t, "default", resp.Queues[0].Name)
+				assert.True(t, resp.Queues[0].Defined)
+				assert.Equal(t, []string{"email"}, resp.Queues[0].AllowedTypes)
+				assert.Equal(t, QueueJobCountsResponse{}, resp.Queues[0].Jobs)
+			},
+		},
+		{
+			name:           "Get an unknown queue",
+			given:          "no definition named reports",
+			when:           "GET to /api/queues/reports",
+			then:           "should return 404",
+			method:         http.MethodGet,
+			path:           "/api/queues/reports",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Pause a queue",
+			given:          "the default queue is defined",
+			when:           "PUT to /api/queues/default with paused set",
+			then:           "should return 200 and store the new settings",
+			method:         http.MethodPut,
+			path:           "/api/queues/default",
+			body:           `{"paused":true,"rate_limit_per_second":2.5}`,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				assert.True(t, repo.defs["default"].Paused)
+				assert.Equal(t, 2.5, repo.defs["default"].RateLimitPerSecond)
+			},
+		},
+		{
+			name:           "Set a queue's payload retention",
+			given:          "the default queue is defined",
+			when:           "PUT to /api/queues/default with payload_retention_days",
+			then:           "should return 200 and the stored retention",
+			method:         http.MethodPut,
+			path:           "/api/queues/default",
+			body:           `{"payload_retention_days":7}`,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				var resp QueueDefinitionResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, 7, resp.PayloadRetentionDays)
+				assert.Equal(t, 7, repo.defs["default"].PayloadRetentionDays)
+			},
+		},
+		{
+			name:           "Set a negative payload retention",
+			given:          "the default queue is defined",
+			when:           "PUT to /api/queues/default with a negative payload_retention_days",
+			then:           "should return 400",
+			method:         http.MethodPut,
+			path:           "/api/queues/default",
+			body:           `{"payload_retention_days":-1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Delete a queue",
+			given:          "the default queue is defined",
+			when:           "DELETE to /api/queues/default",
+			then:           "should return 200 and remove the definition",
+			method:         http.MethodDelete,
+			path:           "/api/queues/default",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				assert.NotContains(t, repo.defs, "default")
+			},
+		},
+		{
+			name:           "Create a job of a type the queue doesn't allow",
+			given:          "the default queue only accepts email jobs",
+			when:           "POST to /api/jobs with an sms job",
+			then:           "should return 400",
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			body:           `{"queue":"default","type":"sms","payload":{}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Create a job of a typo'd type",
+			given:          "the default queue only accepts email jobs",
+			when:           "POST to /api/jobs with an emial job",
+			then:           "should return 400 suggesting the email type",
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			body:           `{"queue":"default","type":"emial","payload":{}}`,
+			expectedStatus: http.StatusBadRequest,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				assert.Contains(t, rec.Body.String(), `did you mean "email"?`)
+			},
+		},
+		{
+			name:           "Define a queue with a default type and strict payloads",
+			given:          "a new queue name",
+			when:           "POST to /api/queues with default_type and strict_payload",
+			then:           "should return 201 and both settings",
+			method:         http.MethodPost,
+			path:           "/api/queues",
+			body:           `{"name":"emails","allowed_types":["email"],"default_type":"email","strict_payload":true}`,
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				var resp QueueDefinitionResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "email", resp.DefaultType)
+				assert.True(t, resp.StrictPayload)
+				assert.True(t, repo.defs["emails"].StrictPayload)
+			},
+		},
+		{
+			name:           "Define a queue with a default type it doesn't allow",
+			given:          "a new queue name",
+			when:           "POST to /api/queues with a default_type outside allowed_types",
+			then:           "should return 400",
+			method:         http.MethodPost,
+			path:           "/api/queues",
+			body:           `{"name":"emails","allowed_types":["email"],"default_type":"sms"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Create a job without a type in a queue with a default type",
+			given:          "the strict queue defaults to email jobs",
+			when:           "POST to /api/jobs without a type",
+			then:           "should return 201 and an email job",
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			body:           `{"queue":"strict","payload":{"to":"a@example.com"}}`,
+			expectedStatus: http.StatusCreated,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				var resp JobResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "email", resp.Type)
+			},
+		},
+		{
+			name:           "Create a job without a type in a queue without a default type",
+			given:          "the default queue has no default type",
+			when:           "POST to /api/jobs without a type",
+			then:           "should return 400",
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			body:           `{"queue":"default","payload":{}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Create a job with a non-object payload in a strict queue",
+			given:          "the strict queue validates payloads strictly",
+			when:           "POST to /api/jobs with a string payload",
+			then:           "should return 400 naming the payload kind",
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			body:           `{"queue":"strict","type":"email","payload":"a@example.com"}`,
+			expectedStatus: http.StatusBadRequest,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder, repo *InMemoryDefinitionRepo) {
+				assert.Contains(t, rec.Body.String(), "got a string")
+			},
+		},
+		{
+			name:           "Definitions disabled",
+			given:          "no definition repository is configured",
+			when:           "GET to /api/queues/default",
+			then:           "should return 503",
+			disabled:       true,
+			method:         http.MethodGet,
+			path:           "/api/queues/default",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &InMemoryDefinitionRepo{defs: map[string]*queue.Definition{
+				"default": {Name: "default", AllowedTypes: []string{"email"}},
+			}}
+			if tt.method == http.MethodPost && tt.path == "/api/jobs" {
+				repo.defs["strict"] = &queue.Definition{Name: "strict", DefaultType: "email", StrictPayload: true}
+			}
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			service := appQueue.NewService(jobRepo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			service.SetQueueCounter(jobRepo)
+			if !tt.disabled {
+				service.SetQueueDefinitions(repo, true)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec, repo)
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_ListQueues(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		noCounter      bool
+		expectedStatus int
+		expected       []QueueResponse
+	}{
+		{
+			name:           "Defined queue and undefined queue with jobs",
+			given:          "the emails queue is defined and the default queue holds a pending and a failed job",
+			when:           "GET to /api/queues",
+			then:           "should return both by name with their counts, settings only for emails",
+			expectedStatus: http.StatusOK,
+			expected: []QueueResponse{
+				{Name: "default", Jobs: QueueJobCountsResponse{Pending: 1, Failed: 1}},
+				{Name: "emails", Defined: true, Jobs: QueueJobCountsResponse{Completed: 1}},
+			},
+		},
+		{
+			name:           "Counts disabled",
+			given:          "no queue counter is configured",
+			when:           "GET to /api/queues",
+			then:           "should return 503",
+			noCounter:      true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			for _, job := range []*queue.Job{
+				{ID: uuid.New(), Queue: "default", Status: queue.StatusPending},
+				{ID: uuid.New(), Queue: "default", Status: queue.StatusFailed},
+				{ID: uuid.New(), Queue: "emails", Status: queue.StatusCompleted},
+			} {
+				jobRepo.jobs[job.ID] = job
+			}
+			service := appQueue.NewService(jobRepo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			service.SetQueueDefinitions(&InMemoryDefinitionRepo{defs: map[string]*queue.Definition{
+				"emails": {Name: "emails", MaxAttempts: 5},
+			}}, false)
+			if !tt.noCounter {
+				service.SetQueueCounter(jobRepo)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/queues", nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expected == nil {
+				return
+			}
+			var resp struct {
+				Queues []QueueResponse `json:"queues"`
+				Total  int             `json:"total"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Len(t, resp.Queues, len(tt.expected))
+			assert.Equal(t, len(tt.expected), resp.Total)
+			for i, expected := range tt.expected {
+				assert.Equal(t, expected.Name, resp.Queues[i].Name)
+				assert.Equal(t, expected.Defined, resp.Queues[i].Defined)
+				assert.Equal(t, expected.Jobs, resp.Queues[i].Jobs)
+				assert.Equal(t, expected.Defined, resp.Queues[i].QueueDefinitionResponse != nil)
+			}
+			assert.Equal(t, 5, resp.Queues[1].MaxAttempts)
+			assert.NotContains(t, rec.Body.String(), `"max_attempts":0`)
+		})
+	}
+}
+
+func TestQueueHandlers_ListQueueJobs(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedTypes  []string
+		expectedTotal  int64
+	}{
+		{
+			name:           "List a queue's jobs",
+			given:          "three jobs in the emails queue and one in the default queue",
+			when:           "GET to /api/queues/emails/jobs",
+			then:           "should return the emails jobs newest first",
+			method:         http.MethodGet,
+			path:           "/api/queues/emails/jobs",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"digest", "bounce", "welcome"},
+			expectedTotal:  3,
+		},
+		{
+			name:           "Filter by status and paginate",
+			given:          "two failed jobs in the emails queue",
+			when:           "GET to /api/queues/emails/jobs?status=failed&limit=1",
+			then:           "should return the newest failed job and the total of both",
+			method:         http.MethodGet,
+			path:           "/api/queues/emails/jobs?status=failed&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"digest"},
+			expectedTotal:  2,
+		},
+		{
+			name:           "Filter by creator",
+			given:          "two emails jobs created by team-a",
+			when:           "GET to /api/queues/emails/jobs?created_by=team-a",
+			then:           "should return only those, newest first",
+			method:         http.MethodGet,
+			path:           "/api/queues/emails/jobs?created_by=team-a",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"digest", "welcome"},
+			expectedTotal:  2,
+		},
+		{
+			name:           "Unknown status",
+			given:          "a status filter naming no job status",
+			when:           "GET to /api/queues/emails/jobs?status=done",
+			then:           "should return 400",
+			method:         http.MethodGet,
+			path:           "/api/queues/emails/jobs?status=done",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unsupported method",
+			given:          "the emails queue",
+			when:           "POST to /api/queues/emails/jobs",
+			then:           "should return 405",
+			method:         http.MethodPost,
+			path:           "/api/queues/emails/jobs",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			now := time.Now().UTC()
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			for _, job := range []*queue.Job{
+				{ID: uuid.New(), Queue: "emails", Type: "welcome", Status: queue.StatusCompleted, CreatedBy: "team-a", CreatedAt: now.Add(-3 * time.Minute)},
+				{ID: uuid.New(), Queue: "emails", Type: "bounce", Status: queue.StatusFailed, CreatedBy: "team-b", CreatedAt: now.Add(-2 * time.Minute)},
+				{ID: uuid.New(), Queue: "emails", Type: "digest", Status: queue.StatusFailed, CreatedBy: "team-a", CreatedAt: now.Add(-time.Minute)},
+				{ID: uuid.New(), Queue: "default", Type: "report", Status: queue.StatusPending, CreatedBy: "team-a", CreatedAt: now},
+			} {
+				jobRepo.jobs[job.ID] = job
+			}
+			service := appQueue.NewService(jobRepo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Queue string        `json:"queue"`
+				Jobs  []JobResponse `json:"jobs"`
+				Total int64         `json:"total"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "emails", resp.Queue)
+			assert.Equal(t, tt.expectedTotal, resp.Total)
+			types := make([]string, len(resp.Jobs))
+			for i, job := range resp.Jobs {
+				types[i] = job.Type
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+		})
+	}
+}
+
+func TestQueueHandlers_ListJobs_CreatedBy(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		path           string
+		expectedStatus int
+		expectedTypes  []string
+	}{
+		{
+			name:           "Jobs of a creator in all queues",
+			given:          "three jobs created by team-a in two queues",
+			when:           "GET to /api/jobs?created_by=team-a",
+			then:           "should return all three, newest first, with their creator",
+			path:           "/api/jobs?created_by=team-a",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"report", "digest", "welcome"},
+		},
+		{
+			name:           "Jobs of a creator in a queue with a status",
+			given:          "one failed emails job created by team-a",
+			when:           "GET to /api/jobs?created_by=team-a&queue=emails&status=failed",
+			then:           "should return only that job",
+			path:           "/api/jobs?created_by=team-a&queue=emails&status=failed",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"digest"},
+		},
+		{
+			name:           "Creator without jobs",
+			given:          "no job created by team-c",
+			when:           "GET to /api/jobs?created_by=team-c",
+			then:           "should return an empty list",
+			path:           "/api/jobs?created_by=team-c",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{},
+		},
+		{
+			name:           "Unknown status",
+			given:          "a status filter naming no job status",
+			when:           "GET to /api/jobs?created_by=team-a&status=done",
+			then:           "should return 400",
+			path:           "/api/jobs?created_by=team-a&status=done",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			now := time.Now().UTC()
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			for _, job := range []*queue.Job{
+				{ID: uuid.New(), Queue: "emails", Type: "welcome", Status: queue.StatusCompleted, CreatedBy: "team-a", CreatedAt: now.Add(-3 * time.Minute)},
+				{ID: uuid.New(), Queue: "emails", Type: "bounce", Status: queue.StatusFailed, CreatedBy: "team-b", CreatedAt: now.Add(-2 * time.Minute)},
+				{ID: uuid.New(), Queue: "emails", Type: "digest", Status: queue.StatusFailed, CreatedBy: "team-a", CreatedAt: now.Add(-time.Minute)},
+				{ID: uuid.New(), Queue: "default", Type: "report", Status: queue.StatusPending, CreatedBy: "team-a", CreatedAt: now},
+			} {
+				jobRepo.jobs[job.ID] = job
+			}
+			service := appQueue.NewService(jobRepo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			handlers := NewQueueHandlers(service, nil)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.ListJobs(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var resp []JobResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			types := make([]string, len(resp))
+			for i, job := range resp {
+				types[i] = job.Type
+				assert.Equal(t, "team-a", job.CreatedBy)
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+		})
+	}
+}
+
+func TestQueueHandlers_ListJobs_Metadata(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		path           string
+		expectedStatus int
+		expectedTypes  []string
+	}{
+		{
+			name:           "Jobs with a metadata value in all queues",
+			given:          "two jobs from the billing system in two queues",
+			when:           "GET to /api/jobs?metadata=source:billing",
+			then:           "should return both, newest first",
+			path:           "/api/jobs?metadata=source:billing",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"report", "welcome"},
+		},
+		{
+			name:           "Jobs with a metadata key in a queue",
+			given:          "two emails jobs carrying a correlation ID",
+			when:           "GET to /api/jobs?metadata=correlation_id&queue=emails",
+			then:           "should return only those",
+			path:           "/api/jobs?metadata=correlation_id&queue=emails",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"bounce", "welcome"},
+		},
+		{
+			name:           "Metadata combined with a creator",
+			given:          "one billing job created by team-a",
+			when:           "GET to /api/jobs?created_by=team-a&metadata=source:billing",
+			then:           "should return only that job",
+			path:           "/api/jobs?created_by=team-a&metadata=source:billing",
+			expectedStatus: http.StatusOK,
+			expectedTypes:  []string{"welcome"},
+		},
+		{
+			name:           "Invalid metadata filter",
+			given:          "a metadata filter without a key",
+			when:           "GET to /api/jobs?metadata=:billing",
+			then:           "should return 400",
+			path:           "/api/jobs?metadata=:billing",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			now := time.Now().UTC()
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			for _, job := range []*queue.Job{
+				{ID: uuid.New(), Queue: "emails", Type: "welcome", Status: queue.StatusCompleted, CreatedBy: "team-a", CreatedAt: now.Add(-3 * time.Minute),
+					Metadata: map[string]string{"source": "billing", "correlation_id": "abc"}},
+				{ID: uuid.New(), Queue: "emails", Type: "bounce", Status: queue.StatusFailed, CreatedBy: "team-b", CreatedAt: now.Add(-2 * time.Minute),
+					Metadata: map[string]string{"source": "crm", "correlation_id": "def"}},
+				{ID: uuid.New(), Queue: "emails", Type: "digest", Status: queue.StatusFailed, CreatedBy: "team-a", CreatedAt: now.Add(-time.Minute)},
+				{ID: uuid.New(), Queue: "default", Type: "report", Status: queue.StatusPending, CreatedBy: "team-b", CreatedAt: now,
+					Metadata: map[string]string{"source": "billing", "correlation_id": "ghi"}},
+			} {
+				jobRepo.jobs[job.ID] = job
+			}
+			service := appQueue.NewService(jobRepo, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			handlers := NewQueueHandlers(service, nil)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			handlers.ListJobs(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var resp []JobResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			types := make([]string, len(resp))
+			for i, job := range resp {
+				types[i] = job.Type
+				assert.NotEmpty(t, job.Metadata)
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+		})
+	}
+}
+
+func TestQueueHandlers_PurgeQueueJobs(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		disabled       bool
+		path           string
+		expectedStatus int
+		expectedBody   string
+		expectedQueued int
+	}{
+		{
+			name:           "Dry run",
+			given:          "two pending jobs in the emails queue",
+			when:           "DELETE to /api/queues/emails/jobs?status=pending&dry_run=true",
+			then:           "should return how many jobs would be purged and leave them queued",
+			path:           "/api/queues/emails/jobs?status=pending&dry_run=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"queue":"emails","status":"pending","dry_run":true,"matched":2,"purged":0,"skipped":0}`,
+			expectedQueued: 3,
+		},
+		{
+			name:           "Purge pending jobs",
+			given:          "two pending jobs in the emails queue",
+			when:           "DELETE to /api/queues/emails/jobs",
+			then:           "should remove both from the queue and report them purged",
+			path:           "/api/queues/emails/jobs",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"queue":"emails","status":"pending","dry_run":false,"matched":2,"purged":2,"skipped":0}`,
+			expectedQueued: 1,
+		},
+		{
+			name:           "Status that can't be purged",
+			given:          "the emails queue",
+			when:           "DELETE to /api/queues/emails/jobs?status=processing",
+			then:           "should return 400",
+			path:           "/api/queues/emails/jobs?status=processing",
+			expectedStatus: http.StatusBadRequest,
+			expectedQueued: 3,
+		},
+		{
+			name:           "Invalid dry run",
+			given:          "the emails queue",
+			when:           "DELETE to /api/queues/emails/jobs?dry_run=maybe",
+			then:           "should return 400",
+			path:           "/api/queues/emails/jobs?dry_run=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedQueued: 3,
+		},
+		{
+			name:           "Purge not enabled",
+			given:          "a service without a queue purger",
+			when:           "DELETE to /api/queues/emails/jobs",
+			then:           "should return 503",
+			disabled:       true,
+			path:           "/api/queues/emails/jobs",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedQueued: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			queueSvc := &InMemoryQueueSvc{}
+			for _, job := range []*queue.Job{
+				{ID: uuid.New(), Queue: "emails", Type: "welcome", Status: queue.StatusPending},
+				{ID: uuid.New(), Queue: "emails", Type: "digest", Status: queue.StatusPending},
+				{ID: uuid.New(), Queue: "default", Type: "report", Status: queue.StatusPending},
+			} {
+				jobRepo.jobs[job.ID] = job
+				queued := *job
+				queueSvc.jobs = append(queueSvc.jobs, &queued)
+			}
+			service := appQueue.NewService(jobRepo, queueSvc, &InMemoryMetrics{})
+			if !tt.disabled {
+				service.SetQueuePurger(queueSvc, jobRepo)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Len(t, queueSvc.jobs, tt.expectedQueued)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_ReleaseParkedJobs(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		disabled       bool
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+		expectedQueued int
+	}{
+		{
+			name:           "Release parked jobs",
+			given:          "two parked jobs in the emails queue",
+			when:           "POST to /api/queues/emails/parked/release",
+			then:           "should retry both and report them released",
+			method:         http.MethodPost,
+			path:           "/api/queues/emails/parked/release",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"queue":"emails","released":2}`,
+			expectedQueued: 2,
+		},
+		{
+			name:           "Release some parked jobs",
+			given:          "two parked jobs in the emails queue",
+			when:           "POST to /api/queues/emails/parked/release?count=1",
+			then:           "should retry one of them",
+			method:         http.MethodPost,
+			path:           "/api/queues/emails/parked/release?count=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"queue":"emails","released":1}`,
+			expectedQueued: 1,
+		},
+		{
+			name:           "Invalid count",
+			given:          "the emails queue",
+			when:           "POST to /api/queues/emails/parked/release?count=0",
+			then:           "should return 400",
+			method:         http.MethodPost,
+			path:           "/api/queues/emails/parked/release?count=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Wrong method",
+			given:          "the emails queue",
+			when:           "GET to /api/queues/emails/parked/release",
+			then:           "should return 405",
+			method:         http.MethodGet,
+			path:           "/api/queues/emails/parked/release",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "Release not enabled",
+			given:          "a service without a parked job repository",
+			when:           "POST to /api/queues/emails/parked/release",
+			then:           "should return 503",
+			disabled:       true,
+			method:         http.MethodPost,
+			path:           "/api/queues/emails/parked/release",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobRepo := &InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}
+			for _, job := range []*queue.Job{
+				{ID: uuid.New(), Queue: "emails", Type: "welcome", Status: queue.StatusParked, Attempts: 1},
+				{ID: uuid.New(), Queue: "emails", Type: "digest", Status: queue.StatusParked, Attempts: 2},
+				{ID: uuid.New(), Queue: "default", Type: "report", Status: queue.StatusParked, Attempts: 1},
+			} {
+				jobRepo.jobs[job.ID] = job
+			}
+			queueSvc := &InMemoryQueueSvc{}
+			service := appQueue.NewService(jobRepo, queueSvc, &InMemoryMetrics{})
+			if !tt.disabled {
+				service.SetParkedJobs(jobRepo)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Len(t, queueSvc.jobs, tt.expectedQueued)
+			for _, job := range queueSvc.jobs {
+				assert.Equal(t, "emails", job.Queue)
+				assert.Equal(t, queue.StatusRetrying, job.Status)
+			}
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_DrainQueue(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		draining       bool
+		queued         int
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedDrain  *DrainStatusResponse
+		expectDraining bool
+	}{
+		{
+			name:           "Drain a queue with jobs left",
+			given:          "the default queue holds two jobs",
+			when:           "POST to /api/queues/default/drain",
+			then:           "should return 202, mark the queue draining and report the jobs left",
+			queued:         2,
+			method:         http.MethodPost,
+			path:           "/api/queues/default/drain",
+			expectedStatus: http.StatusAccepted,
+			expectedDrain:  &DrainStatusResponse{Queue: "default", Draining: true, Ready: 2},
+			expectDraining: true,
+		},
+		{
+			name:           "Drain an empty queue",
+			given:          "the default queue holds no jobs",
+			when:           "POST to /api/queues/default/drain",
+			then:           "should return 200 and report it drained",
+			method:         http.MethodPost,
+			path:           "/api/queues/default/drain",
+			expectedStatus: http.StatusOK,
+			expectedDrain:  &DrainStatusResponse{Queue: "default", Draining: true, Drained: true},
+			expectDraining: true,
+		},
+		{
+			name:           "Create a job in a draining queue",
+			given:          "the default queue is draining",
+			when:           "POST to /api/jobs for the default queue",
+			then:           "should return 409",
+			draining:       true,
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			body:           `{"queue":"default","type":"email","payload":{}}`,
+			expectedStatus: http.StatusConflict,
+			expectDraining: true,
+		},
+		{
+			name:           "Report drain progress",
+			given:          "the draining default queue holds one job",
+			when:           "GET to /api/queues/default/drain",
+			then:           "should return 200 with the job left",
+			draining:       true,
+			queued:         1,
+			method:         http.MethodGet,
+			path:           "/api/queues/default/drain",
+			expectedStatus: http.StatusOK,
+			expectedDrain:  &DrainStatusResponse{Queue: "default", Draining: true, Ready: 1},
+			expectDraining: true,
+		},
+		{
+			name:           "Resume a draining queue",
+			given:          "the default queue is draining",
+			when:           "DELETE to /api/queues/default/drain",
+			then:           "should return 200 and accept jobs again",
+			draining:       true,
+			method:         http.MethodDelete,
+			path:           "/api/queues/default/drain",
+			expectedStatus: http.StatusOK,
+			expectedDrain:  &DrainStatusResponse{Queue: "default"},
+		},
+		{
+			name:           "Replace the settings of a draining queue",
+			given:          "the default queue is draining",
+			when:           "PUT to /api/queues/default",
+			then:           "should keep the queue draining",
+			draining:       true,
+			method:         http.MethodPut,
+			path:           "/api/queues/default",
+			body:           `{"max_attempts":5}`,
+			expectedStatus: http.StatusOK,
+			expectDraining: true,
+		},
+		{
+			name:           "Drain an unknown queue",
+			given:          "no definition named reports",
+			when:           "POST to /api/queues/reports/drain",
+			then:           "should return 404",
+			method:         http.MethodPost,
+			path:           "/api/queues/reports/drain",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &InMemoryDefinitionRepo{defs: map[string]*queue.Definition{
+				"default": {Name: "default", Draining: tt.draining},
+			}}
+			queueSvc := &InMemoryQueueSvc{}
+			for i := 0; i < tt.queued; i++ {
+				job, _ := queue.NewJob("default", "email", []byte(`{}`))
+				queueSvc.jobs = append(queueSvc.jobs, job)
+			}
+			service := appQueue.NewService(&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}, queueSvc, &InMemoryMetrics{})
+			service.SetQueueDefinitions(repo, false)
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedDrain != nil {
+				var resp DrainStatusResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, *tt.expectedDrain, resp)
+			}
+			assert.Equal(t, tt.expectDraining, repo.defs["default"].Draining)
+			assert.Len(t, queueSvc.jobs, tt.queued)
+		})
+	}
+}
+
+// InMemoryUsageStore counts usage per tenant without daily resets
+type InMemoryUsageStore struct {
+	jobs     map[string]int64
+	analyses map[string]int64
+	pending  map[uuid.UUID]string
+}
+
+func NewInMemoryUsageStore() *InMemoryUsageStore {
+	return &InMemoryUsageStore{
+		jobs:     make(map[string]int64),
+		analyses: make(map[string]int64),
+		pending:  make(map[uuid.UUID]string),
+	}
+}
+
+func (s *InMemoryUsageStore) pendingOf(tenant string) int64 {
+	var count int64
+	for _, owner := range s.pending {
+		if owner == tenant {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *InMemoryUsageStore) ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID, limits quota.Quota, now time.Time) error {
+	if limits.JobsPerDay > 0 && s.jobs[tenant] >= int64(limits.JobsPerDay) {
+		return quota.NewExceededError(quota.ResourceJobs, limits.JobsPerDay, now)
+	}
+	if limits.MaxPendingJobs > 0 && s.pendingOf(tenant) >= int64(limits.MaxPendingJobs) {
+		return quota.NewExceededError(quota.ResourcePendingJobs, limits.MaxPendingJobs, now)
+	}
+	s.jobs[tenant]++
+	s.pending[jobID] = tenant
+	return nil
+}
+
+func (s *InMemoryUsageStore) ReleaseJob(ctx context.Context, jobID uuid.UUID) error {
+	delete(s.pending, jobID)
+	return nil
+}
+
+func (s *InMemoryUsageStore) TenantOf(ctx context.Context, jobID uuid.UUID) (string, error) {
+	return s.pending[jobID], nil
+}
+
+func (s *InMemoryUsageStore) ReserveAnalysis(ctx context.Context, tenant string, limits quota.Quota, now time.Time) error {
+	if limits.AnalysesPerDay > 0 && s.analyses[tenant] >= int64(limits.AnalysesPerDay) {
+		return quota.NewExceededError(quota.ResourceAnalyses, limits.AnalysesPerDay, now)
+	}
+	s.analyses[tenant]++
+	return nil
+}
+
+func (s *InMemoryUsageStore) Usage(ctx context.Context, tenant string, now time.Time) (*quota.Usage, error) {
+	return &quota.Usage{
+		Day:           quota.Day(now),
+		JobsToday:     s.jobs[tenant],
+		PendingJobs:   s.pendingOf(tenant),
+		AnalysesToday: s.analyses[tenant],
+	}, nil
+}
+
+func TestQueueHandlers_Quotas(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		disabled       bool
+		limits         quota.Quota
+		created        int // Jobs created with key-a beforehand
+		anonymous      int // Jobs created without an API key beforehand
+		method         string
+		path           string
+		apiKey         string
+		body           string
+		expectedStatus int
+		expectRetry    bool
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Create a job within quota",
+			given:          "an API key that created one of its two jobs per day",
+			when:           "POST to /api/jobs",
+			then:           "should return 201",
+			limits:         quota.Quota{JobsPerDay: 2},
+			created:        1,
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			apiKey:         "key-a",
+			body:           `{"queue":"default","type":"email","payload":{}}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Create a job over the daily quota",
+			given:          "an API key that created its two jobs per day",
+			when:           "POST to /api/jobs",
+			then:           "should return 429 with a Retry-After until the quota resets",
+			limits:         quota.Quota{JobsPerDay: 2},
+			created:        2,
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			apiKey:         "key-a",
+			body:           `{"queue":"default","type":"email","payload":{}}`,
+			expectedStatus: http.StatusTooManyRequests,
+			expectRetry:    true,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp map[string]any
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, "jobs_per_day", resp["quota"])
+				assert.Equal(t, float64(2), resp["limit"])
+			},
+		},
+		{
+			name:           "Create a job over the pending quota",
+			given:          "an API key with its one allowed job still pending",
+			when:           "POST to /api/jobs",
+			then:           "should return 429 without a Retry-After",
+			limits:         quota.Quota{MaxPendingJobs: 1},
+			created:        1,
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			apiKey:         "key-a",
+			body:           `{"queue":"default","type":"email","payload":{}}`,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "Create a job as another API key",
+			given:          "an API key that created its two jobs per day",
+			when:           "POST to /api/jobs with a different API key",
+			then:           "should return 201",
+			limits:         quota.Quota{JobsPerDay: 2},
+			created:        2,
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			apiKey:         "key-b",
+			body:           `{"queue":"default","type":"email","payload":{}}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Report usage",
+			given:          "an API key that created one job",
+			when:           "GET to /api/usage",
+			then:           "should return 200 with its usage and what is left",
+			limits:         quota.Quota{JobsPerDay: 10, MaxPendingJobs: 5},
+			created:        1,
+			method:         http.MethodGet,
+			path:           "/api/usage",
+			apiKey:         "key-a",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp QuotaUsageResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				nine, four := int64(9), int64(4)
+				assert.Equal(t, ResourceUsageResponse{Used: 1, Limit: 10, Remaining: &nine}, resp.JobsPerDay)
+				assert.Equal(t, ResourceUsageResponse{Used: 1, Limit: 5, Remaining: &four}, resp.PendingJobs)
+				assert.Equal(t, ResourceUsageResponse{Used: 0, Limit: 0}, resp.AnalysesPerDay)
+			},
+		},
+		{
+			name:           "Report usage without an API key",
+			given:          "an API key that created one job",
+			when:           "GET to /api/usage without an API key",
+			then:           "should return 200 with the usage callers without an API key share",
+			limits:         quota.Quota{JobsPerDay: 10},
+			created:        1,
+			method:         http.MethodGet,
+			path:           "/api/usage",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp QuotaUsageResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				ten := int64(10)
+				assert.Equal(t, ResourceUsageResponse{Used: 0, Limit: 10, Remaining: &ten}, resp.JobsPerDay)
+			},
+		},
+		{
+			name:           "Create a job without an API key over quota",
+			given:          "callers without an API key that created their two jobs per day",
+			when:           "POST to /api/jobs without an API key",
+			then:           "should return 429, as they share one quota",
+			limits:         quota.Quota{JobsPerDay: 2},
+			anonymous:      2,
+			method:         http.MethodPost,
+			path:           "/api/jobs",
+			body:           `{"queue":"default","type":"email","payload":{}}`,
+			expectedStatus: http.StatusTooManyRequests,
+			expectRetry:    true,
+		},
+		{
+			name:           "Report usage with quotas disabled",
+			given:          "quotas are not enabled",
+			when:           "GET to /api/usage",
+			then:           "should return 503",
+			disabled:       true,
+			method:         http.MethodGet,
+			path:           "/api/usage",
+			apiKey:         "key-a",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appQueue.NewService(&InMemoryJobRepo{jobs: make(map[uuid.UUID]*queue.Job)}, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			handlers := NewQueueHandlers(service, nil)
+			if !tt.disabled {
+				quotaService := appQuota.NewService(NewInMemoryUsageStore(), nil, tt.limits)
+				service.SetQuotaEnforcer(quotaService)
+				handlers.SetQuotaService(quotaService)
+			}
+			for i := 0; i < tt.created; i++ {
+				_, err := service.CreateJob(context.Background(), appQueue.CreateJobCommand{
+					Queue: "default", Type: "email", Payload: map[string]any{}, APIKey: "key-a",
+				})
+				assert.NoError(t, err)
+			}
+			for i := 0; i < tt.anonymous; i++ {
+				_, err := service.CreateJob(context.Background(), appQueue.CreateJobCommand{
+					Queue: "default", Type: "email", Payload: map[string]any{},
+				})
+				assert.NoError(t, err)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, handlers)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.apiKey != "" {
+				req.Header.Set(DefaultAPIKeyHeader, tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectRetry, rec.Header().Get("Retry-After") != "")
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_Consistency(t *testing.T) {
+	tests := []struct {
+		name             string
+		given            string
+		when             string
+		then             string
+		method           string
+		path             string
+		withoutInspector bool
+		expectedStatus   int
+		expectedStranded int
+		expectedRepaired int
+		expectedQueued   int
+	}{
+		{
+			name:             "Report stranded jobs",
+			given:            "a pending job that was never enqueued in Redis next to one that was",
+			when:             "GET /api/consistency",
+			then:             "should report the missing job without enqueueing it",
+			method:           http.MethodGet,
+			path:             "/api/consistency",
+			expectedStatus:   http.StatusOK,
+			expectedStranded: 1,
+			expectedQueued:   1,
+		},
+		{
+			name:             "Repair stranded jobs",
+			given:            "a pending job that was never enqueued in Redis next to one that was",
+			when:             "POST /api/consistency/repair?queue=emails",
+			then:             "should re-enqueue the missing job",
+			method:           http.MethodPost,
+			path:             "/api/consistency/repair?queue=emails",
+			expectedStatus:   http.StatusOK,
+			expectedStranded: 1,
+			expectedRepaired: 1,
+			expectedQueued:   2,
+		},
+		{
+			name:           "Repair with GET",
+			given:          "the repair endpoint",
+			when:           "GET /api/consistency/repair",
+			then:           "should return 405",
+			method:         http.MethodGet,
+			path:           "/api/consistency/repair",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedQueued: 1,
+		},
+		{
+			name:           "Invalid limit",
+			given:          "a limit above the maximum",
+			when:           "GET /api/consistency?limit=1000000",
+			then:           "should return 400",
+			method:         http.MethodGet,
+			path:           "/api/consistency?limit=1000000",
+			expectedStatus: http.StatusBadRequest,
+			expectedQueued: 1,
+		},
+		{
+			name:             "Backend can't be inspected",
+			given:            "a queue service without a queue inspector",
+			when:             "GET /api/consistency",
+			then:             "should return 503",
+			method:           http.MethodGet,
+			path:             "/api/consistency",
+			withoutInspector: true,
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedQueued:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			old := time.Now().UTC().Add(-time.Hour)
+			queued := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, UpdatedAt: old}
+			stranded := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, UpdatedAt: old}
+			jobRepo := &InMemoryJobRepo{jobs: map[uuid.UUID]*queue.Job{queued.ID: queued, stranded.ID: stranded}}
+			queueSvc := &InMemoryQueueSvc{jobs: []*queue.Job{queued}}
+			service := appQueue.NewService(jobRepo, queueSvc, &InMemoryMetrics{})
+			if !tt.withoutInspector {
+				service.SetQueueInspector(queueSvc)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Len(t, queueSvc.jobs, tt.expectedQueued)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp ConsistencyReportResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, []string{"emails"}, resp.Queues)
+			assert.Equal(t, 2, resp.Checked)
+			assert.Len(t, resp.Stranded, tt.expectedStranded)
+			assert.Equal(t, tt.expectedRepaired, resp.Repaired)
+			if tt.expectedStranded > 0 {
+				assert.Equal(t, stranded.ID.String(), resp.Stranded[0].JobID)
+				assert.Equal(t, tt.expectedRepaired > 0, resp.Stranded[0].Repaired)
+			}
+		})
+	}
+}
+
+func TestQueueHandlers_DeadLetters(t *testing.T) {
+	tests := []struct {
+		name                string
+		given               string
+		when                string
+		then                string
+		method              string
+		path                string
+		withoutDeadLetters  bool
+		expectedStatus      int
+		expectedJobs        int
+		expectedQueued      int
+		expectedDeadLetters int
+	}{
+		{
+			name:                "Peek at dead letters",
+			given:               "two dead letters in the emails queue",
+			when:                "GET /api/dlq/redis?queue=emails&limit=1",
+			then:                "should return the oldest one and the total, leaving both in place",
+			method:              http.MethodGet,
+			path:                "/api/dlq/redis?queue=emails&limit=1",
+			expectedStatus:      http.StatusOK,
+			expectedJobs:        1,
+			expectedDeadLetters: 2,
+		},
+		{
+			name:                "Dump dead letters",
+			given:               "two dead letters in the emails queue",
+			when:                "GET /api/dlq/redis/dump?queue=emails",
+			then:                "should return them all as a download",
+			method:              http.MethodGet,
+			path:                "/api/dlq/redis/dump?queue=emails",
+			expectedStatus:      http.StatusOK,
+			expectedJobs:        2,
+			expectedDeadLetters: 2,
+		},
+		{
+			name:                "Replay dead letters",
+			given:               "two dead letters in the emails queue whose jobs are failed",
+			when:                "POST /api/dlq/redis/replay?queue=emails&count=1",
+			then:                "should enqueue the oldest job again and remove its dead letter",
+			method:              http.MethodPost,
+			path:                "/api/dlq/redis/replay?queue=emails&count=1",
+			expectedStatus:      http.StatusOK,
+			expectedJobs:        1,
+			expectedQueued:      1,
+			expectedDeadLetters: 1,
+		},
+		{
+			name:                "Replay without count",
+			given:               "dead letters in the emails queue",
+			when:                "POST /api/dlq/redis/replay?queue=emails",
+			then:                "should return 400",
+			method:              http.MethodPost,
+			path:                "/api/dlq/redis/replay?queue=emails",
+			expectedStatus:      http.StatusBadRequest,
+			expectedDeadLetters: 2,
+		},
+		{
+			name:                "Missing queue",
+			given:               "dead letters in the emails queue",
+			when:                "GET /api/dlq/redis",
+			then:                "should return 400",
+			method:              http.MethodGet,
+			path:                "/api/dlq/redis",
+			expectedStatus:      http.StatusBadRequest,
+			expectedDeadLetters: 2,
+		},
+		{
+			name:                "Dead letters not kept",
+			given:               "a queue service without a dead letter queue",
+			when:                "GET /api/dlq/redis?queue=emails",
+			then:                "should return 503",
+			method:              http.MethodGet,
+			path:                "/api/dlq/redis?queue=emails",
+			withoutDeadLetters:  true,
+			expectedStatus:      http.StatusServiceUnavailable,
+			expectedDeadLetters: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			first := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusFailed, Attempts: 3}
+			second := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusFailed, Attempts: 3}
+			jobRepo := &InMemoryJobRepo{jobs: map[uuid.UUID]*queue.Job{first.ID: first, second.ID: second}}
+			queueSvc := &InMemoryQueueSvc{deadLetters: []*queue.Job{first, second}}
+			service := appQueue.NewService(jobRepo, queueSvc, &InMemoryMetrics{})
+			if !tt.withoutDeadLetters {
+				service.SetDeadLetterQueue(queueSvc)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Len(t, queueSvc.jobs, tt.expectedQueued)
+			assert.Len(t, queueSvc.deadLetters, tt.expectedDeadLetters)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			switch {
+			case strings.HasPrefix(tt.path, "/api/dlq/redis/replay"):
+				var resp ReplayReportResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedJobs, resp.Replayed)
+				assert.Equal(t, int64(1), resp.Remaining)
+				assert.Equal(t, first.ID.String(), resp.Jobs[0].JobID)
+				assert.Equal(t, string(queue.StatusRetrying), resp.Jobs[0].Status)
+			case strings.HasPrefix(tt.path, "/api/dlq/redis/dump"):
+				var resp []JobResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Len(t, resp, tt.expectedJobs)
+				assert.Equal(t, `attachment; filename=dlq-emails.json`, rec.Header().Get("Content-Disposition"))
+			default:
+				var resp DeadLettersResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Len(t, resp.Jobs, tt.expectedJobs)
+				assert.Equal(t, int64(2), resp.Total)
+				assert.Equal(t, first.ID.String(), resp.Jobs[0].ID)
+			}
+		})
+	}
+}
+
+type StaticJobCounters []*queue.JobCounter
+
+func (c StaticJobCounters) JobCounters(ctx context.Context) ([]*queue.JobCounter, error) {
+	return c, nil
+}
+
+func (c StaticJobCounters) JobDurations(ctx context.Context) ([]*queue.DurationHistogram, error) {
+	return nil, nil
+}
+
+func TestQueueHandlers_PrometheusMetrics(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		counters       StaticJobCounters
+		degraded       bool
+		expectedStatus int
+		expectedBody   string
+		expectedLines  []string
+	}{
+		{
+			name:  "Export counters",
+			given: "created and failed counters, with a label value needing escapes",
+			when:  "GET /metrics",
+			then:  "should write each counter family with queue and type labels, and the category of failures",
+			counters: StaticJobCounters{
+				{Event: queue.CounterFailed, Queue: "emails", Type: `send "welcome"`, Category: queue.FailureAuth, Count: 2},
+				{Event: queue.CounterCreated, Queue: "emails", Type: "send-email", Count: 5},
+				{Event: queue.CounterFailed, Queue: "emails", Type: "send-email", Category: queue.FailureTimeout, Count: 3},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `# HELP aisq_jobs_created_total Jobs created.
+# TYPE aisq_jobs_created_total counter
+aisq_jobs_created_total{queue="emails",type="send-email"} 5
+# HELP aisq_jobs_completed_total Job executions that completed.
+# TYPE aisq_jobs_completed_total counter
+# HELP aisq_jobs_failed_total Job executions that failed, by failure category.
+# TYPE aisq_jobs_failed_total counter
+aisq_jobs_failed_total{queue="emails",type="send \"welcome\"",category="auth"} 2
+aisq_jobs_failed_total{queue="emails",type="send-email",category="timeout"} 3
+# HELP aisq_jobs_retried_total Jobs retried by hand or by replaying dead letters.
+# TYPE aisq_jobs_retried_total counter
+# HELP aisq_jobs_parked_total Failed jobs parked because their queue's retry budget was exhausted.
+# TYPE aisq_jobs_parked_total counter
+`,
+		},
+		{
+			name:           "AI provider degraded",
+			given:          "an AI provider that failed as many health probes as the threshold",
+			when:           "GET /metrics",
+			then:           "should write its state, failures and outages after the job counters",
+			counters:       StaticJobCounters{},
+			degraded:       true,
+			expectedStatus: http.StatusOK,
+			expectedLines: []string{
+				"# TYPE aisq_ai_provider_degraded gauge\naisq_ai_provider_degraded 1\n",
+				"aisq_ai_provider_consecutive_failures 3\n",
+				"# TYPE aisq_ai_provider_outages_total counter\naisq_ai_provider_outages_total 1\n",
+				"# TYPE aisq_ai_provider_downtime_seconds_total counter\n",
+			},
+		},
+		{
+			name:           "Counters not kept",
+			given:          "a queue service without a metrics reader",
+			when:           "GET /metrics",
+			then:           "should return 503",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := appQueue.NewService(&InMemoryJobRepo{}, &InMemoryQueueSvc{}, &InMemoryMetrics{})
+			if tt.counters != nil {
+				service.SetMetricsReader(tt.counters)
+			}
+			var insightsService *appInsights.Service
+			if tt.degraded {
+				insightsService = appInsights.NewService(nil, nil, &MockAIService{})
+				degradeProvider(insightsService)
+			}
+			mux := http.NewServeMux()
+			RegisterQueueRoutes(mux, NewQueueHandlers(service, insightsService))
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+				assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+			}
+			for _, line := range tt.expectedLines {
+				assert.Contains(t, rec.Body.String(), line)
+			}
+		})
+	}
+}
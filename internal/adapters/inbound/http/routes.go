@@ -1,8 +1,9 @@
 package http
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 )
 
 // RegisterQueueRoutes registers all queue-related routes
@@ -10,7 +11,10 @@ func RegisterQueueRoutes(mux *http.ServeMux, handlers *QueueHandlers) {
 	// Register handler for /api/jobs (without trailing slash)
 	mux.HandleFunc("/api/jobs", func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
-		log.Printf("[Router] Path: %s, Method: %s", path, r.Method)
+		slog.DebugContext(r.Context(), "Routing request",
+			slog.String("path", path),
+			slog.String("method", r.Method),
+		)
 
 		if path == "/api/jobs" || path == "/api/jobs/" {
 			// /api/jobs endpoint
@@ -36,9 +40,16 @@ func RegisterQueueRoutes(mux *http.ServeMux, handlers *QueueHandlers) {
 	// POST /api/jobs - Create job
 	// GET /api/jobs - List jobs with optional filters and pagination
 	// GET /api/jobs/{id} - Get specific job by ID
+	// PATCH /api/jobs/{id} - Edit a pending or retrying job's payload or schedule
+	// DELETE /api/jobs/{id} - Soft-delete a job
+	// POST /api/jobs/{id}/undelete - Restore a soft-deleted job
+	// GET /api/jobs/{id}/output - Read or tail the output a job streams, with Range support
 	mux.HandleFunc("/api/jobs/", func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
-		log.Printf("[Router] Path: %s, Method: %s", path, r.Method)
+		slog.DebugContext(r.Context(), "Routing request",
+			slog.String("path", path),
+			slog.String("method", r.Method),
+		)
 
 		if path == "/api/jobs" || path == "/api/jobs/" {
 			// /api/jobs endpoint
@@ -51,16 +62,53 @@ func RegisterQueueRoutes(mux *http.ServeMux, handlers *QueueHandlers) {
 			default:
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			}
+		} else if strings.HasSuffix(path, "/output") {
+			// /api/jobs/{id}/output endpoint
+			if r.Method == http.MethodGet {
+				handlers.GetJobOutput(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		} else if strings.HasSuffix(path, "/undelete") {
+			// /api/jobs/{id}/undelete endpoint
+			if r.Method == http.MethodPost {
+				handlers.UndeleteJob(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
 		} else {
 			// /api/jobs/{id} endpoint
-			if r.Method == http.MethodGet {
+			switch r.Method {
+			case http.MethodGet:
 				handlers.GetJobByID(w, r)
-			} else {
+			case http.MethodPatch:
+				handlers.EditJob(w, r)
+			case http.MethodDelete:
+				handlers.DeleteJob(w, r)
+			default:
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			}
 		}
 	})
 
+	// GET /api/jobs/search?q=... - Full-text search over job errors and payloads
+	mux.HandleFunc("/api/jobs/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.SearchJobs(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// POST /api/jobs/execute?timeout=30 - Create a job and wait for it to finish
+	mux.HandleFunc("/api/jobs/execute", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ExecuteJob(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	mux.HandleFunc("/api/jobs/retry", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			handlers.RetryJob(w, r)
@@ -77,6 +125,33 @@ func RegisterQueueRoutes(mux *http.ServeMux, handlers *QueueHandlers) {
 		}
 	})
 
+	// GET /api/dlq/redis?queue=...&limit=... - Peek at the oldest dead letters Redis keeps for a queue
+	mux.HandleFunc("/api/dlq/redis", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetDeadLetters(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/dlq/redis/dump?queue=... - Download every dead letter of a queue
+	mux.HandleFunc("/api/dlq/redis/dump", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.DumpDeadLetters(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// POST /api/dlq/redis/replay?queue=...&count=N - Retry the jobs of the N oldest dead letters
+	mux.HandleFunc("/api/dlq/redis/replay", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ReplayDeadLetters(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	mux.HandleFunc("/api/metrics", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			handlers.GetMetrics(w, r)
@@ -85,6 +160,164 @@ func RegisterQueueRoutes(mux *http.ServeMux, handlers *QueueHandlers) {
 		}
 	})
 
+	// GET /metrics - Job counters in the Prometheus text format
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.PrometheusMetrics(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/metrics/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetMetricsHistory(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/scaling/recommendation", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetScalingRecommendation(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/queues - List every known queue with its job counts and settings
+	// POST /api/queues - Define a queue
+	mux.HandleFunc("/api/queues", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ListQueues(w, r)
+		case http.MethodPost:
+			handlers.CreateQueueDefinition(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/queues/{name} - Get a queue definition
+	// PUT /api/queues/{name} - Replace a queue definition's settings
+	// DELETE /api/queues/{name} - Remove a queue definition
+	// POST /api/queues/{name}/drain - Stop accepting jobs until the queue is empty
+	// GET /api/queues/{name}/drain - Report the drain progress
+	// DELETE /api/queues/{name}/drain - Accept jobs again
+	// GET /api/queues/{name}/jobs - List the queue's jobs, newest first
+	// DELETE /api/queues/{name}/jobs?status=pending - Purge the queue's pending jobs
+	// POST /api/queues/{name}/parked/release?count=... - Retry the jobs the retry budget parked
+	mux.HandleFunc("/api/queues/", func(w http.ResponseWriter, r *http.Request) {
+		if queueNameFromPath(r) == "" {
+			http.Error(w, "queue name is required", http.StatusBadRequest)
+			return
+		}
+		if isQueueJobsPath(r) {
+			switch r.Method {
+			case http.MethodGet:
+				handlers.ListQueueJobs(w, r)
+			case http.MethodDelete:
+				handlers.PurgeQueueJobs(w, r)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if isParkedReleasePath(r) {
+			if r.Method == http.MethodPost {
+				handlers.ReleaseParkedJobs(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if isDrainPath(r) {
+			switch r.Method {
+			case http.MethodPost:
+				handlers.DrainQueue(w, r)
+			case http.MethodGet:
+				handlers.GetDrainStatus(w, r)
+			case http.MethodDelete:
+				handlers.ResumeQueue(w, r)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetQueueDefinition(w, r)
+		case http.MethodPut:
+			handlers.UpdateQueueDefinition(w, r)
+		case http.MethodDelete:
+			handlers.DeleteQueueDefinition(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// POST /api/groups - Create a group of jobs and its completion job
+	mux.HandleFunc("/api/groups", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.CreateGroup(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/groups/{id} - Get the progress of a group
+	mux.HandleFunc("/api/groups/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetGroup(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/dashboard - Everything the dashboard front-end shows, in one call
+	mux.HandleFunc("/api/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetDashboard(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/consistency?queue=...&limit=... - Jobs ready in Postgres that Redis doesn't hold
+	mux.HandleFunc("/api/consistency", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.CheckConsistency(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// POST /api/consistency/repair?queue=...&limit=... - Re-enqueue the stranded jobs
+	mux.HandleFunc("/api/consistency/repair", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.RepairStrandedJobs(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/usage - The caller's quota and what it used of it today
+	mux.HandleFunc("/api/usage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetUsage(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/breakers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.ListBreakers(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
@@ -95,7 +328,33 @@ func RegisterQueueRoutes(mux *http.ServeMux, handlers *QueueHandlers) {
 func RegisterInsightsRoutes(mux *http.ServeMux, handlers *InsightsHandlers) {
 	// GET /api/insights - List insights with optional filters and pagination
 	// GET /api/insights/{id} - Get specific insight by ID
+	// PATCH /api/insights/{id} - Edit the recommendation or annotate an insight
+	// POST /api/insights/{id}/apply?dry_run=true - Preview or apply the suggested fix
+	// GET /api/insights/{id}/report?format=markdown|html - Incident report for a postmortem
 	mux.HandleFunc("/api/insights/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/apply") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			handlers.ApplyInsightFix(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/report") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			handlers.GetInsightReport(w, r)
+			return
+		}
+
+		if r.Method == http.MethodPatch && len(r.URL.Path) > len("/api/insights/") {
+			handlers.EditInsight(w, r)
+			return
+		}
+
 		if r.Method != http.MethodGet {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -117,6 +376,66 @@ func RegisterInsightsRoutes(mux *http.ServeMux, handlers *InsightsHandlers) {
 		}
 	})
 
+	registerAnalyzeRoutes(mux, handlers)
+
+	// GET /api/insights/analysis/{id} - Status of an asynchronous analysis
+	mux.HandleFunc("/api/insights/analysis/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetAnalysis(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/insights/usage?days=30 - AI token usage per day and provider
+	mux.HandleFunc("/api/insights/usage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetUsage(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/insights/effectiveness?days=90[&job_type=...] - How often applied fixes worked
+	mux.HandleFunc("/api/insights/effectiveness", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetFixEffectiveness(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/insights/summary?queue=...[&days=30&ai=false] - Recurring diagnoses and common
+	// fixes of a queue, with an AI executive summary
+	mux.HandleFunc("/api/insights/summary", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetQueueSummary(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/insights/clusters - Groups of insights with similar diagnoses, largest first
+	mux.HandleFunc("/api/insights/clusters", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetInsightClusters(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// RegisterStandaloneInsightsRoutes registers the routes served by an insights service running
+// without the database, which only analyzes failures sent in the request body
+func RegisterStandaloneInsightsRoutes(mux *http.ServeMux, handlers *InsightsHandlers) {
+	registerAnalyzeRoutes(mux, handlers)
+}
+
+func registerAnalyzeRoutes(mux *http.ServeMux, handlers *InsightsHandlers) {
+	// POST /api/insights/analyze?job_id=...[&async=true&callback_url=...] - Analyze a failed job;
+	// async returns 202 with the analysis to poll instead of waiting for the AI
+	// POST /api/insights/analyze with the failed job in the body - Analyze a job the service
+	// can't read, returning the insight without storing it
 	mux.HandleFunc("/api/insights/analyze", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			handlers.AnalyzeJob(w, r)
@@ -124,4 +443,103 @@ func RegisterInsightsRoutes(mux *http.ServeMux, handlers *InsightsHandlers) {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+
+	// GET /api/insights/provider-health - Whether the AI provider passes its health probes,
+	// with its downtime
+	mux.HandleFunc("/api/insights/provider-health", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetProviderHealth(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// RegisterAlertRoutes registers the routes managing alert rules
+func RegisterAlertRoutes(mux *http.ServeMux, handlers *AlertHandlers) {
+	// GET /api/alerts/rules - List alert rules
+	// POST /api/alerts/rules - Create an alert rule
+	mux.HandleFunc("/api/alerts/rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ListAlertRules(w, r)
+		case http.MethodPost:
+			handlers.CreateAlertRule(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/alerts/rules/{id} - Get an alert rule
+	// PATCH /api/alerts/rules/{id} - Change, enable or disable an alert rule
+	// DELETE /api/alerts/rules/{id} - Remove an alert rule
+	mux.HandleFunc("/api/alerts/rules/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetAlertRule(w, r)
+		case http.MethodPatch:
+			handlers.UpdateAlertRule(w, r)
+		case http.MethodDelete:
+			handlers.DeleteAlertRule(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// RegisterDigestRoutes registers the routes reading the DLQ digests sent
+func RegisterDigestRoutes(mux *http.ServeMux, handlers *DigestHandlers) {
+	// GET /api/alerts/digests - List the DLQ digests sent, newest first
+	mux.HandleFunc("/api/alerts/digests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handlers.ListDigests(w, r)
+	})
+}
+
+// RegisterLiveFeedRoutes registers the live dashboard feed
+func RegisterLiveFeedRoutes(mux *http.ServeMux, feed *LiveFeed) {
+	// GET /ws - WebSocket streaming metrics snapshots and job state changes
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		feed.ServeWS(w, r)
+	})
+}
+
+// RegisterWorkerAdminRoutes registers the routes of a worker-runtime process's admin server
+func RegisterWorkerAdminRoutes(mux *http.ServeMux, handlers *WorkerAdminHandlers) {
+	// GET /jobs - Jobs the process is executing
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.RunningJobs(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /stats - Throughput, goroutines and memory of the process
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.Stats(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /metrics - The same figures in the Prometheus text format
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.Metrics(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /health - OK, or DEGRADED while the queue broker is unreachable
+	mux.HandleFunc("/health", handlers.Health)
 }
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server defaults, used for zero ServerOptions fields
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = 1 << 20
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
+// ServerOptions configures the HTTP server of a service. Zero values fall back to the defaults.
+type ServerOptions struct {
+	ReadHeaderTimeout time.Duration // Time allowed to read the request headers
+	ReadTimeout       time.Duration // Time allowed to read the whole request, body included
+	WriteTimeout      time.Duration // Time allowed to write the response, from the end of the headers
+	IdleTimeout       time.Duration // Time a keep-alive connection waits for the next request
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration // Time in-flight requests get to finish once shutdown starts
+
+	// TLS is served when both are set; HTTP/2 is then negotiated with clients supporting it
+	CertFile string
+	KeyFile  string
+	H2C      bool // Also serve HTTP/2 without TLS, for proxies that speak cleartext HTTP/2
+}
+
+// TLSEnabled reports whether the server serves TLS
+func (o ServerOptions) TLSEnabled() bool {
+	return o.CertFile != "" && o.KeyFile != ""
+}
+
+// Server is an HTTP server that drains its connections when its context is cancelled
+type Server struct {
+	server *http.Server
+	opts   ServerOptions
+}
+
+// NewServer creates a server for handler listening on addr
+func NewServer(addr string, handler http.Handler, opts ServerOptions) *Server {
+	opts.ReadHeaderTimeout = durationOr(opts.ReadHeaderTimeout, DefaultReadHeaderTimeout)
+	opts.ReadTimeout = durationOr(opts.ReadTimeout, DefaultReadTimeout)
+	opts.WriteTimeout = durationOr(opts.WriteTimeout, DefaultWriteTimeout)
+	opts.IdleTimeout = durationOr(opts.IdleTimeout, DefaultIdleTimeout)
+	opts.ShutdownTimeout = durationOr(opts.ShutdownTimeout, DefaultShutdownTimeout)
+	if opts.MaxHeaderBytes <= 0 {
+		opts.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	if opts.TLSEnabled() {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if opts.H2C {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetHTTP2(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return &Server{server: server, opts: opts}
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.server.Addr
+}
+
+// ListenAndServe listens on the server's address and serves until ctx is cancelled, see Serve
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves requests from ln until ctx is cancelled, then stops accepting connections and
+// waits up to the shutdown timeout for in-flight requests to finish. Connections still busy
+// after that are closed and an error is returned. Hijacked connections, e.g. WebSockets, are
+// not waited for.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	served := make(chan error, 1)
+	go func() {
+		if s.opts.TLSEnabled() {
+			served <- s.server.ServeTLS(ln, s.opts.CertFile, s.opts.KeyFile)
+		} else {
+			served <- s.server.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Draining HTTP connections", slog.Duration("timeout", s.opts.ShutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.opts.ShutdownTimeout)
+	defer cancel()
+	err := s.server.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.server.Close()
+		return errors.New("shutdown timed out, in-flight requests were cut off")
+	}
+	if err != nil {
+		return err
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
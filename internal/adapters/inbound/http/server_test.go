@@ -0,0 +1,167 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		given    string
+		when     string
+		then     string
+		opts     ServerOptions
+		validate func(*testing.T, *http.Server)
+	}{
+		{
+			name:  "Defaults",
+			given: "no options",
+			when:  "creating the server",
+			then:  "should bound every phase of a request with the defaults",
+			validate: func(t *testing.T, server *http.Server) {
+				assert.Equal(t, DefaultReadHeaderTimeout, server.ReadHeaderTimeout)
+				assert.Equal(t, DefaultReadTimeout, server.ReadTimeout)
+				assert.Equal(t, DefaultWriteTimeout, server.WriteTimeout)
+				assert.Equal(t, DefaultIdleTimeout, server.IdleTimeout)
+				assert.Equal(t, DefaultMaxHeaderBytes, server.MaxHeaderBytes)
+				assert.Nil(t, server.TLSConfig)
+				assert.Nil(t, server.Protocols)
+			},
+		},
+		{
+			name:  "Configured",
+			given: "timeouts, a header limit, a certificate and h2c",
+			when:  "creating the server",
+			then:  "should apply them",
+			opts: ServerOptions{
+				ReadHeaderTimeout: time.Second,
+				ReadTimeout:       2 * time.Second,
+				WriteTimeout:      3 * time.Second,
+				IdleTimeout:       4 * time.Second,
+				MaxHeaderBytes:    4096,
+				CertFile:          "server.crt",
+				KeyFile:           "server.key",
+				H2C:               true,
+			},
+			validate: func(t *testing.T, server *http.Server) {
+				assert.Equal(t, time.Second, server.ReadHeaderTimeout)
+				assert.Equal(t, 2*time.Second, server.ReadTimeout)
+				assert.Equal(t, 3*time.Second, server.WriteTimeout)
+				assert.Equal(t, 4*time.Second, server.IdleTimeout)
+				assert.Equal(t, 4096, server.MaxHeaderBytes)
+				require.NotNil(t, server.TLSConfig)
+				require.NotNil(t, server.Protocols)
+				assert.True(t, server.Protocols.HTTP1())
+				assert.True(t, server.Protocols.UnencryptedHTTP2())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			server := NewServer(":0", http.NotFoundHandler(), tt.opts)
+
+			// Then
+			tt.validate(t, server.server)
+		})
+	}
+}
+
+func TestServer_Serve(t *testing.T) {
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		handlerDelay    time.Duration
+		shutdownTimeout time.Duration
+		expectErr       bool
+		expectResponse  bool
+	}{
+		{
+			name:            "Drains in-flight requests",
+			given:           "a request in flight that finishes within the shutdown timeout",
+			when:            "the context is cancelled",
+			then:            "should let the request finish and stop cleanly",
+			handlerDelay:    100 * time.Millisecond,
+			shutdownTimeout: 5 * time.Second,
+			expectResponse:  true,
+		},
+		{
+			name:            "Cuts off slow requests",
+			given:           "a request in flight that outlasts the shutdown timeout",
+			when:            "the context is cancelled",
+			then:            "should close the connection and return an error",
+			handlerDelay:    5 * time.Second,
+			shutdownTimeout: 100 * time.Millisecond,
+			expectErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			started := make(chan struct{})
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tt.handlerDelay):
+				case <-r.Context().Done():
+					return
+				}
+				w.Write([]byte("done"))
+			})
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			server := NewServer(ln.Addr().String(), handler, ServerOptions{ShutdownTimeout: tt.shutdownTimeout})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			served := make(chan error, 1)
+			go func() { served <- server.Serve(ctx, ln) }()
+
+			type result struct {
+				body string
+				err  error
+			}
+			responded := make(chan result, 1)
+			go func() {
+				resp, err := http.Get("http://" + ln.Addr().String())
+				if err != nil {
+					responded <- result{err: err}
+					return
+				}
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				responded <- result{body: string(body), err: err}
+			}()
+			<-started
+
+			// When
+			cancel()
+
+			// Then
+			err = <-served
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			resp := <-responded
+			if tt.expectResponse {
+				require.NoError(t, resp.err)
+				assert.Equal(t, "done", resp.body)
+			} else {
+				assert.Error(t, resp.err)
+			}
+		})
+	}
+}
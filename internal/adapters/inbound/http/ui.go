@@ -0,0 +1,40 @@
+package http
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// uiContentSecurityPolicy lets the admin UI load its own scripts and styles and call the API
+// of the server it came from, and nothing else
+const uiContentSecurityPolicy = "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'"
+
+// RegisterUIRoutes serves the embedded admin UI, which lists jobs, the DLQ, metrics and
+// insights through the API of the same server
+func RegisterUIRoutes(mux *http.ServeMux) {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The ui directory is embedded at build time
+	}
+	fileServer := http.StripPrefix("/ui/", http.FileServerFS(files))
+
+	// GET /ui - Redirect to the UI, so its relative asset paths resolve
+	mux.HandleFunc("/ui", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ui/", http.StatusMovedPermanently)
+	})
+
+	// GET /ui/ - The admin UI and its assets
+	mux.HandleFunc("/ui/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Security-Policy", uiContentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	})
+}
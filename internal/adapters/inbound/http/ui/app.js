@@ -0,0 +1,193 @@
+// Admin UI over the queue-core API. Everything shown comes from the public endpoints; values
+// are always written as text so job payloads and errors can't inject markup.
+"use strict";
+
+const api = "/api/v1";
+const statuses = ["pending", "processing", "retrying", "failed", "completed", "cancelled", "held", "parked", "dlq"];
+const keyInput = document.getElementById("api-key");
+const errorBox = document.getElementById("error");
+let current = "metrics";
+let refreshTimer;
+
+keyInput.value = localStorage.getItem("aisq-api-key") || "";
+keyInput.addEventListener("change", () => localStorage.setItem("aisq-api-key", keyInput.value));
+
+async function get(path) {
+  const headers = {};
+  if (keyInput.value) {
+    headers["X-API-Key"] = keyInput.value;
+  }
+  const response = await fetch(api + path, { headers });
+  if (!response.ok) {
+    throw new Error(`${path}: ${response.status} ${(await response.text()).trim()}`);
+  }
+  return response.json();
+}
+
+function showError(err) {
+  errorBox.textContent = err ? err.message : "";
+  errorBox.hidden = !err;
+}
+
+function cell(row, value, className) {
+  const td = row.insertCell();
+  td.textContent = value === undefined || value === null ? "" : String(value);
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function fill(tbodyId, items, columns, onClick) {
+  const tbody = document.getElementById(tbodyId);
+  tbody.replaceChildren();
+  if (!items || items.length === 0) {
+    const row = tbody.insertRow();
+    const td = cell(row, "Nothing here", "empty");
+    td.colSpan = columns;
+    return tbody;
+  }
+  for (const item of items) {
+    const row = tbody.insertRow();
+    if (onClick) {
+      row.className = "link";
+      row.addEventListener("click", () => onClick(item));
+    }
+    fillRow(tbodyId, row, item);
+  }
+  return tbody;
+}
+
+function fillRow(tbodyId, row, item) {
+  switch (tbodyId) {
+    case "job-rows":
+      cell(row, item.id, "id");
+      cell(row, item.queue);
+      cell(row, item.type);
+      cell(row, item.status);
+      cell(row, item.attempts);
+      cell(row, item.error, "wrap");
+      cell(row, item.updated_at);
+      break;
+    case "dlq-rows":
+      cell(row, item.id, "id");
+      cell(row, item.queue);
+      cell(row, item.type);
+      cell(row, item.dead_letter_reason);
+      cell(row, item.error, "wrap");
+      cell(row, item.insight ? item.insight.diagnosis : "", "wrap");
+      break;
+    case "insight-rows":
+      cell(row, item.job_id, "id");
+      cell(row, item.triage_label);
+      cell(row, item.diagnosis, "wrap");
+      cell(row, item.recommendation, "wrap");
+      cell(row, Math.round((item.confidence || 0) * 100) + "%");
+      cell(row, item.provider);
+      break;
+  }
+}
+
+async function showJob(job) {
+  try {
+    showDetail("Job " + job.id, await get("/jobs/" + encodeURIComponent(job.id)));
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function showDetail(title, value) {
+  document.getElementById("detail-title").textContent = title;
+  document.getElementById("detail-body").textContent = JSON.stringify(value, null, 2);
+  document.getElementById("detail").hidden = false;
+}
+
+const loaders = {
+  async metrics() {
+    const metrics = await get("/metrics");
+    const cards = document.getElementById("metric-cards");
+    cards.replaceChildren();
+    for (const status of statuses) {
+      const card = document.createElement("div");
+      card.className = "card";
+      const value = document.createElement("div");
+      value.className = "value";
+      value.textContent = metrics[status] ?? 0;
+      const label = document.createElement("div");
+      label.className = "label";
+      label.textContent = status;
+      card.append(value, label);
+      cards.append(card);
+    }
+    const tbody = document.getElementById("queue-rows");
+    tbody.replaceChildren();
+    for (const [name, stats] of Object.entries(metrics.queues || {}).sort(([a], [b]) => a.localeCompare(b))) {
+      const row = tbody.insertRow();
+      cell(row, name);
+      cell(row, stats.ready);
+      cell(row, stats.unacked);
+      cell(row, stats.acked);
+      cell(row, stats.nacked);
+    }
+  },
+
+  async jobs() {
+    const status = document.getElementById("job-status").value;
+    const jobs = await get("/jobs?limit=50&status=" + encodeURIComponent(status));
+    fill("job-rows", jobs, 7, showJob);
+  },
+
+  async dlq() {
+    const page = await get("/dlq?limit=50&include=insights");
+    document.getElementById("dlq-total").textContent = `${page.total} dead-lettered jobs`;
+    fill("dlq-rows", page.jobs, 6, showJob);
+  },
+
+  async insights() {
+    const insights = await get("/insights?limit=50");
+    fill("insight-rows", insights, 6, (insight) => showDetail("Insight " + insight.id, insight));
+  },
+};
+
+async function load(view) {
+  try {
+    await loaders[view]();
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function show(view) {
+  current = view;
+  for (const button of document.querySelectorAll("nav button")) {
+    button.classList.toggle("active", button.dataset.view === view);
+  }
+  for (const name of Object.keys(loaders)) {
+    document.getElementById(name).hidden = name !== view;
+  }
+  document.getElementById("detail").hidden = true;
+  load(view);
+}
+
+function scheduleRefresh() {
+  clearInterval(refreshTimer);
+  if (document.getElementById("auto-refresh").checked) {
+    refreshTimer = setInterval(() => current === "metrics" && load("metrics"), 5000);
+  }
+}
+
+for (const button of document.querySelectorAll("nav button")) {
+  button.addEventListener("click", () => show(button.dataset.view));
+}
+document.getElementById("job-status").addEventListener("change", () => load("jobs"));
+document.getElementById("jobs-refresh").addEventListener("click", () => load("jobs"));
+document.getElementById("dlq-refresh").addEventListener("click", () => load("dlq"));
+document.getElementById("insights-refresh").addEventListener("click", () => load("insights"));
+document.getElementById("detail-close").addEventListener("click", () => {
+  document.getElementById("detail").hidden = true;
+});
+document.getElementById("auto-refresh").addEventListener("change", scheduleRefresh);
+
+show("metrics");
+scheduleRefresh();
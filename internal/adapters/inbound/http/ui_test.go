@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUIRoutes(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		method         string
+		path           string
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "UI page",
+			given:          "the embedded UI",
+			when:           "GET /ui/",
+			then:           "should serve the page with a content security policy",
+			method:         http.MethodGet,
+			path:           "/ui/",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+				assert.Equal(t, uiContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+				assert.Contains(t, rec.Body.String(), `<script src="app.js" defer></script>`)
+			},
+		},
+		{
+			name:           "UI script",
+			given:          "the embedded UI",
+			when:           "GET /ui/app.js",
+			then:           "should serve the script calling the versioned API",
+			method:         http.MethodGet,
+			path:           "/ui/app.js",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+				assert.Contains(t, rec.Body.String(), `const api = "/api/v1";`)
+			},
+		},
+		{
+			name:           "UI without trailing slash",
+			given:          "the embedded UI",
+			when:           "GET /ui",
+			then:           "should redirect to /ui/",
+			method:         http.MethodGet,
+			path:           "/ui",
+			expectedStatus: http.StatusMovedPermanently,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "/ui/", rec.Header().Get("Location"))
+			},
+		},
+		{
+			name:           "Missing asset",
+			given:          "the embedded UI",
+			when:           "GET /ui/missing.js",
+			then:           "should return 404",
+			method:         http.MethodGet,
+			path:           "/ui/missing.js",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Write to the UI",
+			given:          "the embedded UI",
+			when:           "POST /ui/",
+			then:           "should return 405",
+			method:         http.MethodPost,
+			path:           "/ui/",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	mux := http.NewServeMux()
+	RegisterUIRoutes(mux)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
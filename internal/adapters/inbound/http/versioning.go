@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// CurrentAPIVersion is the version of the API served under /api/v1 and, for clients predating
+// versioning, the unversioned /api paths
+const CurrentAPIVersion = "v1"
+
+// APIVersionHeader tells callers which version of the API served their request
+const APIVersionHeader = "API-Version"
+
+// versionSegment matches the first path segment after /api that names a version
+var versionSegment = regexp.MustCompile(`^v[0-9]+$`)
+
+type apiVersionContextKey struct{}
+
+// APIVersions routes requests to the handler of each version of the API. Handlers route the
+// unversioned /api/... paths: /api/{version}/... is served by that version's handler with the
+// version stripped from the path, so each version reuses the same routes. Unversioned /api paths
+// are served by the legacy version and marked deprecated with a link to their versioned
+// successor; paths outside /api, like /health, are served by it unchanged. A breaking change to
+// response shapes ships as a new version with its own handler, leaving older clients unaffected.
+type APIVersions struct {
+	handlers map[string]http.Handler
+	legacy   string
+}
+
+// NewAPIVersions serves the legacy version with handler, and unversioned paths with it too
+func NewAPIVersions(legacy string, handler http.Handler) *APIVersions {
+	return &APIVersions{
+		handlers: map[string]http.Handler{legacy: handler},
+		legacy:   legacy,
+	}
+}
+
+// WithVersion serves /api/{version}/... with handler
+func (v *APIVersions) WithVersion(version string, handler http.Handler) *APIVersions {
+	v.handlers[version] = handler
+	return v
+}
+
+func (v *APIVersions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, isAPI := strings.CutPrefix(r.URL.Path, "/api/")
+	if !isAPI {
+		w.Header().Set(APIVersionHeader, v.legacy)
+		v.handlers[v.legacy].ServeHTTP(w, r)
+		return
+	}
+
+	segment, path, _ := strings.Cut(rest, "/")
+	if !versionSegment.MatchString(segment) {
+		// Clients predating versioning keep working until they move to the versioned paths
+		w.Header().Set(APIVersionHeader, v.legacy)
+		w.Header().Set("Deprecation", "true")
+		w.Header().Add("Link", `</api/`+v.legacy+`/`+rest+`>; rel="successor-version"`)
+		v.handlers[v.legacy].ServeHTTP(w, r)
+		return
+	}
+
+	handler, ok := v.handlers[segment]
+	if !ok {
+		http.Error(w, "unsupported API version "+segment, http.StatusNotFound)
+		return
+	}
+	w.Header().Set(APIVersionHeader, segment)
+
+	// Like http.StripPrefix, the request is copied rather than modified
+	versioned := r.WithContext(context.WithValue(r.Context(), apiVersionContextKey{}, segment))
+	url := *r.URL
+	url.Path = "/api/" + path
+	url.RawPath = ""
+	versioned.URL = &url
+	handler.ServeHTTP(w, versioned)
+}
+
+// apiPath returns the path of an API resource in the version the request was made to, e.g.
+// /api/v1/jobs/{id} for a request to /api/v1/jobs; path starts after /api
+func apiPath(r *http.Request, path string) string {
+	if version, ok := r.Context().Value(apiVersionContextKey{}).(string); ok {
+		return "/api/" + version + path
+	}
+	return "/api" + path
+}
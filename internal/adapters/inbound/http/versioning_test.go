@@ -0,0 +1,149 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersions(t *testing.T) {
+	tests := []struct {
+		name              string
+		given             string
+		when              string
+		then              string
+		path              string
+		expectedStatus    int
+		expectedServed    string
+		expectedPath      string
+		expectedVersion   string
+		expectDeprecation bool
+		expectedLink      string
+	}{
+		{
+			name:            "Versioned path",
+			given:           "v1 and v2 of the API",
+			when:            "GET to /api/v1/jobs/42",
+			then:            "should serve it with v1 on its unversioned path",
+			path:            "/api/v1/jobs/42",
+			expectedStatus:  http.StatusOK,
+			expectedServed:  "v1",
+			expectedPath:    "/api/jobs/42",
+			expectedVersion: "v1",
+		},
+		{
+			name:            "Newer version",
+			given:           "v1 and v2 of the API",
+			when:            "GET to /api/v2/jobs/42",
+			then:            "should serve it with v2",
+			path:            "/api/v2/jobs/42",
+			expectedStatus:  http.StatusOK,
+			expectedServed:  "v2",
+			expectedPath:    "/api/jobs/42",
+			expectedVersion: "v2",
+		},
+		{
+			name:              "Unversioned path",
+			given:             "v1 and v2 of the API, v1 being the legacy version",
+			when:              "GET to /api/jobs/42",
+			then:              "should serve it with v1 and point to its versioned successor",
+			path:              "/api/jobs/42",
+			expectedStatus:    http.StatusOK,
+			expectedServed:    "v1",
+			expectedPath:      "/api/jobs/42",
+			expectedVersion:   "v1",
+			expectDeprecation: true,
+			expectedLink:      `</api/v1/jobs/42>; rel="successor-version"`,
+		},
+		{
+			name:            "Path outside the API",
+			given:           "v1 and v2 of the API",
+			when:            "GET to /health",
+			then:            "should serve it with v1 without deprecating it",
+			path:            "/health",
+			expectedStatus:  http.StatusOK,
+			expectedServed:  "v1",
+			expectedPath:    "/health",
+			expectedVersion: "v1",
+		},
+		{
+			name:           "Unknown version",
+			given:          "v1 and v2 of the API",
+			when:           "GET to /api/v9/jobs/42",
+			then:           "should return 404",
+			path:           "/api/v9/jobs/42",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			var served, path string
+			handler := func(version string) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					served, path = version, r.URL.Path
+				})
+			}
+			versions := NewAPIVersions("v1", handler("v1")).WithVersion("v2", handler("v2"))
+			rec := httptest.NewRecorder()
+
+			// When
+			versions.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedServed, served)
+			assert.Equal(t, tt.expectedPath, path)
+			assert.Equal(t, tt.expectedVersion, rec.Header().Get(APIVersionHeader))
+			assert.Equal(t, tt.expectDeprecation, rec.Header().Get("Deprecation") == "true")
+			assert.Equal(t, tt.expectedLink, rec.Header().Get("Link"))
+		})
+	}
+}
+
+func TestAPIVersions_LocationFollowsVersion(t *testing.T) {
+	tests := []struct {
+		name             string
+		given            string
+		when             string
+		then             string
+		path             string
+		expectedLocation string
+	}{
+		{
+			name:             "Versioned request",
+			given:            "a handler linking to a job",
+			when:             "requested under /api/v1",
+			then:             "should link to the job under /api/v1",
+			path:             "/api/v1/jobs",
+			expectedLocation: "/api/v1/jobs/42",
+		},
+		{
+			name:             "Unversioned request",
+			given:            "a handler linking to a job",
+			when:             "requested on the unversioned path",
+			then:             "should link to the unversioned job path",
+			path:             "/api/jobs",
+			expectedLocation: "/api/jobs/42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			versions := NewAPIVersions(CurrentAPIVersion, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Location", apiPath(r, "/jobs/42"))
+			}))
+			rec := httptest.NewRecorder()
+
+			// When
+			versions.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+
+			// Then
+			assert.Equal(t, tt.expectedLocation, rec.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// WorkerAdminHandlers handles the admin server of a worker-runtime process
+type WorkerAdminHandlers struct {
+	activity *worker.Activity
+	info     worker.Heartbeat     // Who the process is; LastSeen is unused
+	broker   *worker.BrokerHealth // nil when the workers don't track it
+}
+
+// NewWorkerAdminHandlers creates the admin handlers of the worker-runtime process described by info
+func NewWorkerAdminHandlers(activity *worker.Activity, info worker.Heartbeat) *WorkerAdminHandlers {
+	return &WorkerAdminHandlers{activity: activity, info: info}
+}
+
+// SetBrokerHealth reports the health of the process's queue broker, as its workers track it,
+// in /health, /stats and /metrics
+func (h *WorkerAdminHandlers) SetBrokerHealth(health *worker.BrokerHealth) {
+	h.broker = health
+}
+
+// brokerStatus returns the broker health the workers track, healthy when they don't
+func (h *WorkerAdminHandlers) brokerStatus() worker.BrokerHealthStatus {
+	if h.broker == nil {
+		return worker.BrokerHealthStatus{State: worker.BrokerHealthy}
+	}
+	return h.broker.Status()
+}
+
+type RunningJobResponse struct {
+	JobID          string  `json:"job_id"`
+	Queue          string  `json:"queue"`
+	Type           string  `json:"type"`
+	Attempt        int     `json:"attempt"`
+	StartedAt      string  `json:"started_at"`
+	RunningSeconds float64 `json:"running_seconds"`
+}
+
+type ProcessedCountResponse struct {
+	Queue   string `json:"queue"`
+	Type    string `json:"type"`
+	Outcome string `json:"outcome"`
+	Count   int64  `json:"count"`
+}
+
+type WorkerStatsResponse struct {
+	WorkerID           string                    `json:"worker_id"`
+	Queues             []string                  `json:"queues"`
+	Concurrency        int                       `json:"concurrency"`
+	Capabilities       []string                  `json:"capabilities,omitempty"`
+	StartedAt          string                    `json:"started_at"`
+	UptimeSeconds      float64                   `json:"uptime_seconds"`
+	Goroutines         int                       `json:"goroutines"`
+	HeapAllocBytes     uint64                    `json:"heap_alloc_bytes"`
+	Running            int                       `json:"running"`
+	ProcessedPerSecond float64                   `json:"processed_per_second"`
+	Processed          []ProcessedCountResponse  `json:"processed"`
+	Broker             worker.BrokerHealthStatus `json:"broker"`
+}
+
+// RunningJobs lists the jobs the process's workers are executing, longest running first
+func (h *WorkerAdminHandlers) RunningJobs(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	snapshot := h.activity.Snapshot(now)
+
+	response := make([]RunningJobResponse, 0, len(snapshot.Running))
+	for _, job := range snapshot.Running {
+		response = append(response, RunningJobResponse{
+			JobID:          job.JobID.String(),
+			Queue:          job.Queue,
+			Type:           job.Type,
+			Attempt:        job.Attempt,
+			StartedAt:      job.StartedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			RunningSeconds: now.Sub(job.StartedAt).Seconds(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"running": response})
+}
+
+// Stats reports the process's throughput and runtime figures
+func (h *WorkerAdminHandlers) Stats(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	snapshot := h.activity.Snapshot(now)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	response := WorkerStatsResponse{
+		WorkerID:           h.info.WorkerID,
+		Queues:             h.info.Queues,
+		Concurrency:        h.info.Concurrency,
+		Capabilities:       h.info.Capabilities,
+		StartedAt:          h.info.StartedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		UptimeSeconds:      now.Sub(h.info.StartedAt).Seconds(),
+		Goroutines:         runtime.NumGoroutine(),
+		HeapAllocBytes:     mem.HeapAlloc,
+		Running:            len(snapshot.Running),
+		ProcessedPerSecond: snapshot.PerSecond,
+		Processed:          make([]ProcessedCountResponse, 0, len(snapshot.Processed)),
+		Broker:             h.brokerStatus(),
+	}
+	for _, count := range snapshot.Processed {
+		response.Processed = append(response.Processed, ProcessedCountResponse(count))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Metrics writes the process's figures in the Prometheus text exposition format
+func (h *WorkerAdminHandlers) Metrics(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	snapshot := h.activity.Snapshot(now)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var b strings.Builder
+	b.WriteString("# HELP aisq_worker_jobs_processed_total Job executions this process ended, by outcome.\n")
+	b.WriteString("# TYPE aisq_worker_jobs_processed_total counter\n")
+	for _, count := range snapshot.Processed {
+		fmt.Fprintf(&b, "aisq_worker_jobs_processed_total{queue=\"%s\",type=\"%s\",outcome=\"%s\"} %d\n",
+			prometheusLabelEscaper.Replace(count.Queue), prometheusLabelEscaper.Replace(count.Type), count.Outcome, count.Count)
+	}
+	writePrometheusGauge(&b, "aisq_worker_jobs_running", "Jobs this process is executing.", float64(len(snapshot.Running)))
+	writePrometheusGauge(&b, "aisq_worker_processed_per_second", "Job executions ended per second over the last minute.", snapshot.PerSecond)
+	broker := h.brokerStatus()
+	degraded := 0.0
+	if broker.State == worker.BrokerDegraded {
+		degraded = 1
+	}
+	writePrometheusGauge(&b, "aisq_worker_broker_degraded", "1 while the queue broker has failed enough calls in a row to degrade the process.", degraded)
+	writePrometheusGauge(&b, "aisq_worker_broker_consecutive_failures", "Queue broker calls failed in a row.", float64(broker.ConsecutiveFailures))
+	writePrometheusGauge(&b, "aisq_worker_uptime_seconds", "Seconds since the process started.", now.Sub(h.info.StartedAt).Seconds())
+	writePrometheusGauge(&b, "go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	writePrometheusGauge(&b, "go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", float64(mem.HeapAlloc))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// Health reports the process alive. While its queue broker is unreachable it is degraded
+// rather than down, since the workers reconnect on their own, so the status stays 200 and the
+// body says why.
+func (h *WorkerAdminHandlers) Health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	if broker := h.brokerStatus(); broker.State == worker.BrokerDegraded {
+		fmt.Fprintf(w, "DEGRADED: queue broker unreachable after %d failures: %s", broker.ConsecutiveFailures, broker.LastError)
+		return
+	}
+	w.Write([]byte("OK"))
+}
+
+func writePrometheusGauge(b *strings.Builder, name, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+}
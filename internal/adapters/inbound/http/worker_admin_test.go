@@ -0,0 +1,180 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerAdminRoutes(t *testing.T) {
+	running := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "send-email", Attempts: 2}
+	done := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "send-email", Attempts: 1}
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		method         string
+		path           string
+		brokerFailures int
+		expectedStatus int
+		validateResp   func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Running jobs",
+			given:          "a worker executing one job",
+			when:           "GET /jobs",
+			then:           "should list the job with how long it has been running",
+			method:         http.MethodGet,
+			path:           "/jobs",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp struct {
+					Running []RunningJobResponse `json:"running"`
+				}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				require.Len(t, resp.Running, 1)
+				assert.Equal(t, running.ID.String(), resp.Running[0].JobID)
+				assert.Equal(t, 2, resp.Running[0].Attempt)
+				assert.GreaterOrEqual(t, resp.Running[0].RunningSeconds, 30.0)
+			},
+		},
+		{
+			name:           "Stats",
+			given:          "a worker that completed one job",
+			when:           "GET /stats",
+			then:           "should report who the worker is, its counts and runtime figures",
+			method:         http.MethodGet,
+			path:           "/stats",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp WorkerStatsResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "worker-1", resp.WorkerID)
+				assert.Equal(t, []string{"emails"}, resp.Queues)
+				assert.Equal(t, 1, resp.Running)
+				assert.Equal(t, []ProcessedCountResponse{
+					{Queue: "emails", Type: "send-email", Outcome: worker.OutcomeCompleted, Count: 1},
+				}, resp.Processed)
+				assert.Positive(t, resp.Goroutines)
+				assert.GreaterOrEqual(t, resp.UptimeSeconds, 60.0)
+			},
+		},
+		{
+			name:           "Prometheus metrics",
+			given:          "a worker that completed one job",
+			when:           "GET /metrics",
+			then:           "should expose the counts and gauges in the text format",
+			method:         http.MethodGet,
+			path:           "/metrics",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				body := rec.Body.String()
+				assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+				assert.Contains(t, body, `aisq_worker_jobs_processed_total{queue="emails",type="send-email",outcome="completed"} 1`)
+				assert.Contains(t, body, "aisq_worker_jobs_running 1\n")
+				assert.Contains(t, body, "aisq_worker_broker_degraded 0\n")
+				assert.Contains(t, body, "# TYPE go_goroutines gauge\n")
+			},
+		},
+		{
+			name:           "Health",
+			given:          "a running worker",
+			when:           "GET /health",
+			then:           "should return OK",
+			method:         http.MethodGet,
+			path:           "/health",
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "OK", rec.Body.String())
+			},
+		},
+		{
+			name:           "Health while the broker is unreachable",
+			given:          "a worker whose queue broker failed 5 dequeues in a row",
+			when:           "GET /health",
+			then:           "should stay 200 but report the process degraded and why",
+			method:         http.MethodGet,
+			path:           "/health",
+			brokerFailures: 5,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "DEGRADED: queue broker unreachable after 5 failures: connection refused", rec.Body.String())
+			},
+		},
+		{
+			name:           "Stats while the broker is unreachable",
+			given:          "a worker whose queue broker failed 5 dequeues in a row",
+			when:           "GET /stats",
+			then:           "should report the broker degraded",
+			method:         http.MethodGet,
+			path:           "/stats",
+			brokerFailures: 5,
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var resp WorkerStatsResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, worker.BrokerDegraded, resp.Broker.State)
+				assert.Equal(t, 5, resp.Broker.ConsecutiveFailures)
+				assert.Equal(t, "connection refused", resp.Broker.LastError)
+				assert.NotNil(t, resp.Broker.FailingSince)
+			},
+		},
+		{
+			name:           "Wrong method",
+			given:          "a running worker",
+			when:           "POST /stats",
+			then:           "should return 405",
+			method:         http.MethodPost,
+			path:           "/stats",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			now := time.Now().UTC()
+			activity := worker.NewActivity()
+			activity.Started(running, now.Add(-30*time.Second))
+			activity.Started(done, now.Add(-20*time.Second))
+			activity.Finished(done, worker.OutcomeCompleted, now.Add(-10*time.Second))
+			broker, err := worker.NewBrokerHealth(worker.DefaultBrokerHealthConfig())
+			require.NoError(t, err)
+			for range tt.brokerFailures {
+				broker.Failure(errors.New("connection refused"), now)
+			}
+			handlers := NewWorkerAdminHandlers(activity, worker.Heartbeat{
+				WorkerID:    "worker-1",
+				Queues:      []string{"emails"},
+				Concurrency: 2,
+				StartedAt:   now.Add(-time.Minute),
+			})
+			handlers.SetBrokerHealth(broker)
+			mux := http.NewServeMux()
+			RegisterWorkerAdminRoutes(mux, handlers)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(rec, req)
+
+			// Then
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.validateResp != nil {
+				tt.validateResp(t, rec)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// DefaultParseRetries is how many times a model is re-prompted after a malformed reply
+const DefaultParseRetries = 2
+
+// maxEchoedReply bounds how much of a malformed reply is quoted back in a re-prompt
+const maxEchoedReply = 2000
+
+// maxAttemptErrorBytes bounds the error of each past attempt listed in a prompt; the last
+// attempt's error is given in full
+const maxAttemptErrorBytes = 200
+
+// SchemaError reports why a model reply is not a usable analysis
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "analysis does not match schema: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *SchemaError) Unwrap() error {
+	return insights.ErrInvalidAnalysisData
+}
+
+// completer sends a prompt to a model and returns its raw reply
+type completer interface {
+	complete(ctx context.Context, prompt string) (string, *insights.Usage, error)
+}
+
+// analyzeWithRetries prompts the model for an analysis, re-prompting with the problems found
+// when the reply doesn't match the schema, at most retries times. The job data is cut to the
+// budget before the first prompt. Usage covers every prompt.
+func analyzeWithRetries(ctx context.Context, model completer, request *insights.AnalysisRequest, retries int, budget insights.PromptBudget) (*insights.AnalysisResponse, error) {
+	request = fitPrompt(ctx, request, budget)
+	prompt := analysisPrompt(request)
+	var total *insights.Usage
+
+	for attempt := 0; ; attempt++ {
+		reply, usage, err := model.complete(ctx, prompt)
+		if err != nil {
+			return nil, err
+		}
+		total = addUsage(total, usage)
+
+		analysis, err := parseAnalysis(reply)
+		if err == nil {
+			analysis.Usage = total
+			return analysis, nil
+		}
+		if attempt >= retries {
+			return nil, err
+		}
+
+		slog.WarnContext(ctx, "Malformed analysis from model, re-prompting",
+			slog.String("jobId", request.JobID),
+			slog.Int("attempt", attempt+1),
+			slog.String("error", err.Error()),
+		)
+		prompt = repairPrompt(request, reply, err)
+	}
+}
+
+// fitPrompt cuts the job's error, payload and output so the analysis prompt fits the budget
+func fitPrompt(ctx context.Context, request *insights.AnalysisRequest, budget insights.PromptBudget) *insights.AnalysisRequest {
+	rest := *request
+	rest.Error, rest.Payload, rest.Output = "", "", ""
+	overhead := len(analysisPrompt(&rest))
+	fitted := budget.Fit(request, overhead)
+	if len(fitted.Error) < len(request.Error) || len(fitted.Payload) < len(request.Payload) || len(fitted.Output) < len(request.Output) {
+		slog.InfoContext(ctx, "Truncated job data to fit the analysis prompt",
+			slog.String("jobId", request.JobID),
+			slog.Int("errorBytes", len(request.Error)),
+			slog.Int("payloadBytes", len(request.Payload)),
+			slog.Int("outputBytes", len(request.Output)),
+			slog.Int("keptErrorBytes", len(fitted.Error)),
+			slog.Int("keptPayloadBytes", len(fitted.Payload)),
+			slog.Int("keptOutputBytes", len(fitted.Output)),
+		)
+	}
+	return fitted
+}
+
+func analysisPrompt(request *insights.AnalysisRequest) string {
+	return `
+			You are an expert in distributed systems debugging.
+			Return ONLY valid JSON. No comments, no markdown, no explanations.
+
+			Job ID: ` + request.JobID + jobContextPrompt(request) + `
+			Error: ` + request.Error + `
+			Payload: ` + request.Payload + attemptsPrompt(request.Attempts) + outputPrompt(request.Output) +
+		fixHistoryPrompt(request.FixHistory) + `
+
+			Return EXACTLY this JSON structure, with no extra text:
+
+			{
+				"diagnosis": "<short reason>",
+				"recommendation": "<human-readable advice>",
+				"suggested_fix": {
+					"timeout_seconds": <int>,
+					"max_retries": <int>,
+					"payload_patch": { }
+				},
+				"confidence": <number between 0 and 1>
+			}
+		`
+}
+
+// jobContextPrompt gives the job's type, the settings of its queue and its metadata
+func jobContextPrompt(request *insights.AnalysisRequest) string {
+	lines := ""
+	if request.JobType != "" {
+		lines += "\n\t\t\tJob type: " + request.JobType
+	}
+	if request.Queue != "" {
+		lines += "\n\t\t\tQueue: " + request.Queue + queueSettings(request.QueueConfig)
+	}
+	if len(request.Metadata) > 0 {
+		lines += "\n\t\t\tMetadata: " + metadataList(request.Metadata)
+	}
+	return lines
+}
+
+// metadataList lists the job's metadata as key=value pairs, sorted by key
+func metadataList(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		pairs = append(pairs, key+"="+metadata[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// queueSettings describes the settings a queue definition overrides, empty when there are none
+func queueSettings(def *queue.Definition) string {
+	if def == nil {
+		return ""
+	}
+	var settings []string
+	if def.MaxAttempts > 0 {
+		settings = append(settings, fmt.Sprintf("max attempts %d", def.MaxAttempts))
+	}
+	if def.BaseBackoffMs > 0 {
+		settings = append(settings, fmt.Sprintf("base backoff %dms", def.BaseBackoffMs))
+	}
+	if def.RateLimitPerSecond > 0 {
+		settings = append(settings, fmt.Sprintf("rate limit %g jobs/s", def.RateLimitPerSecond))
+	}
+	if def.Paused {
+		settings = append(settings, "paused")
+	}
+	if len(settings) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(settings, ", ") + ")"
+}
+
+// attemptsPrompt lists the job's runs, so the model can tell a transient failure from a recurring one
+func attemptsPrompt(attempts []*queue.Attempt) string {
+	if len(attempts) == 0 {
+		return ""
+	}
+	lines := "\n\n\t\t\tAttempts, oldest first:"
+	for _, attempt := range attempts {
+		line := fmt.Sprintf("\n\t\t\t- #%d at %s, ran %s: ", attempt.Number,
+			attempt.StartedAt.Format("2006-01-02T15:04:05Z"), attempt.Duration.Round(time.Millisecond))
+		if attempt.Succeeded() {
+			lines += line + "succeeded"
+			continue
+		}
+		lines += line + "failed (" + attempt.Category + ") " + insights.Truncate(attempt.Error, maxAttemptErrorBytes)
+	}
+	return lines
+}
+
+// outputPrompt gives the end of what the executor wrote during the last run
+func outputPrompt(output string) string {
+	if output == "" {
+		return ""
+	}
+	return "\n\n\t\t\tExecutor output of the last run:\n" + output
+}
+
+// fixHistoryPrompt lists how past fixes worked for the job's type, so the model favours those that did
+func fixHistoryPrompt(history []*insights.FixEffectiveness) string {
+	if len(history) == 0 {
+		return ""
+	}
+	lines := "\n\n\t\t\tPast fixes for this job type (prefer what worked):"
+	for _, stat := range history {
+		lines += "\n\t\t\t- " + stat.Summary()
+	}
+	return lines
+}
+
+// repairPrompt repeats the original prompt with the invalid reply and what was wrong with it
+func repairPrompt(request *insights.AnalysisRequest, reply string, cause error) string {
+	if len(reply) > maxEchoedReply {
+		reply = reply[:maxEchoedReply]
+	}
+	return analysisPrompt(request) + `
+			Your previous reply could not be used: ` + cause.Error() + `
+
+			Previous reply:
+			` + reply + `
+
+			Reply again with ONLY the corrected JSON object.
+		`
+}
+
+// parseAnalysis extracts the first JSON object from a model reply and validates it against the
+// analysis schema. Markdown fences and text around the object are ignored.
+func parseAnalysis(reply string) (*insights.AnalysisResponse, error) {
+	object, err := extractJSONObject(reply)
+	if err != nil {
+		return nil, &SchemaError{Problems: []string{err.Error()}}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(object))
+	decoder.UseNumber()
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, &SchemaError{Problems: []string{"invalid JSON: " + err.Error()}}
+	}
+	if problems := validateAnalysis(fields); len(problems) > 0 {
+		return nil, &SchemaError{Problems: problems}
+	}
+
+	var analysis insights.AnalysisResponse
+	if err := json.Unmarshal(object, &analysis); err != nil {
+		return nil, &SchemaError{Problems: []string{err.Error()}}
+	}
+	return &analysis, nil
+}
+
+// extractJSONObject returns the first balanced JSON object in the text
+func extractJSONObject(text string) ([]byte, error) {
+	start := strings.Index(text, "{")
+	if start == -1 {
+		return nil, errors.New("no JSON object found")
+	}
+
+	depth, inString, escaped := 0, false, false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return []byte(text[start : i+1]), nil
+			}
+		}
+	}
+	return nil, errors.New("JSON object is truncated")
+}
+
+// validateAnalysis checks the fields of a reply against the analysis schema
+func validateAnalysis(fields map[string]any) []string {
+	var problems []string
+
+	if diagnosis, ok := fields["diagnosis"].(string); !ok || strings.TrimSpace(diagnosis) == "" {
+		problems = append(problems, `"diagnosis" must be a non-empty string`)
+	}
+	if _, ok := fields["recommendation"].(string); !ok {
+		problems = append(problems, `"recommendation" must be a string`)
+	}
+	confidence, ok := number(fields["confidence"])
+	if !ok || confidence < 0 || confidence > 1 {
+		problems = append(problems, `"confidence" must be a number between 0 and 1`)
+	}
+
+	fix, present := fields["suggested_fix"]
+	if !present || fix == nil {
+		return problems
+	}
+	fixFields, ok := fix.(map[string]any)
+	if !ok {
+		return append(problems, `"suggested_fix" must be an object`)
+	}
+	for _, name := range []string{"timeout_seconds", "max_retries"} {
+		value, present := fixFields[name]
+		if !present {
+			continue
+		}
+		if n, ok := number(value); !ok || n < 0 || n != float64(int64(n)) {
+			problems = append(problems, fmt.Sprintf(`"suggested_fix.%s" must be a non-negative integer`, name))
+		}
+	}
+	if patch, present := fixFields["payload_patch"]; present && patch != nil {
+		if _, ok := patch.(map[string]any); !ok {
+			problems = append(problems, `"suggested_fix.payload_patch" must be an object`)
+		}
+	}
+	return problems
+}
+
+func number(value any) (float64, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// addUsage adds the usage of one prompt to the running total
+func addUsage(total, usage *insights.Usage) *insights.Usage {
+	if usage == nil {
+		return total
+	}
+	if total == nil {
+		copied := *usage
+		return &copied
+	}
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.PromptBytes += usage.PromptBytes
+	total.LatencyMs += usage.LatencyMs
+	return total
+}
@@ -0,0 +1,229 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validReply = `{"diagnosis":"SMTP timeout","recommendation":"Raise the timeout","suggested_fix":{"timeout_seconds":30,"max_retries":5,"payload_patch":{"retry":true}},"confidence":0.8}`
+
+func TestParseAnalysis(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want struct {
+			analysis *insights.AnalysisResponse
+			problem  string
+		}
+	}{
+		{
+			name: "Given a JSON reply, When parsing it, Then should return the analysis",
+			in:   validReply,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{analysis: &insights.AnalysisResponse{
+				Diagnosis:      "SMTP timeout",
+				Recommendation: "Raise the timeout",
+				SuggestedFix:   insights.SuggestedFix{TimeoutSeconds: 30, MaxRetries: 5, PayloadPatch: map[string]any{"retry": true}},
+				Confidence:     0.8,
+			}},
+		},
+		{
+			name: "Given a reply fenced in markdown with text around, When parsing it, Then should return the analysis",
+			in:   "Here is the analysis:\n```json\n" + `{"diagnosis":"bad {input}","recommendation":"fix \"it\"","confidence":0.5}` + "\n```\nHope it helps!",
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{analysis: &insights.AnalysisResponse{Diagnosis: "bad {input}", Recommendation: `fix "it"`, Confidence: 0.5}},
+		},
+		{
+			name: "Given a truncated reply, When parsing it, Then should report the object truncated",
+			in:   `{"diagnosis":"SMTP timeout","recommendation":"Raise the`,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: "JSON object is truncated"},
+		},
+		{
+			name: "Given a reply without JSON, When parsing it, Then should report no object found",
+			in:   "I could not analyze this job.",
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: "no JSON object found"},
+		},
+		{
+			name: "Given a confidence above 1, When parsing the reply, Then should reject the confidence",
+			in:   `{"diagnosis":"d","recommendation":"r","confidence":1.5}`,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: `"confidence" must be a number between 0 and 1`},
+		},
+		{
+			name: "Given a confidence given as text, When parsing the reply, Then should reject the confidence",
+			in:   `{"diagnosis":"d","recommendation":"r","confidence":"high"}`,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: `"confidence" must be a number between 0 and 1`},
+		},
+		{
+			name: "Given a fractional timeout, When parsing the reply, Then should reject the timeout",
+			in:   `{"diagnosis":"d","recommendation":"r","confidence":0.5,"suggested_fix":{"timeout_seconds":2.5}}`,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: `"suggested_fix.timeout_seconds" must be a non-negative integer`},
+		},
+		{
+			name: "Given negative max retries, When parsing the reply, Then should reject the max retries",
+			in:   `{"diagnosis":"d","recommendation":"r","confidence":0.5,"suggested_fix":{"max_retries":-1}}`,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: `"suggested_fix.max_retries" must be a non-negative integer`},
+		},
+		{
+			name: "Given a payload patch that isn't an object, When parsing the reply, Then should reject the patch",
+			in:   `{"diagnosis":"d","recommendation":"r","confidence":0.5,"suggested_fix":{"payload_patch":"retry"}}`,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: `"suggested_fix.payload_patch" must be an object`},
+		},
+		{
+			name: "Given a blank diagnosis, When parsing the reply, Then should reject the diagnosis",
+			in:   `{"diagnosis":" ","recommendation":"r","confidence":0.5}`,
+			want: struct {
+				analysis *insights.AnalysisResponse
+				problem  string
+			}{problem: `"diagnosis" must be a non-empty string`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := parseAnalysis(tt.in)
+
+			if tt.want.problem != "" {
+				var schemaErr *SchemaError
+				require.ErrorAs(t, err, &schemaErr)
+				assert.Contains(t, schemaErr.Problems, tt.want.problem)
+				assert.ErrorIs(t, err, insights.ErrInvalidAnalysisData)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.analysis, analysis)
+		})
+	}
+}
+
+// scriptedCompleter replies with its replies in turn, repeating the last one, and records the prompts
+type scriptedCompleter struct {
+	replies []string
+	err     error
+	prompts []string
+}
+
+func (c *scriptedCompleter) complete(ctx context.Context, prompt string) (string, *insights.Usage, error) {
+	c.prompts = append(c.prompts, prompt)
+	if c.err != nil {
+		return "", nil, c.err
+	}
+	reply := c.replies[min(len(c.prompts), len(c.replies))-1]
+	return reply, &insights.Usage{PromptTokens: 10, CompletionTokens: 5}, nil
+}
+
+func TestAnalyzeWithRetries(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			model   *scriptedCompleter
+			retries int
+		}
+		want struct {
+			prompts int
+			err     error
+			usage   *insights.Usage
+		}
+	}{
+		{
+			name: "Given a malformed reply then a valid one, When analyzing, Then should re-prompt once and sum the usage",
+			in: struct {
+				model   *scriptedCompleter
+				retries int
+			}{model: &scriptedCompleter{replies: []string{"not JSON", validReply}}, retries: 2},
+			want: struct {
+				prompts int
+				err     error
+				usage   *insights.Usage
+			}{prompts: 2, usage: &insights.Usage{PromptTokens: 20, CompletionTokens: 10}},
+		},
+		{
+			name: "Given only malformed replies, When analyzing, Then should stop after the retries",
+			in: struct {
+				model   *scriptedCompleter
+				retries int
+			}{model: &scriptedCompleter{replies: []string{`{"diagnosis":"d"`}}, retries: 2},
+			want: struct {
+				prompts int
+				err     error
+				usage   *insights.Usage
+			}{prompts: 3, err: insights.ErrInvalidAnalysisData},
+		},
+		{
+			name: "Given no retries, When the reply is malformed, Then should fail after one prompt",
+			in: struct {
+				model   *scriptedCompleter
+				retries int
+			}{model: &scriptedCompleter{replies: []string{"not JSON"}}, retries: 0},
+			want: struct {
+				prompts int
+				err     error
+				usage   *insights.Usage
+			}{prompts: 1, err: insights.ErrInvalidAnalysisData},
+		},
+		{
+			name: "Given a model that fails, When analyzing, Then should return its error without re-prompting",
+			in: struct {
+				model   *scriptedCompleter
+				retries int
+			}{model: &scriptedCompleter{err: errors.New("connection refused")}, retries: 2},
+			want: struct {
+				prompts int
+				err     error
+				usage   *insights.Usage
+			}{prompts: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &insights.AnalysisRequest{JobID: "job-1", Error: "timeout", Payload: `{"to":"a@b.c"}`}
+
+			analysis, err := analyzeWithRetries(context.Background(), tt.in.model, request, tt.in.retries, insights.PromptBudget{})
+
+			assert.Len(t, tt.in.model.prompts, tt.want.prompts)
+			for _, prompt := range tt.in.model.prompts[1:] {
+				assert.Contains(t, prompt, "Your previous reply could not be used")
+			}
+			switch {
+			case tt.in.model.err != nil:
+				assert.ErrorIs(t, err, tt.in.model.err)
+			case tt.want.err != nil:
+				assert.ErrorIs(t, err, tt.want.err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "SMTP timeout", analysis.Diagnosis)
+				assert.Equal(t, tt.want.usage, analysis.Usage)
+			}
+		})
+	}
+}
@@ -5,104 +5,246 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
 )
 
-// OllamaAIService implements insights.AIService using Ollama
+// DefaultOllamaModel is used when no model is configured
+const DefaultOllamaModel = "phi3:mini"
+
+// DefaultOllamaEmbeddingModel embeds insight diagnoses when no embedding model is configured
+const DefaultOllamaEmbeddingModel = "nomic-embed-text"
+
+// OllamaAIService implements insights.AIService using Ollama.
+// Requests use Ollama's JSON mode and replies that don't match the analysis schema are re-prompted.
 type OllamaAIService struct {
-	baseURL string
-	client  *http.Client
+	mu           sync.RWMutex
+	baseURL      string
+	model        string
+	client       *http.Client
+	parseRetries int
+	budget       insights.PromptBudget
+
+	embeddingModel string
 }
 
 // NewOllamaAIService creates a new Ollama AI service
 func NewOllamaAIService(baseURL string) *OllamaAIService {
 	return &OllamaAIService{
-		baseURL: baseURL,
-		client:  &http.Client{},
+		baseURL:      baseURL,
+		model:        DefaultOllamaModel,
+		client:       &http.Client{},
+		parseRetries: DefaultParseRetries,
+
+		embeddingModel: DefaultOllamaEmbeddingModel,
 	}
 }
 
-func (s *OllamaAIService) Analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
-	prompt := map[string]string{
-		"model": "phi3:mini",
-		"prompt": `
-			You are an expert in distributed systems debugging.
-			Return ONLY valid JSON. No comments, no markdown, no explanations.
-
-			Job ID: ` + request.JobID + `
-			Error: ` + request.Error + `
-			Payload: ` + request.Payload + `
-
-			Return EXACTLY this JSON structure, with no extra text:
-
-			{
-				"diagnosis": "<short reason>",
-				"recommendation": "<human-readable advice>",
-				"suggested_fix": {
-					"timeout_seconds": <int>,
-					"max_retries": <int>,
-					"payload_patch": { }
-				}
-			}
-		`,
+// UpdateSettings changes the Ollama endpoint and model used by subsequent requests.
+// An empty model keeps the default one.
+func (s *OllamaAIService) UpdateSettings(baseURL, model string) {
+	if model == "" {
+		model = DefaultOllamaModel
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = baseURL
+	s.model = model
+}
+
+// SetParseRetries sets how many times the model is re-prompted after a malformed reply
+func (s *OllamaAIService) SetParseRetries(retries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parseRetries = max(0, retries)
+}
+
+// SetPromptBudget bounds the job data put in prompts
+func (s *OllamaAIService) SetPromptBudget(budget insights.PromptBudget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budget = budget
+}
+
+// SetEmbeddingModel sets the model Embed uses; an empty model keeps the default one
+func (s *OllamaAIService) SetEmbeddingModel(model string) {
+	if model == "" {
+		model = DefaultOllamaEmbeddingModel
 	}
 
-	body, err := json.Marshal(prompt)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embeddingModel = model
+}
+
+func (s *OllamaAIService) settings() (string, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.baseURL, s.model
+}
+
+func (s *OllamaAIService) Analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
+	s.mu.RLock()
+	retries, budget := s.parseRetries, s.budget
+	s.mu.RUnlock()
+	return analyzeWithRetries(ctx, s, request, retries, budget)
+}
+
+// ollamaChunk is one line of Ollama's streamed generate response
+type ollamaChunk struct {
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	Error           string `json:"error"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+func (s *OllamaAIService) complete(ctx context.Context, prompt string) (string, *insights.Usage, error) {
+	baseURL, model := s.settings()
+	body, err := json.Marshal(map[string]any{
+		"model":  model,
+		"prompt": prompt,
+		"format": "json", // Constrains generation to valid JSON
+	})
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/api/generate", bytes.NewBuffer(body))
+	startedAt := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/generate", bytes.NewBuffer(body))
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("ollama request failed")
+		return "", nil, errors.New("ollama request failed")
 	}
 
 	// Ollama streams responses, we need to collect all chunks
 	var fullResponse string
+	usage := &insights.Usage{
+		Provider:    "ollama",
+		Model:       model,
+		PromptBytes: len(prompt),
+	}
 	decoder := json.NewDecoder(resp.Body)
 	for {
-		var chunk map[string]any
+		var chunk ollamaChunk
 		if err := decoder.Decode(&chunk); err != nil {
 			if err == io.EOF {
-				break
+				// The stream was cut before the final chunk, so the reply is incomplete
+				return "", nil, errors.New("ollama stream ended before the response was done")
 			}
-			return nil, err
+			return "", nil, fmt.Errorf("reading ollama stream: %w", err)
 		}
-		if part, ok := chunk["response"].(string); ok {
-			fullResponse += part
+		if chunk.Error != "" {
+			return "", nil, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		fullResponse += chunk.Response
+		if chunk.Done {
+			// The final chunk carries the token counts for the whole generation
+			usage.PromptTokens = chunk.PromptEvalCount
+			usage.CompletionTokens = chunk.EvalCount
+			break
 		}
 	}
+	usage.LatencyMs = time.Since(startedAt).Milliseconds()
+
+	return fullResponse, usage, nil
+}
+
+// ollamaEmbedResponse is the reply of Ollama's embed endpoint
+type ollamaEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+	Error      string      `json:"error"`
+}
 
-	// Extract JSON from the response
-	fullResponse = strings.TrimSpace(fullResponse)
+// Embed implements insights.Embedder with Ollama's embed endpoint, in one request for all texts
+func (s *OllamaAIService) Embed(ctx context.Context, texts []string) (*insights.Embeddings, error) {
+	s.mu.RLock()
+	baseURL, model := s.baseURL, s.embeddingModel
+	s.mu.RUnlock()
 
-	// Find JSON boundaries
-	start := strings.Index(fullResponse, "{")
-	end := strings.LastIndex(fullResponse, "}")
-	if start == -1 || end == -1 {
-		return nil, errors.New("no valid JSON found in response")
+	body, err := json.Marshal(map[string]any{
+		"model": model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
 	}
-	jsonStr := fullResponse[start : end+1]
 
-	var analysisResp insights.AnalysisResponse
-	if err := json.Unmarshal([]byte(jsonStr), &analysisResp); err != nil {
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/embed", bytes.NewBuffer(body))
+	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var embedded ollamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedded); err != nil {
+		return nil, fmt.Errorf("reading ollama embeddings: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || embedded.Error != "" {
+		return nil, fmt.Errorf("ollama embed request failed with status %d: %s", resp.StatusCode, embedded.Error)
+	}
+	if len(embedded.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d texts", len(embedded.Embeddings), len(texts))
+	}
+	return &insights.Embeddings{Model: "ollama:" + model, Vectors: embedded.Embeddings}, nil
+}
+
+// ollamaTagsResponse lists the models an Ollama server has pulled
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// Probe implements insights.ProviderProber with Ollama's tags endpoint, failing when the server
+// is unreachable or hasn't pulled the analysis model
+func (s *OllamaAIService) Probe(ctx context.Context) error {
+	baseURL, model := s.settings()
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
 
-	return &analysisResp, nil
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama tags request failed with status %d", resp.StatusCode)
+	}
+	var tags ollamaTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return fmt.Errorf("reading ollama tags: %w", err)
+	}
+	for _, pulled := range tags.Models {
+		// Models pulled without a tag are listed with the implicit latest one
+		if pulled.Name == model || pulled.Name == model+":latest" {
+			return nil
+		}
+	}
+	return fmt.Errorf("ollama has not pulled model %s", model)
 }
@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+)
+
+// maxErrorBody bounds how much of a failed response is included in the error
+const maxErrorBody = 512
+
+// OpenAIAIService implements insights.AIService for servers exposing the OpenAI chat completions
+// API, such as llama.cpp, vLLM, LM Studio or LocalAI. Requests ask for a JSON object reply and
+// replies that don't match the analysis schema are re-prompted.
+type OpenAIAIService struct {
+	baseURL      string // API root including the version, e.g. http://localhost:8000/v1
+	model        string
+	apiKey       string
+	client       *http.Client
+	parseRetries int
+	budget       insights.PromptBudget
+
+	embeddingModel string // Empty when the server isn't used for embeddings
+}
+
+// NewOpenAIAIService creates a new OpenAI-compatible AI service. The API key is optional,
+// since most local servers don't require one.
+func NewOpenAIAIService(baseURL, model, apiKey string) *OpenAIAIService {
+	return &OpenAIAIService{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		model:        model,
+		apiKey:       apiKey,
+		client:       &http.Client{},
+		parseRetries: DefaultParseRetries,
+	}
+}
+
+// SetParseRetries sets how many times the model is re-prompted after a malformed reply
+func (s *OpenAIAIService) SetParseRetries(retries int) {
+	s.parseRetries = max(0, retries)
+}
+
+// SetPromptBudget bounds the job data put in prompts
+func (s *OpenAIAIService) SetPromptBudget(budget insights.PromptBudget) {
+	s.budget = budget
+}
+
+// SetEmbeddingModel sets the model Embed uses; without one the service doesn't embed
+func (s *OpenAIAIService) SetEmbeddingModel(model string) {
+	s.embeddingModel = model
+}
+
+func (s *OpenAIAIService) Analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
+	return analyzeWithRetries(ctx, s, request, s.parseRetries, s.budget)
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (s *OpenAIAIService) complete(ctx context.Context, prompt string) (string, *insights.Usage, error) {
+	body, err := json.Marshal(map[string]any{
+		"model": s.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+		"temperature":     0,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	startedAt := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/chat/completions", bytes.NewBuffer(body))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return "", nil, fmt.Errorf("openai-compatible request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var completion chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", nil, fmt.Errorf("reading openai-compatible response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", nil, errors.New("openai-compatible response has no choices")
+	}
+
+	usage := &insights.Usage{
+		Provider:         "openai",
+		Model:            s.model,
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		PromptBytes:      len(prompt),
+		LatencyMs:        time.Since(startedAt).Milliseconds(),
+	}
+	return completion.Choices[0].Message.Content, usage, nil
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed implements insights.Embedder with the OpenAI embeddings API, in one request for all texts
+func (s *OpenAIAIService) Embed(ctx context.Context, texts []string) (*insights.Embeddings, error) {
+	if s.embeddingModel == "" {
+		return nil, errors.New("openai-compatible provider has no embedding model configured")
+	}
+	body, err := json.Marshal(map[string]any{
+		"model": s.embeddingModel,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/embeddings", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("openai-compatible embeddings request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var embedded embeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedded); err != nil {
+		return nil, fmt.Errorf("reading openai-compatible embeddings: %w", err)
+	}
+	if len(embedded.Data) != len(texts) {
+		return nil, fmt.Errorf("openai-compatible server returned %d embeddings for %d texts", len(embedded.Data), len(texts))
+	}
+	// Entries carry the index of their text, which servers aren't required to keep in order
+	vectors := make([][]float64, len(texts))
+	for _, entry := range embedded.Data {
+		if entry.Index < 0 || entry.Index >= len(texts) {
+			return nil, fmt.Errorf("openai-compatible embedding index %d out of range", entry.Index)
+		}
+		vectors[entry.Index] = entry.Embedding
+	}
+	return &insights.Embeddings{Model: "openai:" + s.embeddingModel, Vectors: vectors}, nil
+}
+
+// Probe implements insights.ProviderProber with the OpenAI models API, failing when the server
+// is unreachable or rejects the request, e.g. with an invalid API key
+func (s *OpenAIAIService) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("openai-compatible models request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
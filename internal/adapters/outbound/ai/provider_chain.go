@@ -0,0 +1,310 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
+)
+
+// Provider types supported in the chain
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai" // Any server exposing the OpenAI chat completions API
+	ProviderStub   = "stub"   // Canned analyses, for demos and tests without a model
+)
+
+var (
+	ErrNoProviders          = errors.New("no AI providers configured")
+	ErrNoEmbeddingProviders = errors.New("no AI provider configured for embeddings")
+)
+
+// namedProvider is one configured link of the chain
+type namedProvider struct {
+	name     string
+	service  insights.AIService
+	embedder insights.Embedder // Nil when the provider isn't used for embeddings
+	prober   insights.ProviderProber
+	timeout  time.Duration // Bounds each call so a hung provider leaves time for the next; 0 for none
+}
+
+// ProviderChain implements insights.AIService over an ordered list of providers.
+// A provider that fails or returns an unusable analysis hands the request to the next one.
+// In ensemble mode the first two providers run together and the more confident analysis wins.
+// Each analysis is labelled with the name of the provider that produced it.
+type ProviderChain struct {
+	mu        sync.RWMutex
+	providers []namedProvider
+	ensemble  bool
+}
+
+// NewProviderChain builds the chain from the AI configuration.
+// Without configured providers it falls back to a single Ollama provider using ollama_url and model.
+func NewProviderChain(cfg config.AIConfig) (*ProviderChain, error) {
+	chain := &ProviderChain{}
+	if err := chain.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// Reload replaces the providers used by subsequent requests; on error the current chain is kept
+func (c *ProviderChain) Reload(cfg config.AIConfig) error {
+	providers, err := buildProviders(cfg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers = providers
+	c.ensemble = cfg.Ensemble
+	return nil
+}
+
+func buildProviders(cfg config.AIConfig) ([]namedProvider, error) {
+	entries := cfg.Providers
+	if len(entries) == 0 {
+		entries = []config.AIProviderConfig{{Type: ProviderOllama, URL: cfg.OllamaURL, Model: cfg.Model}}
+	}
+
+	providers := make([]namedProvider, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Type == "" {
+			entry.Type = ProviderOllama
+		}
+		if entry.URL == "" && entry.Type != ProviderStub {
+			return nil, fmt.Errorf("ai provider %d: url is required", i)
+		}
+		parseRetries := cfg.ParseRetries
+		if parseRetries == 0 {
+			parseRetries = DefaultParseRetries
+		}
+		budget := insights.PromptBudget{
+			MaxPromptBytes:  cfg.MaxPromptBytes,
+			MaxPayloadBytes: cfg.MaxPayloadBytes,
+			MaxErrorBytes:   cfg.MaxErrorBytes,
+			MaxOutputBytes:  cfg.MaxOutputBytes,
+		}
+
+		var (
+			service  insights.AIService
+			embedder insights.Embedder
+			prober   insights.ProviderProber
+		)
+		switch entry.Type {
+		case ProviderOllama:
+			if entry.Model == "" {
+				entry.Model = DefaultOllamaModel
+			}
+			ollama := NewOllamaAIService(entry.URL)
+			ollama.UpdateSettings(entry.URL, entry.Model)
+			ollama.SetParseRetries(parseRetries)
+			ollama.SetPromptBudget(budget)
+			ollama.SetEmbeddingModel(entry.EmbeddingModel)
+			service, embedder, prober = ollama, ollama, ollama
+		case ProviderOpenAI:
+			if entry.Model == "" {
+				return nil, fmt.Errorf("ai provider %d: model is required for openai providers", i)
+			}
+			openAI := NewOpenAIAIService(entry.URL, entry.Model, entry.APIKey)
+			openAI.SetParseRetries(parseRetries)
+			openAI.SetPromptBudget(budget)
+			service, prober = openAI, openAI
+			if entry.EmbeddingModel != "" {
+				openAI.SetEmbeddingModel(entry.EmbeddingModel)
+				embedder = openAI
+			}
+		case ProviderStub:
+			responses, err := NewStubResponses(entry.Responses)
+			if err != nil {
+				return nil, fmt.Errorf("ai provider %d: %w", i, err)
+			}
+			stub := NewStubAIService(responses)
+			service, embedder, prober = stub, stub, stub
+		default:
+			return nil, fmt.Errorf("ai provider %d: unsupported type %q", i, entry.Type)
+		}
+		if entry.Name == "" {
+			entry.Name = entry.Type + ":" + entry.Model
+			if entry.Type == ProviderStub {
+				entry.Name = ProviderStub
+			}
+		}
+		providers = append(providers, namedProvider{
+			name:     entry.Name,
+			service:  service,
+			embedder: embedder,
+			prober:   prober,
+			timeout:  time.Duration(cfg.ProviderTimeoutSeconds) * time.Second,
+		})
+	}
+	return providers, nil
+}
+
+func (c *ProviderChain) snapshot() ([]namedProvider, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.providers, c.ensemble
+}
+
+// Analyze returns the first usable analysis along the chain
+func (c *ProviderChain) Analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
+	providers, ensemble := c.snapshot()
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	var lastErr error
+	if ensemble && len(providers) >= 2 {
+		analysis, err := c.compare(ctx, request, providers[0], providers[1])
+		if err == nil {
+			return analysis, nil
+		}
+		lastErr = err
+		providers = providers[2:]
+	}
+
+	for _, provider := range providers {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		analysis, err := provider.analyze(ctx, request)
+		if err == nil {
+			return analysis, nil
+		}
+		lastErr = err
+		slog.WarnContext(ctx, "AI provider failed, falling back",
+			slog.String("provider", provider.name),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return nil, fmt.Errorf("all AI providers failed: %w", lastErr)
+}
+
+// Embed returns the embeddings of the first provider along the chain that embeds the texts.
+// All vectors of a call come from one provider, so they are comparable with each other.
+func (c *ProviderChain) Embed(ctx context.Context, texts []string) (*insights.Embeddings, error) {
+	providers, _ := c.snapshot()
+
+	lastErr := ErrNoEmbeddingProviders
+	for _, provider := range providers {
+		if provider.embedder == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		embeddings, err := provider.embed(ctx, texts)
+		if err == nil {
+			return embeddings, nil
+		}
+		lastErr = err
+		slog.WarnContext(ctx, "AI provider failed to embed, falling back",
+			slog.String("provider", provider.name),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return nil, fmt.Errorf("all AI providers failed to embed: %w", lastErr)
+}
+
+// Probe implements insights.ProviderProber. The chain can analyze while any of its providers
+// can, since a failing one hands requests to the next, so probing stops at the first healthy
+// provider and fails only when every provider does.
+func (c *ProviderChain) Probe(ctx context.Context) error {
+	providers, _ := c.snapshot()
+	if len(providers) == 0 {
+		return ErrNoProviders
+	}
+
+	var errs []error
+	for _, provider := range providers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := provider.probe(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("provider %s: %w", provider.name, err))
+	}
+	return errors.Join(errs...)
+}
+
+// compare runs two providers concurrently and keeps the more confident analysis
+func (c *ProviderChain) compare(ctx context.Context, request *insights.AnalysisRequest, first, second namedProvider) (*insights.AnalysisResponse, error) {
+	var (
+		wg        sync.WaitGroup
+		analyses  [2]*insights.AnalysisResponse
+		errs      [2]error
+		providers = [2]namedProvider{first, second}
+	)
+	for i, provider := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			analyses[i], errs[i] = provider.analyze(ctx, request)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			slog.WarnContext(ctx, "AI provider failed in ensemble",
+				slog.String("provider", providers[i].name),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	best := insights.MoreConfident(analyses[0], analyses[1])
+	if best == nil {
+		return nil, errors.Join(errs[0], errs[1])
+	}
+	return best, nil
+}
+
+// analyze calls the provider and rejects analyses an insight can't be built from
+func (p namedProvider) analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	analysis, err := p.service.Analyze(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if !analysis.IsValid() {
+		return nil, fmt.Errorf("%w: provider %s returned no diagnosis", insights.ErrInvalidAnalysisData, p.name)
+	}
+	analysis.Provider = p.name
+	return analysis, nil
+}
+
+// embed calls the provider's embedder within the provider timeout
+func (p namedProvider) embed(ctx context.Context, texts []string) (*insights.Embeddings, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	return p.embedder.Embed(ctx, texts)
+}
+
+// probe checks the provider within the provider timeout
+func (p namedProvider) probe(ctx context.Context) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	return p.prober.Probe(ctx)
+}
@@ -0,0 +1,185 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
+)
+
+// DefaultStubConfidence is the confidence of configured stub analyses that don't set one
+const DefaultStubConfidence = 0.8
+
+// StubResponse is the analysis the stub gives jobs whose error matches its pattern
+type StubResponse struct {
+	Match    *regexp.Regexp
+	Analysis insights.AnalysisResponse
+}
+
+// builtinStubResponses cover the failures the simulated executors produce, so a demo gets
+// varied insights with no configuration
+var builtinStubResponses = []StubResponse{
+	{
+		Match: regexp.MustCompile(`(?i)above its SLO`),
+		Analysis: insights.AnalysisResponse{
+			Diagnosis:      "The job ran slower than its SLO, most likely waiting on a slow downstream dependency.",
+			Recommendation: "Check the latency of the services the job calls and split large payloads into smaller jobs.",
+			Confidence:     0.6,
+		},
+	},
+	{
+		Match: regexp.MustCompile(`(?i)timeout|timed out|deadline exceeded`),
+		Analysis: insights.AnalysisResponse{
+			Diagnosis:      "The job timed out waiting on a downstream service.",
+			Recommendation: "Give the job more time and retry it.",
+			SuggestedFix:   insights.SuggestedFix{TimeoutSeconds: 60, MaxRetries: 3},
+			Confidence:     0.85,
+		},
+	},
+	{
+		Match: regexp.MustCompile(`(?i)unauthori[sz]ed|forbidden|invalid token|credential|\b(401|403)\b`),
+		Analysis: insights.AnalysisResponse{
+			Diagnosis:      "The downstream service rejected the job's credentials.",
+			Recommendation: "Rotate or fix the credentials the job uses, then retry it; retrying as is will fail again.",
+			Confidence:     0.9,
+		},
+	},
+	{
+		Match: regexp.MustCompile(`(?i)rate limit|too many requests|\b429\b`),
+		Analysis: insights.AnalysisResponse{
+			Diagnosis:      "The downstream service throttled the job.",
+			Recommendation: "Lower the queue's rate limit or retry later with more backoff.",
+			SuggestedFix:   insights.SuggestedFix{MaxRetries: 5},
+			Confidence:     0.85,
+		},
+	},
+	{
+		Match: regexp.MustCompile(`(?i)connection (refused|reset)|unavailable|\b50[234]\b`),
+		Analysis: insights.AnalysisResponse{
+			Diagnosis:      "The downstream service was unreachable or unavailable.",
+			Recommendation: "Check that the service is up, then retry the job.",
+			SuggestedFix:   insights.SuggestedFix{MaxRetries: 3},
+			Confidence:     0.75,
+		},
+	},
+	{
+		Match: regexp.MustCompile(`(?i)invalid|missing|required|malformed|validation|exceeds maximum|too large|unsupported`),
+		Analysis: insights.AnalysisResponse{
+			Diagnosis:      "The job's payload failed validation.",
+			Recommendation: "Correct the payload before retrying; retrying as is will fail again.",
+			Confidence:     0.7,
+		},
+	},
+}
+
+// stubFallback answers errors no pattern matches
+var stubFallback = insights.AnalysisResponse{
+	Diagnosis:      "The job failed for a reason the stub AI provider has no canned analysis for.",
+	Recommendation: "Inspect the job's error and output, then retry it.",
+	Confidence:     0.3,
+}
+
+// StubAIService implements insights.AIService with canned analyses chosen by matching the job's
+// error against patterns, for demos and tests that run without a model. The same request always
+// gets the same analysis.
+type StubAIService struct {
+	responses []StubResponse
+}
+
+// NewStubAIService creates a stub trying the responses in order, then the built-in ones
+func NewStubAIService(responses []StubResponse) *StubAIService {
+	return &StubAIService{responses: append(append([]StubResponse{}, responses...), builtinStubResponses...)}
+}
+
+// NewStubResponses compiles the configured responses of a stub provider
+func NewStubResponses(configs []config.AIStubResponseConfig) ([]StubResponse, error) {
+	responses := make([]StubResponse, 0, len(configs))
+	for i, cfg := range configs {
+		match, err := regexp.Compile(cfg.Match)
+		if err != nil {
+			return nil, fmt.Errorf("stub response %d: %w", i, err)
+		}
+		confidence := cfg.Confidence
+		if confidence == 0 {
+			confidence = DefaultStubConfidence
+		}
+		responses = append(responses, StubResponse{
+			Match: match,
+			Analysis: insights.AnalysisResponse{
+				Diagnosis:      cfg.Diagnosis,
+				Recommendation: cfg.Recommendation,
+				SuggestedFix: insights.SuggestedFix{
+					TimeoutSeconds: cfg.TimeoutSeconds,
+					MaxRetries:     cfg.MaxRetries,
+					PayloadPatch:   cfg.PayloadPatch,
+				},
+				Confidence: confidence,
+			},
+		})
+	}
+	return responses, nil
+}
+
+func (s *StubAIService) Analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	analysis := stubFallback
+	for _, response := range s.responses {
+		if response.Match.MatchString(request.Error) {
+			analysis = response.Analysis
+			break
+		}
+	}
+	// Callers may change the analysis, so the canned one is copied
+	analysis.SuggestedFix.PayloadPatch = maps.Clone(analysis.SuggestedFix.PayloadPatch)
+	// No tokens are spent, but the calls still show up in the usage report
+	analysis.Usage = &insights.Usage{Provider: ProviderStub}
+	return &analysis, nil
+}
+
+// Probe implements insights.ProviderProber; the stub is always available
+func (s *StubAIService) Probe(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// stubEmbeddingSize is the length of the stub's embedding vectors
+const stubEmbeddingSize = 256
+
+// stubStopWords carry no meaning for telling diagnoses apart
+var stubStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "of": true, "to": true, "in": true,
+	"on": true, "for": true, "was": true, "were": true, "is": true, "be": true, "it": true, "its": true,
+	"job": true, "jobs": true, "s": true,
+}
+
+// Embed implements insights.Embedder with a bag of words hashed into a fixed size vector, so
+// diagnoses sharing most of their words are similar. It needs no model, for demos and tests.
+func (s *StubAIService) Embed(ctx context.Context, texts []string) (*insights.Embeddings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vector := make([]float64, stubEmbeddingSize)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+		for _, word := range words {
+			if stubStopWords[word] {
+				continue
+			}
+			hash := fnv.New32a()
+			hash.Write([]byte(word))
+			vector[hash.Sum32()%stubEmbeddingSize]++
+		}
+		vectors[i] = vector
+	}
+	return &insights.Embeddings{Model: ProviderStub, Vectors: vectors}, nil
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+)
+
+type subscription struct {
+	handler events.Handler
+	names   map[string]bool // empty = all events
+}
+
+// InProcessBus implements events.Publisher by dispatching events synchronously
+// to subscribers registered in the same process. Handlers doing slow I/O should
+// hand the event off to their own goroutine.
+type InProcessBus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+// NewInProcessBus creates a new in-process event bus
+func NewInProcessBus() *InProcessBus {
+	return &InProcessBus{}
+}
+
+// Subscribe registers a handler for the given event names (all events when none given)
+func (b *InProcessBus) Subscribe(handler events.Handler, names ...string) {
+	sub := subscription{handler: handler, names: make(map[string]bool, len(names))}
+	for _, name := range names {
+		sub.names[name] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = append(b.subscriptions, sub)
+}
+
+// Publish delivers the event to every matching subscriber.
+// A panicking subscriber is logged and doesn't affect the others or the publisher.
+func (b *InProcessBus) Publish(ctx context.Context, event events.Event) {
+	b.mu.RLock()
+	subs := make([]subscription, len(b.subscriptions))
+	copy(subs, b.subscriptions)
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		if len(sub.names) > 0 && !sub.names[event.Name()] {
+			continue
+		}
+		b.dispatch(ctx, sub.handler, event)
+	}
+}
+
+func (b *InProcessBus) dispatch(ctx context.Context, handler events.Handler, event events.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "Event subscriber panicked",
+				slog.String("event", event.Name()),
+				slog.Any("panic", r),
+			)
+		}
+	}()
+	handler(ctx, event)
+}
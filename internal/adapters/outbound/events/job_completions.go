@@ -0,0 +1,72 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/google/uuid"
+)
+
+// JobCompletions implements queue.CompletionWaiter over the events relayed by
+// RedisRelaySubscriber: a job is finished once a worker published job.completed or
+// job.moved_to_dlq for it. Like the relay, it misses events published while Redis is
+// unreachable, so waiters should bound their wait.
+type JobCompletions struct {
+	mu      sync.Mutex
+	waiters map[uuid.UUID][]chan struct{}
+}
+
+// NewJobCompletions creates a waiter with no job waited on
+func NewJobCompletions() *JobCompletions {
+	return &JobCompletions{waiters: make(map[uuid.UUID][]chan struct{})}
+}
+
+func (c *JobCompletions) Wait(jobID uuid.UUID) (<-chan struct{}, func()) {
+	done := make(chan struct{})
+
+	c.mu.Lock()
+	c.waiters[jobID] = append(c.waiters[jobID], done)
+	c.mu.Unlock()
+
+	return done, func() { c.release(jobID, done) }
+}
+
+// release stops waiting on the job with done, unless it already finished
+func (c *JobCompletions) release(jobID uuid.UUID, done chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiters := c.waiters[jobID]
+	for i, waiter := range waiters {
+		if waiter == done {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(c.waiters, jobID)
+	} else {
+		c.waiters[jobID] = waiters
+	}
+}
+
+// HandleEnvelope wakes the waiters of the job a relayed event finished; pass it to
+// RedisEventStream.Run
+func (c *JobCompletions) HandleEnvelope(envelope events.Envelope) {
+	if envelope.Name != events.NameJobCompleted && envelope.Name != events.NameJobMovedToDLQ {
+		return
+	}
+	var data struct {
+		JobID uuid.UUID `json:"job_id"`
+	}
+	if err := json.Unmarshal(envelope.Data, &data); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, done := range c.waiters[data.JobID] {
+		close(done)
+	}
+	delete(c.waiters, data.JobID)
+}
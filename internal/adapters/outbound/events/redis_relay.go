@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRelaySubscriber forwards every event to a Redis pub/sub channel so other
+// processes (e.g. queue-core's live feed) see events raised by workers
+func RedisRelaySubscriber(client *redis.Client, channel string) events.Handler {
+	return func(ctx context.Context, event events.Event) {
+		envelope, err := events.NewEnvelope(event)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to encode event for relay",
+				slog.String("event", event.Name()),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		data, err := json.Marshal(envelope)
+		if err != nil {
+			return
+		}
+		if err := client.Publish(ctx, channel, data).Err(); err != nil {
+			slog.WarnContext(ctx, "Failed to relay event to Redis",
+				slog.String("event", event.Name()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// RedisEventStream receives events relayed by RedisRelaySubscriber
+type RedisEventStream struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisEventStream creates a stream reading the given pub/sub channel
+func NewRedisEventStream(client *redis.Client, channel string) *RedisEventStream {
+	return &RedisEventStream{client: client, channel: channel}
+}
+
+// Run delivers relayed events to handle until ctx is cancelled.
+// Pub/sub is fire-and-forget: events published while disconnected are lost.
+func (s *RedisEventStream) Run(ctx context.Context, handle func(events.Envelope)) {
+	pubsub := s.client.Subscribe(ctx, s.channel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var envelope events.Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				slog.WarnContext(ctx, "Dropping malformed relayed event",
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			handle(envelope)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// MetricsSubscriber records job lifecycle events in the metrics service
+func MetricsSubscriber(metrics queue.MetricsService) events.Handler {
+	return func(ctx context.Context, event events.Event) {
+		switch e := event.(type) {
+		case events.JobCreated:
+			metrics.RecordJobCreated(e.Queue, e.Type)
+		case events.JobCompleted:
+			metrics.RecordJobCompleted(e.Queue, e.Type, e.Duration.Seconds())
+		case events.JobFailed:
+			if e.Category == "" {
+				metrics.RecordJobFailed(e.Queue, e.Type)
+			} else {
+				metrics.RecordJobFailedWithReason(e.Queue, e.Type, e.Category)
+			}
+		case events.JobParked:
+			metrics.RecordJobParked(e.Queue, e.Type)
+		}
+	}
+}
+
+// LogSubscriber writes every event to the structured log
+func LogSubscriber() events.Handler {
+	return func(ctx context.Context, event events.Event) {
+		slog.InfoContext(ctx, "Domain event",
+			slog.String("event", event.Name()),
+			slog.Any("data", event),
+		)
+	}
+}
@@ -0,0 +1,235 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
+)
+
+// CommandJobType is the job type handled by CommandJobExecutor
+const CommandJobType = "command"
+
+// CommandPayload is the expected payload of a "command" job
+type CommandPayload struct {
+	Command        string   `json:"command"`
+	Args           []string `json:"args"`
+	TimeoutSeconds int      `json:"timeout_seconds"`
+}
+
+// CommandOutput is stored as the job result
+type CommandOutput struct {
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+}
+
+var (
+	ErrCommandNotAllowed = errors.New("command is not in the allow-list")
+	ErrCommandRequired   = errors.New("command is required")
+)
+
+// CommandJobExecutor runs allow-listed binaries with a wall-clock timeout and an
+// optional CPU time limit. Arguments are passed directly to the process, never
+// through a shell, so payloads can't inject extra commands.
+type CommandJobExecutor struct {
+	config config.CommandExecutorConfig
+}
+
+// NewCommandJobExecutor creates a new command job executor
+func NewCommandJobExecutor(cfg config.CommandExecutorConfig) *CommandJobExecutor {
+	return &CommandJobExecutor{config: cfg}
+}
+
+func (e *CommandJobExecutor) CanHandle(jobType string) bool {
+	return jobType == CommandJobType
+}
+
+func (e *CommandJobExecutor) Execute(ctx context.Context, job *queue.Job) (*worker.ExecutionResult, error) {
+	var payload CommandPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return &worker.ExecutionResult{
+			Success: false,
+			Error:   worker.NewPermanentError(fmt.Errorf("invalid command payload: %w", err)),
+		}, nil
+	}
+	if payload.Command == "" {
+		return &worker.ExecutionResult{Success: false, Error: worker.NewPermanentError(ErrCommandRequired)}, nil
+	}
+	binary, allowed := e.resolve(payload.Command)
+	if !allowed {
+		slog.WarnContext(ctx, "Rejected command outside allow-list",
+			slog.String("jobId", job.ID.String()),
+			slog.String("command", payload.Command),
+		)
+		return &worker.ExecutionResult{
+			Success: false,
+			Error:   worker.NewPermanentError(fmt.Errorf("%w: %s", ErrCommandNotAllowed, payload.Command)),
+		}, nil
+	}
+
+	timeout := e.timeout(payload.TimeoutSeconds)
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	name, args := e.commandLine(binary, payload.Args)
+	cmd := exec.CommandContext(execCtx, name, args...)
+	cmd.WaitDelay = 2 * time.Second
+
+	stdout := newLimitedBuffer(e.config.MaxOutputBytes)
+	stderr := newLimitedBuffer(e.config.MaxOutputBytes)
+	// Output is streamed as it's written too, so long commands can be tailed while they run
+	sink := worker.ResultSinkFrom(ctx)
+	cmd.Stdout = io.MultiWriter(stdout, sink)
+	cmd.Stderr = io.MultiWriter(stderr, sink)
+
+	slog.InfoContext(ctx, "Running command",
+		slog.String("jobId", job.ID.String()),
+		slog.String("command", payload.Command),
+		slog.Int("args", len(payload.Args)),
+		slog.Duration("timeout", timeout),
+	)
+
+	start := time.Now()
+	runErr := cmd.Run()
+	output := CommandOutput{
+		ExitCode:   cmd.ProcessState.ExitCode(),
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		Truncated:  stdout.truncated || stderr.truncated,
+		DurationMs: time.Since(start).Milliseconds(),
+		TimedOut:   errors.Is(execCtx.Err(), context.DeadlineExceeded),
+	}
+
+	if runErr == nil {
+		return &worker.ExecutionResult{Success: true, Output: output}, nil
+	}
+
+	return &worker.ExecutionResult{
+		Success: false,
+		Error:   e.classify(runErr, output),
+		Output:  output,
+	}, nil
+}
+
+// classify decides whether a failed run is worth retrying
+func (e *CommandJobExecutor) classify(runErr error, output CommandOutput) error {
+	if output.TimedOut {
+		return fmt.Errorf("command timed out after %dms", output.DurationMs)
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(runErr, &exitErr) {
+		// The process could not be started at all (missing binary, permissions)
+		return worker.NewPermanentError(fmt.Errorf("failed to start command: %w", runErr))
+	}
+
+	err := fmt.Errorf("command exited with code %d", output.ExitCode)
+	if line := lastLine(output.Stderr); line != "" {
+		err = fmt.Errorf("%w: %s", err, line)
+	}
+	if slices.Contains(e.config.TerminalExitCodes, output.ExitCode) {
+		return worker.NewPermanentError(err)
+	}
+	return err
+}
+
+// resolve matches the command against the allow-list by exact path or base name and returns
+// the binary to run. "ls" matching an allow-listed "/bin/ls" runs /bin/ls, not whichever ls
+// comes first on $PATH; "/bin/ls" never matches an allow-listed "ls".
+func (e *CommandJobExecutor) resolve(command string) (string, bool) {
+	for _, allowed := range e.config.AllowedCommands {
+		if command == allowed {
+			return allowed, true
+		}
+	}
+	if filepath.IsAbs(command) {
+		return "", false
+	}
+	for _, allowed := range e.config.AllowedCommands {
+		if filepath.IsAbs(allowed) && filepath.Base(allowed) == command {
+			return allowed, true
+		}
+	}
+	return "", false
+}
+
+// timeout applies the default timeout and caps payload-provided values
+func (e *CommandJobExecutor) timeout(requestedSeconds int) time.Duration {
+	seconds := e.config.DefaultTimeoutSeconds
+	if seconds <= 0 {
+		seconds = 30
+	}
+	if requestedSeconds > 0 {
+		seconds = requestedSeconds
+	}
+	if e.config.MaxTimeoutSeconds > 0 && seconds > e.config.MaxTimeoutSeconds {
+		seconds = e.config.MaxTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// commandLine wraps the command with a CPU time limit when configured.
+// The shell only runs ulimit and exec; the command and its arguments are passed
+// as positional parameters and are never interpreted by the shell.
+func (e *CommandJobExecutor) commandLine(binary string, commandArgs []string) (string, []string) {
+	if e.config.CPULimitSeconds <= 0 {
+		return binary, commandArgs
+	}
+
+	args := []string{"-c", `ulimit -t "$1" && shift && exec "$@"`, "sh", strconv.Itoa(e.config.CPULimitSeconds), binary}
+	return "/bin/sh", append(args, commandArgs...)
+}
+
+// lastLine returns the last non-empty line of command output for error messages
+func lastLine(s string) string {
+	lines := bytes.Split(bytes.TrimSpace([]byte(s)), []byte("\n"))
+	return string(lines[len(lines)-1])
+}
+
+// limitedBuffer keeps at most max bytes of output and discards the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func newLimitedBuffer(max int) *limitedBuffer {
+	if max <= 0 {
+		max = 64 * 1024
+	}
+	return &limitedBuffer{max: max}
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.max - b.buf.Len()
+	if remaining <= 0 {
+		b.truncated = true
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		b.buf.Write(p[:remaining])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
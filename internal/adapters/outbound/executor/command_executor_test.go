@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandJobExecutor_Resolve(t *testing.T) {
+	executor := NewCommandJobExecutor(config.CommandExecutorConfig{
+		AllowedCommands: []string{"echo", "/usr/local/bin/report"},
+	})
+
+	tests := []struct {
+		name string
+		in   string
+		want struct {
+			binary  string
+			allowed bool
+		}
+	}{
+		{
+			name: "Given a bare allow-listed name, When resolving, Then should run it from $PATH",
+			in:   "echo",
+			want: struct {
+				binary  string
+				allowed bool
+			}{binary: "echo", allowed: true},
+		},
+		{
+			name: "Given the base name of an allow-listed path, When resolving, Then should run the allow-listed path",
+			in:   "report",
+			want: struct {
+				binary  string
+				allowed bool
+			}{binary: "/usr/local/bin/report", allowed: true},
+		},
+		{
+			name: "Given an allow-listed path, When resolving, Then should run it",
+			in:   "/usr/local/bin/report",
+			want: struct {
+				binary  string
+				allowed bool
+			}{binary: "/usr/local/bin/report", allowed: true},
+		},
+		{
+			name: "Given another path to an allow-listed name, When resolving, Then should reject it",
+			in:   "/tmp/echo",
+		},
+		{
+			name: "Given a relative path ending in an allow-listed base name, When resolving, Then should reject it",
+			in:   "./report",
+		},
+		{
+			name: "Given a command outside the allow-list, When resolving, Then should reject it",
+			in:   "rm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binary, allowed := executor.resolve(tt.in)
+
+			assert.Equal(t, tt.want.binary, binary)
+			assert.Equal(t, tt.want.allowed, allowed)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// CompositeJobExecutor routes each job to the first executor that can handle its type
+type CompositeJobExecutor struct {
+	executors []worker.JobExecutor
+}
+
+// NewCompositeJobExecutor creates an executor that delegates by job type, in order
+func NewCompositeJobExecutor(executors ...worker.JobExecutor) *CompositeJobExecutor {
+	return &CompositeJobExecutor{executors: executors}
+}
+
+func (e *CompositeJobExecutor) Execute(ctx context.Context, job *queue.Job) (*worker.ExecutionResult, error) {
+	for _, executor := range e.executors {
+		if executor.CanHandle(job.Type) {
+			return executor.Execute(ctx, job)
+		}
+	}
+
+	return &worker.ExecutionResult{
+		Success: false,
+		Error:   worker.NewPermanentError(errors.New("unsupported job type: " + job.Type)),
+	}, nil
+}
+
+func (e *CompositeJobExecutor) CanHandle(jobType string) bool {
+	for _, executor := range e.executors {
+		if executor.CanHandle(jobType) {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
@@ -16,35 +19,52 @@ import (
 
 // DefaultJobExecutor is a simple executor that handles basic job types
 type DefaultJobExecutor struct {
-	config *config.Config
-	rng    *rand.Rand
+	mu         sync.Mutex
+	simulation config.SimulationConfig
+	rng        randomSource
+	now        func() time.Time
+	bursts     map[string]*burstState // Simulated outages per job type
+}
+
+// randomSource is the part of *rand.Rand the simulation draws from
+type randomSource interface {
+	Float64() float64
+	NormFloat64() float64
+	ExpFloat64() float64
+	Intn(n int) int
 }
 
 // NewDefaultJobExecutor creates a new default job executor
 func NewDefaultJobExecutor(cfg *config.Config) *DefaultJobExecutor {
 	return &DefaultJobExecutor{
-		config: cfg,
-		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		simulation: cfg.Simulation,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:        time.Now,
+		bursts:     make(map[string]*burstState),
 	}
 }
 
+// UpdateSimulation replaces the failure simulation settings at runtime
+func (e *DefaultJobExecutor) UpdateSimulation(sim config.SimulationConfig) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.simulation = sim
+}
+
 func (e *DefaultJobExecutor) Execute(ctx context.Context, job *queue.Job) (*worker.ExecutionResult, error) {
-	slog.InfoContext(ctx, "Executing job",
-		slog.String("jobId", job.ID.String()),
-		slog.String("jobType", job.Type),
-		slog.String("queue", job.Queue),
-	)
+	// Within a worker run the logger already carries the job ID, type, queue and attempt
+	logger := worker.LoggerFrom(ctx)
+	logger.InfoContext(ctx, "Executing job")
 
 	// Parse payload
 	var payload map[string]any
 	if err := json.Unmarshal(job.Payload, &payload); err != nil {
-		slog.ErrorContext(ctx, "Failed to parse job payload",
-			slog.String("jobId", job.ID.String()),
+		logger.ErrorContext(ctx, "Failed to parse job payload",
 			slog.String("error", err.Error()),
 		)
 		return &worker.ExecutionResult{
 			Success: false,
-			Error:   err,
+			Error:   worker.NewPermanentError(fmt.Errorf("invalid payload: %w", err)),
 		}, nil
 	}
 
@@ -59,7 +79,7 @@ func (e *DefaultJobExecutor) Execute(ctx context.Context, job *queue.Job) (*work
 	default:
 		return &worker.ExecutionResult{
 			Success: false,
-			Error:   errors.New("unsupported job type: " + job.Type),
+			Error:   worker.NewPermanentError(errors.New("unsupported job type: " + job.Type)),
 		}, nil
 	}
 }
@@ -81,9 +101,12 @@ func (e *DefaultJobExecutor) executeEmailJob(ctx context.Context, jobID string,
 		slog.String("subject", fmt.Sprintf("%v", payload["subject"])),
 	)
 
-	// Check if simulation is enabled and should fail
-	if e.shouldSimulateFailure() {
-		errorMsg := e.getRandomError("email")
+	// Apply the simulated latency and failures of the job type, if enabled
+	errorMsg, err := e.simulate(ctx, "email")
+	if err != nil {
+		return simulatedTimeout(ctx, jobID, err), nil
+	}
+	if errorMsg != "" {
 		slog.WarnContext(ctx, "Simulating email sending failure",
 			slog.String("jobId", jobID),
 			slog.String("error", errorMsg),
@@ -91,7 +114,7 @@ func (e *DefaultJobExecutor) executeEmailJob(ctx context.Context, jobID string,
 		)
 		return &worker.ExecutionResult{
 			Success: false,
-			Error:   errors.New(errorMsg),
+			Error:   simulatedError(errorMsg),
 		}, nil
 	}
 
@@ -116,9 +139,12 @@ func (e *DefaultJobExecutor) executeNotificationJob(ctx context.Context, jobID s
 		slog.String("message", fmt.Sprintf("%v", payload["message"])),
 	)
 
-	// Check if simulation is enabled and should fail
-	if e.shouldSimulateFailure() {
-		errorMsg := e.getRandomError("notification")
+	// Apply the simulated latency and failures of the job type, if enabled
+	errorMsg, err := e.simulate(ctx, "notification")
+	if err != nil {
+		return simulatedTimeout(ctx, jobID, err), nil
+	}
+	if errorMsg != "" {
 		slog.WarnContext(ctx, "Simulating notification failure",
 			slog.String("jobId", jobID),
 			slog.String("error", errorMsg),
@@ -126,7 +152,7 @@ func (e *DefaultJobExecutor) executeNotificationJob(ctx context.Context, jobID s
 		)
 		return &worker.ExecutionResult{
 			Success: false,
-			Error:   errors.New(errorMsg),
+			Error:   simulatedError(errorMsg),
 		}, nil
 	}
 
@@ -148,18 +174,26 @@ func (e *DefaultJobExecutor) executeDataProcessingJob(ctx context.Context, jobID
 		slog.String("jobId", jobID),
 		slog.Any("data", payload["data"]),
 	)
+	// Progress is streamed so long runs can be tailed through GET /api/jobs/{id}/output
+	sink := worker.ResultSinkFrom(ctx)
+	fmt.Fprintf(sink, "%s processing data\n", e.now().UTC().Format(time.RFC3339))
 
-	// Check if simulation is enabled and should fail
-	if e.shouldSimulateFailure() {
-		errorMsg := e.getRandomError("data_processing")
+	// Apply the simulated latency and failures of the job type, if enabled
+	errorMsg, err := e.simulate(ctx, "data_processing")
+	if err != nil {
+		fmt.Fprintf(sink, "%s timed out: %v\n", e.now().UTC().Format(time.RFC3339), err)
+		return simulatedTimeout(ctx, jobID, err), nil
+	}
+	if errorMsg != "" {
 		slog.WarnContext(ctx, "Simulating data processing failure",
 			slog.String("jobId", jobID),
 			slog.String("error", errorMsg),
 			slog.Bool("simulated", true),
 		)
+		fmt.Fprintf(sink, "%s failed: %s\n", e.now().UTC().Format(time.RFC3339), errorMsg)
 		return &worker.ExecutionResult{
 			Success: false,
-			Error:   errors.New(errorMsg),
+			Error:   simulatedError(errorMsg),
 		}, nil
 	}
 
@@ -168,6 +202,7 @@ func (e *DefaultJobExecutor) executeDataProcessingJob(ctx context.Context, jobID
 	slog.InfoContext(ctx, "Data processed successfully",
 		slog.String("jobId", jobID),
 	)
+	fmt.Fprintf(sink, "%s data processed\n", e.now().UTC().Format(time.RFC3339))
 
 	return &worker.ExecutionResult{
 		Success: true,
@@ -175,16 +210,54 @@ func (e *DefaultJobExecutor) executeDataProcessingJob(ctx context.Context, jobID
 	}, nil
 }
 
-// shouldSimulateFailure determines if this execution should fail based on configuration
-func (e *DefaultJobExecutor) shouldSimulateFailure() bool {
-	if !e.config.Simulation.Enabled {
-		return false
+// simulatedTimeout fails a job whose deadline passed during its simulated latency
+func simulatedTimeout(ctx context.Context, jobID string, err error) *worker.ExecutionResult {
+	slog.WarnContext(ctx, "Simulated latency outlasted the job's deadline",
+		slog.String("jobId", jobID),
+		slog.Bool("simulated", true),
+	)
+	return &worker.ExecutionResult{
+		Success: false,
+		Error:   fmt.Errorf("processing timeout exceeded: %w", err),
 	}
-	return e.rng.Float64() < e.config.Simulation.FailureRate
 }
 
-// getRandomError returns a random error message for the given job type
-func (e *DefaultJobExecutor) getRandomError(jobType string) string {
+// simulatedThrottleRetryAfter is the delay requested by simulated throttling failures
+const simulatedThrottleRetryAfter = 5 * time.Second
+
+// simulatedRejections are the simulated failures a downstream service would answer with a 4xx:
+// retrying the same request can't fix them
+var simulatedRejections = []string{"invalid", "validation failed", "exceeds maximum", "too large"}
+
+// simulatedError turns a simulated failure message into an error, classified by the status a
+// downstream HTTP service would answer it with: throttling is a 429 or 503 with Retry-After,
+// a rejected request a 400, and anything else a 500.
+func simulatedError(msg string) error {
+	return worker.ClassifyHTTPStatus(errors.New(msg), simulatedStatus(msg), simulatedThrottleRetryAfter)
+}
+
+// simulatedStatus is the HTTP status a downstream service would answer the failure with
+func simulatedStatus(msg string) int {
+	switch {
+	case strings.Contains(msg, "rate limit exceeded"):
+		return http.StatusTooManyRequests
+	case strings.Contains(msg, "service unavailable"):
+		return http.StatusServiceUnavailable
+	}
+	for _, rejection := range simulatedRejections {
+		if strings.Contains(msg, rejection) {
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// randomError picks one of the messages, or of the job type's built-in messages when there
+// are none; the caller holds e.mu
+func (e *DefaultJobExecutor) randomError(jobType string, messages []string) string {
+	if len(messages) > 0 {
+		return messages[e.rng.Intn(len(messages))]
+	}
 	errors := map[string][]string{
 		"email": {
 			"failed to connect to SMTP server: connection timeout",
@@ -213,6 +286,5 @@ func (e *DefaultJobExecutor) getRandomError(jobType string) string {
 	if !ok {
 		return fmt.Sprintf("unknown error processing %s job", jobType)
 	}
-
 	return jobErrors[e.rng.Intn(len(jobErrors))]
 }
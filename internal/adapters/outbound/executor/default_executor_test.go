@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulatedError(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want struct {
+			permanent  bool
+			retryAfter time.Duration
+		}
+	}{
+		{
+			name: "Given a rate limit failure, When classifying it, Then should retry it after the throttle delay",
+			in:   "rate limit exceeded for notifications",
+			want: struct {
+				permanent  bool
+				retryAfter time.Duration
+			}{retryAfter: simulatedThrottleRetryAfter},
+		},
+		{
+			name: "Given an unavailable service, When classifying it, Then should retry it after the throttle delay",
+			in:   "push notification service unavailable",
+			want: struct {
+				permanent  bool
+				retryAfter time.Duration
+			}{retryAfter: simulatedThrottleRetryAfter},
+		},
+		{
+			name: "Given a rejected request, When classifying it, Then should make it permanent",
+			in:   "email size exceeds maximum allowed limit",
+			want: struct {
+				permanent  bool
+				retryAfter time.Duration
+			}{permanent: true},
+		},
+		{
+			name: "Given a server failure, When classifying it, Then should retry it with backoff",
+			in:   "DNS lookup failed for mail server",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := simulatedError(tt.in)
+
+			assert.EqualError(t, err, tt.in)
+			assert.Equal(t, tt.want.permanent, worker.IsPermanent(err))
+			retryAfter, _ := worker.RetryAfter(err)
+			assert.Equal(t, tt.want.retryAfter, retryAfter)
+		})
+	}
+}
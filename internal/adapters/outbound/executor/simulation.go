@@ -0,0 +1,148 @@
+package executor
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
+)
+
+// Simulated latency distributions
+const (
+	LatencyFixed     = "fixed"
+	LatencyUniform   = "uniform"
+	LatencyNormal    = "normal"
+	LatencyLognormal = "lognormal"
+)
+
+// burstState tracks the simulated outages of one job type's dependency
+type burstState struct {
+	nextStart time.Time
+	end       time.Time
+}
+
+// simulatedRun is what the simulation decided for one run of a job
+type simulatedRun struct {
+	latency time.Duration
+	failure string // Failure message; empty when the run succeeds
+}
+
+// simulate waits out the job type's simulated latency and returns the message the run fails
+// with, empty when it succeeds. It returns ctx's error when the job's deadline passes first.
+func (e *DefaultJobExecutor) simulate(ctx context.Context, jobType string) (string, error) {
+	run := e.planRun(jobType, e.now().UTC())
+	if run.latency > 0 {
+		timer := time.NewTimer(run.latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return run.failure, nil
+}
+
+// planRun draws the latency and outcome of a run from the job type's profile, adjusted by the
+// time-of-day window and any burst in progress
+func (e *DefaultJobExecutor) planRun(jobType string, now time.Time) simulatedRun {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.simulation.Enabled {
+		return simulatedRun{}
+	}
+
+	rate := e.simulation.FailureRate
+	profile := e.simulation.Profiles[jobType]
+	if profile.FailureRate > 0 {
+		rate = profile.FailureRate
+	}
+	latency := e.sampleLatency(profile.Latency)
+	if window, ok := windowAt(profile.Daily, now.Hour()); ok {
+		if window.FailureRate > 0 {
+			rate = window.FailureRate
+		}
+		if window.LatencyFactor > 0 {
+			latency = time.Duration(float64(latency) * window.LatencyFactor)
+		}
+	}
+	messages := profile.Errors
+	if e.inBurst(jobType, profile.Bursts, now) {
+		rate = profile.Bursts.FailureRate
+		if len(profile.Bursts.Errors) > 0 {
+			messages = profile.Bursts.Errors
+		}
+	}
+
+	run := simulatedRun{latency: latency}
+	if e.rng.Float64() < rate {
+		run.failure = e.randomError(jobType, messages)
+	}
+	return run
+}
+
+// sampleLatency draws a latency from the distribution, clamped to its bounds
+func (e *DefaultJobExecutor) sampleLatency(latency config.SimulatedLatency) time.Duration {
+	var ms float64
+	switch latency.Distribution {
+	case LatencyUniform:
+		ms = float64(latency.MinMs) + e.rng.Float64()*float64(max(latency.MaxMs-latency.MinMs, 0))
+	case LatencyNormal:
+		ms = float64(latency.MeanMs) + e.rng.NormFloat64()*float64(latency.StdDevMs)
+	case LatencyLognormal:
+		// Parameters of the underlying normal giving the configured mean and deviation
+		if latency.MeanMs > 0 {
+			mean, stdDev := float64(latency.MeanMs), float64(latency.StdDevMs)
+			variance := math.Log(1 + stdDev*stdDev/(mean*mean))
+			ms = math.Exp(math.Log(mean) - variance/2 + math.Sqrt(variance)*e.rng.NormFloat64())
+		}
+	default:
+		ms = float64(latency.MeanMs)
+	}
+	ms = max(ms, float64(latency.MinMs), 0)
+	if latency.MaxMs > 0 {
+		ms = min(ms, float64(latency.MaxMs))
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// inBurst reports whether the job type's dependency is in a simulated outage. Bursts start at
+// random, on average every EverySeconds, and never overlap.
+func (e *DefaultJobExecutor) inBurst(jobType string, bursts config.SimulatedBursts, now time.Time) bool {
+	if bursts.EverySeconds <= 0 || bursts.DurationSeconds <= 0 {
+		return false
+	}
+	duration := time.Duration(bursts.DurationSeconds) * time.Second
+	state, ok := e.bursts[jobType]
+	if !ok {
+		state = &burstState{nextStart: now.Add(e.burstGap(bursts, duration))}
+		e.bursts[jobType] = state
+	}
+	if !now.Before(state.nextStart) {
+		state.end = now.Add(duration)
+		state.nextStart = now.Add(e.burstGap(bursts, duration))
+	}
+	return now.Before(state.end)
+}
+
+// burstGap draws the time from the start of one burst to the next
+func (e *DefaultJobExecutor) burstGap(bursts config.SimulatedBursts, duration time.Duration) time.Duration {
+	gap := time.Duration(e.rng.ExpFloat64() * float64(bursts.EverySeconds) * float64(time.Second))
+	return max(gap, duration)
+}
+
+// windowAt returns the first window covering the hour. A window whose end isn't after its
+// start wraps past midnight, so 22 to 6 covers the night and 0 to 0 the whole day.
+func windowAt(windows []config.SimulationWindow, hour int) (config.SimulationWindow, bool) {
+	for _, window := range windows {
+		if window.StartHour < window.EndHour {
+			if hour >= window.StartHour && hour < window.EndHour {
+				return window, true
+			}
+		} else if hour >= window.StartHour || hour < window.EndHour {
+			return window, true
+		}
+	}
+	return config.SimulationWindow{}, false
+}
@@ -0,0 +1,328 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedRandom draws the same values every time
+type fixedRandom struct {
+	float float64
+	norm  float64
+	exp   float64
+	index int
+}
+
+func (r fixedRandom) Float64() float64     { return r.float }
+func (r fixedRandom) NormFloat64() float64 { return r.norm }
+func (r fixedRandom) ExpFloat64() float64  { return r.exp }
+func (r fixedRandom) Intn(n int) int       { return min(r.index, n-1) }
+
+func newSimulatedExecutor(sim config.SimulationConfig, rng fixedRandom, now time.Time) *DefaultJobExecutor {
+	executor := NewDefaultJobExecutor(&config.Config{Simulation: sim})
+	executor.rng = rng
+	executor.now = func() time.Time { return now }
+	return executor
+}
+
+func TestSampleLatency(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			latency config.SimulatedLatency
+			rng     fixedRandom
+		}
+		want time.Duration
+	}{
+		{
+			name: "Given a fixed latency, When sampling it, Then should return the mean",
+			in: struct {
+				latency config.SimulatedLatency
+				rng     fixedRandom
+			}{latency: config.SimulatedLatency{Distribution: LatencyFixed, MeanMs: 100}},
+			want: 100 * time.Millisecond,
+		},
+		{
+			name: "Given a uniform latency, When sampling it, Then should scale the draw between the bounds",
+			in: struct {
+				latency config.SimulatedLatency
+				rng     fixedRandom
+			}{
+				latency: config.SimulatedLatency{Distribution: LatencyUniform, MinMs: 100, MaxMs: 300},
+				rng:     fixedRandom{float: 0.25},
+			},
+			want: 150 * time.Millisecond,
+		},
+		{
+			name: "Given a normal latency, When sampling it, Then should add the deviations drawn to the mean",
+			in: struct {
+				latency config.SimulatedLatency
+				rng     fixedRandom
+			}{
+				latency: config.SimulatedLatency{Distribution: LatencyNormal, MeanMs: 100, StdDevMs: 20},
+				rng:     fixedRandom{norm: 1.5},
+			},
+			want: 130 * time.Millisecond,
+		},
+		{
+			name: "Given a normal latency drawn above its maximum, When sampling it, Then should clamp it to the maximum",
+			in: struct {
+				latency config.SimulatedLatency
+				rng     fixedRandom
+			}{
+				latency: config.SimulatedLatency{Distribution: LatencyNormal, MeanMs: 100, StdDevMs: 20, MaxMs: 150},
+				rng:     fixedRandom{norm: 10},
+			},
+			want: 150 * time.Millisecond,
+		},
+		{
+			name: "Given a normal latency drawn below its minimum, When sampling it, Then should clamp it to the minimum",
+			in: struct {
+				latency config.SimulatedLatency
+				rng     fixedRandom
+			}{
+				latency: config.SimulatedLatency{Distribution: LatencyNormal, MeanMs: 100, StdDevMs: 20, MinMs: 50},
+				rng:     fixedRandom{norm: -10},
+			},
+			want: 50 * time.Millisecond,
+		},
+		{
+			name: "Given a lognormal latency without deviation, When sampling it, Then should return the mean",
+			in: struct {
+				latency config.SimulatedLatency
+				rng     fixedRandom
+			}{
+				latency: config.SimulatedLatency{Distribution: LatencyLognormal, MeanMs: 100},
+				rng:     fixedRandom{norm: 2},
+			},
+			want: 100 * time.Millisecond,
+		},
+		{
+			name: "Given a lognormal latency, When sampling its median, Then should fall below the mean by the deviation",
+			in: struct {
+				latency config.SimulatedLatency
+				rng     fixedRandom
+			}{latency: config.SimulatedLatency{Distribution: LatencyLognormal, MeanMs: 100, StdDevMs: 100}},
+			// The median of a lognormal is mean/sqrt(1+cv²)
+			want: 70710678 * time.Nanosecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newSimulatedExecutor(config.SimulationConfig{}, tt.in.rng, time.Now())
+
+			got := executor.sampleLatency(tt.in.latency)
+
+			assert.InDelta(t, float64(tt.want), float64(got), float64(time.Microsecond))
+		})
+	}
+}
+
+func TestPlanRun_Daily(t *testing.T) {
+	day := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	profile := config.SimulationProfile{
+		FailureRate: 0.2,
+		Errors:      []string{"profile failure"},
+		Latency:     config.SimulatedLatency{MeanMs: 100},
+		Daily: []config.SimulationWindow{
+			{StartHour: 9, EndHour: 17, FailureRate: 0.5},
+			{StartHour: 22, EndHour: 6, LatencyFactor: 3},
+		},
+	}
+
+	tests := []struct {
+		name string
+		in   int // Hour of the run
+		want simulatedRun
+	}{
+		{
+			name: "Given a run outside every window, When planning it, Then should use the profile",
+			in:   7,
+			want: simulatedRun{latency: 100 * time.Millisecond},
+		},
+		{
+			name: "Given a run in a window with a failure rate, When planning it, Then should fail at the window's rate",
+			in:   12,
+			want: simulatedRun{latency: 100 * time.Millisecond, failure: "profile failure"},
+		},
+		{
+			name: "Given a run at a window's end hour, When planning it, Then should leave the window",
+			in:   17,
+			want: simulatedRun{latency: 100 * time.Millisecond},
+		},
+		{
+			name: "Given a run in a window wrapping past midnight, When planning it, Then should scale the latency",
+			in:   3,
+			want: simulatedRun{latency: 300 * time.Millisecond},
+		},
+		{
+			name: "Given a run before midnight in a wrapping window, When planning it, Then should scale the latency",
+			in:   23,
+			want: simulatedRun{latency: 300 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := day.Add(time.Duration(tt.in) * time.Hour)
+			executor := newSimulatedExecutor(config.SimulationConfig{
+				Enabled:  true,
+				Profiles: map[string]config.SimulationProfile{"email": profile},
+			}, fixedRandom{float: 0.3}, now)
+
+			got := executor.planRun("email", now)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanRun_Bursts(t *testing.T) {
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	profile := config.SimulationProfile{
+		Bursts: config.SimulatedBursts{
+			EverySeconds:    60,
+			DurationSeconds: 10,
+			FailureRate:     1,
+			Errors:          []string{"dependency down"},
+		},
+	}
+	executor := newSimulatedExecutor(config.SimulationConfig{
+		Enabled:  true,
+		Profiles: map[string]config.SimulationProfile{"notification": profile},
+	}, fixedRandom{float: 0.5, exp: 1}, start)
+
+	// With every draw at the mean, bursts start every 60s and last 10s
+	tests := []struct {
+		name string
+		in   time.Duration // Time of the run since the first one
+		want string
+	}{
+		{
+			name: "Given the first run, When planning it, Then should schedule a burst without starting one",
+			in:   0,
+		},
+		{
+			name: "Given a run before the burst, When planning it, Then should succeed",
+			in:   59 * time.Second,
+		},
+		{
+			name: "Given a run once the burst is due, When planning it, Then should fail with the burst's errors",
+			in:   60 * time.Second,
+			want: "dependency down",
+		},
+		{
+			name: "Given a run during the burst, When planning it, Then should keep failing",
+			in:   69 * time.Second,
+			want: "dependency down",
+		},
+		{
+			name: "Given a run after the burst, When planning it, Then should succeed",
+			in:   70 * time.Second,
+		},
+		{
+			name: "Given a run once the next burst is due, When planning it, Then should fail again",
+			in:   120 * time.Second,
+			want: "dependency down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := executor.planRun("notification", start.Add(tt.in))
+
+			assert.Equal(t, tt.want, got.failure)
+		})
+	}
+}
+
+func TestBurstGap(t *testing.T) {
+	bursts := config.SimulatedBursts{EverySeconds: 60, DurationSeconds: 10}
+
+	tests := []struct {
+		name string
+		in   float64 // Exponential draw
+		want time.Duration
+	}{
+		{
+			name: "Given a draw at the mean, When drawing the gap, Then should wait the mean time between bursts",
+			in:   1,
+			want: 60 * time.Second,
+		},
+		{
+			name: "Given a draw shorter than a burst, When drawing the gap, Then should wait for the burst to end",
+			in:   0.01,
+			want: 10 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newSimulatedExecutor(config.SimulationConfig{}, fixedRandom{exp: tt.in}, time.Now())
+
+			got := executor.burstGap(bursts, 10*time.Second)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   config.SimulationConfig
+		want struct {
+			failure string
+			err     error
+		}
+	}{
+		{
+			name: "Given the simulation disabled, When simulating a run, Then should succeed",
+			in:   config.SimulationConfig{FailureRate: 1},
+		},
+		{
+			name: "Given a type without a profile, When simulating a failing run, Then should fail with a built-in message",
+			in:   config.SimulationConfig{Enabled: true, FailureRate: 1},
+			want: struct {
+				failure string
+				err     error
+			}{failure: "failed to connect to SMTP server: connection timeout"},
+		},
+		{
+			name: "Given a latency past the deadline, When simulating a run, Then should return the deadline error",
+			in: config.SimulationConfig{
+				Enabled:  true,
+				Profiles: map[string]config.SimulationProfile{"email": {Latency: config.SimulatedLatency{MeanMs: 60_000}}},
+			},
+			want: struct {
+				failure string
+				err     error
+			}{err: context.DeadlineExceeded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newSimulatedExecutor(tt.in, fixedRandom{float: 0.5}, now)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			failure, err := executor.simulate(ctx, "email")
+
+			if tt.want.err != nil {
+				require.ErrorIs(t, err, tt.want.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.failure, failure)
+		})
+	}
+}
@@ -1,20 +1,44 @@
 package insights
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"time"
 
 	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
 )
 
-// HTTPClient is an adapter that calls a remote insights service via HTTP
+// Retry defaults used when none are configured
+const (
+	DefaultMaxAttempts   = 4
+	DefaultBaseBackoffMs = 500
+	DefaultMaxBackoff    = 30 * time.Second
+)
+
+// breakerKey is the name the client's calls are recorded under in the breaker
+const breakerKey = "insights-api"
+
+// HTTPClient is an adapter that calls a remote insights service via HTTP.
+// Transient failures (network errors, 408, 429 and 5xx) are retried with jittered
+// exponential backoff; an optional breaker stops calling the service while it keeps failing.
 type HTTPClient struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL       string
+	httpClient    *http.Client
+	serviceSecret string
+
+	maxAttempts   int
+	baseBackoffMs int
+	maxBackoff    time.Duration
+	breaker       *worker.FailureBreaker
 }
 
 // NewHTTPClient creates a new HTTP client for the insights service
@@ -24,41 +48,205 @@ func NewHTTPClient(baseURL string) *HTTPClient {
 		httpClient: &http.Client{
 			Timeout: 5 * time.Minute, // Long timeout for AI analysis (first load can be slow)
 		},
+		maxAttempts:   DefaultMaxAttempts,
+		baseBackoffMs: DefaultBaseBackoffMs,
+		maxBackoff:    DefaultMaxBackoff,
+	}
+}
+
+// WithRetry sets the attempts per analysis and the backoff bounds; zero values keep the defaults
+func (c *HTTPClient) WithRetry(maxAttempts, baseBackoffMs int, maxBackoff time.Duration) *HTTPClient {
+	if maxAttempts > 0 {
+		c.maxAttempts = maxAttempts
+	}
+	if baseBackoffMs > 0 {
+		c.baseBackoffMs = baseBackoffMs
+	}
+	if maxBackoff > 0 {
+		c.maxBackoff = maxBackoff
+	}
+	return c
+}
+
+// WithTimeout sets the per-attempt HTTP timeout
+func (c *HTTPClient) WithTimeout(timeout time.Duration) *HTTPClient {
+	if timeout > 0 {
+		c.httpClient.Timeout = timeout
 	}
+	return c
+}
+
+// WithServiceSecret sends the secret shared with the insights service as a bearer token
+func (c *HTTPClient) WithServiceSecret(secret string) *HTTPClient {
+	c.serviceSecret = secret
+	return c
+}
+
+// WithTLS calls the service over TLS with the config, e.g. presenting a client certificate
+// when the service requires mutual TLS; nil keeps the default transport
+func (c *HTTPClient) WithTLS(tlsConfig *tls.Config) *HTTPClient {
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		c.httpClient.Transport = transport
+	}
+	return c
+}
+
+// WithBreaker makes the client fail fast with insights.ErrAIServiceUnavailable while the breaker is open
+func (c *HTTPClient) WithBreaker(breaker *worker.FailureBreaker) *HTTPClient {
+	c.breaker = breaker
+	return c
 }
 
 // Analyze calls the remote insights API to analyze a job failure
 func (c *HTTPClient) Analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
-	// The insights API expects job_id as a query parameter, not in the body
-	url := fmt.Sprintf("%s/api/insights/analyze?job_id=%s", c.baseURL, request.JobID)
+	if c.breaker != nil {
+		if allowed, _ := c.breaker.Allow(breakerKey); !allowed {
+			return nil, fmt.Errorf("%w: circuit open", insights.ErrAIServiceUnavailable)
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= c.maxAttempts; attempt++ {
+		analysis, retryAfter, err := c.analyzeOnce(ctx, request)
+		if err == nil {
+			c.record(false)
+			return analysis, nil
+		}
+		lastErr = err
+
+		var transient *transientError
+		if !errors.As(err, &transient) || ctx.Err() != nil {
+			// The service answered; a rejected request isn't a sign it is down
+			c.record(false)
+			return nil, err
+		}
+		if attempt == c.maxAttempts {
+			break
+		}
+
+		backoff := c.backoff(attempt, retryAfter)
+		slog.WarnContext(ctx, "Insights API call failed, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if c.record(true) {
+		slog.ErrorContext(ctx, "Insights API circuit opened",
+			slog.String("url", c.baseURL),
+		)
+	}
+	return nil, fmt.Errorf("%w: %v", insights.ErrAIServiceUnavailable, lastErr)
+}
+
+// transientError marks failures worth retrying
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// analyzeOnce makes one call; the returned duration is the server's Retry-After, if any
+func (c *HTTPClient) analyzeOnce(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, time.Duration, error) {
+	// The insights API reads the job named by job_id; a standalone service, which can't read the
+	// jobs table, analyzes the failure sent in the body instead
+	url := fmt.Sprintf("%s/api/v1/insights/analyze?job_id=%s", c.baseURL, request.JobID)
+	body, err := json.Marshal(newReportedFailure(request))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if c.serviceSecret != "" {
+		req.Header.Set("Authorization", "Bearer "+c.serviceSecret)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call insights API: %w", err)
+		return nil, 0, &transientError{fmt.Errorf("failed to call insights API: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("insights API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		err := fmt.Errorf("insights API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter, _ := worker.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			return nil, retryAfter, &transientError{err}
+		}
+		return nil, 0, err
+	}
+
+	// The insights API returns an insight; its analysis fields share the AnalysisResponse JSON shape
+	var analysis insights.AnalysisResponse
+	if err := json.NewDecoder(resp.Body).Decode(&analysis); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	var insight insights.Insight
-	if err := json.NewDecoder(resp.Body).Decode(&insight); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	return &analysis, 0, nil
+}
+
+// reportedFailure is the failed job sent to the insights API, in the shape it accepts
+type reportedFailure struct {
+	JobID   string          `json:"job_id"`
+	Queue   string          `json:"queue"`
+	Type    string          `json:"type"`
+	Error   string          `json:"error"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Output  string          `json:"output,omitempty"`
+}
+
+func newReportedFailure(request *insights.AnalysisRequest) reportedFailure {
+	failure := reportedFailure{
+		JobID:  request.JobID,
+		Queue:  request.Queue,
+		Type:   request.JobType,
+		Error:  request.Error,
+		Output: request.Output,
+	}
+	// A payload cut to fit the prompt may no longer be valid JSON, so it is sent as a string
+	if json.Valid([]byte(request.Payload)) {
+		failure.Payload = json.RawMessage(request.Payload)
+	} else if request.Payload != "" {
+		failure.Payload, _ = json.Marshal(request.Payload)
+	}
+	return failure
+}
+
+// backoff returns the exponential backoff for the attempt with "equal jitter" (half fixed,
+// half random) so workers retrying together don't hit the service in lockstep.
+// A longer Retry-After from the server wins.
+func (c *HTTPClient) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	backoff := worker.CalculateBackoff(attempt-1, c.baseBackoffMs)
+	if backoff > c.maxBackoff || backoff <= 0 {
+		backoff = c.maxBackoff
+	}
+	backoff = backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1))
+
+	if retryAfter > backoff {
+		backoff = min(retryAfter, c.maxBackoff)
 	}
+	return backoff
+}
 
-	// Convert the insight to an AnalysisResponse
-	return &insights.AnalysisResponse{
-		Diagnosis:      insight.Diagnosis,
-		Recommendation: insight.Recommendation,
-		SuggestedFix:   insight.SuggestedFix,
-	}, nil
+// record feeds the call outcome to the breaker and reports whether it tripped
+func (c *HTTPClient) record(failed bool) bool {
+	if c.breaker == nil {
+		return false
+	}
+	return c.breaker.Record(breakerKey, failed)
 }
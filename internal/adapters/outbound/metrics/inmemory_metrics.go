@@ -1,57 +1,132 @@
 package metrics
 
 import (
+	"context"
+	"slices"
 	"sync"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 )
 
-// InMemoryMetricsService implements queue.MetricsService with in-memory storage
+// counterKey identifies one counter; category is only set on failures
+type counterKey struct {
+	event    string
+	queue    string
+	jobType  string
+	category string
+}
+
+// durationKey identifies the duration histogram of a job type in a queue
+type durationKey struct {
+	queue   string
+	jobType string
+}
+
+// InMemoryMetricsService implements queue.MetricsService and queue.MetricsReader with in-memory
+// storage, so its counters only cover the process recording them
 type InMemoryMetricsService struct {
-	mu      sync.RWMutex
-	metrics map[string]int64
+	mu        sync.RWMutex
+	counters  map[counterKey]int64
+	durations map[durationKey]*queue.DurationHistogram
 }
 
 // NewInMemoryMetricsService creates a new in-memory metrics service
 func NewInMemoryMetricsService() *InMemoryMetricsService {
 	return &InMemoryMetricsService{
-		metrics: make(map[string]int64),
+		counters:  make(map[counterKey]int64),
+		durations: make(map[durationKey]*queue.DurationHistogram),
 	}
 }
 
-func (s *InMemoryMetricsService) RecordJobCreated(queue, jobType string) {
+func (s *InMemoryMetricsService) record(key counterKey) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := "created:" + queue + ":" + jobType
-	s.metrics[key]++
+	s.counters[key]++
 }
 
-func (s *InMemoryMetricsService) RecordJobCompleted(queue, jobType string, duration float64) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	key := "completed:" + queue + ":" + jobType
-	s.metrics[key]++
+func (s *InMemoryMetricsService) RecordJobCreated(queueName, jobType string) {
+	s.record(counterKey{event: queue.CounterCreated, queue: queueName, jobType: jobType})
 }
 
-func (s *InMemoryMetricsService) RecordJobFailed(queue, jobType string) {
+func (s *InMemoryMetricsService) RecordJobCompleted(queueName, jobType string, duration float64) {
+	s.record(counterKey{event: queue.CounterCompleted, queue: queueName, jobType: jobType})
+	if duration <= 0 {
+		return
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := "failed:" + queue + ":" + jobType
-	s.metrics[key]++
+	key := durationKey{queue: queueName, jobType: jobType}
+	histogram, ok := s.durations[key]
+	if !ok {
+		histogram = queue.NewDurationHistogram(queueName, jobType)
+		s.durations[key] = histogram
+	}
+	histogram.Observe(duration)
 }
 
-func (s *InMemoryMetricsService) RecordJobRetried(queue, jobType string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	key := "retried:" + queue + ":" + jobType
-	s.metrics[key]++
+func (s *InMemoryMetricsService) RecordJobFailed(queueName, jobType string) {
+	s.RecordJobFailedWithReason(queueName, jobType, queue.FailureUnknown)
+}
+
+func (s *InMemoryMetricsService) RecordJobFailedWithReason(queueName, jobType, category string) {
+	s.record(counterKey{event: queue.CounterFailed, queue: queueName, jobType: jobType, category: category})
+}
+
+func (s *InMemoryMetricsService) RecordJobRetried(queueName, jobType string) {
+	s.record(counterKey{event: queue.CounterRetried, queue: queueName, jobType: jobType})
+}
+
+func (s *InMemoryMetricsService) RecordJobParked(queueName, jobType string) {
+	s.record(counterKey{event: queue.CounterParked, queue: queueName, jobType: jobType})
+}
+
+func (s *InMemoryMetricsService) JobCounters(ctx context.Context) ([]*queue.JobCounter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counters := make([]*queue.JobCounter, 0, len(s.counters))
+	for key, count := range s.counters {
+		counters = append(counters, &queue.JobCounter{
+			Event:    key.event,
+			Queue:    key.queue,
+			Type:     key.jobType,
+			Category: key.category,
+			Count:    count,
+		})
+	}
+	return counters, nil
+}
+
+func (s *InMemoryMetricsService) JobDurations(ctx context.Context) ([]*queue.DurationHistogram, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	histograms := make([]*queue.DurationHistogram, 0, len(s.durations))
+	for _, histogram := range s.durations {
+		histograms = append(histograms, &queue.DurationHistogram{
+			Queue:  histogram.Queue,
+			Type:   histogram.Type,
+			Counts: slices.Clone(histogram.Counts),
+			Sum:    histogram.Sum,
+		})
+	}
+	return histograms, nil
 }
 
+// GetMetrics returns the counters keyed "event:queue:type"; failures are also counted
+// per category under "failed:queue:type:category"
 func (s *InMemoryMetricsService) GetMetrics() map[string]int64 {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	result := make(map[string]int64)
-	for k, v := range s.metrics {
-		result[k] = v
+	for key, count := range s.counters {
+		name := key.event + ":" + key.queue + ":" + key.jobType
+		result[name] += count
+		if key.category != "" {
+			result[name+":"+key.category] += count
+		}
 	}
 	return result
 }
@@ -0,0 +1,207 @@
+//go:build integration
+
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// insightStores builds each InsightRepository implementation against the test environment;
+// the jobs stay in Postgres for every store
+var insightStores = []struct {
+	name string
+	new  func(t *testing.T, env *testsupport.Env, jobs queue.JobRepository) insights.InsightRepository
+}{
+	{
+		name: "postgres",
+		new: func(t *testing.T, env *testsupport.Env, _ queue.JobRepository) insights.InsightRepository {
+			return persistence.NewPostgresInsightRepository(env.Postgres.Pool)
+		},
+	},
+	{
+		name: "mongodb",
+		new: func(t *testing.T, _ *testsupport.Env, jobs queue.JobRepository) insights.InsightRepository {
+			repo := persistence.NewMongoInsightRepository(testsupport.StartMongo(t), jobs)
+			require.NoError(t, repo.EnsureIndexes(context.Background()))
+			return repo
+		},
+	},
+}
+
+// TestInsightRepository_Parity runs the same scenario against every insight store
+func TestInsightRepository_Parity(t *testing.T) {
+	for _, store := range insightStores {
+		t.Run(store.name, func(t *testing.T) {
+			env := testsupport.Start(t)
+			ctx := context.Background()
+			jobRepo := persistence.NewPostgresJobRepository(env.Postgres.Pool)
+			repo := store.new(t, env, jobRepo)
+
+			// Given two failed jobs of the queue and a completed one
+			jobs := testsupport.NewJobBuilder().WithQueue("parity").WithStatus(queue.StatusFailed)
+			timedOut, unreachable := jobs.Build(), jobs.Build()
+			slow := jobs.WithStatus(queue.StatusCompleted).Build()
+			for _, job := range []*queue.Job{timedOut, unreachable, slow} {
+				require.NoError(t, jobRepo.Create(ctx, job))
+			}
+
+			// The stores keep timestamps to the millisecond at least
+			now := time.Now().UTC().Truncate(time.Millisecond)
+			retryable := testsupport.NewInsightBuilder(timedOut.ID).
+				WithSuggestedFix(insights.SuggestedFix{TimeoutSeconds: 60, PayloadPatch: map[string]any{"retries": float64(2)}}).
+				WithProvider("ollama").Build()
+			retryable.Kind, retryable.Triage = insights.KindFailure, insights.TriageAutoRetryable
+			retryable.Fingerprint = "fp-timeout"
+			retryable.Usage = &insights.Usage{Provider: "ollama", Model: "mistral", PromptTokens: 100, CompletionTokens: 20, PromptBytes: 400, LatencyMs: 900}
+			retryable.CreatedAt = now.Add(-2 * time.Minute)
+			needsHuman := testsupport.NewInsightBuilder(unreachable.ID).WithDiagnosis("Host unreachable").Build()
+			needsHuman.Kind, needsHuman.Triage = insights.KindFailure, insights.TriageNeedsHuman
+			needsHuman.CreatedAt = now.Add(-time.Minute)
+			performance := testsupport.NewInsightBuilder(slow.ID).WithDiagnosis("Slow query").Build()
+			performance.Kind = insights.KindPerformance
+			performance.CreatedAt = now.Add(-3 * time.Minute)
+			for _, insight := range []*insights.Insight{retryable, needsHuman, performance} {
+				require.NoError(t, repo.Create(ctx, insight))
+			}
+
+			t.Run("Given a stored insight, When reading it by ID, Then should return it unchanged", func(t *testing.T) {
+				got, err := repo.GetByID(ctx, retryable.ID)
+				require.NoError(t, err)
+				assert.Equal(t, inUTC(retryable), inUTC(got))
+
+				_, err = repo.GetByID(ctx, uuid.New())
+				assert.ErrorIs(t, err, insights.ErrInsightNotFound)
+			})
+
+			t.Run("Given insights of every kind, When listing by kind, Then should return those of the kind newest first", func(t *testing.T) {
+				all, err := repo.List(ctx, "", 10, 0)
+				require.NoError(t, err)
+				assert.Equal(t, []uuid.UUID{needsHuman.ID, retryable.ID, performance.ID}, insightIDs(all))
+
+				failures, err := repo.List(ctx, insights.KindFailure, 1, 1)
+				require.NoError(t, err)
+				assert.Equal(t, []uuid.UUID{retryable.ID}, insightIDs(failures))
+			})
+
+			t.Run("Given a failure fingerprint, When finding by it, Then should match the job type and window", func(t *testing.T) {
+				got, err := repo.FindByFingerprint(ctx, timedOut.Type, "fp-timeout", now.Add(-time.Hour))
+				require.NoError(t, err)
+				assert.Equal(t, retryable.ID, got.ID)
+
+				_, err = repo.FindByFingerprint(ctx, "other", "fp-timeout", now.Add(-time.Hour))
+				assert.ErrorIs(t, err, insights.ErrInsightNotFound)
+				_, err = repo.FindByFingerprint(ctx, timedOut.Type, "fp-timeout", now)
+				assert.ErrorIs(t, err, insights.ErrInsightNotFound)
+			})
+
+			t.Run("Given a job linked to an insight, When reading by job, Then should return the latest linked insight", func(t *testing.T) {
+				require.NoError(t, repo.LinkJob(ctx, needsHuman.ID, slow.ID))
+				require.NoError(t, repo.LinkJob(ctx, needsHuman.ID, slow.ID))
+
+				linked, err := repo.LinkedJobs(ctx, needsHuman.ID)
+				require.NoError(t, err)
+				assert.Equal(t, []uuid.UUID{slow.ID}, linked)
+
+				got, err := repo.GetByJobID(ctx, slow.ID)
+				require.NoError(t, err)
+				assert.Equal(t, needsHuman.ID, got.ID)
+			})
+
+			t.Run("Given queue insights, When listing by queue, Then should return the latest failure insight per job", func(t *testing.T) {
+				got, err := repo.ListByQueue(ctx, "parity", now.Add(-time.Hour), 10)
+				require.NoError(t, err)
+				require.Len(t, got, 2)
+				assert.Equal(t, needsHuman.ID, got[0].Insight.ID)
+				assert.Equal(t, retryable.ID, got[1].Insight.ID)
+				assert.Equal(t, timedOut.Type, got[1].JobType)
+			})
+
+			t.Run("Given triaged DLQ jobs, When listing by actionability, Then should put auto-retryable jobs first", func(t *testing.T) {
+				got, err := repo.ListDLQWithInsights(ctx, 10, 0, insights.DLQOrderActionability)
+				require.NoError(t, err)
+				require.Len(t, got, 2)
+				assert.Equal(t, timedOut.ID, got[0].Job.ID)
+				assert.Equal(t, retryable.ID, got[0].Insight.ID)
+				assert.Equal(t, needsHuman.ID, got[1].Insight.ID)
+
+				page, err := repo.ListDLQWithInsights(ctx, 1, 1, insights.DLQOrderActionability)
+				require.NoError(t, err)
+				require.Len(t, page, 1)
+				assert.Equal(t, unreachable.ID, page[0].Job.ID)
+			})
+
+			t.Run("Given applied fixes, When aggregating, Then should count outcomes per job type and fix kind", func(t *testing.T) {
+				for _, jobID := range []uuid.UUID{timedOut.ID, unreachable.ID} {
+					require.NoError(t, repo.RecordFixApplication(ctx, &insights.FixApplication{
+						ID: uuid.New(), InsightID: retryable.ID, JobID: jobID, PayloadBefore: []byte(`{}`),
+						StatusBefore: queue.StatusFailed, StatusAfter: queue.StatusPending,
+						JobType: timedOut.Type, FixKind: "timeout", Outcome: insights.FixOutcomePending, AppliedAt: now,
+					}))
+				}
+				require.NoError(t, repo.RecordFixOutcome(ctx, timedOut.ID, insights.FixOutcomeSucceeded, now))
+
+				got, err := repo.FixEffectiveness(ctx, "", now.Add(-time.Hour))
+				require.NoError(t, err)
+				assert.Equal(t, []*insights.FixEffectiveness{
+					{JobType: timedOut.Type, FixKind: "timeout", Applied: 2, Succeeded: 1, Failed: 0},
+				}, got)
+			})
+
+			t.Run("Given recorded usage, When summarizing, Then should aggregate it per day, provider and model", func(t *testing.T) {
+				got, err := repo.UsageSummary(ctx, now.Add(-time.Hour))
+				require.NoError(t, err)
+				require.Len(t, got, 1)
+				assert.Equal(t, "mistral", got[0].Model)
+				assert.Equal(t, int64(1), got[0].Calls)
+				assert.Equal(t, int64(100), got[0].PromptTokens)
+				assert.Equal(t, float64(900), got[0].AvgLatencyMs)
+			})
+
+			t.Run("Given an edited insight, When updating and deleting it, Then should store the edit and then remove it", func(t *testing.T) {
+				edited := *retryable
+				editedAt := now
+				edited.Recommendation, edited.AIRecommendation = "Raise the timeout to 90s", retryable.Recommendation
+				edited.Note, edited.EditedBy, edited.EditedAt = "Checked with the SMTP team", "ana", &editedAt
+				require.NoError(t, repo.Update(ctx, &edited))
+
+				got, err := repo.GetByID(ctx, retryable.ID)
+				require.NoError(t, err)
+				assert.Equal(t, inUTC(&edited), inUTC(got))
+
+				require.NoError(t, repo.Delete(ctx, retryable.ID))
+				_, err = repo.GetByID(ctx, retryable.ID)
+				assert.ErrorIs(t, err, insights.ErrInsightNotFound)
+				assert.ErrorIs(t, repo.Update(ctx, &edited), insights.ErrInsightNotFound)
+			})
+		})
+	}
+}
+
+func insightIDs(list []*insights.Insight) []uuid.UUID {
+	ids := make([]uuid.UUID, len(list))
+	for i, insight := range list {
+		ids[i] = insight.ID
+	}
+	return ids
+}
+
+// inUTC returns a copy of the insight with its times in UTC, whatever location a store reads them in
+func inUTC(insight *insights.Insight) *insights.Insight {
+	copied := *insight
+	copied.CreatedAt = copied.CreatedAt.UTC()
+	if copied.EditedAt != nil {
+		editedAt := copied.EditedAt.UTC()
+		copied.EditedAt = &editedAt
+	}
+	return &copied
+}
@@ -0,0 +1,46 @@
+//go:build integration
+
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresJobRepository_PurgeDeleted(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+	jobRepo := persistence.NewPostgresJobRepository(env.Postgres.Pool)
+	insightRepo := persistence.NewPostgresInsightRepository(env.Postgres.Pool)
+
+	// Given a soft-deleted failed job with an insight, and a live one
+	jobs := testsupport.NewJobBuilder().WithQueue("purge").WithStatus(queue.StatusFailed)
+	deleted, live := jobs.Build(), jobs.Build()
+	for _, job := range []*queue.Job{deleted, live} {
+		require.NoError(t, jobRepo.Create(ctx, job))
+	}
+	insight := testsupport.NewInsightBuilder(deleted.ID).Build()
+	require.NoError(t, insightRepo.Create(ctx, insight))
+	require.NoError(t, jobRepo.Delete(ctx, deleted.ID))
+
+	// When purging the jobs deleted before now
+	purged, err := jobRepo.PurgeDeleted(ctx, time.Now().UTC().Add(time.Minute))
+
+	// Then should remove the job and its insight, and keep the live job
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, err = jobRepo.GetByID(ctx, deleted.ID)
+	assert.ErrorIs(t, err, queue.ErrJobNotFound)
+	_, err = insightRepo.GetByID(ctx, insight.ID)
+	assert.ErrorIs(t, err, insights.ErrInsightNotFound)
+	_, err = jobRepo.GetByID(ctx, live.ID)
+	assert.NoError(t, err)
+}
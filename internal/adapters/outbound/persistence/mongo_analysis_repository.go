@@ -0,0 +1,147 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoAnalysisRepository implements insights.AnalysisRepository using MongoDB, next to the
+// insights of a MongoInsightRepository
+type MongoAnalysisRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAnalysisRepository creates a new MongoDB analysis repository
+func NewMongoAnalysisRepository(db *mongo.Database) *MongoAnalysisRepository {
+	return &MongoAnalysisRepository{collection: db.Collection(mongoAnalysesCollection)}
+}
+
+// mongoAnalysis is an analysis as stored in the insight_analyses collection
+type mongoAnalysis struct {
+	ID          string    `bson:"_id"`
+	JobID       string    `bson:"job_id"`
+	Status      string    `bson:"status"`
+	InsightID   string    `bson:"insight_id,omitempty"`
+	Error       string    `bson:"error"`
+	CallbackURL string    `bson:"callback_url"`
+	CreatedAt   time.Time `bson:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at"`
+}
+
+// EnsureIndexes creates the index ListPending relies on
+func (r *MongoAnalysisRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	return err
+}
+
+func (r *MongoAnalysisRepository) Create(ctx context.Context, analysis *insights.Analysis) error {
+	_, err := r.collection.InsertOne(ctx, newMongoAnalysis(analysis))
+	return err
+}
+
+func (r *MongoAnalysisRepository) GetByID(ctx context.Context, id uuid.UUID) (*insights.Analysis, error) {
+	var doc mongoAnalysis
+	err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id.String()}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, insights.ErrAnalysisNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.analysis()
+}
+
+func (r *MongoAnalysisRepository) Update(ctx context.Context, analysis *insights.Analysis) error {
+	doc := newMongoAnalysis(analysis)
+	result, err := r.collection.UpdateByID(ctx, doc.ID, bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: doc.Status},
+		{Key: "insight_id", Value: doc.InsightID},
+		{Key: "error", Value: doc.Error},
+		{Key: "updated_at", Value: doc.UpdatedAt},
+	}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return insights.ErrAnalysisNotFound
+	}
+	return nil
+}
+
+func (r *MongoAnalysisRepository) ListPending(ctx context.Context, updatedBefore time.Time, limit int) ([]*insights.Analysis, error) {
+	cursor, err := r.collection.Find(ctx,
+		bson.D{
+			{Key: "status", Value: string(insights.AnalysisPending)},
+			{Key: "updated_at", Value: bson.D{{Key: "$lt", Value: updatedBefore}}},
+		},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var analyses []*insights.Analysis
+	for cursor.Next(ctx) {
+		var doc mongoAnalysis
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		analysis, err := doc.analysis()
+		if err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, analysis)
+	}
+	return analyses, cursor.Err()
+}
+
+func newMongoAnalysis(analysis *insights.Analysis) mongoAnalysis {
+	doc := mongoAnalysis{
+		ID:          analysis.ID.String(),
+		JobID:       analysis.JobID.String(),
+		Status:      string(analysis.Status),
+		Error:       analysis.Error,
+		CallbackURL: analysis.CallbackURL,
+		CreatedAt:   analysis.CreatedAt,
+		UpdatedAt:   analysis.UpdatedAt,
+	}
+	if analysis.InsightID != nil {
+		doc.InsightID = analysis.InsightID.String()
+	}
+	return doc
+}
+
+func (d *mongoAnalysis) analysis() (*insights.Analysis, error) {
+	analysis := &insights.Analysis{
+		Status:      insights.AnalysisStatus(d.Status),
+		Error:       d.Error,
+		CallbackURL: d.CallbackURL,
+		CreatedAt:   d.CreatedAt.UTC(),
+		UpdatedAt:   d.UpdatedAt.UTC(),
+	}
+	var err error
+	if analysis.ID, err = uuid.Parse(d.ID); err != nil {
+		return nil, err
+	}
+	if analysis.JobID, err = uuid.Parse(d.JobID); err != nil {
+		return nil, err
+	}
+	if d.InsightID != "" {
+		insightID, err := uuid.Parse(d.InsightID)
+		if err != nil {
+			return nil, err
+		}
+		analysis.InsightID = &insightID
+	}
+	return analysis, nil
+}
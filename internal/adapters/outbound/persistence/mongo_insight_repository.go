@@ -0,0 +1,724 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoDB collections of the insights store
+const (
+	mongoInsightsCollection        = "insights"
+	mongoInsightJobsCollection     = "insight_jobs"
+	mongoFixApplicationsCollection = "insight_fix_applications"
+	mongoAnalysesCollection        = "insight_analyses"
+)
+
+// MongoInsightRepository implements insights.InsightRepository using MongoDB. Jobs stay in
+// Postgres: the type and queue of an insight's job are copied onto the insight when it's
+// created, and the DLQ listing reads its jobs from the job repository.
+type MongoInsightRepository struct {
+	db   *mongo.Database
+	jobs queue.JobRepository
+}
+
+// NewMongoInsightRepository creates a new MongoDB insight repository
+func NewMongoInsightRepository(db *mongo.Database, jobs queue.JobRepository) *MongoInsightRepository {
+	return &MongoInsightRepository{db: db, jobs: jobs}
+}
+
+// mongoInsight is an insight as stored in the insights collection. The suggested fix and the
+// usage are stored as documents encoded like the JSONB columns of the Postgres store.
+type mongoInsight struct {
+	ID               string     `bson:"_id"`
+	JobID            string     `bson:"job_id"`
+	JobType          string     `bson:"job_type"`
+	Queue            string     `bson:"queue"`
+	Kind             string     `bson:"kind"`
+	Diagnosis        string     `bson:"diagnosis"`
+	Recommendation   string     `bson:"recommendation"`
+	SuggestedFix     bson.Raw   `bson:"suggested_fix,omitempty"`
+	Confidence       float64    `bson:"confidence"`
+	Provider         string     `bson:"provider"`
+	Usage            bson.Raw   `bson:"usage,omitempty"`
+	Triage           string     `bson:"triage_label"`
+	AIRecommendation string     `bson:"ai_recommendation"`
+	Fingerprint      string     `bson:"error_fingerprint"`
+	SharedFrom       string     `bson:"shared_from,omitempty"`
+	Note             string     `bson:"note"`
+	EditedBy         string     `bson:"edited_by"`
+	EditedAt         *time.Time `bson:"edited_at,omitempty"`
+	CreatedAt        time.Time  `bson:"created_at"`
+}
+
+// EnsureIndexes creates the indexes the repository's queries rely on; it's a no-op for
+// indexes that already exist
+func (r *MongoInsightRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := map[string][]mongo.IndexModel{
+		mongoInsightsCollection: {
+			{Keys: bson.D{{Key: "job_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "queue", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "error_fingerprint", Value: 1}, {Key: "job_type", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+		mongoInsightJobsCollection: {
+			{Keys: bson.D{{Key: "insight_id", Value: 1}, {Key: "job_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "job_id", Value: 1}}},
+		},
+		mongoFixApplicationsCollection: {
+			{Keys: bson.D{{Key: "job_id", Value: 1}, {Key: "outcome", Value: 1}}},
+			{Keys: bson.D{{Key: "applied_at", Value: 1}}},
+		},
+	}
+	for collection, models := range indexes {
+		if _, err := r.db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MongoInsightRepository) insights() *mongo.Collection {
+	return r.db.Collection(mongoInsightsCollection)
+}
+
+// Create fails with queue.ErrJobNotFound when the insight's job doesn't exist, like the
+// foreign key of the Postgres store
+func (r *MongoInsightRepository) Create(ctx context.Context, insight *insights.Insight) error {
+	job, err := r.jobs.GetByID(ctx, insight.JobID)
+	if err != nil {
+		return err
+	}
+
+	doc := mongoInsight{
+		ID:             insight.ID.String(),
+		JobID:          insight.JobID.String(),
+		JobType:        job.Type,
+		Queue:          job.Queue,
+		Kind:           string(insights.KindOf(insight)),
+		Diagnosis:      insight.Diagnosis,
+		Recommendation: insight.Recommendation,
+		Confidence:     insight.Confidence,
+		Provider:       insight.Provider,
+		Triage:         string(insight.Triage),
+		Fingerprint:    insight.Fingerprint,
+		CreatedAt:      insight.CreatedAt,
+	}
+	if insight.SharedFrom != nil {
+		doc.SharedFrom = insight.SharedFrom.String()
+	}
+	if doc.SuggestedFix, err = jsonDocument(insight.SuggestedFix); err != nil {
+		return err
+	}
+	if insight.Usage != nil {
+		if doc.Usage, err = jsonDocument(insight.Usage); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.insights().InsertOne(ctx, doc)
+	return err
+}
+
+func (r *MongoInsightRepository) GetByID(ctx context.Context, id uuid.UUID) (*insights.Insight, error) {
+	return r.findOne(ctx, bson.D{{Key: "_id", Value: id.String()}})
+}
+
+// GetByJobID returns the latest insight analyzed for the job or linked to it
+func (r *MongoInsightRepository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*insights.Insight, error) {
+	linked, err := r.linkedInsights(ctx, []string{jobID.String()})
+	if err != nil {
+		return nil, err
+	}
+	return r.findOne(ctx, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "job_id", Value: jobID.String()}},
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: append([]string{}, linked[jobID.String()]...)}}}},
+	}}})
+}
+
+func (r *MongoInsightRepository) List(ctx context.Context, kind insights.Kind, limit, offset int) ([]*insights.Insight, error) {
+	filter := bson.D{}
+	if kind != "" {
+		filter = bson.D{{Key: "kind", Value: string(kind)}}
+	}
+	cursor, err := r.insights().Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	return decodeInsights(ctx, cursor)
+}
+
+func (r *MongoInsightRepository) Update(ctx context.Context, insight *insights.Insight) error {
+	result, err := r.insights().UpdateByID(ctx, insight.ID.String(), bson.D{{Key: "$set", Value: bson.D{
+		{Key: "recommendation", Value: insight.Recommendation},
+		{Key: "ai_recommendation", Value: insight.AIRecommendation},
+		{Key: "note", Value: insight.Note},
+		{Key: "edited_by", Value: insight.EditedBy},
+		{Key: "edited_at", Value: insight.EditedAt},
+	}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return insights.ErrInsightNotFound
+	}
+	return nil
+}
+
+// Delete removes the insight with its job links and fix applications, which the Postgres
+// store removes by cascade
+func (r *MongoInsightRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.insights().DeleteOne(ctx, bson.D{{Key: "_id", Value: id.String()}}); err != nil {
+		return err
+	}
+	byInsight := bson.D{{Key: "insight_id", Value: id.String()}}
+	if _, err := r.db.Collection(mongoInsightJobsCollection).DeleteMany(ctx, byInsight); err != nil {
+		return err
+	}
+	_, err := r.db.Collection(mongoFixApplicationsCollection).DeleteMany(ctx, byInsight)
+	return err
+}
+
+// ListDLQWithInsights reads the page of DLQ jobs from the job repository and their latest
+// insights from MongoDB. Ordering by actionability needs every DLQ job's insight, so that
+// order reads the whole DLQ and pages it here.
+func (r *MongoInsightRepository) ListDLQWithInsights(ctx context.Context, limit, offset int, order string) ([]*insights.JobWithInsight, error) {
+	if order != insights.DLQOrderActionability {
+		jobs, err := r.jobs.GetDLQJobs(ctx, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		return r.withLatestInsights(ctx, jobs)
+	}
+
+	total, err := r.jobs.CountDLQJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := r.jobs.GetDLQJobs(ctx, int(total), 0)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.withLatestInsights(ctx, jobs)
+	if err != nil {
+		return nil, err
+	}
+	// The jobs come most recently failed first, which the stable sort keeps within a rank
+	sort.SliceStable(entries, func(i, j int) bool {
+		return triageRank(entries[i]) < triageRank(entries[j])
+	})
+	if offset >= len(entries) {
+		return nil, nil
+	}
+	return entries[offset:min(offset+limit, len(entries))], nil
+}
+
+func triageRank(entry *insights.JobWithInsight) int {
+	if entry.Insight == nil {
+		return insights.TriageLabel("").Rank()
+	}
+	return entry.Insight.Triage.Rank()
+}
+
+// withLatestInsights pairs each job with the latest insight analyzed for it or linked to it
+func (r *MongoInsightRepository) withLatestInsights(ctx context.Context, jobs []*queue.Job) ([]*insights.JobWithInsight, error) {
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	jobIDs := make([]string, len(jobs))
+	for i, job := range jobs {
+		jobIDs[i] = job.ID.String()
+	}
+	linked, err := r.linkedInsights(ctx, jobIDs)
+	if err != nil {
+		return nil, err
+	}
+	linkedIDs := []string{} // $in needs an array, which a nil slice isn't encoded as
+	for _, ids := range linked {
+		linkedIDs = append(linkedIDs, ids...)
+	}
+
+	cursor, err := r.insights().Find(ctx, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "job_id", Value: bson.D{{Key: "$in", Value: jobIDs}}}},
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: linkedIDs}}}},
+	}}}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	found, err := decodeInsights(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// found is newest first, so the first insight seen for a job is its latest
+	latest := make(map[string]*insights.Insight, len(jobs))
+	keep := func(jobID string, insight *insights.Insight) {
+		if _, seen := latest[jobID]; !seen {
+			latest[jobID] = insight
+		}
+	}
+	linkedTo := make(map[string][]string)
+	for jobID, insightIDs := range linked {
+		for _, insightID := range insightIDs {
+			linkedTo[insightID] = append(linkedTo[insightID], jobID)
+		}
+	}
+	for _, insight := range found {
+		keep(insight.JobID.String(), insight)
+		for _, jobID := range linkedTo[insight.ID.String()] {
+			keep(jobID, insight)
+		}
+	}
+
+	result := make([]*insights.JobWithInsight, len(jobs))
+	for i, job := range jobs {
+		result[i] = &insights.JobWithInsight{Job: job, Insight: latest[job.ID.String()]}
+	}
+	return result, nil
+}
+
+// mongoFixApplication is a fix application as stored in the insight_fix_applications collection
+type mongoFixApplication struct {
+	ID            string     `bson:"_id"`
+	InsightID     string     `bson:"insight_id"`
+	JobID         string     `bson:"job_id"`
+	PayloadBefore string     `bson:"payload_before,omitempty"`
+	PayloadAfter  string     `bson:"payload_after,omitempty"`
+	StatusBefore  string     `bson:"status_before"`
+	StatusAfter   string     `bson:"status_after"`
+	JobType       string     `bson:"job_type"`
+	FixKind       string     `bson:"fix_kind"`
+	Outcome       string     `bson:"outcome"`
+	AppliedAt     time.Time  `bson:"applied_at"`
+	ResolvedAt    *time.Time `bson:"resolved_at,omitempty"`
+}
+
+// RecordFixApplication stores the audit entry for an applied suggested fix
+func (r *MongoInsightRepository) RecordFixApplication(ctx context.Context, application *insights.FixApplication) error {
+	_, err := r.db.Collection(mongoFixApplicationsCollection).InsertOne(ctx, mongoFixApplication{
+		ID:            application.ID.String(),
+		InsightID:     application.InsightID.String(),
+		JobID:         application.JobID.String(),
+		PayloadBefore: string(application.PayloadBefore),
+		PayloadAfter:  string(application.PayloadAfter),
+		StatusBefore:  string(application.StatusBefore),
+		StatusAfter:   string(application.StatusAfter),
+		JobType:       application.JobType,
+		FixKind:       application.FixKind,
+		Outcome:       string(application.Outcome),
+		AppliedAt:     application.AppliedAt,
+		ResolvedAt:    application.ResolvedAt,
+	})
+	return err
+}
+
+// FixApplications returns the audit entries of the fixes applied from the insight, oldest first
+func (r *MongoInsightRepository) FixApplications(ctx context.Context, insightID uuid.UUID) ([]*insights.FixApplication, error) {
+	cursor, err := r.db.Collection(mongoFixApplicationsCollection).Find(ctx,
+		bson.D{{Key: "insight_id", Value: insightID.String()}},
+		options.Find().SetSort(bson.D{{Key: "applied_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var applications []*insights.FixApplication
+	for cursor.Next(ctx) {
+		var doc mongoFixApplication
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		application, err := doc.application()
+		if err != nil {
+			return nil, err
+		}
+		applications = append(applications, application)
+	}
+	return applications, cursor.Err()
+}
+
+func (d *mongoFixApplication) application() (*insights.FixApplication, error) {
+	application := &insights.FixApplication{
+		StatusBefore: queue.Status(d.StatusBefore),
+		StatusAfter:  queue.Status(d.StatusAfter),
+		JobType:      d.JobType,
+		FixKind:      d.FixKind,
+		Outcome:      insights.FixOutcome(d.Outcome),
+		AppliedAt:    d.AppliedAt.UTC(),
+		ResolvedAt:   d.ResolvedAt,
+	}
+	if d.PayloadBefore != "" {
+		application.PayloadBefore = []byte(d.PayloadBefore)
+	}
+	if d.PayloadAfter != "" {
+		application.PayloadAfter = []byte(d.PayloadAfter)
+	}
+	var err error
+	if application.ID, err = uuid.Parse(d.ID); err != nil {
+		return nil, err
+	}
+	if application.InsightID, err = uuid.Parse(d.InsightID); err != nil {
+		return nil, err
+	}
+	if application.JobID, err = uuid.Parse(d.JobID); err != nil {
+		return nil, err
+	}
+	return application, nil
+}
+
+// RecordFixOutcome resolves the pending fix applications of a job with how its run went
+func (r *MongoInsightRepository) RecordFixOutcome(ctx context.Context, jobID uuid.UUID, outcome insights.FixOutcome, at time.Time) error {
+	_, err := r.db.Collection(mongoFixApplicationsCollection).UpdateMany(ctx,
+		bson.D{{Key: "job_id", Value: jobID.String()}, {Key: "outcome", Value: string(insights.FixOutcomePending)}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "outcome", Value: string(outcome)}, {Key: "resolved_at", Value: at}}}},
+	)
+	return err
+}
+
+// FixEffectiveness aggregates fix outcomes per job type and fix kind, most applied first
+func (r *MongoInsightRepository) FixEffectiveness(ctx context.Context, jobType string, since time.Time) ([]*insights.FixEffectiveness, error) {
+	match := bson.D{
+		{Key: "applied_at", Value: bson.D{{Key: "$gte", Value: since}}},
+		{Key: "fix_kind", Value: bson.D{{Key: "$ne", Value: ""}}},
+	}
+	if jobType != "" {
+		match = append(match, bson.E{Key: "job_type", Value: jobType})
+	}
+	countOutcome := func(outcome insights.FixOutcome) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$outcome", string(outcome)}}}, 1, 0,
+		}}}}}
+	}
+	cursor, err := r.db.Collection(mongoFixApplicationsCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "job_type", Value: "$job_type"}, {Key: "fix_kind", Value: "$fix_kind"}}},
+			{Key: "applied", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "succeeded", Value: countOutcome(insights.FixOutcomeSucceeded)},
+			{Key: "failed", Value: countOutcome(insights.FixOutcomeFailed)},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "applied", Value: -1}, {Key: "_id.job_type", Value: 1}, {Key: "_id.fix_kind", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var effectiveness []*insights.FixEffectiveness
+	for cursor.Next(ctx) {
+		var row struct {
+			Key struct {
+				JobType string `bson:"job_type"`
+				FixKind string `bson:"fix_kind"`
+			} `bson:"_id"`
+			Applied   int64 `bson:"applied"`
+			Succeeded int64 `bson:"succeeded"`
+			Failed    int64 `bson:"failed"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		effectiveness = append(effectiveness, &insights.FixEffectiveness{
+			JobType:   row.Key.JobType,
+			FixKind:   row.Key.FixKind,
+			Applied:   row.Applied,
+			Succeeded: row.Succeeded,
+			Failed:    row.Failed,
+		})
+	}
+	return effectiveness, cursor.Err()
+}
+
+// UsageSummary aggregates AI usage recorded on insights per day, provider and model
+func (r *MongoInsightRepository) UsageSummary(ctx context.Context, since time.Time) ([]*insights.UsageAggregate, error) {
+	sum := func(field string) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$usage." + field, 0}}}}}
+	}
+	cursor, err := r.insights().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "usage", Value: bson.D{{Key: "$exists", Value: true}}},
+			{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "day", Value: bson.D{{Key: "$dateTrunc", Value: bson.D{{Key: "date", Value: "$created_at"}, {Key: "unit", Value: "day"}}}}},
+				{Key: "provider", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$usage.provider", ""}}}},
+				{Key: "model", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$usage.model", ""}}}},
+			}},
+			{Key: "calls", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "prompt_tokens", Value: sum("prompt_tokens")},
+			{Key: "completion_tokens", Value: sum("completion_tokens")},
+			{Key: "prompt_bytes", Value: sum("prompt_bytes")},
+			{Key: "avg_latency_ms", Value: bson.D{{Key: "$avg", Value: "$usage.latency_ms"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.day", Value: -1}, {Key: "_id.provider", Value: 1}, {Key: "_id.model", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var summary []*insights.UsageAggregate
+	for cursor.Next(ctx) {
+		var row struct {
+			Key struct {
+				Day      time.Time `bson:"day"`
+				Provider string    `bson:"provider"`
+				Model    string    `bson:"model"`
+			} `bson:"_id"`
+			Calls            int64    `bson:"calls"`
+			PromptTokens     int64    `bson:"prompt_tokens"`
+			CompletionTokens int64    `bson:"completion_tokens"`
+			PromptBytes      int64    `bson:"prompt_bytes"`
+			AvgLatencyMs     *float64 `bson:"avg_latency_ms"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		agg := &insights.UsageAggregate{
+			Day:              row.Key.Day.UTC(),
+			Provider:         row.Key.Provider,
+			Model:            row.Key.Model,
+			Calls:            row.Calls,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			PromptBytes:      row.PromptBytes,
+		}
+		if row.AvgLatencyMs != nil {
+			agg.AvgLatencyMs = *row.AvgLatencyMs
+		}
+		summary = append(summary, agg)
+	}
+	return summary, cursor.Err()
+}
+
+// ListByQueue returns the latest failure insight of each of the queue's jobs, or of every job
+// when the queue is empty, skipping jobs deleted since they were analyzed
+func (r *MongoInsightRepository) ListByQueue(ctx context.Context, queueName string, since time.Time, limit int) ([]*insights.QueueInsight, error) {
+	match := bson.D{
+		{Key: "kind", Value: string(insights.KindFailure)},
+		{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}},
+	}
+	if queueName != "" {
+		match = append(match, bson.E{Key: "queue", Value: queueName})
+	}
+	cursor, err := r.insights().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$job_id"}, {Key: "latest", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}}}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$latest"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []*insights.QueueInsight
+	for len(result) < limit && cursor.Next(ctx) {
+		var doc mongoInsight
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		insight, err := doc.insight()
+		if err != nil {
+			return nil, err
+		}
+		job, err := r.jobs.GetByID(ctx, insight.JobID)
+		if errors.Is(err, queue.ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if job.IsDeleted() {
+			continue
+		}
+		result = append(result, &insights.QueueInsight{JobType: doc.JobType, Insight: insight})
+	}
+	return result, cursor.Err()
+}
+
+// FindByFingerprint matches the fingerprint stored on the insight, that of the failure it was
+// analyzed for, and the type of the job it was analyzed for
+func (r *MongoInsightRepository) FindByFingerprint(ctx context.Context, jobType, fingerprint string, since time.Time) (*insights.Insight, error) {
+	return r.findOne(ctx, bson.D{
+		{Key: "error_fingerprint", Value: fingerprint},
+		{Key: "job_type", Value: jobType},
+		{Key: "kind", Value: string(insights.KindFailure)},
+		{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}},
+	})
+}
+
+func (r *MongoInsightRepository) LinkJob(ctx context.Context, insightID, jobID uuid.UUID) error {
+	link := bson.D{{Key: "insight_id", Value: insightID.String()}, {Key: "job_id", Value: jobID.String()}}
+	_, err := r.db.Collection(mongoInsightJobsCollection).UpdateOne(ctx, link,
+		bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "linked_at", Value: time.Now().UTC()}}}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *MongoInsightRepository) LinkedJobs(ctx context.Context, insightID uuid.UUID) ([]uuid.UUID, error) {
+	cursor, err := r.db.Collection(mongoInsightJobsCollection).Find(ctx,
+		bson.D{{Key: "insight_id", Value: insightID.String()}},
+		options.Find().SetSort(bson.D{{Key: "linked_at", Value: 1}, {Key: "job_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobIDs []uuid.UUID
+	for cursor.Next(ctx) {
+		var link struct {
+			JobID string `bson:"job_id"`
+		}
+		if err := cursor.Decode(&link); err != nil {
+			return nil, err
+		}
+		jobID, err := uuid.Parse(link.JobID)
+		if err != nil {
+			return nil, err
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, cursor.Err()
+}
+
+// linkedInsights returns the IDs of the insights linked to each of the jobs
+func (r *MongoInsightRepository) linkedInsights(ctx context.Context, jobIDs []string) (map[string][]string, error) {
+	cursor, err := r.db.Collection(mongoInsightJobsCollection).Find(ctx,
+		bson.D{{Key: "job_id", Value: bson.D{{Key: "$in", Value: jobIDs}}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	linked := make(map[string][]string)
+	for cursor.Next(ctx) {
+		var link struct {
+			InsightID string `bson:"insight_id"`
+			JobID     string `bson:"job_id"`
+		}
+		if err := cursor.Decode(&link); err != nil {
+			return nil, err
+		}
+		linked[link.JobID] = append(linked[link.JobID], link.InsightID)
+	}
+	return linked, cursor.Err()
+}
+
+// findOne returns the newest insight matching the filter
+func (r *MongoInsightRepository) findOne(ctx context.Context, filter bson.D) (*insights.Insight, error) {
+	var doc mongoInsight
+	err := r.insights().FindOne(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, insights.ErrInsightNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.insight()
+}
+
+func decodeInsights(ctx context.Context, cursor *mongo.Cursor) ([]*insights.Insight, error) {
+	defer cursor.Close(ctx)
+
+	var insightsList []*insights.Insight
+	for cursor.Next(ctx) {
+		var doc mongoInsight
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		insight, err := doc.insight()
+		if err != nil {
+			return nil, err
+		}
+		insightsList = append(insightsList, insight)
+	}
+	return insightsList, cursor.Err()
+}
+
+func (d *mongoInsight) insight() (*insights.Insight, error) {
+	insight := &insights.Insight{
+		Kind:             insights.Kind(d.Kind),
+		Diagnosis:        d.Diagnosis,
+		Recommendation:   d.Recommendation,
+		Confidence:       d.Confidence,
+		Provider:         d.Provider,
+		Triage:           insights.TriageLabel(d.Triage),
+		AIRecommendation: d.AIRecommendation,
+		Fingerprint:      d.Fingerprint,
+		Note:             d.Note,
+		EditedBy:         d.EditedBy,
+		CreatedAt:        d.CreatedAt.UTC(),
+	}
+	var err error
+	if insight.ID, err = uuid.Parse(d.ID); err != nil {
+		return nil, err
+	}
+	if insight.JobID, err = uuid.Parse(d.JobID); err != nil {
+		return nil, err
+	}
+	if d.SharedFrom != "" {
+		sharedFrom, err := uuid.Parse(d.SharedFrom)
+		if err != nil {
+			return nil, err
+		}
+		insight.SharedFrom = &sharedFrom
+	}
+	if d.EditedAt != nil {
+		editedAt := d.EditedAt.UTC()
+		insight.EditedAt = &editedAt
+	}
+
+	suggestedFixJSON, err := documentJSON(d.SuggestedFix)
+	if err != nil {
+		return nil, err
+	}
+	usageJSON, err := documentJSON(d.Usage)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeInsightJSON(insight, suggestedFixJSON, usageJSON); err != nil {
+		return nil, err
+	}
+	return insight, nil
+}
+
+// jsonDocument encodes the value as a document with the field names of its JSON encoding,
+// the way the Postgres store keeps it in a JSONB column
+func jsonDocument(v any) (bson.Raw, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.Raw
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// documentJSON decodes a document stored by jsonDocument back to JSON; nil for none
+func documentJSON(doc bson.Raw) ([]byte, error) {
+	if len(doc) == 0 {
+		return nil, nil
+	}
+	return bson.MarshalExtJSON(doc, false, false)
+}
@@ -0,0 +1,136 @@
+package persistence
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Payload compression algorithms accepted by NewPayloadCompressor
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// DefaultCompressionThreshold is the payload size from which payloads are compressed when no
+// threshold is configured. Smaller payloads rarely shrink enough to pay for the CPU.
+const DefaultCompressionThreshold = 16 * 1024
+
+var ErrUnknownCompression = errors.New("unknown payload compression, expected none, gzip or zstd")
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) { return zstd.NewReader(nil) })
+)
+
+// PayloadCompressor compresses job payloads above a size threshold before they are stored.
+// The algorithm is stored alongside each payload, so payloads are decompressed whatever the
+// configured algorithm and compression can be turned on or changed with jobs in flight.
+// A nil compressor stores every payload as it is.
+type PayloadCompressor struct {
+	algorithm string
+	threshold int
+}
+
+// NewPayloadCompressor creates a compressor; an empty or "none" algorithm returns nil, which
+// compresses nothing, and a threshold of 0 uses DefaultCompressionThreshold
+func NewPayloadCompressor(algorithm string, threshold int) (*PayloadCompressor, error) {
+	switch algorithm {
+	case "", CompressionNone:
+		return nil, nil
+	case CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCompression, algorithm)
+	}
+	if threshold <= 0 {
+		threshold = DefaultCompressionThreshold
+	}
+	return &PayloadCompressor{algorithm: algorithm, threshold: threshold}, nil
+}
+
+// Algorithm returns the algorithm large payloads are compressed with
+func (c *PayloadCompressor) Algorithm() string {
+	if c == nil {
+		return CompressionNone
+	}
+	return c.algorithm
+}
+
+// Threshold returns the payload size from which payloads are compressed
+func (c *PayloadCompressor) Threshold() int {
+	if c == nil {
+		return 0
+	}
+	return c.threshold
+}
+
+// Compress returns the payload to store and the algorithm it was compressed with. Payloads
+// below the threshold, and those that don't shrink, are returned as they are with no algorithm.
+func (c *PayloadCompressor) Compress(payload []byte) ([]byte, string, error) {
+	if c == nil || len(payload) < c.threshold {
+		return payload, "", nil
+	}
+
+	var compressed []byte
+	switch c.algorithm {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, "", err
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		compressed = buf.Bytes()
+	case CompressionZstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, "", err
+		}
+		compressed = encoder.EncodeAll(payload, nil)
+	}
+
+	if len(compressed) >= len(payload) {
+		return payload, "", nil
+	}
+	return compressed, c.algorithm, nil
+}
+
+// decompressPayload restores a payload stored with the given algorithm; an empty algorithm
+// means the payload was stored as it is
+func decompressPayload(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case "":
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decompress gzip payload: %w", err)
+		}
+		defer r.Close()
+		payload, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("decompress gzip payload: %w", err)
+		}
+		return payload, nil
+	case CompressionZstd:
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := decoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decompress zstd payload: %w", err)
+		}
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("%w: payload stored with %q", ErrUnknownCompression, algorithm)
+	}
+}
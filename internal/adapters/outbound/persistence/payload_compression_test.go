@@ -0,0 +1,220 @@
+package persistence
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPayloadCompressor(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			algorithm string
+			threshold int
+		}
+		want struct {
+			algorithm string
+			threshold int
+			err       error
+		}
+	}{
+		{
+			name: "Given no algorithm, When creating a compressor, Then should compress nothing",
+			want: struct {
+				algorithm string
+				threshold int
+				err       error
+			}{algorithm: CompressionNone},
+		},
+		{
+			name: "Given zstd without a threshold, When creating a compressor, Then should use the default threshold",
+			in: struct {
+				algorithm string
+				threshold int
+			}{algorithm: CompressionZstd},
+			want: struct {
+				algorithm string
+				threshold int
+				err       error
+			}{algorithm: CompressionZstd, threshold: DefaultCompressionThreshold},
+		},
+		{
+			name: "Given gzip with a threshold, When creating a compressor, Then should keep the threshold",
+			in: struct {
+				algorithm string
+				threshold int
+			}{algorithm: CompressionGzip, threshold: 512},
+			want: struct {
+				algorithm string
+				threshold int
+				err       error
+			}{algorithm: CompressionGzip, threshold: 512},
+		},
+		{
+			name: "Given an unknown algorithm, When creating a compressor, Then should return ErrUnknownCompression",
+			in: struct {
+				algorithm string
+				threshold int
+			}{algorithm: "brotli"},
+			want: struct {
+				algorithm string
+				threshold int
+				err       error
+			}{err: ErrUnknownCompression},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressor, err := NewPayloadCompressor(tt.in.algorithm, tt.in.threshold)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.algorithm, compressor.Algorithm())
+			assert.Equal(t, tt.want.threshold, compressor.Threshold())
+		})
+	}
+}
+
+func TestPayloadCompressor_Compress(t *testing.T) {
+	repetitive := bytes.Repeat([]byte(`{"event":"page_view","user":42},`), 64)
+	random := make([]byte, 4096)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		in   struct {
+			algorithm string
+			payload   []byte
+		}
+		want struct {
+			codec string
+		}
+	}{
+		{
+			name: "Given a payload above the threshold, When compressing with gzip, Then should store it gzipped",
+			in: struct {
+				algorithm string
+				payload   []byte
+			}{algorithm: CompressionGzip, payload: repetitive},
+			want: struct{ codec string }{codec: CompressionGzip},
+		},
+		{
+			name: "Given a payload above the threshold, When compressing with zstd, Then should store it with zstd",
+			in: struct {
+				algorithm string
+				payload   []byte
+			}{algorithm: CompressionZstd, payload: repetitive},
+			want: struct{ codec string }{codec: CompressionZstd},
+		},
+		{
+			name: "Given a payload below the threshold, When compressing, Then should store it as it is",
+			in: struct {
+				algorithm string
+				payload   []byte
+			}{algorithm: CompressionZstd, payload: repetitive[:1023]},
+		},
+		{
+			name: "Given a payload at the threshold, When compressing, Then should compress it",
+			in: struct {
+				algorithm string
+				payload   []byte
+			}{algorithm: CompressionGzip, payload: repetitive[:1024]},
+			want: struct{ codec string }{codec: CompressionGzip},
+		},
+		{
+			name: "Given a payload that doesn't shrink, When compressing, Then should store it as it is",
+			in: struct {
+				algorithm string
+				payload   []byte
+			}{algorithm: CompressionGzip, payload: random},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressor, err := NewPayloadCompressor(tt.in.algorithm, 1024)
+			require.NoError(t, err)
+
+			stored, codec, err := compressor.Compress(tt.in.payload)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.codec, codec)
+			if codec == "" {
+				assert.Equal(t, tt.in.payload, stored)
+			} else {
+				assert.Less(t, len(stored), len(tt.in.payload))
+			}
+			restored, err := decompressPayload(codec, stored)
+			require.NoError(t, err)
+			assert.Equal(t, tt.in.payload, restored)
+		})
+	}
+}
+
+func TestPayloadCompressor_CompressNil(t *testing.T) {
+	// Given a nil compressor, as NewPayloadCompressor returns for "none"
+	var compressor *PayloadCompressor
+	payload := bytes.Repeat([]byte("a"), DefaultCompressionThreshold)
+
+	// When compressing a large payload
+	stored, codec, err := compressor.Compress(payload)
+
+	// Then should store it as it is
+	require.NoError(t, err)
+	assert.Empty(t, codec)
+	assert.Equal(t, payload, stored)
+}
+
+func TestDecompressPayload_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			algorithm string
+			data      []byte
+		}
+		want error
+	}{
+		{
+			name: "Given an unknown algorithm, When decompressing, Then should return ErrUnknownCompression",
+			in: struct {
+				algorithm string
+				data      []byte
+			}{algorithm: "brotli", data: []byte("x")},
+			want: ErrUnknownCompression,
+		},
+		{
+			name: "Given corrupt gzip data, When decompressing, Then should fail",
+			in: struct {
+				algorithm string
+				data      []byte
+			}{algorithm: CompressionGzip, data: []byte("not gzip")},
+		},
+		{
+			name: "Given corrupt zstd data, When decompressing, Then should fail",
+			in: struct {
+				algorithm string
+				data      []byte
+			}{algorithm: CompressionZstd, data: []byte("not zstd")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decompressPayload(tt.in.algorithm, tt.in.data)
+
+			require.Error(t, err)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
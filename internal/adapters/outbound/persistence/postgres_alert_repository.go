@@ -0,0 +1,244 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const alertRuleColumns = `id, name, condition, webhook_url, cooldown_seconds, enabled, last_fired_at, created_at, updated_at`
+
+// PostgresAlertRepository implements alert.RuleRepository, alert.SampleReader and alert.DigestLog using PostgreSQL
+type PostgresAlertRepository struct {
+	db    *pgxpool.Pool
+	reads *ReadRouter
+}
+
+// NewPostgresAlertRepository creates a new PostgreSQL alert repository
+func NewPostgresAlertRepository(db *pgxpool.Pool) *PostgresAlertRepository {
+	return &PostgresAlertRepository{db: db, reads: NewReadRouter(db, nil)}
+}
+
+// WithReadRouter sends the insight and failure samples to the read replica
+func (r *PostgresAlertRepository) WithReadRouter(reads *ReadRouter) *PostgresAlertRepository {
+	r.reads = reads
+	return r
+}
+
+func (r *PostgresAlertRepository) Create(ctx context.Context, rule *alert.Rule) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO alert_rules (`+alertRuleColumns+`)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		rule.ID, rule.Name, rule.Condition.String(), rule.WebhookURL, int(rule.Cooldown/time.Second),
+		rule.Enabled, rule.LastFiredAt, rule.CreatedAt, rule.UpdatedAt,
+	)
+	return err
+}
+
+func (r *PostgresAlertRepository) Get(ctx context.Context, id uuid.UUID) (*alert.Rule, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+alertRuleColumns+`
+         FROM alert_rules WHERE id = $1`, id)
+
+	return scanAlertRule(row)
+}
+
+func (r *PostgresAlertRepository) List(ctx context.Context) ([]*alert.Rule, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+alertRuleColumns+`
+         FROM alert_rules ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*alert.Rule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *PostgresAlertRepository) Update(ctx context.Context, rule *alert.Rule) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE alert_rules
+         SET name = $1, condition = $2, webhook_url = $3, cooldown_seconds = $4, enabled = $5, updated_at = $6
+         WHERE id = $7`,
+		rule.Name, rule.Condition.String(), rule.WebhookURL, int(rule.Cooldown/time.Second),
+		rule.Enabled, rule.UpdatedAt, rule.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return alert.ErrRuleNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAlertRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return alert.ErrRuleNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAlertRepository) MarkFired(ctx context.Context, id uuid.UUID, firedAt, notBefore time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE alert_rules SET last_fired_at = $2
+         WHERE id = $1 AND (last_fired_at IS NULL OR last_fired_at <= $3)`,
+		id, firedAt, notBefore,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *PostgresAlertRepository) InsightSamples(ctx context.Context, since time.Time, limit int) ([]alert.Sample, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT queue, type, job_error, triage_label, provider, confidence, created_at
+         FROM (
+             SELECT DISTINCT ON (i.job_id) j.queue, j.type, COALESCE(j.error, '') AS job_error,
+                    i.triage_label, i.provider, i.confidence, i.created_at
+             FROM insights i
+             JOIN jobs j ON j.id = i.job_id
+             WHERE i.created_at >= $1 AND j.deleted_at IS NULL
+             ORDER BY i.job_id, i.created_at DESC
+         ) latest
+         ORDER BY created_at DESC
+         LIMIT $2`,
+		since, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []alert.Sample
+	for rows.Next() {
+		var sample alert.Sample
+		var jobError string
+		if err := rows.Scan(&sample.Queue, &sample.Type, &jobError, &sample.Triage, &sample.Provider, &sample.Confidence, &sample.At); err != nil {
+			return nil, err
+		}
+		sample.Category = queue.ClassifyFailureMessage(jobError)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+func (r *PostgresAlertRepository) CountFailures(ctx context.Context, since time.Time) (int64, error) {
+	var failed int64
+	err := r.reads.QueryRowScan(ctx,
+		`SELECT COUNT(*) FROM jobs
+         WHERE updated_at >= $1 AND status IN ($2, $3) AND deleted_at IS NULL`,
+		[]any{since, queue.StatusFailed, queue.StatusRetrying},
+		&failed,
+	)
+	return failed, err
+}
+
+// RecordDigest appends the digest to the audit log
+func (r *PostgresAlertRepository) RecordDigest(ctx context.Context, digest *alert.Digest) error {
+	signatures, err := json.Marshal(nonNil(digest.Signatures))
+	if err != nil {
+		return err
+	}
+	queues, err := json.Marshal(nonNil(digest.Queues))
+	if err != nil {
+		return err
+	}
+	sampleInsights, err := json.Marshal(nonNil(digest.Insights))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx,
+		`INSERT INTO dlq_digests (id, growth, dlq_count, window_seconds, failed, signatures, queues, insights, sent_at)
+         VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb, $8::jsonb, $9)`,
+		digest.ID, digest.Growth, digest.DLQCount, int(digest.Window/time.Second), digest.Failed,
+		string(signatures), string(queues), string(sampleInsights), digest.SentAt,
+	)
+	return err
+}
+
+func (r *PostgresAlertRepository) ListDigests(ctx context.Context, limit int) ([]*alert.Digest, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, growth, dlq_count, window_seconds, failed, signatures, queues, insights, sent_at
+         FROM dlq_digests ORDER BY sent_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []*alert.Digest
+	for rows.Next() {
+		digest := &alert.Digest{}
+		var windowSeconds int
+		var signatures, queues, sampleInsights []byte
+		if err := rows.Scan(
+			&digest.ID, &digest.Growth, &digest.DLQCount, &windowSeconds, &digest.Failed,
+			&signatures, &queues, &sampleInsights, &digest.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		digest.Window = time.Duration(windowSeconds) * time.Second
+		if err := json.Unmarshal(signatures, &digest.Signatures); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(queues, &digest.Queues); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(sampleInsights, &digest.Insights); err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
+
+// nonNil returns an empty slice for nil, so it's stored as an empty JSON array rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func scanAlertRule(row rowScanner) (*alert.Rule, error) {
+	rule := &alert.Rule{}
+	var condition string
+	var cooldownSeconds int
+	err := row.Scan(
+		&rule.ID, &rule.Name, &condition, &rule.WebhookURL, &cooldownSeconds,
+		&rule.Enabled, &rule.LastFiredAt, &rule.CreatedAt, &rule.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, alert.ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rule.Cooldown = time.Duration(cooldownSeconds) * time.Second
+	rule.Condition, err = alert.ParseCondition(condition)
+	if err != nil {
+		return nil, fmt.Errorf("alert rule %s: %w", rule.ID, err)
+	}
+	return rule, nil
+}
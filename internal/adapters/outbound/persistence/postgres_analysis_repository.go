@@ -0,0 +1,98 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const analysisColumns = `id, job_id, status, insight_id, error, callback_url, created_at, updated_at`
+
+// PostgresAnalysisRepository implements insights.AnalysisRepository using PostgreSQL
+type PostgresAnalysisRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresAnalysisRepository creates a new PostgreSQL analysis repository
+func NewPostgresAnalysisRepository(db *pgxpool.Pool) *PostgresAnalysisRepository {
+	return &PostgresAnalysisRepository{db: db}
+}
+
+func (r *PostgresAnalysisRepository) Create(ctx context.Context, analysis *insights.Analysis) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO insight_analyses (`+analysisColumns+`)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		analysis.ID, analysis.JobID, analysis.Status, analysis.InsightID, analysis.Error,
+		analysis.CallbackURL, analysis.CreatedAt, analysis.UpdatedAt,
+	)
+	return err
+}
+
+// GetByID reads from the primary, since pollers expect to see the outcome as soon as it's recorded
+func (r *PostgresAnalysisRepository) GetByID(ctx context.Context, id uuid.UUID) (*insights.Analysis, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+analysisColumns+`
+         FROM insight_analyses WHERE id = $1`, id)
+
+	return scanAnalysis(row)
+}
+
+func (r *PostgresAnalysisRepository) Update(ctx context.Context, analysis *insights.Analysis) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE insight_analyses SET status = $1, insight_id = $2, error = $3, updated_at = $4
+         WHERE id = $5`,
+		analysis.Status, analysis.InsightID, analysis.Error, analysis.UpdatedAt, analysis.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return insights.ErrAnalysisNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAnalysisRepository) ListPending(ctx context.Context, updatedBefore time.Time, limit int) ([]*insights.Analysis, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+analysisColumns+`
+         FROM insight_analyses
+         WHERE status = $1 AND updated_at < $2
+         ORDER BY created_at
+         LIMIT $3`,
+		insights.AnalysisPending, updatedBefore, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analyses []*insights.Analysis
+	for rows.Next() {
+		analysis, err := scanAnalysis(rows)
+		if err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, analysis)
+	}
+	return analyses, rows.Err()
+}
+
+func scanAnalysis(row rowScanner) (*insights.Analysis, error) {
+	analysis := &insights.Analysis{}
+	err := row.Scan(
+		&analysis.ID, &analysis.JobID, &analysis.Status, &analysis.InsightID, &analysis.Error,
+		&analysis.CallbackURL, &analysis.CreatedAt, &analysis.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, insights.ErrAnalysisNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresAttemptRepository implements queue.AttemptRepository using PostgreSQL
+type PostgresAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresAttemptRepository creates a new PostgreSQL job attempt repository
+func NewPostgresAttemptRepository(db *pgxpool.Pool) *PostgresAttemptRepository {
+	return &PostgresAttemptRepository{db: db}
+}
+
+var _ queue.AttemptRepository = (*PostgresAttemptRepository)(nil)
+
+func (r *PostgresAttemptRepository) RecordAttempt(ctx context.Context, attempt *queue.Attempt) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO job_attempts (job_id, attempt, worker_id, started_at, duration_ms, error, category)
+         VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		attempt.JobID, attempt.Number, attempt.WorkerID, attempt.StartedAt,
+		attempt.Duration.Milliseconds(), attempt.Error, attempt.Category,
+	)
+	return err
+}
+
+// RecordRetry updates the latest attempt of the job with the number, the one that just failed
+func (r *PostgresAttemptRepository) RecordRetry(ctx context.Context, jobID uuid.UUID, number int, backoff time.Duration, retryAt time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE job_attempts SET backoff_ms = $3, retry_at = $4
+         WHERE id = (SELECT id FROM job_attempts WHERE job_id = $1 AND attempt = $2 ORDER BY id DESC LIMIT 1)`,
+		jobID, number, backoff.Milliseconds(), retryAt,
+	)
+	return err
+}
+
+func (r *PostgresAttemptRepository) ListAttempts(ctx context.Context, jobID uuid.UUID, limit int) ([]*queue.Attempt, error) {
+	// The last attempts are selected newest first, then returned in the order they ran
+	rows, err := r.db.Query(ctx,
+		`SELECT job_id, attempt, worker_id, started_at, duration_ms, error, category, backoff_ms, retry_at
+         FROM (SELECT * FROM job_attempts WHERE job_id = $1 ORDER BY id DESC LIMIT $2) last
+         ORDER BY id ASC`,
+		jobID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []*queue.Attempt{}
+	for rows.Next() {
+		var attempt queue.Attempt
+		var durationMs, backoffMs int64
+		if err := rows.Scan(&attempt.JobID, &attempt.Number, &attempt.WorkerID, &attempt.StartedAt,
+			&durationMs, &attempt.Error, &attempt.Category, &backoffMs, &attempt.RetryAt); err != nil {
+			return nil, err
+		}
+		attempt.Duration = time.Duration(durationMs) * time.Millisecond
+		attempt.Backoff = time.Duration(backoffMs) * time.Millisecond
+		attempts = append(attempts, &attempt)
+	}
+	return attempts, rows.Err()
+}
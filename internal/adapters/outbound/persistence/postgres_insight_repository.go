@@ -3,20 +3,33 @@ package persistence
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const insightColumns = `id, job_id, diagnosis, recommendation, suggested_fix, confidence, provider, usage, ai_recommendation, note, edited_by, edited_at, created_at, triage_label, kind, shared_from, error_fingerprint`
+
 // PostgresInsightRepository implements insights.InsightRepository using PostgreSQL
 type PostgresInsightRepository struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	reads *ReadRouter
 }
 
 // NewPostgresInsightRepository creates a new PostgreSQL insight repository
 func NewPostgresInsightRepository(db *pgxpool.Pool) *PostgresInsightRepository {
-	return &PostgresInsightRepository{db: db}
+	return &PostgresInsightRepository{db: db, reads: NewReadRouter(db, nil)}
+}
+
+// WithReadRouter routes listing queries through the given router
+func (r *PostgresInsightRepository) WithReadRouter(reads *ReadRouter) *PostgresInsightRepository {
+	r.reads = reads
+	return r
 }
 
 func (r *PostgresInsightRepository) Create(ctx context.Context, insight *insights.Insight) error {
@@ -26,93 +39,437 @@ func (r *PostgresInsightRepository) Create(ctx context.Context, insight *insight
 		return err
 	}
 
+	// Usage is optional; a nil value is stored as NULL
+	var usageJSON *string
+	if insight.Usage != nil {
+		data, err := json.Marshal(insight.Usage)
+		if err != nil {
+			return err
+		}
+		encoded := string(data)
+		usageJSON = &encoded
+	}
+
 	_, err = r.db.Exec(ctx,
-		`INSERT INTO insights (id, job_id, diagnosis, recommendation, suggested_fix, created_at)
-         VALUES ($1, $2, $3, $4, $5::jsonb, $6)`,
+		`INSERT INTO insights (id, job_id, diagnosis, recommendation, suggested_fix, confidence, provider, usage, created_at, triage_label, kind, shared_from, error_fingerprint)
+         VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7, $8::jsonb, $9, $10, $11, $12, $13)`,
 		insight.ID, insight.JobID, insight.Diagnosis, insight.Recommendation,
-		string(suggestedFixJSON), insight.CreatedAt,
+		string(suggestedFixJSON), insight.Confidence, insight.Provider, usageJSON, insight.CreatedAt, insight.Triage, insights.KindOf(insight),
+		insight.SharedFrom, insight.Fingerprint,
 	)
 	return err
 }
 
 func (r *PostgresInsightRepository) GetByID(ctx context.Context, id uuid.UUID) (*insights.Insight, error) {
 	row := r.db.QueryRow(ctx,
-		`SELECT id, job_id, diagnosis, recommendation, suggested_fix, created_at
+		`SELECT `+insightColumns+`
          FROM insights WHERE id = $1`, id)
 
-	insight := &insights.Insight{}
-	var suggestedFixJSON []byte
-	err := row.Scan(
-		&insight.ID, &insight.JobID, &insight.Diagnosis, &insight.Recommendation,
-		&suggestedFixJSON, &insight.CreatedAt,
+	return scanInsight(row)
+}
+
+// GetByJobID returns the latest insight analyzed for the job or linked to it
+func (r *PostgresInsightRepository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*insights.Insight, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+insightColumns+`
+         FROM insights
+         WHERE job_id = $1 OR id IN (SELECT insight_id FROM insight_jobs WHERE job_id = $1)
+         ORDER BY created_at DESC LIMIT 1`, jobID)
+
+	return scanInsight(row)
+}
+
+func (r *PostgresInsightRepository) List(ctx context.Context, kind insights.Kind, limit, offset int) ([]*insights.Insight, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT `+insightColumns+`
+         FROM insights
+         WHERE ($3::text = '' OR kind = $3)
+         ORDER BY created_at DESC LIMIT $1 OFFSET $2`,
+		limit, offset, string(kind),
 	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var insightsList []*insights.Insight
+	for rows.Next() {
+		insight, err := scanInsight(rows)
+		if err != nil {
+			return nil, err
+		}
+		insightsList = append(insightsList, insight)
+	}
+
+	return insightsList, rows.Err()
+}
+
+func (r *PostgresInsightRepository) Update(ctx context.Context, insight *insights.Insight) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE insights SET recommendation=$1, ai_recommendation=$2, note=$3, edited_by=$4, edited_at=$5
+         WHERE id=$6`,
+		insight.Recommendation, insight.AIRecommendation, insight.Note, insight.EditedBy, insight.EditedAt, insight.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return insights.ErrInsightNotFound
+	}
+	return nil
+}
+
+func (r *PostgresInsightRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM insights WHERE id = $1`, id)
+	return err
+}
+
+// dlqOrderBy sorts the DLQ query by insights.DLQOrder; by actionability, jobs without a
+// triaged insight come last
+var dlqOrderBy = map[string]string{
+	insights.DLQOrderRecent: `j.updated_at DESC`,
+	insights.DLQOrderActionability: `CASE i.triage_label
+             WHEN 'auto-retryable' THEN 0 WHEN 'config-issue' THEN 1 WHEN 'needs-human' THEN 2 ELSE 3
+         END, j.updated_at DESC`,
+}
 
-	if err := json.Unmarshal(suggestedFixJSON, &insight.SuggestedFix); err != nil {
+// ListDLQWithInsights fetches DLQ jobs and their latest insight in a single query
+func (r *PostgresInsightRepository) ListDLQWithInsights(ctx context.Context, limit, offset int, order string) ([]*insights.JobWithInsight, error) {
+	orderBy, ok := dlqOrderBy[order]
+	if !ok {
+		orderBy = dlqOrderBy[insights.DLQOrderRecent]
+	}
+	rows, err := r.reads.Query(ctx,
+		`SELECT `+qualifiedJobColumns+`,
+                i.id, i.job_id, i.diagnosis, i.recommendation, i.suggested_fix, i.confidence, i.provider, i.usage,
+                i.ai_recommendation, i.note, i.edited_by, i.edited_at, i.created_at, i.triage_label, i.kind, i.shared_from, i.error_fingerprint
+         FROM jobs j
+         LEFT JOIN LATERAL (
+             SELECT `+insightColumns+`
+             FROM insights
+             WHERE job_id = j.id OR id IN (SELECT insight_id FROM insight_jobs WHERE job_id = j.id)
+             ORDER BY created_at DESC LIMIT 1
+         ) i ON TRUE
+         WHERE j.status = $1 AND j.deleted_at IS NULL
+         ORDER BY `+orderBy+`
+         LIMIT $2 OFFSET $3`,
+		queue.StatusFailed, limit, offset,
+	)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return insight, nil
+	var result []*insights.JobWithInsight
+	for rows.Next() {
+		job := &queue.Job{}
+		var (
+			stored           storedPayload
+			insightID        *uuid.UUID
+			insightJobID     *uuid.UUID
+			diagnosis        *string
+			recommendation   *string
+			suggestedFixJSON []byte
+			confidence       *float64
+			provider         *string
+			usageJSON        []byte
+			aiRecommendation *string
+			note             *string
+			editedBy         *string
+			editedAt         *time.Time
+			insightCreatedAt *time.Time
+			triage           *string
+			kind             *string
+			sharedFrom       *uuid.UUID
+			fingerprint      *string
+		)
+		dest := append(jobScanDest(job, &stored),
+			&insightID, &insightJobID, &diagnosis, &recommendation, &suggestedFixJSON, &confidence, &provider, &usageJSON,
+			&aiRecommendation, &note, &editedBy, &editedAt, &insightCreatedAt, &triage, &kind, &sharedFrom, &fingerprint,
+		)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if err := stored.restore(job); err != nil {
+			return nil, err
+		}
+
+		entry := &insights.JobWithInsight{Job: job}
+		if insightID != nil {
+			insight := &insights.Insight{
+				ID:               *insightID,
+				JobID:            *insightJobID,
+				Kind:             insights.Kind(*kind),
+				Diagnosis:        *diagnosis,
+				Recommendation:   *recommendation,
+				Confidence:       *confidence,
+				Provider:         *provider,
+				AIRecommendation: *aiRecommendation,
+				Note:             *note,
+				EditedBy:         *editedBy,
+				EditedAt:         editedAt,
+				CreatedAt:        *insightCreatedAt,
+				Triage:           insights.TriageLabel(*triage),
+				SharedFrom:       sharedFrom,
+				Fingerprint:      *fingerprint,
+			}
+			if err := decodeInsightJSON(insight, suggestedFixJSON, usageJSON); err != nil {
+				return nil, err
+			}
+			entry.Insight = insight
+		}
+		result = append(result, entry)
+	}
+
+	return result, rows.Err()
 }
 
-func (r *PostgresInsightRepository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*insights.Insight, error) {
-	row := r.db.QueryRow(ctx,
-		`SELECT id, job_id, diagnosis, recommendation, suggested_fix, created_at
-         FROM insights WHERE job_id = $1 ORDER BY created_at DESC LIMIT 1`, jobID)
+// RecordFixApplication stores the audit entry for an applied suggested fix
+func (r *PostgresInsightRepository) RecordFixApplication(ctx context.Context, application *insights.FixApplication) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO insight_fix_applications (id, insight_id, job_id, payload_before, payload_after, status_before, status_after, job_type, fix_kind, outcome, applied_at)
+         VALUES ($1, $2, $3, $4::jsonb, $5::jsonb, $6, $7, $8, $9, $10, $11)`,
+		application.ID, application.InsightID, application.JobID,
+		jsonbParam(application.PayloadBefore), jsonbParam(application.PayloadAfter),
+		application.StatusBefore, application.StatusAfter,
+		application.JobType, application.FixKind, application.Outcome, application.AppliedAt,
+	)
+	return err
+}
 
-	insight := &insights.Insight{}
-	var suggestedFixJSON []byte
-	err := row.Scan(
-		&insight.ID, &insight.JobID, &insight.Diagnosis, &insight.Recommendation,
-		&suggestedFixJSON, &insight.CreatedAt,
+// FixApplications returns the audit entries of the fixes applied from the insight, oldest first
+func (r *PostgresInsightRepository) FixApplications(ctx context.Context, insightID uuid.UUID) ([]*insights.FixApplication, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT id, insight_id, job_id, payload_before, payload_after, status_before, status_after, job_type, fix_kind, outcome, applied_at, resolved_at
+         FROM insight_fix_applications
+         WHERE insight_id = $1
+         ORDER BY applied_at, id`,
+		insightID,
 	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	if err := json.Unmarshal(suggestedFixJSON, &insight.SuggestedFix); err != nil {
+	var applications []*insights.FixApplication
+	for rows.Next() {
+		a := &insights.FixApplication{}
+		var statusBefore, statusAfter, outcome string
+		if err := rows.Scan(&a.ID, &a.InsightID, &a.JobID, &a.PayloadBefore, &a.PayloadAfter,
+			&statusBefore, &statusAfter, &a.JobType, &a.FixKind, &outcome, &a.AppliedAt, &a.ResolvedAt); err != nil {
+			return nil, err
+		}
+		a.StatusBefore = queue.Status(statusBefore)
+		a.StatusAfter = queue.Status(statusAfter)
+		a.Outcome = insights.FixOutcome(outcome)
+		applications = append(applications, a)
+	}
+	return applications, rows.Err()
+}
+
+// RecordFixOutcome resolves the pending fix applications of a job with how its run went
+func (r *PostgresInsightRepository) RecordFixOutcome(ctx context.Context, jobID uuid.UUID, outcome insights.FixOutcome, at time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE insight_fix_applications SET outcome = $2, resolved_at = $3
+         WHERE job_id = $1 AND outcome = $4`,
+		jobID, outcome, at, insights.FixOutcomePending,
+	)
+	return err
+}
+
+// FixEffectiveness aggregates fix outcomes per job type and fix kind, most applied first
+func (r *PostgresInsightRepository) FixEffectiveness(ctx context.Context, jobType string, since time.Time) ([]*insights.FixEffectiveness, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT job_type, fix_kind,
+                COUNT(*),
+                COUNT(*) FILTER (WHERE outcome = $3),
+                COUNT(*) FILTER (WHERE outcome = $4)
+         FROM insight_fix_applications
+         WHERE applied_at >= $1 AND fix_kind <> '' AND ($2 = '' OR job_type = $2)
+         GROUP BY 1, 2
+         ORDER BY 3 DESC, 1, 2`,
+		since, jobType, insights.FixOutcomeSucceeded, insights.FixOutcomeFailed,
+	)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return insight, nil
+	var effectiveness []*insights.FixEffectiveness
+	for rows.Next() {
+		e := &insights.FixEffectiveness{}
+		if err := rows.Scan(&e.JobType, &e.FixKind, &e.Applied, &e.Succeeded, &e.Failed); err != nil {
+			return nil, err
+		}
+		effectiveness = append(effectiveness, e)
+	}
+
+	return effectiveness, rows.Err()
 }
 
-func (r *PostgresInsightRepository) List(ctx context.Context, limit, offset int) ([]*insights.Insight, error) {
-	rows, err := r.db.Query(ctx,
-		`SELECT id, job_id, diagnosis, recommendation, suggested_fix, created_at
-         FROM insights ORDER BY created_at DESC LIMIT $1 OFFSET $2`,
-		limit, offset,
+// UsageSummary aggregates AI usage recorded on insights per day, provider and model
+func (r *PostgresInsightRepository) UsageSummary(ctx context.Context, since time.Time) ([]*insights.UsageAggregate, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT date_trunc('day', created_at) AS day,
+                COALESCE(usage->>'provider', ''),
+                COALESCE(usage->>'model', ''),
+                COUNT(*),
+                COALESCE(SUM((usage->>'prompt_tokens')::bigint), 0),
+                COALESCE(SUM((usage->>'completion_tokens')::bigint), 0),
+                COALESCE(SUM((usage->>'prompt_bytes')::bigint), 0),
+                COALESCE(AVG((usage->>'latency_ms')::float8), 0)
+         FROM insights
+         WHERE usage IS NOT NULL AND created_at >= $1
+         GROUP BY 1, 2, 3
+         ORDER BY 1 DESC, 2, 3`,
+		since,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var insightsList []*insights.Insight
+	var summary []*insights.UsageAggregate
 	for rows.Next() {
-		insight := &insights.Insight{}
-		var suggestedFixJSON []byte
-		err := rows.Scan(
-			&insight.ID, &insight.JobID, &insight.Diagnosis, &insight.Recommendation,
-			&suggestedFixJSON, &insight.CreatedAt,
-		)
+		agg := &insights.UsageAggregate{}
+		if err := rows.Scan(
+			&agg.Day, &agg.Provider, &agg.Model, &agg.Calls,
+			&agg.PromptTokens, &agg.CompletionTokens, &agg.PromptBytes, &agg.AvgLatencyMs,
+		); err != nil {
+			return nil, err
+		}
+		summary = append(summary, agg)
+	}
+
+	return summary, rows.Err()
+}
+
+func (r *PostgresInsightRepository) ListByQueue(ctx context.Context, queueName string, since time.Time, limit int) ([]*insights.QueueInsight, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT job_type, `+insightColumns+`
+         FROM (
+             SELECT DISTINCT ON (i.job_id) j.type AS job_type, i.*
+             FROM insights i
+             JOIN jobs j ON j.id = i.job_id
+             WHERE ($1::text = '' OR j.queue = $1) AND j.deleted_at IS NULL AND i.created_at >= $2 AND i.kind = $4
+             ORDER BY i.job_id, i.created_at DESC
+         ) latest
+         ORDER BY created_at DESC
+         LIMIT $3`,
+		queueName, since, limit, insights.KindFailure,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*insights.QueueInsight
+	for rows.Next() {
+		entry := &insights.QueueInsight{}
+		entry.Insight, err = scanInsight(prefixedScanner{rows, []any{&entry.JobType}})
 		if err != nil {
 			return nil, err
 		}
+		result = append(result, entry)
+	}
+	return result, rows.Err()
+}
 
-		if err := json.Unmarshal(suggestedFixJSON, &insight.SuggestedFix); err != nil {
+// FindByFingerprint matches the fingerprint stored on the insight, that of the failure it was
+// analyzed for, and the type of the job it was analyzed for
+func (r *PostgresInsightRepository) FindByFingerprint(ctx context.Context, jobType, fingerprint string, since time.Time) (*insights.Insight, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+insightColumns+`
+         FROM insights
+         WHERE error_fingerprint = $1 AND kind = $3 AND created_at >= $4
+           AND job_id IN (SELECT id FROM jobs WHERE type = $2)
+         ORDER BY created_at DESC LIMIT 1`,
+		fingerprint, jobType, insights.KindFailure, since,
+	)
+	return scanInsight(row)
+}
+
+func (r *PostgresInsightRepository) LinkJob(ctx context.Context, insightID, jobID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO insight_jobs (insight_id, job_id) VALUES ($1, $2)
+         ON CONFLICT (insight_id, job_id) DO NOTHING`,
+		insightID, jobID,
+	)
+	return err
+}
+
+func (r *PostgresInsightRepository) LinkedJobs(ctx context.Context, insightID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT job_id FROM insight_jobs WHERE insight_id = $1 ORDER BY linked_at, job_id`,
+		insightID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobIDs []uuid.UUID
+	for rows.Next() {
+		var jobID uuid.UUID
+		if err := rows.Scan(&jobID); err != nil {
 			return nil, err
 		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, rows.Err()
+}
 
-		insightsList = append(insightsList, insight)
+// prefixedScanner scans columns selected before insightColumns into prefix
+type prefixedScanner struct {
+	row    rowScanner
+	prefix []any
+}
+
+func (s prefixedScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(s.prefix, dest...)...)
+}
+
+// rowScanner is satisfied by both pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanInsight scans a row selected with insightColumns
+func scanInsight(row rowScanner) (*insights.Insight, error) {
+	insight := &insights.Insight{}
+	var suggestedFixJSON, usageJSON []byte
+	err := row.Scan(
+		&insight.ID, &insight.JobID, &insight.Diagnosis, &insight.Recommendation,
+		&suggestedFixJSON, &insight.Confidence, &insight.Provider, &usageJSON,
+		&insight.AIRecommendation, &insight.Note, &insight.EditedBy, &insight.EditedAt, &insight.CreatedAt, &insight.Triage,
+		&insight.Kind, &insight.SharedFrom, &insight.Fingerprint,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, insights.ErrInsightNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decodeInsightJSON(insight, suggestedFixJSON, usageJSON); err != nil {
+		return nil, err
 	}
 
-	return insightsList, nil
+	return insight, nil
 }
 
-func (r *PostgresInsightRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.Exec(ctx, `DELETE FROM insights WHERE id = $1`, id)
-	return err
+// decodeInsightJSON fills the insight's JSONB-backed fields; empty columns are left unset
+func decodeInsightJSON(insight *insights.Insight, suggestedFixJSON, usageJSON []byte) error {
+	if len(suggestedFixJSON) > 0 {
+		if err := json.Unmarshal(suggestedFixJSON, &insight.SuggestedFix); err != nil {
+			return err
+		}
+	}
+	if len(usageJSON) > 0 {
+		insight.Usage = &insights.Usage{}
+		if err := json.Unmarshal(usageJSON, insight.Usage); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresJobGroupRepository implements queue.GroupRepository using PostgreSQL
+type PostgresJobGroupRepository struct {
+	db    *pgxpool.Pool
+	reads *ReadRouter
+}
+
+// NewPostgresJobGroupRepository creates a new PostgreSQL job group repository
+func NewPostgresJobGroupRepository(db *pgxpool.Pool) *PostgresJobGroupRepository {
+	return &PostgresJobGroupRepository{db: db, reads: NewReadRouter(db, nil)}
+}
+
+// WithReadRouter sends the progress counts to the read replica
+func (r *PostgresJobGroupRepository) WithReadRouter(reads *ReadRouter) *PostgresJobGroupRepository {
+	r.reads = reads
+	return r
+}
+
+func (r *PostgresJobGroupRepository) Create(ctx context.Context, group *queue.Group) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO job_groups (id, size, completion_job_id, created_at, completed_at)
+         VALUES ($1, $2, $3, $4, $5)`,
+		group.ID, group.Size, group.CompletionJobID, group.CreatedAt, group.CompletedAt,
+	)
+	return err
+}
+
+func (r *PostgresJobGroupRepository) Get(ctx context.Context, id uuid.UUID) (*queue.Group, error) {
+	group := &queue.Group{}
+	err := r.db.QueryRow(ctx,
+		`SELECT id, size, completion_job_id, created_at, completed_at
+         FROM job_groups WHERE id = $1`, id,
+	).Scan(&group.ID, &group.Size, &group.CompletionJobID, &group.CreatedAt, &group.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, queue.ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (r *PostgresJobGroupRepository) Progress(ctx context.Context, id uuid.UUID) (queue.GroupProgress, error) {
+	var progress queue.GroupProgress
+	err := r.reads.QueryRowScan(ctx,
+		`SELECT COUNT(*),
+                COUNT(*) FILTER (WHERE status = $2),
+                COUNT(*) FILTER (WHERE status <> $2 AND (status IN ($3, $4) OR deleted_at IS NOT NULL)),
+                COUNT(*) FILTER (WHERE status NOT IN ($2, $3, $4) AND deleted_at IS NULL)
+         FROM jobs WHERE group_id = $1`,
+		[]any{id, queue.StatusCompleted, queue.StatusFailed, queue.StatusCancelled},
+		&progress.Total, &progress.Completed, &progress.Failed, &progress.Pending,
+	)
+	return progress, err
+}
+
+func (r *PostgresJobGroupRepository) Resize(ctx context.Context, id uuid.UUID, size int) error {
+	tag, err := r.db.Exec(ctx, `UPDATE job_groups SET size = $2 WHERE id = $1`, id, size)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return queue.ErrGroupNotFound
+	}
+	return nil
+}
+
+// Finish claims the group and releases its completion job in one statement. Each worker calls it
+// after storing its job's final status, so the last of concurrent callers counts every job.
+func (r *PostgresJobGroupRepository) Finish(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	rows, err := r.db.Query(ctx,
+		`WITH finished AS (
+             UPDATE job_groups g SET completed_at = $2
+             WHERE g.id = $1 AND g.completed_at IS NULL
+               AND (SELECT COUNT(*) FROM jobs j
+                    WHERE j.group_id = g.id AND (j.status IN ($3, $4, $7) OR j.deleted_at IS NOT NULL)) >= g.size
+             RETURNING g.id, g.completion_job_id
+         ), released AS (
+             UPDATE jobs SET scheduled_for = $2, updated_at = $2, version = version + 1
+             WHERE id IN (SELECT completion_job_id FROM finished) AND status = $5 AND scheduled_for = $6
+             RETURNING id
+         )
+         SELECT id FROM finished`,
+		id, now, queue.StatusCompleted, queue.StatusFailed, queue.StatusPending, queue.GroupHoldUntil,
+		queue.StatusCancelled,
+	)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	finished := rows.Next()
+	return finished, rows.Err()
+}
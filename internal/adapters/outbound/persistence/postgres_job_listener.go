@@ -0,0 +1,68 @@
+package persistence
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresJobListener implements queue.ReadySignal with Postgres LISTEN/NOTIFY, for the
+// PostgresQueueService. It holds one dedicated connection and fans notifications out to
+// per-queue channels.
+type PostgresJobListener struct {
+	readySignals
+	db *pgxpool.Pool
+}
+
+// NewPostgresJobListener creates a listener; call Run to start receiving notifications
+func NewPostgresJobListener(db *pgxpool.Pool) *PostgresJobListener {
+	return &PostgresJobListener{db: db}
+}
+
+// Run listens for job notifications until ctx is cancelled, reconnecting on failure.
+// Workers keep polling while the listener is down.
+func (l *PostgresJobListener) Run(ctx context.Context) {
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.WarnContext(ctx, "Job listener stopped, reconnecting",
+			slog.Duration("retryIn", listenRetryDelay),
+			slog.String("error", err.Error()),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (l *PostgresJobListener) listen(ctx context.Context) error {
+	pooled, err := l.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The LISTEN session must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+jobReadyChannel); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Listening for job notifications",
+		slog.String("channel", jobReadyChannel),
+	)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		l.signal(notification.Payload)
+	}
+}
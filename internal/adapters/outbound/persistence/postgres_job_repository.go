@@ -2,81 +2,329 @@ package persistence
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const jobColumns = `id, queue, type, status, attempts, payload, result, scheduled_for, created_at, updated_at, error, deleted_at, callback_url, signature, signing_key_id, requires, payload_codec, payload_compressed, version, group_id, dead_letter_reason, schema_version, created_by, metadata`
+
+// qualifiedJobColumns selects the same columns as jobColumns from a table aliased as j
+const qualifiedJobColumns = `j.id, j.queue, j.type, j.status, j.attempts, j.payload, j.result, j.scheduled_for, j.created_at, j.updated_at, j.error, j.deleted_at, j.callback_url, j.signature, j.signing_key_id, j.requires, j.payload_codec, j.payload_compressed, j.version, j.group_id, j.dead_letter_reason, j.schema_version, j.created_by, j.metadata`
+
 // PostgresJobRepository implements queue.JobRepository using PostgreSQL
 type PostgresJobRepository struct {
-	db *pgxpool.Pool
+	db          *pgxpool.Pool
+	reads       *ReadRouter
+	compression *PayloadCompressor
 }
 
 // NewPostgresJobRepository creates a new PostgreSQL job repository
 func NewPostgresJobRepository(db *pgxpool.Pool) *PostgresJobRepository {
-	return &PostgresJobRepository{db: db}
+	return &PostgresJobRepository{db: db, reads: NewReadRouter(db, nil)}
+}
+
+// WithReadRouter routes listing and counting queries through the given router
+func (r *PostgresJobRepository) WithReadRouter(reads *ReadRouter) *PostgresJobRepository {
+	r.reads = reads
+	return r
+}
+
+// WithPayloadCompression stores the payloads the compressor selects compressed. Compressed
+// payloads are read back whatever the compressor, but aren't covered by job search.
+func (r *PostgresJobRepository) WithPayloadCompression(compressor *PayloadCompressor) *PostgresJobRepository {
+	r.compression = compressor
+	return r
 }
 
 func (r *PostgresJobRepository) Create(ctx context.Context, job *queue.Job) error {
-	var payload interface{}
-	if job.Payload != nil {
-		// Convert []byte to string for JSONB column
-		payload = string(job.Payload)
+	payload, err := r.payloadParams(job.Payload)
+	if err != nil {
+		return err
 	}
-
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO jobs (id, queue, type, status, attempts, payload, scheduled_for, created_at, updated_at, error)
-         VALUES ($1,$2,$3,$4,$5,$6::jsonb,$7,$8,$9,$10)`,
+	metadata, err := metadataParam(job.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).Exec(ctx,
+		`INSERT INTO jobs (id, queue, type, status, attempts, payload, result, scheduled_for, created_at, updated_at, error, callback_url, signature, signing_key_id, requires, payload_codec, payload_compressed, version, group_id, error_fingerprint, schema_version, created_by, metadata)
+         VALUES ($1,$2,$3,$4,$5,$6::jsonb,$7::jsonb,$8,$9,$10,$11,$12,$13,$14,COALESCE($15::text[], '{}'),$16,$17,$18,$19,$20,$21,$22,$23::jsonb)`,
 		job.ID, job.Queue, job.Type, job.Status, job.Attempts,
-		payload, job.ScheduledFor, job.CreatedAt, job.UpdatedAt, job.Error,
+		payload.json, jsonbParam(job.Result), job.ScheduledFor, job.CreatedAt, job.UpdatedAt, job.Error, job.CallbackURL,
+		job.Signature, job.SigningKeyID, job.Requires, payload.codec, payload.compressed, job.Version, job.GroupID,
+		job.ErrorFingerprint(), job.EffectiveSchemaVersion(), job.CreatedBy, metadata,
 	)
 	return err
 }
 
 func (r *PostgresJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*queue.Job, error) {
-	row := r.db.QueryRow(ctx,
-		`SELECT id, queue, type, status, attempts, payload, scheduled_for, created_at, updated_at, error
+	row := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT `+jobColumns+`
          FROM jobs WHERE id = $1`, id)
 
-	job := &queue.Job{}
-	err := row.Scan(
-		&job.ID, &job.Queue, &job.Type, &job.Status, &job.Attempts,
-		&job.Payload, &job.ScheduledFor, &job.CreatedAt, &job.UpdatedAt, &job.Error,
+	return scanJob(row)
+}
+
+// GetByIDForUpdate locks the job's row until the transaction in ctx ends; without one the
+// lock would be released at once, so it is a plain read
+func (r *PostgresJobRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*queue.Job, error) {
+	if !inTransaction(ctx) {
+		return r.GetByID(ctx, id)
+	}
+	row := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT `+jobColumns+`
+         FROM jobs WHERE id = $1 FOR UPDATE`, id)
+
+	return scanJob(row)
+}
+
+// updateJobSQL writes a job whose stored status may move to the new one, returning its version
+const updateJobSQL = `UPDATE jobs SET status=$1, attempts=$2, payload=$3::jsonb, result=$4::jsonb, scheduled_for=$5, updated_at=$6, error=$7, signature=$8,
+                payload_codec=$11, payload_compressed=$12, error_fingerprint=$13, dead_letter_reason=$14, schema_version=$15, version = version + 1
+         WHERE id=$9 AND status = ANY($10)
+         RETURNING version`
+
+func (r *PostgresJobRepository) updateArgs(job *queue.Job) ([]any, error) {
+	payload, err := r.payloadParams(job.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return []any{
+		job.Status, job.Attempts, payload.json, jsonbParam(job.Result), job.ScheduledFor, job.UpdatedAt, job.Error, job.Signature, job.ID,
+		previousStatuses(job.Status), payload.codec, payload.compressed, job.ErrorFingerprint(), job.DeadLetterReason,
+		job.EffectiveSchemaVersion(),
+	}, nil
+}
+
+// Update only applies when the stored status may move to the job's status, so a duplicate
+// delivery can't drag a completed job back to processing. The job takes the stored version.
+func (r *PostgresJobRepository) Update(ctx context.Context, job *queue.Job) error {
+	args, err := r.updateArgs(job)
+	if err != nil {
+		return err
+	}
+	err = conn(ctx, r.db).QueryRow(ctx, updateJobSQL, args...).Scan(&job.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.transitionError(ctx, job.ID, job.Status)
+	}
+	return err
+}
+
+// UpdateBatch sends the updates of the jobs together, so they cost one round trip instead of
+// one each. Every job is checked and written on its own: a job that can't move to its new
+// status gets ErrInvalidTransition without failing the others.
+func (r *PostgresJobRepository) UpdateBatch(ctx context.Context, jobs []*queue.Job) []error {
+	errs := make([]error, len(jobs))
+	batch := &pgx.Batch{}
+	queued := make([]int, 0, len(jobs)) // Index in jobs of each queued update
+	for i, job := range jobs {
+		args, err := r.updateArgs(job)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		batch.Queue(updateJobSQL, args...)
+		queued = append(queued, i)
+	}
+	if len(queued) == 0 {
+		return errs
+	}
+
+	results := conn(ctx, r.db).SendBatch(ctx, batch)
+	for _, i := range queued {
+		errs[i] = results.QueryRow().Scan(&jobs[i].Version)
+	}
+	if err := results.Close(); err != nil {
+		for _, i := range queued {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+	}
+	// The batch must be closed before the rejected jobs' current status can be read
+	for _, i := range queued {
+		if errors.Is(errs[i], pgx.ErrNoRows) {
+			errs[i] = r.transitionError(ctx, jobs[i].ID, jobs[i].Status)
+		}
+	}
+	return errs
+}
+
+func (r *PostgresJobRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := conn(ctx, r.db).Exec(ctx,
+		`UPDATE jobs SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return queue.ErrJobNotFound
+	}
+	return nil
+}
+
+func (r *PostgresJobRepository) Undelete(ctx context.Context, id uuid.UUID) error {
+	tag, err := conn(ctx, r.db).Exec(ctx,
+		`UPDATE jobs SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL`, id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return queue.ErrJobNotFound
+	}
+	return nil
+}
+
+func (r *PostgresJobRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	tag, err := conn(ctx, r.db).Exec(ctx,
+		`DELETE FROM jobs WHERE deleted_at IS NOT NULL AND deleted_at < $1`, deletedBefore,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClearPayloads clears the oldest matching jobs first, skipping jobs locked by another writer.
+// The jobs' updated_at is left alone, so it keeps telling when they completed.
+func (r *PostgresJobRepository) ClearPayloads(ctx context.Context, expiry queue.PayloadExpiry) (int64, error) {
+	queues, except := expiry.Queues, expiry.ExceptQueues
+	if queues == nil {
+		queues = []string{}
+	}
+	if except == nil {
+		except = []string{}
+	}
+	tag, err := conn(ctx, r.db).Exec(ctx,
+		`UPDATE jobs SET payload = NULL, payload_codec = '', payload_compressed = NULL, result = NULL
+         WHERE id IN (
+             SELECT id FROM jobs
+             WHERE status = $1 AND updated_at < $2
+               AND (payload IS NOT NULL OR payload_compressed IS NOT NULL OR result IS NOT NULL)
+               AND (cardinality($3::text[]) = 0 OR queue = ANY($3))
+               AND NOT (queue = ANY($4))
+             ORDER BY updated_at
+             LIMIT $5
+             FOR UPDATE SKIP LOCKED
+         )`,
+		queue.StatusCompleted, expiry.CompletedBefore, queues, except, expiry.Limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *PostgresJobRepository) CountPurgeable(ctx context.Context, queueName string, status queue.Status) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT COUNT(*) FROM jobs WHERE queue = $1 AND status = $2 AND deleted_at IS NULL`,
+		queueName, status,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresJobRepository) FindHeld(ctx context.Context, queueName string, maxVersion int, limit int) ([]*queue.Job, error) {
+	rows, err := conn(ctx, r.db).Query(ctx,
+		`SELECT `+jobColumns+`
+         FROM jobs
+         WHERE queue = $1 AND status = $2 AND schema_version <= $3 AND deleted_at IS NULL
+         ORDER BY created_at ASC
+         LIMIT $4`,
+		queueName, queue.StatusHeld, maxVersion, limit,
 	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return job, nil
+	return collectJobs(rows)
 }
 
-func (r *PostgresJobRepository) Update(ctx context.Context, job *queue.Job) error {
-	var payload interface{}
-	if job.Payload != nil {
-		// Convert []byte to string for JSONB column
-		payload = string(job.Payload)
+// FindParked skips rows locked by another release, so concurrent releases split the jobs
+func (r *PostgresJobRepository) FindParked(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	lock := ""
+	if inTransaction(ctx) {
+		lock = "FOR UPDATE SKIP LOCKED"
+	}
+	rows, err := conn(ctx, r.db).Query(ctx,
+		`SELECT `+jobColumns+`
+         FROM jobs
+         WHERE queue = $1 AND status = $2 AND deleted_at IS NULL
+         ORDER BY created_at ASC
+         LIMIT $3 `+lock,
+		queueName, queue.StatusParked, limit,
+	)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	return collectJobs(rows)
+}
 
-	_, err := r.db.Exec(ctx,
-		`UPDATE jobs SET status=$1, attempts=$2, payload=$3::jsonb, scheduled_for=$4, updated_at=$5, error=$6
-         WHERE id=$7`,
-		job.Status, job.Attempts, payload, job.ScheduledFor, job.UpdatedAt, job.Error, job.ID,
+// FindPurgeable skips rows locked by a worker claiming them or by another purge, so concurrent
+// purges split the queue between them
+func (r *PostgresJobRepository) FindPurgeable(ctx context.Context, queueName string, status queue.Status, limit int) ([]*queue.Job, error) {
+	lock := ""
+	if inTransaction(ctx) {
+		lock = "FOR UPDATE SKIP LOCKED"
+	}
+	rows, err := conn(ctx, r.db).Query(ctx,
+		`SELECT `+jobColumns+`
+         FROM jobs
+         WHERE queue = $1 AND status = $2 AND deleted_at IS NULL
+         ORDER BY created_at ASC
+         LIMIT $3 `+lock,
+		queueName, status, limit,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectJobs(rows)
 }
 
-func (r *PostgresJobRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.Exec(ctx, `DELETE FROM jobs WHERE id = $1`, id)
-	return err
+// CancelJobs stores the cancellations in one statement; like Update, it only applies to jobs
+// whose stored status may move to cancelled
+func (r *PostgresJobRepository) CancelJobs(ctx context.Context, jobs []*queue.Job) (int64, error) {
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+	ids := make([]uuid.UUID, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	// The jobs share the reason and time of the purge
+	tag, err := conn(ctx, r.db).Exec(ctx,
+		`UPDATE jobs SET status = $2, error = $3, updated_at = $4, version = version + 1
+         WHERE id = ANY($1) AND status = ANY($5)`,
+		ids, queue.StatusCancelled, jobs[0].Error, jobs[0].UpdatedAt, previousStatuses(queue.StatusCancelled),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
 
+// pendingJobsFilter matches the live jobs of queue $1 ready to run now, pending ($2) or
+// retrying ($3); the Postgres queue backend claims jobs with the same filter
+const pendingJobsFilter = `FROM jobs
+         WHERE queue = $1 AND status IN ($2, $3) AND deleted_at IS NULL
+         AND (scheduled_for IS NULL OR scheduled_for <= NOW())`
+
 func (r *PostgresJobRepository) FindPendingJobs(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
-	rows, err := r.db.Query(ctx,
-		`SELECT id, queue, type, status, attempts, payload, scheduled_for, created_at, updated_at, error
-         FROM jobs 
-         WHERE queue = $1 AND status IN ($2, $3)
-         AND (scheduled_for IS NULL OR scheduled_for <= NOW())
+	rows, err := conn(ctx, r.db).Query(ctx,
+		`SELECT `+jobColumns+`
+         `+pendingJobsFilter+`
          ORDER BY created_at ASC
          LIMIT $4`,
 		queueName, queue.StatusPending, queue.StatusRetrying, limit,
@@ -86,26 +334,30 @@ func (r *PostgresJobRepository) FindPendingJobs(ctx context.Context, queueName s
 	}
 	defer rows.Close()
 
-	var jobs []*queue.Job
-	for rows.Next() {
-		job := &queue.Job{}
-		err := rows.Scan(
-			&job.ID, &job.Queue, &job.Type, &job.Status, &job.Attempts,
-			&job.Payload, &job.ScheduledFor, &job.CreatedAt, &job.UpdatedAt, &job.Error,
-		)
-		if err != nil {
-			return nil, err
-		}
-		jobs = append(jobs, job)
+	return collectJobs(rows)
+}
+
+func (r *PostgresJobRepository) FindDueDelayedJobs(ctx context.Context, now time.Time, limit int) ([]*queue.Job, error) {
+	rows, err := conn(ctx, r.db).Query(ctx,
+		`SELECT `+jobColumns+`
+         FROM jobs
+         WHERE status = $1 AND scheduled_for IS NOT NULL AND scheduled_for <= $2 AND deleted_at IS NULL
+         ORDER BY scheduled_for ASC
+         LIMIT $3`,
+		queue.StatusPending, now, limit,
+	)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return jobs, nil
+	return collectJobs(rows)
 }
 
 func (r *PostgresJobRepository) FindByStatus(ctx context.Context, status queue.Status, limit int) ([]*queue.Job, error) {
-	rows, err := r.db.Query(ctx,
-		`SELECT id, queue, type, status, attempts, payload, scheduled_for, created_at, updated_at, error
-         FROM jobs WHERE status = $1 LIMIT $2`,
+	rows, err := r.reads.Query(ctx,
+		`SELECT `+jobColumns+`
+         FROM jobs WHERE status = $1 AND deleted_at IS NULL LIMIT $2`,
 		status, limit,
 	)
 	if err != nil {
@@ -113,35 +365,181 @@ func (r *PostgresJobRepository) FindByStatus(ctx context.Context, status queue.S
 	}
 	defer rows.Close()
 
-	var jobs []*queue.Job
+	return collectJobs(rows)
+}
+
+func (r *PostgresJobRepository) CountByStatus(ctx context.Context, status queue.Status) (int64, error) {
+	var count int64
+	err := r.reads.QueryRowScan(ctx,
+		`SELECT COUNT(*) FROM jobs WHERE status = $1 AND deleted_at IS NULL`, []any{status}, &count,
+	)
+	return count, err
+}
+
+func (r *PostgresJobRepository) CountDelayed(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := r.reads.QueryRowScan(ctx,
+		`SELECT COUNT(*) FROM jobs WHERE status = $1 AND scheduled_for > $2 AND deleted_at IS NULL`,
+		[]any{queue.StatusPending, now}, &count,
+	)
+	return count, err
+}
+
+// Activity counts by updated_at, which for completed and failed jobs is when they got there
+func (r *PostgresJobRepository) Activity(ctx context.Context, since time.Time, topTypes int) (*queue.Activity, error) {
+	activity := &queue.Activity{Since: since, TopFailingTypes: []*queue.TypeFailures{}, DeadLettered: map[string]int64{}}
+	err := r.reads.QueryRowScan(ctx,
+		`SELECT COUNT(*) FILTER (WHERE status = $2),
+                COUNT(*) FILTER (WHERE status IN ($3, $4))
+         FROM jobs WHERE updated_at >= $1 AND deleted_at IS NULL`,
+		[]any{since, queue.StatusCompleted, queue.StatusFailed, queue.StatusRetrying},
+		&activity.Completed, &activity.Failed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if activity.Failed == 0 {
+		return activity, nil
+	}
+
+	rows, err := r.reads.Query(ctx,
+		`SELECT type, COUNT(*) AS failed
+         FROM jobs
+         WHERE updated_at >= $1 AND status IN ($2, $3) AND deleted_at IS NULL
+         GROUP BY type
+         ORDER BY failed DESC, type
+         LIMIT $4`,
+		since, queue.StatusFailed, queue.StatusRetrying, topTypes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
 	for rows.Next() {
-		job := &queue.Job{}
-		err := rows.Scan(
-			&job.ID, &job.Queue, &job.Type, &job.Status, &job.Attempts,
-			&job.Payload, &job.ScheduledFor, &job.CreatedAt, &job.UpdatedAt, &job.Error,
-		)
-		if err != nil {
+		failures := &queue.TypeFailures{}
+		if err := rows.Scan(&failures.Type, &failures.Failed); err != nil {
 			return nil, err
 		}
-		jobs = append(jobs, job)
+		activity.TopFailingTypes = append(activity.TopFailingTypes, failures)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	reasons, err := r.reads.Query(ctx,
+		`SELECT dead_letter_reason, COUNT(*)
+         FROM jobs
+         WHERE updated_at >= $1 AND status = $2 AND dead_letter_reason <> '' AND deleted_at IS NULL
+         GROUP BY dead_letter_reason`,
+		since, queue.StatusFailed,
+	)
+	if err != nil {
+		return nil, err
 	}
+	defer reasons.Close()
 
-	return jobs, nil
+	for reasons.Next() {
+		var reason string
+		var count int64
+		if err := reasons.Scan(&reason, &count); err != nil {
+			return nil, err
+		}
+		activity.DeadLettered[reason] = count
+	}
+	return activity, reasons.Err()
 }
 
-func (r *PostgresJobRepository) CountByStatus(ctx context.Context, status queue.Status) (int64, error) {
-	var count int64
-	err := r.db.QueryRow(ctx,
-		`SELECT COUNT(*) FROM jobs WHERE status = $1`, status,
-	).Scan(&count)
-	return count, err
+// searchFilter matches jobs against a websearch-style query (quoted phrases, -exclusions)
+// using the search_vector column, with optional status and queue filters
+const searchFilter = `FROM jobs, websearch_to_tsquery('simple', $1) AS query
+         WHERE search_vector @@ query AND deleted_at IS NULL
+         AND ($2::text = '' OR status = $2)
+         AND ($3::text = '' OR queue = $3)`
+
+func (r *PostgresJobRepository) Search(ctx context.Context, criteria queue.SearchCriteria) ([]*queue.Job, int64, error) {
+	args := []any{criteria.Text, string(criteria.Status), criteria.Queue}
+
+	var total int64
+	if err := r.reads.QueryRowScan(ctx, `SELECT COUNT(*) `+searchFilter, args, &total); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*queue.Job{}, 0, nil
+	}
+
+	rows, err := r.reads.Query(ctx,
+		`SELECT `+jobColumns+`
+         `+searchFilter+`
+         ORDER BY ts_rank_cd(search_vector, query) DESC, updated_at DESC
+         LIMIT $4 OFFSET $5`,
+		append(args, criteria.Limit, criteria.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs, err := collectJobs(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
 }
 
+// queueListingFilter matches the live jobs of a queue, with optional status, creator and
+// metadata filters. Listings by creator or metadata may leave the queue out. An empty key
+// list and object match any metadata.
+const queueListingFilter = `FROM jobs
+         WHERE ($1::text = '' OR queue = $1) AND deleted_at IS NULL
+         AND ($2::text = '' OR status = $2)
+         AND ($3::text = '' OR created_by = $3)
+         AND metadata ?& COALESCE($4::text[], '{}')
+         AND metadata @> $5::jsonb`
+
+func (r *PostgresJobRepository) ListByQueue(ctx context.Context, listing queue.QueueListing) ([]*queue.Job, int64, error) {
+	values, err := metadataParam(listing.Metadata.Values)
+	if err != nil {
+		return nil, 0, err
+	}
+	args := []any{listing.Queue, string(listing.Status), listing.CreatedBy, listing.Metadata.Keys, values}
+
+	var total int64
+	if err := r.reads.QueryRowScan(ctx, `SELECT COUNT(*) `+queueListingFilter, args, &total); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*queue.Job{}, 0, nil
+	}
+
+	rows, err := r.reads.Query(ctx,
+		`SELECT `+jobColumns+`
+         `+queueListingFilter+`
+         ORDER BY created_at DESC, id
+         LIMIT $6 OFFSET $7`,
+		append(args, listing.Limit, listing.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs, err := collectJobs(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// The worker only leaves a job in the failed status once it has been dead-lettered
+// (retryable failures move on to retrying), so failed jobs make up the DLQ.
+
 func (r *PostgresJobRepository) GetDLQJobs(ctx context.Context, limit, offset int) ([]*queue.Job, error) {
-	rows, err := r.db.Query(ctx,
-		`SELECT id, queue, type, status, attempts, payload, scheduled_for, created_at, updated_at, error
+	rows, err := r.reads.Query(ctx,
+		`SELECT `+jobColumns+`
          FROM jobs 
-         WHERE status = $1 AND attempts >= 3
+         WHERE status = $1 AND deleted_at IS NULL
          ORDER BY updated_at DESC
          LIMIT $2 OFFSET $3`,
 		queue.StatusFailed, limit, offset,
@@ -151,37 +549,152 @@ func (r *PostgresJobRepository) GetDLQJobs(ctx context.Context, limit, offset in
 	}
 	defer rows.Close()
 
-	var jobs []*queue.Job
-	for rows.Next() {
-		job := &queue.Job{}
-		err := rows.Scan(
-			&job.ID, &job.Queue, &job.Type, &job.Status, &job.Attempts,
-			&job.Payload, &job.ScheduledFor, &job.CreatedAt, &job.UpdatedAt, &job.Error,
-		)
-		if err != nil {
-			return nil, err
-		}
-		jobs = append(jobs, job)
-	}
-
-	return jobs, nil
+	return collectJobs(rows)
 }
 
 func (r *PostgresJobRepository) MoveToDLQ(ctx context.Context, jobID uuid.UUID) error {
 	// In this implementation, we keep failed jobs in the same table
 	// but could move to a separate dlq table if needed
-	_, err := r.db.Exec(ctx,
-		`UPDATE jobs SET status = $1, updated_at = NOW() WHERE id = $2`,
-		queue.StatusFailed, jobID,
+	tag, err := conn(ctx, r.db).Exec(ctx,
+		`UPDATE jobs SET status = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 AND status = ANY($3)`,
+		queue.StatusFailed, jobID, previousStatuses(queue.StatusFailed),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return r.transitionError(ctx, jobID, queue.StatusFailed)
+	}
+	return nil
+}
+
+// transitionError explains why a guarded status update matched no row
+func (r *PostgresJobRepository) transitionError(ctx context.Context, id uuid.UUID, next queue.Status) error {
+	var current queue.Status
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT status FROM jobs WHERE id = $1`, id).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return queue.ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s to %s", queue.ErrInvalidTransition, current, next)
+}
+
+// previousStatuses returns the statuses a stored job may be in to move to next, as text for ANY()
+func previousStatuses(next queue.Status) []string {
+	previous := queue.PreviousStatuses(next)
+	statuses := make([]string, len(previous))
+	for i, status := range previous {
+		statuses[i] = string(status)
+	}
+	return statuses
 }
 
 func (r *PostgresJobRepository) CountDLQJobs(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.QueryRow(ctx,
-		`SELECT COUNT(*) FROM jobs WHERE status = $1 AND attempts >= 3`,
-		queue.StatusFailed,
-	).Scan(&count)
+	err := r.reads.QueryRowScan(ctx,
+		`SELECT COUNT(*) FROM jobs WHERE status = $1 AND deleted_at IS NULL`,
+		[]any{queue.StatusFailed}, &count,
+	)
 	return count, err
 }
+
+// jsonbParam converts raw JSON bytes to a JSONB query parameter (nil stays NULL)
+func jsonbParam(data []byte) any {
+	if data == nil {
+		return nil
+	}
+	// Convert []byte to string for JSONB column
+	return string(data)
+}
+
+// metadataParam encodes job metadata as a JSONB query parameter, an empty object when there is none
+func metadataParam(metadata map[string]string) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("encode metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// storedPayload is a job payload as written to its columns: JSON in payload, or compressed
+// in payload_compressed with payload_codec naming the algorithm
+type storedPayload struct {
+	json       any
+	codec      string
+	compressed []byte
+}
+
+// payloadParams compresses a payload the repository's compressor selects
+func (r *PostgresJobRepository) payloadParams(payload []byte) (storedPayload, error) {
+	data, codec, err := r.compression.Compress(payload)
+	if err != nil {
+		return storedPayload{}, fmt.Errorf("compress payload: %w", err)
+	}
+	if codec == "" {
+		return storedPayload{json: jsonbParam(payload)}, nil
+	}
+	return storedPayload{codec: codec, compressed: data}, nil
+}
+
+// restore decompresses the payload into a job scanned with jobScanDest
+func (p *storedPayload) restore(job *queue.Job) error {
+	if p.codec == "" {
+		return nil
+	}
+	payload, err := decompressPayload(p.codec, p.compressed)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.ID, err)
+	}
+	job.Payload = payload
+	return nil
+}
+
+// jobScanDest returns the scan destinations matching jobColumns. A compressed payload is
+// scanned into stored, whose restore must be called once the row is scanned.
+func jobScanDest(job *queue.Job, stored *storedPayload) []any {
+	return []any{
+		&job.ID, &job.Queue, &job.Type, &job.Status, &job.Attempts,
+		&job.Payload, &job.Result, &job.ScheduledFor, &job.CreatedAt, &job.UpdatedAt, &job.Error, &job.DeletedAt, &job.CallbackURL,
+		&job.Signature, &job.SigningKeyID, &job.Requires, &stored.codec, &stored.compressed, &job.Version, &job.GroupID, &job.DeadLetterReason,
+		&job.SchemaVersion, &job.CreatedBy, &job.Metadata,
+	}
+}
+
+// scanJob scans a row selected with jobColumns
+func scanJob(row rowScanner) (*queue.Job, error) {
+	job := &queue.Job{}
+	var stored storedPayload
+	err := row.Scan(jobScanDest(job, &stored)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, queue.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := stored.restore(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// collectJobs scans all rows selected with jobColumns
+func collectJobs(rows interface {
+	rowScanner
+	Next() bool
+	Err() error
+}) ([]*queue.Job, error) {
+	var jobs []*queue.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,77 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresOutputStore implements queue.OutputStore using PostgreSQL, one row per chunk flushed
+type PostgresOutputStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresOutputStore creates a new PostgreSQL job output store
+func NewPostgresOutputStore(db *pgxpool.Pool) *PostgresOutputStore {
+	return &PostgresOutputStore{db: db}
+}
+
+var _ queue.OutputStore = (*PostgresOutputStore)(nil)
+
+func (s *PostgresOutputStore) AppendOutput(ctx context.Context, jobID uuid.UUID, offset int64, data []byte) error {
+	// A chunk retried after a lost acknowledgement is stored once
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO job_output_chunks (job_id, byte_offset, data) VALUES ($1, $2, $3)
+         ON CONFLICT (job_id, byte_offset) DO NOTHING`,
+		jobID, offset, data,
+	)
+	return err
+}
+
+func (s *PostgresOutputStore) OutputSize(ctx context.Context, jobID uuid.UUID) (int64, error) {
+	var size int64
+	err := s.db.QueryRow(ctx,
+		`SELECT COALESCE(MAX(byte_offset + octet_length(data)), 0) FROM job_output_chunks WHERE job_id = $1`,
+		jobID,
+	).Scan(&size)
+	return size, err
+}
+
+func (s *PostgresOutputStore) ReadOutput(ctx context.Context, jobID uuid.UUID, offset, length int64) ([]byte, error) {
+	if length <= 0 {
+		return []byte{}, nil
+	}
+	end := offset + length
+
+	// Only the chunks overlapping the range are read, cut to it
+	rows, err := s.db.Query(ctx,
+		`SELECT byte_offset, data FROM job_output_chunks
+         WHERE job_id = $1 AND byte_offset < $3 AND byte_offset + octet_length(data) > $2
+         ORDER BY byte_offset`,
+		jobID, offset, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]byte, 0, length)
+	for rows.Next() {
+		var chunkOffset int64
+		var data []byte
+		if err := rows.Scan(&chunkOffset, &data); err != nil {
+			return nil, err
+		}
+		from := max(offset, chunkOffset) - chunkOffset
+		to := min(end, chunkOffset+int64(len(data))) - chunkOffset
+		out = append(out, data[from:to]...)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresOutputStore) ClearOutput(ctx context.Context, jobID uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM job_output_chunks WHERE job_id = $1`, jobID)
+	return err
+}
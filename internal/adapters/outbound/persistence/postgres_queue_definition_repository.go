@@ -0,0 +1,120 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const queueDefinitionColumns = `name, max_attempts, base_backoff_ms, rate_limit_per_second, allowed_types, default_type, strict_payload, paused, draining, insight_policy, payload_retention_days, created_at, updated_at`
+
+// PostgresQueueDefinitionRepository implements queue.DefinitionRepository using PostgreSQL
+type PostgresQueueDefinitionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresQueueDefinitionRepository creates a new PostgreSQL queue definition repository
+func NewPostgresQueueDefinitionRepository(db *pgxpool.Pool) *PostgresQueueDefinitionRepository {
+	return &PostgresQueueDefinitionRepository{db: db}
+}
+
+func (r *PostgresQueueDefinitionRepository) Create(ctx context.Context, def *queue.Definition) error {
+	tag, err := r.db.Exec(ctx,
+		`INSERT INTO queue_definitions (`+queueDefinitionColumns+`)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+         ON CONFLICT (name) DO NOTHING`,
+		def.Name, def.MaxAttempts, def.BaseBackoffMs, def.RateLimitPerSecond,
+		allowedTypes(def), def.DefaultType, def.StrictPayload, def.Paused, def.Draining, def.InsightPolicy, def.PayloadRetentionDays, def.CreatedAt, def.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return queue.ErrQueueDefined
+	}
+	return nil
+}
+
+func (r *PostgresQueueDefinitionRepository) Get(ctx context.Context, name string) (*queue.Definition, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+queueDefinitionColumns+`
+         FROM queue_definitions WHERE name = $1`, name)
+
+	return scanQueueDefinition(row)
+}
+
+func (r *PostgresQueueDefinitionRepository) List(ctx context.Context) ([]*queue.Definition, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+queueDefinitionColumns+`
+         FROM queue_definitions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var defs []*queue.Definition
+	for rows.Next() {
+		def, err := scanQueueDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, rows.Err()
+}
+
+func (r *PostgresQueueDefinitionRepository) Update(ctx context.Context, def *queue.Definition) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE queue_definitions
+         SET max_attempts = $1, base_backoff_ms = $2, rate_limit_per_second = $3,
+             allowed_types = $4, default_type = $5, strict_payload = $6, paused = $7, draining = $8,
+             insight_policy = $9, payload_retention_days = $10, updated_at = $11
+         WHERE name = $12`,
+		def.MaxAttempts, def.BaseBackoffMs, def.RateLimitPerSecond,
+		allowedTypes(def), def.DefaultType, def.StrictPayload, def.Paused, def.Draining, def.InsightPolicy, def.PayloadRetentionDays, def.UpdatedAt, def.Name,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return queue.ErrQueueNotDefined
+	}
+	return nil
+}
+
+func (r *PostgresQueueDefinitionRepository) Delete(ctx context.Context, name string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM queue_definitions WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return queue.ErrQueueNotDefined
+	}
+	return nil
+}
+
+// allowedTypes returns the definition's allowed types, never nil since the column is NOT NULL
+func allowedTypes(def *queue.Definition) []string {
+	if def.AllowedTypes == nil {
+		return []string{}
+	}
+	return def.AllowedTypes
+}
+
+func scanQueueDefinition(row rowScanner) (*queue.Definition, error) {
+	def := &queue.Definition{}
+	err := row.Scan(
+		&def.Name, &def.MaxAttempts, &def.BaseBackoffMs, &def.RateLimitPerSecond,
+		&def.AllowedTypes, &def.DefaultType, &def.StrictPayload, &def.Paused, &def.Draining, &def.InsightPolicy, &def.PayloadRetentionDays, &def.CreatedAt, &def.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, queue.ErrQueueNotDefined
+	}
+	if err != nil {
+		return nil, err
+	}
+	return def, nil
+}
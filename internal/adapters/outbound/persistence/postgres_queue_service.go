@@ -0,0 +1,236 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultQueuePollInterval is how often a waiting Dequeue looks for jobs again when no
+// interval is set
+const DefaultQueuePollInterval = 250 * time.Millisecond
+
+// PostgresQueueService implements queue.QueueService over the jobs table, for deployments
+// that don't use Redis as the queue backend. A job's row is its queue entry: pending and
+// retrying jobs due to run are ready, and Dequeue claims the oldest with FOR UPDATE SKIP
+// LOCKED, moving it to processing in the same statement so concurrent workers never claim
+// it twice. The worker records every outcome on the row itself, so there is nothing to
+// acknowledge, and failed rows are the dead letters.
+type PostgresQueueService struct {
+	db           *pgxpool.Pool
+	pollInterval time.Duration
+}
+
+// NewPostgresQueueService creates a queue service claiming jobs from the jobs table
+func NewPostgresQueueService(db *pgxpool.Pool) *PostgresQueueService {
+	return &PostgresQueueService{db: db, pollInterval: DefaultQueuePollInterval}
+}
+
+// WithPollInterval sets how often a waiting Dequeue looks for jobs again; 0 or less uses
+// DefaultQueuePollInterval
+func (s *PostgresQueueService) WithPollInterval(interval time.Duration) *PostgresQueueService {
+	if interval <= 0 {
+		interval = DefaultQueuePollInterval
+	}
+	s.pollInterval = interval
+	return s
+}
+
+// Enqueue only wakes the workers listening with a PostgresJobListener: the job was made ready
+// when its row was written
+func (s *PostgresQueueService) Enqueue(ctx context.Context, job *queue.Job) error {
+	return s.notifyReady(ctx, job.Queue)
+}
+
+// notifyReady wakes the workers of the queue; within a transaction, once it commits
+func (s *PostgresQueueService) notifyReady(ctx context.Context, queueName string) error {
+	_, err := conn(ctx, s.db).Exec(ctx, `SELECT pg_notify($1, $2)`, jobReadyChannel, queueName)
+	return err
+}
+
+// Dequeue claims the oldest ready job of the queue the capabilities cover, looking again every
+// poll interval until the timeout passes; a timeout of zero or less doesn't wait at all.
+// The job is returned as it was before it was claimed, so the worker moves it to processing
+// as with any backend.
+func (s *PostgresQueueService) Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*queue.Job, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := s.claim(ctx, queueName, capabilities)
+		if job != nil || err != nil {
+			return job, err
+		}
+
+		wait := min(s.pollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return nil, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// claimedJobColumns are the columns a claim returns: the job as it was claimed, so a Nack knows
+// which status to return it to, but at the version the claim stored
+var claimedJobColumns = strings.Replace(qualifiedJobColumns, "j.version", "jobs.version", 1)
+
+// claim moves the oldest ready job to processing, skipping the rows other workers are claiming
+func (s *PostgresQueueService) claim(ctx context.Context, queueName string, capabilities []string) (*queue.Job, error) {
+	if capabilities == nil {
+		capabilities = []string{}
+	}
+	row := s.db.QueryRow(ctx,
+		`UPDATE jobs SET status = $5, updated_at = NOW(), version = jobs.version + 1
+         FROM (
+             SELECT `+jobColumns+`
+             `+pendingJobsFilter+`
+             AND requires <@ $4::text[]
+             ORDER BY created_at ASC
+             LIMIT 1
+             FOR UPDATE SKIP LOCKED
+         ) AS j
+         WHERE jobs.id = j.id
+         RETURNING `+claimedJobColumns,
+		queueName, queue.StatusPending, queue.StatusRetrying, capabilities, queue.StatusProcessing,
+	)
+	job, err := scanJob(row)
+	if errors.Is(err, queue.ErrJobNotFound) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// Acknowledge has nothing to do: the worker already recorded the job's outcome on its row
+func (s *PostgresQueueService) Acknowledge(ctx context.Context, jobID uuid.UUID) error {
+	return nil
+}
+
+// Nack makes a claimed job ready again in the status it was claimed in. Only the claim the job
+// was read at is returned: once the worker moved the job on, e.g. to retrying with a backoff,
+// another worker may have claimed it again, and that claim is left alone.
+func (s *PostgresQueueService) Nack(ctx context.Context, job *queue.Job) error {
+	if job.Status != queue.StatusPending && job.Status != queue.StatusRetrying {
+		return nil
+	}
+	_, err := conn(ctx, s.db).Exec(ctx,
+		`UPDATE jobs SET status = $2, updated_at = NOW(), version = version + 1
+         WHERE id = $1 AND status = $3 AND version = $4`,
+		job.ID, job.Status, queue.StatusProcessing, job.Version,
+	)
+	if err != nil {
+		return err
+	}
+	// Either way the job is ready now; a retrying one is handed back once its backoff passed
+	return s.notifyReady(ctx, job.Queue)
+}
+
+// DeliveryStats counts the jobs of every queue by where they are in delivery. Finished jobs
+// count as acknowledged; returned jobs aren't told apart from new ones, so Nacked stays zero.
+func (s *PostgresQueueService) DeliveryStats(ctx context.Context) ([]*queue.DeliveryStats, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT queue,
+                COUNT(*) FILTER (WHERE status IN ($1, $2)),
+                COUNT(*) FILTER (WHERE status = $3),
+                COUNT(*) FILTER (WHERE status IN ($4, $5) AND (scheduled_for IS NULL OR scheduled_for <= NOW()))
+         FROM jobs
+         WHERE deleted_at IS NULL
+         GROUP BY queue
+         ORDER BY queue`,
+		queue.StatusCompleted, queue.StatusFailed, queue.StatusProcessing, queue.StatusPending, queue.StatusRetrying,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*queue.DeliveryStats
+	for rows.Next() {
+		delivery := &queue.DeliveryStats{}
+		if err := rows.Scan(&delivery.Queue, &delivery.Acked, &delivery.Unacked, &delivery.Ready); err != nil {
+			return nil, err
+		}
+		stats = append(stats, delivery)
+	}
+	return stats, rows.Err()
+}
+
+// Withdraw reports whether the job is still ready, i.e. no worker claimed it. Within a unit of
+// work holding the job's row lock, workers skip the row until the unit of work ends.
+func (s *PostgresQueueService) Withdraw(ctx context.Context, job *queue.Job) (bool, error) {
+	var ready bool
+	err := conn(ctx, s.db).QueryRow(ctx,
+		`SELECT status IN ($2, $3) FROM jobs WHERE id = $1 AND deleted_at IS NULL`,
+		job.ID, queue.StatusPending, queue.StatusRetrying,
+	).Scan(&ready)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return ready, err
+}
+
+// BeginPurge returns the service itself, as a purge has nothing to read ahead
+func (s *PostgresQueueService) BeginPurge(ctx context.Context, queueName string) (queue.QueuePurge, error) {
+	return s, nil
+}
+
+// Purge reports the jobs still ready, i.e. that no worker claimed. The jobs table is the queue,
+// so cancelling them removes them from it; within the purge's unit of work their rows are locked
+// and workers skip them until it ends.
+func (s *PostgresQueueService) Purge(ctx context.Context, jobs []*queue.Job) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	rows, err := conn(ctx, s.db).Query(ctx,
+		`SELECT id FROM jobs WHERE id = ANY($1) AND status IN ($2, $3) AND deleted_at IS NULL`,
+		ids, queue.StatusPending, queue.StatusRetrying,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ready []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ready = append(ready, id)
+	}
+	return ready, rows.Err()
+}
+
+// Snapshot returns the queue's ready and claimed jobs. The jobs table is the queue, so the
+// snapshot always holds the jobs the database says are ready.
+func (s *PostgresQueueService) Snapshot(ctx context.Context, queueName string) (queue.QueueSnapshot, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id FROM jobs
+         WHERE queue = $1 AND status IN ($2, $3, $4) AND deleted_at IS NULL`,
+		queueName, queue.StatusPending, queue.StatusRetrying, queue.StatusProcessing,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshot := make(queue.QueueSnapshot)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		snapshot[id] = struct{}{}
+	}
+	return snapshot, rows.Err()
+}
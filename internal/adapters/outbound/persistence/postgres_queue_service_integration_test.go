@@ -0,0 +1,65 @@
+//go:build integration
+
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresQueueService_Nack(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+	jobRepo := persistence.NewPostgresJobRepository(env.Postgres.Pool)
+	queueService := persistence.NewPostgresQueueService(env.Postgres.Pool)
+
+	t.Run("Given a claimed job, When nacking it, Then should make it ready again", func(t *testing.T) {
+		job := testsupport.NewJobBuilder().WithQueue("nack-claimed").Build()
+		require.NoError(t, jobRepo.Create(ctx, job))
+		claimed, err := queueService.Dequeue(ctx, "nack-claimed", nil, 0)
+		require.NoError(t, err)
+		require.NotNil(t, claimed)
+
+		require.NoError(t, queueService.Nack(ctx, claimed))
+
+		stored, err := jobRepo.GetByID(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, queue.StatusPending, stored.Status)
+	})
+
+	t.Run("Given a retrying job claimed again by another worker, When the first worker nacks it, Then should leave the new claim alone", func(t *testing.T) {
+		job := testsupport.NewJobBuilder().WithQueue("nack-reclaimed").Build()
+		require.NoError(t, jobRepo.Create(ctx, job))
+		first, err := queueService.Dequeue(ctx, "nack-reclaimed", nil, 0)
+		require.NoError(t, err)
+		require.NotNil(t, first)
+		// The first worker runs the job, which fails and is stored as retrying once its backoff passed
+		require.NoError(t, first.MarkAsProcessing())
+		require.NoError(t, jobRepo.Update(ctx, first))
+		require.NoError(t, first.MarkAsFailed(errors.New("connection reset")))
+		first.Schedule(time.Now().UTC().Add(-time.Second))
+		require.NoError(t, first.MarkAsRetrying())
+		require.NoError(t, jobRepo.Update(ctx, first))
+		second, err := queueService.Dequeue(ctx, "nack-reclaimed", nil, 0)
+		require.NoError(t, err)
+		require.NotNil(t, second)
+
+		require.NoError(t, queueService.Nack(ctx, first))
+
+		stored, err := jobRepo.GetByID(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, queue.StatusProcessing, stored.Status)
+		assert.Equal(t, second.Version, stored.Version)
+		again, err := queueService.Dequeue(ctx, "nack-reclaimed", nil, 0)
+		require.NoError(t, err)
+		assert.Nil(t, again)
+	})
+}
@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresQueueStatsRepository implements queue.QueueStatsRepository using PostgreSQL
+type PostgresQueueStatsRepository struct {
+	db    *pgxpool.Pool
+	reads *ReadRouter
+}
+
+// NewPostgresQueueStatsRepository creates a new PostgreSQL queue stats repository
+func NewPostgresQueueStatsRepository(db *pgxpool.Pool) *PostgresQueueStatsRepository {
+	return &PostgresQueueStatsRepository{db: db, reads: NewReadRouter(db, nil)}
+}
+
+// WithReadRouter routes history queries through the given router
+func (r *PostgresQueueStatsRepository) WithReadRouter(reads *ReadRouter) *PostgresQueueStatsRepository {
+	r.reads = reads
+	return r
+}
+
+// CountByQueue counts live jobs per queue and status on the primary
+func (r *PostgresQueueStatsRepository) CountByQueue(ctx context.Context) ([]*queue.QueueStats, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT queue,
+                COUNT(*) FILTER (WHERE status = $1),
+                COUNT(*) FILTER (WHERE status = $2),
+                COUNT(*) FILTER (WHERE status = $3),
+                COUNT(*) FILTER (WHERE status = $4),
+                COUNT(*) FILTER (WHERE status = $5)
+         FROM jobs
+         WHERE deleted_at IS NULL
+         GROUP BY queue
+         ORDER BY queue`,
+		queue.StatusPending, queue.StatusProcessing, queue.StatusRetrying, queue.StatusFailed, queue.StatusCompleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*queue.QueueStats
+	for rows.Next() {
+		sample := &queue.QueueStats{}
+		if err := rows.Scan(&sample.Queue, &sample.Pending, &sample.Processing, &sample.Retrying, &sample.Failed, &sample.Completed); err != nil {
+			return nil, err
+		}
+		stats = append(stats, sample)
+	}
+	return stats, rows.Err()
+}
+
+// Record upserts the samples in one round trip
+func (r *PostgresQueueStatsRepository) Record(ctx context.Context, stats []*queue.QueueStats) error {
+	batch := &pgx.Batch{}
+	for _, sample := range stats {
+		batch.Queue(
+			`INSERT INTO queue_stats (queue, sampled_at, pending, processing, retrying, failed, completed)
+             VALUES ($1, $2, $3, $4, $5, $6, $7)
+             ON CONFLICT (queue, sampled_at) DO UPDATE
+             SET pending = EXCLUDED.pending, processing = EXCLUDED.processing, retrying = EXCLUDED.retrying,
+                 failed = EXCLUDED.failed, completed = EXCLUDED.completed`,
+			sample.Queue, sample.SampledAt, sample.Pending, sample.Processing, sample.Retrying, sample.Failed, sample.Completed,
+		)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+func (r *PostgresQueueStatsRepository) History(ctx context.Context, queueName string, since time.Time) ([]*queue.QueueStats, error) {
+	rows, err := r.reads.Query(ctx,
+		`SELECT queue, sampled_at, pending, processing, retrying, failed, completed
+         FROM queue_stats
+         WHERE queue = $1 AND sampled_at >= $2
+         ORDER BY sampled_at`,
+		queueName, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*queue.QueueStats{}
+	for rows.Next() {
+		sample := &queue.QueueStats{}
+		if err := rows.Scan(&sample.Queue, &sample.SampledAt, &sample.Pending, &sample.Processing, &sample.Retrying, &sample.Failed, &sample.Completed); err != nil {
+			return nil, err
+		}
+		stats = append(stats, sample)
+	}
+	return stats, rows.Err()
+}
+
+func (r *PostgresQueueStatsRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM queue_stats WHERE sampled_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
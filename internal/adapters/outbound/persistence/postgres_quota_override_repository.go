@@ -0,0 +1,35 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresQuotaOverrideRepository implements quota.OverrideRepository using PostgreSQL
+type PostgresQuotaOverrideRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresQuotaOverrideRepository creates a new PostgreSQL quota override repository
+func NewPostgresQuotaOverrideRepository(db *pgxpool.Pool) *PostgresQuotaOverrideRepository {
+	return &PostgresQuotaOverrideRepository{db: db}
+}
+
+func (r *PostgresQuotaOverrideRepository) Get(ctx context.Context, tenant string) (*quota.Override, error) {
+	override := &quota.Override{}
+	err := r.db.QueryRow(ctx,
+		`SELECT tenant, jobs_per_day, max_pending_jobs, analyses_per_day
+         FROM tenant_quotas WHERE tenant = $1`, tenant,
+	).Scan(&override.Tenant, &override.JobsPerDay, &override.MaxPendingJobs, &override.AnalysesPerDay)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, quota.ErrNoOverride
+	}
+	if err != nil {
+		return nil, err
+	}
+	return override, nil
+}
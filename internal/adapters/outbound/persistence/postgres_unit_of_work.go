@@ -0,0 +1,55 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// txKey carries the transaction of a unit of work in its context
+type txKey struct{}
+
+// querier is what a repository runs statements on: the pool, or the transaction of the unit
+// of work in progress
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// conn returns the transaction of the unit of work in ctx, or the pool outside one
+func conn(ctx context.Context, db *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// inTransaction reports whether ctx belongs to a unit of work
+func inTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return ok
+}
+
+// PostgresUnitOfWork implements queue.UnitOfWork with a PostgreSQL transaction. Repositories
+// built on the same pool join it through the context.
+type PostgresUnitOfWork struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresUnitOfWork creates a unit of work running transactions on the pool
+func NewPostgresUnitOfWork(db *pgxpool.Pool) *PostgresUnitOfWork {
+	return &PostgresUnitOfWork{db: db}
+}
+
+func (u *PostgresUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if inTransaction(ctx) {
+		return fn(ctx)
+	}
+	return pgx.BeginFunc(ctx, u.db, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
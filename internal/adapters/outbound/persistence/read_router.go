@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicaCooldown is how long reads stay on the primary after the replica failed
+const replicaCooldown = 30 * time.Second
+
+// ReadRouter sends heavy read queries to a read replica when one is configured,
+// falling back to the primary while the replica is unavailable.
+// Writes and read-your-writes lookups always go to the primary.
+type ReadRouter struct {
+	primary   *pgxpool.Pool
+	replica   *pgxpool.Pool
+	downUntil atomic.Int64 // unix nanos until which the replica is skipped
+}
+
+// NewReadRouter creates a router; replica may be nil to send everything to the primary
+func NewReadRouter(primary, replica *pgxpool.Pool) *ReadRouter {
+	return &ReadRouter{primary: primary, replica: replica}
+}
+
+func (r *ReadRouter) useReplica() bool {
+	return r.replica != nil && time.Now().UnixNano() >= r.downUntil.Load()
+}
+
+func (r *ReadRouter) markReplicaDown(err error) {
+	slog.Warn("Read replica unavailable, falling back to primary",
+		slog.Duration("cooldown", replicaCooldown),
+		slog.String("error", err.Error()),
+	)
+	r.downUntil.Store(time.Now().Add(replicaCooldown).UnixNano())
+}
+
+// Query runs a read query on the replica, retrying on the primary if the replica fails.
+// Inside a unit of work it runs on the transaction, which sees its own writes.
+func (r *ReadRouter) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if inTransaction(ctx) {
+		return conn(ctx, r.primary).Query(ctx, sql, args...)
+	}
+	if r.useReplica() {
+		rows, err := r.replica.Query(ctx, sql, args...)
+		if err == nil {
+			return rows, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		r.markReplicaDown(err)
+	}
+	return r.primary.Query(ctx, sql, args...)
+}
+
+// QueryRowScan runs a single-row read query and scans it into dest,
+// retrying on the primary if the replica fails for any reason other than no rows
+func (r *ReadRouter) QueryRowScan(ctx context.Context, sql string, args []any, dest ...any) error {
+	if inTransaction(ctx) {
+		return conn(ctx, r.primary).QueryRow(ctx, sql, args...).Scan(dest...)
+	}
+	if r.useReplica() {
+		err := r.replica.QueryRow(ctx, sql, args...).Scan(dest...)
+		if err == nil || errors.Is(err, pgx.ErrNoRows) || ctx.Err() != nil {
+			return err
+		}
+		r.markReplicaDown(err)
+	}
+	return r.primary.QueryRow(ctx, sql, args...).Scan(dest...)
+}
@@ -0,0 +1,108 @@
+package persistence
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// jobReadyChannel is the channel the queue services notify whenever they make a job ready to
+// dequeue; the payload is the queue name. RedisQueueService publishes on it, prefixed like its
+// keys, and PostgresQueueService sends it with NOTIFY.
+const jobReadyChannel = "job_ready"
+
+// listenRetryDelay is how long a listener waits before reconnecting after a failure
+const listenRetryDelay = 5 * time.Second
+
+// readySignals fans job-ready notifications out to per-queue channels
+type readySignals struct {
+	mu     sync.Mutex
+	queues map[string]chan struct{}
+}
+
+// Ready returns the wake-up channel for a queue. Notifications are coalesced:
+// while a wake-up is pending, further ones for the same queue are dropped.
+func (r *readySignals) Ready(queueName string) <-chan struct{} {
+	return r.channel(queueName)
+}
+
+func (r *readySignals) channel(queueName string) chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.queues == nil {
+		r.queues = make(map[string]chan struct{})
+	}
+	ch, ok := r.queues[queueName]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		r.queues[queueName] = ch
+	}
+	return ch
+}
+
+func (r *readySignals) signal(queueName string) {
+	select {
+	case r.channel(queueName) <- struct{}{}:
+	default:
+	}
+}
+
+// RedisJobListener implements queue.ReadySignal with the Redis pub/sub channel RedisQueueService
+// publishes on after pushing a job, so a woken worker finds the job in the list.
+type RedisJobListener struct {
+	readySignals
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisJobListener creates a listener for the queue service using the key prefix; call Run
+// to start receiving notifications
+func NewRedisJobListener(client *redis.Client, keyPrefix string) *RedisJobListener {
+	return &RedisJobListener{client: client, channel: keyPrefix + jobReadyChannel}
+}
+
+// Run listens for job notifications until ctx is cancelled, reconnecting on failure.
+// Workers keep polling while the listener is down.
+func (l *RedisJobListener) Run(ctx context.Context) {
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.WarnContext(ctx, "Job listener stopped, reconnecting",
+			slog.Duration("retryIn", listenRetryDelay),
+			slog.String("error", err.Error()),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (l *RedisJobListener) listen(ctx context.Context) error {
+	pubsub := l.client.Subscribe(ctx, l.channel)
+	defer pubsub.Close()
+
+	// Wait for the subscription so a failure to connect is retried
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Listening for job notifications",
+		slog.String("channel", l.channel),
+	)
+
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		l.signal(msg.Payload)
+	}
+}
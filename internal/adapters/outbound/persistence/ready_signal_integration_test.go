@@ -0,0 +1,43 @@
+//go:build integration
+
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisJobListener(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queueService := persistence.NewRedisQueueService(env.Redis.Client).WithKeyPrefix(env.KeyPrefix)
+	listener := persistence.NewRedisJobListener(env.Redis.Client, env.KeyPrefix)
+	ready := listener.Ready("wake")
+	go listener.Run(ctx)
+	// Pub/sub drops what is published before the subscription
+	time.Sleep(200 * time.Millisecond)
+
+	// Given a job pushed to the queue
+	job := testsupport.NewJobBuilder().WithQueue("wake").Build()
+	require.NoError(t, queueService.Enqueue(ctx, job))
+
+	// When the listener wakes the queue's workers
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no wake-up after the job was pushed")
+	}
+
+	// Then should find the job in the queue already
+	dequeued, err := queueService.Dequeue(ctx, "wake", nil, 0)
+	require.NoError(t, err)
+	require.NotNil(t, dequeued)
+	assert.Equal(t, job.ID, dequeued.ID)
+}
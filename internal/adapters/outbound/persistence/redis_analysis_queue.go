@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	analysisPendingKey    = "insights:analyze"
+	analysisProcessingKey = "insights:analyze:processing"
+)
+
+// DefaultAnalysisQueueMax is the backlog size used when none is configured
+const DefaultAnalysisQueueMax = 1000
+
+// RedisAnalysisQueue implements insights.AnalysisQueue with a pair of Redis lists.
+// Dequeued IDs are moved atomically to a processing list and claimed until completed, so
+// analyses interrupted by a shutdown or a crash are picked up again by Recover once their claim
+// expires.
+type RedisAnalysisQueue struct {
+	client       *redis.Client
+	maxSize      int64
+	prefix       string
+	claimTimeout time.Duration
+}
+
+// NewRedisAnalysisQueue creates a new Redis analysis queue holding at most maxSize pending jobs
+func NewRedisAnalysisQueue(client *redis.Client, maxSize int) *RedisAnalysisQueue {
+	if maxSize <= 0 {
+		maxSize = DefaultAnalysisQueueMax
+	}
+	return &RedisAnalysisQueue{client: client, maxSize: int64(maxSize), claimTimeout: DefaultClaimTimeout}
+}
+
+// WithKeyPrefix namespaces the queue's keys, e.g. "aisq:prod:"
+func (q *RedisAnalysisQueue) WithKeyPrefix(prefix string) *RedisAnalysisQueue {
+	q.prefix = prefix
+	return q
+}
+
+// WithClaimTimeout sets how long a dequeued job ID stays with its consumer before Recover
+// hands it to another; 0 or less uses DefaultClaimTimeout
+func (q *RedisAnalysisQueue) WithClaimTimeout(timeout time.Duration) *RedisAnalysisQueue {
+	if timeout <= 0 {
+		timeout = DefaultClaimTimeout
+	}
+	q.claimTimeout = timeout
+	return q
+}
+
+func (q *RedisAnalysisQueue) pendingKey() string {
+	return q.prefix + analysisPendingKey
+}
+
+func (q *RedisAnalysisQueue) claims() claimedList {
+	return claimedList{
+		client:        q.client,
+		pendingKey:    q.pendingKey(),
+		processingKey: q.prefix + analysisProcessingKey,
+		timeout:       q.claimTimeout,
+	}
+}
+
+func (q *RedisAnalysisQueue) Enqueue(ctx context.Context, jobID uuid.UUID) error {
+	size, err := q.client.LLen(ctx, q.pendingKey()).Result()
+	if err != nil {
+		return err
+	}
+	if size >= q.maxSize {
+		return insights.ErrAnalysisQueueFull
+	}
+	return q.client.LPush(ctx, q.pendingKey(), jobID.String()).Err()
+}
+
+func (q *RedisAnalysisQueue) Dequeue(ctx context.Context, timeout time.Duration) (*uuid.UUID, error) {
+	value, ok, err := q.claims().dequeue(ctx, timeout)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	jobID, err := uuid.Parse(value)
+	if err != nil {
+		// Drop malformed entries so they don't block the queue
+		q.claims().complete(ctx, value)
+		return nil, err
+	}
+	return &jobID, nil
+}
+
+func (q *RedisAnalysisQueue) Complete(ctx context.Context, jobID uuid.UUID) error {
+	return q.claims().complete(ctx, jobID.String())
+}
+
+// Recover moves the job IDs whose claim expired back to the queue, whichever consumer had them
+func (q *RedisAnalysisQueue) Recover(ctx context.Context) (int, error) {
+	return q.claims().recover(ctx)
+}
@@ -0,0 +1,63 @@
+//go:build integration
+
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisAnalysisQueue_Recover(t *testing.T) {
+	tests := []struct {
+		name         string
+		claimTimeout time.Duration
+		wait         time.Duration
+		want         int // Job IDs recovered by the other replica
+	}{
+		{
+			name:         "Given an analysis another replica is running, When recovering, Then should leave it claimed",
+			claimTimeout: time.Minute,
+		},
+		{
+			name:         "Given an analysis whose claim expired, When recovering, Then should queue it again",
+			claimTimeout: 50 * time.Millisecond,
+			wait:         100 * time.Millisecond,
+			want:         1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testsupport.Start(t)
+			ctx := context.Background()
+			running := persistence.NewRedisAnalysisQueue(env.Redis.Client, 10).WithKeyPrefix(env.KeyPrefix).WithClaimTimeout(tt.claimTimeout)
+			starting := persistence.NewRedisAnalysisQueue(env.Redis.Client, 10).WithKeyPrefix(env.KeyPrefix).WithClaimTimeout(tt.claimTimeout)
+
+			jobID := uuid.New()
+			require.NoError(t, running.Enqueue(ctx, jobID))
+			dequeued, err := running.Dequeue(ctx, time.Second)
+			require.NoError(t, err)
+			require.NotNil(t, dequeued)
+			time.Sleep(tt.wait)
+
+			recovered, err := starting.Recover(ctx)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, recovered)
+			next, err := starting.Dequeue(ctx, 100*time.Millisecond)
+			require.NoError(t, err)
+			if tt.want == 0 {
+				assert.Nil(t, next)
+			} else {
+				assert.Equal(t, &jobID, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/redis/go-redis/v9"
+)
+
+const breakersKey = "breakers"
+
+// RedisBreakerStore implements worker.BreakerStore with a Redis hash keyed by job type,
+// so queue-core can report breakers tripped by any worker-runtime replica
+type RedisBreakerStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisBreakerStore creates a new Redis breaker store
+func NewRedisBreakerStore(client *redis.Client) *RedisBreakerStore {
+	return &RedisBreakerStore{client: client}
+}
+
+// WithKeyPrefix namespaces the store's key, e.g. "aisq:prod:"
+func (s *RedisBreakerStore) WithKeyPrefix(prefix string) *RedisBreakerStore {
+	s.prefix = prefix
+	return s
+}
+
+func (s *RedisBreakerStore) key() string {
+	return s.prefix + breakersKey
+}
+
+func (s *RedisBreakerStore) Save(ctx context.Context, status worker.BreakerStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, s.key(), status.JobType, data).Err()
+}
+
+func (s *RedisBreakerStore) List(ctx context.Context) ([]worker.BreakerStatus, error) {
+	values, err := s.client.HGetAll(ctx, s.key()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]worker.BreakerStatus, 0, len(values))
+	for _, value := range values {
+		var status worker.BreakerStatus
+		if err := json.Unmarshal([]byte(value), &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].JobType < statuses[j].JobType })
+	return statuses, nil
+}
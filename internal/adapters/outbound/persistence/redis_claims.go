@@ -0,0 +1,83 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultClaimTimeout is how long a dequeued analysis stays claimed by its consumer before
+// Recover hands it to another; longer than an analysis takes, fallbacks and retries included
+const DefaultClaimTimeout = 30 * time.Minute
+
+// claimedList is a Redis list whose entries consumers claim: Dequeue moves an entry to the
+// processing list and records when in a sorted set next to it, Complete drops it from both, and
+// Recover moves back the entries claimed longer than the timeout ago. Every replica shares the
+// lists, so only claims that expired are taken back from consumers that may still run them.
+type claimedList struct {
+	client        *redis.Client
+	pendingKey    string
+	processingKey string
+	timeout       time.Duration
+}
+
+func (l claimedList) claimsKey() string {
+	return l.processingKey + ":claims"
+}
+
+// dequeue waits up to timeout for an entry and claims it; it returns false when none is available
+func (l claimedList) dequeue(ctx context.Context, timeout time.Duration) (string, bool, error) {
+	entry, err := l.client.BLMove(ctx, l.pendingKey, l.processingKey, "RIGHT", "LEFT", timeout).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	// An entry moved but not claimed yet is given a claim by the next Recover, not taken back
+	claim := redis.Z{Score: float64(time.Now().UnixMilli()), Member: entry}
+	if err := l.client.ZAdd(ctx, l.claimsKey(), claim).Err(); err != nil {
+		return "", false, err
+	}
+	return entry, true, nil
+}
+
+// complete drops a claimed entry
+func (l claimedList) complete(ctx context.Context, entry string) error {
+	pipe := l.client.TxPipeline()
+	pipe.LRem(ctx, l.processingKey, 1, entry)
+	pipe.ZRem(ctx, l.claimsKey(), entry)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// recoverClaimsScript moves the processing entries claimed at or before ARGV[2] back to the
+// front of the pending list, and starts the clock of the entries without a claim
+var recoverClaimsScript = redis.NewScript(`
+local entries = redis.call('LRANGE', KEYS[2], 0, -1)
+local recovered = 0
+for _, entry in ipairs(entries) do
+  local claimed = redis.call('ZSCORE', KEYS[3], entry)
+  if not claimed then
+    redis.call('ZADD', KEYS[3], 'NX', ARGV[1], entry)
+  elseif tonumber(claimed) <= tonumber(ARGV[2]) then
+    redis.call('LREM', KEYS[2], 1, entry)
+    redis.call('ZREM', KEYS[3], entry)
+    redis.call('RPUSH', KEYS[1], entry)
+    recovered = recovered + 1
+  end
+end
+return recovered
+`)
+
+// recover moves the entries whose claim expired back to the pending list, to be dequeued next
+func (l claimedList) recover(ctx context.Context) (int, error) {
+	now := time.Now()
+	recovered, err := recoverClaimsScript.Run(ctx, l.client,
+		[]string{l.pendingKey, l.processingKey, l.claimsKey()},
+		now.UnixMilli(), now.Add(-l.timeout).UnixMilli(),
+	).Int()
+	return recovered, err
+}
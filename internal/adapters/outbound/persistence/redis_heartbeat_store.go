@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/redis/go-redis/v9"
+)
+
+const heartbeatsKey = "worker_heartbeats"
+
+// RedisHeartbeatStore implements worker.HeartbeatStore with a Redis hash keyed by worker ID.
+// Heartbeats of workers that stopped reporting are removed when the store is listed.
+type RedisHeartbeatStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisHeartbeatStore creates a new Redis heartbeat store
+func NewRedisHeartbeatStore(client *redis.Client) *RedisHeartbeatStore {
+	return &RedisHeartbeatStore{client: client}
+}
+
+// WithKeyPrefix namespaces the store's key, e.g. "aisq:prod:"
+func (s *RedisHeartbeatStore) WithKeyPrefix(prefix string) *RedisHeartbeatStore {
+	s.prefix = prefix
+	return s
+}
+
+func (s *RedisHeartbeatStore) key() string {
+	return s.prefix + heartbeatsKey
+}
+
+func (s *RedisHeartbeatStore) Beat(ctx context.Context, heartbeat worker.Heartbeat) error {
+	data, err := json.Marshal(heartbeat)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, s.key(), heartbeat.WorkerID, data).Err()
+}
+
+func (s *RedisHeartbeatStore) List(ctx context.Context) ([]worker.Heartbeat, error) {
+	values, err := s.client.HGetAll(ctx, s.key()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	heartbeats := make([]worker.Heartbeat, 0, len(values))
+	var gone []string
+	for workerID, value := range values {
+		var heartbeat worker.Heartbeat
+		if err := json.Unmarshal([]byte(value), &heartbeat); err != nil || !heartbeat.Alive(now) {
+			gone = append(gone, workerID)
+			continue
+		}
+		heartbeats = append(heartbeats, heartbeat)
+	}
+	if len(gone) > 0 {
+		// Best effort: a failed cleanup is retried on the next listing
+		s.client.HDel(ctx, s.key(), gone...)
+	}
+
+	sort.Slice(heartbeats, func(i, j int) bool { return heartbeats[i].WorkerID < heartbeats[j].WorkerID })
+	return heartbeats, nil
+}
@@ -0,0 +1,510 @@
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Codec names accepted by NewJobCodec
+const (
+	CodecJSON     = "json"
+	CodecMsgPack  = "msgpack"
+	CodecProtobuf = "protobuf"
+)
+
+// Binary entries start with a format byte. JSON entries have none, since a JSON object never
+// starts with these bytes, so entries written before codecs existed still decode.
+const (
+	msgpackFormat  byte = 0x01
+	protobufFormat byte = 0x02
+)
+
+var ErrUnknownCodec = errors.New("unknown queue codec, expected json, msgpack or protobuf")
+
+// JobCodec encodes jobs into Redis queue entries. Decoding detects the format of the entry
+// rather than assuming the configured one, so the codec of a deployment can be changed while
+// entries written with the previous one are still queued.
+type JobCodec interface {
+	Name() string
+	Encode(job *queue.Job) ([]byte, error)
+	Decode(data []byte) (*queue.Job, error)
+}
+
+// NewJobCodec returns the codec with the given name; an empty name selects JSON. Payloads are
+// compressed by the compressor, with the algorithm recorded in the entry; nil leaves them as they are.
+func NewJobCodec(name string, compressor *PayloadCompressor) (JobCodec, error) {
+	switch name {
+	case "", CodecJSON:
+		return jsonJobCodec{compressor: compressor}, nil
+	case CodecMsgPack:
+		return msgpackJobCodec{compressor: compressor}, nil
+	case CodecProtobuf:
+		return protobufJobCodec{compressor: compressor}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCodec, name)
+	}
+}
+
+// decodeJob decodes an entry written by any of the codecs
+func decodeJob(data []byte) (*queue.Job, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty queue entry")
+	}
+	switch data[0] {
+	case msgpackFormat:
+		return decodeMsgPackJob(data[1:])
+	case protobufFormat:
+		return decodeProtobufJob(data[1:])
+	default:
+		var entry jsonJob
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, err
+		}
+		return restorePayload(&entry.Job, entry.PayloadCodec)
+	}
+}
+
+// restorePayload decompresses the payload of a decoded job
+func restorePayload(job *queue.Job, payloadCodec string) (*queue.Job, error) {
+	payload, err := decompressPayload(payloadCodec, job.Payload)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	return job, nil
+}
+
+// jsonJob is the JSON form of a job. PayloadCodec names the algorithm the payload is compressed
+// with and is left out when it isn't, so uncompressed entries read the same as before.
+type jsonJob struct {
+	queue.Job
+	PayloadCodec string `json:",omitempty"`
+}
+
+type jsonJobCodec struct {
+	compressor *PayloadCompressor
+}
+
+func (jsonJobCodec) Name() string { return CodecJSON }
+
+func (c jsonJobCodec) Encode(job *queue.Job) ([]byte, error) {
+	entry := jsonJob{Job: *job}
+	var err error
+	entry.Payload, entry.PayloadCodec, err = c.compressor.Compress(job.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&entry)
+}
+
+func (jsonJobCodec) Decode(data []byte) (*queue.Job, error) {
+	return decodeJob(data)
+}
+
+// msgpackJob is the MessagePack form of a job, with short keys and empty fields left out
+type msgpackJob struct {
+	ID           uuid.UUID         `msgpack:"i"`
+	Queue        string            `msgpack:"q"`
+	Type         string            `msgpack:"t"`
+	Status       string            `msgpack:"s"`
+	Attempts     int               `msgpack:"a,omitempty"`
+	Payload      []byte            `msgpack:"p,omitempty"`
+	Result       []byte            `msgpack:"r,omitempty"`
+	Error        string            `msgpack:"e,omitempty"`
+	ScheduledFor *time.Time        `msgpack:"sf,omitempty"`
+	CallbackURL  string            `msgpack:"cb,omitempty"`
+	Signature    string            `msgpack:"sg,omitempty"`
+	SigningKeyID string            `msgpack:"sk,omitempty"`
+	CreatedAt    time.Time         `msgpack:"ca"`
+	UpdatedAt    time.Time         `msgpack:"ua"`
+	DeletedAt    *time.Time        `msgpack:"da,omitempty"`
+	Requires     []string          `msgpack:"rq,omitempty"`
+	PayloadCodec string            `msgpack:"pc,omitempty"`
+	GroupID      *uuid.UUID        `msgpack:"g,omitempty"`
+	DeadLetter   string            `msgpack:"dl,omitempty"`
+	Schema       int               `msgpack:"sv,omitempty"`
+	CreatedBy    string            `msgpack:"by,omitempty"`
+	Metadata     map[string]string `msgpack:"md,omitempty"`
+	Version      int               `msgpack:"v,omitempty"`
+}
+
+type msgpackJobCodec struct {
+	compressor *PayloadCompressor
+}
+
+func (msgpackJobCodec) Name() string { return CodecMsgPack }
+
+func (c msgpackJobCodec) Encode(job *queue.Job) ([]byte, error) {
+	payload, payloadCodec, err := c.compressor.Compress(job.Payload)
+	if err != nil {
+		return nil, err
+	}
+	data, err := msgpack.Marshal(&msgpackJob{
+		ID:           job.ID,
+		Queue:        job.Queue,
+		Type:         job.Type,
+		Status:       string(job.Status),
+		Attempts:     job.Attempts,
+		Payload:      payload,
+		Result:       job.Result,
+		Error:        job.Error,
+		ScheduledFor: job.ScheduledFor,
+		CallbackURL:  job.CallbackURL,
+		Signature:    job.Signature,
+		SigningKeyID: job.SigningKeyID,
+		CreatedAt:    job.CreatedAt,
+		UpdatedAt:    job.UpdatedAt,
+		DeletedAt:    job.DeletedAt,
+		Requires:     job.Requires,
+		PayloadCodec: payloadCodec,
+		GroupID:      job.GroupID,
+		DeadLetter:   job.DeadLetterReason,
+		Schema:       job.SchemaVersion,
+		CreatedBy:    job.CreatedBy,
+		Metadata:     job.Metadata,
+		Version:      job.Version,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{msgpackFormat}, data...), nil
+}
+
+func (msgpackJobCodec) Decode(data []byte) (*queue.Job, error) {
+	return decodeJob(data)
+}
+
+func decodeMsgPackJob(data []byte) (*queue.Job, error) {
+	var entry msgpackJob
+	if err := msgpack.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return restorePayload(&queue.Job{
+		ID:           entry.ID,
+		Queue:        entry.Queue,
+		Type:         entry.Type,
+		Status:       queue.Status(entry.Status),
+		Attempts:     entry.Attempts,
+		Payload:      entry.Payload,
+		Result:       entry.Result,
+		Error:        entry.Error,
+		ScheduledFor: utcPtr(entry.ScheduledFor),
+		CallbackURL:  entry.CallbackURL,
+		Signature:    entry.Signature,
+		SigningKeyID: entry.SigningKeyID,
+		CreatedAt:    entry.CreatedAt.UTC(),
+		UpdatedAt:    entry.UpdatedAt.UTC(),
+		DeletedAt:    utcPtr(entry.DeletedAt),
+		Requires:     entry.Requires,
+		GroupID:      entry.GroupID,
+		CreatedBy:    entry.CreatedBy,
+		Metadata:     entry.Metadata,
+		Version:      entry.Version,
+
+		DeadLetterReason: entry.DeadLetter,
+		SchemaVersion:    entry.Schema,
+	}, entry.PayloadCodec)
+}
+
+// Protobuf field numbers of a job. Times are Unix nanoseconds and left out when unset.
+// Numbers must never be reused so entries written by older releases keep decoding.
+//
+//	message Job {
+//	  bytes  id             = 1;
+//	  string queue          = 2;
+//	  string type           = 3;
+//	  string status         = 4;
+//	  int64  attempts       = 5;
+//	  bytes  payload        = 6;
+//	  bytes  result         = 7;
+//	  string error          = 8;
+//	  int64  scheduled_for  = 9;
+//	  string callback_url   = 10;
+//	  string signature      = 11;
+//	  string signing_key_id = 12;
+//	  int64  created_at     = 13;
+//	  int64  updated_at     = 14;
+//	  int64  deleted_at     = 15;
+//	  repeated string requires = 16;
+//	  string payload_codec  = 17; // Algorithm the payload is compressed with, unset when it isn't
+//	  bytes  group_id       = 18;
+//	  string dead_letter_reason = 19;
+//	  int64  schema_version = 20;
+//	  string created_by     = 21;
+//	  map<string, string> metadata = 22;
+//	  int64  version        = 23;
+//	}
+const (
+	pbJobID protowire.Number = iota + 1
+	pbJobQueue
+	pbJobType
+	pbJobStatus
+	pbJobAttempts
+	pbJobPayload
+	pbJobResult
+	pbJobError
+	pbJobScheduledFor
+	pbJobCallbackURL
+	pbJobSignature
+	pbJobSigningKeyID
+	pbJobCreatedAt
+	pbJobUpdatedAt
+	pbJobDeletedAt
+	pbJobRequires
+	pbJobPayloadCodec
+	pbJobGroupID
+	pbJobDeadLetterReason
+	pbJobSchemaVersion
+	pbJobCreatedBy
+	pbJobMetadata
+	pbJobVersion
+)
+
+// Protobuf field numbers of a metadata map entry
+const (
+	pbMetadataKey protowire.Number = iota + 1
+	pbMetadataValue
+)
+
+type protobufJobCodec struct {
+	compressor *PayloadCompressor
+}
+
+func (protobufJobCodec) Name() string { return CodecProtobuf }
+
+func (c protobufJobCodec) Encode(job *queue.Job) ([]byte, error) {
+	payload, payloadCodec, err := c.compressor.Compress(job.Payload)
+	if err != nil {
+		return nil, err
+	}
+	b := []byte{protobufFormat}
+	b = appendBytesField(b, pbJobID, job.ID[:])
+	b = appendBytesField(b, pbJobQueue, []byte(job.Queue))
+	b = appendBytesField(b, pbJobType, []byte(job.Type))
+	b = appendBytesField(b, pbJobStatus, []byte(job.Status))
+	if job.Attempts != 0 {
+		b = protowire.AppendTag(b, pbJobAttempts, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(job.Attempts))
+	}
+	b = appendBytesField(b, pbJobPayload, payload)
+	b = appendBytesField(b, pbJobResult, job.Result)
+	b = appendBytesField(b, pbJobError, []byte(job.Error))
+	if job.ScheduledFor != nil {
+		b = appendTimeField(b, pbJobScheduledFor, *job.ScheduledFor)
+	}
+	b = appendBytesField(b, pbJobCallbackURL, []byte(job.CallbackURL))
+	b = appendBytesField(b, pbJobSignature, []byte(job.Signature))
+	b = appendBytesField(b, pbJobSigningKeyID, []byte(job.SigningKeyID))
+	b = appendTimeField(b, pbJobCreatedAt, job.CreatedAt)
+	b = appendTimeField(b, pbJobUpdatedAt, job.UpdatedAt)
+	if job.DeletedAt != nil {
+		b = appendTimeField(b, pbJobDeletedAt, *job.DeletedAt)
+	}
+	for _, capability := range job.Requires {
+		b = appendBytesField(b, pbJobRequires, []byte(capability))
+	}
+	b = appendBytesField(b, pbJobPayloadCodec, []byte(payloadCodec))
+	if job.GroupID != nil {
+		b = appendBytesField(b, pbJobGroupID, job.GroupID[:])
+	}
+	b = appendBytesField(b, pbJobDeadLetterReason, []byte(job.DeadLetterReason))
+	if job.SchemaVersion != 0 {
+		b = protowire.AppendTag(b, pbJobSchemaVersion, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(job.SchemaVersion))
+	}
+	b = appendBytesField(b, pbJobCreatedBy, []byte(job.CreatedBy))
+	for _, key := range job.MetadataKeys() {
+		entry := appendBytesField(nil, pbMetadataKey, []byte(key))
+		entry = appendBytesField(entry, pbMetadataValue, []byte(job.Metadata[key]))
+		b = appendBytesField(b, pbJobMetadata, entry)
+	}
+	if job.Version != 0 {
+		b = protowire.AppendTag(b, pbJobVersion, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(job.Version))
+	}
+	return b, nil
+}
+
+func (protobufJobCodec) Decode(data []byte) (*queue.Job, error) {
+	return decodeJob(data)
+}
+
+func decodeProtobufJob(data []byte) (*queue.Job, error) {
+	job := &queue.Job{}
+	var payloadCodec string
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case typ == protowire.BytesType && num == pbJobPayloadCodec:
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			payloadCodec = string(value)
+		case typ == protowire.BytesType && (num <= pbJobRequires || num == pbJobGroupID || num == pbJobDeadLetterReason || num == pbJobCreatedBy || num == pbJobMetadata):
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			if err := setProtobufBytes(job, num, value); err != nil {
+				return nil, err
+			}
+		case typ == protowire.VarintType && (num <= pbJobDeletedAt || num == pbJobSchemaVersion || num == pbJobVersion):
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			setProtobufVarint(job, num, value)
+		default:
+			// Fields added by newer releases are skipped
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return restorePayload(job, payloadCodec)
+}
+
+func setProtobufBytes(job *queue.Job, num protowire.Number, value []byte) error {
+	switch num {
+	case pbJobID:
+		id, err := uuid.FromBytes(value)
+		if err != nil {
+			return err
+		}
+		job.ID = id
+	case pbJobQueue:
+		job.Queue = string(value)
+	case pbJobType:
+		job.Type = string(value)
+	case pbJobStatus:
+		job.Status = queue.Status(value)
+	case pbJobPayload:
+		job.Payload = append([]byte(nil), value...)
+	case pbJobResult:
+		job.Result = append([]byte(nil), value...)
+	case pbJobError:
+		job.Error = string(value)
+	case pbJobCallbackURL:
+		job.CallbackURL = string(value)
+	case pbJobSignature:
+		job.Signature = string(value)
+	case pbJobSigningKeyID:
+		job.SigningKeyID = string(value)
+	case pbJobRequires:
+		job.Requires = append(job.Requires, string(value))
+	case pbJobGroupID:
+		id, err := uuid.FromBytes(value)
+		if err != nil {
+			return err
+		}
+		job.GroupID = &id
+	case pbJobDeadLetterReason:
+		job.DeadLetterReason = string(value)
+	case pbJobCreatedBy:
+		job.CreatedBy = string(value)
+	case pbJobMetadata:
+		key, entryValue, err := decodeProtobufMetadataEntry(value)
+		if err != nil {
+			return err
+		}
+		if job.Metadata == nil {
+			job.Metadata = make(map[string]string)
+		}
+		job.Metadata[key] = entryValue
+	}
+	return nil
+}
+
+// decodeProtobufMetadataEntry decodes a map entry of the job's metadata, skipping unknown fields
+func decodeProtobufMetadataEntry(data []byte) (key, value string, err error) {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ != protowire.BytesType || (num != pbMetadataKey && num != pbMetadataValue) {
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return "", "", protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		field, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		data = data[n:]
+		if num == pbMetadataKey {
+			key = string(field)
+		} else {
+			value = string(field)
+		}
+	}
+	return key, value, nil
+}
+
+func setProtobufVarint(job *queue.Job, num protowire.Number, value uint64) {
+	at := time.Unix(0, int64(value)).UTC()
+	switch num {
+	case pbJobAttempts:
+		job.Attempts = int(value)
+	case pbJobScheduledFor:
+		job.ScheduledFor = &at
+	case pbJobCreatedAt:
+		job.CreatedAt = at
+	case pbJobUpdatedAt:
+		job.UpdatedAt = at
+	case pbJobDeletedAt:
+		job.DeletedAt = &at
+	case pbJobSchemaVersion:
+		job.SchemaVersion = int(value)
+	case pbJobVersion:
+		job.Version = int(value)
+	}
+}
+
+// appendBytesField appends a length-delimited field, leaving empty values out
+func appendBytesField(b []byte, num protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+// appendTimeField appends a time as Unix nanoseconds, leaving the zero time out
+func appendTimeField(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(t.UnixNano()))
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
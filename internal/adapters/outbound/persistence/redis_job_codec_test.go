@@ -0,0 +1,170 @@
+package persistence_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var codecNames = []string{persistence.CodecJSON, persistence.CodecMsgPack, persistence.CodecProtobuf}
+
+// codecTestJob returns a job with every field the codecs carry set
+func codecTestJob(payload []byte) *queue.Job {
+	at := time.Date(2026, 3, 14, 15, 9, 26, 535897932, time.UTC)
+	scheduledFor := at.Add(time.Hour)
+	deletedAt := at.Add(2 * time.Hour)
+	groupID := uuid.New()
+	return &queue.Job{
+		ID:               uuid.New(),
+		Queue:            "reports",
+		Type:             "data_processing",
+		Status:           queue.StatusFailed,
+		Attempts:         3,
+		Payload:          payload,
+		Result:           []byte(`{"rows":42}`),
+		Error:            "out of memory during data processing",
+		ScheduledFor:     &scheduledFor,
+		CallbackURL:      "https://example.com/hooks/jobs",
+		Signature:        "c2lnbmF0dXJl",
+		SigningKeyID:     "key-2",
+		Requires:         []string{"gpu", "linux"},
+		Version:          7,
+		GroupID:          &groupID,
+		CreatedBy:        "billing",
+		Metadata:         map[string]string{"source": "erp", "traceparent": "00-abc-def-01"},
+		CreatedAt:        at,
+		UpdatedAt:        at.Add(time.Minute),
+		DeletedAt:        &deletedAt,
+		DeadLetterReason: queue.DeadLetterMaxAttempts,
+		SchemaVersion:    2,
+	}
+}
+
+func TestJobCodec_RoundTrip(t *testing.T) {
+	large := bytes.Repeat([]byte(`{"line":"the same line again"},`), 100)
+	tests := []struct {
+		name string
+		in   struct {
+			compression string
+			payload     []byte
+		}
+	}{
+		{
+			name: "Given an uncompressed payload, When encoding and decoding a job, Then should restore every field",
+			in: struct {
+				compression string
+				payload     []byte
+			}{compression: persistence.CompressionNone, payload: []byte(`{"to":"test@example.com"}`)},
+		},
+		{
+			name: "Given a gzip compressed payload, When encoding and decoding a job, Then should restore every field",
+			in: struct {
+				compression string
+				payload     []byte
+			}{compression: persistence.CompressionGzip, payload: large},
+		},
+		{
+			name: "Given a zstd compressed payload, When encoding and decoding a job, Then should restore every field",
+			in: struct {
+				compression string
+				payload     []byte
+			}{compression: persistence.CompressionZstd, payload: large},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, name := range codecNames {
+			t.Run(tt.name+" ("+name+")", func(t *testing.T) {
+				compressor, err := persistence.NewPayloadCompressor(tt.in.compression, 1024)
+				require.NoError(t, err)
+				codec, err := persistence.NewJobCodec(name, compressor)
+				require.NoError(t, err)
+				job := codecTestJob(tt.in.payload)
+
+				data, err := codec.Encode(job)
+				require.NoError(t, err)
+				decoded, err := codec.Decode(data)
+
+				require.NoError(t, err)
+				assert.Equal(t, job, decoded)
+				if compressor != nil {
+					assert.Less(t, len(data), len(tt.in.payload), "payload should be stored compressed")
+				}
+			})
+		}
+	}
+}
+
+func TestJobCodec_DecodeAcrossFormats(t *testing.T) {
+	compressor, err := persistence.NewPayloadCompressor(persistence.CompressionZstd, 1024)
+	require.NoError(t, err)
+	job := codecTestJob(bytes.Repeat([]byte("abc"), 1000))
+
+	for _, encodedWith := range codecNames {
+		for _, decodedWith := range codecNames {
+			name := fmt.Sprintf("Given an entry written by the %s codec, When the %s codec decodes it, Then should restore the job", encodedWith, decodedWith)
+			t.Run(name, func(t *testing.T) {
+				encoder, err := persistence.NewJobCodec(encodedWith, compressor)
+				require.NoError(t, err)
+				decoder, err := persistence.NewJobCodec(decodedWith, nil)
+				require.NoError(t, err)
+
+				data, err := encoder.Encode(job)
+				require.NoError(t, err)
+				decoded, err := decoder.Decode(data)
+
+				require.NoError(t, err)
+				assert.Equal(t, job, decoded)
+			})
+		}
+	}
+}
+
+func TestJobCodec_Errors(t *testing.T) {
+	codec, err := persistence.NewJobCodec(persistence.CodecJSON, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{
+			name: "Given an empty entry, When decoding it, Then should fail",
+			in:   nil,
+		},
+		{
+			name: "Given a truncated protobuf entry, When decoding it, Then should fail",
+			in:   []byte{0x02, 0x0a, 0x10, 0x01},
+		},
+		{
+			name: "Given an entry compressed with an unknown algorithm, When decoding it, Then should fail with ErrUnknownCompression",
+			in:   []byte(`{"ID":"` + uuid.NewString() + `","Payload":"eA==","PayloadCodec":"brotli"}`),
+			want: persistence.ErrUnknownCompression,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := codec.Decode(tt.in)
+
+			require.Error(t, err)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("Given an unknown codec name, When creating the codec, Then should fail with ErrUnknownCodec", func(t *testing.T) {
+		_, err := persistence.NewJobCodec("avro", nil)
+
+		assert.ErrorIs(t, err, persistence.ErrUnknownCodec)
+	})
+}
@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// campaignScript takes a lease that is free or renews one the holder already has.
+// KEYS: lease. ARGV: holder, TTL in milliseconds. Returns 1 when the holder leads.
+var campaignScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if current then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// resignScript deletes a lease only when the holder has it.
+// KEYS: lease. ARGV: holder.
+var resignScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisLeaderElector implements queue.LeaderElector with one expiring key per lease holding
+// the leader's name
+type RedisLeaderElector struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLeaderElector creates a new Redis leader elector
+func NewRedisLeaderElector(client *redis.Client) *RedisLeaderElector {
+	return &RedisLeaderElector{client: client}
+}
+
+// WithKeyPrefix namespaces the elector's keys, e.g. "aisq:prod:"
+func (e *RedisLeaderElector) WithKeyPrefix(prefix string) *RedisLeaderElector {
+	e.prefix = prefix
+	return e
+}
+
+func (e *RedisLeaderElector) key(name string) string {
+	return e.prefix + "leader:" + name
+}
+
+func (e *RedisLeaderElector) Campaign(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	leads, err := campaignScript.Run(ctx, e.client, []string{e.key(name)}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return leads == 1, nil
+}
+
+func (e *RedisLeaderElector) Resign(ctx context.Context, name, holder string) error {
+	return resignScript.Run(ctx, e.client, []string{e.key(name)}, holder).Err()
+}
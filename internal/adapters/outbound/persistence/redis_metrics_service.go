@@ -0,0 +1,215 @@
+package persistence
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/redis/go-redis/v9"
+)
+
+// jobCountersKey holds every job counter in one hash, so all services add to the same counts
+const jobCountersKey = "metrics:job_counters"
+
+// jobDurationsKey holds every duration histogram in one hash, with fields "queue|type|le" counting
+// the runs of each bucket, by its upper bound, and "queue|type|sum" adding up their seconds
+const jobDurationsKey = "metrics:job_durations"
+
+// durationSumField names the hash field with the seconds of every run of a histogram
+const durationSumField = "sum"
+
+// metricsWriteTimeout bounds a counter update; metrics never hold up a job for long
+const metricsWriteTimeout = time.Second
+
+// RedisMetricsService implements queue.MetricsService and queue.MetricsReader with counters in
+// a Redis hash, so failures recorded by workers show in queue-core's metrics. Counter fields
+// are "event|queue|type", with "|category" appended for failures.
+type RedisMetricsService struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisMetricsService creates a new Redis metrics service
+func NewRedisMetricsService(client *redis.Client) *RedisMetricsService {
+	return &RedisMetricsService{client: client}
+}
+
+// WithKeyPrefix namespaces the service's key, e.g. "aisq:prod:"
+func (s *RedisMetricsService) WithKeyPrefix(prefix string) *RedisMetricsService {
+	s.prefix = prefix
+	return s
+}
+
+func (s *RedisMetricsService) key() string {
+	return s.prefix + jobCountersKey
+}
+
+func (s *RedisMetricsService) durationsKey() string {
+	return s.prefix + jobDurationsKey
+}
+
+// increment adds one to a counter; failures are logged, since losing a count beats failing a job
+func (s *RedisMetricsService) increment(fields ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsWriteTimeout)
+	defer cancel()
+
+	field := strings.Join(fields, "|")
+	if err := s.client.HIncrBy(ctx, s.key(), field, 1).Err(); err != nil {
+		slog.Warn("Failed to record job metric",
+			slog.String("counter", field),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (s *RedisMetricsService) RecordJobCreated(queueName, jobType string) {
+	s.increment(queue.CounterCreated, queueName, jobType)
+}
+
+func (s *RedisMetricsService) RecordJobCompleted(queueName, jobType string, duration float64) {
+	s.increment(queue.CounterCompleted, queueName, jobType)
+	if duration > 0 {
+		s.observeDuration(queueName, jobType, duration)
+	}
+}
+
+// observeDuration counts a run in its histogram bucket; like counters, failures are only logged
+func (s *RedisMetricsService) observeDuration(queueName, jobType string, seconds float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsWriteTimeout)
+	defer cancel()
+
+	histogram := queueName + "|" + jobType + "|"
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, s.durationsKey(), histogram+durationBound(queue.DurationBucket(seconds)), 1)
+		pipe.HIncrByFloat(ctx, s.durationsKey(), histogram+durationSumField, seconds)
+		return nil
+	})
+	if err != nil {
+		slog.Warn("Failed to record job duration",
+			slog.String("queue", queueName),
+			slog.String("jobType", jobType),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// durationBound names a bucket by its upper bound in seconds, "+Inf" for the unbounded one
+func durationBound(bucket int) string {
+	if bucket >= len(queue.DurationBuckets) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(queue.DurationBuckets[bucket], 'g', -1, 64)
+}
+
+func (s *RedisMetricsService) RecordJobFailed(queueName, jobType string) {
+	s.RecordJobFailedWithReason(queueName, jobType, queue.FailureUnknown)
+}
+
+func (s *RedisMetricsService) RecordJobFailedWithReason(queueName, jobType, category string) {
+	s.increment(queue.CounterFailed, queueName, jobType, category)
+}
+
+func (s *RedisMetricsService) RecordJobRetried(queueName, jobType string) {
+	s.increment(queue.CounterRetried, queueName, jobType)
+}
+
+func (s *RedisMetricsService) RecordJobParked(queueName, jobType string) {
+	s.increment(queue.CounterParked, queueName, jobType)
+}
+
+func (s *RedisMetricsService) JobCounters(ctx context.Context) ([]*queue.JobCounter, error) {
+	values, err := s.client.HGetAll(ctx, s.key()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]*queue.JobCounter, 0, len(values))
+	for field, value := range values {
+		counter, ok := parseJobCounter(field, value)
+		if !ok {
+			continue
+		}
+		counters = append(counters, counter)
+	}
+	return counters, nil
+}
+
+func (s *RedisMetricsService) JobDurations(ctx context.Context) ([]*queue.DurationHistogram, error) {
+	values, err := s.client.HGetAll(ctx, s.durationsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[string]int, len(queue.DurationBuckets)+1)
+	for i := range len(queue.DurationBuckets) + 1 {
+		buckets[durationBound(i)] = i
+	}
+	histograms := make(map[string]*queue.DurationHistogram)
+	for field, value := range values {
+		// The queue comes first and the bucket last, so a job type containing "|" survives
+		i := strings.LastIndex(field, "|")
+		if i < 0 {
+			continue
+		}
+		name, suffix := field[:i], field[i+1:]
+		queueName, jobType, ok := strings.Cut(name, "|")
+		if !ok {
+			continue
+		}
+		histogram, ok := histograms[name]
+		if !ok {
+			histogram = queue.NewDurationHistogram(queueName, jobType)
+			histograms[name] = histogram
+		}
+		if suffix == durationSumField {
+			if sum, err := strconv.ParseFloat(value, 64); err == nil {
+				histogram.Sum = sum
+			}
+			continue
+		}
+		bucket, ok := buckets[suffix]
+		if !ok {
+			// Bucket of a former set of bounds
+			continue
+		}
+		if count, err := strconv.ParseInt(value, 10, 64); err == nil {
+			histogram.Counts[bucket] = count
+		}
+	}
+
+	result := make([]*queue.DurationHistogram, 0, len(histograms))
+	for _, histogram := range histograms {
+		result = append(result, histogram)
+	}
+	return result, nil
+}
+
+// parseJobCounter reads back a counter field; the event and queue come first and the category
+// of failures last, so a job type containing "|" survives
+func parseJobCounter(field, value string) (*queue.JobCounter, bool) {
+	event, rest, ok := strings.Cut(field, "|")
+	if !ok {
+		return nil, false
+	}
+	queueName, jobType, ok := strings.Cut(rest, "|")
+	if !ok {
+		return nil, false
+	}
+	counter := &queue.JobCounter{Event: event, Queue: queueName, Type: jobType}
+	if event == queue.CounterFailed {
+		i := strings.LastIndex(jobType, "|")
+		if i < 0 {
+			return nil, false
+		}
+		counter.Type, counter.Category = jobType[:i], jobType[i+1:]
+	}
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	counter.Count = count
+	return counter, true
+}
@@ -0,0 +1,102 @@
+//go:build integration
+
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisQueueService_RecoverExpired(t *testing.T) {
+	tests := []struct {
+		name              string
+		visibilityTimeout time.Duration
+		wait              time.Duration
+		acknowledge       bool
+		want              int // Jobs returned to the queue
+	}{
+		{
+			name:              "Given a job another worker is running, When recovering, Then should leave it in flight",
+			visibilityTimeout: time.Minute,
+		},
+		{
+			name:              "Given a job dequeued by a worker that died, When recovering after the visibility timeout, Then should queue it again",
+			visibilityTimeout: 50 * time.Millisecond,
+			wait:              100 * time.Millisecond,
+			want:              1,
+		},
+		{
+			name:              "Given a job acknowledged before its visibility timeout, When recovering, Then should leave it acknowledged",
+			visibilityTimeout: 50 * time.Millisecond,
+			wait:              100 * time.Millisecond,
+			acknowledge:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testsupport.Start(t)
+			ctx := context.Background()
+			queueService := persistence.NewRedisQueueService(env.Redis.Client).WithKeyPrefix(env.KeyPrefix).
+				WithVisibilityTimeout(tt.visibilityTimeout)
+
+			job := testsupport.NewJobBuilder().WithQueue("deliveries").Build()
+			require.NoError(t, queueService.Enqueue(ctx, job))
+			dequeued, err := queueService.Dequeue(ctx, "deliveries", nil, 0)
+			require.NoError(t, err)
+			require.NotNil(t, dequeued)
+			if tt.acknowledge {
+				require.NoError(t, queueService.Acknowledge(ctx, job.ID))
+			}
+			time.Sleep(tt.wait)
+
+			recovered, err := queueService.RecoverExpired(ctx)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, recovered)
+			next, err := queueService.Dequeue(ctx, "deliveries", nil, 0)
+			require.NoError(t, err)
+			if tt.want == 0 {
+				assert.Nil(t, next)
+			} else {
+				require.NotNil(t, next)
+				assert.Equal(t, job.ID, next.ID)
+			}
+		})
+	}
+}
+
+func TestRedisQueueService_RecoverUnclaimed(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+	queueService := persistence.NewRedisQueueService(env.Redis.Client).WithKeyPrefix(env.KeyPrefix).
+		WithVisibilityTimeout(50 * time.Millisecond)
+
+	// Given an entry moved to the in-flight list by a worker that died before claiming it
+	job := testsupport.NewJobBuilder().WithQueue("unclaimed").Build()
+	require.NoError(t, queueService.Enqueue(ctx, job))
+	require.NoError(t, env.Redis.Client.LMove(ctx,
+		env.KeyPrefix+"queue:unclaimed", env.KeyPrefix+"inflight:unclaimed", "RIGHT", "LEFT",
+	).Err())
+
+	// When recovering, Then should start its clock rather than take it back at once
+	recovered, err := queueService.RecoverExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+
+	// When recovering after the visibility timeout, Then should queue it again
+	time.Sleep(100 * time.Millisecond)
+	recovered, err = queueService.RecoverExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+	next, err := queueService.Dequeue(ctx, "unclaimed", nil, 0)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, job.ID, next.ID)
+}
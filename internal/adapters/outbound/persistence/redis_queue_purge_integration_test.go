@@ -0,0 +1,54 @@
+//go:build integration
+
+package persistence_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisQueueService_Purge(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+	queueService := persistence.NewRedisQueueService(env.Redis.Client).WithKeyPrefix(env.KeyPrefix)
+
+	// Given jobs in the queue, one of them popped by a worker
+	var jobs []*queue.Job
+	for i := 0; i < 4; i++ {
+		job := testsupport.NewJobBuilder().WithQueue("flood").Build()
+		require.NoError(t, queueService.Enqueue(ctx, job))
+		jobs = append(jobs, job)
+	}
+	popped, err := queueService.Dequeue(ctx, "flood", nil, 0)
+	require.NoError(t, err)
+	require.NotNil(t, popped)
+	purge, err := queueService.BeginPurge(ctx, "flood")
+	require.NoError(t, err)
+
+	// When purging them in two batches, with a job enqueued after the first
+	first, err := purge.Purge(ctx, jobs[:2])
+	require.NoError(t, err)
+	late := testsupport.NewJobBuilder().WithQueue("flood").Build()
+	require.NoError(t, queueService.Enqueue(ctx, late))
+	second, err := purge.Purge(ctx, append(jobs[2:], late))
+	require.NoError(t, err)
+
+	// Then should remove every job but the popped one and leave the queue empty
+	var want []uuid.UUID
+	for _, job := range append(jobs, late) {
+		if job.ID != popped.ID {
+			want = append(want, job.ID)
+		}
+	}
+	assert.ElementsMatch(t, want, append(first, second...))
+	left, err := queueService.Dequeue(ctx, "flood", nil, 0)
+	require.NoError(t, err)
+	assert.Nil(t, left)
+}
@@ -2,56 +2,724 @@ package persistence
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
-// RedisQueueService implements queue.QueueService using Redis
+const (
+	knownQueuesKey       = "queues"
+	processingIndexKey   = "processing:index"   // job ID -> queue name
+	processingEntriesKey = "processing:entries" // job ID -> in-flight entry
+	ackedStatsKey        = "stats:acked"        // queue name -> count
+	nackedStatsKey       = "stats:nacked"       // queue name -> count
+)
+
+// DefaultDeadLetterLimit is how many dead letters are kept per queue when no limit is set
+const DefaultDeadLetterLimit = 10000
+
+// DefaultVisibilityTimeout is how long a dequeued job stays with its worker before
+// RecoverExpired hands it to another; longer than a job runs and waits out its retry backoff
+const DefaultVisibilityTimeout = 30 * time.Minute
+
+// routePollInterval bounds how long a Dequeue covering several routes blocks on the plain
+// list alone before it looks at the route lists again
+const routePollInterval = time.Second
+
+// RedisQueueService implements queue.QueueService using Redis.
+// Jobs that require capabilities wait on a list per route next to the queue's plain list.
+// Dequeue moves a job's entry to the queue's in-flight list and claims it there with the time
+// it was dequeued, in one step, so a worker dying right after the pop can't lose the job. The
+// entry stays in flight until the job is acknowledged, nacked or dead-lettered, or its claim
+// expires and RecoverExpired returns it to its queue.
 type RedisQueueService struct {
-	client *redis.Client
+	client            *redis.Client
+	prefix            string
+	codec             JobCodec
+	deadLetterLimit   int
+	visibilityTimeout time.Duration
 }
 
-// NewRedisQueueService creates a new Redis queue service
+// NewRedisQueueService creates a new Redis queue service storing jobs as JSON
 func NewRedisQueueService(client *redis.Client) *RedisQueueService {
-	return &RedisQueueService{client: client}
+	return &RedisQueueService{
+		client:            client,
+		codec:             jsonJobCodec{},
+		deadLetterLimit:   DefaultDeadLetterLimit,
+		visibilityTimeout: DefaultVisibilityTimeout,
+	}
+}
+
+// WithKeyPrefix namespaces every key the service uses, e.g. "aisq:prod:"
+func (s *RedisQueueService) WithKeyPrefix(prefix string) *RedisQueueService {
+	s.prefix = prefix
+	return s
+}
+
+// WithCodec sets the codec new queue entries are written with. Entries are decoded in
+// whichever format they were written, so the codec can be switched on a live queue.
+func (s *RedisQueueService) WithCodec(codec JobCodec) *RedisQueueService {
+	s.codec = codec
+	return s
+}
+
+// WithDeadLetterLimit sets how many dead letters are kept per queue; the oldest are dropped
+// first. A limit of 0 or less uses DefaultDeadLetterLimit.
+func (s *RedisQueueService) WithDeadLetterLimit(limit int) *RedisQueueService {
+	if limit <= 0 {
+		limit = DefaultDeadLetterLimit
+	}
+	s.deadLetterLimit = limit
+	return s
+}
+
+// WithVisibilityTimeout sets how long a dequeued job stays with its worker before
+// RecoverExpired hands it to another; 0 or less uses DefaultVisibilityTimeout
+func (s *RedisQueueService) WithVisibilityTimeout(timeout time.Duration) *RedisQueueService {
+	if timeout <= 0 {
+		timeout = DefaultVisibilityTimeout
+	}
+	s.visibilityTimeout = timeout
+	return s
+}
+
+func (s *RedisQueueService) key(name string) string {
+	return s.prefix + name
+}
+
+func (s *RedisQueueService) queueKey(queueName string) string {
+	return s.key(fmt.Sprintf("queue:%s", queueName))
+}
+
+// routeKey is the list of the queue's jobs on a route; the empty route is the plain queue list
+func (s *RedisQueueService) routeKey(queueName, route string) string {
+	if route == "" {
+		return s.queueKey(queueName)
+	}
+	return s.queueKey(queueName) + "#" + route
+}
+
+// routesKey is the set of the non-empty routes jobs of the queue were pushed on
+func (s *RedisQueueService) routesKey(queueName string) string {
+	return s.key(fmt.Sprintf("routes:%s", queueName))
+}
+
+// routeKeys returns the lists of every route of the queue
+func (s *RedisQueueService) routeKeys(ctx context.Context, queueName string) ([]string, error) {
+	routes, err := s.client.SMembers(ctx, s.routesKey(queueName)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(routes)
+
+	keys := []string{s.queueKey(queueName)}
+	for _, route := range routes {
+		keys = append(keys, s.routeKey(queueName, route))
+	}
+	return keys, nil
+}
+
+// push queues a job on the list of its route and, once the transaction runs, wakes the workers
+// listening with a RedisJobListener
+func (s *RedisQueueService) push(ctx context.Context, pipe redis.Pipeliner, job *queue.Job, data []byte) {
+	route := job.Route()
+	if route != "" {
+		pipe.SAdd(ctx, s.routesKey(job.Queue), route)
+	}
+	pipe.LPush(ctx, s.routeKey(job.Queue, route), data)
+	pipe.Publish(ctx, s.key(jobReadyChannel), job.Queue)
+}
+
+// inFlightKey is the list of the entries of the queue's dequeued jobs
+func (s *RedisQueueService) inFlightKey(queueName string) string {
+	return s.key(fmt.Sprintf("inflight:%s", queueName))
+}
+
+// claimsKey scores the in-flight entries of the queue by when they were dequeued
+func (s *RedisQueueService) claimsKey(queueName string) string {
+	return s.inFlightKey(queueName) + ":claims"
+}
+
+// deadLetterKey is the list of the queue's dead letters, oldest first
+func (s *RedisQueueService) deadLetterKey(queueName string) string {
+	return s.key(fmt.Sprintf("dlq:%s", queueName))
 }
 
 func (s *RedisQueueService) Enqueue(ctx context.Context, job *queue.Job) error {
-	data, err := json.Marshal(job)
+	data, err := s.codec.Encode(job)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.SAdd(ctx, s.key(knownQueuesKey), job.Queue)
+	s.push(ctx, pipe, job, data)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// popScript moves the first entry of the route lists KEYS[1] to KEYS[#KEYS-2] to the in-flight
+// list KEYS[#KEYS-1] and claims it at ARGV[1] in the sorted set KEYS[#KEYS]
+var popScript = redis.NewScript(`
+local inflight, claims = KEYS[#KEYS - 1], KEYS[#KEYS]
+for i = 1, #KEYS - 2 do
+  local entry = redis.call('RPOP', KEYS[i])
+  if entry then
+    redis.call('LPUSH', inflight, entry)
+    redis.call('ZADD', claims, ARGV[1], entry)
+    return entry
+  end
+end
+return false
+`)
+
+// Dequeue pops from the lists of every route the capabilities cover, most specific first,
+// waiting up to timeout; a timeout of zero or less doesn't wait at all. While it waits it blocks
+// on the plain list with BLMOVE, looking at the route lists again every routePollInterval.
+func (s *RedisQueueService) Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*queue.Job, error) {
+	routes := queue.Routes(capabilities)
+	keys := make([]string, len(routes))
+	for i, route := range routes {
+		keys[i] = s.routeKey(queueName, route)
+	}
+
+	data, err := s.pop(ctx, queueName, keys, timeout)
+	if err != nil || data == "" {
+		return nil, err
+	}
+
+	job, err := s.codec.Decode([]byte(data))
+	if err != nil {
+		// An entry that can't be decoded would be recovered and fail again forever
+		pipe := s.client.TxPipeline()
+		pipe.LRem(ctx, s.inFlightKey(queueName), 1, data)
+		pipe.ZRem(ctx, s.claimsKey(queueName), data)
+		if _, dropErr := pipe.Exec(ctx); dropErr != nil {
+			return nil, dropErr
+		}
+		return nil, err
+	}
+
+	// Acknowledge, Nack and DeadLetter find the job's queue and entry by its ID
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.key(processingIndexKey), job.ID.String(), queueName)
+	pipe.HSet(ctx, s.key(processingEntriesKey), job.ID.String(), data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// pop moves an entry of the route lists to the in-flight list, returning it, or "" when none
+// came within the timeout
+func (s *RedisQueueService) pop(ctx context.Context, queueName string, keys []string, timeout time.Duration) (string, error) {
+	inFlight, claims := s.inFlightKey(queueName), s.claimsKey(queueName)
+	popKeys := append(append([]string{}, keys...), inFlight, claims)
+	deadline := time.Now().Add(timeout)
+	for {
+		data, err := popScript.Run(ctx, s.client, popKeys, time.Now().UnixMilli()).Text()
+		if err == nil || !errors.Is(err, redis.Nil) {
+			return data, err
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return "", nil
+		}
+		if len(keys) > 1 {
+			wait = min(wait, routePollInterval)
+		}
+		// The plain list is the last, least specific one
+		data, err = s.client.BLMove(ctx, keys[len(keys)-1], inFlight, "RIGHT", "LEFT", wait).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		// An entry moved but not claimed yet is given a claim by the next RecoverExpired
+		claim := redis.Z{Score: float64(time.Now().UnixMilli()), Member: data}
+		if err := s.client.ZAdd(ctx, claims, claim).Err(); err != nil {
+			return "", err
+		}
+		return data, nil
+	}
+}
+
+// inFlightEntry returns the in-flight entry of a dequeued job, "" when it isn't in flight
+func (s *RedisQueueService) inFlightEntry(ctx context.Context, jobID uuid.UUID) (string, error) {
+	entry, err := s.client.HGet(ctx, s.key(processingEntriesKey), jobID.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return entry, err
+}
+
+func (s *RedisQueueService) Acknowledge(ctx context.Context, jobID uuid.UUID) error {
+	pipe := s.client.Pipeline()
+	queueName := pipe.HGet(ctx, s.key(processingIndexKey), jobID.String())
+	entry := pipe.HGet(ctx, s.key(processingEntriesKey), jobID.String())
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if queueName.Val() == "" {
+		// Not in flight (already acked or never dequeued)
+		return nil
+	}
+
+	tx := s.client.TxPipeline()
+	s.removeFromProcessing(ctx, tx, queueName.Val(), jobID, entry.Val())
+	tx.HIncrBy(ctx, s.key(ackedStatsKey), queueName.Val(), 1)
+	_, err := tx.Exec(ctx)
+	return err
+}
+
+// AcknowledgeBatch acknowledges the jobs with two round trips whatever their number: one to
+// find their queues and entries, one to remove them from processing. Jobs not in flight are skipped.
+func (s *RedisQueueService) AcknowledgeBatch(ctx context.Context, jobIDs []uuid.UUID) error {
+	if len(jobIDs) == 0 {
+		return nil
+	}
+	fields := make([]string, len(jobIDs))
+	for i, jobID := range jobIDs {
+		fields[i] = jobID.String()
+	}
+	pipe := s.client.Pipeline()
+	queueNames := pipe.HMGet(ctx, s.key(processingIndexKey), fields...)
+	entries := pipe.HMGet(ctx, s.key(processingEntriesKey), fields...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	tx := s.client.TxPipeline()
+	acked := 0
+	for i, queueName := range queueNames.Val() {
+		name, ok := queueName.(string)
+		if !ok {
+			// Not in flight (already acked or never dequeued)
+			continue
+		}
+		entry, _ := entries.Val()[i].(string)
+		s.removeFromProcessing(ctx, tx, name, jobIDs[i], entry)
+		tx.HIncrBy(ctx, s.key(ackedStatsKey), name, 1)
+		acked++
+	}
+	if acked == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisQueueService) Nack(ctx context.Context, job *queue.Job) error {
+	data, err := s.codec.Encode(job)
+	if err != nil {
+		return err
+	}
+	entry, err := s.inFlightEntry(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	s.removeFromProcessing(ctx, pipe, job.Queue, job.ID, entry)
+	pipe.HIncrBy(ctx, s.key(nackedStatsKey), job.Queue, 1)
+	s.push(ctx, pipe, job, data)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeadLetter acknowledges the job and appends it to its queue's dead letters in one transaction
+func (s *RedisQueueService) DeadLetter(ctx context.Context, job *queue.Job) error {
+	data, err := s.codec.Encode(job)
 	if err != nil {
 		return err
 	}
+	entry, err := s.inFlightEntry(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	s.removeFromProcessing(ctx, pipe, job.Queue, job.ID, entry)
+	pipe.HIncrBy(ctx, s.key(ackedStatsKey), job.Queue, 1)
+	pipe.RPush(ctx, s.deadLetterKey(job.Queue), data)
+	pipe.LTrim(ctx, s.deadLetterKey(job.Queue), int64(-s.deadLetterLimit), -1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisQueueService) PeekDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	stop := int64(limit) - 1
+	if limit <= 0 {
+		stop = -1
+	}
+	entries, err := s.client.LRange(ctx, s.deadLetterKey(queueName), 0, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeDeadLetters(queueName, entries)
+}
+
+func (s *RedisQueueService) PopDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	entries, err := s.client.LPopCount(ctx, s.deadLetterKey(queueName), limit).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeDeadLetters(queueName, entries)
+}
+
+func (s *RedisQueueService) RestoreDeadLetters(ctx context.Context, queueName string, jobs []*queue.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	// LPUSH prepends its values one by one, so the newest goes first to keep the oldest in front
+	entries := make([]any, 0, len(jobs))
+	for i := len(jobs) - 1; i >= 0; i-- {
+		data, err := s.codec.Encode(jobs[i])
+		if err != nil {
+			return err
+		}
+		entries = append(entries, data)
+	}
+	return s.client.LPush(ctx, s.deadLetterKey(queueName), entries...).Err()
+}
 
-	key := fmt.Sprintf("queue:%s", job.Queue)
-	return s.client.LPush(ctx, key, data).Err()
+func (s *RedisQueueService) CountDeadLetters(ctx context.Context, queueName string) (int64, error) {
+	return s.client.LLen(ctx, s.deadLetterKey(queueName)).Result()
 }
 
-func (s *RedisQueueService) Dequeue(ctx context.Context, queueName string) (*queue.Job, error) {
-	key := fmt.Sprintf("queue:%s", queueName)
+func (s *RedisQueueService) decodeDeadLetters(queueName string, entries []string) ([]*queue.Job, error) {
+	jobs := make([]*queue.Job, 0, len(entries))
+	for _, data := range entries {
+		job, err := s.codec.Decode([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode dead letter of queue %s: %w", queueName, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// removeFromProcessing drops a dequeued job's in-flight entry, its claim and its index
+func (s *RedisQueueService) removeFromProcessing(ctx context.Context, pipe redis.Pipeliner, queueName string, jobID uuid.UUID, entry string) {
+	if entry != "" {
+		pipe.LRem(ctx, s.inFlightKey(queueName), 1, entry)
+		pipe.ZRem(ctx, s.claimsKey(queueName), entry)
+	}
+	pipe.HDel(ctx, s.key(processingIndexKey), jobID.String())
+	pipe.HDel(ctx, s.key(processingEntriesKey), jobID.String())
+}
+
+// requeueScript returns the in-flight entry ARGV[1] of job ARGV[2] to the front of its route
+// list KEYS[3] unless its claim in KEYS[2] is gone, i.e. the job was acknowledged meanwhile.
+// KEYS[1] is the in-flight list, KEYS[4] and KEYS[5] the processing index and entries, KEYS[6]
+// the routes set ARGV[3] joins, KEYS[7] the nacked stats of queue ARGV[4], and ARGV[5] the
+// channel waking the workers.
+var requeueScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[2], ARGV[1]) == 0 then
+  return 0
+end
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('RPUSH', KEYS[3], ARGV[1])
+if ARGV[3] ~= '' then
+  redis.call('SADD', KEYS[6], ARGV[3])
+end
+redis.call('HDEL', KEYS[4], ARGV[2])
+redis.call('HDEL', KEYS[5], ARGV[2])
+redis.call('HINCRBY', KEYS[7], ARGV[4], 1)
+redis.call('PUBLISH', ARGV[5], ARGV[4])
+return 1
+`)
+
+// RecoverExpired returns the jobs of every queue dequeued longer than the visibility timeout
+// ago, by workers that died or lost Redis before acknowledging them, to the front of their
+// queue, and starts the clock of the entries moved but never claimed. Every replica shares the
+// lists, so only claims that expired are taken back from workers that may still run them.
+func (s *RedisQueueService) RecoverExpired(ctx context.Context) (int, error) {
+	queueNames, err := s.client.SMembers(ctx, s.key(knownQueuesKey)).Result()
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(queueNames)
+
+	recovered := 0
+	for _, queueName := range queueNames {
+		count, err := s.recoverQueue(ctx, queueName)
+		recovered += count
+		if err != nil {
+			return recovered, err
+		}
+	}
+	return recovered, nil
+}
+
+// recoverQueue returns the queue's in-flight entries whose claim expired to their route lists
+func (s *RedisQueueService) recoverQueue(ctx context.Context, queueName string) (int, error) {
+	now := time.Now()
+	inFlight, claims := s.inFlightKey(queueName), s.claimsKey(queueName)
+	entries, err := s.client.LRange(ctx, inFlight, 0, -1).Result()
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	pipe := s.client.Pipeline()
+	for _, entry := range entries {
+		pipe.ZAddNX(ctx, claims, redis.Z{Score: float64(now.UnixMilli()), Member: entry})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	expired, err := s.client.ZRangeByScore(ctx, claims, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Add(-s.visibilityTimeout).UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	recovered := 0
+	for _, entry := range expired {
+		job, err := s.codec.Decode([]byte(entry))
+		if err != nil {
+			return recovered, fmt.Errorf("decode in-flight entry of queue %s: %w", queueName, err)
+		}
+		route := job.Route()
+		count, err := requeueScript.Run(ctx, s.client,
+			[]string{
+				inFlight, claims, s.routeKey(queueName, route),
+				s.key(processingIndexKey), s.key(processingEntriesKey), s.routesKey(queueName), s.key(nackedStatsKey),
+			},
+			entry, job.ID.String(), route, queueName, s.key(jobReadyChannel),
+		).Int()
+		if err != nil {
+			return recovered, err
+		}
+		recovered += count
+	}
+	return recovered, nil
+}
+
+func (s *RedisQueueService) DeliveryStats(ctx context.Context) ([]*queue.DeliveryStats, error) {
+	queueNames, err := s.client.SMembers(ctx, s.key(knownQueuesKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(queueNames)
 
-	result, err := s.client.BRPop(ctx, 0, key).Result()
+	acked, err := s.client.HGetAll(ctx, s.key(ackedStatsKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+	nacked, err := s.client.HGetAll(ctx, s.key(nackedStatsKey)).Result()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(result) < 2 {
+	pipe := s.client.Pipeline()
+	routes := make([]*redis.StringSliceCmd, len(queueNames))
+	for i, name := range queueNames {
+		routes[i] = pipe.SMembers(ctx, s.routesKey(name))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	pipe = s.client.Pipeline()
+	unacked := make([]*redis.IntCmd, len(queueNames))
+	ready := make([][]*redis.IntCmd, len(queueNames))
+	for i, name := range queueNames {
+		unacked[i] = pipe.LLen(ctx, s.inFlightKey(name))
+		ready[i] = append(ready[i], pipe.LLen(ctx, s.queueKey(name)))
+		for _, route := range routes[i].Val() {
+			ready[i] = append(ready[i], pipe.LLen(ctx, s.routeKey(name, route)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	stats := make([]*queue.DeliveryStats, 0, len(queueNames))
+	for i, name := range queueNames {
+		var readyCount int64
+		for _, length := range ready[i] {
+			readyCount += length.Val()
+		}
+		stats = append(stats, &queue.DeliveryStats{
+			Queue:   name,
+			Acked:   parseCount(acked[name]),
+			Nacked:  parseCount(nacked[name]),
+			Unacked: unacked[i].Val(),
+			Ready:   readyCount,
+		})
+	}
+	return stats, nil
+}
+
+// Withdraw finds the job's entry in the list of its route and removes it. Entries are matched
+// by decoding them, since the same job may have been encoded with another codec; LREM then
+// removes it unless a worker popped it in between.
+func (s *RedisQueueService) Withdraw(ctx context.Context, job *queue.Job) (bool, error) {
+	key := s.routeKey(job.Queue, job.Route())
+	entries, err := s.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, data := range entries {
+		queued, err := s.codec.Decode([]byte(data))
+		if err != nil {
+			return false, fmt.Errorf("decode entry of queue %s: %w", job.Queue, err)
+		}
+		if queued.ID != job.ID {
+			continue
+		}
+		removed, err := s.client.LRem(ctx, key, 1, data).Result()
+		if err != nil {
+			return false, err
+		}
+		return removed == 1, nil
+	}
+	return false, nil
+}
+
+// BeginPurge starts a purge that reads each route list of the queue once and indexes its entries
+// by job, so its batches don't decode the whole list again
+func (s *RedisQueueService) BeginPurge(ctx context.Context, queueName string) (queue.QueuePurge, error) {
+	return &redisPurge{
+		service: s,
+		entries: make(map[string]map[uuid.UUID]string),
+		gone:    make(map[uuid.UUID]struct{}),
+	}, nil
+}
+
+// redisPurge holds the entries of the route lists a purge read. Entries are matched by decoding
+// them, as Withdraw does.
+type redisPurge struct {
+	service *RedisQueueService
+	entries map[string]map[uuid.UUID]string // Route key to the entries of its jobs
+	gone    map[uuid.UUID]struct{}          // Jobs missing from their list once it was read again
+}
+
+// Purge removes the entries of the jobs with LREMs in one MULTI, so the queue loses them all at
+// once. A route list is read again only for a job it didn't hold when read, i.e. one enqueued
+// since; a job missing then, or an LREM finding nothing, means a worker popped it.
+func (p *redisPurge) Purge(ctx context.Context, jobs []*queue.Job) ([]uuid.UUID, error) {
+	type entry struct {
+		key   string
+		data  string
+		jobID uuid.UUID
+	}
+	var entries []entry
+	read := make(map[string]bool) // Lists read by this batch
+	for _, job := range jobs {
+		if _, ok := p.gone[job.ID]; ok {
+			continue
+		}
+		key := p.service.routeKey(job.Queue, job.Route())
+		index := p.entries[key]
+		data, ok := index[job.ID]
+		if !ok && !read[key] {
+			// Read the list the first time, and again for a job enqueued since
+			var err error
+			if index, err = p.read(ctx, key); err != nil {
+				return nil, err
+			}
+			read[key] = true
+			data, ok = index[job.ID]
+		}
+		if !ok {
+			p.gone[job.ID] = struct{}{}
+			continue
+		}
+		entries = append(entries, entry{key: key, data: data, jobID: job.ID})
+		delete(index, job.ID)
+	}
+	if len(entries) == 0 {
 		return nil, nil
 	}
 
-	var job queue.Job
-	if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
+	// Entries are pushed at the head, so the oldest, purged first, are found from the tail
+	pipe := p.service.client.TxPipeline()
+	removed := make([]*redis.IntCmd, len(entries))
+	for i, e := range entries {
+		removed[i] = pipe.LRem(ctx, e.key, -1, e.data)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
 		return nil, err
 	}
 
-	return &job, nil
+	purged := make([]uuid.UUID, 0, len(entries))
+	for i, e := range entries {
+		if removed[i].Val() == 1 {
+			purged = append(purged, e.jobID)
+		}
+	}
+	return purged, nil
 }
 
-func (s *RedisQueueService) Acknowledge(ctx context.Context, jobID uuid.UUID) error {
-	// Remove from processing set if we're tracking that
-	key := fmt.Sprintf("processing:%s", jobID.String())
-	return s.client.Del(ctx, key).Err()
+// read indexes the entries of the route list by job
+func (p *redisPurge) read(ctx context.Context, key string) (map[uuid.UUID]string, error) {
+	list, err := p.service.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[uuid.UUID]string, len(list))
+	for _, data := range list {
+		queued, err := p.service.codec.Decode([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode entry of %s: %w", key, err)
+		}
+		index[queued.ID] = data
+	}
+	p.entries[key] = index
+	return index, nil
+}
+
+// Snapshot reads the queue's route lists and in-flight list in one transaction. A dequeued job
+// moves from one to the other in one step, so every job the queue holds is in the snapshot.
+func (s *RedisQueueService) Snapshot(ctx context.Context, queueName string) (queue.QueueSnapshot, error) {
+	keys, err := s.routeKeys(ctx, queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := s.client.TxPipeline()
+	waiting := make([]*redis.StringSliceCmd, len(keys))
+	for i, key := range keys {
+		waiting[i] = pipe.LRange(ctx, key, 0, -1)
+	}
+	waiting = append(waiting, pipe.LRange(ctx, s.inFlightKey(queueName), 0, -1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	snapshot := make(queue.QueueSnapshot)
+	for _, list := range waiting {
+		for _, data := range list.Val() {
+			job, err := s.codec.Decode([]byte(data))
+			if err != nil {
+				return nil, fmt.Errorf("decode entry of queue %s: %w", queueName, err)
+			}
+			snapshot[job.ID] = struct{}{}
+		}
+	}
+	return snapshot, nil
+}
+
+// parseCount converts a Redis counter value, treating missing values as zero
+func parseCount(value string) int64 {
+	count, _ := strconv.ParseInt(value, 10, 64)
+	return count
 }
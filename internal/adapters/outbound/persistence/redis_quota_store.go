@@ -0,0 +1,146 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// quotaJobTenantsKey maps every pending job to the tenant that created it
+const quotaJobTenantsKey = "quota:job_tenants"
+
+// dailyCounterTTL keeps a day's counters readable for a while after the day ends
+const dailyCounterTTL = 48 * time.Hour
+
+// reserveJobScript counts a job against the daily and pending limits unless one is reached.
+// KEYS: daily jobs counter, pending jobs set, job tenants hash.
+// ARGV: jobs per day, max pending jobs, job ID, tenant, counter TTL in seconds.
+// Returns {0} when counted, {1} when the daily limit is reached, {2} when the pending one is.
+var reserveJobScript = redis.NewScript(`
+local jobs_per_day = tonumber(ARGV[1])
+local max_pending = tonumber(ARGV[2])
+
+if jobs_per_day > 0 and tonumber(redis.call('GET', KEYS[1]) or '0') >= jobs_per_day then
+	return {1}
+end
+if max_pending > 0 and redis.call('SCARD', KEYS[2]) >= max_pending then
+	return {2}
+end
+
+redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+redis.call('SADD', KEYS[2], ARGV[3])
+redis.call('HSET', KEYS[3], ARGV[3], ARGV[4])
+return {0}
+`)
+
+// reserveCounterScript increments a daily counter unless it reached the limit.
+// KEYS: daily counter. ARGV: limit, counter TTL in seconds. Returns 1 when counted.
+var reserveCounterScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+if limit > 0 and tonumber(redis.call('GET', KEYS[1]) or '0') >= limit then
+	return 0
+end
+redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// RedisQuotaStore implements quota.UsageStore with daily counters that expire on their own
+// and a set of the pending jobs of each tenant
+type RedisQuotaStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisQuotaStore creates a new Redis quota usage store
+func NewRedisQuotaStore(client *redis.Client) *RedisQuotaStore {
+	return &RedisQuotaStore{client: client}
+}
+
+// WithKeyPrefix namespaces the store's keys, e.g. "aisq:prod:"
+func (s *RedisQuotaStore) WithKeyPrefix(prefix string) *RedisQuotaStore {
+	s.prefix = prefix
+	return s
+}
+
+func (s *RedisQuotaStore) dailyKey(tenant string, resource quota.Resource, now time.Time) string {
+	return s.prefix + "quota:" + tenant + ":" + string(resource) + ":" + quota.Day(now).Format("2006-01-02")
+}
+
+func (s *RedisQuotaStore) pendingKey(tenant string) string {
+	return s.prefix + "quota:" + tenant + ":" + string(quota.ResourcePendingJobs)
+}
+
+func (s *RedisQuotaStore) ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID, limits quota.Quota, now time.Time) error {
+	res, err := reserveJobScript.Run(ctx, s.client,
+		[]string{s.dailyKey(tenant, quota.ResourceJobs, now), s.pendingKey(tenant), s.prefix + quotaJobTenantsKey},
+		limits.JobsPerDay, limits.MaxPendingJobs, jobID.String(), tenant, int(dailyCounterTTL.Seconds()),
+	).Int64Slice()
+	if err != nil {
+		return err
+	}
+
+	switch res[0] {
+	case 1:
+		return quota.NewExceededError(quota.ResourceJobs, limits.JobsPerDay, now)
+	case 2:
+		return quota.NewExceededError(quota.ResourcePendingJobs, limits.MaxPendingJobs, now)
+	default:
+		return nil
+	}
+}
+
+func (s *RedisQuotaStore) ReleaseJob(ctx context.Context, jobID uuid.UUID) error {
+	tenant, err := s.TenantOf(ctx, jobID)
+	if err != nil || tenant == "" {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.SRem(ctx, s.pendingKey(tenant), jobID.String())
+	pipe.HDel(ctx, s.prefix+quotaJobTenantsKey, jobID.String())
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisQuotaStore) TenantOf(ctx context.Context, jobID uuid.UUID) (string, error) {
+	tenant, err := s.client.HGet(ctx, s.prefix+quotaJobTenantsKey, jobID.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return tenant, err
+}
+
+func (s *RedisQuotaStore) ReserveAnalysis(ctx context.Context, tenant string, limits quota.Quota, now time.Time) error {
+	counted, err := reserveCounterScript.Run(ctx, s.client,
+		[]string{s.dailyKey(tenant, quota.ResourceAnalyses, now)},
+		limits.AnalysesPerDay, int(dailyCounterTTL.Seconds()),
+	).Int64()
+	if err != nil {
+		return err
+	}
+	if counted == 0 {
+		return quota.NewExceededError(quota.ResourceAnalyses, limits.AnalysesPerDay, now)
+	}
+	return nil
+}
+
+func (s *RedisQuotaStore) Usage(ctx context.Context, tenant string, now time.Time) (*quota.Usage, error) {
+	pipe := s.client.Pipeline()
+	jobs := pipe.Get(ctx, s.dailyKey(tenant, quota.ResourceJobs, now))
+	pending := pipe.SCard(ctx, s.pendingKey(tenant))
+	analyses := pipe.Get(ctx, s.dailyKey(tenant, quota.ResourceAnalyses, now))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	usage := &quota.Usage{Day: quota.Day(now), PendingJobs: pending.Val()}
+	usage.JobsToday, _ = jobs.Int64()
+	usage.AnalysesToday, _ = analyses.Int64()
+	return usage, nil
+}
@@ -0,0 +1,48 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const resultCacheKeyPrefix = "results:"
+
+// RedisResultCache implements worker.ResultCache with one expiring Redis string per cache key,
+// so the worker-runtime replicas share the results of idempotent jobs
+type RedisResultCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisResultCache creates a new Redis result cache
+func NewRedisResultCache(client *redis.Client) *RedisResultCache {
+	return &RedisResultCache{client: client}
+}
+
+// WithKeyPrefix namespaces the cache's keys, e.g. "aisq:prod:"
+func (c *RedisResultCache) WithKeyPrefix(prefix string) *RedisResultCache {
+	c.prefix = prefix
+	return c
+}
+
+func (c *RedisResultCache) key(cacheKey string) string {
+	return c.prefix + resultCacheKeyPrefix + cacheKey
+}
+
+func (c *RedisResultCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	result, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+func (c *RedisResultCache) Set(ctx context.Context, key string, result []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.key(key), result, ttl).Err()
+}
@@ -0,0 +1,127 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	slowRunPendingKey    = "insights:performance"
+	slowRunProcessingKey = "insights:performance:processing"
+)
+
+// RedisSlowRunQueue implements insights.SlowRunQueue with a pair of Redis lists and claims,
+// like RedisAnalysisQueue, holding each slow run as JSON
+type RedisSlowRunQueue struct {
+	client       *redis.Client
+	maxSize      int64
+	prefix       string
+	claimTimeout time.Duration
+}
+
+// storedSlowRun is the JSON form of a slow run
+type storedSlowRun struct {
+	JobID      uuid.UUID `json:"job_id"`
+	DurationMs int64     `json:"duration_ms"`
+	SLOMs      int64     `json:"slo_ms"`
+}
+
+// NewRedisSlowRunQueue creates a new Redis slow run queue holding at most maxSize pending runs
+func NewRedisSlowRunQueue(client *redis.Client, maxSize int) *RedisSlowRunQueue {
+	if maxSize <= 0 {
+		maxSize = DefaultAnalysisQueueMax
+	}
+	return &RedisSlowRunQueue{client: client, maxSize: int64(maxSize), claimTimeout: DefaultClaimTimeout}
+}
+
+// WithKeyPrefix namespaces the queue's keys, e.g. "aisq:prod:"
+func (q *RedisSlowRunQueue) WithKeyPrefix(prefix string) *RedisSlowRunQueue {
+	q.prefix = prefix
+	return q
+}
+
+func (q *RedisSlowRunQueue) pendingKey() string {
+	return q.prefix + slowRunPendingKey
+}
+
+// WithClaimTimeout sets how long a dequeued slow run stays with its consumer before Recover
+// hands it to another; 0 or less uses DefaultClaimTimeout
+func (q *RedisSlowRunQueue) WithClaimTimeout(timeout time.Duration) *RedisSlowRunQueue {
+	if timeout <= 0 {
+		timeout = DefaultClaimTimeout
+	}
+	q.claimTimeout = timeout
+	return q
+}
+
+func (q *RedisSlowRunQueue) claims() claimedList {
+	return claimedList{
+		client:        q.client,
+		pendingKey:    q.pendingKey(),
+		processingKey: q.prefix + slowRunProcessingKey,
+		timeout:       q.claimTimeout,
+	}
+}
+
+// encodeSlowRun returns the list entry of a run; encoding a run twice gives the same entry,
+// so Complete finds the one Dequeue moved
+func encodeSlowRun(run *insights.SlowRun) (string, error) {
+	data, err := json.Marshal(storedSlowRun{
+		JobID:      run.JobID,
+		DurationMs: run.Duration.Milliseconds(),
+		SLOMs:      run.SLO.Milliseconds(),
+	})
+	return string(data), err
+}
+
+func (q *RedisSlowRunQueue) Enqueue(ctx context.Context, run *insights.SlowRun) error {
+	entry, err := encodeSlowRun(run)
+	if err != nil {
+		return err
+	}
+	size, err := q.client.LLen(ctx, q.pendingKey()).Result()
+	if err != nil {
+		return err
+	}
+	if size >= q.maxSize {
+		return insights.ErrAnalysisQueueFull
+	}
+	return q.client.LPush(ctx, q.pendingKey(), entry).Err()
+}
+
+func (q *RedisSlowRunQueue) Dequeue(ctx context.Context, timeout time.Duration) (*insights.SlowRun, error) {
+	value, ok, err := q.claims().dequeue(ctx, timeout)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	var stored storedSlowRun
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		// Drop malformed entries so they don't block the queue
+		q.claims().complete(ctx, value)
+		return nil, err
+	}
+	return &insights.SlowRun{
+		JobID:    stored.JobID,
+		Duration: time.Duration(stored.DurationMs) * time.Millisecond,
+		SLO:      time.Duration(stored.SLOMs) * time.Millisecond,
+	}, nil
+}
+
+func (q *RedisSlowRunQueue) Complete(ctx context.Context, run *insights.SlowRun) error {
+	entry, err := encodeSlowRun(run)
+	if err != nil {
+		return err
+	}
+	return q.claims().complete(ctx, entry)
+}
+
+// Recover moves the slow runs whose claim expired back to the queue, whichever consumer had them
+func (q *RedisSlowRunQueue) Recover(ctx context.Context) (int, error) {
+	return q.claims().recover(ctx)
+}
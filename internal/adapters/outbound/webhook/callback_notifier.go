@@ -0,0 +1,417 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxAttempts   = 5
+	DefaultBaseBackoffMs = 1000
+	DefaultTimeout       = 10 * time.Second
+
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// CallbackNotifier implements worker.ResultNotifier by POSTing the final job state
+// to the job's callback URL, insights.AnalysisNotifier likewise for asynchronous
+// analyses and alert.Notifier for alerts of rules with a webhook. Deliveries run in the background and are retried with
+// exponential backoff; when a secret is configured each body is signed with HMAC-SHA256.
+type CallbackNotifier struct {
+	client        *http.Client
+	secret        []byte
+	maxAttempts   int
+	baseBackoffMs int
+	insightRepo   insights.InsightRepository // Optional; attaches the latest insight to failures
+
+	wg sync.WaitGroup
+}
+
+// NewCallbackNotifier creates a notifier; zero maxAttempts or timeout use the defaults
+func NewCallbackNotifier(secret string, maxAttempts int, timeout time.Duration, insightRepo insights.InsightRepository) *CallbackNotifier {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &CallbackNotifier{
+		client:        &http.Client{Timeout: timeout},
+		secret:        []byte(secret),
+		maxAttempts:   maxAttempts,
+		baseBackoffMs: DefaultBaseBackoffMs,
+		insightRepo:   insightRepo,
+	}
+}
+
+var _ worker.ResultNotifier = (*CallbackNotifier)(nil)
+
+type callbackJob struct {
+	ID        string            `json:"id"`
+	Queue     string            `json:"queue"`
+	Type      string            `json:"type"`
+	Status    string            `json:"status"`
+	Attempts  int               `json:"attempts"`
+	Payload   json.RawMessage   `json:"payload,omitempty"`
+	Result    json.RawMessage   `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt string            `json:"created_at"`
+	UpdatedAt string            `json:"updated_at"`
+}
+
+type callbackInsight struct {
+	ID             string                `json:"id"`
+	Diagnosis      string                `json:"diagnosis"`
+	Recommendation string                `json:"recommendation"`
+	SuggestedFix   insights.SuggestedFix `json:"suggested_fix"`
+	Confidence     float64               `json:"confidence"`
+	CreatedAt      string                `json:"created_at"`
+}
+
+type callbackBody struct {
+	Event   string           `json:"event"`
+	Job     callbackJob      `json:"job"`
+	Insight *callbackInsight `json:"insight,omitempty"`
+	SentAt  string           `json:"sent_at"`
+}
+
+// NotifyResult schedules delivery of the job's final state and returns immediately
+func (n *CallbackNotifier) NotifyResult(ctx context.Context, job *queue.Job) {
+	snapshot := *job
+	// Deliveries outlive the job's processing context, so shutdown doesn't cut them short
+	deliveryCtx := context.WithoutCancel(ctx)
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(deliveryCtx, &snapshot)
+	}()
+}
+
+var _ insights.AnalysisNotifier = (*CallbackNotifier)(nil)
+
+type callbackAnalysis struct {
+	ID        string `json:"id"`
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type analysisCallbackBody struct {
+	Event    string           `json:"event"`
+	Analysis callbackAnalysis `json:"analysis"`
+	Insight  *callbackInsight `json:"insight,omitempty"`
+	SentAt   string           `json:"sent_at"`
+}
+
+// NotifyAnalysis schedules delivery of an asynchronous analysis outcome and returns immediately
+func (n *CallbackNotifier) NotifyAnalysis(ctx context.Context, analysis *insights.Analysis, insight *insights.Insight) {
+	event := "analysis.completed"
+	if analysis.Status == insights.AnalysisFailed {
+		event = "analysis.failed"
+	}
+	body := analysisCallbackBody{
+		Event: event,
+		Analysis: callbackAnalysis{
+			ID:        analysis.ID.String(),
+			JobID:     analysis.JobID.String(),
+			Status:    string(analysis.Status),
+			Error:     analysis.Error,
+			CreatedAt: analysis.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			UpdatedAt: analysis.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		},
+		SentAt: time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if insight != nil {
+		body.Insight = newCallbackInsight(insight)
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode callback body",
+			slog.String("analysisId", analysis.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	deliveryCtx := context.WithoutCancel(ctx)
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.send(deliveryCtx, analysis.CallbackURL, event, encoded, slog.String("analysisId", analysis.ID.String()))
+	}()
+}
+
+var _ alert.Notifier = (*CallbackNotifier)(nil)
+
+type callbackAlert struct {
+	RuleID    string  `json:"rule_id"`
+	RuleName  string  `json:"rule_name"`
+	Condition string  `json:"condition"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	FiredAt   string  `json:"fired_at"`
+}
+
+type alertCallbackBody struct {
+	Event  string        `json:"event"`
+	Alert  callbackAlert `json:"alert"`
+	SentAt string        `json:"sent_at"`
+}
+
+// NotifyAlert schedules delivery of an alert to its rule's webhook and returns immediately
+func (n *CallbackNotifier) NotifyAlert(ctx context.Context, rule *alert.Rule, fired *alert.Alert) {
+	event := "alert.fired"
+	encoded, err := json.Marshal(alertCallbackBody{
+		Event: event,
+		Alert: callbackAlert{
+			RuleID:    fired.RuleID.String(),
+			RuleName:  fired.RuleName,
+			Condition: fired.Condition,
+			Value:     fired.Value,
+			Threshold: fired.Threshold,
+			FiredAt:   fired.FiredAt.Format("2006-01-02T15:04:05Z"),
+		},
+		SentAt: time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode callback body",
+			slog.String("ruleId", rule.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	deliveryCtx := context.WithoutCancel(ctx)
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.send(deliveryCtx, rule.WebhookURL, event, encoded, slog.String("ruleId", rule.ID.String()))
+	}()
+}
+
+// digestCallbackBody is what NotifyDigest posts to the digest webhook
+type digestCallbackBody struct {
+	Event  string         `json:"event"`
+	Digest callbackDigest `json:"digest"`
+	SentAt string         `json:"sent_at"`
+}
+
+type callbackDigest struct {
+	ID         string                 `json:"id"`
+	Growth     int64                  `json:"growth"`
+	DLQCount   int64                  `json:"dlq_count"`
+	Window     string                 `json:"window"`
+	Failed     int                    `json:"failed"`
+	Signatures []alert.SignatureCount `json:"signatures"`
+	Queues     []alert.QueueCount     `json:"queues"`
+	Insights   []alert.DigestInsight  `json:"insights"`
+	SentAt     string                 `json:"sent_at"`
+}
+
+var _ alert.DigestNotifier = (*CallbackNotifier)(nil)
+
+// NotifyDigest schedules delivery of a DLQ digest to the webhook and returns immediately
+func (n *CallbackNotifier) NotifyDigest(ctx context.Context, webhookURL string, digest *alert.Digest) {
+	event := "dlq.digest"
+	encoded, err := json.Marshal(digestCallbackBody{
+		Event: event,
+		Digest: callbackDigest{
+			ID:         digest.ID.String(),
+			Growth:     digest.Growth,
+			DLQCount:   digest.DLQCount,
+			Window:     digest.Window.String(),
+			Failed:     digest.Failed,
+			Signatures: orEmpty(digest.Signatures),
+			Queues:     orEmpty(digest.Queues),
+			Insights:   orEmpty(digest.Insights),
+			SentAt:     digest.SentAt.Format("2006-01-02T15:04:05Z"),
+		},
+		SentAt: time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode callback body",
+			slog.String("digestId", digest.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	deliveryCtx := context.WithoutCancel(ctx)
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.send(deliveryCtx, webhookURL, event, encoded, slog.String("digestId", digest.ID.String()))
+	}()
+}
+
+// orEmpty returns an empty slice for nil, so receivers get [] rather than null
+func orEmpty[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// Wait blocks until all scheduled deliveries have finished
+func (n *CallbackNotifier) Wait() {
+	n.wg.Wait()
+}
+
+func (n *CallbackNotifier) deliver(ctx context.Context, job *queue.Job) {
+	event := "job.completed"
+	if job.Status == queue.StatusFailed {
+		event = "job.failed"
+	}
+
+	body, err := json.Marshal(n.buildBody(ctx, event, job))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode callback body",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	n.send(ctx, job.CallbackURL, event, body, slog.String("jobId", job.ID.String()))
+}
+
+// send posts the body until it's accepted, a non-retryable error occurs or attempts run out
+func (n *CallbackNotifier) send(ctx context.Context, url, event string, body []byte, subject slog.Attr) {
+	deliveryID := uuid.NewString()
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		retryable, err := n.post(ctx, url, event, deliveryID, body)
+		if err == nil {
+			slog.InfoContext(ctx, "Callback delivered",
+				subject,
+				slog.String("event", event),
+				slog.Int("attempt", attempt),
+			)
+			return
+		}
+
+		slog.WarnContext(ctx, "Callback delivery failed",
+			subject,
+			slog.String("url", url),
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+		)
+		if !retryable || attempt == n.maxAttempts {
+			break
+		}
+		time.Sleep(worker.CalculateBackoff(attempt-1, n.baseBackoffMs))
+	}
+
+	slog.ErrorContext(ctx, "Giving up on callback",
+		subject,
+		slog.String("url", url),
+	)
+}
+
+func (n *CallbackNotifier) buildBody(ctx context.Context, event string, job *queue.Job) callbackBody {
+	body := callbackBody{
+		Event: event,
+		Job: callbackJob{
+			ID:        job.ID.String(),
+			Queue:     job.Queue,
+			Type:      job.Type,
+			Status:    string(job.Status),
+			Attempts:  job.Attempts,
+			Payload:   rawJSON(job.Payload),
+			Result:    rawJSON(job.Result),
+			Error:     job.Error,
+			Metadata:  job.Metadata,
+			CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			UpdatedAt: job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		},
+		SentAt: time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+
+	if n.insightRepo != nil && job.Status == queue.StatusFailed {
+		// The insight may still be in the analysis queue; the callback is sent without it then
+		if insight, err := n.insightRepo.GetByJobID(ctx, job.ID); err == nil && insight != nil {
+			body.Insight = newCallbackInsight(insight)
+		}
+	}
+	return body
+}
+
+func newCallbackInsight(insight *insights.Insight) *callbackInsight {
+	return &callbackInsight{
+		ID:             insight.ID.String(),
+		Diagnosis:      insight.Diagnosis,
+		Recommendation: insight.Recommendation,
+		SuggestedFix:   insight.SuggestedFix,
+		Confidence:     insight.Confidence,
+		CreatedAt:      insight.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// post sends one delivery attempt and reports whether a failure is worth retrying
+func (n *CallbackNotifier) post(ctx context.Context, url, event, deliveryID string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("callback returned status %d", resp.StatusCode)
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" that receivers recompute to verify a callback
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// rawJSON passes stored JSON through unchanged, dropping empty values
+func rawJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.RawMessage(data)
+}
@@ -0,0 +1,233 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// DLQ monitor defaults
+const (
+	DefaultDigestInterval = time.Minute
+	DLQDigestLease        = "dlq-digest"
+	digestPageSize        = 500
+)
+
+// DLQMonitor samples the DLQ size and, when it grows past the policy's threshold within its
+// window, sends a digest of the jobs that reached it: their top error signatures, the queues
+// they came from and the insights of a few of them. Digests are recorded in an audit log.
+type DLQMonitor struct {
+	dlq        alert.DLQReader
+	insights   alert.InsightFinder
+	log        alert.DigestLog
+	events     events.Publisher
+	notifier   alert.DigestNotifier
+	webhookURL string
+	policy     alert.DigestPolicy
+	history    *alert.DLQHistory
+	lastSent   *time.Time
+	now        func() time.Time
+}
+
+// NewDLQMonitor creates a monitor; it fails with alert.ErrInvalidDigestPolicy for an invalid policy
+func NewDLQMonitor(dlq alert.DLQReader, insightFinder alert.InsightFinder, log alert.DigestLog, policy alert.DigestPolicy) (*DLQMonitor, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	policy = policy.WithDefaults()
+	return &DLQMonitor{
+		dlq:      dlq,
+		insights: insightFinder,
+		log:      log,
+		events:   events.NopPublisher{},
+		policy:   policy,
+		history:  alert.NewDLQHistory(policy.Window),
+		now:      time.Now,
+	}, nil
+}
+
+// SetEventPublisher sets the publisher digests are announced through
+func (m *DLQMonitor) SetEventPublisher(publisher events.Publisher) {
+	m.events = publisher
+}
+
+// SetNotifier sets the notifier delivering digests to the webhook
+func (m *DLQMonitor) SetNotifier(notifier alert.DigestNotifier, webhookURL string) {
+	m.notifier = notifier
+	m.webhookURL = webhookURL
+}
+
+// Digests returns up to limit digests sent, newest first
+func (m *DLQMonitor) Digests(ctx context.Context, limit int) ([]*alert.Digest, error) {
+	return m.log.ListDigests(ctx, limit)
+}
+
+// Check samples the DLQ size and sends a digest when it grew past the threshold within the
+// window and no digest was sent within the cooldown. It returns the digest sent, or nil.
+func (m *DLQMonitor) Check(ctx context.Context) (*alert.Digest, error) {
+	now := m.now().UTC()
+	count, err := m.dlq.CountDLQJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	growth := m.history.Record(count, now)
+	if growth < m.policy.Threshold {
+		return nil, nil
+	}
+
+	if m.lastSent == nil {
+		// Another instance may have sent one before this one took over
+		latest, err := m.log.ListDigests(ctx, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) > 0 {
+			m.lastSent = &latest[0].SentAt
+		}
+	}
+	if m.lastSent != nil && now.Sub(*m.lastSent) < m.policy.Cooldown {
+		return nil, nil
+	}
+
+	jobs, err := m.deadLetteredSince(ctx, now.Add(-m.policy.Window))
+	if err != nil {
+		return nil, err
+	}
+	digest := alert.NewDigest(jobs, growth, count, m.policy, now)
+	m.attachInsights(ctx, digest)
+
+	if err := m.log.RecordDigest(ctx, digest); err != nil {
+		return nil, err
+	}
+	m.lastSent = &now
+
+	slog.WarnContext(ctx, "DLQ growing, digest sent",
+		slog.String("digestId", digest.ID.String()),
+		slog.Int64("growth", growth),
+		slog.Int64("dlqCount", count),
+		slog.Duration("window", m.policy.Window),
+	)
+	m.events.Publish(ctx, events.DLQDigestSent{
+		DigestID: digest.ID,
+		Growth:   growth,
+		DLQCount: count,
+		Window:   m.policy.Window.String(),
+		At:       now,
+	})
+	if m.notifier != nil && m.webhookURL != "" {
+		m.notifier.NotifyDigest(ctx, m.webhookURL, digest)
+	}
+	return digest, nil
+}
+
+// deadLetteredSince pages through the DLQ, most recently failed first, until the jobs failed
+// before since, reading MaxSamples jobs at most
+func (m *DLQMonitor) deadLetteredSince(ctx context.Context, since time.Time) ([]*queue.Job, error) {
+	var jobs []*queue.Job
+	for offset := 0; offset < MaxSamples; offset += digestPageSize {
+		page, err := m.dlq.GetDLQJobs(ctx, digestPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range page {
+			if job.UpdatedAt.Before(since) {
+				return jobs, nil
+			}
+			jobs = append(jobs, job)
+		}
+		if len(page) < digestPageSize {
+			break
+		}
+	}
+	return jobs, nil
+}
+
+// attachInsights adds the insight of the sample job of each top signature, up to the policy's
+// sample count. A digest is worth sending without them, so lookup failures are only logged.
+func (m *DLQMonitor) attachInsights(ctx context.Context, digest *alert.Digest) {
+	for _, signature := range digest.Signatures {
+		if len(digest.Insights) >= m.policy.SampleInsights {
+			return
+		}
+		insight, err := m.insights.GetByJobID(ctx, signature.SampleJobID)
+		if errors.Is(err, insights.ErrInsightNotFound) {
+			continue
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to read the insight of a DLQ digest",
+				slog.String("jobId", signature.SampleJobID.String()),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		digest.Insights = append(digest.Insights, alert.DigestInsight{
+			InsightID:      insight.ID,
+			JobID:          signature.SampleJobID,
+			Signature:      signature.Signature,
+			Diagnosis:      insight.Diagnosis,
+			Recommendation: insight.Recommendation,
+			Confidence:     insight.Confidence,
+		})
+	}
+}
+
+// Run checks the DLQ every interval until the context is cancelled. Only the instance holding
+// the lease samples it, so replicas send each digest once.
+func (m *DLQMonitor) Run(ctx context.Context, elector queue.LeaderElector, holder string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultDigestInterval
+	}
+	slog.InfoContext(ctx, "DLQ digest monitor started",
+		slog.String("holder", holder),
+		slog.Duration("interval", interval),
+		slog.Int64("threshold", m.policy.Threshold),
+		slog.Duration("window", m.policy.Window),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	leading := false
+	for {
+		leads, err := elector.Campaign(ctx, DLQDigestLease, holder, 3*interval)
+		if err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "Failed to campaign for the DLQ digest lease",
+				slog.String("error", err.Error()),
+			)
+		}
+		if leads != leading {
+			leading = leads
+			// The history of the previous leader is lost; growth is measured afresh
+			m.history = alert.NewDLQHistory(m.policy.Window)
+			m.lastSent = nil
+		}
+
+		if leading {
+			if _, err := m.Check(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to check the DLQ growth",
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if leading {
+				if err := elector.Resign(context.WithoutCancel(ctx), DLQDigestLease, holder); err != nil {
+					slog.WarnContext(ctx, "Failed to resign the DLQ digest lease",
+						slog.String("error", err.Error()),
+					)
+				}
+			}
+			slog.InfoContext(ctx, "DLQ digest monitor shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDLQReader struct {
+	mock.Mock
+}
+
+func (m *MockDLQReader) CountDLQJobs(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDLQReader) GetDLQJobs(ctx context.Context, limit, offset int) ([]*queue.Job, error) {
+	args := m.Called(ctx, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.Job), args.Error(1)
+}
+
+type MockInsightFinder struct {
+	mock.Mock
+}
+
+func (m *MockInsightFinder) GetByJobID(ctx context.Context, jobID uuid.UUID) (*insights.Insight, error) {
+	args := m.Called(ctx, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*insights.Insight), args.Error(1)
+}
+
+// InMemoryDigestLog is an in-memory alert.DigestLog
+type InMemoryDigestLog struct {
+	digests []*alert.Digest
+}
+
+func (l *InMemoryDigestLog) RecordDigest(ctx context.Context, digest *alert.Digest) error {
+	l.digests = append([]*alert.Digest{digest}, l.digests...)
+	return nil
+}
+
+func (l *InMemoryDigestLog) ListDigests(ctx context.Context, limit int) ([]*alert.Digest, error) {
+	return l.digests[:min(limit, len(l.digests))], nil
+}
+
+type RecordingDigestNotifier struct {
+	webhookURLs []string
+	digests     []*alert.Digest
+}
+
+func (n *RecordingDigestNotifier) NotifyDigest(ctx context.Context, webhookURL string, digest *alert.Digest) {
+	n.webhookURLs = append(n.webhookURLs, webhookURL)
+	n.digests = append(n.digests, digest)
+}
+
+func TestDLQMonitor_Check(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	timedOut := &queue.Job{ID: uuid.New(), Type: "send_email", Queue: "emails", Error: "i/o timeout", UpdatedAt: now.Add(-time.Minute)}
+	declined := &queue.Job{ID: uuid.New(), Type: "charge_card", Queue: "billing", Error: "card declined", UpdatedAt: now.Add(-2 * time.Minute)}
+	stale := &queue.Job{ID: uuid.New(), Type: "send_email", Queue: "emails", Error: "i/o timeout", UpdatedAt: now.Add(-2 * time.Hour)}
+	insight := &insights.Insight{ID: uuid.New(), JobID: timedOut.ID, Diagnosis: "SMTP relay unreachable", Recommendation: "Check the relay", Confidence: 0.8}
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		counts         []int64 // DLQ sizes sampled a minute apart
+		lastSent       *time.Time
+		expectDigest   bool
+		expectFailed   int
+		expectInsights int
+	}{
+		{
+			name:           "Surge",
+			given:          "a DLQ that grew past the threshold within the window",
+			when:           "checking it",
+			then:           "should record, publish and post a digest of the jobs failed in the window",
+			counts:         []int64{100, 108},
+			expectDigest:   true,
+			expectFailed:   2,
+			expectInsights: 1,
+		},
+		{
+			name:   "Slow growth",
+			given:  "a DLQ that grew less than the threshold",
+			when:   "checking it",
+			then:   "should not send a digest",
+			counts: []int64{100, 104},
+		},
+		{
+			name:     "Cooling down",
+			given:    "a surge shortly after another instance sent a digest",
+			when:     "checking it",
+			then:     "should not send another one",
+			counts:   []int64{100, 108},
+			lastSent: func() *time.Time { at := now.Add(-10 * time.Minute); return &at }(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			dlq := new(MockDLQReader)
+			for _, count := range tt.counts {
+				dlq.On("CountDLQJobs", mock.Anything).Return(count, nil).Once()
+			}
+			dlq.On("GetDLQJobs", mock.Anything, digestPageSize, 0).Return([]*queue.Job{timedOut, declined, stale}, nil)
+			finder := new(MockInsightFinder)
+			finder.On("GetByJobID", mock.Anything, timedOut.ID).Return(insight, nil)
+			finder.On("GetByJobID", mock.Anything, declined.ID).Return(nil, insights.ErrInsightNotFound)
+			log := &InMemoryDigestLog{}
+			if tt.lastSent != nil {
+				log.digests = []*alert.Digest{{ID: uuid.New(), SentAt: *tt.lastSent}}
+			}
+			publisher := &RecordingPublisher{}
+			notifier := &RecordingDigestNotifier{}
+			monitor, err := NewDLQMonitor(dlq, finder, log, alert.DigestPolicy{Threshold: 5, Window: time.Hour})
+			require.NoError(t, err)
+			monitor.SetEventPublisher(publisher)
+			monitor.SetNotifier(notifier, "https://hooks.example.com/dlq")
+
+			// When
+			var digest *alert.Digest
+			for i := range tt.counts {
+				monitor.now = func() time.Time { return now.Add(time.Duration(i-len(tt.counts)+1) * time.Minute) }
+				digest, err = monitor.Check(context.Background())
+				require.NoError(t, err)
+			}
+
+			// Then
+			if !tt.expectDigest {
+				assert.Nil(t, digest)
+				assert.Empty(t, notifier.digests)
+				assert.Empty(t, publisher.events)
+				return
+			}
+			require.NotNil(t, digest)
+			assert.Equal(t, int64(8), digest.Growth)
+			assert.Equal(t, tt.expectFailed, digest.Failed)
+			assert.Len(t, digest.Insights, tt.expectInsights)
+			assert.Equal(t, insight.ID, digest.Insights[0].InsightID)
+			assert.Equal(t, []*alert.Digest{digest}, log.digests)
+			assert.Equal(t, []string{"https://hooks.example.com/dlq"}, notifier.webhookURLs)
+			require.Len(t, publisher.events, 1)
+			assert.Equal(t, events.NameDLQDigestSent, publisher.events[0].Name())
+		})
+	}
+}
+
+func TestNewDLQMonitor(t *testing.T) {
+	t.Run("Given a policy without a threshold, When creating a monitor, Then should fail", func(t *testing.T) {
+		_, err := NewDLQMonitor(new(MockDLQReader), new(MockInsightFinder), &InMemoryDigestLog{}, alert.DigestPolicy{})
+
+		assert.ErrorIs(t, err, alert.ErrInvalidDigestPolicy)
+	})
+}
@@ -0,0 +1,249 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/google/uuid"
+)
+
+// Alert evaluation defaults
+const (
+	DefaultEvaluationInterval = 30 * time.Second
+	MaxSamples                = 10000 // Insights a count covers at most; larger counts are capped
+)
+
+// Service manages alert rules and evaluates them over recent insights and failures. Alerts are
+// published as events and POSTed to the webhooks of the rules that have one.
+type Service struct {
+	rules    alert.RuleRepository
+	samples  alert.SampleReader
+	events   events.Publisher
+	notifier alert.Notifier
+	now      func() time.Time
+}
+
+// NewService creates a new alert service
+func NewService(rules alert.RuleRepository, samples alert.SampleReader) *Service {
+	return &Service{
+		rules:   rules,
+		samples: samples,
+		events:  events.NopPublisher{},
+		now:     time.Now,
+	}
+}
+
+// SetEventPublisher sets the publisher alerts are emitted through
+func (s *Service) SetEventPublisher(publisher events.Publisher) {
+	s.events = publisher
+}
+
+// SetNotifier sets the notifier delivering alerts to rule webhooks
+func (s *Service) SetNotifier(notifier alert.Notifier) {
+	s.notifier = notifier
+}
+
+// CreateRuleCommand is a new alert rule
+type CreateRuleCommand struct {
+	Name       string
+	Condition  string // e.g. "category=auth AND count>5 in 10m"
+	WebhookURL string
+	Cooldown   time.Duration
+}
+
+// CreateRule stores a new, enabled alert rule
+func (s *Service) CreateRule(ctx context.Context, cmd CreateRuleCommand) (*alert.Rule, error) {
+	rule, err := alert.NewRule(cmd.Name, cmd.Condition, cmd.WebhookURL, cmd.Cooldown, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rules.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// GetRule returns an alert rule
+func (s *Service) GetRule(ctx context.Context, id uuid.UUID) (*alert.Rule, error) {
+	return s.rules.Get(ctx, id)
+}
+
+// ListRules returns every alert rule, oldest first
+func (s *Service) ListRules(ctx context.Context) ([]*alert.Rule, error) {
+	return s.rules.List(ctx)
+}
+
+// UpdateRuleCommand changes the fields of a rule that are set
+type UpdateRuleCommand struct {
+	Name       *string
+	Condition  *string
+	WebhookURL *string
+	Cooldown   *time.Duration
+	Enabled    *bool
+}
+
+// UpdateRule applies the set fields of the command to a rule
+func (s *Service) UpdateRule(ctx context.Context, id uuid.UUID, cmd UpdateRuleCommand) (*alert.Rule, error) {
+	rule, err := s.rules.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Name != nil {
+		rule.Name = strings.TrimSpace(*cmd.Name)
+	}
+	if cmd.Condition != nil {
+		condition, err := alert.ParseCondition(*cmd.Condition)
+		if err != nil {
+			return nil, err
+		}
+		rule.Condition = condition
+	}
+	if cmd.WebhookURL != nil {
+		rule.WebhookURL = *cmd.WebhookURL
+	}
+	if cmd.Cooldown != nil {
+		rule.Cooldown = *cmd.Cooldown
+	}
+	if cmd.Enabled != nil {
+		rule.Enabled = *cmd.Enabled
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	rule.UpdatedAt = s.now().UTC()
+	if err := s.rules.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule removes an alert rule
+func (s *Service) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return s.rules.Delete(ctx, id)
+}
+
+// Evaluate checks every enabled rule once and returns the alerts raised. A rule failing to
+// evaluate doesn't stop the others; the errors are returned together.
+func (s *Service) Evaluate(ctx context.Context) ([]*alert.Alert, error) {
+	rules, err := s.rules.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+
+	// Insights are read once, over the longest window of the rules measuring them
+	var longest time.Duration
+	for _, rule := range rules {
+		if rule.Enabled && rule.Condition.Metric != alert.MetricFailed {
+			longest = max(longest, rule.Condition.Window)
+		}
+	}
+	var samples []alert.Sample
+	if longest > 0 {
+		samples, err = s.samples.InsightSamples(ctx, now.Add(-longest), MaxSamples)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fired []*alert.Alert
+	var errs []error
+	for _, rule := range rules {
+		if !rule.Enabled || rule.CoolingDown(now) {
+			continue
+		}
+		raised, err := s.evaluateRule(ctx, rule, samples, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to evaluate alert rule",
+				slog.String("ruleId", rule.ID.String()),
+				slog.String("error", err.Error()),
+			)
+			errs = append(errs, err)
+			continue
+		}
+		if raised != nil {
+			fired = append(fired, raised)
+		}
+	}
+	return fired, errors.Join(errs...)
+}
+
+func (s *Service) evaluateRule(ctx context.Context, rule *alert.Rule, samples []alert.Sample, now time.Time) (*alert.Alert, error) {
+	var value float64
+	if rule.Condition.Metric == alert.MetricFailed {
+		failed, err := s.samples.CountFailures(ctx, now.Add(-rule.Condition.Window))
+		if err != nil {
+			return nil, err
+		}
+		value = float64(failed)
+	} else {
+		measured, ok := rule.Condition.Measure(samples, now)
+		if !ok {
+			return nil, nil
+		}
+		value = measured
+	}
+
+	raised := rule.Check(value, now)
+	if raised == nil {
+		return nil, nil
+	}
+	claimed, err := s.rules.MarkFired(ctx, rule.ID, now, now.Add(-rule.EffectiveCooldown()))
+	if err != nil || !claimed {
+		// Another replica raised it
+		return nil, err
+	}
+	rule.LastFiredAt = &now
+
+	slog.WarnContext(ctx, "Alert fired",
+		slog.String("ruleId", rule.ID.String()),
+		slog.String("rule", rule.Name),
+		slog.String("condition", raised.Condition),
+		slog.Float64("value", raised.Value),
+	)
+	s.events.Publish(ctx, events.AlertFired{
+		RuleID:    raised.RuleID,
+		RuleName:  raised.RuleName,
+		Condition: raised.Condition,
+		Value:     raised.Value,
+		Threshold: raised.Threshold,
+		At:        raised.FiredAt,
+	})
+	if s.notifier != nil && rule.WebhookURL != "" {
+		s.notifier.NotifyAlert(ctx, rule, raised)
+	}
+	return raised, nil
+}
+
+// Run evaluates the rules every interval until the context is cancelled. Every replica may run
+// it; a rule's cooldown is claimed in the repository so each firing alerts once.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultEvaluationInterval
+	}
+	slog.InfoContext(ctx, "Alert evaluator started",
+		slog.Duration("interval", interval),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Alert evaluator shutting down")
+			return
+		case <-ticker.C:
+			if _, err := s.Evaluate(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to evaluate alert rules",
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/alert"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRuleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRuleRepository) Create(ctx context.Context, rule *alert.Rule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockRuleRepository) Get(ctx context.Context, id uuid.UUID) (*alert.Rule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*alert.Rule), args.Error(1)
+}
+
+func (m *MockRuleRepository) List(ctx context.Context) ([]*alert.Rule, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*alert.Rule), args.Error(1)
+}
+
+func (m *MockRuleRepository) Update(ctx context.Context, rule *alert.Rule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockRuleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRuleRepository) MarkFired(ctx context.Context, id uuid.UUID, firedAt, notBefore time.Time) (bool, error) {
+	args := m.Called(ctx, id, firedAt, notBefore)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockSampleReader struct {
+	mock.Mock
+}
+
+func (m *MockSampleReader) InsightSamples(ctx context.Context, since time.Time, limit int) ([]alert.Sample, error) {
+	args := m.Called(ctx, since, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]alert.Sample), args.Error(1)
+}
+
+func (m *MockSampleReader) CountFailures(ctx context.Context, since time.Time) (int64, error) {
+	args := m.Called(ctx, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// RecordingPublisher collects published events for assertions
+type RecordingPublisher struct {
+	events []events.Event
+}
+
+func (p *RecordingPublisher) Publish(ctx context.Context, event events.Event) {
+	p.events = append(p.events, event)
+}
+
+type RecordingNotifier struct {
+	alerts []*alert.Alert
+}
+
+func (n *RecordingNotifier) NotifyAlert(ctx context.Context, rule *alert.Rule, fired *alert.Alert) {
+	n.alerts = append(n.alerts, fired)
+}
+
+func TestService_Evaluate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	authFailures := []alert.Sample{
+		{Queue: "emails", Category: "auth", At: now.Add(-time.Minute)},
+		{Queue: "emails", Category: "auth", At: now.Add(-2 * time.Minute)},
+		{Queue: "emails", Category: "timeout", At: now.Add(-3 * time.Minute)},
+	}
+
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		condition     string
+		webhookURL    string
+		disabled      bool
+		samples       []alert.Sample
+		failures      int64
+		claimed       bool
+		expectSince   time.Time
+		expectMarked  bool
+		expectAlert   bool
+		expectNotify  bool
+		expectedValue float64
+	}{
+		{
+			name:          "Condition holds",
+			given:         "a rule on auth failures with a webhook and two auth failures in its window",
+			when:          "evaluating the rules",
+			then:          "should fire once, publish the alert and post it to the webhook",
+			condition:     "category=auth AND count>1 in 10m",
+			webhookURL:    "https://hooks.example.com/alerts",
+			samples:       authFailures,
+			claimed:       true,
+			expectSince:   now.Add(-10 * time.Minute),
+			expectMarked:  true,
+			expectAlert:   true,
+			expectNotify:  true,
+			expectedValue: 2,
+		},
+		{
+			name:         "Raised by another replica",
+			given:        "a rule whose condition holds but that another replica just fired",
+			when:         "evaluating the rules",
+			then:         "should not alert again",
+			condition:    "category=auth AND count>1 in 10m",
+			samples:      authFailures,
+			expectSince:  now.Add(-10 * time.Minute),
+			expectMarked: true,
+		},
+		{
+			name:        "Condition doesn't hold",
+			given:       "a rule on more auth failures than there were",
+			when:        "evaluating the rules",
+			then:        "should not fire",
+			condition:   "category=auth AND count>5 in 10m",
+			samples:     authFailures,
+			expectSince: now.Add(-10 * time.Minute),
+		},
+		{
+			name:          "Failures",
+			given:         "a rule on failed jobs and 12 failures in its window",
+			when:          "evaluating the rules",
+			then:          "should count the failures instead of reading insights",
+			condition:     "failed>=10 in 5m",
+			failures:      12,
+			claimed:       true,
+			expectMarked:  true,
+			expectAlert:   true,
+			expectedValue: 12,
+		},
+		{
+			name:      "Disabled rule",
+			given:     "a disabled rule whose condition holds",
+			when:      "evaluating the rules",
+			then:      "should skip it",
+			condition: "category=auth AND count>1 in 10m",
+			disabled:  true,
+			samples:   authFailures,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			rule, err := alert.NewRule("Auth failures", tt.condition, tt.webhookURL, 0, now.Add(-time.Hour))
+			require.NoError(t, err)
+			rule.Enabled = !tt.disabled
+			rules := new(MockRuleRepository)
+			rules.On("List", mock.Anything).Return([]*alert.Rule{rule}, nil)
+			rules.On("MarkFired", mock.Anything, rule.ID, now, now.Add(-rule.EffectiveCooldown())).Return(tt.claimed, nil)
+			samples := new(MockSampleReader)
+			samples.On("InsightSamples", mock.Anything, tt.expectSince, MaxSamples).Return(tt.samples, nil)
+			samples.On("CountFailures", mock.Anything, now.Add(-rule.Condition.Window)).Return(tt.failures, nil)
+			publisher := &RecordingPublisher{}
+			notifier := &RecordingNotifier{}
+			service := NewService(rules, samples)
+			service.SetEventPublisher(publisher)
+			service.SetNotifier(notifier)
+			service.now = func() time.Time { return now }
+
+			// When
+			fired, err := service.Evaluate(context.Background())
+
+			// Then
+			require.NoError(t, err)
+			if tt.expectMarked {
+				rules.AssertCalled(t, "MarkFired", mock.Anything, rule.ID, now, mock.Anything)
+			} else {
+				rules.AssertNotCalled(t, "MarkFired", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectSince.IsZero() {
+				samples.AssertNotCalled(t, "InsightSamples", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if !tt.expectAlert {
+				assert.Empty(t, fired)
+				assert.Empty(t, publisher.events)
+				assert.Empty(t, notifier.alerts)
+				return
+			}
+			require.Len(t, fired, 1)
+			assert.Equal(t, tt.expectedValue, fired[0].Value)
+			require.Len(t, publisher.events, 1)
+			assert.Equal(t, events.NameAlertFired, publisher.events[0].Name())
+			if tt.expectNotify {
+				assert.Len(t, notifier.alerts, 1)
+			} else {
+				assert.Empty(t, notifier.alerts)
+			}
+		})
+	}
+}
+
+func TestService_UpdateRule(t *testing.T) {
+	disabled := false
+	badCondition := "count>1"
+	newCondition := "queue=emails AND count>=3 in 15m"
+
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		cmd             UpdateRuleCommand
+		expectErr       error
+		expectEnabled   bool
+		expectCondition string
+	}{
+		{
+			name:            "Disable",
+			given:           "an enabled rule",
+			when:            "updating it with enabled false",
+			then:            "should store it disabled with its condition unchanged",
+			cmd:             UpdateRuleCommand{Enabled: &disabled},
+			expectCondition: "category=auth AND count>5 in 10m",
+		},
+		{
+			name:            "New condition",
+			given:           "an enabled rule",
+			when:            "updating its condition",
+			then:            "should store the parsed condition",
+			cmd:             UpdateRuleCommand{Condition: &newCondition},
+			expectEnabled:   true,
+			expectCondition: newCondition,
+		},
+		{
+			name:      "Invalid condition",
+			given:     "an enabled rule",
+			when:      "updating it with a condition without a window",
+			then:      "should return ErrInvalidCondition and keep the rule",
+			cmd:       UpdateRuleCommand{Condition: &badCondition},
+			expectErr: alert.ErrInvalidCondition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			rule, err := alert.NewRule("Auth failures", "category=auth AND count>5 in 10m", "", 0, time.Now().UTC())
+			require.NoError(t, err)
+			rules := new(MockRuleRepository)
+			rules.On("Get", mock.Anything, rule.ID).Return(rule, nil)
+			rules.On("Update", mock.Anything, mock.Anything).Return(nil)
+			service := NewService(rules, new(MockSampleReader))
+
+			// When
+			updated, err := service.UpdateRule(context.Background(), rule.ID, tt.cmd)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				rules.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectEnabled, updated.Enabled)
+			assert.Equal(t, tt.expectCondition, updated.Condition.String())
+			rules.AssertCalled(t, "Update", mock.Anything, rule)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
+	"github.com/google/uuid"
+)
+
+// DefaultAnalysisConcurrency is the number of analyses run in parallel when none is configured
+const DefaultAnalysisConcurrency = 2
+
+// analysisPollTimeout bounds how long a consumer blocks waiting for work,
+// so shutdown is noticed promptly
+const analysisPollTimeout = 5 * time.Second
+
+// DefaultRecoverInterval is how often consumers take back the tasks whose claim expired
+const DefaultRecoverInterval = time.Minute
+
+// DefaultUnavailableDelay is how long a consumer pauses after requeueing a task
+// because the AI service was unavailable
+const DefaultUnavailableDelay = 10 * time.Second
+
+// AnalysisConsumer drains the analysis queue with a fixed number of goroutines
+type AnalysisConsumer struct {
+	queue       insights.AnalysisQueue
+	service     *Service
+	concurrency int
+
+	unavailableDelay time.Duration
+}
+
+// NewAnalysisConsumer creates a consumer running at most concurrency analyses at a time
+func NewAnalysisConsumer(queue insights.AnalysisQueue, service *Service, concurrency int) *AnalysisConsumer {
+	if concurrency <= 0 {
+		concurrency = DefaultAnalysisConcurrency
+	}
+	return &AnalysisConsumer{
+		queue:       queue,
+		service:     service,
+		concurrency: concurrency,
+
+		unavailableDelay: DefaultUnavailableDelay,
+	}
+}
+
+// Run consumes analysis tasks until the context is cancelled and returns once
+// in-flight analyses have stopped. Interrupted tasks stay in flight and are
+// recovered, by this consumer or another, once their claim expires.
+func (c *AnalysisConsumer) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Analysis consumer started",
+		slog.Int("concurrency", c.concurrency),
+	)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recoverInFlight(ctx, "analysis", c.queue.Recover)
+	}()
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.consume(ctx)
+		}()
+	}
+	wg.Wait()
+
+	slog.InfoContext(ctx, "Analysis consumer stopped")
+}
+
+// recoverInFlight takes back the tasks of the queue whose claim expired, now and then every
+// DefaultRecoverInterval until the context is cancelled
+func recoverInFlight(ctx context.Context, queueName string, recover func(context.Context) (int, error)) {
+	ticker := time.NewTicker(DefaultRecoverInterval)
+	defer ticker.Stop()
+
+	for {
+		recovered, err := recover(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to recover in-flight analyses",
+				slog.String("queue", queueName),
+				slog.String("error", err.Error()),
+			)
+		} else if recovered > 0 {
+			slog.InfoContext(ctx, "Recovered in-flight analyses",
+				slog.String("queue", queueName),
+				slog.Int("count", recovered),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *AnalysisConsumer) consume(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobID, err := c.queue.Dequeue(ctx, analysisPollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.ErrorContext(ctx, "Failed to dequeue analysis task",
+				slog.String("error", err.Error()),
+			)
+			time.Sleep(time.Second)
+			continue
+		}
+		if jobID == nil {
+			continue
+		}
+
+		c.analyze(ctx, *jobID)
+	}
+}
+
+func (c *AnalysisConsumer) analyze(ctx context.Context, jobID uuid.UUID) {
+	ctx = logging.WithJobID(ctx, jobID.String())
+	_, err := c.service.AnalyzeJobFailure(ctx, jobID)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: leave the task in flight so it's recovered on restart
+		slog.WarnContext(ctx, "Analysis interrupted by shutdown",
+			slog.String("jobId", jobID.String()),
+		)
+		return
+	}
+	if errors.Is(err, insights.ErrAIServiceUnavailable) {
+		c.requeue(ctx, jobID, err)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Analysis failed, dropping task",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if err := c.queue.Complete(context.WithoutCancel(ctx), jobID); err != nil {
+		slog.ErrorContext(ctx, "Failed to complete analysis task",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// requeue puts the task back at the end of the queue and pauses this consumer,
+// so an outage of the AI service delays analyses instead of losing them
+func (c *AnalysisConsumer) requeue(ctx context.Context, jobID uuid.UUID, cause error) {
+	slog.WarnContext(ctx, "AI service unavailable, requeueing analysis",
+		slog.String("jobId", jobID.String()),
+		slog.Duration("pause", c.unavailableDelay),
+		slog.String("error", cause.Error()),
+	)
+
+	if err := c.queue.Enqueue(context.WithoutCancel(ctx), jobID); err != nil {
+		slog.ErrorContext(ctx, "Failed to requeue analysis task, dropping it",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+	if err := c.queue.Complete(context.WithoutCancel(ctx), jobID); err != nil {
+		slog.ErrorContext(ctx, "Failed to complete analysis task",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.unavailableDelay):
+	}
+}
@@ -0,0 +1,156 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// FakeAnalysisQueue hands out queued job IDs and records completions
+type FakeAnalysisQueue struct {
+	mu        sync.Mutex
+	pending   []uuid.UUID
+	completed []uuid.UUID
+	done      chan struct{}
+}
+
+func NewFakeAnalysisQueue(jobIDs ...uuid.UUID) *FakeAnalysisQueue {
+	return &FakeAnalysisQueue{pending: jobIDs, done: make(chan struct{})}
+}
+
+func (q *FakeAnalysisQueue) Enqueue(ctx context.Context, jobID uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, jobID)
+	return nil
+}
+
+func (q *FakeAnalysisQueue) Dequeue(ctx context.Context, timeout time.Duration) (*uuid.UUID, error) {
+	q.mu.Lock()
+	if len(q.pending) > 0 {
+		jobID := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+		return &jobID, nil
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(10 * time.Millisecond):
+		return nil, nil
+	}
+}
+
+func (q *FakeAnalysisQueue) Complete(ctx context.Context, jobID uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.completed = append(q.completed, jobID)
+	if len(q.pending) == 0 {
+		close(q.done)
+	}
+	return nil
+}
+
+func (q *FakeAnalysisQueue) Recover(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func TestAnalysisConsumer_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		given      string
+		when       string
+		then       string
+		setupMocks func(*MockInsightRepository, *MockJobRepository, *MockAIService, uuid.UUID)
+		completed  int
+	}{
+		{
+			name:  "Complete task after successful analysis",
+			given: "a queued job that already has an insight",
+			when:  "the consumer runs",
+			then:  "should analyze the job and complete the task",
+			setupMocks: func(insightRepo *MockInsightRepository, jobRepo *MockJobRepository, aiService *MockAIService, jobID uuid.UUID) {
+				insightRepo.On("GetByJobID", mock.Anything, jobID).Return(&insights.Insight{ID: uuid.New(), JobID: jobID}, nil)
+			},
+			completed: 1,
+		},
+		{
+			name:  "Drop task when analysis fails",
+			given: "a queued job that can't be loaded",
+			when:  "the consumer runs",
+			then:  "should complete the task instead of retrying forever",
+			setupMocks: func(insightRepo *MockInsightRepository, jobRepo *MockJobRepository, aiService *MockAIService, jobID uuid.UUID) {
+				insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, errors.New("not found"))
+				jobRepo.On("GetByID", mock.Anything, jobID).Return(nil, errors.New("job not found"))
+			},
+			completed: 1,
+		},
+		{
+			name:  "Requeue task when the AI service is unavailable",
+			given: "an AI service that is down for the first call",
+			when:  "the consumer runs",
+			then:  "should put the task back and analyze it on the next pass",
+			setupMocks: func(insightRepo *MockInsightRepository, jobRepo *MockJobRepository, aiService *MockAIService, jobID uuid.UUID) {
+				insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, errors.New("not found"))
+				insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+				jobRepo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID, Error: "timeout"}, nil)
+				aiService.On("Analyze", mock.Anything, mock.Anything).Return(nil, insights.ErrAIServiceUnavailable).Once()
+				aiService.On("Analyze", mock.Anything, mock.Anything).Return(&insights.AnalysisResponse{
+					Diagnosis: "Upstream timeout", Recommendation: "Raise the timeout", Confidence: 0.8,
+				}, nil).Once()
+			},
+			completed: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobID := uuid.New()
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("FixEffectiveness", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+			jobRepo := new(MockJobRepository)
+			aiService := new(MockAIService)
+			tt.setupMocks(insightRepo, jobRepo, aiService, jobID)
+
+			analysisQueue := NewFakeAnalysisQueue(jobID)
+			service := NewService(insightRepo, jobRepo, aiService)
+			consumer := NewAnalysisConsumer(analysisQueue, service, 2)
+			consumer.unavailableDelay = time.Millisecond
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// When
+			stopped := make(chan struct{})
+			go func() {
+				consumer.Run(ctx)
+				close(stopped)
+			}()
+
+			select {
+			case <-analysisQueue.done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("analysis task was not completed")
+			}
+			cancel()
+			<-stopped
+
+			// Then
+			assert.Len(t, analysisQueue.completed, tt.completed)
+			assert.Contains(t, analysisQueue.completed, jobID)
+			insightRepo.AssertExpectations(t)
+			jobRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package insights
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
+	"github.com/google/uuid"
+)
+
+// Asynchronous analysis defaults
+const (
+	DefaultAsyncBacklog = 100
+	// DefaultAnalysisStaleAfter is how long a pending analysis goes without an update before
+	// it is taken for abandoned; it must outlast any analysis run
+	DefaultAnalysisStaleAfter = 10 * time.Minute
+	asyncRecoveryTimeout      = 10 * time.Second
+)
+
+// AsyncAnalyzer runs analyses requested asynchronously on a fixed number of background
+// goroutines. Analyses are persisted as pending before they run, so callers can poll their
+// status. queue-core and ai-insights-service share the analyses, so an analyzer only takes
+// over those left pending for staleAfter, e.g. by a restart, not those another one is running.
+type AsyncAnalyzer struct {
+	service     *Service
+	repo        insights.AnalysisRepository
+	notifier    insights.AnalysisNotifier
+	tasks       chan uuid.UUID
+	concurrency int
+	staleAfter  time.Duration
+}
+
+// NewAsyncAnalyzer creates an analyzer running at most concurrency analyses at a time with
+// up to backlog analyses waiting; zero values use the defaults
+func NewAsyncAnalyzer(service *Service, repo insights.AnalysisRepository, concurrency, backlog int) *AsyncAnalyzer {
+	if concurrency <= 0 {
+		concurrency = DefaultAnalysisConcurrency
+	}
+	if backlog <= 0 {
+		backlog = DefaultAsyncBacklog
+	}
+	return &AsyncAnalyzer{
+		service:     service,
+		repo:        repo,
+		tasks:       make(chan uuid.UUID, backlog),
+		concurrency: concurrency,
+		staleAfter:  DefaultAnalysisStaleAfter,
+	}
+}
+
+// SetNotifier sets the notifier posting finished analyses to their callback URL
+func (a *AsyncAnalyzer) SetNotifier(notifier insights.AnalysisNotifier) {
+	a.notifier = notifier
+}
+
+// Submit records a pending analysis of the job and schedules it. It fails with
+// ErrAnalysisQueueFull when the backlog is at capacity.
+func (a *AsyncAnalyzer) Submit(ctx context.Context, jobID uuid.UUID, callbackURL string) (*insights.Analysis, error) {
+	if _, err := a.service.jobRepo.GetByID(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	analysis, err := insights.NewAnalysis(jobID, callbackURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.repo.Create(ctx, analysis); err != nil {
+		return nil, err
+	}
+
+	select {
+	case a.tasks <- analysis.ID:
+	default:
+		analysis.Fail(insights.ErrAnalysisQueueFull)
+		if err := a.repo.Update(ctx, analysis); err != nil {
+			slog.ErrorContext(ctx, "Failed to record rejected analysis",
+				slog.String("analysisId", analysis.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+		return nil, insights.ErrAnalysisQueueFull
+	}
+
+	slog.InfoContext(ctx, "Analysis submitted",
+		slog.String("analysisId", analysis.ID.String()),
+		slog.String("jobId", jobID.String()),
+	)
+	return analysis, nil
+}
+
+// GetAnalysis returns an analysis and, once it completed, the insight it produced
+func (a *AsyncAnalyzer) GetAnalysis(ctx context.Context, id uuid.UUID) (*insights.Analysis, *insights.Insight, error) {
+	analysis, err := a.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if analysis.InsightID == nil {
+		return analysis, nil, nil
+	}
+
+	insight, err := a.service.GetInsight(ctx, *analysis.InsightID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return analysis, insight, nil
+}
+
+// Run processes submitted analyses until the context is cancelled and returns once
+// in-flight analyses have stopped. Interrupted analyses stay pending until they are stale.
+func (a *AsyncAnalyzer) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Async analyzer started",
+		slog.Int("concurrency", a.concurrency),
+	)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recoverInFlight(ctx, "async", a.recover)
+	}()
+	for i := 0; i < a.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-a.tasks:
+					a.process(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	slog.InfoContext(ctx, "Async analyzer stopped")
+}
+
+// recover schedules the analyses no analyzer updated for staleAfter, as far as the backlog
+// allows, and returns how many it scheduled
+func (a *AsyncAnalyzer) recover(ctx context.Context) (int, error) {
+	recoverCtx, cancel := context.WithTimeout(ctx, asyncRecoveryTimeout)
+	defer cancel()
+
+	free := cap(a.tasks) - len(a.tasks)
+	if free <= 0 {
+		return 0, nil
+	}
+	stale, err := a.repo.ListPending(recoverCtx, time.Now().UTC().Add(-a.staleAfter), free)
+	if err != nil {
+		return 0, err
+	}
+	for i, analysis := range stale {
+		select {
+		case a.tasks <- analysis.ID:
+		default:
+			return i, nil
+		}
+	}
+	return len(stale), nil
+}
+
+func (a *AsyncAnalyzer) process(ctx context.Context, id uuid.UUID) {
+	analysis, err := a.repo.GetByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load analysis",
+			slog.String("analysisId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if analysis.IsFinished() {
+		return
+	}
+	analysis.Start()
+	if err := a.repo.Update(ctx, analysis); err != nil {
+		slog.ErrorContext(ctx, "Failed to start analysis",
+			slog.String("analysisId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	ctx = logging.WithJobID(ctx, analysis.JobID.String())
+	insight, err := a.service.AnalyzeJobFailure(ctx, analysis.JobID)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: leave the analysis pending so it's recovered once stale
+		slog.WarnContext(ctx, "Analysis interrupted by shutdown",
+			slog.String("analysisId", id.String()),
+		)
+		return
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Async analysis failed",
+			slog.String("analysisId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		analysis.Fail(err)
+	} else {
+		analysis.Complete(insight.ID)
+	}
+
+	if err := a.repo.Update(context.WithoutCancel(ctx), analysis); err != nil {
+		slog.ErrorContext(ctx, "Failed to record analysis outcome",
+			slog.String("analysisId", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if a.notifier != nil && analysis.CallbackURL != "" {
+		a.notifier.NotifyAnalysis(ctx, analysis, insight)
+	}
+}
@@ -0,0 +1,272 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// FakeAnalysisRepository keeps analyses in memory and signals every analysis that finishes
+type FakeAnalysisRepository struct {
+	mu       sync.Mutex
+	analyses map[uuid.UUID]insights.Analysis
+	updated  chan uuid.UUID
+}
+
+func NewFakeAnalysisRepository() *FakeAnalysisRepository {
+	return &FakeAnalysisRepository{
+		analyses: make(map[uuid.UUID]insights.Analysis),
+		updated:  make(chan uuid.UUID, 10),
+	}
+}
+
+func (r *FakeAnalysisRepository) Create(ctx context.Context, analysis *insights.Analysis) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.analyses[analysis.ID] = *analysis
+	return nil
+}
+
+func (r *FakeAnalysisRepository) GetByID(ctx context.Context, id uuid.UUID) (*insights.Analysis, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	analysis, ok := r.analyses[id]
+	if !ok {
+		return nil, insights.ErrAnalysisNotFound
+	}
+	return &analysis, nil
+}
+
+func (r *FakeAnalysisRepository) Update(ctx context.Context, analysis *insights.Analysis) error {
+	r.mu.Lock()
+	r.analyses[analysis.ID] = *analysis
+	r.mu.Unlock()
+	if analysis.IsFinished() {
+		r.updated <- analysis.ID
+	}
+	return nil
+}
+
+func (r *FakeAnalysisRepository) ListPending(ctx context.Context, updatedBefore time.Time, limit int) ([]*insights.Analysis, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []*insights.Analysis
+	for _, analysis := range r.analyses {
+		if analysis.Status == insights.AnalysisPending && analysis.UpdatedAt.Before(updatedBefore) && len(pending) < limit {
+			pending = append(pending, &analysis)
+		}
+	}
+	return pending, nil
+}
+
+// RecordingAnalysisNotifier records the analyses it was asked to deliver
+type RecordingAnalysisNotifier struct {
+	mu       sync.Mutex
+	analyses []insights.Analysis
+	insights []*insights.Insight
+}
+
+func (n *RecordingAnalysisNotifier) NotifyAnalysis(ctx context.Context, analysis *insights.Analysis, insight *insights.Insight) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.analyses = append(n.analyses, *analysis)
+	n.insights = append(n.insights, insight)
+}
+
+func TestAsyncAnalyzer_Run(t *testing.T) {
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		callbackURL   string
+		aiErr         error
+		expectStatus  insights.AnalysisStatus
+		expectInsight bool
+		expectNotify  bool
+	}{
+		{
+			name:          "Complete analysis and notify",
+			given:         "a failed job and an analysis with a callback URL",
+			when:          "the analyzer runs it",
+			then:          "should complete it with the generated insight and post the outcome",
+			callbackURL:   "https://example.com/hooks/analyses",
+			expectStatus:  insights.AnalysisCompleted,
+			expectInsight: true,
+			expectNotify:  true,
+		},
+		{
+			name:         "Record failed analysis",
+			given:        "an AI service returning an error",
+			when:         "the analyzer runs the analysis",
+			then:         "should mark it failed with the error",
+			callbackURL:  "https://example.com/hooks/analyses",
+			aiErr:        errors.New("model crashed"),
+			expectStatus: insights.AnalysisFailed,
+			expectNotify: true,
+		},
+		{
+			name:          "No callback URL",
+			given:         "an analysis without a callback URL",
+			when:          "the analyzer runs it",
+			then:          "should complete it without notifying",
+			expectStatus:  insights.AnalysisCompleted,
+			expectInsight: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobID := uuid.New()
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("FixEffectiveness", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+			jobRepo := new(MockJobRepository)
+			aiService := new(MockAIService)
+			insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, errors.New("not found"))
+			insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+			insightRepo.On("GetByID", mock.Anything, mock.Anything).Return(&insights.Insight{ID: uuid.New(), JobID: jobID}, nil)
+			jobRepo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID, Error: "timeout"}, nil)
+			if tt.aiErr != nil {
+				aiService.On("Analyze", mock.Anything, mock.Anything).Return(nil, tt.aiErr)
+			} else {
+				aiService.On("Analyze", mock.Anything, mock.Anything).Return(&insights.AnalysisResponse{
+					Diagnosis: "Upstream timeout", Recommendation: "Raise the timeout", Confidence: 0.8,
+				}, nil)
+			}
+
+			repo := NewFakeAnalysisRepository()
+			notifier := &RecordingAnalysisNotifier{}
+			analyzer := NewAsyncAnalyzer(NewService(insightRepo, jobRepo, aiService), repo, 1, 10)
+			analyzer.SetNotifier(notifier)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stopped := make(chan struct{})
+			go func() {
+				analyzer.Run(ctx)
+				close(stopped)
+			}()
+
+			// When
+			submitted, err := analyzer.Submit(ctx, jobID, tt.callbackURL)
+			assert.NoError(t, err)
+			assert.Equal(t, insights.AnalysisPending, submitted.Status)
+
+			select {
+			case <-repo.updated:
+			case <-time.After(2 * time.Second):
+				t.Fatal("analysis did not finish")
+			}
+			cancel()
+			<-stopped
+
+			// Then
+			analysis, insight, err := analyzer.GetAnalysis(context.Background(), submitted.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectStatus, analysis.Status)
+			assert.Equal(t, tt.expectInsight, insight != nil)
+			if tt.aiErr != nil {
+				assert.Contains(t, analysis.Error, tt.aiErr.Error())
+			}
+			if tt.expectNotify {
+				assert.Len(t, notifier.analyses, 1)
+				assert.Equal(t, tt.expectStatus, notifier.analyses[0].Status)
+			} else {
+				assert.Empty(t, notifier.analyses)
+			}
+		})
+	}
+}
+
+func TestAsyncAnalyzer_Submit(t *testing.T) {
+	tests := []struct {
+		name       string
+		given      string
+		when       string
+		then       string
+		jobErr     error
+		backlog    int
+		submits    int
+		expectErr  error
+		expectRuns int
+	}{
+		{
+			name:      "Unknown job",
+			given:     "a job ID that doesn't exist",
+			when:      "submitting an analysis",
+			then:      "should return the lookup error without recording an analysis",
+			jobErr:    queue.ErrJobNotFound,
+			backlog:   1,
+			submits:   1,
+			expectErr: queue.ErrJobNotFound,
+		},
+		{
+			name:       "Backlog full",
+			given:      "an analyzer whose backlog holds one analysis",
+			when:       "submitting two analyses before it runs",
+			then:       "should reject the second with ErrAnalysisQueueFull",
+			backlog:    1,
+			submits:    2,
+			expectErr:  insights.ErrAnalysisQueueFull,
+			expectRuns: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobID := uuid.New()
+			jobRepo := new(MockJobRepository)
+			if tt.jobErr != nil {
+				jobRepo.On("GetByID", mock.Anything, jobID).Return(nil, tt.jobErr)
+			} else {
+				jobRepo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID}, nil)
+			}
+			repo := NewFakeAnalysisRepository()
+			analyzer := NewAsyncAnalyzer(NewService(new(MockInsightRepository), jobRepo, new(MockAIService)), repo, 1, tt.backlog)
+
+			// When
+			var err error
+			for i := 0; i < tt.submits; i++ {
+				_, err = analyzer.Submit(context.Background(), jobID, "")
+			}
+
+			// Then
+			assert.ErrorIs(t, err, tt.expectErr)
+			assert.Len(t, analyzer.tasks, tt.expectRuns)
+			pending, _ := repo.ListPending(context.Background(), time.Now().Add(time.Minute), 10)
+			assert.Len(t, pending, tt.expectRuns)
+		})
+	}
+}
+
+func TestAsyncAnalyzer_Recover(t *testing.T) {
+	// Given a pending analysis left stale, one another analyzer is running and a finished one
+	repo := NewFakeAnalysisRepository()
+	now := time.Now().UTC()
+	stale := insights.Analysis{ID: uuid.New(), Status: insights.AnalysisPending, UpdatedAt: now.Add(-time.Hour)}
+	running := insights.Analysis{ID: uuid.New(), Status: insights.AnalysisPending, UpdatedAt: now}
+	finished := insights.Analysis{ID: uuid.New(), Status: insights.AnalysisCompleted, UpdatedAt: now.Add(-time.Hour)}
+	for _, analysis := range []insights.Analysis{stale, running, finished} {
+		assert.NoError(t, repo.Create(context.Background(), &analysis))
+	}
+	analyzer := NewAsyncAnalyzer(NewService(new(MockInsightRepository), new(MockJobRepository), new(MockAIService)), repo, 1, 10)
+
+	// When recovering
+	recovered, err := analyzer.recover(context.Background())
+
+	// Then should only schedule the stale analysis
+	assert.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+	assert.Len(t, analyzer.tasks, 1)
+	assert.Equal(t, stale.ID, <-analyzer.tasks)
+}
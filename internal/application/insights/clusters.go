@@ -0,0 +1,224 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+)
+
+// DefaultClusterInterval is the time between clustering runs when none is configured
+const DefaultClusterInterval = 15 * time.Minute
+
+// embedBatchSize bounds the diagnoses sent to the embedder in one call
+const embedBatchSize = 64
+
+var (
+	ErrClusteringDisabled = errors.New("insight clustering is not enabled")
+	ErrClustersNotReady   = errors.New("insight clusters have not been computed yet")
+)
+
+// insightClusterer keeps the latest clusters and the embeddings of the diagnoses clustered,
+// so a run only embeds the diagnoses it hasn't seen
+type insightClusterer struct {
+	embedder insights.Embedder
+	cfg      insights.ClusterConfig
+	now      func() time.Time
+
+	run     sync.Mutex // Serializes runs, which own model and vectors
+	model   string
+	vectors map[string][]float64 // By diagnosis, embedded with model
+
+	mu     sync.RWMutex
+	report *insights.ClusterReport
+}
+
+// SetInsightClustering enables grouping failure insights by the similarity of their diagnoses,
+// embedded by the embedder; clusters are computed by ClusterInsights or RunInsightClustering
+func (s *Service) SetInsightClustering(embedder insights.Embedder, cfg insights.ClusterConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	s.clusters = &insightClusterer{
+		embedder: embedder,
+		cfg:      cfg,
+		now:      time.Now,
+		vectors:  make(map[string][]float64),
+	}
+	return nil
+}
+
+// InsightClusters returns the clusters of the latest run
+func (s *Service) InsightClusters() (*insights.ClusterReport, error) {
+	if s.clusters == nil {
+		return nil, ErrClusteringDisabled
+	}
+	s.clusters.mu.RLock()
+	defer s.clusters.mu.RUnlock()
+	if s.clusters.report == nil {
+		return nil, ErrClustersNotReady
+	}
+	return s.clusters.report, nil
+}
+
+// ClusterInsights groups the failure insights of every queue created within the configured
+// window, and keeps the outcome for InsightClusters
+func (s *Service) ClusterInsights(ctx context.Context) (*insights.ClusterReport, error) {
+	c := s.clusters
+	if c == nil {
+		return nil, ErrClusteringDisabled
+	}
+	c.run.Lock()
+	defer c.run.Unlock()
+
+	now := c.now().UTC()
+	since := now.Add(-c.cfg.Window)
+	listed, err := s.insightRepo.ListByQueue(ctx, "", since, c.cfg.MaxInsights)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*insights.QueueInsight, 0, len(listed))
+	for _, entry := range listed {
+		if entry.Insight.Diagnosis != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := c.embed(ctx, entries); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, len(entries))
+	for i, entry := range entries {
+		vectors[i] = c.vectors[entry.Insight.Diagnosis]
+	}
+
+	report := &insights.ClusterReport{
+		ComputedAt: now,
+		Since:      since,
+		Model:      c.model,
+		Insights:   len(entries),
+		Clusters:   insights.ClusterInsights(entries, vectors, c.cfg),
+	}
+	report.Unclustered = report.Insights
+	for _, cluster := range report.Clusters {
+		report.Unclustered -= cluster.Members
+	}
+
+	c.mu.Lock()
+	c.report = report
+	c.mu.Unlock()
+	return report, nil
+}
+
+// embed makes sure vectors holds the embedding of every entry's diagnosis, and only those. When
+// the embedder answers with another model than the last run, e.g. after falling back to another
+// provider, the kept vectors aren't comparable anymore and every diagnosis is embedded again.
+func (c *insightClusterer) embed(ctx context.Context, entries []*insights.QueueInsight) error {
+	kept := make(map[string][]float64)
+	var missing []string
+	for _, entry := range entries {
+		diagnosis := entry.Insight.Diagnosis
+		if _, seen := kept[diagnosis]; seen {
+			continue
+		}
+		vector, ok := c.vectors[diagnosis]
+		if !ok {
+			missing = append(missing, diagnosis)
+		}
+		kept[diagnosis] = vector
+	}
+	if len(missing) == 0 {
+		c.vectors = kept
+		return nil
+	}
+
+	model, embedded, err := c.embedAll(ctx, missing)
+	if err != nil {
+		return err
+	}
+	if model != c.model && len(kept) > len(missing) {
+		all := make([]string, 0, len(kept))
+		for diagnosis := range kept {
+			all = append(all, diagnosis)
+		}
+		if model, embedded, err = c.embedAll(ctx, all); err != nil {
+			return err
+		}
+	} else {
+		for diagnosis, vector := range kept {
+			if vector != nil {
+				embedded[diagnosis] = vector
+			}
+		}
+	}
+	c.model, c.vectors = model, embedded
+	return nil
+}
+
+// embedAll embeds the texts in batches, failing when the batches come from different models
+func (c *insightClusterer) embedAll(ctx context.Context, texts []string) (string, map[string][]float64, error) {
+	var model string
+	vectors := make(map[string][]float64, len(texts))
+	for start := 0; start < len(texts); start += embedBatchSize {
+		batch := texts[start:min(start+embedBatchSize, len(texts))]
+		embeddings, err := c.embedder.Embed(ctx, batch)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(embeddings.Vectors) != len(batch) {
+			return "", nil, fmt.Errorf("embedder returned %d vectors for %d diagnoses", len(embeddings.Vectors), len(batch))
+		}
+		if start > 0 && embeddings.Model != model {
+			return "", nil, fmt.Errorf("embedding model changed from %s to %s during the run", model, embeddings.Model)
+		}
+		model = embeddings.Model
+		for i, text := range batch {
+			vectors[text] = embeddings.Vectors[i]
+		}
+	}
+	return model, vectors, nil
+}
+
+// RunInsightClustering clusters the insights now and then every interval until the context is
+// cancelled. Every replica clusters on its own, serving its latest clusters.
+func (s *Service) RunInsightClustering(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultClusterInterval
+	}
+	slog.InfoContext(ctx, "Insight clustering started",
+		slog.Duration("interval", interval),
+		slog.Float64("similarity", s.clusters.cfg.Similarity),
+		slog.Duration("window", s.clusters.cfg.Window),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := s.ClusterInsights(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.ErrorContext(ctx, "Failed to cluster insights",
+				slog.String("error", err.Error()),
+			)
+		case err == nil:
+			slog.InfoContext(ctx, "Insights clustered",
+				slog.Int("insights", report.Insights),
+				slog.Int("clusters", len(report.Clusters)),
+				slog.Int("unclustered", report.Unclustered),
+				slog.String("model", report.Model),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Insight clustering shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// maxAttemptHistory bounds the past runs of a job included in an analysis prompt
+const maxAttemptHistory = 10
+
+// maxOutputRead bounds the end of a job's output read for an analysis; the prompt budget cuts
+// it further
+const maxOutputRead = 16 * 1024
+
+// SetAttemptHistory gives failure analyses the job's past runs, their durations and errors
+func (s *Service) SetAttemptHistory(attempts queue.AttemptRepository) {
+	s.attempts = attempts
+}
+
+// SetOutputStore gives failure analyses the end of what the executor wrote during the last run
+func (s *Service) SetOutputStore(outputs queue.OutputStore) {
+	s.outputs = outputs
+}
+
+// SetQueueDefinitions gives failure analyses the settings of the job's queue
+func (s *Service) SetQueueDefinitions(definitions queue.DefinitionRepository) {
+	s.definitions = definitions
+}
+
+// attemptHistory returns the job's last runs, oldest first. The analysis goes ahead without
+// them when they can't be loaded.
+func (s *Service) attemptHistory(ctx context.Context, job *queue.Job) []*queue.Attempt {
+	if s.attempts == nil {
+		return nil
+	}
+	attempts, err := s.attempts.ListAttempts(ctx, job.ID, maxAttemptHistory)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load attempt history for AI analysis",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	return attempts
+}
+
+// lastOutput returns the end of the output of the job's last run, empty when it has none or
+// it can't be loaded
+func (s *Service) lastOutput(ctx context.Context, job *queue.Job) string {
+	if s.outputs == nil {
+		return ""
+	}
+	size, err := s.outputs.OutputSize(ctx, job.ID)
+	var data []byte
+	if err == nil && size > 0 {
+		start := max(size-maxOutputRead, 0)
+		data, err = s.outputs.ReadOutput(ctx, job.ID, start, size-start)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load job output for AI analysis",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return ""
+	}
+	return string(data)
+}
+
+// queueConfig returns the definition of the job's queue, nil when it has none or it can't be
+// loaded
+func (s *Service) queueConfig(ctx context.Context, job *queue.Job) *queue.Definition {
+	if s.definitions == nil {
+		return nil
+	}
+	def, err := s.definitions.Get(ctx, job.Queue)
+	if err != nil {
+		if !errors.Is(err, queue.ErrQueueNotDefined) {
+			slog.WarnContext(ctx, "Failed to load queue definition for AI analysis",
+				slog.String("queue", job.Queue),
+				slog.String("error", err.Error()),
+			)
+		}
+		return nil
+	}
+	return def
+}
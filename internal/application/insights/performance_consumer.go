@@ -0,0 +1,106 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
+)
+
+// PerformanceConsumer drains the slow run queue one analysis at a time. Slow runs are rarer
+// than failures and their analyses less urgent, so they don't compete with failure analyses
+// for more than one AI call.
+type PerformanceConsumer struct {
+	queue   insights.SlowRunQueue
+	service *Service
+
+	unavailableDelay time.Duration
+}
+
+// NewPerformanceConsumer creates a consumer analyzing the slow runs of the queue
+func NewPerformanceConsumer(queue insights.SlowRunQueue, service *Service) *PerformanceConsumer {
+	return &PerformanceConsumer{
+		queue:   queue,
+		service: service,
+
+		unavailableDelay: DefaultUnavailableDelay,
+	}
+}
+
+// Run consumes slow runs until the context is cancelled. Like the analysis consumer, an
+// interrupted analysis stays in flight and is recovered once its claim expires, and one the AI
+// service was unavailable for is requeued.
+func (c *PerformanceConsumer) Run(ctx context.Context) {
+	recovering := make(chan struct{})
+	go func() {
+		defer close(recovering)
+		recoverInFlight(ctx, "performance", c.queue.Recover)
+	}()
+	defer func() { <-recovering }()
+
+	slog.InfoContext(ctx, "Performance analysis consumer started")
+	for ctx.Err() == nil {
+		run, err := c.queue.Dequeue(ctx, analysisPollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			slog.ErrorContext(ctx, "Failed to dequeue slow run",
+				slog.String("error", err.Error()),
+			)
+			time.Sleep(time.Second)
+			continue
+		}
+		if run != nil {
+			c.analyze(ctx, run)
+		}
+	}
+	slog.InfoContext(ctx, "Performance analysis consumer stopped")
+}
+
+func (c *PerformanceConsumer) analyze(ctx context.Context, run *insights.SlowRun) {
+	ctx = logging.WithJobID(ctx, run.JobID.String())
+	_, err := c.service.AnalyzeSlowRun(ctx, run)
+	if err != nil && ctx.Err() != nil {
+		slog.WarnContext(ctx, "Performance analysis interrupted by shutdown",
+			slog.String("jobId", run.JobID.String()),
+		)
+		return
+	}
+
+	requeue := errors.Is(err, insights.ErrAIServiceUnavailable)
+	if requeue {
+		slog.WarnContext(ctx, "AI service unavailable, requeueing performance analysis",
+			slog.String("jobId", run.JobID.String()),
+			slog.Duration("pause", c.unavailableDelay),
+			slog.String("error", err.Error()),
+		)
+		if err := c.queue.Enqueue(context.WithoutCancel(ctx), run); err != nil {
+			slog.ErrorContext(ctx, "Failed to requeue slow run, dropping it",
+				slog.String("jobId", run.JobID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Performance analysis failed, dropping slow run",
+			slog.String("jobId", run.JobID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if err := c.queue.Complete(context.WithoutCancel(ctx), run); err != nil {
+		slog.ErrorContext(ctx, "Failed to complete slow run",
+			slog.String("jobId", run.JobID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+	if requeue {
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.unavailableDelay):
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+)
+
+// ErrProviderHealthDisabled is returned when the AI provider isn't probed
+var ErrProviderHealthDisabled = errors.New("AI provider health checks are not enabled")
+
+// ProviderUnavailableError is returned instead of running an analysis synchronously while the
+// AI provider is degraded; it wraps insights.ErrAIServiceUnavailable
+type ProviderUnavailableError struct {
+	RetryAfter time.Duration // Until the next health probe
+	LastError  string        // Of the last failed probe
+}
+
+func (e *ProviderUnavailableError) Error() string {
+	return fmt.Sprintf("AI provider is degraded, retry after %s: %s", e.RetryAfter, e.LastError)
+}
+
+func (e *ProviderUnavailableError) Unwrap() error {
+	return insights.ErrAIServiceUnavailable
+}
+
+// providerMonitor probes the AI provider and keeps the outcome
+type providerMonitor struct {
+	prober insights.ProviderProber
+	health *insights.ProviderHealth
+	now    func() time.Time
+}
+
+// SetProviderHealth enables probing the AI provider with the prober, by ProbeProvider or
+// RunProviderHealthChecks. While it is degraded, CheckProvider fails so synchronous analyses
+// are skipped instead of waiting for the provider to time out.
+func (s *Service) SetProviderHealth(prober insights.ProviderProber, cfg insights.ProviderHealthConfig) error {
+	health, err := insights.NewProviderHealth(cfg)
+	if err != nil {
+		return err
+	}
+	s.provider = &providerMonitor{prober: prober, health: health, now: time.Now}
+	return nil
+}
+
+// ProviderHealth returns the AI provider's health and downtime
+func (s *Service) ProviderHealth() (insights.ProviderHealthStatus, error) {
+	if s.provider == nil {
+		return insights.ProviderHealthStatus{}, ErrProviderHealthDisabled
+	}
+	return s.provider.health.Status(s.provider.now().UTC()), nil
+}
+
+// CheckProvider returns a ProviderUnavailableError while the AI provider is degraded, and nil
+// when it is healthy or isn't probed
+func (s *Service) CheckProvider() error {
+	if s.provider == nil {
+		return nil
+	}
+	now := s.provider.now().UTC()
+	retryAfter, degraded := s.provider.health.RetryAfter(now)
+	if !degraded {
+		return nil
+	}
+	return &ProviderUnavailableError{
+		RetryAfter: retryAfter,
+		LastError:  s.provider.health.Status(now).LastError,
+	}
+}
+
+// ProbeProvider probes the AI provider once and records the outcome. Only the probes degrading
+// insight generation or ending an outage are logged above debug level and published as events.
+func (s *Service) ProbeProvider(ctx context.Context) error {
+	m := s.provider
+	if m == nil {
+		return ErrProviderHealthDisabled
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, m.health.Config().Timeout)
+	err := m.prober.Probe(probeCtx)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	now := m.now().UTC()
+	if err != nil {
+		failure := m.health.Failure(err, now)
+		if !failure.Degraded {
+			slog.DebugContext(ctx, "AI provider health probe failed",
+				slog.Int("failures", failure.Failures),
+				slog.String("error", err.Error()),
+			)
+			return err
+		}
+		slog.ErrorContext(ctx, "AI provider unhealthy, insight generation degraded",
+			slog.Int("failures", failure.Failures),
+			slog.Time("since", failure.Since),
+			slog.String("error", err.Error()),
+		)
+		s.events.Publish(ctx, events.AIProviderDegraded{
+			Failures: failure.Failures,
+			Error:    err.Error(),
+			Since:    failure.Since,
+			At:       now,
+		})
+		return err
+	}
+
+	recovery := m.health.Success(now)
+	if recovery.WasDegraded {
+		slog.InfoContext(ctx, "AI provider healthy again",
+			slog.Int("failures", recovery.Failures),
+			slog.Duration("downtime", recovery.Downtime),
+		)
+		s.events.Publish(ctx, events.AIProviderRecovered{
+			Failures: recovery.Failures,
+			Downtime: recovery.Downtime,
+			At:       now,
+		})
+	}
+	return nil
+}
+
+// RunProviderHealthChecks probes the AI provider now and then every configured interval until
+// the context is cancelled. Every replica probes on its own.
+func (s *Service) RunProviderHealthChecks(ctx context.Context) {
+	cfg := s.provider.health.Config()
+	slog.InfoContext(ctx, "AI provider health checks started",
+		slog.Duration("interval", cfg.Interval),
+		slog.Duration("timeout", cfg.Timeout),
+		slog.Int("failAfter", cfg.FailAfter),
+	)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.ProbeProvider(ctx)
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "AI provider health checks shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
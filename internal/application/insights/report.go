@@ -0,0 +1,76 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// IncidentReport gathers the insight, its job, the job's runs and the fixes applied from the
+// insight into a report for a postmortem. The job's payload and errors are redacted when a
+// redactor is set, since reports are meant to be shared. A deleted job leaves the report
+// with the insight alone.
+func (s *Service) IncidentReport(ctx context.Context, id uuid.UUID) (*insights.IncidentReport, error) {
+	insight, err := s.insightRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	fixes, err := s.insightRepo.FixApplications(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := s.insightRepo.LinkedJobs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	report := &insights.IncidentReport{
+		Insight:     insight,
+		Fixes:       fixes,
+		LinkedJobs:  linked,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	job, err := s.jobRepo.GetByID(ctx, insight.JobID)
+	if errors.Is(err, queue.ErrJobNotFound) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	report.Job = job
+	if s.attempts != nil {
+		report.Attempts, err = s.attempts.ListAttempts(ctx, job.ID, insights.MaxReportAttempts)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to load attempt history for incident report",
+				slog.String("jobId", job.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	s.redactReport(report)
+	return report, nil
+}
+
+// redactReport masks sensitive data in the report's copies of the job's payload and errors
+func (s *Service) redactReport(report *insights.IncidentReport) {
+	if s.redactor == nil {
+		return
+	}
+
+	job := *report.Job
+	job.Payload, _ = s.redactor.RedactPayload(job.Payload)
+	job.Error, _ = s.redactor.RedactText("error", job.Error)
+	report.Job = &job
+
+	for i, attempt := range report.Attempts {
+		redacted := *attempt
+		redacted.Error, _ = s.redactor.RedactText(fmt.Sprintf("attempts[%d].error", i), attempt.Error)
+		report.Attempts[i] = &redacted
+	}
+}
@@ -0,0 +1,56 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// DefaultInsightReuseWindow is how old an insight may be to be reused when no window is set
+const DefaultInsightReuseWindow = 24 * time.Hour
+
+// SetInsightReuse makes failure analyses reuse the insight of a job of the same type whose
+// error had the same fingerprint, analyzed within the window, instead of calling the AI again.
+// The failed job is linked to the insight. Zero uses DefaultInsightReuseWindow.
+func (s *Service) SetInsightReuse(window time.Duration) {
+	if window <= 0 {
+		window = DefaultInsightReuseWindow
+	}
+	s.reuseWindow = window
+}
+
+// reuseInsight links the job to an insight analyzed for the same failure, reporting whether
+// it did. The job is analyzed as usual when the lookup or the link fails.
+func (s *Service) reuseInsight(ctx context.Context, job *queue.Job) (*insights.Insight, bool) {
+	fingerprint := job.ErrorFingerprint()
+	if s.reuseWindow <= 0 || fingerprint == "" {
+		return nil, false
+	}
+
+	insight, err := s.insightRepo.FindByFingerprint(ctx, job.Type, fingerprint, time.Now().UTC().Add(-s.reuseWindow))
+	if errors.Is(err, insights.ErrInsightNotFound) {
+		return nil, false
+	}
+	if err == nil {
+		err = s.insightRepo.LinkJob(ctx, insight.ID, job.ID)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to reuse insight by error fingerprint, analyzing the job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("fingerprint", fingerprint),
+			slog.String("error", err.Error()),
+		)
+		return nil, false
+	}
+
+	slog.InfoContext(ctx, "Reusing the insight of an earlier failure with the same fingerprint",
+		slog.String("jobId", job.ID.String()),
+		slog.String("fingerprint", fingerprint),
+		slog.String("insightId", insight.ID.String()),
+	)
+	return insight, true
+}
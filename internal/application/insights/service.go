@@ -2,18 +2,48 @@ package insights
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/redaction"
 	"github.com/google/uuid"
 )
 
+// FixHistoryWindow is how far back applied fixes are considered when showing the AI what worked
+const FixHistoryWindow = 90 * 24 * time.Hour
+
+// maxFixHistory bounds the past fixes included in an analysis prompt
+const maxFixHistory = 5
+
+// DefaultAnalysisTimeout bounds the AI call of an analysis when no timeout is configured.
+// It is long because local models can take minutes on their first load.
+const DefaultAnalysisTimeout = 5 * time.Minute
+
 // Service orchestrates AI insights use cases
 type Service struct {
 	insightRepo insights.InsightRepository
 	jobRepo     queue.JobRepository
 	aiService   insights.AIService
+	events      events.Publisher
+	signer      *queue.PayloadSigner
+	redactor    *redaction.Redactor
+	jobQueue    queue.QueueService
+	durations   queue.MetricsReader
+	attempts    queue.AttemptRepository
+	outputs     queue.OutputStore
+	definitions queue.DefinitionRepository
+	storms      *stormSampler
+	clusters    *insightClusterer
+	provider    *providerMonitor // Nil when the AI provider isn't probed
+
+	analysisTimeout time.Duration
+	reuseWindow     time.Duration // Zero when insights aren't reused by error fingerprint
 }
 
 // NewService creates a new insights application service
@@ -26,103 +56,530 @@ func NewService(
 		insightRepo: insightRepo,
 		jobRepo:     jobRepo,
 		aiService:   aiService,
+		events:      events.NopPublisher{},
+
+		analysisTimeout: DefaultAnalysisTimeout,
 	}
 }
 
+// SetAnalysisTimeout bounds the AI call of each analysis, fallbacks and re-prompts included;
+// zero keeps the default
+func (s *Service) SetAnalysisTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.analysisTimeout = timeout
+	}
+}
+
+// SetEventPublisher sets the publisher used to emit insight events
+func (s *Service) SetEventPublisher(publisher events.Publisher) {
+	s.events = publisher
+}
+
+// SetPayloadSigner re-signs signed jobs whose payload is changed by an applied fix
+func (s *Service) SetPayloadSigner(signer *queue.PayloadSigner) {
+	s.signer = signer
+}
+
+// SetQueueService makes applying a fix that retries its job enqueue the job again. Without one
+// the job is only marked retrying and waits for the consistency repair to enqueue it.
+func (s *Service) SetQueueService(jobQueue queue.QueueService) {
+	s.jobQueue = jobQueue
+}
+
+// SetRedactor masks sensitive data in job payloads and errors before they are sent for AI analysis
+func (s *Service) SetRedactor(redactor *redaction.Redactor) {
+	s.redactor = redactor
+}
+
+// SetDurationHistory gives performance analyses the timing history of the slow job's type
+func (s *Service) SetDurationHistory(reader queue.MetricsReader) {
+	s.durations = reader
+}
+
 // AnalyzeJobFailure analyzes a failed job and generates insights
 func (s *Service) AnalyzeJobFailure(ctx context.Context, jobID uuid.UUID) (*insights.Insight, error) {
-	log.Printf("[Insights] Starting AI analysis for failed job: id=%s", jobID)
+	if s.Standalone() {
+		return nil, ErrStandalone
+	}
+	slog.InfoContext(ctx, "Starting AI analysis for failed job",
+		slog.String("jobId", jobID.String()),
+	)
 
 	// Check if an insight already exists for this job (cache)
 	existingInsight, err := s.insightRepo.GetByJobID(ctx, jobID)
 	if err == nil && existingInsight != nil {
-		log.Printf("[Insights] Using cached insight for job: id=%s, insight_id=%s", jobID, existingInsight.ID)
+		slog.InfoContext(ctx, "Using cached insight for job",
+			slog.String("jobId", jobID.String()),
+			slog.String("insightId", existingInsight.ID.String()),
+		)
 		return existingInsight, nil
 	}
 
-	log.Printf("[Insights] No cached insight found, proceeding with AI analysis: job_id=%s", jobID)
+	slog.InfoContext(ctx, "No cached insight found, proceeding with AI analysis",
+		slog.String("jobId", jobID.String()),
+	)
 	// Get the failed job
 	job, err := s.jobRepo.GetByID(ctx, jobID)
 	if err != nil {
-		log.Printf("[Insights] Failed to retrieve job: id=%s, error=%v", jobID, err)
+		slog.ErrorContext(ctx, "Failed to retrieve job",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
 		return nil, err
 	}
 
-	log.Printf("[Insights] Retrieved job: id=%s, type=%s, error=%s", job.ID, job.Type, job.Error)
-	// Prepare analysis request
-	request := &insights.AnalysisRequest{
-		JobID:   job.ID.String(),
-		Error:   job.Error,
-		Payload: string(job.Payload),
+	slog.InfoContext(ctx, "Retrieved job",
+		slog.String("jobId", job.ID.String()),
+		slog.String("type", job.Type),
+		slog.String("jobError", job.Error),
+	)
+	if insight, reused := s.reuseInsight(ctx, job); reused {
+		return insight, nil
 	}
+	if s.storms != nil {
+		if insight, sampled, err := s.sampleStorm(ctx, job); sampled {
+			return insight, err
+		}
+	}
+	return s.analyzeFailure(ctx, job)
+}
 
-	// Call AI service for analysis
-	log.Printf("[Insights] Calling AI service for analysis: job_id=%s", jobID)
-	response, err := s.aiService.Analyze(ctx, request)
+// analyzeFailure asks the AI about the job's failure and stores the insight
+func (s *Service) analyzeFailure(ctx context.Context, job *queue.Job) (*insights.Insight, error) {
+	response, err := s.analyze(ctx, job.ID, s.analysisRequest(ctx, job))
 	if err != nil {
-		log.Printf("[Insights] AI analysis failed: job_id=%s, error=%v", jobID, err)
 		return nil, err
 	}
 
-	log.Printf("[Insights] AI analysis completed: job_id=%s, diagnosis=%s", jobID, response.Diagnosis)
 	// Create insight from response
-	insight, err := insights.NewInsight(jobID, response)
+	insight, err := insights.NewInsight(job.ID, response)
 	if err != nil {
-		log.Printf("[Insights] Failed to create insight: job_id=%s, error=%v", jobID, err)
+		slog.ErrorContext(ctx, "Failed to create insight",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
 		return nil, err
 	}
+	insight.Triage = insights.Triage(job, insight)
+	insight.Fingerprint = job.ErrorFingerprint()
 
-	// Persist the insight
-	log.Printf("[Insights] Persisting insight: id=%s, job_id=%s", insight.ID, jobID)
-	if err := s.insightRepo.Create(ctx, insight); err != nil {
-		log.Printf("[Insights] Failed to persist insight: error=%v", err)
+	if err := s.saveInsight(ctx, insight); err != nil {
+		return nil, err
+	}
+	return insight, nil
+}
+
+// AnalyzeSlowRun analyzes why a job that completed ran slower than its SLO and generates a
+// performance insight. The payload and the timing history of the job's type go to the AI.
+func (s *Service) AnalyzeSlowRun(ctx context.Context, run *insights.SlowRun) (*insights.Insight, error) {
+	slog.InfoContext(ctx, "Starting AI analysis for slow job",
+		slog.String("jobId", run.JobID.String()),
+		slog.Duration("duration", run.Duration),
+		slog.Duration("slo", run.SLO),
+	)
+
+	existingInsight, err := s.insightRepo.GetByJobID(ctx, run.JobID)
+	if err == nil && existingInsight != nil && existingInsight.Kind == insights.KindPerformance {
+		slog.InfoContext(ctx, "Using cached performance insight for job",
+			slog.String("jobId", run.JobID.String()),
+			slog.String("insightId", existingInsight.ID.String()),
+		)
+		return existingInsight, nil
+	}
+
+	job, err := s.jobRepo.GetByID(ctx, run.JobID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to retrieve job",
+			slog.String("jobId", run.JobID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	request := s.redact(ctx, job, run.AnalysisRequest(job, s.durationHistory(ctx, job)))
+	response, err := s.analyze(ctx, run.JobID, request)
+	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("[Insights] Insight created successfully: id=%s, job_id=%s", insight.ID, jobID)
+	insight, err := insights.NewInsight(run.JobID, response)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create insight",
+			slog.String("jobId", run.JobID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	insight.Kind = insights.KindPerformance
+
+	if err := s.saveInsight(ctx, insight); err != nil {
+		return nil, err
+	}
 	return insight, nil
 }
 
+// analyze calls the AI service, bounded by the analysis timeout
+func (s *Service) analyze(ctx context.Context, jobID uuid.UUID, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
+	slog.InfoContext(ctx, "Calling AI service for analysis",
+		slog.String("jobId", jobID.String()),
+	)
+	aiCtx, cancel := context.WithTimeout(ctx, s.analysisTimeout)
+	response, err := s.aiService.Analyze(aiCtx, request)
+	cancel()
+	if err != nil && errors.Is(aiCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		// A model too slow to answer is treated like one that's down, so queued analyses are retried later
+		err = fmt.Errorf("%w: AI analysis timed out after %s: %w", insights.ErrAIServiceUnavailable, s.analysisTimeout, err)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "AI analysis failed",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "AI analysis completed",
+		slog.String("jobId", jobID.String()),
+		slog.String("diagnosis", response.Diagnosis),
+	)
+	return response, nil
+}
+
+// saveInsight persists a new insight and announces it
+func (s *Service) saveInsight(ctx context.Context, insight *insights.Insight) error {
+	slog.InfoContext(ctx, "Persisting insight",
+		slog.String("insightId", insight.ID.String()),
+		slog.String("jobId", insight.JobID.String()),
+		slog.String("kind", string(insight.Kind)),
+	)
+	if err := s.insightRepo.Create(ctx, insight); err != nil {
+		slog.ErrorContext(ctx, "Failed to persist insight",
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	slog.InfoContext(ctx, "Insight created successfully",
+		slog.String("insightId", insight.ID.String()),
+		slog.String("jobId", insight.JobID.String()),
+	)
+	s.events.Publish(ctx, events.InsightGenerated{
+		InsightID: insight.ID,
+		JobID:     insight.JobID,
+		Diagnosis: insight.Diagnosis,
+		At:        time.Now().UTC(),
+	})
+	return nil
+}
+
+// durationHistory returns the execution durations recorded for the job's queue and type, nil
+// when they aren't known. The analysis goes ahead without them when they can't be loaded.
+func (s *Service) durationHistory(ctx context.Context, job *queue.Job) *queue.DurationHistogram {
+	if s.durations == nil {
+		return nil
+	}
+	histograms, err := s.durations.JobDurations(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load timing history for AI analysis",
+			slog.String("jobType", job.Type),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	for _, histogram := range histograms {
+		if histogram.Queue == job.Queue && histogram.Type == job.Type {
+			return histogram
+		}
+	}
+	return nil
+}
+
+// analysisRequest builds the AI prompt input for a job, redacted when a redactor is set.
+// What was redacted is logged as an audit trail, without the redacted values.
+func (s *Service) analysisRequest(ctx context.Context, job *queue.Job) *insights.AnalysisRequest {
+	return s.redact(ctx, job, &insights.AnalysisRequest{
+		JobID:       job.ID.String(),
+		JobType:     job.Type,
+		Queue:       job.Queue,
+		Error:       job.Error,
+		Payload:     string(job.Payload),
+		Metadata:    job.Metadata,
+		Attempts:    s.attemptHistory(ctx, job),
+		Output:      s.lastOutput(ctx, job),
+		QueueConfig: s.queueConfig(ctx, job),
+		FixHistory:  s.fixHistory(ctx, job.Type),
+	})
+}
+
+// redact masks sensitive data in the job's payload, the request's error, output and attempt
+// errors when a redactor is set
+func (s *Service) redact(ctx context.Context, job *queue.Job, request *insights.AnalysisRequest) *insights.AnalysisRequest {
+	if s.redactor == nil {
+		return request
+	}
+
+	payload, redactions := s.redactor.RedactPayload(job.Payload)
+	jobError, errorRedactions := s.redactor.RedactText("error", request.Error)
+	output, outputRedactions := s.redactor.RedactText("output", request.Output)
+	redactions = append(redactions, errorRedactions...)
+	redactions = append(redactions, outputRedactions...)
+	request.Payload = string(payload)
+	request.Error = jobError
+	request.Output = output
+	// The metadata is copied so the job keeps its original values
+	if len(request.Metadata) > 0 {
+		metadata := make(map[string]string, len(request.Metadata))
+		for key, value := range request.Metadata {
+			var metadataRedactions []redaction.Redaction
+			metadata[key], metadataRedactions = s.redactor.RedactText("metadata."+key, value)
+			redactions = append(redactions, metadataRedactions...)
+		}
+		request.Metadata = metadata
+	}
+	// The attempts are copied so the history loaded for the job keeps the original errors
+	attempts := make([]*queue.Attempt, len(request.Attempts))
+	for i, attempt := range request.Attempts {
+		redacted := *attempt
+		var attemptRedactions []redaction.Redaction
+		redacted.Error, attemptRedactions = s.redactor.RedactText(fmt.Sprintf("attempts[%d].error", i), attempt.Error)
+		redactions = append(redactions, attemptRedactions...)
+		attempts[i] = &redacted
+	}
+	request.Attempts = attempts
+
+	if len(redactions) > 0 {
+		slog.InfoContext(ctx, "Redacted job data before AI analysis",
+			slog.String("jobId", job.ID.String()),
+			slog.Any("redactions", redactions),
+		)
+	}
+	return request
+}
+
+// fixHistory returns the fixes applied to jobs of the type that ran again, most effective first.
+// The analysis goes ahead without them when they can't be loaded or no insights are stored.
+func (s *Service) fixHistory(ctx context.Context, jobType string) []*insights.FixEffectiveness {
+	if s.insightRepo == nil {
+		return nil
+	}
+	stats, err := s.insightRepo.FixEffectiveness(ctx, jobType, time.Now().UTC().Add(-FixHistoryWindow))
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load fix history for AI analysis",
+			slog.String("jobType", jobType),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+
+	var history []*insights.FixEffectiveness
+	for _, stat := range stats {
+		if stat.Resolved() > 0 {
+			history = append(history, stat)
+		}
+	}
+	slices.SortStableFunc(history, func(a, b *insights.FixEffectiveness) int {
+		switch {
+		case a.SuccessRate() > b.SuccessRate():
+			return -1
+		case a.SuccessRate() < b.SuccessRate():
+			return 1
+		default:
+			return int(b.Resolved() - a.Resolved())
+		}
+	})
+	if len(history) > maxFixHistory {
+		history = history[:maxFixHistory]
+	}
+	return history
+}
+
 // GetInsight retrieves an insight by ID
 func (s *Service) GetInsight(ctx context.Context, id uuid.UUID) (*insights.Insight, error) {
 	return s.insightRepo.GetByID(ctx, id)
 }
 
+// GetLinkedJobs returns the jobs that reused the insight because they failed the same way
+func (s *Service) GetLinkedJobs(ctx context.Context, insightID uuid.UUID) ([]uuid.UUID, error) {
+	return s.insightRepo.LinkedJobs(ctx, insightID)
+}
+
 // GetInsightByJobID retrieves an insight for a specific job
 func (s *Service) GetInsightByJobID(ctx context.Context, jobID uuid.UUID) (*insights.Insight, error) {
 	return s.insightRepo.GetByJobID(ctx, jobID)
 }
 
-// ListInsights retrieves all insights with pagination
-func (s *Service) ListInsights(ctx context.Context, limit, offset int) ([]*insights.Insight, error) {
-	return s.insightRepo.List(ctx, limit, offset)
+// ListInsights retrieves insights of the kind, or of every kind when empty, with pagination
+func (s *Service) ListInsights(ctx context.Context, kind insights.Kind, limit, offset int) ([]*insights.Insight, error) {
+	if err := kind.Validate(); err != nil {
+		return nil, err
+	}
+	return s.insightRepo.List(ctx, kind, limit, offset)
 }
 
-// ApplyInsightFix applies the suggested fix from an insight to a job
-func (s *Service) ApplyInsightFix(ctx context.Context, insightID uuid.UUID) error {
-	insight, err := s.insightRepo.GetByID(ctx, insightID)
+// EditInsight applies an operator's correction or annotation to an insight
+func (s *Service) EditInsight(ctx context.Context, id uuid.UUID, edit insights.InsightEdit) (*insights.Insight, error) {
+	insight, err := s.insightRepo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := insight.Edit(edit); err != nil {
+		return nil, err
+	}
+	if err := s.insightRepo.Update(ctx, insight); err != nil {
+		return nil, err
 	}
 
-	job, err := s.jobRepo.GetByID(ctx, insight.JobID)
+	slog.InfoContext(ctx, "Insight edited",
+		slog.String("insightId", insight.ID.String()),
+		slog.String("jobId", insight.JobID.String()),
+		slog.String("editedBy", insight.EditedBy),
+		slog.Bool("recommendationEdited", edit.Recommendation != nil),
+		slog.Bool("noteEdited", edit.Note != nil),
+	)
+	return insight, nil
+}
+
+// GetDLQTriage retrieves dead letter jobs together with their latest insight, in the given
+// insights.DLQOrder
+func (s *Service) GetDLQTriage(ctx context.Context, limit, offset int, order string) ([]*insights.JobWithInsight, error) {
+	if err := insights.ValidateDLQOrder(order); err != nil {
+		return nil, err
+	}
+	return s.insightRepo.ListDLQWithInsights(ctx, limit, offset, order)
+}
+
+// GetUsageSummary aggregates AI usage per day, provider and model since the given time
+func (s *Service) GetUsageSummary(ctx context.Context, since time.Time) ([]*insights.UsageAggregate, error) {
+	return s.insightRepo.UsageSummary(ctx, since)
+}
+
+// GetFixEffectiveness aggregates how applied fixes worked per job type and fix kind since the
+// given time; an empty job type includes every type
+func (s *Service) GetFixEffectiveness(ctx context.Context, jobType string, since time.Time) ([]*insights.FixEffectiveness, error) {
+	return s.insightRepo.FixEffectiveness(ctx, jobType, since)
+}
+
+// SummarizeQueue aggregates the insights of a queue's jobs analyzed since the given time into
+// recurring diagnoses and common fixes. With withAI the AI also writes an executive summary;
+// when it fails the summary is returned without one and SummaryError tells why.
+func (s *Service) SummarizeQueue(ctx context.Context, queueName string, since time.Time, withAI bool) (*insights.QueueSummary, error) {
+	entries, err := s.insightRepo.ListByQueue(ctx, queueName, since, insights.MaxSummaryInsights)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	summary := insights.SummarizeQueue(queueName, since, entries)
+	if !withAI || summary.Insights == 0 {
+		return summary, nil
+	}
+	if err := s.CheckProvider(); err != nil {
+		summary.SummaryError = err.Error()
+		return summary, nil
 	}
 
-	// Apply the suggested payload patch
-	if len(insight.SuggestedFix.PayloadPatch) > 0 {
-		patchedPayload, err := insight.ApplySuggestedFix(job.Payload)
-		if err != nil {
-			return err
-		}
-		job.Payload = patchedPayload
+	aiCtx, cancel := context.WithTimeout(ctx, s.analysisTimeout)
+	defer cancel()
+	response, err := s.aiService.Analyze(aiCtx, summary.AnalysisRequest())
+	if err != nil {
+		slog.WarnContext(ctx, "AI executive summary failed, returning the aggregates alone",
+			slog.String("queue", queueName),
+			slog.String("error", err.Error()),
+		)
+		summary.SummaryError = err.Error()
+		return summary, nil
+	}
+	summary.SetExecutiveSummary(response)
+	return summary, nil
+}
+
+// PreviewInsightFix returns what applying the insight's suggested fix would change, without modifying the job
+func (s *Service) PreviewInsightFix(ctx context.Context, insightID uuid.UUID) (*insights.FixPlan, error) {
+	_, plan, err := s.planInsightFix(ctx, insightID)
+	return plan, err
+}
+
+// AppliedFix is the outcome of applying an insight's suggested fix
+type AppliedFix struct {
+	Plan     *insights.FixPlan
+	Job      *queue.Job // The job as updated by the fix
+	Enqueued bool       // Whether the job was enqueued again to be retried
+}
+
+// ApplyInsightFix applies the suggested fix from an insight to a job, records an audit entry and,
+// when the plan retries the job, enqueues it again
+func (s *Service) ApplyInsightFix(ctx context.Context, insightID uuid.UUID) (*AppliedFix, error) {
+	job, plan, err := s.planInsightFix(ctx, insightID)
+	if err != nil {
+		return nil, err
 	}
 
+	job.Payload = plan.PayloadAfter
+	if s.signer != nil {
+		if err := s.signer.Resign(job); err != nil {
+			return nil, err
+		}
+	}
 	// Reset job for retry if recommended
-	if insight.HasRetryRecommendation() {
-		job.MarkAsRetrying()
+	if plan.WillRetry {
+		if err := job.MarkAsRetrying(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// The fix is already in place; a failed audit write is logged rather than reported as a failed apply
+	if err := s.insightRepo.RecordFixApplication(ctx, insights.NewFixApplication(plan)); err != nil {
+		slog.ErrorContext(ctx, "Failed to record fix application",
+			slog.String("insightId", insightID.String()),
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+	applied := &AppliedFix{Plan: plan, Job: job}
+	if plan.WillRetry {
+		applied.Enqueued = s.enqueueFixedJob(ctx, job)
+	}
+	slog.InfoContext(ctx, "Applied fix",
+		slog.String("insightId", insightID.String()),
+		slog.String("jobId", job.ID.String()),
+		slog.Int("changes", len(plan.Changes)),
+		slog.Bool("retry", plan.WillRetry),
+		slog.Bool("enqueued", applied.Enqueued),
+	)
+
+	return applied, nil
+}
+
+// enqueueFixedJob enqueues a job a fix marked retrying. The fix is in place whatever happens
+// here, so a failure is logged and the job left to the consistency repair.
+func (s *Service) enqueueFixedJob(ctx context.Context, job *queue.Job) bool {
+	if s.jobQueue == nil {
+		return false
+	}
+	if err := s.jobQueue.Enqueue(ctx, job); err != nil {
+		slog.ErrorContext(ctx, "Failed to enqueue fixed job, leaving it to the consistency repair",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+	return true
+}
+
+func (s *Service) planInsightFix(ctx context.Context, insightID uuid.UUID) (*queue.Job, *insights.FixPlan, error) {
+	insight, err := s.insightRepo.GetByID(ctx, insightID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return s.jobRepo.Update(ctx, job)
+	job, err := s.jobRepo.GetByID(ctx, insight.JobID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := insight.PlanFix(job)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, plan, nil
 }
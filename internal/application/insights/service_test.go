@@ -3,11 +3,14 @@ package insights
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/redaction"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -39,19 +42,95 @@ func (m *MockInsightRepository) GetByJobID(ctx context.Context, jobID uuid.UUID)
 	return args.Get(0).(*insights.Insight), args.Error(1)
 }
 
-func (m *MockInsightRepository) List(ctx context.Context, limit, offset int) ([]*insights.Insight, error) {
-	args := m.Called(ctx, limit, offset)
+func (m *MockInsightRepository) List(ctx context.Context, kind insights.Kind, limit, offset int) ([]*insights.Insight, error) {
+	args := m.Called(ctx, kind, limit, offset)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*insights.Insight), args.Error(1)
 }
 
+func (m *MockInsightRepository) Update(ctx context.Context, insight *insights.Insight) error {
+	args := m.Called(ctx, insight)
+	return args.Error(0)
+}
+
 func (m *MockInsightRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockInsightRepository) ListDLQWithInsights(ctx context.Context, limit, offset int, order string) ([]*insights.JobWithInsight, error) {
+	args := m.Called(ctx, limit, offset, order)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*insights.JobWithInsight), args.Error(1)
+}
+
+func (m *MockInsightRepository) RecordFixApplication(ctx context.Context, application *insights.FixApplication) error {
+	args := m.Called(ctx, application)
+	return args.Error(0)
+}
+
+func (m *MockInsightRepository) FixApplications(ctx context.Context, insightID uuid.UUID) ([]*insights.FixApplication, error) {
+	args := m.Called(ctx, insightID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*insights.FixApplication), args.Error(1)
+}
+
+func (m *MockInsightRepository) RecordFixOutcome(ctx context.Context, jobID uuid.UUID, outcome insights.FixOutcome, at time.Time) error {
+	args := m.Called(ctx, jobID, outcome, at)
+	return args.Error(0)
+}
+
+func (m *MockInsightRepository) FixEffectiveness(ctx context.Context, jobType string, since time.Time) ([]*insights.FixEffectiveness, error) {
+	args := m.Called(ctx, jobType, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*insights.FixEffectiveness), args.Error(1)
+}
+
+func (m *MockInsightRepository) UsageSummary(ctx context.Context, since time.Time) ([]*insights.UsageAggregate, error) {
+	args := m.Called(ctx, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*insights.UsageAggregate), args.Error(1)
+}
+
+func (m *MockInsightRepository) ListByQueue(ctx context.Context, queueName string, since time.Time, limit int) ([]*insights.QueueInsight, error) {
+	args := m.Called(ctx, queueName, since, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*insights.QueueInsight), args.Error(1)
+}
+
+func (m *MockInsightRepository) FindByFingerprint(ctx context.Context, jobType, fingerprint string, since time.Time) (*insights.Insight, error) {
+	args := m.Called(ctx, jobType, fingerprint, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*insights.Insight), args.Error(1)
+}
+
+func (m *MockInsightRepository) LinkJob(ctx context.Context, insightID, jobID uuid.UUID) error {
+	args := m.Called(ctx, insightID, jobID)
+	return args.Error(0)
+}
+
+func (m *MockInsightRepository) LinkedJobs(ctx context.Context, insightID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(ctx, insightID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
 type MockJobRepository struct {
 	mock.Mock
 }
@@ -64,6 +143,14 @@ func (m *MockJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*queue.J
 	return args.Get(0).(*queue.Job), args.Error(1)
 }
 
+func (m *MockJobRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*queue.Job, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Job), args.Error(1)
+}
+
 func (m *MockJobRepository) Create(ctx context.Context, job *queue.Job) error {
 	args := m.Called(ctx, job)
 	return args.Error(0)
@@ -79,6 +166,16 @@ func (m *MockJobRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockJobRepository) Undelete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockJobRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockJobRepository) FindPendingJobs(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
 	args := m.Called(ctx, queueName, limit)
 	if args.Get(0) == nil {
@@ -87,6 +184,14 @@ func (m *MockJobRepository) FindPendingJobs(ctx context.Context, queueName strin
 	return args.Get(0).([]*queue.Job), args.Error(1)
 }
 
+func (m *MockJobRepository) FindDueDelayedJobs(ctx context.Context, now time.Time, limit int) ([]*queue.Job, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.Job), args.Error(1)
+}
+
 func (m *MockJobRepository) FindByStatus(ctx context.Context, status queue.Status, limit int) ([]*queue.Job, error) {
 	args := m.Called(ctx, status, limit)
 	if args.Get(0) == nil {
@@ -100,6 +205,35 @@ func (m *MockJobRepository) CountByStatus(ctx context.Context, status queue.Stat
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockJobRepository) CountDelayed(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockJobRepository) Search(ctx context.Context, criteria queue.SearchCriteria) ([]*queue.Job, int64, error) {
+	args := m.Called(ctx, criteria)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*queue.Job), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockJobRepository) ListByQueue(ctx context.Context, listing queue.QueueListing) ([]*queue.Job, int64, error) {
+	args := m.Called(ctx, listing)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*queue.Job), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockJobRepository) Activity(ctx context.Context, since time.Time, topTypes int) (*queue.Activity, error) {
+	args := m.Called(ctx, since, topTypes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Activity), args.Error(1)
+}
+
 func (m *MockJobRepository) GetDLQJobs(ctx context.Context, limit, offset int) ([]*queue.Job, error) {
 	args := m.Called(ctx, limit, offset)
 	if args.Get(0) == nil {
@@ -216,6 +350,8 @@ func TestService_AnalyzeJobFailure(t *testing.T) {
 				assert.Equal(t, "Increase connection timeout to 30 seconds", insight.Recommendation)
 				assert.Equal(t, 30, insight.SuggestedFix.TimeoutSeconds)
 				assert.Equal(t, 5, insight.SuggestedFix.MaxRetries)
+				// The AI reported no confidence, so its fix isn't trusted unattended
+				assert.Equal(t, insights.TriageNeedsHuman, insight.Triage)
 			},
 		},
 		{
@@ -291,6 +427,7 @@ func TestService_AnalyzeJobFailure(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Given
 			insightRepo := new(MockInsightRepository)
+			insightRepo.On("FixEffectiveness", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
 			jobRepo := new(MockJobRepository)
 			aiService := new(MockAIService)
 
@@ -321,215 +458,1165 @@ func TestService_AnalyzeJobFailure(t *testing.T) {
 	}
 }
 
-func TestService_GetInsight(t *testing.T) {
+// StaticDurations is a queue.MetricsReader returning fixed duration histograms
+type StaticDurations []*queue.DurationHistogram
+
+func (d StaticDurations) JobCounters(ctx context.Context) ([]*queue.JobCounter, error) {
+	return nil, nil
+}
+
+func (d StaticDurations) JobDurations(ctx context.Context) ([]*queue.DurationHistogram, error) {
+	return d, nil
+}
+
+func TestService_AnalyzeSlowRun(t *testing.T) {
+	history := queue.NewDurationHistogram("default", "report")
+	history.Observe(1)
+	history.Observe(2)
+	other := queue.NewDurationHistogram("default", "email")
+	other.Observe(50)
+
 	tests := []struct {
 		name            string
 		given           string
 		when            string
 		then            string
-		insightID       uuid.UUID
-		setupMocks      func(*MockInsightRepository, uuid.UUID)
-		expectErr       bool
-		validateInsight func(*testing.T, *insights.Insight)
+		cached          *insights.Insight
+		aiErr           error
+		expectErr       error
+		expectAICall    bool
+		expectInRequest string
 	}{
 		{
-			name:      "Successfully get insight by ID",
-			given:     "valid insight ID",
-			when:      "retrieving insight",
-			then:      "should return insight",
-			insightID: uuid.New(),
-			setupMocks: func(repo *MockInsightRepository, id uuid.UUID) {
-				insight := &insights.Insight{
-					ID:             id,
-					JobID:          uuid.New(),
-					Diagnosis:      "Test diagnosis",
-					Recommendation: "Test recommendation",
-					CreatedAt:      time.Now().UTC(),
-				}
-				repo.On("GetByID", mock.Anything, id).Return(insight, nil)
-			},
-			expectErr: false,
-			validateInsight: func(t *testing.T, insight *insights.Insight) {
-				assert.Equal(t, "Test diagnosis", insight.Diagnosis)
-			},
+			name:            "Analyze a slow run",
+			given:           "a completed job that ran over its SLO and the timing history of its type",
+			when:            "analyzing the slow run",
+			then:            "should send the run and history to the AI and save a performance insight",
+			expectAICall:    true,
+			expectInRequest: "Timing of the 2 recorded runs of report jobs in queue default: mean 1.5s",
 		},
 		{
-			name:      "Insight not found",
-			given:     "non-existent insight ID",
-			when:      "retrieving insight",
-			then:      "should return not found error",
-			insightID: uuid.New(),
-			setupMocks: func(repo *MockInsightRepository, id uuid.UUID) {
-				repo.On("GetByID", mock.Anything, id).
-					Return(nil, insights.ErrInsightNotFound)
-			},
-			expectErr: true,
+			name:            "Failure insight for the job",
+			given:           "a failure insight for the job from an earlier attempt",
+			when:            "analyzing the slow run",
+			then:            "should not reuse it and save a performance insight",
+			cached:          &insights.Insight{ID: uuid.New(), Kind: insights.KindFailure, Diagnosis: "Timeout"},
+			expectAICall:    true,
+			expectInRequest: "above its SLO of 2s",
+		},
+		{
+			name:   "Performance insight for the job",
+			given:  "a performance insight for the job",
+			when:   "analyzing the slow run",
+			then:   "should return it without calling the AI",
+			cached: &insights.Insight{ID: uuid.New(), Kind: insights.KindPerformance, Diagnosis: "Slow query"},
+		},
+		{
+			name:         "AI service unavailable",
+			given:        "an AI service that is down",
+			when:         "analyzing the slow run",
+			then:         "should return ErrAIServiceUnavailable without saving an insight",
+			aiErr:        insights.ErrAIServiceUnavailable,
+			expectErr:    insights.ErrAIServiceUnavailable,
+			expectAICall: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Given
+			job := &queue.Job{
+				ID:      uuid.New(),
+				Queue:   "default",
+				Type:    "report",
+				Status:  queue.StatusCompleted,
+				Payload: []byte(`{"range":"year"}`),
+			}
+			run := &insights.SlowRun{JobID: job.ID, Duration: 5 * time.Second, SLO: 2 * time.Second}
+
 			insightRepo := new(MockInsightRepository)
-			tt.setupMocks(insightRepo, tt.insightID)
+			insightRepo.On("FixEffectiveness", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+			if tt.cached != nil {
+				insightRepo.On("GetByJobID", mock.Anything, job.ID).Return(tt.cached, nil)
+			} else {
+				insightRepo.On("GetByJobID", mock.Anything, job.ID).Return(nil, errors.New("not found"))
+			}
+			jobRepo := new(MockJobRepository)
+			aiService := new(MockAIService)
+			var request *insights.AnalysisRequest
+			if tt.expectAICall {
+				jobRepo.On("GetByID", mock.Anything, job.ID).Return(job, nil)
+				call := aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+					Run(func(args mock.Arguments) { request = args.Get(1).(*insights.AnalysisRequest) })
+				if tt.aiErr != nil {
+					call.Return(nil, tt.aiErr)
+				} else {
+					call.Return(&insights.AnalysisResponse{Diagnosis: "Report query scans the whole year", Recommendation: "Paginate the report"}, nil)
+					insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+				}
+			}
 
-			service := NewService(insightRepo, new(MockJobRepository), new(MockAIService))
-			ctx := context.Background()
+			service := NewService(insightRepo, jobRepo, aiService)
+			service.SetDurationHistory(StaticDurations{other, history})
 
 			// When
-			insight, err := service.GetInsight(ctx, tt.insightID)
+			insight, err := service.AnalyzeSlowRun(context.Background(), run)
 
 			// Then
-			if tt.expectErr {
-				assert.Error(t, err)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
 				assert.Nil(t, insight)
+				insightRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 			} else {
 				assert.NoError(t, err)
-				assert.NotNil(t, insight)
-				if tt.validateInsight != nil {
-					tt.validateInsight(t, insight)
+				assert.Equal(t, insights.KindPerformance, insight.Kind)
+				if !tt.expectAICall {
+					assert.Equal(t, tt.cached, insight)
 				}
 			}
+			if tt.expectInRequest != "" {
+				assert.Contains(t, request.Error, tt.expectInRequest)
+				assert.Equal(t, string(job.Payload), request.Payload)
+			}
 
 			insightRepo.AssertExpectations(t)
+			jobRepo.AssertExpectations(t)
+			aiService.AssertExpectations(t)
 		})
 	}
 }
 
-func TestService_GetInsightByJobID(t *testing.T) {
+func TestService_AnalyzeJobFailure_Redaction(t *testing.T) {
 	tests := []struct {
-		name            string
-		given           string
-		when            string
-		then            string
-		jobID           uuid.UUID
-		setupMocks      func(*MockInsightRepository, uuid.UUID)
-		expectErr       bool
-		validateInsight func(*testing.T, *insights.Insight)
+		name           string
+		given          string
+		when           string
+		then           string
+		redact         bool
+		expectPayload  string
+		expectError    string
+		expectMetadata map[string]string
 	}{
 		{
-			name:  "Successfully get insight by job ID",
-			given: "valid job ID with existing insight",
-			when:  "retrieving insight by job ID",
-			then:  "should return insight",
-			jobID: uuid.New(),
-			setupMocks: func(repo *MockInsightRepository, jobID uuid.UUID) {
-				insight := &insights.Insight{
-					ID:             uuid.New(),
-					JobID:          jobID,
-					Diagnosis:      "Job-specific diagnosis",
-					Recommendation: "Job-specific recommendation",
-					CreatedAt:      time.Now().UTC(),
-				}
-				repo.On("GetByJobID", mock.Anything, jobID).Return(insight, nil)
-			},
-			expectErr: false,
-			validateInsight: func(t *testing.T, insight *insights.Insight) {
-				assert.Equal(t, "Job-specific diagnosis", insight.Diagnosis)
-			},
+			name:           "Redact before analysis",
+			given:          "a redactor masking the to field and email addresses",
+			when:           "analyzing a failed email job",
+			then:           "should send the AI the payload, error and metadata with those masked",
+			redact:         true,
+			expectPayload:  `{"subject":"Hello","to":"[REDACTED]"}`,
+			expectError:    "mailbox [REDACTED] is full",
+			expectMetadata: map[string]string{"requested_by": "[REDACTED]", "source": "crm"},
 		},
 		{
-			name:  "Insight not found for job",
-			given: "job ID without insight",
-			when:  "retrieving insight by job ID",
-			then:  "should return not found error",
-			jobID: uuid.New(),
-			setupMocks: func(repo *MockInsightRepository, jobID uuid.UUID) {
-				repo.On("GetByJobID", mock.Anything, jobID).
-					Return(nil, insights.ErrInsightNotFound)
-			},
-			expectErr: true,
+			name:           "No redactor",
+			given:          "no redactor",
+			when:           "analyzing a failed email job",
+			then:           "should send the AI the payload, error and metadata as stored",
+			expectPayload:  `{"to":"ana@example.com","subject":"Hello"}`,
+			expectError:    "mailbox ana@example.com is full",
+			expectMetadata: map[string]string{"requested_by": "bob@example.com", "source": "crm"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Given
+			jobID := uuid.New()
 			insightRepo := new(MockInsightRepository)
-			tt.setupMocks(insightRepo, tt.jobID)
+			insightRepo.On("FixEffectiveness", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+			insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, errors.New("not found"))
+			insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+			jobRepo := new(MockJobRepository)
+			job := &queue.Job{
+				ID:       jobID,
+				Type:     "email",
+				Status:   queue.StatusFailed,
+				Error:    "mailbox ana@example.com is full",
+				Payload:  []byte(`{"to":"ana@example.com","subject":"Hello"}`),
+				Metadata: map[string]string{"requested_by": "bob@example.com", "source": "crm"},
+			}
+			jobRepo.On("GetByID", mock.Anything, jobID).Return(job, nil)
+			aiService := new(MockAIService)
+			aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+				Return(&insights.AnalysisResponse{Diagnosis: "Recipient mailbox full", Confidence: 0.9}, nil)
 
-			service := NewService(insightRepo, new(MockJobRepository), new(MockAIService))
-			ctx := context.Background()
+			service := NewService(insightRepo, jobRepo, aiService)
+			if tt.redact {
+				redactor, err := redaction.NewRedactor([]string{"to"}, []redaction.Pattern{{Name: "email", Regex: redaction.EmailPattern}}, "")
+				assert.NoError(t, err)
+				service.SetRedactor(redactor)
+			}
 
 			// When
-			insight, err := service.GetInsightByJobID(ctx, tt.jobID)
+			_, err := service.AnalyzeJobFailure(context.Background(), jobID)
 
 			// Then
-			if tt.expectErr {
-				assert.Error(t, err)
-				assert.Nil(t, insight)
-			} else {
-				assert.NoError(t, err)
-				assert.NotNil(t, insight)
-				if tt.validateInsight != nil {
-					tt.validateInsight(t, insight)
-				}
-			}
-
-			insightRepo.AssertExpectations(t)
+			assert.NoError(t, err)
+			aiService.AssertCalled(t, "Analyze", mock.Anything, mock.MatchedBy(func(request *insights.AnalysisRequest) bool {
+				return request.Payload == tt.expectPayload && request.Error == tt.expectError &&
+					assert.ObjectsAreEqual(tt.expectMetadata, request.Metadata)
+			}))
+			assert.Equal(t, "bob@example.com", job.Metadata["requested_by"])
 		})
 	}
 }
 
-func TestService_ListInsights(t *testing.T) {
+func TestService_AnalyzeJobFailure_FixHistory(t *testing.T) {
+	timeout := &insights.FixEffectiveness{JobType: "email", FixKind: insights.FixKindTimeout, Applied: 25, Succeeded: 18, Failed: 7}
+	patch := &insights.FixEffectiveness{JobType: "email", FixKind: insights.FixKindPayloadPatch, Applied: 4, Succeeded: 4}
+	unresolved := &insights.FixEffectiveness{JobType: "email", FixKind: insights.FixKindRetry, Applied: 3}
+
 	tests := []struct {
-		name         string
-		given        string
-		when         string
-		then         string
-		limit        int
-		offset       int
-		setupMocks   func(*MockInsightRepository, int, int)
-		expectErr    bool
-		validateList func(*testing.T, []*insights.Insight)
+		name          string
+		given         string
+		when          string
+		then          string
+		stats         []*insights.FixEffectiveness
+		statsErr      error
+		expectHistory []*insights.FixEffectiveness
 	}{
 		{
-			name:   "Successfully list insights with default pagination",
-			given:  "multiple insights in repository",
-			when:   "listing insights with limit 50 and offset 0",
-			then:   "should return list of insights",
-			limit:  50,
-			offset: 0,
-			setupMocks: func(repo *MockInsightRepository, limit, offset int) {
-				insightsList := []*insights.Insight{
-					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Diagnosis 1", CreatedAt: time.Now().UTC()},
-					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Diagnosis 2", CreatedAt: time.Now().UTC()},
-					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Diagnosis 3", CreatedAt: time.Now().UTC()},
-				}
-				repo.On("List", mock.Anything, limit, offset).Return(insightsList, nil)
-			},
-			expectErr: false,
-			validateList: func(t *testing.T, list []*insights.Insight) {
-				assert.Equal(t, 3, len(list))
-			},
+			name:          "Fix history in the prompt",
+			given:         "fixes applied to email jobs, one of them not run again yet",
+			when:          "analyzing a failed email job",
+			then:          "should send the AI the fixes that ran again, most effective first",
+			stats:         []*insights.FixEffectiveness{timeout, unresolved, patch},
+			expectHistory: []*insights.FixEffectiveness{patch, timeout},
 		},
 		{
-			name:   "List with custom pagination",
-			given:  "custom limit and offset",
-			when:   "listing insights with limit 10 and offset 5",
-			then:   "should return paginated results",
-			limit:  10,
-			offset: 5,
-			setupMocks: func(repo *MockInsightRepository, limit, offset int) {
-				insightsList := []*insights.Insight{
-					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Paginated insight", CreatedAt: time.Now().UTC()},
-				}
-				repo.On("List", mock.Anything, limit, offset).Return(insightsList, nil)
-			},
-			expectErr: false,
-			validateList: func(t *testing.T, list []*insights.Insight) {
-				assert.Equal(t, 1, len(list))
-			},
+			name:     "Fix history unavailable",
+			given:    "a repository that can't load the fix history",
+			when:     "analyzing a failed email job",
+			then:     "should analyze the job without it",
+			statsErr: errors.New("connection refused"),
 		},
-		{
-			name:   "Empty list when no insights exist",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobID := uuid.New()
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("FixEffectiveness", mock.Anything, "email", mock.AnythingOfType("time.Time")).Return(tt.stats, tt.statsErr)
+			insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, errors.New("not found"))
+			insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+			jobRepo := new(MockJobRepository)
+			jobRepo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID, Type: "email", Status: queue.StatusFailed, Error: "smtp timeout"}, nil)
+			aiService := new(MockAIService)
+			aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+				Return(&insights.AnalysisResponse{Diagnosis: "SMTP server slow", Confidence: 0.8}, nil)
+			service := NewService(insightRepo, jobRepo, aiService)
+
+			// When
+			_, err := service.AnalyzeJobFailure(context.Background(), jobID)
+
+			// Then
+			assert.NoError(t, err)
+			aiService.AssertCalled(t, "Analyze", mock.Anything, mock.MatchedBy(func(request *insights.AnalysisRequest) bool {
+				return assert.ObjectsAreEqual(tt.expectHistory, request.FixHistory)
+			}))
+		})
+	}
+}
+
+type StaticAttempts struct {
+	attempts []*queue.Attempt
+	err      error
+}
+
+func (a StaticAttempts) RecordAttempt(ctx context.Context, attempt *queue.Attempt) error {
+	return nil
+}
+
+func (a StaticAttempts) RecordRetry(ctx context.Context, jobID uuid.UUID, number int, backoff time.Duration, retryAt time.Time) error {
+	return nil
+}
+
+func (a StaticAttempts) ListAttempts(ctx context.Context, jobID uuid.UUID, limit int) ([]*queue.Attempt, error) {
+	return a.attempts, a.err
+}
+
+type StaticOutput []byte
+
+func (o StaticOutput) AppendOutput(ctx context.Context, jobID uuid.UUID, offset int64, data []byte) error {
+	return nil
+}
+
+func (o StaticOutput) OutputSize(ctx context.Context, jobID uuid.UUID) (int64, error) {
+	return int64(len(o)), nil
+}
+
+func (o StaticOutput) ReadOutput(ctx context.Context, jobID uuid.UUID, offset, length int64) ([]byte, error) {
+	return o[offset : offset+length], nil
+}
+
+func (o StaticOutput) ClearOutput(ctx context.Context, jobID uuid.UUID) error {
+	return nil
+}
+
+type StaticDefinitions map[string]*queue.Definition
+
+func (d StaticDefinitions) Create(ctx context.Context, def *queue.Definition) error { return nil }
+
+func (d StaticDefinitions) Get(ctx context.Context, name string) (*queue.Definition, error) {
+	if def, ok := d[name]; ok {
+		return def, nil
+	}
+	return nil, queue.ErrQueueNotDefined
+}
+
+func (d StaticDefinitions) List(ctx context.Context) ([]*queue.Definition, error) { return nil, nil }
+
+func (d StaticDefinitions) Update(ctx context.Context, def *queue.Definition) error { return nil }
+
+func (d StaticDefinitions) Delete(ctx context.Context, name string) error { return nil }
+
+func TestService_AnalyzeJobFailure_JobContext(t *testing.T) {
+	startedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	attempts := []*queue.Attempt{
+		{Number: 1, StartedAt: startedAt, Duration: 30 * time.Second, Error: "smtp timeout for ana@example.com", Category: queue.FailureTimeout},
+		{Number: 2, StartedAt: startedAt.Add(time.Minute), Duration: 30 * time.Second, Error: "smtp timeout for ana@example.com", Category: queue.FailureTimeout},
+	}
+	emails := &queue.Definition{Name: "emails", MaxAttempts: 5}
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		attempts       StaticAttempts
+		output         StaticOutput
+		definitions    StaticDefinitions
+		redact         bool
+		expectAttempts []*queue.Attempt
+		expectOutput   string
+		expectQueue    *queue.Definition
+	}{
+		{
+			name:           "Job context in the prompt",
+			given:          "a job that failed twice, wrote output and runs in a defined queue",
+			when:           "analyzing its failure",
+			then:           "should send the AI its attempts, output, type and queue settings",
+			attempts:       StaticAttempts{attempts: attempts},
+			output:         StaticOutput("connecting to smtp.example.com\ntimed out after 30s\n"),
+			definitions:    StaticDefinitions{"emails": emails},
+			expectAttempts: attempts,
+			expectOutput:   "connecting to smtp.example.com\ntimed out after 30s\n",
+			expectQueue:    emails,
+		},
+		{
+			name:     "Redacted job context",
+			given:    "a redactor masking email addresses",
+			when:     "analyzing a job whose attempt errors and output name a recipient",
+			then:     "should mask them without changing the stored attempts",
+			attempts: StaticAttempts{attempts: attempts},
+			output:   StaticOutput("sending to ana@example.com\n"),
+			redact:   true,
+			expectAttempts: []*queue.Attempt{
+				{Number: 1, StartedAt: startedAt, Duration: 30 * time.Second, Error: "smtp timeout for [REDACTED]", Category: queue.FailureTimeout},
+				{Number: 2, StartedAt: startedAt.Add(time.Minute), Duration: 30 * time.Second, Error: "smtp timeout for [REDACTED]", Category: queue.FailureTimeout},
+			},
+			expectOutput: "sending to [REDACTED]\n",
+		},
+		{
+			name:     "Job context unavailable",
+			given:    "attempts that can't be loaded, no output and an undefined queue",
+			when:     "analyzing the failure",
+			then:     "should analyze the job without them",
+			attempts: StaticAttempts{err: errors.New("connection refused")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobID := uuid.New()
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("FixEffectiveness", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+			insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, errors.New("not found"))
+			insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+			jobRepo := new(MockJobRepository)
+			jobRepo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID, Queue: "emails", Type: "email", Status: queue.StatusFailed, Error: "smtp timeout", Attempts: 2}, nil)
+			aiService := new(MockAIService)
+			aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+				Return(&insights.AnalysisResponse{Diagnosis: "SMTP server unreachable", Confidence: 0.8}, nil)
+			service := NewService(insightRepo, jobRepo, aiService)
+			service.SetAttemptHistory(tt.attempts)
+			service.SetOutputStore(tt.output)
+			service.SetQueueDefinitions(tt.definitions)
+			if tt.redact {
+				redactor, err := redaction.NewRedactor(nil, []redaction.Pattern{{Name: "email", Regex: redaction.EmailPattern}}, "")
+				assert.NoError(t, err)
+				service.SetRedactor(redactor)
+			}
+
+			// When
+			_, err := service.AnalyzeJobFailure(context.Background(), jobID)
+
+			// Then
+			assert.NoError(t, err)
+			aiService.AssertCalled(t, "Analyze", mock.Anything, mock.MatchedBy(func(request *insights.AnalysisRequest) bool {
+				return request.JobType == "email" && request.Queue == "emails" &&
+					assert.ObjectsAreEqual(tt.expectAttempts, request.Attempts) &&
+					request.Output == tt.expectOutput &&
+					request.QueueConfig == tt.expectQueue
+			}))
+			assert.Equal(t, "smtp timeout for ana@example.com", attempts[0].Error)
+		})
+	}
+}
+
+func TestService_AnalyzeJobFailure_Timeout(t *testing.T) {
+	// Given
+	jobID := uuid.New()
+	insightRepo := new(MockInsightRepository)
+	insightRepo.On("FixEffectiveness", mock.Anything, "email", mock.AnythingOfType("time.Time")).Return(nil, nil)
+	insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, errors.New("not found"))
+	jobRepo := new(MockJobRepository)
+	jobRepo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID, Type: "email", Status: queue.StatusFailed, Error: "smtp timeout"}, nil)
+	aiService := new(MockAIService)
+	aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+		Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(nil, context.DeadlineExceeded)
+	service := NewService(insightRepo, jobRepo, aiService)
+	service.SetAnalysisTimeout(10 * time.Millisecond)
+
+	// When
+	insight, err := service.AnalyzeJobFailure(context.Background(), jobID)
+
+	// Then
+	assert.Nil(t, insight)
+	assert.ErrorIs(t, err, insights.ErrAIServiceUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "AI analysis timed out after 10ms")
+	insightRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_AnalyzeJobFailure_Storm(t *testing.T) {
+	// Given a storm threshold of two failures and three email jobs failing the same way, then a fourth failing differently
+	failures := []struct {
+		id    uuid.UUID
+		error string
+	}{
+		{uuid.New(), "dial tcp 10.0.0.1:587: connection refused"},
+		{uuid.New(), "dial tcp 10.0.0.2:587: connection refused"},
+		{uuid.New(), "dial tcp 10.0.0.3:587: connection refused"},
+		{uuid.New(), "401 Unauthorized"},
+	}
+	insightRepo := new(MockInsightRepository)
+	insightRepo.On("FixEffectiveness", mock.Anything, "email", mock.AnythingOfType("time.Time")).Return(nil, nil)
+	insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+	jobRepo := new(MockJobRepository)
+	for _, failure := range failures {
+		insightRepo.On("GetByJobID", mock.Anything, failure.id).Return(nil, errors.New("not found"))
+		jobRepo.On("GetByID", mock.Anything, failure.id).Return(&queue.Job{ID: failure.id, Type: "email", Status: queue.StatusFailed, Error: failure.error}, nil)
+	}
+	aiService := new(MockAIService)
+	aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+		Return(&insights.AnalysisResponse{Diagnosis: "SMTP server down", Confidence: 0.8}, nil)
+	service := NewService(insightRepo, jobRepo, aiService)
+	detector, err := insights.NewStormDetector(insights.StormConfig{FailureThreshold: 2, Window: time.Minute})
+	assert.NoError(t, err)
+	service.SetStormDetector(detector)
+
+	// When
+	var results []*insights.Insight
+	for _, failure := range failures {
+		insight, err := service.AnalyzeJobFailure(context.Background(), failure.id)
+		assert.NoError(t, err)
+		results = append(results, insight)
+	}
+
+	// Then the first failure is analyzed before the storm, the second as the sample of its
+	// signature, the third shares the sample's insight and the fourth is a sample of its own
+	aiService.AssertNumberOfCalls(t, "Analyze", 3)
+	assert.Nil(t, results[0].SharedFrom)
+	assert.Nil(t, results[1].SharedFrom)
+	if assert.NotNil(t, results[2].SharedFrom) {
+		assert.Equal(t, results[1].ID, *results[2].SharedFrom)
+	}
+	assert.Equal(t, failures[2].id, results[2].JobID)
+	assert.Equal(t, "SMTP server down", results[2].Diagnosis)
+	assert.Nil(t, results[3].SharedFrom)
+}
+
+func TestService_AnalyzeJobFailure_StormSampleFails(t *testing.T) {
+	// Given a storm from the first failure and an AI service that is down
+	first, second := uuid.New(), uuid.New()
+	insightRepo := new(MockInsightRepository)
+	insightRepo.On("FixEffectiveness", mock.Anything, "email", mock.AnythingOfType("time.Time")).Return(nil, nil)
+	jobRepo := new(MockJobRepository)
+	for _, id := range []uuid.UUID{first, second} {
+		insightRepo.On("GetByJobID", mock.Anything, id).Return(nil, errors.New("not found"))
+		jobRepo.On("GetByID", mock.Anything, id).Return(&queue.Job{ID: id, Type: "email", Status: queue.StatusFailed, Error: "connection refused"}, nil)
+	}
+	aiService := new(MockAIService)
+	aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+		Return(nil, insights.ErrAIServiceUnavailable)
+	service := NewService(insightRepo, jobRepo, aiService)
+	detector, err := insights.NewStormDetector(insights.StormConfig{FailureThreshold: 1, Window: time.Minute})
+	assert.NoError(t, err)
+	service.SetStormDetector(detector)
+
+	// When
+	_, firstErr := service.AnalyzeJobFailure(context.Background(), first)
+	_, secondErr := service.AnalyzeJobFailure(context.Background(), second)
+
+	// Then the failed sample is dropped, so the next failure of the signature is analyzed again
+	assert.ErrorIs(t, firstErr, insights.ErrAIServiceUnavailable)
+	assert.ErrorIs(t, secondErr, insights.ErrAIServiceUnavailable)
+	aiService.AssertNumberOfCalls(t, "Analyze", 2)
+	insightRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_AnalyzeJobFailure_Reuse(t *testing.T) {
+	jobError := "dial tcp 10.0.0.7:587: connection refused"
+	existing := &insights.Insight{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "SMTP server down", Fingerprint: queue.ErrorFingerprint(jobError)}
+
+	tests := []struct {
+		name string
+		in   struct {
+			found   *insights.Insight
+			findErr error
+			linkErr error
+		}
+		want struct {
+			reused  bool
+			aiCalls int
+		}
+	}{
+		{
+			name: "Given an insight of the same fingerprint, When analyzing, Then should link the job to it without calling the AI",
+			in: struct {
+				found   *insights.Insight
+				findErr error
+				linkErr error
+			}{found: existing},
+			want: struct {
+				reused  bool
+				aiCalls int
+			}{reused: true, aiCalls: 0},
+		},
+		{
+			name: "Given no insight of the same fingerprint, When analyzing, Then should call the AI",
+			in: struct {
+				found   *insights.Insight
+				findErr error
+				linkErr error
+			}{findErr: insights.ErrInsightNotFound},
+			want: struct {
+				reused  bool
+				aiCalls int
+			}{reused: false, aiCalls: 1},
+		},
+		{
+			name: "Given the link fails, When analyzing, Then should call the AI",
+			in: struct {
+				found   *insights.Insight
+				findErr error
+				linkErr error
+			}{found: existing, linkErr: errors.New("connection reset")},
+			want: struct {
+				reused  bool
+				aiCalls int
+			}{reused: false, aiCalls: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobID := uuid.New()
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("GetByJobID", mock.Anything, jobID).Return(nil, insights.ErrInsightNotFound)
+			insightRepo.On("FindByFingerprint", mock.Anything, "email", existing.Fingerprint, mock.AnythingOfType("time.Time")).
+				Return(tt.in.found, tt.in.findErr)
+			insightRepo.On("LinkJob", mock.Anything, existing.ID, jobID).Return(tt.in.linkErr)
+			insightRepo.On("FixEffectiveness", mock.Anything, "email", mock.AnythingOfType("time.Time")).Return(nil, nil)
+			insightRepo.On("Create", mock.Anything, mock.AnythingOfType("*insights.Insight")).Return(nil)
+			jobRepo := new(MockJobRepository)
+			jobRepo.On("GetByID", mock.Anything, jobID).
+				Return(&queue.Job{ID: jobID, Type: "email", Status: queue.StatusFailed, Error: "dial tcp 10.0.0.9:25: connection refused"}, nil)
+			aiService := new(MockAIService)
+			aiService.On("Analyze", mock.Anything, mock.AnythingOfType("*insights.AnalysisRequest")).
+				Return(&insights.AnalysisResponse{Diagnosis: "SMTP server down", Confidence: 0.8}, nil)
+			service := NewService(insightRepo, jobRepo, aiService)
+			service.SetInsightReuse(time.Hour)
+
+			// When
+			insight, err := service.AnalyzeJobFailure(context.Background(), jobID)
+
+			// Then
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.want.reused, insight.ID == existing.ID)
+			assert.Equal(t, existing.Fingerprint, insight.Fingerprint)
+			aiService.AssertNumberOfCalls(t, "Analyze", tt.want.aiCalls)
+		})
+	}
+}
+
+func TestService_SummarizeQueue(t *testing.T) {
+	since := time.Now().UTC().AddDate(0, 0, -30)
+	entries := []*insights.QueueInsight{
+		{JobType: "email", Insight: &insights.Insight{Diagnosis: "SMTP timeout", Confidence: 0.8, CreatedAt: time.Now().UTC()}},
+		{JobType: "email", Insight: &insights.Insight{Diagnosis: "SMTP timeout", Confidence: 0.6, CreatedAt: time.Now().UTC()}},
+	}
+
+	tests := []struct {
+		name             string
+		given            string
+		when             string
+		then             string
+		entries          []*insights.QueueInsight
+		withAI           bool
+		setupAI          func(*MockAIService)
+		expectExecutive  *insights.ExecutiveSummary
+		expectSummaryErr string
+	}{
+		{
+			name:    "Summary with executive summary",
+			given:   "a queue with analyzed jobs",
+			when:    "summarizing it with the AI",
+			then:    "should aggregate the insights and keep the AI's executive summary",
+			entries: entries,
+			withAI:  true,
+			setupAI: func(ai *MockAIService) {
+				ai.On("Analyze", mock.Anything, mock.MatchedBy(func(request *insights.AnalysisRequest) bool {
+					return request.JobID == "summary of queue emails" && strings.Contains(request.Error, "2 jobs (email): SMTP timeout")
+				})).Return(&insights.AnalysisResponse{Diagnosis: "The relay is slow", Recommendation: "Raise the timeout", Confidence: 0.9}, nil)
+			},
+			expectExecutive: &insights.ExecutiveSummary{Summary: "The relay is slow", Recommendation: "Raise the timeout", Confidence: 0.9},
+		},
+		{
+			name:    "AI failure",
+			given:   "an AI that fails",
+			when:    "summarizing the queue with the AI",
+			then:    "should return the aggregates with the reason there is no executive summary",
+			entries: entries,
+			withAI:  true,
+			setupAI: func(ai *MockAIService) {
+				ai.On("Analyze", mock.Anything, mock.Anything).Return(nil, errors.New("provider unavailable"))
+			},
+			expectSummaryErr: "provider unavailable",
+		},
+		{
+			name:    "Without AI",
+			given:   "a queue with analyzed jobs",
+			when:    "summarizing it without the AI",
+			then:    "should aggregate the insights without calling the AI",
+			entries: entries,
+			setupAI: func(ai *MockAIService) {},
+		},
+		{
+			name:    "No insights",
+			given:   "a queue without analyzed jobs",
+			when:    "summarizing it with the AI",
+			then:    "should return an empty summary without calling the AI",
+			withAI:  true,
+			setupAI: func(ai *MockAIService) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("ListByQueue", mock.Anything, "emails", since, insights.MaxSummaryInsights).Return(tt.entries, nil)
+			aiService := new(MockAIService)
+			tt.setupAI(aiService)
+			service := NewService(insightRepo, new(MockJobRepository), aiService)
+
+			// When
+			summary, err := service.SummarizeQueue(context.Background(), "emails", since, tt.withAI)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.entries), summary.Insights)
+			if len(tt.entries) > 0 {
+				assert.Equal(t, 2, summary.Diagnoses[0].Count)
+			}
+			assert.Equal(t, tt.expectExecutive, summary.ExecutiveSummary)
+			assert.Equal(t, tt.expectSummaryErr, summary.SummaryError)
+			aiService.AssertExpectations(t)
+		})
+	}
+}
+
+// fakeEmbedder embeds texts with fixed vectors, recording the texts of each call
+type fakeEmbedder struct {
+	model   string
+	vectors map[string][]float64
+	err     error
+	calls   [][]string
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) (*insights.Embeddings, error) {
+	e.calls = append(e.calls, texts)
+	if e.err != nil {
+		return nil, e.err
+	}
+	embeddings := &insights.Embeddings{Model: e.model}
+	for _, text := range texts {
+		embeddings.Vectors = append(embeddings.Vectors, e.vectors[text])
+	}
+	return embeddings, nil
+}
+
+func TestService_ClusterInsights(t *testing.T) {
+	now := time.Now().UTC()
+	entry := func(jobType, diagnosis string) *insights.QueueInsight {
+		return &insights.QueueInsight{JobType: jobType, Insight: &insights.Insight{ID: uuid.New(), Diagnosis: diagnosis, Confidence: 0.8, CreatedAt: now}}
+	}
+	entries := []*insights.QueueInsight{
+		entry("email", "SMTP server timed out"),
+		entry("digest", "SMTP server timed out"),
+		entry("email", "SMTP connection timed out"),
+		entry("billing", "Credentials were rejected"),
+	}
+	vectors := map[string][]float64{
+		"SMTP server timed out":     {1, 0.1, 0},
+		"SMTP connection timed out": {1, 0, 0},
+		"Credentials were rejected": {0, 0, 1},
+	}
+
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		embedder        *fakeEmbedder
+		secondModel     string
+		expectErr       error
+		expectClusters  []int
+		expectEmbedded  [][]string
+		expectReembedAt int // Run embedding every diagnosis again; 0 when none does
+	}{
+		{
+			name:           "Clusters of similar diagnoses",
+			given:          "insights of two SMTP timeouts worded differently and a credentials failure",
+			when:           "clustering twice",
+			then:           "should group the SMTP timeouts and embed each diagnosis once",
+			embedder:       &fakeEmbedder{model: "stub", vectors: vectors},
+			expectClusters: []int{3},
+			expectEmbedded: [][]string{{"SMTP server timed out", "SMTP connection timed out", "Credentials were rejected"}},
+		},
+		{
+			name:           "Embedding model changed",
+			given:          "an embedder answering with another model on the second run",
+			when:           "clustering twice",
+			then:           "should embed every diagnosis again with the new model",
+			embedder:       &fakeEmbedder{model: "stub", vectors: vectors},
+			secondModel:    "ollama:nomic-embed-text",
+			expectClusters: []int{3},
+			expectEmbedded: [][]string{
+				{"SMTP server timed out", "SMTP connection timed out", "Credentials were rejected"},
+				{"Unseen diagnosis"},
+			},
+			expectReembedAt: 2,
+		},
+		{
+			name:      "Embedder failure",
+			given:     "an embedder that fails",
+			when:      "clustering",
+			then:      "should return the error and keep no clusters",
+			embedder:  &fakeEmbedder{err: errors.New("no provider embeds")},
+			expectErr: errors.New("no provider embeds"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("ListByQueue", mock.Anything, "", mock.Anything, 1000).Return(entries, nil).Once()
+			service := NewService(insightRepo, new(MockJobRepository), new(MockAIService))
+			assert.NoError(t, service.SetInsightClustering(tt.embedder, insights.DefaultClusterConfig()))
+
+			// When
+			report, err := service.ClusterInsights(context.Background())
+
+			// Then
+			if tt.expectErr != nil {
+				assert.EqualError(t, err, tt.expectErr.Error())
+				_, err = service.InsightClusters()
+				assert.ErrorIs(t, err, ErrClustersNotReady)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, len(entries), report.Insights)
+			assert.Len(t, report.Clusters, len(tt.expectClusters))
+			for i, members := range tt.expectClusters {
+				assert.Equal(t, members, report.Clusters[i].Members)
+				assert.Equal(t, []string{"digest", "email"}, report.Clusters[i].JobTypes)
+			}
+			assert.Equal(t, 1, report.Unclustered)
+			latest, err := service.InsightClusters()
+			assert.NoError(t, err)
+			assert.Same(t, report, latest)
+
+			// When clustering again
+			second := entries
+			if tt.secondModel != "" {
+				tt.embedder.model = tt.secondModel
+				second = append(append([]*insights.QueueInsight{}, entries...), entry("email", "Unseen diagnosis"))
+			}
+			insightRepo.On("ListByQueue", mock.Anything, "", mock.Anything, 1000).Return(second, nil).Once()
+			report, err = service.ClusterInsights(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectEmbedded, tt.embedder.calls[:len(tt.expectEmbedded)])
+			if tt.expectReembedAt > 0 {
+				assert.Len(t, tt.embedder.calls, tt.expectReembedAt+1)
+				assert.ElementsMatch(t, append(tt.expectEmbedded[0], "Unseen diagnosis"), tt.embedder.calls[tt.expectReembedAt])
+				assert.Equal(t, tt.secondModel, report.Model)
+			} else {
+				assert.Len(t, tt.embedder.calls, len(tt.expectEmbedded))
+			}
+		})
+	}
+}
+
+func TestService_InsightClusters_Disabled(t *testing.T) {
+	// Given
+	service := NewService(new(MockInsightRepository), new(MockJobRepository), new(MockAIService))
+
+	// When
+	_, err := service.InsightClusters()
+
+	// Then
+	assert.ErrorIs(t, err, ErrClusteringDisabled)
+}
+
+type RecordingPublisher struct {
+	events []events.Event
+}
+
+func (p *RecordingPublisher) Publish(ctx context.Context, event events.Event) {
+	p.events = append(p.events, event)
+}
+
+// scriptedProber fails the probes while err is set
+type scriptedProber struct {
+	err error
+}
+
+func (p *scriptedProber) Probe(ctx context.Context) error {
+	return p.err
+}
+
+func TestService_ProbeProvider(t *testing.T) {
+	errDown := errors.New("dial tcp 127.0.0.1:11434: connection refused")
+
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		failures      int
+		recover       bool
+		expectState   insights.ProviderState
+		expectEvents  []string
+		expectOutages int
+	}{
+		{
+			name:        "Failures below the threshold",
+			given:       "an AI provider failing two probes",
+			when:        "checking the provider",
+			then:        "should stay healthy and allow synchronous analyses",
+			failures:    2,
+			expectState: insights.ProviderHealthy,
+		},
+		{
+			name:          "Degraded provider",
+			given:         "an AI provider failing as many probes as the threshold",
+			when:          "checking the provider",
+			then:          "should report it degraded once and reject synchronous analyses",
+			failures:      3,
+			expectState:   insights.ProviderDegraded,
+			expectEvents:  []string{events.NameAIProviderDegraded},
+			expectOutages: 1,
+		},
+		{
+			name:          "Recovered provider",
+			given:         "a degraded AI provider",
+			when:          "a probe succeeds",
+			then:          "should report the recovery and allow synchronous analyses again",
+			failures:      4,
+			recover:       true,
+			expectState:   insights.ProviderHealthy,
+			expectEvents:  []string{events.NameAIProviderDegraded, events.NameAIProviderRecovered},
+			expectOutages: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			prober := &scriptedProber{err: errDown}
+			publisher := &RecordingPublisher{}
+			service := NewService(new(MockInsightRepository), new(MockJobRepository), new(MockAIService))
+			service.SetEventPublisher(publisher)
+			assert.NoError(t, service.SetProviderHealth(prober, insights.DefaultProviderHealthConfig()))
+			for range tt.failures {
+				assert.ErrorIs(t, service.ProbeProvider(context.Background()), errDown)
+			}
+			if tt.recover {
+				prober.err = nil
+				assert.NoError(t, service.ProbeProvider(context.Background()))
+			}
+
+			// When
+			status, err := service.ProviderHealth()
+			checkErr := service.CheckProvider()
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectState, status.State)
+			assert.Equal(t, tt.expectOutages, status.Outages)
+			var names []string
+			for _, event := range publisher.events {
+				names = append(names, event.Name())
+			}
+			assert.Equal(t, tt.expectEvents, names)
+			if tt.expectState == insights.ProviderHealthy {
+				assert.NoError(t, checkErr)
+				return
+			}
+			var unavailable *ProviderUnavailableError
+			assert.ErrorAs(t, checkErr, &unavailable)
+			assert.ErrorIs(t, checkErr, insights.ErrAIServiceUnavailable)
+			assert.Positive(t, unavailable.RetryAfter)
+			assert.Equal(t, errDown.Error(), unavailable.LastError)
+		})
+	}
+}
+
+func TestService_ProviderHealth_Disabled(t *testing.T) {
+	// Given
+	service := NewService(new(MockInsightRepository), new(MockJobRepository), new(MockAIService))
+
+	// When
+	_, err := service.ProviderHealth()
+
+	// Then
+	assert.ErrorIs(t, err, ErrProviderHealthDisabled)
+	assert.NoError(t, service.CheckProvider())
+}
+
+func TestService_GetInsight(t *testing.T) {
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		insightID       uuid.UUID
+		setupMocks      func(*MockInsightRepository, uuid.UUID)
+		expectErr       bool
+		validateInsight func(*testing.T, *insights.Insight)
+	}{
+		{
+			name:      "Successfully get insight by ID",
+			given:     "valid insight ID",
+			when:      "retrieving insight",
+			then:      "should return insight",
+			insightID: uuid.New(),
+			setupMocks: func(repo *MockInsightRepository, id uuid.UUID) {
+				insight := &insights.Insight{
+					ID:             id,
+					JobID:          uuid.New(),
+					Diagnosis:      "Test diagnosis",
+					Recommendation: "Test recommendation",
+					CreatedAt:      time.Now().UTC(),
+				}
+				repo.On("GetByID", mock.Anything, id).Return(insight, nil)
+			},
+			expectErr: false,
+			validateInsight: func(t *testing.T, insight *insights.Insight) {
+				assert.Equal(t, "Test diagnosis", insight.Diagnosis)
+			},
+		},
+		{
+			name:      "Insight not found",
+			given:     "non-existent insight ID",
+			when:      "retrieving insight",
+			then:      "should return not found error",
+			insightID: uuid.New(),
+			setupMocks: func(repo *MockInsightRepository, id uuid.UUID) {
+				repo.On("GetByID", mock.Anything, id).
+					Return(nil, insights.ErrInsightNotFound)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insightRepo := new(MockInsightRepository)
+			tt.setupMocks(insightRepo, tt.insightID)
+
+			service := NewService(insightRepo, new(MockJobRepository), new(MockAIService))
+			ctx := context.Background()
+
+			// When
+			insight, err := service.GetInsight(ctx, tt.insightID)
+
+			// Then
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, insight)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, insight)
+				if tt.validateInsight != nil {
+					tt.validateInsight(t, insight)
+				}
+			}
+
+			insightRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_GetInsightByJobID(t *testing.T) {
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		jobID           uuid.UUID
+		setupMocks      func(*MockInsightRepository, uuid.UUID)
+		expectErr       bool
+		validateInsight func(*testing.T, *insights.Insight)
+	}{
+		{
+			name:  "Successfully get insight by job ID",
+			given: "valid job ID with existing insight",
+			when:  "retrieving insight by job ID",
+			then:  "should return insight",
+			jobID: uuid.New(),
+			setupMocks: func(repo *MockInsightRepository, jobID uuid.UUID) {
+				insight := &insights.Insight{
+					ID:             uuid.New(),
+					JobID:          jobID,
+					Diagnosis:      "Job-specific diagnosis",
+					Recommendation: "Job-specific recommendation",
+					CreatedAt:      time.Now().UTC(),
+				}
+				repo.On("GetByJobID", mock.Anything, jobID).Return(insight, nil)
+			},
+			expectErr: false,
+			validateInsight: func(t *testing.T, insight *insights.Insight) {
+				assert.Equal(t, "Job-specific diagnosis", insight.Diagnosis)
+			},
+		},
+		{
+			name:  "Insight not found for job",
+			given: "job ID without insight",
+			when:  "retrieving insight by job ID",
+			then:  "should return not found error",
+			jobID: uuid.New(),
+			setupMocks: func(repo *MockInsightRepository, jobID uuid.UUID) {
+				repo.On("GetByJobID", mock.Anything, jobID).
+					Return(nil, insights.ErrInsightNotFound)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insightRepo := new(MockInsightRepository)
+			tt.setupMocks(insightRepo, tt.jobID)
+
+			service := NewService(insightRepo, new(MockJobRepository), new(MockAIService))
+			ctx := context.Background()
+
+			// When
+			insight, err := service.GetInsightByJobID(ctx, tt.jobID)
+
+			// Then
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, insight)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, insight)
+				if tt.validateInsight != nil {
+					tt.validateInsight(t, insight)
+				}
+			}
+
+			insightRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_ListInsights(t *testing.T) {
+	tests := []struct {
+		name         string
+		given        string
+		when         string
+		then         string
+		kind         insights.Kind
+		limit        int
+		offset       int
+		setupMocks   func(*MockInsightRepository, int, int)
+		expectErr    bool
+		validateList func(*testing.T, []*insights.Insight)
+	}{
+		{
+			name:   "Successfully list insights with default pagination",
+			given:  "multiple insights in repository",
+			when:   "listing insights with limit 50 and offset 0",
+			then:   "should return list of insights",
+			limit:  50,
+			offset: 0,
+			setupMocks: func(repo *MockInsightRepository, limit, offset int) {
+				insightsList := []*insights.Insight{
+					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Diagnosis 1", CreatedAt: time.Now().UTC()},
+					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Diagnosis 2", CreatedAt: time.Now().UTC()},
+					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Diagnosis 3", CreatedAt: time.Now().UTC()},
+				}
+				repo.On("List", mock.Anything, insights.Kind(""), limit, offset).Return(insightsList, nil)
+			},
+			expectErr: false,
+			validateList: func(t *testing.T, list []*insights.Insight) {
+				assert.Equal(t, 3, len(list))
+			},
+		},
+		{
+			name:   "List with custom pagination",
+			given:  "custom limit and offset",
+			when:   "listing insights with limit 10 and offset 5",
+			then:   "should return paginated results",
+			limit:  10,
+			offset: 5,
+			setupMocks: func(repo *MockInsightRepository, limit, offset int) {
+				insightsList := []*insights.Insight{
+					{ID: uuid.New(), JobID: uuid.New(), Diagnosis: "Paginated insight", CreatedAt: time.Now().UTC()},
+				}
+				repo.On("List", mock.Anything, insights.Kind(""), limit, offset).Return(insightsList, nil)
+			},
+			expectErr: false,
+			validateList: func(t *testing.T, list []*insights.Insight) {
+				assert.Equal(t, 1, len(list))
+			},
+		},
+		{
+			name:   "Empty list when no insights exist",
 			given:  "empty repository",
 			when:   "listing insights",
 			then:   "should return empty list",
 			limit:  50,
 			offset: 0,
 			setupMocks: func(repo *MockInsightRepository, limit, offset int) {
-				repo.On("List", mock.Anything, limit, offset).Return([]*insights.Insight{}, nil)
+				repo.On("List", mock.Anything, insights.Kind(""), limit, offset).Return([]*insights.Insight{}, nil)
 			},
 			expectErr: false,
 			validateList: func(t *testing.T, list []*insights.Insight) {
@@ -544,11 +1631,42 @@ func TestService_ListInsights(t *testing.T) {
 			limit:  50,
 			offset: 0,
 			setupMocks: func(repo *MockInsightRepository, limit, offset int) {
-				repo.On("List", mock.Anything, limit, offset).
+				repo.On("List", mock.Anything, insights.Kind(""), limit, offset).
 					Return(nil, errors.New("database error"))
 			},
 			expectErr: true,
 		},
+		{
+			name:   "List performance insights",
+			given:  "the performance kind",
+			when:   "listing insights",
+			then:   "should pass the kind to the repository",
+			kind:   insights.KindPerformance,
+			limit:  50,
+			offset: 0,
+			setupMocks: func(repo *MockInsightRepository, limit, offset int) {
+				insightsList := []*insights.Insight{
+					{ID: uuid.New(), JobID: uuid.New(), Kind: insights.KindPerformance, Diagnosis: "Slow query", CreatedAt: time.Now().UTC()},
+				}
+				repo.On("List", mock.Anything, insights.KindPerformance, limit, offset).Return(insightsList, nil)
+			},
+			expectErr: false,
+			validateList: func(t *testing.T, list []*insights.Insight) {
+				assert.Equal(t, 1, len(list))
+				assert.Equal(t, insights.KindPerformance, list[0].Kind)
+			},
+		},
+		{
+			name:       "Invalid kind",
+			given:      "an unknown kind",
+			when:       "listing insights",
+			then:       "should return ErrInvalidKind without querying the repository",
+			kind:       insights.Kind("latency"),
+			limit:      50,
+			offset:     0,
+			setupMocks: func(repo *MockInsightRepository, limit, offset int) {},
+			expectErr:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -561,7 +1679,7 @@ func TestService_ListInsights(t *testing.T) {
 			ctx := context.Background()
 
 			// When
-			list, err := service.ListInsights(ctx, tt.limit, tt.offset)
+			list, err := service.ListInsights(ctx, tt.kind, tt.limit, tt.offset)
 
 			// Then
 			if tt.expectErr {
@@ -579,3 +1697,227 @@ func TestService_ListInsights(t *testing.T) {
 		})
 	}
 }
+
+// MockQueueService is a queue.QueueService recording the jobs enqueued
+type MockQueueService struct {
+	mock.Mock
+}
+
+func (m *MockQueueService) Enqueue(ctx context.Context, job *queue.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *MockQueueService) Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*queue.Job, error) {
+	return nil, nil
+}
+
+func (m *MockQueueService) Acknowledge(ctx context.Context, jobID uuid.UUID) error {
+	return nil
+}
+
+func (m *MockQueueService) Nack(ctx context.Context, job *queue.Job) error {
+	return nil
+}
+
+func (m *MockQueueService) DeliveryStats(ctx context.Context) ([]*queue.DeliveryStats, error) {
+	return nil, nil
+}
+
+func TestService_ApplyInsightFix(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		dryRun         bool
+		withQueue      bool
+		enqueueErr     error
+		expectWrite    bool
+		expectEnqueued bool
+	}{
+		{
+			name:        "Preview fix without modifying the job",
+			given:       "an insight with a payload patch and retry recommendation",
+			when:        "previewing the fix",
+			then:        "should return the plan without updating the job or writing an audit entry",
+			dryRun:      true,
+			withQueue:   true,
+			expectWrite: false,
+		},
+		{
+			name:           "Apply fix and re-enqueue the job",
+			given:          "an insight with a payload patch and retry recommendation and a queue service",
+			when:           "applying the fix",
+			then:           "should update the job, record the application and enqueue the patched job",
+			withQueue:      true,
+			expectWrite:    true,
+			expectEnqueued: true,
+		},
+		{
+			name:        "Apply fix without a queue service",
+			given:       "an insight with a retry recommendation and no queue service",
+			when:        "applying the fix",
+			then:        "should update the job and leave it retrying without enqueueing it",
+			expectWrite: true,
+		},
+		{
+			name:        "Apply fix when enqueueing fails",
+			given:       "a queue service that fails to enqueue",
+			when:        "applying the fix",
+			then:        "should still report the fix applied, with the job not enqueued",
+			withQueue:   true,
+			enqueueErr:  errors.New("redis unavailable"),
+			expectWrite: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job := &queue.Job{
+				ID:      uuid.New(),
+				Status:  queue.StatusFailed,
+				Payload: []byte(`{"url":"http://api","timeout":5}`),
+			}
+			insight := &insights.Insight{
+				ID:    uuid.New(),
+				JobID: job.ID,
+				SuggestedFix: insights.SuggestedFix{
+					MaxRetries:   3,
+					PayloadPatch: map[string]any{"timeout": 30},
+				},
+			}
+
+			insightRepo := new(MockInsightRepository)
+			insightRepo.On("GetByID", mock.Anything, insight.ID).Return(insight, nil)
+			jobRepo := new(MockJobRepository)
+			jobRepo.On("GetByID", mock.Anything, job.ID).Return(job, nil)
+			if tt.expectWrite {
+				jobRepo.On("Update", mock.Anything, job).Return(nil)
+				insightRepo.On("RecordFixApplication", mock.Anything, mock.MatchedBy(func(app *insights.FixApplication) bool {
+					return app.InsightID == insight.ID && app.JobID == job.ID && app.StatusAfter == queue.StatusRetrying &&
+						app.JobType == job.Type && app.Outcome == insights.FixOutcomePending
+				})).Return(nil)
+			}
+			jobQueue := new(MockQueueService)
+			jobQueue.On("Enqueue", mock.Anything, mock.MatchedBy(func(queued *queue.Job) bool {
+				return queued.ID == job.ID && queued.Status == queue.StatusRetrying &&
+					string(queued.Payload) == `{"timeout":30,"url":"http://api"}`
+			})).Return(tt.enqueueErr)
+
+			service := NewService(insightRepo, jobRepo, new(MockAIService))
+			if tt.withQueue {
+				service.SetQueueService(jobQueue)
+			}
+
+			// When
+			var plan *insights.FixPlan
+			var applied *AppliedFix
+			var err error
+			if tt.dryRun {
+				plan, err = service.PreviewInsightFix(context.Background(), insight.ID)
+			} else {
+				applied, err = service.ApplyInsightFix(context.Background(), insight.ID)
+				if err == nil {
+					plan = applied.Plan
+				}
+			}
+
+			// Then
+			assert.NoError(t, err)
+			assert.Len(t, plan.Changes, 1)
+			assert.True(t, plan.WillRetry)
+			if tt.expectWrite {
+				assert.Equal(t, queue.StatusRetrying, job.Status)
+				assert.JSONEq(t, `{"url":"http://api","timeout":30}`, string(job.Payload))
+				assert.Same(t, job, applied.Job)
+				assert.Equal(t, tt.expectEnqueued, applied.Enqueued)
+			} else {
+				assert.Equal(t, queue.StatusFailed, job.Status)
+				assert.JSONEq(t, `{"url":"http://api","timeout":5}`, string(job.Payload))
+			}
+			if tt.withQueue && tt.expectWrite {
+				jobQueue.AssertNumberOfCalls(t, "Enqueue", 1)
+			} else {
+				jobQueue.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+			insightRepo.AssertExpectations(t)
+			jobRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_EditInsight(t *testing.T) {
+	recommendation := "Raise the gateway timeout to 60s"
+
+	tests := []struct {
+		name         string
+		given        string
+		when         string
+		then         string
+		edit         insights.InsightEdit
+		getErr       error
+		expectErr    error
+		expectUpdate bool
+	}{
+		{
+			name:         "Edit recommendation",
+			given:        "an insight written by the AI",
+			when:         "an operator replaces its recommendation",
+			then:         "should store the new text and keep the AI's",
+			edit:         insights.InsightEdit{Recommendation: &recommendation, EditedBy: "ana"},
+			expectUpdate: true,
+		},
+		{
+			name:      "Invalid edit",
+			given:     "an insight written by the AI",
+			when:      "an edit without an editor is submitted",
+			then:      "should return ErrInvalidEdit without storing anything",
+			edit:      insights.InsightEdit{Recommendation: &recommendation},
+			expectErr: insights.ErrInvalidEdit,
+		},
+		{
+			name:      "Insight not found",
+			given:     "no insight with the ID",
+			when:      "editing it",
+			then:      "should return ErrInsightNotFound",
+			edit:      insights.InsightEdit{Recommendation: &recommendation, EditedBy: "ana"},
+			getErr:    insights.ErrInsightNotFound,
+			expectErr: insights.ErrInsightNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			insight := &insights.Insight{ID: uuid.New(), JobID: uuid.New(), Recommendation: "Increase timeout"}
+			insightRepo := new(MockInsightRepository)
+			if tt.getErr != nil {
+				insightRepo.On("GetByID", mock.Anything, insight.ID).Return(nil, tt.getErr)
+			} else {
+				insightRepo.On("GetByID", mock.Anything, insight.ID).Return(insight, nil)
+			}
+			insightRepo.On("Update", mock.Anything, insight).Return(nil)
+			service := NewService(insightRepo, new(MockJobRepository), new(MockAIService))
+
+			// When
+			edited, err := service.EditInsight(context.Background(), insight.ID, tt.edit)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, recommendation, edited.Recommendation)
+				assert.Equal(t, "Increase timeout", edited.AIRecommendation)
+				assert.Equal(t, "ana", edited.EditedBy)
+			}
+			if tt.expectUpdate {
+				insightRepo.AssertCalled(t, "Update", mock.Anything, insight)
+			} else {
+				insightRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
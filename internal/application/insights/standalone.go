@@ -0,0 +1,58 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// ErrStandalone is returned for analyses that need the jobs table when the service runs without a database
+var ErrStandalone = errors.New("insights service runs standalone, send the failed job in the request body instead of its ID")
+
+// Standalone reports whether the service runs without the jobs and insights tables, analyzing
+// only the failures described to it
+func (s *Service) Standalone() bool {
+	return s.jobRepo == nil || s.insightRepo == nil
+}
+
+// AnalyzeReportedFailure analyzes a failure the caller describes, with the end of the job's
+// output when it has one, instead of a job read from the jobs table, so the service can run
+// apart from the database. Insights belong to stored jobs, so the insight is returned without
+// being stored or announced. Context the service can load, like the queue's settings, is still
+// added to the prompt.
+func (s *Service) AnalyzeReportedFailure(ctx context.Context, job *queue.Job, output string) (*insights.Insight, error) {
+	slog.InfoContext(ctx, "Starting AI analysis for reported job failure",
+		slog.String("jobId", job.ID.String()),
+		slog.String("type", job.Type),
+	)
+	request := s.redact(ctx, job, &insights.AnalysisRequest{
+		JobID:       job.ID.String(),
+		JobType:     job.Type,
+		Queue:       job.Queue,
+		Error:       job.Error,
+		Payload:     string(job.Payload),
+		Metadata:    job.Metadata,
+		Output:      output,
+		QueueConfig: s.queueConfig(ctx, job),
+		FixHistory:  s.fixHistory(ctx, job.Type),
+	})
+	response, err := s.analyze(ctx, job.ID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	insight, err := insights.NewInsight(job.ID, response)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create insight",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	insight.Triage = insights.Triage(job, insight)
+	insight.Fingerprint = job.ErrorFingerprint()
+	return insight, nil
+}
@@ -0,0 +1,125 @@
+package insights
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// stormSample is the analysis of the one job analyzed for an error signature during a storm
+type stormSample struct {
+	done    chan struct{} // Closed once the analysis finished
+	insight *insights.Insight
+	err     error
+	expires time.Time // Zero while the analysis runs
+}
+
+// stormSampler holds the samples of the signatures seen during failure storms. The first
+// failure of a signature becomes its sample; the others wait for it and share its insight.
+type stormSampler struct {
+	detector *insights.StormDetector
+	now      func() time.Time
+
+	mu      sync.Mutex
+	samples map[string]*stormSample
+}
+
+func newStormSampler(detector *insights.StormDetector) *stormSampler {
+	return &stormSampler{
+		detector: detector,
+		now:      time.Now,
+		samples:  make(map[string]*stormSample),
+	}
+}
+
+// join returns the signature's sample, and whether the caller is to analyze it
+func (s *stormSampler) join(signature string) (*stormSample, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, sample := range s.samples {
+		if !sample.expires.IsZero() && now.After(sample.expires) {
+			delete(s.samples, key)
+		}
+	}
+	if sample, ok := s.samples[signature]; ok {
+		return sample, false
+	}
+	sample := &stormSample{done: make(chan struct{})}
+	s.samples[signature] = sample
+	return sample, true
+}
+
+// finish releases the jobs waiting on the sample. A sample whose analysis failed is dropped,
+// so the next failure of the signature is analyzed again; an insight is shared for a window.
+func (s *stormSampler) finish(signature string, sample *stormSample, insight *insights.Insight, err error) {
+	s.mu.Lock()
+	sample.insight, sample.err = insight, err
+	if err != nil {
+		delete(s.samples, signature)
+	} else {
+		sample.expires = s.now().Add(s.detector.Config().Window)
+	}
+	s.mu.Unlock()
+	close(sample.done)
+}
+
+// SetStormDetector switches failure analyses to sampling while a job type fails in a storm:
+// one job is analyzed per error signature, and the others failing the same way get a copy of
+// its insight linked to it instead of an AI call each
+func (s *Service) SetStormDetector(detector *insights.StormDetector) {
+	s.storms = newStormSampler(detector)
+}
+
+// sampleStorm handles the failure when its job type is in a storm, reporting whether it did
+func (s *Service) sampleStorm(ctx context.Context, job *queue.Job) (*insights.Insight, bool, error) {
+	storming, started := s.storms.detector.Observe(job.Type)
+	if started {
+		slog.WarnContext(ctx, "Failure storm detected, sampling AI analyses by error signature",
+			slog.String("jobType", job.Type),
+			slog.Int("failureThreshold", s.storms.detector.Config().FailureThreshold),
+			slog.Duration("window", s.storms.detector.Config().Window),
+		)
+	}
+	if !storming {
+		return nil, false, nil
+	}
+
+	signature := insights.ErrorSignature(job.Type, job.Error)
+	sample, analyze := s.storms.join(signature)
+	if analyze {
+		slog.InfoContext(ctx, "Analyzing job as the sample of its error signature",
+			slog.String("jobId", job.ID.String()),
+			slog.String("signature", signature),
+		)
+		insight, err := s.analyzeFailure(ctx, job)
+		s.storms.finish(signature, sample, insight, err)
+		return insight, true, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, true, ctx.Err()
+	case <-sample.done:
+	}
+	if sample.err != nil {
+		return nil, true, sample.err
+	}
+
+	insight := sample.insight.ShareWith(job.ID)
+	insight.Triage = insights.Triage(job, insight)
+	slog.InfoContext(ctx, "Sharing the insight of the error signature's sample",
+		slog.String("jobId", job.ID.String()),
+		slog.String("signature", signature),
+		slog.String("sharedFrom", insight.SharedFrom.String()),
+	)
+	if err := s.saveInsight(ctx, insight); err != nil {
+		return nil, true, err
+	}
+	return insight, true, nil
+}
@@ -0,0 +1,189 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// Consistency check limits
+const (
+	DefaultConsistencyLimit = 1000
+	MaxConsistencyLimit     = 10000
+)
+
+// ErrConsistencyCheckDisabled is returned when the queue backend can't be inspected
+var ErrConsistencyCheckDisabled = errors.New("consistency check is not enabled")
+
+// ConsistencyCheck selects what CheckConsistency compares
+type ConsistencyCheck struct {
+	Queue  string // Empty checks every known queue
+	Limit  int    // Ready jobs compared per queue, oldest first
+	Repair bool   // Re-enqueue the stranded jobs found
+}
+
+// StrandedJob is a job ready to run according to the database that the queue backend doesn't hold
+type StrandedJob struct {
+	Job      *queue.Job
+	Repaired bool
+}
+
+// ConsistencyReport is the outcome of a consistency check
+type ConsistencyReport struct {
+	CheckedAt time.Time
+	Queues    []string
+	Checked   int // Ready jobs in the database compared against the backend
+	Stranded  []*StrandedJob
+	Repaired  int
+	// Truncated is set when a queue had more ready jobs than the limit; check again after repairing
+	Truncated bool
+}
+
+// SetQueueInspector enables consistency checks against what the queue backend holds
+func (s *Service) SetQueueInspector(inspector queue.QueueInspector) {
+	s.inspector = inspector
+}
+
+// CheckConsistency cross-checks the jobs the database says are ready to run against the queue
+// backend, reporting the stranded ones it doesn't hold and, when asked to, re-enqueueing them.
+// A job is only reported once it is missing from two snapshots and is still ready when re-read,
+// so jobs moving through the pipeline during the check aren't mistaken for stranded ones.
+func (s *Service) CheckConsistency(ctx context.Context, check ConsistencyCheck) (*ConsistencyReport, error) {
+	if s.inspector == nil {
+		return nil, ErrConsistencyCheckDisabled
+	}
+	if check.Limit <= 0 {
+		check.Limit = DefaultConsistencyLimit
+	}
+	if check.Limit > MaxConsistencyLimit {
+		check.Limit = MaxConsistencyLimit
+	}
+
+	queues := []string{check.Queue}
+	if check.Queue == "" {
+		known, err := s.knownQueues(ctx)
+		if err != nil {
+			return nil, err
+		}
+		queues = known
+	}
+
+	report := &ConsistencyReport{CheckedAt: time.Now().UTC(), Queues: queues, Stranded: []*StrandedJob{}}
+	for _, name := range queues {
+		if err := s.checkQueue(ctx, name, check, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(report.Stranded) > 0 {
+		slog.WarnContext(ctx, "Consistency check found stranded jobs",
+			slog.Int("checked", report.Checked),
+			slog.Int("stranded", len(report.Stranded)),
+			slog.Int("repaired", report.Repaired),
+		)
+	}
+	return report, nil
+}
+
+func (s *Service) checkQueue(ctx context.Context, name string, check ConsistencyCheck, report *ConsistencyReport) error {
+	// The backend is read before the database: a job stored after the snapshot is too recent
+	// to count as stranded, and one delivered after it is no longer ready in the database
+	snapshot, err := s.inspector.Snapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	pending, err := s.jobRepo.FindPendingJobs(ctx, name, check.Limit)
+	if err != nil {
+		return err
+	}
+	report.Checked += len(pending)
+	if len(pending) >= check.Limit {
+		report.Truncated = true
+	}
+
+	candidates := queue.FindStranded(pending, snapshot, queue.DefaultStrandedAfter, time.Now().UTC())
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Confirm against a fresh snapshot and the current state of each job
+	snapshot, err = s.inspector.Snapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, candidate := range candidates {
+		if snapshot.Holds(candidate.ID) {
+			continue
+		}
+		job, err := s.jobRepo.GetByID(ctx, candidate.ID)
+		if errors.Is(err, queue.ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Delayed jobs that are due are the scheduler's to promote
+		if !job.IsReady() || job.IsDelayed() || job.IsDeleted() || !job.UpdatedAt.Equal(candidate.UpdatedAt) {
+			continue
+		}
+
+		stranded := &StrandedJob{Job: job}
+		report.Stranded = append(report.Stranded, stranded)
+		slog.WarnContext(ctx, "Stranded job found",
+			slog.String("jobId", job.ID.String()),
+			slog.String("queue", job.Queue),
+			slog.String("status", string(job.Status)),
+		)
+		if !check.Repair {
+			continue
+		}
+
+		if err := s.queueService.Enqueue(ctx, job); err != nil {
+			slog.ErrorContext(ctx, "Failed to re-enqueue stranded job",
+				slog.String("jobId", job.ID.String()),
+				slog.String("queue", job.Queue),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		stranded.Repaired = true
+		report.Repaired++
+		slog.InfoContext(ctx, "Re-enqueued stranded job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("queue", job.Queue),
+		)
+	}
+	return nil
+}
+
+// knownQueues returns the queues the backend has seen and the defined ones, sorted
+func (s *Service) knownQueues(ctx context.Context) ([]string, error) {
+	names := make(map[string]struct{})
+	deliveryStats, err := s.queueService.DeliveryStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stats := range deliveryStats {
+		names[stats.Queue] = struct{}{}
+	}
+	if s.definitions != nil {
+		defs, err := s.definitions.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, def := range defs {
+			names[def.Name] = struct{}{}
+		}
+	}
+
+	queues := make([]string, 0, len(names))
+	for name := range names {
+		queues = append(queues, name)
+	}
+	sort.Strings(queues)
+	return queues, nil
+}
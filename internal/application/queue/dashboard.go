@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// Dashboard defaults
+const (
+	DashboardWindow   = time.Hour
+	DashboardTopTypes = 5
+)
+
+// Dashboard aggregates the figures the dashboard front-end shows, so it needs a single call
+type Dashboard struct {
+	StatusCounts map[queue.Status]int64
+	DLQ          int64
+	Activity     *queue.Activity // Throughput and failing types over DashboardWindow
+	Workers      []worker.Heartbeat
+	GeneratedAt  time.Time
+}
+
+// SetHeartbeatStore sets the store worker-runtime processes report their heartbeats to
+func (s *Service) SetHeartbeatStore(store worker.HeartbeatStore) {
+	s.heartbeats = store
+}
+
+// ListWorkers returns the worker-runtime processes that are alive
+func (s *Service) ListWorkers(ctx context.Context) ([]worker.Heartbeat, error) {
+	if s.heartbeats == nil {
+		return []worker.Heartbeat{}, nil
+	}
+	return s.heartbeats.List(ctx)
+}
+
+// GetDashboard returns job counts, the DLQ size, the last hour's activity and the live workers.
+// Workers are left out rather than failing the dashboard when their store is unavailable.
+func (s *Service) GetDashboard(ctx context.Context) (*Dashboard, error) {
+	now := time.Now().UTC()
+	dashboard := &Dashboard{
+		StatusCounts: make(map[queue.Status]int64),
+		GeneratedAt:  now,
+	}
+
+	for _, status := range []queue.Status{
+		queue.StatusPending,
+		queue.StatusProcessing,
+		queue.StatusRetrying,
+		queue.StatusCompleted,
+		queue.StatusFailed,
+		queue.StatusCancelled,
+		queue.StatusHeld,
+		queue.StatusParked,
+	} {
+		count, err := s.jobRepo.CountByStatus(ctx, status)
+		if err != nil {
+			return nil, err
+		}
+		dashboard.StatusCounts[status] = count
+	}
+
+	dlq, err := s.jobRepo.CountDLQJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dashboard.DLQ = dlq
+
+	dashboard.Activity, err = s.jobRepo.Activity(ctx, now.Add(-DashboardWindow), DashboardTopTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard.Workers, err = s.ListWorkers(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to list worker heartbeats",
+			slog.String("error", err.Error()),
+		)
+		dashboard.Workers = []worker.Heartbeat{}
+	}
+	return dashboard, nil
+}
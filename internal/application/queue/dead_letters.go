@@ -0,0 +1,187 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// Dead letter limits
+const (
+	DefaultDeadLetterPeek = 20
+	MaxDeadLetterPeek     = 1000
+	MaxDeadLetterReplay   = 1000
+)
+
+// ErrDeadLettersDisabled is returned when the queue backend doesn't keep dead letters
+var ErrDeadLettersDisabled = errors.New("dead letter queue is not enabled")
+
+// Why a dead letter wasn't replayed
+const (
+	ReplaySkippedNotFound  = "not_found"  // The job was purged from the database
+	ReplaySkippedDeleted   = "deleted"    // The job was soft-deleted
+	ReplaySkippedNotFailed = "not_failed" // The job left the failed status, e.g. it was retried meanwhile
+)
+
+// ReplayedJob is the outcome of replaying one dead letter
+type ReplayedJob struct {
+	Job      *queue.Job
+	Replayed bool
+	Skipped  string // Why it wasn't replayed; empty when it was
+}
+
+// ReplayReport is the outcome of replaying dead letters
+type ReplayReport struct {
+	Queue     string
+	Jobs      []*ReplayedJob
+	Replayed  int
+	Skipped   int
+	Remaining int64 // Dead letters left in the queue
+}
+
+// SetDeadLetterQueue enables inspecting and replaying the dead letters the queue backend keeps
+func (s *Service) SetDeadLetterQueue(deadLetters queue.DeadLetterQueue) {
+	s.deadLetters = deadLetters
+}
+
+// PeekDeadLetters returns up to limit of the queue's oldest dead letters, as they were when
+// they failed, and how many the queue holds
+func (s *Service) PeekDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, int64, error) {
+	if s.deadLetters == nil {
+		return nil, 0, ErrDeadLettersDisabled
+	}
+	if limit <= 0 {
+		limit = DefaultDeadLetterPeek
+	}
+	if limit > MaxDeadLetterPeek {
+		limit = MaxDeadLetterPeek
+	}
+
+	jobs, err := s.deadLetters.PeekDeadLetters(ctx, queueName, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.deadLetters.CountDeadLetters(ctx, queueName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, count, nil
+}
+
+// DumpDeadLetters returns every dead letter of the queue, oldest first
+func (s *Service) DumpDeadLetters(ctx context.Context, queueName string) ([]*queue.Job, error) {
+	if s.deadLetters == nil {
+		return nil, ErrDeadLettersDisabled
+	}
+	return s.deadLetters.PeekDeadLetters(ctx, queueName, 0)
+}
+
+// ReplayDeadLetters takes up to count of the queue's oldest dead letters and retries their jobs.
+// Each job is re-read from the database, which has the final say: jobs that are no longer failed
+// there, e.g. retried meanwhile, are dropped from the dead letters without running again.
+// Replaying is an operator's decision, so jobs that used up their attempts run once more.
+func (s *Service) ReplayDeadLetters(ctx context.Context, queueName string, count int) (*ReplayReport, error) {
+	if s.deadLetters == nil {
+		return nil, ErrDeadLettersDisabled
+	}
+	if count > MaxDeadLetterReplay {
+		count = MaxDeadLetterReplay
+	}
+	if err := s.checkNotDraining(ctx, queueName); err != nil {
+		return nil, err
+	}
+
+	letters, err := s.deadLetters.PopDeadLetters(ctx, queueName, count)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReplayReport{Queue: queueName, Jobs: make([]*ReplayedJob, 0, len(letters))}
+	for i, letter := range letters {
+		replayed, err := s.replayDeadLetter(ctx, letter)
+		if err != nil {
+			// Put back what wasn't replayed so nothing is lost
+			if restoreErr := s.deadLetters.RestoreDeadLetters(ctx, queueName, letters[i:]); restoreErr != nil {
+				slog.ErrorContext(ctx, "Failed to restore dead letters",
+					slog.String("queue", queueName),
+					slog.Int("count", len(letters)-i),
+					slog.String("error", restoreErr.Error()),
+				)
+			}
+			return nil, err
+		}
+		report.Jobs = append(report.Jobs, replayed)
+		if replayed.Replayed {
+			report.Replayed++
+		} else {
+			report.Skipped++
+		}
+	}
+
+	report.Remaining, err = s.deadLetters.CountDeadLetters(ctx, queueName)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Replayed dead letters",
+		slog.String("queue", queueName),
+		slog.Int("replayed", report.Replayed),
+		slog.Int("skipped", report.Skipped),
+		slog.Int64("remaining", report.Remaining),
+	)
+	return report, nil
+}
+
+func (s *Service) replayDeadLetter(ctx context.Context, letter *queue.Job) (*ReplayedJob, error) {
+	var job *queue.Job
+	var skipped string
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		job, err = s.jobRepo.GetByIDForUpdate(ctx, letter.ID)
+		if errors.Is(err, queue.ErrJobNotFound) {
+			job, skipped = letter, ReplaySkippedNotFound
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if job.IsDeleted() {
+			skipped = ReplaySkippedDeleted
+			return nil
+		}
+		if job.Status != queue.StatusFailed {
+			skipped = ReplaySkippedNotFailed
+			return nil
+		}
+
+		if err := job.MarkAsRetrying(); err != nil {
+			return err
+		}
+		if err := s.jobRepo.Update(ctx, job); err != nil {
+			if errors.Is(err, queue.ErrInvalidTransition) {
+				skipped = ReplaySkippedNotFailed
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if skipped != "" {
+		return &ReplayedJob{Job: job, Skipped: skipped}, nil
+	}
+
+	if err := s.queueService.Enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.metrics.RecordJobRetried(job.Queue, job.Type)
+	slog.InfoContext(ctx, "Replayed dead letter",
+		slog.String("jobId", job.ID.String()),
+		slog.String("queue", job.Queue),
+	)
+	return &ReplayedJob{Job: job, Replayed: true}, nil
+}
@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/ratelimit"
+)
+
+// ErrDefinitionsDisabled is returned when no queue definition repository is configured
+var ErrDefinitionsDisabled = errors.New("queue definitions are not enabled")
+
+// RateLimitedError is returned by CreateJob when a queue's job creation rate is exceeded
+type RateLimitedError struct {
+	Queue      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("queue %s is rate limited, retry after %s", e.Queue, e.RetryAfter)
+}
+
+// SetQueueDefinitions sets the repository queue definitions are managed in. Jobs created in a
+// defined queue get its default type when they have none, and must match its allowed types,
+// payload validation and rate limit; with enforce, queues without a definition are rejected.
+func (s *Service) SetQueueDefinitions(repo queue.DefinitionRepository, enforce bool) {
+	s.definitions = repo
+	s.enforceDefinitions = enforce
+}
+
+// SetQueueRateLimiter sets the limiter applying the rate limits of queue definitions
+func (s *Service) SetQueueRateLimiter(limiter ratelimit.BucketLimiter) {
+	s.queueLimiter = limiter
+}
+
+// DefineQueue stores a new queue definition
+func (s *Service) DefineQueue(ctx context.Context, def *queue.Definition) error {
+	if s.definitions == nil {
+		return ErrDefinitionsDisabled
+	}
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	def.CreatedAt, def.UpdatedAt = now, now
+	return s.definitions.Create(ctx, def)
+}
+
+// GetQueueDefinition returns the definition of a queue
+func (s *Service) GetQueueDefinition(ctx context.Context, name string) (*queue.Definition, error) {
+	if s.definitions == nil {
+		return nil, ErrDefinitionsDisabled
+	}
+	return s.definitions.Get(ctx, name)
+}
+
+// ListQueueDefinitions returns every queue definition ordered by name
+func (s *Service) ListQueueDefinitions(ctx context.Context) ([]*queue.Definition, error) {
+	if s.definitions == nil {
+		return nil, ErrDefinitionsDisabled
+	}
+	return s.definitions.List(ctx)
+}
+
+// UpdateQueueDefinition replaces the settings of an existing queue definition
+func (s *Service) UpdateQueueDefinition(ctx context.Context, def *queue.Definition) error {
+	if s.definitions == nil {
+		return ErrDefinitionsDisabled
+	}
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	existing, err := s.definitions.Get(ctx, def.Name)
+	if err != nil {
+		return err
+	}
+	def.CreatedAt = existing.CreatedAt
+	def.Draining = existing.Draining // Changed through DrainQueue and ResumeQueue only
+	def.UpdatedAt = time.Now().UTC()
+	return s.definitions.Update(ctx, def)
+}
+
+// DeleteQueueDefinition removes a queue definition; jobs already in the queue are kept
+func (s *Service) DeleteQueueDefinition(ctx context.Context, name string) error {
+	if s.definitions == nil {
+		return ErrDefinitionsDisabled
+	}
+	return s.definitions.Delete(ctx, name)
+}
+
+// withDefaultType returns the command with the default type of its queue's definition when it
+// has no type of its own
+func (s *Service) withDefaultType(ctx context.Context, cmd CreateJobCommand) (CreateJobCommand, error) {
+	if cmd.Type != "" || cmd.Queue == "" || s.definitions == nil {
+		return cmd, nil
+	}
+	def, err := s.definitions.Get(ctx, cmd.Queue)
+	if errors.Is(err, queue.ErrQueueNotDefined) {
+		return cmd, nil // NewJob rejects the missing type, or admitJob the undefined queue
+	}
+	if err != nil {
+		return cmd, err
+	}
+	cmd.Type = def.DefaultType
+	return cmd, nil
+}
+
+// admitJob checks a new job against its queue's definition
+func (s *Service) admitJob(ctx context.Context, job *queue.Job) error {
+	if s.definitions == nil {
+		return nil
+	}
+
+	def, err := s.definitions.Get(ctx, job.Queue)
+	if errors.Is(err, queue.ErrQueueNotDefined) && !s.enforceDefinitions {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if def.Draining {
+		return fmt.Errorf("%w: %s", queue.ErrQueueDraining, def.Name)
+	}
+	if err := def.CheckType(job.Type); err != nil {
+		return err
+	}
+	if err := def.CheckPayload(job.Payload); err != nil {
+		return err
+	}
+
+	if def.RateLimitPerSecond <= 0 || s.queueLimiter == nil {
+		return nil
+	}
+	limit := ratelimit.Limit{
+		RequestsPerSecond: def.RateLimitPerSecond,
+		Burst:             max(1, int(math.Ceil(def.RateLimitPerSecond))),
+	}
+	result, err := s.queueLimiter.AllowLimit(ctx, "queue:"+def.Name, limit)
+	if err != nil {
+		// Fail open like the API rate limiter so Redis hiccups don't stop job creation
+		slog.WarnContext(ctx, "Queue rate limiter unavailable, allowing job",
+			slog.String("queue", def.Name),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	if !result.Allowed {
+		return &RateLimitedError{Queue: def.Name, RetryAfter: result.RetryAfter}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// ErrJobEditsDisabled is returned when the queue backend can't take jobs back out of a queue
+var ErrJobEditsDisabled = errors.New("job edits are not enabled")
+
+// EditJobCommand represents the changes to a job that hasn't started running
+type EditJobCommand struct {
+	Payload any // Replaces the payload when not nil
+	// ScheduledFor reschedules a pending job when not nil; a time that has passed runs it now
+	ScheduledFor *time.Time
+	Version      int // The version of the job the changes were based on
+}
+
+// SetQueueWithdrawer enables editing jobs, which are taken out of their queue while they change
+func (s *Service) SetQueueWithdrawer(withdrawer queue.QueueWithdrawer) {
+	s.withdrawer = withdrawer
+}
+
+// EditJob changes the payload or schedule of a pending or retrying job, refusing the edit when
+// the job changed since the given version. A job waiting in its queue is withdrawn before its
+// row is updated and enqueued again once it is committed, at the back of the queue, so no
+// worker runs the old copy. A job a worker already dequeued can't be edited.
+func (s *Service) EditJob(ctx context.Context, id uuid.UUID, cmd EditJobCommand) (*queue.Job, error) {
+	if s.withdrawer == nil {
+		return nil, ErrJobEditsDisabled
+	}
+	var payload []byte
+	if cmd.Payload != nil {
+		var err error
+		if payload, err = json.Marshal(cmd.Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	var job, original *queue.Job
+	withdrawn := false
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		job, err = s.jobRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if job.IsDeleted() {
+			return queue.ErrJobNotFound
+		}
+		if err := job.CheckEditable(cmd.Version); err != nil {
+			return err
+		}
+		copied := *job
+		original = &copied
+
+		if err := job.Edit(payload, cmd.ScheduledFor, time.Now().UTC()); err != nil {
+			return err
+		}
+		if payload != nil && s.signer != nil && job.Signature != "" {
+			if err := s.signer.Resign(job); err != nil {
+				return err
+			}
+		}
+
+		// Delayed jobs aren't in the queue backend yet. The row lock keeps the job from being
+		// promoted meanwhile, and a rollback puts a withdrawn job back.
+		if !original.IsDelayed() {
+			withdrawn, err = s.withdrawer.Withdraw(ctx, original)
+			if err != nil {
+				return err
+			}
+			if !withdrawn {
+				return fmt.Errorf("%w: a worker has dequeued it", queue.ErrJobNotEditable)
+			}
+		}
+		return s.jobRepo.Update(ctx, job)
+	})
+	if err != nil {
+		if withdrawn {
+			if enqueueErr := s.queueService.Enqueue(ctx, original); enqueueErr != nil {
+				slog.ErrorContext(ctx, "Failed to put back withdrawn job",
+					slog.String("jobId", id.String()),
+					slog.String("error", enqueueErr.Error()),
+				)
+			}
+		}
+		return nil, err
+	}
+
+	if !job.IsDelayed() {
+		if err := s.queueService.Enqueue(ctx, job); err != nil {
+			return nil, err
+		}
+	}
+	slog.InfoContext(ctx, "Edited job",
+		slog.String("jobId", job.ID.String()),
+		slog.Bool("payloadChanged", payload != nil),
+		slog.Bool("delayed", job.IsDelayed()),
+		slog.Int("version", job.Version),
+	)
+	return job, nil
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// Synchronous execution bounds
+const (
+	DefaultExecuteTimeout = 30 * time.Second
+	MaxExecuteTimeout     = 5 * time.Minute
+)
+
+var (
+	// ErrExecuteDisabled is returned when no completion waiter is configured
+	ErrExecuteDisabled       = errors.New("synchronous job execution is not enabled")
+	ErrInvalidExecuteTimeout = errors.New("execution timeout must be between 0 and 300 seconds")
+	ErrExecuteDelayed        = errors.New("a scheduled job can't be executed synchronously")
+)
+
+// SetCompletionWaiter lets ExecuteJob wait for the jobs it creates to finish
+func (s *Service) SetCompletionWaiter(waiter queue.CompletionWaiter) {
+	s.completions = waiter
+}
+
+// ExecuteJob creates a job and waits up to timeout for a worker to finish it; a zero timeout
+// waits DefaultExecuteTimeout. It returns the job as stored once it completed or failed for
+// good, or as it was when the wait ended, with finished false; the job keeps running then.
+func (s *Service) ExecuteJob(ctx context.Context, cmd CreateJobCommand, timeout time.Duration) (job *queue.Job, finished bool, err error) {
+	if s.completions == nil {
+		return nil, false, ErrExecuteDisabled
+	}
+	if timeout < 0 || timeout > MaxExecuteTimeout {
+		return nil, false, ErrInvalidExecuteTimeout
+	}
+	if timeout == 0 {
+		timeout = DefaultExecuteTimeout
+	}
+	if cmd.ScheduledFor != nil && cmd.ScheduledFor.After(time.Now()) {
+		return nil, false, ErrExecuteDelayed
+	}
+
+	created, err := s.CreateJob(ctx, cmd)
+	if err != nil {
+		return nil, false, err
+	}
+	done, release := s.completions.Wait(created.ID)
+	defer release()
+
+	// A worker may have finished the job before the wait began
+	if job, err := s.jobRepo.GetByID(ctx, created.ID); err == nil && job.IsFinished() {
+		return job, true, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+
+	job, err = s.jobRepo.GetByID(ctx, created.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return job, job.IsFinished(), nil
+}
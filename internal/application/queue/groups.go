@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// ErrGroupsDisabled is returned when no job group repository is configured
+var ErrGroupsDisabled = errors.New("job groups are not enabled")
+
+// SetGroupRepository sets the repository job groups are kept in
+func (s *Service) SetGroupRepository(groups queue.GroupRepository) {
+	s.groups = groups
+}
+
+// CreateGroupCommand is a group of jobs submitted together
+type CreateGroupCommand struct {
+	Jobs []CreateJobCommand
+	// OnComplete is created with the group and held back until every job of the group
+	// completed or failed; optional
+	OnComplete *CreateJobCommand
+}
+
+// GroupCreated is a new group and its jobs, in the order they were submitted
+type GroupCreated struct {
+	Group         *queue.Group
+	Jobs          []*queue.Job
+	CompletionJob *queue.Job // nil without OnComplete
+}
+
+// CreateGroup creates the jobs of a group and its completion job. The jobs are created one by
+// one; if one can't be, the group is cut down to the jobs created before it and the error is
+// returned along with them.
+func (s *Service) CreateGroup(ctx context.Context, cmd CreateGroupCommand) (*GroupCreated, error) {
+	if s.groups == nil {
+		return nil, ErrGroupsDisabled
+	}
+	group, err := queue.NewGroup(len(cmd.Jobs))
+	if err != nil {
+		return nil, err
+	}
+	cmd.Jobs = slices.Clone(cmd.Jobs)
+	for i, jobCmd := range cmd.Jobs {
+		if cmd.Jobs[i], err = s.withDefaultType(ctx, jobCmd); err != nil {
+			return nil, fmt.Errorf("job %d: %w", i, err)
+		}
+		if err := validateJob(cmd.Jobs[i]); err != nil {
+			return nil, fmt.Errorf("job %d: %w", i, err)
+		}
+	}
+	if cmd.OnComplete != nil {
+		onComplete, err := s.withDefaultType(ctx, *cmd.OnComplete)
+		if err != nil {
+			return nil, fmt.Errorf("completion job: %w", err)
+		}
+		if err := validateJob(onComplete); err != nil {
+			return nil, fmt.Errorf("completion job: %w", err)
+		}
+		cmd.OnComplete = &onComplete
+	}
+	created := &GroupCreated{Group: group}
+
+	if cmd.OnComplete != nil {
+		onComplete := *cmd.OnComplete
+		onComplete.ScheduledFor = &queue.GroupHoldUntil
+		onComplete.GroupID = nil
+		created.CompletionJob, err = s.CreateJob(ctx, onComplete)
+		if err != nil {
+			return nil, fmt.Errorf("completion job: %w", err)
+		}
+		group.CompletionJobID = &created.CompletionJob.ID
+	}
+	if err := s.groups.Create(ctx, group); err != nil {
+		return nil, err
+	}
+
+	for i, jobCmd := range cmd.Jobs {
+		jobCmd.GroupID = &group.ID
+		job, err := s.CreateJob(ctx, jobCmd)
+		if err != nil {
+			s.truncateGroup(ctx, group, i)
+			return created, fmt.Errorf("job %d of group %s: %w", i, group.ID, err)
+		}
+		created.Jobs = append(created.Jobs, job)
+	}
+
+	slog.InfoContext(ctx, "Job group created",
+		slog.String("groupId", group.ID.String()),
+		slog.Int("jobs", group.Size),
+		slog.Bool("completionJob", group.CompletionJobID != nil),
+	)
+	return created, nil
+}
+
+// validateJob checks what CreateJob rejects about a job itself, so a group with an invalid
+// job is refused before any of its jobs is created
+func validateJob(cmd CreateJobCommand) error {
+	job, err := queue.NewJob(cmd.Queue, cmd.Type, nil)
+	if err != nil {
+		return err
+	}
+	if cmd.CallbackURL != "" {
+		if err := job.SetCallbackURL(cmd.CallbackURL); err != nil {
+			return err
+		}
+	}
+	if err := job.SetMetadata(cmd.Metadata); err != nil {
+		return err
+	}
+	return job.Require(cmd.Requires)
+}
+
+// truncateGroup cuts a group down to the jobs created so far, finishing it right away when they
+// are all done already, so its completion job isn't held forever. A group left without jobs
+// deletes its completion job instead of running it.
+func (s *Service) truncateGroup(ctx context.Context, group *queue.Group, size int) {
+	if size == 0 && group.CompletionJobID != nil {
+		if err := s.DeleteJob(ctx, *group.CompletionJobID); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete completion job of empty group",
+				slog.String("groupId", group.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	if err := s.groups.Resize(ctx, group.ID, size); err != nil {
+		slog.ErrorContext(ctx, "Failed to resize job group",
+			slog.String("groupId", group.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	group.Size = size
+	if _, err := s.groups.Finish(ctx, group.ID, time.Now().UTC()); err != nil {
+		slog.ErrorContext(ctx, "Failed to finish job group",
+			slog.String("groupId", group.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// GetGroup returns a group and the progress of its jobs
+func (s *Service) GetGroup(ctx context.Context, id uuid.UUID) (*queue.Group, queue.GroupProgress, error) {
+	if s.groups == nil {
+		return nil, queue.GroupProgress{}, ErrGroupsDisabled
+	}
+	group, err := s.groups.Get(ctx, id)
+	if err != nil {
+		return nil, queue.GroupProgress{}, err
+	}
+	progress, err := s.groups.Progress(ctx, id)
+	if err != nil {
+		return nil, queue.GroupProgress{}, err
+	}
+
+	// Finishing the group is retried here in case the worker that ran its last job couldn't
+	if group.CompletedAt == nil && progress.Total > 0 && progress.Finished() {
+		now := time.Now().UTC()
+		finished, err := s.groups.Finish(ctx, id, now)
+		if err != nil {
+			return nil, queue.GroupProgress{}, err
+		}
+		if finished {
+			group.CompletedAt = &now
+			s.events.Publish(ctx, events.GroupCompleted{GroupID: id, At: now})
+		}
+	}
+	return group, progress, nil
+}
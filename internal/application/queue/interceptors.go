@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+)
+
+// CreateFunc takes a job built from a create command the rest of the way to its queue. It
+// returns the job the caller gets back, normally the one it was given.
+type CreateFunc func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error)
+
+// CreateInterceptor wraps job creation with producer-side logic, like validation, enrichment,
+// deduplication or payload encryption. It may change the job before calling next, reject it
+// by returning an error without calling next, answer with another job, e.g. a duplicate
+// created before, or act on the outcome of next.
+type CreateInterceptor func(next CreateFunc) CreateFunc
+
+// SetCreateInterceptors sets the interceptors every new job goes through, first to last,
+// before the queue's definition, payload signing and quota steps configured with the other
+// setters. Jobs created for a group or run synchronously go through them too.
+func (s *Service) SetCreateInterceptors(interceptors ...CreateInterceptor) {
+	s.interceptors = interceptors
+}
+
+// createChain composes the configured interceptors and the built-in creation steps around
+// storeJob. Built-in steps run last, so the job is admitted, signed and counted the way
+// the interceptors left it.
+func (s *Service) createChain() CreateFunc {
+	create := s.storeJob
+	builtin := []CreateInterceptor{s.admitStep, s.signStep, s.quotaStep}
+	for i := len(builtin) - 1; i >= 0; i-- {
+		create = builtin[i](create)
+	}
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		create = s.interceptors[i](create)
+	}
+	return create
+}
+
+// admitStep rejects jobs their queue's definition doesn't allow, see admitJob
+func (s *Service) admitStep(next CreateFunc) CreateFunc {
+	return func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+		if err := s.admitJob(ctx, job); err != nil {
+			return nil, err
+		}
+		return next(ctx, cmd, job)
+	}
+}
+
+// signStep signs the job's payload with the caller's secret when a signer is set
+func (s *Service) signStep(next CreateFunc) CreateFunc {
+	if s.signer == nil {
+		return next
+	}
+	return func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+		if err := s.signer.Sign(job, cmd.APIKey); err != nil {
+			return nil, err
+		}
+		return next(ctx, cmd, job)
+	}
+}
+
+// quotaStep counts the job against the quota of its owner, and stops counting it when it
+// couldn't be stored or enqueued
+func (s *Service) quotaStep(next CreateFunc) CreateFunc {
+	if s.quotas == nil {
+		return next
+	}
+	return func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+		if err := s.quotas.ReserveJob(ctx, quota.Tenant(job.CreatedBy), job.ID); err != nil {
+			return nil, err
+		}
+		created, err := next(ctx, cmd, job)
+		if err != nil {
+			s.releaseQuota(ctx, job.ID)
+		}
+		return created, err
+	}
+}
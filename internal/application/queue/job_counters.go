@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// ErrJobCountersDisabled is returned when no metrics reader was set
+var ErrJobCountersDisabled = errors.New("job counters are not enabled")
+
+// SetMetricsReader enables the job counters, and the failure breakdown of GetMetrics, read
+// back from the metrics the services record
+func (s *Service) SetMetricsReader(reader queue.MetricsReader) {
+	s.metricsReader = reader
+}
+
+// JobCounters returns the lifecycle counters of every queue and job type, sorted by event,
+// queue, type and failure category
+func (s *Service) JobCounters(ctx context.Context) ([]*queue.JobCounter, error) {
+	if s.metricsReader == nil {
+		return nil, ErrJobCountersDisabled
+	}
+	counters, err := s.metricsReader.JobCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(counters, func(a, b *queue.JobCounter) int {
+		return cmp.Or(
+			strings.Compare(a.Event, b.Event),
+			strings.Compare(a.Queue, b.Queue),
+			strings.Compare(a.Type, b.Type),
+			strings.Compare(a.Category, b.Category),
+		)
+	})
+	return counters, nil
+}
+
+// failureBreakdown sums the failed counters per category, overall and per queue and job type
+func failureBreakdown(counters []*queue.JobCounter) map[string]any {
+	var total int64
+	byCategory := make(map[string]int64)
+	byQueue := make(map[string]map[string]map[string]int64)
+	for _, counter := range counters {
+		if counter.Event != queue.CounterFailed {
+			continue
+		}
+		total += counter.Count
+		byCategory[counter.Category] += counter.Count
+		types, ok := byQueue[counter.Queue]
+		if !ok {
+			types = make(map[string]map[string]int64)
+			byQueue[counter.Queue] = types
+		}
+		categories, ok := types[counter.Type]
+		if !ok {
+			categories = make(map[string]int64)
+			types[counter.Type] = categories
+		}
+		categories[counter.Category] += counter.Count
+	}
+	return map[string]any{
+		"total":       total,
+		"by_category": byCategory,
+		"by_queue":    byQueue,
+	}
+}
+
+// latencyBreakdown sums up the execution durations per queue and job type, in seconds
+func latencyBreakdown(histograms []*queue.DurationHistogram) map[string]map[string]map[string]any {
+	byQueue := make(map[string]map[string]map[string]any)
+	for _, histogram := range histograms {
+		count := histogram.Count()
+		if count == 0 {
+			continue
+		}
+		types, ok := byQueue[histogram.Queue]
+		if !ok {
+			types = make(map[string]map[string]any)
+			byQueue[histogram.Queue] = types
+		}
+		types[histogram.Type] = map[string]any{
+			"count":        count,
+			"mean_seconds": histogram.Mean(),
+			"p50_seconds":  histogram.Percentile(0.50),
+			"p95_seconds":  histogram.Percentile(0.95),
+			"p99_seconds":  histogram.Percentile(0.99),
+		}
+	}
+	return byQueue
+}
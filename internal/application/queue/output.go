@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// MaxOutputRead caps the bytes of output returned at once; longer outputs are read in ranges
+const MaxOutputRead = 4 * 1024 * 1024
+
+// ErrOutputDisabled is returned when job output isn't stored
+var ErrOutputDisabled = errors.New("job output streaming is not enabled")
+
+// SetOutputStore enables reading the output jobs stream while they run
+func (s *Service) SetOutputStore(outputs queue.OutputStore) {
+	s.outputs = outputs
+}
+
+// ReadJobOutput returns the range of the output the job streamed so far, or all of it when rng
+// is nil, up to MaxOutputRead bytes. A range past the end fails with queue.ErrRangeNotSatisfiable
+// along with the output's job and size, without data.
+func (s *Service) ReadJobOutput(ctx context.Context, id uuid.UUID, rng *queue.OutputRange) (*queue.JobOutput, error) {
+	if s.outputs == nil {
+		return nil, ErrOutputDisabled
+	}
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	size, err := s.outputs.OutputSize(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rng == nil {
+		rng = &queue.OutputRange{End: -1}
+	}
+	start, length, err := rng.Resolve(size)
+	if err != nil {
+		return &queue.JobOutput{Job: job, Size: size}, err
+	}
+	data, err := s.outputs.ReadOutput(ctx, id, start, min(length, MaxOutputRead))
+	if err != nil {
+		return nil, err
+	}
+	return &queue.JobOutput{Job: job, Data: data, Start: start, Size: size}, nil
+}
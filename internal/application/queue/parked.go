@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// MaxParkedRelease is how many parked jobs one release retries at most
+const MaxParkedRelease = 1000
+
+// ErrParkedJobsDisabled is returned when no repository finds parked jobs
+var ErrParkedJobsDisabled = errors.New("parked job release is not enabled")
+
+// ParkedRelease is the outcome of releasing a queue's parked jobs
+type ParkedRelease struct {
+	Queue    string
+	Released int
+}
+
+// SetParkedJobs enables releasing the jobs workers parked while their queue's retry budget
+// was exhausted
+func (s *Service) SetParkedJobs(repo queue.ParkedJobRepository) {
+	s.parked = repo
+}
+
+// ReleaseParkedJobs retries up to count of the queue's oldest parked jobs, MaxParkedRelease when
+// count is zero or more than that, e.g. once the downstream they failed on recovered. The jobs
+// keep the attempts they used. Jobs of a draining queue aren't released.
+func (s *Service) ReleaseParkedJobs(ctx context.Context, queueName string, count int) (*ParkedRelease, error) {
+	if s.parked == nil {
+		return nil, ErrParkedJobsDisabled
+	}
+	if count <= 0 || count > MaxParkedRelease {
+		count = MaxParkedRelease
+	}
+	if err := s.checkNotDraining(ctx, queueName); err != nil {
+		return nil, err
+	}
+
+	var released []*queue.Job
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		jobs, err := s.parked.FindParked(ctx, queueName, count)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for _, job := range jobs {
+			if err := job.Unpark(now); err != nil {
+				return err
+			}
+			if err := s.jobRepo.Update(ctx, job); err != nil {
+				if errors.Is(err, queue.ErrInvalidTransition) {
+					// Released by a concurrent call first
+					continue
+				}
+				return err
+			}
+			released = append(released, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range released {
+		if err := s.queueService.Enqueue(ctx, job); err != nil {
+			return nil, err
+		}
+		s.metrics.RecordJobRetried(job.Queue, job.Type)
+	}
+	slog.InfoContext(ctx, "Released parked jobs",
+		slog.String("queue", queueName),
+		slog.Int("released", len(released)),
+	)
+	return &ParkedRelease{Queue: queueName, Released: len(released)}, nil
+}
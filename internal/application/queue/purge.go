@@ -0,0 +1,186 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// PurgeBatch is how many jobs one step of a purge cancels at once
+const PurgeBatch = 500
+
+// ErrPurgeDisabled is returned when the queue backend can't purge queues
+var ErrPurgeDisabled = errors.New("queue purge is not enabled")
+
+// PurgeReport is the outcome of purging a queue
+type PurgeReport struct {
+	Queue   string
+	Status  queue.Status
+	DryRun  bool
+	Matched int64 // Jobs of the status when the purge started
+	Purged  int64 // Jobs removed from the queue and cancelled; zero for a dry run
+	Skipped int64 // Jobs a worker dequeued before they could be removed
+}
+
+// SetQueuePurger enables purging queues: the purger removes jobs from the queue backend and
+// the repository cancels them
+func (s *Service) SetQueuePurger(purger queue.QueuePurger, repo queue.PurgeRepository) {
+	s.purger = purger
+	s.purges = repo
+}
+
+// PurgeQueue removes the queue's jobs of the status, pending when empty, from the queue backend
+// and cancels them, e.g. when a bad producer flooded the queue. Jobs are purged in batches of
+// PurgeBatch: each batch is removed from the backend at once and cancelled in one transaction,
+// and put back in the queue if cancelling it fails. A dry run only counts the jobs.
+func (s *Service) PurgeQueue(ctx context.Context, name string, status queue.Status, dryRun bool) (*PurgeReport, error) {
+	if s.purger == nil || s.purges == nil {
+		return nil, ErrPurgeDisabled
+	}
+	status, err := queue.PurgeableStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PurgeReport{Queue: name, Status: status, DryRun: dryRun}
+	report.Matched, err = s.purges.CountPurgeable(ctx, name, status)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun || report.Matched == 0 {
+		return report, nil
+	}
+	purge, err := s.purger.BeginPurge(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Jobs a worker dequeued stay pending until it marks them processing, so they are found
+	// again by the next batch; they're only counted once
+	skipped := make(map[uuid.UUID]struct{})
+	groups := make(map[uuid.UUID]struct{})
+	for {
+		cancelled, batchSkipped, found, err := s.purgeBatch(ctx, purge, name, status)
+		if err != nil {
+			return nil, err
+		}
+		report.Purged += int64(len(cancelled))
+		for _, id := range batchSkipped {
+			skipped[id] = struct{}{}
+		}
+		for _, job := range cancelled {
+			if job.GroupID != nil {
+				groups[*job.GroupID] = struct{}{}
+			}
+			s.releaseQuota(ctx, job.ID)
+		}
+		if found < PurgeBatch || len(cancelled) == 0 {
+			break
+		}
+	}
+	report.Skipped = int64(len(skipped))
+	s.finishGroups(ctx, groups)
+
+	slog.WarnContext(ctx, "Purged queue",
+		slog.String("queue", name),
+		slog.String("status", string(status)),
+		slog.Int64("purged", report.Purged),
+		slog.Int64("skipped", report.Skipped),
+	)
+	return report, nil
+}
+
+// purgeBatch cancels the next batch of the queue's jobs of the status. It returns the jobs
+// cancelled, those a worker dequeued first and how many jobs the batch found.
+func (s *Service) purgeBatch(ctx context.Context, purge queue.QueuePurge, name string, status queue.Status) ([]*queue.Job, []uuid.UUID, int, error) {
+	var cancelled, removed []*queue.Job
+	var skipped []uuid.UUID
+	found := 0
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		jobs, err := s.purges.FindPurgeable(ctx, name, status, PurgeBatch)
+		if err != nil {
+			return err
+		}
+		found = len(jobs)
+
+		// Delayed jobs aren't in the queue backend yet; the row lock keeps them from being promoted
+		var queued []*queue.Job
+		for _, job := range jobs {
+			if job.IsDelayed() {
+				cancelled = append(cancelled, job)
+			} else {
+				queued = append(queued, job)
+			}
+		}
+		if len(queued) > 0 {
+			ids, err := purge.Purge(ctx, queued)
+			if err != nil {
+				return err
+			}
+			purged := make(map[uuid.UUID]bool, len(ids))
+			for _, id := range ids {
+				purged[id] = true
+			}
+			for _, job := range queued {
+				if purged[job.ID] {
+					removed = append(removed, job)
+				} else {
+					skipped = append(skipped, job.ID)
+				}
+			}
+			cancelled = append(cancelled, removed...)
+		}
+
+		now := time.Now().UTC()
+		reason := fmt.Sprintf("purged from queue %s", name)
+		for _, job := range cancelled {
+			if err := job.MarkAsCancelled(reason); err != nil {
+				return err
+			}
+			job.UpdatedAt = now
+		}
+		_, err = s.purges.CancelJobs(ctx, cancelled)
+		return err
+	})
+	if err != nil {
+		// The rollback keeps the jobs pending, so those removed go back in the queue
+		for _, job := range removed {
+			job.Status, job.Error = queue.StatusPending, ""
+			if enqueueErr := s.queueService.Enqueue(ctx, job); enqueueErr != nil {
+				slog.ErrorContext(ctx, "Failed to put back purged job",
+					slog.String("jobId", job.ID.String()),
+					slog.String("error", enqueueErr.Error()),
+				)
+			}
+		}
+		return nil, nil, 0, err
+	}
+	return cancelled, skipped, found, nil
+}
+
+// finishGroups finishes the groups of cancelled jobs whose last jobs were cancelled
+func (s *Service) finishGroups(ctx context.Context, groups map[uuid.UUID]struct{}) {
+	if s.groups == nil {
+		return
+	}
+	now := time.Now().UTC()
+	for groupID := range groups {
+		finished, err := s.groups.Finish(ctx, groupID, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to finish job group",
+				slog.String("groupId", groupID.String()),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if finished {
+			s.events.Publish(ctx, events.GroupCompleted{GroupID: groupID, At: now})
+		}
+	}
+}
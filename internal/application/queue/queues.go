@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// ErrQueueCountsDisabled is returned when no queue counter is configured
+var ErrQueueCountsDisabled = errors.New("queue job counts are not enabled")
+
+// QueueSummary is a queue known from its definition or its jobs, with its job counts
+type QueueSummary struct {
+	Name       string
+	Definition *queue.Definition // Nil when the queue has jobs but no definition
+	Counts     *queue.QueueStats // Zero counts when the queue is defined but has no jobs
+}
+
+// SetQueueCounter sets the counter queue listings read their job counts from
+func (s *Service) SetQueueCounter(counter queue.QueueCounter) {
+	s.counter = counter
+}
+
+// ListQueues returns every queue that is defined or has jobs, ordered by name, with its job
+// counts and its definition when it has one
+func (s *Service) ListQueues(ctx context.Context) ([]*QueueSummary, error) {
+	if s.counter == nil {
+		return nil, ErrQueueCountsDisabled
+	}
+
+	counts, err := s.counter.CountByQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make(map[string]*QueueSummary, len(counts))
+	for _, c := range counts {
+		summaries[c.Queue] = &QueueSummary{Name: c.Queue, Counts: c}
+	}
+
+	if s.definitions != nil {
+		defs, err := s.definitions.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, def := range defs {
+			summary, ok := summaries[def.Name]
+			if !ok {
+				summary = &QueueSummary{Name: def.Name, Counts: &queue.QueueStats{Queue: def.Name}}
+				summaries[def.Name] = summary
+			}
+			summary.Definition = def
+		}
+	}
+
+	queues := make([]*QueueSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		queues = append(queues, summary)
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+	return queues, nil
+}
+
+// ListQueueJobs returns a page of a queue's jobs, or of the jobs a caller created, newest
+// first, plus the total count
+func (s *Service) ListQueueJobs(ctx context.Context, listing queue.QueueListing) ([]*queue.Job, int64, error) {
+	return s.jobRepo.ListByQueue(ctx, listing)
+}
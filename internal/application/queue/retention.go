@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// Retention janitor defaults
+const (
+	DefaultRetentionInterval = time.Hour
+	RetentionBatch           = 1000 // Jobs cleared per query; a full batch is followed by another
+)
+
+// SetPayloadRetentionRepository lets the retention janitor clear the payload and result of jobs
+// that completed longer ago than their queue's retention
+func (s *Service) SetPayloadRetentionRepository(repo queue.PayloadRetentionRepository) {
+	s.payloadRetention = repo
+}
+
+// EnforceRetention clears the payload and result of the completed jobs the policy and the queue
+// definitions let expire, and purges the jobs soft-deleted longer than the policy keeps them.
+// It returns how many jobs it cleared and purged.
+func (s *Service) EnforceRetention(ctx context.Context, policy queue.RetentionPolicy) (cleared, purged int64, err error) {
+	now := time.Now().UTC()
+	if s.payloadRetention != nil {
+		var defs []*queue.Definition
+		if s.definitions != nil {
+			if defs, err = s.definitions.List(ctx); err != nil {
+				return 0, 0, err
+			}
+		}
+		for _, expiry := range policy.PayloadExpiries(defs, now, RetentionBatch) {
+			n, err := s.clearPayloads(ctx, expiry)
+			cleared += n
+			if err != nil {
+				return cleared, 0, err
+			}
+		}
+	}
+
+	if policy.DeletedRetention > 0 {
+		purged, err = s.jobRepo.PurgeDeleted(ctx, now.Add(-policy.DeletedRetention))
+		if err != nil {
+			return cleared, 0, err
+		}
+	}
+	return cleared, purged, nil
+}
+
+// clearPayloads clears the jobs of one expiry batch after batch
+func (s *Service) clearPayloads(ctx context.Context, expiry queue.PayloadExpiry) (int64, error) {
+	var cleared int64
+	for {
+		n, err := s.payloadRetention.ClearPayloads(ctx, expiry)
+		cleared += n
+		if err != nil || n == 0 || n < int64(expiry.Limit) {
+			return cleared, err
+		}
+	}
+}
+
+// RunRetentionJanitor enforces the retention policy every interval until the context is
+// cancelled. Every instance may run it; a job cleared or purged by one is skipped by the others.
+func (s *Service) RunRetentionJanitor(ctx context.Context, policy queue.RetentionPolicy, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRetentionInterval
+	}
+	slog.InfoContext(ctx, "Retention janitor started",
+		slog.Duration("interval", interval),
+		slog.Duration("payloadRetention", policy.PayloadRetention),
+		slog.Duration("deletedRetention", policy.DeletedRetention),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cleared, purged, err := s.EnforceRetention(ctx, policy)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "Failed to enforce job retention",
+				slog.String("error", err.Error()),
+			)
+		}
+		if cleared > 0 || purged > 0 {
+			slog.InfoContext(ctx, "Enforced job retention",
+				slog.Int64("payloadsCleared", cleared),
+				slog.Int64("jobsPurged", purged),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Retention janitor shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
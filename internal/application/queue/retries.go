@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// MaxRetryHistory bounds the recorded attempts a job's retry history is read from
+const MaxRetryHistory = 100
+
+// SetAttemptHistory enables the retry history of jobs, read from the attempts workers record
+func (s *Service) SetAttemptHistory(attempts queue.AttemptRepository) {
+	s.attempts = attempts
+}
+
+// RetryHistory returns the failed attempts of the job, oldest first, with the backoff each was
+// retried after; it is empty without an attempt history
+func (s *Service) RetryHistory(ctx context.Context, jobID uuid.UUID) ([]*queue.Attempt, error) {
+	if s.attempts == nil {
+		return nil, nil
+	}
+	attempts, err := s.attempts.ListAttempts(ctx, jobID, MaxRetryHistory)
+	if err != nil {
+		return nil, err
+	}
+	failed := make([]*queue.Attempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		if !attempt.Succeeded() {
+			failed = append(failed, attempt)
+		}
+	}
+	return failed, nil
+}
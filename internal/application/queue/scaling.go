@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// DefaultScalingWindow is how far back arrival and completion rates are measured
+const DefaultScalingWindow = 15 * time.Minute
+
+// SetScalingPolicy sets the policy worker replica recommendations follow
+func (s *Service) SetScalingPolicy(policy queue.ScalingPolicy) {
+	s.scaling = policy
+}
+
+// RecommendScaling returns the desired worker replicas of a queue, or of every queue with jobs
+// when the name is empty. Rates are measured over the stats history of the window.
+func (s *Service) RecommendScaling(ctx context.Context, queueName string, window time.Duration) ([]*queue.ScalingRecommendation, error) {
+	if s.stats == nil {
+		return nil, ErrStatsDisabled
+	}
+	if window <= 0 {
+		window = DefaultScalingWindow
+	}
+
+	queueNames := []string{queueName}
+	if queueName == "" {
+		counts, err := s.stats.CountByQueue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		queueNames = make([]string, len(counts))
+		for i, count := range counts {
+			queueNames[i] = count.Queue
+		}
+	}
+
+	since := time.Now().UTC().Add(-window)
+	recommendations := make([]*queue.ScalingRecommendation, 0, len(queueNames))
+	for _, name := range queueNames {
+		history, err := s.stats.History(ctx, name, since)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, queue.RecommendScaling(name, history, s.scaling))
+	}
+	return recommendations, nil
+}
@@ -0,0 +1,154 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// Delayed job scheduler defaults
+const (
+	DefaultSchedulerInterval = time.Second
+	SchedulerBatch           = 500 // Due jobs promoted per query; a full batch is followed by another
+	SchedulerLease           = "scheduler"
+)
+
+// schedulerLeaseTTL lets another instance take over within a few intervals of the leader
+// dying, and never sooner than five seconds so a slow tick doesn't lose the lease
+func schedulerLeaseTTL(interval time.Duration) time.Duration {
+	return max(3*interval, 5*time.Second)
+}
+
+// PromoteDueJobs hands the delayed jobs whose time has come to the queue backend, returning
+// how many it promoted. Each job is re-read and its schedule cleared before it is enqueued,
+// so a job deleted or already promoted meanwhile is skipped.
+func (s *Service) PromoteDueJobs(ctx context.Context) (int, error) {
+	promoted := 0
+	for {
+		now := time.Now().UTC()
+		due, err := s.jobRepo.FindDueDelayedJobs(ctx, now, SchedulerBatch)
+		if err != nil {
+			return promoted, err
+		}
+		batchPromoted := 0
+		for _, candidate := range due {
+			ok, err := s.promoteJob(ctx, candidate, now)
+			if err != nil {
+				return promoted, err
+			}
+			if ok {
+				batchPromoted++
+			}
+		}
+		promoted += batchPromoted
+		if len(due) < SchedulerBatch || batchPromoted == 0 {
+			return promoted, nil
+		}
+	}
+}
+
+func (s *Service) promoteJob(ctx context.Context, candidate *queue.Job, now time.Time) (bool, error) {
+	var job *queue.Job
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		job, err = s.jobRepo.GetByIDForUpdate(ctx, candidate.ID)
+		if err != nil {
+			return err
+		}
+		if job.IsDeleted() {
+			return queue.ErrJobNotDue
+		}
+		if err := job.Promote(now); err != nil {
+			return err
+		}
+		return s.jobRepo.Update(ctx, job)
+	})
+	if errors.Is(err, queue.ErrJobNotFound) || errors.Is(err, queue.ErrJobNotDue) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Once the schedule is cleared the job is never promoted again, so a failed enqueue leaves
+	// it stranded until the consistency check repairs it
+	if err := s.queueService.Enqueue(ctx, job); err != nil {
+		slog.ErrorContext(ctx, "Failed to enqueue promoted job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("queue", job.Queue),
+			slog.String("error", err.Error()),
+		)
+		return false, nil
+	}
+	slog.DebugContext(ctx, "Promoted delayed job",
+		slog.String("jobId", job.ID.String()),
+		slog.String("queue", job.Queue),
+		slog.Time("scheduledFor", *candidate.ScheduledFor),
+	)
+	return true, nil
+}
+
+// RunScheduler promotes due delayed jobs every interval until the context is cancelled. Every
+// queue-core instance runs it, but only the one holding the scheduler lease promotes jobs.
+func (s *Service) RunScheduler(ctx context.Context, elector queue.LeaderElector, holder string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+	ttl := schedulerLeaseTTL(interval)
+	slog.InfoContext(ctx, "Delayed job scheduler started",
+		slog.String("holder", holder),
+		slog.Duration("interval", interval),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	leading := false
+	for {
+		leads, err := elector.Campaign(ctx, SchedulerLease, holder, ttl)
+		if err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "Failed to campaign for the scheduler lease",
+				slog.String("error", err.Error()),
+			)
+		}
+		if leads != leading {
+			leading = leads
+			slog.InfoContext(ctx, "Delayed job scheduler leadership changed",
+				slog.String("holder", holder),
+				slog.Bool("leading", leading),
+			)
+		}
+
+		if leading {
+			promoted, err := s.PromoteDueJobs(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to promote delayed jobs",
+					slog.String("error", err.Error()),
+				)
+			}
+			if promoted > 0 {
+				slog.InfoContext(ctx, "Promoted delayed jobs",
+					slog.Int("count", promoted),
+				)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if leading {
+				// Let another instance take over without waiting for the lease to lapse
+				if err := elector.Resign(context.WithoutCancel(ctx), SchedulerLease, holder); err != nil {
+					slog.WarnContext(ctx, "Failed to resign the scheduler lease",
+						slog.String("error", err.Error()),
+					)
+				}
+			}
+			slog.InfoContext(ctx, "Delayed job scheduler shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
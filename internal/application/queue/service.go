@@ -3,16 +3,50 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"log/slog"
+	"time"
 
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/ratelimit"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
 	"github.com/google/uuid"
 )
 
 // Service orchestrates queue-related use cases
 type Service struct {
-	jobRepo      queue.JobRepository
-	queueService queue.QueueService
-	metrics      queue.MetricsService
+	jobRepo       queue.JobRepository
+	unitOfWork    queue.UnitOfWork
+	queueService  queue.QueueService
+	metrics       queue.MetricsService
+	metricsReader queue.MetricsReader
+	events        events.Publisher
+	breakers      worker.BreakerStore
+	heartbeats    worker.HeartbeatStore
+	stats         queue.QueueStatsRepository
+	counter       queue.QueueCounter
+	signer        *queue.PayloadSigner
+	owners        queue.Owners
+	scaling       queue.ScalingPolicy
+	quotas        quota.Enforcer
+	inspector     queue.QueueInspector
+	withdrawer    queue.QueueWithdrawer
+	purger        queue.QueuePurger
+	purges        queue.PurgeRepository
+	parked        queue.ParkedJobRepository
+	deadLetters   queue.DeadLetterQueue
+	groups        queue.GroupRepository
+	completions   queue.CompletionWaiter
+	outputs       queue.OutputStore
+	attempts      queue.AttemptRepository
+
+	payloadRetention queue.PayloadRetentionRepository
+	interceptors     []CreateInterceptor
+
+	definitions        queue.DefinitionRepository
+	enforceDefinitions bool
+	queueLimiter       ratelimit.BucketLimiter
 }
 
 // NewService creates a new queue application service
@@ -23,20 +57,93 @@ func NewService(
 ) *Service {
 	return &Service{
 		jobRepo:      jobRepo,
+		unitOfWork:   queue.NopUnitOfWork{},
 		queueService: queueService,
 		metrics:      metrics,
+		events:       events.NopPublisher{},
+	}
+}
+
+// SetUnitOfWork makes multi-step job writes transactional, e.g. reading a job and writing its
+// new status with no concurrent update in between
+func (s *Service) SetUnitOfWork(unitOfWork queue.UnitOfWork) {
+	s.unitOfWork = unitOfWork
+}
+
+// SetEventPublisher sets the publisher used to emit job lifecycle events
+func (s *Service) SetEventPublisher(publisher events.Publisher) {
+	s.events = publisher
+}
+
+// SetPayloadSigner makes new jobs carry an HMAC signature of their payload
+func (s *Service) SetPayloadSigner(signer *queue.PayloadSigner) {
+	s.signer = signer
+}
+
+// SetJobOwners names the callers new jobs are attributed to by API key. Without it, jobs
+// created with an API key are attributed to the key's hashed ID.
+func (s *Service) SetJobOwners(owners queue.Owners) {
+	s.owners = owners
+}
+
+// OwnerOf returns the owner the jobs created with the API key are attributed to
+func (s *Service) OwnerOf(apiKey string) string {
+	return s.owners.Of(apiKey)
+}
+
+// SetQuotaEnforcer meters the jobs created by each API key against the quota of its owner
+func (s *Service) SetQuotaEnforcer(enforcer quota.Enforcer) {
+	s.quotas = enforcer
+}
+
+// SetBreakerStore sets the store the worker runtime reports circuit breaker state to
+func (s *Service) SetBreakerStore(store worker.BreakerStore) {
+	s.breakers = store
+}
+
+// ListBreakers returns the circuit breaker state of every job type seen by the workers.
+// Breakers whose cooldown has passed are reported closed even if no worker has resumed the type yet.
+func (s *Service) ListBreakers(ctx context.Context) ([]worker.BreakerStatus, error) {
+	if s.breakers == nil {
+		return []worker.BreakerStatus{}, nil
+	}
+	statuses, err := s.breakers.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range statuses {
+		statuses[i] = statuses[i].At(now)
 	}
+	return statuses, nil
 }
 
 // CreateJobCommand represents the data needed to create a job
 type CreateJobCommand struct {
-	Queue   string
-	Type    string
-	Payload any
+	Queue       string
+	Type        string
+	Payload     any
+	CallbackURL string   // Optional URL notified with the final job state
+	Requires    []string // Capabilities a worker needs to run the job, e.g. gpu or region=eu
+	APIKey      string   // Identifies the caller; selects its payload signing secret and quota, and is recorded as created_by
+	// ScheduledFor delays the job until the given time; a time that has passed runs it now
+	ScheduledFor *time.Time
+	GroupID      *uuid.UUID // Group the job belongs to; set by CreateGroup
+	// SchemaVersion is the version of the payload's schema; zero is queue.DefaultSchemaVersion
+	SchemaVersion int
+	// Metadata is carried with the job apart from its payload, e.g. correlation IDs or trace context
+	Metadata map[string]string
 }
 
-// CreateJob creates a new job and enqueues it
+// CreateJob creates a new job and enqueues it, or keeps it in the database until the
+// scheduler promotes it when it is delayed. The job goes through the create interceptors
+// and built-in creation steps on its way, see SetCreateInterceptors.
 func (s *Service) CreateJob(ctx context.Context, cmd CreateJobCommand) (*queue.Job, error) {
+	cmd, err := s.withDefaultType(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert payload to JSON
 	payloadBytes, err := json.Marshal(cmd.Payload)
 	if err != nil {
@@ -48,20 +155,56 @@ func (s *Service) CreateJob(ctx context.Context, cmd CreateJobCommand) (*queue.J
 	if err != nil {
 		return nil, err
 	}
+	if cmd.CallbackURL != "" {
+		if err := job.SetCallbackURL(cmd.CallbackURL); err != nil {
+			return nil, err
+		}
+	}
+	if err := job.Require(cmd.Requires); err != nil {
+		return nil, err
+	}
+	if cmd.SchemaVersion != 0 {
+		if err := job.SetSchemaVersion(cmd.SchemaVersion); err != nil {
+			return nil, err
+		}
+	}
+	if err := job.SetMetadata(cmd.Metadata); err != nil {
+		return nil, err
+	}
+	job.GroupID = cmd.GroupID
+	job.CreatedBy = s.owners.Of(cmd.APIKey)
+	if cmd.ScheduledFor != nil && cmd.ScheduledFor.After(time.Now()) {
+		job.Schedule(cmd.ScheduledFor.UTC())
+	}
 
-	// Persist the job
+	return s.createChain()(ctx, cmd, job)
+}
+
+// storeJob persists a new job and enqueues it, the last step of job creation
+func (s *Service) storeJob(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+	// Persist the job. It is enqueued only once its row is committed, never from inside a
+	// unit of work, so a worker can't pop a job whose row it can't see yet.
 	if err := s.jobRepo.Create(ctx, job); err != nil {
 		return nil, err
 	}
 
-	// Enqueue for processing
-	if err := s.queueService.Enqueue(ctx, job); err != nil {
-		return nil, err
+	// Enqueue for processing; delayed jobs wait for the scheduler
+	if !job.IsDelayed() {
+		if err := s.queueService.Enqueue(ctx, job); err != nil {
+			return nil, err
+		}
 	}
 
 	// Record metrics
 	s.metrics.RecordJobCreated(job.Queue, job.Type)
 
+	s.events.Publish(ctx, events.JobCreated{
+		JobID: job.ID,
+		Queue: job.Queue,
+		Type:  job.Type,
+		At:    time.Now().UTC(),
+	})
+
 	return job, nil
 }
 
@@ -75,41 +218,77 @@ func (s *Service) GetJobsByStatus(ctx context.Context, status queue.Status, limi
 	return s.jobRepo.FindByStatus(ctx, status, limit)
 }
 
+// SearchJobs performs a full-text search over job errors and payloads
+func (s *Service) SearchJobs(ctx context.Context, criteria queue.SearchCriteria) ([]*queue.Job, int64, error) {
+	return s.jobRepo.Search(ctx, criteria)
+}
+
 // UpdateJobStatus updates the status of a job
 func (s *Service) UpdateJobStatus(ctx context.Context, jobID uuid.UUID, status queue.Status) error {
-	job, err := s.jobRepo.GetByID(ctx, jobID)
+	var job *queue.Job
+	var duration time.Duration
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		job, err = s.jobRepo.GetByIDForUpdate(ctx, jobID)
+		if err != nil {
+			return err
+		}
+
+		// Apply business rules based on status
+		switch status {
+		case queue.StatusProcessing:
+			err = job.MarkAsProcessing()
+		case queue.StatusCompleted:
+			// A processing job was last updated when it started running
+			processing, startedAt := job.Status == queue.StatusProcessing, job.UpdatedAt
+			err = job.MarkAsCompleted()
+			if err == nil && processing {
+				duration = job.UpdatedAt.Sub(startedAt)
+			}
+		case queue.StatusFailed:
+			err = job.MarkAsFailed(nil)
+		}
+		if err != nil {
+			return err
+		}
+		return s.jobRepo.Update(ctx, job)
+	})
 	if err != nil {
 		return err
 	}
 
-	// Apply business rules based on status
 	switch status {
-	case queue.StatusProcessing:
-		job.MarkAsProcessing()
 	case queue.StatusCompleted:
-		job.MarkAsCompleted()
-		s.metrics.RecordJobCompleted(job.Queue, job.Type, 0) // Duration can be calculated
+		s.metrics.RecordJobCompleted(job.Queue, job.Type, duration.Seconds())
 	case queue.StatusFailed:
-		job.MarkAsFailed(nil)
 		s.metrics.RecordJobFailed(job.Queue, job.Type)
 	}
-
-	return s.jobRepo.Update(ctx, job)
+	return nil
 }
 
 // RetryJob retries a failed job
 func (s *Service) RetryJob(ctx context.Context, jobID uuid.UUID, maxAttempts int) error {
-	job, err := s.jobRepo.GetByID(ctx, jobID)
-	if err != nil {
-		return err
-	}
+	var job *queue.Job
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		job, err = s.jobRepo.GetByIDForUpdate(ctx, jobID)
+		if err != nil {
+			return err
+		}
 
-	if !job.CanRetry(maxAttempts) {
-		return queue.ErrMaxAttemptsReached
-	}
+		if !job.CanRetry(maxAttempts) {
+			return queue.ErrMaxAttemptsReached
+		}
+		if err := s.checkNotDraining(ctx, job.Queue); err != nil {
+			return err
+		}
 
-	job.MarkAsRetrying()
-	if err := s.jobRepo.Update(ctx, job); err != nil {
+		if err := job.MarkAsRetrying(); err != nil {
+			return err
+		}
+		return s.jobRepo.Update(ctx, job)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -137,14 +316,95 @@ func (s *Service) GetDLQJobs(ctx context.Context, limit, offset int) ([]*queue.J
 	return jobs, count, nil
 }
 
-// DeleteJob deletes a job
+// CountDLQJobs returns the number of jobs in the dead letter queue
+func (s *Service) CountDLQJobs(ctx context.Context) (int64, error) {
+	return s.jobRepo.CountDLQJobs(ctx)
+}
+
+// DeleteJob soft-deletes a job; it can be restored until it is purged. A job waiting in its
+// queue is withdrawn with its row locked, so no worker runs it once it is deleted.
 func (s *Service) DeleteJob(ctx context.Context, id uuid.UUID) error {
-	return s.jobRepo.Delete(ctx, id)
+	var job *queue.Job
+	withdrawn := false
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		job, err = s.jobRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if job.IsDeleted() {
+			return queue.ErrJobNotFound
+		}
+		if s.withdrawer != nil && job.IsReady() {
+			if withdrawn, err = s.withdrawer.Withdraw(ctx, job); err != nil {
+				return err
+			}
+		}
+		return s.jobRepo.Delete(ctx, id)
+	})
+	if err != nil {
+		if withdrawn {
+			if enqueueErr := s.queueService.Enqueue(ctx, job); enqueueErr != nil {
+				slog.ErrorContext(ctx, "Failed to put back withdrawn job",
+					slog.String("jobId", id.String()),
+					slog.String("error", enqueueErr.Error()),
+				)
+			}
+		}
+		return err
+	}
+	s.releaseQuota(ctx, id)
+	return nil
+}
+
+// releaseQuota stops counting a job against its creator's pending jobs
+func (s *Service) releaseQuota(ctx context.Context, jobID uuid.UUID) {
+	if s.quotas == nil {
+		return
+	}
+	if err := s.quotas.ReleaseJob(ctx, jobID); err != nil {
+		slog.WarnContext(ctx, "Failed to release pending job quota",
+			slog.String("jobId", jobID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// UndeleteJob restores a soft-deleted job. A ready job, withdrawn from its queue when it was
+// deleted, is enqueued again.
+func (s *Service) UndeleteJob(ctx context.Context, id uuid.UUID) (*queue.Job, error) {
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !job.IsDeleted() {
+		return nil, queue.ErrJobNotDeleted
+	}
+
+	if err := s.jobRepo.Undelete(ctx, id); err != nil {
+		return nil, err
+	}
+	job, err = s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.withdrawer != nil && job.IsReady() {
+		if err := s.queueService.Enqueue(ctx, job); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// PurgeDeletedJobs permanently removes jobs soft-deleted longer than the grace period ago
+func (s *Service) PurgeDeletedJobs(ctx context.Context, gracePeriod time.Duration) (int64, error) {
+	return s.jobRepo.PurgeDeleted(ctx, time.Now().UTC().Add(-gracePeriod))
 }
 
 // GetMetrics retrieves queue metrics
 func (s *Service) GetMetrics(ctx context.Context) (map[string]any, error) {
 	metrics := make(map[string]any)
+	counts := make(map[queue.Status]int64)
 
 	// Count jobs by status
 	for _, status := range []queue.Status{
@@ -152,12 +412,17 @@ func (s *Service) GetMetrics(ctx context.Context) (map[string]any, error) {
 		queue.StatusProcessing,
 		queue.StatusCompleted,
 		queue.StatusFailed,
+		queue.StatusRetrying,
+		queue.StatusCancelled,
+		queue.StatusHeld,
+		queue.StatusParked,
 	} {
 		count, err := s.jobRepo.CountByStatus(ctx, status)
 		if err != nil {
 			return nil, err
 		}
 		metrics[string(status)] = count
+		counts[status] = count
 	}
 
 	dlqCount, err := s.jobRepo.CountDLQJobs(ctx)
@@ -166,5 +431,57 @@ func (s *Service) GetMetrics(ctx context.Context) (map[string]any, error) {
 	}
 	metrics["dlq"] = dlqCount
 
+	// Delayed jobs are pending in the database but only reach the queue backend once due
+	delayed, err := s.jobRepo.CountDelayed(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	// Delivery state tracked by the queue backend
+	deliveryStats, err := s.queueService.DeliveryStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	queues := make(map[string]any, len(deliveryStats))
+	var ready, inFlight int64
+	for _, stats := range deliveryStats {
+		queues[stats.Queue] = map[string]int64{
+			"acked":   stats.Acked,
+			"nacked":  stats.Nacked,
+			"unacked": stats.Unacked,
+			"ready":   stats.Ready,
+		}
+		ready += stats.Ready
+		inFlight += stats.Unacked
+	}
+	metrics["queues"] = queues
+
+	// What the broker holds next to what the database says, so jobs lost between them show up.
+	// Retrying jobs stay in the broker's in-flight list while the worker waits out their backoff,
+	// and delayed jobs not yet due aren't in the broker at all.
+	metrics["broker"] = map[string]int64{
+		"ready":      ready,
+		"processing": inFlight,
+	}
+	metrics["drift"] = map[string]int64{
+		"pending":    counts[queue.StatusPending] - delayed - ready,
+		"processing": counts[queue.StatusProcessing] + counts[queue.StatusRetrying] - inFlight,
+	}
+
+	// Why jobs failed, counted as the workers ran them
+	if s.metricsReader != nil {
+		counters, err := s.metricsReader.JobCounters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		metrics["failures"] = failureBreakdown(counters)
+
+		durations, err := s.metricsReader.JobDurations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		metrics["latency"] = latencyBreakdown(durations)
+	}
+
 	return metrics, nil
 }
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/ratelimit"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock implementations
@@ -29,6 +34,14 @@ func (m *MockJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*queue.J
 	return args.Get(0).(*queue.Job), args.Error(1)
 }
 
+func (m *MockJobRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*queue.Job, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Job), args.Error(1)
+}
+
 func (m *MockJobRepository) Update(ctx context.Context, job *queue.Job) error {
 	args := m.Called(ctx, job)
 	return args.Error(0)
@@ -39,6 +52,16 @@ func (m *MockJobRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockJobRepository) Undelete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockJobRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockJobRepository) FindPendingJobs(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
 	args := m.Called(ctx, queueName, limit)
 	if args.Get(0) == nil {
@@ -47,6 +70,14 @@ func (m *MockJobRepository) FindPendingJobs(ctx context.Context, queueName strin
 	return args.Get(0).([]*queue.Job), args.Error(1)
 }
 
+func (m *MockJobRepository) FindDueDelayedJobs(ctx context.Context, now time.Time, limit int) ([]*queue.Job, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.Job), args.Error(1)
+}
+
 func (m *MockJobRepository) FindByStatus(ctx context.Context, status queue.Status, limit int) ([]*queue.Job, error) {
 	args := m.Called(ctx, status, limit)
 	if args.Get(0) == nil {
@@ -60,6 +91,35 @@ func (m *MockJobRepository) CountByStatus(ctx context.Context, status queue.Stat
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockJobRepository) CountDelayed(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockJobRepository) Search(ctx context.Context, criteria queue.SearchCriteria) ([]*queue.Job, int64, error) {
+	args := m.Called(ctx, criteria)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*queue.Job), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockJobRepository) ListByQueue(ctx context.Context, listing queue.QueueListing) ([]*queue.Job, int64, error) {
+	args := m.Called(ctx, listing)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*queue.Job), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockJobRepository) Activity(ctx context.Context, since time.Time, topTypes int) (*queue.Activity, error) {
+	args := m.Called(ctx, since, topTypes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Activity), args.Error(1)
+}
+
 func (m *MockJobRepository) GetDLQJobs(ctx context.Context, limit, offset int) ([]*queue.Job, error) {
 	args := m.Called(ctx, limit, offset)
 	if args.Get(0) == nil {
@@ -87,8 +147,8 @@ func (m *MockQueueService) Enqueue(ctx context.Context, job *queue.Job) error {
 	return args.Error(0)
 }
 
-func (m *MockQueueService) Dequeue(ctx context.Context, queueName string) (*queue.Job, error) {
-	args := m.Called(ctx, queueName)
+func (m *MockQueueService) Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*queue.Job, error) {
+	args := m.Called(ctx, queueName, capabilities, timeout)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -100,6 +160,19 @@ func (m *MockQueueService) Acknowledge(ctx context.Context, jobID uuid.UUID) err
 	return args.Error(0)
 }
 
+func (m *MockQueueService) Nack(ctx context.Context, job *queue.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *MockQueueService) DeliveryStats(ctx context.Context) ([]*queue.DeliveryStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.DeliveryStats), args.Error(1)
+}
+
 type MockMetricsService struct {
 	mock.Mock
 }
@@ -116,10 +189,32 @@ func (m *MockMetricsService) RecordJobFailed(queueName, jobType string) {
 	m.Called(queueName, jobType)
 }
 
+func (m *MockMetricsService) RecordJobFailedWithReason(queueName, jobType, category string) {
+	m.Called(queueName, jobType, category)
+}
+
 func (m *MockMetricsService) RecordJobRetried(queueName, jobType string) {
 	m.Called(queueName, jobType)
 }
 
+func (m *MockMetricsService) RecordJobParked(queueName, jobType string) {
+	m.Called(queueName, jobType)
+}
+
+type StaticMetricsReader struct {
+	counters  []*queue.JobCounter
+	durations []*queue.DurationHistogram
+	err       error
+}
+
+func (r *StaticMetricsReader) JobCounters(ctx context.Context) ([]*queue.JobCounter, error) {
+	return r.counters, r.err
+}
+
+func (r *StaticMetricsReader) JobDurations(ctx context.Context) ([]*queue.DurationHistogram, error) {
+	return r.durations, r.err
+}
+
 func TestService_CreateJob(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -154,6 +249,148 @@ func TestService_CreateJob(t *testing.T) {
 				assert.Equal(t, queue.StatusPending, job.Status)
 			},
 		},
+		{
+			name:  "Job created with an API key",
+			given: "a command carrying the caller's API key and no name configured for it",
+			when:  "creating a new job",
+			then:  "should attribute the job to the key's hashed ID, never the key itself",
+			command: CreateJobCommand{
+				Queue:   "default",
+				Type:    "email",
+				Payload: map[string]any{},
+				APIKey:  "team-a-secret",
+			},
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, metrics *MockMetricsService) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(job *queue.Job) bool {
+					return job.CreatedBy == queue.SigningKeyID("team-a-secret")
+				})).Return(nil)
+				queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				metrics.On("RecordJobCreated", "default", "email").Return()
+			},
+			expectErr: false,
+			validateJob: func(t *testing.T, job *queue.Job) {
+				assert.Equal(t, queue.SigningKeyID("team-a-secret"), job.CreatedBy)
+			},
+		},
+		{
+			name:  "Job requiring capabilities",
+			given: "a command requiring capabilities in mixed case and with a duplicate",
+			when:  "creating a new job",
+			then:  "should store them normalized so the job is routed to matching workers",
+			command: CreateJobCommand{
+				Queue:    "default",
+				Type:     "email",
+				Payload:  map[string]any{},
+				Requires: []string{"Region=EU", "gpu", "gpu"},
+			},
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, metrics *MockMetricsService) {
+				repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				metrics.On("RecordJobCreated", "default", "email").Return()
+			},
+			expectErr: false,
+			validateJob: func(t *testing.T, job *queue.Job) {
+				assert.Equal(t, []string{"gpu", "region=eu"}, job.Requires)
+				assert.Equal(t, "gpu,region=eu", job.Route())
+			},
+		},
+		{
+			name:  "Invalid capability",
+			given: "a command requiring a capability with spaces inside",
+			when:  "creating a new job",
+			then:  "should return a validation error without storing the job",
+			command: CreateJobCommand{
+				Queue:    "default",
+				Type:     "email",
+				Payload:  map[string]any{},
+				Requires: []string{"big gpu"},
+			},
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, metrics *MockMetricsService) {
+				// No mocks needed as validation fails before repo call
+			},
+			expectErr: true,
+		},
+		{
+			name:  "Job with metadata",
+			given: "a command carrying a correlation ID and the source system under mixed case keys",
+			when:  "creating a new job",
+			then:  "should store the metadata with lowercased keys",
+			command: CreateJobCommand{
+				Queue:    "default",
+				Type:     "email",
+				Payload:  map[string]any{},
+				Metadata: map[string]string{"Correlation_ID": "abc-123", "source": "billing"},
+			},
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, metrics *MockMetricsService) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(job *queue.Job) bool {
+					return job.Metadata["correlation_id"] == "abc-123"
+				})).Return(nil)
+				queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				metrics.On("RecordJobCreated", "default", "email").Return()
+			},
+			expectErr: false,
+			validateJob: func(t *testing.T, job *queue.Job) {
+				assert.Equal(t, map[string]string{"correlation_id": "abc-123", "source": "billing"}, job.Metadata)
+			},
+		},
+		{
+			name:  "Invalid metadata",
+			given: "a command carrying a metadata key with spaces inside",
+			when:  "creating a new job",
+			then:  "should return a validation error without storing the job",
+			command: CreateJobCommand{
+				Queue:    "default",
+				Type:     "email",
+				Payload:  map[string]any{},
+				Metadata: map[string]string{"source system": "billing"},
+			},
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, metrics *MockMetricsService) {
+				// No mocks needed as validation fails before repo call
+			},
+			expectErr: true,
+		},
+		{
+			name:  "Delayed job",
+			given: "a command scheduled an hour from now",
+			when:  "creating a new job",
+			then:  "should store it with its schedule and leave it to the scheduler to enqueue",
+			command: CreateJobCommand{
+				Queue:        "default",
+				Type:         "email",
+				Payload:      map[string]any{},
+				ScheduledFor: func() *time.Time { at := time.Now().Add(time.Hour); return &at }(),
+			},
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, metrics *MockMetricsService) {
+				repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				metrics.On("RecordJobCreated", "default", "email").Return()
+			},
+			expectErr: false,
+			validateJob: func(t *testing.T, job *queue.Job) {
+				assert.True(t, job.IsDelayed())
+				assert.Equal(t, queue.StatusPending, job.Status)
+			},
+		},
+		{
+			name:  "Schedule in the past",
+			given: "a command scheduled a minute ago",
+			when:  "creating a new job",
+			then:  "should enqueue it right away",
+			command: CreateJobCommand{
+				Queue:        "default",
+				Type:         "email",
+				Payload:      map[string]any{},
+				ScheduledFor: func() *time.Time { at := time.Now().Add(-time.Minute); return &at }(),
+			},
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, metrics *MockMetricsService) {
+				repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				metrics.On("RecordJobCreated", "default", "email").Return()
+			},
+			expectErr: false,
+			validateJob: func(t *testing.T, job *queue.Job) {
+				assert.False(t, job.IsDelayed())
+			},
+		},
 		{
 			name:  "Empty queue name",
 			given: "command with empty queue name",
@@ -308,6 +545,72 @@ func TestService_GetJob(t *testing.T) {
 	}
 }
 
+// histogramOf returns the duration histogram of runs lasting the given seconds
+func histogramOf(queueName, jobType string, durations ...float64) *queue.DurationHistogram {
+	histogram := queue.NewDurationHistogram(queueName, jobType)
+	for _, d := range durations {
+		histogram.Observe(d)
+	}
+	return histogram
+}
+
+func TestService_UpdateJobStatus(t *testing.T) {
+	tests := []struct {
+		name             string
+		given            string
+		when             string
+		then             string
+		status           queue.Status
+		runningFor       time.Duration
+		expectedDuration float64
+	}{
+		{
+			name:             "Completed job records its run",
+			given:            "a job that started processing 2 seconds ago",
+			when:             "marking it completed",
+			then:             "should record the completion with the time it ran for",
+			status:           queue.StatusProcessing,
+			runningFor:       2 * time.Second,
+			expectedDuration: 2,
+		},
+		{
+			name:             "Completed job marked again",
+			given:            "a job that already completed",
+			when:             "marking it completed",
+			then:             "should record the completion without a duration",
+			status:           queue.StatusCompleted,
+			runningFor:       time.Minute,
+			expectedDuration: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			job.Status = tt.status
+			job.UpdatedAt = time.Now().UTC().Add(-tt.runningFor)
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("GetByIDForUpdate", mock.Anything, job.ID).Return(job, nil)
+			mockRepo.On("Update", mock.Anything, job).Return(nil)
+			mockMetrics := new(MockMetricsService)
+			mockMetrics.On("RecordJobCompleted", "default", "email", mock.AnythingOfType("float64")).Return()
+
+			service := NewService(mockRepo, new(MockQueueService), mockMetrics)
+
+			// When
+			err := service.UpdateJobStatus(context.Background(), job.ID, queue.StatusCompleted)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, queue.StatusCompleted, job.Status)
+			duration := mockMetrics.Calls[0].Arguments.Get(2).(float64)
+			assert.InDelta(t, tt.expectedDuration, duration, 0.5)
+		})
+	}
+}
+
 func TestService_RetryJob(t *testing.T) {
 	jobID := uuid.New()
 
@@ -334,7 +637,7 @@ func TestService_RetryJob(t *testing.T) {
 					Status:   queue.StatusFailed,
 					Attempts: 2,
 				}
-				repo.On("GetByID", mock.Anything, jobID).Return(job, nil)
+				repo.On("GetByIDForUpdate", mock.Anything, jobID).Return(job, nil)
 				repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
 				queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
 				metrics.On("RecordJobRetried", "default", "email").Return()
@@ -353,7 +656,7 @@ func TestService_RetryJob(t *testing.T) {
 					Status:   queue.StatusFailed,
 					Attempts: 3,
 				}
-				repo.On("GetByID", mock.Anything, jobID).Return(job, nil)
+				repo.On("GetByIDForUpdate", mock.Anything, jobID).Return(job, nil)
 			},
 			expectErr: true,
 		},
@@ -386,3 +689,2687 @@ func TestService_RetryJob(t *testing.T) {
 		})
 	}
 }
+
+type FakeUnitOfWork struct {
+	active       bool
+	commits      int
+	rollbacks    int
+	enqueuedInTx bool
+}
+
+func (u *FakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	u.active = true
+	err := fn(ctx)
+	u.active = false
+	if err != nil {
+		u.rollbacks++
+		return err
+	}
+	u.commits++
+	return nil
+}
+
+func TestService_RetryJob_UnitOfWork(t *testing.T) {
+	jobID := uuid.New()
+
+	tests := []struct {
+		name              string
+		given             string
+		when              string
+		then              string
+		updateErr         error
+		expectErr         bool
+		expectedCommits   int
+		expectedRollbacks int
+		expectEnqueue     bool
+	}{
+		{
+			name:            "Commit before enqueueing",
+			given:           "a failed job that may be retried",
+			when:            "retrying the job in a unit of work",
+			then:            "should lock, update and commit, then enqueue outside the transaction",
+			expectedCommits: 1,
+			expectEnqueue:   true,
+		},
+		{
+			name:              "Roll back a failed update",
+			given:             "a failed job whose update fails",
+			when:              "retrying the job in a unit of work",
+			then:              "should roll back and enqueue nothing",
+			updateErr:         errors.New("connection reset"),
+			expectErr:         true,
+			expectedRollbacks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			unitOfWork := &FakeUnitOfWork{}
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("GetByIDForUpdate", mock.Anything, jobID).Return(&queue.Job{ID: jobID, Queue: "default", Type: "email", Status: queue.StatusFailed, Attempts: 1}, nil)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(tt.updateErr)
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).
+				Run(func(mock.Arguments) { unitOfWork.enqueuedInTx = unitOfWork.active })
+			mockMetrics := new(MockMetricsService)
+			mockMetrics.On("RecordJobRetried", "default", "email").Return()
+			service := NewService(mockRepo, mockQueueSvc, mockMetrics)
+			service.SetUnitOfWork(unitOfWork)
+
+			// When
+			err := service.RetryJob(context.Background(), jobID, 3)
+
+			// Then
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCommits, unitOfWork.commits)
+			assert.Equal(t, tt.expectedRollbacks, unitOfWork.rollbacks)
+			assert.False(t, unitOfWork.enqueuedInTx)
+			if tt.expectEnqueue {
+				mockQueueSvc.AssertCalled(t, "Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job"))
+			} else {
+				mockQueueSvc.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_GetMetrics(t *testing.T) {
+	tests := []struct {
+		name       string
+		given      string
+		when       string
+		then       string
+		setupMocks func(*MockJobRepository, *MockQueueService)
+		reader     queue.MetricsReader
+		expectErr  bool
+		validate   func(*testing.T, map[string]any)
+	}{
+		{
+			name:  "Include delivery stats per queue",
+			given: "job counts in the database and delivery stats in the queue backend",
+			when:  "getting metrics",
+			then:  "should return status counts and ack/unacked counts per queue",
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService) {
+				repo.On("CountByStatus", mock.Anything, mock.Anything).Return(int64(1), nil)
+				repo.On("CountDLQJobs", mock.Anything).Return(int64(2), nil)
+				repo.On("CountDelayed", mock.Anything, mock.Anything).Return(int64(0), nil)
+				queueSvc.On("DeliveryStats", mock.Anything).Return([]*queue.DeliveryStats{
+					{Queue: "default", Acked: 10, Nacked: 3, Unacked: 2},
+				}, nil)
+			},
+			expectErr: false,
+			validate: func(t *testing.T, metrics map[string]any) {
+				assert.Equal(t, int64(1), metrics["pending"])
+				assert.Equal(t, int64(2), metrics["dlq"])
+				assert.Equal(t, map[string]any{
+					"default": map[string]int64{"acked": 10, "nacked": 3, "unacked": 2, "ready": 0},
+				}, metrics["queues"])
+			},
+		},
+		{
+			name:  "Broker counts next to database counts",
+			given: "pending jobs in the database that are missing from the broker's lists",
+			when:  "getting metrics",
+			then:  "should report the broker's totals and how far they drift from the database",
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService) {
+				repo.On("CountByStatus", mock.Anything, queue.StatusPending).Return(int64(7), nil)
+				repo.On("CountByStatus", mock.Anything, queue.StatusProcessing).Return(int64(2), nil)
+				repo.On("CountByStatus", mock.Anything, queue.StatusRetrying).Return(int64(1), nil)
+				repo.On("CountByStatus", mock.Anything, mock.Anything).Return(int64(0), nil)
+				repo.On("CountDLQJobs", mock.Anything).Return(int64(0), nil)
+				repo.On("CountDelayed", mock.Anything, mock.Anything).Return(int64(0), nil)
+				queueSvc.On("DeliveryStats", mock.Anything).Return([]*queue.DeliveryStats{
+					{Queue: "default", Unacked: 2, Ready: 3},
+					{Queue: "emails", Unacked: 1, Ready: 1},
+				}, nil)
+			},
+			expectErr: false,
+			validate: func(t *testing.T, metrics map[string]any) {
+				assert.Equal(t, map[string]int64{"ready": 4, "processing": 3}, metrics["broker"])
+				assert.Equal(t, map[string]int64{"pending": 3, "processing": 0}, metrics["drift"])
+				assert.NotContains(t, metrics, "failures")
+			},
+		},
+		{
+			name:  "Delayed jobs left out of the drift",
+			given: "pending jobs in the database of which some are delayed and not yet due",
+			when:  "getting metrics",
+			then:  "should not count the delayed jobs as missing from the broker",
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService) {
+				repo.On("CountByStatus", mock.Anything, queue.StatusPending).Return(int64(5), nil)
+				repo.On("CountByStatus", mock.Anything, mock.Anything).Return(int64(0), nil)
+				repo.On("CountDLQJobs", mock.Anything).Return(int64(0), nil)
+				repo.On("CountDelayed", mock.Anything, mock.Anything).Return(int64(2), nil)
+				queueSvc.On("DeliveryStats", mock.Anything).Return([]*queue.DeliveryStats{
+					{Queue: "default", Ready: 3},
+				}, nil)
+			},
+			expectErr: false,
+			validate: func(t *testing.T, metrics map[string]any) {
+				assert.Equal(t, int64(5), metrics["pending"])
+				assert.Equal(t, map[string]int64{"pending": 0, "processing": 0}, metrics["drift"])
+			},
+		},
+		{
+			name:  "Failures broken down by category",
+			given: "a metrics reader with failed counters in two queues",
+			when:  "getting metrics",
+			then:  "should sum the failures per category, overall and per queue and job type",
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService) {
+				repo.On("CountByStatus", mock.Anything, mock.Anything).Return(int64(0), nil)
+				repo.On("CountDLQJobs", mock.Anything).Return(int64(0), nil)
+				repo.On("CountDelayed", mock.Anything, mock.Anything).Return(int64(0), nil)
+				queueSvc.On("DeliveryStats", mock.Anything).Return([]*queue.DeliveryStats{}, nil)
+			},
+			reader: &StaticMetricsReader{counters: []*queue.JobCounter{
+				{Event: queue.CounterCreated, Queue: "default", Type: "send-email", Count: 9},
+				{Event: queue.CounterFailed, Queue: "default", Type: "send-email", Category: queue.FailureTimeout, Count: 3},
+				{Event: queue.CounterFailed, Queue: "default", Type: "send-email", Category: queue.FailureAuth, Count: 1},
+				{Event: queue.CounterFailed, Queue: "reports", Type: "build-report", Category: queue.FailureTimeout, Count: 2},
+			}},
+			expectErr: false,
+			validate: func(t *testing.T, metrics map[string]any) {
+				assert.Equal(t, map[string]any{
+					"total":       int64(6),
+					"by_category": map[string]int64{queue.FailureTimeout: 5, queue.FailureAuth: 1},
+					"by_queue": map[string]map[string]map[string]int64{
+						"default": {"send-email": {queue.FailureTimeout: 3, queue.FailureAuth: 1}},
+						"reports": {"build-report": {queue.FailureTimeout: 2}},
+					},
+				}, metrics["failures"])
+			},
+		},
+		{
+			name:  "Latency percentiles",
+			given: "a metrics reader with the duration histograms of two job types",
+			when:  "getting metrics",
+			then:  "should sum up the durations per queue and job type, leaving out empty histograms",
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService) {
+				repo.On("CountByStatus", mock.Anything, mock.Anything).Return(int64(0), nil)
+				repo.On("CountDLQJobs", mock.Anything).Return(int64(0), nil)
+				repo.On("CountDelayed", mock.Anything, mock.Anything).Return(int64(0), nil)
+				queueSvc.On("DeliveryStats", mock.Anything).Return([]*queue.DeliveryStats{}, nil)
+			},
+			reader: &StaticMetricsReader{durations: []*queue.DurationHistogram{
+				histogramOf("default", "send-email", 0.6, 0.7, 0.8, 0.9),
+				queue.NewDurationHistogram("reports", "build-report"),
+			}},
+			expectErr: false,
+			validate: func(t *testing.T, metrics map[string]any) {
+				latency := metrics["latency"].(map[string]map[string]map[string]any)
+				assert.NotContains(t, latency, "reports")
+				summary := latency["default"]["send-email"]
+				assert.Equal(t, int64(4), summary["count"])
+				assert.InDelta(t, 0.75, summary["mean_seconds"], 1e-9)
+				assert.InDelta(t, 0.75, summary["p50_seconds"], 1e-9)
+				assert.InDelta(t, 0.975, summary["p95_seconds"], 1e-9)
+				assert.InDelta(t, 0.995, summary["p99_seconds"], 1e-9)
+			},
+		},
+		{
+			name:  "Metrics reader unavailable",
+			given: "a metrics reader that cannot be read",
+			when:  "getting metrics",
+			then:  "should return error",
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService) {
+				repo.On("CountByStatus", mock.Anything, mock.Anything).Return(int64(0), nil)
+				repo.On("CountDLQJobs", mock.Anything).Return(int64(0), nil)
+				repo.On("CountDelayed", mock.Anything, mock.Anything).Return(int64(0), nil)
+				queueSvc.On("DeliveryStats", mock.Anything).Return([]*queue.DeliveryStats{}, nil)
+			},
+			reader:    &StaticMetricsReader{err: errors.New("redis down")},
+			expectErr: true,
+		},
+		{
+			name:  "Queue backend unavailable",
+			given: "delivery stats cannot be read",
+			when:  "getting metrics",
+			then:  "should return error",
+			setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService) {
+				repo.On("CountByStatus", mock.Anything, mock.Anything).Return(int64(0), nil)
+				repo.On("CountDLQJobs", mock.Anything).Return(int64(0), nil)
+				repo.On("CountDelayed", mock.Anything, mock.Anything).Return(int64(0), nil)
+				queueSvc.On("DeliveryStats", mock.Anything).Return(nil, errors.New("redis down"))
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := new(MockJobRepository)
+			mockQueueSvc := new(MockQueueService)
+			mockMetrics := new(MockMetricsService)
+			tt.setupMocks(mockRepo, mockQueueSvc)
+
+			service := NewService(mockRepo, mockQueueSvc, mockMetrics)
+			if tt.reader != nil {
+				service.SetMetricsReader(tt.reader)
+			}
+
+			// When
+			metrics, err := service.GetMetrics(context.Background())
+
+			// Then
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, metrics)
+			} else {
+				assert.NoError(t, err)
+				tt.validate(t, metrics)
+			}
+
+			mockQueueSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_DeleteJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		status         queue.Status
+		withdrawn      bool
+		deleteErr      error
+		expectErr      error
+		expectWithdraw bool
+		expectEnqueue  bool
+	}{
+		{
+			name:           "Delete queued job",
+			given:          "a pending job waiting in its queue",
+			when:           "deleting the job",
+			then:           "should withdraw it from the queue before deleting it",
+			status:         queue.StatusPending,
+			withdrawn:      true,
+			expectWithdraw: true,
+		},
+		{
+			name:   "Delete failed job",
+			given:  "a failed job, which isn't queued",
+			when:   "deleting the job",
+			then:   "should delete it without touching the queue",
+			status: queue.StatusFailed,
+		},
+		{
+			name:           "Delete fails",
+			given:          "a pending job whose row can't be deleted",
+			when:           "deleting the job",
+			then:           "should put the withdrawn job back in its queue",
+			status:         queue.StatusPending,
+			withdrawn:      true,
+			deleteErr:      errors.New("connection reset"),
+			expectErr:      errors.New("connection reset"),
+			expectWithdraw: true,
+			expectEnqueue:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job := &queue.Job{ID: uuid.New(), Queue: "default", Type: "email", Status: tt.status}
+			mockRepo := new(MockJobRepository)
+			mockQueueSvc := new(MockQueueService)
+			withdrawer := new(MockQueueWithdrawer)
+			mockRepo.On("GetByIDForUpdate", mock.Anything, job.ID).Return(job, nil)
+			mockRepo.On("Delete", mock.Anything, job.ID).Return(tt.deleteErr)
+			withdrawer.On("Withdraw", mock.Anything, job).Return(tt.withdrawn, nil)
+			mockQueueSvc.On("Enqueue", mock.Anything, job).Return(nil)
+			service := NewService(mockRepo, mockQueueSvc, new(MockMetricsService))
+			service.SetQueueWithdrawer(withdrawer)
+
+			// When
+			err := service.DeleteJob(context.Background(), job.ID)
+
+			// Then
+			assert.Equal(t, tt.expectErr, err)
+			if tt.expectWithdraw {
+				withdrawer.AssertCalled(t, "Withdraw", mock.Anything, job)
+			} else {
+				withdrawer.AssertNotCalled(t, "Withdraw", mock.Anything, mock.Anything)
+			}
+			if tt.expectEnqueue {
+				mockQueueSvc.AssertCalled(t, "Enqueue", mock.Anything, job)
+			} else {
+				mockQueueSvc.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_UndeleteJob(t *testing.T) {
+	jobID := uuid.New()
+	deletedAt := time.Now().UTC().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		given      string
+		when       string
+		then       string
+		setupMocks func(*MockJobRepository)
+		expectErr  error
+	}{
+		{
+			name:  "Restore soft-deleted job",
+			given: "a soft-deleted job",
+			when:  "undeleting the job",
+			then:  "should restore it and return the job",
+			setupMocks: func(repo *MockJobRepository) {
+				repo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID, DeletedAt: &deletedAt}, nil).Once()
+				repo.On("Undelete", mock.Anything, jobID).Return(nil)
+				repo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID}, nil).Once()
+			},
+		},
+		{
+			name:  "Job is not deleted",
+			given: "a live job",
+			when:  "undeleting the job",
+			then:  "should return ErrJobNotDeleted without touching the repository",
+			setupMocks: func(repo *MockJobRepository) {
+				repo.On("GetByID", mock.Anything, jobID).Return(&queue.Job{ID: jobID}, nil)
+			},
+			expectErr: queue.ErrJobNotDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := new(MockJobRepository)
+			tt.setupMocks(mockRepo)
+			service := NewService(mockRepo, new(MockQueueService), new(MockMetricsService))
+
+			// When
+			job, err := service.UndeleteJob(context.Background(), jobID)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				assert.Nil(t, job)
+				mockRepo.AssertNotCalled(t, "Undelete", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.False(t, job.IsDeleted())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+type MockQueueStatsRepository struct {
+	mock.Mock
+}
+
+func (m *MockQueueStatsRepository) CountByQueue(ctx context.Context) ([]*queue.QueueStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.QueueStats), args.Error(1)
+}
+
+func (m *MockQueueStatsRepository) Record(ctx context.Context, stats []*queue.QueueStats) error {
+	args := m.Called(ctx, stats)
+	return args.Error(0)
+}
+
+func (m *MockQueueStatsRepository) History(ctx context.Context, queueName string, since time.Time) ([]*queue.QueueStats, error) {
+	args := m.Called(ctx, queueName, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.QueueStats), args.Error(1)
+}
+
+func (m *MockQueueStatsRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestService_SampleQueueStats(t *testing.T) {
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		counts        []*queue.QueueStats
+		countErr      error
+		expectRecord  bool
+		expectSampled int
+		expectErr     error
+	}{
+		{
+			name:  "Record counts of every queue",
+			given: "two queues with jobs",
+			when:  "sampling queue stats",
+			then:  "should record both stamped with the current minute",
+			counts: []*queue.QueueStats{
+				{Queue: "default", Pending: 4, Completed: 10},
+				{Queue: "emails", Failed: 2},
+			},
+			expectRecord:  true,
+			expectSampled: 2,
+		},
+		{
+			name:          "No queues",
+			given:         "no jobs in any queue",
+			when:          "sampling queue stats",
+			then:          "should not record anything",
+			counts:        []*queue.QueueStats{},
+			expectRecord:  false,
+			expectSampled: 0,
+		},
+		{
+			name:      "Counting fails",
+			given:     "the repository fails to count jobs",
+			when:      "sampling queue stats",
+			then:      "should return the error",
+			countErr:  errors.New("database down"),
+			expectErr: errors.New("database down"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			statsRepo := new(MockQueueStatsRepository)
+			statsRepo.On("CountByQueue", mock.Anything).Return(tt.counts, tt.countErr)
+			if tt.expectRecord {
+				statsRepo.On("Record", mock.Anything, mock.MatchedBy(func(stats []*queue.QueueStats) bool {
+					for _, sample := range stats {
+						if sample.SampledAt.IsZero() || !sample.SampledAt.Equal(sample.SampledAt.Truncate(time.Minute)) {
+							return false
+						}
+					}
+					return len(stats) == len(tt.counts)
+				})).Return(nil)
+			}
+			service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+			service.SetStatsRepository(statsRepo)
+
+			// When
+			sampled, err := service.SampleQueueStats(context.Background())
+
+			// Then
+			if tt.expectErr != nil {
+				assert.EqualError(t, err, tt.expectErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectSampled, sampled)
+			if !tt.expectRecord {
+				statsRepo.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+			}
+			statsRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_GetQueueStatsHistory(t *testing.T) {
+	history := []*queue.QueueStats{{Queue: "default", Pending: 3}}
+
+	tests := []struct {
+		name      string
+		given     string
+		when      string
+		then      string
+		queueName string
+		withRepo  bool
+		expectErr error
+	}{
+		{
+			name:      "Return history over the window",
+			given:     "a configured stats repository",
+			when:      "fetching the history of a queue",
+			then:      "should query samples since the start of the window",
+			queueName: "default",
+			withRepo:  true,
+		},
+		{
+			name:      "Missing queue name",
+			given:     "a configured stats repository",
+			when:      "fetching the history without a queue",
+			then:      "should return ErrInvalidQueue",
+			withRepo:  true,
+			expectErr: queue.ErrInvalidQueue,
+		},
+		{
+			name:      "History not enabled",
+			given:     "no stats repository",
+			when:      "fetching the history of a queue",
+			then:      "should return ErrStatsDisabled",
+			queueName: "default",
+			expectErr: ErrStatsDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+			statsRepo := new(MockQueueStatsRepository)
+			windowStart := time.Now().UTC().Add(-24 * time.Hour)
+			statsRepo.On("History", mock.Anything, "default", mock.MatchedBy(func(since time.Time) bool {
+				return since.Sub(windowStart).Abs() < time.Minute
+			})).Return(history, nil)
+			if tt.withRepo {
+				service.SetStatsRepository(statsRepo)
+			}
+
+			// When
+			got, err := service.GetQueueStatsHistory(context.Background(), tt.queueName, 24*time.Hour)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				assert.Nil(t, got)
+				statsRepo.AssertNotCalled(t, "History", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, history, got)
+			}
+		})
+	}
+}
+
+func TestService_CreateJob_SignsPayload(t *testing.T) {
+	signer, _ := queue.NewPayloadSigner("default-secret", map[string]string{"tenant-a": "secret-a"}, false)
+
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		apiKey        string
+		expectedKeyID string
+	}{
+		{
+			name:          "Caller with its own secret",
+			given:         "an API key with a signing secret",
+			when:          "creating a job",
+			then:          "should sign it with the key's secret",
+			apiKey:        "tenant-a",
+			expectedKeyID: queue.SigningKeyID("tenant-a"),
+		},
+		{
+			name:          "Anonymous caller",
+			given:         "no API key",
+			when:          "creating a job",
+			then:          "should sign it with the default secret",
+			apiKey:        "",
+			expectedKeyID: queue.DefaultSigningKeyID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := new(MockJobRepository)
+			repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			queueSvc := new(MockQueueService)
+			queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			metrics := new(MockMetricsService)
+			metrics.On("RecordJobCreated", "default", "email").Return()
+			service := NewService(repo, queueSvc, metrics)
+			service.SetPayloadSigner(signer)
+
+			// When
+			job, err := service.CreateJob(context.Background(), CreateJobCommand{
+				Queue:   "default",
+				Type:    "email",
+				Payload: map[string]any{"to": "test@example.com"},
+				APIKey:  tt.apiKey,
+			})
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedKeyID, job.SigningKeyID)
+			assert.NotEmpty(t, job.Signature)
+			assert.NoError(t, signer.Verify(job))
+		})
+	}
+}
+
+func TestService_RecommendScaling(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	history := []*queue.QueueStats{
+		{Queue: "default", Processing: 1, SampledAt: now.Add(-10 * time.Minute)},
+		{Queue: "default", Pending: 60, Processing: 1, Completed: 600, SampledAt: now},
+	}
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		queueName      string
+		withRepo       bool
+		expectQueues   []string
+		expectReplicas []int
+		expectErr      error
+	}{
+		{
+			name:           "Recommend replicas for one queue",
+			given:          "a queue with a backlog and one second jobs",
+			when:           "recommending scaling for that queue",
+			then:           "should size replicas to the arrival rate plus the backlog",
+			queueName:      "default",
+			withRepo:       true,
+			expectQueues:   []string{"default"},
+			expectReplicas: []int{3},
+		},
+		{
+			name:           "Recommend replicas for every queue",
+			given:          "two queues with jobs, one without history",
+			when:           "recommending scaling without a queue name",
+			then:           "should return a recommendation per queue",
+			withRepo:       true,
+			expectQueues:   []string{"default", "emails"},
+			expectReplicas: []int{3, 0},
+		},
+		{
+			name:      "History not enabled",
+			given:     "no stats repository",
+			when:      "recommending scaling",
+			then:      "should return ErrStatsDisabled",
+			queueName: "default",
+			expectErr: ErrStatsDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+			statsRepo := new(MockQueueStatsRepository)
+			statsRepo.On("CountByQueue", mock.Anything).Return([]*queue.QueueStats{{Queue: "default"}, {Queue: "emails"}}, nil)
+			statsRepo.On("History", mock.Anything, "default", mock.Anything).Return(history, nil)
+			statsRepo.On("History", mock.Anything, "emails", mock.Anything).Return([]*queue.QueueStats{}, nil)
+			if tt.withRepo {
+				service.SetStatsRepository(statsRepo)
+			}
+
+			// When
+			got, err := service.RecommendScaling(context.Background(), tt.queueName, 0)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				assert.Nil(t, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, got, len(tt.expectQueues))
+			for i, rec := range got {
+				assert.Equal(t, tt.expectQueues[i], rec.Queue)
+				assert.Equal(t, tt.expectReplicas[i], rec.DesiredReplicas)
+			}
+		})
+	}
+}
+
+type MockDefinitionRepository struct {
+	mock.Mock
+}
+
+func (m *MockDefinitionRepository) Create(ctx context.Context, def *queue.Definition) error {
+	args := m.Called(ctx, def)
+	return args.Error(0)
+}
+
+func (m *MockDefinitionRepository) Get(ctx context.Context, name string) (*queue.Definition, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Definition), args.Error(1)
+}
+
+func (m *MockDefinitionRepository) List(ctx context.Context) ([]*queue.Definition, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.Definition), args.Error(1)
+}
+
+func (m *MockDefinitionRepository) Update(ctx context.Context, def *queue.Definition) error {
+	args := m.Called(ctx, def)
+	return args.Error(0)
+}
+
+func (m *MockDefinitionRepository) Delete(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+// StaticBucketLimiter answers every AllowLimit call with the same result
+type StaticBucketLimiter struct {
+	result *ratelimit.Result
+}
+
+func (l *StaticBucketLimiter) AllowLimit(ctx context.Context, key string, limit ratelimit.Limit) (*ratelimit.Result, error) {
+	return l.result, nil
+}
+
+func TestService_CreateJob_QueueDefinitions(t *testing.T) {
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		definition    *queue.Definition
+		enforce       bool
+		allowed       bool
+		untyped       bool // Creates the job without a type
+		payload       any  // Replaces the default object payload when set
+		expectErr     error
+		expectLimited bool
+	}{
+		{
+			name:       "Allowed job type",
+			given:      "a queue definition listing the job type",
+			when:       "creating a job",
+			then:       "should create the job",
+			definition: &queue.Definition{Name: "default", AllowedTypes: []string{"email"}},
+			allowed:    true,
+		},
+		{
+			name:       "Job type not allowed",
+			given:      "a queue definition that only accepts sms jobs",
+			when:       "creating an email job",
+			then:       "should return ErrJobTypeNotAllowed",
+			definition: &queue.Definition{Name: "default", AllowedTypes: []string{"sms"}},
+			allowed:    true,
+			expectErr:  queue.ErrJobTypeNotAllowed,
+		},
+		{
+			name:       "Default job type",
+			given:      "a queue definition with a default type",
+			when:       "creating a job without a type",
+			then:       "should create the job with the default type",
+			definition: &queue.Definition{Name: "default", AllowedTypes: []string{"email"}, DefaultType: "email"},
+			allowed:    true,
+			untyped:    true,
+		},
+		{
+			name:       "Missing job type without default",
+			given:      "a queue definition without a default type",
+			when:       "creating a job without a type",
+			then:       "should return ErrInvalidType",
+			definition: &queue.Definition{Name: "default"},
+			allowed:    true,
+			untyped:    true,
+			expectErr:  queue.ErrInvalidType,
+		},
+		{
+			name:       "Strict payload validation",
+			given:      "a queue definition validating payloads strictly",
+			when:       "creating a job with an array payload",
+			then:       "should return ErrPayloadNotObject",
+			definition: &queue.Definition{Name: "default", StrictPayload: true},
+			allowed:    true,
+			payload:    []string{"test@example.com"},
+			expectErr:  queue.ErrPayloadNotObject,
+		},
+		{
+			name:          "Queue rate limit exceeded",
+			given:         "a rate limited queue without tokens left",
+			when:          "creating a job",
+			then:          "should return a RateLimitedError",
+			definition:    &queue.Definition{Name: "default", RateLimitPerSecond: 1},
+			expectLimited: true,
+		},
+		{
+			name:    "Undefined queue without enforcement",
+			given:   "no definition for the queue",
+			when:    "creating a job",
+			then:    "should create the job",
+			allowed: true,
+		},
+		{
+			name:      "Undefined queue with enforcement",
+			given:     "no definition for the queue and enforcement on",
+			when:      "creating a job",
+			then:      "should return ErrQueueNotDefined",
+			enforce:   true,
+			allowed:   true,
+			expectErr: queue.ErrQueueNotDefined,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := new(MockJobRepository)
+			repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			queueSvc := new(MockQueueService)
+			queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			metrics := new(MockMetricsService)
+			metrics.On("RecordJobCreated", "default", "email").Return()
+			definitions := new(MockDefinitionRepository)
+			if tt.definition != nil {
+				definitions.On("Get", mock.Anything, "default").Return(tt.definition, nil)
+			} else {
+				definitions.On("Get", mock.Anything, "default").Return(nil, queue.ErrQueueNotDefined)
+			}
+			service := NewService(repo, queueSvc, metrics)
+			service.SetQueueDefinitions(definitions, tt.enforce)
+			service.SetQueueRateLimiter(&StaticBucketLimiter{result: &ratelimit.Result{Allowed: tt.allowed, RetryAfter: time.Second}})
+
+			cmd := CreateJobCommand{
+				Queue:   "default",
+				Type:    "email",
+				Payload: map[string]any{"to": "test@example.com"},
+			}
+			if tt.untyped {
+				cmd.Type = ""
+			}
+			if tt.payload != nil {
+				cmd.Payload = tt.payload
+			}
+
+			// When
+			job, err := service.CreateJob(context.Background(), cmd)
+
+			// Then
+			var limited *RateLimitedError
+			switch {
+			case tt.expectLimited:
+				assert.ErrorAs(t, err, &limited)
+				assert.Equal(t, time.Second, limited.RetryAfter)
+			case tt.expectErr != nil:
+				assert.ErrorIs(t, err, tt.expectErr)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, "email", job.Type)
+				return
+			}
+			assert.Nil(t, job)
+			repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_UpdateQueueDefinition(t *testing.T) {
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		given     string
+		when      string
+		then      string
+		def       *queue.Definition
+		getErr    error
+		expectErr error
+	}{
+		{
+			name:  "Replace settings",
+			given: "an existing queue definition",
+			when:  "updating it",
+			then:  "should store the new settings and keep the creation time",
+			def:   &queue.Definition{Name: "emails", MaxAttempts: 8, Paused: true},
+		},
+		{
+			name:      "Unknown queue",
+			given:     "no definition for the queue",
+			when:      "updating it",
+			then:      "should return ErrQueueNotDefined",
+			def:       &queue.Definition{Name: "emails"},
+			getErr:    queue.ErrQueueNotDefined,
+			expectErr: queue.ErrQueueNotDefined,
+		},
+		{
+			name:      "Invalid settings",
+			given:     "an existing queue definition",
+			when:      "updating it with a negative rate limit",
+			then:      "should return ErrInvalidDefinition",
+			def:       &queue.Definition{Name: "emails", RateLimitPerSecond: -1},
+			expectErr: queue.ErrInvalidDefinition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			definitions := new(MockDefinitionRepository)
+			if tt.getErr != nil {
+				definitions.On("Get", mock.Anything, "emails").Return(nil, tt.getErr)
+			} else {
+				definitions.On("Get", mock.Anything, "emails").Return(&queue.Definition{Name: "emails", CreatedAt: createdAt}, nil)
+			}
+			definitions.On("Update", mock.Anything, mock.AnythingOfType("*queue.Definition")).Return(nil)
+			service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+			service.SetQueueDefinitions(definitions, false)
+
+			// When
+			err := service.UpdateQueueDefinition(context.Background(), tt.def)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				definitions.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, createdAt, tt.def.CreatedAt)
+			assert.False(t, tt.def.UpdatedAt.IsZero())
+			definitions.AssertCalled(t, "Update", mock.Anything, tt.def)
+		})
+	}
+}
+
+func TestService_ListQueues(t *testing.T) {
+	emails := &queue.Definition{Name: "emails", MaxAttempts: 5}
+	reports := &queue.Definition{Name: "reports"}
+
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		noCounter     bool
+		noDefinitions bool
+		counts        []*queue.QueueStats
+		defs          []*queue.Definition
+		expected      []*QueueSummary
+		expectErr     error
+	}{
+		{
+			name:   "Defined queues and queues with jobs",
+			given:  "jobs in the default and emails queues, and definitions of emails and reports",
+			when:   "listing queues",
+			then:   "should return the three queues by name, reports with zero counts",
+			counts: []*queue.QueueStats{{Queue: "default", Pending: 2}, {Queue: "emails", Failed: 1}},
+			defs:   []*queue.Definition{emails, reports},
+			expected: []*QueueSummary{
+				{Name: "default", Counts: &queue.QueueStats{Queue: "default", Pending: 2}},
+				{Name: "emails", Definition: emails, Counts: &queue.QueueStats{Queue: "emails", Failed: 1}},
+				{Name: "reports", Definition: reports, Counts: &queue.QueueStats{Queue: "reports"}},
+			},
+		},
+		{
+			name:          "Definitions disabled",
+			given:         "jobs in the default queue and no definition repository",
+			when:          "listing queues",
+			then:          "should return the queues with jobs",
+			noDefinitions: true,
+			counts:        []*queue.QueueStats{{Queue: "default", Processing: 1}},
+			expected: []*QueueSummary{
+				{Name: "default", Counts: &queue.QueueStats{Queue: "default", Processing: 1}},
+			},
+		},
+		{
+			name:      "Counts disabled",
+			given:     "no queue counter",
+			when:      "listing queues",
+			then:      "should return ErrQueueCountsDisabled",
+			noCounter: true,
+			expectErr: ErrQueueCountsDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+			if !tt.noCounter {
+				counter := new(MockQueueStatsRepository)
+				counter.On("CountByQueue", mock.Anything).Return(tt.counts, nil)
+				service.SetQueueCounter(counter)
+			}
+			if !tt.noDefinitions {
+				definitions := new(MockDefinitionRepository)
+				definitions.On("List", mock.Anything).Return(tt.defs, nil)
+				service.SetQueueDefinitions(definitions, false)
+			}
+
+			// When
+			queues, err := service.ListQueues(context.Background())
+
+			// Then
+			assert.ErrorIs(t, err, tt.expectErr)
+			assert.Equal(t, tt.expected, queues)
+		})
+	}
+}
+
+func TestService_ListQueueJobs(t *testing.T) {
+	// Given
+	listing := queue.QueueListing{Queue: "emails", Status: queue.StatusFailed, Limit: 10}
+	jobs := []*queue.Job{{ID: uuid.New(), Queue: "emails", Status: queue.StatusFailed}}
+	mockRepo := new(MockJobRepository)
+	mockRepo.On("ListByQueue", mock.Anything, listing).Return(jobs, int64(12), nil)
+	service := NewService(mockRepo, new(MockQueueService), new(MockMetricsService))
+
+	// When
+	result, total, err := service.ListQueueJobs(context.Background(), listing)
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, jobs, result)
+	assert.Equal(t, int64(12), total)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_DrainQueue(t *testing.T) {
+	jobID := uuid.New()
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		draining       bool
+		deliveryStats  []*queue.DeliveryStats
+		action         func(*Service) error
+		expectErr      error
+		expectUpdate   bool
+		expectEnqueued bool
+	}{
+		{
+			name:          "Start draining",
+			given:         "a queue with jobs waiting and in flight",
+			when:          "draining it",
+			then:          "should mark it draining and report it isn't drained yet",
+			deliveryStats: []*queue.DeliveryStats{{Queue: "emails", Ready: 3, Unacked: 1}, {Queue: "reports", Ready: 9}},
+			action: func(s *Service) error {
+				status, err := s.DrainQueue(context.Background(), "emails")
+				if err == nil && (status.Ready != 3 || status.InFlight != 1 || status.Drained()) {
+					return errors.New("unexpected drain status")
+				}
+				return err
+			},
+			expectUpdate: true,
+		},
+		{
+			name:     "Drain an already draining queue",
+			given:    "a draining queue with no jobs left",
+			when:     "draining it again",
+			then:     "should report it drained without updating the definition",
+			draining: true,
+			action: func(s *Service) error {
+				status, err := s.DrainQueue(context.Background(), "emails")
+				if err == nil && !status.Drained() {
+					return errors.New("expected queue to be drained")
+				}
+				return err
+			},
+		},
+		{
+			name:     "Retry a job in a draining queue",
+			given:    "a failed job in a draining queue",
+			when:     "retrying it",
+			then:     "should return ErrQueueDraining without re-enqueueing it",
+			draining: true,
+			action: func(s *Service) error {
+				return s.RetryJob(context.Background(), jobID, 3)
+			},
+			expectErr: queue.ErrQueueDraining,
+		},
+		{
+			name:  "Retry a job in a queue that accepts jobs",
+			given: "a failed job in a queue that isn't draining",
+			when:  "retrying it",
+			then:  "should re-enqueue it",
+			action: func(s *Service) error {
+				return s.RetryJob(context.Background(), jobID, 3)
+			},
+			expectEnqueued: true,
+		},
+		{
+			name:     "Release parked jobs of a draining queue",
+			given:    "a parked job in a draining queue",
+			when:     "releasing the queue's parked jobs",
+			then:     "should return ErrQueueDraining without re-enqueueing it",
+			draining: true,
+			action: func(s *Service) error {
+				s.SetParkedJobs(StaticParkedJobs{{ID: jobID, Queue: "emails", Type: "email", Status: queue.StatusParked, Attempts: 1}})
+				_, err := s.ReleaseParkedJobs(context.Background(), "emails", 0)
+				return err
+			},
+			expectErr: queue.ErrQueueDraining,
+		},
+		{
+			name:  "Release parked jobs of a queue that accepts jobs",
+			given: "a parked job in a queue that isn't draining",
+			when:  "releasing the queue's parked jobs",
+			then:  "should retry it and re-enqueue it",
+			action: func(s *Service) error {
+				job := &queue.Job{ID: jobID, Queue: "emails", Type: "email", Status: queue.StatusParked, Attempts: 1}
+				s.SetParkedJobs(StaticParkedJobs{job})
+				release, err := s.ReleaseParkedJobs(context.Background(), "emails", 0)
+				if err == nil && (release.Released != 1 || job.Status != queue.StatusRetrying) {
+					return errors.New("expected the parked job to be released")
+				}
+				return err
+			},
+			expectEnqueued: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			definitions := new(MockDefinitionRepository)
+			definitions.On("Get", mock.Anything, "emails").Return(&queue.Definition{Name: "emails", Draining: tt.draining}, nil)
+			definitions.On("Update", mock.Anything, mock.AnythingOfType("*queue.Definition")).Return(nil)
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("GetByIDForUpdate", mock.Anything, jobID).Return(&queue.Job{ID: jobID, Queue: "emails", Type: "email", Status: queue.StatusFailed, Attempts: 1}, nil)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("DeliveryStats", mock.Anything).Return(tt.deliveryStats, nil)
+			mockQueueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockMetrics := new(MockMetricsService)
+			mockMetrics.On("RecordJobRetried", "emails", "email").Return()
+			service := NewService(mockRepo, mockQueueSvc, mockMetrics)
+			service.SetQueueDefinitions(definitions, false)
+
+			// When
+			err := tt.action(service)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.expectUpdate {
+				definitions.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(def *queue.Definition) bool {
+					return def.Draining
+				}))
+			} else {
+				definitions.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			}
+			if tt.expectEnqueued {
+				mockQueueSvc.AssertCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			} else {
+				mockQueueSvc.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type MockQuotaEnforcer struct {
+	mock.Mock
+}
+
+func (m *MockQuotaEnforcer) ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID) error {
+	args := m.Called(ctx, tenant, jobID)
+	return args.Error(0)
+}
+
+func (m *MockQuotaEnforcer) ReleaseJob(ctx context.Context, jobID uuid.UUID) error {
+	args := m.Called(ctx, jobID)
+	return args.Error(0)
+}
+
+func (m *MockQuotaEnforcer) ReserveAnalysis(ctx context.Context, tenant string) error {
+	args := m.Called(ctx, tenant)
+	return args.Error(0)
+}
+
+func (m *MockQuotaEnforcer) TenantOf(ctx context.Context, jobID uuid.UUID) (string, error) {
+	args := m.Called(ctx, jobID)
+	return args.String(0), args.Error(1)
+}
+
+func TestService_CreateJob_Quota(t *testing.T) {
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		reserveErr    error
+		createErr     error
+		expectErr     error
+		expectCreate  bool
+		expectRelease bool
+	}{
+		{
+			name:         "Within quota",
+			given:        "an API key with quota left",
+			when:         "creating a job",
+			then:         "should count the job and create it",
+			expectCreate: true,
+		},
+		{
+			name:       "Over quota",
+			given:      "an API key that used up its jobs per day",
+			when:       "creating a job",
+			then:       "should return ErrQuotaExceeded without creating the job",
+			reserveErr: quota.NewExceededError(quota.ResourceJobs, 10, time.Now()),
+			expectErr:  quota.ErrQuotaExceeded,
+		},
+		{
+			name:          "Job not persisted",
+			given:         "an API key with quota left and a failing repository",
+			when:          "creating a job",
+			then:          "should release the reserved pending job",
+			createErr:     errors.New("connection refused"),
+			expectErr:     errors.New("connection refused"),
+			expectCreate:  true,
+			expectRelease: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := new(MockJobRepository)
+			repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(tt.createErr)
+			queueSvc := new(MockQueueService)
+			queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			metrics := new(MockMetricsService)
+			metrics.On("RecordJobCreated", "default", "email").Return()
+			quotas := new(MockQuotaEnforcer)
+			quotas.On("ReserveJob", mock.Anything, "team-a", mock.AnythingOfType("uuid.UUID")).Return(tt.reserveErr)
+			quotas.On("ReleaseJob", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil)
+			service := NewService(repo, queueSvc, metrics)
+			service.SetJobOwners(queue.Owners{"key-a": "team-a"})
+			service.SetQuotaEnforcer(quotas)
+
+			// When
+			job, err := service.CreateJob(context.Background(), CreateJobCommand{
+				Queue:   "default",
+				Type:    "email",
+				Payload: map[string]any{"to": "test@example.com"},
+				APIKey:  "key-a",
+			})
+
+			// Then
+			if tt.expectErr != nil {
+				if errors.Is(tt.expectErr, quota.ErrQuotaExceeded) {
+					assert.ErrorIs(t, err, tt.expectErr)
+				} else {
+					assert.EqualError(t, err, tt.expectErr.Error())
+				}
+				assert.Nil(t, job)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, job)
+			}
+			if tt.expectCreate {
+				repo.AssertCalled(t, "Create", mock.Anything, mock.Anything)
+			} else {
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			}
+			if tt.expectRelease {
+				quotas.AssertCalled(t, "ReleaseJob", mock.Anything, mock.Anything)
+			} else {
+				quotas.AssertNotCalled(t, "ReleaseJob", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_CreateJob_Interceptors(t *testing.T) {
+	existing := &queue.Job{ID: uuid.New(), Queue: "default", Type: "email", Status: queue.StatusPending}
+
+	tests := []struct {
+		name         string
+		given        string
+		when         string
+		then         string
+		interceptor  CreateInterceptor
+		createErr    error
+		expectErr    string
+		expectJob    *queue.Job
+		expectCreate bool
+		expectCalls  []string
+	}{
+		{
+			name:  "Enrich the job",
+			given: "an interceptor adding a field to the payload",
+			when:  "creating a job",
+			then:  "should run the interceptors in order and store the enriched job, counted by the quota",
+			interceptor: func(next CreateFunc) CreateFunc {
+				return func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+					job.Payload = []byte(`{"to":"test@example.com","tenant":"acme"}`)
+					return next(ctx, cmd, job)
+				}
+			},
+			expectCreate: true,
+			expectCalls:  []string{"first", "second", "reserve"},
+		},
+		{
+			name:  "Reject the job",
+			given: "an interceptor rejecting the job",
+			when:  "creating a job",
+			then:  "should return its error without reserving quota or storing the job",
+			interceptor: func(next CreateFunc) CreateFunc {
+				return func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+					return nil, errors.New("payload rejected")
+				}
+			},
+			expectErr:   "payload rejected",
+			expectCalls: []string{"first"},
+		},
+		{
+			name:  "Answer with a duplicate",
+			given: "an interceptor that found a duplicate created before",
+			when:  "creating a job",
+			then:  "should return the duplicate without storing a new job",
+			interceptor: func(next CreateFunc) CreateFunc {
+				return func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+					return existing, nil
+				}
+			},
+			expectJob:   existing,
+			expectCalls: []string{"first"},
+		},
+		{
+			name:  "Job not stored",
+			given: "an interceptor and a failing repository",
+			when:  "creating a job",
+			then:  "should let the interceptors see the error and release the reserved quota",
+			interceptor: func(next CreateFunc) CreateFunc {
+				return next
+			},
+			createErr:    errors.New("connection refused"),
+			expectErr:    "connection refused",
+			expectCreate: true,
+			expectCalls:  []string{"first", "second", "reserve", "release", "second failed", "first failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			var calls []string
+			record := func(name string, next CreateFunc) CreateFunc {
+				return func(ctx context.Context, cmd CreateJobCommand, job *queue.Job) (*queue.Job, error) {
+					calls = append(calls, name)
+					created, err := next(ctx, cmd, job)
+					if err != nil && tt.createErr != nil {
+						calls = append(calls, name+" failed")
+					}
+					return created, err
+				}
+			}
+			repo := new(MockJobRepository)
+			repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(tt.createErr)
+			queueSvc := new(MockQueueService)
+			queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			metrics := new(MockMetricsService)
+			metrics.On("RecordJobCreated", "default", "email").Return()
+			quotas := new(MockQuotaEnforcer)
+			quotas.On("ReserveJob", mock.Anything, "team-a", mock.AnythingOfType("uuid.UUID")).
+				Run(func(mock.Arguments) { calls = append(calls, "reserve") }).Return(nil)
+			quotas.On("ReleaseJob", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+				Run(func(mock.Arguments) { calls = append(calls, "release") }).Return(nil)
+			service := NewService(repo, queueSvc, metrics)
+			service.SetJobOwners(queue.Owners{"key-a": "team-a"})
+			service.SetQuotaEnforcer(quotas)
+			service.SetCreateInterceptors(
+				func(next CreateFunc) CreateFunc { return record("first", tt.interceptor(next)) },
+				func(next CreateFunc) CreateFunc { return record("second", next) },
+			)
+
+			// When
+			job, err := service.CreateJob(context.Background(), CreateJobCommand{
+				Queue:   "default",
+				Type:    "email",
+				Payload: map[string]any{"to": "test@example.com"},
+				APIKey:  "key-a",
+			})
+
+			// Then
+			if tt.expectErr != "" {
+				assert.EqualError(t, err, tt.expectErr)
+				assert.Nil(t, job)
+			} else {
+				require.NoError(t, err)
+				if tt.expectJob != nil {
+					assert.Same(t, tt.expectJob, job)
+				}
+			}
+			assert.Equal(t, tt.expectCalls, calls)
+			if tt.expectCreate {
+				repo.AssertCalled(t, "Create", mock.Anything, mock.Anything)
+			} else {
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			}
+			if tt.expectCreate && tt.createErr == nil {
+				assert.JSONEq(t, `{"to":"test@example.com","tenant":"acme"}`, string(job.Payload))
+				metrics.AssertCalled(t, "RecordJobCreated", "default", "email")
+			} else {
+				metrics.AssertNotCalled(t, "RecordJobCreated", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type MockQueueInspector struct {
+	mock.Mock
+}
+
+func (m *MockQueueInspector) Snapshot(ctx context.Context, queueName string) (queue.QueueSnapshot, error) {
+	args := m.Called(ctx, queueName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(queue.QueueSnapshot), args.Error(1)
+}
+
+func TestService_CheckConsistency(t *testing.T) {
+	old := time.Now().UTC().Add(-time.Hour)
+	queued := &queue.Job{ID: uuid.New(), Queue: "emails", Status: queue.StatusPending, UpdatedAt: old}
+	lost := &queue.Job{ID: uuid.New(), Queue: "emails", Status: queue.StatusPending, UpdatedAt: old}
+
+	tests := []struct {
+		name             string
+		given            string
+		when             string
+		then             string
+		repair           bool
+		secondSnapshot   queue.QueueSnapshot
+		current          *queue.Job
+		expectStranded   int
+		expectEnqueued   bool
+		expectRepaired   int
+		expectDisabled   bool
+		withoutInspector bool
+	}{
+		{
+			name:           "Report stranded job",
+			given:          "a pending job the queue backend doesn't hold",
+			when:           "checking consistency",
+			then:           "should report it without re-enqueueing it",
+			secondSnapshot: queue.QueueSnapshot{queued.ID: {}},
+			current:        lost,
+			expectStranded: 1,
+		},
+		{
+			name:           "Repair stranded job",
+			given:          "a pending job the queue backend doesn't hold",
+			when:           "checking consistency with repair",
+			then:           "should re-enqueue it",
+			repair:         true,
+			secondSnapshot: queue.QueueSnapshot{queued.ID: {}},
+			current:        lost,
+			expectStranded: 1,
+			expectEnqueued: true,
+			expectRepaired: 1,
+		},
+		{
+			name:           "Job picked up during the check",
+			given:          "a pending job missing from the first snapshot that a worker was dequeueing",
+			when:           "checking consistency with repair",
+			then:           "should not report it once the second snapshot holds it",
+			repair:         true,
+			secondSnapshot: queue.QueueSnapshot{queued.ID: {}, lost.ID: {}},
+			current:        lost,
+		},
+		{
+			name:           "Job processed during the check",
+			given:          "a pending job missing from both snapshots that completed meanwhile",
+			when:           "checking consistency with repair",
+			then:           "should not report it",
+			repair:         true,
+			secondSnapshot: queue.QueueSnapshot{queued.ID: {}},
+			current:        &queue.Job{ID: lost.ID, Queue: "emails", Status: queue.StatusCompleted, UpdatedAt: time.Now().UTC()},
+		},
+		{
+			name:             "No inspector",
+			given:            "a service without a queue inspector",
+			when:             "checking consistency",
+			then:             "should return ErrConsistencyCheckDisabled",
+			withoutInspector: true,
+			expectDisabled:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("FindPendingJobs", mock.Anything, "emails", DefaultConsistencyLimit).Return([]*queue.Job{queued, lost}, nil)
+			mockRepo.On("GetByID", mock.Anything, lost.ID).Return(tt.current, nil)
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			inspector := new(MockQueueInspector)
+			inspector.On("Snapshot", mock.Anything, "emails").Return(queue.QueueSnapshot{queued.ID: {}}, nil).Once()
+			inspector.On("Snapshot", mock.Anything, "emails").Return(tt.secondSnapshot, nil).Once()
+			service := NewService(mockRepo, mockQueueSvc, new(MockMetricsService))
+			if !tt.withoutInspector {
+				service.SetQueueInspector(inspector)
+			}
+
+			// When
+			report, err := service.CheckConsistency(context.Background(), ConsistencyCheck{Queue: "emails", Repair: tt.repair})
+
+			// Then
+			if tt.expectDisabled {
+				assert.ErrorIs(t, err, ErrConsistencyCheckDisabled)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 2, report.Checked)
+			assert.Len(t, report.Stranded, tt.expectStranded)
+			assert.Equal(t, tt.expectRepaired, report.Repaired)
+			if tt.expectEnqueued {
+				mockQueueSvc.AssertCalled(t, "Enqueue", mock.Anything, lost)
+			} else {
+				mockQueueSvc.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type MockDeadLetterQueue struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterQueue) DeadLetter(ctx context.Context, job *queue.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *MockDeadLetterQueue) PeekDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	args := m.Called(ctx, queueName, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.Job), args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) PopDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	args := m.Called(ctx, queueName, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.Job), args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) RestoreDeadLetters(ctx context.Context, queueName string, jobs []*queue.Job) error {
+	args := m.Called(ctx, queueName, jobs)
+	return args.Error(0)
+}
+
+func (m *MockDeadLetterQueue) CountDeadLetters(ctx context.Context, queueName string) (int64, error) {
+	args := m.Called(ctx, queueName)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestService_ReplayDeadLetters(t *testing.T) {
+	letter := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "smtp", Status: queue.StatusFailed, Attempts: 3}
+
+	tests := []struct {
+		name               string
+		given              string
+		when               string
+		then               string
+		current            *queue.Job
+		enqueueErr         error
+		withoutDeadLetters bool
+		expectErr          error
+		expectReplayed     int
+		expectSkipped      string
+		expectRestored     bool
+	}{
+		{
+			name:           "Replay failed job",
+			given:          "a dead letter whose job used up its attempts and is still failed",
+			when:           "replaying the queue's dead letters",
+			then:           "should mark the job retrying and enqueue it",
+			current:        &queue.Job{ID: letter.ID, Queue: "emails", Type: "smtp", Status: queue.StatusFailed, Attempts: 3},
+			expectReplayed: 1,
+		},
+		{
+			name:          "Job retried meanwhile",
+			given:         "a dead letter whose job was retried through the API since it failed",
+			when:          "replaying the queue's dead letters",
+			then:          "should drop it without running the job again",
+			current:       &queue.Job{ID: letter.ID, Queue: "emails", Type: "smtp", Status: queue.StatusCompleted},
+			expectSkipped: ReplaySkippedNotFailed,
+		},
+		{
+			name:           "Queue unavailable",
+			given:          "a dead letter whose job can't be enqueued",
+			when:           "replaying the queue's dead letters",
+			then:           "should return the error and put the dead letter back",
+			current:        &queue.Job{ID: letter.ID, Queue: "emails", Type: "smtp", Status: queue.StatusFailed, Attempts: 3},
+			enqueueErr:     errors.New("connection refused"),
+			expectErr:      errors.New("connection refused"),
+			expectRestored: true,
+		},
+		{
+			name:               "No dead letter queue",
+			given:              "a service without a dead letter queue",
+			when:               "replaying dead letters",
+			then:               "should return ErrDeadLettersDisabled",
+			withoutDeadLetters: true,
+			expectErr:          ErrDeadLettersDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("GetByIDForUpdate", mock.Anything, letter.ID).Return(tt.current, nil)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(tt.enqueueErr)
+			mockMetrics := new(MockMetricsService)
+			mockMetrics.On("RecordJobRetried", "emails", "smtp").Return()
+			deadLetters := new(MockDeadLetterQueue)
+			deadLetters.On("PopDeadLetters", mock.Anything, "emails", 5).Return([]*queue.Job{letter}, nil)
+			deadLetters.On("RestoreDeadLetters", mock.Anything, "emails", []*queue.Job{letter}).Return(nil)
+			deadLetters.On("CountDeadLetters", mock.Anything, "emails").Return(int64(0), nil)
+			service := NewService(mockRepo, mockQueueSvc, mockMetrics)
+			if !tt.withoutDeadLetters {
+				service.SetDeadLetterQueue(deadLetters)
+			}
+
+			// When
+			report, err := service.ReplayDeadLetters(context.Background(), "emails", 5)
+
+			// Then
+			if tt.expectRestored {
+				deadLetters.AssertCalled(t, "RestoreDeadLetters", mock.Anything, "emails", []*queue.Job{letter})
+			} else {
+				deadLetters.AssertNotCalled(t, "RestoreDeadLetters", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectErr != nil {
+				assert.ErrorContains(t, err, tt.expectErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectReplayed, report.Replayed)
+			assert.Len(t, report.Jobs, 1)
+			assert.Equal(t, tt.expectSkipped, report.Jobs[0].Skipped)
+			if tt.expectReplayed > 0 {
+				assert.Equal(t, queue.StatusRetrying, tt.current.Status)
+				mockQueueSvc.AssertCalled(t, "Enqueue", mock.Anything, tt.current)
+			} else {
+				mockQueueSvc.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type FakeLeaderElector struct {
+	leads     bool
+	campaigns int
+	resigned  bool
+}
+
+func (e *FakeLeaderElector) Campaign(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	e.campaigns++
+	return e.leads, nil
+}
+
+func (e *FakeLeaderElector) Resign(ctx context.Context, name, holder string) error {
+	e.resigned = true
+	return nil
+}
+
+func TestService_PromoteDueJobs(t *testing.T) {
+	dueAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		current         *queue.Job
+		enqueueErr      error
+		expectPromoted  int
+		expectEnqueued  bool
+		expectScheduled bool
+	}{
+		{
+			name:           "Due delayed job",
+			given:          "a delayed job whose time has come",
+			when:           "promoting due jobs",
+			then:           "should clear its schedule and enqueue it",
+			current:        &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, ScheduledFor: &dueAt},
+			expectPromoted: 1,
+			expectEnqueued: true,
+		},
+		{
+			name:    "Job promoted meanwhile",
+			given:   "a due job another instance promoted since it was listed",
+			when:    "promoting due jobs",
+			then:    "should skip it without enqueueing it again",
+			current: &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusProcessing},
+		},
+		{
+			name:            "Job deleted meanwhile",
+			given:           "a due job soft deleted since it was listed",
+			when:            "promoting due jobs",
+			then:            "should skip it",
+			current:         &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, ScheduledFor: &dueAt, DeletedAt: &dueAt},
+			expectScheduled: true,
+		},
+		{
+			name:           "Queue unavailable",
+			given:          "a due job that can't be enqueued",
+			when:           "promoting due jobs",
+			then:           "should not count it and leave it for the consistency check",
+			current:        &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, ScheduledFor: &dueAt},
+			enqueueErr:     errors.New("connection refused"),
+			expectEnqueued: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			candidate := &queue.Job{ID: tt.current.ID, Queue: "emails", Status: queue.StatusPending, ScheduledFor: &dueAt}
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("FindDueDelayedJobs", mock.Anything, mock.AnythingOfType("time.Time"), SchedulerBatch).Return([]*queue.Job{candidate}, nil)
+			mockRepo.On("GetByIDForUpdate", mock.Anything, candidate.ID).Return(tt.current, nil)
+			mockRepo.On("Update", mock.Anything, tt.current).Return(nil)
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("Enqueue", mock.Anything, tt.current).Return(tt.enqueueErr)
+			unitOfWork := &FakeUnitOfWork{}
+			service := NewService(mockRepo, mockQueueSvc, new(MockMetricsService))
+			service.SetUnitOfWork(unitOfWork)
+
+			// When
+			promoted, err := service.PromoteDueJobs(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectPromoted, promoted)
+			assert.Equal(t, tt.expectScheduled, tt.current.ScheduledFor != nil)
+			if tt.expectEnqueued {
+				mockRepo.AssertCalled(t, "Update", mock.Anything, tt.current)
+				mockQueueSvc.AssertCalled(t, "Enqueue", mock.Anything, tt.current)
+			} else {
+				mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				mockQueueSvc.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_RunScheduler(t *testing.T) {
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		leads         bool
+		expectResign  bool
+		expectPromote bool
+	}{
+		{
+			name:          "Leader",
+			given:         "an instance holding the scheduler lease",
+			when:          "running the scheduler until it is stopped",
+			then:          "should promote due jobs and resign the lease",
+			leads:         true,
+			expectResign:  true,
+			expectPromote: true,
+		},
+		{
+			name:  "Follower",
+			given: "an instance another one holds the scheduler lease against",
+			when:  "running the scheduler until it is stopped",
+			then:  "should leave promotion to the leader",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("FindDueDelayedJobs", mock.Anything, mock.AnythingOfType("time.Time"), SchedulerBatch).Return([]*queue.Job{}, nil)
+			elector := &FakeLeaderElector{leads: tt.leads}
+			service := NewService(mockRepo, new(MockQueueService), new(MockMetricsService))
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			// When
+			service.RunScheduler(ctx, elector, "queue-core-1", 10*time.Millisecond)
+
+			// Then
+			assert.Greater(t, elector.campaigns, 1)
+			assert.Equal(t, tt.expectResign, elector.resigned)
+			if tt.expectPromote {
+				mockRepo.AssertCalled(t, "FindDueDelayedJobs", mock.Anything, mock.Anything, SchedulerBatch)
+			} else {
+				mockRepo.AssertNotCalled(t, "FindDueDelayedJobs", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type MockQueueWithdrawer struct {
+	mock.Mock
+}
+
+func (m *MockQueueWithdrawer) Withdraw(ctx context.Context, job *queue.Job) (bool, error) {
+	args := m.Called(ctx, job)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestService_EditJob(t *testing.T) {
+	signer, _ := queue.NewPayloadSigner("default-secret", nil, false)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		current        func() *queue.Job
+		command        EditJobCommand
+		withdrawn      bool
+		updateErr      error
+		expectErr      error
+		expectWithdraw bool
+		expectEnqueues int
+		validateJob    func(*testing.T, *queue.Job)
+	}{
+		{
+			name:  "Edit signed queued job",
+			given: "a signed pending job waiting in its queue",
+			when:  "editing its payload at its current version",
+			then:  "should re-sign it, withdraw the old copy and enqueue the new one",
+			current: func() *queue.Job {
+				job := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, Payload: []byte(`{"to":"a"}`), Version: 3}
+				signer.Sign(job, "")
+				return job
+			},
+			command:        EditJobCommand{Payload: map[string]any{"to": "b"}, Version: 3},
+			withdrawn:      true,
+			expectWithdraw: true,
+			expectEnqueues: 1,
+			validateJob: func(t *testing.T, job *queue.Job) {
+				assert.JSONEq(t, `{"to":"b"}`, string(job.Payload))
+				assert.NoError(t, signer.Verify(job))
+			},
+		},
+		{
+			name:  "Reschedule delayed job",
+			given: "a delayed job, which isn't in the queue backend yet",
+			when:  "rescheduling it",
+			then:  "should update it without touching the queue backend",
+			current: func() *queue.Job {
+				at := time.Now().Add(time.Minute)
+				return &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, ScheduledFor: &at}
+			},
+			command: EditJobCommand{ScheduledFor: &future},
+			validateJob: func(t *testing.T, job *queue.Job) {
+				assert.True(t, job.IsDelayed())
+				assert.WithinDuration(t, future, *job.ScheduledFor, time.Millisecond)
+			},
+		},
+		{
+			name:  "Job dequeued meanwhile",
+			given: "a pending job a worker dequeued since it was read",
+			when:  "editing its payload",
+			then:  "should return ErrJobNotEditable without updating it",
+			current: func() *queue.Job {
+				return &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending}
+			},
+			command:        EditJobCommand{Payload: map[string]any{"to": "b"}},
+			expectErr:      queue.ErrJobNotEditable,
+			expectWithdraw: true,
+		},
+		{
+			name:  "Database unavailable",
+			given: "a queued job whose update fails",
+			when:  "editing its payload",
+			then:  "should return the error and put the original job back in its queue",
+			current: func() *queue.Job {
+				return &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, Payload: []byte(`{"to":"a"}`)}
+			},
+			command:        EditJobCommand{Payload: map[string]any{"to": "b"}},
+			withdrawn:      true,
+			updateErr:      errors.New("connection refused"),
+			expectErr:      errors.New("connection refused"),
+			expectWithdraw: true,
+			expectEnqueues: 1,
+		},
+		{
+			name:  "Stale version",
+			given: "a job updated since the caller read it",
+			when:  "editing it with the old version",
+			then:  "should return ErrVersionConflict",
+			current: func() *queue.Job {
+				return &queue.Job{ID: uuid.New(), Queue: "emails", Type: "email", Status: queue.StatusPending, Version: 2}
+			},
+			command:   EditJobCommand{Payload: map[string]any{"to": "b"}, Version: 1},
+			expectErr: queue.ErrVersionConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			current := tt.current()
+			original := *current
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("GetByIDForUpdate", mock.Anything, current.ID).Return(current, nil)
+			mockRepo.On("Update", mock.Anything, current).Return(tt.updateErr)
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			withdrawer := new(MockQueueWithdrawer)
+			withdrawer.On("Withdraw", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(tt.withdrawn, nil)
+			service := NewService(mockRepo, mockQueueSvc, new(MockMetricsService))
+			service.SetUnitOfWork(&FakeUnitOfWork{})
+			service.SetQueueWithdrawer(withdrawer)
+			service.SetPayloadSigner(signer)
+
+			// When
+			job, err := service.EditJob(context.Background(), current.ID, tt.command)
+
+			// Then
+			if tt.expectWithdraw {
+				withdrawer.AssertCalled(t, "Withdraw", mock.Anything, &original)
+			} else {
+				withdrawer.AssertNotCalled(t, "Withdraw", mock.Anything, mock.Anything)
+			}
+			mockQueueSvc.AssertNumberOfCalls(t, "Enqueue", tt.expectEnqueues)
+			if tt.expectErr != nil {
+				assert.ErrorContains(t, err, tt.expectErr.Error())
+				if tt.updateErr != nil {
+					mockQueueSvc.AssertCalled(t, "Enqueue", mock.Anything, &original)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			if tt.validateJob != nil {
+				tt.validateJob(t, job)
+			}
+		})
+	}
+}
+
+func TestService_EditJob_Disabled(t *testing.T) {
+	// Given
+	service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+
+	// When
+	_, err := service.EditJob(context.Background(), uuid.New(), EditJobCommand{Payload: map[string]any{}})
+
+	// Then
+	assert.ErrorIs(t, err, ErrJobEditsDisabled)
+}
+
+// FakeQueuePurger removes the jobs it holds, as a queue backend would
+type FakeQueuePurger struct {
+	queued map[uuid.UUID]bool
+	err    error
+}
+
+func (p *FakeQueuePurger) BeginPurge(ctx context.Context, queueName string) (queue.QueuePurge, error) {
+	return p, nil
+}
+
+func (p *FakeQueuePurger) Purge(ctx context.Context, jobs []*queue.Job) ([]uuid.UUID, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	var removed []uuid.UUID
+	for _, job := range jobs {
+		if p.queued[job.ID] {
+			delete(p.queued, job.ID)
+			removed = append(removed, job.ID)
+		}
+	}
+	return removed, nil
+}
+
+// StaticParkedJobs finds the parked jobs it holds
+type StaticParkedJobs []*queue.Job
+
+func (p StaticParkedJobs) FindParked(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	var jobs []*queue.Job
+	for _, job := range p {
+		if job.Queue == queueName && job.Status == queue.StatusParked && len(jobs) < limit {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// FakePurgeRepository keeps the queue's jobs in memory
+type FakePurgeRepository struct {
+	jobs      []*queue.Job
+	cancelled []*queue.Job
+	cancelErr error
+}
+
+func (r *FakePurgeRepository) CountPurgeable(ctx context.Context, queueName string, status queue.Status) (int64, error) {
+	jobs, _ := r.FindPurgeable(ctx, queueName, status, len(r.jobs))
+	return int64(len(jobs)), nil
+}
+
+func (r *FakePurgeRepository) FindPurgeable(ctx context.Context, queueName string, status queue.Status, limit int) ([]*queue.Job, error) {
+	var jobs []*queue.Job
+	for _, job := range r.jobs {
+		if job.Queue == queueName && job.Status == status && len(jobs) < limit {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *FakePurgeRepository) CancelJobs(ctx context.Context, jobs []*queue.Job) (int64, error) {
+	if r.cancelErr != nil {
+		return 0, r.cancelErr
+	}
+	for _, cancelled := range jobs {
+		for _, job := range r.jobs {
+			if job.ID == cancelled.ID {
+				job.Status, job.Error = cancelled.Status, cancelled.Error
+			}
+		}
+	}
+	r.cancelled = append(r.cancelled, jobs...)
+	return int64(len(jobs)), nil
+}
+
+func TestService_PurgeQueue(t *testing.T) {
+	later := time.Now().Add(time.Hour)
+	queued := &queue.Job{ID: uuid.New(), Queue: "emails", Status: queue.StatusPending}
+	dequeued := &queue.Job{ID: uuid.New(), Queue: "emails", Status: queue.StatusPending}
+	delayed := &queue.Job{ID: uuid.New(), Queue: "emails", Status: queue.StatusPending, ScheduledFor: &later}
+	retrying := &queue.Job{ID: uuid.New(), Queue: "emails", Status: queue.StatusRetrying}
+	other := &queue.Job{ID: uuid.New(), Queue: "reports", Status: queue.StatusPending}
+
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		status          queue.Status
+		dryRun          bool
+		purgeErr        error
+		cancelErr       error
+		expectErr       error
+		expectReport    *PurgeReport
+		expectCancelled []uuid.UUID
+		expectEnqueues  int
+	}{
+		{
+			name:         "Dry run",
+			given:        "a queue with three pending jobs",
+			when:         "purging it as a dry run",
+			then:         "should count the pending jobs and cancel none",
+			dryRun:       true,
+			expectReport: &PurgeReport{Queue: "emails", Status: queue.StatusPending, DryRun: true, Matched: 3},
+		},
+		{
+			name:            "Purge pending jobs",
+			given:           "a queued, a delayed and a dequeued pending job",
+			when:            "purging the queue",
+			then:            "should cancel the queued and delayed jobs and skip the dequeued one",
+			status:          queue.StatusPending,
+			expectReport:    &PurgeReport{Queue: "emails", Status: queue.StatusPending, Matched: 3, Purged: 2, Skipped: 1},
+			expectCancelled: []uuid.UUID{delayed.ID, queued.ID},
+		},
+		{
+			name:      "Retrying jobs",
+			given:     "a queue with retrying jobs",
+			when:      "purging its retrying jobs",
+			then:      "should return ErrStatusNotPurgeable",
+			status:    queue.StatusRetrying,
+			expectErr: queue.ErrStatusNotPurgeable,
+		},
+		{
+			name:      "Backend unavailable",
+			given:     "a queue backend that can't be reached",
+			when:      "purging the queue",
+			then:      "should return the error and cancel nothing",
+			purgeErr:  errors.New("connection refused"),
+			expectErr: errors.New("connection refused"),
+		},
+		{
+			name:           "Database unavailable",
+			given:          "jobs whose cancellation fails",
+			when:           "purging the queue",
+			then:           "should return the error and put the removed job back in its queue",
+			cancelErr:      errors.New("connection refused"),
+			expectErr:      errors.New("connection refused"),
+			expectEnqueues: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := &FakePurgeRepository{cancelErr: tt.cancelErr}
+			for _, job := range []*queue.Job{queued, dequeued, delayed, retrying, other} {
+				copied := *job
+				repo.jobs = append(repo.jobs, &copied)
+			}
+			purger := &FakeQueuePurger{queued: map[uuid.UUID]bool{queued.ID: true, other.ID: true}, err: tt.purgeErr}
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			service := NewService(new(MockJobRepository), mockQueueSvc, new(MockMetricsService))
+			service.SetUnitOfWork(&FakeUnitOfWork{})
+			service.SetQueuePurger(purger, repo)
+
+			// When
+			report, err := service.PurgeQueue(context.Background(), "emails", tt.status, tt.dryRun)
+
+			// Then
+			mockQueueSvc.AssertNumberOfCalls(t, "Enqueue", tt.expectEnqueues)
+			if tt.expectErr != nil {
+				assert.ErrorContains(t, err, tt.expectErr.Error())
+				assert.Empty(t, repo.cancelled)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectReport, report)
+			var cancelled []uuid.UUID
+			for _, job := range repo.cancelled {
+				assert.Equal(t, queue.StatusCancelled, job.Status)
+				assert.Equal(t, "purged from queue emails", job.Error)
+				cancelled = append(cancelled, job.ID)
+			}
+			assert.Equal(t, tt.expectCancelled, cancelled)
+		})
+	}
+}
+
+func TestService_PurgeQueue_Disabled(t *testing.T) {
+	// Given
+	service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+
+	// When
+	_, err := service.PurgeQueue(context.Background(), "emails", queue.StatusPending, false)
+
+	// Then
+	assert.ErrorIs(t, err, ErrPurgeDisabled)
+}
+
+type MockGroupRepository struct {
+	mock.Mock
+}
+
+func (m *MockGroupRepository) Create(ctx context.Context, group *queue.Group) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) Get(ctx context.Context, id uuid.UUID) (*queue.Group, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) Progress(ctx context.Context, id uuid.UUID) (queue.GroupProgress, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(queue.GroupProgress), args.Error(1)
+}
+
+func (m *MockGroupRepository) Resize(ctx context.Context, id uuid.UUID, size int) error {
+	args := m.Called(ctx, id, size)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) Finish(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	args := m.Called(ctx, id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestService_CreateGroup(t *testing.T) {
+	emailJob := CreateJobCommand{Queue: "emails", Type: "email", Payload: map[string]any{"to": "a@example.com"}}
+	onComplete := &CreateJobCommand{Queue: "reports", Type: "batch_report"}
+
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		cmd           CreateGroupCommand
+		storeFailsAt  int // Number of jobs stored before the repository fails; -1 for never
+		expectErr     error
+		expectJobs    int
+		expectResize  bool
+		expectGroup   bool
+		expectEnqueue int
+	}{
+		{
+			name:          "Group with a completion job",
+			given:         "two jobs and a completion job",
+			when:          "creating the group",
+			then:          "should tag the jobs with the group and hold the completion job",
+			cmd:           CreateGroupCommand{Jobs: []CreateJobCommand{emailJob, emailJob}, OnComplete: onComplete},
+			storeFailsAt:  -1,
+			expectJobs:    2,
+			expectGroup:   true,
+			expectEnqueue: 2,
+		},
+		{
+			name:         "Empty group",
+			given:        "no jobs",
+			when:         "creating the group",
+			then:         "should return ErrInvalidGroup and create nothing",
+			cmd:          CreateGroupCommand{},
+			storeFailsAt: -1,
+			expectErr:    queue.ErrInvalidGroup,
+		},
+		{
+			name:         "Invalid job",
+			given:        "a job without a type",
+			when:         "creating the group",
+			then:         "should return ErrInvalidType and create nothing",
+			cmd:          CreateGroupCommand{Jobs: []CreateJobCommand{emailJob, {Queue: "emails"}}},
+			storeFailsAt: -1,
+			expectErr:    queue.ErrInvalidType,
+		},
+		{
+			name:          "Job creation failing mid-way",
+			given:         "a second job the repository fails to store",
+			when:          "creating the group",
+			then:          "should cut the group down to the first job and return the error",
+			cmd:           CreateGroupCommand{Jobs: []CreateJobCommand{emailJob, emailJob, emailJob}},
+			storeFailsAt:  1,
+			expectErr:     errors.New("database error"),
+			expectJobs:    1,
+			expectResize:  true,
+			expectGroup:   true,
+			expectEnqueue: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := new(MockJobRepository)
+			if tt.storeFailsAt >= 0 {
+				mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).Times(tt.storeFailsAt)
+				mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(errors.New("database error"))
+			} else {
+				mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			}
+			mockRepo.On("Delete", mock.Anything, mock.Anything).Return(nil)
+			mockQueueSvc := new(MockQueueService)
+			mockQueueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockMetrics := new(MockMetricsService)
+			mockMetrics.On("RecordJobCreated", mock.Anything, mock.Anything).Return()
+			groups := new(MockGroupRepository)
+			groups.On("Create", mock.Anything, mock.AnythingOfType("*queue.Group")).Return(nil)
+			groups.On("Resize", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			groups.On("Finish", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+			service := NewService(mockRepo, mockQueueSvc, mockMetrics)
+			service.SetGroupRepository(groups)
+
+			// When
+			created, err := service.CreateGroup(context.Background(), tt.cmd)
+
+			// Then
+			if tt.expectErr != nil {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectErr.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			if !tt.expectGroup {
+				assert.Nil(t, created)
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				groups.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			require.NotNil(t, created)
+			require.Len(t, created.Jobs, tt.expectJobs)
+			for _, job := range created.Jobs {
+				require.NotNil(t, job.GroupID)
+				assert.Equal(t, created.Group.ID, *job.GroupID)
+			}
+			if tt.cmd.OnComplete != nil {
+				require.NotNil(t, created.CompletionJob)
+				assert.Nil(t, created.CompletionJob.GroupID)
+				assert.True(t, created.CompletionJob.ScheduledFor.Equal(queue.GroupHoldUntil))
+				assert.Equal(t, &created.CompletionJob.ID, created.Group.CompletionJobID)
+			}
+			mockQueueSvc.AssertNumberOfCalls(t, "Enqueue", tt.expectEnqueue)
+			if tt.expectResize {
+				groups.AssertCalled(t, "Resize", mock.Anything, created.Group.ID, tt.expectJobs)
+				groups.AssertCalled(t, "Finish", mock.Anything, created.Group.ID, mock.Anything)
+			} else {
+				groups.AssertNotCalled(t, "Resize", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// RecordingPublisher collects the names of published events for assertions
+type RecordingPublisher struct {
+	names []string
+}
+
+func (p *RecordingPublisher) Publish(ctx context.Context, event events.Event) {
+	p.names = append(p.names, event.Name())
+}
+
+func TestService_GetGroup(t *testing.T) {
+	groupID := uuid.New()
+	completedAt := time.Now().UTC()
+
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		completedAt   *time.Time
+		progress      queue.GroupProgress
+		finishClaimed bool
+		expectFinish  bool
+		expectEvent   bool
+	}{
+		{
+			name:     "Running group",
+			given:    "a group with jobs still pending",
+			when:     "getting it",
+			then:     "should return its progress without finishing it",
+			progress: queue.GroupProgress{Total: 3, Completed: 1, Pending: 2},
+		},
+		{
+			name:        "Completed group",
+			given:       "a group already finished",
+			when:        "getting it",
+			then:        "should return it without finishing it again",
+			completedAt: &completedAt,
+			progress:    queue.GroupProgress{Total: 3, Completed: 2, Failed: 1},
+		},
+		{
+			name:          "Group whose last worker couldn't finish it",
+			given:         "a group with no job pending that isn't finished",
+			when:          "getting it",
+			then:          "should finish it and publish group.completed",
+			progress:      queue.GroupProgress{Total: 3, Completed: 3},
+			finishClaimed: true,
+			expectFinish:  true,
+			expectEvent:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			groups := new(MockGroupRepository)
+			groups.On("Get", mock.Anything, groupID).Return(&queue.Group{ID: groupID, Size: 3, CompletedAt: tt.completedAt}, nil)
+			groups.On("Progress", mock.Anything, groupID).Return(tt.progress, nil)
+			groups.On("Finish", mock.Anything, groupID, mock.Anything).Return(tt.finishClaimed, nil)
+			publisher := &RecordingPublisher{}
+			service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+			service.SetGroupRepository(groups)
+			service.SetEventPublisher(publisher)
+
+			// When
+			group, progress, err := service.GetGroup(context.Background(), groupID)
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, tt.progress, progress)
+			assert.Equal(t, tt.completedAt != nil || tt.finishClaimed, group.CompletedAt != nil)
+			if tt.expectFinish {
+				groups.AssertCalled(t, "Finish", mock.Anything, groupID, mock.Anything)
+			} else {
+				groups.AssertNotCalled(t, "Finish", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectEvent {
+				assert.Equal(t, []string{events.NameGroupCompleted}, publisher.names)
+			} else {
+				assert.Empty(t, publisher.names)
+			}
+		})
+	}
+}
+
+func TestService_GroupsDisabled(t *testing.T) {
+	service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+
+	_, err := service.CreateGroup(context.Background(), CreateGroupCommand{})
+	assert.ErrorIs(t, err, ErrGroupsDisabled)
+	_, _, err = service.GetGroup(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrGroupsDisabled)
+}
+
+// RecordingPayloadRetention clears the next of its batch sizes on each call
+type RecordingPayloadRetention struct {
+	batches  []int64
+	err      error
+	expiries []queue.PayloadExpiry
+}
+
+func (r *RecordingPayloadRetention) ClearPayloads(ctx context.Context, expiry queue.PayloadExpiry) (int64, error) {
+	r.expiries = append(r.expiries, expiry)
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(r.batches) == 0 {
+		return 0, nil
+	}
+	n := r.batches[0]
+	r.batches = r.batches[1:]
+	return n, nil
+}
+
+func TestService_EnforceRetention(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name            string
+		given           string
+		when            string
+		then            string
+		policy          queue.RetentionPolicy
+		defs            []*queue.Definition
+		batches         []int64
+		clearErr        error
+		expectedCleared int64
+		expectedPurged  int64
+		expectedCalls   int
+		expectErr       bool
+	}{
+		{
+			name:            "Full batches",
+			given:           "a default payload retention and more expired jobs than fit a batch",
+			when:            "enforcing retention",
+			then:            "should clear batch after batch until one comes back short",
+			policy:          queue.RetentionPolicy{PayloadRetention: 30 * day},
+			batches:         []int64{RetentionBatch, RetentionBatch, 5},
+			expectedCleared: 2*RetentionBatch + 5,
+			expectedCalls:   3,
+		},
+		{
+			name:            "Queue override",
+			given:           "a queue definition keeping payloads 7 days next to a default of 30",
+			when:            "enforcing retention",
+			then:            "should clear that queue and the others separately",
+			policy:          queue.RetentionPolicy{PayloadRetention: 30 * day},
+			defs:            []*queue.Definition{{Name: "reports", PayloadRetentionDays: 7}},
+			batches:         []int64{2, 3},
+			expectedCleared: 5,
+			expectedCalls:   2,
+		},
+		{
+			name:           "Deleted jobs",
+			given:          "a retention for soft-deleted jobs and none for payloads",
+			when:           "enforcing retention",
+			then:           "should purge the deleted jobs without clearing payloads",
+			policy:         queue.RetentionPolicy{DeletedRetention: 7 * day},
+			expectedPurged: 4,
+		},
+		{
+			name:          "Clearing fails",
+			given:         "a database that cannot clear payloads",
+			when:          "enforcing retention",
+			then:          "should return the error",
+			policy:        queue.RetentionPolicy{PayloadRetention: 30 * day, DeletedRetention: 7 * day},
+			clearErr:      errors.New("connection refused"),
+			expectedCalls: 1,
+			expectErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockRepo := new(MockJobRepository)
+			if tt.expectedPurged > 0 {
+				mockRepo.On("PurgeDeleted", mock.Anything, mock.AnythingOfType("time.Time")).Return(tt.expectedPurged, nil)
+			}
+			definitions := new(MockDefinitionRepository)
+			definitions.On("List", mock.Anything).Return(tt.defs, nil)
+			retention := &RecordingPayloadRetention{batches: tt.batches, err: tt.clearErr}
+
+			service := NewService(mockRepo, new(MockQueueService), new(MockMetricsService))
+			service.SetQueueDefinitions(definitions, false)
+			service.SetPayloadRetentionRepository(retention)
+
+			// When
+			cleared, purged, err := service.EnforceRetention(context.Background(), tt.policy)
+
+			// Then
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCleared, cleared)
+			assert.Equal(t, tt.expectedPurged, purged)
+			assert.Len(t, retention.expiries, tt.expectedCalls)
+			mockRepo.AssertExpectations(t)
+			if tt.expectedPurged == 0 {
+				mockRepo.AssertNotCalled(t, "PurgeDeleted", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// FakeCompletionWaiter reports every job waited on finished, or none
+type FakeCompletionWaiter struct {
+	finish bool
+}
+
+func (w *FakeCompletionWaiter) Wait(jobID uuid.UUID) (<-chan struct{}, func()) {
+	done := make(chan struct{})
+	if w.finish {
+		close(done)
+	}
+	return done, func() {}
+}
+
+func TestService_ExecuteJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		given          string
+		when           string
+		then           string
+		command        CreateJobCommand
+		timeout        time.Duration
+		finish         bool           // Whether the waiter reports the job finished
+		stored         []queue.Status // Statuses GetByID returns, one per read
+		expectErr      error
+		expectFinished bool
+		expectStatus   queue.Status
+	}{
+		{
+			name:           "Job finishes during the wait",
+			given:          "a job a worker completes after the wait began",
+			when:           "executing it",
+			then:           "should return the completed job as stored",
+			command:        CreateJobCommand{Queue: "default", Type: "email", Payload: map[string]any{}},
+			finish:         true,
+			stored:         []queue.Status{queue.StatusProcessing, queue.StatusCompleted},
+			expectFinished: true,
+			expectStatus:   queue.StatusCompleted,
+		},
+		{
+			name:           "Job finished before the wait",
+			given:          "a job a worker failed for good before the wait began",
+			when:           "executing it",
+			then:           "should return the failed job without waiting",
+			command:        CreateJobCommand{Queue: "default", Type: "email", Payload: map[string]any{}},
+			stored:         []queue.Status{queue.StatusFailed},
+			expectFinished: true,
+			expectStatus:   queue.StatusFailed,
+		},
+		{
+			name:           "Timeout passes first",
+			given:          "a job no worker finishes",
+			when:           "executing it with a 10ms timeout",
+			then:           "should return the job as it is, not finished",
+			command:        CreateJobCommand{Queue: "default", Type: "email", Payload: map[string]any{}},
+			timeout:        10 * time.Millisecond,
+			stored:         []queue.Status{queue.StatusPending, queue.StatusProcessing},
+			expectFinished: false,
+			expectStatus:   queue.StatusProcessing,
+		},
+		{
+			name:      "Timeout over the maximum",
+			given:     "a timeout of an hour",
+			when:      "executing a job",
+			then:      "should return ErrInvalidExecuteTimeout without creating the job",
+			command:   CreateJobCommand{Queue: "default", Type: "email", Payload: map[string]any{}},
+			timeout:   time.Hour,
+			expectErr: ErrInvalidExecuteTimeout,
+		},
+		{
+			name:      "Scheduled job",
+			given:     "a command scheduled an hour from now",
+			when:      "executing it",
+			then:      "should return ErrExecuteDelayed without creating the job",
+			command:   CreateJobCommand{Queue: "default", Type: "email", ScheduledFor: func() *time.Time { at := time.Now().Add(time.Hour); return &at }()},
+			expectErr: ErrExecuteDelayed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			repo := new(MockJobRepository)
+			queueSvc := new(MockQueueService)
+			metrics := new(MockMetricsService)
+			if tt.expectErr == nil {
+				repo.On("Create", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				metrics.On("RecordJobCreated", "default", "email").Return()
+				for _, status := range tt.stored {
+					repo.On("GetByID", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+						Return(&queue.Job{ID: uuid.New(), Queue: "default", Type: "email", Status: status}, nil).Once()
+				}
+			}
+
+			service := NewService(repo, queueSvc, metrics)
+			service.SetCompletionWaiter(&FakeCompletionWaiter{finish: tt.finish})
+
+			// When
+			job, finished, err := service.ExecuteJob(context.Background(), tt.command, tt.timeout)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				assert.Nil(t, job)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectFinished, finished)
+			assert.Equal(t, tt.expectStatus, job.Status)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_ExecuteJob_Disabled(t *testing.T) {
+	service := NewService(new(MockJobRepository), new(MockQueueService), new(MockMetricsService))
+
+	_, _, err := service.ExecuteJob(context.Background(), CreateJobCommand{Queue: "default", Type: "email"}, 0)
+	assert.ErrorIs(t, err, ErrExecuteDisabled)
+}
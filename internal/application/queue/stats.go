@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// Stats sampling defaults
+const (
+	DefaultStatsInterval  = time.Minute
+	DefaultStatsRetention = 7 * 24 * time.Hour
+)
+
+// ErrStatsDisabled is returned when queue statistics history is not configured
+var ErrStatsDisabled = errors.New("queue statistics history is not enabled")
+
+// SetStatsRepository sets the repository queue statistics samples are stored in
+func (s *Service) SetStatsRepository(repo queue.QueueStatsRepository) {
+	s.stats = repo
+}
+
+// SampleQueueStats records the current job counts of every queue, stamped with the start
+// of the current minute so concurrent samplers overwrite each other instead of duplicating
+func (s *Service) SampleQueueStats(ctx context.Context) (int, error) {
+	if s.stats == nil {
+		return 0, ErrStatsDisabled
+	}
+
+	stats, err := s.stats.CountByQueue(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sampledAt := time.Now().UTC().Truncate(time.Minute)
+	for _, sample := range stats {
+		sample.SampledAt = sampledAt
+	}
+	if len(stats) == 0 {
+		return 0, nil
+	}
+
+	if err := s.stats.Record(ctx, stats); err != nil {
+		return 0, err
+	}
+	return len(stats), nil
+}
+
+// GetQueueStatsHistory returns a queue's samples over the window, oldest first
+func (s *Service) GetQueueStatsHistory(ctx context.Context, queueName string, window time.Duration) ([]*queue.QueueStats, error) {
+	if s.stats == nil {
+		return nil, ErrStatsDisabled
+	}
+	if queueName == "" {
+		return nil, queue.ErrInvalidQueue
+	}
+	return s.stats.History(ctx, queueName, time.Now().UTC().Add(-window))
+}
+
+// RunStatsSampler samples queue statistics every interval until the context is cancelled,
+// dropping samples older than the retention period
+func (s *Service) RunStatsSampler(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 {
+		interval = DefaultStatsInterval
+	}
+	if retention <= 0 {
+		retention = DefaultStatsRetention
+	}
+	slog.InfoContext(ctx, "Queue stats sampler started",
+		slog.Duration("interval", interval),
+		slog.Duration("retention", retention),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.sampleAndPrune(ctx, retention)
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Queue stats sampler shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) sampleAndPrune(ctx context.Context, retention time.Duration) {
+	sampled, err := s.SampleQueueStats(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to sample queue stats",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	slog.DebugContext(ctx, "Sampled queue stats",
+		slog.Int("queues", sampled),
+	)
+
+	pruned, err := s.stats.Prune(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to prune queue stats",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if pruned > 0 {
+		slog.DebugContext(ctx, "Pruned queue stats",
+			slog.Int64("samples", pruned),
+		)
+	}
+}
@@ -0,0 +1,127 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/google/uuid"
+)
+
+// Service enforces per-tenant quotas. Tenants are named by quota.Tenant, never by the API key
+// itself, and get the default quota unless an override is stored for them. An empty tenant,
+// e.g. of a job created before quotas were enabled, is not metered.
+type Service struct {
+	usage     quota.UsageStore
+	overrides quota.OverrideRepository
+	defaults  quota.Quota
+	now       func() time.Time
+}
+
+// NewService creates a new quota service; overrides is optional
+func NewService(usage quota.UsageStore, overrides quota.OverrideRepository, defaults quota.Quota) *Service {
+	return &Service{
+		usage:     usage,
+		overrides: overrides,
+		defaults:  defaults,
+		now:       time.Now,
+	}
+}
+
+// Report is a tenant's quota with what it used of it today
+type Report struct {
+	Quota    quota.Quota
+	Usage    *quota.Usage
+	ResetsAt time.Time // When the daily counters reset
+}
+
+// QuotaFor returns the quota that applies to a tenant
+func (s *Service) QuotaFor(ctx context.Context, tenant string) (quota.Quota, error) {
+	if s.overrides == nil {
+		return s.defaults, nil
+	}
+	override, err := s.overrides.Get(ctx, tenant)
+	if errors.Is(err, quota.ErrNoOverride) {
+		return s.defaults, nil
+	}
+	if err != nil {
+		return quota.Quota{}, err
+	}
+	return override.Apply(s.defaults), nil
+}
+
+// ReserveJob counts a new job against the tenant's quota, failing with a *quota.ExceededError
+// when the tenant created too many jobs today or has too many pending
+func (s *Service) ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID) error {
+	if tenant == "" {
+		return nil
+	}
+	limits, err := s.QuotaFor(ctx, tenant)
+	if err != nil {
+		return s.failOpen(ctx, "job", err)
+	}
+	err = s.usage.ReserveJob(ctx, tenant, jobID, limits, s.now())
+	var exceeded *quota.ExceededError
+	if err != nil && !errors.As(err, &exceeded) {
+		return s.failOpen(ctx, "job", err)
+	}
+	return err
+}
+
+// ReleaseJob stops counting a job against its tenant's pending jobs once it no longer runs
+func (s *Service) ReleaseJob(ctx context.Context, jobID uuid.UUID) error {
+	return s.usage.ReleaseJob(ctx, jobID)
+}
+
+// TenantOf returns the tenant that created a pending job, or "" when it isn't metered
+func (s *Service) TenantOf(ctx context.Context, jobID uuid.UUID) (string, error) {
+	return s.usage.TenantOf(ctx, jobID)
+}
+
+// ReserveAnalysis counts an AI analysis against the tenant's quota, failing with a
+// *quota.ExceededError when the tenant requested too many analyses today
+func (s *Service) ReserveAnalysis(ctx context.Context, tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+	limits, err := s.QuotaFor(ctx, tenant)
+	if err != nil {
+		return s.failOpen(ctx, "analysis", err)
+	}
+	err = s.usage.ReserveAnalysis(ctx, tenant, limits, s.now())
+	var exceeded *quota.ExceededError
+	if err != nil && !errors.As(err, &exceeded) {
+		return s.failOpen(ctx, "analysis", err)
+	}
+	return err
+}
+
+// GetUsage returns the tenant's quota and what it used of it today
+func (s *Service) GetUsage(ctx context.Context, tenant string) (*Report, error) {
+	limits, err := s.QuotaFor(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	usage, err := s.usage.Usage(ctx, tenant, now)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{
+		Quota:    limits,
+		Usage:    usage,
+		ResetsAt: now.Add(quota.UntilReset(now)),
+	}, nil
+}
+
+// failOpen lets the request through when usage can't be checked, like the rate limiters,
+// so a Redis or Postgres hiccup doesn't stop job creation
+func (s *Service) failOpen(ctx context.Context, reservation string, err error) error {
+	slog.WarnContext(ctx, "Quota check unavailable, allowing request",
+		slog.String("reservation", reservation),
+		slog.String("error", err.Error()),
+	)
+	return nil
+}
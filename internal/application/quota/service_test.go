@@ -0,0 +1,175 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUsageStore struct {
+	mock.Mock
+}
+
+func (m *MockUsageStore) ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID, limits quota.Quota, now time.Time) error {
+	args := m.Called(ctx, tenant, jobID, limits, now)
+	return args.Error(0)
+}
+
+func (m *MockUsageStore) ReleaseJob(ctx context.Context, jobID uuid.UUID) error {
+	args := m.Called(ctx, jobID)
+	return args.Error(0)
+}
+
+func (m *MockUsageStore) TenantOf(ctx context.Context, jobID uuid.UUID) (string, error) {
+	args := m.Called(ctx, jobID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUsageStore) ReserveAnalysis(ctx context.Context, tenant string, limits quota.Quota, now time.Time) error {
+	args := m.Called(ctx, tenant, limits, now)
+	return args.Error(0)
+}
+
+func (m *MockUsageStore) Usage(ctx context.Context, tenant string, now time.Time) (*quota.Usage, error) {
+	args := m.Called(ctx, tenant, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*quota.Usage), args.Error(1)
+}
+
+type MockOverrideRepository struct {
+	mock.Mock
+}
+
+func (m *MockOverrideRepository) Get(ctx context.Context, tenant string) (*quota.Override, error) {
+	args := m.Called(ctx, tenant)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*quota.Override), args.Error(1)
+}
+
+var defaultQuota = quota.Quota{JobsPerDay: 100, MaxPendingJobs: 10, AnalysesPerDay: 5}
+
+func TestService_ReserveJob(t *testing.T) {
+	jobsPerDay := 1000
+
+	tests := []struct {
+		name          string
+		given         string
+		when          string
+		then          string
+		tenant        string
+		override      *quota.Override
+		overrideErr   error
+		storeErr      error
+		expectErr     error
+		expectLimits  quota.Quota
+		expectReserve bool
+	}{
+		{
+			name:          "Default quota",
+			given:         "an API key without an override",
+			when:          "reserving a job",
+			then:          "should count it against the default quota",
+			tenant:        "key-a",
+			overrideErr:   quota.ErrNoOverride,
+			expectLimits:  defaultQuota,
+			expectReserve: true,
+		},
+		{
+			name:          "Overridden quota",
+			given:         "an API key whose jobs per day are overridden",
+			when:          "reserving a job",
+			then:          "should count it against the overridden limit and the other defaults",
+			tenant:        "key-a",
+			override:      &quota.Override{Tenant: "key-a", JobsPerDay: &jobsPerDay},
+			expectLimits:  quota.Quota{JobsPerDay: 1000, MaxPendingJobs: 10, AnalysesPerDay: 5},
+			expectReserve: true,
+		},
+		{
+			name:          "Quota exceeded",
+			given:         "an API key with as many pending jobs as its quota allows",
+			when:          "reserving a job",
+			then:          "should return ErrQuotaExceeded",
+			tenant:        "key-a",
+			overrideErr:   quota.ErrNoOverride,
+			storeErr:      quota.NewExceededError(quota.ResourcePendingJobs, 10, time.Now()),
+			expectErr:     quota.ErrQuotaExceeded,
+			expectLimits:  defaultQuota,
+			expectReserve: true,
+		},
+		{
+			name:          "Usage store unavailable",
+			given:         "a usage store that can't be reached",
+			when:          "reserving a job",
+			then:          "should allow the job",
+			tenant:        "key-a",
+			overrideErr:   quota.ErrNoOverride,
+			storeErr:      errors.New("connection refused"),
+			expectLimits:  defaultQuota,
+			expectReserve: true,
+		},
+		{
+			name:   "No API key",
+			given:  "a caller without an API key",
+			when:   "reserving a job",
+			then:   "should allow the job without metering it",
+			tenant: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			jobID := uuid.New()
+			usage := new(MockUsageStore)
+			usage.On("ReserveJob", mock.Anything, tt.tenant, jobID, tt.expectLimits, mock.AnythingOfType("time.Time")).Return(tt.storeErr)
+			overrides := new(MockOverrideRepository)
+			overrides.On("Get", mock.Anything, tt.tenant).Return(tt.override, tt.overrideErr)
+			service := NewService(usage, overrides, defaultQuota)
+
+			// When
+			err := service.ReserveJob(context.Background(), tt.tenant, jobID)
+
+			// Then
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.expectReserve {
+				usage.AssertCalled(t, "ReserveJob", mock.Anything, tt.tenant, jobID, tt.expectLimits, mock.Anything)
+			} else {
+				usage.AssertNotCalled(t, "ReserveJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_GetUsage(t *testing.T) {
+	// Given
+	now := time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)
+	usage := new(MockUsageStore)
+	usage.On("Usage", mock.Anything, "key-a", now).Return(&quota.Usage{Day: quota.Day(now), JobsToday: 7, PendingJobs: 2}, nil)
+	overrides := new(MockOverrideRepository)
+	overrides.On("Get", mock.Anything, "key-a").Return(nil, quota.ErrNoOverride)
+	service := NewService(usage, overrides, defaultQuota)
+	service.now = func() time.Time { return now }
+
+	// When
+	report, err := service.GetUsage(context.Background(), "key-a")
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, defaultQuota, report.Quota)
+	assert.Equal(t, int64(7), report.Usage.JobsToday)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), report.ResetsAt)
+}
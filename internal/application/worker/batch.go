@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// Batching defaults used when none are configured
+const (
+	DefaultBatchWindow  = 5 * time.Millisecond
+	DefaultBatchMaxSize = 100
+)
+
+// batchCall is an item waiting in a batch and the channel its error is sent on
+type batchCall[T any] struct {
+	item T
+	done chan error
+}
+
+// batch collects the items concurrent callers submit for a short window, or until it is full,
+// and flushes them together. Each caller waits for the error of its own item, so it sees the
+// same outcome as if it had written the item alone.
+type batch[T any] struct {
+	window  time.Duration
+	maxSize int
+	flush   func(ctx context.Context, items []T) []error
+
+	mu      sync.Mutex
+	pending []*batchCall[T]
+	timer   *time.Timer
+}
+
+func newBatch[T any](window time.Duration, maxSize int, flush func(ctx context.Context, items []T) []error) *batch[T] {
+	return &batch[T]{window: window, maxSize: maxSize, flush: flush}
+}
+
+// submit adds the item to the pending batch and waits until the batch is flushed
+func (b *batch[T]) submit(item T) error {
+	call := &batchCall[T]{item: item, done: make(chan error, 1)}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, call)
+	if len(b.pending) >= b.maxSize {
+		calls := b.take()
+		b.mu.Unlock()
+		b.run(calls)
+	} else {
+		if len(b.pending) == 1 {
+			b.timer = time.AfterFunc(b.window, b.flushPending)
+		}
+		b.mu.Unlock()
+	}
+	return <-call.done
+}
+
+// take empties the pending batch; b.mu must be held
+func (b *batch[T]) take() []*batchCall[T] {
+	calls := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return calls
+}
+
+func (b *batch[T]) flushPending() {
+	b.mu.Lock()
+	calls := b.take()
+	b.mu.Unlock()
+	if len(calls) > 0 {
+		b.run(calls)
+	}
+}
+
+// run flushes the calls outside any caller's context, since they share one write
+func (b *batch[T]) run(calls []*batchCall[T]) {
+	items := make([]T, len(calls))
+	for i, call := range calls {
+		items[i] = call.item
+	}
+	errs := b.flush(context.Background(), items)
+	for i, call := range calls {
+		call.done <- errs[i]
+	}
+}
+
+// CompletionBatcher groups the status updates and acknowledgements of jobs finished by
+// concurrent workers, so a burst of short jobs costs a few round trips to Postgres and Redis
+// instead of one per job. It is shared by all the worker services of a process.
+type CompletionBatcher struct {
+	updates *batch[*queue.Job]
+	acks    *batch[uuid.UUID]
+}
+
+// NewCompletionBatcher creates a batcher flushing every window or once maxSize items are
+// waiting; zero values keep the defaults. Either updater or acker may be nil to leave that
+// write unbatched.
+func NewCompletionBatcher(updater queue.BatchJobUpdater, acker queue.BatchAcknowledger, window time.Duration, maxSize int) *CompletionBatcher {
+	if window <= 0 {
+		window = DefaultBatchWindow
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultBatchMaxSize
+	}
+	b := &CompletionBatcher{}
+	if updater != nil {
+		b.updates = newBatch(window, maxSize, updater.UpdateBatch)
+	}
+	if acker != nil {
+		b.acks = newBatch(window, maxSize, func(ctx context.Context, jobIDs []uuid.UUID) []error {
+			err := acker.AcknowledgeBatch(ctx, jobIDs)
+			errs := make([]error, len(jobIDs))
+			for i := range errs {
+				errs[i] = err
+			}
+			return errs
+		})
+	}
+	return b
+}
+
+// SetCompletionBatcher batches the status updates and acknowledgements of started and
+// completed jobs with those of the other workers sharing the batcher
+func (s *Service) SetCompletionBatcher(batcher *CompletionBatcher) {
+	s.batcher = batcher
+}
+
+// updateJob writes the job, in a batch when batching is on
+func (s *Service) updateJob(ctx context.Context, job *queue.Job) error {
+	if s.batcher == nil || s.batcher.updates == nil {
+		return s.jobRepo.Update(ctx, job)
+	}
+	return s.batcher.updates.submit(job)
+}
+
+// acknowledge acknowledges the job at the queue backend, in a batch when batching is on
+func (s *Service) acknowledge(ctx context.Context, jobID uuid.UUID) error {
+	if s.batcher == nil || s.batcher.acks == nil {
+		return s.queueService.Acknowledge(ctx, jobID)
+	}
+	return s.batcher.acks.submit(jobID)
+}
@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// RecordingBatchStore is a queue.BatchJobUpdater and queue.BatchAcknowledger recording the
+// size of each batch
+type RecordingBatchStore struct {
+	mu       sync.Mutex
+	rejected uuid.UUID // Job whose update is refused
+	updates  []int
+	acks     []int
+}
+
+func (s *RecordingBatchStore) UpdateBatch(ctx context.Context, jobs []*queue.Job) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updates = append(s.updates, len(jobs))
+	errs := make([]error, len(jobs))
+	for i, job := range jobs {
+		if job.ID == s.rejected {
+			errs[i] = queue.ErrInvalidTransition
+		}
+	}
+	return errs
+}
+
+func (s *RecordingBatchStore) AcknowledgeBatch(ctx context.Context, jobIDs []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acks = append(s.acks, len(jobIDs))
+	return nil
+}
+
+func TestService_CompletionBatcher(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			jobs     int
+			window   time.Duration
+			maxSize  int
+			rejectAt int // Index of the job whose update is refused, -1 for none
+		}
+		want struct {
+			updates []int
+			acks    []int
+		}
+	}{
+		{
+			name: "Given workers finishing as many jobs as a batch holds, When completing them, Then should write them in one round trip",
+			in: struct {
+				jobs     int
+				window   time.Duration
+				maxSize  int
+				rejectAt int
+			}{jobs: 4, window: time.Hour, maxSize: 4, rejectAt: -1},
+			want: struct {
+				updates []int
+				acks    []int
+			}{updates: []int{4}, acks: []int{4}},
+		},
+		{
+			name: "Given fewer jobs than a batch holds, When the window ends, Then should write them together",
+			in: struct {
+				jobs     int
+				window   time.Duration
+				maxSize  int
+				rejectAt int
+			}{jobs: 3, window: 20 * time.Millisecond, maxSize: 100, rejectAt: -1},
+			want: struct {
+				updates []int
+				acks    []int
+			}{updates: []int{3}, acks: []int{3}},
+		},
+		{
+			name: "Given a job already moved on, When completing it with others, Then only it should get the transition error",
+			in: struct {
+				jobs     int
+				window   time.Duration
+				maxSize  int
+				rejectAt int
+			}{jobs: 2, window: 20 * time.Millisecond, maxSize: 2, rejectAt: 1},
+			want: struct {
+				updates []int
+				acks    []int
+			}{updates: []int{2}, acks: []int{1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := make([]*queue.Job, tt.in.jobs)
+			for i := range jobs {
+				jobs[i], _ = queue.NewJob("default", "email", nil)
+			}
+			store := &RecordingBatchStore{}
+			if tt.in.rejectAt >= 0 {
+				store.rejected = jobs[tt.in.rejectAt].ID
+			}
+			service := NewService(nil, nil, nil, nil, nil)
+			service.SetCompletionBatcher(NewCompletionBatcher(store, store, tt.in.window, tt.in.maxSize))
+
+			errs := make([]error, len(jobs))
+			var wg sync.WaitGroup
+			for i, job := range jobs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if errs[i] = service.updateJob(context.Background(), job); errs[i] == nil {
+						errs[i] = service.acknowledge(context.Background(), job.ID)
+					}
+				}()
+			}
+			wg.Wait()
+
+			for i, err := range errs {
+				if i == tt.in.rejectAt {
+					assert.ErrorIs(t, err, queue.ErrInvalidTransition)
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+			assert.Equal(t, tt.want.updates, store.updates)
+			assert.Equal(t, tt.want.acks, store.acks)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// brokerFailed records a failed dequeue. Without a broker health tracker every failure is
+// logged; with one, only the first failure of an outage and the one that degrades the
+// process are, the rest at debug level while the workers back off.
+func (s *Service) brokerFailed(ctx context.Context, cfg *worker.WorkerConfig, err error) {
+	if s.brokerHealth == nil {
+		slog.ErrorContext(ctx, "Failed to dequeue job",
+			slog.String("error", err.Error()),
+			slog.String("queue", cfg.QueueName),
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	failure := s.brokerHealth.Failure(err, now)
+	backoff := s.brokerHealth.Backoff(now)
+	switch {
+	case failure.Degraded:
+		slog.ErrorContext(ctx, "Queue broker unreachable, worker degraded",
+			slog.String("workerId", cfg.WorkerID),
+			slog.String("queue", cfg.QueueName),
+			slog.Int("failures", failure.Failures),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+		s.events.Publish(ctx, events.BrokerDegraded{
+			WorkerID: cfg.WorkerID,
+			Failures: failure.Failures,
+			Error:    err.Error(),
+			Since:    failure.Since,
+			At:       now,
+		})
+	case failure.Failures == 1:
+		slog.ErrorContext(ctx, "Failed to dequeue job, backing off",
+			slog.String("queue", cfg.QueueName),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+	default:
+		slog.DebugContext(ctx, "Queue broker still unreachable",
+			slog.String("queue", cfg.QueueName),
+			slog.Int("failures", failure.Failures),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// brokerReached records a successful dequeue, ending the outage the tracker counted, if any
+func (s *Service) brokerReached(ctx context.Context, cfg *worker.WorkerConfig) {
+	if s.brokerHealth == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	recovery := s.brokerHealth.Success(now)
+	if recovery.Failures == 0 {
+		return
+	}
+	slog.InfoContext(ctx, "Queue broker reachable again",
+		slog.String("workerId", cfg.WorkerID),
+		slog.String("queue", cfg.QueueName),
+		slog.Int("failures", recovery.Failures),
+		slog.Duration("downtime", recovery.Downtime),
+	)
+	if recovery.WasDegraded {
+		s.events.Publish(ctx, events.BrokerRecovered{
+			WorkerID: cfg.WorkerID,
+			Failures: recovery.Failures,
+			Downtime: recovery.Downtime,
+			At:       now,
+		})
+	}
+}
+
+// brokerBackoff returns how long to wait before polling again after a failed dequeue; without
+// a broker health tracker it is the dequeue timeout, as for any poll that popped nothing
+func (s *Service) brokerBackoff(cfg *worker.WorkerConfig) time.Duration {
+	if s.brokerHealth == nil {
+		return cfg.DequeueTimeout
+	}
+	return s.brokerHealth.Backoff(time.Now().UTC())
+}
@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// definitionCacheTTL bounds how long a queue definition is reused before it's read again,
+// so pausing a queue or changing its retry settings reaches workers within seconds
+const definitionCacheTTL = 10 * time.Second
+
+// SetQueueDefinitions makes the worker honor the definition of its queue: a paused queue
+// isn't consumed and the definition's retry settings override the worker's. With enforce,
+// the worker doesn't consume a queue that has no definition.
+func (s *Service) SetQueueDefinitions(repo queue.DefinitionRepository, enforce bool) {
+	s.definitions = repo
+	s.enforceDefinitions = enforce
+}
+
+// queueDefinition returns the cached definition of the worker's queue, reloading it once
+// the cache expires. When the repository fails the last known definition is kept.
+func (s *Service) queueDefinition(ctx context.Context) (*queue.Definition, bool) {
+	s.definitionMu.Lock()
+	defer s.definitionMu.Unlock()
+
+	if !s.definitionLoadedAt.IsZero() && time.Since(s.definitionLoadedAt) < definitionCacheTTL {
+		return s.definition, s.definition != nil
+	}
+
+	def, err := s.definitions.Get(ctx, s.currentConfig().QueueName)
+	switch {
+	case errors.Is(err, queue.ErrQueueNotDefined):
+		s.definition = nil
+	case err != nil:
+		slog.WarnContext(ctx, "Failed to load queue definition, using the last known one",
+			slog.String("queue", s.currentConfig().QueueName),
+			slog.String("error", err.Error()),
+		)
+	default:
+		s.definition = def
+	}
+	s.definitionLoadedAt = time.Now()
+	return s.definition, s.definition != nil
+}
+
+// queueOpen reports whether the worker may consume its queue
+func (s *Service) queueOpen(ctx context.Context, queueName string) bool {
+	if s.definitions == nil {
+		return true
+	}
+
+	def, defined := s.queueDefinition(ctx)
+	if !defined {
+		if s.enforceDefinitions {
+			slog.WarnContext(ctx, "Queue is not defined, skipping poll",
+				slog.String("queue", queueName),
+			)
+			return false
+		}
+		return true
+	}
+	if def.Paused {
+		slog.DebugContext(ctx, "Queue is paused, skipping poll",
+			slog.String("queue", queueName),
+		)
+		return false
+	}
+	return true
+}
+
+// retryPolicy returns how jobs of the type are retried: the job type's policy, with the
+// settings it leaves unset taken from the queue definition and then the worker config
+func (s *Service) retryPolicy(ctx context.Context, cfg *worker.WorkerConfig, jobType string) worker.RetryPolicy {
+	maxAttempts := cfg.MaxAttempts
+	baseBackoffMs := cfg.BaseBackoffMs
+	if s.definitions != nil {
+		if def, defined := s.queueDefinition(ctx); defined {
+			if def.MaxAttempts > 0 {
+				maxAttempts = def.MaxAttempts
+			}
+			if def.BaseBackoffMs > 0 {
+				baseBackoffMs = def.BaseBackoffMs
+			}
+		}
+	}
+	return cfg.RetryPolicies.For(jobType).WithDefaults(maxAttempts, time.Duration(baseBackoffMs)*time.Millisecond)
+}
+
+// insightPolicy returns the policy of the queue definition, or the worker's when the queue
+// doesn't set one
+func (s *Service) insightPolicy(ctx context.Context, cfg *worker.WorkerConfig) queue.InsightPolicy {
+	if s.definitions != nil {
+		if def, defined := s.queueDefinition(ctx); defined && def.InsightPolicy != "" {
+			return def.InsightPolicy
+		}
+	}
+	if cfg.InsightPolicy != "" {
+		return cfg.InsightPolicy
+	}
+	return queue.DefaultInsightPolicy
+}
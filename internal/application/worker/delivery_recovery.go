@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// DefaultDeliveryRecoveryInterval is how often expired deliveries are looked for
+const DefaultDeliveryRecoveryInterval = time.Minute
+
+// RunDeliveryRecovery returns the jobs whose delivery expired to their queues every interval
+// until the context is cancelled. Failed runs are logged and retried on the next tick.
+func RunDeliveryRecovery(ctx context.Context, recoverer queue.DeliveryRecoverer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		recovered, err := recoverer.RecoverExpired(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to recover expired deliveries",
+				slog.String("error", err.Error()),
+			)
+		}
+		if recovered > 0 {
+			slog.WarnContext(ctx, "Returned jobs of unresponsive workers to their queues",
+				slog.Int("count", recovered),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// DefaultFairIdleWait is how long a fair dispatcher slot waits after finding every queue
+// empty, unless the idle sleep is longer or a job notification cuts it short
+const DefaultFairIdleWait = 250 * time.Millisecond
+
+// FairDispatcher shares a pool of slots between the worker services of several queues, so a
+// flooded queue can't starve the others. Each slot asks the scheduler which queue to poll and
+// pops without blocking; an empty queue hands its turn to the next one, so idle queues' share
+// goes to busy ones. Slots wait only once a whole round found no job.
+type FairDispatcher struct {
+	scheduler *worker.FairScheduler
+	services  map[string]*Service
+	slots     int
+	idleWait  time.Duration
+}
+
+// NewFairDispatcher creates a dispatcher running slots jobs at a time across the services'
+// queues, polled according to the scheduler. Every scheduled queue needs a service.
+func NewFairDispatcher(scheduler *worker.FairScheduler, services []*Service, slots int) (*FairDispatcher, error) {
+	byQueue := make(map[string]*Service, len(services))
+	for _, service := range services {
+		byQueue[service.currentConfig().QueueName] = service
+	}
+	idleWait := DefaultFairIdleWait
+	for _, q := range scheduler.Queues() {
+		service, ok := byQueue[q.Queue]
+		if !ok {
+			return nil, fmt.Errorf("no worker service for queue %s", q.Queue)
+		}
+		idleWait = max(idleWait, service.currentConfig().IdleSleep)
+	}
+	return &FairDispatcher{
+		scheduler: scheduler,
+		services:  byQueue,
+		slots:     max(1, slots),
+		idleWait:  idleWait,
+	}, nil
+}
+
+// Run processes jobs on every slot until the context is cancelled and returns once in-flight
+// jobs have finished
+func (d *FairDispatcher) Run(ctx context.Context) {
+	queues := d.scheduler.Queues()
+	slog.InfoContext(ctx, "Fair dispatcher started",
+		slog.Any("queues", queues),
+		slog.Int("slots", d.slots),
+		slog.Duration("idleWait", d.idleWait),
+	)
+
+	wake := d.wakeOnReady(ctx)
+	var wg sync.WaitGroup
+	for range d.slots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				handled, wait := d.dispatch(ctx)
+				if !handled && !d.wait(ctx, wake, wait) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	slog.InfoContext(ctx, "Fair dispatcher stopped")
+}
+
+// dispatch polls queues in the scheduler's order until one yields a job, at most one round.
+// It returns whether a job was handled, and otherwise how long the slot should wait. A broker
+// that can't be reached ends the round early, since every queue shares it, and the slot backs
+// off as the queue's worker service says.
+func (d *FairDispatcher) dispatch(ctx context.Context) (bool, time.Duration) {
+	for range d.services {
+		queueName := d.scheduler.Next()
+		service := d.services[queueName]
+		outcome, err := service.processNextJob(ctx, 0)
+		if outcome == pollBrokerDown {
+			return false, max(service.brokerBackoff(service.currentConfig()), d.idleWait)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error processing job",
+				slog.String("queue", queueName),
+				slog.String("error", err.Error()),
+			)
+		}
+		if outcome == pollHandled {
+			return true, 0
+		}
+		if ctx.Err() != nil {
+			return false, 0
+		}
+	}
+	return false, d.idleWait
+}
+
+// wait pauses a slot after a round without a job until the wait elapses or a job notification
+// arrives. It returns false once the dispatcher is shutting down.
+func (d *FairDispatcher) wait(ctx context.Context, wake <-chan struct{}, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-wake:
+		return true
+	case <-timer.C:
+		return true
+	}
+}
+
+// wakeOnReady merges the job notifications of every queue into one channel
+func (d *FairDispatcher) wakeOnReady(ctx context.Context) <-chan struct{} {
+	wake := make(chan struct{}, 1)
+	for queueName, service := range d.services {
+		if service.readySignal == nil {
+			continue
+		}
+		ready := service.readySignal.Ready(queueName)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ready:
+					select {
+					case wake <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}()
+	}
+	return wake
+}
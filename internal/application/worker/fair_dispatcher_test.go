@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// FakeQueues serves jobs from in-memory lists per queue
+type FakeQueues struct {
+	mu   sync.Mutex
+	jobs map[string][]*queue.Job
+}
+
+func NewFakeQueues(counts map[string]int) *FakeQueues {
+	q := &FakeQueues{jobs: make(map[string][]*queue.Job)}
+	for name, count := range counts {
+		for range count {
+			job, _ := queue.NewJob(name, "email", []byte(`{}`))
+			q.jobs[name] = append(q.jobs[name], job)
+		}
+	}
+	return q
+}
+
+func (q *FakeQueues) Enqueue(ctx context.Context, job *queue.Job) error { return nil }
+
+func (q *FakeQueues) Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*queue.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.jobs[queueName]) == 0 {
+		return nil, nil
+	}
+	job := q.jobs[queueName][0]
+	q.jobs[queueName] = q.jobs[queueName][1:]
+	return job, nil
+}
+
+func (q *FakeQueues) Acknowledge(ctx context.Context, jobID uuid.UUID) error { return nil }
+func (q *FakeQueues) Nack(ctx context.Context, job *queue.Job) error         { return nil }
+func (q *FakeQueues) DeliveryStats(ctx context.Context) ([]*queue.DeliveryStats, error) {
+	return nil, nil
+}
+
+func TestFairDispatcher_Run(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			weights []worker.QueueWeight
+			jobs    map[string]int
+			runs    int
+		}
+		want struct {
+			order []string
+		}
+	}{
+		{
+			name: "Given a flooded queue and a queue with one job, When dispatching, Then should run the lone job second instead of after the flood",
+			in: struct {
+				weights []worker.QueueWeight
+				jobs    map[string]int
+				runs    int
+			}{
+				weights: []worker.QueueWeight{{Queue: "bulk", Weight: 1}, {Queue: "critical", Weight: 1}},
+				jobs:    map[string]int{"bulk": 100, "critical": 1},
+				runs:    4,
+			},
+			want: struct{ order []string }{order: []string{"bulk", "critical", "bulk", "bulk"}},
+		},
+		{
+			name: "Given weights 2 and 1 with both queues busy, When dispatching, Then should run jobs interleaved in proportion to the weights",
+			in: struct {
+				weights []worker.QueueWeight
+				jobs    map[string]int
+				runs    int
+			}{
+				weights: []worker.QueueWeight{{Queue: "bulk", Weight: 2}, {Queue: "critical", Weight: 1}},
+				jobs:    map[string]int{"bulk": 100, "critical": 100},
+				runs:    6,
+			},
+			want: struct{ order []string }{order: []string{"bulk", "critical", "bulk", "bulk", "critical", "bulk"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			queues := NewFakeQueues(tt.in.jobs)
+			repo := new(MockJobRepository)
+			repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			var order []string
+			executor := new(MockJobExecutor)
+			executor.On("Execute", mock.Anything, mock.AnythingOfType("*queue.Job")).Run(func(args mock.Arguments) {
+				order = append(order, args.Get(1).(*queue.Job).Queue)
+				if len(order) == tt.in.runs {
+					cancel()
+				}
+			}).Return(&worker.ExecutionResult{Success: true}, nil)
+
+			var services []*Service
+			for _, weight := range tt.in.weights {
+				config, _ := worker.NewWorkerConfig(weight.Queue, 3, 500)
+				services = append(services, NewService(repo, queues, executor, nil, config))
+			}
+			scheduler, err := worker.NewFairScheduler(tt.in.weights)
+			assert.NoError(t, err)
+			dispatcher, err := NewFairDispatcher(scheduler, services, 1)
+			assert.NoError(t, err)
+
+			// When
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				dispatcher.Run(ctx)
+			}()
+
+			// Then
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("dispatcher did not stop")
+			}
+			assert.Equal(t, tt.want.order, order)
+		})
+	}
+}
+
+func TestNewFairDispatcher_MissingService(t *testing.T) {
+	// Given
+	scheduler, _ := worker.NewFairScheduler([]worker.QueueWeight{{Queue: "bulk", Weight: 1}, {Queue: "critical", Weight: 1}})
+	config, _ := worker.NewWorkerConfig("bulk", 3, 500)
+	service := NewService(new(MockJobRepository), NewFakeQueues(nil), new(MockJobExecutor), nil, config)
+
+	// When
+	_, err := NewFairDispatcher(scheduler, []*Service{service}, 1)
+
+	// Then
+	assert.ErrorContains(t, err, "no worker service for queue critical")
+}
@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// RunHeartbeat reports the worker-runtime process as alive every interval until the
+// context is cancelled. Failed reports are logged and retried on the next tick.
+func RunHeartbeat(ctx context.Context, store worker.HeartbeatStore, heartbeat worker.Heartbeat, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		heartbeat.LastSeen = time.Now().UTC()
+		if err := store.Beat(ctx, heartbeat); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "Failed to report worker heartbeat",
+				slog.String("workerId", heartbeat.WorkerID),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
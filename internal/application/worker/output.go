@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/google/uuid"
+)
+
+// Output streaming defaults
+const (
+	DefaultOutputChunkBytes    = 16 * 1024
+	DefaultOutputFlushInterval = time.Second
+	DefaultMaxOutputBytes      = 10 * 1024 * 1024
+)
+
+// outputTruncated ends an output that reached its limit
+const outputTruncated = "\n[output truncated]\n"
+
+// SetOutputStore keeps the output executors write to the job's worker.ResultSink while it
+// runs, in chunks flushed once chunkBytes are buffered or every flushInterval. A job keeps at
+// most maxBytes of output; zero values use the defaults.
+func (s *Service) SetOutputStore(store queue.OutputStore, chunkBytes int, flushInterval time.Duration, maxBytes int64) {
+	if chunkBytes <= 0 {
+		chunkBytes = DefaultOutputChunkBytes
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultOutputFlushInterval
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxOutputBytes
+	}
+	s.outputs = store
+	s.outputChunkBytes = chunkBytes
+	s.outputFlushInterval = flushInterval
+	s.maxOutputBytes = maxBytes
+}
+
+// openOutput returns the context the job runs with, carrying the sink its output is streamed
+// to, and a func flushing what's left once it finished. Each run writes the output afresh.
+func (s *Service) openOutput(ctx context.Context, job *queue.Job) (context.Context, func()) {
+	if s.outputs == nil {
+		return ctx, func() {}
+	}
+	if err := s.outputs.ClearOutput(ctx, job.ID); err != nil {
+		slog.WarnContext(ctx, "Failed to clear the output of a previous run",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	sink := &outputSink{
+		ctx:        context.WithoutCancel(ctx),
+		store:      s.outputs,
+		jobID:      job.ID,
+		chunkBytes: s.outputChunkBytes,
+		maxBytes:   s.maxOutputBytes,
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.outputFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				sink.flush()
+			}
+		}
+	}()
+
+	return worker.WithResultSink(ctx, sink), func() {
+		close(stop)
+		<-done
+		sink.flush()
+	}
+}
+
+// outputSink buffers a job's output and appends it to the store in chunks. Store failures are
+// logged rather than returned, so a job isn't failed for output nobody may read.
+type outputSink struct {
+	ctx        context.Context
+	store      queue.OutputStore
+	jobID      uuid.UUID
+	chunkBytes int
+	maxBytes   int64
+
+	mu        sync.Mutex
+	buf       []byte
+	offset    int64 // Bytes flushed so far
+	written   int64 // Bytes accepted so far
+	truncated bool
+}
+
+func (o *outputSink) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.truncated {
+		return len(p), nil
+	}
+	accepted := p
+	if remaining := o.maxBytes - o.written; int64(len(p)) > remaining {
+		accepted = p[:remaining]
+		o.truncated = true
+	}
+	o.buf = append(o.buf, accepted...)
+	o.written += int64(len(accepted))
+	if o.truncated {
+		o.buf = append(o.buf, outputTruncated...)
+	}
+	if len(o.buf) >= o.chunkBytes || o.truncated {
+		o.flushLocked()
+	}
+	return len(p), nil
+}
+
+func (o *outputSink) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushLocked()
+}
+
+func (o *outputSink) flushLocked() {
+	if len(o.buf) == 0 {
+		return
+	}
+	if err := o.store.AppendOutput(o.ctx, o.jobID, o.offset, o.buf); err != nil {
+		// Kept buffered, so the next flush retries it
+		slog.WarnContext(o.ctx, "Failed to store job output",
+			slog.String("jobId", o.jobID.String()),
+			slog.Int("bytes", len(o.buf)),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	o.offset += int64(len(o.buf))
+	o.buf = nil
+}
@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type outputChunk struct {
+	offset int64
+	data   string
+}
+
+// RecordingOutputStore is a queue.OutputStore recording the chunks appended
+type RecordingOutputStore struct {
+	mu      sync.Mutex
+	chunks  []outputChunk
+	cleared int
+	failing int // Appends that fail before they succeed
+}
+
+func (s *RecordingOutputStore) AppendOutput(ctx context.Context, jobID uuid.UUID, offset int64, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing > 0 {
+		s.failing--
+		return fmt.Errorf("connection reset")
+	}
+	s.chunks = append(s.chunks, outputChunk{offset: offset, data: string(data)})
+	return nil
+}
+
+func (s *RecordingOutputStore) OutputSize(ctx context.Context, jobID uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (s *RecordingOutputStore) ReadOutput(ctx context.Context, jobID uuid.UUID, offset, length int64) ([]byte, error) {
+	return nil, nil
+}
+
+func (s *RecordingOutputStore) ClearOutput(ctx context.Context, jobID uuid.UUID) error {
+	s.cleared++
+	return nil
+}
+
+func TestService_OpenOutput(t *testing.T) {
+	tests := []struct {
+		name         string
+		given        string
+		when         string
+		then         string
+		writes       []string
+		maxBytes     int64
+		failing      int
+		expectChunks []outputChunk
+	}{
+		{
+			name:   "Chunked",
+			given:  "writes larger than a chunk together",
+			when:   "the job writes them and finishes",
+			then:   "should store a chunk once it's full and the rest when the job ends",
+			writes: []string{"0123", "4567", "89"},
+			expectChunks: []outputChunk{
+				{offset: 0, data: "01234567"},
+				{offset: 8, data: "89"},
+			},
+		},
+		{
+			name:     "Truncated",
+			given:    "a job writing more than the output limit",
+			when:     "it writes past the limit",
+			then:     "should keep the output up to the limit and mark it truncated",
+			writes:   []string{"0123", "456789", "more"},
+			maxBytes: 6,
+			expectChunks: []outputChunk{
+				{offset: 0, data: "012345" + outputTruncated},
+			},
+		},
+		{
+			name:    "Store failure",
+			given:   "a store failing once",
+			when:    "the job writes a full chunk",
+			then:    "should keep the chunk and store it on the next flush",
+			writes:  []string{"01234567"},
+			failing: 1,
+			expectChunks: []outputChunk{
+				{offset: 0, data: "01234567"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			store := &RecordingOutputStore{failing: tt.failing}
+			service := &Service{}
+			service.SetOutputStore(store, 8, time.Hour, tt.maxBytes)
+			job := &queue.Job{ID: uuid.New()}
+
+			// When
+			ctx, closeOutput := service.openOutput(context.Background(), job)
+			sink := worker.ResultSinkFrom(ctx)
+			for _, write := range tt.writes {
+				n, err := sink.Write([]byte(write))
+				require.NoError(t, err)
+				assert.Equal(t, len(write), n)
+			}
+			closeOutput()
+
+			// Then
+			assert.Equal(t, 1, store.cleared)
+			assert.Equal(t, tt.expectChunks, store.chunks)
+		})
+	}
+}
+
+func TestService_OpenOutput_FlushesOnInterval(t *testing.T) {
+	t.Run("Given a job writing less than a chunk, When the flush interval passes, Then should store it while the job runs", func(t *testing.T) {
+		store := &RecordingOutputStore{}
+		service := &Service{}
+		service.SetOutputStore(store, 1024, 10*time.Millisecond, 0)
+
+		ctx, closeOutput := service.openOutput(context.Background(), &queue.Job{ID: uuid.New()})
+		defer closeOutput()
+		fmt.Fprint(worker.ResultSinkFrom(ctx), "step 1\n")
+
+		assert.Eventually(t, func() bool {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			return len(store.chunks) == 1 && strings.HasPrefix(store.chunks[0].data, "step 1")
+		}, time.Second, 5*time.Millisecond)
+	})
+}
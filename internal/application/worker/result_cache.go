@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// SetResultCache makes the worker return the cached result of a duplicate of an idempotent job
+// instead of running it. The result of a job of an idempotent type is cached once it completes.
+func (s *Service) SetResultCache(cache worker.ResultCache, policies worker.IdempotencyPolicies) {
+	s.results = cache
+	s.idempotency = policies
+}
+
+// execute runs the job, or returns the cached result when it duplicates an idempotent job that
+// completed within the TTL. cacheKey is where the result of a run is to be cached once the job
+// completes, empty when it isn't.
+func (s *Service) execute(ctx context.Context, job *queue.Job) (result *worker.ExecutionResult, cacheKey string, err error) {
+	policy, idempotent := s.idempotency.For(job.Type)
+	if s.results == nil || !idempotent {
+		result, err = s.executor.Execute(ctx, job)
+		return result, "", err
+	}
+
+	cacheKey, err = policy.CacheKey(job)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to derive the result cache key, running the job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		result, err = s.executor.Execute(ctx, job)
+		return result, "", err
+	}
+
+	cached, found, err := s.results.Get(ctx, cacheKey)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read the result cache, running the job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+	if found {
+		slog.InfoContext(ctx, "Returning the cached result of an idempotent job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("jobType", job.Type),
+			slog.String("cacheKey", cacheKey),
+		)
+		job.RecordResult(cached)
+		return &worker.ExecutionResult{Success: true}, "", nil
+	}
+
+	result, err = s.executor.Execute(ctx, job)
+	return result, cacheKey, err
+}
+
+// cacheResult stores the result of a completed idempotent job. The job already completed, so
+// failing to cache it only means its duplicates run again.
+func (s *Service) cacheResult(ctx context.Context, job *queue.Job, cacheKey string) {
+	if cacheKey == "" {
+		return
+	}
+	policy, _ := s.idempotency.For(job.Type)
+	if err := s.results.Set(ctx, cacheKey, job.Result, policy.CacheTTL()); err != nil {
+		slog.WarnContext(ctx, "Failed to cache the result of an idempotent job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// SetRetryBudget caps the retries of each queue at a share of its first attempts. A failing
+// job its queue has no retry left for is parked instead of retried, until it is released.
+func (s *Service) SetRetryBudget(budget *worker.RetryBudget) {
+	s.retryBudget = budget
+}
+
+// recordFirstAttempt earns the job's queue retries when the job is about to run for the first time
+func (s *Service) recordFirstAttempt(job *queue.Job) {
+	if s.retryBudget != nil && job.Status == queue.StatusPending {
+		s.retryBudget.RecordAttempt(job.Queue)
+	}
+}
+
+// retryAllowed spends a retry of the job's queue, reporting false when its budget is exhausted
+func (s *Service) retryAllowed(job *queue.Job) bool {
+	return s.retryBudget == nil || s.retryBudget.Spend(job.Queue)
+}
+
+// parkJob sets aside a failed job whose queue's retry budget is exhausted, taking it off the
+// queue so the downstream it failed on isn't called again until the job is released
+func (s *Service) parkJob(ctx context.Context, job *queue.Job) error {
+	slog.WarnContext(ctx, "Retry budget exhausted, parking job",
+		slog.String("jobId", job.ID.String()),
+		slog.String("queue", job.Queue),
+		slog.Int("attempt", job.Attempts),
+	)
+	if err := job.MarkAsParked(); err != nil {
+		return s.dropDuplicate(ctx, job, err)
+	}
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		if errors.Is(err, queue.ErrInvalidTransition) {
+			return s.dropDuplicate(ctx, job, err)
+		}
+		slog.ErrorContext(ctx, "Failed to park job",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+	s.events.Publish(ctx, events.JobParked{
+		JobID:    job.ID,
+		Queue:    job.Queue,
+		Type:     job.Type,
+		Attempts: job.Attempts,
+		At:       time.Now().UTC(),
+	})
+	return s.queueService.Acknowledge(ctx, job.ID)
+}
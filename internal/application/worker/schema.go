@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// heldReleaseBatch is how many held jobs ReleaseHeldJobs reads at a time
+const heldReleaseBatch = 100
+
+// SetSchemaMigrations makes the worker upgrade the payloads of jobs older than its schema
+// version before running them, and hold the jobs newer than it instead of failing them. held
+// finds the jobs a worker that didn't know their version held; nil leaves them held.
+func (s *Service) SetSchemaMigrations(migrations *worker.SchemaMigrations, held queue.HeldJobRepository) {
+	s.schemas = migrations
+	s.held = held
+}
+
+// prepareSchema upgrades the job's payload to the worker's schema version. It returns false
+// when the job was held or failed instead, with the error of doing so.
+func (s *Service) prepareSchema(ctx context.Context, job *queue.Job) (bool, error) {
+	if s.schemas == nil {
+		return true, nil
+	}
+	if !s.schemas.Knows(job) {
+		return false, s.holdJob(ctx, job)
+	}
+
+	migrated, err := s.schemas.Migrate(job)
+	if err == nil && migrated && s.signer != nil {
+		// The signature was verified before the migration, which is trusted
+		err = s.signer.Resign(job)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to migrate job payload",
+			slog.String("jobId", job.ID.String()),
+			slog.String("jobType", job.Type),
+			slog.Int("schemaVersion", job.EffectiveSchemaVersion()),
+			slog.String("error", err.Error()),
+		)
+		return false, s.handleJobFailure(ctx, job, worker.NewPermanentError(err))
+	}
+	if migrated {
+		slog.InfoContext(ctx, "Migrated job payload",
+			slog.String("jobId", job.ID.String()),
+			slog.String("jobType", job.Type),
+			slog.Int("schemaVersion", job.SchemaVersion),
+		)
+	}
+	return true, nil
+}
+
+// holdJob parks a job newer than the worker's schema version, taking it off the queue so it
+// isn't popped again until a worker that knows its version releases it
+func (s *Service) holdJob(ctx context.Context, job *queue.Job) error {
+	slog.WarnContext(ctx, "Job schema version is newer than the worker knows, holding job",
+		slog.String("jobId", job.ID.String()),
+		slog.String("jobType", job.Type),
+		slog.Int("schemaVersion", job.EffectiveSchemaVersion()),
+		slog.Int("workerSchemaVersion", s.schemas.Version()),
+	)
+	if err := job.MarkAsHeld(worker.ErrSchemaVersionTooNew.Error()); err != nil {
+		return s.dropDuplicate(ctx, job, err)
+	}
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		if errors.Is(err, queue.ErrInvalidTransition) {
+			return s.dropDuplicate(ctx, job, err)
+		}
+		return err
+	}
+	return s.queueService.Acknowledge(ctx, job.ID)
+}
+
+// ReleaseHeldJobs queues again the jobs of the worker's queue that were held by workers that
+// didn't know their schema version, if this one does, and returns how many it released. Run
+// it once as a worker of a new release starts.
+func (s *Service) ReleaseHeldJobs(ctx context.Context) (int, error) {
+	if s.schemas == nil || s.held == nil {
+		return 0, nil
+	}
+	queueName := s.currentConfig().QueueName
+	released := 0
+	for {
+		jobs, err := s.held.FindHeld(ctx, queueName, s.schemas.Version(), heldReleaseBatch)
+		if err != nil {
+			return released, err
+		}
+		for _, job := range jobs {
+			if err := job.Release(); err != nil {
+				return released, err
+			}
+			if err := s.jobRepo.Update(ctx, job); err != nil {
+				if errors.Is(err, queue.ErrInvalidTransition) {
+					// Another worker released it first
+					continue
+				}
+				return released, err
+			}
+			if err := s.queueService.Enqueue(ctx, job); err != nil {
+				return released, err
+			}
+			released++
+		}
+		if len(jobs) < heldReleaseBatch {
+			break
+		}
+	}
+	if released > 0 {
+		slog.InfoContext(ctx, "Released held jobs",
+			slog.String("queue", queueName),
+			slog.Int("released", released),
+			slog.Int("schemaVersion", s.schemas.Version()),
+		)
+	}
+	return released, nil
+}
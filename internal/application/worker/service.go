@@ -2,21 +2,61 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"sync"
 	"time"
 
-	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/logging"
 )
 
 // Service orchestrates worker-related use cases
 type Service struct {
-	jobRepo         queue.JobRepository
-	queueService    queue.QueueService
-	executor        worker.JobExecutor
-	insightsService *appInsights.Service
-	config          *worker.WorkerConfig
+	jobRepo       queue.JobRepository
+	queueService  queue.QueueService
+	executor      worker.JobExecutor
+	analysisQueue insights.AnalysisQueue
+	slowRuns      insights.SlowRunQueue
+	performance   *insights.PerformancePolicy
+	events        events.Publisher
+	notifier      worker.ResultNotifier
+	readySignal   queue.ReadySignal
+	breaker       *worker.FailureBreaker
+	breakerStore  worker.BreakerStore
+	signer        *queue.PayloadSigner
+	quotas        quota.Enforcer
+	fixOutcomes   insights.FixOutcomeRecorder
+	deadLetters   queue.DeadLetterQueue
+	activity      *worker.Activity
+	groups        queue.GroupRepository
+	results       worker.ResultCache
+	idempotency   worker.IdempotencyPolicies
+	attempts      queue.AttemptRepository
+	schemas       *worker.SchemaMigrations
+	held          queue.HeldJobRepository
+	retryBudget   *worker.RetryBudget
+	batcher       *CompletionBatcher
+	brokerHealth  *worker.BrokerHealth
+
+	outputs             queue.OutputStore
+	outputChunkBytes    int
+	outputFlushInterval time.Duration
+	maxOutputBytes      int64
+
+	definitions        queue.DefinitionRepository
+	enforceDefinitions bool
+	definitionMu       sync.Mutex
+	definition         *queue.Definition // nil when the queue has no definition
+	definitionLoadedAt time.Time
+
+	mu     sync.RWMutex
+	config *worker.WorkerConfig
 }
 
 // NewService creates a new worker application service
@@ -24,54 +64,221 @@ func NewService(
 	jobRepo queue.JobRepository,
 	queueService queue.QueueService,
 	executor worker.JobExecutor,
-	insightsService *appInsights.Service,
+	analysisQueue insights.AnalysisQueue,
 	config *worker.WorkerConfig,
 ) *Service {
 	return &Service{
-		jobRepo:         jobRepo,
-		queueService:    queueService,
-		executor:        executor,
-		insightsService: insightsService,
-		config:          config,
+		jobRepo:       jobRepo,
+		queueService:  queueService,
+		executor:      executor,
+		analysisQueue: analysisQueue,
+		config:        config,
+		events:        events.NopPublisher{},
 	}
 }
 
-// ProcessNextJob processes the next available job from the queue
+// SetPerformanceAnalysis queues the completed runs slower than the SLO of their job type for
+// an AI performance analysis, as the policy selects them
+func (s *Service) SetPerformanceAnalysis(policy *insights.PerformancePolicy, slowRuns insights.SlowRunQueue) {
+	s.performance = policy
+	s.slowRuns = slowRuns
+}
+
+// SetEventPublisher sets the publisher used to emit job lifecycle events
+func (s *Service) SetEventPublisher(publisher events.Publisher) {
+	s.events = publisher
+}
+
+// SetResultNotifier sets the notifier that posts final job states to callback URLs
+func (s *Service) SetResultNotifier(notifier worker.ResultNotifier) {
+	s.notifier = notifier
+}
+
+// SetReadySignal lets the worker wake as soon as jobs become ready instead of waiting for the next poll
+func (s *Service) SetReadySignal(signal queue.ReadySignal) {
+	s.readySignal = signal
+}
+
+// SetBreaker pauses job types whose failure rate trips the breaker. The store is optional
+// and receives every state change so the API can report it.
+func (s *Service) SetBreaker(breaker *worker.FailureBreaker, store worker.BreakerStore) {
+	s.breaker = breaker
+	s.breakerStore = store
+}
+
+// SetPayloadSigner makes the worker verify payload signatures before executing jobs
+func (s *Service) SetPayloadSigner(signer *queue.PayloadSigner) {
+	s.signer = signer
+}
+
+// SetQuotaEnforcer meters the AI analyses of failed jobs against the quota of the tenant
+// that created them, and frees a pending job slot once a job completes or is dead-lettered
+func (s *Service) SetQuotaEnforcer(enforcer quota.Enforcer) {
+	s.quotas = enforcer
+}
+
+// SetFixOutcomeRecorder records whether jobs completed on the run after an insight's fix was
+// applied to them, so the effectiveness of each kind of fix can be measured
+func (s *Service) SetFixOutcomeRecorder(recorder insights.FixOutcomeRecorder) {
+	s.fixOutcomes = recorder
+}
+
+// SetDeadLetterQueue keeps the jobs that fail permanently in the queue backend's dead letters,
+// next to their failed status in the database, so they can be inspected and replayed
+func (s *Service) SetDeadLetterQueue(deadLetters queue.DeadLetterQueue) {
+	s.deadLetters = deadLetters
+}
+
+// SetActivity reports the jobs the worker runs and how they end to the tracker, which the
+// workers of a process share
+func (s *Service) SetActivity(activity *worker.Activity) {
+	s.activity = activity
+}
+
+// SetGroupRepository makes the worker finish the group of a job once the group's last job
+// completes or fails, releasing the group's completion job
+func (s *Service) SetGroupRepository(groups queue.GroupRepository) {
+	s.groups = groups
+}
+
+// SetAttemptRepository records every run of a job, so its failures can be told apart after
+// the next run overwrites its error
+func (s *Service) SetAttemptRepository(repo queue.AttemptRepository) {
+	s.attempts = repo
+}
+
+// SetBrokerHealth makes the worker back off while the queue broker is unreachable instead of
+// polling it at the usual pace, and report the outage to the tracker, which the workers of a
+// process share. The process is alerted on with a BrokerDegraded event once the tracker
+// counts enough failures in a row, and a BrokerRecovered event once a call succeeds again.
+func (s *Service) SetBrokerHealth(health *worker.BrokerHealth) {
+	s.brokerHealth = health
+}
+
+// UpdateConfig swaps the retry settings used for subsequent jobs.
+// The worker ID, queue name, capabilities, dequeue timeout and idle sleep are fixed for the
+// lifetime of the worker.
+func (s *Service) UpdateConfig(cfg *worker.WorkerConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := *cfg
+	updated.WorkerID = s.config.WorkerID
+	updated.QueueName = s.config.QueueName
+	updated.DequeueTimeout = s.config.DequeueTimeout
+	updated.IdleSleep = s.config.IdleSleep
+	updated.Capabilities = s.config.Capabilities
+	s.config = &updated
+}
+
+func (s *Service) currentConfig() *worker.WorkerConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// pollOutcome tells the worker loop how long to wait before polling again
+type pollOutcome int
+
+const (
+	pollHandled    pollOutcome = iota // A job was handled, poll again right away
+	pollEmpty                         // The pop waited and found no job, wait the idle sleep
+	pollSkipped                       // Nothing was popped or the job went back, wait at least the dequeue timeout
+	pollBrokerDown                    // The broker couldn't be reached, back off before polling again
+)
+
+// ProcessNextJob waits up to the dequeue timeout for the next job and processes it
 func (s *Service) ProcessNextJob(ctx context.Context) error {
+	_, err := s.processNextJob(ctx, s.currentConfig().DequeueTimeout)
+	return err
+}
+
+// processNextJob pops the next job, waiting up to the timeout for one; zero doesn't wait
+func (s *Service) processNextJob(ctx context.Context, timeout time.Duration) (pollOutcome, error) {
+	cfg := s.currentConfig()
+	if !s.queueOpen(ctx, cfg.QueueName) {
+		return pollSkipped, nil
+	}
+
 	// Dequeue a job
-	slog.InfoContext(ctx, "Polling queue for jobs",
-		slog.String("queue", s.config.QueueName),
+	slog.DebugContext(ctx, "Polling queue for jobs",
+		slog.String("queue", cfg.QueueName),
+		slog.Duration("dequeueTimeout", timeout),
 	)
-	job, err := s.queueService.Dequeue(ctx, s.config.QueueName)
+	job, err := s.queueService.Dequeue(ctx, cfg.QueueName, cfg.Capabilities, timeout)
 	if err != nil {
-		slog.ErrorContext(ctx, "Failed to dequeue job",
-			slog.String("error", err.Error()),
-			slog.String("queue", s.config.QueueName),
-		)
-		return err
+		if ctx.Err() != nil {
+			// The worker is shutting down
+			return pollSkipped, nil
+		}
+		s.brokerFailed(ctx, cfg, err)
+		return pollBrokerDown, err
 	}
+	s.brokerReached(ctx, cfg)
 
 	if job == nil {
 		// No jobs available
 		slog.DebugContext(ctx, "No jobs available in queue",
-			slog.String("queue", s.config.QueueName),
+			slog.String("queue", cfg.QueueName),
 		)
-		return nil
+		return pollEmpty, nil
 	}
 
+	// Everything logged while handling the job, including by the executor, carries its ID
+	ctx = logging.WithJobID(ctx, job.ID.String())
 	slog.InfoContext(ctx, "Dequeued job",
+		slog.String("workerId", cfg.WorkerID),
 		slog.String("jobId", job.ID.String()),
 		slog.String("jobType", job.Type),
 		slog.String("queue", job.Queue),
 		slog.Int("attempt", job.Attempts),
 	)
 
+	if !job.SatisfiedBy(cfg.Capabilities) {
+		slog.WarnContext(ctx, "Job requires capabilities the worker lacks, returning job to queue",
+			slog.String("jobId", job.ID.String()),
+			slog.Any("requires", job.Requires),
+			slog.Any("capabilities", cfg.Capabilities),
+		)
+		return pollSkipped, s.queueService.Nack(ctx, job)
+	}
+
+	if s.signer != nil {
+		if err := s.signer.Verify(job); err != nil {
+			return pollHandled, s.rejectUnverifiedJob(ctx, job, err)
+		}
+	}
+
+	if ready, err := s.prepareSchema(ctx, job); !ready {
+		return pollHandled, err
+	}
+
+	if !s.breakerAllows(ctx, job) {
+		slog.WarnContext(ctx, "Circuit open for job type, returning job to queue",
+			slog.String("jobId", job.ID.String()),
+			slog.String("jobType", job.Type),
+		)
+		// Popping it straight back would spin while the circuit is open
+		return pollSkipped, s.queueService.Nack(ctx, job)
+	}
+
+	return pollHandled, s.executeJob(ctx, job)
+}
+
+// executeJob runs a dequeued job and records its outcome
+func (s *Service) executeJob(ctx context.Context, job *queue.Job) error {
 	// Mark job as processing
 	slog.InfoContext(ctx, "Marking job as processing",
 		slog.String("jobId", job.ID.String()),
 	)
-	job.MarkAsProcessing()
-	if err := s.jobRepo.Update(ctx, job); err != nil {
+	s.recordFirstAttempt(job)
+	if err := job.MarkAsProcessing(); err != nil {
+		return s.dropDuplicate(ctx, job, err)
+	}
+	if err := s.updateJob(ctx, job); err != nil {
+		if errors.Is(err, queue.ErrInvalidTransition) {
+			return s.dropDuplicate(ctx, job, err)
+		}
 		slog.ErrorContext(ctx, "Failed to update job status to processing",
 			slog.String("jobId", job.ID.String()),
 			slog.String("error", err.Error()),
@@ -84,21 +291,45 @@ func (s *Service) ProcessNextJob(ctx context.Context) error {
 		slog.String("jobId", job.ID.String()),
 		slog.String("jobType", job.Type),
 	)
-	result, err := s.executor.Execute(ctx, job)
+	startedAt := time.Now()
+	s.activity.Started(job, startedAt)
+	runCtx, closeOutput := s.openOutput(ctx, job)
+	runCtx = s.withExecutionContext(runCtx, job, startedAt)
+	result, cacheKey, err := s.execute(runCtx, job)
+	closeOutput()
+	duration := time.Since(startedAt)
+	if result != nil {
+		s.recordResult(ctx, job, result.Output)
+	}
 	if err != nil || !result.Success {
+		execErr := err
+		if result != nil && result.Error != nil {
+			execErr = result.Error
+		}
 		slog.WarnContext(ctx, "Job execution failed",
 			slog.String("jobId", job.ID.String()),
-			slog.String("error", result.Error.Error()),
+			slog.String("error", execErr.Error()),
 		)
-		return s.handleJobFailure(ctx, job, result.Error)
+		s.activity.Finished(job, worker.OutcomeFailed, time.Now())
+		s.recordAttempt(ctx, job, startedAt, duration, execErr)
+		s.recordOutcome(ctx, job, execErr)
+		return s.handleJobFailure(ctx, job, execErr)
 	}
+	s.activity.Finished(job, worker.OutcomeCompleted, time.Now())
+	s.recordAttempt(ctx, job, startedAt, duration, nil)
+	s.recordOutcome(ctx, job, nil)
 
 	// Mark as completed
 	slog.InfoContext(ctx, "Job executed successfully",
 		slog.String("jobId", job.ID.String()),
 	)
-	job.MarkAsCompleted()
-	if err := s.jobRepo.Update(ctx, job); err != nil {
+	if err := job.MarkAsCompleted(); err != nil {
+		return s.dropDuplicate(ctx, job, err)
+	}
+	if err := s.updateJob(ctx, job); err != nil {
+		if errors.Is(err, queue.ErrInvalidTransition) {
+			return s.dropDuplicate(ctx, job, err)
+		}
 		slog.ErrorContext(ctx, "Failed to update job status to completed",
 			slog.String("jobId", job.ID.String()),
 			slog.String("error", err.Error()),
@@ -111,114 +342,551 @@ func (s *Service) ProcessNextJob(ctx context.Context) error {
 		slog.String("jobType", job.Type),
 		slog.String("queue", job.Queue),
 	)
+	s.cacheResult(ctx, job, cacheKey)
+	s.events.Publish(ctx, events.JobCompleted{
+		JobID:    job.ID,
+		Queue:    job.Queue,
+		Type:     job.Type,
+		Attempt:  job.Attempts,
+		Duration: duration,
+		At:       time.Now().UTC(),
+	})
+	s.releaseQuota(ctx, job)
+	s.recordFixOutcome(ctx, job, insights.FixOutcomeSucceeded)
+	s.queueSlowRun(ctx, job, duration)
+	s.notifyResult(ctx, job)
+	s.finishGroup(ctx, job)
 	// Acknowledge from queue
-	return s.queueService.Acknowledge(ctx, job.ID)
+	return s.acknowledge(ctx, job.ID)
 }
 
-// handleJobFailure handles job failure with retry logic and AI insights
+// rejectUnverifiedJob fails a job whose payload signature doesn't verify without executing it.
+// The failure is recorded on the stored job so the altered payload doesn't overwrite the original.
+func (s *Service) rejectUnverifiedJob(ctx context.Context, job *queue.Job, verifyErr error) error {
+	slog.ErrorContext(ctx, "Rejecting job with invalid payload signature",
+		slog.String("jobId", job.ID.String()),
+		slog.String("jobType", job.Type),
+		slog.String("signingKeyId", job.SigningKeyID),
+		slog.String("error", verifyErr.Error()),
+	)
+
+	stored, err := s.jobRepo.GetByID(ctx, job.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load stored job, failing the dequeued copy",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		stored = job
+	}
+	return s.handleJobFailure(ctx, stored, worker.NewPermanentError(verifyErr))
+}
+
+// handleJobFailure handles job failure with retry logic and queues AI analysis. The retry
+// policy of the job's type decides whether and when it's retried and what happens once it
+// fails for good.
 func (s *Service) handleJobFailure(ctx context.Context, job *queue.Job, execError error) error {
-	job.MarkAsFailed(execError)
+	cfg := s.currentConfig()
+	retry := s.retryPolicy(ctx, cfg, job.Type)
+	permanent := worker.IsPermanent(execError) || !retry.Retries(execError)
+	retryAfter, throttled := worker.RetryAfter(execError)
+	throttled = throttled && !permanent
+	mark := job.MarkAsFailed
+	if throttled {
+		// The downstream service turned the job away, so it doesn't use up an attempt
+		mark = job.MarkAsThrottled
+	}
+	// Marking a failed job counts its attempt, which a throttled run doesn't use up
+	attemptNumber := job.Attempts + 1
+	if err := mark(execError); err != nil {
+		return s.dropDuplicate(ctx, job, err)
+	}
+	retryable := throttled || (job.CanRetry(retry.MaxAttempts) && !permanent)
+	discard := !retryable && retry.DLQ == worker.DLQDiscard
+	s.events.Publish(ctx, events.JobFailed{
+		JobID:     job.ID,
+		Queue:     job.Queue,
+		Type:      job.Type,
+		Attempt:   job.Attempts,
+		Error:     job.Error,
+		Category:  queue.ClassifyFailure(execError),
+		Retryable: retryable,
+		Throttled: throttled,
+		At:        time.Now().UTC(),
+	})
+	if !throttled {
+		// A throttled run says nothing about whether an applied fix worked
+		s.recordFixOutcome(ctx, job, insights.FixOutcomeFailed)
+	}
 
-	// Generate AI insights for any job failure (before retry or permanent failure)
-	if s.insightsService != nil && job.Attempts == 1 {
-		jobIDStr := job.ID.String()
-		slog.InfoContext(ctx, "Generating AI insights for failed job",
-			slog.String("jobId", jobIDStr),
+	// Queue AI analysis for the failures the insight policy selects.
+	// Throttling is expected behaviour of the downstream service and isn't analyzed, nor are
+	// jobs their retry policy discards.
+	policy := s.insightPolicy(ctx, cfg)
+	if s.analysisQueue != nil && !throttled && !discard && policy.ShouldAnalyze(job, !retryable) && s.analysisAllowed(ctx, job) {
+		slog.InfoContext(ctx, "Queueing AI analysis for failed job",
+			slog.String("jobId", job.ID.String()),
 			slog.Int("attempt", job.Attempts),
+			slog.String("insightPolicy", string(policy)),
 		)
-		go func() {
-			// Run async to not block worker
-			_, err := s.insightsService.AnalyzeJobFailure(context.Background(), job.ID)
-			if err != nil {
-				slog.ErrorContext(context.Background(), "Failed to generate AI insights",
-					slog.String("jobId", jobIDStr),
-					slog.String("error", err.Error()),
-				)
-			} else {
-				slog.InfoContext(context.Background(), "AI insights generated successfully",
-					slog.String("jobId", jobIDStr),
-				)
-			}
-		}()
+		if err := s.analysisQueue.Enqueue(ctx, job.ID); err != nil {
+			slog.WarnContext(ctx, "Failed to queue AI analysis",
+				slog.String("jobId", job.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
 	}
 
-	if job.CanRetry(s.config.MaxAttempts) {
-		// Schedule retry with exponential backoff
-		backoff := worker.CalculateBackoff(job.Attempts, s.config.BaseBackoffMs)
+	if retryable {
+		// A throttled job waits as long as the downstream asked, so it doesn't spend the budget
+		if !throttled && !s.retryAllowed(job) {
+			return s.parkJob(ctx, job)
+		}
+
+		// Schedule retry with exponential backoff, or when the throttling service asked for
+		backoff := retry.Delay(job.Attempts)
+		if throttled {
+			backoff = retryAfter
+		}
 		retryTime := time.Now().UTC().Add(backoff)
 		job.Schedule(retryTime)
-		job.MarkAsRetrying()
+		if err := job.MarkAsRetrying(); err != nil {
+			return err
+		}
 
 		slog.InfoContext(ctx, "Job will retry with backoff",
 			slog.String("jobId", job.ID.String()),
 			slog.Duration("backoff", backoff),
+			slog.Bool("throttled", throttled),
 			slog.Int("attempt", job.Attempts),
-			slog.Int("maxAttempts", s.config.MaxAttempts),
+			slog.Int("maxAttempts", retry.MaxAttempts),
 		)
 
 		// Update job in database first
 		if err := s.jobRepo.Update(ctx, job); err != nil {
+			if errors.Is(err, queue.ErrInvalidTransition) {
+				return s.dropDuplicate(ctx, job, err)
+			}
 			slog.ErrorContext(ctx, "Failed to update job for retry",
 				slog.String("jobId", job.ID.String()),
 				slog.String("error", err.Error()),
 			)
 			return err
 		}
+		s.recordRetry(ctx, job, attemptNumber, backoff, retryTime)
 
-		// Wait for the backoff period, then re-enqueue
+		// Wait for the backoff period, then hand the job back to the queue
 		time.Sleep(backoff)
 		slog.InfoContext(ctx, "Re-enqueueing job for retry",
 			slog.String("jobId", job.ID.String()),
 		)
-		return s.queueService.Enqueue(ctx, job)
+		return s.queueService.Nack(ctx, job)
+	}
+
+	// Max attempts reached or non-retryable error - move to DLQ (AI analysis already queued on first failure)
+	reason := deadLetterReason(execError, retry)
+	if err := job.MarkAsDeadLettered(reason); err != nil {
+		return s.dropDuplicate(ctx, job, err)
+	}
+	slog.WarnContext(ctx, "Job failed permanently, moving to DLQ",
+		slog.String("jobId", job.ID.String()),
+		slog.Int("attempts", job.Attempts),
+		slog.String("reason", reason),
+	)
+
+	if err := s.jobRepo.MoveToDLQ(ctx, job.ID); err != nil {
+		if errors.Is(err, queue.ErrInvalidTransition) {
+			return s.dropDuplicate(ctx, job, err)
+		}
+		slog.ErrorContext(ctx, "Failed to move job to DLQ",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	slog.InfoContext(ctx, "Job moved to DLQ",
+		slog.String("jobId", job.ID.String()),
+	)
+	s.releaseQuota(ctx, job)
+
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		return err
+	}
+	// Published once the failed job is stored, so subscribers reading it see its error
+	s.events.Publish(ctx, events.JobMovedToDLQ{
+		JobID:    job.ID,
+		Queue:    job.Queue,
+		Type:     job.Type,
+		Attempts: job.Attempts,
+		Reason:   reason,
+		At:       time.Now().UTC(),
+	})
+	s.notifyResult(ctx, job)
+	s.finishGroup(ctx, job)
+
+	// The failure is recorded in the DLQ; the message must not be redelivered
+	if discard {
+		return s.discard(ctx, job)
+	}
+	return s.deadLetter(ctx, job)
+}
+
+// deadLetterReason tells why a job that failed for good with execErr is moved to the DLQ
+func deadLetterReason(execErr error, retry worker.RetryPolicy) string {
+	switch {
+	case worker.IsPermanent(execErr):
+		return queue.DeadLetterNonRetryableError
+	case !retry.Retries(execErr):
+		return queue.DeadLetterCancelledByPolicy
+	case queue.ClassifyFailure(execErr) == queue.FailureTimeout:
+		return queue.DeadLetterExpired
+	default:
+		return queue.DeadLetterMaxAttempts
+	}
+}
+
+// discard soft-deletes a job its retry policy doesn't keep in the DLQ and acknowledges it.
+// The job is acknowledged even when it can't be deleted, since it already failed for good.
+func (s *Service) discard(ctx context.Context, job *queue.Job) error {
+	if err := s.jobRepo.Delete(ctx, job.ID); err != nil {
+		slog.WarnContext(ctx, "Failed to discard job, leaving it in the DLQ",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
 	} else {
-		// Max attempts reached - move to DLQ (AI insights already generated on first failure)
-		slog.WarnContext(ctx, "Job failed permanently, moving to DLQ",
+		slog.InfoContext(ctx, "Job discarded by its retry policy",
 			slog.String("jobId", job.ID.String()),
-			slog.Int("attempts", job.Attempts),
-			slog.String("reason", "max_attempts_exceeded"),
+			slog.String("jobType", job.Type),
 		)
+	}
+	return s.queueService.Acknowledge(ctx, job.ID)
+}
 
-		if err := s.jobRepo.MoveToDLQ(ctx, job.ID); err != nil {
-			slog.ErrorContext(ctx, "Failed to move job to DLQ",
-				slog.String("jobId", job.ID.String()),
-				slog.String("error", err.Error()),
-			)
-			return err
+// finishGroup finishes the group of a job that completed or failed for good when it was the
+// group's last job left. The job's final status must be stored already.
+func (s *Service) finishGroup(ctx context.Context, job *queue.Job) {
+	if s.groups == nil || job.GroupID == nil {
+		return
+	}
+	now := time.Now().UTC()
+	finished, err := s.groups.Finish(ctx, *job.GroupID, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to finish job group",
+			slog.String("jobId", job.ID.String()),
+			slog.String("groupId", job.GroupID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if !finished {
+		return
+	}
+	slog.InfoContext(ctx, "Job group completed",
+		slog.String("groupId", job.GroupID.String()),
+	)
+	s.events.Publish(ctx, events.GroupCompleted{
+		GroupID: *job.GroupID,
+		At:      now,
+	})
+}
+
+// deadLetter acknowledges a job that failed permanently, keeping it in the backend's dead
+// letters when they are enabled. The job is acknowledged anyway when it can't be kept there,
+// since its failed status in the database is what counts.
+func (s *Service) deadLetter(ctx context.Context, job *queue.Job) error {
+	if s.deadLetters != nil {
+		err := s.deadLetters.DeadLetter(ctx, job)
+		if err == nil {
+			return nil
 		}
+		slog.WarnContext(ctx, "Failed to keep job in the dead letter queue",
+			slog.String("jobId", job.ID.String()),
+			slog.String("queue", job.Queue),
+			slog.String("error", err.Error()),
+		)
+	}
+	return s.queueService.Acknowledge(ctx, job.ID)
+}
+
+// dropDuplicate acknowledges a delivery whose job already moved on, e.g. a duplicate
+// delivery of a job another worker completed, without touching the stored job
+func (s *Service) dropDuplicate(ctx context.Context, job *queue.Job, transitionErr error) error {
+	slog.WarnContext(ctx, "Dropping duplicate delivery",
+		slog.String("jobId", job.ID.String()),
+		slog.String("error", transitionErr.Error()),
+	)
+	return s.queueService.Acknowledge(ctx, job.ID)
+}
+
+// breakerAllows reports whether the job's type may run, announcing the end of a cooldown
+func (s *Service) breakerAllows(ctx context.Context, job *queue.Job) bool {
+	if s.breaker == nil {
+		return true
+	}
+	allowed, reclosed := s.breaker.Allow(job.Type)
+	if reclosed {
+		cfg := s.currentConfig()
+		slog.InfoContext(ctx, "Circuit closed, resuming job type",
+			slog.String("jobType", job.Type),
+			slog.String("workerId", cfg.WorkerID),
+		)
+		s.events.Publish(ctx, events.CircuitClosed{
+			JobType:  job.Type,
+			WorkerID: cfg.WorkerID,
+			At:       time.Now().UTC(),
+		})
+		s.saveBreakerStatus(ctx, job.Type)
+	}
+	return allowed
+}
+
+// recordOutcome feeds an execution result to the breaker. Permanent errors are about the
+// job itself rather than its dependencies, so they don't count as failures.
+func (s *Service) recordOutcome(ctx context.Context, job *queue.Job, execErr error) {
+	if s.breaker == nil || worker.IsPermanent(execErr) {
+		return
+	}
+	if !s.breaker.Record(job.Type, execErr != nil) {
+		return
+	}
+
+	cfg := s.currentConfig()
+	status := s.breaker.Status(job.Type)
+	slog.WarnContext(ctx, "Circuit opened, pausing job type",
+		slog.String("jobType", job.Type),
+		slog.Float64("failureRate", status.FailureRate),
+		slog.Int("samples", status.Samples),
+		slog.Time("openUntil", *status.OpenUntil),
+	)
+	s.events.Publish(ctx, events.CircuitOpened{
+		JobType:     job.Type,
+		FailureRate: status.FailureRate,
+		Samples:     status.Samples,
+		OpenUntil:   *status.OpenUntil,
+		WorkerID:    cfg.WorkerID,
+		At:          time.Now().UTC(),
+	})
+	s.saveBreakerStatus(ctx, job.Type)
+}
+
+func (s *Service) saveBreakerStatus(ctx context.Context, jobType string) {
+	if s.breakerStore == nil {
+		return
+	}
+	status := s.breaker.Status(jobType)
+	status.WorkerID = s.currentConfig().WorkerID
+	if err := s.breakerStore.Save(ctx, status); err != nil {
+		slog.WarnContext(ctx, "Failed to save circuit breaker state",
+			slog.String("jobType", jobType),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// analysisAllowed counts the AI analysis of a failed job against the quota of the tenant
+// that created it. Analyses over quota are skipped; the job itself still retries or fails.
+func (s *Service) analysisAllowed(ctx context.Context, job *queue.Job) bool {
+	if s.quotas == nil {
+		return true
+	}
+	tenant, err := s.quotas.TenantOf(ctx, job.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to look up job tenant, analyzing anyway",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return true
+	}
+	if err := s.quotas.ReserveAnalysis(ctx, tenant); err != nil {
+		slog.WarnContext(ctx, "Skipping AI analysis over quota",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+	return true
+}
+
+// queueSlowRun queues a completed run for a performance analysis when it was slower than the
+// SLO of its job type and the policy selects it
+func (s *Service) queueSlowRun(ctx context.Context, job *queue.Job, duration time.Duration) {
+	if s.performance == nil || s.slowRuns == nil {
+		return
+	}
+	run, selected := s.performance.Select(job, duration, time.Now())
+	if !selected || !s.analysisAllowed(ctx, job) {
+		return
+	}
+
+	slog.InfoContext(ctx, "Queueing AI performance analysis for slow job",
+		slog.String("jobId", job.ID.String()),
+		slog.String("jobType", job.Type),
+		slog.Duration("duration", run.Duration),
+		slog.Duration("slo", run.SLO),
+	)
+	if err := s.slowRuns.Enqueue(ctx, run); err != nil {
+		slog.WarnContext(ctx, "Failed to queue AI performance analysis",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
 
-		slog.InfoContext(ctx, "Job moved to DLQ",
+// releaseQuota frees the pending job slot of a job that completed or was dead-lettered
+func (s *Service) releaseQuota(ctx context.Context, job *queue.Job) {
+	if s.quotas == nil {
+		return
+	}
+	if err := s.quotas.ReleaseJob(ctx, job.ID); err != nil {
+		slog.WarnContext(ctx, "Failed to release pending job quota",
 			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
 		)
 	}
+}
 
-	return s.jobRepo.Update(ctx, job)
+// recordFixOutcome resolves the fixes applied to the job with the outcome of its run
+func (s *Service) recordFixOutcome(ctx context.Context, job *queue.Job, outcome insights.FixOutcome) {
+	if s.fixOutcomes == nil {
+		return
+	}
+	if err := s.fixOutcomes.RecordFixOutcome(ctx, job.ID, outcome, time.Now().UTC()); err != nil {
+		slog.WarnContext(ctx, "Failed to record fix outcome",
+			slog.String("jobId", job.ID.String()),
+			slog.String("outcome", string(outcome)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// notifyResult hands a job in its final state to the notifier when the job has a callback URL
+func (s *Service) notifyResult(ctx context.Context, job *queue.Job) {
+	if s.notifier == nil || job.CallbackURL == "" {
+		return
+	}
+	s.notifier.NotifyResult(ctx, job)
+}
+
+// withExecutionContext returns the context the job runs with, describing the run to the
+// executor with a logger scoped to the job
+func (s *Service) withExecutionContext(ctx context.Context, job *queue.Job, startedAt time.Time) context.Context {
+	cfg := s.currentConfig()
+	retry := s.retryPolicy(ctx, cfg, job.Type)
+	exec := worker.NewExecutionContext(ctx, slog.Default(), job, cfg.WorkerID, retry.MaxAttempts, startedAt)
+	return worker.WithExecutionContext(ctx, exec)
+}
+
+// recordAttempt stores the run of the job; a run that can't be stored is only logged
+func (s *Service) recordAttempt(ctx context.Context, job *queue.Job, startedAt time.Time, duration time.Duration, execErr error) {
+	if s.attempts == nil {
+		return
+	}
+	attempt := queue.NewAttempt(job, s.currentConfig().WorkerID, startedAt, duration, execErr)
+	if err := s.attempts.RecordAttempt(ctx, attempt); err != nil {
+		slog.WarnContext(ctx, "Failed to record job attempt",
+			slog.String("jobId", job.ID.String()),
+			slog.Int("attempt", attempt.Number),
+			slog.String("error", err.Error()),
+		)
+	}
 }
 
-// Start starts the worker processing loop
+// recordRetry stores when the failed attempt is retried; a retry that can't be stored is only logged
+func (s *Service) recordRetry(ctx context.Context, job *queue.Job, number int, backoff time.Duration, retryAt time.Time) {
+	if s.attempts == nil {
+		return
+	}
+	if err := s.attempts.RecordRetry(ctx, job.ID, number, backoff, retryAt); err != nil {
+		slog.WarnContext(ctx, "Failed to record job retry",
+			slog.String("jobId", job.ID.String()),
+			slog.Int("attempt", number),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// recordResult stores the executor output on the job; output that can't be encoded is dropped
+func (s *Service) recordResult(ctx context.Context, job *queue.Job, output any) {
+	if output == nil {
+		return
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to encode job result",
+			slog.String("jobId", job.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	job.RecordResult(data)
+}
+
+// Start starts the worker processing loop. The worker long-polls the queue with bounded
+// blocking pops, so a job is picked up as soon as it's pushed; the idle sleep only
+// throttles polling an empty queue, and a job notification cuts it short.
 func (s *Service) Start(ctx context.Context) {
+	cfg := s.currentConfig()
 	slog.InfoContext(ctx, "Worker started",
-		slog.String("queue", s.config.QueueName),
-		slog.Duration("pollInterval", s.config.PollInterval),
-		slog.Int("maxAttempts", s.config.MaxAttempts),
+		slog.String("workerId", cfg.WorkerID),
+		slog.String("queue", cfg.QueueName),
+		slog.Duration("dequeueTimeout", cfg.DequeueTimeout),
+		slog.Duration("idleSleep", cfg.IdleSleep),
+		slog.Any("capabilities", cfg.Capabilities),
+		slog.Int("maxAttempts", cfg.MaxAttempts),
 	)
 
-	ticker := time.NewTicker(s.config.PollInterval)
-	defer ticker.Stop()
+	var ready <-chan struct{}
+	if s.readySignal != nil {
+		ready = s.readySignal.Ready(cfg.QueueName)
+	}
 
 	for {
-		select {
-		case <-ctx.Done():
+		outcome, err := s.processNextJob(ctx, cfg.DequeueTimeout)
+		if err != nil && outcome != pollBrokerDown {
+			slog.ErrorContext(ctx, "Error processing job",
+				slog.String("error", err.Error()),
+			)
+		}
+
+		var wait time.Duration
+		switch outcome {
+		case pollEmpty:
+			wait = cfg.IdleSleep
+		case pollSkipped:
+			wait = max(cfg.DequeueTimeout, cfg.IdleSleep)
+		case pollBrokerDown:
+			wait = max(s.brokerBackoff(cfg), cfg.IdleSleep)
+		}
+		if !s.waitToPoll(ctx, ready, wait) {
 			slog.InfoContext(ctx, "Worker shutting down",
-				slog.String("queue", s.config.QueueName),
+				slog.String("workerId", cfg.WorkerID),
+				slog.String("queue", cfg.QueueName),
 			)
 			return
-		case <-ticker.C:
-			if err := s.ProcessNextJob(ctx); err != nil {
-				slog.ErrorContext(ctx, "Error processing job",
-					slog.String("error", err.Error()),
-				)
-			}
 		}
 	}
 }
+
+// waitToPoll waits before the next poll until the wait elapses or a job notification
+// arrives. It returns false once the worker is shutting down.
+func (s *Service) waitToPoll(ctx context.Context, ready <-chan struct{}, wait time.Duration) bool {
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-ready:
+		slog.DebugContext(ctx, "Woken by job notification",
+			slog.String("queue", s.currentConfig().QueueName),
+		)
+		return true
+	case <-timer.C:
+		return true
+	}
+}
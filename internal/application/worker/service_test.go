@@ -1,17 +1,22 @@
 package worker
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
-	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/events"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/quota"
 	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock implementations
@@ -27,6 +32,14 @@ func (m *MockJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*queue.J
 	return args.Get(0).(*queue.Job), args.Error(1)
 }
 
+func (m *MockJobRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*queue.Job, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Job), args.Error(1)
+}
+
 func (m *MockJobRepository) Create(ctx context.Context, job *queue.Job) error {
 	args := m.Called(ctx, job)
 	return args.Error(0)
@@ -42,6 +55,16 @@ func (m *MockJobRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockJobRepository) Undelete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockJobRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockJobRepository) FindPendingJobs(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
 	args := m.Called(ctx, queueName, limit)
 	if args.Get(0) == nil {
@@ -50,6 +73,14 @@ func (m *MockJobRepository) FindPendingJobs(ctx context.Context, queueName strin
 	return args.Get(0).([]*queue.Job), args.Error(1)
 }
 
+func (m *MockJobRepository) FindDueDelayedJobs(ctx context.Context, now time.Time, limit int) ([]*queue.Job, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.Job), args.Error(1)
+}
+
 func (m *MockJobRepository) FindByStatus(ctx context.Context, status queue.Status, limit int) ([]*queue.Job, error) {
 	args := m.Called(ctx, status, limit)
 	if args.Get(0) == nil {
@@ -63,6 +94,35 @@ func (m *MockJobRepository) CountByStatus(ctx context.Context, status queue.Stat
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockJobRepository) CountDelayed(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockJobRepository) Search(ctx context.Context, criteria queue.SearchCriteria) ([]*queue.Job, int64, error) {
+	args := m.Called(ctx, criteria)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*queue.Job), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockJobRepository) ListByQueue(ctx context.Context, listing queue.QueueListing) ([]*queue.Job, int64, error) {
+	args := m.Called(ctx, listing)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*queue.Job), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockJobRepository) Activity(ctx context.Context, since time.Time, topTypes int) (*queue.Activity, error) {
+	args := m.Called(ctx, since, topTypes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.Activity), args.Error(1)
+}
+
 func (m *MockJobRepository) MoveToDLQ(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -90,8 +150,8 @@ func (m *MockQueueService) Enqueue(ctx context.Context, job *queue.Job) error {
 	return args.Error(0)
 }
 
-func (m *MockQueueService) Dequeue(ctx context.Context, queueName string) (*queue.Job, error) {
-	args := m.Called(ctx, queueName)
+func (m *MockQueueService) Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*queue.Job, error) {
+	args := m.Called(ctx, queueName, capabilities, timeout)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -103,6 +163,19 @@ func (m *MockQueueService) Acknowledge(ctx context.Context, jobID uuid.UUID) err
 	return args.Error(0)
 }
 
+func (m *MockQueueService) Nack(ctx context.Context, job *queue.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *MockQueueService) DeliveryStats(ctx context.Context) ([]*queue.DeliveryStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*queue.DeliveryStats), args.Error(1)
+}
+
 type MockJobExecutor struct {
 	mock.Mock
 }
@@ -120,16 +193,56 @@ func (m *MockJobExecutor) CanHandle(jobType string) bool {
 	return args.Bool(0)
 }
 
-type MockInsightsService struct {
+type MockAnalysisQueue struct {
 	mock.Mock
 }
 
-func (m *MockInsightsService) AnalyzeJobFailure(ctx context.Context, jobID uuid.UUID) (*appInsights.Service, error) {
+func (m *MockAnalysisQueue) Enqueue(ctx context.Context, jobID uuid.UUID) error {
 	args := m.Called(ctx, jobID)
+	return args.Error(0)
+}
+
+func (m *MockAnalysisQueue) Dequeue(ctx context.Context, timeout time.Duration) (*uuid.UUID, error) {
+	args := m.Called(ctx, timeout)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*appInsights.Service), args.Error(1)
+	return args.Get(0).(*uuid.UUID), args.Error(1)
+}
+
+func (m *MockAnalysisQueue) Complete(ctx context.Context, jobID uuid.UUID) error {
+	args := m.Called(ctx, jobID)
+	return args.Error(0)
+}
+
+func (m *MockAnalysisQueue) Recover(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+// RecordingPublisher collects published events for assertions
+type RecordingPublisher struct {
+	events []events.Event
+}
+
+func (p *RecordingPublisher) Publish(ctx context.Context, event events.Event) {
+	p.events = append(p.events, event)
+}
+
+func (p *RecordingPublisher) Names() []string {
+	names := make([]string, 0, len(p.events))
+	for _, event := range p.events {
+		names = append(names, event.Name())
+	}
+	return names
+}
+
+type RecordingNotifier struct {
+	statuses []queue.Status
+}
+
+func (n *RecordingNotifier) NotifyResult(ctx context.Context, job *queue.Job) {
+	n.statuses = append(n.statuses, job.Status)
 }
 
 func TestService_ProcessNextJob(t *testing.T) {
@@ -151,7 +264,7 @@ func TestService_ProcessNextJob(t *testing.T) {
 				setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, executor *MockJobExecutor) {
 					job, _ := queue.NewJob("default", "email", []byte(`{"to":"test@example.com"}`))
 
-					queueSvc.On("Dequeue", mock.Anything, "default").Return(job, nil)
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
 					repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).Times(2)
 					executor.On("Execute", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(
 						&worker.ExecutionResult{Success: true, Error: nil}, nil,
@@ -177,7 +290,7 @@ func TestService_ProcessNextJob(t *testing.T) {
 				setupMocks func(*MockJobRepository, *MockQueueService, *MockJobExecutor)
 			}{
 				setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, executor *MockJobExecutor) {
-					queueSvc.On("Dequeue", mock.Anything, "default").Return(nil, nil)
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(nil, nil)
 				},
 			},
 			want: struct {
@@ -193,7 +306,7 @@ func TestService_ProcessNextJob(t *testing.T) {
 				setupMocks func(*MockJobRepository, *MockQueueService, *MockJobExecutor)
 			}{
 				setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, executor *MockJobExecutor) {
-					queueSvc.On("Dequeue", mock.Anything, "default").Return(nil, errors.New("dequeue failed"))
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(nil, errors.New("dequeue failed"))
 				},
 			},
 			want: struct {
@@ -212,13 +325,13 @@ func TestService_ProcessNextJob(t *testing.T) {
 					job, _ := queue.NewJob("default", "email", []byte(`{"to":"test@example.com"}`))
 					job.Attempts = 1
 
-					queueSvc.On("Dequeue", mock.Anything, "default").Return(job, nil)
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
 					repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).Times(2)
 					executor.On("Execute", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(
 						&worker.ExecutionResult{Success: false, Error: errors.New("execution failed")}, nil,
 					)
 					// Add expectation for re-enqueue after retry backoff
-					queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+					queueSvc.On("Nack", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
 				},
 			},
 			want: struct {
@@ -242,12 +355,13 @@ func TestService_ProcessNextJob(t *testing.T) {
 					job, _ := queue.NewJob("default", "email", []byte(`{"to":"test@example.com"}`))
 					job.Attempts = 3 // At max attempts
 
-					queueSvc.On("Dequeue", mock.Anything, "default").Return(job, nil)
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
 					repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).Times(2)
 					executor.On("Execute", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(
 						&worker.ExecutionResult{Success: false, Error: errors.New("execution failed")}, nil,
 					)
 					repo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+					queueSvc.On("Acknowledge", mock.Anything, job.ID).Return(nil)
 				},
 			},
 			want: struct {
@@ -262,6 +376,35 @@ func TestService_ProcessNextJob(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Given job execution fails permanently on first attempt, When processing job, Then should move to DLQ without retrying",
+			in: struct {
+				setupMocks func(*MockJobRepository, *MockQueueService, *MockJobExecutor)
+			}{
+				setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, executor *MockJobExecutor) {
+					job, _ := queue.NewJob("default", "email", []byte(`{"to":"test@example.com"}`))
+
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+					repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).Times(2)
+					executor.On("Execute", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(
+						&worker.ExecutionResult{Success: false, Error: worker.NewPermanentError(errors.New("data validation failed: missing required fields"))}, nil,
+					)
+					repo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+					queueSvc.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+				},
+			},
+			want: struct {
+				err         bool
+				validateJob func(*testing.T, *MockJobRepository)
+			}{
+				err: false,
+				validateJob: func(t *testing.T, repo *MockJobRepository) {
+					job := repo.Calls[0].Arguments.Get(1).(*queue.Job)
+					assert.Equal(t, queue.StatusFailed, job.Status)
+					assert.Equal(t, 1, job.Attempts)
+				},
+			},
+		},
 		{
 			name: "Given repository update fails, When marking job as processing, Then should return error",
 			in: struct {
@@ -270,7 +413,7 @@ func TestService_ProcessNextJob(t *testing.T) {
 				setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, executor *MockJobExecutor) {
 					job, _ := queue.NewJob("default", "email", []byte(`{"to":"test@example.com"}`))
 
-					queueSvc.On("Dequeue", mock.Anything, "default").Return(job, nil)
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
 					repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(errors.New("update failed"))
 				},
 			},
@@ -289,14 +432,14 @@ func TestService_ProcessNextJob(t *testing.T) {
 				setupMocks: func(repo *MockJobRepository, queueSvc *MockQueueService, executor *MockJobExecutor) {
 					job, _ := queue.NewJob("default", "email", []byte(`{"to":"test@example.com"}`))
 
-					queueSvc.On("Dequeue", mock.Anything, "default").Return(job, nil)
+					queueSvc.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
 					repo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).Times(2)
 					executor.On("Execute", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(
 						&worker.ExecutionResult{Success: false, Error: errors.New("executor error")},
 						errors.New("executor error"),
 					)
 					// Add expectation for re-enqueue after retry backoff
-					queueSvc.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+					queueSvc.On("Nack", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
 				},
 			},
 			want: struct {
@@ -432,12 +575,13 @@ func TestService_HandleJobFailure_WithRetry(t *testing.T) {
 
 			mockRepo := new(MockJobRepository)
 			mockQueue := new(MockQueueService)
+			mockQueue.On("Acknowledge", mock.Anything, mock.Anything).Return(nil).Maybe()
 			mockExecutor := new(MockJobExecutor)
 			tt.in.setupMocks(mockRepo)
 
 			// Add Enqueue expectation for retry case
 			if tt.in.jobAttempts < tt.in.maxAttempts {
-				mockQueue.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+				mockQueue.On("Nack", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
 			}
 
 			config, _ := worker.NewWorkerConfig("default", tt.in.maxAttempts, 500)
@@ -509,6 +653,7 @@ func TestService_HandleJobFailure_DLQError(t *testing.T) {
 
 			mockRepo := new(MockJobRepository)
 			mockQueue := new(MockQueueService)
+			mockQueue.On("Acknowledge", mock.Anything, mock.Anything).Return(nil).Maybe()
 			mockExecutor := new(MockJobExecutor)
 			tt.in.setupMocks(mockRepo)
 
@@ -585,6 +730,7 @@ func TestService_HandleJobFailure_UpdateError(t *testing.T) {
 
 			mockRepo := new(MockJobRepository)
 			mockQueue := new(MockQueueService)
+			mockQueue.On("Acknowledge", mock.Anything, mock.Anything).Return(nil).Maybe()
 			mockExecutor := new(MockJobExecutor)
 			tt.in.setupMocks(mockRepo)
 
@@ -679,7 +825,7 @@ func TestService_ExponentialBackoff(t *testing.T) {
 			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil).Times(2)
 
 			mockQueue := new(MockQueueService)
-			mockQueue.On("Enqueue", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockQueue.On("Nack", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
 
 			config, _ := worker.NewWorkerConfig("default", 5, int(tt.in.baseBackoff.Milliseconds()))
 			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), nil, config)
@@ -703,3 +849,2149 @@ func TestService_ExponentialBackoff(t *testing.T) {
 		})
 	}
 }
+
+func TestService_UpdateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			maxAttempts   int
+			baseBackoffMs int
+		}
+		want struct {
+			maxAttempts   int
+			baseBackoffMs int
+		}
+	}{
+		{
+			name: "Given new retry settings, When updating config, Then should apply them and keep queue name and dequeue timing",
+			in: struct {
+				maxAttempts   int
+				baseBackoffMs int
+			}{
+				maxAttempts:   5,
+				baseBackoffMs: 1000,
+			},
+			want: struct {
+				maxAttempts   int
+				baseBackoffMs int
+			}{
+				maxAttempts:   5,
+				baseBackoffMs: 1000,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(new(MockJobRepository), new(MockQueueService), new(MockJobExecutor), nil, config)
+			updated, _ := worker.NewWorkerConfig("other", tt.in.maxAttempts, tt.in.baseBackoffMs)
+			updated.DequeueTimeout = time.Minute
+			updated.IdleSleep = time.Minute
+
+			// When
+			service.UpdateConfig(updated)
+
+			// Then
+			current := service.currentConfig()
+			assert.Equal(t, tt.want.maxAttempts, current.MaxAttempts)
+			assert.Equal(t, tt.want.baseBackoffMs, current.BaseBackoffMs)
+			assert.Equal(t, "default", current.QueueName)
+			assert.Equal(t, worker.DefaultDequeueTimeout, current.DequeueTimeout)
+			assert.Equal(t, time.Duration(0), current.IdleSleep)
+		})
+	}
+}
+
+func TestService_HandleJobFailure_PermanentError(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			execErr error
+		}
+		want struct {
+			status queue.Status
+		}
+	}{
+		{
+			name: "Given a permanent error on first attempt, When handling job failure, Then should move to DLQ without retrying",
+			in:   struct{ execErr error }{execErr: worker.NewPermanentError(errors.New("command is not in the allow-list"))},
+			want: struct{ status queue.Status }{status: queue.StatusFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "command", []byte(`{"command":"rm"}`))
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), nil, config)
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, tt.in.execErr)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.status, job.Status)
+			assert.Equal(t, 1, job.Attempts)
+			mockRepo.AssertExpectations(t)
+			mockQueue.AssertExpectations(t)
+			mockQueue.AssertNotCalled(t, "Nack", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_PublishesEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			result  *worker.ExecutionResult
+			execErr error
+		}
+		want struct {
+			names []string
+		}
+	}{
+		{
+			name: "Given a job that succeeds, When processing it, Then should publish JobCompleted",
+			in: struct {
+				result  *worker.ExecutionResult
+				execErr error
+			}{result: &worker.ExecutionResult{Success: true}},
+			want: struct{ names []string }{names: []string{events.NameJobCompleted}},
+		},
+		{
+			name: "Given a job failing permanently, When processing it, Then should publish JobFailed and JobMovedToDLQ",
+			in: struct {
+				result  *worker.ExecutionResult
+				execErr error
+			}{execErr: worker.NewPermanentError(errors.New("unsupported job type"))},
+			want: struct{ names []string }{names: []string{events.NameJobFailed, events.NameJobMovedToDLQ}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(tt.in.result, tt.in.execErr)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.names, publisher.Names())
+		})
+	}
+}
+
+// MemoryResultCache is a worker.ResultCache over a map, ignoring TTLs
+type MemoryResultCache struct {
+	results map[string][]byte
+}
+
+func (c *MemoryResultCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	result, found := c.results[key]
+	return result, found, nil
+}
+
+func (c *MemoryResultCache) Set(ctx context.Context, key string, result []byte, ttl time.Duration) error {
+	c.results[key] = result
+	return nil
+}
+
+func TestService_ResultCache(t *testing.T) {
+	// Given an idempotent report type keyed by its dataset, and an email type that isn't idempotent
+	cache := &MemoryResultCache{results: map[string][]byte{}}
+	policies := worker.IdempotencyPolicies{"report": {KeyFields: []string{"dataset"}, TTL: time.Minute}}
+	jobs := []*queue.Job{}
+	for _, spec := range []struct{ jobType, payload string }{
+		{"report", `{"dataset": "sales", "requested_by": "ana"}`},
+		{"report", `{"requested_by": "bo", "dataset": "sales"}`},
+		{"report", `{"dataset": "stock"}`},
+		{"email", `{"to": "a@example.com"}`},
+		{"email", `{"to": "a@example.com"}`},
+	} {
+		job, err := queue.NewJob("default", spec.jobType, []byte(spec.payload))
+		require.NoError(t, err)
+		jobs = append(jobs, job)
+	}
+
+	mockRepo := new(MockJobRepository)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+	mockQueue := new(MockQueueService)
+	for _, job := range jobs {
+		mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil).Once()
+		mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+	}
+	mockExecutor := new(MockJobExecutor)
+	mockExecutor.On("Execute", mock.Anything, mock.AnythingOfType("*queue.Job")).
+		Return(&worker.ExecutionResult{Success: true, Output: map[string]any{"rows": 42}}, nil)
+
+	config, _ := worker.NewWorkerConfig("default", 3, 500)
+	service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+	service.SetResultCache(cache, policies)
+
+	// When
+	for range jobs {
+		require.NoError(t, service.ProcessNextJob(context.Background()))
+	}
+
+	// Then the duplicate report returns the first one's result without running, while the
+	// other dataset and the emails run
+	mockExecutor.AssertNumberOfCalls(t, "Execute", 4)
+	mockExecutor.AssertNotCalled(t, "Execute", mock.Anything, jobs[1])
+	assert.Equal(t, queue.StatusCompleted, jobs[1].Status)
+	assert.JSONEq(t, `{"rows": 42}`, string(jobs[1].Result))
+	assert.Len(t, cache.results, 2, "Given the email type isn't idempotent, Then only the reports should be cached")
+}
+
+// StaticGroupRepository is a queue.GroupRepository whose Finish claims the group when told to
+type StaticGroupRepository struct {
+	claims   bool
+	finished []uuid.UUID
+}
+
+func (r *StaticGroupRepository) Create(ctx context.Context, group *queue.Group) error {
+	return nil
+}
+
+func (r *StaticGroupRepository) Get(ctx context.Context, id uuid.UUID) (*queue.Group, error) {
+	return nil, queue.ErrGroupNotFound
+}
+
+func (r *StaticGroupRepository) Progress(ctx context.Context, id uuid.UUID) (queue.GroupProgress, error) {
+	return queue.GroupProgress{}, nil
+}
+
+func (r *StaticGroupRepository) Resize(ctx context.Context, id uuid.UUID, size int) error {
+	return nil
+}
+
+func (r *StaticGroupRepository) Finish(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	r.finished = append(r.finished, id)
+	return r.claims, nil
+}
+
+func TestService_FinishesGroups(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			grouped bool
+			claims  bool
+			execErr error
+		}
+		want struct {
+			finishCalls int
+			names       []string
+		}
+	}{
+		{
+			name: "Given the last job of a group, When it succeeds, Then should finish the group and publish GroupCompleted",
+			in: struct {
+				grouped bool
+				claims  bool
+				execErr error
+			}{grouped: true, claims: true},
+			want: struct {
+				finishCalls int
+				names       []string
+			}{finishCalls: 1, names: []string{events.NameJobCompleted, events.NameGroupCompleted}},
+		},
+		{
+			name: "Given a job of a group with jobs left, When it succeeds, Then should try finishing the group without publishing",
+			in: struct {
+				grouped bool
+				claims  bool
+				execErr error
+			}{grouped: true},
+			want: struct {
+				finishCalls int
+				names       []string
+			}{finishCalls: 1, names: []string{events.NameJobCompleted}},
+		},
+		{
+			name: "Given the last job of a group, When it fails permanently, Then should finish the group",
+			in: struct {
+				grouped bool
+				claims  bool
+				execErr error
+			}{grouped: true, claims: true, execErr: worker.NewPermanentError(errors.New("unsupported job type"))},
+			want: struct {
+				finishCalls int
+				names       []string
+			}{finishCalls: 1, names: []string{events.NameJobFailed, events.NameJobMovedToDLQ, events.NameGroupCompleted}},
+		},
+		{
+			name: "Given a job of a group, When it fails and will be retried, Then should not try finishing the group",
+			in: struct {
+				grouped bool
+				claims  bool
+				execErr error
+			}{grouped: true, claims: true, execErr: errors.New("timeout")},
+			want: struct {
+				finishCalls int
+				names       []string
+			}{names: []string{events.NameJobFailed}},
+		},
+		{
+			name: "Given a job without a group, When it succeeds, Then should not touch any group",
+			in: struct {
+				grouped bool
+				claims  bool
+				execErr error
+			}{claims: true},
+			want: struct {
+				finishCalls int
+				names       []string
+			}{names: []string{events.NameJobCompleted}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			if tt.in.grouped {
+				groupID := uuid.New()
+				job.GroupID = &groupID
+			}
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockQueue.On("Nack", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			var result *worker.ExecutionResult
+			if tt.in.execErr == nil {
+				result = &worker.ExecutionResult{Success: true}
+			}
+			mockExecutor.On("Execute", mock.Anything, job).Return(result, tt.in.execErr)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+			groups := &StaticGroupRepository{claims: tt.in.claims}
+			service.SetGroupRepository(groups)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Len(t, groups.finished, tt.want.finishCalls)
+			assert.Equal(t, tt.want.names, publisher.Names())
+		})
+	}
+}
+
+func TestService_HandleJobFailure_QueuesAnalysis(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			attempts   int
+			enqueueErr error
+		}
+		want struct {
+			enqueued bool
+		}
+	}{
+		{
+			name: "Given a job failing for the first time, When handling job failure, Then should queue AI analysis",
+			in: struct {
+				attempts   int
+				enqueueErr error
+			}{attempts: 0},
+			want: struct{ enqueued bool }{enqueued: true},
+		},
+		{
+			name: "Given a full analysis queue, When handling job failure, Then should still schedule the retry",
+			in: struct {
+				attempts   int
+				enqueueErr error
+			}{attempts: 0, enqueueErr: insights.ErrAnalysisQueueFull},
+			want: struct{ enqueued bool }{enqueued: true},
+		},
+		{
+			name: "Given a job that already failed before, When handling job failure, Then should not queue analysis again",
+			in: struct {
+				attempts   int
+				enqueueErr error
+			}{attempts: 1},
+			want: struct{ enqueued bool }{enqueued: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			job.Attempts = tt.in.attempts
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockAnalysis := new(MockAnalysisQueue)
+			mockAnalysis.On("Enqueue", mock.Anything, job.ID).Return(tt.in.enqueueErr)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 1)
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), mockAnalysis, config)
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, errors.New("boom"))
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, queue.StatusRetrying, job.Status)
+			if tt.want.enqueued {
+				mockAnalysis.AssertCalled(t, "Enqueue", mock.Anything, job.ID)
+			} else {
+				mockAnalysis.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_HandleJobFailure_InsightPolicy(t *testing.T) {
+	type input struct {
+		workerPolicy queue.InsightPolicy
+		queuePolicy  queue.InsightPolicy
+		attempts     int
+	}
+
+	tests := []struct {
+		name string
+		in   input
+		want struct {
+			enqueued bool
+		}
+	}{
+		{
+			name: "Given the every_failure policy, When a job fails on its second attempt, Then should queue analysis",
+			in:   input{workerPolicy: queue.InsightOnEveryFailure, attempts: 1},
+			want: struct{ enqueued bool }{enqueued: true},
+		},
+		{
+			name: "Given the terminal_failure policy, When a job fails and will retry, Then should not queue analysis",
+			in:   input{workerPolicy: queue.InsightOnTerminalFailure, attempts: 0},
+			want: struct{ enqueued bool }{enqueued: false},
+		},
+		{
+			name: "Given the terminal_failure policy, When a job fails its last attempt, Then should queue analysis",
+			in:   input{workerPolicy: queue.InsightOnTerminalFailure, attempts: 2},
+			want: struct{ enqueued bool }{enqueued: true},
+		},
+		{
+			name: "Given the default policy, When a job first analyzed on attempt 1 fails its last attempt, Then should queue analysis",
+			in:   input{attempts: 2},
+			want: struct{ enqueued bool }{enqueued: true},
+		},
+		{
+			name: "Given a queue definition with every_failure, When a terminal_failure worker retries a job, Then the queue policy should apply",
+			in:   input{workerPolicy: queue.InsightOnTerminalFailure, queuePolicy: queue.InsightOnEveryFailure, attempts: 1},
+			want: struct{ enqueued bool }{enqueued: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			job.Attempts = tt.in.attempts
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockAnalysis := new(MockAnalysisQueue)
+			mockAnalysis.On("Enqueue", mock.Anything, job.ID).Return(nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 1)
+			config.InsightPolicy = tt.in.workerPolicy
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), mockAnalysis, config)
+			if tt.in.queuePolicy != "" {
+				service.SetQueueDefinitions(&StaticDefinitionRepository{def: &queue.Definition{
+					Name:          "default",
+					InsightPolicy: tt.in.queuePolicy,
+				}}, false)
+			}
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, errors.New("boom"))
+
+			// Then
+			assert.NoError(t, err)
+			if tt.want.enqueued {
+				mockAnalysis.AssertCalled(t, "Enqueue", mock.Anything, job.ID)
+			} else {
+				mockAnalysis.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type MockQuotaEnforcer struct {
+	mock.Mock
+}
+
+func (m *MockQuotaEnforcer) ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID) error {
+	args := m.Called(ctx, tenant, jobID)
+	return args.Error(0)
+}
+
+func (m *MockQuotaEnforcer) ReleaseJob(ctx context.Context, jobID uuid.UUID) error {
+	args := m.Called(ctx, jobID)
+	return args.Error(0)
+}
+
+func (m *MockQuotaEnforcer) ReserveAnalysis(ctx context.Context, tenant string) error {
+	args := m.Called(ctx, tenant)
+	return args.Error(0)
+}
+
+func (m *MockQuotaEnforcer) TenantOf(ctx context.Context, jobID uuid.UUID) (string, error) {
+	args := m.Called(ctx, jobID)
+	return args.String(0), args.Error(1)
+}
+
+func TestService_HandleJobFailure_Quota(t *testing.T) {
+	type input struct {
+		attempts   int
+		reserveErr error
+	}
+
+	tests := []struct {
+		name string
+		in   input
+		want struct {
+			enqueued bool
+			released bool
+		}
+	}{
+		{
+			name: "Given an API key with analyses left, When its job first fails, Then should queue analysis and keep the job pending",
+			in:   input{attempts: 0},
+			want: struct {
+				enqueued bool
+				released bool
+			}{enqueued: true, released: false},
+		},
+		{
+			name: "Given an API key that used up its analyses, When its job first fails, Then should skip the analysis",
+			in:   input{attempts: 0, reserveErr: quota.NewExceededError(quota.ResourceAnalyses, 5, time.Now())},
+			want: struct {
+				enqueued bool
+				released bool
+			}{enqueued: false, released: false},
+		},
+		{
+			name: "Given a job on its last attempt, When it fails, Then should release its pending job slot",
+			in:   input{attempts: 2},
+			want: struct {
+				enqueued bool
+				released bool
+			}{enqueued: true, released: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			job.Attempts = tt.in.attempts
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockAnalysis := new(MockAnalysisQueue)
+			mockAnalysis.On("Enqueue", mock.Anything, job.ID).Return(nil)
+			quotas := new(MockQuotaEnforcer)
+			quotas.On("TenantOf", mock.Anything, job.ID).Return("key-a", nil)
+			quotas.On("ReserveAnalysis", mock.Anything, "key-a").Return(tt.in.reserveErr)
+			quotas.On("ReleaseJob", mock.Anything, job.ID).Return(nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 1)
+			config.InsightPolicy = queue.InsightOnEveryFailure
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), mockAnalysis, config)
+			service.SetQuotaEnforcer(quotas)
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, errors.New("boom"))
+
+			// Then
+			assert.NoError(t, err)
+			if tt.want.enqueued {
+				mockAnalysis.AssertCalled(t, "Enqueue", mock.Anything, job.ID)
+			} else {
+				mockAnalysis.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+			if tt.want.released {
+				quotas.AssertCalled(t, "ReleaseJob", mock.Anything, job.ID)
+			} else {
+				quotas.AssertNotCalled(t, "ReleaseJob", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_NotifiesResult(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			callbackURL string
+			result      *worker.ExecutionResult
+			execErr     error
+		}
+		want struct {
+			statuses []queue.Status
+		}
+	}{
+		{
+			name: "Given a job with a callback URL that succeeds, When processing it, Then should notify the completed state",
+			in: struct {
+				callbackURL string
+				result      *worker.ExecutionResult
+				execErr     error
+			}{callbackURL: "https://example.com/hooks", result: &worker.ExecutionResult{Success: true}},
+			want: struct{ statuses []queue.Status }{statuses: []queue.Status{queue.StatusCompleted}},
+		},
+		{
+			name: "Given a job with a callback URL failing permanently, When processing it, Then should notify the failed state",
+			in: struct {
+				callbackURL string
+				result      *worker.ExecutionResult
+				execErr     error
+			}{callbackURL: "https://example.com/hooks", execErr: worker.NewPermanentError(errors.New("unsupported job type"))},
+			want: struct{ statuses []queue.Status }{statuses: []queue.Status{queue.StatusFailed}},
+		},
+		{
+			name: "Given a job without a callback URL, When processing it, Then should not notify",
+			in: struct {
+				callbackURL string
+				result      *worker.ExecutionResult
+				execErr     error
+			}{result: &worker.ExecutionResult{Success: true}},
+			want: struct{ statuses []queue.Status }{statuses: nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			job.CallbackURL = tt.in.callbackURL
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(tt.in.result, tt.in.execErr)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			notifier := &RecordingNotifier{}
+			service.SetResultNotifier(notifier)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.statuses, notifier.statuses)
+		})
+	}
+}
+
+type RecordingAttempts struct {
+	attempts []*queue.Attempt
+}
+
+func (a *RecordingAttempts) RecordAttempt(ctx context.Context, attempt *queue.Attempt) error {
+	a.attempts = append(a.attempts, attempt)
+	return nil
+}
+
+func (a *RecordingAttempts) RecordRetry(ctx context.Context, jobID uuid.UUID, number int, backoff time.Duration, retryAt time.Time) error {
+	for _, attempt := range a.attempts {
+		if attempt.JobID == jobID && attempt.Number == number {
+			attempt.Backoff, attempt.RetryAt = backoff, &retryAt
+		}
+	}
+	return nil
+}
+
+func (a *RecordingAttempts) ListAttempts(ctx context.Context, jobID uuid.UUID, limit int) ([]*queue.Attempt, error) {
+	return a.attempts, nil
+}
+
+func TestService_RecordsAttempts(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			attempts int
+			result   *worker.ExecutionResult
+			execErr  error
+		}
+		want struct {
+			number   int
+			error    string
+			category string
+		}
+	}{
+		{
+			name: "Given a job that succeeds on its second attempt, When processing it, Then should record a successful second attempt",
+			in: struct {
+				attempts int
+				result   *worker.ExecutionResult
+				execErr  error
+			}{attempts: 1, result: &worker.ExecutionResult{Success: true}},
+			want: struct {
+				number   int
+				error    string
+				category string
+			}{number: 2},
+		},
+		{
+			name: "Given a job failing permanently on its first attempt, When processing it, Then should record the failed attempt and its category",
+			in: struct {
+				attempts int
+				result   *worker.ExecutionResult
+				execErr  error
+			}{execErr: worker.NewPermanentError(errors.New("invalid payload: missing recipient"))},
+			want: struct {
+				number   int
+				error    string
+				category string
+			}{number: 1, error: "invalid payload: missing recipient", category: queue.FailureValidation},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			job.Attempts = tt.in.attempts
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(tt.in.result, tt.in.execErr)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			attempts := &RecordingAttempts{}
+			service.SetAttemptRepository(attempts)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			if assert.Len(t, attempts.attempts, 1) {
+				attempt := attempts.attempts[0]
+				assert.Equal(t, job.ID, attempt.JobID)
+				assert.Equal(t, tt.want.number, attempt.Number)
+				assert.Equal(t, config.WorkerID, attempt.WorkerID)
+				assert.Equal(t, tt.want.error, attempt.Error)
+				assert.Equal(t, tt.want.category, attempt.Category)
+				assert.False(t, attempt.StartedAt.IsZero())
+			}
+		})
+	}
+}
+
+func TestService_ExecutionContext(t *testing.T) {
+	// Given
+	job, _ := queue.NewJob("default", "email", []byte(`{}`))
+	job.Attempts = 1
+
+	mockRepo := new(MockJobRepository)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+	mockQueue := new(MockQueueService)
+	mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+	mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+	var (
+		exec  *worker.ExecutionContext
+		found bool
+	)
+	mockExecutor := new(MockJobExecutor)
+	mockExecutor.On("Execute", mock.Anything, job).Run(func(args mock.Arguments) {
+		exec, found = worker.ExecutionContextFrom(args.Get(0).(context.Context))
+	}).Return(&worker.ExecutionResult{Success: true}, nil)
+
+	config, _ := worker.NewWorkerConfig("default", 3, 500)
+	config.WorkerID = "worker-1"
+	service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+
+	// When
+	err := service.ProcessNextJob(context.Background())
+
+	// Then
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, job.ID, exec.JobID)
+	assert.Equal(t, "email", exec.JobType)
+	assert.Equal(t, "default", exec.Queue)
+	assert.Equal(t, 2, exec.Attempt)
+	assert.Equal(t, 3, exec.MaxAttempts)
+	assert.Equal(t, "worker-1", exec.WorkerID)
+	assert.NotNil(t, exec.Logger)
+	assert.False(t, exec.StartedAt.IsZero())
+}
+
+func TestService_ProcessNextJob_BrokerHealth(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			failures int
+		}
+		want struct {
+			events []string
+			errors int
+		}
+	}{
+		{
+			name: "Given a dequeue failing once, When the next one succeeds, Then should recover without alerting",
+			in:   struct{ failures int }{failures: 1},
+			want: struct {
+				events []string
+				errors int
+			}{events: []string{}, errors: 1},
+		},
+		{
+			name: "Given dequeues failing as many times as the alert threshold, When the next one succeeds, Then should alert on the degradation and the recovery once each",
+			in:   struct{ failures int }{failures: 3},
+			want: struct {
+				events []string
+				errors int
+			}{events: []string{events.NameBrokerDegraded, events.NameBrokerRecovered}, errors: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(nil, errors.New("connection refused")).Times(tt.in.failures)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(nil, nil).Once()
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			health, err := worker.NewBrokerHealth(worker.BrokerHealthConfig{BaseDelay: time.Millisecond, MaxDelay: time.Second, AlertAfter: 2})
+			require.NoError(t, err)
+			publisher := &RecordingPublisher{}
+			service := NewService(new(MockJobRepository), mockQueue, new(MockJobExecutor), nil, config)
+			service.SetEventPublisher(publisher)
+			service.SetBrokerHealth(health)
+
+			// When
+			errs := 0
+			for range tt.in.failures + 1 {
+				if service.ProcessNextJob(context.Background()) != nil {
+					errs++
+				}
+			}
+
+			// Then
+			assert.Equal(t, tt.want.errors, errs)
+			assert.Equal(t, tt.want.events, publisher.Names())
+			assert.Equal(t, worker.BrokerHealthy, health.Status().State)
+			mockQueue.AssertExpectations(t)
+		})
+	}
+}
+
+type FakeReadySignal struct {
+	ch chan struct{}
+}
+
+func (f *FakeReadySignal) Ready(queueName string) <-chan struct{} {
+	return f.ch
+}
+
+func TestService_Start_PollsAgain(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			idleSleep time.Duration
+			notify    bool
+		}
+		want struct {
+			polled bool
+		}
+	}{
+		{
+			name: "Given no idle sleep, When a pop finds no job, Then should pop again right away",
+			in: struct {
+				idleSleep time.Duration
+				notify    bool
+			}{idleSleep: 0},
+			want: struct{ polled bool }{polled: true},
+		},
+		{
+			name: "Given a long idle sleep, When a job ready notification arrives after an empty pop, Then should poll the queue immediately",
+			in: struct {
+				idleSleep time.Duration
+				notify    bool
+			}{idleSleep: time.Hour, notify: true},
+			want: struct{ polled bool }{polled: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			signal := &FakeReadySignal{ch: make(chan struct{}, 1)}
+			polled := make(chan struct{})
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(nil, nil).Run(func(args mock.Arguments) {
+				// When
+				if tt.in.notify {
+					signal.ch <- struct{}{}
+				}
+			}).Once()
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(nil, nil).Run(func(args mock.Arguments) {
+				close(polled)
+				cancel()
+			}).Once()
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			config.IdleSleep = tt.in.idleSleep
+			service := NewService(new(MockJobRepository), mockQueue, new(MockJobExecutor), nil, config)
+			service.SetReadySignal(signal)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				service.Start(ctx)
+			}()
+
+			// Then
+			select {
+			case <-polled:
+				assert.True(t, tt.want.polled)
+			case <-time.After(time.Second):
+				t.Fatal("worker did not poll the queue again")
+			}
+			<-done
+		})
+	}
+}
+
+// RecordingBreakerStore collects saved breaker states for assertions
+type RecordingBreakerStore struct {
+	saved []worker.BreakerStatus
+}
+
+func (s *RecordingBreakerStore) Save(ctx context.Context, status worker.BreakerStatus) error {
+	s.saved = append(s.saved, status)
+	return nil
+}
+
+func (s *RecordingBreakerStore) List(ctx context.Context) ([]worker.BreakerStatus, error) {
+	return s.saved, nil
+}
+
+func TestService_CircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			tripped bool
+			execErr error
+		}
+		want struct {
+			executed bool
+			names    []string
+			state    worker.BreakerState
+		}
+	}{
+		{
+			name: "Given an open breaker for the job type, When processing a job, Then should return it to the queue without executing it",
+			in: struct {
+				tripped bool
+				execErr error
+			}{tripped: true},
+			want: struct {
+				executed bool
+				names    []string
+				state    worker.BreakerState
+			}{executed: false, names: []string{}, state: worker.BreakerOpen},
+		},
+		{
+			name: "Given a closed breaker, When the job fails past the threshold, Then should trip the breaker and publish CircuitOpened",
+			in: struct {
+				tripped bool
+				execErr error
+			}{execErr: errors.New("connection refused")},
+			want: struct {
+				executed bool
+				names    []string
+				state    worker.BreakerState
+			}{executed: true, names: []string{events.NameCircuitOpened, events.NameJobFailed, events.NameJobMovedToDLQ}, state: worker.BreakerOpen},
+		},
+		{
+			name: "Given a closed breaker, When the job fails with a permanent error, Then should not count it",
+			in: struct {
+				tripped bool
+				execErr error
+			}{execErr: worker.NewPermanentError(errors.New("invalid payload"))},
+			want: struct {
+				executed bool
+				names    []string
+				state    worker.BreakerState
+			}{executed: true, names: []string{events.NameJobFailed, events.NameJobMovedToDLQ}, state: worker.BreakerClosed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "http", []byte(`{}`))
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(nil, tt.in.execErr)
+
+			breaker, _ := worker.NewFailureBreaker(worker.BreakerConfig{
+				FailureThreshold: 0.5,
+				Window:           time.Minute,
+				MinSamples:       1,
+				Cooldown:         time.Minute,
+			})
+			if tt.in.tripped {
+				breaker.Record("http", true)
+			}
+			store := &RecordingBreakerStore{}
+
+			config, _ := worker.NewWorkerConfig("default", 1, 1)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+			service.SetBreaker(breaker, store)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.names, publisher.Names())
+			assert.Equal(t, tt.want.state, breaker.Status("http").State)
+			if tt.want.executed {
+				mockExecutor.AssertCalled(t, "Execute", mock.Anything, job)
+				mockQueue.AssertNotCalled(t, "Nack", mock.Anything, mock.Anything)
+			} else {
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+				mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				mockQueue.AssertCalled(t, "Nack", mock.Anything, job)
+				assert.Equal(t, 0, job.Attempts)
+			}
+			if tt.want.state == worker.BreakerOpen && tt.want.executed {
+				assert.Len(t, store.saved, 1)
+				assert.Equal(t, "http", store.saved[0].JobType)
+			}
+		})
+	}
+}
+
+func TestService_PayloadSignature(t *testing.T) {
+	signer, _ := queue.NewPayloadSigner("secret", nil, true)
+
+	tests := []struct {
+		name string
+		in   struct {
+			sign    bool
+			payload []byte // Replaces the dequeued payload after signing when set
+		}
+		want struct {
+			executed bool
+			names    []string
+		}
+	}{
+		{
+			name: "Given a job with a valid signature, When processing it, Then should execute it",
+			in: struct {
+				sign    bool
+				payload []byte
+			}{sign: true},
+			want: struct {
+				executed bool
+				names    []string
+			}{executed: true, names: []string{events.NameJobCompleted}},
+		},
+		{
+			name: "Given a job whose payload changed after signing, When processing it, Then should move the stored job to the DLQ without executing it",
+			in: struct {
+				sign    bool
+				payload []byte
+			}{sign: true, payload: []byte(`{"to":"attacker@example.com"}`)},
+			want: struct {
+				executed bool
+				names    []string
+			}{executed: false, names: []string{events.NameJobFailed, events.NameJobMovedToDLQ}},
+		},
+		{
+			name: "Given an unsigned job and required signatures, When processing it, Then should move it to the DLQ without executing it",
+			in: struct {
+				sign    bool
+				payload []byte
+			}{sign: false},
+			want: struct {
+				executed bool
+				names    []string
+			}{executed: false, names: []string{events.NameJobFailed, events.NameJobMovedToDLQ}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			stored, _ := queue.NewJob("default", "email", []byte(`{"to":"user@example.com"}`))
+			if tt.in.sign {
+				assert.NoError(t, signer.Sign(stored, ""))
+			}
+			dequeued := *stored
+			if tt.in.payload != nil {
+				dequeued.Payload = tt.in.payload
+			}
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("GetByID", mock.Anything, stored.ID).Return(stored, nil)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, stored.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(&dequeued, nil)
+			mockQueue.On("Acknowledge", mock.Anything, stored.ID).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, &dequeued).Return(&worker.ExecutionResult{Success: true}, nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 1)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+			service.SetPayloadSigner(signer)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.names, publisher.Names())
+			if tt.want.executed {
+				mockExecutor.AssertCalled(t, "Execute", mock.Anything, &dequeued)
+				return
+			}
+			mockExecutor.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+			mockRepo.AssertCalled(t, "MoveToDLQ", mock.Anything, stored.ID)
+			// The failure is recorded on the stored job, keeping its original payload
+			mockRepo.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(job *queue.Job) bool {
+				return job == stored && job.Status == queue.StatusFailed && string(job.Payload) == `{"to":"user@example.com"}`
+			}))
+		})
+	}
+}
+
+// StaticDefinitionRepository serves a single queue definition, or none when def is nil
+type StaticDefinitionRepository struct {
+	def *queue.Definition
+}
+
+func (r *StaticDefinitionRepository) Create(ctx context.Context, def *queue.Definition) error {
+	return nil
+}
+
+func (r *StaticDefinitionRepository) Get(ctx context.Context, name string) (*queue.Definition, error) {
+	if r.def == nil || r.def.Name != name {
+		return nil, queue.ErrQueueNotDefined
+	}
+	return r.def, nil
+}
+
+func (r *StaticDefinitionRepository) List(ctx context.Context) ([]*queue.Definition, error) {
+	return nil, nil
+}
+
+func (r *StaticDefinitionRepository) Update(ctx context.Context, def *queue.Definition) error {
+	return nil
+}
+
+func (r *StaticDefinitionRepository) Delete(ctx context.Context, name string) error {
+	return nil
+}
+
+func TestService_QueueDefinitions(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			def     *queue.Definition
+			enforce bool
+		}
+		want struct {
+			dequeued bool
+			names    []string
+		}
+	}{
+		{
+			name: "Given a paused queue, When processing the next job, Then should not dequeue",
+			in: struct {
+				def     *queue.Definition
+				enforce bool
+			}{def: &queue.Definition{Name: "default", Paused: true}},
+			want: struct {
+				dequeued bool
+				names    []string
+			}{dequeued: false, names: []string{}},
+		},
+		{
+			name: "Given an undefined queue and enforced definitions, When processing the next job, Then should not dequeue",
+			in: struct {
+				def     *queue.Definition
+				enforce bool
+			}{enforce: true},
+			want: struct {
+				dequeued bool
+				names    []string
+			}{dequeued: false, names: []string{}},
+		},
+		{
+			name: "Given an undefined queue without enforcement, When a job fails on its first attempt, Then should retry with the worker settings",
+			in: struct {
+				def     *queue.Definition
+				enforce bool
+			}{},
+			want: struct {
+				dequeued bool
+				names    []string
+			}{dequeued: true, names: []string{events.NameJobFailed}},
+		},
+		{
+			name: "Given a queue defined with one attempt, When a job fails on its first attempt, Then should move it to the DLQ",
+			in: struct {
+				def     *queue.Definition
+				enforce bool
+			}{def: &queue.Definition{Name: "default", MaxAttempts: 1}},
+			want: struct {
+				dequeued bool
+				names    []string
+			}{dequeued: true, names: []string{events.NameJobFailed, events.NameJobMovedToDLQ}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{"to":"user@example.com"}`))
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(nil, errors.New("smtp unavailable"))
+
+			config, _ := worker.NewWorkerConfig("default", 3, 1)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+			service.SetQueueDefinitions(&StaticDefinitionRepository{def: tt.in.def}, tt.in.enforce)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.names, publisher.Names())
+			if !tt.want.dequeued {
+				mockQueue.AssertNotCalled(t, "Dequeue", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_Capabilities(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			requires []string
+		}
+		want struct {
+			executed bool
+		}
+	}{
+		{
+			name: "Given a job requiring a capability the worker has, When processing the next job, Then should execute it",
+			in:   struct{ requires []string }{requires: []string{"gpu"}},
+			want: struct{ executed bool }{executed: true},
+		},
+		{
+			name: "Given a job requiring a capability the worker lacks, When processing the next job, Then should return it to the queue without executing it",
+			in:   struct{ requires []string }{requires: []string{"region=eu"}},
+			want: struct{ executed bool }{executed: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "render", []byte(`{}`))
+			_ = job.Require(tt.in.requires)
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", []string{"gpu"}, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(&worker.ExecutionResult{Success: true}, nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 1)
+			config.Capabilities = []string{"gpu"}
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			if tt.want.executed {
+				mockExecutor.AssertCalled(t, "Execute", mock.Anything, job)
+				mockQueue.AssertNotCalled(t, "Nack", mock.Anything, mock.Anything)
+			} else {
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+				mockQueue.AssertCalled(t, "Nack", mock.Anything, job)
+			}
+		})
+	}
+}
+
+func TestService_HandleJobFailure_Throttled(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			attempts int
+			execErr  error
+		}
+		want struct {
+			attempts int
+			status   queue.Status
+			nacked   bool
+		}
+	}{
+		{
+			name: "Given a throttled job on its last attempt, When handling the failure, Then should retry without using up an attempt",
+			in: struct {
+				attempts int
+				execErr  error
+			}{attempts: 2, execErr: worker.NewRetryableError(errors.New("429 Too Many Requests"), 10*time.Millisecond)},
+			want: struct {
+				attempts int
+				status   queue.Status
+				nacked   bool
+			}{attempts: 2, status: queue.StatusRetrying, nacked: true},
+		},
+		{
+			name: "Given a permanent error wrapping a throttling error, When handling the failure, Then should move the job to the DLQ",
+			in: struct {
+				attempts int
+				execErr  error
+			}{attempts: 0, execErr: worker.NewPermanentError(worker.NewRetryableError(errors.New("quota exhausted"), time.Second))},
+			want: struct {
+				attempts int
+				status   queue.Status
+				nacked   bool
+			}{attempts: 1, status: queue.StatusFailed, nacked: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{"to":"user@example.com"}`))
+			job.Attempts = tt.in.attempts
+			job.MarkAsProcessing()
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockAnalysis := new(MockAnalysisQueue)
+			mockAnalysis.On("Enqueue", mock.Anything, job.ID).Return(nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), mockAnalysis, config)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, tt.in.execErr)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.attempts, job.Attempts)
+			assert.Equal(t, tt.want.status, job.Status)
+			failed := publisher.events[0].(events.JobFailed)
+			assert.Equal(t, tt.want.nacked, failed.Throttled)
+			if tt.want.nacked {
+				mockQueue.AssertCalled(t, "Nack", mock.Anything, job)
+				mockRepo.AssertNotCalled(t, "MoveToDLQ", mock.Anything, mock.Anything)
+				mockAnalysis.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+				return
+			}
+			mockRepo.AssertCalled(t, "MoveToDLQ", mock.Anything, job.ID)
+		})
+	}
+}
+
+func TestService_ProcessNextJob_DuplicateDelivery(t *testing.T) {
+	// Given a redelivered job that another worker already completed
+	job, _ := queue.NewJob("default", "email", []byte(`{"to":"user@example.com"}`))
+
+	mockRepo := new(MockJobRepository)
+	mockRepo.On("Update", mock.Anything, job).Return(fmt.Errorf("%w: completed to processing", queue.ErrInvalidTransition))
+	mockQueue := new(MockQueueService)
+	mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+	mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+	mockExecutor := new(MockJobExecutor)
+
+	config, _ := worker.NewWorkerConfig("default", 3, 1)
+	service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+
+	// When
+	err := service.ProcessNextJob(context.Background())
+
+	// Then the delivery is dropped without running the job again
+	assert.NoError(t, err)
+	mockQueue.AssertCalled(t, "Acknowledge", mock.Anything, job.ID)
+	mockExecutor.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+}
+
+type RecordingFixOutcomeRecorder struct {
+	outcomes []insights.FixOutcome
+}
+
+func (r *RecordingFixOutcomeRecorder) RecordFixOutcome(ctx context.Context, jobID uuid.UUID, outcome insights.FixOutcome, at time.Time) error {
+	r.outcomes = append(r.outcomes, outcome)
+	return nil
+}
+
+func TestService_FixOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			result  *worker.ExecutionResult
+			execErr error
+		}
+		want struct {
+			outcomes []insights.FixOutcome
+		}
+	}{
+		{
+			name: "Given a job that completes, When processing it, Then should record its applied fixes as succeeded",
+			in: struct {
+				result  *worker.ExecutionResult
+				execErr error
+			}{result: &worker.ExecutionResult{Success: true}},
+			want: struct{ outcomes []insights.FixOutcome }{outcomes: []insights.FixOutcome{insights.FixOutcomeSucceeded}},
+		},
+		{
+			name: "Given a job that fails again, When processing it, Then should record its applied fixes as failed",
+			in: struct {
+				result  *worker.ExecutionResult
+				execErr error
+			}{execErr: worker.NewPermanentError(errors.New("smtp: connection refused"))},
+			want: struct{ outcomes []insights.FixOutcome }{outcomes: []insights.FixOutcome{insights.FixOutcomeFailed}},
+		},
+		{
+			name: "Given a job that is throttled, When processing it, Then should not resolve its applied fixes",
+			in: struct {
+				result  *worker.ExecutionResult
+				execErr error
+			}{execErr: worker.NewRetryableError(errors.New("429 Too Many Requests"), time.Millisecond)},
+			want: struct{ outcomes []insights.FixOutcome }{outcomes: nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(tt.in.result, tt.in.execErr)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			recorder := &RecordingFixOutcomeRecorder{}
+			service.SetFixOutcomeRecorder(recorder)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.outcomes, recorder.outcomes)
+		})
+	}
+}
+
+type RecordingSlowRunQueue struct {
+	runs []*insights.SlowRun
+}
+
+func (q *RecordingSlowRunQueue) Enqueue(ctx context.Context, run *insights.SlowRun) error {
+	q.runs = append(q.runs, run)
+	return nil
+}
+
+func (q *RecordingSlowRunQueue) Dequeue(ctx context.Context, timeout time.Duration) (*insights.SlowRun, error) {
+	return nil, nil
+}
+
+func (q *RecordingSlowRunQueue) Complete(ctx context.Context, run *insights.SlowRun) error {
+	return nil
+}
+
+func (q *RecordingSlowRunQueue) Recover(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func TestService_PerformanceAnalysis(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			slo     time.Duration
+			execErr error
+		}
+		want struct {
+			queued bool
+		}
+	}{
+		{
+			name: "Given a job that completes slower than its SLO, When processing it, Then should queue a performance analysis",
+			in: struct {
+				slo     time.Duration
+				execErr error
+			}{slo: time.Millisecond},
+			want: struct{ queued bool }{queued: true},
+		},
+		{
+			name: "Given a job that completes within its SLO, When processing it, Then should not queue a performance analysis",
+			in: struct {
+				slo     time.Duration
+				execErr error
+			}{slo: time.Minute},
+			want: struct{ queued bool }{queued: false},
+		},
+		{
+			name: "Given a job that fails slower than its SLO, When processing it, Then should not queue a performance analysis",
+			in: struct {
+				slo     time.Duration
+				execErr error
+			}{slo: time.Millisecond, execErr: worker.NewPermanentError(errors.New("smtp: connection refused"))},
+			want: struct{ queued bool }{queued: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			result := &worker.ExecutionResult{Success: tt.in.execErr == nil}
+			mockExecutor.On("Execute", mock.Anything, job).Return(result, tt.in.execErr).After(5 * time.Millisecond)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			policy, _ := insights.NewPerformancePolicy(map[string]time.Duration{"email": tt.in.slo}, 0, 0)
+			slowRuns := &RecordingSlowRunQueue{}
+			service.SetPerformanceAnalysis(policy, slowRuns)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			assert.NoError(t, err)
+			if !tt.want.queued {
+				assert.Empty(t, slowRuns.runs)
+				return
+			}
+			assert.Len(t, slowRuns.runs, 1)
+			assert.Equal(t, job.ID, slowRuns.runs[0].JobID)
+			assert.Equal(t, tt.in.slo, slowRuns.runs[0].SLO)
+			assert.Greater(t, slowRuns.runs[0].Duration, tt.in.slo)
+		})
+	}
+}
+
+type RecordingDeadLetters struct {
+	err  error
+	jobs []*queue.Job
+}
+
+func (d *RecordingDeadLetters) DeadLetter(ctx context.Context, job *queue.Job) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.jobs = append(d.jobs, job)
+	return nil
+}
+
+func (d *RecordingDeadLetters) PeekDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	return d.jobs, nil
+}
+
+func (d *RecordingDeadLetters) PopDeadLetters(ctx context.Context, queueName string, limit int) ([]*queue.Job, error) {
+	return nil, nil
+}
+
+func (d *RecordingDeadLetters) RestoreDeadLetters(ctx context.Context, queueName string, jobs []*queue.Job) error {
+	return nil
+}
+
+func (d *RecordingDeadLetters) CountDeadLetters(ctx context.Context, queueName string) (int64, error) {
+	return int64(len(d.jobs)), nil
+}
+
+func TestService_HandleJobFailure_DeadLetterQueue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			deadLetterErr error
+		}
+		want struct {
+			deadLettered bool
+			acknowledged bool
+		}
+	}{
+		{
+			name: "Given a job failing permanently, When handling the failure, Then should keep it in the dead letters instead of only acknowledging it",
+			in:   struct{ deadLetterErr error }{deadLetterErr: nil},
+			want: struct {
+				deadLettered bool
+				acknowledged bool
+			}{deadLettered: true, acknowledged: false},
+		},
+		{
+			name: "Given dead letters that can't be written, When handling the failure, Then should still acknowledge the job",
+			in:   struct{ deadLetterErr error }{deadLetterErr: errors.New("connection refused")},
+			want: struct {
+				deadLettered bool
+				acknowledged bool
+			}{deadLettered: false, acknowledged: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+			job.MarkAsProcessing()
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), nil, config)
+			deadLetters := &RecordingDeadLetters{err: tt.in.deadLetterErr}
+			service.SetDeadLetterQueue(deadLetters)
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, worker.NewPermanentError(errors.New("invalid recipient")))
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, queue.StatusFailed, job.Status)
+			if tt.want.deadLettered {
+				assert.Equal(t, []*queue.Job{job}, deadLetters.jobs)
+			} else {
+				assert.Empty(t, deadLetters.jobs)
+			}
+			if tt.want.acknowledged {
+				mockQueue.AssertCalled(t, "Acknowledge", mock.Anything, job.ID)
+			} else {
+				mockQueue.AssertNotCalled(t, "Acknowledge", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_HandleJobFailure_DeadLetterReason(t *testing.T) {
+	invalidRecipient, _ := worker.CompileErrorPatterns([]string{`(?i)invalid recipient`})
+
+	tests := []struct {
+		name string
+		in   struct {
+			attempts int
+			execErr  error
+		}
+		want struct {
+			reason string
+		}
+	}{
+		{
+			name: "Given a job whose last attempt fails, When it fails for good, Then should record that its attempts ran out",
+			in: struct {
+				attempts int
+				execErr  error
+			}{attempts: 2, execErr: errors.New("connection refused")},
+			want: struct{ reason string }{reason: queue.DeadLetterMaxAttempts},
+		},
+		{
+			name: "Given a permanent error, When the job fails for good, Then should record a non-retryable error",
+			in: struct {
+				attempts int
+				execErr  error
+			}{execErr: worker.NewPermanentError(errors.New("command is not in the allow-list"))},
+			want: struct{ reason string }{reason: queue.DeadLetterNonRetryableError},
+		},
+		{
+			name: "Given an error the retry policy doesn't retry, When the job fails for good, Then should record that the policy cancelled it",
+			in: struct {
+				attempts int
+				execErr  error
+			}{execErr: errors.New("550 Invalid recipient")},
+			want: struct{ reason string }{reason: queue.DeadLetterCancelledByPolicy},
+		},
+		{
+			name: "Given a job whose last attempt timed out, When it fails for good, Then should record that it expired",
+			in: struct {
+				attempts int
+				execErr  error
+			}{attempts: 2, execErr: fmt.Errorf("send: %w", context.DeadlineExceeded)},
+			want: struct{ reason string }{reason: queue.DeadLetterExpired},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{"to":"user@example.com"}`))
+			job.Attempts = tt.in.attempts
+			job.MarkAsProcessing()
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			config.RetryPolicies = worker.RetryPolicies{"email": {NoRetryOn: invalidRecipient}}
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), nil, config)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, tt.in.execErr)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, queue.StatusFailed, job.Status)
+			assert.Equal(t, tt.want.reason, job.DeadLetterReason)
+			mockRepo.AssertCalled(t, "MoveToDLQ", mock.Anything, job.ID)
+			var moved []events.JobMovedToDLQ
+			for _, event := range publisher.events {
+				if e, ok := event.(events.JobMovedToDLQ); ok {
+					moved = append(moved, e)
+				}
+			}
+			if assert.Len(t, moved, 1) {
+				assert.Equal(t, tt.want.reason, moved[0].Reason)
+			}
+		})
+	}
+}
+
+func TestService_HandleJobFailure_RetryPolicy(t *testing.T) {
+	invalidRecipient, _ := worker.CompileErrorPatterns([]string{`(?i)invalid recipient`})
+	timeouts, _ := worker.CompileErrorPatterns([]string{`timeout`})
+
+	tests := []struct {
+		name string
+		in   struct {
+			policy   worker.RetryPolicy
+			attempts int
+			execErr  error
+		}
+		want struct {
+			status    queue.Status
+			nacked    bool
+			discarded bool
+			analyzed  bool
+		}
+	}{
+		{
+			name: "Given a job type allowed more attempts than the worker, When its third attempt fails, Then should retry it",
+			in: struct {
+				policy   worker.RetryPolicy
+				attempts int
+				execErr  error
+			}{policy: worker.RetryPolicy{MaxAttempts: 5, Backoff: worker.BackoffFixed, BaseBackoff: time.Millisecond}, attempts: 2, execErr: errors.New("boom")},
+			want: struct {
+				status    queue.Status
+				nacked    bool
+				discarded bool
+				analyzed  bool
+			}{status: queue.StatusRetrying, nacked: true},
+		},
+		{
+			name: "Given an error matching a non-retryable pattern, When handling the failure, Then should move the job to the DLQ on its first attempt",
+			in: struct {
+				policy   worker.RetryPolicy
+				attempts int
+				execErr  error
+			}{policy: worker.RetryPolicy{NoRetryOn: invalidRecipient}, execErr: errors.New("550 Invalid recipient")},
+			want: struct {
+				status    queue.Status
+				nacked    bool
+				discarded bool
+				analyzed  bool
+			}{status: queue.StatusFailed, analyzed: true},
+		},
+		{
+			name: "Given retry patterns the error doesn't match, When handling the failure, Then should move the job to the DLQ",
+			in: struct {
+				policy   worker.RetryPolicy
+				attempts int
+				execErr  error
+			}{policy: worker.RetryPolicy{RetryOn: timeouts}, execErr: errors.New("500 internal error")},
+			want: struct {
+				status    queue.Status
+				nacked    bool
+				discarded bool
+				analyzed  bool
+			}{status: queue.StatusFailed, analyzed: true},
+		},
+		{
+			name: "Given a job type whose failed jobs are discarded, When it fails for good, Then should delete it without a dead letter or an analysis",
+			in: struct {
+				policy   worker.RetryPolicy
+				attempts int
+				execErr  error
+			}{policy: worker.RetryPolicy{NoRetryOn: invalidRecipient, DLQ: worker.DLQDiscard}, execErr: errors.New("550 Invalid recipient")},
+			want: struct {
+				status    queue.Status
+				nacked    bool
+				discarded bool
+				analyzed  bool
+			}{status: queue.StatusFailed, discarded: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{"to":"user@example.com"}`))
+			job.Attempts = tt.in.attempts
+			job.MarkAsProcessing()
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockRepo.On("MoveToDLQ", mock.Anything, job.ID).Return(nil)
+			mockRepo.On("Delete", mock.Anything, job.ID).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockAnalysis := new(MockAnalysisQueue)
+			mockAnalysis.On("Enqueue", mock.Anything, job.ID).Return(nil)
+
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			config.RetryPolicies = worker.RetryPolicies{"email": tt.in.policy}
+			config.InsightPolicy = queue.InsightOnTerminalFailure
+			service := NewService(mockRepo, mockQueue, new(MockJobExecutor), mockAnalysis, config)
+			deadLetters := &RecordingDeadLetters{}
+			service.SetDeadLetterQueue(deadLetters)
+
+			// When
+			err := service.handleJobFailure(context.Background(), job, tt.in.execErr)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.status, job.Status)
+			if tt.want.nacked {
+				mockQueue.AssertCalled(t, "Nack", mock.Anything, job)
+				mockRepo.AssertNotCalled(t, "MoveToDLQ", mock.Anything, mock.Anything)
+				return
+			}
+			mockRepo.AssertCalled(t, "MoveToDLQ", mock.Anything, job.ID)
+			if tt.want.discarded {
+				mockRepo.AssertCalled(t, "Delete", mock.Anything, job.ID)
+				mockQueue.AssertCalled(t, "Acknowledge", mock.Anything, job.ID)
+				assert.Empty(t, deadLetters.jobs)
+			} else {
+				mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+				assert.Equal(t, []*queue.Job{job}, deadLetters.jobs)
+			}
+			if tt.want.analyzed {
+				mockAnalysis.AssertCalled(t, "Enqueue", mock.Anything, job.ID)
+			} else {
+				mockAnalysis.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type StaticHeldJobs struct {
+	jobs []*queue.Job
+}
+
+func (h *StaticHeldJobs) FindHeld(ctx context.Context, queueName string, maxVersion int, limit int) ([]*queue.Job, error) {
+	var found []*queue.Job
+	for _, job := range h.jobs {
+		if job.Queue == queueName && job.Status == queue.StatusHeld && job.EffectiveSchemaVersion() <= maxVersion {
+			found = append(found, job)
+		}
+	}
+	return found, nil
+}
+
+func TestService_SchemaVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			version int // Of the job; the worker knows up to 2
+		}
+		want struct {
+			executed bool
+			status   queue.Status
+			payload  string
+			version  int
+		}
+	}{
+		{
+			name: "Given a job of an older schema version, When processing it, Then should migrate its payload before running it",
+			in:   struct{ version int }{version: 1},
+			want: struct {
+				executed bool
+				status   queue.Status
+				payload  string
+				version  int
+			}{executed: true, status: queue.StatusCompleted, payload: `{"recipient":"a@example.com"}`, version: 2},
+		},
+		{
+			name: "Given a job of a newer schema version, When processing it, Then should hold it instead of failing it",
+			in:   struct{ version int }{version: 3},
+			want: struct {
+				executed bool
+				status   queue.Status
+				payload  string
+				version  int
+			}{status: queue.StatusHeld, payload: `{"to":"a@example.com"}`, version: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{"to":"a@example.com"}`))
+			job.SchemaVersion = tt.in.version
+
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(&worker.ExecutionResult{Success: true}, nil)
+
+			migrations := worker.NewSchemaMigrations(2)
+			migrations.Register("email", 1, func(payload []byte) ([]byte, error) {
+				return bytes.Replace(payload, []byte(`"to"`), []byte(`"recipient"`), 1), nil
+			})
+			config, _ := worker.NewWorkerConfig("default", 3, 500)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			service.SetSchemaMigrations(migrations, nil)
+
+			// When
+			err := service.ProcessNextJob(context.Background())
+
+			// Then
+			require.NoError(t, err)
+			if tt.want.executed {
+				mockExecutor.AssertCalled(t, "Execute", mock.Anything, job)
+			} else {
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+			}
+			assert.Equal(t, tt.want.status, job.Status)
+			assert.Equal(t, tt.want.payload, string(job.Payload))
+			assert.Equal(t, tt.want.version, job.SchemaVersion)
+			mockQueue.AssertCalled(t, "Acknowledge", mock.Anything, job.ID)
+		})
+	}
+}
+
+func TestService_ReleaseHeldJobs(t *testing.T) {
+	// Given jobs held by an older worker, one of a version this worker knows too
+	known, _ := queue.NewJob("default", "email", []byte(`{}`))
+	known.SchemaVersion = 2
+	unknown, _ := queue.NewJob("default", "email", []byte(`{}`))
+	unknown.SchemaVersion = 3
+	for _, job := range []*queue.Job{known, unknown} {
+		require.NoError(t, job.MarkAsHeld("job schema version is newer than the worker knows"))
+	}
+
+	mockRepo := new(MockJobRepository)
+	mockRepo.On("Update", mock.Anything, known).Return(nil)
+	mockQueue := new(MockQueueService)
+	mockQueue.On("Enqueue", mock.Anything, known).Return(nil)
+
+	config, _ := worker.NewWorkerConfig("default", 3, 500)
+	service := NewService(mockRepo, mockQueue, new(MockJobExecutor), nil, config)
+	service.SetSchemaMigrations(worker.NewSchemaMigrations(2), &StaticHeldJobs{jobs: []*queue.Job{known, unknown}})
+
+	// When
+	released, err := service.ReleaseHeldJobs(context.Background())
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, 1, released)
+	assert.Equal(t, queue.StatusPending, known.Status)
+	assert.Equal(t, queue.StatusHeld, unknown.Status)
+	mockQueue.AssertCalled(t, "Enqueue", mock.Anything, known)
+	mockQueue.AssertNotCalled(t, "Enqueue", mock.Anything, unknown)
+}
+
+func TestService_RetryBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			ratio float64
+		}
+		want struct {
+			status queue.Status
+			names  []string
+		}
+	}{
+		{
+			name: "Given a queue with retries left in its budget, When a job fails, Then should retry it",
+			in:   struct{ ratio float64 }{ratio: 1},
+			want: struct {
+				status queue.Status
+				names  []string
+			}{status: queue.StatusRetrying, names: []string{events.NameJobFailed}},
+		},
+		{
+			name: "Given a queue whose retry budget is exhausted, When a job fails, Then should park it",
+			in:   struct{ ratio float64 }{ratio: 0.2},
+			want: struct {
+				status queue.Status
+				names  []string
+			}{status: queue.StatusParked, names: []string{events.NameJobFailed, events.NameJobParked}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, _ := queue.NewJob("default", "email", []byte(`{}`))
+
+			var stored []queue.Status
+			mockRepo := new(MockJobRepository)
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*queue.Job")).Run(func(args mock.Arguments) {
+				stored = append(stored, args.Get(1).(*queue.Job).Status)
+			}).Return(nil)
+			mockQueue := new(MockQueueService)
+			mockQueue.On("Dequeue", mock.Anything, "default", mock.Anything, worker.DefaultDequeueTimeout).Return(job, nil)
+			mockQueue.On("Acknowledge", mock.Anything, job.ID).Return(nil)
+			mockQueue.On("Nack", mock.Anything, job).Return(nil)
+			mockExecutor := new(MockJobExecutor)
+			mockExecutor.On("Execute", mock.Anything, job).Return(nil, errors.New("connection refused"))
+
+			budget, err := worker.NewRetryBudget(worker.RetryBudgetConfig{
+				Ratio:  tt.in.ratio,
+				Window: time.Minute,
+			})
+			require.NoError(t, err)
+			config, _ := worker.NewWorkerConfig("default", 3, 1)
+			service := NewService(mockRepo, mockQueue, mockExecutor, nil, config)
+			service.SetRetryBudget(budget)
+			publisher := &RecordingPublisher{}
+			service.SetEventPublisher(publisher)
+
+			// When
+			err = service.ProcessNextJob(context.Background())
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.status, job.Status)
+			assert.Equal(t, tt.want.status, stored[len(stored)-1])
+			assert.Equal(t, 1, job.Attempts)
+			assert.Equal(t, tt.want.names, publisher.Names())
+			if tt.want.status == queue.StatusParked {
+				mockQueue.AssertCalled(t, "Acknowledge", mock.Anything, job.ID)
+				mockQueue.AssertNotCalled(t, "Nack", mock.Anything, mock.Anything)
+			} else {
+				mockQueue.AssertCalled(t, "Nack", mock.Anything, job)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+var ErrInvalidCondition = errors.New("invalid condition")
+
+// Metrics a condition compares against its threshold
+const (
+	MetricCount         = "count"          // Jobs analyzed in the window whose insight matches the filters
+	MetricAvgConfidence = "avg_confidence" // Average confidence of those insights
+	MetricFailed        = "failed"         // Jobs that failed in the window, analyzed or not; takes no filters
+)
+
+// Fields insights are filtered on
+const (
+	FieldCategory = "category" // Failure category of the job's error, e.g. queue.FailureAuth
+	FieldQueue    = "queue"
+	FieldType     = "type"
+	FieldTriage   = "triage" // The insight's triage label
+	FieldProvider = "provider"
+)
+
+// Window bounds
+const (
+	MinWindow = time.Minute
+	MaxWindow = 24 * time.Hour
+)
+
+var (
+	metrics    = []string{MetricCount, MetricAvgConfidence, MetricFailed}
+	fields     = []string{FieldCategory, FieldQueue, FieldType, FieldTriage, FieldProvider}
+	categories = []string{queue.FailureTimeout, queue.FailureAuth, queue.FailureValidation, queue.FailureUnknown}
+	triages    = []string{string(insights.TriageAutoRetryable), string(insights.TriageConfigIssue), string(insights.TriageNeedsHuman)}
+
+	conjunction     = regexp.MustCompile(`(?i)\s+and\s+`)
+	comparisonTerm  = regexp.MustCompile(`^(?i)([a-z_]+)\s*(>=|<=|!=|>|<|=)\s*([0-9]*\.?[0-9]+)\s+in\s+(\S+)$`)
+	filterTerm      = regexp.MustCompile(`^(?i)([a-z_]+)\s*(!=|=)\s*(.+)$`)
+	comparisonOps   = []string{">", ">=", "<", "<=", "=", "!="}
+	durationPattern = regexp.MustCompile(`^(\d+)([smhd])$`)
+)
+
+// Filter keeps the insights whose field equals, or with Negate differs from, the value
+type Filter struct {
+	Field  string
+	Value  string
+	Negate bool
+}
+
+// Condition is a rule's trigger, e.g. "category=auth AND count>5 in 10m": the metric over the
+// insights matching every filter in the window, compared with the threshold
+type Condition struct {
+	Filters   []Filter
+	Metric    string
+	Op        string
+	Threshold float64
+	Window    time.Duration
+}
+
+// Sample is the latest insight of a failed job, as seen by conditions
+type Sample struct {
+	Queue      string
+	Type       string
+	Category   string
+	Triage     string
+	Provider   string
+	Confidence float64
+	At         time.Time // When the insight was created
+}
+
+// ParseCondition parses terms joined by AND: filters such as queue=emails or triage!=needs-human,
+// and one comparison of a metric over a window, such as count>5 in 10m
+func ParseCondition(expression string) (Condition, error) {
+	var c Condition
+	compared := false
+	for _, term := range conjunction.Split(strings.TrimSpace(expression), -1) {
+		term = strings.TrimSpace(term)
+		if m := comparisonTerm.FindStringSubmatch(term); m != nil {
+			if compared {
+				return Condition{}, fmt.Errorf("%w: only one metric comparison is allowed", ErrInvalidCondition)
+			}
+			compared = true
+			c.Metric, c.Op = strings.ToLower(m[1]), m[2]
+			c.Threshold, _ = strconv.ParseFloat(m[3], 64)
+			window, err := parseWindow(m[4])
+			if err != nil {
+				return Condition{}, err
+			}
+			c.Window = window
+			continue
+		}
+		if m := filterTerm.FindStringSubmatch(term); m != nil {
+			c.Filters = append(c.Filters, Filter{
+				Field:  strings.ToLower(m[1]),
+				Value:  strings.Trim(strings.TrimSpace(m[3]), `"'`),
+				Negate: m[2] == "!=",
+			})
+			continue
+		}
+		return Condition{}, fmt.Errorf("%w: can't parse %q", ErrInvalidCondition, term)
+	}
+	if !compared {
+		return Condition{}, fmt.Errorf("%w: a metric comparison such as count>5 in 10m is required", ErrInvalidCondition)
+	}
+	return c, c.Validate()
+}
+
+// parseWindow accepts a number followed by s, m, h or d
+func parseWindow(text string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(strings.ToLower(text))
+	if m == nil {
+		return 0, fmt.Errorf("%w: window %q must be a number followed by s, m, h or d", ErrInvalidCondition, text)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, fmt.Errorf("%w: window %q is too long", ErrInvalidCondition, text)
+	}
+	unit := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}[m[2]]
+	return time.Duration(n) * unit, nil
+}
+
+// Validate checks the metric, operator, window and filters are known and fit together
+func (c Condition) Validate() error {
+	if !slices.Contains(metrics, c.Metric) {
+		return fmt.Errorf("%w: metric must be one of %s", ErrInvalidCondition, strings.Join(metrics, ", "))
+	}
+	if !slices.Contains(comparisonOps, c.Op) {
+		return fmt.Errorf("%w: operator must be one of %s", ErrInvalidCondition, strings.Join(comparisonOps, " "))
+	}
+	if c.Threshold < 0 {
+		return fmt.Errorf("%w: threshold must not be negative", ErrInvalidCondition)
+	}
+	if c.Window < MinWindow || c.Window > MaxWindow {
+		return fmt.Errorf("%w: window must be between %s and %s", ErrInvalidCondition, formatWindow(MinWindow), formatWindow(MaxWindow))
+	}
+	if c.Metric == MetricFailed && len(c.Filters) > 0 {
+		return fmt.Errorf("%w: %s counts every failed job and takes no filters", ErrInvalidCondition, MetricFailed)
+	}
+	for _, filter := range c.Filters {
+		if !slices.Contains(fields, filter.Field) {
+			return fmt.Errorf("%w: filter field must be one of %s", ErrInvalidCondition, strings.Join(fields, ", "))
+		}
+		if filter.Value == "" {
+			return fmt.Errorf("%w: %s needs a value", ErrInvalidCondition, filter.Field)
+		}
+		if filter.Field == FieldCategory && !slices.Contains(categories, strings.ToLower(filter.Value)) {
+			return fmt.Errorf("%w: category must be one of %s", ErrInvalidCondition, strings.Join(categories, ", "))
+		}
+		if filter.Field == FieldTriage && !slices.Contains(triages, strings.ToLower(filter.Value)) {
+			return fmt.Errorf("%w: triage must be one of %s", ErrInvalidCondition, strings.Join(triages, ", "))
+		}
+	}
+	return nil
+}
+
+// String returns the condition in the form ParseCondition reads
+func (c Condition) String() string {
+	terms := make([]string, 0, len(c.Filters)+1)
+	for _, filter := range c.Filters {
+		op := "="
+		if filter.Negate {
+			op = "!="
+		}
+		terms = append(terms, filter.Field+op+filter.Value)
+	}
+	terms = append(terms, c.Metric+c.Op+strconv.FormatFloat(c.Threshold, 'f', -1, 64)+" in "+formatWindow(c.Window))
+	return strings.Join(terms, " AND ")
+}
+
+func formatWindow(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return strconv.FormatInt(int64(d/(24*time.Hour)), 10) + "d"
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	default:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	}
+}
+
+// Matches reports whether a sample passes every filter; values compare case-insensitively
+func (c Condition) Matches(sample Sample) bool {
+	for _, filter := range c.Filters {
+		var value string
+		switch filter.Field {
+		case FieldCategory:
+			value = sample.Category
+		case FieldQueue:
+			value = sample.Queue
+		case FieldType:
+			value = sample.Type
+		case FieldTriage:
+			value = sample.Triage
+		case FieldProvider:
+			value = sample.Provider
+		}
+		if strings.EqualFold(value, filter.Value) == filter.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Measure computes an insight metric over the samples within the window ending at now. There
+// is no average without samples, so ok is false then and the condition can't hold.
+func (c Condition) Measure(samples []Sample, now time.Time) (value float64, ok bool) {
+	since := now.Add(-c.Window)
+	matched, confidence := 0, 0.0
+	for _, sample := range samples {
+		if sample.At.Before(since) || !c.Matches(sample) {
+			continue
+		}
+		matched++
+		confidence += sample.Confidence
+	}
+	if c.Metric == MetricAvgConfidence {
+		if matched == 0 {
+			return 0, false
+		}
+		return confidence / float64(matched), true
+	}
+	return float64(matched), true
+}
+
+// Holds compares a measured value with the threshold
+func (c Condition) Holds(value float64) bool {
+	switch c.Op {
+	case ">":
+		return value > c.Threshold
+	case ">=":
+		return value >= c.Threshold
+	case "<":
+		return value < c.Threshold
+	case "<=":
+		return value <= c.Threshold
+	case "=":
+		return value == c.Threshold
+	case "!=":
+		return value != c.Threshold
+	default:
+		return false
+	}
+}
@@ -0,0 +1,203 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want struct {
+			condition Condition
+			canonical string
+			err       bool
+		}
+	}{
+		{
+			name: "Given filters and a count over minutes, When parsing, Then should read every term",
+			in:   `category=auth and queue = "emails" AND count>5 in 10m`,
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{
+				condition: Condition{
+					Filters:   []Filter{{Field: FieldCategory, Value: "auth"}, {Field: FieldQueue, Value: "emails"}},
+					Metric:    MetricCount,
+					Op:        ">",
+					Threshold: 5,
+					Window:    10 * time.Minute,
+				},
+				canonical: "category=auth AND queue=emails AND count>5 in 10m",
+			},
+		},
+		{
+			name: "Given a negated filter and an average over hours, When parsing, Then should read the negation",
+			in:   "triage!=needs-human AND avg_confidence<=0.5 in 2h",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{
+				condition: Condition{
+					Filters:   []Filter{{Field: FieldTriage, Value: "needs-human", Negate: true}},
+					Metric:    MetricAvgConfidence,
+					Op:        "<=",
+					Threshold: 0.5,
+					Window:    2 * time.Hour,
+				},
+				canonical: "triage!=needs-human AND avg_confidence<=0.5 in 2h",
+			},
+		},
+		{
+			name: "Given failures over a day, When parsing, Then should need no filters",
+			in:   "failed >= 100 in 1d",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{
+				condition: Condition{Metric: MetricFailed, Op: ">=", Threshold: 100, Window: 24 * time.Hour},
+				canonical: "failed>=100 in 1d",
+			},
+		},
+		{
+			name: "Given no metric comparison, When parsing, Then should fail",
+			in:   "category=auth",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{err: true},
+		},
+		{
+			name: "Given an unknown field, When parsing, Then should fail",
+			in:   "color=red AND count>1 in 5m",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{err: true},
+		},
+		{
+			name: "Given an unknown category, When parsing, Then should fail",
+			in:   "category=network AND count>1 in 5m",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{err: true},
+		},
+		{
+			name: "Given filters on failures, When parsing, Then should fail since failures aren't filtered",
+			in:   "queue=emails AND failed>1 in 5m",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{err: true},
+		},
+		{
+			name: "Given a window beyond a day, When parsing, Then should fail",
+			in:   "count>1 in 2d",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{err: true},
+		},
+		{
+			name: "Given two comparisons, When parsing, Then should fail",
+			in:   "count>1 in 5m AND count<10 in 5m",
+			want: struct {
+				condition Condition
+				canonical string
+				err       bool
+			}{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := ParseCondition(tt.in)
+
+			if tt.want.err {
+				assert.ErrorIs(t, err, ErrInvalidCondition)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.condition, condition)
+			assert.Equal(t, tt.want.canonical, condition.String())
+		})
+	}
+}
+
+func TestCondition_Measure(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Queue: "emails", Category: "auth", Confidence: 0.9, At: now.Add(-time.Minute)},
+		{Queue: "emails", Category: "auth", Confidence: 0.5, At: now.Add(-5 * time.Minute)},
+		{Queue: "reports", Category: "auth", Confidence: 0.4, At: now.Add(-2 * time.Minute)},
+		{Queue: "emails", Category: "timeout", Confidence: 0.8, At: now.Add(-3 * time.Minute)},
+		{Queue: "emails", Category: "auth", Confidence: 0.1, At: now.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want struct {
+			value float64
+			ok    bool
+		}
+	}{
+		{
+			name: "Given samples inside and outside the window, When counting the matching ones, Then should count those inside",
+			in:   "category=auth AND queue=emails AND count>1 in 10m",
+			want: struct {
+				value float64
+				ok    bool
+			}{value: 2, ok: true},
+		},
+		{
+			name: "Given a negated filter, When counting, Then should count the samples that differ",
+			in:   "queue!=emails AND count>0 in 10m",
+			want: struct {
+				value float64
+				ok    bool
+			}{value: 1, ok: true},
+		},
+		{
+			name: "Given matching samples, When averaging the confidence, Then should average those inside the window",
+			in:   "queue=emails AND category=auth AND avg_confidence<0.8 in 10m",
+			want: struct {
+				value float64
+				ok    bool
+			}{value: 0.7, ok: true},
+		},
+		{
+			name: "Given no matching samples, When averaging the confidence, Then should report there is no value",
+			in:   "queue=payments AND avg_confidence<0.5 in 10m",
+			want: struct {
+				value float64
+				ok    bool
+			}{ok: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := ParseCondition(tt.in)
+			require.NoError(t, err)
+
+			value, ok := condition.Measure(samples, now)
+
+			assert.Equal(t, tt.want.ok, ok)
+			assert.InDelta(t, tt.want.value, value, 1e-9)
+		})
+	}
+}
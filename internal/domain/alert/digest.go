@@ -0,0 +1,193 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidDigestPolicy = errors.New("invalid DLQ digest policy")
+
+// DLQ digest defaults
+const (
+	DefaultDigestWindow         = time.Hour
+	DefaultDigestTopSignatures  = 5
+	DefaultDigestSampleInsights = 3
+)
+
+// DigestPolicy tells when the DLQ grows fast enough to send a digest of what's failing
+type DigestPolicy struct {
+	Threshold      int64         // DLQ growth within the window that sends a digest
+	Window         time.Duration // Zero uses DefaultDigestWindow
+	Cooldown       time.Duration // Time between two digests; zero waits for the window
+	TopSignatures  int           // Error signatures listed; zero uses DefaultDigestTopSignatures
+	SampleInsights int           // Insights of the top signatures included; zero uses DefaultDigestSampleInsights
+}
+
+// Validate checks the threshold is positive and nothing is negative
+func (p DigestPolicy) Validate() error {
+	if p.Threshold <= 0 {
+		return fmt.Errorf("%w: threshold must be greater than 0", ErrInvalidDigestPolicy)
+	}
+	if p.Window < 0 || p.Cooldown < 0 || p.TopSignatures < 0 || p.SampleInsights < 0 {
+		return fmt.Errorf("%w: window, cooldown, top signatures and sample insights must not be negative", ErrInvalidDigestPolicy)
+	}
+	return nil
+}
+
+// WithDefaults returns the policy with its zero values replaced by the defaults
+func (p DigestPolicy) WithDefaults() DigestPolicy {
+	if p.Window == 0 {
+		p.Window = DefaultDigestWindow
+	}
+	if p.Cooldown == 0 {
+		p.Cooldown = p.Window
+	}
+	if p.TopSignatures == 0 {
+		p.TopSignatures = DefaultDigestTopSignatures
+	}
+	if p.SampleInsights == 0 {
+		p.SampleInsights = DefaultDigestSampleInsights
+	}
+	return p
+}
+
+// DLQSample is the DLQ size at a point in time
+type DLQSample struct {
+	Count int64
+	At    time.Time
+}
+
+// DLQHistory keeps the DLQ sizes sampled within a window to measure how much the DLQ grew
+type DLQHistory struct {
+	window  time.Duration
+	samples []DLQSample
+}
+
+// NewDLQHistory creates an empty history of the given window
+func NewDLQHistory(window time.Duration) *DLQHistory {
+	return &DLQHistory{window: window}
+}
+
+// Record adds a sample, forgets those older than the window and returns how much the DLQ grew
+// from its smallest size within the window, so a replay followed by a surge still counts
+func (h *DLQHistory) Record(count int64, at time.Time) int64 {
+	cutoff := at.Add(-h.window)
+	kept := h.samples[:0]
+	for _, sample := range h.samples {
+		if !sample.At.Before(cutoff) {
+			kept = append(kept, sample)
+		}
+	}
+	h.samples = append(kept, DLQSample{Count: count, At: at})
+
+	lowest := count
+	for _, sample := range h.samples {
+		lowest = min(lowest, sample.Count)
+	}
+	return count - lowest
+}
+
+// Digest summarizes the jobs that reached the DLQ during a surge
+type Digest struct {
+	ID         uuid.UUID
+	Growth     int64 // Jobs the DLQ grew by within the window
+	DLQCount   int64 // DLQ size when the digest was sent
+	Window     time.Duration
+	Failed     int // Jobs of the window summarized
+	Signatures []SignatureCount
+	Queues     []QueueCount
+	Insights   []DigestInsight
+	SentAt     time.Time
+}
+
+// SignatureCount counts the jobs failing with an error signature, see insights.ErrorSignature
+type SignatureCount struct {
+	Signature   string    `json:"signature"`
+	JobType     string    `json:"job_type"`
+	SampleError string    `json:"sample_error"` // The error of the most recent job of the signature
+	SampleJobID uuid.UUID `json:"sample_job_id"`
+	Count       int       `json:"count"`
+}
+
+// QueueCount counts the jobs of a queue that reached the DLQ
+type QueueCount struct {
+	Queue string `json:"queue"`
+	Count int    `json:"count"`
+}
+
+// DigestInsight is the insight of a job of one of the top signatures
+type DigestInsight struct {
+	InsightID      uuid.UUID `json:"insight_id"`
+	JobID          uuid.UUID `json:"job_id"`
+	Signature      string    `json:"signature"`
+	Diagnosis      string    `json:"diagnosis"`
+	Recommendation string    `json:"recommendation"`
+	Confidence     float64   `json:"confidence"`
+}
+
+// NewDigest summarizes the dead-lettered jobs, most recent first, by error signature and queue,
+// each most frequent first. The insights are attached separately.
+func NewDigest(jobs []*queue.Job, growth, dlqCount int64, policy DigestPolicy, now time.Time) *Digest {
+	digest := &Digest{
+		ID:       uuid.New(),
+		Growth:   growth,
+		DLQCount: dlqCount,
+		Window:   policy.Window,
+		Failed:   len(jobs),
+		SentAt:   now,
+	}
+
+	signatures := map[string]*SignatureCount{}
+	queues := map[string]*QueueCount{}
+	for _, job := range jobs {
+		signature := insights.ErrorSignature(job.Type, job.Error)
+		if count, ok := signatures[signature]; ok {
+			count.Count++
+		} else {
+			signatures[signature] = &SignatureCount{
+				Signature:   signature,
+				JobType:     job.Type,
+				SampleError: job.Error,
+				SampleJobID: job.ID,
+				Count:       1,
+			}
+		}
+		if count, ok := queues[job.Queue]; ok {
+			count.Count++
+		} else {
+			queues[job.Queue] = &QueueCount{Queue: job.Queue, Count: 1}
+		}
+	}
+
+	for _, count := range signatures {
+		digest.Signatures = append(digest.Signatures, *count)
+	}
+	sort.Slice(digest.Signatures, func(i, j int) bool {
+		a, b := digest.Signatures[i], digest.Signatures[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Signature < b.Signature
+	})
+	if len(digest.Signatures) > policy.TopSignatures {
+		digest.Signatures = digest.Signatures[:policy.TopSignatures]
+	}
+
+	for _, count := range queues {
+		digest.Queues = append(digest.Queues, *count)
+	}
+	sort.Slice(digest.Queues, func(i, j int) bool {
+		a, b := digest.Queues[i], digest.Queues[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Queue < b.Queue
+	})
+	return digest
+}
@@ -0,0 +1,141 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DigestPolicy
+		want   error
+	}{
+		{
+			name:   "Given a threshold, When validating, Then should accept it",
+			policy: DigestPolicy{Threshold: 10},
+		},
+		{
+			name:   "Given no threshold, When validating, Then should fail",
+			policy: DigestPolicy{},
+			want:   ErrInvalidDigestPolicy,
+		},
+		{
+			name:   "Given a negative window, When validating, Then should fail",
+			policy: DigestPolicy{Threshold: 10, Window: -time.Minute},
+			want:   ErrInvalidDigestPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.policy.Validate(), tt.want)
+		})
+	}
+}
+
+func TestDigestPolicy_WithDefaults(t *testing.T) {
+	t.Run("Given an empty policy, When applying the defaults, Then should cool down for the window", func(t *testing.T) {
+		got := DigestPolicy{Threshold: 10}.WithDefaults()
+
+		assert.Equal(t, DigestPolicy{
+			Threshold:      10,
+			Window:         DefaultDigestWindow,
+			Cooldown:       DefaultDigestWindow,
+			TopSignatures:  DefaultDigestTopSignatures,
+			SampleInsights: DefaultDigestSampleInsights,
+		}, got)
+	})
+}
+
+func TestDLQHistory_Record(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		samples []int64 // One a minute
+		want    int64
+	}{
+		{
+			name:    "Given a single sample, When recording it, Then should not count any growth",
+			samples: []int64{40},
+			want:    0,
+		},
+		{
+			name:    "Given a DLQ growing within the window, When recording, Then should return the growth since the first sample",
+			samples: []int64{40, 45, 60},
+			want:    20,
+		},
+		{
+			name:    "Given a replay followed by a surge, When recording, Then should measure from the smallest size",
+			samples: []int64{40, 5, 30},
+			want:    25,
+		},
+		{
+			name:    "Given samples older than the window, When recording, Then should forget them",
+			samples: []int64{0, 50, 50, 50, 50, 55},
+			want:    5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := NewDLQHistory(3 * time.Minute)
+
+			var got int64
+			for i, count := range tt.samples {
+				got = history.Record(count, start.Add(time.Duration(i)*time.Minute))
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewDigest(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	failed := func(jobType, queueName, message string) *queue.Job {
+		return &queue.Job{ID: uuid.New(), Type: jobType, Queue: queueName, Error: message, Status: queue.StatusFailed}
+	}
+	timeout := failed("send_email", "emails", "dial tcp 10.0.0.1:25: i/o timeout")
+	jobs := []*queue.Job{
+		timeout,
+		failed("send_email", "emails", "dial tcp 10.0.0.2:25: i/o timeout"),
+		failed("send_email", "notifications", "dial tcp 10.0.0.3:25: i/o timeout"),
+		failed("resize_image", "images", "image too large"),
+		failed("charge_card", "billing", "card declined"),
+	}
+
+	t.Run("Given dead-lettered jobs, When building a digest, Then should count them by signature and queue, most frequent first", func(t *testing.T) {
+		policy := DigestPolicy{Threshold: 5, TopSignatures: 2}.WithDefaults()
+
+		digest := NewDigest(jobs, 7, 120, policy, now)
+
+		require.Len(t, digest.Signatures, 2)
+		assert.Equal(t, SignatureCount{
+			Signature:   insights.ErrorSignature("send_email", timeout.Error),
+			JobType:     "send_email",
+			SampleError: timeout.Error,
+			SampleJobID: timeout.ID,
+			Count:       3,
+		}, digest.Signatures[0])
+		assert.Equal(t, insights.ErrorSignature("charge_card", "card declined"), digest.Signatures[1].Signature)
+		assert.Equal(t, []QueueCount{
+			{Queue: "emails", Count: 2},
+			{Queue: "billing", Count: 1},
+			{Queue: "images", Count: 1},
+			{Queue: "notifications", Count: 1},
+		}, digest.Queues)
+		assert.Equal(t, int64(7), digest.Growth)
+		assert.Equal(t, int64(120), digest.DLQCount)
+		assert.Equal(t, 5, digest.Failed)
+		assert.Equal(t, DefaultDigestWindow, digest.Window)
+		assert.Equal(t, now, digest.SentAt)
+	})
+}
@@ -0,0 +1,60 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// RuleRepository stores alert rules
+type RuleRepository interface {
+	Create(ctx context.Context, rule *Rule) error
+	Get(ctx context.Context, id uuid.UUID) (*Rule, error)
+	// List returns every rule, oldest first
+	List(ctx context.Context) ([]*Rule, error)
+	Update(ctx context.Context, rule *Rule) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// MarkFired records that the rule fired at firedAt unless it already fired after notBefore,
+	// and reports whether it did, so replicas evaluating the same rule raise one alert
+	MarkFired(ctx context.Context, id uuid.UUID, firedAt, notBefore time.Time) (bool, error)
+}
+
+// SampleReader reads what conditions are evaluated over
+type SampleReader interface {
+	// InsightSamples returns the latest insight of up to limit jobs analyzed since the given time
+	InsightSamples(ctx context.Context, since time.Time, limit int) ([]Sample, error)
+	// CountFailures counts the jobs that failed since the given time, whether they will retry or not
+	CountFailures(ctx context.Context, since time.Time) (int64, error)
+}
+
+// Notifier delivers an alert to the rule's webhook
+type Notifier interface {
+	NotifyAlert(ctx context.Context, rule *Rule, alert *Alert)
+}
+
+// DLQReader reads the dead letter queue whose growth is digested
+type DLQReader interface {
+	CountDLQJobs(ctx context.Context) (int64, error)
+	// GetDLQJobs returns a page of dead-lettered jobs, most recently failed first
+	GetDLQJobs(ctx context.Context, limit, offset int) ([]*queue.Job, error)
+}
+
+// InsightFinder finds the insight explaining a job's failure for a digest
+type InsightFinder interface {
+	GetByJobID(ctx context.Context, jobID uuid.UUID) (*insights.Insight, error)
+}
+
+// DigestLog is the audit log of the DLQ digests sent
+type DigestLog interface {
+	RecordDigest(ctx context.Context, digest *Digest) error
+	// ListDigests returns up to limit digests, newest first
+	ListDigests(ctx context.Context, limit int) ([]*Digest, error)
+}
+
+// DigestNotifier delivers a DLQ digest to a webhook
+type DigestNotifier interface {
+	NotifyDigest(ctx context.Context, webhookURL string, digest *Digest)
+}
@@ -0,0 +1,111 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRuleNotFound = errors.New("alert rule not found")
+	ErrInvalidRule  = errors.New("invalid alert rule")
+)
+
+// MaxRuleNameLength bounds rule names
+const MaxRuleNameLength = 128
+
+// Rule raises an alert when its condition holds. Once fired it stays quiet for its cooldown,
+// so a lasting problem alerts once per cooldown rather than on every evaluation.
+type Rule struct {
+	ID          uuid.UUID
+	Name        string
+	Condition   Condition
+	WebhookURL  string        // Optional; alerts are also POSTed there
+	Cooldown    time.Duration // Zero waits for the condition's window
+	Enabled     bool
+	LastFiredAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// NewRule creates an enabled rule from a condition expression such as "category=auth AND count>5 in 10m"
+func NewRule(name, expression, webhookURL string, cooldown time.Duration, now time.Time) (*Rule, error) {
+	condition, err := ParseCondition(expression)
+	if err != nil {
+		return nil, err
+	}
+	rule := &Rule{
+		ID:         uuid.New(),
+		Name:       strings.TrimSpace(name),
+		Condition:  condition,
+		WebhookURL: webhookURL,
+		Cooldown:   cooldown,
+		Enabled:    true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Validate checks the name, webhook URL, cooldown and condition
+func (r *Rule) Validate() error {
+	if r.Name == "" || len(r.Name) > MaxRuleNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidRule, MaxRuleNameLength)
+	}
+	if r.WebhookURL != "" {
+		u, err := url.Parse(r.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: webhook_url must be an absolute http or https URL", ErrInvalidRule)
+		}
+	}
+	if r.Cooldown < 0 || r.Cooldown > MaxWindow {
+		return fmt.Errorf("%w: cooldown must be between 0 and %s", ErrInvalidRule, formatWindow(MaxWindow))
+	}
+	return r.Condition.Validate()
+}
+
+// EffectiveCooldown returns the cooldown, defaulting to the condition's window
+func (r *Rule) EffectiveCooldown() time.Duration {
+	if r.Cooldown > 0 {
+		return r.Cooldown
+	}
+	return r.Condition.Window
+}
+
+// CoolingDown reports whether the rule fired too recently to fire again at now
+func (r *Rule) CoolingDown(now time.Time) bool {
+	return r.LastFiredAt != nil && now.Before(r.LastFiredAt.Add(r.EffectiveCooldown()))
+}
+
+// Alert is a rule firing
+type Alert struct {
+	RuleID    uuid.UUID
+	RuleName  string
+	Condition string
+	Value     float64 // The measured metric
+	Threshold float64
+	FiredAt   time.Time
+}
+
+// Check returns the alert the rule raises for the measured value at now, or nil when the
+// condition doesn't hold or the rule is disabled or cooling down
+func (r *Rule) Check(value float64, now time.Time) *Alert {
+	if !r.Enabled || r.CoolingDown(now) || !r.Condition.Holds(value) {
+		return nil
+	}
+	return &Alert{
+		RuleID:    r.ID,
+		RuleName:  r.Name,
+		Condition: r.Condition.String(),
+		Value:     value,
+		Threshold: r.Condition.Threshold,
+		FiredAt:   now,
+	}
+}
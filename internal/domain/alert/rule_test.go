@@ -0,0 +1,166 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRule(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			ruleName   string
+			expression string
+			webhookURL string
+			cooldown   time.Duration
+		}
+		want error
+	}{
+		{
+			name: "Given a name, a condition and a webhook, When creating a rule, Then should create it enabled",
+			in: struct {
+				ruleName   string
+				expression string
+				webhookURL string
+				cooldown   time.Duration
+			}{ruleName: "Auth failures", expression: "category=auth AND count>5 in 10m", webhookURL: "https://hooks.example.com/alerts"},
+		},
+		{
+			name: "Given no name, When creating a rule, Then should fail",
+			in: struct {
+				ruleName   string
+				expression string
+				webhookURL string
+				cooldown   time.Duration
+			}{ruleName: " ", expression: "count>5 in 10m"},
+			want: ErrInvalidRule,
+		},
+		{
+			name: "Given a webhook that isn't an http URL, When creating a rule, Then should fail",
+			in: struct {
+				ruleName   string
+				expression string
+				webhookURL string
+				cooldown   time.Duration
+			}{ruleName: "Auth failures", expression: "count>5 in 10m", webhookURL: "ftp://example.com"},
+			want: ErrInvalidRule,
+		},
+		{
+			name: "Given an invalid condition, When creating a rule, Then should fail",
+			in: struct {
+				ruleName   string
+				expression string
+				webhookURL string
+				cooldown   time.Duration
+			}{ruleName: "Auth failures", expression: "count>5"},
+			want: ErrInvalidCondition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewRule(tt.in.ruleName, tt.in.expression, tt.in.webhookURL, tt.in.cooldown, now)
+
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, rule.Enabled)
+			assert.Equal(t, tt.in.ruleName, rule.Name)
+			assert.Equal(t, now, rule.CreatedAt)
+		})
+	}
+}
+
+func TestRule_Check(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	recently := now.Add(-5 * time.Minute)
+	longAgo := now.Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		in   struct {
+			value       float64
+			enabled     bool
+			cooldown    time.Duration
+			lastFiredAt *time.Time
+		}
+		want bool
+	}{
+		{
+			name: "Given a value over the threshold, When checking, Then should fire",
+			in: struct {
+				value       float64
+				enabled     bool
+				cooldown    time.Duration
+				lastFiredAt *time.Time
+			}{value: 6, enabled: true, lastFiredAt: &longAgo},
+			want: true,
+		},
+		{
+			name: "Given a value at the threshold, When checking, Then should not fire",
+			in: struct {
+				value       float64
+				enabled     bool
+				cooldown    time.Duration
+				lastFiredAt *time.Time
+			}{value: 5, enabled: true},
+		},
+		{
+			name: "Given a rule that fired within its window, When checking, Then should stay quiet",
+			in: struct {
+				value       float64
+				enabled     bool
+				cooldown    time.Duration
+				lastFiredAt *time.Time
+			}{value: 6, enabled: true, lastFiredAt: &recently},
+		},
+		{
+			name: "Given a cooldown shorter than the time since it fired, When checking, Then should fire again",
+			in: struct {
+				value       float64
+				enabled     bool
+				cooldown    time.Duration
+				lastFiredAt *time.Time
+			}{value: 6, enabled: true, cooldown: time.Minute, lastFiredAt: &recently},
+			want: true,
+		},
+		{
+			name: "Given a disabled rule, When checking, Then should not fire",
+			in: struct {
+				value       float64
+				enabled     bool
+				cooldown    time.Duration
+				lastFiredAt *time.Time
+			}{value: 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewRule("Auth failures", "category=auth AND count>5 in 10m", "", tt.in.cooldown, longAgo)
+			require.NoError(t, err)
+			rule.Enabled = tt.in.enabled
+			rule.LastFiredAt = tt.in.lastFiredAt
+
+			alert := rule.Check(tt.in.value, now)
+
+			if !tt.want {
+				assert.Nil(t, alert)
+				return
+			}
+			require.NotNil(t, alert)
+			assert.Equal(t, rule.ID, alert.RuleID)
+			assert.Equal(t, "category=auth AND count>5 in 10m", alert.Condition)
+			assert.Equal(t, tt.in.value, alert.Value)
+			assert.Equal(t, 5.0, alert.Threshold)
+			assert.Equal(t, now, alert.FiredAt)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event names used to route events to subscribers
+const (
+	NameJobCreated       = "job.created"
+	NameJobCompleted     = "job.completed"
+	NameJobFailed        = "job.failed"
+	NameJobMovedToDLQ    = "job.moved_to_dlq"
+	NameJobParked        = "job.parked"
+	NameInsightGenerated = "insight.generated"
+	NameCircuitOpened    = "circuit.opened"
+	NameCircuitClosed    = "circuit.closed"
+	NameAlertFired       = "alert.fired"
+	NameDLQDigestSent    = "dlq.digest_sent"
+	NameGroupCompleted   = "group.completed"
+	NameBrokerDegraded   = "broker.degraded"
+	NameBrokerRecovered  = "broker.recovered"
+
+	NameAIProviderDegraded  = "ai_provider.degraded"
+	NameAIProviderRecovered = "ai_provider.recovered"
+)
+
+// Event is a fact that happened in the domain and that side effects
+// (metrics, webhooks, notifications, live feeds) can react to
+type Event interface {
+	Name() string
+	OccurredAt() time.Time
+}
+
+// JobCreated is published when a job has been persisted and enqueued
+type JobCreated struct {
+	JobID uuid.UUID `json:"job_id"`
+	Queue string    `json:"queue"`
+	Type  string    `json:"type"`
+	At    time.Time `json:"at"`
+}
+
+func (e JobCreated) Name() string          { return NameJobCreated }
+func (e JobCreated) OccurredAt() time.Time { return e.At }
+
+// JobCompleted is published when a job finished successfully
+type JobCompleted struct {
+	JobID    uuid.UUID     `json:"job_id"`
+	Queue    string        `json:"queue"`
+	Type     string        `json:"type"`
+	Attempt  int           `json:"attempt"`
+	Duration time.Duration `json:"duration_ns"`
+	At       time.Time     `json:"at"`
+}
+
+func (e JobCompleted) Name() string          { return NameJobCompleted }
+func (e JobCompleted) OccurredAt() time.Time { return e.At }
+
+// JobFailed is published for every failed execution attempt
+type JobFailed struct {
+	JobID     uuid.UUID `json:"job_id"`
+	Queue     string    `json:"queue"`
+	Type      string    `json:"type"`
+	Attempt   int       `json:"attempt"`
+	Error     string    `json:"error"`
+	Category  string    `json:"category,omitempty"` // Why it failed, e.g. queue.FailureTimeout
+	Retryable bool      `json:"retryable"`
+	Throttled bool      `json:"throttled,omitempty"` // A downstream service asked to retry later; no attempt was used
+	At        time.Time `json:"at"`
+}
+
+func (e JobFailed) Name() string          { return NameJobFailed }
+func (e JobFailed) OccurredAt() time.Time { return e.At }
+
+// JobMovedToDLQ is published when a job failed permanently
+type JobMovedToDLQ struct {
+	JobID    uuid.UUID `json:"job_id"`
+	Queue    string    `json:"queue"`
+	Type     string    `json:"type"`
+	Attempts int       `json:"attempts"`
+	Reason   string    `json:"reason"` // The job's dead letter reason, e.g. queue.DeadLetterMaxAttempts
+	At       time.Time `json:"at"`
+}
+
+func (e JobMovedToDLQ) Name() string          { return NameJobMovedToDLQ }
+func (e JobMovedToDLQ) OccurredAt() time.Time { return e.At }
+
+// JobParked is published when a failed job was parked instead of retried because its queue's
+// retry budget was exhausted
+type JobParked struct {
+	JobID    uuid.UUID `json:"job_id"`
+	Queue    string    `json:"queue"`
+	Type     string    `json:"type"`
+	Attempts int       `json:"attempts"`
+	At       time.Time `json:"at"`
+}
+
+func (e JobParked) Name() string          { return NameJobParked }
+func (e JobParked) OccurredAt() time.Time { return e.At }
+
+// InsightGenerated is published when a new AI insight has been persisted
+type InsightGenerated struct {
+	InsightID uuid.UUID `json:"insight_id"`
+	JobID     uuid.UUID `json:"job_id"`
+	Diagnosis string    `json:"diagnosis"`
+	At        time.Time `json:"at"`
+}
+
+func (e InsightGenerated) Name() string          { return NameInsightGenerated }
+func (e InsightGenerated) OccurredAt() time.Time { return e.At }
+
+// CircuitOpened is published when a job type's failure rate tripped its breaker
+// and consumption of that type has been paused
+type CircuitOpened struct {
+	JobType     string    `json:"job_type"`
+	FailureRate float64   `json:"failure_rate"`
+	Samples     int       `json:"samples"`
+	OpenUntil   time.Time `json:"open_until"`
+	WorkerID    string    `json:"worker_id"`
+	At          time.Time `json:"at"`
+}
+
+func (e CircuitOpened) Name() string          { return NameCircuitOpened }
+func (e CircuitOpened) OccurredAt() time.Time { return e.At }
+
+// CircuitClosed is published when a paused job type's cooldown ended and it runs again
+type CircuitClosed struct {
+	JobType  string    `json:"job_type"`
+	WorkerID string    `json:"worker_id"`
+	At       time.Time `json:"at"`
+}
+
+func (e CircuitClosed) Name() string          { return NameCircuitClosed }
+func (e CircuitClosed) OccurredAt() time.Time { return e.At }
+
+// GroupCompleted is published when every job of a group completed or failed
+type GroupCompleted struct {
+	GroupID uuid.UUID `json:"group_id"`
+	At      time.Time `json:"at"`
+}
+
+func (e GroupCompleted) Name() string          { return NameGroupCompleted }
+func (e GroupCompleted) OccurredAt() time.Time { return e.At }
+
+// BrokerDegraded is published when a worker-runtime process's calls to its queue broker
+// failed the configured number of times in a row
+type BrokerDegraded struct {
+	WorkerID string    `json:"worker_id"`
+	Failures int       `json:"failures"`
+	Error    string    `json:"error"`
+	Since    time.Time `json:"since"` // First failure of the outage
+	At       time.Time `json:"at"`
+}
+
+func (e BrokerDegraded) Name() string          { return NameBrokerDegraded }
+func (e BrokerDegraded) OccurredAt() time.Time { return e.At }
+
+// BrokerRecovered is published when a degraded worker-runtime process reaches its queue
+// broker again
+type BrokerRecovered struct {
+	WorkerID string        `json:"worker_id"`
+	Failures int           `json:"failures"`
+	Downtime time.Duration `json:"downtime_ns"`
+	At       time.Time     `json:"at"`
+}
+
+func (e BrokerRecovered) Name() string          { return NameBrokerRecovered }
+func (e BrokerRecovered) OccurredAt() time.Time { return e.At }
+
+// AIProviderDegraded is published when the AI provider failed the configured number of health
+// probes in a row, and synchronous analyses are skipped until it recovers
+type AIProviderDegraded struct {
+	Failures int       `json:"failures"`
+	Error    string    `json:"error"`
+	Since    time.Time `json:"since"` // First failed probe of the outage
+	At       time.Time `json:"at"`
+}
+
+func (e AIProviderDegraded) Name() string          { return NameAIProviderDegraded }
+func (e AIProviderDegraded) OccurredAt() time.Time { return e.At }
+
+// AIProviderRecovered is published when a degraded AI provider passes a health probe again
+type AIProviderRecovered struct {
+	Failures int           `json:"failures"`
+	Downtime time.Duration `json:"downtime_ns"`
+	At       time.Time     `json:"at"`
+}
+
+func (e AIProviderRecovered) Name() string          { return NameAIProviderRecovered }
+func (e AIProviderRecovered) OccurredAt() time.Time { return e.At }
+
+// AlertFired is published when an alert rule's condition held
+type AlertFired struct {
+	RuleID    uuid.UUID `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	Condition string    `json:"condition"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	At        time.Time `json:"at"`
+}
+
+func (e AlertFired) Name() string          { return NameAlertFired }
+func (e AlertFired) OccurredAt() time.Time { return e.At }
+
+// DLQDigestSent is published when the DLQ grew past the digest threshold and a digest was sent
+type DLQDigestSent struct {
+	DigestID uuid.UUID `json:"digest_id"`
+	Growth   int64     `json:"growth"`
+	DLQCount int64     `json:"dlq_count"`
+	Window   string    `json:"window"`
+	At       time.Time `json:"at"`
+}
+
+func (e DLQDigestSent) Name() string          { return NameDLQDigestSent }
+func (e DLQDigestSent) OccurredAt() time.Time { return e.At }
+
+// Envelope is the serialized form of an event, used to carry events between processes
+type Envelope struct {
+	Name       string          `json:"name"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// NewEnvelope serializes an event into an envelope
+func NewEnvelope(event Event) (Envelope, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{Name: event.Name(), OccurredAt: event.OccurredAt(), Data: data}, nil
+}
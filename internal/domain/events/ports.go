@@ -0,0 +1,16 @@
+package events
+
+import "context"
+
+// Publisher defines the interface application services use to emit domain events
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// Handler reacts to a published event
+type Handler func(ctx context.Context, event Event)
+
+// NopPublisher discards all events; it is the default until a bus is wired in
+type NopPublisher struct{}
+
+func (NopPublisher) Publish(ctx context.Context, event Event) {}
@@ -0,0 +1,78 @@
+package insights
+
+import (
+	"errors"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// ErrAnalysisNotFound is returned when an asynchronous analysis doesn't exist
+var ErrAnalysisNotFound = errors.New("analysis not found")
+
+// AnalysisStatus is the state of an asynchronous analysis
+type AnalysisStatus string
+
+const (
+	AnalysisPending   AnalysisStatus = "pending"
+	AnalysisCompleted AnalysisStatus = "completed"
+	AnalysisFailed    AnalysisStatus = "failed"
+)
+
+// Analysis tracks an analysis requested asynchronously, from submission until the insight
+// is generated or the analysis fails
+type Analysis struct {
+	ID          uuid.UUID
+	JobID       uuid.UUID
+	Status      AnalysisStatus
+	InsightID   *uuid.UUID // Set once the analysis completed
+	Error       string     // Set when the analysis failed
+	CallbackURL string     // Receives the outcome when set
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// NewAnalysis creates a pending analysis of a job; the callback URL is optional
+func NewAnalysis(jobID uuid.UUID, callbackURL string) (*Analysis, error) {
+	if callbackURL != "" {
+		if err := queue.ValidateCallbackURL(callbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	return &Analysis{
+		ID:          uuid.New(),
+		JobID:       jobID,
+		Status:      AnalysisPending,
+		CallbackURL: callbackURL,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// Start records that the analysis is being run now, so it isn't taken for an abandoned one
+func (a *Analysis) Start() {
+	a.UpdatedAt = time.Now().UTC()
+}
+
+// Complete records the insight the analysis produced
+func (a *Analysis) Complete(insightID uuid.UUID) {
+	a.Status = AnalysisCompleted
+	a.InsightID = &insightID
+	a.Error = ""
+	a.UpdatedAt = time.Now().UTC()
+}
+
+// Fail records why the analysis couldn't produce an insight
+func (a *Analysis) Fail(err error) {
+	a.Status = AnalysisFailed
+	a.Error = err.Error()
+	a.UpdatedAt = time.Now().UTC()
+}
+
+// IsFinished reports whether the analysis completed or failed
+func (a *Analysis) IsFinished() bool {
+	return a.Status == AnalysisCompleted || a.Status == AnalysisFailed
+}
@@ -0,0 +1,107 @@
+package insights
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAnalysis(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			callbackURL string
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given no callback URL, When creating an analysis, Then should be pending",
+			in:   struct{ callbackURL string }{callbackURL: ""},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given an https callback URL, When creating an analysis, Then should keep it",
+			in:   struct{ callbackURL string }{callbackURL: "https://example.com/hooks/analyses"},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given a non-http callback URL, When creating an analysis, Then should return ErrInvalidCallbackURL",
+			in:   struct{ callbackURL string }{callbackURL: "ftp://example.com/hooks"},
+			want: struct{ err error }{err: queue.ErrInvalidCallbackURL},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobID := uuid.New()
+
+			analysis, err := NewAnalysis(jobID, tt.in.callbackURL)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+				assert.Nil(t, analysis)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, jobID, analysis.JobID)
+			assert.Equal(t, AnalysisPending, analysis.Status)
+			assert.Equal(t, tt.in.callbackURL, analysis.CallbackURL)
+			assert.False(t, analysis.IsFinished())
+		})
+	}
+}
+
+func TestAnalysis_Transitions(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			fail bool
+		}
+		want struct {
+			status     AnalysisStatus
+			hasInsight bool
+			err        string
+		}
+	}{
+		{
+			name: "Given a pending analysis, When it completes, Then should reference the insight",
+			in:   struct{ fail bool }{fail: false},
+			want: struct {
+				status     AnalysisStatus
+				hasInsight bool
+				err        string
+			}{status: AnalysisCompleted, hasInsight: true},
+		},
+		{
+			name: "Given a pending analysis, When it fails, Then should record the error",
+			in:   struct{ fail bool }{fail: true},
+			want: struct {
+				status     AnalysisStatus
+				hasInsight bool
+				err        string
+			}{status: AnalysisFailed, err: "model unavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, _ := NewAnalysis(uuid.New(), "")
+
+			if tt.in.fail {
+				analysis.Fail(errors.New("model unavailable"))
+			} else {
+				analysis.Complete(uuid.New())
+			}
+
+			assert.Equal(t, tt.want.status, analysis.Status)
+			assert.Equal(t, tt.want.hasInsight, analysis.InsightID != nil)
+			assert.Equal(t, tt.want.err, analysis.Error)
+			assert.True(t, analysis.IsFinished())
+		})
+	}
+}
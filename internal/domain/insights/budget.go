@@ -0,0 +1,99 @@
+package insights
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Default limits on the job data sent to the AI
+const (
+	DefaultMaxPromptBytes  = 16 * 1024
+	DefaultMaxPayloadBytes = 8 * 1024
+	DefaultMaxErrorBytes   = 2 * 1024
+	DefaultMaxOutputBytes  = 4 * 1024
+)
+
+// PromptBudget bounds the job data put in an analysis prompt, so huge payloads or stack traces
+// neither overflow the model's context window nor slow every analysis down. Zero or negative
+// limits use the defaults.
+type PromptBudget struct {
+	MaxPromptBytes  int // The whole prompt, template, attempts and fix history included
+	MaxPayloadBytes int
+	MaxErrorBytes   int
+	MaxOutputBytes  int // The end of the executor output is kept
+}
+
+func (b PromptBudget) withDefaults() PromptBudget {
+	if b.MaxPromptBytes <= 0 {
+		b.MaxPromptBytes = DefaultMaxPromptBytes
+	}
+	if b.MaxPayloadBytes <= 0 {
+		b.MaxPayloadBytes = DefaultMaxPayloadBytes
+	}
+	if b.MaxErrorBytes <= 0 {
+		b.MaxErrorBytes = DefaultMaxErrorBytes
+	}
+	if b.MaxOutputBytes <= 0 {
+		b.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	return b
+}
+
+// Fit returns a copy of the request whose error, payload and output fit the budget, given the
+// bytes the rest of the prompt takes. Each is cut to its own limit first; when the prompt would
+// still be too long the payload gives way first, then the output, and the error last, as it
+// usually says the most about the failure.
+func (b PromptBudget) Fit(request *AnalysisRequest, overhead int) *AnalysisRequest {
+	b = b.withDefaults()
+	errorLimit := min(len(request.Error), b.MaxErrorBytes)
+	payloadLimit := min(len(request.Payload), b.MaxPayloadBytes)
+	outputLimit := min(len(request.Output), b.MaxOutputBytes)
+
+	excess := errorLimit + payloadLimit + outputLimit - max(0, b.MaxPromptBytes-overhead)
+	for _, limit := range []*int{&payloadLimit, &outputLimit, &errorLimit} {
+		cut := min(max(excess, 0), *limit)
+		*limit -= cut
+		excess -= cut
+	}
+
+	fitted := *request
+	fitted.Error = Truncate(request.Error, errorLimit)
+	fitted.Payload = Truncate(request.Payload, payloadLimit)
+	fitted.Output = TruncateHead(request.Output, outputLimit)
+	return &fitted
+}
+
+// Truncate cuts the text to at most maxBytes on a UTF-8 boundary, ending it with a marker
+// telling the model how long the text was, unless the limit is too small to hold it
+func Truncate(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	marker := fmt.Sprintf(" ...[truncated, %d bytes in all]", len(text))
+	cut := maxBytes - len(marker)
+	if cut <= 0 {
+		marker, cut = "", max(0, maxBytes)
+	}
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + marker
+}
+
+// TruncateHead cuts the text to at most maxBytes on a UTF-8 boundary like Truncate, but keeps
+// its end, where output usually tells how a run ended
+func TruncateHead(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	marker := fmt.Sprintf("[truncated, %d bytes in all]... ", len(text))
+	keep := maxBytes - len(marker)
+	if keep <= 0 {
+		marker, keep = "", max(0, maxBytes)
+	}
+	start := len(text) - keep
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return marker + text[start:]
+}
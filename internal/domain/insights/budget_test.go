@@ -0,0 +1,241 @@
+package insights
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			text     string
+			maxBytes int
+		}
+		want struct {
+			text string
+		}
+	}{
+		{
+			name: "Given a text within the limit, When truncating, Then should return it unchanged",
+			in: struct {
+				text     string
+				maxBytes int
+			}{text: "connection refused", maxBytes: 100},
+			want: struct{ text string }{text: "connection refused"},
+		},
+		{
+			name: "Given a text over the limit, When truncating, Then should cut it and tell its length",
+			in: struct {
+				text     string
+				maxBytes int
+			}{text: strings.Repeat("a", 100), maxBytes: 40},
+			want: struct{ text string }{text: "aaaaaaa ...[truncated, 100 bytes in all]"},
+		},
+		{
+			name: "Given a cut inside a multibyte character, When truncating, Then should cut before the character",
+			in: struct {
+				text     string
+				maxBytes int
+			}{text: "ééééé", maxBytes: 5},
+			want: struct{ text string }{text: "éé"},
+		},
+		{
+			name: "Given a limit of zero, When truncating, Then should return an empty text",
+			in: struct {
+				text     string
+				maxBytes int
+			}{text: "boom", maxBytes: 0},
+			want: struct{ text string }{text: ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := Truncate(tt.in.text, tt.in.maxBytes)
+
+			assert.Equal(t, tt.want.text, text)
+			assert.LessOrEqual(t, len(text), max(tt.in.maxBytes, 0))
+		})
+	}
+}
+
+func TestPromptBudget_Fit(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			budget   PromptBudget
+			request  *AnalysisRequest
+			overhead int
+		}
+		want struct {
+			errorBytes   int
+			payloadBytes int
+			outputBytes  int
+		}
+	}{
+		{
+			name: "Given data within every limit, When fitting, Then should keep it whole",
+			in: struct {
+				budget   PromptBudget
+				request  *AnalysisRequest
+				overhead int
+			}{
+				request:  &AnalysisRequest{Error: "timeout", Payload: `{"to":"a"}`},
+				overhead: 500,
+			},
+			want: struct {
+				errorBytes   int
+				payloadBytes int
+				outputBytes  int
+			}{errorBytes: 7, payloadBytes: 10},
+		},
+		{
+			name: "Given a huge payload and error, When fitting, Then should cut each to its own limit",
+			in: struct {
+				budget   PromptBudget
+				request  *AnalysisRequest
+				overhead int
+			}{
+				budget:   PromptBudget{MaxPromptBytes: 10000, MaxPayloadBytes: 1000, MaxErrorBytes: 200},
+				request:  &AnalysisRequest{Error: strings.Repeat("e", 5000), Payload: strings.Repeat("p", 50000)},
+				overhead: 500,
+			},
+			want: struct {
+				errorBytes   int
+				payloadBytes int
+				outputBytes  int
+			}{errorBytes: 200, payloadBytes: 1000},
+		},
+		{
+			name: "Given a prompt over its limit, When fitting, Then should shrink the payload before the error",
+			in: struct {
+				budget   PromptBudget
+				request  *AnalysisRequest
+				overhead int
+			}{
+				budget:   PromptBudget{MaxPromptBytes: 1500, MaxPayloadBytes: 1000, MaxErrorBytes: 200},
+				request:  &AnalysisRequest{Error: strings.Repeat("e", 200), Payload: strings.Repeat("p", 1000)},
+				overhead: 1000,
+			},
+			want: struct {
+				errorBytes   int
+				payloadBytes int
+				outputBytes  int
+			}{errorBytes: 200, payloadBytes: 300},
+		},
+		{
+			name: "Given a long executor output, When fitting, Then should cut it to its own limit",
+			in: struct {
+				budget   PromptBudget
+				request  *AnalysisRequest
+				overhead int
+			}{
+				budget:   PromptBudget{MaxOutputBytes: 500},
+				request:  &AnalysisRequest{Error: "timeout", Output: strings.Repeat("o", 5000)},
+				overhead: 500,
+			},
+			want: struct {
+				errorBytes   int
+				payloadBytes int
+				outputBytes  int
+			}{errorBytes: 7, outputBytes: 500},
+		},
+		{
+			name: "Given a prompt over its limit, When fitting, Then should shrink the payload, then the output, before the error",
+			in: struct {
+				budget   PromptBudget
+				request  *AnalysisRequest
+				overhead int
+			}{
+				budget:   PromptBudget{MaxPromptBytes: 1500, MaxPayloadBytes: 1000, MaxErrorBytes: 200, MaxOutputBytes: 400},
+				request:  &AnalysisRequest{Error: strings.Repeat("e", 200), Payload: strings.Repeat("p", 1000), Output: strings.Repeat("o", 400)},
+				overhead: 1200,
+			},
+			want: struct {
+				errorBytes   int
+				payloadBytes int
+				outputBytes  int
+			}{errorBytes: 200, payloadBytes: 0, outputBytes: 100},
+		},
+		{
+			name: "Given an overhead taking the whole prompt, When fitting, Then should drop the payload and error",
+			in: struct {
+				budget   PromptBudget
+				request  *AnalysisRequest
+				overhead int
+			}{
+				budget:   PromptBudget{MaxPromptBytes: 1000},
+				request:  &AnalysisRequest{Error: "timeout", Payload: `{"to":"a"}`},
+				overhead: 2000,
+			},
+			want: struct {
+				errorBytes   int
+				payloadBytes int
+				outputBytes  int
+			}{errorBytes: 0, payloadBytes: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := *tt.in.request
+
+			fitted := tt.in.budget.Fit(tt.in.request, tt.in.overhead)
+
+			assert.Len(t, fitted.Error, tt.want.errorBytes)
+			assert.Len(t, fitted.Payload, tt.want.payloadBytes)
+			assert.Len(t, fitted.Output, tt.want.outputBytes)
+			assert.Equal(t, original, *tt.in.request)
+		})
+	}
+}
+
+func TestTruncateHead(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			text     string
+			maxBytes int
+		}
+		want struct {
+			text string
+		}
+	}{
+		{
+			name: "Given a text within the limit, When truncating its head, Then should return it unchanged",
+			in: struct {
+				text     string
+				maxBytes int
+			}{text: "done", maxBytes: 100},
+			want: struct{ text string }{text: "done"},
+		},
+		{
+			name: "Given a text over the limit, When truncating its head, Then should keep its end and tell its length",
+			in: struct {
+				text     string
+				maxBytes int
+			}{text: strings.Repeat("a", 95) + "panic", maxBytes: 40},
+			want: struct{ text string }{text: "[truncated, 100 bytes in all]... aapanic"},
+		},
+		{
+			name: "Given a cut inside a multibyte character, When truncating its head, Then should cut after the character",
+			in: struct {
+				text     string
+				maxBytes int
+			}{text: "ééééé", maxBytes: 5},
+			want: struct{ text string }{text: "éé"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := TruncateHead(tt.in.text, tt.in.maxBytes)
+
+			assert.Equal(t, tt.want.text, text)
+			assert.LessOrEqual(t, len(text), max(tt.in.maxBytes, 0))
+		})
+	}
+}
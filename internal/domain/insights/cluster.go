@@ -0,0 +1,194 @@
+package insights
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxClusterInsightIDs bounds the member insights listed per cluster, newest first
+const MaxClusterInsightIDs = 20
+
+var ErrInvalidClusterConfig = errors.New("cluster similarity must be between 0 and 1, and the min members, window and max insights must be positive")
+
+// ClusterConfig controls how failure insights are grouped by the similarity of their diagnoses
+type ClusterConfig struct {
+	Similarity  float64       // Cosine similarity an insight needs with a cluster's centroid to join it
+	MinMembers  int           // Smaller groups aren't reported as clusters
+	Window      time.Duration // Only insights created within it are clustered
+	MaxInsights int           // The newest insights clustered at most
+}
+
+// DefaultClusterConfig clusters up to 1000 insights of the last 7 days at a similarity of 0.85,
+// reporting groups of at least 2
+func DefaultClusterConfig() ClusterConfig {
+	return ClusterConfig{
+		Similarity:  0.85,
+		MinMembers:  2,
+		Window:      7 * 24 * time.Hour,
+		MaxInsights: 1000,
+	}
+}
+
+// Validate checks the configuration values
+func (c ClusterConfig) Validate() error {
+	if c.Similarity <= 0 || c.Similarity > 1 || c.MinMembers <= 0 || c.Window <= 0 || c.MaxInsights <= 0 {
+		return ErrInvalidClusterConfig
+	}
+	return nil
+}
+
+// Embeddings are the vectors an Embedder returned for a batch of texts, in the order of the texts
+type Embeddings struct {
+	Model   string // Identifies the embedding space; vectors of different models aren't comparable
+	Vectors [][]float64
+}
+
+// InsightCluster is a group of failure insights whose diagnoses mean about the same thing,
+// typically one systemic problem failing many jobs
+type InsightCluster struct {
+	Representative *Insight // The member closest to the centroid, whose diagnosis stands for the cluster
+	Members        int
+	JobTypes       []string    // Sorted
+	InsightIDs     []uuid.UUID // Newest first, up to MaxClusterInsightIDs
+	AvgConfidence  float64
+	Cohesion       float64 // Mean similarity of the members to the centroid
+	FirstSeen      time.Time
+	LastSeen       time.Time
+}
+
+// ClusterReport is the outcome of one clustering run
+type ClusterReport struct {
+	ComputedAt  time.Time
+	Since       time.Time
+	Model       string // Embedding model the insights were compared with
+	Insights    int
+	Unclustered int               // Insights in no group of at least MinMembers
+	Clusters    []*InsightCluster // Largest first
+}
+
+// clusterDraft accumulates the members of a cluster while insights are assigned
+type clusterDraft struct {
+	members  []*QueueInsight
+	vectors  [][]float64
+	centroid []float64 // Sum of the members' unit vectors
+}
+
+// ClusterInsights groups insights, newest first, by the cosine similarity of their vectors,
+// vectors[i] being the embedding of entries[i]'s diagnosis. Each insight joins the cluster whose
+// centroid it is most similar to when that reaches the configured similarity, and starts a new
+// cluster otherwise. Insights without a usable vector are left unclustered.
+func ClusterInsights(entries []*QueueInsight, vectors [][]float64, cfg ClusterConfig) []*InsightCluster {
+	var drafts []*clusterDraft
+	for i, entry := range entries {
+		if i >= len(vectors) {
+			break
+		}
+		vector := normalize(vectors[i])
+		if vector == nil {
+			continue
+		}
+
+		var best *clusterDraft
+		bestSimilarity := cfg.Similarity
+		for _, draft := range drafts {
+			if similarity := CosineSimilarity(vector, draft.centroid); similarity >= bestSimilarity {
+				best, bestSimilarity = draft, similarity
+			}
+		}
+		if best == nil {
+			best = &clusterDraft{centroid: make([]float64, len(vector))}
+			drafts = append(drafts, best)
+		}
+		best.members = append(best.members, entry)
+		best.vectors = append(best.vectors, vector)
+		if len(best.centroid) == len(vector) {
+			for j, value := range vector {
+				best.centroid[j] += value
+			}
+		}
+	}
+
+	var clusters []*InsightCluster
+	for _, draft := range drafts {
+		if len(draft.members) < cfg.MinMembers {
+			continue
+		}
+		clusters = append(clusters, draft.cluster())
+	}
+	slices.SortFunc(clusters, func(a, b *InsightCluster) int {
+		if a.Members != b.Members {
+			return b.Members - a.Members
+		}
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	return clusters
+}
+
+// cluster summarizes the draft's members
+func (d *clusterDraft) cluster() *InsightCluster {
+	cluster := &InsightCluster{Members: len(d.members)}
+	var confidence, cohesion float64
+	bestSimilarity := math.Inf(-1)
+	for i, member := range d.members {
+		insight := member.Insight
+		similarity := CosineSimilarity(d.vectors[i], d.centroid)
+		cohesion += similarity
+		if similarity > bestSimilarity {
+			cluster.Representative, bestSimilarity = insight, similarity
+		}
+
+		confidence += insight.Confidence
+		cluster.JobTypes = appendType(cluster.JobTypes, member.JobType)
+		if len(cluster.InsightIDs) < MaxClusterInsightIDs {
+			cluster.InsightIDs = append(cluster.InsightIDs, insight.ID)
+		}
+		if cluster.FirstSeen.IsZero() || insight.CreatedAt.Before(cluster.FirstSeen) {
+			cluster.FirstSeen = insight.CreatedAt
+		}
+		if insight.CreatedAt.After(cluster.LastSeen) {
+			cluster.LastSeen = insight.CreatedAt
+		}
+	}
+	cluster.AvgConfidence = confidence / float64(len(d.members))
+	cluster.Cohesion = cohesion / float64(len(d.members))
+	return cluster
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, from -1 to 1; vectors
+// of different lengths or without magnitude have a similarity of 0
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// normalize returns the unit vector of v, or nil when v has no magnitude
+func normalize(v []float64) []float64 {
+	var norm float64
+	for _, value := range v {
+		norm += value * value
+	}
+	if norm == 0 || math.IsNaN(norm) || math.IsInf(norm, 0) {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	unit := make([]float64, len(v))
+	for i, value := range v {
+		unit[i] = value / norm
+	}
+	return unit
+}
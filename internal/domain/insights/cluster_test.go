@@ -0,0 +1,169 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterInsights(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	entry := func(jobType, diagnosis string, confidence float64, age time.Duration) *QueueInsight {
+		return &QueueInsight{JobType: jobType, Insight: &Insight{
+			ID: uuid.New(), Diagnosis: diagnosis, Confidence: confidence, CreatedAt: now.Add(-age),
+		}}
+	}
+	smtpLatest := entry("email", "SMTP server timed out", 0.8, time.Hour)
+	smtpCentral := entry("digest", "The SMTP server timed out", 0.6, 2*time.Hour)
+	smtpOldest := entry("email", "SMTP connection timed out", 0.7, 3*time.Hour)
+	credentials := entry("billing", "Credentials were rejected", 0.9, 4*time.Hour)
+	noVector := entry("billing", "Unexplained failure", 0.3, 5*time.Hour)
+
+	type want struct {
+		representatives []*Insight
+		members         []int
+		jobTypes        [][]string
+		avgConfidence   []float64
+	}
+	tests := []struct {
+		name string
+		in   struct {
+			entries []*QueueInsight
+			vectors [][]float64
+			cfg     ClusterConfig
+		}
+		want want
+	}{
+		{
+			name: "Given similar diagnoses and an unrelated one, When clustering, Then should group the similar ones around the most central",
+			in: struct {
+				entries []*QueueInsight
+				vectors [][]float64
+				cfg     ClusterConfig
+			}{
+				entries: []*QueueInsight{smtpLatest, smtpCentral, smtpOldest, credentials, noVector},
+				vectors: [][]float64{{1, 0.2, 0}, {1, 0, 0}, {1, -0.2, 0}, {0, 0, 1}, {0, 0, 0}},
+				cfg:     ClusterConfig{Similarity: 0.9, MinMembers: 1},
+			},
+			want: want{
+				representatives: []*Insight{smtpCentral.Insight, credentials.Insight},
+				members:         []int{3, 1},
+				jobTypes:        [][]string{{"digest", "email"}, {"billing"}},
+				avgConfidence:   []float64{0.7, 0.9},
+			},
+		},
+		{
+			name: "Given a minimum of two members, When clustering, Then should leave out the singleton",
+			in: struct {
+				entries []*QueueInsight
+				vectors [][]float64
+				cfg     ClusterConfig
+			}{
+				entries: []*QueueInsight{smtpLatest, smtpCentral, credentials},
+				vectors: [][]float64{{1, 0.2, 0}, {1, 0, 0}, {0, 0, 1}},
+				cfg:     ClusterConfig{Similarity: 0.9, MinMembers: 2},
+			},
+			want: want{
+				representatives: []*Insight{smtpLatest.Insight},
+				members:         []int{2},
+				jobTypes:        [][]string{{"digest", "email"}},
+				avgConfidence:   []float64{0.7},
+			},
+		},
+		{
+			name: "Given a similarity threshold above that of the diagnoses, When clustering, Then should keep every insight apart",
+			in: struct {
+				entries []*QueueInsight
+				vectors [][]float64
+				cfg     ClusterConfig
+			}{
+				entries: []*QueueInsight{smtpLatest, smtpOldest},
+				vectors: [][]float64{{1, 0.2, 0}, {1, -0.2, 0}},
+				cfg:     ClusterConfig{Similarity: 0.99, MinMembers: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters := ClusterInsights(tt.in.entries, tt.in.vectors, tt.in.cfg)
+
+			assert.Len(t, clusters, len(tt.want.members))
+			for i, cluster := range clusters {
+				assert.Same(t, tt.want.representatives[i], cluster.Representative)
+				assert.Equal(t, tt.want.members[i], cluster.Members)
+				assert.Equal(t, tt.want.jobTypes[i], cluster.JobTypes)
+				assert.InDelta(t, tt.want.avgConfidence[i], cluster.AvgConfidence, 1e-9)
+				assert.Len(t, cluster.InsightIDs, cluster.Members)
+				assert.LessOrEqual(t, cluster.Cohesion, 1.0+1e-9)
+				assert.False(t, cluster.FirstSeen.After(cluster.LastSeen))
+			}
+		})
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct{ a, b []float64 }
+		want float64
+	}{
+		{
+			name: "Given vectors pointing the same way, When comparing, Then should return 1",
+			in:   struct{ a, b []float64 }{a: []float64{1, 2}, b: []float64{2, 4}},
+			want: 1,
+		},
+		{
+			name: "Given orthogonal vectors, When comparing, Then should return 0",
+			in:   struct{ a, b []float64 }{a: []float64{1, 0}, b: []float64{0, 3}},
+			want: 0,
+		},
+		{
+			name: "Given vectors of different lengths, When comparing, Then should return 0",
+			in:   struct{ a, b []float64 }{a: []float64{1, 0}, b: []float64{1, 0, 0}},
+			want: 0,
+		},
+		{
+			name: "Given a zero vector, When comparing, Then should return 0",
+			in:   struct{ a, b []float64 }{a: []float64{0, 0}, b: []float64{1, 0}},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, CosineSimilarity(tt.in.a, tt.in.b), 1e-9)
+		})
+	}
+}
+
+func TestClusterConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   ClusterConfig
+		want error
+	}{
+		{
+			name: "Given the default config, When validating, Then should accept it",
+			in:   DefaultClusterConfig(),
+		},
+		{
+			name: "Given a similarity above 1, When validating, Then should reject it",
+			in:   ClusterConfig{Similarity: 1.5, MinMembers: 2, Window: time.Hour, MaxInsights: 10},
+			want: ErrInvalidClusterConfig,
+		},
+		{
+			name: "Given no minimum members, When validating, Then should reject it",
+			in:   ClusterConfig{Similarity: 0.8, Window: time.Hour, MaxInsights: 10},
+			want: ErrInvalidClusterConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.in.Validate())
+		})
+	}
+}
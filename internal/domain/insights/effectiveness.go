@@ -0,0 +1,83 @@
+package insights
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FixOutcome tells how the first run of a job after one of its insight's fixes was applied went
+type FixOutcome string
+
+const (
+	FixOutcomePending   FixOutcome = "pending"   // The job hasn't run since the fix was applied
+	FixOutcomeSucceeded FixOutcome = "succeeded" // The job completed on its next run
+	FixOutcomeFailed    FixOutcome = "failed"    // The job failed again on its next run
+)
+
+// Kinds of suggested fix, combined with "+" when a fix does several things
+const (
+	FixKindTimeout      = "timeout"
+	FixKindRetries      = "retries"
+	FixKindPayloadPatch = "payload_patch"
+	FixKindRetry        = "retry" // Nothing but retrying the job
+)
+
+var fixKindLabels = map[string]string{
+	FixKindTimeout:      "timeout increase",
+	FixKindRetries:      "more retries",
+	FixKindPayloadPatch: "payload patch",
+	FixKindRetry:        "plain retry",
+}
+
+// Kind names what the fix changes, e.g. "timeout" or "payload_patch+timeout"
+func (f SuggestedFix) Kind() string {
+	var kinds []string
+	if len(f.PayloadPatch) > 0 {
+		kinds = append(kinds, FixKindPayloadPatch)
+	}
+	if f.MaxRetries > 0 {
+		kinds = append(kinds, FixKindRetries)
+	}
+	if f.TimeoutSeconds > 0 {
+		kinds = append(kinds, FixKindTimeout)
+	}
+	if len(kinds) == 0 {
+		return FixKindRetry
+	}
+	return strings.Join(kinds, "+")
+}
+
+// FixEffectiveness aggregates the outcomes of one kind of fix applied to jobs of one type
+type FixEffectiveness struct {
+	JobType   string
+	FixKind   string
+	Applied   int64
+	Succeeded int64
+	Failed    int64
+}
+
+// Resolved counts the applications whose job ran again
+func (e *FixEffectiveness) Resolved() int64 {
+	return e.Succeeded + e.Failed
+}
+
+// SuccessRate is the share of resolved applications whose job then completed, 0 when none resolved
+func (e *FixEffectiveness) SuccessRate() float64 {
+	if e.Resolved() == 0 {
+		return 0
+	}
+	return float64(e.Succeeded) / float64(e.Resolved())
+}
+
+// Summary describes the effectiveness in a sentence, e.g.
+// "timeout increase fixed 72% of send-email failures (18 of 25 retries)"
+func (e *FixEffectiveness) Summary() string {
+	kinds := strings.Split(e.FixKind, "+")
+	for i, kind := range kinds {
+		if label, ok := fixKindLabels[kind]; ok {
+			kinds[i] = label
+		}
+	}
+	return fmt.Sprintf("%s fixed %.0f%% of %s failures (%d of %d retries)",
+		strings.Join(kinds, " + "), e.SuccessRate()*100, e.JobType, e.Succeeded, e.Resolved())
+}
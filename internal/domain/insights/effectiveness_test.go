@@ -0,0 +1,82 @@
+package insights
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestedFix_Kind(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			fix SuggestedFix
+		}
+		want struct {
+			kind string
+		}
+	}{
+		{
+			name: "Given a fix raising the timeout, When naming its kind, Then should be a timeout fix",
+			in:   struct{ fix SuggestedFix }{fix: SuggestedFix{TimeoutSeconds: 30}},
+			want: struct{ kind string }{kind: FixKindTimeout},
+		},
+		{
+			name: "Given a fix patching the payload and raising the timeout, When naming its kind, Then should combine both",
+			in:   struct{ fix SuggestedFix }{fix: SuggestedFix{TimeoutSeconds: 30, PayloadPatch: map[string]any{"port": 587}}},
+			want: struct{ kind string }{kind: "payload_patch+timeout"},
+		},
+		{
+			name: "Given a fix that changes nothing, When naming its kind, Then should be a plain retry",
+			in:   struct{ fix SuggestedFix }{fix: SuggestedFix{}},
+			want: struct{ kind string }{kind: FixKindRetry},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.kind, tt.in.fix.Kind())
+		})
+	}
+}
+
+func TestFixEffectiveness_Summary(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			effectiveness FixEffectiveness
+		}
+		want struct {
+			rate    float64
+			summary string
+		}
+	}{
+		{
+			name: "Given resolved and pending applications, When summarizing, Then should rate only those that ran again",
+			in: struct{ effectiveness FixEffectiveness }{effectiveness: FixEffectiveness{
+				JobType: "smtp", FixKind: FixKindTimeout, Applied: 30, Succeeded: 18, Failed: 7,
+			}},
+			want: struct {
+				rate    float64
+				summary string
+			}{rate: 0.72, summary: "timeout increase fixed 72% of smtp failures (18 of 25 retries)"},
+		},
+		{
+			name: "Given no application ran again, When summarizing, Then should rate it zero",
+			in: struct{ effectiveness FixEffectiveness }{effectiveness: FixEffectiveness{
+				JobType: "smtp", FixKind: "payload_patch+timeout", Applied: 2,
+			}},
+			want: struct {
+				rate    float64
+				summary string
+			}{rate: 0, summary: "payload patch + timeout increase fixed 0% of smtp failures (0 of 0 retries)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want.rate, tt.in.effectiveness.SuccessRate(), 0.0001)
+			assert.Equal(t, tt.want.summary, tt.in.effectiveness.Summary())
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package insights
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// ErrPayloadNotObject is returned when a payload patch targets a payload that isn't a JSON object
+var ErrPayloadNotObject = errors.New("job payload is not a JSON object")
+
+// Payload change operations
+const (
+	ChangeAdd     = "add"
+	ChangeReplace = "replace"
+)
+
+// PayloadChange is a single top-level payload field changed by a suggested fix
+type PayloadChange struct {
+	Op     string `json:"op"`
+	Key    string `json:"key"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after"`
+}
+
+// FixPlan describes what applying an insight's suggested fix does to its job
+type FixPlan struct {
+	InsightID      uuid.UUID
+	JobID          uuid.UUID
+	JobType        string
+	FixKind        string
+	PayloadBefore  []byte
+	PayloadAfter   []byte
+	Changes        []PayloadChange
+	StatusBefore   queue.Status
+	StatusAfter    queue.Status
+	WillRetry      bool
+	MaxRetries     int
+	TimeoutSeconds int
+}
+
+// FixApplication is the audit record written when a fix plan is applied to a job
+type FixApplication struct {
+	ID            uuid.UUID
+	InsightID     uuid.UUID
+	JobID         uuid.UUID
+	PayloadBefore []byte
+	PayloadAfter  []byte
+	StatusBefore  queue.Status
+	StatusAfter   queue.Status
+	JobType       string
+	FixKind       string
+	Outcome       FixOutcome
+	AppliedAt     time.Time
+	ResolvedAt    *time.Time // When the outcome was recorded
+}
+
+// PlanFix computes the changes the suggested fix would make to the job without modifying it
+func (i *Insight) PlanFix(job *queue.Job) (*FixPlan, error) {
+	plan := &FixPlan{
+		InsightID:      i.ID,
+		JobID:          job.ID,
+		JobType:        job.Type,
+		FixKind:        i.SuggestedFix.Kind(),
+		PayloadBefore:  job.Payload,
+		PayloadAfter:   job.Payload,
+		Changes:        []PayloadChange{},
+		StatusBefore:   job.Status,
+		StatusAfter:    job.Status,
+		WillRetry:      i.HasRetryRecommendation() && job.Status.CanTransitionTo(queue.StatusRetrying),
+		MaxRetries:     i.SuggestedFix.MaxRetries,
+		TimeoutSeconds: i.SuggestedFix.TimeoutSeconds,
+	}
+	if plan.WillRetry {
+		plan.StatusAfter = queue.StatusRetrying
+	}
+
+	if len(i.SuggestedFix.PayloadPatch) == 0 {
+		return plan, nil
+	}
+
+	var before map[string]any
+	if err := json.Unmarshal(job.Payload, &before); err != nil || before == nil {
+		return nil, ErrPayloadNotObject
+	}
+
+	patched, err := i.ApplySuggestedFix(job.Payload)
+	if err != nil {
+		return nil, err
+	}
+	plan.PayloadAfter = patched
+
+	// Decode the patched payload so values compare with JSON types on both sides
+	var after map[string]any
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(i.SuggestedFix.PayloadPatch))
+	for key := range i.SuggestedFix.PayloadPatch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		previous, existed := before[key]
+		switch {
+		case !existed:
+			plan.Changes = append(plan.Changes, PayloadChange{Op: ChangeAdd, Key: key, After: after[key]})
+		case !reflect.DeepEqual(previous, after[key]):
+			plan.Changes = append(plan.Changes, PayloadChange{Op: ChangeReplace, Key: key, Before: previous, After: after[key]})
+		}
+	}
+
+	return plan, nil
+}
+
+// NewFixApplication creates the audit record for an applied plan
+func NewFixApplication(plan *FixPlan) *FixApplication {
+	return &FixApplication{
+		ID:            uuid.New(),
+		InsightID:     plan.InsightID,
+		JobID:         plan.JobID,
+		PayloadBefore: plan.PayloadBefore,
+		PayloadAfter:  plan.PayloadAfter,
+		StatusBefore:  plan.StatusBefore,
+		StatusAfter:   plan.StatusAfter,
+		JobType:       plan.JobType,
+		FixKind:       plan.FixKind,
+		Outcome:       FixOutcomePending,
+		AppliedAt:     time.Now().UTC(),
+	}
+}
@@ -3,19 +3,50 @@ package insights
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 	"github.com/google/uuid"
 )
 
-// Insight represents an AI-generated analysis of a job failure
+// Insight represents an AI-generated analysis of a job failure or slow run
 type Insight struct {
-	ID             uuid.UUID
-	JobID          uuid.UUID
+	ID    uuid.UUID
+	JobID uuid.UUID
+	// Kind tells whether the insight explains a failure or a slow run
+	Kind           Kind
 	Diagnosis      string
 	Recommendation string
 	SuggestedFix   SuggestedFix
-	CreatedAt      time.Time
+	Confidence     float64
+	// Provider names the configured AI provider that produced the analysis
+	Provider string
+	// Usage is nil when the AI provider did not report it
+	Usage *Usage
+	// Triage tells how actionable the failure is; empty for insights stored before triage
+	Triage TriageLabel
+	// AIRecommendation keeps the AI's recommendation once an operator replaced it; empty otherwise
+	AIRecommendation string
+	// Fingerprint is the queue.ErrorFingerprint of the failure analyzed; empty for performance
+	// insights and those stored before fingerprints
+	Fingerprint string
+	// SharedFrom is the insight analyzed for another job failing the same way during a failure
+	// storm, which this one was copied from instead of calling the AI; nil otherwise
+	SharedFrom *uuid.UUID
+	// Note is an operator's annotation of the insight
+	Note      string
+	EditedBy  string
+	EditedAt  *time.Time
+	CreatedAt time.Time
+}
+
+// InsightEdit is an operator's correction or annotation of an insight; nil fields are left unchanged
+type InsightEdit struct {
+	Recommendation *string
+	Note           *string
+	EditedBy       string
 }
 
 // SuggestedFix contains AI-recommended fixes for job failures
@@ -28,8 +59,22 @@ type SuggestedFix struct {
 // AnalysisRequest represents the data needed for AI analysis
 type AnalysisRequest struct {
 	JobID   string
+	JobType string
+	Queue   string
 	Error   string
 	Payload string
+	// Metadata is what the job carries apart from its payload, e.g. the system it came from or
+	// its correlation ID; empty when it has none
+	Metadata map[string]string
+	// Attempts lists the job's last runs, oldest first, so the AI can tell a transient failure
+	// from one that recurs; empty when they aren't recorded
+	Attempts []*queue.Attempt
+	// Output is the end of what the executor wrote during the last run
+	Output string
+	// QueueConfig holds the settings of the job's queue, nil when it has no definition
+	QueueConfig *queue.Definition
+	// FixHistory tells how past fixes worked for jobs of the same type, best first
+	FixHistory []*FixEffectiveness
 }
 
 // AnalysisResponse represents the AI analysis result
@@ -37,6 +82,35 @@ type AnalysisResponse struct {
 	Diagnosis      string       `json:"diagnosis"`
 	Recommendation string       `json:"recommendation"`
 	SuggestedFix   SuggestedFix `json:"suggested_fix"`
+	Confidence     float64      `json:"confidence"`
+	Provider       string       `json:"provider,omitempty"`
+	Usage          *Usage       `json:"usage,omitempty"`
+}
+
+// MoreConfident returns whichever analysis has the higher confidence, preferring a
+// when they tie; nil or unusable analyses lose to valid ones
+func MoreConfident(a, b *AnalysisResponse) *AnalysisResponse {
+	if !a.IsValid() {
+		if b.IsValid() {
+			return b
+		}
+		return nil
+	}
+	if b.IsValid() && clampConfidence(b.Confidence) > clampConfidence(a.Confidence) {
+		return b
+	}
+	return a
+}
+
+// IsValid reports whether the analysis carries enough to build an insight
+func (r *AnalysisResponse) IsValid() bool {
+	return r != nil && r.Diagnosis != ""
+}
+
+// JobWithInsight pairs a job with its latest insight (nil when not analyzed yet)
+type JobWithInsight struct {
+	Job     *queue.Job
+	Insight *Insight
 }
 
 var (
@@ -44,6 +118,7 @@ var (
 	ErrAnalysisFailed      = errors.New("AI analysis failed")
 	ErrInsightNotFound     = errors.New("insight not found")
 	ErrInvalidAnalysisData = errors.New("invalid analysis data")
+	ErrInvalidEdit         = errors.New("invalid insight edit")
 )
 
 // NewInsight creates a new insight from an analysis response
@@ -51,20 +126,93 @@ func NewInsight(jobID uuid.UUID, response *AnalysisResponse) (*Insight, error) {
 	if jobID == uuid.Nil {
 		return nil, ErrInvalidJobID
 	}
-	if response == nil || response.Diagnosis == "" {
+	if !response.IsValid() {
 		return nil, ErrInvalidAnalysisData
 	}
 
 	return &Insight{
 		ID:             uuid.New(),
 		JobID:          jobID,
+		Kind:           KindFailure,
 		Diagnosis:      response.Diagnosis,
 		Recommendation: response.Recommendation,
 		SuggestedFix:   response.SuggestedFix,
+		Confidence:     clampConfidence(response.Confidence),
+		Provider:       response.Provider,
+		Usage:          response.Usage,
 		CreatedAt:      time.Now().UTC(),
 	}, nil
 }
 
+// ShareWith copies the insight for another job failing the same way, linked back to the
+// insight that was analyzed. The copy has no usage of its own and none of the edits.
+func (i *Insight) ShareWith(jobID uuid.UUID) *Insight {
+	source := i.ID
+	if i.SharedFrom != nil {
+		source = *i.SharedFrom
+	}
+	return &Insight{
+		ID:             uuid.New(),
+		JobID:          jobID,
+		Kind:           i.Kind,
+		Diagnosis:      i.Diagnosis,
+		Recommendation: i.Recommendation,
+		SuggestedFix:   i.SuggestedFix,
+		Confidence:     i.Confidence,
+		Provider:       i.Provider,
+		Fingerprint:    i.Fingerprint,
+		SharedFrom:     &source,
+		CreatedAt:      time.Now().UTC(),
+	}
+}
+
+// clampConfidence keeps model-reported confidence within [0, 1]
+func clampConfidence(confidence float64) float64 {
+	if confidence < 0 {
+		return 0
+	}
+	if confidence > 1 {
+		return 1
+	}
+	return confidence
+}
+
+// Edit applies an operator's edit. The first time the recommendation is replaced, the AI's
+// text is kept in AIRecommendation so it is never lost.
+func (i *Insight) Edit(edit InsightEdit) error {
+	editedBy := strings.TrimSpace(edit.EditedBy)
+	if editedBy == "" {
+		return fmt.Errorf("%w: edited_by is required", ErrInvalidEdit)
+	}
+	if edit.Recommendation == nil && edit.Note == nil {
+		return fmt.Errorf("%w: nothing to change", ErrInvalidEdit)
+	}
+
+	if edit.Recommendation != nil {
+		recommendation := strings.TrimSpace(*edit.Recommendation)
+		if recommendation == "" {
+			return fmt.Errorf("%w: recommendation can't be empty", ErrInvalidEdit)
+		}
+		if !i.IsEdited() {
+			i.AIRecommendation = i.Recommendation
+		}
+		i.Recommendation = recommendation
+	}
+	if edit.Note != nil {
+		i.Note = strings.TrimSpace(*edit.Note)
+	}
+
+	now := time.Now().UTC()
+	i.EditedBy = editedBy
+	i.EditedAt = &now
+	return nil
+}
+
+// IsEdited reports whether an operator replaced the AI's recommendation
+func (i *Insight) IsEdited() bool {
+	return i.AIRecommendation != ""
+}
+
 // ApplySuggestedFix applies the suggested fix to a job payload
 func (i *Insight) ApplySuggestedFix(originalPayload []byte) ([]byte, error) {
 	if len(i.SuggestedFix.PayloadPatch) == 0 {
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewInsight(t *testing.T) {
@@ -323,3 +325,379 @@ func TestInsight_HasRetryRecommendation(t *testing.T) {
 		})
 	}
 }
+
+func TestNewInsight_Confidence(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			confidence float64
+		}
+		want struct {
+			confidence float64
+		}
+	}{
+		{
+			name: "Given confidence within range, When creating insight, Then should keep it",
+			in:   struct{ confidence float64 }{confidence: 0.75},
+			want: struct{ confidence float64 }{confidence: 0.75},
+		},
+		{
+			name: "Given confidence above 1, When creating insight, Then should clamp to 1",
+			in:   struct{ confidence float64 }{confidence: 85},
+			want: struct{ confidence float64 }{confidence: 1},
+		},
+		{
+			name: "Given negative confidence, When creating insight, Then should clamp to 0",
+			in:   struct{ confidence float64 }{confidence: -0.2},
+			want: struct{ confidence float64 }{confidence: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insight, err := NewInsight(uuid.New(), &AnalysisResponse{
+				Diagnosis:  "Network timeout",
+				Confidence: tt.in.confidence,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.confidence, insight.Confidence)
+		})
+	}
+}
+
+func TestNewInsight_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			usage *Usage
+		}
+		want struct {
+			usage       *Usage
+			totalTokens int
+		}
+	}{
+		{
+			name: "Given reported usage, When creating insight, Then should keep it",
+			in: struct{ usage *Usage }{
+				usage: &Usage{Provider: "ollama", Model: "phi3:mini", PromptTokens: 120, CompletionTokens: 80, PromptBytes: 900, LatencyMs: 1500},
+			},
+			want: struct {
+				usage       *Usage
+				totalTokens int
+			}{
+				usage:       &Usage{Provider: "ollama", Model: "phi3:mini", PromptTokens: 120, CompletionTokens: 80, PromptBytes: 900, LatencyMs: 1500},
+				totalTokens: 200,
+			},
+		},
+		{
+			name: "Given no reported usage, When creating insight, Then usage should be nil",
+			in:   struct{ usage *Usage }{usage: nil},
+			want: struct {
+				usage       *Usage
+				totalTokens int
+			}{usage: nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insight, err := NewInsight(uuid.New(), &AnalysisResponse{
+				Diagnosis: "Network timeout",
+				Usage:     tt.in.usage,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.usage, insight.Usage)
+			if tt.want.usage != nil {
+				assert.Equal(t, tt.want.totalTokens, insight.Usage.TotalTokens())
+			}
+		})
+	}
+}
+
+func TestMoreConfident(t *testing.T) {
+	primary := &AnalysisResponse{Diagnosis: "Network timeout", Confidence: 0.6, Provider: "primary"}
+	secondary := &AnalysisResponse{Diagnosis: "DNS failure", Confidence: 0.9, Provider: "secondary"}
+	tied := &AnalysisResponse{Diagnosis: "DNS failure", Confidence: 0.6, Provider: "secondary"}
+	empty := &AnalysisResponse{Confidence: 1, Provider: "secondary"}
+
+	tests := []struct {
+		name string
+		in   struct {
+			a, b *AnalysisResponse
+		}
+		want struct {
+			provider string
+		}
+	}{
+		{
+			name: "Given a more confident second analysis, When comparing, Then should keep the second",
+			in:   struct{ a, b *AnalysisResponse }{a: primary, b: secondary},
+			want: struct{ provider string }{provider: "secondary"},
+		},
+		{
+			name: "Given equal confidence, When comparing, Then should keep the first",
+			in:   struct{ a, b *AnalysisResponse }{a: primary, b: tied},
+			want: struct{ provider string }{provider: "primary"},
+		},
+		{
+			name: "Given an analysis without diagnosis, When comparing, Then should keep the valid one",
+			in:   struct{ a, b *AnalysisResponse }{a: primary, b: empty},
+			want: struct{ provider string }{provider: "primary"},
+		},
+		{
+			name: "Given a failed first analysis, When comparing, Then should keep the second",
+			in:   struct{ a, b *AnalysisResponse }{a: nil, b: secondary},
+			want: struct{ provider string }{provider: "secondary"},
+		},
+		{
+			name: "Given no valid analysis, When comparing, Then should return nil",
+			in:   struct{ a, b *AnalysisResponse }{a: nil, b: empty},
+			want: struct{ provider string }{provider: ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MoreConfident(tt.in.a, tt.in.b)
+
+			if tt.want.provider == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want.provider, got.Provider)
+		})
+	}
+}
+
+func TestInsight_PlanFix(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			payload []byte
+			fix     SuggestedFix
+		}
+		want struct {
+			err         error
+			changes     []PayloadChange
+			statusAfter queue.Status
+		}
+	}{
+		{
+			name: "Given a patch adding and replacing fields, When planning the fix, Then should list both changes sorted by key",
+			in: struct {
+				payload []byte
+				fix     SuggestedFix
+			}{
+				payload: []byte(`{"timeout":5,"url":"http://api"}`),
+				fix:     SuggestedFix{PayloadPatch: map[string]any{"timeout": 30, "retries": 2, "url": "http://api"}},
+			},
+			want: struct {
+				err         error
+				changes     []PayloadChange
+				statusAfter queue.Status
+			}{
+				changes: []PayloadChange{
+					{Op: ChangeAdd, Key: "retries", After: 2.0},
+					{Op: ChangeReplace, Key: "timeout", Before: 5.0, After: 30.0},
+				},
+				statusAfter: queue.StatusFailed,
+			},
+		},
+		{
+			name: "Given a retry recommendation, When planning the fix, Then should plan a retry",
+			in: struct {
+				payload []byte
+				fix     SuggestedFix
+			}{
+				payload: []byte(`{}`),
+				fix:     SuggestedFix{MaxRetries: 3},
+			},
+			want: struct {
+				err         error
+				changes     []PayloadChange
+				statusAfter queue.Status
+			}{
+				changes:     []PayloadChange{},
+				statusAfter: queue.StatusRetrying,
+			},
+		},
+		{
+			name: "Given a non-object payload, When planning a patch, Then should return ErrPayloadNotObject",
+			in: struct {
+				payload []byte
+				fix     SuggestedFix
+			}{
+				payload: []byte(`[1,2]`),
+				fix:     SuggestedFix{PayloadPatch: map[string]any{"timeout": 30}},
+			},
+			want: struct {
+				err         error
+				changes     []PayloadChange
+				statusAfter queue.Status
+			}{err: ErrPayloadNotObject},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &queue.Job{ID: uuid.New(), Status: queue.StatusFailed, Payload: tt.in.payload}
+			insight := &Insight{ID: uuid.New(), JobID: job.ID, SuggestedFix: tt.in.fix}
+
+			plan, err := insight.PlanFix(job)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			if tt.want.err != nil {
+				return
+			}
+			assert.Equal(t, tt.want.changes, plan.Changes)
+			assert.Equal(t, tt.want.statusAfter, plan.StatusAfter)
+			assert.Equal(t, queue.StatusFailed, job.Status)
+			assert.Equal(t, tt.in.payload, job.Payload)
+		})
+	}
+}
+
+func TestInsight_Edit(t *testing.T) {
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		in   struct {
+			edits []InsightEdit
+		}
+		want struct {
+			err              error
+			recommendation   string
+			aiRecommendation string
+			note             string
+		}
+	}{
+		{
+			name: "Given a new recommendation, When editing, Then should replace it and keep the AI's text",
+			in: struct{ edits []InsightEdit }{edits: []InsightEdit{
+				{Recommendation: text("Raise the gateway timeout to 60s"), EditedBy: "ana"},
+			}},
+			want: struct {
+				err              error
+				recommendation   string
+				aiRecommendation string
+				note             string
+			}{recommendation: "Raise the gateway timeout to 60s", aiRecommendation: "Increase timeout"},
+		},
+		{
+			name: "Given two recommendation edits, When editing, Then should keep the AI's text rather than the first edit",
+			in: struct{ edits []InsightEdit }{edits: []InsightEdit{
+				{Recommendation: text("Raise the gateway timeout to 60s"), EditedBy: "ana"},
+				{Recommendation: text("Raise the gateway timeout to 90s"), EditedBy: "bruno"},
+			}},
+			want: struct {
+				err              error
+				recommendation   string
+				aiRecommendation string
+				note             string
+			}{recommendation: "Raise the gateway timeout to 90s", aiRecommendation: "Increase timeout"},
+		},
+		{
+			name: "Given only a note, When editing, Then should annotate without touching the recommendation",
+			in: struct{ edits []InsightEdit }{edits: []InsightEdit{
+				{Note: text("Confirmed with the payments team"), EditedBy: "ana"},
+			}},
+			want: struct {
+				err              error
+				recommendation   string
+				aiRecommendation string
+				note             string
+			}{recommendation: "Increase timeout", note: "Confirmed with the payments team"},
+		},
+		{
+			name: "Given no editor, When editing, Then should return ErrInvalidEdit",
+			in: struct{ edits []InsightEdit }{edits: []InsightEdit{
+				{Note: text("Confirmed"), EditedBy: " "},
+			}},
+			want: struct {
+				err              error
+				recommendation   string
+				aiRecommendation string
+				note             string
+			}{err: ErrInvalidEdit, recommendation: "Increase timeout"},
+		},
+		{
+			name: "Given an empty recommendation, When editing, Then should return ErrInvalidEdit",
+			in: struct{ edits []InsightEdit }{edits: []InsightEdit{
+				{Recommendation: text(""), EditedBy: "ana"},
+			}},
+			want: struct {
+				err              error
+				recommendation   string
+				aiRecommendation string
+				note             string
+			}{err: ErrInvalidEdit, recommendation: "Increase timeout"},
+		},
+		{
+			name: "Given nothing to change, When editing, Then should return ErrInvalidEdit",
+			in: struct{ edits []InsightEdit }{edits: []InsightEdit{
+				{EditedBy: "ana"},
+			}},
+			want: struct {
+				err              error
+				recommendation   string
+				aiRecommendation string
+				note             string
+			}{err: ErrInvalidEdit, recommendation: "Increase timeout"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insight := &Insight{ID: uuid.New(), JobID: uuid.New(), Recommendation: "Increase timeout"}
+
+			var err error
+			for _, edit := range tt.in.edits {
+				err = insight.Edit(edit)
+			}
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.recommendation, insight.Recommendation)
+			assert.Equal(t, tt.want.aiRecommendation, insight.AIRecommendation)
+			assert.Equal(t, tt.want.note, insight.Note)
+			if tt.want.err == nil {
+				assert.Equal(t, tt.in.edits[len(tt.in.edits)-1].EditedBy, insight.EditedBy)
+				assert.NotNil(t, insight.EditedAt)
+			} else {
+				assert.Nil(t, insight.EditedAt)
+			}
+		})
+	}
+}
+
+func TestInsight_ShareWith(t *testing.T) {
+	original := &Insight{
+		ID:             uuid.New(),
+		JobID:          uuid.New(),
+		Kind:           KindFailure,
+		Diagnosis:      "SMTP server down",
+		Recommendation: "Retry later",
+		Confidence:     0.8,
+		Provider:       "ollama:phi3:mini",
+		Usage:          &Usage{PromptTokens: 120},
+		Note:           "known outage",
+		EditedBy:       "ana",
+	}
+	jobID := uuid.New()
+
+	shared := original.ShareWith(jobID)
+	assert.NotEqual(t, original.ID, shared.ID)
+	assert.Equal(t, jobID, shared.JobID)
+	assert.Equal(t, original.Diagnosis, shared.Diagnosis)
+	assert.Equal(t, original.Confidence, shared.Confidence)
+	require.NotNil(t, shared.SharedFrom)
+	assert.Equal(t, original.ID, *shared.SharedFrom)
+	assert.Nil(t, shared.Usage, "Given a shared insight, When copying, Then should not count the AI usage twice")
+	assert.Empty(t, shared.Note)
+
+	// Sharing a shared insight links back to the analyzed one
+	again := shared.ShareWith(uuid.New())
+	assert.Equal(t, original.ID, *again.SharedFrom)
+}
@@ -0,0 +1,136 @@
+package insights
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// Kind tells what an insight analyzed
+type Kind string
+
+const (
+	KindFailure     Kind = "failure"     // Why a job failed
+	KindPerformance Kind = "performance" // Why a job that completed ran slower than its SLO
+)
+
+var ErrInvalidKind = errors.New("insight kind must be failure or performance")
+
+// Validate checks the kind is a known one; an empty kind is valid and means any kind
+func (k Kind) Validate() error {
+	switch k {
+	case "", KindFailure, KindPerformance:
+		return nil
+	default:
+		return ErrInvalidKind
+	}
+}
+
+// KindOf returns the kind of an insight; insights built without one explain a failure
+func KindOf(insight *Insight) Kind {
+	if insight.Kind == "" {
+		return KindFailure
+	}
+	return insight.Kind
+}
+
+var ErrInvalidPerformancePolicy = errors.New("performance SLOs and cooldown must not be negative")
+
+// DefaultPerformanceCooldown is the time between two analyses of a job type when none is set
+const DefaultPerformanceCooldown = time.Hour
+
+// PerformancePolicy selects the completed runs sent for a performance analysis: those slower
+// than the SLO of their job type, at most one per job type every cooldown. It is safe for
+// concurrent use, so the workers of a process share it.
+type PerformancePolicy struct {
+	slos       map[string]time.Duration
+	defaultSLO time.Duration
+	cooldown   time.Duration
+
+	mu         sync.Mutex
+	lastQueued map[string]time.Time // Job type -> when a slow run of it was last selected
+}
+
+// NewPerformancePolicy creates a policy with an SLO per job type. Types without their own use
+// defaultSLO; a zero SLO leaves the type out. A zero cooldown uses DefaultPerformanceCooldown.
+func NewPerformancePolicy(slos map[string]time.Duration, defaultSLO, cooldown time.Duration) (*PerformancePolicy, error) {
+	if defaultSLO < 0 || cooldown < 0 {
+		return nil, ErrInvalidPerformancePolicy
+	}
+	for _, slo := range slos {
+		if slo < 0 {
+			return nil, ErrInvalidPerformancePolicy
+		}
+	}
+	if cooldown == 0 {
+		cooldown = DefaultPerformanceCooldown
+	}
+	return &PerformancePolicy{
+		slos:       slos,
+		defaultSLO: defaultSLO,
+		cooldown:   cooldown,
+		lastQueued: make(map[string]time.Time),
+	}, nil
+}
+
+// SLO returns the duration runs of the job type are expected to stay under, zero when none
+func (p *PerformancePolicy) SLO(jobType string) time.Duration {
+	if slo, ok := p.slos[jobType]; ok {
+		return slo
+	}
+	return p.defaultSLO
+}
+
+// Select reports whether a run of the job that lasted duration is analyzed, returning it as a
+// slow run. A selected run starts the cooldown of its job type.
+func (p *PerformancePolicy) Select(job *queue.Job, duration time.Duration, now time.Time) (*SlowRun, bool) {
+	slo := p.SLO(job.Type)
+	if slo <= 0 || duration <= slo {
+		return nil, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if last, ok := p.lastQueued[job.Type]; ok && now.Sub(last) < p.cooldown {
+		return nil, false
+	}
+	p.lastQueued[job.Type] = now
+	return &SlowRun{JobID: job.ID, Duration: duration, SLO: slo}, true
+}
+
+// SlowRun is a completed run of a job that lasted longer than its SLO
+type SlowRun struct {
+	JobID    uuid.UUID
+	Duration time.Duration
+	SLO      time.Duration
+}
+
+// AnalysisRequest asks the AI why the run was slow. The run and the timing history of the
+// job's type stand for the error, so any AIService can answer it; history is nil when unknown.
+func (r *SlowRun) AnalysisRequest(job *queue.Job, history *queue.DurationHistogram) *AnalysisRequest {
+	text := fmt.Sprintf("The job completed successfully but took %s, above its SLO of %s. "+
+		"Diagnose why it ran slow and recommend how to bring it under the SLO; "+
+		"only suggest a timeout or payload change that would speed it up.",
+		r.Duration.Round(time.Millisecond), r.SLO)
+	if history != nil && history.Count() > 0 {
+		text += fmt.Sprintf("\nTiming of the %d recorded runs of %s jobs in queue %s: mean %s, p50 %s, p95 %s, p99 %s.",
+			history.Count(), job.Type, job.Queue,
+			seconds(history.Mean()), seconds(history.Percentile(0.5)),
+			seconds(history.Percentile(0.95)), seconds(history.Percentile(0.99)))
+	}
+
+	return &AnalysisRequest{
+		JobID:   job.ID.String(),
+		Error:   text,
+		Payload: string(job.Payload),
+	}
+}
+
+// seconds formats a duration given in seconds to the millisecond
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,266 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKind_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			kind Kind
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given no kind, When validating, Then should accept it as any kind",
+			in:   struct{ kind Kind }{kind: ""},
+		},
+		{
+			name: "Given the performance kind, When validating, Then should accept it",
+			in:   struct{ kind Kind }{kind: KindPerformance},
+		},
+		{
+			name: "Given an unknown kind, When validating, Then should return ErrInvalidKind",
+			in:   struct{ kind Kind }{kind: "latency"},
+			want: struct{ err error }{err: ErrInvalidKind},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.kind.Validate()
+
+			assert.ErrorIs(t, err, tt.want.err)
+		})
+	}
+}
+
+func TestNewPerformancePolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			slos       map[string]time.Duration
+			defaultSLO time.Duration
+			cooldown   time.Duration
+		}
+		want struct {
+			err      error
+			cooldown time.Duration
+		}
+	}{
+		{
+			name: "Given no cooldown, When creating a policy, Then should use the default cooldown",
+			in: struct {
+				slos       map[string]time.Duration
+				defaultSLO time.Duration
+				cooldown   time.Duration
+			}{slos: map[string]time.Duration{"email": time.Second}},
+			want: struct {
+				err      error
+				cooldown time.Duration
+			}{cooldown: DefaultPerformanceCooldown},
+		},
+		{
+			name: "Given a negative SLO, When creating a policy, Then should return ErrInvalidPerformancePolicy",
+			in: struct {
+				slos       map[string]time.Duration
+				defaultSLO time.Duration
+				cooldown   time.Duration
+			}{slos: map[string]time.Duration{"email": -time.Second}},
+			want: struct {
+				err      error
+				cooldown time.Duration
+			}{err: ErrInvalidPerformancePolicy},
+		},
+		{
+			name: "Given a negative cooldown, When creating a policy, Then should return ErrInvalidPerformancePolicy",
+			in: struct {
+				slos       map[string]time.Duration
+				defaultSLO time.Duration
+				cooldown   time.Duration
+			}{defaultSLO: time.Second, cooldown: -time.Minute},
+			want: struct {
+				err      error
+				cooldown time.Duration
+			}{err: ErrInvalidPerformancePolicy},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewPerformancePolicy(tt.in.slos, tt.in.defaultSLO, tt.in.cooldown)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+				assert.Nil(t, policy)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.cooldown, policy.cooldown)
+		})
+	}
+}
+
+func TestPerformancePolicy_Select(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	job := func(jobType string) *queue.Job {
+		return &queue.Job{ID: uuid.New(), Queue: "default", Type: jobType}
+	}
+
+	tests := []struct {
+		name string
+		in   struct {
+			selected []string // Job types a slow run was selected for a minute earlier
+			job      *queue.Job
+			duration time.Duration
+		}
+		want struct {
+			selected bool
+			slo      time.Duration
+		}
+	}{
+		{
+			name: "Given a run slower than its type's SLO, When selecting, Then should select it with that SLO",
+			in: struct {
+				selected []string
+				job      *queue.Job
+				duration time.Duration
+			}{job: job("email"), duration: 3 * time.Second},
+			want: struct {
+				selected bool
+				slo      time.Duration
+			}{selected: true, slo: 2 * time.Second},
+		},
+		{
+			name: "Given a run under its type's SLO, When selecting, Then should not select it",
+			in: struct {
+				selected []string
+				job      *queue.Job
+				duration time.Duration
+			}{job: job("email"), duration: time.Second},
+		},
+		{
+			name: "Given a type without its own SLO, When selecting a run slower than the default, Then should select it with the default SLO",
+			in: struct {
+				selected []string
+				job      *queue.Job
+				duration time.Duration
+			}{job: job("report"), duration: 11 * time.Second},
+			want: struct {
+				selected bool
+				slo      time.Duration
+			}{selected: true, slo: 10 * time.Second},
+		},
+		{
+			name: "Given a type with a zero SLO, When selecting a long run, Then should not select it",
+			in: struct {
+				selected []string
+				job      *queue.Job
+				duration time.Duration
+			}{job: job("backup"), duration: time.Hour},
+		},
+		{
+			name: "Given a slow run of the type selected within the cooldown, When selecting, Then should not select it",
+			in: struct {
+				selected []string
+				job      *queue.Job
+				duration time.Duration
+			}{selected: []string{"email"}, job: job("email"), duration: 3 * time.Second},
+		},
+		{
+			name: "Given a slow run of another type selected within the cooldown, When selecting, Then should select it",
+			in: struct {
+				selected []string
+				job      *queue.Job
+				duration time.Duration
+			}{selected: []string{"report"}, job: job("email"), duration: 3 * time.Second},
+			want: struct {
+				selected bool
+				slo      time.Duration
+			}{selected: true, slo: 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewPerformancePolicy(map[string]time.Duration{"email": 2 * time.Second, "backup": 0}, 10*time.Second, 5*time.Minute)
+			assert.NoError(t, err)
+			for _, jobType := range tt.in.selected {
+				_, ok := policy.Select(job(jobType), time.Hour, now.Add(-time.Minute))
+				assert.True(t, ok)
+			}
+
+			run, ok := policy.Select(tt.in.job, tt.in.duration, now)
+
+			assert.Equal(t, tt.want.selected, ok)
+			if !tt.want.selected {
+				assert.Nil(t, run)
+				return
+			}
+			assert.Equal(t, tt.in.job.ID, run.JobID)
+			assert.Equal(t, tt.in.duration, run.Duration)
+			assert.Equal(t, tt.want.slo, run.SLO)
+		})
+	}
+}
+
+func TestSlowRun_AnalysisRequest(t *testing.T) {
+	history := queue.NewDurationHistogram("default", "email")
+	for _, seconds := range []float64{0.5, 0.8, 1.5, 4} {
+		history.Observe(seconds)
+	}
+
+	tests := []struct {
+		name string
+		in   struct {
+			history *queue.DurationHistogram
+		}
+		want struct {
+			contains []string
+			excludes []string
+		}
+	}{
+		{
+			name: "Given the type's timing history, When building the request, Then should describe the run and the history",
+			in:   struct{ history *queue.DurationHistogram }{history: history},
+			want: struct {
+				contains []string
+				excludes []string
+			}{contains: []string{"took 3.2s, above its SLO of 2s", "Timing of the 4 recorded runs of email jobs in queue default: mean 1.7s"}},
+		},
+		{
+			name: "Given no timing history, When building the request, Then should only describe the run",
+			in:   struct{ history *queue.DurationHistogram }{history: nil},
+			want: struct {
+				contains []string
+				excludes []string
+			}{contains: []string{"took 3.2s, above its SLO of 2s"}, excludes: []string{"Timing of"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &queue.Job{ID: uuid.New(), Queue: "default", Type: "email", Payload: []byte(`{"to":"a@example.com"}`)}
+			run := &SlowRun{JobID: job.ID, Duration: 3200 * time.Millisecond, SLO: 2 * time.Second}
+
+			request := run.AnalysisRequest(job, tt.in.history)
+
+			assert.Equal(t, job.ID.String(), request.JobID)
+			assert.Equal(t, string(job.Payload), request.Payload)
+			for _, text := range tt.want.contains {
+				assert.Contains(t, request.Error, text)
+			}
+			for _, text := range tt.want.excludes {
+				assert.NotContains(t, request.Error, text)
+			}
+		})
+	}
+}
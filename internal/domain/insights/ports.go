@@ -2,17 +2,81 @@ package insights
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrAnalysisQueueFull is returned when the analysis backlog is at capacity
+var ErrAnalysisQueueFull = errors.New("analysis queue is full")
+
+// ErrAIServiceUnavailable is returned by AIService implementations when the service
+// can't be reached right now; the analysis should be attempted again later
+var ErrAIServiceUnavailable = errors.New("AI service unavailable")
+
 // InsightRepository defines the interface for insight persistence
 type InsightRepository interface {
 	Create(ctx context.Context, insight *Insight) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Insight, error)
 	GetByJobID(ctx context.Context, jobID uuid.UUID) (*Insight, error)
-	List(ctx context.Context, limit, offset int) ([]*Insight, error)
+	// List returns insights newest first; an empty kind lists every kind
+	List(ctx context.Context, kind Kind, limit, offset int) ([]*Insight, error)
+	// Update stores an operator's edit of the insight; it fails with ErrInsightNotFound when the insight is gone
+	Update(ctx context.Context, insight *Insight) error
 	Delete(ctx context.Context, id uuid.UUID) error
+
+	// ListDLQWithInsights returns dead letter jobs joined with their latest insight, in the
+	// given DLQOrder; jobs not analyzed yet come after every triaged one when by actionability
+	ListDLQWithInsights(ctx context.Context, limit, offset int, order string) ([]*JobWithInsight, error)
+
+	// RecordFixApplication stores the audit entry for a suggested fix applied to a job
+	RecordFixApplication(ctx context.Context, application *FixApplication) error
+	// FixApplications returns the audit entries of the insight's applied fixes, oldest first
+	FixApplications(ctx context.Context, insightID uuid.UUID) ([]*FixApplication, error)
+	FixOutcomeRecorder
+
+	// FixEffectiveness aggregates fix outcomes per job type and fix kind for fixes applied
+	// since the given time; an empty job type includes every type
+	FixEffectiveness(ctx context.Context, jobType string, since time.Time) ([]*FixEffectiveness, error)
+
+	// UsageSummary aggregates recorded AI usage per day, provider and model since the given time
+	UsageSummary(ctx context.Context, since time.Time) ([]*UsageAggregate, error)
+
+	// ListByQueue returns the latest failure insight of up to limit jobs of the queue, analyzed
+	// since the given time, newest first; an empty queue includes every queue
+	ListByQueue(ctx context.Context, queue string, since time.Time, limit int) ([]*QueueInsight, error)
+
+	// FindByFingerprint returns the latest failure insight created since the given time for a
+	// job of the type whose error had the fingerprint; ErrInsightNotFound when there is none
+	FindByFingerprint(ctx context.Context, jobType, fingerprint string, since time.Time) (*Insight, error)
+	// LinkJob records that the job failed the way the insight explains, so GetByJobID finds
+	// the insight for it; linking a job twice is a no-op
+	LinkJob(ctx context.Context, insightID, jobID uuid.UUID) error
+	// LinkedJobs returns the jobs linked to the insight, oldest link first
+	LinkedJobs(ctx context.Context, insightID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// FixOutcomeRecorder records how a job ran after a fix was applied to it
+type FixOutcomeRecorder interface {
+	// RecordFixOutcome resolves the job's pending fix applications; it's a no-op for jobs without one
+	RecordFixOutcome(ctx context.Context, jobID uuid.UUID, outcome FixOutcome, at time.Time) error
+}
+
+// AnalysisRepository stores analyses requested asynchronously
+type AnalysisRepository interface {
+	Create(ctx context.Context, analysis *Analysis) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Analysis, error)
+	Update(ctx context.Context, analysis *Analysis) error
+	// ListPending returns up to limit unfinished analyses last updated before updatedBefore,
+	// oldest first
+	ListPending(ctx context.Context, updatedBefore time.Time, limit int) ([]*Analysis, error)
+}
+
+// AnalysisNotifier delivers the outcome of an asynchronous analysis to its callback URL.
+// The insight is nil when the analysis failed.
+type AnalysisNotifier interface {
+	NotifyAnalysis(ctx context.Context, analysis *Analysis, insight *Insight)
 }
 
 // AIService defines the interface for AI analysis
@@ -20,3 +84,40 @@ type InsightRepository interface {
 type AIService interface {
 	Analyze(ctx context.Context, request *AnalysisRequest) (*AnalysisResponse, error)
 }
+
+// Embedder turns texts into embedding vectors, close by cosine similarity when the texts mean
+// about the same thing
+type Embedder interface {
+	// Embed returns one vector per text, in order, all from the same model
+	Embed(ctx context.Context, texts []string) (*Embeddings, error)
+}
+
+// ProviderProber checks that the AI provider can take analyses, e.g. that it is reachable and
+// serves the configured model, without running one
+type ProviderProber interface {
+	Probe(ctx context.Context) error
+}
+
+// AnalysisQueue buffers failed jobs awaiting AI analysis so insight generation
+// runs with bounded concurrency and survives process restarts
+type AnalysisQueue interface {
+	Enqueue(ctx context.Context, jobID uuid.UUID) error
+	// Dequeue waits up to timeout for a job ID; it returns nil when none is available
+	Dequeue(ctx context.Context, timeout time.Duration) (*uuid.UUID, error)
+	// Complete removes a dequeued job ID from the in-flight set
+	Complete(ctx context.Context, jobID uuid.UUID) error
+	// Recover moves job IDs left in flight longer than their claim lasts, by a consumer that
+	// stopped or crashed, back to the queue; job IDs other consumers are running stay with them
+	Recover(ctx context.Context) (int, error)
+}
+
+// SlowRunQueue buffers slow runs awaiting a performance analysis, like AnalysisQueue does failed jobs
+type SlowRunQueue interface {
+	Enqueue(ctx context.Context, run *SlowRun) error
+	// Dequeue waits up to timeout for a slow run; it returns nil when none is available
+	Dequeue(ctx context.Context, timeout time.Duration) (*SlowRun, error)
+	// Complete removes a dequeued slow run from the in-flight set
+	Complete(ctx context.Context, run *SlowRun) error
+	// Recover moves slow runs left in flight longer than their claim lasts back to the queue
+	Recover(ctx context.Context) (int, error)
+}
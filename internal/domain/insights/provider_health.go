@@ -0,0 +1,173 @@
+package insights
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ProviderState is whether the AI provider answers its health probes
+type ProviderState string
+
+const (
+	ProviderHealthy  ProviderState = "healthy"  // The last probe succeeded, or fewer than FailAfter failed in a row
+	ProviderDegraded ProviderState = "degraded" // FailAfter probes failed in a row; synchronous analyses are skipped
+)
+
+var ErrInvalidProviderHealthConfig = errors.New("provider health interval, timeout and failure threshold must be positive, and the timeout must not exceed the interval")
+
+// ProviderHealthConfig controls how often the AI provider is probed and when insight generation
+// is reported degraded
+type ProviderHealthConfig struct {
+	Interval  time.Duration // Time between probes
+	Timeout   time.Duration // Bound on each probe
+	FailAfter int           // Probes failed in a row after which the provider is degraded
+}
+
+// DefaultProviderHealthConfig probes every 30 seconds with a 5 second timeout and is degraded
+// after 3 failed probes
+func DefaultProviderHealthConfig() ProviderHealthConfig {
+	return ProviderHealthConfig{
+		Interval:  30 * time.Second,
+		Timeout:   5 * time.Second,
+		FailAfter: 3,
+	}
+}
+
+// Validate checks the configuration values
+func (c ProviderHealthConfig) Validate() error {
+	if c.Interval <= 0 || c.Timeout <= 0 || c.FailAfter <= 0 || c.Timeout > c.Interval {
+		return ErrInvalidProviderHealthConfig
+	}
+	return nil
+}
+
+// ProviderHealthStatus is a snapshot of the AI provider's health and downtime
+type ProviderHealthStatus struct {
+	State               ProviderState
+	ConsecutiveFailures int
+	LastError           string
+	LastProbeAt         time.Time  // Zero before the first probe
+	FailingSince        *time.Time // First failed probe of the current outage
+	Outages             int        // Times the provider was degraded since the process started
+	Downtime            time.Duration
+}
+
+// ProviderFailure is what recording a failed probe tells the monitor
+type ProviderFailure struct {
+	Failures int       // Consecutive failures, this one included
+	Since    time.Time // First failure of the outage
+	Degraded bool      // This failure degraded insight generation, and should be alerted on
+}
+
+// ProviderRecovery is what recording a successful probe tells the monitor
+type ProviderRecovery struct {
+	Failures    int           // Consecutive failures the probe ended; zero when the provider was healthy
+	Downtime    time.Duration // Time since the first of those failures
+	WasDegraded bool          // Insight generation was degraded, and its recovery should be alerted on
+}
+
+// ProviderHealth tracks the outcome of the AI provider's health probes. The provider is
+// degraded after FailAfter failed probes in a row until one succeeds. Downtime is counted from
+// the first failed probe of each outage that degraded it. It is safe for concurrent use.
+type ProviderHealth struct {
+	cfg ProviderHealthConfig
+
+	mu        sync.Mutex
+	failures  int
+	lastError string
+	lastProbe time.Time
+	since     time.Time // First failure of the current outage
+	outages   int
+	downtime  time.Duration // Of the outages that ended
+}
+
+// NewProviderHealth creates a healthy tracker
+func NewProviderHealth(cfg ProviderHealthConfig) (*ProviderHealth, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &ProviderHealth{cfg: cfg}, nil
+}
+
+// Config returns the configuration the tracker was created with
+func (h *ProviderHealth) Config() ProviderHealthConfig {
+	return h.cfg
+}
+
+// Failure records a failed probe
+func (h *ProviderHealth) Failure(err error, now time.Time) ProviderFailure {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failures == 0 {
+		h.since = now
+	}
+	h.failures++
+	h.lastError = err.Error()
+	h.lastProbe = now
+	degraded := h.failures == h.cfg.FailAfter
+	if degraded {
+		h.outages++
+	}
+	return ProviderFailure{
+		Failures: h.failures,
+		Since:    h.since,
+		Degraded: degraded,
+	}
+}
+
+// Success records a successful probe, ending the outage if there was one
+func (h *ProviderHealth) Success(now time.Time) ProviderRecovery {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recovery := ProviderRecovery{Failures: h.failures, WasDegraded: h.failures >= h.cfg.FailAfter}
+	if h.failures > 0 {
+		recovery.Downtime = now.Sub(h.since)
+	}
+	if recovery.WasDegraded {
+		h.downtime += recovery.Downtime
+	}
+	h.failures = 0
+	h.lastError = ""
+	h.lastProbe = now
+	h.since = time.Time{}
+	return recovery
+}
+
+// RetryAfter returns how long until the next probe may find the provider healthy again, at
+// least a second, and false when the provider isn't degraded
+func (h *ProviderHealth) RetryAfter(now time.Time) (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failures < h.cfg.FailAfter {
+		return 0, false
+	}
+	return max(h.lastProbe.Add(h.cfg.Interval).Sub(now), time.Second), true
+}
+
+// Status returns the current health, the downtime of the ongoing outage included
+func (h *ProviderHealth) Status(now time.Time) ProviderHealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := ProviderHealthStatus{
+		State:               ProviderHealthy,
+		ConsecutiveFailures: h.failures,
+		LastError:           h.lastError,
+		LastProbeAt:         h.lastProbe,
+		Outages:             h.outages,
+		Downtime:            h.downtime,
+	}
+	if h.failures >= h.cfg.FailAfter {
+		status.State = ProviderDegraded
+		status.Downtime += now.Sub(h.since)
+	}
+	if h.failures > 0 {
+		since := h.since
+		status.FailingSince = &since
+	}
+	return status
+}
@@ -0,0 +1,177 @@
+package insights
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderHealth(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	errDown := errors.New("dial tcp 127.0.0.1:11434: connection refused")
+
+	tests := []struct {
+		name string
+		in   struct {
+			failures []time.Duration // Offsets from start of the failed probes, in order
+		}
+		want struct {
+			last       ProviderFailure
+			state      ProviderState
+			retryAfter time.Duration // Zero when synchronous analyses are allowed
+			downtime   time.Duration
+		}
+	}{
+		{
+			name: "Given a first failed probe, When checking the provider, Then should stay healthy",
+			in:   struct{ failures []time.Duration }{failures: []time.Duration{0}},
+			want: struct {
+				last       ProviderFailure
+				state      ProviderState
+				retryAfter time.Duration
+				downtime   time.Duration
+			}{last: ProviderFailure{Failures: 1, Since: start}, state: ProviderHealthy},
+		},
+		{
+			name: "Given as many failed probes as the threshold, When recording the last, Then should degrade once and hint at the next probe",
+			in:   struct{ failures []time.Duration }{failures: []time.Duration{0, 30 * time.Second, time.Minute}},
+			want: struct {
+				last       ProviderFailure
+				state      ProviderState
+				retryAfter time.Duration
+				downtime   time.Duration
+			}{last: ProviderFailure{Failures: 3, Since: start, Degraded: true}, state: ProviderDegraded, retryAfter: 30 * time.Second, downtime: time.Minute},
+		},
+		{
+			name: "Given more failed probes than the threshold, When recording them, Then should not degrade again",
+			in:   struct{ failures []time.Duration }{failures: []time.Duration{0, 30 * time.Second, time.Minute, 90 * time.Second}},
+			want: struct {
+				last       ProviderFailure
+				state      ProviderState
+				retryAfter time.Duration
+				downtime   time.Duration
+			}{last: ProviderFailure{Failures: 4, Since: start}, state: ProviderDegraded, retryAfter: 30 * time.Second, downtime: 90 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health, err := NewProviderHealth(DefaultProviderHealthConfig())
+			require.NoError(t, err)
+
+			var last ProviderFailure
+			var now time.Time
+			for _, offset := range tt.in.failures {
+				now = start.Add(offset)
+				last = health.Failure(errDown, now)
+			}
+
+			assert.Equal(t, tt.want.last, last)
+			retryAfter, degraded := health.RetryAfter(now)
+			assert.Equal(t, tt.want.retryAfter, retryAfter)
+			assert.Equal(t, tt.want.retryAfter > 0, degraded)
+			status := health.Status(now)
+			assert.Equal(t, tt.want.state, status.State)
+			assert.Equal(t, tt.want.downtime, status.Downtime)
+			assert.Equal(t, errDown.Error(), status.LastError)
+		})
+	}
+}
+
+func TestProviderHealth_Success(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			failures int
+		}
+		want struct {
+			recovery ProviderRecovery
+			outages  int
+			downtime time.Duration
+		}
+	}{
+		{
+			name: "Given a healthy provider, When a probe succeeds, Then should report no outage",
+			want: struct {
+				recovery ProviderRecovery
+				outages  int
+				downtime time.Duration
+			}{},
+		},
+		{
+			name: "Given failures below the threshold, When a probe succeeds, Then should end the outage without counting downtime",
+			in:   struct{ failures int }{failures: 2},
+			want: struct {
+				recovery ProviderRecovery
+				outages  int
+				downtime time.Duration
+			}{recovery: ProviderRecovery{Failures: 2, Downtime: time.Minute}},
+		},
+		{
+			name: "Given a degraded provider, When a probe succeeds, Then should report the recovery and keep its downtime",
+			in:   struct{ failures int }{failures: 3},
+			want: struct {
+				recovery ProviderRecovery
+				outages  int
+				downtime time.Duration
+			}{recovery: ProviderRecovery{Failures: 3, Downtime: time.Minute, WasDegraded: true}, outages: 1, downtime: time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health, err := NewProviderHealth(DefaultProviderHealthConfig())
+			require.NoError(t, err)
+			for range tt.in.failures {
+				health.Failure(errors.New("connection refused"), start)
+			}
+
+			now := start.Add(time.Minute)
+			recovery := health.Success(now)
+
+			assert.Equal(t, tt.want.recovery, recovery)
+			_, degraded := health.RetryAfter(now)
+			assert.False(t, degraded)
+			assert.Equal(t, ProviderHealthStatus{
+				State:       ProviderHealthy,
+				LastProbeAt: now,
+				Outages:     tt.want.outages,
+				Downtime:    tt.want.downtime,
+			}, health.Status(now.Add(time.Hour)))
+		})
+	}
+}
+
+func TestProviderHealthConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   ProviderHealthConfig
+		want error
+	}{
+		{
+			name: "Given the defaults, When validating, Then should accept them",
+			in:   DefaultProviderHealthConfig(),
+		},
+		{
+			name: "Given a timeout above the interval, When validating, Then should reject it",
+			in:   ProviderHealthConfig{Interval: time.Second, Timeout: time.Minute, FailAfter: 3},
+			want: ErrInvalidProviderHealthConfig,
+		},
+		{
+			name: "Given no failure threshold, When validating, Then should reject it",
+			in:   ProviderHealthConfig{Interval: time.Minute, Timeout: time.Second},
+			want: ErrInvalidProviderHealthConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.in.Validate())
+		})
+	}
+}
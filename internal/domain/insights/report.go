@@ -0,0 +1,403 @@
+package insights
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// MaxReportAttempts bounds the runs of the job listed in an incident report
+const MaxReportAttempts = 50
+
+// IncidentReport gathers what is known about an analyzed failure for a postmortem: the job,
+// its runs, the AI's diagnosis and recommendation, and the fixes applied from it
+type IncidentReport struct {
+	Insight     *Insight
+	Job         *queue.Job        // Nil when the job no longer exists
+	Attempts    []*queue.Attempt  // Oldest first
+	Fixes       []*FixApplication // Oldest first
+	LinkedJobs  []uuid.UUID       // Other jobs that failed the same way, oldest link first
+	GeneratedAt time.Time
+}
+
+// Title names the job the report is about
+func (r *IncidentReport) Title() string {
+	if r.Job == nil {
+		return fmt.Sprintf("Incident report: job %s", r.Insight.JobID)
+	}
+	return fmt.Sprintf("Incident report: %s job %s", r.Job.Type, r.Job.ID)
+}
+
+// Markdown renders the report as a markdown document. Job data is quoted in code spans and
+// blocks, so payloads and errors can't change the document's structure.
+func (r *IncidentReport) Markdown() string {
+	var b strings.Builder
+	insight := r.Insight
+
+	fmt.Fprintf(&b, "# %s\n\n", markdownText(r.Title()))
+	fmt.Fprintf(&b, "Generated %s from insight %s.\n\n", formatReportTime(r.GeneratedAt), codeSpan(insight.ID.String()))
+
+	b.WriteString("## Job\n\n")
+	if r.Job == nil {
+		fmt.Fprintf(&b, "Job %s no longer exists.\n\n", codeSpan(insight.JobID.String()))
+	} else {
+		for _, field := range r.jobFields() {
+			fmt.Fprintf(&b, "- **%s:** %s\n", field.Name, codeSpan(field.Value))
+		}
+		b.WriteString("\n")
+		if r.Job.Error != "" {
+			fmt.Fprintf(&b, "### Error\n\n%s\n", codeBlock("", r.Job.Error))
+		}
+		fmt.Fprintf(&b, "### Payload\n\n%s\n", codeBlock("json", indentJSON(r.Job.Payload)))
+	}
+
+	b.WriteString("## Attempts\n\n")
+	if len(r.Attempts) == 0 {
+		b.WriteString("No attempts recorded.\n\n")
+	} else {
+		b.WriteString("| # | Started | Duration | Worker | Outcome |\n|---|---|---|---|---|\n")
+		for _, attempt := range r.Attempts {
+			fmt.Fprintf(&b, "| %d | %s | %s | %s | %s |\n", attempt.Number, formatReportTime(attempt.StartedAt),
+				attempt.Duration.Round(time.Millisecond), tableCell(attempt.WorkerID), tableCell(attemptOutcome(attempt)))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Diagnosis\n\n")
+	fmt.Fprintf(&b, "%s\n\n", markdownText(insight.Diagnosis))
+	fmt.Fprintf(&b, "- **Kind:** %s\n", insight.Kind)
+	fmt.Fprintf(&b, "- **Confidence:** %.0f%%\n", insight.Confidence*100)
+	if insight.Triage != "" {
+		fmt.Fprintf(&b, "- **Triage:** %s\n", insight.Triage)
+	}
+	if insight.Provider != "" {
+		fmt.Fprintf(&b, "- **Provider:** %s\n", markdownText(insight.Provider))
+	}
+	fmt.Fprintf(&b, "- **Analyzed:** %s\n", formatReportTime(insight.CreatedAt))
+	if insight.SharedFrom != nil {
+		fmt.Fprintf(&b, "- **Shared from:** insight %s, analyzed for another job failing the same way\n", codeSpan(insight.SharedFrom.String()))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Recommendation\n\n")
+	fmt.Fprintf(&b, "%s\n\n", markdownText(insight.Recommendation))
+	if insight.AIRecommendation != "" {
+		fmt.Fprintf(&b, "Edited by %s; the AI recommended:\n\n> %s\n\n", markdownText(reportEditor(insight)), markdownText(insight.AIRecommendation))
+	}
+	if insight.Note != "" {
+		fmt.Fprintf(&b, "**Operator note:** %s\n\n", markdownText(insight.Note))
+	}
+
+	b.WriteString("### Suggested fix\n\n")
+	fmt.Fprintf(&b, "- **Kind:** %s\n", insight.SuggestedFix.Kind())
+	if insight.SuggestedFix.TimeoutSeconds > 0 {
+		fmt.Fprintf(&b, "- **Timeout:** %ds\n", insight.SuggestedFix.TimeoutSeconds)
+	}
+	if insight.SuggestedFix.MaxRetries > 0 {
+		fmt.Fprintf(&b, "- **Max retries:** %d\n", insight.SuggestedFix.MaxRetries)
+	}
+	if patch := r.payloadPatch(); patch != "" {
+		fmt.Fprintf(&b, "- **Payload patch:**\n\n%s", codeBlock("json", patch))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Applied fixes\n\n")
+	if len(r.Fixes) == 0 {
+		b.WriteString("No fixes applied.\n\n")
+	} else {
+		b.WriteString("| Applied | Job | Fix | Status | Outcome | Resolved |\n|---|---|---|---|---|---|\n")
+		for _, fix := range r.Fixes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s → %s | %s | %s |\n", formatReportTime(fix.AppliedAt), codeSpan(fix.JobID.String()),
+				tableCell(fix.FixKind), fix.StatusBefore, fix.StatusAfter, fix.Outcome, formatReportTimePtr(fix.ResolvedAt))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(r.LinkedJobs) > 0 {
+		b.WriteString("## Jobs failing the same way\n\n")
+		for _, jobID := range r.LinkedJobs {
+			fmt.Fprintf(&b, "- %s\n", codeSpan(jobID.String()))
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// HTML renders the report as a standalone, printable page, so it can be saved as a PDF from
+// a browser
+func (r *IncidentReport) HTML() ([]byte, error) {
+	var b bytes.Buffer
+	err := reportTemplate.Execute(&b, map[string]any{
+		"Report":       r,
+		"JobFields":    r.jobFields(),
+		"Payload":      r.jobPayload(),
+		"PayloadPatch": r.payloadPatch(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// reportField is a labelled value of the job listed in a report
+type reportField struct {
+	Name  string
+	Value string
+}
+
+func (r *IncidentReport) jobFields() []reportField {
+	job := r.Job
+	if job == nil {
+		return nil
+	}
+	fields := []reportField{
+		{"ID", job.ID.String()},
+		{"Queue", job.Queue},
+		{"Type", job.Type},
+		{"Status", string(job.Status)},
+		{"Attempts", fmt.Sprint(job.Attempts)},
+		{"Created", formatReportTime(job.CreatedAt)},
+		{"Updated", formatReportTime(job.UpdatedAt)},
+	}
+	if job.DeadLetterReason != "" {
+		fields = append(fields, reportField{"Dead-letter reason", job.DeadLetterReason})
+	}
+	return fields
+}
+
+func (r *IncidentReport) jobPayload() string {
+	if r.Job == nil {
+		return ""
+	}
+	return indentJSON(r.Job.Payload)
+}
+
+func (r *IncidentReport) payloadPatch() string {
+	if len(r.Insight.SuggestedFix.PayloadPatch) == 0 {
+		return ""
+	}
+	patch, err := json.MarshalIndent(r.Insight.SuggestedFix.PayloadPatch, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(patch)
+}
+
+// attemptOutcome describes how a run ended: "succeeded", or its failure category and error
+func attemptOutcome(attempt *queue.Attempt) string {
+	if attempt.Error == "" {
+		return "succeeded"
+	}
+	if attempt.Category == "" {
+		return attempt.Error
+	}
+	return attempt.Category + ": " + attempt.Error
+}
+
+// reportEditor names who replaced the AI's recommendation
+func reportEditor(insight *Insight) string {
+	if insight.EditedBy == "" {
+		return "an operator"
+	}
+	return insight.EditedBy
+}
+
+// indentJSON pretty-prints a JSON document, returning anything else unchanged
+func indentJSON(data []byte) string {
+	var b bytes.Buffer
+	if err := json.Indent(&b, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return b.String()
+}
+
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+func formatReportTimePtr(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return formatReportTime(*t)
+}
+
+// markdownEscaper escapes the characters that start markdown markup or raw HTML
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", "&lt;", ">", "&gt;", "#", `\#`, "|", `\|`,
+)
+
+// markdownText escapes free text written into the report and keeps it on one paragraph
+func markdownText(text string) string {
+	return markdownEscaper.Replace(strings.Join(strings.Fields(text), " "))
+}
+
+// tableCell escapes text written into a table cell, which has to stay on one line
+func tableCell(text string) string {
+	if text == "" {
+		return "-"
+	}
+	return markdownText(text)
+}
+
+// codeSpan quotes text in a code span, with a fence longer than any backticks it contains
+func codeSpan(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	fence := strings.Repeat("`", longestRun(text, '`')+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// codeBlock quotes text in a fenced code block, with a fence longer than any backticks it contains
+func codeBlock(lang, text string) string {
+	fence := strings.Repeat("`", max(3, longestRun(text, '`')+1))
+	return fence + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + fence + "\n"
+}
+
+func longestRun(text string, c rune) int {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r != c {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time":    formatReportTime,
+	"timePtr": formatReportTimePtr,
+	"outcome": attemptOutcome,
+	"editor":  reportEditor,
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"round":   func(d time.Duration) time.Duration { return d.Round(time.Millisecond) },
+}).Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Report.Title}}</title>
+<style>
+body { font: 14px/1.5 system-ui, sans-serif; color: #1f2933; max-width: 960px; margin: 32px auto; padding: 0 24px; }
+h1 { font-size: 22px; } h2 { font-size: 18px; border-bottom: 1px solid #e4e7eb; padding-bottom: 4px; margin-top: 28px; } h3 { font-size: 15px; }
+table { width: 100%; border-collapse: collapse; } th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #e4e7eb; vertical-align: top; }
+th { background: #f5f7fa; } code, pre { font-family: monospace; font-size: 12px; } pre { background: #f5f7fa; padding: 8px; white-space: pre-wrap; word-break: break-word; }
+.muted { color: #616e7c; } @media print { body { margin: 0; } pre { break-inside: avoid; } }
+</style>
+</head>
+<body>
+{{- with .Report}}
+<h1>{{.Title}}</h1>
+<p class="muted">Generated {{time .GeneratedAt}} from insight <code>{{.Insight.ID}}</code>.</p>
+
+<h2>Job</h2>
+{{- if .Job}}
+<table>
+{{- range $.JobFields}}
+<tr><th>{{.Name}}</th><td><code>{{.Value}}</code></td></tr>
+{{- end}}
+</table>
+{{- if .Job.Error}}
+<h3>Error</h3>
+<pre>{{.Job.Error}}</pre>
+{{- end}}
+<h3>Payload</h3>
+<pre>{{$.Payload}}</pre>
+{{- else}}
+<p>Job <code>{{.Insight.JobID}}</code> no longer exists.</p>
+{{- end}}
+
+<h2>Attempts</h2>
+{{- if .Attempts}}
+<table>
+<tr><th>#</th><th>Started</th><th>Duration</th><th>Worker</th><th>Outcome</th></tr>
+{{- range .Attempts}}
+<tr><td>{{.Number}}</td><td>{{time .StartedAt}}</td><td>{{round .Duration}}</td><td>{{.WorkerID}}</td><td>{{outcome .}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No attempts recorded.</p>
+{{- end}}
+
+{{- with .Insight}}
+<h2>Diagnosis</h2>
+<p>{{.Diagnosis}}</p>
+<ul>
+<li><strong>Kind:</strong> {{.Kind}}</li>
+<li><strong>Confidence:</strong> {{percent .Confidence}}</li>
+{{- if .Triage}}
+<li><strong>Triage:</strong> {{.Triage}}</li>
+{{- end}}
+{{- if .Provider}}
+<li><strong>Provider:</strong> {{.Provider}}</li>
+{{- end}}
+<li><strong>Analyzed:</strong> {{time .CreatedAt}}</li>
+{{- if .SharedFrom}}
+<li><strong>Shared from:</strong> insight <code>{{.SharedFrom}}</code>, analyzed for another job failing the same way</li>
+{{- end}}
+</ul>
+
+<h2>Recommendation</h2>
+<p>{{.Recommendation}}</p>
+{{- if .AIRecommendation}}
+<p>Edited by {{editor .}}; the AI recommended:</p>
+<blockquote>{{.AIRecommendation}}</blockquote>
+{{- end}}
+{{- if .Note}}
+<p><strong>Operator note:</strong> {{.Note}}</p>
+{{- end}}
+
+<h3>Suggested fix</h3>
+<ul>
+<li><strong>Kind:</strong> {{.SuggestedFix.Kind}}</li>
+{{- if gt .SuggestedFix.TimeoutSeconds 0}}
+<li><strong>Timeout:</strong> {{.SuggestedFix.TimeoutSeconds}}s</li>
+{{- end}}
+{{- if gt .SuggestedFix.MaxRetries 0}}
+<li><strong>Max retries:</strong> {{.SuggestedFix.MaxRetries}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if $.PayloadPatch}}
+<pre>{{$.PayloadPatch}}</pre>
+{{- end}}
+
+<h2>Applied fixes</h2>
+{{- if .Fixes}}
+<table>
+<tr><th>Applied</th><th>Job</th><th>Fix</th><th>Status</th><th>Outcome</th><th>Resolved</th></tr>
+{{- range .Fixes}}
+<tr><td>{{time .AppliedAt}}</td><td><code>{{.JobID}}</code></td><td>{{.FixKind}}</td><td>{{.StatusBefore}} → {{.StatusAfter}}</td><td>{{.Outcome}}</td><td>{{timePtr .ResolvedAt}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No fixes applied.</p>
+{{- end}}
+
+{{- if .LinkedJobs}}
+<h2>Jobs failing the same way</h2>
+<ul>
+{{- range .LinkedJobs}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
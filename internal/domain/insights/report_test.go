@@ -0,0 +1,89 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncidentReport_Markdown(t *testing.T) {
+	jobID := uuid.MustParse("3f0c2a9e-8a51-4d6e-9b3e-2f1d6c7a8b90")
+	insightID := uuid.MustParse("9d8e7f6a-5b4c-4d3e-8f2a-1b0c9d8e7f6a")
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	job := &queue.Job{ID: jobID, Queue: "default", Type: "http", Status: queue.StatusFailed, Payload: []byte(`{"a":1}`), CreatedAt: at, UpdatedAt: at}
+
+	tests := []struct {
+		name string
+		in   struct {
+			report *IncidentReport
+		}
+		want struct {
+			contains []string
+		}
+	}{
+		{
+			name: "Given job data with markdown markup, When rendering, Then should quote it so it can't change the document",
+			in: struct{ report *IncidentReport }{report: &IncidentReport{
+				Insight: &Insight{ID: insightID, JobID: jobID, Kind: KindFailure, Diagnosis: "# Not a heading\n| not | a table |", Recommendation: "Use `retry` *now*"},
+				Job: &queue.Job{ID: jobID, Queue: "default", Type: "http", Status: queue.StatusFailed,
+					Payload: []byte(`{"a":1}`), Error: "failed:\n```\nbroken fence", CreatedAt: at, UpdatedAt: at},
+				Attempts:    []*queue.Attempt{{JobID: jobID, Number: 1, WorkerID: "w1", StartedAt: at, Duration: 1500 * time.Millisecond, Error: "a | b", Category: queue.FailureTimeout}},
+				GeneratedAt: at,
+			}},
+			want: struct{ contains []string }{contains: []string{
+				"# Incident report: http job " + jobID.String() + "\n",
+				"\\# Not a heading \\| not \\| a table \\|\n",
+				"Use \\`retry\\` \\*now\\*\n",
+				"````\nfailed:\n```\nbroken fence\n````\n",
+				"```json\n{\n  \"a\": 1\n}\n```\n",
+				"| 1 | 2026-10-01 12:00:00 UTC | 1.5s | w1 | timeout: a \\| b |\n",
+				"No fixes applied.",
+			}},
+		},
+		{
+			name: "Given a deleted job, an edited recommendation and a linked job, When rendering, Then should say so",
+			in: struct{ report *IncidentReport }{report: &IncidentReport{
+				Insight: &Insight{ID: insightID, JobID: jobID, Kind: KindFailure, Diagnosis: "Timeout", Recommendation: "Raise it",
+					AIRecommendation: "Retry", EditedBy: "alice", Note: "Seen during the outage"},
+				LinkedJobs:  []uuid.UUID{insightID},
+				GeneratedAt: at,
+			}},
+			want: struct{ contains []string }{contains: []string{
+				"# Incident report: job " + jobID.String() + "\n",
+				"Job `" + jobID.String() + "` no longer exists.",
+				"No attempts recorded.",
+				"Edited by alice; the AI recommended:\n\n> Retry\n",
+				"**Operator note:** Seen during the outage\n",
+				"## Jobs failing the same way\n\n- `" + insightID.String() + "`\n",
+			}},
+		},
+		{
+			name: "Given an applied fix, When rendering, Then should list it with its outcome",
+			in: struct{ report *IncidentReport }{report: &IncidentReport{
+				Insight: &Insight{ID: insightID, JobID: jobID, Kind: KindFailure, Diagnosis: "Timeout", Recommendation: "Raise it",
+					SuggestedFix: SuggestedFix{TimeoutSeconds: 30}},
+				Job: job,
+				Fixes: []*FixApplication{{InsightID: insightID, JobID: jobID, FixKind: FixKindTimeout, StatusBefore: queue.StatusFailed,
+					StatusAfter: queue.StatusRetrying, Outcome: FixOutcomeSucceeded, AppliedAt: at, ResolvedAt: &at}},
+				GeneratedAt: at,
+			}},
+			want: struct{ contains []string }{contains: []string{
+				"- **Kind:** timeout\n- **Timeout:** 30s\n",
+				"| 2026-10-01 12:00:00 UTC | `" + jobID.String() + "` | timeout | failed → retrying | succeeded | 2026-10-01 12:00:00 UTC |\n",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := tt.in.report.Markdown()
+
+			for _, want := range tt.want.contains {
+				assert.Contains(t, markdown, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package insights
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+var ErrInvalidStormConfig = errors.New("storm failure threshold and window must be positive")
+
+// StormConfig controls when the failures of a job type count as a storm
+type StormConfig struct {
+	FailureThreshold int           // Failures of a type within the window that start a storm
+	Window           time.Duration // Failures older than this are ignored
+}
+
+// DefaultStormConfig starts a storm at 50 failures of a type within a minute
+func DefaultStormConfig() StormConfig {
+	return StormConfig{FailureThreshold: 50, Window: time.Minute}
+}
+
+// Validate checks the configuration values
+func (c StormConfig) Validate() error {
+	if c.FailureThreshold <= 0 || c.Window <= 0 {
+		return ErrInvalidStormConfig
+	}
+	return nil
+}
+
+// StormDetector spots failure storms: spikes in the failures of a job type, typically a
+// downstream outage failing every job the same way. During a storm analyzing every failure
+// would cost an AI call each to learn the same thing over and over.
+type StormDetector struct {
+	mu       sync.Mutex
+	cfg      StormConfig
+	now      func() time.Time
+	failures map[string][]time.Time // Failure times per job type, oldest first
+	storming map[string]bool
+}
+
+// NewStormDetector creates a detector with the given configuration
+func NewStormDetector(cfg StormConfig) (*StormDetector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &StormDetector{
+		cfg:      cfg,
+		now:      time.Now,
+		failures: make(map[string][]time.Time),
+		storming: make(map[string]bool),
+	}, nil
+}
+
+// Config returns the detector's configuration
+func (d *StormDetector) Config() StormConfig {
+	return d.cfg
+}
+
+// Observe records a failure of the job type and reports whether the type is in a storm.
+// started is true on the failure that starts one; the storm lasts while the failures within
+// the window stay at the threshold.
+func (d *StormDetector) Observe(jobType string) (storming bool, started bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	failures := d.failures[jobType]
+	cutoff := now.Add(-d.cfg.Window)
+	kept := 0
+	for kept < len(failures) && !failures[kept].After(cutoff) {
+		kept++
+	}
+	failures = append(failures[kept:], now)
+	d.failures[jobType] = failures
+
+	storming = len(failures) >= d.cfg.FailureThreshold
+	started = storming && !d.storming[jobType]
+	d.storming[jobType] = storming
+	return storming, started
+}
+
+// ErrorSignature identifies the cause of a job type's failure: the type and the fingerprint of
+// the error message, so failures differing only in IDs, numbers or addresses share a signature
+func ErrorSignature(jobType, message string) string {
+	return jobType + ":" + queue.ErrorFingerprint(message)
+}
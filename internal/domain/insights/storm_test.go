@@ -0,0 +1,101 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStormDetector(t *testing.T, now *time.Time) *StormDetector {
+	t.Helper()
+	detector, err := NewStormDetector(StormConfig{FailureThreshold: 3, Window: time.Minute})
+	require.NoError(t, err)
+	detector.now = func() time.Time { return *now }
+	return detector
+}
+
+func TestStormDetector_Observe(t *testing.T) {
+	now := time.Now()
+	detector := newTestStormDetector(t, &now)
+
+	storming, started := detector.Observe("email")
+	assert.False(t, storming)
+	assert.False(t, started)
+	detector.Observe("email")
+
+	// Another type's failures don't count
+	storming, _ = detector.Observe("report")
+	assert.False(t, storming)
+
+	storming, started = detector.Observe("email")
+	assert.True(t, storming, "Given failures reaching the threshold, When observing, Then should start a storm")
+	assert.True(t, started)
+
+	storming, started = detector.Observe("email")
+	assert.True(t, storming)
+	assert.False(t, started, "Given a storm in progress, When observing, Then should not start it again")
+
+	now = now.Add(2 * time.Minute)
+	storming, started = detector.Observe("email")
+	assert.False(t, storming, "Given the failures aged out of the window, When observing, Then should end the storm")
+	assert.False(t, started)
+}
+
+func TestStormConfig_Validate(t *testing.T) {
+	assert.NoError(t, DefaultStormConfig().Validate())
+	assert.ErrorIs(t, StormConfig{FailureThreshold: 0, Window: time.Minute}.Validate(), ErrInvalidStormConfig)
+	assert.ErrorIs(t, StormConfig{FailureThreshold: 10}.Validate(), ErrInvalidStormConfig)
+}
+
+func TestErrorSignature(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			a, b [2]string // job type, error
+		}
+		want bool // same signature
+	}{
+		{
+			name: "Given errors differing in numbers and IDs, When signing, Then should share a signature",
+			in: struct{ a, b [2]string }{
+				a: [2]string{"email", "dial tcp 10.0.0.12:587: i/o timeout after 3000ms (job 3f0c2a1e-8d6b-4c1a-9a77-2b1f7c0e9d11)"},
+				b: [2]string{"email", "dial tcp 10.0.0.7:587: i/o timeout after 2999ms (job 9b2d4e6f-1a3c-4e5b-8c7d-0f1e2d3c4b5a)"},
+			},
+			want: true,
+		},
+		{
+			name: "Given errors differing in quoted values and spacing, When signing, Then should share a signature",
+			in: struct{ a, b [2]string }{
+				a: [2]string{"report", `missing field "to"`},
+				b: [2]string{"report", `Missing  field 'cc'`},
+			},
+			want: true,
+		},
+		{
+			name: "Given different errors, When signing, Then should not share a signature",
+			in: struct{ a, b [2]string }{
+				a: [2]string{"email", "connection refused"},
+				b: [2]string{"email", "401 Unauthorized"},
+			},
+			want: false,
+		},
+		{
+			name: "Given the same error of different job types, When signing, Then should not share a signature",
+			in: struct{ a, b [2]string }{
+				a: [2]string{"email", "connection refused"},
+				b: [2]string{"report", "connection refused"},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := ErrorSignature(tt.in.a[0], tt.in.a[1])
+			b := ErrorSignature(tt.in.b[0], tt.in.b[1])
+			assert.Equal(t, tt.want, a == b)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package insights
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Queue summary limits
+const (
+	DefaultSummaryDays  = 30
+	MaxSummaryInsights  = 500 // The newest insights of a queue a summary covers
+	MaxSummaryDiagnoses = 5
+	MaxSummaryFixes     = 5
+)
+
+// QueueInsight is the latest insight of a job in a queue, with the job's type
+type QueueInsight struct {
+	JobType string
+	Insight *Insight
+}
+
+// RecurringDiagnosis is a diagnosis the AI gave for several jobs of a queue
+type RecurringDiagnosis struct {
+	Diagnosis     string // The latest wording
+	Count         int
+	JobTypes      []string
+	AvgConfidence float64
+	LastSeen      time.Time
+}
+
+// CommonFix is a kind of fix the AI suggested for several jobs of a queue
+type CommonFix struct {
+	Kind     string // SuggestedFix.Kind
+	Count    int
+	JobTypes []string
+	Example  SuggestedFix // The latest suggestion of the kind
+}
+
+// ExecutiveSummary is the AI's overview of a queue's failures
+type ExecutiveSummary struct {
+	Summary        string
+	Recommendation string
+	Confidence     float64
+	Provider       string
+}
+
+// QueueSummary aggregates the insights of a queue, so systemic problems stand out from
+// one-off failures
+type QueueSummary struct {
+	Queue     string
+	Since     time.Time
+	Insights  int
+	Diagnoses []*RecurringDiagnosis // Most frequent first
+	Fixes     []*CommonFix          // Most frequent first
+
+	ExecutiveSummary *ExecutiveSummary // Nil when not requested or the AI failed
+	SummaryError     string            // Why the AI gave no executive summary
+}
+
+// volatileText matches the parts of a diagnosis that vary between occurrences of one problem:
+// UUIDs, hex IDs and numbers
+var volatileText = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|\b0x[0-9a-f]+\b|\d+(\.\d+)?`)
+
+// diagnosisKey groups diagnoses that differ only in case, punctuation, IDs or numbers
+func diagnosisKey(diagnosis string) string {
+	key := volatileText.ReplaceAllString(strings.ToLower(diagnosis), "#")
+	key = strings.Join(strings.Fields(key), " ")
+	return strings.TrimRight(key, ".!;: ")
+}
+
+// SummarizeQueue groups a queue's insights, newest first, into recurring diagnoses and
+// common fixes
+func SummarizeQueue(queueName string, since time.Time, entries []*QueueInsight) *QueueSummary {
+	summary := &QueueSummary{Queue: queueName, Since: since, Insights: len(entries)}
+
+	diagnoses := make(map[string]*RecurringDiagnosis)
+	confidence := make(map[string]float64)
+	fixes := make(map[string]*CommonFix)
+	for _, entry := range entries {
+		insight := entry.Insight
+
+		key := diagnosisKey(insight.Diagnosis)
+		diagnosis, ok := diagnoses[key]
+		if !ok {
+			diagnosis = &RecurringDiagnosis{Diagnosis: insight.Diagnosis, LastSeen: insight.CreatedAt}
+			diagnoses[key] = diagnosis
+		}
+		diagnosis.Count++
+		diagnosis.JobTypes = appendType(diagnosis.JobTypes, entry.JobType)
+		confidence[key] += insight.Confidence
+		if insight.CreatedAt.After(diagnosis.LastSeen) {
+			diagnosis.Diagnosis, diagnosis.LastSeen = insight.Diagnosis, insight.CreatedAt
+		}
+
+		kind := insight.SuggestedFix.Kind()
+		fix, ok := fixes[kind]
+		if !ok {
+			fix = &CommonFix{Kind: kind, Example: insight.SuggestedFix}
+			fixes[kind] = fix
+		}
+		fix.Count++
+		fix.JobTypes = appendType(fix.JobTypes, entry.JobType)
+	}
+
+	for key, diagnosis := range diagnoses {
+		diagnosis.AvgConfidence = confidence[key] / float64(diagnosis.Count)
+		summary.Diagnoses = append(summary.Diagnoses, diagnosis)
+	}
+	slices.SortFunc(summary.Diagnoses, func(a, b *RecurringDiagnosis) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	summary.Diagnoses = summary.Diagnoses[:min(len(summary.Diagnoses), MaxSummaryDiagnoses)]
+
+	for _, fix := range fixes {
+		summary.Fixes = append(summary.Fixes, fix)
+	}
+	slices.SortFunc(summary.Fixes, func(a, b *CommonFix) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Kind, b.Kind)
+	})
+	summary.Fixes = summary.Fixes[:min(len(summary.Fixes), MaxSummaryFixes)]
+
+	return summary
+}
+
+// appendType adds a job type to a sorted list unless it is already there
+func appendType(types []string, jobType string) []string {
+	i, found := slices.BinarySearch(types, jobType)
+	if found {
+		return types
+	}
+	return slices.Insert(types, i, jobType)
+}
+
+// AnalysisRequest asks the AI for an executive summary of the queue: the recurring diagnoses
+// stand for the error and the fix counts for the payload, so any AIService can answer it
+func (s *QueueSummary) AnalysisRequest() *AnalysisRequest {
+	var lines []string
+	for _, diagnosis := range s.Diagnoses {
+		lines = append(lines, fmt.Sprintf("%d jobs (%s): %s",
+			diagnosis.Count, strings.Join(diagnosis.JobTypes, ", "), diagnosis.Diagnosis))
+	}
+
+	fixCounts := make(map[string]int, len(s.Fixes))
+	for _, fix := range s.Fixes {
+		fixCounts[fix.Kind] = fix.Count
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"queue":           s.Queue,
+		"analyzed_jobs":   s.Insights,
+		"since":           s.Since.Format(time.RFC3339),
+		"suggested_fixes": fixCounts,
+	})
+
+	return &AnalysisRequest{
+		JobID: "summary of queue " + s.Queue,
+		Error: fmt.Sprintf("Recurring failures across %d analyzed jobs, most frequent first. "+
+			"Diagnose the systemic cause and recommend what to fix first instead of retrying jobs one by one.\n%s",
+			s.Insights, strings.Join(lines, "\n")),
+		Payload: string(payload),
+	}
+}
+
+// SetExecutiveSummary keeps the AI's answer to AnalysisRequest
+func (s *QueueSummary) SetExecutiveSummary(response *AnalysisResponse) {
+	s.ExecutiveSummary = &ExecutiveSummary{
+		Summary:        response.Diagnosis,
+		Recommendation: response.Recommendation,
+		Confidence:     clampConfidence(response.Confidence),
+		Provider:       response.Provider,
+	}
+	s.SummaryError = ""
+}
@@ -0,0 +1,86 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeQueue(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	insight := func(diagnosis string, fix SuggestedFix, confidence float64, age time.Duration) *Insight {
+		return &Insight{Diagnosis: diagnosis, SuggestedFix: fix, Confidence: confidence, CreatedAt: now.Add(-age)}
+	}
+
+	tests := []struct {
+		name string
+		in   struct {
+			entries []*QueueInsight
+		}
+		want struct {
+			diagnoses []*RecurringDiagnosis
+			fixes     []*CommonFix
+		}
+	}{
+		{
+			name: "Given diagnoses differing only in numbers and case, When summarizing, Then should count them as one with the latest wording",
+			in: struct{ entries []*QueueInsight }{entries: []*QueueInsight{
+				{JobType: "email", Insight: insight("SMTP timeout after 30s.", SuggestedFix{TimeoutSeconds: 60}, 0.8, time.Hour)},
+				{JobType: "digest", Insight: insight("smtp timeout after 45s", SuggestedFix{TimeoutSeconds: 90}, 0.6, 2*time.Hour)},
+				{JobType: "email", Insight: insight("Invalid recipient address", SuggestedFix{PayloadPatch: map[string]any{"to": "x"}}, 0.9, 3*time.Hour)},
+			}},
+			want: struct {
+				diagnoses []*RecurringDiagnosis
+				fixes     []*CommonFix
+			}{
+				diagnoses: []*RecurringDiagnosis{
+					{Diagnosis: "SMTP timeout after 30s.", Count: 2, JobTypes: []string{"digest", "email"}, AvgConfidence: 0.7, LastSeen: now.Add(-time.Hour)},
+					{Diagnosis: "Invalid recipient address", Count: 1, JobTypes: []string{"email"}, AvgConfidence: 0.9, LastSeen: now.Add(-3 * time.Hour)},
+				},
+				fixes: []*CommonFix{
+					{Kind: FixKindTimeout, Count: 2, JobTypes: []string{"digest", "email"}, Example: SuggestedFix{TimeoutSeconds: 60}},
+					{Kind: FixKindPayloadPatch, Count: 1, JobTypes: []string{"email"}, Example: SuggestedFix{PayloadPatch: map[string]any{"to": "x"}}},
+				},
+			},
+		},
+		{
+			name: "Given no insights, When summarizing, Then should return an empty summary",
+			in:   struct{ entries []*QueueInsight }{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := SummarizeQueue("emails", now.AddDate(0, 0, -30), tt.in.entries)
+
+			assert.Equal(t, "emails", summary.Queue)
+			assert.Equal(t, len(tt.in.entries), summary.Insights)
+			assert.Len(t, summary.Diagnoses, len(tt.want.diagnoses))
+			for i, want := range tt.want.diagnoses {
+				assert.Equal(t, want.Diagnosis, summary.Diagnoses[i].Diagnosis)
+				assert.Equal(t, want.Count, summary.Diagnoses[i].Count)
+				assert.Equal(t, want.JobTypes, summary.Diagnoses[i].JobTypes)
+				assert.InDelta(t, want.AvgConfidence, summary.Diagnoses[i].AvgConfidence, 1e-9)
+				assert.Equal(t, want.LastSeen, summary.Diagnoses[i].LastSeen)
+			}
+			assert.Equal(t, tt.want.fixes, summary.Fixes)
+		})
+	}
+}
+
+func TestQueueSummary_AnalysisRequest(t *testing.T) {
+	summary := &QueueSummary{
+		Queue:     "emails",
+		Since:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Insights:  3,
+		Diagnoses: []*RecurringDiagnosis{{Diagnosis: "SMTP timeout", Count: 2, JobTypes: []string{"digest", "email"}}},
+		Fixes:     []*CommonFix{{Kind: FixKindTimeout, Count: 2}},
+	}
+
+	request := summary.AnalysisRequest()
+
+	assert.Equal(t, "summary of queue emails", request.JobID)
+	assert.Contains(t, request.Error, "2 jobs (digest, email): SMTP timeout")
+	assert.JSONEq(t, `{"queue":"emails","analyzed_jobs":3,"since":"2025-01-01T00:00:00Z","suggested_fixes":{"timeout":2}}`, request.Payload)
+}
@@ -0,0 +1,72 @@
+package insights
+
+import (
+	"errors"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// TriageLabel tells what it takes to get a failed job running again, so dead letters can be
+// worked through the most actionable first
+type TriageLabel string
+
+const (
+	TriageAutoRetryable TriageLabel = "auto-retryable" // Retrying, with the suggested fix applied, is likely to work
+	TriageConfigIssue   TriageLabel = "config-issue"   // Credentials or settings outside the job need changing first
+	TriageNeedsHuman    TriageLabel = "needs-human"    // Nothing points to a fix; someone has to look into it
+)
+
+// Rank orders labels by actionability, lowest first; unlabeled failures rank last
+func (l TriageLabel) Rank() int {
+	switch l {
+	case TriageAutoRetryable:
+		return 0
+	case TriageConfigIssue:
+		return 1
+	case TriageNeedsHuman:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// MinAutoRetryConfidence is the confidence below which an insight's fix isn't trusted to
+// be retried unattended
+const MinAutoRetryConfidence = 0.6
+
+// Orders of the dead letter queue
+const (
+	DLQOrderRecent        = "recent"        // Most recently failed first
+	DLQOrderActionability = "actionability" // Auto-retryable first, then config issues, then the rest
+)
+
+var ErrInvalidDLQOrder = errors.New("order must be recent or actionability")
+
+// ValidateDLQOrder checks the order is a known one; an empty order is valid and means recent
+func ValidateDLQOrder(order string) error {
+	switch order {
+	case "", DLQOrderRecent, DLQOrderActionability:
+		return nil
+	default:
+		return ErrInvalidDLQOrder
+	}
+}
+
+// Triage labels a failed job from its insight. Auth failures need new credentials whatever the
+// AI suggests, and a timeout can only be raised where the job runs; a payload patch or more
+// retries are applied with the fix. Low-confidence insights are left to a human.
+func Triage(job *queue.Job, insight *Insight) TriageLabel {
+	category := queue.ClassifyFailureMessage(job.Error)
+	switch {
+	case category == queue.FailureAuth:
+		return TriageConfigIssue
+	case insight.Confidence < MinAutoRetryConfidence:
+		return TriageNeedsHuman
+	case insight.HasTimeoutRecommendation():
+		return TriageConfigIssue
+	case len(insight.SuggestedFix.PayloadPatch) > 0, insight.HasRetryRecommendation(), category == queue.FailureTimeout:
+		return TriageAutoRetryable
+	default:
+		return TriageNeedsHuman
+	}
+}
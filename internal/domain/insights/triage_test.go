@@ -0,0 +1,78 @@
+package insights
+
+import (
+	"testing"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriage(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			jobError string
+			insight  Insight
+		}
+		want TriageLabel
+	}{
+		{
+			name: "Given a confident insight patching the payload, When triaging, Then should label it auto-retryable",
+			in: struct {
+				jobError string
+				insight  Insight
+			}{jobError: "missing field: to", insight: Insight{Confidence: 0.9, SuggestedFix: SuggestedFix{PayloadPatch: map[string]any{"to": "a@example.com"}}}},
+			want: TriageAutoRetryable,
+		},
+		{
+			name: "Given a timeout without a suggested fix, When triaging, Then should label it auto-retryable",
+			in: struct {
+				jobError string
+				insight  Insight
+			}{jobError: "context deadline exceeded", insight: Insight{Confidence: 0.7}},
+			want: TriageAutoRetryable,
+		},
+		{
+			name: "Given an auth failure, When triaging, Then should label it a config issue whatever the insight suggests",
+			in: struct {
+				jobError string
+				insight  Insight
+			}{jobError: "401 Unauthorized", insight: Insight{Confidence: 0.9, SuggestedFix: SuggestedFix{MaxRetries: 3}}},
+			want: TriageConfigIssue,
+		},
+		{
+			name: "Given a confident insight raising the timeout, When triaging, Then should label it a config issue",
+			in: struct {
+				jobError string
+				insight  Insight
+			}{jobError: "request timed out", insight: Insight{Confidence: 0.8, SuggestedFix: SuggestedFix{TimeoutSeconds: 60}}},
+			want: TriageConfigIssue,
+		},
+		{
+			name: "Given a low-confidence insight, When triaging, Then should label it needs-human",
+			in: struct {
+				jobError string
+				insight  Insight
+			}{jobError: "missing field: to", insight: Insight{Confidence: 0.3, SuggestedFix: SuggestedFix{PayloadPatch: map[string]any{"to": "a@example.com"}}}},
+			want: TriageNeedsHuman,
+		},
+		{
+			name: "Given a confident insight without a fix, When triaging, Then should label it needs-human",
+			in: struct {
+				jobError string
+				insight  Insight
+			}{jobError: "segmentation fault", insight: Insight{Confidence: 0.9}},
+			want: TriageNeedsHuman,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &queue.Job{Status: queue.StatusFailed, Error: tt.in.jobError}
+
+			label := Triage(job, &tt.in.insight)
+
+			assert.Equal(t, tt.want, label)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package insights
+
+import "time"
+
+// Usage records what a single AI call cost in tokens and latency
+type Usage struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	PromptBytes      int    `json:"prompt_bytes"`
+	LatencyMs        int64  `json:"latency_ms"`
+}
+
+// TotalTokens returns prompt and completion tokens combined
+func (u *Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// UsageAggregate summarizes AI usage for one provider and model on one day
+type UsageAggregate struct {
+	Day              time.Time
+	Provider         string
+	Model            string
+	Calls            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	PromptBytes      int64
+	AvgLatencyMs     float64
+}
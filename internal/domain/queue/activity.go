@@ -0,0 +1,27 @@
+package queue
+
+import "time"
+
+// Activity summarizes job outcomes since a point in time
+type Activity struct {
+	Since           time.Time
+	Completed       int64            // Jobs completed
+	Failed          int64            // Jobs that failed, whether they will retry or were dead-lettered
+	TopFailingTypes []*TypeFailures  // Job types with the most failures, most first
+	DeadLettered    map[string]int64 // Jobs moved to the DLQ by reason, e.g. DeadLetterMaxAttempts
+}
+
+// TypeFailures counts the failed jobs of one type
+type TypeFailures struct {
+	Type   string
+	Failed int64
+}
+
+// PerMinute returns the completion rate over the period up to now
+func (a *Activity) PerMinute(now time.Time) float64 {
+	minutes := now.Sub(a.Since).Minutes()
+	if minutes <= 0 {
+		return 0
+	}
+	return float64(a.Completed) / minutes
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivity_PerMinute(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			since     time.Time
+			completed int64
+		}
+		want struct {
+			perMinute float64
+		}
+	}{
+		{
+			name: "Given 120 jobs completed over an hour, When computing the rate, Then should return 2 per minute",
+			in: struct {
+				since     time.Time
+				completed int64
+			}{since: now.Add(-time.Hour), completed: 120},
+			want: struct{ perMinute float64 }{perMinute: 2},
+		},
+		{
+			name: "Given a period starting now, When computing the rate, Then should return zero",
+			in: struct {
+				since     time.Time
+				completed int64
+			}{since: now, completed: 5},
+			want: struct{ perMinute float64 }{perMinute: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := &Activity{Since: tt.in.since, Completed: tt.in.completed}
+
+			assert.Equal(t, tt.want.perMinute, activity.PerMinute(now))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attempt is one execution of a job by a worker
+type Attempt struct {
+	JobID     uuid.UUID
+	Number    int // 1 for the first run; a throttled run doesn't use up an attempt, so the next reuses its number
+	WorkerID  string
+	StartedAt time.Time
+	Duration  time.Duration
+	Error     string        // Empty when the attempt succeeded
+	Category  string        // Why it failed, e.g. FailureTimeout; empty when it succeeded
+	Backoff   time.Duration // Wait before the job ran again after the attempt failed; zero unless it was retried
+	RetryAt   *time.Time    // When the job was due to run again; nil unless it was retried
+}
+
+// NewAttempt records a run of the job that started at startedAt and ended with execErr, nil
+// when it succeeded. It is created before the job is marked failed, which counts the attempt.
+func NewAttempt(job *Job, workerID string, startedAt time.Time, duration time.Duration, execErr error) *Attempt {
+	attempt := &Attempt{
+		JobID:     job.ID,
+		Number:    job.Attempts + 1,
+		WorkerID:  workerID,
+		StartedAt: startedAt.UTC(),
+		Duration:  duration,
+	}
+	if execErr != nil {
+		attempt.Error = execErr.Error()
+		attempt.Category = ClassifyFailure(execErr)
+	}
+	return attempt
+}
+
+// EndedAt returns when the run finished
+func (a *Attempt) EndedAt() time.Time {
+	return a.StartedAt.Add(a.Duration)
+}
+
+// Succeeded reports whether the run completed the job
+func (a *Attempt) Succeeded() bool {
+	return a.Error == ""
+}
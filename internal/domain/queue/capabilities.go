@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MaxCapabilities bounds the capabilities a job may require or a worker may announce.
+// A worker consumes one route per subset of its capabilities, so 2^n routes in all.
+const MaxCapabilities = 6
+
+var ErrInvalidCapability = errors.New("invalid capability, expected a name like gpu or a pair like region=eu")
+
+var capabilityPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*(=[a-z0-9][a-z0-9_.-]*)?$`)
+
+// NormalizeCapabilities validates capabilities, e.g. "gpu" or "region=eu", and returns them
+// lowercased, sorted and without duplicates so equal sets always compare equal
+func NormalizeCapabilities(capabilities []string) ([]string, error) {
+	var normalized []string
+	for _, capability := range capabilities {
+		capability = strings.ToLower(strings.TrimSpace(capability))
+		if capability == "" {
+			continue
+		}
+		if !capabilityPattern.MatchString(capability) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCapability, capability)
+		}
+		normalized = append(normalized, capability)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > MaxCapabilities {
+		return nil, fmt.Errorf("%w: at most %d capabilities, got %d", ErrInvalidCapability, MaxCapabilities, len(normalized))
+	}
+	return normalized, nil
+}
+
+// Route names the part of a queue holding the jobs that require the capabilities.
+// Jobs without requirements are on the empty route, which every worker consumes.
+func Route(capabilities []string) string {
+	return strings.Join(capabilities, ",")
+}
+
+// Routes returns the routes a worker with the normalized capabilities consumes, most
+// specific first, so a worker takes the jobs only it can run before those any worker can
+func Routes(capabilities []string) []string {
+	routes := make([]string, 0, 1<<len(capabilities))
+	for mask := 0; mask < 1<<len(capabilities); mask++ {
+		var subset []string
+		for i, capability := range capabilities {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, capability)
+			}
+		}
+		routes = append(routes, Route(subset))
+	}
+	slices.SortFunc(routes, func(a, b string) int {
+		if n := routeSize(b) - routeSize(a); n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+	return routes
+}
+
+// routeSize counts the capabilities a route requires
+func routeSize(route string) int {
+	if route == "" {
+		return 0
+	}
+	return strings.Count(route, ",") + 1
+}
+
+// Require sets the capabilities a worker must have to run the job
+func (j *Job) Require(capabilities []string) error {
+	normalized, err := NormalizeCapabilities(capabilities)
+	if err != nil {
+		return err
+	}
+	j.Requires = normalized
+	return nil
+}
+
+// Route names the part of the job's queue it waits on
+func (j *Job) Route() string {
+	return Route(j.Requires)
+}
+
+// SatisfiedBy reports whether a worker with the capabilities may run the job
+func (j *Job) SatisfiedBy(capabilities []string) bool {
+	for _, required := range j.Requires {
+		if !slices.Contains(capabilities, required) {
+			return false
+		}
+	}
+	return true
+}
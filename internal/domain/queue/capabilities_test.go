@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCapabilities(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			capabilities []string
+		}
+		want struct {
+			capabilities []string
+			err          error
+		}
+	}{
+		{
+			name: "Given capabilities in mixed case with duplicates and blanks, When normalizing, Then should return them lowercased, sorted and unique",
+			in:   struct{ capabilities []string }{capabilities: []string{" Region=EU", "gpu", "", "GPU"}},
+			want: struct {
+				capabilities []string
+				err          error
+			}{capabilities: []string{"gpu", "region=eu"}},
+		},
+		{
+			name: "Given no capabilities, When normalizing, Then should return none",
+			in:   struct{ capabilities []string }{capabilities: []string{""}},
+			want: struct {
+				capabilities []string
+				err          error
+			}{capabilities: nil},
+		},
+		{
+			name: "Given a capability with a route separator, When normalizing, Then should return ErrInvalidCapability",
+			in:   struct{ capabilities []string }{capabilities: []string{"gpu,cuda"}},
+			want: struct {
+				capabilities []string
+				err          error
+			}{err: ErrInvalidCapability},
+		},
+		{
+			name: "Given more capabilities than allowed, When normalizing, Then should return ErrInvalidCapability",
+			in:   struct{ capabilities []string }{capabilities: []string{"a", "b", "c", "d", "e", "f", "g"}},
+			want: struct {
+				capabilities []string
+				err          error
+			}{err: ErrInvalidCapability},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capabilities, err := NormalizeCapabilities(tt.in.capabilities)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want.capabilities, capabilities)
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			capabilities []string
+		}
+		want struct {
+			routes []string
+		}
+	}{
+		{
+			name: "Given a worker without capabilities, When listing its routes, Then should only consume the plain route",
+			in:   struct{ capabilities []string }{capabilities: nil},
+			want: struct{ routes []string }{routes: []string{""}},
+		},
+		{
+			name: "Given a worker with two capabilities, When listing its routes, Then should consume every subset with the most specific first",
+			in:   struct{ capabilities []string }{capabilities: []string{"gpu", "region=eu"}},
+			want: struct{ routes []string }{routes: []string{"gpu,region=eu", "gpu", "region=eu", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.routes, Routes(tt.in.capabilities))
+		})
+	}
+}
+
+func TestJob_SatisfiedBy(t *testing.T) {
+	job, _ := NewJob("default", "render", nil)
+	_ = job.Require([]string{"gpu", "region=eu"})
+
+	tests := []struct {
+		name string
+		in   struct {
+			capabilities []string
+		}
+		want struct {
+			satisfied bool
+		}
+	}{
+		{
+			name: "Given a worker with every required capability and more, When matching, Then should be satisfied",
+			in:   struct{ capabilities []string }{capabilities: []string{"gpu", "region=eu", "ssd"}},
+			want: struct{ satisfied bool }{satisfied: true},
+		},
+		{
+			name: "Given a worker in another region, When matching, Then should not be satisfied",
+			in:   struct{ capabilities []string }{capabilities: []string{"gpu", "region=us"}},
+			want: struct{ satisfied bool }{satisfied: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.satisfied, job.SatisfiedBy(tt.in.capabilities))
+		})
+	}
+}
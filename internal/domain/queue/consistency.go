@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultStrandedAfter is how long a job may wait unseen by the queue backend before it
+// counts as stranded; it covers the gap between a job being stored and being enqueued
+const DefaultStrandedAfter = time.Minute
+
+// QueueSnapshot holds the IDs of the jobs a queue backend has for a queue, waiting or in flight
+type QueueSnapshot map[uuid.UUID]struct{}
+
+// Holds reports whether the backend had the job when the snapshot was taken
+func (s QueueSnapshot) Holds(jobID uuid.UUID) bool {
+	_, ok := s[jobID]
+	return ok
+}
+
+// FindStranded returns the jobs ready to run according to the database that the snapshot
+// doesn't hold, e.g. because enqueueing them failed after they were stored or the backend
+// lost its data. Jobs scheduled for later and jobs changed less than strandedAfter before now
+// are skipped, since they may be on their way to the backend.
+func FindStranded(pending []*Job, snapshot QueueSnapshot, strandedAfter time.Duration, now time.Time) []*Job {
+	var stranded []*Job
+	for _, job := range pending {
+		if !job.IsReady() || job.IsDeleted() || snapshot.Holds(job.ID) {
+			continue
+		}
+		if now.Sub(job.UpdatedAt) < strandedAfter {
+			continue
+		}
+		stranded = append(stranded, job)
+	}
+	return stranded
+}
@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindStranded(t *testing.T) {
+	now := time.Now().UTC()
+	later := now.Add(time.Hour)
+	deletedAt := now.Add(-time.Hour)
+	queued := &Job{ID: uuid.New(), Status: StatusPending, UpdatedAt: now.Add(-time.Hour)}
+	lost := &Job{ID: uuid.New(), Status: StatusPending, UpdatedAt: now.Add(-time.Hour)}
+	lostRetry := &Job{ID: uuid.New(), Status: StatusRetrying, UpdatedAt: now.Add(-time.Hour)}
+	snapshot := QueueSnapshot{queued.ID: {}}
+
+	tests := []struct {
+		name string
+		in   struct {
+			pending []*Job
+		}
+		want struct {
+			stranded []*Job
+		}
+	}{
+		{
+			name: "Given ready jobs the backend doesn't hold, When looking for stranded jobs, Then should return them",
+			in:   struct{ pending []*Job }{pending: []*Job{queued, lost, lostRetry}},
+			want: struct{ stranded []*Job }{stranded: []*Job{lost, lostRetry}},
+		},
+		{
+			name: "Given a job stored moments ago, When looking for stranded jobs, Then should skip it as it may still be enqueued",
+			in: struct{ pending []*Job }{pending: []*Job{
+				{ID: uuid.New(), Status: StatusPending, UpdatedAt: now.Add(-time.Second)},
+			}},
+			want: struct{ stranded []*Job }{stranded: nil},
+		},
+		{
+			name: "Given jobs scheduled for later, deleted or already running, When looking for stranded jobs, Then should skip them",
+			in: struct{ pending []*Job }{pending: []*Job{
+				{ID: uuid.New(), Status: StatusRetrying, ScheduledFor: &later, UpdatedAt: now.Add(-time.Hour)},
+				{ID: uuid.New(), Status: StatusPending, DeletedAt: &deletedAt, UpdatedAt: now.Add(-time.Hour)},
+				{ID: uuid.New(), Status: StatusProcessing, UpdatedAt: now.Add(-time.Hour)},
+			}},
+			want: struct{ stranded []*Job }{stranded: nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.stranded, FindStranded(tt.in.pending, snapshot, DefaultStrandedAfter, now))
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package queue
+
+import "fmt"
+
+// Reasons a job was moved to the DLQ, recorded with it so dead letters can be told apart and
+// counted. A job failing for several reasons at once takes the first that applies, in this order.
+const (
+	DeadLetterNonRetryableError = "non_retryable_error" // The executor reported an error retrying can't fix
+	DeadLetterCancelledByPolicy = "cancelled_by_policy" // The retry policy of the job's type doesn't retry the error
+	DeadLetterExpired           = "expired"             // The last attempt ran out of time
+	DeadLetterMaxAttempts       = "max_attempts"        // Every attempt allowed failed
+)
+
+// DeadLetterReasons lists the reasons a job may be dead-lettered for
+var DeadLetterReasons = []string{
+	DeadLetterNonRetryableError,
+	DeadLetterCancelledByPolicy,
+	DeadLetterExpired,
+	DeadLetterMaxAttempts,
+}
+
+// MarkAsDeadLettered records why a job that failed for good is moved to the DLQ
+func (j *Job) MarkAsDeadLettered(reason string) error {
+	if j.Status != StatusFailed {
+		return fmt.Errorf("%w: %s job can't be dead-lettered", ErrInvalidTransition, j.Status)
+	}
+	j.DeadLetterReason = reason
+	return nil
+}
@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob_MarkAsDeadLettered(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			status Status
+		}
+		want struct {
+			reason string
+			err    error
+		}
+	}{
+		{
+			name: "Given a failed job, When dead-lettering it, Then should record the reason",
+			in:   struct{ status Status }{status: StatusFailed},
+			want: struct {
+				reason string
+				err    error
+			}{reason: DeadLetterMaxAttempts},
+		},
+		{
+			name: "Given a processing job, When dead-lettering it, Then should be refused",
+			in:   struct{ status Status }{status: StatusProcessing},
+			want: struct {
+				reason string
+				err    error
+			}{err: ErrInvalidTransition},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Status: tt.in.status}
+
+			err := job.MarkAsDeadLettered(DeadLetterMaxAttempts)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.reason, job.DeadLetterReason)
+		})
+	}
+}
+
+func TestJob_MarkAsRetrying_LeavesDLQ(t *testing.T) {
+	// Given
+	job := &Job{Status: StatusFailed}
+	assert.NoError(t, job.MarkAsDeadLettered(DeadLetterNonRetryableError))
+
+	// When
+	err := job.MarkAsRetrying()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRetrying, job.Status)
+	assert.Empty(t, job.DeadLetterReason)
+}
@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrQueueNotDefined    = errors.New("queue is not defined")
+	ErrQueueDefined       = errors.New("queue is already defined")
+	ErrInvalidQueueName   = errors.New("queue name must be 1-64 letters, digits, '.', '-' or '_'")
+	ErrInvalidDefinition  = errors.New("max attempts, backoff, rate limit and payload retention must not be negative")
+	ErrJobTypeNotAllowed  = errors.New("job type is not allowed in this queue")
+	ErrQueueDraining      = errors.New("queue is draining and doesn't accept new jobs")
+	ErrInvalidDefaultType = errors.New("default job type must be one of the queue's allowed types")
+	ErrPayloadNotObject   = errors.New("queue requires a JSON object payload")
+)
+
+var queueNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Definition configures a named queue. Zero values fall back to the worker defaults.
+type Definition struct {
+	Name               string
+	MaxAttempts        int           // Overrides the worker's max attempts when positive
+	BaseBackoffMs      int           // Overrides the worker's base backoff when positive
+	RateLimitPerSecond float64       // Job creations per second; zero is unlimited
+	AllowedTypes       []string      // Job types the queue accepts; empty accepts any
+	Paused             bool          // Workers stop consuming the queue; jobs are still accepted
+	Draining           bool          // New jobs are rejected while workers finish the queued ones
+	DefaultType        string        // Type of the jobs created without one; empty requires a type
+	StrictPayload      bool          // Jobs must carry a JSON object payload
+	InsightPolicy      InsightPolicy // Failures sent for AI analysis; empty uses the worker's policy
+	// PayloadRetentionDays is how long completed jobs keep their payload and result; zero uses
+	// the retention policy's
+	PayloadRetentionDays int
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+// NewDefinition creates a definition with the worker defaults for the named queue
+func NewDefinition(name string) (*Definition, error) {
+	now := time.Now().UTC()
+	def := &Definition{Name: name, CreatedAt: now, UpdatedAt: now}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// Validate checks the queue name, that no setting is negative and that the default type is
+// allowed
+func (d *Definition) Validate() error {
+	if !queueNamePattern.MatchString(d.Name) {
+		return ErrInvalidQueueName
+	}
+	if d.MaxAttempts < 0 || d.BaseBackoffMs < 0 || d.RateLimitPerSecond < 0 || d.PayloadRetentionDays < 0 {
+		return ErrInvalidDefinition
+	}
+	if d.DefaultType != "" && !d.AllowsType(d.DefaultType) {
+		return fmt.Errorf("%w: %q not in %v", ErrInvalidDefaultType, d.DefaultType, d.AllowedTypes)
+	}
+	return d.InsightPolicy.Validate()
+}
+
+// AllowsType reports whether jobs of the type may be created in the queue
+func (d *Definition) AllowsType(jobType string) bool {
+	return len(d.AllowedTypes) == 0 || slices.Contains(d.AllowedTypes, jobType)
+}
+
+// CheckType returns ErrJobTypeNotAllowed, naming the allowed types and the closest one, when
+// jobs of the type may not be created in the queue
+func (d *Definition) CheckType(jobType string) error {
+	if d.AllowsType(jobType) {
+		return nil
+	}
+	err := fmt.Errorf("%w: %q is not one of %s", ErrJobTypeNotAllowed, jobType, strings.Join(d.AllowedTypes, ", "))
+	if suggestion := closestType(jobType, d.AllowedTypes); suggestion != "" {
+		err = fmt.Errorf("%w, did you mean %q?", err, suggestion)
+	}
+	return err
+}
+
+// CheckPayload returns ErrPayloadNotObject when the queue validates payloads strictly and the
+// payload isn't a JSON object
+func (d *Definition) CheckPayload(payload []byte) error {
+	if !d.StrictPayload {
+		return nil
+	}
+	var object map[string]json.RawMessage
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || trimmed[0] != '{' || json.Unmarshal(trimmed, &object) != nil {
+		return fmt.Errorf("%w, got %s", ErrPayloadNotObject, payloadKind(trimmed))
+	}
+	return nil
+}
+
+// payloadKind names the kind of JSON value a payload holds for error messages
+func payloadKind(payload []byte) string {
+	if len(payload) == 0 {
+		return "no payload"
+	}
+	switch payload[0] {
+	case '{':
+		return "malformed JSON"
+	case '[':
+		return "an array"
+	case '"':
+		return "a string"
+	case 'n':
+		return "null"
+	case 't', 'f':
+		return "a boolean"
+	default:
+		return "a number"
+	}
+}
+
+// closestType returns the allowed type a typo'd job type most likely meant: the one at the
+// smallest edit distance, when that is at most a third of its length
+func closestType(jobType string, allowed []string) string {
+	best, bestDistance := "", 0
+	for _, candidate := range allowed {
+		distance := editDistance(strings.ToLower(jobType), strings.ToLower(candidate))
+		if distance > max(1, len(candidate)/3) {
+			continue
+		}
+		if best == "" || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions of
+// adjacent bytes turning a into b
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
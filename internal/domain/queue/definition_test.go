@@ -0,0 +1,210 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefinition_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			def Definition
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given a valid name and settings, When validating, Then should succeed",
+			in:   struct{ def Definition }{def: Definition{Name: "emails.high-priority_1", MaxAttempts: 5, RateLimitPerSecond: 2.5}},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given an empty name, When validating, Then should return ErrInvalidQueueName",
+			in:   struct{ def Definition }{def: Definition{Name: ""}},
+			want: struct{ err error }{err: ErrInvalidQueueName},
+		},
+		{
+			name: "Given a name with a slash, When validating, Then should return ErrInvalidQueueName",
+			in:   struct{ def Definition }{def: Definition{Name: "emails/drain"}},
+			want: struct{ err error }{err: ErrInvalidQueueName},
+		},
+		{
+			name: "Given a negative backoff, When validating, Then should return ErrInvalidDefinition",
+			in:   struct{ def Definition }{def: Definition{Name: "emails", BaseBackoffMs: -1}},
+			want: struct{ err error }{err: ErrInvalidDefinition},
+		},
+		{
+			name: "Given a negative payload retention, When validating, Then should return ErrInvalidDefinition",
+			in:   struct{ def Definition }{def: Definition{Name: "emails", PayloadRetentionDays: -1}},
+			want: struct{ err error }{err: ErrInvalidDefinition},
+		},
+		{
+			name: "Given a default type outside the allowed types, When validating, Then should return ErrInvalidDefaultType",
+			in:   struct{ def Definition }{def: Definition{Name: "emails", AllowedTypes: []string{"email"}, DefaultType: "sms"}},
+			want: struct{ err error }{err: ErrInvalidDefaultType},
+		},
+		{
+			name: "Given a default type without allowed types, When validating, Then should succeed",
+			in:   struct{ def Definition }{def: Definition{Name: "emails", DefaultType: "email"}},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given an unknown insight policy, When validating, Then should return ErrInvalidInsightPolicy",
+			in:   struct{ def Definition }{def: Definition{Name: "emails", InsightPolicy: "sometimes"}},
+			want: struct{ err error }{err: ErrInvalidInsightPolicy},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.in.def.Validate(), tt.want.err)
+		})
+	}
+}
+
+func TestDefinition_AllowsType(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			allowed []string
+			jobType string
+		}
+		want struct {
+			allowed bool
+		}
+	}{
+		{
+			name: "Given no allowed types, When checking a type, Then should allow it",
+			in: struct {
+				allowed []string
+				jobType string
+			}{allowed: nil, jobType: "email"},
+			want: struct{ allowed bool }{allowed: true},
+		},
+		{
+			name: "Given a listed type, When checking it, Then should allow it",
+			in: struct {
+				allowed []string
+				jobType string
+			}{allowed: []string{"email", "sms"}, jobType: "sms"},
+			want: struct{ allowed bool }{allowed: true},
+		},
+		{
+			name: "Given an unlisted type, When checking it, Then should reject it",
+			in: struct {
+				allowed []string
+				jobType string
+			}{allowed: []string{"email"}, jobType: "emial"},
+			want: struct{ allowed bool }{allowed: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := &Definition{Name: "default", AllowedTypes: tt.in.allowed}
+
+			assert.Equal(t, tt.want.allowed, def.AllowsType(tt.in.jobType))
+		})
+	}
+}
+
+func TestDefinition_CheckType(t *testing.T) {
+	def := Definition{Name: "notifications", AllowedTypes: []string{"send_email", "send_sms"}}
+
+	tests := []struct {
+		name string
+		in   string
+		want struct {
+			err     error
+			message string
+		}
+	}{
+		{
+			name: "Given an allowed type, When checking it, Then should accept it",
+			in:   "send_sms",
+		},
+		{
+			name: "Given a typo'd type, When checking it, Then should suggest the closest allowed type",
+			in:   "send_emial",
+			want: struct {
+				err     error
+				message string
+			}{err: ErrJobTypeNotAllowed, message: `"send_emial" is not one of send_email, send_sms, did you mean "send_email"?`},
+		},
+		{
+			name: "Given an unrelated type, When checking it, Then should list the allowed types without a suggestion",
+			in:   "resize_image",
+			want: struct {
+				err     error
+				message string
+			}{err: ErrJobTypeNotAllowed, message: `"resize_image" is not one of send_email, send_sms`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := def.CheckType(tt.in)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			if tt.want.message != "" {
+				assert.True(t, strings.HasSuffix(err.Error(), tt.want.message), err.Error())
+			}
+		})
+	}
+}
+
+func TestDefinition_CheckPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			strict  bool
+			payload string
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given a strict queue and an object, When checking the payload, Then should accept it",
+			in: struct {
+				strict  bool
+				payload string
+			}{strict: true, payload: ` {"to": "a@example.com"}`},
+		},
+		{
+			name: "Given a strict queue and an array, When checking the payload, Then should return ErrPayloadNotObject",
+			in: struct {
+				strict  bool
+				payload string
+			}{strict: true, payload: `["a@example.com"]`},
+			want: struct{ err error }{err: ErrPayloadNotObject},
+		},
+		{
+			name: "Given a strict queue and a null payload, When checking the payload, Then should return ErrPayloadNotObject",
+			in: struct {
+				strict  bool
+				payload string
+			}{strict: true, payload: `null`},
+			want: struct{ err error }{err: ErrPayloadNotObject},
+		},
+		{
+			name: "Given a lenient queue and a string, When checking the payload, Then should accept it",
+			in: struct {
+				strict  bool
+				payload string
+			}{strict: false, payload: `"hello"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := Definition{Name: "emails", StrictPayload: tt.in.strict}
+
+			assert.ErrorIs(t, def.CheckPayload([]byte(tt.in.payload)), tt.want.err)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package queue
+
+// DeliveryStats summarizes message acknowledgements for a single queue
+type DeliveryStats struct {
+	Queue   string
+	Acked   int64 // Jobs acknowledged after being handled
+	Nacked  int64 // Jobs returned to the queue for redelivery
+	Unacked int64 // Jobs dequeued and still being processed
+	Ready   int64 // Jobs waiting in the queue to be dequeued
+}
@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Failure categories, so failures can be broken down by reason
+const (
+	FailureTimeout    = "timeout"
+	FailureAuth       = "auth"
+	FailureValidation = "validation"
+	FailureUnknown    = "unknown"
+)
+
+var (
+	timeoutFailure    = regexp.MustCompile(`timeout|timed out|deadline exceeded`)
+	authFailure       = regexp.MustCompile(`unauthori[sz]ed|unauthenticated|forbidden|permission denied|access denied|authenticat|credential|invalid token|token expired|\b(401|403)\b`)
+	validationFailure = regexp.MustCompile(`invalid|validation|malformed|required|bad request|unprocessable|schema|\b(400|422)\b`)
+)
+
+// ClassifyFailure tells why a job failed. Deadlines and network timeouts are recognized by
+// type, anything else by its message.
+func ClassifyFailure(err error) string {
+	if err == nil {
+		return FailureUnknown
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return FailureTimeout
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return FailureTimeout
+	}
+	return ClassifyFailureMessage(err.Error())
+}
+
+// ClassifyFailureMessage tells why a job failed from its error message. Timeouts win over auth
+// and auth over validation, so "invalid token" is an auth failure.
+func ClassifyFailureMessage(message string) string {
+	message = strings.ToLower(message)
+	switch {
+	case timeoutFailure.MatchString(message):
+		return FailureTimeout
+	case authFailure.MatchString(message):
+		return FailureAuth
+	case validationFailure.MatchString(message):
+		return FailureValidation
+	default:
+		return FailureUnknown
+	}
+}
+
+// Job lifecycle events counted by a MetricsService
+const (
+	CounterCreated   = "created"
+	CounterCompleted = "completed"
+	CounterFailed    = "failed"
+	CounterRetried   = "retried"
+	CounterParked    = "parked"
+)
+
+// JobCounter is how many jobs of a type in a queue went through a lifecycle event
+type JobCounter struct {
+	Event    string
+	Queue    string
+	Type     string
+	Category string // Failure category; failed counters only
+	Count    int64
+}
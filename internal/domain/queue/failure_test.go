@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type netTimeout struct{}
+
+func (netTimeout) Error() string   { return "dial tcp 10.0.0.1:443: i/o wait" }
+func (netTimeout) Timeout() bool   { return true }
+func (netTimeout) Temporary() bool { return true }
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			err error
+		}
+		want struct {
+			category string
+		}
+	}{
+		{
+			name: "Given a wrapped deadline, When classifying, Then should be a timeout",
+			in:   struct{ err error }{err: fmt.Errorf("call billing: %w", context.DeadlineExceeded)},
+			want: struct{ category string }{category: FailureTimeout},
+		},
+		{
+			name: "Given a network error reporting a timeout, When classifying, Then should be a timeout",
+			in:   struct{ err error }{err: fmt.Errorf("send: %w", netTimeout{})},
+			want: struct{ category string }{category: FailureTimeout},
+		},
+		{
+			name: "Given a 401 from a downstream service, When classifying, Then should be an auth failure",
+			in:   struct{ err error }{err: errors.New("smtp relay returned status 401")},
+			want: struct{ category string }{category: FailureAuth},
+		},
+		{
+			name: "Given an invalid token, When classifying, Then should be an auth failure rather than validation",
+			in:   struct{ err error }{err: errors.New("Invalid token for account")},
+			want: struct{ category string }{category: FailureAuth},
+		},
+		{
+			name: "Given a missing field, When classifying, Then should be a validation failure",
+			in:   struct{ err error }{err: errors.New("field 'to' is required")},
+			want: struct{ category string }{category: FailureValidation},
+		},
+		{
+			name: "Given a number containing 400, When classifying, Then should not be a validation failure",
+			in:   struct{ err error }{err: errors.New("disk full after 14000 writes")},
+			want: struct{ category string }{category: FailureUnknown},
+		},
+		{
+			name: "Given no error, When classifying, Then should be unknown",
+			in:   struct{ err error }{},
+			want: struct{ category string }{category: FailureUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.category, ClassifyFailure(tt.in.err))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// Volatile parts of error messages, replaced in order so failures of the same cause share a
+// fingerprint. Addresses go before numbers so an IP and its port mask as one address.
+var (
+	urlPattern      = regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^\s"']+`)
+	emailPattern    = regexp.MustCompile(`\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+	uuidPattern     = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	ipPattern       = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`)
+	hostPortPattern = regexp.MustCompile(`\b[a-z0-9-]+(\.[a-z0-9-]+)*:\d{2,5}\b`)
+	hexPattern      = regexp.MustCompile(`\b0x[0-9a-f]+\b|\b[0-9a-f]{16,}\b`)
+	quotedPattern   = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	numberPattern   = regexp.MustCompile(`\d+(\.\d+)?`)
+	spacePattern    = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeError masks the volatile parts of an error message: URLs, email and network
+// addresses, IDs, hex values, quoted values and numbers
+func NormalizeError(message string) string {
+	normalized := strings.ToLower(message)
+	normalized = urlPattern.ReplaceAllString(normalized, "<url>")
+	normalized = emailPattern.ReplaceAllString(normalized, "<email>")
+	normalized = uuidPattern.ReplaceAllString(normalized, "<id>")
+	normalized = ipPattern.ReplaceAllString(normalized, "<addr>")
+	normalized = hostPortPattern.ReplaceAllString(normalized, "<addr>")
+	normalized = hexPattern.ReplaceAllString(normalized, "<hex>")
+	normalized = quotedPattern.ReplaceAllString(normalized, "<value>")
+	normalized = numberPattern.ReplaceAllString(normalized, "<n>")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(normalized, " "))
+}
+
+// ErrorFingerprint identifies the cause of a failure: a hash of the normalized error message,
+// so failures differing only in IDs, numbers or addresses share a fingerprint. It is empty for
+// an empty message.
+func ErrorFingerprint(message string) string {
+	normalized := NormalizeError(message)
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ErrorFingerprint returns the fingerprint of the job's last error, empty when it has none
+func (j *Job) ErrorFingerprint() string {
+	return ErrorFingerprint(j.Error)
+}
@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			a, b string
+		}
+		want bool // same fingerprint
+	}{
+		{
+			name: "Given errors differing in IPs, ports, durations and IDs, When fingerprinting, Then should share a fingerprint",
+			in: struct{ a, b string }{
+				a: "dial tcp 10.0.0.12:587: i/o timeout after 3000ms (job 3f0c2a1e-8d6b-4c1a-9a77-2b1f7c0e9d11)",
+				b: "dial tcp 192.168.1.7:25: i/o timeout after 2999ms (job 9b2d4e6f-1a3c-4e5b-8c7d-0f1e2d3c4b5a)",
+			},
+			want: true,
+		},
+		{
+			name: "Given errors differing in host addresses, URLs and emails, When fingerprinting, Then should share a fingerprint",
+			in: struct{ a, b string }{
+				a: "POST https://api.example.com/v1/send?id=1 failed: mailbox alice@example.com unavailable at smtp-1.example.com:587",
+				b: "POST https://api.example.org/v2/send failed: mailbox bob@corp.example.net unavailable at mx.example.org:2525",
+			},
+			want: true,
+		},
+		{
+			name: "Given errors differing in memory addresses and case, When fingerprinting, Then should share a fingerprint",
+			in: struct{ a, b string }{
+				a: "panic: nil pointer dereference at 0xc000123abc",
+				b: "Panic: nil pointer  dereference at 0xC000FFEE00",
+			},
+			want: true,
+		},
+		{
+			name: "Given different errors, When fingerprinting, Then should not share a fingerprint",
+			in: struct{ a, b string }{
+				a: "connection refused",
+				b: "401 Unauthorized",
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := ErrorFingerprint(tt.in.a)
+			b := ErrorFingerprint(tt.in.b)
+			assert.NotEmpty(t, a)
+			assert.Equal(t, tt.want, a == b)
+		})
+	}
+}
+
+func TestErrorFingerprint_Empty(t *testing.T) {
+	assert.Empty(t, ErrorFingerprint(""), "Given no error, When fingerprinting, Then should be empty")
+	assert.Empty(t, (&Job{}).ErrorFingerprint())
+}
@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrGroupNotFound = errors.New("job group not found")
+	ErrInvalidGroup  = errors.New("a job group must have between 1 and 1000 jobs")
+)
+
+// MaxGroupJobs bounds the jobs submitted in one group
+const MaxGroupJobs = 1000
+
+// GroupHoldUntil is the schedule of a group's completion job until the group finishes. The job
+// waits in the database like a delayed job and is released to the scheduler by setting its
+// schedule to the time the group finished.
+var GroupHoldUntil = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// Group is a set of jobs submitted together whose progress is tracked as a whole
+type Group struct {
+	ID              uuid.UUID
+	Size            int        // Jobs in the group
+	CompletionJobID *uuid.UUID // Job released once every job of the group completed or failed
+	CreatedAt       time.Time
+	CompletedAt     *time.Time // Set once every job of the group completed or failed
+}
+
+// NewGroup creates a group of size jobs
+func NewGroup(size int) (*Group, error) {
+	if size < 1 || size > MaxGroupJobs {
+		return nil, ErrInvalidGroup
+	}
+	return &Group{ID: uuid.New(), Size: size, CreatedAt: time.Now().UTC()}, nil
+}
+
+// GroupProgress counts the jobs of a group by how far they got
+type GroupProgress struct {
+	Total     int64
+	Completed int64
+	Failed    int64 // Failed for good, i.e. in the DLQ, cancelled or deleted
+	Pending   int64 // Waiting, running or retrying
+}
+
+// Finished reports whether no job of the group is left to run
+func (p GroupProgress) Finished() bool {
+	return p.Pending == 0
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		in   int
+		want error
+	}{
+		{
+			name: "Given one job, When creating a group, Then should accept it",
+			in:   1,
+		},
+		{
+			name: "Given the maximum number of jobs, When creating a group, Then should accept it",
+			in:   MaxGroupJobs,
+		},
+		{
+			name: "Given no jobs, When creating a group, Then should return ErrInvalidGroup",
+			in:   0,
+			want: ErrInvalidGroup,
+		},
+		{
+			name: "Given more jobs than the maximum, When creating a group, Then should return ErrInvalidGroup",
+			in:   MaxGroupJobs + 1,
+			want: ErrInvalidGroup,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group, err := NewGroup(tt.in)
+
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.in, group.Size)
+			assert.Nil(t, group.CompletedAt)
+		})
+	}
+}
+
+func TestGroupProgress_Finished(t *testing.T) {
+	tests := []struct {
+		name string
+		in   GroupProgress
+		want bool
+	}{
+		{
+			name: "Given jobs still pending, When checking the progress, Then should not be finished",
+			in:   GroupProgress{Total: 3, Completed: 1, Pending: 2},
+		},
+		{
+			name: "Given every job completed or failed, When checking the progress, Then should be finished",
+			in:   GroupProgress{Total: 3, Completed: 2, Failed: 1},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.in.Finished())
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package queue
+
+import "errors"
+
+// InsightPolicy decides which failures of a job are sent for AI analysis. Terminal failures,
+// after which the job won't retry, are analyzed under every policy so each job that ends up
+// failed has an insight; policies differ in which failures before that are analyzed.
+type InsightPolicy string
+
+const (
+	InsightOnFirstFailure    InsightPolicy = "first_failure"    // The first attempt's failure
+	InsightOnEveryFailure    InsightPolicy = "every_failure"    // Every failure
+	InsightOnTerminalFailure InsightPolicy = "terminal_failure" // Only the terminal failure
+)
+
+// DefaultInsightPolicy is used when neither the worker nor the queue sets a policy
+const DefaultInsightPolicy = InsightOnFirstFailure
+
+var ErrInvalidInsightPolicy = errors.New("insight policy must be first_failure, every_failure or terminal_failure")
+
+// Validate checks the policy is a known one; an empty policy is valid and means the default
+func (p InsightPolicy) Validate() error {
+	switch p {
+	case "", InsightOnFirstFailure, InsightOnEveryFailure, InsightOnTerminalFailure:
+		return nil
+	default:
+		return ErrInvalidInsightPolicy
+	}
+}
+
+// ShouldAnalyze reports whether a failure of the job is analyzed. The job must already be
+// marked as failed, so its attempts include this failure; terminal is true when it won't retry.
+func (p InsightPolicy) ShouldAnalyze(job *Job, terminal bool) bool {
+	if terminal {
+		return true
+	}
+	switch p {
+	case InsightOnEveryFailure:
+		return true
+	case InsightOnTerminalFailure:
+		return false
+	default:
+		return job.Attempts == 1
+	}
+}
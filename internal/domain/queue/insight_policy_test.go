@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsightPolicy_ShouldAnalyze(t *testing.T) {
+	type input struct {
+		policy   InsightPolicy
+		attempts int
+		terminal bool
+	}
+
+	tests := []struct {
+		name string
+		in   input
+		want struct {
+			analyze bool
+		}
+	}{
+		{
+			name: "Given the first_failure policy, When the first attempt fails, Then should analyze",
+			in:   input{policy: InsightOnFirstFailure, attempts: 1},
+			want: struct{ analyze bool }{analyze: true},
+		},
+		{
+			name: "Given the first_failure policy, When a later attempt fails and will retry, Then should not analyze",
+			in:   input{policy: InsightOnFirstFailure, attempts: 2},
+			want: struct{ analyze bool }{analyze: false},
+		},
+		{
+			name: "Given the first_failure policy, When a later attempt fails terminally, Then should analyze",
+			in:   input{policy: InsightOnFirstFailure, attempts: 3, terminal: true},
+			want: struct{ analyze bool }{analyze: true},
+		},
+		{
+			name: "Given the every_failure policy, When a later attempt fails and will retry, Then should analyze",
+			in:   input{policy: InsightOnEveryFailure, attempts: 2},
+			want: struct{ analyze bool }{analyze: true},
+		},
+		{
+			name: "Given the terminal_failure policy, When the first attempt fails and will retry, Then should not analyze",
+			in:   input{policy: InsightOnTerminalFailure, attempts: 1},
+			want: struct{ analyze bool }{analyze: false},
+		},
+		{
+			name: "Given no policy, When the first attempt fails, Then should behave like first_failure",
+			in:   input{attempts: 1},
+			want: struct{ analyze bool }{analyze: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Attempts: tt.in.attempts, Status: StatusFailed}
+
+			assert.Equal(t, tt.want.analyze, tt.in.policy.ShouldAnalyze(job, tt.in.terminal))
+		})
+	}
+}
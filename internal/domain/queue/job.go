@@ -2,6 +2,9 @@ package queue
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,10 +18,23 @@ type Job struct {
 	Status       Status
 	Attempts     int
 	Payload      []byte
+	Result       []byte
 	Error        string
 	ScheduledFor *time.Time
+	CallbackURL  string            // Receives the final job state when set
+	Signature    string            // HMAC of the payload, empty when the job is unsigned
+	SigningKeyID string            // Identifies the secret the signature was made with
+	Requires     []string          // Capabilities a worker needs to run the job, normalized; empty runs anywhere
+	Version      int               // Counts the stored updates; edits name the version they were based on
+	GroupID      *uuid.UUID        // Group the job was submitted in, if any
+	CreatedBy    string            // Caller that created the job, see Owners; empty when created without an API key
+	Metadata     map[string]string // Correlation IDs, source system, trace context and the like, apart from the payload; see SetMetadata
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	DeletedAt    *time.Time // Set when the job is soft-deleted
+
+	DeadLetterReason string // Why the job was moved to the DLQ, e.g. DeadLetterMaxAttempts; empty unless it was
+	SchemaVersion    int    // Version of the payload's schema, see EffectiveSchemaVersion
 }
 
 // Status represents job processing status
@@ -30,8 +46,46 @@ const (
 	StatusCompleted  Status = "completed"
 	StatusFailed     Status = "failed"
 	StatusRetrying   Status = "retrying"
+	StatusCancelled  Status = "cancelled" // Purged from its queue before it ran
+	StatusHeld       Status = "held"      // Parked by a worker that doesn't know its schema version
+	StatusParked     Status = "parked"    // Failed while its queue's retry budget was exhausted
 )
 
+// transitions lists the statuses a job may move to from each status. A job may always keep
+// its status, so its other fields can be updated. Processing may be re-entered when a job
+// is redelivered after its worker died. Completed is terminal, and a failed job only leaves
+// the failed status when it is retried. Only pending jobs can be cancelled, which is terminal.
+// Jobs waiting to run, or claimed by a worker that didn't run them, may be held, and a held
+// job is released back to pending. A failed job denied a retry by its queue's retry budget is
+// parked, and retried once it is released.
+var transitions = map[Status][]Status{
+	StatusPending:    {StatusProcessing, StatusFailed, StatusCancelled, StatusHeld},
+	StatusRetrying:   {StatusProcessing, StatusFailed, StatusHeld},
+	StatusProcessing: {StatusCompleted, StatusFailed, StatusHeld},
+	StatusFailed:     {StatusRetrying, StatusParked},
+	StatusCompleted:  {},
+	StatusCancelled:  {},
+	StatusHeld:       {StatusPending},
+	StatusParked:     {StatusRetrying},
+}
+
+// CanTransitionTo reports whether a job in status s may move to next
+func (s Status) CanTransitionTo(next Status) bool {
+	return s == next || slices.Contains(transitions[s], next)
+}
+
+// PreviousStatuses returns the statuses a job may be in to move to next, including next itself
+func PreviousStatuses(next Status) []Status {
+	previous := []Status{next}
+	for from, targets := range transitions {
+		if from != next && slices.Contains(targets, next) {
+			previous = append(previous, from)
+		}
+	}
+	slices.Sort(previous)
+	return previous
+}
+
 // Business rules and validation
 
 var (
@@ -39,6 +93,12 @@ var (
 	ErrInvalidType        = errors.New("job type is required")
 	ErrMaxAttemptsReached = errors.New("maximum retry attempts reached")
 	ErrJobNotFound        = errors.New("job not found")
+	ErrJobNotDeleted      = errors.New("job is not deleted")
+	ErrInvalidCallbackURL = errors.New("callback URL must be an absolute http or https URL")
+	ErrInvalidTransition  = errors.New("invalid job status transition")
+	ErrJobNotDue          = errors.New("job is not a delayed job that is due")
+	ErrJobNotEditable     = errors.New("job can only be edited while it is pending or retrying")
+	ErrVersionConflict    = errors.New("job was changed since the given version")
 )
 
 // NewJob creates a new job with validation
@@ -52,46 +112,105 @@ func NewJob(queue, jobType string, payload []byte) (*Job, error) {
 
 	now := time.Now().UTC()
 	return &Job{
-		ID:        uuid.New(),
-		Queue:     queue,
-		Type:      jobType,
-		Status:    StatusPending,
-		Attempts:  0,
-		Payload:   payload,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:            uuid.New(),
+		Queue:         queue,
+		Type:          jobType,
+		Status:        StatusPending,
+		Attempts:      0,
+		Payload:       payload,
+		SchemaVersion: DefaultSchemaVersion,
+		CreatedAt:     now,
+		UpdatedAt:     now,
 	}, nil
 }
 
+// SetCallbackURL sets the URL notified when the job completes or fails permanently
+func (j *Job) SetCallbackURL(rawURL string) error {
+	if err := ValidateCallbackURL(rawURL); err != nil {
+		return err
+	}
+	j.CallbackURL = rawURL
+	return nil
+}
+
+// ValidateCallbackURL checks that a callback URL is an absolute http or https URL
+func ValidateCallbackURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidCallbackURL
+	}
+	return nil
+}
+
 // CanRetry checks if the job can be retried based on business rules
 func (j *Job) CanRetry(maxAttempts int) bool {
 	return j.Attempts < maxAttempts && j.Status == StatusFailed
 }
 
 // MarkAsProcessing marks the job as being processed
-func (j *Job) MarkAsProcessing() {
-	j.Status = StatusProcessing
-	j.UpdatedAt = time.Now().UTC()
+func (j *Job) MarkAsProcessing() error {
+	return j.transition(StatusProcessing)
 }
 
 // MarkAsCompleted marks the job as successfully completed
-func (j *Job) MarkAsCompleted() {
-	j.Status = StatusCompleted
-	j.UpdatedAt = time.Now().UTC()
+func (j *Job) MarkAsCompleted() error {
+	return j.transition(StatusCompleted)
 }
 
 // MarkAsFailed marks the job as failed with an error message
-func (j *Job) MarkAsFailed(err error) {
-	j.Status = StatusFailed
-	j.Error = err.Error()
+func (j *Job) MarkAsFailed(err error) error {
+	if err := j.transition(StatusFailed); err != nil {
+		return err
+	}
+	if err != nil {
+		j.Error = err.Error()
+	}
 	j.Attempts++
+	return nil
+}
+
+// MarkAsThrottled marks the job as failed because a downstream service throttled it.
+// Unlike MarkAsFailed it doesn't count as an attempt.
+func (j *Job) MarkAsThrottled(err error) error {
+	if err := j.transition(StatusFailed); err != nil {
+		return err
+	}
+	j.Error = err.Error()
+	return nil
+}
+
+// RecordResult stores the JSON-encoded executor output for the job
+func (j *Job) RecordResult(result []byte) {
+	j.Result = result
 	j.UpdatedAt = time.Now().UTC()
 }
 
-// MarkAsRetrying marks the job for retry
-func (j *Job) MarkAsRetrying() {
-	j.Status = StatusRetrying
+// MarkAsRetrying marks the job for retry; a job retried from the DLQ leaves it
+func (j *Job) MarkAsRetrying() error {
+	if err := j.transition(StatusRetrying); err != nil {
+		return err
+	}
+	j.DeadLetterReason = ""
+	return nil
+}
+
+// transition moves the job to next, refusing moves the state machine doesn't allow
+func (j *Job) transition(next Status) error {
+	if !j.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, j.Status, next)
+	}
+	j.Status = next
 	j.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// MarkAsCancelled cancels a pending job purged from its queue, recording why
+func (j *Job) MarkAsCancelled(reason string) error {
+	if err := j.transition(StatusCancelled); err != nil {
+		return err
+	}
+	j.Error = reason
+	return nil
 }
 
 // Schedule schedules the job for future execution
@@ -100,6 +219,65 @@ func (j *Job) Schedule(scheduledFor time.Time) {
 	j.UpdatedAt = time.Now().UTC()
 }
 
+// IsDelayed reports whether the job was created to run later and is still held back from the
+// queue backend. Retries scheduled by workers are not delayed jobs.
+func (j *Job) IsDelayed() bool {
+	return j.Status == StatusPending && j.ScheduledFor != nil
+}
+
+// NextRetryAt returns when a retrying job runs again, as scheduled by the worker that failed
+// it; nil for jobs that aren't waiting for a retry
+func (j *Job) NextRetryAt() *time.Time {
+	if j.Status != StatusRetrying {
+		return nil
+	}
+	return j.ScheduledFor
+}
+
+// Promote clears the schedule of a delayed job that is due, so it is handed to the queue
+// backend exactly once
+func (j *Job) Promote(now time.Time) error {
+	if !j.IsDelayed() || j.ScheduledFor.After(now) {
+		return ErrJobNotDue
+	}
+	j.ScheduledFor = nil
+	j.UpdatedAt = now
+	return nil
+}
+
+// CheckEditable reports why the job, last read at version, can't be edited; a job that has
+// started running or was changed by someone else since can't
+func (j *Job) CheckEditable(version int) error {
+	if j.Version != version {
+		return fmt.Errorf("%w: expected version %d, job is at version %d", ErrVersionConflict, version, j.Version)
+	}
+	if j.Status != StatusPending && j.Status != StatusRetrying {
+		return fmt.Errorf("%w: job is %s", ErrJobNotEditable, j.Status)
+	}
+	return nil
+}
+
+// Edit replaces the payload and schedule of a job that hasn't started running; a nil payload
+// or schedule is left as it is. A schedule that has passed runs the job now. The next run of
+// a retrying job follows its backoff, so only pending jobs can be rescheduled.
+func (j *Job) Edit(payload []byte, scheduledFor *time.Time, now time.Time) error {
+	if scheduledFor != nil && j.Status != StatusPending {
+		return fmt.Errorf("%w: only pending jobs can be rescheduled", ErrJobNotEditable)
+	}
+	if payload != nil {
+		j.Payload = payload
+	}
+	if scheduledFor != nil {
+		j.ScheduledFor = nil
+		if scheduledFor.After(now) {
+			at := scheduledFor.UTC()
+			j.ScheduledFor = &at
+		}
+	}
+	j.UpdatedAt = now
+	return nil
+}
+
 // IsReady checks if the job is ready to be processed
 func (j *Job) IsReady() bool {
 	if j.Status != StatusPending && j.Status != StatusRetrying {
@@ -110,3 +288,14 @@ func (j *Job) IsReady() bool {
 	}
 	return true
 }
+
+// IsFinished reports whether the job completed, failed for good or was cancelled; a failed job
+// only runs again when it is retried
+func (j *Job) IsFinished() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// IsDeleted reports whether the job has been soft-deleted
+func (j *Job) IsDeleted() bool {
+	return j.DeletedAt != nil
+}
@@ -242,6 +242,58 @@ func TestJob_MarkAsCompleted(t *testing.T) {
 	assert.True(t, job.UpdatedAt.After(oldUpdateTime))
 }
 
+func TestJob_MarkAsCancelled(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			status Status
+		}
+		want struct {
+			status Status
+			err    error
+		}
+	}{
+		{
+			name: "Given a pending job, When cancelling it, Then should be cancelled with the reason",
+			in:   struct{ status Status }{status: StatusPending},
+			want: struct {
+				status Status
+				err    error
+			}{status: StatusCancelled},
+		},
+		{
+			name: "Given a processing job, When cancelling it, Then should be refused",
+			in:   struct{ status Status }{status: StatusProcessing},
+			want: struct {
+				status Status
+				err    error
+			}{status: StatusProcessing, err: ErrInvalidTransition},
+		},
+		{
+			name: "Given a completed job, When cancelling it, Then should be refused",
+			in:   struct{ status Status }{status: StatusCompleted},
+			want: struct {
+				status Status
+				err    error
+			}{status: StatusCompleted, err: ErrInvalidTransition},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Status: tt.in.status}
+
+			err := job.MarkAsCancelled("purged from queue emails")
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.status, job.Status)
+			if tt.want.err == nil {
+				assert.Equal(t, "purged from queue emails", job.Error)
+			}
+		})
+	}
+}
+
 func TestJob_MarkAsFailed(t *testing.T) {
 	tests := []struct {
 		name string
@@ -472,3 +524,475 @@ func TestJob_IsReady(t *testing.T) {
 		})
 	}
 }
+
+func TestJob_IsFinished(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			status Status
+		}
+		want struct {
+			finished bool
+		}
+	}{
+		{
+			name: "Given a completed job, When checking if finished, Then should return true",
+			in:   struct{ status Status }{status: StatusCompleted},
+			want: struct{ finished bool }{finished: true},
+		},
+		{
+			name: "Given a failed job, When checking if finished, Then should return true",
+			in:   struct{ status Status }{status: StatusFailed},
+			want: struct{ finished bool }{finished: true},
+		},
+		{
+			name: "Given a cancelled job, When checking if finished, Then should return true",
+			in:   struct{ status Status }{status: StatusCancelled},
+			want: struct{ finished bool }{finished: true},
+		},
+		{
+			name: "Given a retrying job, When checking if finished, Then should return false",
+			in:   struct{ status Status }{status: StatusRetrying},
+			want: struct{ finished bool }{finished: false},
+		},
+		{
+			name: "Given a processing job, When checking if finished, Then should return false",
+			in:   struct{ status Status }{status: StatusProcessing},
+			want: struct{ finished bool }{finished: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Status: tt.in.status}
+
+			assert.Equal(t, tt.want.finished, job.IsFinished())
+		})
+	}
+}
+
+func TestJob_NextRetryAt(t *testing.T) {
+	scheduled := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		in   struct {
+			status       Status
+			scheduledFor *time.Time
+		}
+		want *time.Time
+	}{
+		{
+			name: "Given a retrying job, When getting its next retry, Then should return when it is scheduled",
+			in: struct {
+				status       Status
+				scheduledFor *time.Time
+			}{status: StatusRetrying, scheduledFor: &scheduled},
+			want: &scheduled,
+		},
+		{
+			name: "Given a delayed pending job, When getting its next retry, Then should return nil",
+			in: struct {
+				status       Status
+				scheduledFor *time.Time
+			}{status: StatusPending, scheduledFor: &scheduled},
+		},
+		{
+			name: "Given a failed job, When getting its next retry, Then should return nil",
+			in: struct {
+				status       Status
+				scheduledFor *time.Time
+			}{status: StatusFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Status: tt.in.status, ScheduledFor: tt.in.scheduledFor}
+
+			assert.Equal(t, tt.want, job.NextRetryAt())
+		})
+	}
+}
+
+func TestJob_Promote(t *testing.T) {
+	now := time.Now().UTC()
+	pastTime := now.Add(-time.Minute)
+	futureTime := now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		in   struct {
+			status       Status
+			scheduledFor *time.Time
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given a delayed job that is due, When promoting it, Then should clear its schedule",
+			in: struct {
+				status       Status
+				scheduledFor *time.Time
+			}{status: StatusPending, scheduledFor: &pastTime},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given a delayed job that isn't due, When promoting it, Then should return ErrJobNotDue",
+			in: struct {
+				status       Status
+				scheduledFor *time.Time
+			}{status: StatusPending, scheduledFor: &futureTime},
+			want: struct{ err error }{err: ErrJobNotDue},
+		},
+		{
+			name: "Given a pending job without a schedule, When promoting it, Then should return ErrJobNotDue",
+			in: struct {
+				status       Status
+				scheduledFor *time.Time
+			}{status: StatusPending},
+			want: struct{ err error }{err: ErrJobNotDue},
+		},
+		{
+			name: "Given a retry scheduled by a worker, When promoting it, Then should return ErrJobNotDue",
+			in: struct {
+				status       Status
+				scheduledFor *time.Time
+			}{status: StatusRetrying, scheduledFor: &pastTime},
+			want: struct{ err error }{err: ErrJobNotDue},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Status: tt.in.status, ScheduledFor: tt.in.scheduledFor}
+
+			err := job.Promote(now)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+				assert.Equal(t, tt.in.scheduledFor, job.ScheduledFor)
+			} else {
+				assert.NoError(t, err)
+				assert.Nil(t, job.ScheduledFor)
+				assert.False(t, job.IsDelayed())
+				assert.Equal(t, now, job.UpdatedAt)
+			}
+		})
+	}
+}
+
+func TestJob_CheckEditable(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			status  Status
+			version int
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given a pending job at the expected version, When checking it is editable, Then should return no error",
+			in: struct {
+				status  Status
+				version int
+			}{status: StatusPending, version: 2},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given a retrying job at the expected version, When checking it is editable, Then should return no error",
+			in: struct {
+				status  Status
+				version int
+			}{status: StatusRetrying, version: 2},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given a job updated since the expected version, When checking it is editable, Then should return ErrVersionConflict",
+			in: struct {
+				status  Status
+				version int
+			}{status: StatusPending, version: 1},
+			want: struct{ err error }{err: ErrVersionConflict},
+		},
+		{
+			name: "Given a processing job, When checking it is editable, Then should return ErrJobNotEditable",
+			in: struct {
+				status  Status
+				version int
+			}{status: StatusProcessing, version: 2},
+			want: struct{ err error }{err: ErrJobNotEditable},
+		},
+		{
+			name: "Given a completed job, When checking it is editable, Then should return ErrJobNotEditable",
+			in: struct {
+				status  Status
+				version int
+			}{status: StatusCompleted, version: 2},
+			want: struct{ err error }{err: ErrJobNotEditable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Status: tt.in.status, Version: 2}
+
+			err := job.CheckEditable(tt.in.version)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJob_Edit(t *testing.T) {
+	now := time.Now().UTC()
+	pastTime := now.Add(-time.Minute)
+	futureTime := now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		in   struct {
+			status       Status
+			payload      []byte
+			scheduledFor *time.Time
+		}
+		want struct {
+			err          error
+			payload      []byte
+			scheduledFor *time.Time
+		}
+	}{
+		{
+			name: "Given a pending job, When editing only its payload, Then should replace the payload and keep the schedule",
+			in: struct {
+				status       Status
+				payload      []byte
+				scheduledFor *time.Time
+			}{status: StatusPending, payload: []byte(`{"to":"b"}`)},
+			want: struct {
+				err          error
+				payload      []byte
+				scheduledFor *time.Time
+			}{payload: []byte(`{"to":"b"}`), scheduledFor: &pastTime},
+		},
+		{
+			name: "Given a pending job, When rescheduling it to the future, Then should delay it",
+			in: struct {
+				status       Status
+				payload      []byte
+				scheduledFor *time.Time
+			}{status: StatusPending, scheduledFor: &futureTime},
+			want: struct {
+				err          error
+				payload      []byte
+				scheduledFor *time.Time
+			}{payload: []byte(`{"to":"a"}`), scheduledFor: &futureTime},
+		},
+		{
+			name: "Given a pending job, When rescheduling it to the past, Then should clear its schedule so it runs now",
+			in: struct {
+				status       Status
+				payload      []byte
+				scheduledFor *time.Time
+			}{status: StatusPending, scheduledFor: &pastTime},
+			want: struct {
+				err          error
+				payload      []byte
+				scheduledFor *time.Time
+			}{payload: []byte(`{"to":"a"}`)},
+		},
+		{
+			name: "Given a retrying job, When rescheduling it, Then should return ErrJobNotEditable",
+			in: struct {
+				status       Status
+				payload      []byte
+				scheduledFor *time.Time
+			}{status: StatusRetrying, scheduledFor: &futureTime},
+			want: struct {
+				err          error
+				payload      []byte
+				scheduledFor *time.Time
+			}{err: ErrJobNotEditable, payload: []byte(`{"to":"a"}`), scheduledFor: &pastTime},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled := pastTime
+			job := &Job{Status: tt.in.status, Payload: []byte(`{"to":"a"}`), ScheduledFor: &scheduled}
+
+			err := job.Edit(tt.in.payload, tt.in.scheduledFor, now)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, now, job.UpdatedAt)
+			}
+			assert.Equal(t, tt.want.payload, job.Payload)
+			assert.Equal(t, tt.want.scheduledFor, job.ScheduledFor)
+		})
+	}
+}
+
+func TestJob_SetCallbackURL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			url string
+		}
+		want struct {
+			err         error
+			callbackURL string
+		}
+	}{
+		{
+			name: "Given an https URL, When setting the callback, Then should store it",
+			in:   struct{ url string }{url: "https://example.com/hooks/jobs"},
+			want: struct {
+				err         error
+				callbackURL string
+			}{callbackURL: "https://example.com/hooks/jobs"},
+		},
+		{
+			name: "Given a relative URL, When setting the callback, Then should return ErrInvalidCallbackURL",
+			in:   struct{ url string }{url: "/hooks/jobs"},
+			want: struct {
+				err         error
+				callbackURL string
+			}{err: ErrInvalidCallbackURL},
+		},
+		{
+			name: "Given a non-http scheme, When setting the callback, Then should return ErrInvalidCallbackURL",
+			in:   struct{ url string }{url: "ftp://example.com/hooks"},
+			want: struct {
+				err         error
+				callbackURL string
+			}{err: ErrInvalidCallbackURL},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, _ := NewJob("default", "email", []byte(`{}`))
+
+			err := job.SetCallbackURL(tt.in.url)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.callbackURL, job.CallbackURL)
+		})
+	}
+}
+
+func TestJob_MarkAsThrottled(t *testing.T) {
+	// Given
+	job := &Job{
+		Status:    StatusProcessing,
+		Attempts:  1,
+		UpdatedAt: time.Now().Add(-1 * time.Hour),
+	}
+	oldUpdateTime := job.UpdatedAt
+
+	// When
+	job.MarkAsThrottled(errors.New("429 Too Many Requests"))
+
+	// Then
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "429 Too Many Requests", job.Error)
+	assert.True(t, job.UpdatedAt.After(oldUpdateTime))
+}
+
+func TestStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			from Status
+			to   Status
+		}
+		want struct {
+			allowed bool
+		}
+	}{
+		{
+			name: "Given a pending job, When moving to processing, Then should be allowed",
+			in: struct {
+				from Status
+				to   Status
+			}{from: StatusPending, to: StatusProcessing},
+			want: struct{ allowed bool }{allowed: true},
+		},
+		{
+			name: "Given a processing job, When moving to processing again, Then should be allowed for redelivery",
+			in: struct {
+				from Status
+				to   Status
+			}{from: StatusProcessing, to: StatusProcessing},
+			want: struct{ allowed bool }{allowed: true},
+		},
+		{
+			name: "Given a completed job, When moving back to processing, Then should be refused",
+			in: struct {
+				from Status
+				to   Status
+			}{from: StatusCompleted, to: StatusProcessing},
+			want: struct{ allowed bool }{allowed: false},
+		},
+		{
+			name: "Given a completed job, When moving to failed, Then should be refused",
+			in: struct {
+				from Status
+				to   Status
+			}{from: StatusCompleted, to: StatusFailed},
+			want: struct{ allowed bool }{allowed: false},
+		},
+		{
+			name: "Given a failed job, When moving to retrying, Then should be allowed",
+			in: struct {
+				from Status
+				to   Status
+			}{from: StatusFailed, to: StatusRetrying},
+			want: struct{ allowed bool }{allowed: true},
+		},
+		{
+			name: "Given a failed job, When moving straight to processing, Then should be refused",
+			in: struct {
+				from Status
+				to   Status
+			}{from: StatusFailed, to: StatusProcessing},
+			want: struct{ allowed bool }{allowed: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := tt.in.from.CanTransitionTo(tt.in.to)
+
+			assert.Equal(t, tt.want.allowed, allowed)
+		})
+	}
+}
+
+func TestPreviousStatuses(t *testing.T) {
+	assert.Equal(t, []Status{StatusPending, StatusProcessing, StatusRetrying}, PreviousStatuses(StatusProcessing))
+	assert.Equal(t, []Status{StatusCompleted, StatusProcessing}, PreviousStatuses(StatusCompleted))
+	assert.Equal(t, []Status{StatusFailed, StatusPending, StatusProcessing, StatusRetrying}, PreviousStatuses(StatusFailed))
+}
+
+func TestJob_MarkAsProcessing_Completed(t *testing.T) {
+	// Given
+	job := &Job{Status: StatusCompleted}
+
+	// When
+	err := job.MarkAsProcessing()
+
+	// Then
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	assert.Equal(t, StatusCompleted, job.Status)
+}
@@ -0,0 +1,76 @@
+package queue
+
+import "sort"
+
+// DurationBuckets are the upper bounds, in seconds, of the buckets execution durations are
+// counted in. Runs longer than the last bound fall in one more, unbounded bucket.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// DurationHistogram counts the execution durations of the jobs of one type in a queue
+type DurationHistogram struct {
+	Queue  string
+	Type   string
+	Counts []int64 // Runs per bucket of DurationBuckets, plus the unbounded bucket last
+	Sum    float64 // Seconds spent in every run
+}
+
+// NewDurationHistogram creates an empty histogram of the jobs of jobType in queueName
+func NewDurationHistogram(queueName, jobType string) *DurationHistogram {
+	return &DurationHistogram{Queue: queueName, Type: jobType, Counts: make([]int64, len(DurationBuckets)+1)}
+}
+
+// DurationBucket returns the index of the bucket a run of seconds falls in
+func DurationBucket(seconds float64) int {
+	return sort.SearchFloat64s(DurationBuckets, seconds)
+}
+
+// Observe counts one run of seconds
+func (h *DurationHistogram) Observe(seconds float64) {
+	h.Counts[DurationBucket(seconds)]++
+	h.Sum += seconds
+}
+
+// Count returns the runs counted
+func (h *DurationHistogram) Count() int64 {
+	var count int64
+	for _, c := range h.Counts {
+		count += c
+	}
+	return count
+}
+
+// Mean returns the average run in seconds, zero when no run was counted
+func (h *DurationHistogram) Mean() float64 {
+	count := h.Count()
+	if count == 0 {
+		return 0
+	}
+	return h.Sum / float64(count)
+}
+
+// Percentile estimates the duration in seconds that the fraction p of runs didn't exceed, by
+// interpolating within the bucket it falls in. Runs in the unbounded bucket are taken to have
+// lasted the last bound. It returns zero when no run was counted.
+func (h *DurationHistogram) Percentile(p float64) float64 {
+	count := h.Count()
+	if count == 0 {
+		return 0
+	}
+	rank := min(max(p, 0), 1) * float64(count)
+	var seen int64
+	for i, c := range h.Counts {
+		if c == 0 || float64(seen+c) < rank {
+			seen += c
+			continue
+		}
+		if i == len(DurationBuckets) {
+			return DurationBuckets[i-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = DurationBuckets[i-1]
+		}
+		return lower + (DurationBuckets[i]-lower)*(rank-float64(seen))/float64(c)
+	}
+	return DurationBuckets[len(DurationBuckets)-1]
+}
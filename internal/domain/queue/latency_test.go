@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationHistogram_Percentile(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			durations []float64
+			p         float64
+		}
+		want struct {
+			seconds float64
+		}
+	}{
+		{
+			name: "Given no runs, When estimating the median, Then should return zero",
+			in: struct {
+				durations []float64
+				p         float64
+			}{p: 0.5},
+			want: struct{ seconds float64 }{seconds: 0},
+		},
+		{
+			name: "Given four runs in the 0.5s to 1s bucket, When estimating the median, Then should interpolate halfway through the bucket",
+			in: struct {
+				durations []float64
+				p         float64
+			}{durations: []float64{0.6, 0.7, 0.8, 0.9}, p: 0.5},
+			want: struct{ seconds float64 }{seconds: 0.75},
+		},
+		{
+			name: "Given 99 fast runs and a slow one, When estimating p99, Then should stay in the fast bucket",
+			in: struct {
+				durations []float64
+				p         float64
+			}{durations: append(repeat(0.002, 99), 4), p: 0.99},
+			want: struct{ seconds float64 }{seconds: 0.005},
+		},
+		{
+			name: "Given runs longer than the last bound, When estimating p95, Then should return the last bound",
+			in: struct {
+				durations []float64
+				p         float64
+			}{durations: []float64{900, 1200}, p: 0.95},
+			want: struct{ seconds float64 }{seconds: 600},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			histogram := NewDurationHistogram("default", "email")
+			for _, d := range tt.in.durations {
+				histogram.Observe(d)
+			}
+
+			result := histogram.Percentile(tt.in.p)
+
+			assert.InDelta(t, tt.want.seconds, result, 1e-9)
+			assert.Equal(t, int64(len(tt.in.durations)), histogram.Count())
+		})
+	}
+}
+
+func TestDurationBucket(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			seconds float64
+		}
+		want struct {
+			bucket int
+		}
+	}{
+		{
+			name: "Given a run on a bucket's bound, When bucketing, Then should count it in that bucket",
+			in:   struct{ seconds float64 }{seconds: 1},
+			want: struct{ bucket int }{bucket: 7},
+		},
+		{
+			name: "Given a run just over a bound, When bucketing, Then should count it in the next bucket",
+			in:   struct{ seconds float64 }{seconds: 1.01},
+			want: struct{ bucket int }{bucket: 8},
+		},
+		{
+			name: "Given a run over the last bound, When bucketing, Then should count it in the unbounded bucket",
+			in:   struct{ seconds float64 }{seconds: 3600},
+			want: struct{ bucket int }{bucket: len(DurationBuckets)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DurationBucket(tt.in.seconds)
+
+			assert.Equal(t, tt.want.bucket, result)
+		})
+	}
+}
+
+// repeat returns n copies of seconds
+func repeat(seconds float64, n int) []float64 {
+	durations := make([]float64, n)
+	for i := range durations {
+		durations[i] = seconds
+	}
+	return durations
+}
@@ -0,0 +1,67 @@
+package queue
+
+import "errors"
+
+var (
+	// ErrInvalidStatus is returned when a status filter names no job status
+	ErrInvalidStatus = errors.New("invalid job status")
+	// ErrInvalidCreator is returned when a listing by creator names no creator
+	ErrInvalidCreator = errors.New("created_by is required")
+)
+
+// QueueListing describes a page of a queue's jobs, newest first. A listing by creator or
+// metadata may span all queues.
+type QueueListing struct {
+	Queue     string         // Empty only in a listing by creator or metadata
+	Status    Status         // Optional status filter
+	CreatedBy string         // Optional filter on the caller that created the jobs, see Owners
+	Metadata  MetadataFilter // Optional filter on the jobs' metadata
+	Limit     int
+	Offset    int
+}
+
+// NewQueueListing validates the queue and status filter and normalizes pagination like a search
+func NewQueueListing(queueName string, status Status, limit, offset int) (QueueListing, error) {
+	if queueName == "" {
+		return QueueListing{}, ErrInvalidQueue
+	}
+	return newListing(queueName, status, "", limit, offset)
+}
+
+// NewCreatorListing lists the jobs a caller created, in all queues unless queueName is set,
+// validating the status filter and normalizing pagination like NewQueueListing
+func NewCreatorListing(createdBy, queueName string, status Status, limit, offset int) (QueueListing, error) {
+	if createdBy == "" {
+		return QueueListing{}, ErrInvalidCreator
+	}
+	return newListing(queueName, status, createdBy, limit, offset)
+}
+
+// NewMetadataListing lists the jobs whose metadata passes the filter, in all queues unless
+// queueName is set, validating the status filter and normalizing pagination like NewQueueListing
+func NewMetadataListing(filter MetadataFilter, queueName string, status Status, limit, offset int) (QueueListing, error) {
+	if filter.IsZero() {
+		return QueueListing{}, ErrInvalidMetadataFilter
+	}
+	listing, err := newListing(queueName, status, "", limit, offset)
+	if err != nil {
+		return QueueListing{}, err
+	}
+	listing.Metadata = filter
+	return listing, nil
+}
+
+func newListing(queueName string, status Status, createdBy string, limit, offset int) (QueueListing, error) {
+	if _, known := transitions[status]; status != "" && !known {
+		return QueueListing{}, ErrInvalidStatus
+	}
+
+	limit, offset = pageBounds(limit, offset)
+	return QueueListing{
+		Queue:     queueName,
+		Status:    status,
+		CreatedBy: createdBy,
+		Limit:     limit,
+		Offset:    offset,
+	}, nil
+}
@@ -0,0 +1,221 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewQueueListing(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			queue  string
+			status Status
+			limit  int
+			offset int
+		}
+		want struct {
+			listing QueueListing
+			err     error
+		}
+	}{
+		{
+			name: "Given a queue and no pagination, When creating a listing, Then should apply the default limit",
+			in: struct {
+				queue  string
+				status Status
+				limit  int
+				offset int
+			}{queue: "emails"},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{listing: QueueListing{Queue: "emails", Limit: DefaultSearchLimit}},
+		},
+		{
+			name: "Given a status filter, a limit above the maximum and a negative offset, When creating a listing, Then should keep the status and clamp both",
+			in: struct {
+				queue  string
+				status Status
+				limit  int
+				offset int
+			}{queue: "emails", status: StatusFailed, limit: 1000, offset: -5},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{listing: QueueListing{Queue: "emails", Status: StatusFailed, Limit: MaxSearchLimit}},
+		},
+		{
+			name: "Given no queue, When creating a listing, Then should return ErrInvalidQueue",
+			in: struct {
+				queue  string
+				status Status
+				limit  int
+				offset int
+			}{},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{err: ErrInvalidQueue},
+		},
+		{
+			name: "Given an unknown status, When creating a listing, Then should return ErrInvalidStatus",
+			in: struct {
+				queue  string
+				status Status
+				limit  int
+				offset int
+			}{queue: "emails", status: "done"},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{err: ErrInvalidStatus},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, err := NewQueueListing(tt.in.queue, tt.in.status, tt.in.limit, tt.in.offset)
+
+			assert.Equal(t, tt.want.err, err)
+			assert.Equal(t, tt.want.listing, listing)
+		})
+	}
+}
+
+func TestNewCreatorListing(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			createdBy string
+			queue     string
+			status    Status
+		}
+		want struct {
+			listing QueueListing
+			err     error
+		}
+	}{
+		{
+			name: "Given a creator and no queue, When creating a listing, Then should list all queues with the default limit",
+			in: struct {
+				createdBy string
+				queue     string
+				status    Status
+			}{createdBy: "team-a"},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{listing: QueueListing{CreatedBy: "team-a", Limit: DefaultSearchLimit}},
+		},
+		{
+			name: "Given a creator, a queue and a status, When creating a listing, Then should keep all filters",
+			in: struct {
+				createdBy string
+				queue     string
+				status    Status
+			}{createdBy: "team-a", queue: "emails", status: StatusFailed},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{listing: QueueListing{Queue: "emails", Status: StatusFailed, CreatedBy: "team-a", Limit: DefaultSearchLimit}},
+		},
+		{
+			name: "Given no creator, When creating a listing, Then should return ErrInvalidCreator",
+			in: struct {
+				createdBy string
+				queue     string
+				status    Status
+			}{queue: "emails"},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{err: ErrInvalidCreator},
+		},
+		{
+			name: "Given an unknown status, When creating a listing, Then should return ErrInvalidStatus",
+			in: struct {
+				createdBy string
+				queue     string
+				status    Status
+			}{createdBy: "team-a", status: "done"},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{err: ErrInvalidStatus},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, err := NewCreatorListing(tt.in.createdBy, tt.in.queue, tt.in.status, 0, 0)
+
+			assert.Equal(t, tt.want.err, err)
+			assert.Equal(t, tt.want.listing, listing)
+		})
+	}
+}
+
+func TestNewMetadataListing(t *testing.T) {
+	filter := MetadataFilter{Values: map[string]string{"source": "billing"}}
+
+	tests := []struct {
+		name string
+		in   struct {
+			filter MetadataFilter
+			queue  string
+			status Status
+		}
+		want struct {
+			listing QueueListing
+			err     error
+		}
+	}{
+		{
+			name: "Given a metadata filter and no queue, When creating a listing, Then should list all queues with the default limit",
+			in: struct {
+				filter MetadataFilter
+				queue  string
+				status Status
+			}{filter: filter},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{listing: QueueListing{Metadata: filter, Limit: DefaultSearchLimit}},
+		},
+		{
+			name: "Given a zero metadata filter, When creating a listing, Then should return ErrInvalidMetadataFilter",
+			in: struct {
+				filter MetadataFilter
+				queue  string
+				status Status
+			}{queue: "emails"},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{err: ErrInvalidMetadataFilter},
+		},
+		{
+			name: "Given an unknown status, When creating a listing, Then should return ErrInvalidStatus",
+			in: struct {
+				filter MetadataFilter
+				queue  string
+				status Status
+			}{filter: filter, status: "done"},
+			want: struct {
+				listing QueueListing
+				err     error
+			}{err: ErrInvalidStatus},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, err := NewMetadataListing(tt.in.filter, tt.in.queue, tt.in.status, 0, 0)
+
+			assert.Equal(t, tt.want.err, err)
+			assert.Equal(t, tt.want.listing, listing)
+		})
+	}
+}
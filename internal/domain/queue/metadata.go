@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Bounds of the metadata a job may carry
+const (
+	MaxMetadataEntries     = 32
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 512
+)
+
+var (
+	ErrInvalidMetadata       = errors.New("invalid job metadata, expected up to 32 keys like correlation_id or trace-parent with values of at most 512 bytes")
+	ErrInvalidMetadataFilter = errors.New("invalid metadata filter, expected a key like source or a pair like source:billing")
+)
+
+var metadataKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// NormalizeMetadata validates the metadata of a job, e.g. correlation IDs, the system it came
+// from or trace context, and returns it with lowercased keys; empty metadata is nil
+func NormalizeMetadata(metadata map[string]string) (map[string]string, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	if len(metadata) > MaxMetadataEntries {
+		return nil, fmt.Errorf("%w: %d keys", ErrInvalidMetadata, len(metadata))
+	}
+	normalized := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if err := validateMetadataKey(key); err != nil {
+			return nil, err
+		}
+		if len(value) > MaxMetadataValueLength {
+			return nil, fmt.Errorf("%w: value of %q is %d bytes", ErrInvalidMetadata, key, len(value))
+		}
+		if _, duplicate := normalized[key]; duplicate {
+			return nil, fmt.Errorf("%w: %q is set twice", ErrInvalidMetadata, key)
+		}
+		normalized[key] = value
+	}
+	return normalized, nil
+}
+
+func validateMetadataKey(key string) error {
+	if len(key) > MaxMetadataKeyLength || !metadataKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q", ErrInvalidMetadata, key)
+	}
+	return nil
+}
+
+// SetMetadata sets the metadata the job carries apart from its payload
+func (j *Job) SetMetadata(metadata map[string]string) error {
+	normalized, err := NormalizeMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	j.Metadata = normalized
+	return nil
+}
+
+// MetadataKeys returns the keys of the job's metadata, sorted
+func (j *Job) MetadataKeys() []string {
+	return slices.Sorted(maps.Keys(j.Metadata))
+}
+
+// MetadataFilter keeps the jobs whose metadata has every key, with the given value when one is
+type MetadataFilter struct {
+	Keys   []string          // Keys the metadata must have, whatever their value; sorted
+	Values map[string]string // Entries the metadata must contain
+}
+
+// ParseMetadataFilter parses filters like "source", which the metadata must have, and
+// "source:billing", which it must have with that value
+func ParseMetadataFilter(filters []string) (MetadataFilter, error) {
+	var filter MetadataFilter
+	for _, raw := range filters {
+		key, value, withValue := strings.Cut(raw, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if validateMetadataKey(key) != nil {
+			return MetadataFilter{}, fmt.Errorf("%w: %q", ErrInvalidMetadataFilter, raw)
+		}
+		if !withValue {
+			filter.Keys = append(filter.Keys, key)
+			continue
+		}
+		if filter.Values == nil {
+			filter.Values = make(map[string]string)
+		}
+		if previous, ok := filter.Values[key]; ok && previous != value {
+			return MetadataFilter{}, fmt.Errorf("%w: %q is filtered on two values", ErrInvalidMetadataFilter, key)
+		}
+		filter.Values[key] = value
+	}
+	slices.Sort(filter.Keys)
+	filter.Keys = slices.Compact(filter.Keys)
+	return filter, nil
+}
+
+// IsZero reports whether the filter keeps every job
+func (f MetadataFilter) IsZero() bool {
+	return len(f.Keys) == 0 && len(f.Values) == 0
+}
+
+// Matches reports whether the metadata passes the filter
+func (f MetadataFilter) Matches(metadata map[string]string) bool {
+	for _, key := range f.Keys {
+		if _, ok := metadata[key]; !ok {
+			return false
+		}
+	}
+	for key, want := range f.Values {
+		if value, ok := metadata[key]; !ok || value != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMetadata(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := range MaxMetadataEntries + 1 {
+		tooMany[fmt.Sprintf("key-%d", i)] = "value"
+	}
+
+	tests := []struct {
+		name string
+		in   map[string]string
+		want struct {
+			metadata map[string]string
+			err      error
+		}
+	}{
+		{
+			name: "Given mixed case keys, When normalizing, Then should lowercase them and keep the values",
+			in:   map[string]string{" Correlation_ID ": "abc-123", "traceparent": "00-4bf9-01"},
+			want: struct {
+				metadata map[string]string
+				err      error
+			}{metadata: map[string]string{"correlation_id": "abc-123", "traceparent": "00-4bf9-01"}},
+		},
+		{
+			name: "Given no metadata, When normalizing, Then should return nil",
+			in:   map[string]string{},
+		},
+		{
+			name: "Given a key with a space, When normalizing, Then should return ErrInvalidMetadata",
+			in:   map[string]string{"source system": "billing"},
+			want: struct {
+				metadata map[string]string
+				err      error
+			}{err: ErrInvalidMetadata},
+		},
+		{
+			name: "Given keys only differing by case, When normalizing, Then should return ErrInvalidMetadata",
+			in:   map[string]string{"source": "billing", "Source": "crm"},
+			want: struct {
+				metadata map[string]string
+				err      error
+			}{err: ErrInvalidMetadata},
+		},
+		{
+			name: "Given a value above the maximum length, When normalizing, Then should return ErrInvalidMetadata",
+			in:   map[string]string{"note": strings.Repeat("x", MaxMetadataValueLength+1)},
+			want: struct {
+				metadata map[string]string
+				err      error
+			}{err: ErrInvalidMetadata},
+		},
+		{
+			name: "Given more keys than allowed, When normalizing, Then should return ErrInvalidMetadata",
+			in:   tooMany,
+			want: struct {
+				metadata map[string]string
+				err      error
+			}{err: ErrInvalidMetadata},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := NormalizeMetadata(tt.in)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.metadata, metadata)
+		})
+	}
+}
+
+func TestParseMetadataFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want struct {
+			filter MetadataFilter
+			err    error
+		}
+	}{
+		{
+			name: "Given keys and pairs, When parsing, Then should split them into required keys and values",
+			in:   []string{"Trace_ID", "source:billing", "trace_id", "url:https://example.com"},
+			want: struct {
+				filter MetadataFilter
+				err    error
+			}{filter: MetadataFilter{
+				Keys:   []string{"trace_id"},
+				Values: map[string]string{"source": "billing", "url": "https://example.com"},
+			}},
+		},
+		{
+			name: "Given no filters, When parsing, Then should return a zero filter",
+			want: struct {
+				filter MetadataFilter
+				err    error
+			}{},
+		},
+		{
+			name: "Given an empty key, When parsing, Then should return ErrInvalidMetadataFilter",
+			in:   []string{":billing"},
+			want: struct {
+				filter MetadataFilter
+				err    error
+			}{err: ErrInvalidMetadataFilter},
+		},
+		{
+			name: "Given one key filtered on two values, When parsing, Then should return ErrInvalidMetadataFilter",
+			in:   []string{"source:billing", "source:crm"},
+			want: struct {
+				filter MetadataFilter
+				err    error
+			}{err: ErrInvalidMetadataFilter},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseMetadataFilter(tt.in)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.filter, filter)
+		})
+	}
+}
+
+func TestMetadataFilter_Matches(t *testing.T) {
+	metadata := map[string]string{"source": "billing", "trace_id": "abc"}
+
+	tests := []struct {
+		name string
+		in   MetadataFilter
+		want bool
+	}{
+		{
+			name: "Given a zero filter, When matching, Then should keep the job",
+			want: true,
+		},
+		{
+			name: "Given a key and a value the metadata has, When matching, Then should keep the job",
+			in:   MetadataFilter{Keys: []string{"trace_id"}, Values: map[string]string{"source": "billing"}},
+			want: true,
+		},
+		{
+			name: "Given a key the metadata lacks, When matching, Then should drop the job",
+			in:   MetadataFilter{Keys: []string{"tenant"}},
+		},
+		{
+			name: "Given another value, When matching, Then should drop the job",
+			in:   MetadataFilter{Values: map[string]string{"source": "crm"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.in.Matches(metadata))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRangeNotSatisfiable is returned for an output range starting past the end of the output
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// OutputRange selects part of a job's output, as an HTTP byte range does
+type OutputRange struct {
+	Start  int64 // First byte; ignored when Suffix is set
+	End    int64 // Last byte, inclusive; negative reads to the end
+	Suffix int64 // When positive, selects the last Suffix bytes
+}
+
+// Resolve returns the first byte and the length of the range within an output of size bytes.
+// A range starting at or past the end fails with ErrRangeNotSatisfiable, except on an empty
+// output, so tailing a job that hasn't written anything yet isn't an error.
+func (r OutputRange) Resolve(size int64) (start, length int64, err error) {
+	if r.Suffix > 0 {
+		start = max(size-r.Suffix, 0)
+		return start, size - start, nil
+	}
+	if r.Start < 0 || (r.End >= 0 && r.End < r.Start) {
+		return 0, 0, fmt.Errorf("%w: invalid range %d-%d", ErrRangeNotSatisfiable, r.Start, r.End)
+	}
+	if r.Start >= size && !(r.Start == 0 && size == 0) {
+		return 0, 0, fmt.Errorf("%w: output has %d bytes", ErrRangeNotSatisfiable, size)
+	}
+	end := size - 1
+	if r.End >= 0 && r.End < end {
+		end = r.End
+	}
+	return r.Start, end - r.Start + 1, nil
+}
+
+// JobOutput is part of the output a job streamed while running
+type JobOutput struct {
+	Job   *Job
+	Data  []byte
+	Start int64 // Offset of Data within the output
+	Size  int64 // Size of the whole output so far
+}
@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputRange_Resolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		rng        OutputRange
+		size       int64
+		wantStart  int64
+		wantLength int64
+		wantErr    error
+	}{
+		{name: "Given a whole range, When resolving, Then should cover the output", rng: OutputRange{End: -1}, size: 100, wantLength: 100},
+		{name: "Given an end past the output, When resolving, Then should stop at its end", rng: OutputRange{Start: 90, End: 199}, size: 100, wantStart: 90, wantLength: 10},
+		{name: "Given a suffix longer than the output, When resolving, Then should cover the output", rng: OutputRange{Suffix: 500}, size: 100, wantLength: 100},
+		{name: "Given an empty output, When resolving from the start, Then should return nothing", rng: OutputRange{End: -1}, size: 0},
+		{name: "Given a start at the end, When resolving, Then should not be satisfiable", rng: OutputRange{Start: 100, End: -1}, size: 100, wantErr: ErrRangeNotSatisfiable},
+		{name: "Given a reversed range, When resolving, Then should not be satisfiable", rng: OutputRange{Start: 10, End: 5}, size: 100, wantErr: ErrRangeNotSatisfiable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, length, err := tt.rng.Resolve(tt.size)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantLength, length)
+		})
+	}
+}
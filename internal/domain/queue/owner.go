@@ -0,0 +1,17 @@
+package queue
+
+// Owners names the callers jobs are attributed to, by API key. Keys without a name are
+// attributed to their hashed key ID, so the API key itself is never stored with a job.
+type Owners map[string]string
+
+// Of returns the created_by recorded on the jobs created with the API key: its configured
+// name, else the key ID SigningKeyID derives from it. Jobs created without a key have no owner.
+func (o Owners) Of(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	if name, ok := o[apiKey]; ok && name != "" {
+		return name
+	}
+	return SigningKeyID(apiKey)
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwners_Of(t *testing.T) {
+	owners := Owners{"secret-a": "team-a", "secret-b": ""}
+
+	tests := []struct {
+		name string
+		in   struct {
+			apiKey string
+		}
+		want struct {
+			createdBy string
+		}
+	}{
+		{
+			name: "Given a named API key, When attributing a job, Then should return its name",
+			in:   struct{ apiKey string }{apiKey: "secret-a"},
+			want: struct{ createdBy string }{createdBy: "team-a"},
+		},
+		{
+			name: "Given an unnamed API key, When attributing a job, Then should return its key ID rather than the key",
+			in:   struct{ apiKey string }{apiKey: "secret-c"},
+			want: struct{ createdBy string }{createdBy: SigningKeyID("secret-c")},
+		},
+		{
+			name: "Given an API key with an empty name, When attributing a job, Then should return its key ID",
+			in:   struct{ apiKey string }{apiKey: "secret-b"},
+			want: struct{ createdBy string }{createdBy: SigningKeyID("secret-b")},
+		},
+		{
+			name: "Given no API key, When attributing a job, Then should return no owner",
+			in:   struct{ apiKey string }{},
+			want: struct{ createdBy string }{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.createdBy, owners.Of(tt.in.apiKey))
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+)
+
+// MarkAsParked sets aside a failed job whose retry its queue's retry budget denied, keeping its
+// error, until the downstream it failed on recovers and the job is released
+func (j *Job) MarkAsParked() error {
+	if j.Status != StatusFailed {
+		return fmt.Errorf("%w: %s job can't be parked", ErrInvalidTransition, j.Status)
+	}
+	return j.transition(StatusParked)
+}
+
+// Unpark retries a parked job now; it keeps the attempts it used
+func (j *Job) Unpark(now time.Time) error {
+	if j.Status != StatusParked {
+		return fmt.Errorf("%w: %s job isn't parked", ErrInvalidTransition, j.Status)
+	}
+	if err := j.transition(StatusRetrying); err != nil {
+		return err
+	}
+	j.ScheduledFor = nil
+	j.UpdatedAt = now
+	return nil
+}
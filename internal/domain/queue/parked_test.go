@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob_ParkAndUnpark(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			status Status
+		}
+		want struct {
+			parkErr error
+		}
+	}{
+		{
+			name: "Given a failed job, When parking and unparking it, Then should be retrying with its attempts",
+			in:   struct{ status Status }{status: StatusFailed},
+		},
+		{
+			name: "Given a retrying job, When parking it, Then should be refused",
+			in:   struct{ status Status }{status: StatusRetrying},
+			want: struct{ parkErr error }{parkErr: ErrInvalidTransition},
+		},
+		{
+			name: "Given a parked job, When parking it again, Then should be refused",
+			in:   struct{ status Status }{status: StatusParked},
+			want: struct{ parkErr error }{parkErr: ErrInvalidTransition},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryAt := now.Add(time.Minute)
+			job := &Job{Status: tt.in.status, Attempts: 2, Error: "connection refused", ScheduledFor: &retryAt}
+
+			err := job.MarkAsParked()
+
+			assert.ErrorIs(t, err, tt.want.parkErr)
+			if tt.want.parkErr != nil {
+				assert.Equal(t, tt.in.status, job.Status)
+				if tt.in.status != StatusParked {
+					assert.ErrorIs(t, job.Unpark(now), ErrInvalidTransition)
+				}
+				return
+			}
+			assert.Equal(t, StatusParked, job.Status)
+			assert.Equal(t, "connection refused", job.Error)
+			assert.False(t, job.IsFinished())
+
+			assert.NoError(t, job.Unpark(now))
+			assert.Equal(t, StatusRetrying, job.Status)
+			assert.Equal(t, 2, job.Attempts)
+			assert.Nil(t, job.ScheduledFor)
+			assert.True(t, job.IsReady())
+		})
+	}
+}
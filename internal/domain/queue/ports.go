@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,13 +12,35 @@ import (
 type JobRepository interface {
 	Create(ctx context.Context, job *Job) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Job, error)
+	// GetByIDForUpdate reads a job and locks it until the unit of work in ctx ends, so it can
+	// be read, changed and written back without a concurrent update in between. Outside a
+	// unit of work it is GetByID.
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*Job, error)
 	Update(ctx context.Context, job *Job) error
+	// Delete soft-deletes a job; soft-deleted jobs are excluded from listings and counts
 	Delete(ctx context.Context, id uuid.UUID) error
+	// Undelete restores a soft-deleted job
+	Undelete(ctx context.Context, id uuid.UUID) error
+	// PurgeDeleted permanently removes jobs soft-deleted before the given time
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
 
 	// Query methods
 	FindPendingJobs(ctx context.Context, queue string, limit int) ([]*Job, error)
+	// FindDueDelayedJobs returns up to limit delayed jobs of any queue scheduled for now or
+	// earlier, the longest overdue first
+	FindDueDelayedJobs(ctx context.Context, now time.Time, limit int) ([]*Job, error)
 	FindByStatus(ctx context.Context, status Status, limit int) ([]*Job, error)
 	CountByStatus(ctx context.Context, status Status) (int64, error)
+	// CountDelayed counts the delayed jobs scheduled after now, which the queue backend doesn't
+	// hold yet
+	CountDelayed(ctx context.Context, now time.Time) (int64, error)
+	// Search returns jobs matching the criteria ordered by relevance, plus the total match count
+	Search(ctx context.Context, criteria SearchCriteria) ([]*Job, int64, error)
+	// ListByQueue returns a page of a queue's jobs, newest first, plus the total count
+	ListByQueue(ctx context.Context, listing QueueListing) ([]*Job, int64, error)
+	// Activity summarizes jobs completed and failed since the given time, with up to
+	// topTypes of the job types failing most
+	Activity(ctx context.Context, since time.Time, topTypes int) (*Activity, error)
 
 	// Dead letter queue
 	GetDLQJobs(ctx context.Context, limit, offset int) ([]*Job, error)
@@ -25,18 +48,239 @@ type JobRepository interface {
 	CountDLQJobs(ctx context.Context) (int64, error)
 }
 
+// PayloadRetentionRepository clears the data of old jobs for the retention janitor
+type PayloadRetentionRepository interface {
+	// ClearPayloads removes the payload and result of up to expiry.Limit completed jobs the
+	// expiry selects, keeping the jobs, and returns how many it cleared
+	ClearPayloads(ctx context.Context, expiry PayloadExpiry) (int64, error)
+}
+
+// HeldJobRepository finds the jobs parked by workers that didn't know their schema version
+type HeldJobRepository interface {
+	// FindHeld returns up to limit held jobs of the queue with a schema version of at most
+	// maxVersion, oldest first
+	FindHeld(ctx context.Context, queueName string, maxVersion int, limit int) ([]*Job, error)
+}
+
+// ParkedJobRepository finds the jobs parked while their queue's retry budget was exhausted
+type ParkedJobRepository interface {
+	// FindParked returns up to limit parked jobs of the queue, oldest first, locked until the
+	// unit of work in ctx ends; rows another transaction holds are skipped
+	FindParked(ctx context.Context, queueName string, limit int) ([]*Job, error)
+}
+
+// BatchJobUpdater writes several jobs in one round trip, each as Update would write it
+type BatchJobUpdater interface {
+	// UpdateBatch returns the error of each job, in the order of jobs
+	UpdateBatch(ctx context.Context, jobs []*Job) []error
+}
+
 // QueueService defines the interface for queue operations
 // This will be used by workers to dequeue jobs
 type QueueService interface {
 	Enqueue(ctx context.Context, job *Job) error
-	Dequeue(ctx context.Context, queueName string) (*Job, error)
+	// Dequeue waits up to timeout for a job whose required capabilities are all among the
+	// given ones; it returns nil when none is available
+	Dequeue(ctx context.Context, queueName string, capabilities []string, timeout time.Duration) (*Job, error)
 	Acknowledge(ctx context.Context, jobID uuid.UUID) error
+	// Nack returns a dequeued job to its queue for redelivery
+	Nack(ctx context.Context, job *Job) error
+	// DeliveryStats reports acknowledgement counts for every known queue
+	DeliveryStats(ctx context.Context) ([]*DeliveryStats, error)
+}
+
+// BatchAcknowledger acknowledges several dequeued jobs in one round trip
+type BatchAcknowledger interface {
+	AcknowledgeBatch(ctx context.Context, jobIDs []uuid.UUID) error
+}
+
+// DeliveryRecoverer hands the jobs of workers that stopped without acknowledging or returning
+// them to other workers
+type DeliveryRecoverer interface {
+	// RecoverExpired returns the jobs dequeued longer than the visibility timeout ago to their
+	// queues, reporting how many
+	RecoverExpired(ctx context.Context) (int, error)
+}
+
+// QueueInspector reads what the queue backend holds, to check it against the database
+type QueueInspector interface {
+	// Snapshot returns the IDs of the jobs waiting in the queue or dequeued and not yet acknowledged
+	Snapshot(ctx context.Context, queueName string) (QueueSnapshot, error)
+}
+
+// QueueWithdrawer takes jobs back out of the queue backend before a worker dequeues them
+type QueueWithdrawer interface {
+	// Withdraw removes the job from its queue, reporting whether it was waiting there; a job a
+	// worker already dequeued, or that isn't queued at all, is left alone
+	Withdraw(ctx context.Context, job *Job) (bool, error)
+}
+
+// DeadLetterQueue keeps the jobs that failed permanently at the queue backend, in a list per
+// queue next to their failed status in the database, so they can be inspected and replayed
+type DeadLetterQueue interface {
+	// DeadLetter acknowledges a dequeued job and appends it to its queue's dead letters
+	DeadLetter(ctx context.Context, job *Job) error
+	// PeekDeadLetters returns up to limit of the queue's dead letters, oldest first, leaving them
+	// in place; a limit of 0 or less returns them all
+	PeekDeadLetters(ctx context.Context, queueName string, limit int) ([]*Job, error)
+	// PopDeadLetters removes and returns up to limit of the queue's oldest dead letters
+	PopDeadLetters(ctx context.Context, queueName string, limit int) ([]*Job, error)
+	// RestoreDeadLetters puts popped dead letters back at the front of the queue's dead letters
+	RestoreDeadLetters(ctx context.Context, queueName string, jobs []*Job) error
+	// CountDeadLetters counts the queue's dead letters
+	CountDeadLetters(ctx context.Context, queueName string) (int64, error)
+}
+
+// ReadySignal wakes workers as soon as jobs become ready in a queue,
+// so they don't have to wait for the next poll
+type ReadySignal interface {
+	// Ready returns a channel that receives whenever jobs become ready in the queue
+	Ready(queueName string) <-chan struct{}
+}
+
+// DefinitionRepository stores queue definitions
+type DefinitionRepository interface {
+	// Create fails with ErrQueueDefined when the queue already has a definition
+	Create(ctx context.Context, def *Definition) error
+	// Get fails with ErrQueueNotDefined when the queue has no definition
+	Get(ctx context.Context, name string) (*Definition, error)
+	// List returns every definition ordered by name
+	List(ctx context.Context) ([]*Definition, error)
+	Update(ctx context.Context, def *Definition) error
+	Delete(ctx context.Context, name string) error
+}
+
+// GroupRepository stores job groups
+type GroupRepository interface {
+	Create(ctx context.Context, group *Group) error
+	// Get fails with ErrGroupNotFound when there is no such group
+	Get(ctx context.Context, id uuid.UUID) (*Group, error)
+	// Progress counts the group's jobs by status; soft-deleted jobs that didn't complete count as failed
+	Progress(ctx context.Context, id uuid.UUID) (GroupProgress, error)
+	// Resize changes the size of a group, e.g. when not all of its jobs could be created
+	Resize(ctx context.Context, id uuid.UUID, size int) error
+	// Finish marks the group finished and releases its completion job once as many of its jobs
+	// as its size completed, failed or were deleted. It reports whether this call finished the
+	// group, so concurrent calls finish it once.
+	Finish(ctx context.Context, id uuid.UUID, now time.Time) (bool, error)
+}
+
+// QueueCounter counts the live jobs of every queue
+type QueueCounter interface {
+	// CountByQueue returns the current job counts of every queue that has jobs, unstamped
+	CountByQueue(ctx context.Context) ([]*QueueStats, error)
+}
+
+// QueueStatsRepository stores periodic samples of per-queue job counts
+type QueueStatsRepository interface {
+	QueueCounter
+	// Record stores samples; recording a queue twice for the same time keeps the latest counts
+	Record(ctx context.Context, stats []*QueueStats) error
+	// History returns a queue's samples taken since the given time, oldest first
+	History(ctx context.Context, queue string, since time.Time) ([]*QueueStats, error)
+	// Prune removes samples taken before the given time
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LeaderElector lets one of several queue-core instances run a singleton loop, such as
+// promoting delayed jobs. Leadership is a lease that lapses unless it is renewed.
+type LeaderElector interface {
+	// Campaign takes the named lease for the holder, or renews it when the holder already has
+	// it, for ttl; it reports whether the holder leads
+	Campaign(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// Resign gives up the named lease if the holder has it
+	Resign(ctx context.Context, name, holder string) error
 }
 
 // MetricsService defines the interface for metrics collection
 type MetricsService interface {
 	RecordJobCreated(queue, jobType string)
+	// RecordJobCompleted counts a completion that ran for duration seconds. A duration of zero
+	// is unknown and left out of the duration histograms.
 	RecordJobCompleted(queue, jobType string, duration float64)
 	RecordJobFailed(queue, jobType string)
+	// RecordJobFailedWithReason counts a failure under its category, e.g. FailureTimeout
+	RecordJobFailedWithReason(queue, jobType, category string)
 	RecordJobRetried(queue, jobType string)
+	// RecordJobParked counts a failed job parked because its queue's retry budget was exhausted
+	RecordJobParked(queue, jobType string)
+}
+
+// UnitOfWork runs several repository calls as one transaction
+type UnitOfWork interface {
+	// Do runs fn in a transaction, committed when fn returns nil and rolled back otherwise.
+	// Repository calls made with the ctx fn receives join the transaction; a Do nested in fn
+	// joins it too. Side effects outside the database, like enqueueing, belong after Do.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// NopUnitOfWork runs fn without a transaction; it is the default until a database is wired in
+type NopUnitOfWork struct{}
+
+func (NopUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// MetricsReader reads back the counters a MetricsService recorded
+type MetricsReader interface {
+	// JobCounters returns every counter; failures are counted per category, with
+	// RecordJobFailed counting under FailureUnknown
+	JobCounters(ctx context.Context) ([]*JobCounter, error)
+	// JobDurations returns the execution duration histogram of every queue and job type
+	JobDurations(ctx context.Context) ([]*DurationHistogram, error)
+}
+
+// CompletionWaiter tells when jobs finish, whichever process ran them
+type CompletionWaiter interface {
+	// Wait returns a channel closed once the job completed or failed for good, and a func
+	// releasing the wait; it must be called whether or not the job finished
+	Wait(jobID uuid.UUID) (<-chan struct{}, func())
+}
+
+// AttemptRepository keeps every execution of a job, so its history outlives the last error
+type AttemptRepository interface {
+	RecordAttempt(ctx context.Context, attempt *Attempt) error
+	// RecordRetry stores the backoff after which the job's failed attempt with the given number
+	// was retried, and when the retry was due; it's a no-op when the attempt wasn't recorded
+	RecordRetry(ctx context.Context, jobID uuid.UUID, number int, backoff time.Duration, retryAt time.Time) error
+	// ListAttempts returns the job's last limit attempts, oldest first
+	ListAttempts(ctx context.Context, jobID uuid.UUID, limit int) ([]*Attempt, error)
+}
+
+// OutputStore keeps the output jobs stream while they run, so it can be tailed before they finish
+type OutputStore interface {
+	// AppendOutput stores a chunk written at offset within the job's output
+	AppendOutput(ctx context.Context, jobID uuid.UUID, offset int64, data []byte) error
+	// OutputSize returns how many bytes of output the job has, zero when it has none
+	OutputSize(ctx context.Context, jobID uuid.UUID) (int64, error)
+	// ReadOutput returns up to length bytes of the job's output from offset
+	ReadOutput(ctx context.Context, jobID uuid.UUID, offset, length int64) ([]byte, error)
+	// ClearOutput removes the job's output, before a run writes it afresh
+	ClearOutput(ctx context.Context, jobID uuid.UUID) error
+}
+
+// QueuePurger removes jobs from the queue backend in one step, so a flooded queue can be emptied
+type QueuePurger interface {
+	// BeginPurge starts purging the queue. The purge may read the queue once and keep what it
+	// learned across its batches, so it only lasts as long as one purge.
+	BeginPurge(ctx context.Context, queueName string) (QueuePurge, error)
+}
+
+// QueuePurge removes the batches of jobs of one purge from the queue backend
+type QueuePurge interface {
+	// Purge removes the jobs waiting in their queue all at once and returns the IDs of those it
+	// removed; jobs a worker dequeued meanwhile are left alone
+	Purge(ctx context.Context, jobs []*Job) ([]uuid.UUID, error)
+}
+
+// PurgeRepository selects and cancels the jobs purged from a queue
+type PurgeRepository interface {
+	// CountPurgeable counts the queue's jobs of the status that aren't deleted
+	CountPurgeable(ctx context.Context, queueName string, status Status) (int64, error)
+	// FindPurgeable returns up to limit of the queue's jobs of the status, oldest first, locked
+	// until the unit of work in ctx ends; rows another transaction holds are skipped
+	FindPurgeable(ctx context.Context, queueName string, status Status, limit int) ([]*Job, error)
+	// CancelJobs stores the cancellation of the jobs, cancelled already at the same time and
+	// for the same reason, and returns how many it cancelled
+	CancelJobs(ctx context.Context, jobs []*Job) (int64, error)
 }
@@ -0,0 +1,22 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStatusNotPurgeable is returned for purging jobs of a status that can't be cancelled
+var ErrStatusNotPurgeable = errors.New("status can't be purged")
+
+// PurgeableStatus returns the status of the jobs a purge selects, pending when status is empty.
+// Only pending jobs wait in the queue backend; retrying jobs are held by the worker waiting out
+// their backoff and the others have started or finished.
+func PurgeableStatus(status Status) (Status, error) {
+	if status == "" {
+		return StatusPending, nil
+	}
+	if status != StatusPending {
+		return "", fmt.Errorf("%w: only %s jobs can be purged, got %q", ErrStatusNotPurgeable, StatusPending, status)
+	}
+	return status, nil
+}
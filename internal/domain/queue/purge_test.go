@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeableStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			status Status
+		}
+		want struct {
+			status Status
+			err    error
+		}
+	}{
+		{
+			name: "Given no status, When resolving the purged status, Then should default to pending",
+			in:   struct{ status Status }{},
+			want: struct {
+				status Status
+				err    error
+			}{status: StatusPending},
+		},
+		{
+			name: "Given pending, When resolving the purged status, Then should keep it",
+			in:   struct{ status Status }{status: StatusPending},
+			want: struct {
+				status Status
+				err    error
+			}{status: StatusPending},
+		},
+		{
+			name: "Given retrying, When resolving the purged status, Then should be refused",
+			in:   struct{ status Status }{status: StatusRetrying},
+			want: struct {
+				status Status
+				err    error
+			}{err: ErrStatusNotPurgeable},
+		},
+		{
+			name: "Given processing, When resolving the purged status, Then should be refused",
+			in:   struct{ status Status }{status: StatusProcessing},
+			want: struct {
+				status Status
+				err    error
+			}{err: ErrStatusNotPurgeable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := PurgeableStatus(tt.in.status)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.status, status)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"slices"
+	"time"
+)
+
+// RetentionPolicy is how long finished jobs keep their data
+type RetentionPolicy struct {
+	// PayloadRetention clears the payload and result of jobs completed longer ago than it, while
+	// the job itself is kept for audit; zero keeps them. Queue definitions may override it.
+	PayloadRetention time.Duration
+	// DeletedRetention purges soft-deleted jobs that long after they were deleted; zero keeps them
+	DeletedRetention time.Duration
+}
+
+// PayloadExpiry selects the completed jobs whose payload and result are cleared
+type PayloadExpiry struct {
+	CompletedBefore time.Time
+	Queues          []string // Only jobs of these queues; empty selects every queue but ExceptQueues
+	ExceptQueues    []string
+	Limit           int // Jobs cleared at most at once
+}
+
+// PayloadExpiries returns what the policy and the queue definitions clear at now: the jobs of
+// the queues overriding the retention, grouped by their retention, and those of every other
+// queue when the policy has a retention of its own
+func (p RetentionPolicy) PayloadExpiries(defs []*Definition, now time.Time, limit int) []PayloadExpiry {
+	var expiries []PayloadExpiry
+	var overridden []string
+	byDays := make(map[int]int) // Index in expiries of the expiry of a retention in days
+	for _, def := range defs {
+		if def.PayloadRetentionDays <= 0 {
+			continue
+		}
+		overridden = append(overridden, def.Name)
+		i, ok := byDays[def.PayloadRetentionDays]
+		if !ok {
+			i = len(expiries)
+			byDays[def.PayloadRetentionDays] = i
+			expiries = append(expiries, PayloadExpiry{
+				CompletedBefore: now.Add(-time.Duration(def.PayloadRetentionDays) * 24 * time.Hour),
+				Limit:           limit,
+			})
+		}
+		expiries[i].Queues = append(expiries[i].Queues, def.Name)
+	}
+	if p.PayloadRetention > 0 {
+		slices.Sort(overridden)
+		expiries = append(expiries, PayloadExpiry{
+			CompletedBefore: now.Add(-p.PayloadRetention),
+			ExceptQueues:    overridden,
+			Limit:           limit,
+		})
+	}
+	return expiries
+}
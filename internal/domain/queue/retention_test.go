@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicy_PayloadExpiries(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name string
+		in   struct {
+			policy RetentionPolicy
+			defs   []*Definition
+		}
+		want struct {
+			expiries []PayloadExpiry
+		}
+	}{
+		{
+			name: "Given no default retention and no overrides, When listing expiries, Then should clear nothing",
+			in: struct {
+				policy RetentionPolicy
+				defs   []*Definition
+			}{defs: []*Definition{{Name: "emails"}}},
+			want: struct{ expiries []PayloadExpiry }{expiries: nil},
+		},
+		{
+			name: "Given a default retention, When listing expiries, Then should clear every queue after it",
+			in: struct {
+				policy RetentionPolicy
+				defs   []*Definition
+			}{policy: RetentionPolicy{PayloadRetention: 30 * day}, defs: []*Definition{{Name: "emails"}}},
+			want: struct{ expiries []PayloadExpiry }{expiries: []PayloadExpiry{
+				{CompletedBefore: now.Add(-30 * day), Limit: 100},
+			}},
+		},
+		{
+			name: "Given queues overriding the default retention, When listing expiries, Then should group them by retention and leave them out of the default",
+			in: struct {
+				policy RetentionPolicy
+				defs   []*Definition
+			}{
+				policy: RetentionPolicy{PayloadRetention: 30 * day},
+				defs: []*Definition{
+					{Name: "reports", PayloadRetentionDays: 7},
+					{Name: "emails"},
+					{Name: "audit", PayloadRetentionDays: 365},
+					{Name: "exports", PayloadRetentionDays: 7},
+				},
+			},
+			want: struct{ expiries []PayloadExpiry }{expiries: []PayloadExpiry{
+				{CompletedBefore: now.Add(-7 * day), Queues: []string{"reports", "exports"}, Limit: 100},
+				{CompletedBefore: now.Add(-365 * day), Queues: []string{"audit"}, Limit: 100},
+				{CompletedBefore: now.Add(-30 * day), ExceptQueues: []string{"audit", "exports", "reports"}, Limit: 100},
+			}},
+		},
+		{
+			name: "Given a queue override and no default retention, When listing expiries, Then should clear that queue alone",
+			in: struct {
+				policy RetentionPolicy
+				defs   []*Definition
+			}{defs: []*Definition{{Name: "reports", PayloadRetentionDays: 7}, {Name: "emails"}}},
+			want: struct{ expiries []PayloadExpiry }{expiries: []PayloadExpiry{
+				{CompletedBefore: now.Add(-7 * day), Queues: []string{"reports"}, Limit: 100},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.in.policy.PayloadExpiries(tt.in.defs, now, 100)
+
+			assert.Equal(t, tt.want.expiries, result)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package queue
+
+import (
+	"math"
+	"time"
+)
+
+// Scaling recommendation defaults
+const (
+	DefaultScalingTargetDrain    = time.Minute
+	DefaultScalingExecutionTime  = time.Second
+	DefaultScalingMaxReplicas    = 10
+	DefaultScalingJobsPerReplica = 1
+)
+
+// ScalingPolicy tunes worker replica recommendations; zero fields take the defaults
+type ScalingPolicy struct {
+	TargetDrain          time.Duration // The backlog should be worked off within this time (default 1m)
+	DefaultExecutionTime time.Duration // Assumed while the history shows no completed jobs (default 1s)
+	JobsPerReplica       int           // Jobs of one queue a worker replica runs at once (default 1)
+	MinReplicas          int
+	MaxReplicas          int // default 10
+}
+
+func (p ScalingPolicy) withDefaults() ScalingPolicy {
+	if p.TargetDrain <= 0 {
+		p.TargetDrain = DefaultScalingTargetDrain
+	}
+	if p.DefaultExecutionTime <= 0 {
+		p.DefaultExecutionTime = DefaultScalingExecutionTime
+	}
+	if p.JobsPerReplica <= 0 {
+		p.JobsPerReplica = DefaultScalingJobsPerReplica
+	}
+	if p.MinReplicas < 0 {
+		p.MinReplicas = 0
+	}
+	if p.MaxReplicas <= 0 {
+		p.MaxReplicas = DefaultScalingMaxReplicas
+	}
+	if p.MaxReplicas < p.MinReplicas {
+		p.MaxReplicas = p.MinReplicas
+	}
+	return p
+}
+
+// ScalingRecommendation is the number of worker replicas a queue needs to keep up with its load
+type ScalingRecommendation struct {
+	Queue            string
+	Backlog          int64
+	Processing       int64
+	ArrivalRate      float64       // Jobs created per second
+	CompletionRate   float64       // Jobs completed per second
+	AvgExecutionTime time.Duration // Estimated; zero while the history shows no completed jobs
+	DesiredReplicas  int
+	SampledAt        time.Time // Time of the latest sample, zero without history
+}
+
+// RecommendScaling derives a queue's desired worker replicas from a time-ordered history of samples.
+//
+// Rates are measured between the first and the last sample. The average execution time follows
+// from Little's law: jobs in progress divided by the completion rate. The replicas must absorb the
+// arrival rate and work off the current backlog within the policy's target drain time.
+func RecommendScaling(queueName string, history []*QueueStats, policy ScalingPolicy) *ScalingRecommendation {
+	policy = policy.withDefaults()
+	rec := &ScalingRecommendation{Queue: queueName}
+	if len(history) == 0 {
+		rec.DesiredReplicas = policy.MinReplicas
+		return rec
+	}
+
+	latest := history[len(history)-1]
+	rec.Backlog = latest.Backlog()
+	rec.Processing = latest.Processing
+	rec.SampledAt = latest.SampledAt
+
+	if elapsed := latest.SampledAt.Sub(history[0].SampledAt).Seconds(); elapsed > 0 {
+		var arrivals, completions, inProgress int64
+		for i, sample := range history {
+			inProgress += sample.Processing
+			// New jobs grow the total; deletions and purges only ever shrink it
+			if i > 0 {
+				if delta := sample.total() - history[i-1].total(); delta > 0 {
+					arrivals += delta
+				}
+			}
+		}
+		for _, completed := range Throughput(history) {
+			completions += completed
+		}
+
+		rec.ArrivalRate = float64(arrivals) / elapsed
+		rec.CompletionRate = float64(completions) / elapsed
+		if completions > 0 {
+			avgInProgress := float64(inProgress) / float64(len(history))
+			rec.AvgExecutionTime = time.Duration(avgInProgress / rec.CompletionRate * float64(time.Second))
+		}
+	}
+
+	executionTime := rec.AvgExecutionTime
+	if executionTime <= 0 {
+		executionTime = policy.DefaultExecutionTime
+	}
+	perReplica := float64(policy.JobsPerReplica) / executionTime.Seconds()
+	required := rec.ArrivalRate + float64(rec.Backlog)/policy.TargetDrain.Seconds()
+
+	// The epsilon keeps float noise from adding a replica to an exact fit
+	desired := int(math.Ceil(required/perReplica - 1e-9))
+	if desired == 0 && (rec.Backlog > 0 || rec.Processing > 0) {
+		desired = 1
+	}
+	rec.DesiredReplicas = min(max(desired, policy.MinReplicas), policy.MaxReplicas)
+	return rec
+}
+
+// total counts the live jobs of every status
+func (s *QueueStats) total() int64 {
+	return s.Pending + s.Processing + s.Retrying + s.Failed + s.Completed
+}
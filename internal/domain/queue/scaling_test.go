@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendScaling(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(minute int, pending, processing, completed int64) *QueueStats {
+		return &QueueStats{
+			Queue:      "default",
+			Pending:    pending,
+			Processing: processing,
+			Completed:  completed,
+			SampledAt:  start.Add(time.Duration(minute) * time.Minute),
+		}
+	}
+
+	tests := []struct {
+		name string
+		in   struct {
+			history []*QueueStats
+			policy  ScalingPolicy
+		}
+		want struct {
+			replicas      int
+			arrivalRate   float64
+			executionTime time.Duration
+		}
+	}{
+		{
+			name: "Given a steady load without backlog, When recommending, Then should size replicas to the arrival rate",
+			in: struct {
+				history []*QueueStats
+				policy  ScalingPolicy
+			}{
+				history: []*QueueStats{sample(0, 0, 2, 0), sample(5, 0, 2, 300), sample(10, 0, 2, 600)},
+			},
+			want: struct {
+				replicas      int
+				arrivalRate   float64
+				executionTime time.Duration
+			}{replicas: 2, arrivalRate: 1, executionTime: 2 * time.Second},
+		},
+		{
+			name: "Given a growing backlog, When recommending, Then should add replicas to drain it within the target time",
+			in: struct {
+				history []*QueueStats
+				policy  ScalingPolicy
+			}{
+				history: []*QueueStats{sample(0, 0, 2, 0), sample(5, 0, 2, 300), sample(10, 120, 2, 600)},
+			},
+			want: struct {
+				replicas      int
+				arrivalRate   float64
+				executionTime time.Duration
+			}{replicas: 7, arrivalRate: 1.2, executionTime: 2 * time.Second},
+		},
+		{
+			name: "Given a growing backlog and a replica cap, When recommending, Then should not exceed the cap",
+			in: struct {
+				history []*QueueStats
+				policy  ScalingPolicy
+			}{
+				history: []*QueueStats{sample(0, 0, 2, 0), sample(5, 0, 2, 300), sample(10, 120, 2, 600)},
+				policy:  ScalingPolicy{MaxReplicas: 3},
+			},
+			want: struct {
+				replicas      int
+				arrivalRate   float64
+				executionTime time.Duration
+			}{replicas: 3, arrivalRate: 1.2, executionTime: 2 * time.Second},
+		},
+		{
+			name: "Given a single sample with a backlog, When recommending, Then should assume the default execution time",
+			in: struct {
+				history []*QueueStats
+				policy  ScalingPolicy
+			}{
+				history: []*QueueStats{sample(0, 30, 0, 0)},
+			},
+			want: struct {
+				replicas      int
+				arrivalRate   float64
+				executionTime time.Duration
+			}{replicas: 1},
+		},
+		{
+			name: "Given an idle queue, When recommending, Then should scale to zero",
+			in: struct {
+				history []*QueueStats
+				policy  ScalingPolicy
+			}{
+				history: []*QueueStats{sample(0, 0, 0, 50), sample(5, 0, 0, 50)},
+			},
+			want: struct {
+				replicas      int
+				arrivalRate   float64
+				executionTime time.Duration
+			}{replicas: 0},
+		},
+		{
+			name: "Given no history and a minimum, When recommending, Then should keep the minimum replicas",
+			in: struct {
+				history []*QueueStats
+				policy  ScalingPolicy
+			}{
+				policy: ScalingPolicy{MinReplicas: 2},
+			},
+			want: struct {
+				replicas      int
+				arrivalRate   float64
+				executionTime time.Duration
+			}{replicas: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := RecommendScaling("default", tt.in.history, tt.in.policy)
+
+			assert.Equal(t, "default", rec.Queue)
+			assert.Equal(t, tt.want.replicas, rec.DesiredReplicas)
+			assert.InDelta(t, tt.want.arrivalRate, rec.ArrivalRate, 1e-9)
+			assert.Equal(t, tt.want.executionTime, rec.AvgExecutionTime)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultSchemaVersion is the payload schema version of jobs whose producer doesn't set one,
+// and of jobs stored before versions were recorded
+const DefaultSchemaVersion = 1
+
+var ErrInvalidSchemaVersion = errors.New("schema version must be positive")
+
+// SetSchemaVersion records the version of the payload's schema the producer wrote, so workers
+// of different releases can tell whether they understand the payload
+func (j *Job) SetSchemaVersion(version int) error {
+	if version < 1 {
+		return fmt.Errorf("%w, got %d", ErrInvalidSchemaVersion, version)
+	}
+	j.SchemaVersion = version
+	return nil
+}
+
+// EffectiveSchemaVersion returns the job's payload schema version; queue entries written before
+// versions were recorded carry none and are DefaultSchemaVersion
+func (j *Job) EffectiveSchemaVersion() int {
+	return max(j.SchemaVersion, DefaultSchemaVersion)
+}
+
+// MarkAsHeld parks a job waiting to run whose schema version is newer than its worker knows,
+// recording why, until a worker that knows it releases the job
+func (j *Job) MarkAsHeld(reason string) error {
+	if err := j.transition(StatusHeld); err != nil {
+		return err
+	}
+	j.Error = reason
+	return nil
+}
+
+// Release returns a held job to pending, so it is queued again
+func (j *Job) Release() error {
+	if j.Status != StatusHeld {
+		return fmt.Errorf("%w: %s job can't be released", ErrInvalidTransition, j.Status)
+	}
+	if err := j.transition(StatusPending); err != nil {
+		return err
+	}
+	j.Error = ""
+	return nil
+}
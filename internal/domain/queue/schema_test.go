@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob_SetSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			version int
+		}
+		want struct {
+			version int
+			err     error
+		}
+	}{
+		{
+			name: "Given a positive version, When setting it, Then should record it",
+			in:   struct{ version int }{version: 3},
+			want: struct {
+				version int
+				err     error
+			}{version: 3},
+		},
+		{
+			name: "Given version zero, When setting it, Then should be refused and keep the default",
+			in:   struct{ version int }{version: 0},
+			want: struct {
+				version int
+				err     error
+			}{version: DefaultSchemaVersion, err: ErrInvalidSchemaVersion},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, _ := NewJob("emails", "email", []byte(`{}`))
+
+			err := job.SetSchemaVersion(tt.in.version)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.version, job.SchemaVersion)
+		})
+	}
+}
+
+func TestJob_EffectiveSchemaVersion(t *testing.T) {
+	// Given a job decoded from a queue entry written before versions were recorded
+	job := &Job{}
+
+	// When
+	version := job.EffectiveSchemaVersion()
+
+	// Then
+	assert.Equal(t, DefaultSchemaVersion, version)
+}
+
+func TestJob_HoldAndRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			status Status
+		}
+		want struct {
+			holdErr error
+		}
+	}{
+		{
+			name: "Given a pending job, When holding and releasing it, Then should be pending again",
+			in:   struct{ status Status }{status: StatusPending},
+		},
+		{
+			name: "Given a job claimed by a worker, When holding and releasing it, Then should be pending again",
+			in:   struct{ status Status }{status: StatusProcessing},
+		},
+		{
+			name: "Given a completed job, When holding it, Then should be refused",
+			in:   struct{ status Status }{status: StatusCompleted},
+			want: struct{ holdErr error }{holdErr: ErrInvalidTransition},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Status: tt.in.status}
+
+			err := job.MarkAsHeld("job schema version is newer than the worker knows")
+
+			assert.ErrorIs(t, err, tt.want.holdErr)
+			if tt.want.holdErr != nil {
+				assert.Equal(t, tt.in.status, job.Status)
+				assert.ErrorIs(t, job.Release(), ErrInvalidTransition)
+				return
+			}
+			assert.Equal(t, StatusHeld, job.Status)
+			assert.False(t, job.IsFinished())
+			assert.NoError(t, job.Release())
+			assert.Equal(t, StatusPending, job.Status)
+			assert.Empty(t, job.Error)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 200
+)
+
+// ErrEmptySearchText is returned when a search has nothing to look for
+var ErrEmptySearchText = errors.New("search text is required")
+
+// SearchCriteria describes a full-text search over job errors and payloads
+type SearchCriteria struct {
+	Text   string // Free text, supports quoted phrases and -exclusions
+	Status Status // Optional status filter
+	Queue  string // Optional queue filter
+	Limit  int
+	Offset int
+}
+
+// NewSearchCriteria validates the search text and normalizes pagination
+func NewSearchCriteria(text string, status Status, queueName string, limit, offset int) (SearchCriteria, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return SearchCriteria{}, ErrEmptySearchText
+	}
+
+	limit, offset = pageBounds(limit, offset)
+	return SearchCriteria{
+		Text:   text,
+		Status: status,
+		Queue:  queueName,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// pageBounds applies the default and maximum page size and clamps a negative offset to zero
+func pageBounds(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	return limit, max(offset, 0)
+}
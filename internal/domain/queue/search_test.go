@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSearchCriteria(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			text   string
+			limit  int
+			offset int
+		}
+		want struct {
+			text   string
+			limit  int
+			offset int
+			err    error
+		}
+	}{
+		{
+			name: "Given search text with surrounding spaces, When creating criteria, Then should trim it and apply default limit",
+			in: struct {
+				text   string
+				limit  int
+				offset int
+			}{text: "  SMTP auth  "},
+			want: struct {
+				text   string
+				limit  int
+				offset int
+				err    error
+			}{text: "SMTP auth", limit: DefaultSearchLimit},
+		},
+		{
+			name: "Given a limit above the maximum and a negative offset, When creating criteria, Then should clamp both",
+			in: struct {
+				text   string
+				limit  int
+				offset int
+			}{text: "timeout", limit: 1000, offset: -5},
+			want: struct {
+				text   string
+				limit  int
+				offset int
+				err    error
+			}{text: "timeout", limit: MaxSearchLimit},
+		},
+		{
+			name: "Given blank search text, When creating criteria, Then should return error",
+			in: struct {
+				text   string
+				limit  int
+				offset int
+			}{text: "   "},
+			want: struct {
+				text   string
+				limit  int
+				offset int
+				err    error
+			}{err: ErrEmptySearchText},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria, err := NewSearchCriteria(tt.in.text, "", "", tt.in.limit, tt.in.offset)
+
+			assert.Equal(t, tt.want.err, err)
+			if tt.want.err == nil {
+				assert.Equal(t, tt.want.text, criteria.Text)
+				assert.Equal(t, tt.want.limit, criteria.Limit)
+				assert.Equal(t, tt.want.offset, criteria.Offset)
+			}
+		})
+	}
+}
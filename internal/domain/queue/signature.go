@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// DefaultSigningKeyID identifies the secret used for callers without a secret of their own
+const DefaultSigningKeyID = "default"
+
+var (
+	ErrNoSigningSecrets  = errors.New("payload signing needs a default secret or at least one API key secret")
+	ErrNoSigningSecret   = errors.New("no payload signing secret for the caller")
+	ErrPayloadUnsigned   = errors.New("job payload is not signed")
+	ErrPayloadTampered   = errors.New("job payload signature does not match")
+	ErrUnknownSigningKey = errors.New("job payload was signed with an unknown key")
+)
+
+// PayloadSigner signs job payloads with HMAC-SHA256 at creation and verifies them before
+// execution, so payloads changed in transit (e.g. in a shared Redis) are rejected.
+// Each API key may have its own secret; jobs record the ID of the key they were signed with.
+type PayloadSigner struct {
+	secrets  map[string][]byte // Secret by key ID
+	keyIDs   map[string]string // Key ID by API key
+	required bool
+}
+
+// NewPayloadSigner creates a signer. defaultSecret signs jobs of callers without a secret in
+// apiKeySecrets; when required is set, unsigned jobs fail verification.
+func NewPayloadSigner(defaultSecret string, apiKeySecrets map[string]string, required bool) (*PayloadSigner, error) {
+	if defaultSecret == "" && len(apiKeySecrets) == 0 {
+		return nil, ErrNoSigningSecrets
+	}
+
+	s := &PayloadSigner{
+		secrets:  make(map[string][]byte, len(apiKeySecrets)+1),
+		keyIDs:   make(map[string]string, len(apiKeySecrets)),
+		required: required,
+	}
+	if defaultSecret != "" {
+		s.secrets[DefaultSigningKeyID] = []byte(defaultSecret)
+	}
+	for apiKey, secret := range apiKeySecrets {
+		keyID := SigningKeyID(apiKey)
+		s.keyIDs[apiKey] = keyID
+		s.secrets[keyID] = []byte(secret)
+	}
+	return s, nil
+}
+
+// SigningKeyID derives the key ID recorded on jobs signed with an API key's secret,
+// so the API key itself is never stored with the job
+func SigningKeyID(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "key-" + hex.EncodeToString(sum[:6])
+}
+
+// Sign signs the job with the caller's secret, falling back to the default secret.
+// Without a usable secret the job stays unsigned, which is an error only when signatures are required.
+func (s *PayloadSigner) Sign(job *Job, apiKey string) error {
+	keyID := DefaultSigningKeyID
+	if id, ok := s.keyIDs[apiKey]; ok && apiKey != "" {
+		keyID = id
+	}
+	secret, ok := s.secrets[keyID]
+	if !ok {
+		if s.required {
+			return ErrNoSigningSecret
+		}
+		return nil
+	}
+
+	job.SigningKeyID = keyID
+	job.Signature = signPayload(secret, job)
+	return nil
+}
+
+// Resign signs the job again with the key it was signed with, after a trusted change to its payload
+func (s *PayloadSigner) Resign(job *Job) error {
+	if job.SigningKeyID == "" {
+		return nil
+	}
+	secret, ok := s.secrets[job.SigningKeyID]
+	if !ok {
+		return ErrUnknownSigningKey
+	}
+	job.Signature = signPayload(secret, job)
+	return nil
+}
+
+// Verify checks the job's signature against its current queue, type and payload
+func (s *PayloadSigner) Verify(job *Job) error {
+	if job.Signature == "" {
+		if s.required {
+			return ErrPayloadUnsigned
+		}
+		return nil
+	}
+	secret, ok := s.secrets[job.SigningKeyID]
+	if !ok {
+		return ErrUnknownSigningKey
+	}
+	if !hmac.Equal([]byte(job.Signature), []byte(signPayload(secret, job))) {
+		return ErrPayloadTampered
+	}
+	return nil
+}
+
+// signPayload covers the fields that decide what a job does, bound to the job ID so a
+// signature can't be replayed on another job
+func signPayload(secret []byte, job *Job) string {
+	mac := hmac.New(sha256.New, secret)
+	for _, part := range [][]byte{
+		[]byte(job.SigningKeyID),
+		[]byte(job.ID.String()),
+		[]byte(job.Queue),
+		[]byte(job.Type),
+		canonicalPayload(job.Payload),
+	} {
+		mac.Write(part)
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// canonicalPayload re-encodes JSON payloads so key order and whitespace don't matter;
+// Postgres JSONB doesn't preserve either, and jobs are re-enqueued from the database on retry
+func canonicalPayload(payload []byte) []byte {
+	var decoded any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return payload
+	}
+	canonical, err := json.Marshal(decoded)
+	if err != nil {
+		return payload
+	}
+	return canonical
+}
@@ -0,0 +1,182 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSignedTestJob(t *testing.T, signer *PayloadSigner, apiKey string) *Job {
+	t.Helper()
+	job, err := NewJob("default", "email", []byte(`{"to":"user@example.com","subject":"Hello"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, signer.Sign(job, apiKey))
+	return job
+}
+
+func TestPayloadSigner_Verify(t *testing.T) {
+	signer, err := NewPayloadSigner("default-secret", map[string]string{"tenant-a": "secret-a"}, false)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		in   struct {
+			apiKey string
+			change func(job *Job)
+		}
+		want struct {
+			keyID string
+			err   error
+		}
+	}{
+		{
+			name: "Given an untouched job signed with the default secret, When verifying, Then should accept it",
+			in: struct {
+				apiKey string
+				change func(job *Job)
+			}{apiKey: "", change: func(job *Job) {}},
+			want: struct {
+				keyID string
+				err   error
+			}{keyID: DefaultSigningKeyID},
+		},
+		{
+			name: "Given a job signed with an API key secret, When verifying, Then should accept it with the key's ID",
+			in: struct {
+				apiKey string
+				change func(job *Job)
+			}{apiKey: "tenant-a", change: func(job *Job) {}},
+			want: struct {
+				keyID string
+				err   error
+			}{keyID: SigningKeyID("tenant-a")},
+		},
+		{
+			name: "Given a payload with reordered keys and whitespace, When verifying, Then should accept it",
+			in: struct {
+				apiKey string
+				change func(job *Job)
+			}{apiKey: "", change: func(job *Job) {
+				job.Payload = []byte(`{ "subject": "Hello", "to": "user@example.com" }`)
+			}},
+			want: struct {
+				keyID string
+				err   error
+			}{keyID: DefaultSigningKeyID},
+		},
+		{
+			name: "Given a changed payload, When verifying, Then should return ErrPayloadTampered",
+			in: struct {
+				apiKey string
+				change func(job *Job)
+			}{apiKey: "tenant-a", change: func(job *Job) {
+				job.Payload = []byte(`{"to":"attacker@example.com","subject":"Hello"}`)
+			}},
+			want: struct {
+				keyID string
+				err   error
+			}{keyID: SigningKeyID("tenant-a"), err: ErrPayloadTampered},
+		},
+		{
+			name: "Given a changed job type, When verifying, Then should return ErrPayloadTampered",
+			in: struct {
+				apiKey string
+				change func(job *Job)
+			}{apiKey: "", change: func(job *Job) { job.Type = "command" }},
+			want: struct {
+				keyID string
+				err   error
+			}{keyID: DefaultSigningKeyID, err: ErrPayloadTampered},
+		},
+		{
+			name: "Given a signature moved to another key ID, When verifying, Then should return ErrUnknownSigningKey",
+			in: struct {
+				apiKey string
+				change func(job *Job)
+			}{apiKey: "", change: func(job *Job) { job.SigningKeyID = "key-forged" }},
+			want: struct {
+				keyID string
+				err   error
+			}{keyID: "key-forged", err: ErrUnknownSigningKey},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := newSignedTestJob(t, signer, tt.in.apiKey)
+			tt.in.change(job)
+
+			err := signer.Verify(job)
+
+			assert.Equal(t, tt.want.keyID, job.SigningKeyID)
+			assert.ErrorIs(t, err, tt.want.err)
+			if tt.want.err == nil {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPayloadSigner_Required(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			required bool
+		}
+		want struct {
+			signErr   error
+			verifyErr error
+		}
+	}{
+		{
+			name: "Given signatures are required and the caller has no secret, When signing and verifying, Then both should fail",
+			in:   struct{ required bool }{required: true},
+			want: struct {
+				signErr   error
+				verifyErr error
+			}{signErr: ErrNoSigningSecret, verifyErr: ErrPayloadUnsigned},
+		},
+		{
+			name: "Given signatures are optional and the caller has no secret, When signing and verifying, Then the unsigned job should be accepted",
+			in:   struct{ required bool }{required: false},
+			want: struct {
+				signErr   error
+				verifyErr error
+			}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewPayloadSigner("", map[string]string{"tenant-a": "secret-a"}, tt.in.required)
+			assert.NoError(t, err)
+			job, err := NewJob("default", "email", []byte(`{}`))
+			assert.NoError(t, err)
+
+			signErr := signer.Sign(job, "unknown-key")
+			verifyErr := signer.Verify(job)
+
+			assert.Equal(t, tt.want.signErr, signErr)
+			assert.Equal(t, tt.want.verifyErr, verifyErr)
+			assert.Empty(t, job.Signature)
+		})
+	}
+}
+
+func TestPayloadSigner_Resign(t *testing.T) {
+	signer, err := NewPayloadSigner("default-secret", nil, true)
+	assert.NoError(t, err)
+	job := newSignedTestJob(t, signer, "")
+
+	job.Payload = []byte(`{"to":"user@example.com","subject":"Hello","timeout":30}`)
+	assert.ErrorIs(t, signer.Verify(job), ErrPayloadTampered)
+
+	assert.NoError(t, signer.Resign(job))
+	assert.NoError(t, signer.Verify(job))
+}
+
+func TestNewPayloadSigner_NoSecrets(t *testing.T) {
+	_, err := NewPayloadSigner("", nil, false)
+
+	assert.ErrorIs(t, err, ErrNoSigningSecrets)
+}
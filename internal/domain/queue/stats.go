@@ -0,0 +1,32 @@
+package queue
+
+import "time"
+
+// QueueStats is a sample of a queue's job counts by status
+type QueueStats struct {
+	Queue      string
+	Pending    int64
+	Processing int64
+	Retrying   int64
+	Failed     int64
+	Completed  int64
+	SampledAt  time.Time
+}
+
+// Backlog returns the jobs waiting to run, including those scheduled for a retry
+func (s *QueueStats) Backlog() int64 {
+	return s.Pending + s.Retrying
+}
+
+// Throughput returns the jobs completed since the previous sample for each sample of a
+// time-ordered history. The first sample has no predecessor and reports zero, as do samples
+// whose completed count dropped (e.g. after completed jobs were purged).
+func Throughput(history []*QueueStats) []int64 {
+	throughput := make([]int64, len(history))
+	for i := 1; i < len(history); i++ {
+		if delta := history[i].Completed - history[i-1].Completed; delta > 0 {
+			throughput[i] = delta
+		}
+	}
+	return throughput
+}
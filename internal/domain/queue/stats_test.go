@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThroughput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			completed []int64
+		}
+		want struct {
+			throughput []int64
+		}
+	}{
+		{
+			name: "Given growing completed counts, When computing throughput, Then should report the difference to the previous sample",
+			in:   struct{ completed []int64 }{completed: []int64{10, 15, 15, 22}},
+			want: struct{ throughput []int64 }{throughput: []int64{0, 5, 0, 7}},
+		},
+		{
+			name: "Given a completed count that drops, When computing throughput, Then should report zero for that sample",
+			in:   struct{ completed []int64 }{completed: []int64{40, 5, 9}},
+			want: struct{ throughput []int64 }{throughput: []int64{0, 0, 4}},
+		},
+		{
+			name: "Given no samples, When computing throughput, Then should return an empty series",
+			in:   struct{ completed []int64 }{completed: nil},
+			want: struct{ throughput []int64 }{throughput: []int64{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := make([]*QueueStats, len(tt.in.completed))
+			for i, completed := range tt.in.completed {
+				history[i] = &QueueStats{Queue: "default", Completed: completed}
+			}
+
+			assert.Equal(t, tt.want.throughput, Throughput(history))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package quota
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageStore keeps the usage counters of every tenant, shared by all replicas. Checking a
+// limit and counting against it is atomic so concurrent requests can't overshoot it.
+type UsageStore interface {
+	// ReserveJob counts a new job against the tenant's jobs per day and pending jobs, returning
+	// an *ExceededError without counting it when either limit is reached
+	ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID, limits Quota, now time.Time) error
+	// ReleaseJob stops counting a job as pending; jobs that aren't counted are ignored
+	ReleaseJob(ctx context.Context, jobID uuid.UUID) error
+	// TenantOf returns the tenant that created a pending job, or "" when the job isn't counted
+	TenantOf(ctx context.Context, jobID uuid.UUID) (string, error)
+	// ReserveAnalysis counts an AI analysis against the tenant's analyses per day, returning
+	// an *ExceededError without counting it when the limit is reached
+	ReserveAnalysis(ctx context.Context, tenant string, limits Quota, now time.Time) error
+	Usage(ctx context.Context, tenant string, now time.Time) (*Usage, error)
+}
+
+// OverrideRepository stores the tenants whose quota differs from the default
+type OverrideRepository interface {
+	Get(ctx context.Context, tenant string) (*Override, error)
+}
+
+// Enforcer applies tenant quotas to the use cases that consume them
+type Enforcer interface {
+	ReserveJob(ctx context.Context, tenant string, jobID uuid.UUID) error
+	ReleaseJob(ctx context.Context, jobID uuid.UUID) error
+	ReserveAnalysis(ctx context.Context, tenant string) error
+	TenantOf(ctx context.Context, jobID uuid.UUID) (string, error)
+}
@@ -0,0 +1,142 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Resource is a quantity metered per tenant
+type Resource string
+
+const (
+	ResourceJobs        Resource = "jobs_per_day"     // Jobs created since the start of the UTC day
+	ResourcePendingJobs Resource = "pending_jobs"     // Jobs created and not yet completed, dead-lettered or deleted
+	ResourceAnalyses    Resource = "analyses_per_day" // AI analyses requested since the start of the UTC day
+)
+
+var (
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrInvalidQuota  = errors.New("quota limits must not be negative")
+	ErrNoOverride    = errors.New("no quota override for tenant")
+)
+
+// AnonymousTenant is the tenant the callers without an API key share
+const AnonymousTenant = "anonymous"
+
+// Tenant returns the tenant of a caller from the owner its jobs are attributed to (see
+// queue.Owners), so quotas are keyed by a name or hashed key ID rather than the API key itself.
+// Callers without an API key have no owner and are metered together as AnonymousTenant.
+func Tenant(owner string) string {
+	if owner == "" {
+		return AnonymousTenant
+	}
+	return owner
+}
+
+// Quota limits what a tenant may use. A zero limit is unlimited.
+type Quota struct {
+	JobsPerDay     int
+	MaxPendingJobs int
+	AnalysesPerDay int
+}
+
+// Validate checks that no limit is negative
+func (q Quota) Validate() error {
+	if q.JobsPerDay < 0 || q.MaxPendingJobs < 0 || q.AnalysesPerDay < 0 {
+		return ErrInvalidQuota
+	}
+	return nil
+}
+
+// Limit returns the limit of a resource
+func (q Quota) Limit(resource Resource) int {
+	switch resource {
+	case ResourceJobs:
+		return q.JobsPerDay
+	case ResourcePendingJobs:
+		return q.MaxPendingJobs
+	case ResourceAnalyses:
+		return q.AnalysesPerDay
+	default:
+		return 0
+	}
+}
+
+// Override replaces some limits of the default quota for one tenant; nil limits keep the default
+type Override struct {
+	Tenant         string
+	JobsPerDay     *int
+	MaxPendingJobs *int
+	AnalysesPerDay *int
+}
+
+// Apply returns the quota with the override's limits in place of its own
+func (o *Override) Apply(q Quota) Quota {
+	if o.JobsPerDay != nil {
+		q.JobsPerDay = *o.JobsPerDay
+	}
+	if o.MaxPendingJobs != nil {
+		q.MaxPendingJobs = *o.MaxPendingJobs
+	}
+	if o.AnalysesPerDay != nil {
+		q.AnalysesPerDay = *o.AnalysesPerDay
+	}
+	return q
+}
+
+// Usage is what a tenant used of each resource on one UTC day
+type Usage struct {
+	Day           time.Time // Start of the UTC day the daily counters cover
+	JobsToday     int64
+	PendingJobs   int64
+	AnalysesToday int64
+}
+
+// Used returns the usage of a resource
+func (u *Usage) Used(resource Resource) int64 {
+	switch resource {
+	case ResourceJobs:
+		return u.JobsToday
+	case ResourcePendingJobs:
+		return u.PendingJobs
+	case ResourceAnalyses:
+		return u.AnalysesToday
+	default:
+		return 0
+	}
+}
+
+// ExceededError is returned when a tenant has used up a resource
+type ExceededError struct {
+	Resource   Resource
+	Limit      int
+	RetryAfter time.Duration // Zero when capacity is freed by jobs finishing rather than by time
+}
+
+// NewExceededError reports that the limit of a resource was reached at now
+func NewExceededError(resource Resource, limit int, now time.Time) *ExceededError {
+	err := &ExceededError{Resource: resource, Limit: limit}
+	if resource != ResourcePendingJobs {
+		err.RetryAfter = UntilReset(now)
+	}
+	return err
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s: %s limit of %d reached", ErrQuotaExceeded, e.Resource, e.Limit)
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Day returns the start of the UTC day containing t; daily counters reset at that boundary
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// UntilReset returns how long after t the daily counters reset
+func UntilReset(t time.Time) time.Duration {
+	return Day(t).Add(24 * time.Hour).Sub(t)
+}
@@ -0,0 +1,176 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenant(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "Given an owner, When getting the tenant, Then should meter the owner",
+			in:   "team-a",
+			want: "team-a",
+		},
+		{
+			name: "Given no owner, When getting the tenant, Then should meter the anonymous tenant",
+			in:   "",
+			want: AnonymousTenant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Tenant(tt.in))
+		})
+	}
+}
+
+func TestQuota_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			quota Quota
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given positive limits, When validating, Then should succeed",
+			in:   struct{ quota Quota }{quota: Quota{JobsPerDay: 100, MaxPendingJobs: 10, AnalysesPerDay: 5}},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given zero limits, When validating, Then should succeed as unlimited",
+			in:   struct{ quota Quota }{quota: Quota{}},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given a negative limit, When validating, Then should return ErrInvalidQuota",
+			in:   struct{ quota Quota }{quota: Quota{MaxPendingJobs: -1}},
+			want: struct{ err error }{err: ErrInvalidQuota},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.quota.Validate()
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOverride_Apply(t *testing.T) {
+	zero, fifty := 0, 50
+	defaults := Quota{JobsPerDay: 100, MaxPendingJobs: 10, AnalysesPerDay: 5}
+
+	tests := []struct {
+		name string
+		in   struct {
+			override Override
+		}
+		want struct {
+			quota Quota
+		}
+	}{
+		{
+			name: "Given an override without limits, When applied, Then should keep the default quota",
+			in:   struct{ override Override }{override: Override{Tenant: "key-a"}},
+			want: struct{ quota Quota }{quota: defaults},
+		},
+		{
+			name: "Given an override of some limits, When applied, Then should replace only those",
+			in:   struct{ override Override }{override: Override{Tenant: "key-a", JobsPerDay: &fifty, AnalysesPerDay: &zero}},
+			want: struct{ quota Quota }{quota: Quota{JobsPerDay: 50, MaxPendingJobs: 10, AnalysesPerDay: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.quota, tt.in.override.Apply(defaults))
+		})
+	}
+}
+
+func TestNewExceededError(t *testing.T) {
+	now := time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			resource Resource
+		}
+		want struct {
+			retryAfter time.Duration
+		}
+	}{
+		{
+			name: "Given the daily jobs quota, When exceeded, Then should retry after the UTC day resets",
+			in:   struct{ resource Resource }{resource: ResourceJobs},
+			want: struct{ retryAfter time.Duration }{retryAfter: 6 * time.Hour},
+		},
+		{
+			name: "Given the daily analyses quota, When exceeded, Then should retry after the UTC day resets",
+			in:   struct{ resource Resource }{resource: ResourceAnalyses},
+			want: struct{ retryAfter time.Duration }{retryAfter: 6 * time.Hour},
+		},
+		{
+			name: "Given the pending jobs quota, When exceeded, Then should not set a retry time",
+			in:   struct{ resource Resource }{resource: ResourcePendingJobs},
+			want: struct{ retryAfter time.Duration }{retryAfter: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewExceededError(tt.in.resource, 10, now)
+
+			assert.ErrorIs(t, err, ErrQuotaExceeded)
+			assert.Equal(t, tt.want.retryAfter, err.RetryAfter)
+			assert.Contains(t, err.Error(), string(tt.in.resource))
+		})
+	}
+}
+
+func TestDay(t *testing.T) {
+	local := time.FixedZone("UTC-3", -3*60*60)
+
+	tests := []struct {
+		name string
+		in   struct {
+			at time.Time
+		}
+		want struct {
+			day time.Time
+		}
+	}{
+		{
+			name: "Given a UTC time, When getting its day, Then should return UTC midnight",
+			in:   struct{ at time.Time }{at: time.Date(2026, 10, 17, 18, 30, 0, 0, time.UTC)},
+			want: struct{ day time.Time }{day: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "Given a local time past UTC midnight, When getting its day, Then should return the UTC day",
+			in:   struct{ at time.Time }{at: time.Date(2026, 10, 17, 22, 0, 0, 0, local)},
+			want: struct{ day time.Time }{day: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.day, Day(tt.in.at))
+		})
+	}
+}
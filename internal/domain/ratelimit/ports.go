@@ -0,0 +1,15 @@
+package ratelimit
+
+import "context"
+
+// Limiter defines the interface for rate limiting callers identified by a key
+// (API key or client IP). Implementations must be safe across replicas.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (*Result, error)
+}
+
+// BucketLimiter consumes tokens from buckets whose limit is chosen by the caller,
+// e.g. per-queue limits read from queue definitions
+type BucketLimiter interface {
+	AllowLimit(ctx context.Context, key string, limit Limit) (*Result, error)
+}
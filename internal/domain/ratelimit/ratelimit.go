@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"errors"
+	"time"
+)
+
+// Limit describes a token bucket: sustained refill rate and maximum burst size
+type Limit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// Result represents the outcome of consuming a token for a caller
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+var (
+	ErrInvalidLimit = errors.New("rate limit must have a positive rate and burst")
+)
+
+// NewLimit creates and validates a token bucket limit
+func NewLimit(requestsPerSecond float64, burst int) (Limit, error) {
+	if requestsPerSecond <= 0 || burst <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	return Limit{RequestsPerSecond: requestsPerSecond, Burst: burst}, nil
+}
+
+// TimeToFull returns how long it takes for a bucket with the given remaining
+// tokens to refill completely
+func (l Limit) TimeToFull(remaining int) time.Duration {
+	missing := l.Burst - remaining
+	if missing <= 0 || l.RequestsPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(missing) / l.RequestsPerSecond * float64(time.Second))
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			requestsPerSecond float64
+			burst             int
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given positive rate and burst, When creating limit, Then should succeed",
+			in: struct {
+				requestsPerSecond float64
+				burst             int
+			}{requestsPerSecond: 10, burst: 20},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given zero rate, When creating limit, Then should return ErrInvalidLimit",
+			in: struct {
+				requestsPerSecond float64
+				burst             int
+			}{requestsPerSecond: 0, burst: 20},
+			want: struct{ err error }{err: ErrInvalidLimit},
+		},
+		{
+			name: "Given zero burst, When creating limit, Then should return ErrInvalidLimit",
+			in: struct {
+				requestsPerSecond float64
+				burst             int
+			}{requestsPerSecond: 10, burst: 0},
+			want: struct{ err error }{err: ErrInvalidLimit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, err := NewLimit(tt.in.requestsPerSecond, tt.in.burst)
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.in.requestsPerSecond, limit.RequestsPerSecond)
+				assert.Equal(t, tt.in.burst, limit.Burst)
+			}
+		})
+	}
+}
+
+func TestLimit_TimeToFull(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			remaining int
+		}
+		want struct {
+			duration time.Duration
+		}
+	}{
+		{
+			name: "Given full bucket, When computing time to full, Then should return 0",
+			in:   struct{ remaining int }{remaining: 20},
+			want: struct{ duration time.Duration }{duration: 0},
+		},
+		{
+			name: "Given empty bucket at 10 req/s, When computing time to full, Then should return 2s",
+			in:   struct{ remaining int }{remaining: 0},
+			want: struct{ duration time.Duration }{duration: 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := Limit{RequestsPerSecond: 10, Burst: 20}
+
+			result := limit.TimeToFull(tt.in.remaining)
+
+			assert.Equal(t, tt.want.duration, result)
+		})
+	}
+}
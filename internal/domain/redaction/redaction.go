@@ -0,0 +1,167 @@
+package redaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMask replaces redacted values when no mask is configured
+const DefaultMask = "[REDACTED]"
+
+// EmailPattern matches email addresses
+const EmailPattern = `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`
+
+var ErrInvalidPattern = errors.New("invalid redaction pattern")
+
+// Pattern masks every match of a regular expression in string values
+type Pattern struct {
+	Name  string
+	Regex string
+}
+
+// Redaction records that values were masked at a path, without the values themselves
+type Redaction struct {
+	Path  string `json:"path"`  // JSON path in the payload, e.g. "user.emails[0]", or what the text was
+	Rule  string `json:"rule"`  // "field:<name>" or "pattern:<name>"
+	Count int    `json:"count"` // How many values or matches were masked
+}
+
+type compiledPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor masks sensitive data before it leaves the process in logs or AI prompts.
+// Values of the configured field names, matched case-insensitively at any depth, are
+// replaced whole; matches of the patterns are replaced within any other string.
+type Redactor struct {
+	fields   map[string]struct{}
+	patterns []compiledPattern
+	mask     string
+}
+
+// NewRedactor creates a redactor; an empty mask uses DefaultMask
+func NewRedactor(fields []string, patterns []Pattern, mask string) (*Redactor, error) {
+	if mask == "" {
+		mask = DefaultMask
+	}
+	r := &Redactor{fields: make(map[string]struct{}, len(fields)), mask: mask}
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			r.fields[strings.ToLower(field)] = struct{}{}
+		}
+	}
+	for _, pattern := range patterns {
+		if pattern.Name == "" {
+			return nil, fmt.Errorf("%w: pattern %q has no name", ErrInvalidPattern, pattern.Regex)
+		}
+		re, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidPattern, pattern.Name, err)
+		}
+		r.patterns = append(r.patterns, compiledPattern{name: pattern.Name, re: re})
+	}
+	return r, nil
+}
+
+// MasksField reports whether values of the field are masked whole
+func (r *Redactor) MasksField(name string) bool {
+	_, ok := r.fields[strings.ToLower(name)]
+	return ok
+}
+
+// Mask returns what redacted values are replaced with
+func (r *Redactor) Mask() string {
+	return r.mask
+}
+
+// RedactText masks the pattern matches in text; path names the text in the redactions
+func (r *Redactor) RedactText(path, text string) (string, []Redaction) {
+	var redactions []Redaction
+	for _, pattern := range r.patterns {
+		count := 0
+		text = pattern.re.ReplaceAllStringFunc(text, func(string) string {
+			count++
+			return r.mask
+		})
+		if count > 0 {
+			redactions = append(redactions, Redaction{Path: path, Rule: "pattern:" + pattern.name, Count: count})
+		}
+	}
+	return text, redactions
+}
+
+// RedactPayload masks a JSON payload. A payload that isn't JSON is redacted as text.
+func (r *Redactor) RedactPayload(payload []byte) ([]byte, []Redaction) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		text, redactions := r.RedactText("payload", string(payload))
+		return []byte(text), redactions
+	}
+
+	var redactions []Redaction
+	value = r.redactValue("", value, &redactions)
+	if len(redactions) == 0 {
+		return payload, nil
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		text, redactions := r.RedactText("payload", string(payload))
+		return []byte(text), redactions
+	}
+	return redacted, redactions
+}
+
+// RedactValue masks a decoded JSON value, as found in payload maps or log attributes
+func (r *Redactor) RedactValue(value any) (any, []Redaction) {
+	var redactions []Redaction
+	value = r.redactValue("", value, &redactions)
+	return value, redactions
+}
+
+func (r *Redactor) redactValue(path string, value any, redactions *[]Redaction) any {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Deterministic audit order
+		for _, key := range keys {
+			fieldPath := joinPath(path, key)
+			if r.MasksField(key) {
+				v[key] = r.mask
+				*redactions = append(*redactions, Redaction{Path: fieldPath, Rule: "field:" + strings.ToLower(key), Count: 1})
+				continue
+			}
+			v[key] = r.redactValue(fieldPath, v[key], redactions)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = r.redactValue(path+"["+strconv.Itoa(i)+"]", v[i], redactions)
+		}
+		return v
+	case string:
+		text, found := r.RedactText(path, v)
+		*redactions = append(*redactions, found...)
+		return text
+	default:
+		return value
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,144 @@
+package redaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedactor(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			patterns []Pattern
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given valid named patterns, When creating a redactor, Then should succeed",
+			in:   struct{ patterns []Pattern }{patterns: []Pattern{{Name: "email", Regex: EmailPattern}}},
+			want: struct{ err error }{err: nil},
+		},
+		{
+			name: "Given a pattern that doesn't compile, When creating a redactor, Then should return ErrInvalidPattern",
+			in:   struct{ patterns []Pattern }{patterns: []Pattern{{Name: "broken", Regex: "(unclosed"}}},
+			want: struct{ err error }{err: ErrInvalidPattern},
+		},
+		{
+			name: "Given a pattern without a name, When creating a redactor, Then should return ErrInvalidPattern",
+			in:   struct{ patterns []Pattern }{patterns: []Pattern{{Regex: EmailPattern}}},
+			want: struct{ err error }{err: ErrInvalidPattern},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := NewRedactor([]string{"password"}, tt.in.patterns, "")
+
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, DefaultMask, redactor.Mask())
+			}
+		})
+	}
+}
+
+func TestRedactor_RedactPayload(t *testing.T) {
+	redactor, _ := NewRedactor([]string{"Password", "token"}, []Pattern{{Name: "email", Regex: EmailPattern}}, "***")
+
+	tests := []struct {
+		name string
+		in   struct {
+			payload string
+		}
+		want struct {
+			payload    string
+			redactions []Redaction
+		}
+	}{
+		{
+			name: "Given a payload with a masked field, When redacting, Then should mask its value whatever its case",
+			in:   struct{ payload string }{payload: `{"user":"ana","PASSWORD":"hunter2"}`},
+			want: struct {
+				payload    string
+				redactions []Redaction
+			}{
+				payload:    `{"PASSWORD":"***","user":"ana"}`,
+				redactions: []Redaction{{Path: "PASSWORD", Rule: "field:password", Count: 1}},
+			},
+		},
+		{
+			name: "Given emails nested in objects and arrays, When redacting, Then should mask each match and record its path",
+			in:   struct{ payload string }{payload: `{"to":["a@example.com","b@example.com"],"meta":{"note":"cc c@example.com and d@example.com","retries":3}}`},
+			want: struct {
+				payload    string
+				redactions []Redaction
+			}{
+				payload: `{"meta":{"note":"cc *** and ***","retries":3},"to":["***","***"]}`,
+				redactions: []Redaction{
+					{Path: "meta.note", Rule: "pattern:email", Count: 2},
+					{Path: "to[0]", Rule: "pattern:email", Count: 1},
+					{Path: "to[1]", Rule: "pattern:email", Count: 1},
+				},
+			},
+		},
+		{
+			name: "Given a masked field holding an object, When redacting, Then should mask the whole object",
+			in:   struct{ payload string }{payload: `{"token":{"value":"abc","expires":60}}`},
+			want: struct {
+				payload    string
+				redactions []Redaction
+			}{
+				payload:    `{"token":"***"}`,
+				redactions: []Redaction{{Path: "token", Rule: "field:token", Count: 1}},
+			},
+		},
+		{
+			name: "Given a payload with nothing sensitive, When redacting, Then should return it unchanged",
+			in:   struct{ payload string }{payload: `{"count": 1.50}`},
+			want: struct {
+				payload    string
+				redactions []Redaction
+			}{payload: `{"count": 1.50}`},
+		},
+		{
+			name: "Given a payload that isn't JSON, When redacting, Then should mask matches in the raw text",
+			in:   struct{ payload string }{payload: `send to a@example.com`},
+			want: struct {
+				payload    string
+				redactions []Redaction
+			}{
+				payload:    `send to ***`,
+				redactions: []Redaction{{Path: "payload", Rule: "pattern:email", Count: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, redactions := redactor.RedactPayload([]byte(tt.in.payload))
+
+			assert.Equal(t, tt.want.payload, string(payload))
+			assert.Equal(t, tt.want.redactions, redactions)
+		})
+	}
+}
+
+func TestRedactor_RedactText(t *testing.T) {
+	redactor, _ := NewRedactor(nil, []Pattern{
+		{Name: "email", Regex: EmailPattern},
+		{Name: "card", Regex: `\b\d{4}(?:[ -]?\d{4}){3}\b`},
+	}, "")
+
+	text, redactions := redactor.RedactText("error", "charge of 4111 1111 1111 1111 for ana@example.com declined")
+
+	assert.Equal(t, "charge of [REDACTED] for [REDACTED] declined", text)
+	assert.Equal(t, []Redaction{
+		{Path: "error", Rule: "pattern:email", Count: 1},
+		{Path: "error", Rule: "pattern:card", Count: 1},
+	}, redactions)
+}
@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// ThroughputWindow is the span processed jobs per second are averaged over
+const ThroughputWindow = time.Minute
+
+// Outcomes of a job execution counted by Activity
+const (
+	OutcomeCompleted = "completed"
+	OutcomeFailed    = "failed"
+)
+
+// RunningJob is a job a worker is executing
+type RunningJob struct {
+	JobID     uuid.UUID
+	Queue     string
+	Type      string
+	Attempt   int
+	StartedAt time.Time
+}
+
+// ProcessedCount counts the executions of one job type of a queue that ended with one outcome
+type ProcessedCount struct {
+	Queue   string
+	Type    string
+	Outcome string
+	Count   int64
+}
+
+// ActivitySnapshot is what a worker-runtime process is doing and has done since it started
+type ActivitySnapshot struct {
+	Running   []RunningJob     // Longest running first
+	Processed []ProcessedCount // Sorted by queue, type and outcome
+	PerSecond float64          // Executions ended per second over the last ThroughputWindow
+}
+
+type processedKey struct {
+	queue, jobType, outcome string
+}
+
+// Activity tracks the jobs the workers of a process run, for the admin server.
+// It is safe for concurrent use, and a nil Activity tracks nothing.
+type Activity struct {
+	mu        sync.Mutex
+	running   map[uuid.UUID]RunningJob
+	processed map[processedKey]int64
+	// Executions ended in each second of the throughput window and the current second, keyed by
+	// Unix second modulo their number
+	buckets [int(ThroughputWindow/time.Second) + 1]struct {
+		second int64
+		count  int64
+	}
+}
+
+// NewActivity creates an empty activity tracker
+func NewActivity() *Activity {
+	return &Activity{
+		running:   make(map[uuid.UUID]RunningJob),
+		processed: make(map[processedKey]int64),
+	}
+}
+
+// Started records that a worker began executing the job
+func (a *Activity) Started(job *queue.Job, at time.Time) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.running[job.ID] = RunningJob{
+		JobID:     job.ID,
+		Queue:     job.Queue,
+		Type:      job.Type,
+		Attempt:   job.Attempts,
+		StartedAt: at,
+	}
+}
+
+// Finished records that the execution of the job ended with the outcome
+func (a *Activity) Finished(job *queue.Job, outcome string, at time.Time) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.running, job.ID)
+	a.processed[processedKey{job.Queue, job.Type, outcome}]++
+
+	second := at.Unix()
+	bucket := &a.buckets[second%int64(len(a.buckets))]
+	if bucket.second != second {
+		bucket.second, bucket.count = second, 0
+	}
+	bucket.count++
+}
+
+// Snapshot returns the activity as of now
+func (a *Activity) Snapshot(now time.Time) ActivitySnapshot {
+	snapshot := ActivitySnapshot{Running: []RunningJob{}, Processed: []ProcessedCount{}}
+	if a == nil {
+		return snapshot
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, job := range a.running {
+		snapshot.Running = append(snapshot.Running, job)
+	}
+	slices.SortFunc(snapshot.Running, func(x, y RunningJob) int {
+		return cmp.Or(x.StartedAt.Compare(y.StartedAt), cmp.Compare(x.JobID.String(), y.JobID.String()))
+	})
+
+	for key, count := range a.processed {
+		snapshot.Processed = append(snapshot.Processed, ProcessedCount{
+			Queue: key.queue, Type: key.jobType, Outcome: key.outcome, Count: count,
+		})
+	}
+	slices.SortFunc(snapshot.Processed, func(x, y ProcessedCount) int {
+		return cmp.Or(cmp.Compare(x.Queue, y.Queue), cmp.Compare(x.Type, y.Type), cmp.Compare(x.Outcome, y.Outcome))
+	})
+
+	// The current second is still filling, so the window is the seconds before it
+	var ended int64
+	current := now.Unix()
+	for _, bucket := range a.buckets {
+		if bucket.second < current && bucket.second >= current-int64(ThroughputWindow.Seconds()) {
+			ended += bucket.count
+		}
+	}
+	snapshot.PerSecond = float64(ended) / ThroughputWindow.Seconds()
+	return snapshot
+}
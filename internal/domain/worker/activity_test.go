@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivity_Snapshot(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "send-email", Attempts: 1}
+	second := &queue.Job{ID: uuid.New(), Queue: "emails", Type: "send-email", Attempts: 2}
+	third := &queue.Job{ID: uuid.New(), Queue: "reports", Type: "render", Attempts: 1}
+
+	tests := []struct {
+		name string
+		in   struct {
+			record func(*Activity)
+		}
+		want struct {
+			running   []uuid.UUID
+			processed []ProcessedCount
+			perSecond float64
+		}
+	}{
+		{
+			name: "Given jobs that started and some that ended, When taking a snapshot, Then should list the running ones longest first and count the ended ones",
+			in: struct{ record func(*Activity) }{record: func(a *Activity) {
+				a.Started(first, now.Add(-10*time.Second))
+				a.Started(second, now.Add(-30*time.Second))
+				a.Started(third, now.Add(-20*time.Second))
+				a.Finished(first, OutcomeCompleted, now.Add(-5*time.Second))
+			}},
+			want: struct {
+				running   []uuid.UUID
+				processed []ProcessedCount
+				perSecond float64
+			}{
+				running:   []uuid.UUID{second.ID, third.ID},
+				processed: []ProcessedCount{{Queue: "emails", Type: "send-email", Outcome: OutcomeCompleted, Count: 1}},
+				perSecond: 1.0 / 60,
+			},
+		},
+		{
+			name: "Given executions ended before and within the last minute, When taking a snapshot, Then should average only those within it",
+			in: struct{ record func(*Activity) }{record: func(a *Activity) {
+				a.Finished(first, OutcomeFailed, now.Add(-2*time.Minute))
+				a.Finished(first, OutcomeFailed, now.Add(-time.Minute))
+				a.Finished(second, OutcomeCompleted, now.Add(-time.Second))
+				a.Finished(third, OutcomeCompleted, now)
+			}},
+			want: struct {
+				running   []uuid.UUID
+				processed []ProcessedCount
+				perSecond float64
+			}{
+				running: []uuid.UUID{},
+				processed: []ProcessedCount{
+					{Queue: "emails", Type: "send-email", Outcome: OutcomeCompleted, Count: 1},
+					{Queue: "emails", Type: "send-email", Outcome: OutcomeFailed, Count: 2},
+					{Queue: "reports", Type: "render", Outcome: OutcomeCompleted, Count: 1},
+				},
+				perSecond: 2.0 / 60,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := NewActivity()
+			tt.in.record(activity)
+
+			snapshot := activity.Snapshot(now)
+
+			running := []uuid.UUID{}
+			for _, job := range snapshot.Running {
+				running = append(running, job.JobID)
+			}
+			assert.Equal(t, tt.want.running, running)
+			assert.Equal(t, tt.want.processed, snapshot.Processed)
+			assert.InDelta(t, tt.want.perSecond, snapshot.PerSecond, 1e-9)
+		})
+	}
+}
+
+func TestActivity_Nil(t *testing.T) {
+	var activity *Activity
+	job := &queue.Job{ID: uuid.New()}
+
+	activity.Started(job, time.Now())
+	activity.Finished(job, OutcomeCompleted, time.Now())
+
+	assert.Empty(t, activity.Snapshot(time.Now()).Running)
+}
@@ -0,0 +1,212 @@
+package worker
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a job type's circuit breaker
+type BreakerState string
+
+const (
+	BreakerClosed BreakerState = "closed" // Jobs of the type run normally
+	BreakerOpen   BreakerState = "open"   // Jobs of the type are paused until the cooldown ends
+)
+
+var ErrInvalidBreakerConfig = errors.New("failure threshold must be in (0, 1] and window, cooldown and min samples must be positive")
+
+// BreakerConfig controls when a job type's breaker trips
+type BreakerConfig struct {
+	FailureThreshold float64       // Failure ratio that trips the breaker, e.g. 0.8
+	Window           time.Duration // Outcomes older than this are ignored
+	MinSamples       int           // Outcomes needed in the window before the breaker may trip
+	Cooldown         time.Duration // How long a tripped type stays paused
+}
+
+// DefaultBreakerConfig trips at 80% failures over 5 minutes and pauses for 2 minutes
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 0.8,
+		Window:           5 * time.Minute,
+		MinSamples:       10,
+		Cooldown:         2 * time.Minute,
+	}
+}
+
+// Validate checks the configuration values
+func (c BreakerConfig) Validate() error {
+	if c.FailureThreshold <= 0 || c.FailureThreshold > 1 || c.Window <= 0 || c.Cooldown <= 0 || c.MinSamples <= 0 {
+		return ErrInvalidBreakerConfig
+	}
+	return nil
+}
+
+// BreakerStatus is a snapshot of one job type's breaker
+type BreakerStatus struct {
+	JobType     string       `json:"job_type"`
+	State       BreakerState `json:"state"`
+	FailureRate float64      `json:"failure_rate"`
+	Samples     int          `json:"samples"`
+	OpenedAt    *time.Time   `json:"opened_at,omitempty"`
+	OpenUntil   *time.Time   `json:"open_until,omitempty"`
+	WorkerID    string       `json:"worker_id,omitempty"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// At returns the status as of now: an open breaker whose cooldown has passed is reported closed
+func (s BreakerStatus) At(now time.Time) BreakerStatus {
+	if s.State == BreakerOpen && s.OpenUntil != nil && !now.Before(*s.OpenUntil) {
+		s.State = BreakerClosed
+	}
+	return s
+}
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+type typeBreaker struct {
+	outcomes  []outcome
+	openedAt  time.Time
+	openUntil time.Time
+}
+
+// FailureBreaker pauses job types whose failure rate over a sliding window exceeds a threshold,
+// which usually means a downstream dependency is down and retries would only be burned
+type FailureBreaker struct {
+	mu    sync.Mutex
+	cfg   BreakerConfig
+	now   func() time.Time
+	types map[string]*typeBreaker
+}
+
+// NewFailureBreaker creates a breaker with the given configuration
+func NewFailureBreaker(cfg BreakerConfig) (*FailureBreaker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &FailureBreaker{
+		cfg:   cfg,
+		now:   time.Now,
+		types: make(map[string]*typeBreaker),
+	}, nil
+}
+
+func (b *FailureBreaker) breakerFor(jobType string) *typeBreaker {
+	tb, ok := b.types[jobType]
+	if !ok {
+		tb = &typeBreaker{}
+		b.types[jobType] = tb
+	}
+	return tb
+}
+
+// Allow reports whether jobs of the type may run. reclosed is true on the call
+// that ends a cooldown; the type then starts over with an empty window.
+func (b *FailureBreaker) Allow(jobType string) (allowed bool, reclosed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tb, ok := b.types[jobType]
+	if !ok || tb.openUntil.IsZero() {
+		return true, false
+	}
+	if b.now().Before(tb.openUntil) {
+		return false, false
+	}
+
+	tb.openedAt = time.Time{}
+	tb.openUntil = time.Time{}
+	tb.outcomes = nil
+	return true, true
+}
+
+// Record adds an execution outcome for the type and reports whether it tripped the breaker
+func (b *FailureBreaker) Record(jobType string, failed bool) (tripped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	tb := b.breakerFor(jobType)
+	if !tb.openUntil.IsZero() {
+		// Jobs already running when the breaker opened don't extend the pause
+		return false
+	}
+
+	tb.outcomes = append(b.prune(tb.outcomes, now), outcome{at: now, failed: failed})
+	rate, samples := failureRate(tb.outcomes)
+	if samples < b.cfg.MinSamples || rate < b.cfg.FailureThreshold {
+		return false
+	}
+
+	tb.openedAt = now
+	tb.openUntil = now.Add(b.cfg.Cooldown)
+	return true
+}
+
+// Status returns the current snapshot for a job type
+func (b *FailureBreaker) Status(jobType string) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status(jobType, b.breakerFor(jobType))
+}
+
+// Snapshot returns the status of every job type seen so far, sorted by type
+func (b *FailureBreaker) Snapshot() []BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(b.types))
+	for jobType, tb := range b.types {
+		statuses = append(statuses, b.status(jobType, tb))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].JobType < statuses[j].JobType })
+	return statuses
+}
+
+func (b *FailureBreaker) status(jobType string, tb *typeBreaker) BreakerStatus {
+	now := b.now()
+	tb.outcomes = b.prune(tb.outcomes, now)
+	rate, samples := failureRate(tb.outcomes)
+
+	status := BreakerStatus{
+		JobType:     jobType,
+		State:       BreakerClosed,
+		FailureRate: rate,
+		Samples:     samples,
+		UpdatedAt:   now.UTC(),
+	}
+	if !tb.openUntil.IsZero() {
+		openedAt, openUntil := tb.openedAt.UTC(), tb.openUntil.UTC()
+		status.State = BreakerOpen
+		status.OpenedAt = &openedAt
+		status.OpenUntil = &openUntil
+	}
+	return status.At(now)
+}
+
+// prune drops outcomes that fell out of the window
+func (b *FailureBreaker) prune(outcomes []outcome, now time.Time) []outcome {
+	cutoff := now.Add(-b.cfg.Window)
+	i := 0
+	for i < len(outcomes) && outcomes[i].at.Before(cutoff) {
+		i++
+	}
+	return outcomes[i:]
+}
+
+func failureRate(outcomes []outcome) (float64, int) {
+	if len(outcomes) == 0 {
+		return 0, 0
+	}
+	failures := 0
+	for _, o := range outcomes {
+		if o.failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(outcomes)), len(outcomes)
+}
@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBreaker(t *testing.T, now *time.Time) *FailureBreaker {
+	t.Helper()
+	breaker, err := NewFailureBreaker(BreakerConfig{
+		FailureThreshold: 0.8,
+		Window:           5 * time.Minute,
+		MinSamples:       5,
+		Cooldown:         2 * time.Minute,
+	})
+	assert.NoError(t, err)
+	breaker.now = func() time.Time { return *now }
+	return breaker
+}
+
+func TestFailureBreaker_Record(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			outcomes []bool // true = failed
+		}
+		want struct {
+			tripped bool
+			state   BreakerState
+		}
+	}{
+		{
+			name: "Given failures above the threshold with enough samples, When recording, Then should trip the breaker",
+			in:   struct{ outcomes []bool }{outcomes: []bool{true, true, false, true, true, true}},
+			want: struct {
+				tripped bool
+				state   BreakerState
+			}{tripped: true, state: BreakerOpen},
+		},
+		{
+			name: "Given failures below the threshold, When recording, Then should stay closed",
+			in:   struct{ outcomes []bool }{outcomes: []bool{true, false, true, false, true, false}},
+			want: struct {
+				tripped bool
+				state   BreakerState
+			}{tripped: false, state: BreakerClosed},
+		},
+		{
+			name: "Given only failures but too few samples, When recording, Then should stay closed",
+			in:   struct{ outcomes []bool }{outcomes: []bool{true, true, true}},
+			want: struct {
+				tripped bool
+				state   BreakerState
+			}{tripped: false, state: BreakerClosed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			breaker := newTestBreaker(t, &now)
+
+			tripped := false
+			for _, failed := range tt.in.outcomes {
+				if breaker.Record("http", failed) {
+					tripped = true
+				}
+			}
+
+			assert.Equal(t, tt.want.tripped, tripped)
+			assert.Equal(t, tt.want.state, breaker.Status("http").State)
+			allowed, _ := breaker.Allow("http")
+			assert.Equal(t, tt.want.state == BreakerClosed, allowed)
+		})
+	}
+}
+
+func TestFailureBreaker_Cooldown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			elapsed time.Duration
+		}
+		want struct {
+			allowed  bool
+			reclosed bool
+		}
+	}{
+		{
+			name: "Given an open breaker within its cooldown, When checking, Then should keep the type paused",
+			in:   struct{ elapsed time.Duration }{elapsed: time.Minute},
+			want: struct {
+				allowed  bool
+				reclosed bool
+			}{allowed: false, reclosed: false},
+		},
+		{
+			name: "Given an open breaker past its cooldown, When checking, Then should close and allow jobs",
+			in:   struct{ elapsed time.Duration }{elapsed: 3 * time.Minute},
+			want: struct {
+				allowed  bool
+				reclosed bool
+			}{allowed: true, reclosed: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			breaker := newTestBreaker(t, &now)
+			for i := 0; i < 5; i++ {
+				breaker.Record("http", true)
+			}
+
+			now = now.Add(tt.in.elapsed)
+			allowed, reclosed := breaker.Allow("http")
+
+			assert.Equal(t, tt.want.allowed, allowed)
+			assert.Equal(t, tt.want.reclosed, reclosed)
+			if tt.want.reclosed {
+				assert.Equal(t, 0, breaker.Status("http").Samples)
+			}
+		})
+	}
+}
+
+func TestFailureBreaker_Window(t *testing.T) {
+	now := time.Now()
+	breaker := newTestBreaker(t, &now)
+	for i := 0; i < 4; i++ {
+		breaker.Record("http", true)
+	}
+
+	// Old failures fall out of the window, so one more failure doesn't trip the breaker
+	now = now.Add(6 * time.Minute)
+	tripped := breaker.Record("http", true)
+
+	assert.False(t, tripped)
+	assert.Equal(t, 1, breaker.Status("http").Samples)
+}
+
+func TestNewFailureBreaker_InvalidConfig(t *testing.T) {
+	_, err := NewFailureBreaker(BreakerConfig{FailureThreshold: 1.5, Window: time.Minute, MinSamples: 1, Cooldown: time.Minute})
+
+	assert.ErrorIs(t, err, ErrInvalidBreakerConfig)
+}
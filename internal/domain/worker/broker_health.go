@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// BrokerState is whether a worker-runtime process reaches its queue broker
+type BrokerState string
+
+const (
+	BrokerHealthy  BrokerState = "healthy"  // The last broker call succeeded, or failed fewer than AlertAfter times in a row
+	BrokerDegraded BrokerState = "degraded" // Broker calls failed AlertAfter times in a row
+)
+
+var ErrInvalidBrokerHealthConfig = errors.New("broker health base delay, max delay and alert threshold must be positive, and the base delay must not exceed the max delay")
+
+// BrokerHealthConfig controls how workers back off while the broker is unreachable and when
+// the process is reported degraded
+type BrokerHealthConfig struct {
+	BaseDelay  time.Duration // Wait before polling again after the first failure
+	MaxDelay   time.Duration // Upper bound for the wait
+	AlertAfter int           // Consecutive failures after which the process is degraded
+}
+
+// DefaultBrokerHealthConfig waits 1 to 30 seconds between polls and is degraded after 5 failures
+func DefaultBrokerHealthConfig() BrokerHealthConfig {
+	return BrokerHealthConfig{
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+		AlertAfter: 5,
+	}
+}
+
+// Validate checks the configuration values
+func (c BrokerHealthConfig) Validate() error {
+	if c.BaseDelay <= 0 || c.MaxDelay <= 0 || c.AlertAfter <= 0 || c.BaseDelay > c.MaxDelay {
+		return ErrInvalidBrokerHealthConfig
+	}
+	return nil
+}
+
+// BrokerHealthStatus is a snapshot of a process's broker health
+type BrokerHealthStatus struct {
+	State               BrokerState `json:"state"`
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+	LastError           string      `json:"last_error,omitempty"`
+	FailingSince        *time.Time  `json:"failing_since,omitempty"`
+}
+
+// BrokerFailure is what recording a failed broker call tells the worker
+type BrokerFailure struct {
+	Failures int       // Consecutive failures, this one included
+	Since    time.Time // First failure of the outage
+	Degraded bool      // This failure made the process degraded, and should be alerted on
+}
+
+// BrokerRecovery is what recording a successful broker call tells the worker
+type BrokerRecovery struct {
+	Failures    int           // Consecutive failures the call ended; zero when the broker was reachable
+	Downtime    time.Duration // Time since the first of those failures
+	WasDegraded bool          // The process was degraded, and its recovery should be alerted on
+}
+
+// BrokerHealth tracks the consecutive failures of the broker calls the workers of a process
+// make. While calls fail, workers wait about as long as the outage has lasted before polling
+// again, between the base and max delay, so the wait grows the same however many workers
+// share the tracker. It is safe for concurrent use.
+type BrokerHealth struct {
+	cfg BrokerHealthConfig
+
+	mu        sync.Mutex
+	failures  int
+	lastError string
+	since     time.Time // First failure of the current outage
+}
+
+// NewBrokerHealth creates a healthy tracker
+func NewBrokerHealth(cfg BrokerHealthConfig) (*BrokerHealth, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &BrokerHealth{cfg: cfg}, nil
+}
+
+// Failure records a failed broker call
+func (h *BrokerHealth) Failure(err error, now time.Time) BrokerFailure {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failures == 0 {
+		h.since = now
+	}
+	h.failures++
+	h.lastError = err.Error()
+	return BrokerFailure{
+		Failures: h.failures,
+		Since:    h.since,
+		Degraded: h.failures == h.cfg.AlertAfter,
+	}
+}
+
+// Backoff returns how long a worker waits before polling again: about as long as the outage
+// has lasted, between the base and max delay, and zero when the broker is reachable
+func (h *BrokerHealth) Backoff(now time.Time) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failures == 0 {
+		return 0
+	}
+	return min(max(now.Sub(h.since), h.cfg.BaseDelay), h.cfg.MaxDelay)
+}
+
+// Success records a successful broker call, ending the outage if there was one
+func (h *BrokerHealth) Success(now time.Time) BrokerRecovery {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recovery := BrokerRecovery{Failures: h.failures, WasDegraded: h.failures >= h.cfg.AlertAfter}
+	if h.failures > 0 {
+		recovery.Downtime = now.Sub(h.since)
+	}
+	h.failures = 0
+	h.lastError = ""
+	h.since = time.Time{}
+	return recovery
+}
+
+// Status returns the current broker health
+func (h *BrokerHealth) Status() BrokerHealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := BrokerHealthStatus{
+		State:               BrokerHealthy,
+		ConsecutiveFailures: h.failures,
+		LastError:           h.lastError,
+	}
+	if h.failures >= h.cfg.AlertAfter {
+		status.State = BrokerDegraded
+	}
+	if h.failures > 0 {
+		since := h.since
+		status.FailingSince = &since
+	}
+	return status
+}
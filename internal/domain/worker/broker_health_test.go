@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrokerHealth(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	errDown := errors.New("dial tcp: connection refused")
+
+	tests := []struct {
+		name string
+		in   struct {
+			failures []time.Duration // Offsets from start of the failed calls, in order
+		}
+		want struct {
+			last    BrokerFailure
+			backoff time.Duration
+			state   BrokerState
+		}
+	}{
+		{
+			name: "Given a first failure, When backing off, Then should wait the base delay and stay healthy",
+			in:   struct{ failures []time.Duration }{failures: []time.Duration{0}},
+			want: struct {
+				last    BrokerFailure
+				backoff time.Duration
+				state   BrokerState
+			}{last: BrokerFailure{Failures: 1, Since: start}, backoff: time.Second, state: BrokerHealthy},
+		},
+		{
+			name: "Given failures for 8 seconds, When backing off, Then should wait as long as the outage lasted",
+			in:   struct{ failures []time.Duration }{failures: []time.Duration{0, time.Second, 3 * time.Second, 8 * time.Second}},
+			want: struct {
+				last    BrokerFailure
+				backoff time.Duration
+				state   BrokerState
+			}{last: BrokerFailure{Failures: 4, Since: start}, backoff: 8 * time.Second, state: BrokerHealthy},
+		},
+		{
+			name: "Given as many failures as the alert threshold, When recording the last, Then should degrade once and cap the wait",
+			in: struct{ failures []time.Duration }{failures: []time.Duration{
+				0, time.Second, 3 * time.Second, 7 * time.Second, 2 * time.Minute,
+			}},
+			want: struct {
+				last    BrokerFailure
+				backoff time.Duration
+				state   BrokerState
+			}{last: BrokerFailure{Failures: 5, Since: start, Degraded: true}, backoff: 30 * time.Second, state: BrokerDegraded},
+		},
+		{
+			name: "Given more failures than the alert threshold, When recording them, Then should not degrade again",
+			in: struct{ failures []time.Duration }{failures: []time.Duration{
+				0, time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second, 5 * time.Second,
+			}},
+			want: struct {
+				last    BrokerFailure
+				backoff time.Duration
+				state   BrokerState
+			}{last: BrokerFailure{Failures: 6, Since: start}, backoff: 5 * time.Second, state: BrokerDegraded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health, err := NewBrokerHealth(DefaultBrokerHealthConfig())
+			require.NoError(t, err)
+
+			var last BrokerFailure
+			var now time.Time
+			for _, offset := range tt.in.failures {
+				now = start.Add(offset)
+				last = health.Failure(errDown, now)
+			}
+
+			assert.Equal(t, tt.want.last, last)
+			assert.Equal(t, tt.want.backoff, health.Backoff(now))
+			status := health.Status()
+			assert.Equal(t, tt.want.state, status.State)
+			assert.Equal(t, errDown.Error(), status.LastError)
+		})
+	}
+}
+
+func TestBrokerHealth_Success(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			failures int
+		}
+		want struct {
+			recovery BrokerRecovery
+		}
+	}{
+		{
+			name: "Given a reachable broker, When a call succeeds, Then should report no outage",
+			in:   struct{ failures int }{},
+			want: struct{ recovery BrokerRecovery }{},
+		},
+		{
+			name: "Given a short outage, When a call succeeds, Then should report it ended without having degraded",
+			in:   struct{ failures int }{failures: 2},
+			want: struct{ recovery BrokerRecovery }{recovery: BrokerRecovery{Failures: 2, Downtime: time.Minute}},
+		},
+		{
+			name: "Given a degraded process, When a call succeeds, Then should report the recovery",
+			in:   struct{ failures int }{failures: 5},
+			want: struct{ recovery BrokerRecovery }{recovery: BrokerRecovery{Failures: 5, Downtime: time.Minute, WasDegraded: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health, err := NewBrokerHealth(DefaultBrokerHealthConfig())
+			require.NoError(t, err)
+			for range tt.in.failures {
+				health.Failure(errors.New("connection refused"), start)
+			}
+
+			recovery := health.Success(start.Add(time.Minute))
+
+			assert.Equal(t, tt.want.recovery, recovery)
+			assert.Equal(t, BrokerHealthStatus{State: BrokerHealthy}, health.Status())
+			assert.Zero(t, health.Backoff(start.Add(time.Minute)))
+		})
+	}
+}
+
+func TestBrokerHealthConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			cfg BrokerHealthConfig
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given the defaults, When validating, Then should accept them",
+			in:   struct{ cfg BrokerHealthConfig }{cfg: DefaultBrokerHealthConfig()},
+		},
+		{
+			name: "Given a base delay above the max delay, When validating, Then should reject it",
+			in:   struct{ cfg BrokerHealthConfig }{cfg: BrokerHealthConfig{BaseDelay: time.Minute, MaxDelay: time.Second, AlertAfter: 5}},
+			want: struct{ err error }{err: ErrInvalidBrokerHealthConfig},
+		},
+		{
+			name: "Given no alert threshold, When validating, Then should reject it",
+			in:   struct{ cfg BrokerHealthConfig }{cfg: BrokerHealthConfig{BaseDelay: time.Second, MaxDelay: time.Minute}},
+			want: struct{ err error }{err: ErrInvalidBrokerHealthConfig},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.err, tt.in.cfg.Validate())
+		})
+	}
+}
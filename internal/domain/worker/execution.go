@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// Metadata keys an ExecutionContext carries when the job has them, besides the metadata the
+// job was created with. These take precedence over job metadata under the same key.
+const (
+	MetadataGroupID      = "group_id"
+	MetadataRequires     = "requires"       // Comma separated capabilities
+	MetadataSigningKeyID = "signing_key_id" // Set for signed jobs only
+	MetadataCallbackURL  = "callback_url"
+	MetadataScheduledFor = "scheduled_for" // RFC 3339
+)
+
+// ExecutionContext describes the run of a job to its executor, so custom executors get the
+// same observability as the worker without reading it off the job themselves
+type ExecutionContext struct {
+	JobID       uuid.UUID
+	JobType     string
+	Queue       string
+	Attempt     int // 1 on the first run
+	MaxAttempts int // Runs allowed before the job is dead-lettered
+	WorkerID    string
+	StartedAt   time.Time
+	Deadline    time.Time    // When the run is cancelled; zero when it isn't bounded
+	Logger      *slog.Logger // Logs with jobId, jobType, queue and attempt attached
+
+	metadata map[string]string
+}
+
+// NewExecutionContext describes the next run of the job on the worker, with a logger derived
+// from base. ctx bounds the run; its deadline, if any, becomes the run's.
+func NewExecutionContext(ctx context.Context, base *slog.Logger, job *queue.Job, workerID string, maxAttempts int, startedAt time.Time) *ExecutionContext {
+	exec := &ExecutionContext{
+		JobID:       job.ID,
+		JobType:     job.Type,
+		Queue:       job.Queue,
+		Attempt:     job.Attempts + 1,
+		MaxAttempts: maxAttempts,
+		WorkerID:    workerID,
+		StartedAt:   startedAt,
+		metadata:    jobMetadata(job),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		exec.Deadline = deadline
+	}
+	exec.Logger = base.With(
+		slog.String("jobId", job.ID.String()),
+		slog.String("jobType", job.Type),
+		slog.String("queue", job.Queue),
+		slog.Int("attempt", exec.Attempt),
+	)
+	return exec
+}
+
+func jobMetadata(job *queue.Job) map[string]string {
+	metadata := maps.Clone(job.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	if job.GroupID != nil {
+		metadata[MetadataGroupID] = job.GroupID.String()
+	}
+	if len(job.Requires) > 0 {
+		metadata[MetadataRequires] = strings.Join(job.Requires, ",")
+	}
+	if job.SigningKeyID != "" {
+		metadata[MetadataSigningKeyID] = job.SigningKeyID
+	}
+	if job.CallbackURL != "" {
+		metadata[MetadataCallbackURL] = job.CallbackURL
+	}
+	if job.ScheduledFor != nil {
+		metadata[MetadataScheduledFor] = job.ScheduledFor.UTC().Format(time.RFC3339)
+	}
+	return metadata
+}
+
+// Metadata returns the value of a metadata key, e.g. MetadataGroupID
+func (e *ExecutionContext) Metadata(key string) (string, bool) {
+	value, ok := e.metadata[key]
+	return value, ok
+}
+
+// MetadataKeys returns the metadata keys the job has, in no particular order
+func (e *ExecutionContext) MetadataKeys() []string {
+	keys := make([]string, 0, len(e.metadata))
+	for key := range e.metadata {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// LastAttempt reports whether a failure of this run dead-letters the job instead of retrying
+// it, unless the failure is throttled
+func (e *ExecutionContext) LastAttempt() bool {
+	return e.MaxAttempts > 0 && e.Attempt >= e.MaxAttempts
+}
+
+// Remaining returns the time left before the deadline; ok is false when the run isn't bounded
+func (e *ExecutionContext) Remaining(now time.Time) (remaining time.Duration, ok bool) {
+	if e.Deadline.IsZero() {
+		return 0, false
+	}
+	return max(e.Deadline.Sub(now), 0), true
+}
+
+type executionContextKey struct{}
+
+// WithExecutionContext returns a context carrying the description of the job run
+func WithExecutionContext(ctx context.Context, exec *ExecutionContext) context.Context {
+	return context.WithValue(ctx, executionContextKey{}, exec)
+}
+
+// ExecutionContextFrom returns the description of the job being executed; ok is false outside
+// a worker run, e.g. when an executor is called directly in a test
+func ExecutionContextFrom(ctx context.Context) (*ExecutionContext, bool) {
+	exec, ok := ctx.Value(executionContextKey{}).(*ExecutionContext)
+	return exec, ok
+}
+
+// LoggerFrom returns the job-scoped logger of the run, or the default logger outside one
+func LoggerFrom(ctx context.Context) *slog.Logger {
+	if exec, ok := ExecutionContextFrom(ctx); ok && exec.Logger != nil {
+		return exec.Logger
+	}
+	return slog.Default()
+}
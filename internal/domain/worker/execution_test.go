@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExecutionContext(t *testing.T) {
+	groupID := uuid.New()
+	scheduledFor := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		in   struct {
+			mutate      func(*queue.Job)
+			maxAttempts int
+			timeout     time.Duration
+		}
+		want struct {
+			attempt     int
+			lastAttempt bool
+			bounded     bool
+			metadata    map[string]string
+		}
+	}{
+		{
+			name: "Given a first run without a deadline, When describing it, Then should be unbounded and carry no metadata",
+			in: struct {
+				mutate      func(*queue.Job)
+				maxAttempts int
+				timeout     time.Duration
+			}{mutate: func(*queue.Job) {}, maxAttempts: 3},
+			want: struct {
+				attempt     int
+				lastAttempt bool
+				bounded     bool
+				metadata    map[string]string
+			}{attempt: 1, metadata: map[string]string{}},
+		},
+		{
+			name: "Given the last run of a grouped, scheduled job under a deadline, When describing it, Then should say so and carry its metadata",
+			in: struct {
+				mutate      func(*queue.Job)
+				maxAttempts int
+				timeout     time.Duration
+			}{
+				mutate: func(job *queue.Job) {
+					job.Attempts = 2
+					job.GroupID = &groupID
+					job.Requires = []string{"gpu", "region=eu"}
+					job.ScheduledFor = &scheduledFor
+				},
+				maxAttempts: 3,
+				timeout:     time.Minute,
+			},
+			want: struct {
+				attempt     int
+				lastAttempt bool
+				bounded     bool
+				metadata    map[string]string
+			}{
+				attempt:     3,
+				lastAttempt: true,
+				bounded:     true,
+				metadata: map[string]string{
+					MetadataGroupID:      groupID.String(),
+					MetadataRequires:     "gpu,region=eu",
+					MetadataScheduledFor: "2026-05-01T09:00:00Z",
+				},
+			},
+		},
+		{
+			name: "Given a job created with metadata, When describing its run, Then should carry it with the built-in keys taking precedence",
+			in: struct {
+				mutate      func(*queue.Job)
+				maxAttempts int
+				timeout     time.Duration
+			}{
+				mutate: func(job *queue.Job) {
+					job.GroupID = &groupID
+					job.Metadata = map[string]string{"correlation_id": "abc-123", MetadataGroupID: "spoofed"}
+				},
+				maxAttempts: 3,
+			},
+			want: struct {
+				attempt     int
+				lastAttempt bool
+				bounded     bool
+				metadata    map[string]string
+			}{
+				attempt: 1,
+				metadata: map[string]string{
+					"correlation_id": "abc-123",
+					MetadataGroupID:  groupID.String(),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			job, err := queue.NewJob("emails", "email", []byte(`{}`))
+			require.NoError(t, err)
+			tt.in.mutate(job)
+			ctx := context.Background()
+			if tt.in.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.in.timeout)
+				defer cancel()
+			}
+			var logs bytes.Buffer
+			base := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			// When
+			exec := NewExecutionContext(ctx, base, job, "worker-1", tt.in.maxAttempts, time.Now())
+			exec.Logger.Info("Sending email")
+
+			// Then
+			assert.Equal(t, tt.want.attempt, exec.Attempt)
+			assert.Equal(t, tt.want.lastAttempt, exec.LastAttempt())
+			remaining, bounded := exec.Remaining(time.Now())
+			assert.Equal(t, tt.want.bounded, bounded)
+			if bounded {
+				assert.Positive(t, remaining)
+			}
+			assert.ElementsMatch(t, keysOf(tt.want.metadata), exec.MetadataKeys())
+			for key, value := range tt.want.metadata {
+				got, ok := exec.Metadata(key)
+				assert.True(t, ok)
+				assert.Equal(t, value, got)
+			}
+
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+			assert.Equal(t, job.ID.String(), record["jobId"])
+			assert.Equal(t, "email", record["jobType"])
+			assert.Equal(t, "emails", record["queue"])
+			assert.EqualValues(t, tt.want.attempt, record["attempt"])
+		})
+	}
+}
+
+func TestLoggerFrom(t *testing.T) {
+	// Given
+	job, err := queue.NewJob("emails", "email", []byte(`{}`))
+	require.NoError(t, err)
+	exec := NewExecutionContext(context.Background(), slog.Default(), job, "worker-1", 3, time.Now())
+
+	// When
+	scoped := LoggerFrom(WithExecutionContext(context.Background(), exec))
+	outside := LoggerFrom(context.Background())
+
+	// Then
+	assert.Same(t, exec.Logger, scoped)
+	assert.Same(t, slog.Default(), outside)
+}
+
+func keysOf(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxQueueWeight bounds a queue's weight so one queue can't lock the others out for long
+const MaxQueueWeight = 100
+
+var ErrInvalidQueueWeight = errors.New("invalid queue weight, expected a queue name optionally followed by :weight, e.g. emails:3")
+
+// QueueWeight is a queue's share of a fair scheduler's polls
+type QueueWeight struct {
+	Queue  string
+	Weight int
+}
+
+// ParseQueueWeight parses "emails:3" into the queue and its weight; a queue without a weight has weight 1
+func ParseQueueWeight(spec string) (QueueWeight, error) {
+	name, weight, found := strings.Cut(strings.TrimSpace(spec), ":")
+	if name == "" {
+		return QueueWeight{}, fmt.Errorf("%w: %q", ErrInvalidQueueWeight, spec)
+	}
+	if !found {
+		return QueueWeight{Queue: name, Weight: 1}, nil
+	}
+	n, err := strconv.Atoi(weight)
+	if err != nil || n < 1 || n > MaxQueueWeight {
+		return QueueWeight{}, fmt.Errorf("%w: %q, weights go from 1 to %d", ErrInvalidQueueWeight, spec, MaxQueueWeight)
+	}
+	return QueueWeight{Queue: name, Weight: n}, nil
+}
+
+// FairScheduler picks the queue to poll next by smooth weighted round robin: with weights 3
+// and 1 every four polls go a, a, b, a, interleaved rather than in bursts. Callers move on to
+// the next queue when one is empty, so idle queues' turns go to busy ones and a flooded queue
+// gets its weight's share of the polls and never more while others have jobs.
+type FairScheduler struct {
+	mu      sync.Mutex
+	queues  []QueueWeight
+	current []int
+	total   int
+}
+
+// NewFairScheduler creates a scheduler over the queues; a queue may appear only once
+func NewFairScheduler(queues []QueueWeight) (*FairScheduler, error) {
+	if len(queues) == 0 {
+		return nil, ErrQueueNameRequired
+	}
+	seen := make(map[string]bool, len(queues))
+	total := 0
+	for _, q := range queues {
+		if q.Queue == "" {
+			return nil, ErrQueueNameRequired
+		}
+		if q.Weight < 1 || q.Weight > MaxQueueWeight {
+			return nil, fmt.Errorf("%w: %s has weight %d", ErrInvalidQueueWeight, q.Queue, q.Weight)
+		}
+		if seen[q.Queue] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidQueueWeight, q.Queue)
+		}
+		seen[q.Queue] = true
+		total += q.Weight
+	}
+	return &FairScheduler{
+		queues:  append([]QueueWeight(nil), queues...),
+		current: make([]int, len(queues)),
+		total:   total,
+	}, nil
+}
+
+// Queues returns the scheduled queues and their weights
+func (s *FairScheduler) Queues() []QueueWeight {
+	return append([]QueueWeight(nil), s.queues...)
+}
+
+// Next returns the queue to poll next
+func (s *FairScheduler) Next() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	best := 0
+	for i, q := range s.queues {
+		s.current[i] += q.Weight
+		if s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	s.current[best] -= s.total
+	return s.queues[best].Queue
+}
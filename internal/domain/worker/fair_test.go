@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQueueWeight(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			spec string
+		}
+		want struct {
+			weight QueueWeight
+			err    error
+		}
+	}{
+		{
+			name: "Given a queue with a weight, When parsing, Then should return both",
+			in:   struct{ spec string }{spec: "emails:3"},
+			want: struct {
+				weight QueueWeight
+				err    error
+			}{weight: QueueWeight{Queue: "emails", Weight: 3}},
+		},
+		{
+			name: "Given a queue without a weight, When parsing, Then should weigh it 1",
+			in:   struct{ spec string }{spec: " reports "},
+			want: struct {
+				weight QueueWeight
+				err    error
+			}{weight: QueueWeight{Queue: "reports", Weight: 1}},
+		},
+		{
+			name: "Given a zero weight, When parsing, Then should return ErrInvalidQueueWeight",
+			in:   struct{ spec string }{spec: "emails:0"},
+			want: struct {
+				weight QueueWeight
+				err    error
+			}{err: ErrInvalidQueueWeight},
+		},
+		{
+			name: "Given a weight that isn't a number, When parsing, Then should return ErrInvalidQueueWeight",
+			in:   struct{ spec string }{spec: "emails:high"},
+			want: struct {
+				weight QueueWeight
+				err    error
+			}{err: ErrInvalidQueueWeight},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weight, err := ParseQueueWeight(tt.in.spec)
+
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.weight, weight)
+		})
+	}
+}
+
+func TestFairScheduler_Next(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			queues []QueueWeight
+			polls  int
+		}
+		want struct {
+			order []string
+		}
+	}{
+		{
+			name: "Given equal weights, When polling, Then should alternate between the queues",
+			in: struct {
+				queues []QueueWeight
+				polls  int
+			}{queues: []QueueWeight{{Queue: "a", Weight: 1}, {Queue: "b", Weight: 1}}, polls: 4},
+			want: struct{ order []string }{order: []string{"a", "b", "a", "b"}},
+		},
+		{
+			name: "Given weights 3 and 1, When polling, Then should interleave three polls of a with one of b",
+			in: struct {
+				queues []QueueWeight
+				polls  int
+			}{queues: []QueueWeight{{Queue: "a", Weight: 3}, {Queue: "b", Weight: 1}}, polls: 8},
+			want: struct{ order []string }{order: []string{"a", "a", "b", "a", "a", "a", "b", "a"}},
+		},
+		{
+			name: "Given weights 5, 1 and 1, When polling, Then should spread the light queues between the heavy one's polls",
+			in: struct {
+				queues []QueueWeight
+				polls  int
+			}{queues: []QueueWeight{{Queue: "a", Weight: 5}, {Queue: "b", Weight: 1}, {Queue: "c", Weight: 1}}, polls: 7},
+			want: struct{ order []string }{order: []string{"a", "a", "b", "a", "c", "a", "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler, err := NewFairScheduler(tt.in.queues)
+			assert.NoError(t, err)
+
+			order := make([]string, 0, tt.in.polls)
+			for range tt.in.polls {
+				order = append(order, scheduler.Next())
+			}
+
+			assert.Equal(t, tt.want.order, order)
+		})
+	}
+}
+
+func TestNewFairScheduler(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			queues []QueueWeight
+		}
+		want struct {
+			err error
+		}
+	}{
+		{
+			name: "Given no queues, When creating a scheduler, Then should return ErrQueueNameRequired",
+			in:   struct{ queues []QueueWeight }{},
+			want: struct{ err error }{err: ErrQueueNameRequired},
+		},
+		{
+			name: "Given a queue listed twice, When creating a scheduler, Then should return ErrInvalidQueueWeight",
+			in:   struct{ queues []QueueWeight }{queues: []QueueWeight{{Queue: "a", Weight: 1}, {Queue: "a", Weight: 2}}},
+			want: struct{ err error }{err: ErrInvalidQueueWeight},
+		},
+		{
+			name: "Given a weight over the maximum, When creating a scheduler, Then should return ErrInvalidQueueWeight",
+			in:   struct{ queues []QueueWeight }{queues: []QueueWeight{{Queue: "a", Weight: MaxQueueWeight + 1}}},
+			want: struct{ err error }{err: ErrInvalidQueueWeight},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFairScheduler(tt.in.queues)
+
+			assert.ErrorIs(t, err, tt.want.err)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import "time"
+
+// Worker-runtime processes report that they are alive every HeartbeatInterval and are
+// considered gone once they haven't for HeartbeatTTL
+const (
+	HeartbeatInterval = 15 * time.Second
+	HeartbeatTTL      = 3 * HeartbeatInterval
+)
+
+// Heartbeat is the latest report of a worker-runtime process
+type Heartbeat struct {
+	WorkerID     string    `json:"worker_id"`
+	Queues       []string  `json:"queues"`
+	Concurrency  int       `json:"concurrency"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// Alive reports whether the worker reported within HeartbeatTTL of now
+func (h Heartbeat) Alive(now time.Time) bool {
+	return now.Sub(h.LastSeen) < HeartbeatTTL
+}
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat_Alive(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			lastSeen time.Time
+		}
+		want struct {
+			alive bool
+		}
+	}{
+		{
+			name: "Given a heartbeat reported one interval ago, When checking, Then should be alive",
+			in:   struct{ lastSeen time.Time }{lastSeen: now.Add(-HeartbeatInterval)},
+			want: struct{ alive bool }{alive: true},
+		},
+		{
+			name: "Given a heartbeat older than the TTL, When checking, Then should not be alive",
+			in:   struct{ lastSeen time.Time }{lastSeen: now.Add(-HeartbeatTTL)},
+			want: struct{ alive bool }{alive: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heartbeat := Heartbeat{WorkerID: "worker-1", LastSeen: tt.in.lastSeen}
+
+			assert.Equal(t, tt.want.alive, heartbeat.Alive(now))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+var ErrInvalidIdempotencyPolicy = errors.New("invalid idempotency policy")
+
+// DefaultResultCacheTTL is how long the result of an idempotent job is reused when its policy
+// sets no TTL
+const DefaultResultCacheTTL = time.Hour
+
+// IdempotencyPolicy declares a job type idempotent: jobs of the type with the same cache key
+// produce the same result, so a duplicate within the TTL returns the cached result instead of
+// running again
+type IdempotencyPolicy struct {
+	KeyFields []string      // Top-level payload fields the cache key is derived from; empty uses the whole payload
+	TTL       time.Duration // How long a result is reused; zero uses DefaultResultCacheTTL
+}
+
+// IdempotencyPolicies are the idempotency policies by job type; types without one always run
+type IdempotencyPolicies map[string]IdempotencyPolicy
+
+// For returns the policy of the job type, and whether the type is idempotent
+func (p IdempotencyPolicies) For(jobType string) (IdempotencyPolicy, bool) {
+	policy, ok := p[jobType]
+	return policy, ok
+}
+
+// Validate checks that the TTL isn't negative and that no key field is empty
+func (p IdempotencyPolicy) Validate() error {
+	if p.TTL < 0 {
+		return fmt.Errorf("%w: ttl must not be negative", ErrInvalidIdempotencyPolicy)
+	}
+	for _, field := range p.KeyFields {
+		if field == "" {
+			return fmt.Errorf("%w: key fields must not be empty", ErrInvalidIdempotencyPolicy)
+		}
+	}
+	return nil
+}
+
+// CacheTTL returns how long a result is reused
+func (p IdempotencyPolicy) CacheTTL() time.Duration {
+	if p.TTL <= 0 {
+		return DefaultResultCacheTTL
+	}
+	return p.TTL
+}
+
+// CacheKey derives the cache key of the job from its type and its payload, or the key fields of
+// the payload when the policy names some. The payload is re-encoded first, so neither the order
+// of its fields nor its spacing changes the key; a missing key field counts as null.
+func (p IdempotencyPolicy) CacheKey(job *queue.Job) (string, error) {
+	var payload any
+	if len(job.Payload) > 0 {
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return "", fmt.Errorf("decoding payload for the cache key: %w", err)
+		}
+	}
+	if len(p.KeyFields) > 0 {
+		fields, _ := payload.(map[string]any)
+		selected := make(map[string]any, len(p.KeyFields))
+		for _, field := range p.KeyFields {
+			selected[field] = fields[field]
+		}
+		payload = selected
+	}
+
+	canonical, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(job.Type+"\x00"), canonical...))
+	return job.Type + ":" + hex.EncodeToString(sum[:]), nil
+}
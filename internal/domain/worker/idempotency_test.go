@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyPolicy_CacheKey(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			policy IdempotencyPolicy
+			a, b   [2]string // job type, payload
+		}
+		want bool // same key
+	}{
+		{
+			name: "Given payloads differing in field order and spacing, When deriving keys, Then should share a key",
+			in: struct {
+				policy IdempotencyPolicy
+				a, b   [2]string
+			}{
+				a: [2]string{"data_processing", `{"dataset":"sales","rows":[1,2]}`},
+				b: [2]string{"data_processing", `{ "rows": [1, 2], "dataset": "sales" }`},
+			},
+			want: true,
+		},
+		{
+			name: "Given payloads differing outside the key fields, When deriving keys, Then should share a key",
+			in: struct {
+				policy IdempotencyPolicy
+				a, b   [2]string
+			}{
+				policy: IdempotencyPolicy{KeyFields: []string{"dataset"}},
+				a:      [2]string{"data_processing", `{"dataset":"sales","requested_by":"ana"}`},
+				b:      [2]string{"data_processing", `{"dataset":"sales","requested_by":"bo"}`},
+			},
+			want: true,
+		},
+		{
+			name: "Given payloads differing in a key field, When deriving keys, Then should not share a key",
+			in: struct {
+				policy IdempotencyPolicy
+				a, b   [2]string
+			}{
+				policy: IdempotencyPolicy{KeyFields: []string{"dataset"}},
+				a:      [2]string{"data_processing", `{"dataset":"sales"}`},
+				b:      [2]string{"data_processing", `{"dataset":"stock"}`},
+			},
+			want: false,
+		},
+		{
+			name: "Given the same payload of different job types, When deriving keys, Then should not share a key",
+			in: struct {
+				policy IdempotencyPolicy
+				a, b   [2]string
+			}{
+				a: [2]string{"data_processing", `{"dataset":"sales"}`},
+				b: [2]string{"report", `{"dataset":"sales"}`},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := tt.in.policy.CacheKey(&queue.Job{Type: tt.in.a[0], Payload: []byte(tt.in.a[1])})
+			require.NoError(t, err)
+			b, err := tt.in.policy.CacheKey(&queue.Job{Type: tt.in.b[0], Payload: []byte(tt.in.b[1])})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, a == b)
+		})
+	}
+}
+
+func TestIdempotencyPolicy_CacheKey_InvalidPayload(t *testing.T) {
+	_, err := IdempotencyPolicy{}.CacheKey(&queue.Job{Type: "report", Payload: []byte(`{"dataset":`)})
+	assert.Error(t, err, "Given a payload that isn't JSON, When deriving the key, Then should fail")
+}
+
+func TestIdempotencyPolicy_Validate(t *testing.T) {
+	assert.NoError(t, IdempotencyPolicy{KeyFields: []string{"dataset"}, TTL: time.Minute}.Validate())
+	assert.ErrorIs(t, IdempotencyPolicy{TTL: -time.Second}.Validate(), ErrInvalidIdempotencyPolicy)
+	assert.ErrorIs(t, IdempotencyPolicy{KeyFields: []string{""}}.Validate(), ErrInvalidIdempotencyPolicy)
+	assert.Equal(t, DefaultResultCacheTTL, IdempotencyPolicy{}.CacheTTL())
+}
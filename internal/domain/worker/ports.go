@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"time"
 
 	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 )
@@ -12,3 +13,30 @@ type JobExecutor interface {
 	Execute(ctx context.Context, job *queue.Job) (*ExecutionResult, error)
 	CanHandle(jobType string) bool
 }
+
+// ResultNotifier delivers the final state of a job to its callback URL.
+// Implementations must not block job processing while delivering.
+type ResultNotifier interface {
+	NotifyResult(ctx context.Context, job *queue.Job)
+}
+
+// BreakerStore shares circuit breaker state so it can be inspected outside the worker process
+type BreakerStore interface {
+	Save(ctx context.Context, status BreakerStatus) error
+	List(ctx context.Context) ([]BreakerStatus, error)
+}
+
+// HeartbeatStore records worker heartbeats so live workers can be listed outside their process
+type HeartbeatStore interface {
+	Beat(ctx context.Context, heartbeat Heartbeat) error
+	// List returns the heartbeats of workers that are still alive, ordered by worker ID
+	List(ctx context.Context) ([]Heartbeat, error)
+}
+
+// ResultCache keeps the results of idempotent jobs by cache key, so duplicates within the TTL
+// can return them without running
+type ResultCache interface {
+	// Get returns the cached result of the key; found is false when there is none or it expired
+	Get(ctx context.Context, key string) (result []byte, found bool, err error)
+	Set(ctx context.Context, key string, result []byte, ttl time.Duration) error
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrInvalidRetryBudget = errors.New("retry budget ratio must be in (0, 1], window must be positive and min retries can't be negative")
+
+// RetryBudgetConfig controls how many retries a queue may spend
+type RetryBudgetConfig struct {
+	Ratio      float64       // Retries allowed per first attempt in the window, e.g. 0.2
+	Window     time.Duration // First attempts and retries older than this are ignored
+	MinRetries int           // Retries allowed in the window whatever the ratio, so quiet queues still retry
+}
+
+// DefaultRetryBudgetConfig allows retries of up to 20% of the first attempts over a minute,
+// and 10 retries a minute whatever the traffic
+func DefaultRetryBudgetConfig() RetryBudgetConfig {
+	return RetryBudgetConfig{
+		Ratio:      0.2,
+		Window:     time.Minute,
+		MinRetries: 10,
+	}
+}
+
+// Validate checks the configuration values
+func (c RetryBudgetConfig) Validate() error {
+	if c.Ratio <= 0 || c.Ratio > 1 || c.Window <= 0 || c.MinRetries < 0 {
+		return ErrInvalidRetryBudget
+	}
+	return nil
+}
+
+type queueBudget struct {
+	attempts []time.Time // First attempts, oldest first
+	retries  []time.Time // Retries spent, oldest first
+}
+
+// RetryBudget caps the retries of each queue at a share of its first attempts over a sliding
+// window. When a downstream goes down every job fails and would retry several times, multiplying
+// the load on it just as it tries to recover; once the budget is spent failing jobs are parked
+// instead.
+type RetryBudget struct {
+	mu     sync.Mutex
+	cfg    RetryBudgetConfig
+	now    func() time.Time
+	queues map[string]*queueBudget
+}
+
+// NewRetryBudget creates a budget with the given configuration
+func NewRetryBudget(cfg RetryBudgetConfig) (*RetryBudget, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &RetryBudget{
+		cfg:    cfg,
+		now:    time.Now,
+		queues: make(map[string]*queueBudget),
+	}, nil
+}
+
+func (b *RetryBudget) budgetFor(queueName string) *queueBudget {
+	qb, ok := b.queues[queueName]
+	if !ok {
+		qb = &queueBudget{}
+		b.queues[queueName] = qb
+	}
+	return qb
+}
+
+// RecordAttempt adds a first attempt of a job in the queue, which earns the queue retries
+func (b *RetryBudget) RecordAttempt(queueName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	qb := b.budgetFor(queueName)
+	qb.attempts = append(b.prune(qb.attempts, now), now)
+}
+
+// Spend takes a retry from the queue's budget, reporting false when the budget is exhausted
+func (b *RetryBudget) Spend(queueName string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	qb := b.budgetFor(queueName)
+	qb.attempts = b.prune(qb.attempts, now)
+	qb.retries = b.prune(qb.retries, now)
+	if len(qb.retries) >= b.allowed(len(qb.attempts)) {
+		return false
+	}
+	qb.retries = append(qb.retries, now)
+	return true
+}
+
+// allowed returns how many retries the first attempts in the window earn
+func (b *RetryBudget) allowed(attempts int) int {
+	return max(b.cfg.MinRetries, int(b.cfg.Ratio*float64(attempts)))
+}
+
+// prune drops the times that fell out of the window
+func (b *RetryBudget) prune(times []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-b.cfg.Window)
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
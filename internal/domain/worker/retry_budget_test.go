@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBudget_Spend(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			attempts int
+			retries  int
+			elapsed  time.Duration // Between recording them and the last spend
+		}
+		want struct {
+			spent int // Retries the budget granted
+			last  bool
+		}
+	}{
+		{
+			name: "Given few first attempts, When spending retries, Then should grant the minimum retries",
+			in: struct {
+				attempts int
+				retries  int
+				elapsed  time.Duration
+			}{attempts: 5, retries: 4},
+			want: struct {
+				spent int
+				last  bool
+			}{spent: 2, last: false},
+		},
+		{
+			name: "Given many first attempts, When spending retries, Then should grant the ratio of them",
+			in: struct {
+				attempts int
+				retries  int
+				elapsed  time.Duration
+			}{attempts: 50, retries: 12},
+			want: struct {
+				spent int
+				last  bool
+			}{spent: 10, last: false},
+		},
+		{
+			name: "Given an exhausted budget, When the window passed, Then should grant retries again",
+			in: struct {
+				attempts int
+				retries  int
+				elapsed  time.Duration
+			}{attempts: 50, retries: 12, elapsed: 2 * time.Minute},
+			want: struct {
+				spent int
+				last  bool
+			}{spent: 10, last: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+			budget, err := NewRetryBudget(RetryBudgetConfig{Ratio: 0.2, Window: time.Minute, MinRetries: 2})
+			assert.NoError(t, err)
+			budget.now = func() time.Time { return now }
+
+			for range tt.in.attempts {
+				budget.RecordAttempt("emails")
+			}
+			spent := 0
+			for range tt.in.retries {
+				if budget.Spend("emails") {
+					spent++
+				}
+			}
+			now = now.Add(tt.in.elapsed)
+
+			assert.Equal(t, tt.want.spent, spent)
+			assert.Equal(t, tt.want.last, budget.Spend("emails"))
+			assert.True(t, budget.Spend("reports"), "other queues have their own budget")
+		})
+	}
+}
+
+func TestRetryBudgetConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   RetryBudgetConfig
+		want error
+	}{
+		{
+			name: "Given the defaults, When validating, Then should pass",
+			in:   DefaultRetryBudgetConfig(),
+		},
+		{
+			name: "Given a ratio above one, When validating, Then should fail",
+			in:   RetryBudgetConfig{Ratio: 1.5, Window: time.Minute},
+			want: ErrInvalidRetryBudget,
+		},
+		{
+			name: "Given no window, When validating, Then should fail",
+			in:   RetryBudgetConfig{Ratio: 0.2},
+			want: ErrInvalidRetryBudget,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.in.Validate(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// BackoffStrategy is how the delay before a retry grows with the attempts
+type BackoffStrategy string
+
+const (
+	BackoffExponential BackoffStrategy = "exponential" // base, doubled per attempt
+	BackoffLinear      BackoffStrategy = "linear"      // base times the attempt
+	BackoffFixed       BackoffStrategy = "fixed"       // base every time
+)
+
+// DLQBehavior is what happens to a job that fails for good
+type DLQBehavior string
+
+const (
+	// DLQDeadLetter marks the job failed and keeps it in the dead letters
+	DLQDeadLetter DLQBehavior = "dead_letter"
+	// DLQDiscard marks the job failed and soft-deletes it, without a dead letter or an AI analysis.
+	// It can still be restored with POST /api/jobs/{id}/undelete until it's purged.
+	DLQDiscard DLQBehavior = "discard"
+)
+
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// maxBackoffShift bounds the doubling of exponential backoff so the delay can't overflow
+const maxBackoffShift = 30
+
+// RetryPolicy overrides how the jobs of one type are retried. Zero values fall back to the
+// queue definition and then to the worker config.
+type RetryPolicy struct {
+	MaxAttempts int              // Overrides the max attempts when positive
+	Backoff     BackoffStrategy  // Empty is exponential
+	BaseBackoff time.Duration    // Overrides the base backoff when positive
+	MaxBackoff  time.Duration    // Upper bound for a retry delay; zero is unbounded
+	RetryOn     []*regexp.Regexp // When set, only errors matching one of them are retried
+	NoRetryOn   []*regexp.Regexp // Errors matching one of them aren't retried; wins over RetryOn
+	DLQ         DLQBehavior      // Empty is DLQDeadLetter
+}
+
+// RetryPolicies are the retry policies by job type
+type RetryPolicies map[string]RetryPolicy
+
+// For returns the policy of the job type, or the zero policy when the type has none
+func (p RetryPolicies) For(jobType string) RetryPolicy {
+	return p[jobType]
+}
+
+// CompileErrorPatterns compiles the regular expressions errors are matched against
+func CompileErrorPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: error pattern %q: %v", ErrInvalidRetryPolicy, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Validate checks that no setting is negative and that the strategy and DLQ behavior are known
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 || p.BaseBackoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("%w: max attempts and backoff must not be negative", ErrInvalidRetryPolicy)
+	}
+	switch p.Backoff {
+	case "", BackoffExponential, BackoffLinear, BackoffFixed:
+	default:
+		return fmt.Errorf("%w: backoff must be exponential, linear or fixed", ErrInvalidRetryPolicy)
+	}
+	switch p.DLQ {
+	case "", DLQDeadLetter, DLQDiscard:
+	default:
+		return fmt.Errorf("%w: dlq must be dead_letter or discard", ErrInvalidRetryPolicy)
+	}
+	return nil
+}
+
+// WithDefaults returns the policy with the unset max attempts, base backoff, strategy and DLQ
+// behavior taken from the given defaults
+func (p RetryPolicy) WithDefaults(maxAttempts int, baseBackoff time.Duration) RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = maxAttempts
+	}
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = baseBackoff
+	}
+	if p.Backoff == "" {
+		p.Backoff = BackoffExponential
+	}
+	if p.DLQ == "" {
+		p.DLQ = DLQDeadLetter
+	}
+	return p
+}
+
+// Retries reports whether the policy lets a job failing with err be retried
+func (p RetryPolicy) Retries(err error) bool {
+	if err == nil {
+		return true
+	}
+	message := err.Error()
+	for _, re := range p.NoRetryOn {
+		if re.MatchString(message) {
+			return false
+		}
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	for _, re := range p.RetryOn {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns how long to wait before retrying a job that has run attempt times
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	attempt = max(attempt, 0)
+	var delay time.Duration
+	switch p.Backoff {
+	case BackoffLinear:
+		delay = p.BaseBackoff * time.Duration(max(attempt, 1))
+	case BackoffFixed:
+		delay = p.BaseBackoff
+	default:
+		delay = p.BaseBackoff * time.Duration(1<<min(attempt, maxBackoffShift))
+	}
+	if p.MaxBackoff > 0 {
+		delay = min(delay, p.MaxBackoff)
+	}
+	return delay
+}
@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Retries(t *testing.T) {
+	retryOn, err := CompileErrorPatterns([]string{"timeout", `connection (reset|refused)`})
+	require.NoError(t, err)
+	noRetryOn, err := CompileErrorPatterns([]string{`(?i)invalid recipient`})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		in   struct {
+			policy RetryPolicy
+			err    error
+		}
+		want bool
+	}{
+		{
+			name: "Given a policy without patterns, When checking any error, Then should retry",
+			in: struct {
+				policy RetryPolicy
+				err    error
+			}{policy: RetryPolicy{}, err: errors.New("boom")},
+			want: true,
+		},
+		{
+			name: "Given retry patterns, When the error matches one, Then should retry",
+			in: struct {
+				policy RetryPolicy
+				err    error
+			}{policy: RetryPolicy{RetryOn: retryOn}, err: errors.New("dial tcp: connection refused")},
+			want: true,
+		},
+		{
+			name: "Given retry patterns, When the error matches none, Then should not retry",
+			in: struct {
+				policy RetryPolicy
+				err    error
+			}{policy: RetryPolicy{RetryOn: retryOn}, err: errors.New("boom")},
+		},
+		{
+			name: "Given an error matching both kinds of pattern, When checking it, Then the non-retryable pattern should win",
+			in: struct {
+				policy RetryPolicy
+				err    error
+			}{policy: RetryPolicy{RetryOn: retryOn, NoRetryOn: noRetryOn}, err: errors.New("timeout: Invalid Recipient")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.in.policy.Retries(tt.in.err))
+		})
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			policy  RetryPolicy
+			attempt int
+		}
+		want time.Duration
+	}{
+		{
+			name: "Given exponential backoff, When computing the delay, Then should match CalculateBackoff",
+			in: struct {
+				policy  RetryPolicy
+				attempt int
+			}{policy: RetryPolicy{}.WithDefaults(3, 500*time.Millisecond), attempt: 3},
+			want: CalculateBackoff(3, 500),
+		},
+		{
+			name: "Given linear backoff, When computing the delay, Then should multiply the base by the attempt",
+			in: struct {
+				policy  RetryPolicy
+				attempt int
+			}{policy: RetryPolicy{Backoff: BackoffLinear, BaseBackoff: time.Second}, attempt: 3},
+			want: 3 * time.Second,
+		},
+		{
+			name: "Given fixed backoff, When computing the delay, Then should always wait the base",
+			in: struct {
+				policy  RetryPolicy
+				attempt int
+			}{policy: RetryPolicy{Backoff: BackoffFixed, BaseBackoff: time.Second}, attempt: 5},
+			want: time.Second,
+		},
+		{
+			name: "Given a max backoff, When the delay would exceed it, Then should cap it",
+			in: struct {
+				policy  RetryPolicy
+				attempt int
+			}{policy: RetryPolicy{Backoff: BackoffExponential, BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}, attempt: 40},
+			want: 10 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.in.policy.Delay(tt.in.attempt))
+		})
+	}
+}
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   RetryPolicy
+		want error
+	}{
+		{
+			name: "Given an empty policy, When validating, Then should accept it",
+			in:   RetryPolicy{},
+		},
+		{
+			name: "Given an unknown backoff strategy, When validating, Then should fail",
+			in:   RetryPolicy{Backoff: "random"},
+			want: ErrInvalidRetryPolicy,
+		},
+		{
+			name: "Given an unknown DLQ behavior, When validating, Then should fail",
+			in:   RetryPolicy{DLQ: "drop"},
+			want: ErrInvalidRetryPolicy,
+		},
+		{
+			name: "Given negative max attempts, When validating, Then should fail",
+			in:   RetryPolicy{MaxAttempts: -1},
+			want: ErrInvalidRetryPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.Validate()
+
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestCompileErrorPatterns(t *testing.T) {
+	_, err := CompileErrorPatterns([]string{"timeout", "(unclosed"})
+
+	assert.ErrorIs(t, err, ErrInvalidRetryPolicy)
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+)
+
+// PayloadMigration upgrades a payload from the schema version it was registered for to the next
+type PayloadMigration func(payload []byte) ([]byte, error)
+
+var ErrSchemaVersionTooNew = errors.New("job schema version is newer than the worker knows")
+
+// SchemaMigrations let workers and producers of different releases share a queue during a
+// rolling upgrade. A worker runs jobs up to its schema version, upgrading older payloads with
+// the migrations registered for their job type, and holds the newer ones for a worker that
+// knows them.
+type SchemaMigrations struct {
+	version    int
+	migrations map[string]map[int]PayloadMigration // By job type, then the version upgraded from
+}
+
+// NewSchemaMigrations creates the migrations of a worker knowing schemas up to version; zero
+// or less is queue.DefaultSchemaVersion
+func NewSchemaMigrations(version int) *SchemaMigrations {
+	return &SchemaMigrations{
+		version:    max(version, queue.DefaultSchemaVersion),
+		migrations: make(map[string]map[int]PayloadMigration),
+	}
+}
+
+// Version returns the newest schema version the worker knows
+func (m *SchemaMigrations) Version() int {
+	return m.version
+}
+
+// Register upgrades payloads of the job type from version from to from+1. A version without
+// a migration is read as is by the next one.
+func (m *SchemaMigrations) Register(jobType string, from int, migration PayloadMigration) {
+	if m.migrations[jobType] == nil {
+		m.migrations[jobType] = make(map[int]PayloadMigration)
+	}
+	m.migrations[jobType][from] = migration
+}
+
+// Knows reports whether the worker knows the job's schema version
+func (m *SchemaMigrations) Knows(job *queue.Job) bool {
+	return job.EffectiveSchemaVersion() <= m.version
+}
+
+// Migrate upgrades the job's payload to the worker's schema version one version at a time,
+// reporting whether it changed. A job newer than the worker is refused with
+// ErrSchemaVersionTooNew.
+func (m *SchemaMigrations) Migrate(job *queue.Job) (bool, error) {
+	from := job.EffectiveSchemaVersion()
+	if from > m.version {
+		return false, fmt.Errorf("%w: version %d, worker knows up to %d", ErrSchemaVersionTooNew, from, m.version)
+	}
+	if from == m.version {
+		return false, nil
+	}
+
+	payload := job.Payload
+	for version := from; version < m.version; version++ {
+		migration, ok := m.migrations[job.Type][version]
+		if !ok {
+			continue
+		}
+		migrated, err := migration(payload)
+		if err != nil {
+			return false, fmt.Errorf("migrate %s payload from schema version %d: %w", job.Type, version, err)
+		}
+		payload = migrated
+	}
+	job.Payload = payload
+	job.SchemaVersion = m.version
+	return true, nil
+}
@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaMigrations_Migrate(t *testing.T) {
+	rename := func(payload []byte) ([]byte, error) {
+		return bytes.ReplaceAll(payload, []byte(`"to"`), []byte(`"recipient"`)), nil
+	}
+	tests := []struct {
+		name string
+		in   struct {
+			version int // Of the job
+			fail    bool
+		}
+		want struct {
+			migrated bool
+			payload  string
+			version  int
+			err      error
+		}
+	}{
+		{
+			name: "Given a job two versions behind, When migrating, Then should run the registered step and skip the missing one",
+			in: struct {
+				version int
+				fail    bool
+			}{version: 1},
+			want: struct {
+				migrated bool
+				payload  string
+				version  int
+				err      error
+			}{migrated: true, payload: `{"recipient":"a@example.com"}`, version: 3},
+		},
+		{
+			name: "Given a job of the worker's version, When migrating, Then should leave it as it is",
+			in: struct {
+				version int
+				fail    bool
+			}{version: 3},
+			want: struct {
+				migrated bool
+				payload  string
+				version  int
+				err      error
+			}{payload: `{"to":"a@example.com"}`, version: 3},
+		},
+		{
+			name: "Given a job newer than the worker, When migrating, Then should be refused",
+			in: struct {
+				version int
+				fail    bool
+			}{version: 4},
+			want: struct {
+				migrated bool
+				payload  string
+				version  int
+				err      error
+			}{payload: `{"to":"a@example.com"}`, version: 4, err: ErrSchemaVersionTooNew},
+		},
+		{
+			name: "Given a failing migration, When migrating, Then should keep the payload and version",
+			in: struct {
+				version int
+				fail    bool
+			}{version: 1, fail: true},
+			want: struct {
+				migrated bool
+				payload  string
+				version  int
+				err      error
+			}{payload: `{"to":"a@example.com"}`, version: 1, err: errMigration},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			migrations := NewSchemaMigrations(3)
+			migrations.Register("email", 1, rename)
+			if tt.in.fail {
+				migrations.Register("email", 2, func([]byte) ([]byte, error) { return nil, errMigration })
+			}
+			job, _ := queue.NewJob("emails", "email", []byte(`{"to":"a@example.com"}`))
+			job.SchemaVersion = tt.in.version
+
+			// When
+			migrated, err := migrations.Migrate(job)
+
+			// Then
+			assert.ErrorIs(t, err, tt.want.err)
+			assert.Equal(t, tt.want.migrated, migrated)
+			assert.Equal(t, tt.want.payload, string(job.Payload))
+			assert.Equal(t, tt.want.version, job.SchemaVersion)
+		})
+	}
+}
+
+var errMigration = errors.New("recipient missing")
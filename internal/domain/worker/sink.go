@@ -0,0 +1,29 @@
+package worker
+
+import (
+	"context"
+	"io"
+)
+
+// ResultSink receives the output a job writes while it runs, e.g. the progress of a long data
+// processing job, so consumers can tail it before the job finishes. Writes may come from
+// several goroutines; the final result is still returned in ExecutionResult.Output.
+type ResultSink interface {
+	io.Writer
+}
+
+type resultSinkContextKey struct{}
+
+// WithResultSink returns a context carrying the sink the executor of a job writes its output to
+func WithResultSink(ctx context.Context, sink ResultSink) context.Context {
+	return context.WithValue(ctx, resultSinkContextKey{}, sink)
+}
+
+// ResultSinkFrom returns the sink of the job being executed, which discards the output when
+// the worker doesn't keep it
+func ResultSinkFrom(ctx context.Context) ResultSink {
+	if sink, ok := ctx.Value(resultSinkContextKey{}).(ResultSink); ok {
+		return sink
+	}
+	return io.Discard
+}
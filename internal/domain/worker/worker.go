@@ -2,17 +2,33 @@ package worker
 
 import (
 	"errors"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
 )
 
 // WorkerConfig contains worker configuration
 type WorkerConfig struct {
+	WorkerID      string // Identifies this worker in logs; optional
 	QueueName     string
 	MaxAttempts   int
 	BaseBackoffMs int
-	PollInterval  time.Duration
+	// DequeueTimeout bounds how long one pop waits for a job, so the worker notices shutdown
+	// and paused queues while the queue is empty
+	DequeueTimeout time.Duration
+	// IdleSleep pauses the worker after a pop finds no job; zero pops again right away
+	IdleSleep     time.Duration
+	Capabilities  []string            // Normalized capabilities the worker announces, e.g. gpu or region=eu
+	InsightPolicy queue.InsightPolicy // Failures sent for AI analysis; empty uses queue.DefaultInsightPolicy
+	RetryPolicies RetryPolicies       // Per job type overrides of the retry settings
 }
 
+// DefaultDequeueTimeout is how long a pop waits for a job unless configured otherwise
+const DefaultDequeueTimeout = 2 * time.Second
+
 // ExecutionResult represents the result of job execution
 type ExecutionResult struct {
 	Success bool
@@ -27,6 +43,100 @@ var (
 	ErrMaxAttemptsInvalid = errors.New("max attempts must be greater than 0")
 )
 
+// PermanentError marks an execution error that retrying cannot fix
+// (e.g. a disallowed command or a terminal exit code)
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// NewPermanentError wraps err so the worker sends the job straight to the DLQ
+func NewPermanentError(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err (or any error it wraps) is a PermanentError
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// MaxRetryAfter caps how long the worker waits on a RetryableError, so a bogus
+// Retry-After from a downstream service can't stall the worker for hours
+const MaxRetryAfter = 5 * time.Minute
+
+// RetryableError marks a failure caused by downstream throttling (e.g. an HTTP 429 or 503).
+// The worker retries the job after RetryAfter instead of backing off exponentially and
+// doesn't count the failure toward max attempts.
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// NewRetryableError wraps err so the worker retries the job after retryAfter
+func NewRetryableError(err error, retryAfter time.Duration) error {
+	return &RetryableError{Err: err, RetryAfter: retryAfter}
+}
+
+// RetryAfter returns the delay requested by a RetryableError in err, capped at MaxRetryAfter.
+// It reports false when err carries no RetryableError or one without a positive delay.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryable *RetryableError
+	if !errors.As(err, &retryable) || retryable.RetryAfter <= 0 {
+		return 0, false
+	}
+	return min(retryable.RetryAfter, MaxRetryAfter), true
+}
+
+// ClassifyHTTPStatus marks err, the failure of a call a downstream HTTP service answered with
+// status, for the worker. 429 and 503 are throttling and retried after retryAfter. Any other
+// 4xx but 408 and 425 is a request the service rejected, which retrying can't fix, so it's
+// permanent. Everything else, e.g. a 5xx, is retried with backoff.
+func ClassifyHTTPStatus(err error, status int, retryAfter time.Duration) error {
+	switch {
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		return NewRetryableError(err, retryAfter)
+	case status == http.StatusRequestTimeout || status == http.StatusTooEarly:
+		return err
+	case status >= 400 && status < 500:
+		return NewPermanentError(err)
+	default:
+		return err
+	}
+}
+
+// ParseRetryAfter parses an HTTP Retry-After header, given either in seconds or as an
+// HTTP date, into the delay from now
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := time.Parse(time.RFC1123, value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
 // NewWorkerConfig creates and validates worker configuration
 func NewWorkerConfig(queueName string, maxAttempts, baseBackoffMs int) (*WorkerConfig, error) {
 	if queueName == "" {
@@ -37,10 +147,10 @@ func NewWorkerConfig(queueName string, maxAttempts, baseBackoffMs int) (*WorkerC
 	}
 
 	return &WorkerConfig{
-		QueueName:     queueName,
-		MaxAttempts:   maxAttempts,
-		BaseBackoffMs: baseBackoffMs,
-		PollInterval:  5 * time.Second, // Default poll interval
+		QueueName:      queueName,
+		MaxAttempts:    maxAttempts,
+		BaseBackoffMs:  baseBackoffMs,
+		DequeueTimeout: DefaultDequeueTimeout,
 	}, nil
 }
 
@@ -1,6 +1,8 @@
 package worker
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -102,7 +104,7 @@ func TestNewWorkerConfig(t *testing.T) {
 				assert.Equal(t, tt.in.queueName, config.QueueName)
 				assert.Equal(t, tt.in.maxAttempts, config.MaxAttempts)
 				assert.Equal(t, tt.in.baseBackoffMs, config.BaseBackoffMs)
-				assert.Equal(t, 5*time.Second, config.PollInterval)
+				assert.Equal(t, DefaultDequeueTimeout, config.DequeueTimeout)
 			}
 		})
 	}
@@ -204,3 +206,229 @@ func TestCalculateBackoff(t *testing.T) {
 		})
 	}
 }
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			err error
+		}
+		want struct {
+			permanent bool
+		}
+	}{
+		{
+			name: "Given a permanent error, When checking, Then should return true",
+			in:   struct{ err error }{err: NewPermanentError(errors.New("bad payload"))},
+			want: struct{ permanent bool }{permanent: true},
+		},
+		{
+			name: "Given a wrapped permanent error, When checking, Then should return true",
+			in:   struct{ err error }{err: fmt.Errorf("execute: %w", NewPermanentError(errors.New("bad payload")))},
+			want: struct{ permanent bool }{permanent: true},
+		},
+		{
+			name: "Given a plain error, When checking, Then should return false",
+			in:   struct{ err error }{err: errors.New("connection reset")},
+			want: struct{ permanent bool }{permanent: false},
+		},
+		{
+			name: "Given nil error, When checking, Then should return false",
+			in:   struct{ err error }{err: nil},
+			want: struct{ permanent bool }{permanent: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := IsPermanent(tt.in.err)
+
+			assert.Equal(t, tt.want.permanent, result)
+		})
+	}
+}
+
+func TestClassifyHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			status int
+		}
+		want struct {
+			permanent bool
+			throttled bool
+		}
+	}{
+		{
+			name: "Given a 400, When classifying, Then should be permanent",
+			in:   struct{ status int }{status: 400},
+			want: struct {
+				permanent bool
+				throttled bool
+			}{permanent: true},
+		},
+		{
+			name: "Given a 404, When classifying, Then should be permanent",
+			in:   struct{ status int }{status: 404},
+			want: struct {
+				permanent bool
+				throttled bool
+			}{permanent: true},
+		},
+		{
+			name: "Given a 408, When classifying, Then should be transient",
+			in:   struct{ status int }{status: 408},
+			want: struct {
+				permanent bool
+				throttled bool
+			}{},
+		},
+		{
+			name: "Given a 429, When classifying, Then should be throttled",
+			in:   struct{ status int }{status: 429},
+			want: struct {
+				permanent bool
+				throttled bool
+			}{throttled: true},
+		},
+		{
+			name: "Given a 503, When classifying, Then should be throttled",
+			in:   struct{ status int }{status: 503},
+			want: struct {
+				permanent bool
+				throttled bool
+			}{throttled: true},
+		},
+		{
+			name: "Given a 500, When classifying, Then should be transient",
+			in:   struct{ status int }{status: 500},
+			want: struct {
+				permanent bool
+				throttled bool
+			}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClassifyHTTPStatus(errors.New("downstream call failed"), tt.in.status, 10*time.Second)
+
+			_, throttled := RetryAfter(err)
+			assert.Equal(t, tt.want.permanent, IsPermanent(err))
+			assert.Equal(t, tt.want.throttled, throttled)
+			assert.EqualError(t, err, "downstream call failed")
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   struct {
+			err error
+		}
+		want struct {
+			delay     time.Duration
+			throttled bool
+		}
+	}{
+		{
+			name: "Given a wrapped retryable error, When reading its delay, Then should return it",
+			in:   struct{ err error }{err: fmt.Errorf("send: %w", NewRetryableError(errors.New("429 Too Many Requests"), 30*time.Second))},
+			want: struct {
+				delay     time.Duration
+				throttled bool
+			}{delay: 30 * time.Second, throttled: true},
+		},
+		{
+			name: "Given a retryable error asking for an hour, When reading its delay, Then should cap it at MaxRetryAfter",
+			in:   struct{ err error }{err: NewRetryableError(errors.New("503 Service Unavailable"), time.Hour)},
+			want: struct {
+				delay     time.Duration
+				throttled bool
+			}{delay: MaxRetryAfter, throttled: true},
+		},
+		{
+			name: "Given a retryable error without a delay, When reading its delay, Then should report no throttling",
+			in:   struct{ err error }{err: NewRetryableError(errors.New("503 Service Unavailable"), 0)},
+			want: struct {
+				delay     time.Duration
+				throttled bool
+			}{},
+		},
+		{
+			name: "Given a plain error, When reading its delay, Then should report no throttling",
+			in:   struct{ err error }{err: errors.New("connection reset")},
+			want: struct {
+				delay     time.Duration
+				throttled bool
+			}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, throttled := RetryAfter(tt.in.err)
+
+			assert.Equal(t, tt.want.delay, delay)
+			assert.Equal(t, tt.want.throttled, throttled)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   struct {
+			value string
+		}
+		want struct {
+			delay time.Duration
+			ok    bool
+		}
+	}{
+		{
+			name: "Given a delay in seconds, When parsing, Then should return it",
+			in:   struct{ value string }{value: "120"},
+			want: struct {
+				delay time.Duration
+				ok    bool
+			}{delay: 2 * time.Minute, ok: true},
+		},
+		{
+			name: "Given an HTTP date, When parsing, Then should return the time until it",
+			in:   struct{ value string }{value: "Sun, 01 Mar 2026 12:00:45 GMT"},
+			want: struct {
+				delay time.Duration
+				ok    bool
+			}{delay: 45 * time.Second, ok: true},
+		},
+		{
+			name: "Given an HTTP date in the past, When parsing, Then should return no delay",
+			in:   struct{ value string }{value: "Sun, 01 Mar 2026 11:00:00 GMT"},
+			want: struct {
+				delay time.Duration
+				ok    bool
+			}{delay: 0, ok: true},
+		},
+		{
+			name: "Given garbage, When parsing, Then should fail",
+			in:   struct{ value string }{value: "soon"},
+			want: struct {
+				delay time.Duration
+				ok    bool
+			}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := ParseRetryAfter(tt.in.value, now)
+
+			assert.Equal(t, tt.want.delay, delay)
+			assert.Equal(t, tt.want.ok, ok)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+//go:build integration
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/metrics"
+	"github.com/erickfunier/ai-smart-queue/internal/adapters/outbound/persistence"
+	appInsights "github.com/erickfunier/ai-smart-queue/internal/application/insights"
+	appQueue "github.com/erickfunier/ai-smart-queue/internal/application/queue"
+	appWorker "github.com/erickfunier/ai-smart-queue/internal/application/worker"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+	"github.com/erickfunier/ai-smart-queue/internal/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testQueue = "e2e"
+
+// pipeline wires the production adapters against the test environment
+type pipeline struct {
+	jobRepo       *persistence.PostgresJobRepository
+	insightRepo   *persistence.PostgresInsightRepository
+	analysisQueue *persistence.RedisAnalysisQueue
+	queueApp      *appQueue.Service
+	insightsApp   *appInsights.Service
+	worker        *appWorker.Service
+	ai            *testsupport.StaticAIService
+}
+
+func newPipeline(t *testing.T, env *testsupport.Env, executor worker.JobExecutor, maxAttempts int) *pipeline {
+	t.Helper()
+
+	var analysis insights.AnalysisResponse
+	require.NoError(t, json.Unmarshal(testsupport.Fixture(t, "timeout_analysis"), &analysis))
+	analysis.Provider = "static"
+
+	p := &pipeline{
+		jobRepo:       persistence.NewPostgresJobRepository(env.Postgres.Pool),
+		insightRepo:   persistence.NewPostgresInsightRepository(env.Postgres.Pool),
+		analysisQueue: persistence.NewRedisAnalysisQueue(env.Redis.Client, 0).WithKeyPrefix(env.KeyPrefix),
+		ai:            &testsupport.StaticAIService{Response: &analysis},
+	}
+	queueService := persistence.NewRedisQueueService(env.Redis.Client).WithKeyPrefix(env.KeyPrefix)
+	p.queueApp = appQueue.NewService(p.jobRepo, queueService, metrics.NewInMemoryMetricsService())
+	p.queueApp.SetQueueInspector(queueService)
+	p.insightsApp = appInsights.NewService(p.insightRepo, p.jobRepo, p.ai)
+
+	workerConfig, err := worker.NewWorkerConfig(testQueue, maxAttempts, 10)
+	require.NoError(t, err)
+	p.worker = appWorker.NewService(p.jobRepo, queueService, executor, p.analysisQueue, workerConfig)
+	return p
+}
+
+// runConsumer drains the analysis queue until the test ends
+func (p *pipeline) runConsumer(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		appInsights.NewAnalysisConsumer(p.analysisQueue, p.insightsApp, 1).Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestJobPipeline_FailureGeneratesInsight(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+	p := newPipeline(t, env, &testsupport.FailingExecutor{Err: errors.New("smtp timeout")}, 1)
+
+	job, err := p.queueApp.CreateJob(ctx, appQueue.CreateJobCommand{
+		Queue:   testQueue,
+		Type:    "email",
+		Payload: testsupport.FixtureMap(t, "email_payload"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, p.worker.ProcessNextJob(ctx))
+
+	stored, err := p.jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, queue.StatusFailed, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, "smtp timeout", stored.Error)
+
+	p.runConsumer(t)
+
+	var insight *insights.Insight
+	require.Eventually(t, func() bool {
+		insight, err = p.insightRepo.GetByJobID(ctx, job.ID)
+		return err == nil
+	}, 15*time.Second, 100*time.Millisecond)
+
+	assert.Equal(t, "SMTP server timed out", insight.Diagnosis)
+	assert.Equal(t, 60, insight.SuggestedFix.TimeoutSeconds)
+	assert.Equal(t, 0.8, insight.Confidence)
+	assert.Equal(t, "static", insight.Provider)
+
+	requests := p.ai.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, job.ID.String(), requests[0].JobID)
+	assert.Equal(t, "smtp timeout", requests[0].Error)
+
+	triage, err := p.insightsApp.GetDLQTriage(ctx, 10, 0, insights.DLQOrderRecent)
+	require.NoError(t, err)
+	require.Len(t, triage, 1)
+	assert.Equal(t, job.ID, triage[0].Job.ID)
+	require.NotNil(t, triage[0].Insight)
+	assert.Equal(t, insight.ID, triage[0].Insight.ID)
+}
+
+func TestJobPipeline_DLQTriageFromFixtures(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+	p := newPipeline(t, env, &testsupport.FailingExecutor{}, 1)
+
+	jobs := testsupport.NewJobBuilder().
+		WithQueue(testQueue).
+		WithStatus(queue.StatusFailed).
+		WithAttempts(3).
+		WithError("connection refused").
+		WithPayload(testsupport.Fixture(t, "email_payload"))
+	analyzed, unanalyzed := jobs.Build(), jobs.Build()
+	require.NoError(t, p.jobRepo.Create(ctx, analyzed))
+	require.NoError(t, p.jobRepo.Create(ctx, unanalyzed))
+
+	insight := testsupport.NewInsightBuilder(analyzed.ID).WithProvider("ollama:phi3:mini").Build()
+	require.NoError(t, p.insightRepo.Create(ctx, insight))
+
+	triage, err := p.insightsApp.GetDLQTriage(ctx, 10, 0, insights.DLQOrderRecent)
+	require.NoError(t, err)
+	require.Len(t, triage, 2)
+
+	byJob := make(map[string]*insights.Insight)
+	for _, entry := range triage {
+		byJob[entry.Job.ID.String()] = entry.Insight
+	}
+	require.NotNil(t, byJob[analyzed.ID.String()])
+	assert.Equal(t, insight.ID, byJob[analyzed.ID.String()].ID)
+	assert.Equal(t, "ollama:phi3:mini", byJob[analyzed.ID.String()].Provider)
+	assert.Nil(t, byJob[unanalyzed.ID.String()])
+}
+
+func TestJobPipeline_StrandedJobIsRepaired(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+	p := newPipeline(t, env, &testsupport.FailingExecutor{Err: errors.New("smtp timeout")}, 1)
+
+	job, err := p.queueApp.CreateJob(ctx, appQueue.CreateJobCommand{
+		Queue:   testQueue,
+		Type:    "email",
+		Payload: testsupport.FixtureMap(t, "email_payload"),
+	})
+	require.NoError(t, err)
+
+	// Lose the job in Redis, long enough ago that it can't be on its way there
+	require.NoError(t, env.Redis.Client.Del(ctx, env.KeyPrefix+"queue:"+testQueue).Err())
+	_, err = env.Postgres.Pool.Exec(ctx, `UPDATE jobs SET updated_at = updated_at - interval '1 hour' WHERE id = $1`, job.ID)
+	require.NoError(t, err)
+
+	report, err := p.queueApp.CheckConsistency(ctx, appQueue.ConsistencyCheck{Queue: testQueue})
+	require.NoError(t, err)
+	require.Len(t, report.Stranded, 1)
+	assert.Equal(t, job.ID, report.Stranded[0].Job.ID)
+	assert.False(t, report.Stranded[0].Repaired)
+
+	report, err = p.queueApp.CheckConsistency(ctx, appQueue.ConsistencyCheck{Queue: testQueue, Repair: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Repaired)
+
+	report, err = p.queueApp.CheckConsistency(ctx, appQueue.ConsistencyCheck{Queue: testQueue})
+	require.NoError(t, err)
+	assert.Empty(t, report.Stranded)
+
+	// The repaired job is delivered exactly as if it had never been lost
+	require.NoError(t, p.worker.ProcessNextJob(ctx))
+	stored, err := p.jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, queue.StatusFailed, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+}
+
+func TestJobPipeline_PostgresQueueBackend(t *testing.T) {
+	env := testsupport.Start(t)
+	ctx := context.Background()
+
+	jobRepo := persistence.NewPostgresJobRepository(env.Postgres.Pool)
+	queueService := persistence.NewPostgresQueueService(env.Postgres.Pool)
+	queueApp := appQueue.NewService(jobRepo, queueService, metrics.NewInMemoryMetricsService())
+	workerConfig, err := worker.NewWorkerConfig(testQueue, 1, 10)
+	require.NoError(t, err)
+	workerService := appWorker.NewService(jobRepo, queueService, &testsupport.FailingExecutor{Err: errors.New("smtp timeout")}, nil, workerConfig)
+
+	gpuJob, err := queueApp.CreateJob(ctx, appQueue.CreateJobCommand{
+		Queue:    testQueue,
+		Type:     "render",
+		Payload:  map[string]any{},
+		Requires: []string{"gpu"},
+	})
+	require.NoError(t, err)
+	job, err := queueApp.CreateJob(ctx, appQueue.CreateJobCommand{
+		Queue:   testQueue,
+		Type:    "email",
+		Payload: testsupport.FixtureMap(t, "email_payload"),
+	})
+	require.NoError(t, err)
+
+	// The worker lacks the capability, so it claims the newer job
+	require.NoError(t, workerService.ProcessNextJob(ctx))
+	stored, err := jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, queue.StatusFailed, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+
+	stored, err = jobRepo.GetByID(ctx, gpuJob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, queue.StatusPending, stored.Status)
+
+	claimed, err := queueService.Dequeue(ctx, testQueue, []string{"gpu"}, 0)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, gpuJob.ID, claimed.ID)
+	assert.Equal(t, queue.StatusPending, claimed.Status)
+
+	// A claimed job isn't claimed again until it is returned
+	again, err := queueService.Dequeue(ctx, testQueue, []string{"gpu"}, 0)
+	require.NoError(t, err)
+	assert.Nil(t, again)
+
+	require.NoError(t, queueService.Nack(ctx, claimed))
+	again, err = queueService.Dequeue(ctx, testQueue, []string{"gpu"}, 0)
+	require.NoError(t, err)
+	require.NotNil(t, again)
+	assert.Equal(t, gpuJob.ID, again.ID)
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Reloader keeps the active configuration and re-reads it from disk on demand.
+// Components that support runtime changes register a listener with OnReload.
+type Reloader struct {
+	load      func() (*Config, error)
+	mu        sync.RWMutex
+	current   *Config
+	listeners []func(*Config)
+}
+
+// NewReloader creates a reloader seeded with an already loaded configuration
+func NewReloader(path string, initial *Config) *Reloader {
+	return &Reloader{
+		load:    func() (*Config, error) { return LoadConfig(path) },
+		current: initial,
+	}
+}
+
+// NewFileReloader creates a reloader that re-reads the given file instead of
+// the CONFIG_ENV-selected one
+func NewFileReloader(path string, initial *Config) *Reloader {
+	return &Reloader{
+		load:    func() (*Config, error) { return LoadConfigFile(path) },
+		current: initial,
+	}
+}
+
+// Current returns the most recently loaded configuration
+func (r *Reloader) Current() *Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// OnReload registers a listener invoked with the new configuration after each successful reload
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Reload re-reads and validates the configuration file and notifies listeners.
+// On failure the previous configuration stays active.
+func (r *Reloader) Reload() error {
+	cfg, err := r.load()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.current = cfg
+	listeners := make([]func(*Config), len(r.listeners))
+	copy(listeners, r.listeners)
+	r.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(cfg)
+	}
+	return nil
+}
+
+// WatchSignals reloads the configuration every time the process receives SIGHUP
+// until the context is cancelled
+func (r *Reloader) WatchSignals(ctx context.Context) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChan:
+				slog.Info("Received SIGHUP, reloading configuration")
+				if err := r.Reload(); err != nil {
+					slog.Error("Config reload failed, keeping previous configuration", slog.String("error", err.Error()))
+					continue
+				}
+				slog.Info("Configuration reloaded")
+			}
+		}
+	}()
+}
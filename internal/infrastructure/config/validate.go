@@ -0,0 +1,310 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ValidationError lists every problem found in a configuration, so they can all be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config (%d problems): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// validator collects the problems found while checking a configuration
+type validator struct {
+	problems []string
+}
+
+func (v *validator) fail(format string, args ...any) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.fail("%s is required", field)
+	}
+}
+
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.fail("%s must not be negative, got %d", field, value)
+	}
+}
+
+func (v *validator) positive(field string, value int) {
+	if value <= 0 {
+		v.fail("%s must be greater than 0, got %d", field, value)
+	}
+}
+
+func (v *validator) rate(field string, value float64) {
+	if value < 0 || value > 1 {
+		v.fail("%s must be between 0 and 1, got %g", field, value)
+	}
+}
+
+func (v *validator) port(field string, value int, required bool) {
+	if value < 0 || value > 65535 || (required && value == 0) {
+		v.fail("%s must be a port between 1 and 65535, got %d", field, value)
+	}
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	v.fail("%s must be one of %s, got %q", field, strings.Join(allowed[1:], ", "), value)
+}
+
+// Validate checks the settings every service needs and the ranges of the others, returning a
+// *ValidationError listing every problem, or nil. Zero values that fall back to a default are
+// accepted; settings are checked even when their feature is off, so turning it on can't fail.
+// A standalone insights service listens on its own port and connects to neither Postgres nor
+// Redis, so it doesn't need server.port, postgres.dsn or a Redis address.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("server.port", c.Server.Port, !c.AI.Standalone)
+	v.nonNegative("server.read_header_timeout_seconds", c.Server.ReadHeaderTimeoutSeconds)
+	v.nonNegative("server.read_timeout_seconds", c.Server.ReadTimeoutSeconds)
+	v.nonNegative("server.write_timeout_seconds", c.Server.WriteTimeoutSeconds)
+	v.nonNegative("server.idle_timeout_seconds", c.Server.IdleTimeoutSeconds)
+	v.nonNegative("server.shutdown_timeout_seconds", c.Server.ShutdownTimeoutSeconds)
+	v.nonNegative("server.pagination.default_limit", c.Server.Pagination.DefaultLimit)
+	v.nonNegative("server.pagination.max_limit", c.Server.Pagination.MaxLimit)
+	if c.Server.Pagination.MaxLimit > 0 && c.Server.Pagination.DefaultLimit > c.Server.Pagination.MaxLimit {
+		v.fail("server.pagination.default_limit must not exceed server.pagination.max_limit (%d), got %d",
+			c.Server.Pagination.MaxLimit, c.Server.Pagination.DefaultLimit)
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		v.fail("server.tls.cert_file and server.tls.key_file must be set together")
+	}
+
+	if !c.AI.Standalone {
+		v.required("postgres.dsn", c.Postgres.DSN)
+	}
+	v.nonNegative("postgres.query_timeout_ms", c.Postgres.QueryTimeoutMs)
+	v.nonNegative("postgres.slow_query_ms", c.Postgres.SlowQueryMs)
+	if c.Redis.Addr == "" && c.Redis.URL == "" && !c.AI.Standalone {
+		v.fail("redis.addr or redis.url is required")
+	}
+	v.nonNegative("redis.db", c.Redis.DB)
+	v.nonNegative("redis.dead_letter_limit", c.Redis.DeadLetterLimit)
+	v.nonNegative("redis.visibility_timeout_seconds", c.Redis.VisibilityTimeoutSeconds)
+	v.nonNegative("redis.pool_size", c.Redis.PoolSize)
+	v.nonNegative("redis.min_idle_conns", c.Redis.MinIdleConns)
+	v.nonNegative("redis.pool_timeout_seconds", c.Redis.PoolTimeoutSeconds)
+	if c.Redis.PoolSize > 0 && c.Redis.MinIdleConns > c.Redis.PoolSize {
+		v.fail("redis.min_idle_conns must not exceed redis.pool_size (%d), got %d", c.Redis.PoolSize, c.Redis.MinIdleConns)
+	}
+	if (c.Redis.TLS.CertFile == "") != (c.Redis.TLS.KeyFile == "") {
+		v.fail("redis.tls.cert_file and redis.tls.key_file must be set together")
+	}
+	if err := c.QueueBackend.Validate(); err != nil {
+		v.fail("queue_backend.type: %v", err)
+	}
+	v.nonNegative("queue_backend.poll_interval_ms", c.QueueBackend.PollIntervalMs)
+	if err := c.InsightStore.Validate(); err != nil {
+		v.fail("insight_store: %v", err)
+	}
+
+	c.Worker.validate(v)
+	c.Simulation.validate(v)
+	c.AI.validate(v)
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.RequestsPerSecond <= 0 {
+			v.fail("rate_limit.requests_per_second must be greater than 0, got %g", c.RateLimit.RequestsPerSecond)
+		}
+		v.positive("rate_limit.burst", c.RateLimit.Burst)
+	}
+	for key, override := range c.RateLimit.Overrides {
+		if override.RequestsPerSecond < 0 || override.Burst < 0 {
+			v.fail("rate_limit.overrides.%s must not be negative", key)
+		}
+	}
+
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		v.fail("cors.allow_credentials must not be set when cors.allowed_origins has \"*\"")
+	}
+
+	v.oneOf("logging.level", strings.ToLower(c.Logging.Level), "", "debug", "info", "warn", "warning", "error")
+	v.oneOf("logging.format", strings.ToLower(c.Logging.Format), "", "json", "text")
+	if c.Logging.AccessLog.SampleRate != nil {
+		v.rate("logging.access_log.sample_rate", *c.Logging.AccessLog.SampleRate)
+	}
+	v.nonNegative("logging.access_log.slow_threshold_ms", c.Logging.AccessLog.SlowThresholdMs)
+
+	v.nonNegative("stats.sample_interval_seconds", c.Stats.SampleIntervalSeconds)
+	v.nonNegative("stats.retention_days", c.Stats.RetentionDays)
+	v.nonNegative("scheduler.interval_ms", c.Scheduler.IntervalMs)
+	v.nonNegative("alerts.interval_seconds", c.Alerts.IntervalSeconds)
+	digest := c.Alerts.DLQDigest
+	if digest.Enabled {
+		v.positive("alerts.dlq_digest.threshold", digest.Threshold)
+	}
+	v.nonNegative("alerts.dlq_digest.window_minutes", digest.WindowMinutes)
+	v.nonNegative("alerts.dlq_digest.cooldown_minutes", digest.CooldownMinutes)
+	v.nonNegative("alerts.dlq_digest.top_signatures", digest.TopSignatures)
+	v.nonNegative("alerts.dlq_digest.sample_insights", digest.SampleInsights)
+	v.nonNegative("alerts.dlq_digest.interval_seconds", digest.IntervalSeconds)
+	v.nonNegative("retention.interval_minutes", c.Retention.IntervalMinutes)
+	v.nonNegative("retention.payload_days", c.Retention.PayloadDays)
+	v.nonNegative("retention.deleted_job_days", c.Retention.DeletedJobDays)
+	v.nonNegative("webhook.max_attempts", c.Webhook.MaxAttempts)
+	v.nonNegative("webhook.timeout_seconds", c.Webhook.TimeoutSeconds)
+	v.nonNegative("payload_compression.threshold_bytes", c.PayloadCompression.ThresholdBytes)
+	v.oneOf("payload_compression.algorithm", c.PayloadCompression.Algorithm, "", "none", "gzip", "zstd")
+	v.nonNegative("job_output.chunk_bytes", c.JobOutput.ChunkBytes)
+	v.nonNegative("job_output.flush_interval_ms", c.JobOutput.FlushIntervalMs)
+	if c.JobOutput.MaxBytes < 0 {
+		v.fail("job_output.max_bytes must not be negative, got %d", c.JobOutput.MaxBytes)
+	}
+
+	if c.Scaling.MinReplicas < 0 || c.Scaling.MaxReplicas < 0 {
+		v.fail("scaling.min_replicas and scaling.max_replicas must not be negative")
+	} else if c.Scaling.MaxReplicas > 0 && c.Scaling.MinReplicas > c.Scaling.MaxReplicas {
+		v.fail("scaling.min_replicas (%d) must not exceed scaling.max_replicas (%d)", c.Scaling.MinReplicas, c.Scaling.MaxReplicas)
+	}
+
+	if c.PayloadSigning.Enabled && c.PayloadSigning.Secret == "" && len(c.PayloadSigning.APIKeySecrets) == 0 {
+		v.fail("payload_signing.secret or payload_signing.api_key_secrets is required when payload signing is enabled")
+	}
+	if c.Quotas.JobsPerDay < 0 || c.Quotas.MaxPendingJobs < 0 || c.Quotas.AnalysesPerDay < 0 {
+		v.fail("quotas limits must not be negative")
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (c WorkerConfig) validate(v *validator) {
+	v.positive("worker.max_attempts", c.MaxAttempts)
+	v.positive("worker.base_backoff_ms", c.BaseBackoffMs)
+	v.port("worker.admin_port", c.AdminPort, false)
+	v.nonNegative("worker.schema_version", c.SchemaVersion)
+	v.oneOf("worker.insight_policy", c.InsightPolicy, "", "first_failure", "every_failure", "terminal_failure")
+	if c.CircuitBreaker.FailureThreshold != 0 {
+		v.rate("worker.circuit_breaker.failure_threshold", c.CircuitBreaker.FailureThreshold)
+	}
+	if c.RetryBudget.Ratio != 0 {
+		v.rate("worker.retry_budget.ratio", c.RetryBudget.Ratio)
+	}
+	v.nonNegative("worker.retry_budget.window_seconds", c.RetryBudget.WindowSeconds)
+	v.nonNegative("worker.retry_budget.min_retries", c.RetryBudget.MinRetries)
+	v.nonNegative("worker.batching.window_ms", c.Batching.WindowMs)
+	v.nonNegative("worker.batching.max_size", c.Batching.MaxSize)
+	v.nonNegative("worker.broker_health.base_delay_ms", c.BrokerHealth.BaseDelayMs)
+	v.nonNegative("worker.broker_health.max_delay_ms", c.BrokerHealth.MaxDelayMs)
+	v.nonNegative("worker.broker_health.alert_after", c.BrokerHealth.AlertAfter)
+	if c.BrokerHealth.MaxDelayMs > 0 && c.BrokerHealth.BaseDelayMs > c.BrokerHealth.MaxDelayMs {
+		v.fail("worker.broker_health.base_delay_ms must not exceed worker.broker_health.max_delay_ms (%d), got %d",
+			c.BrokerHealth.MaxDelayMs, c.BrokerHealth.BaseDelayMs)
+	}
+	for jobType, policy := range c.RetryPolicies {
+		field := "worker.retry_policies." + jobType
+		v.nonNegative(field+".max_attempts", policy.MaxAttempts)
+		v.nonNegative(field+".base_backoff_ms", policy.BaseBackoffMs)
+		v.nonNegative(field+".max_backoff_ms", policy.MaxBackoffMs)
+		v.oneOf(field+".backoff", policy.Backoff, "", "exponential", "linear", "fixed")
+		v.oneOf(field+".dlq", policy.DLQ, "", "dead_letter", "discard")
+	}
+	for jobType, idempotent := range c.IdempotentTypes {
+		v.nonNegative("worker.idempotent_types."+jobType+".ttl_seconds", idempotent.TTLSeconds)
+	}
+}
+
+func (c SimulationConfig) validate(v *validator) {
+	v.rate("simulation.failure_rate", c.FailureRate)
+	for jobType, profile := range c.Profiles {
+		field := "simulation.profiles." + jobType
+		v.rate(field+".failure_rate", profile.FailureRate)
+		v.rate(field+".bursts.failure_rate", profile.Bursts.FailureRate)
+		v.nonNegative(field+".bursts.every_seconds", profile.Bursts.EverySeconds)
+		v.nonNegative(field+".bursts.duration_seconds", profile.Bursts.DurationSeconds)
+		v.oneOf(field+".latency.distribution", profile.Latency.Distribution, "", "fixed", "uniform", "normal", "lognormal")
+		for i, window := range profile.Daily {
+			windowField := fmt.Sprintf("%s.daily[%d]", field, i)
+			if window.StartHour < 0 || window.StartHour > 23 || window.EndHour < 0 || window.EndHour > 23 {
+				v.fail("%s hours must be between 0 and 23", windowField)
+			}
+			v.rate(windowField+".failure_rate", window.FailureRate)
+			if window.LatencyFactor < 0 {
+				v.fail("%s.latency_factor must not be negative, got %g", windowField, window.LatencyFactor)
+			}
+		}
+	}
+}
+
+func (c AIConfig) validate(v *validator) {
+	v.nonNegative("ai.analysis_concurrency", c.AnalysisConcurrency)
+	v.nonNegative("ai.analysis_queue_max", c.AnalysisQueueMax)
+	v.nonNegative("ai.async_backlog", c.AsyncBacklog)
+	v.nonNegative("ai.analysis_timeout_seconds", c.AnalysisTimeoutSeconds)
+	v.nonNegative("ai.provider_timeout_seconds", c.ProviderTimeoutSeconds)
+	v.nonNegative("ai.max_prompt_bytes", c.MaxPromptBytes)
+	v.nonNegative("ai.max_payload_bytes", c.MaxPayloadBytes)
+	v.nonNegative("ai.max_error_bytes", c.MaxErrorBytes)
+	v.nonNegative("ai.max_output_bytes", c.MaxOutputBytes)
+
+	for i, provider := range c.Providers {
+		field := fmt.Sprintf("ai.providers[%d]", i)
+		v.oneOf(field+".type", provider.Type, "", "ollama", "openai", "stub")
+		if provider.Type == "openai" {
+			v.required(field+".url", provider.URL)
+			v.required(field+".model", provider.Model)
+		}
+		for j, response := range provider.Responses {
+			responseField := fmt.Sprintf("%s.responses[%d]", field, j)
+			if _, err := regexp.Compile(response.Match); err != nil {
+				v.fail("%s.match must be a regular expression: %v", responseField, err)
+			}
+			v.required(responseField+".diagnosis", response.Diagnosis)
+			v.rate(responseField+".confidence", response.Confidence)
+			v.nonNegative(responseField+".timeout_seconds", response.TimeoutSeconds)
+			v.nonNegative(responseField+".max_retries", response.MaxRetries)
+		}
+	}
+
+	client := c.InsightsClient
+	v.nonNegative("ai.insights_client.max_attempts", client.MaxAttempts)
+	v.nonNegative("ai.insights_client.base_backoff_ms", client.BaseBackoffMs)
+	v.nonNegative("ai.insights_client.max_backoff_ms", client.MaxBackoffMs)
+	v.nonNegative("ai.insights_client.timeout_seconds", client.TimeoutSeconds)
+	if client.CircuitBreaker.FailureThreshold != 0 {
+		v.rate("ai.insights_client.circuit_breaker.failure_threshold", client.CircuitBreaker.FailureThreshold)
+	}
+
+	tls := c.InsightsAuth.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		v.fail("ai.insights_auth.tls.cert_file and ai.insights_auth.tls.key_file must be set together")
+	}
+
+	v.nonNegative("ai.performance_insights.cooldown_seconds", c.PerformanceInsights.CooldownSeconds)
+	v.nonNegative("ai.storm.failure_threshold", c.Storm.FailureThreshold)
+	v.nonNegative("ai.storm.window_seconds", c.Storm.WindowSeconds)
+	v.nonNegative("ai.insight_reuse.window_hours", c.InsightReuse.WindowHours)
+	v.nonNegative("ai.clustering.interval_minutes", c.Clustering.IntervalMinutes)
+	v.nonNegative("ai.clustering.window_hours", c.Clustering.WindowHours)
+	v.nonNegative("ai.clustering.max_insights", c.Clustering.MaxInsights)
+	v.nonNegative("ai.clustering.min_members", c.Clustering.MinMembers)
+	v.rate("ai.clustering.similarity", c.Clustering.Similarity)
+	health := c.HealthCheck
+	v.nonNegative("ai.health_check.interval_seconds", health.IntervalSeconds)
+	v.nonNegative("ai.health_check.timeout_seconds", health.TimeoutSeconds)
+	v.nonNegative("ai.health_check.fail_after", health.FailAfter)
+	if health.IntervalSeconds > 0 && health.TimeoutSeconds > health.IntervalSeconds {
+		v.fail("ai.health_check.timeout_seconds must not exceed ai.health_check.interval_seconds (%d), got %d",
+			health.IntervalSeconds, health.TimeoutSeconds)
+	}
+}
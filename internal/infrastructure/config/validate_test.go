@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   []string // problems reported
+	}{
+		{
+			name:   "Given the dev config, When validating, Then should pass",
+			mutate: func(*Config) {},
+		},
+		{
+			name: "Given missing required fields, When validating, Then should list each of them",
+			mutate: func(c *Config) {
+				c.Server.Port = 0
+				c.Postgres.DSN = ""
+				c.Redis.Addr, c.Redis.URL = "", ""
+			},
+			want: []string{
+				"server.port must be a port between 1 and 65535, got 0",
+				"postgres.dsn is required",
+				"redis.addr or redis.url is required",
+			},
+		},
+		{
+			name: "Given a standalone insights config without server port, Postgres or Redis, When validating, Then should pass",
+			mutate: func(c *Config) {
+				c.AI.Standalone = true
+				c.Server.Port = 0
+				c.Postgres.DSN = ""
+				c.Redis.Addr, c.Redis.URL = "", ""
+			},
+		},
+		{
+			name: "Given values out of range, When validating, Then should list each of them",
+			mutate: func(c *Config) {
+				c.Simulation.FailureRate = 1.5
+				c.Worker.BaseBackoffMs = 0
+				c.Worker.RetryBudget.Ratio = 2
+				c.Postgres.QueryTimeoutMs = -1
+			},
+			want: []string{
+				"postgres.query_timeout_ms must not be negative, got -1",
+				"worker.base_backoff_ms must be greater than 0, got 0",
+				"worker.retry_budget.ratio must be between 0 and 1, got 2",
+				"simulation.failure_rate must be between 0 and 1, got 1.5",
+			},
+		},
+		{
+			name: "Given an incomplete Redis client certificate and an oversized idle pool, When validating, Then should list each of them",
+			mutate: func(c *Config) {
+				c.Redis.TLS.CertFile = "/etc/aisq/redis/client.crt"
+				c.Redis.PoolSize = 10
+				c.Redis.MinIdleConns = 20
+			},
+			want: []string{
+				"redis.min_idle_conns must not exceed redis.pool_size (10), got 20",
+				"redis.tls.cert_file and redis.tls.key_file must be set together",
+			},
+		},
+		{
+			name: "Given a broker health base delay longer than its max delay, When validating, Then should report it",
+			mutate: func(c *Config) {
+				c.Worker.BrokerHealth.BaseDelayMs = 5000
+				c.Worker.BrokerHealth.MaxDelayMs = 1000
+			},
+			want: []string{
+				"worker.broker_health.base_delay_ms must not exceed worker.broker_health.max_delay_ms (1000), got 5000",
+			},
+		},
+		{
+			name: "Given a default page larger than the maximum, When validating, Then should report it",
+			mutate: func(c *Config) {
+				c.Server.Pagination.DefaultLimit = 500
+				c.Server.Pagination.MaxLimit = 100
+			},
+			want: []string{
+				"server.pagination.default_limit must not exceed server.pagination.max_limit (100), got 500",
+			},
+		},
+		{
+			name: "Given a clustering similarity above 1 and a negative interval, When validating, Then should report both",
+			mutate: func(c *Config) {
+				c.AI.Clustering.Similarity = 1.5
+				c.AI.Clustering.IntervalMinutes = -1
+			},
+			want: []string{
+				"ai.clustering.interval_minutes must not be negative, got -1",
+				"ai.clustering.similarity must be between 0 and 1, got 1.5",
+			},
+		},
+		{
+			name: "Given an AI health check timeout longer than its interval, When validating, Then should report it",
+			mutate: func(c *Config) {
+				c.AI.HealthCheck.IntervalSeconds = 10
+				c.AI.HealthCheck.TimeoutSeconds = 30
+			},
+			want: []string{
+				"ai.health_check.timeout_seconds must not exceed ai.health_check.interval_seconds (10), got 30",
+			},
+		},
+		{
+			name: "Given a stub provider with a bad pattern and no diagnosis, When validating, Then should list each of them",
+			mutate: func(c *Config) {
+				c.AI.Providers = []AIProviderConfig{{
+					Type:      "stub",
+					Responses: []AIStubResponseConfig{{Match: "timeout(", Confidence: 0.9}},
+				}}
+			},
+			want: []string{
+				"ai.providers[0].responses[0].match must be a regular expression: error parsing regexp: missing closing ): `timeout(`",
+				"ai.providers[0].responses[0].diagnosis is required",
+			},
+		},
+		{
+			name: "Given CORS allowing every origin with credentials, When validating, Then should report it",
+			mutate: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"https://demo.example.com", "*"}
+				c.CORS.AllowCredentials = true
+			},
+			want: []string{
+				`cors.allow_credentials must not be set when cors.allowed_origins has "*"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfigFile("../../../configs/config.dev.yaml")
+			require.NoError(t, err)
+			tt.mutate(cfg)
+
+			err = cfg.Validate()
+			if len(tt.want) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var invalid *ValidationError
+			require.True(t, errors.As(err, &invalid))
+			assert.Equal(t, tt.want, invalid.Problems)
+		})
+	}
+}
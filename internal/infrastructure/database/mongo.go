@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoConnection manages a MongoDB client and the database the application uses
+type MongoConnection struct {
+	Client   *mongo.Client
+	Database *mongo.Database
+}
+
+// NewMongoConnection creates a new MongoDB connection; the client connects lazily,
+// so Ping to check the server is reachable
+func NewMongoConnection(uri, database string) (*MongoConnection, error) {
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongodb: %w", err)
+	}
+
+	return &MongoConnection{Client: client, Database: client.Database(database)}, nil
+}
+
+// Ping verifies the connection is alive
+func (m *MongoConnection) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, nil)
+}
+
+// Close disconnects the client
+func (m *MongoConnection) Close() {
+	_ = m.Client.Disconnect(context.Background())
+}
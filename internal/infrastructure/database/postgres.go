@@ -12,12 +12,15 @@ type PostgresConnection struct {
 	Pool *pgxpool.Pool
 }
 
-// NewPostgresConnection creates a new PostgreSQL connection
-func NewPostgresConnection(dsn string) (*PostgresConnection, error) {
+// NewPostgresConnection creates a new PostgreSQL connection whose statements are bounded and
+// logged according to limits
+func NewPostgresConnection(dsn string, limits QueryLimits) (*PostgresConnection, error) {
 	cfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse postgres config: %w", err)
 	}
+	cfg.ConnConfig.Tracer = newQueryTracer(limits)
+	cfg.ConnConfig.BuildContextWatcherHandler = cancelOnServer
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
 	if err != nil {
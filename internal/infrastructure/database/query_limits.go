@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+)
+
+// DefaultQueryTimeout bounds a statement when no timeout is configured
+const DefaultQueryTimeout = 30 * time.Second
+
+// cancelDeadlineDelay is how long a canceled statement has to stop on the server before its
+// connection is closed
+const cancelDeadlineDelay = time.Second
+
+// QueryLimits bounds the statements run on a connection pool
+type QueryLimits struct {
+	Timeout       time.Duration // Bound on each statement; zero uses DefaultQueryTimeout
+	SlowThreshold time.Duration // Statements slower than this are logged; zero disables the log
+}
+
+// queryStart is what a traced statement carries from its start to its end
+type queryStart struct {
+	sql    string
+	at     time.Time
+	cancel context.CancelFunc
+}
+
+type queryStartKey struct{}
+
+// queryTracer gives every statement its own deadline, so one stuck query fails instead of
+// holding its caller and its connection, and logs the slow ones. Deadlines already on the
+// caller's context that are shorter win.
+type queryTracer struct {
+	limits QueryLimits
+}
+
+func newQueryTracer(limits QueryLimits) *queryTracer {
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultQueryTimeout
+	}
+	return &queryTracer{limits: limits}
+}
+
+func (t *queryTracer) start(ctx context.Context, sql string) context.Context {
+	ctx, cancel := context.WithTimeout(ctx, t.limits.Timeout)
+	return context.WithValue(ctx, queryStartKey{}, &queryStart{sql: sql, at: time.Now(), cancel: cancel})
+}
+
+func (t *queryTracer) end(ctx context.Context, err error) {
+	start, ok := ctx.Value(queryStartKey{}).(*queryStart)
+	if !ok {
+		return
+	}
+	defer start.cancel()
+
+	elapsed := time.Since(start.at)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.WarnContext(ctx, "Postgres query timed out",
+			slog.String("sql", compactSQL(start.sql)),
+			slog.Duration("elapsed", elapsed),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if t.limits.SlowThreshold > 0 && elapsed >= t.limits.SlowThreshold {
+		slog.WarnContext(ctx, "Slow Postgres query",
+			slog.String("sql", compactSQL(start.sql)),
+			slog.Duration("elapsed", elapsed),
+		)
+	}
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return t.start(ctx, data.SQL)
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.end(ctx, data.Err)
+}
+
+// Batches are bounded and logged as a whole, named by their first statement
+func (t *queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	sql := "batch"
+	if data.Batch != nil && len(data.Batch.QueuedQueries) > 0 {
+		sql = data.Batch.QueuedQueries[0].SQL
+	}
+	return t.start(ctx, sql)
+}
+
+func (t *queryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (t *queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.end(ctx, data.Err)
+}
+
+// cancelOnServer makes a canceled statement stop on the server too, instead of only dropping
+// the connection while the query keeps running; the connection is closed only if the
+// server doesn't stop it in time
+func cancelOnServer(conn *pgconn.PgConn) ctxwatch.Handler {
+	return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: cancelDeadlineDelay}
+}
+
+// compactSQL folds a statement onto one line for the logs
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
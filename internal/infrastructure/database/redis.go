@@ -2,7 +2,11 @@ package database
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"time"
 
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -12,34 +16,58 @@ type RedisConnection struct {
 }
 
 // NewRedisConnection creates a new Redis connection
-// Supports both simple addr and full URL (for Upstash, etc.)
-func NewRedisConnection(addr, url, password string, db int, tlsSkipVerify bool) *RedisConnection {
-	var client *redis.Client
+// Supports both simple addr and full URL (for Upstash, etc.). The username and password
+// override those of the URL, and TLS settings turn TLS on for either.
+func NewRedisConnection(cfg config.RedisConfig) (*RedisConnection, error) {
+	opts := &redis.Options{Addr: cfg.Addr, DB: cfg.DB}
 
 	// If URL is provided (Upstash), use ParseURL
 	// ParseURL handles TLS automatically when using rediss:// scheme
-	if url != "" {
-		opts, err := redis.ParseURL(url)
+	if cfg.URL != "" {
+		parsed, err := redis.ParseURL(cfg.URL)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("failed to parse redis url: %w", err)
 		}
+		opts = parsed
+	}
+	if cfg.Username != "" {
+		opts.Username = cfg.Username
+	}
+	if cfg.Password != "" {
+		opts.Password = cfg.Password
+	}
 
-		// If TLS skip verify is enabled (for Upstash in Docker/Windows)
-		if tlsSkipVerify && opts.TLSConfig != nil {
-			opts.TLSConfig.InsecureSkipVerify = true
+	tlsCfg, err := cfg.TLS.ClientTLS()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		if tlsCfg.ServerName == "" {
+			tlsCfg.ServerName = hostOf(opts.Addr)
 		}
+		opts.TLSConfig = tlsCfg
+	}
 
-		client = redis.NewClient(opts)
-	} else {
-		// Traditional connection with addr
-		client = redis.NewClient(&redis.Options{
-			Addr:     addr,
-			Password: password,
-			DB:       db,
-		})
+	// If TLS skip verify is enabled (for Upstash in Docker/Windows)
+	if cfg.TLSSkipVerify && opts.TLSConfig != nil {
+		opts.TLSConfig.InsecureSkipVerify = true
 	}
 
-	return &RedisConnection{Client: client}
+	// Zero keeps go-redis defaults
+	opts.PoolSize = cfg.PoolSize
+	opts.MinIdleConns = cfg.MinIdleConns
+	opts.PoolTimeout = time.Duration(cfg.PoolTimeoutSeconds) * time.Second
+
+	return &RedisConnection{Client: redis.NewClient(opts)}, nil
+}
+
+// hostOf returns the host of a host:port address
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
 }
 
 // Ping verifies the connection is alive
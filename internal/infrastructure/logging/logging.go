@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Context field keys added to every record logged with a context carrying them
+const (
+	ServiceKey   = "service"
+	RequestIDKey = "requestId"
+	JobIDKey     = "jobId"
+)
+
+type contextKey int
+
+const (
+	requestIDContextKey contextKey = iota
+	jobIDContextKey
+)
+
+// Setup installs a slog logger as the process default and returns it.
+// Records carry the service name plus the request and job IDs found in their context.
+// Output from the standard log package is routed through the same logger.
+func Setup(service, level, format string) *slog.Logger {
+	logger := New(os.Stdout, service, level, format)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New creates a logger writing to w. format is "json" (default) or "text";
+// level is "debug", "info" (default), "warn" or "error".
+func New(w io.Writer, service, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	logger := slog.New(contextHandler{Handler: handler})
+	if service != "" {
+		logger = logger.With(slog.String(ServiceKey, service))
+	}
+	return logger
+}
+
+// Fatal logs msg at error level and exits, the slog counterpart of log.Fatalf
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// ParseLevel converts a configured level name, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithRequestID returns a context whose log records carry the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestID returns the request ID stored in the context, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// WithJobID returns a context whose log records carry the job ID
+func WithJobID(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, jobIDContextKey, jobID)
+}
+
+// JobID returns the job ID stored in the context, if any
+func JobID(ctx context.Context) string {
+	id, _ := ctx.Value(jobIDContextKey).(string)
+	return id
+}
+
+// contextHandler adds the request and job IDs carried by the context to each record,
+// unless the call site or the logger (through With) already logged them explicitly
+type contextHandler struct {
+	slog.Handler
+	hasRequestID bool // The logger carries a request ID
+	hasJobID     bool // The logger carries a job ID
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	requestID, jobID := RequestID(ctx), JobID(ctx)
+	if h.hasRequestID {
+		requestID = ""
+	}
+	if h.hasJobID {
+		jobID = ""
+	}
+	if requestID == "" && jobID == "" {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	r.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case RequestIDKey:
+			requestID = ""
+		case JobIDKey:
+			jobID = ""
+		}
+		return true
+	})
+	if requestID != "" {
+		r.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+	if jobID != "" {
+		r.AddAttrs(slog.String(JobIDKey, jobID))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := h
+	handler.Handler = h.Handler.WithAttrs(attrs)
+	for _, attr := range attrs {
+		switch attr.Key {
+		case RequestIDKey:
+			handler.hasRequestID = true
+		case JobIDKey:
+			handler.hasJobID = true
+		}
+	}
+	return handler
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	handler := h
+	handler.Handler = h.Handler.WithGroup(name)
+	return handler
+}
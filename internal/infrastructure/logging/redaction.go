@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/redaction"
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/config"
+)
+
+// SetupRedaction builds the redactor of the config and enables it on the default logger. It
+// returns nil when redaction is disabled, and an error when a pattern doesn't compile.
+func SetupRedaction(cfg config.RedactionConfig) (*redaction.Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	patterns := make([]redaction.Pattern, 0, len(cfg.Patterns))
+	for _, pattern := range cfg.Patterns {
+		patterns = append(patterns, redaction.Pattern{Name: pattern.Name, Regex: pattern.Regex})
+	}
+	redactor, err := redaction.NewRedactor(cfg.Fields, patterns, cfg.Mask)
+	if err != nil {
+		return nil, err
+	}
+	EnableRedaction(redactor)
+	slog.Info("Payload redaction enabled",
+		slog.Int("fields", len(cfg.Fields)),
+		slog.Int("patterns", len(patterns)),
+	)
+	return redactor, nil
+}
+
+// EnableRedaction masks sensitive values in everything logged through the default logger:
+// attributes named like a redacted field are masked whole and pattern matches are masked
+// within strings, errors and structured values
+func EnableRedaction(redactor *redaction.Redactor) {
+	slog.SetDefault(slog.New(redactingHandler{Handler: slog.Default().Handler(), redactor: redactor}))
+}
+
+type redactingHandler struct {
+	slog.Handler
+	redactor *redaction.Redactor
+}
+
+func (h redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return redactingHandler{Handler: h.Handler.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{Handler: h.Handler.WithGroup(name), redactor: h.redactor}
+}
+
+func (h redactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	if h.redactor.MasksField(attr.Key) {
+		return slog.String(attr.Key, h.redactor.Mask())
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		text, _ := h.redactor.RedactText(attr.Key, value.String())
+		return slog.String(attr.Key, text)
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = h.redactAttr(member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			text, _ := h.redactor.RedactText(attr.Key, err.Error())
+			return slog.String(attr.Key, text)
+		}
+		// Structured values, e.g. parts of a payload, are redacted in their JSON form
+		data, err := json.Marshal(value.Any())
+		if err != nil {
+			return attr
+		}
+		redacted, redactions := h.redactor.RedactPayload(data)
+		if len(redactions) == 0 {
+			return attr
+		}
+		var decoded any
+		if err := json.Unmarshal(redacted, &decoded); err != nil {
+			return slog.String(attr.Key, h.redactor.Mask())
+		}
+		return slog.Any(attr.Key, decoded)
+	default:
+		return attr
+	}
+}
@@ -0,0 +1,120 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/google/uuid"
+)
+
+// JobBuilder builds jobs with sensible defaults for tests
+type JobBuilder struct {
+	job queue.Job
+}
+
+// NewJobBuilder starts a pending email job on the "default" queue
+func NewJobBuilder() *JobBuilder {
+	now := time.Now().UTC()
+	return &JobBuilder{job: queue.Job{
+		ID:        uuid.New(),
+		Queue:     "default",
+		Type:      "email",
+		Status:    queue.StatusPending,
+		Payload:   []byte(`{}`),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}}
+}
+
+func (b *JobBuilder) WithQueue(name string) *JobBuilder {
+	b.job.Queue = name
+	return b
+}
+
+func (b *JobBuilder) WithType(jobType string) *JobBuilder {
+	b.job.Type = jobType
+	return b
+}
+
+func (b *JobBuilder) WithStatus(status queue.Status) *JobBuilder {
+	b.job.Status = status
+	return b
+}
+
+func (b *JobBuilder) WithAttempts(attempts int) *JobBuilder {
+	b.job.Attempts = attempts
+	return b
+}
+
+func (b *JobBuilder) WithError(message string) *JobBuilder {
+	b.job.Error = message
+	return b
+}
+
+// WithPayload sets the payload; values other than []byte are marshalled to JSON
+func (b *JobBuilder) WithPayload(payload any) *JobBuilder {
+	if raw, ok := payload.([]byte); ok {
+		b.job.Payload = raw
+		return b
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+	b.job.Payload = data
+	return b
+}
+
+// Build returns a copy, so the builder can be reused for similar jobs
+func (b *JobBuilder) Build() *queue.Job {
+	job := b.job
+	b.job.ID = uuid.New()
+	return &job
+}
+
+// InsightBuilder builds insights with sensible defaults for tests
+type InsightBuilder struct {
+	insight insights.Insight
+}
+
+// NewInsightBuilder starts an insight recommending a longer timeout for the job
+func NewInsightBuilder(jobID uuid.UUID) *InsightBuilder {
+	return &InsightBuilder{insight: insights.Insight{
+		ID:             uuid.New(),
+		JobID:          jobID,
+		Diagnosis:      "Network timeout",
+		Recommendation: "Increase the timeout",
+		SuggestedFix:   insights.SuggestedFix{TimeoutSeconds: 60},
+		Confidence:     0.8,
+		CreatedAt:      time.Now().UTC(),
+	}}
+}
+
+func (b *InsightBuilder) WithDiagnosis(diagnosis string) *InsightBuilder {
+	b.insight.Diagnosis = diagnosis
+	return b
+}
+
+func (b *InsightBuilder) WithSuggestedFix(fix insights.SuggestedFix) *InsightBuilder {
+	b.insight.SuggestedFix = fix
+	return b
+}
+
+func (b *InsightBuilder) WithConfidence(confidence float64) *InsightBuilder {
+	b.insight.Confidence = confidence
+	return b
+}
+
+func (b *InsightBuilder) WithProvider(provider string) *InsightBuilder {
+	b.insight.Provider = provider
+	return b
+}
+
+// Build returns a copy, so the builder can be reused for similar insights
+func (b *InsightBuilder) Build() *insights.Insight {
+	insight := b.insight
+	b.insight.ID = uuid.New()
+	return &insight
+}
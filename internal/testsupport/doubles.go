@@ -0,0 +1,50 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/erickfunier/ai-smart-queue/internal/domain/insights"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/queue"
+	"github.com/erickfunier/ai-smart-queue/internal/domain/worker"
+)
+
+// FailingExecutor fails every job it handles with the given error
+type FailingExecutor struct {
+	Err error
+}
+
+func (e *FailingExecutor) CanHandle(jobType string) bool { return true }
+
+func (e *FailingExecutor) Execute(ctx context.Context, job *queue.Job) (*worker.ExecutionResult, error) {
+	err := e.Err
+	if err == nil {
+		err = errors.New("simulated failure")
+	}
+	return &worker.ExecutionResult{Success: false, Error: err}, nil
+}
+
+// StaticAIService answers every analysis request with the same response and records the requests
+type StaticAIService struct {
+	Response *insights.AnalysisResponse
+
+	mu       sync.Mutex
+	requests []*insights.AnalysisRequest
+}
+
+func (s *StaticAIService) Analyze(ctx context.Context, request *insights.AnalysisRequest) (*insights.AnalysisResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	s.mu.Unlock()
+
+	response := *s.Response
+	return &response, nil
+}
+
+// Requests returns the analysis requests received so far
+func (s *StaticAIService) Requests() []*insights.AnalysisRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*insights.AnalysisRequest(nil), s.requests...)
+}
@@ -0,0 +1,30 @@
+package testsupport
+
+import (
+	"embed"
+	"encoding/json"
+	"testing"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Fixture returns the raw contents of fixtures/<name>.json
+func Fixture(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := fixtures.ReadFile("fixtures/" + name + ".json")
+	if err != nil {
+		t.Fatalf("unknown fixture %q: %v", name, err)
+	}
+	return data
+}
+
+// FixtureMap decodes fixtures/<name>.json into a map, the payload shape job commands take
+func FixtureMap(t testing.TB, name string) map[string]any {
+	t.Helper()
+	var payload map[string]any
+	if err := json.Unmarshal(Fixture(t, name), &payload); err != nil {
+		t.Fatalf("fixture %q is not a JSON object: %v", name, err)
+	}
+	return payload
+}
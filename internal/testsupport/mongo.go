@@ -0,0 +1,41 @@
+package testsupport
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/erickfunier/ai-smart-queue/internal/infrastructure/database"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MongoURIEnv overrides the docker-compose MongoDB used by the insight store tests
+const MongoURIEnv = "TEST_MONGO_URI"
+
+// DefaultMongoURI matches the mongodb service of docker-compose.dev.yml
+const DefaultMongoURI = "mongodb://localhost:27017"
+
+// StartMongo creates a MongoDB database nothing else uses, dropped when the test ends, or
+// skips the test when MongoDB is unreachable
+func StartMongo(t testing.TB) *mongo.Database {
+	t.Helper()
+	ctx := context.Background()
+
+	name := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	conn, err := database.NewMongoConnection(getenv(MongoURIEnv, DefaultMongoURI), name)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", MongoURIEnv, err)
+	}
+	t.Cleanup(conn.Close)
+	if err := ping(ctx, conn.Ping); err != nil {
+		t.Skipf("mongodb unavailable: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := conn.Database.Drop(context.Background()); err != nil {
+			t.Logf("failed to drop database %s: %v", name, err)
+		}
+	})
+	return conn.Database
+}
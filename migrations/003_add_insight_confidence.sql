@@ -0,0 +1,3 @@
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS confidence DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_insights_job_id_created_at ON insights (job_id, created_at DESC);
@@ -0,0 +1,8 @@
+-- Full-text search over job errors and payload values
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(error, '')), 'A') ||
+        setweight(jsonb_to_tsvector('simple', coalesce(payload, '{}'::jsonb), '["string", "numeric"]'), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_search_vector ON jobs USING GIN (search_vector);
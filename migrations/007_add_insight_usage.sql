@@ -0,0 +1,5 @@
+-- Token counts, prompt size and latency reported for the AI call behind each insight
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS usage JSONB;
+
+-- Usage reports aggregate by day
+CREATE INDEX IF NOT EXISTS idx_insights_created_at ON insights (created_at) WHERE usage IS NOT NULL;
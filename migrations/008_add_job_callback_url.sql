@@ -0,0 +1,2 @@
+-- URL that receives the final job state when the job completes or fails permanently
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS callback_url TEXT NOT NULL DEFAULT '';
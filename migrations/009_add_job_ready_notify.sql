@@ -0,0 +1,14 @@
+-- Wake listening workers as soon as a job is inserted or scheduled for retry
+CREATE OR REPLACE FUNCTION notify_job_ready() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('job_ready', NEW.queue);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS jobs_notify_ready ON jobs;
+CREATE TRIGGER jobs_notify_ready
+    AFTER INSERT OR UPDATE OF status ON jobs
+    FOR EACH ROW
+    WHEN (NEW.status IN ('pending', 'retrying'))
+    EXECUTE FUNCTION notify_job_ready();
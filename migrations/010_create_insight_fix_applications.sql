@@ -0,0 +1,13 @@
+-- Audit trail of suggested fixes applied to jobs
+CREATE TABLE IF NOT EXISTS insight_fix_applications (
+    id UUID PRIMARY KEY,
+    insight_id UUID NOT NULL REFERENCES insights(id) ON DELETE CASCADE,
+    job_id UUID NOT NULL,
+    payload_before JSONB,
+    payload_after JSONB,
+    status_before TEXT NOT NULL,
+    status_after TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_insight_fix_applications_insight_id ON insight_fix_applications (insight_id, applied_at DESC);
@@ -0,0 +1,2 @@
+-- Name of the AI provider in the fallback chain that produced each insight
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS provider TEXT NOT NULL DEFAULT '';
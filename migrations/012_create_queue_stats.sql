@@ -0,0 +1,14 @@
+-- Per-minute samples of each queue's job counts, for backlog and throughput history
+CREATE TABLE IF NOT EXISTS queue_stats (
+    queue TEXT NOT NULL,
+    sampled_at TIMESTAMPTZ NOT NULL,
+    pending BIGINT NOT NULL DEFAULT 0,
+    processing BIGINT NOT NULL DEFAULT 0,
+    retrying BIGINT NOT NULL DEFAULT 0,
+    failed BIGINT NOT NULL DEFAULT 0,
+    completed BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (queue, sampled_at)
+);
+
+-- Retention pruning deletes by time across queues
+CREATE INDEX IF NOT EXISTS idx_queue_stats_sampled_at ON queue_stats (sampled_at);
@@ -0,0 +1,3 @@
+-- HMAC of the job payload and the ID of the secret it was made with (empty when unsigned)
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS signature TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS signing_key_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- Analyses requested asynchronously, polled via GET /api/insights/analysis/{id}
+CREATE TABLE IF NOT EXISTS insight_analyses (
+    id UUID PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    insight_id UUID REFERENCES insights(id) ON DELETE SET NULL,
+    error TEXT NOT NULL DEFAULT '',
+    callback_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Pending analyses are recovered oldest first on startup
+CREATE INDEX IF NOT EXISTS idx_insight_analyses_pending ON insight_analyses (created_at) WHERE status = 'pending';
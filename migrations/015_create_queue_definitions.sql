@@ -0,0 +1,14 @@
+-- Named queues managed via /api/queues; zero settings fall back to the worker defaults
+CREATE TABLE IF NOT EXISTS queue_definitions (
+    name TEXT PRIMARY KEY,
+    max_attempts INTEGER NOT NULL DEFAULT 0,
+    base_backoff_ms INTEGER NOT NULL DEFAULT 0,
+    rate_limit_per_second DOUBLE PRECISION NOT NULL DEFAULT 0,
+    allowed_types TEXT[] NOT NULL DEFAULT '{}',
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The default queue exists out of the box so enforcing definitions doesn't reject it
+INSERT INTO queue_definitions (name) VALUES ('default') ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,2 @@
+-- Which failures of a queue's jobs are sent for AI analysis; empty uses the worker's policy
+ALTER TABLE queue_definitions ADD COLUMN IF NOT EXISTS insight_policy TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Draining queues reject new jobs until workers have finished the queued ones
+ALTER TABLE queue_definitions ADD COLUMN IF NOT EXISTS draining BOOLEAN NOT NULL DEFAULT FALSE;
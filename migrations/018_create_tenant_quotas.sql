@@ -0,0 +1,9 @@
+-- Per API key quota overrides; NULL limits fall back to the configured default and 0 is unlimited
+CREATE TABLE IF NOT EXISTS tenant_quotas (
+    api_key TEXT PRIMARY KEY,
+    jobs_per_day INTEGER CHECK (jobs_per_day >= 0),
+    max_pending_jobs INTEGER CHECK (max_pending_jobs >= 0),
+    analyses_per_day INTEGER CHECK (analyses_per_day >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
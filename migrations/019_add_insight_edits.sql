@@ -0,0 +1,5 @@
+-- Operator edits of insights: the AI's recommendation is kept once it is replaced
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS ai_recommendation TEXT NOT NULL DEFAULT '';
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS edited_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- Capabilities a worker needs to run the job, e.g. {gpu,region=eu}; empty runs on any worker
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS requires TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,11 @@
+-- How the job ran after a suggested fix was applied, to measure which fixes work.
+-- Applications recorded before outcomes were tracked are 'unknown' and left out of the stats.
+ALTER TABLE insight_fix_applications ADD COLUMN IF NOT EXISTS job_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE insight_fix_applications ADD COLUMN IF NOT EXISTS fix_kind TEXT NOT NULL DEFAULT '';
+ALTER TABLE insight_fix_applications ADD COLUMN IF NOT EXISTS outcome TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE insight_fix_applications ALTER COLUMN outcome SET DEFAULT 'pending';
+ALTER TABLE insight_fix_applications ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMPTZ;
+
+-- Workers resolve a job's pending applications whenever it completes or fails
+CREATE INDEX IF NOT EXISTS idx_insight_fix_applications_pending ON insight_fix_applications (job_id) WHERE outcome = 'pending';
+CREATE INDEX IF NOT EXISTS idx_insight_fix_applications_effectiveness ON insight_fix_applications (applied_at, job_type);
@@ -0,0 +1,4 @@
+-- Large payloads are stored compressed in payload_compressed, with payload left NULL.
+-- payload_codec names the algorithm (gzip or zstd) and is empty when payload holds the JSON.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS payload_codec TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS payload_compressed BYTEA;
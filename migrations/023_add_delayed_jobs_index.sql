@@ -0,0 +1,3 @@
+-- Delayed jobs wait in Postgres until queue-core's scheduler promotes them to Redis
+CREATE INDEX IF NOT EXISTS idx_jobs_delayed ON jobs (scheduled_for)
+    WHERE status = 'pending' AND scheduled_for IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- version counts the updates of a job, so edits can be refused when the job changed since it was read
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0;
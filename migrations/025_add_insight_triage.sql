@@ -0,0 +1,3 @@
+-- triage_label tells how actionable a failure is (auto-retryable, config-issue or needs-human),
+-- so the DLQ can be sorted by it; insights stored before it have an empty label
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS triage_label TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Alert rules managed via /api/alerts/rules and evaluated by queue-core. The condition is stored
+-- as its expression, e.g. "category=auth AND count>5 in 10m"; 0 cooldown waits for its window.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    condition TEXT NOT NULL,
+    webhook_url TEXT NOT NULL DEFAULT '',
+    cooldown_seconds INTEGER NOT NULL DEFAULT 0 CHECK (cooldown_seconds >= 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_fired_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Insights are sampled by creation time when rules are evaluated
+CREATE INDEX IF NOT EXISTS idx_insights_created_at_all ON insights (created_at);
@@ -0,0 +1,14 @@
+-- Groups of jobs submitted together via /api/groups. size is the number of jobs in the group;
+-- once that many have completed or failed, completed_at is set and the completion job, held
+-- back until then, is released to the scheduler
+CREATE TABLE IF NOT EXISTS job_groups (
+    id UUID PRIMARY KEY,
+    size INTEGER NOT NULL CHECK (size >= 0),
+    completion_job_id UUID REFERENCES jobs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS group_id UUID REFERENCES job_groups(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_group_id ON jobs (group_id) WHERE group_id IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Days a queue's completed jobs keep their payload and result before the retention janitor
+-- clears them; 0 uses retention.payload_days from the config
+ALTER TABLE queue_definitions ADD COLUMN IF NOT EXISTS payload_retention_days INTEGER NOT NULL DEFAULT 0;
+
+-- The janitor looks for completed jobs by when they completed
+CREATE INDEX IF NOT EXISTS idx_jobs_completed_updated_at ON jobs (updated_at) WHERE status = 'completed';
@@ -0,0 +1,2 @@
+-- Serves queue-scoped job listings, newest first
+CREATE INDEX IF NOT EXISTS idx_jobs_queue_created_at ON jobs (queue, created_at DESC) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- kind tells whether an insight explains a failure or a run slower than its job type's SLO
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'failure';
+
+CREATE INDEX IF NOT EXISTS idx_insights_kind_created_at ON insights (kind, created_at DESC);
@@ -0,0 +1,5 @@
+-- shared_from links an insight copied during a failure storm to the insight analyzed for
+-- another job failing the same way
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS shared_from UUID REFERENCES insights(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_insights_shared_from ON insights (shared_from) WHERE shared_from IS NOT NULL;
@@ -0,0 +1,17 @@
+-- error_fingerprint is the normalized error of a job's last failure, and of the failure an
+-- insight analyzed; failures of the same cause share it so their insight can be reused
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS error_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS error_fingerprint TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_jobs_error_fingerprint ON jobs (error_fingerprint) WHERE error_fingerprint <> '';
+CREATE INDEX IF NOT EXISTS idx_insights_error_fingerprint ON insights (error_fingerprint, created_at DESC) WHERE error_fingerprint <> '';
+
+-- Jobs that reused an insight analyzed for another job failing the same way
+CREATE TABLE IF NOT EXISTS insight_jobs (
+    insight_id UUID NOT NULL REFERENCES insights(id) ON DELETE CASCADE,
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    linked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (insight_id, job_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_insight_jobs_job_id ON insight_jobs (job_id, linked_at DESC);
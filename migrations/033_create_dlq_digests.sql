@@ -0,0 +1,15 @@
+-- Audit log of the digests queue-core sends when the DLQ grows past its threshold within a
+-- window. The top error signatures, affected queues and sample insights are kept as sent.
+CREATE TABLE IF NOT EXISTS dlq_digests (
+    id UUID PRIMARY KEY,
+    growth BIGINT NOT NULL,
+    dlq_count BIGINT NOT NULL,
+    window_seconds INTEGER NOT NULL,
+    failed INTEGER NOT NULL,
+    signatures JSONB NOT NULL DEFAULT '[]',
+    queues JSONB NOT NULL DEFAULT '[]',
+    insights JSONB NOT NULL DEFAULT '[]',
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dlq_digests_sent_at ON dlq_digests (sent_at DESC);
@@ -0,0 +1,9 @@
+-- Output jobs stream while they run, in the chunks the worker flushed. A chunk's offset is
+-- where it starts within the job's output; a new run of the job clears the previous output.
+CREATE TABLE IF NOT EXISTS job_output_chunks (
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    byte_offset BIGINT NOT NULL,
+    data BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, byte_offset)
+);
@@ -0,0 +1,14 @@
+-- Every execution of a job, so its history is kept after the next run overwrites its error.
+-- Sent to the AI with a failure to tell transient failures from systemic ones.
+CREATE TABLE IF NOT EXISTS job_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    worker_id TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_attempts_job_id ON job_attempts (job_id, id);
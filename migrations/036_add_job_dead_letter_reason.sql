@@ -0,0 +1,5 @@
+-- dead_letter_reason tells why a failed job was moved to the DLQ, e.g. max_attempts; empty for
+-- jobs that weren't, and for those dead-lettered before reasons were recorded
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS dead_letter_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_jobs_dead_letter_reason ON jobs (updated_at, dead_letter_reason) WHERE dead_letter_reason <> '';
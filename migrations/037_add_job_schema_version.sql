@@ -0,0 +1,6 @@
+-- schema_version is the version of the payload's schema the producer wrote; workers run the jobs
+-- whose version they know, migrating older payloads, and hold the newer ones until a worker
+-- that knows them releases them. Jobs stored before versions were recorded are version 1.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS schema_version INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_held ON jobs (queue, schema_version, created_at) WHERE status = 'held';
@@ -0,0 +1,3 @@
+-- Jobs denied a retry while their queue's retry budget was exhausted are parked until they are
+-- released, a queue at a time and oldest first
+CREATE INDEX IF NOT EXISTS idx_jobs_parked ON jobs (queue, created_at) WHERE status = 'parked';
@@ -0,0 +1,6 @@
+-- created_by is the caller that created the job: the name configured for its API key, or the
+-- key's hashed ID. The API key itself is never stored. Jobs created without a key, or before
+-- callers were recorded, have none.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_jobs_created_by ON jobs (created_by, created_at DESC) WHERE created_by <> '' AND deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- backoff_ms is the wait after which a failed attempt was retried and retry_at when the retry
+-- was due, so a job's retry history shows when each retry happens. Attempts that weren't
+-- retried, and those recorded before retries were, have neither.
+ALTER TABLE job_attempts ADD COLUMN IF NOT EXISTS backoff_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE job_attempts ADD COLUMN IF NOT EXISTS retry_at TIMESTAMPTZ;
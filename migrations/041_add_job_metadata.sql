@@ -0,0 +1,6 @@
+-- metadata holds the string keys and values a job carries apart from its payload, e.g.
+-- correlation IDs, the system it came from or trace context. Jobs created before metadata
+-- existed have none. The GIN index serves listings filtered by metadata keys and values.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_jobs_metadata ON jobs USING GIN (metadata) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- default_type is the type of the jobs created in the queue without one; empty requires a type.
+-- strict_payload rejects jobs whose payload isn't a JSON object.
+ALTER TABLE queue_definitions ADD COLUMN IF NOT EXISTS default_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE queue_definitions ADD COLUMN IF NOT EXISTS strict_payload BOOLEAN NOT NULL DEFAULT FALSE;
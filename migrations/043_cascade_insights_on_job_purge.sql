@@ -0,0 +1,5 @@
+-- Insights are removed with their job when soft-deleted jobs are purged; without the cascade
+-- purging a job with an insight fails on the foreign key
+ALTER TABLE insights DROP CONSTRAINT IF EXISTS insights_job_id_fkey;
+ALTER TABLE insights ADD CONSTRAINT insights_job_id_fkey
+    FOREIGN KEY (job_id) REFERENCES jobs(id) ON DELETE CASCADE;
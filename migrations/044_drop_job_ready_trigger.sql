@@ -0,0 +1,5 @@
+-- Workers are woken by the queue services once a job is ready to dequeue: after the Redis push,
+-- or with a NOTIFY from the Postgres queue backend. The trigger fired when the row committed,
+-- before the job was pushed to Redis and before the backoff of a retry had passed.
+DROP TRIGGER IF EXISTS jobs_notify_ready ON jobs;
+DROP FUNCTION IF EXISTS notify_job_ready();
@@ -0,0 +1,14 @@
+-- Quota overrides are keyed by the tenant, the owner jobs are attributed to, instead of the
+-- raw API key. Existing rows move to the key's hashed ID (see queue.SigningKeyID); rows of
+-- keys named in server.api_key_names must be renamed to that name by hand.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'tenant_quotas' AND column_name = 'api_key'
+    ) THEN
+        ALTER TABLE tenant_quotas RENAME COLUMN api_key TO tenant;
+        UPDATE tenant_quotas
+        SET tenant = 'key-' || left(encode(sha256(convert_to(tenant, 'UTF8')), 'hex'), 12);
+    END IF;
+END $$;